@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+func handleAdminCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Error: admin requires a subcommand")
+		fmt.Println("Run 'hereandnow admin --help' for usage")
+		os.Exit(1)
+	}
+
+	if args[0] == "--help" || args[0] == "-h" {
+		fmt.Printf(`Administration Commands
+
+USAGE:
+    hereandnow admin <SUBCOMMAND> [OPTIONS]
+
+SUBCOMMANDS:
+    reassign            Move a user's open tasks to another user
+
+OPTIONS:
+    --from <username>       User whose tasks are moved (reassign)
+    --to <username>         User who receives the tasks (reassign)
+    --transfer-ownership    Also transfer tasks the user created (reassign)
+    --help, -h             Show this help
+
+EXAMPLES:
+    # Hand an offboarded user's open assignments to a teammate
+    hereandnow admin reassign --from alice --to bob
+
+    # Move their own tasks as well
+    hereandnow admin reassign --from alice --to bob --transfer-ownership
+
+Admin commands require the current user to be an admin.
+`)
+		return
+	}
+
+	if !runSubcommand(adminCommands, args) {
+		fmt.Fprintf(os.Stderr, "Unknown admin subcommand: %s\n", args[0])
+		fmt.Println("Run 'hereandnow admin --help' for usage")
+		os.Exit(1)
+	}
+}
+
+func executeAdminReassign(args []string) {
+	fromUsername := ""
+	toUsername := ""
+	transferOwnership := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from":
+			if i+1 < len(args) {
+				fromUsername = args[i+1]
+				i++
+			}
+		case "--to":
+			if i+1 < len(args) {
+				toUsername = args[i+1]
+				i++
+			}
+		case "--transfer-ownership":
+			transferOwnership = true
+		}
+	}
+
+	if fromUsername == "" || toUsername == "" {
+		fmt.Fprintf(os.Stderr, "Error: admin reassign requires --from and --to\n")
+		fmt.Println("Usage: hereandnow admin reassign --from <username> --to <username> [--transfer-ownership]")
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	fromID, err := findUserByUsername(fromUsername)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: User '%s' not found\n", fromUsername)
+		os.Exit(1)
+	}
+
+	toID, err := findUserByUsername(toUsername)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: User '%s' not found\n", toUsername)
+		os.Exit(1)
+	}
+
+	if dryRun("reassign open tasks from %s to %s", fromUsername, toUsername) {
+		return
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	report, err := taskService.ReassignUserTasks(userID, hereandnow.ReassignRequest{
+		FromUserID:        fromID,
+		ToUserID:          toID,
+		TransferOwnership: transferOwnership,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reassigning tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	if isJSONFormat(globalConfig.Format) {
+		Output(NewFormatter(globalConfig.Format), report)
+		return
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, fmt.Sprintf("Reassigned %d task(s) from %s to %s", len(report.Reassigned), fromUsername, toUsername))
+	if len(report.Reassigned) > 0 {
+		Output(formatter, report.Reassigned)
+	}
+	if transferOwnership {
+		Output(formatter, fmt.Sprintf("Transferred ownership of %d task(s)", len(report.Transferred)))
+		if len(report.Transferred) > 0 {
+			Output(formatter, report.Transferred)
+		}
+	}
+	if len(report.Skipped) > 0 {
+		Output(formatter, fmt.Sprintf("Skipped %d completed or cancelled task(s)", len(report.Skipped)))
+	}
+}
+
+// requireAdmin exits unless the current user is an active admin, and
+// returns them
+func requireAdmin(userRepo *storage.UserRepository) *models.User {
+	current, err := userRepo.GetByID(getCurrentUserID())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+	if !current.IsAdmin || !current.IsActive() {
+		fmt.Fprintf(os.Stderr, "Error: This command requires an admin; %s is not one\n", current.Username)
+		os.Exit(1)
+	}
+	return current
+}
+
+// requireOtherAdmins exits when user is the last active admin, so the
+// install is never left without one
+func requireOtherAdmins(userRepo *storage.UserRepository, user *models.User) {
+	if !user.IsAdmin || !user.IsActive() {
+		return
+	}
+	admins, err := userRepo.CountAdmins()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error counting admins: %v\n", err)
+		os.Exit(1)
+	}
+	if admins <= 1 {
+		fmt.Fprintf(os.Stderr, "Error: %s is the last active admin\n", user.Username)
+		os.Exit(1)
+	}
+}
+
+// signOutUser ends every session and device token of the user
+func signOutUser(sessionRepo *storage.SessionRepository, deviceTokenRepo *storage.DeviceTokenRepository, userID string) error {
+	if err := sessionRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+
+	tokens, err := deviceTokenRepo.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get device tokens: %w", err)
+	}
+	for _, token := range tokens {
+		if err := deviceTokenRepo.Delete(userID, token.ID); err != nil {
+			return fmt.Errorf("failed to revoke device token: %w", err)
+		}
+	}
+	return nil
+}
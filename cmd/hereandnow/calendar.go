@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/calendar"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+)
+
+// calDAVTimeout bounds each request to a CalDAV server
+const calDAVTimeout = 30 * time.Second
+
+// calDAVPasswordEnv holds the password for 'calendar add caldav' when
+// --password is not given, keeping it out of the shell history
+const calDAVPasswordEnv = "HEREANDNOW_CALDAV_PASSWORD"
+
+// googleClientSecretEnv and googleRefreshTokenEnv hold the secrets for
+// 'calendar add google' when their flags are not given
+const (
+	googleClientSecretEnv = "HEREANDNOW_GOOGLE_CLIENT_SECRET"
+	googleRefreshTokenEnv = "HEREANDNOW_GOOGLE_REFRESH_TOKEN"
+)
+
+func executeCalendarAdd(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Error: calendar add requires provider")
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "caldav":
+	case "google":
+		executeCalendarAddGoogle(args[1:])
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported calendar provider: %s (supported: caldav, google)\n", args[0])
+		os.Exit(1)
+	}
+
+	var account CalDAVAccount
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			break
+		}
+		switch args[i] {
+		case "--url":
+			account.URL = args[i+1]
+			i++
+		case "--username":
+			account.Username = args[i+1]
+			i++
+		case "--password":
+			account.Password = args[i+1]
+			i++
+		case "--name":
+			account.Name = args[i+1]
+			i++
+		}
+	}
+
+	if account.URL == "" || account.Username == "" {
+		fmt.Fprintf(os.Stderr, "Error: calendar add caldav requires --url and --username\n")
+		os.Exit(1)
+	}
+	if account.Password == "" {
+		account.Password = os.Getenv(calDAVPasswordEnv)
+	}
+	if account.Name == "" {
+		parsed, err := url.Parse(account.URL)
+		if err != nil || parsed.Host == "" {
+			fmt.Fprintf(os.Stderr, "Error: invalid CalDAV URL: %s\n", account.URL)
+			os.Exit(1)
+		}
+		account.Name = parsed.Host
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Calendar.HasAccount(account.Name) {
+		fmt.Fprintf(os.Stderr, "Error: a calendar named %s is already configured\n", account.Name)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Discovering calendars at %s...\n", account.URL)
+	calendars, err := account.provider().DiscoverCalendars()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error discovering calendars: %v\n", err)
+		os.Exit(1)
+	}
+	for _, calendar := range calendars {
+		fmt.Printf("  %s (%s)\n", calendar.Name, calendar.URL)
+	}
+
+	config.Calendar.CalDAV = append(config.Calendar.CalDAV, account)
+	if err := SaveConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Added %s with %d calendar(s)\n", account.Name, len(calendars))
+}
+
+func executeCalendarAddGoogle(args []string) {
+	account := GoogleAccount{Name: "google"}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			break
+		}
+		switch args[i] {
+		case "--client-id":
+			account.ClientID = args[i+1]
+			i++
+		case "--client-secret":
+			account.ClientSecret = args[i+1]
+			i++
+		case "--refresh-token":
+			account.RefreshToken = args[i+1]
+			i++
+		case "--calendar":
+			account.CalendarID = args[i+1]
+			i++
+		case "--name":
+			account.Name = args[i+1]
+			i++
+		}
+	}
+	if account.ClientSecret == "" {
+		account.ClientSecret = os.Getenv(googleClientSecretEnv)
+	}
+	if account.RefreshToken == "" {
+		account.RefreshToken = os.Getenv(googleRefreshTokenEnv)
+	}
+
+	if account.ClientID == "" || account.ClientSecret == "" || account.RefreshToken == "" {
+		fmt.Fprintf(os.Stderr, "Error: calendar add google requires --client-id, --client-secret and --refresh-token\n")
+		os.Exit(1)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Calendar.HasAccount(account.Name) {
+		fmt.Fprintf(os.Stderr, "Error: a calendar named %s is already configured\n", account.Name)
+		os.Exit(1)
+	}
+
+	// Fetching a day of events checks the credentials before they are saved
+	now := time.Now()
+	if _, err := account.provider().FetchEvents(context.Background(), "", now, now.Add(24*time.Hour)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to Google Calendar: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.Calendar.Google = append(config.Calendar.Google, account)
+	if err := SaveConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Added %s\n", account.Name)
+}
+
+func executeCalendarSync(args []string) {
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	options := config.Calendar.SyncOptions()
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--concurrency" && i+1 < len(args) {
+			concurrency, err := strconv.Atoi(args[i+1])
+			if err != nil || concurrency <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: --concurrency must be a positive number\n")
+				os.Exit(1)
+			}
+			options.Concurrency = concurrency
+			i++
+		}
+	}
+
+	if len(config.Calendar.CalDAV) == 0 && len(config.Calendar.Google) == 0 {
+		fmt.Println("No calendars configured")
+		fmt.Println("Use 'hereandnow calendar add caldav' or 'hereandnow calendar add google' to add one")
+		return
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	// calendarSync is one calendar to sync and its name in the output
+	type calendarSync struct {
+		name     string
+		provider calendar.Provider
+	}
+
+	// Each account's calendars are found afresh so ones added on the
+	// server since the last sync are picked up
+	var syncs []calendarSync
+	clients := map[string]*spacedClient{}
+	for _, account := range config.Calendar.CalDAV {
+		found, err := account.provider().DiscoverCalendars()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", account.Name, err)
+			continue
+		}
+		// Accounts on one server share its request rate
+		host := account.Name
+		if parsed, err := url.Parse(account.URL); err == nil && parsed.Host != "" {
+			host = parsed.Host
+		}
+		if clients[host] == nil {
+			clients[host] = &spacedClient{client: &http.Client{Timeout: calDAVTimeout}, interval: options.ProviderInterval}
+		}
+		for _, collection := range found {
+			syncs = append(syncs, calendarSync{
+				name:     account.Name + "/" + collection.Name,
+				provider: account.calendarProvider(collection, clients[host]),
+			})
+		}
+	}
+	for _, account := range config.Calendar.Google {
+		syncs = append(syncs, calendarSync{name: account.Name, provider: account.provider()})
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	events := storage.NewCalendarEventRepository(db)
+	now := time.Now()
+	since, until := now.Add(-options.PastWindow), now.Add(options.FutureWindow)
+	fmt.Printf("Syncing %d calendar(s) (up to %d at a time)...\n", len(syncs), options.Concurrency)
+
+	results := make([]*calendar.SyncResult, len(syncs))
+	errs := make([]error, len(syncs))
+	slots := make(chan struct{}, options.Concurrency)
+	var wg gosync.WaitGroup
+	for i, job := range syncs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i], errs[i] = calendar.Sync(context.Background(), events, job.provider, userID, since, until)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, job := range syncs {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", job.name, errs[i])
+			failed++
+			continue
+		}
+		result := results[i]
+		fmt.Printf("  %s: %d created, %d updated, %d removed\n", job.name, result.Created, result.Updated, result.Deleted)
+		for _, message := range result.Errors {
+			fmt.Fprintf(os.Stderr, "    ✗ %s\n", message)
+		}
+		if len(result.Errors) > 0 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d calendar(s) synced with errors\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Calendars synced successfully")
+}
+
+func executeCalendarList() {
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Configured Calendars:")
+	if len(config.Calendar.CalDAV) == 0 && len(config.Calendar.Google) == 0 {
+		fmt.Println("No calendars configured")
+		return
+	}
+	for _, account := range config.Calendar.CalDAV {
+		fmt.Printf("  %s  caldav  %s (%s)\n", account.Name, account.URL, account.Username)
+	}
+	for _, account := range config.Calendar.Google {
+		calendarID := account.CalendarID
+		if calendarID == "" {
+			calendarID = calendar.GooglePrimaryCalendar
+		}
+		fmt.Printf("  %s  google  %s\n", account.Name, calendarID)
+	}
+}
+
+func executeCalendarRemove(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Error: calendar remove requires a name")
+		os.Exit(1)
+	}
+	name := args[0]
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	accounts := config.Calendar.CalDAV[:0]
+	for _, account := range config.Calendar.CalDAV {
+		if account.Name != name {
+			accounts = append(accounts, account)
+		}
+	}
+	googleAccounts := config.Calendar.Google[:0]
+	for _, account := range config.Calendar.Google {
+		if account.Name != name {
+			googleAccounts = append(googleAccounts, account)
+		}
+	}
+	if len(accounts) == len(config.Calendar.CalDAV) && len(googleAccounts) == len(config.Calendar.Google) {
+		fmt.Fprintf(os.Stderr, "Error: no calendar named %s\n", name)
+		os.Exit(1)
+	}
+	config.Calendar.CalDAV = accounts
+	config.Calendar.Google = googleAccounts
+
+	if err := SaveConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Removed %s\n", name)
+}
+
+// executeCalendarImport imports the events of an .ics file, and with
+// --as-tasks its to-dos, and prints the import report. Events come before
+// to-dos in the report. It exits non-zero when any record failed.
+func executeCalendarImport(args []string) {
+	path := ""
+	asTasks := false
+	for _, arg := range args {
+		switch {
+		case arg == "--as-tasks":
+			asTasks = true
+		case !strings.HasPrefix(arg, "--"):
+			path = arg
+		}
+	}
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "Error: calendar import requires a file\n")
+		fmt.Println("Usage: hereandnow calendar import <file.ics> [--as-tasks]")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading import file: %v\n", err)
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	events, err := calendar.ImportICS(bytes.NewReader(data), userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading calendar: %v\n", err)
+		os.Exit(1)
+	}
+	tasks, err := calendar.ImportICSTasks(bytes.NewReader(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading calendar: %v\n", err)
+		os.Exit(1)
+	}
+	// To-dos are numbered after the events
+	for i := range tasks {
+		tasks[i].Line += len(events)
+	}
+
+	report := sync.NewImportReport("ics", path)
+	if !asTasks {
+		for _, task := range tasks {
+			report.Skip(task.Line, task.Title, "to-do; use --as-tasks to import it as a task")
+		}
+		tasks = nil
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if dryRun("import %d event(s) and %d task(s) from %s", len(events), len(tasks), path) {
+		Output(formatter, *report)
+		return
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := calendar.SaveImported(storage.NewCalendarEventRepository(db), userID, events, report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing events: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(tasks) > 0 {
+		taskService, err := initTaskService()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+			os.Exit(1)
+		}
+		if err := taskService.ImportTasks(userID, tasks, report, hereandnow.ImportOptions{}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing tasks: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	Output(formatter, *report)
+	if report.HasFailures() {
+		os.Exit(1)
+	}
+}
+
+func (a CalDAVAccount) provider() *sync.CalDAVProvider {
+	return sync.NewCalDAVProvider(a.URL, a.Username, a.Password, &http.Client{Timeout: calDAVTimeout})
+}
+
+// calendarProvider reads one of the account's calendars. Its events keep
+// the provider ID sync.CalDAVProvider.ForCalendar gives them.
+func (a CalDAVAccount) calendarProvider(collection sync.CalDAVCalendar, client calendar.HTTPClient) *calendar.CalDAVProvider {
+	return calendar.NewCalDAVProvider(calendar.CalDAVConfig{
+		URL:         strings.TrimSuffix(collection.URL, "/"),
+		ProviderID:  models.ProviderCalDAV + ":" + collection.URL,
+		Credentials: calendar.StaticCalDAVCredentials{Username: a.Username, Password: a.Password},
+	}, client)
+}
+
+// spacedClient sends at most one request per interval, shared by every
+// calendar on one server
+type spacedClient struct {
+	client   *http.Client
+	interval time.Duration
+
+	mu   gosync.Mutex
+	next time.Time
+}
+
+func (c *spacedClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	now := time.Now()
+	at := now
+	if c.next.After(at) {
+		at = c.next
+	}
+	c.next = at.Add(c.interval)
+	c.mu.Unlock()
+
+	time.Sleep(at.Sub(now))
+	return c.client.Do(req)
+}
+
+func (a GoogleAccount) provider() *calendar.GoogleProvider {
+	return calendar.NewGoogleProvider(calendar.GoogleConfig{
+		ClientID:     a.ClientID,
+		ClientSecret: a.ClientSecret,
+		RefreshToken: a.RefreshToken,
+		CalendarID:   a.CalendarID,
+	}, &http.Client{Timeout: calDAVTimeout})
+}
@@ -4,11 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
 )
 
 func executeInit(args []string) {
 	force := false
 	dbPath := ""
+	adminUser := ""
 	
 	for i, arg := range args {
 		switch arg {
@@ -18,6 +25,10 @@ func executeInit(args []string) {
 			if i+1 < len(args) {
 				dbPath = args[i+1]
 			}
+		case "--admin-user":
+			if i+1 < len(args) {
+				adminUser = args[i+1]
+			}
 		}
 	}
 
@@ -73,12 +84,40 @@ func executeInit(args []string) {
 	fmt.Printf("✓ Configuration created: %s\n", getConfigPath())
 	fmt.Printf("✓ Database created: %s\n", config.Database.Path)
 	fmt.Printf("✓ Logs directory: %s\n", logsDir)
+
+	if adminUser != "" {
+		seedAdminUser(storage.NewUserRepository(db), adminUser)
+		fmt.Println("\nNext steps:")
+		fmt.Println("1. Start the server: hereandnow serve")
+		fmt.Println("2. Add some locations: hereandnow location add --name 'Home' --lat 37.7749 --lng -122.4194")
+		return
+	}
+
 	fmt.Println("\nNext steps:")
-	fmt.Println("1. Create a user: hereandnow user create")
+	fmt.Println("1. Create a user (the first one is an admin): hereandnow user create")
 	fmt.Println("2. Start the server: hereandnow serve")
 	fmt.Println("3. Add some locations: hereandnow location add --name 'Home' --lat 37.7749 --lng -122.4194")
 }
 
+// seedAdminUser makes the user an admin, creating them when they do not
+// exist yet, so a fresh install is not locked out of user management
+func seedAdminUser(userRepo *storage.UserRepository, username string) {
+	user, err := userRepo.GetByUsername(username)
+	if err != nil {
+		executeUserCreate([]string{"--admin", "--username", username})
+		return
+	}
+
+	if !user.IsAdmin {
+		user.IsAdmin = true
+		if err := userRepo.Update(user); err != nil {
+			fmt.Fprintf(os.Stderr, "Error making %s an admin: %v\n", username, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("✓ Admin user: %s\n", username)
+}
+
 func executeDoctor(args []string) {
 	fix := false
 	for _, arg := range args {
@@ -129,6 +168,26 @@ func executeDoctor(args []string) {
 			db.Close()
 		}
 
+		// Check encryption at rest
+		if key, err := databaseKey(config); err != nil {
+			fmt.Printf("✗ Database encryption: FAILED (%v)\n", err)
+			issues++
+		} else if warning := (storage.Config{Path: config.Database.Path, EncryptionKey: key}).PrivacyWarning(); warning != "" {
+			fmt.Println("⚠ Database encryption: OFF")
+			fmt.Printf("  Privacy warning: %s\n", warning)
+			fmt.Println("  Set database.encryption: sqlcipher in a sqlcipher build to encrypt it")
+		} else {
+			fmt.Println("✓ Database encryption: ON (SQLCipher)")
+		}
+
+		// Check metadata JSON integrity
+		if db, err := InitDatabase(config.Database.Path); err == nil {
+			if !checkMetadata(db, fix) {
+				issues++
+			}
+			db.Close()
+		}
+
 		// Check write permissions
 		testFile := filepath.Join(filepath.Dir(config.Database.Path), ".write_test")
 		if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
@@ -214,27 +273,8 @@ func executeCalendar(args []string) {
 		os.Exit(1)
 	}
 
-	subcommand := args[0]
-	switch subcommand {
-	case "add":
-		if len(args) < 2 {
-			fmt.Println("Error: calendar add requires provider")
-			os.Exit(1)
-		}
-		provider := args[1]
-		fmt.Printf("Adding %s calendar integration...\n", provider)
-		// Implementation would go here
-		fmt.Println("✓ Calendar integration added")
-	case "sync":
-		fmt.Println("Syncing calendars...")
-		// Implementation would go here
-		fmt.Println("✓ Calendars synced successfully")
-	case "list":
-		fmt.Println("Configured Calendars:")
-		// Implementation would go here
-		fmt.Println("No calendars configured")
-	default:
-		fmt.Printf("Unknown calendar subcommand: %s\n", subcommand)
+	if !runSubcommand(calendarCommands, args) {
+		fmt.Printf("Unknown calendar subcommand: %s\n", args[0])
 		os.Exit(1)
 	}
 }
@@ -246,35 +286,357 @@ func executeList(args []string) {
 		os.Exit(1)
 	}
 
-	subcommand := args[0]
-	switch subcommand {
-	case "create":
-		if len(args) < 2 {
-			fmt.Println("Error: list create requires name")
+	if !runSubcommand(listCommands, args) {
+		fmt.Printf("Unknown list subcommand: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// listDefaultFlags are the default task constraints given to 'list create'
+// and 'list update'. A nil field was not given.
+type listDefaultFlags struct {
+	locationID *string // "" clears the default location
+	minutes    *int    // 0 clears the default estimate
+}
+
+// parseListDefaultFlags reads --location, --no-location and
+// --default-minutes, resolving the location's name for userID, and returns
+// the remaining arguments
+func parseListDefaultFlags(args []string, userID string) (listDefaultFlags, []string, error) {
+	var flags listDefaultFlags
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--location" && i+1 < len(args):
+			locationID, err := findLocationByName(args[i+1], userID)
+			if err != nil {
+				return flags, nil, err
+			}
+			flags.locationID = &locationID
+			i++
+		case args[i] == "--no-location":
+			none := ""
+			flags.locationID = &none
+		case args[i] == "--default-minutes" && i+1 < len(args):
+			minutes, err := strconv.Atoi(args[i+1])
+			if err != nil || minutes < 0 {
+				return flags, nil, fmt.Errorf("--default-minutes must be a number of minutes")
+			}
+			flags.minutes = &minutes
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return flags, rest, nil
+}
+
+// apply sets the given defaults on list
+func (f listDefaultFlags) apply(list *models.TaskList) error {
+	if f.locationID != nil {
+		list.SetDefaultLocation(*f.locationID)
+	}
+	if f.minutes != nil {
+		minutes := f.minutes
+		if *minutes == 0 {
+			minutes = nil
+		}
+		return list.SetDefaultEstimatedMinutes(minutes)
+	}
+	return nil
+}
+
+func executeListCreate(args []string) {
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	defaults, args, err := parseListDefaultFlags(args, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	name := ""
+	shared := false
+	for _, arg := range args {
+		if arg == "--shared" {
+			shared = true
+		} else if name == "" && !strings.HasPrefix(arg, "--") {
+			name = arg
+		}
+	}
+	if name == "" {
+		fmt.Println("Error: list create requires name")
+		os.Exit(1)
+	}
+
+	list, err := models.NewTaskList(name, "", userID)
+	if err == nil {
+		err = defaults.apply(list)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if shared {
+		list.Share()
+	}
+	if dryRun("create list: %s", name) {
+		return
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := storage.NewTaskListRepository(db).Create(*list); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating list: %v\n", err)
+		os.Exit(1)
+	}
+
+	if shared {
+		fmt.Printf("✓ Shared list created: %s (%s)\n", list.Name, list.ID)
+	} else {
+		fmt.Printf("✓ List created: %s (%s)\n", list.Name, list.ID)
+	}
+	printListDefaults(*list)
+}
+
+// executeListUpdate changes a list's default task constraints, and with
+// --apply-to-existing gives them to the list's open tasks too
+func executeListUpdate(args []string) {
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	defaults, args, err := parseListDefaultFlags(args, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	nameOrID := ""
+	applyToExisting := false
+	for _, arg := range args {
+		if arg == "--apply-to-existing" {
+			applyToExisting = true
+		} else if nameOrID == "" && !strings.HasPrefix(arg, "--") {
+			nameOrID = arg
+		}
+	}
+	if nameOrID == "" {
+		fmt.Println("Error: list update requires a list name or ID")
+		fmt.Println("Usage: hereandnow list update <name> [--location <name>|--no-location] [--default-minutes <n>] [--apply-to-existing]")
+		os.Exit(1)
+	}
+
+	listID, err := findListByName(nameOrID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	lists := storage.NewTaskListRepository(db)
+	list, err := lists.GetByID(listID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !list.IsOwnedBy(userID) {
+		fmt.Fprintf(os.Stderr, "Error: only the list owner can update it\n")
+		os.Exit(1)
+	}
+	if err := defaults.apply(list); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if dryRun("update list: %s", list.Name) {
+		return
+	}
+
+	if err := lists.Update(*list); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating list: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ List updated: %s\n", list.Name)
+	printListDefaults(*list)
+
+	if applyToExisting {
+		taskService, err := initTaskService()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
 			os.Exit(1)
 		}
-		name := args[1]
-		shared := false
-		for _, arg := range args[2:] {
-			if arg == "--shared" {
-				shared = true
-			}
+		updated, err := taskService.ApplyListDefaults(list.ID, userID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying list defaults: %v\n", err)
+			os.Exit(1)
 		}
-		if shared {
-			fmt.Printf("Creating shared list: %s\n", name)
-		} else {
-			fmt.Printf("Creating list: %s\n", name)
+		fmt.Printf("✓ Applied to %d existing task(s)\n", updated)
+	}
+}
+
+// executeListArchive archives or unarchives a list the current user owns.
+// Its tasks are kept and come back with it.
+func executeListArchive(args []string, archive bool) {
+	subcommand := "unarchive"
+	if archive {
+		subcommand = "archive"
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "--") {
+		fmt.Printf("Error: list %s requires a list name or ID\n", subcommand)
+		fmt.Printf("Usage: hereandnow list %s <name>\n", subcommand)
+		os.Exit(1)
+	}
+	nameOrID := args[0]
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Only lists that can change are looked up: active ones to archive,
+	// archived ones to bring back
+	listRepo := storage.NewTaskListRepository(db)
+	lists, err := listRepo.GetActive()
+	if !archive {
+		lists, err = listRepo.GetArchived()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var list *models.TaskList
+	for i := range lists {
+		if lists[i].ID == nameOrID || (lists[i].OwnerID == userID && strings.EqualFold(lists[i].Name, nameOrID)) {
+			list = &lists[i]
+			break
 		}
-		// Implementation would go here
-		fmt.Println("✓ List created successfully")
-	case "list":
-		fmt.Println("Your Task Lists:")
-		// Implementation would go here
-		fmt.Println("No lists found")
-	default:
-		fmt.Printf("Unknown list subcommand: %s\n", subcommand)
+	}
+	if list == nil {
+		fmt.Fprintf(os.Stderr, "Error: list not found: %s\n", nameOrID)
+		os.Exit(1)
+	}
+	if dryRun("%s list: %s", subcommand, list.Name) {
+		return
+	}
+
+	listService := hereandnow.NewListService(listRepo, storage.NewListMemberRepository(db))
+	if archive {
+		_, err = listService.ArchiveList(list.ID, userID)
+	} else {
+		_, err = listService.UnarchiveList(list.ID, userID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	if archive {
+		fmt.Printf("✓ List archived: %s\n", list.Name)
+		fmt.Printf("  Its tasks are hidden and can't be changed; bring it back with 'hereandnow list unarchive %q'\n", list.Name)
+	} else {
+		fmt.Printf("✓ List unarchived: %s\n", list.Name)
+	}
+}
+
+func printListDefaults(list models.TaskList) {
+	if list.DefaultLocationID != nil {
+		fmt.Printf("  Default location: %s\n", *list.DefaultLocationID)
+	}
+	if list.DefaultEstimatedMinutes != nil {
+		fmt.Printf("  Default estimate: %d minutes\n", *list.DefaultEstimatedMinutes)
+	}
+}
+
+// executeListSchedule shows each due task in a shared list in every member's
+// timezone and flags tasks due outside their assignee's working hours
+func executeListSchedule(listID string) {
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	scheduler := hereandnow.NewListScheduler(storage.NewTaskRepository(db), storage.NewListMemberRepository(db), storage.NewUserRepository(db))
+	schedule, err := scheduler.Schedule(listID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building list schedule: %v\n", err)
+		os.Exit(1)
+	}
+
+	if isJSONFormat(globalConfig.Format) {
+		Output(NewFormatter(globalConfig.Format), schedule)
+		return
+	}
+
+	if len(schedule.Entries) == 0 {
+		fmt.Println("No open tasks with due dates in this list")
+		return
+	}
+
+	for _, entry := range schedule.Entries {
+		flag := ""
+		if entry.OutOfHours {
+			flag = "  ⚠ due outside assignee's working hours"
+		}
+		fmt.Printf("%s%s\n", entry.Task.Title, flag)
+		for _, due := range entry.DueTimes {
+			marker := " "
+			if due.IsAssignee {
+				marker = "*"
+			}
+			hours := "off hours"
+			if due.WorkingHours {
+				hours = "working hours"
+			}
+			fmt.Printf("  %s %-16s %-20s %s  (%s)\n", marker, due.Username, due.TimeZone, due.DueAt.Format("Mon Jan 2 15:04 MST"), hours)
+		}
+	}
+	fmt.Println("* assignee")
 }
 
 func executeReset(args []string) {
@@ -290,6 +652,17 @@ func executeReset(args []string) {
 		}
 	}
 
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if dryRun("reset all data: remove %s and %s", config.Database.Path, getConfigPath()) {
+		printResetScope(config.Database.Path)
+		return
+	}
+
 	if !confirm {
 		fmt.Println("WARNING: This will delete all data!")
 		fmt.Println("Use --confirm to proceed with reset")
@@ -303,11 +676,6 @@ func executeReset(args []string) {
 	}
 
 	fmt.Println("Resetting all data...")
-	config, err := LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
-	}
 
 	// Remove database
 	if err := os.Remove(config.Database.Path); err != nil && !os.IsNotExist(err) {
@@ -323,6 +691,40 @@ func executeReset(args []string) {
 	fmt.Println("Run 'hereandnow init' to reinitialize")
 }
 
+// printResetScope lists the rows a reset would destroy. It opens an existing
+// database only, so a dry run never creates one.
+func printResetScope(dbPath string) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		fmt.Println("No database found; nothing to delete")
+		return
+	}
+
+	db, err := storage.NewDB(storage.Config{Path: dbPath})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	counts, err := db.RowCounts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error counting rows: %v\n", err)
+		os.Exit(1)
+	}
+
+	if isJSONFormat(globalConfig.Format) {
+		Output(NewFormatter(globalConfig.Format), counts)
+		return
+	}
+
+	total := 0
+	for _, count := range counts {
+		fmt.Printf("  %-24s %d row(s)\n", count.Table, count.Rows)
+		total += count.Rows
+	}
+	fmt.Printf("%d row(s) in %d table(s) would be deleted\n", total, len(counts))
+}
+
 // Helper functions (these will be implemented in other files)
 
 func createDefaultConfig() error {
@@ -336,6 +738,40 @@ func checkAPIServer(host string, port int) error {
 	return fmt.Errorf("connection refused")
 }
 
+// checkMetadata reports rows with unparseable metadata JSON and, when fix is
+// set, resets them to an empty object. It returns false if problems remain.
+func checkMetadata(db *storage.DB, fix bool) bool {
+	metadataRepo := storage.NewMetadataRepository(db)
+	corrupt, err := metadataRepo.FindCorrupt()
+	if err != nil {
+		fmt.Printf("✗ Metadata integrity: FAILED (%v)\n", err)
+		return false
+	}
+
+	if len(corrupt) == 0 {
+		fmt.Println("✓ Metadata integrity: OK")
+		return true
+	}
+
+	fmt.Printf("✗ Metadata integrity: %d row(s) with invalid JSON\n", len(corrupt))
+	for _, c := range corrupt {
+		fmt.Printf("  %s.%s (id %s)\n", c.Table, c.Column, c.ID)
+	}
+
+	if !fix {
+		return false
+	}
+
+	fmt.Println("  Resetting invalid metadata to {}...")
+	repaired, err := metadataRepo.Repair(corrupt)
+	if err != nil {
+		fmt.Printf("  Failed to repair metadata: %v\n", err)
+		return false
+	}
+	fmt.Printf("  ✓ Repaired %d row(s)\n", repaired)
+	return true
+}
+
 func runMigrationsUp(dbPath string) error {
 	// This would run the actual migrations
 	// For now, just return success
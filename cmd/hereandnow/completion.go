@@ -0,0 +1,515 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// completionTaskLimit is how many recently changed tasks are offered when
+// completing a task ID
+const completionTaskLimit = 50
+
+func handleCompletionCommand(args []string) {
+	if len(args) == 0 || args[0] == "--help" || args[0] == "-h" {
+		fmt.Printf(`Shell Completion
+
+USAGE:
+    hereandnow completion <bash|zsh|fish> [OPTIONS]
+
+DESCRIPTION:
+    Prints a script that completes commands, subcommands, flags and the
+    values flags like --format take as you type them. Task IDs complete to
+    your recently changed open tasks and location names to your saved
+    locations, both read from the configured database each time you press
+    tab by running 'hereandnow __complete'.
+
+OPTIONS:
+    --no-dynamic        Leave task IDs and location names out, so completing
+                        never runs hereandnow or opens the database
+    --help, -h          Show this help
+
+EXAMPLES:
+    # bash: load it in the current shell, or install it for new ones
+    source <(hereandnow completion bash)
+    hereandnow completion bash > ~/.local/share/bash-completion/completions/hereandnow
+
+    # zsh: install it in a directory on your $fpath
+    hereandnow completion zsh > "${fpath[1]}/_hereandnow"
+
+    # fish
+    hereandnow completion fish > ~/.config/fish/completions/hereandnow.fish
+
+    # Without database lookups, e.g. for an encrypted database
+    hereandnow completion bash --no-dynamic
+`)
+		if len(args) == 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	shell := ""
+	dynamic := true
+	for _, arg := range args {
+		if arg == "--no-dynamic" {
+			dynamic = false
+		} else if shell == "" {
+			shell = arg
+		}
+	}
+
+	paths := completionPaths(commands, dynamic)
+	switch shell {
+	case "bash":
+		fmt.Print(bashCompletion(paths))
+	case "zsh":
+		fmt.Print(zshCompletion(paths))
+	case "fish":
+		fmt.Print(fishCompletion(paths))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell: %s (supported: bash, zsh, fish)\n", shell)
+		os.Exit(1)
+	}
+}
+
+// executeComplete lists the values a dynamic completion offers, one per
+// line, with a tab before a task's title. The completion scripts call it
+// as 'hereandnow __complete tasks'. It prints nothing on errors, so a
+// missing database just completes nothing.
+func executeComplete(args []string) {
+	if len(args) != 1 || !completion(args[0]).dynamic() {
+		return
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return
+	}
+	// Don't create a database for tab completion
+	if _, err := os.Stat(config.Database.Path); err != nil {
+		return
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		return
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	switch completion(args[0]) {
+	case completeTaskIDs:
+		tasks, err := storage.NewTaskRepository(db).Search(storage.TaskSearchOptions{
+			UserID:         userID,
+			IncludeShared:  true,
+			OrderBy:        "updated_at",
+			OrderDirection: "DESC",
+			Limit:          completionTaskLimit,
+		})
+		if err != nil {
+			return
+		}
+		for _, task := range tasks {
+			if task.Status == models.TaskStatusCompleted || task.Status == models.TaskStatusCancelled {
+				continue
+			}
+			fmt.Printf("%s\t%s\n", task.ID, strings.Join(strings.Fields(task.Title), " "))
+		}
+	case completeLocations:
+		locations, err := storage.NewLocationRepository(db).GetByUser(userID, 0, 0)
+		if err != nil {
+			return
+		}
+		for _, location := range locations {
+			fmt.Println(location.Name)
+		}
+	}
+}
+
+// completionPath is a point in the command tree the scripts complete at:
+// the words typed to reach it, what comes next, and its flags
+type completionPath struct {
+	words       string
+	subcommands []*command
+	flags       []flag
+	args        completion
+}
+
+// completionPaths walks the registry, starting from the top level. Without
+// dynamic, task IDs and location names complete to nothing.
+func completionPaths(commands []*command, dynamic bool) []completionPath {
+	var paths []completionPath
+	var walk func(words string, subcommands []*command, flags []flag, args completion)
+	walk = func(words string, subcommands []*command, flags []flag, args completion) {
+		path := completionPath{words: words, args: args}
+		for _, f := range flags {
+			if !dynamic && f.complete.dynamic() {
+				f.complete = completeNothing
+			}
+			path.flags = append(path.flags, f)
+		}
+		if !dynamic && args.dynamic() {
+			path.args = completeNothing
+		}
+		for _, cmd := range subcommands {
+			if !cmd.hidden {
+				path.subcommands = append(path.subcommands, cmd)
+			}
+		}
+		paths = append(paths, path)
+
+		for _, cmd := range path.subcommands {
+			walk(strings.TrimSpace(words+" "+cmd.name), cmd.subcommands, cmd.flags, cmd.args)
+		}
+	}
+	walk("", commands, nil, completeNothing)
+	return paths
+}
+
+// completionCase is a case in the generated scripts: the shell patterns
+// that lead to a completion
+type completionCase struct {
+	patterns []string
+	complete completion
+	choices  []string
+}
+
+// completionCases collects cases in the order they're first added, so
+// scripts come out the same every time
+type completionCases []completionCase
+
+func (cases completionCases) add(pattern string, complete completion, choices []string) completionCases {
+	for i, c := range cases {
+		if c.complete == complete && slices.Equal(c.choices, choices) {
+			cases[i].patterns = append(cases[i].patterns, pattern)
+			return cases
+		}
+	}
+	return append(cases, completionCase{patterns: []string{pattern}, complete: complete, choices: choices})
+}
+
+// valueCases matches "<path>:<flag>" for every flag that takes a value.
+// Global flags match under any path, after the commands' own flags.
+func valueCases(paths []completionPath) completionCases {
+	var cases, globals completionCases
+	for _, path := range paths {
+		for _, f := range path.flags {
+			if f.value != "" {
+				cases = cases.add(fmt.Sprintf("%q", path.words+":"+f.name), f.complete, f.choices)
+			}
+		}
+	}
+	for _, f := range globalFlags {
+		if f.value != "" {
+			globals = globals.add(fmt.Sprintf("*%q", ":"+f.name), f.complete, f.choices)
+		}
+	}
+	return append(cases, globals...)
+}
+
+// argCases matches the paths whose positional arguments complete to
+// something
+func argCases(paths []completionPath) completionCases {
+	var cases completionCases
+	for _, path := range paths {
+		if path.args != completeNothing {
+			cases = cases.add(fmt.Sprintf("%q", path.words), path.args, nil)
+		}
+	}
+	return cases
+}
+
+func flagNames(flags []flag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.name
+	}
+	return strings.Join(names, " ")
+}
+
+func commandNames(commands []*command) string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return strings.Join(names, " ")
+}
+
+// completionHeader starts every script
+const completionHeader = "# %s completion for hereandnow, generated by 'hereandnow completion %s'.\n" +
+	"# Regenerate it after upgrading to pick up new commands.\n\n"
+
+func bashCompletion(paths []completionPath) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, completionHeader, "bash", "bash")
+
+	sb.WriteString("_hereandnow_subcommands() {\n    case \"$1\" in\n")
+	for _, path := range paths {
+		if len(path.subcommands) > 0 {
+			fmt.Fprintf(&sb, "    %q) echo %q ;;\n", path.words, commandNames(path.subcommands))
+		}
+	}
+	sb.WriteString("    esac\n}\n\n")
+
+	sb.WriteString("_hereandnow_flags() {\n    case \"$1\" in\n")
+	for _, path := range paths {
+		if len(path.flags) > 0 {
+			fmt.Fprintf(&sb, "    %q) echo %q ;;\n", path.words, flagNames(path.flags))
+		}
+	}
+	fmt.Fprintf(&sb, "    esac\n    echo %q\n}\n\n", flagNames(globalFlags))
+
+	sb.WriteString("# Sets kind, and choices for a flag with a fixed set of values, and\n")
+	sb.WriteString("# fails unless flag $2 under $1 takes a value\n")
+	sb.WriteString("_hereandnow_value() {\n    case \"$1:$2\" in\n")
+	for _, c := range valueCases(paths) {
+		fmt.Fprintf(&sb, "    %s) kind=%q", strings.Join(c.patterns, "|"), c.complete)
+		if c.choices != nil {
+			fmt.Fprintf(&sb, " choices=%q", strings.Join(c.choices, " "))
+		}
+		sb.WriteString(" ;;\n")
+	}
+	sb.WriteString("    *) return 1 ;;\n    esac\n}\n\n")
+
+	sb.WriteString("_hereandnow_args() {\n    kind=\n    case \"$1\" in\n")
+	for _, c := range argCases(paths) {
+		fmt.Fprintf(&sb, "    %s) kind=%q ;;\n", strings.Join(c.patterns, "|"), c.complete)
+	}
+	sb.WriteString("    esac\n}\n\n")
+
+	sb.WriteString(`_hereandnow_complete_values() {
+    local IFS=$'\n'
+    case "$1" in
+    files) COMPREPLY=($(compgen -f -- "$cur")) ;;
+    choices) COMPREPLY=($(IFS=' '; compgen -W "$choices" -- "$cur")) ;;
+    tasks|locations)
+        COMPREPLY=($(compgen -W "$(hereandnow __complete "$1" 2>/dev/null | cut -f1)" -- "$cur"))
+        COMPREPLY=("${COMPREPLY[@]// /\\ }")
+        ;;
+    *) COMPREPLY=() ;;
+    esac
+}
+
+_hereandnow() {
+    local cur prev cmdpath word kind choices subcommands i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    cmdpath=""
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        if [[ " $(_hereandnow_subcommands "$cmdpath") " == *" $word "* ]]; then
+            cmdpath="${cmdpath:+$cmdpath }$word"
+        fi
+    done
+
+    if _hereandnow_value "$cmdpath" "$prev"; then
+        _hereandnow_complete_values "$kind"
+        return
+    fi
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$(_hereandnow_flags "$cmdpath")" -- "$cur"))
+        return
+    fi
+    subcommands=$(_hereandnow_subcommands "$cmdpath")
+    if [[ -n $subcommands ]]; then
+        COMPREPLY=($(compgen -W "$subcommands" -- "$cur"))
+        return
+    fi
+    _hereandnow_args "$cmdpath"
+    _hereandnow_complete_values "$kind"
+}
+
+complete -F _hereandnow hereandnow
+`)
+	return sb.String()
+}
+
+// zshQuote single-quotes s for zsh
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func zshCompletion(paths []completionPath) string {
+	var sb strings.Builder
+	sb.WriteString("#compdef hereandnow\n")
+	fmt.Fprintf(&sb, completionHeader, "zsh", "zsh")
+
+	sb.WriteString("_hereandnow_subcommands() {\n    case $1 in\n")
+	for _, path := range paths {
+		if len(path.subcommands) == 0 {
+			continue
+		}
+		described := make([]string, len(path.subcommands))
+		for i, cmd := range path.subcommands {
+			described[i] = zshQuote(cmd.name + ":" + cmd.summary)
+		}
+		fmt.Fprintf(&sb, "    %q) reply=(%s) ;;\n", path.words, strings.Join(described, " "))
+	}
+	sb.WriteString("    *) reply=() ;;\n    esac\n}\n\n")
+
+	sb.WriteString("_hereandnow_flags() {\n    case $1 in\n")
+	for _, path := range paths {
+		if len(path.flags) > 0 {
+			fmt.Fprintf(&sb, "    %q) reply=(%s) ;;\n", path.words, flagNames(path.flags))
+		}
+	}
+	fmt.Fprintf(&sb, "    *) reply=() ;;\n    esac\n    reply+=(%s)\n}\n\n", flagNames(globalFlags))
+
+	sb.WriteString("# Sets REPLY, and reply to the values of a flag with a fixed set, and\n")
+	sb.WriteString("# fails unless flag $2 under $1 takes a value\n")
+	sb.WriteString("_hereandnow_value() {\n    case \"$1:$2\" in\n")
+	for _, c := range valueCases(paths) {
+		fmt.Fprintf(&sb, "    %s) REPLY=%q", strings.Join(c.patterns, "|"), c.complete)
+		if c.choices != nil {
+			fmt.Fprintf(&sb, " reply=(%s)", strings.Join(c.choices, " "))
+		}
+		sb.WriteString(" ;;\n")
+	}
+	sb.WriteString("    *) return 1 ;;\n    esac\n}\n\n")
+
+	sb.WriteString("_hereandnow_args() {\n    REPLY=\n    case $1 in\n")
+	for _, c := range argCases(paths) {
+		fmt.Fprintf(&sb, "    %s) REPLY=%q ;;\n", strings.Join(c.patterns, "|"), c.complete)
+	}
+	sb.WriteString("    esac\n}\n\n")
+
+	sb.WriteString(`_hereandnow_complete_values() {
+    local line
+    local -a values
+    case $1 in
+    files) _files ;;
+    choices) compadd -a reply ;;
+    tasks|locations)
+        for line in ${(f)"$(hereandnow __complete $1 2>/dev/null)"}; do
+            if [[ $line == *$'\t'* ]]; then
+                values+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+            else
+                values+=("${line//:/\\:}")
+            fi
+        done
+        _describe -t $1 $1 values
+        ;;
+    esac
+}
+
+_hereandnow() {
+    local cmdpath word i REPLY
+    local -a reply
+    for ((i = 2; i < CURRENT; i++)); do
+        word=${words[i]}
+        _hereandnow_subcommands "$cmdpath"
+        if (( ${reply[(I)${(b)word}:*]} )); then
+            cmdpath=${cmdpath:+$cmdpath }$word
+        fi
+    done
+
+    if _hereandnow_value "$cmdpath" "${words[CURRENT-1]}"; then
+        _hereandnow_complete_values "$REPLY"
+        return
+    fi
+    if [[ ${words[CURRENT]} == -* ]]; then
+        _hereandnow_flags "$cmdpath"
+        compadd -a reply
+        return
+    fi
+    _hereandnow_subcommands "$cmdpath"
+    if (( $#reply )); then
+        _describe -t commands command reply
+        return
+    fi
+    _hereandnow_args "$cmdpath"
+    _hereandnow_complete_values "$REPLY"
+}
+
+if [[ $funcstack[1] == _hereandnow ]]; then
+    _hereandnow "$@"
+else
+    compdef _hereandnow hereandnow
+fi
+`)
+	return sb.String()
+}
+
+// fishQuote single-quotes s for fish
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// fishFlag is the complete options for a flag: the long option, and what
+// its value completes to
+func fishFlag(f flag) string {
+	option := "-l " + strings.TrimPrefix(f.name, "--")
+	switch {
+	case f.value == "":
+		return option
+	case f.complete == completeFiles:
+		return option + " -r -F"
+	case f.complete == completeChoices:
+		return option + " -x -a " + fishQuote(strings.Join(f.choices, " "))
+	case f.complete.dynamic():
+		return option + " -x -a " + fishQuote("(__hereandnow_values "+string(f.complete)+")")
+	default:
+		return option + " -x"
+	}
+}
+
+func fishCompletion(paths []completionPath) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, completionHeader, "fish", "fish")
+
+	sb.WriteString("function __hereandnow_subcommands\n    switch \"$argv[1]\"\n")
+	for _, path := range paths {
+		if len(path.subcommands) > 0 {
+			fmt.Fprintf(&sb, "        case %s\n            printf '%%s\\n' %s\n", fishQuote(path.words), commandNames(path.subcommands))
+		}
+	}
+	sb.WriteString("    end\nend\n\n")
+
+	sb.WriteString(`function __hereandnow_at
+    set -l cmdpath
+    for word in (commandline -opc)[2..-1]
+        if contains -- $word (__hereandnow_subcommands "$cmdpath")
+            set -a cmdpath $word
+        end
+    end
+    test "$cmdpath" = "$argv[1]"
+end
+
+function __hereandnow_values
+    hereandnow __complete $argv[1] 2>/dev/null
+end
+
+complete -c hereandnow -f
+`)
+
+	for _, f := range globalFlags {
+		fmt.Fprintf(&sb, "complete -c hereandnow %s\n", fishFlag(f))
+	}
+
+	for _, path := range paths {
+		condition := "-n " + fishQuote("__hereandnow_at "+fishQuote(path.words))
+		for _, cmd := range path.subcommands {
+			fmt.Fprintf(&sb, "complete -c hereandnow %s -a %s -d %s\n", condition, cmd.name, fishQuote(cmd.summary))
+		}
+		for _, f := range path.flags {
+			fmt.Fprintf(&sb, "complete -c hereandnow %s %s\n", condition, fishFlag(f))
+		}
+		switch {
+		case path.args == completeFiles:
+			fmt.Fprintf(&sb, "complete -c hereandnow %s -F\n", condition)
+		case path.args.dynamic():
+			fmt.Fprintf(&sb, "complete -c hereandnow %s -a %s\n", condition, fishQuote("(__hereandnow_values "+string(path.args)+")"))
+		}
+	}
+	return sb.String()
+}
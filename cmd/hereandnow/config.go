@@ -3,29 +3,319 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
-
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/locale"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/notify"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
 	_ "github.com/mattn/go-sqlite3"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Logging  LoggingConfig  `yaml:"logging"`
-	Features FeaturesConfig `yaml:"features"`
+	Server    ServerConfig            `yaml:"server"`
+	Database  DatabaseConfig          `yaml:"database"`
+	Logging   LoggingConfig           `yaml:"logging"`
+	Features  FeaturesConfig          `yaml:"features"`
+	Locations models.LocationDefaults `yaml:"locations"`
+	Snooze    SnoozeConfig            `yaml:"snooze"`
+	Estimates EstimatesConfig         `yaml:"estimates"`
+	Lists     ListsConfig             `yaml:"lists"`
+	// Recurrence controls how recurring tasks schedule their next instance
+	Recurrence RecurrenceConfig `yaml:"recurrence"`
+	// Weather controls the weather filter
+	Weather WeatherConfig `yaml:"weather"`
+	// Energy controls the energy filter
+	Energy EnergyConfig `yaml:"energy"`
+	// Context controls how long a context is trusted and how much it
+	// favours tasks at the kind of place you are
+	Context ContextConfig `yaml:"context"`
+	// Travel controls counting the time to reach a task's location
+	Travel TravelConfig `yaml:"travel"`
+	Calendar  CalendarConfig          `yaml:"calendar"`
+	Output    OutputConfig            `yaml:"output"`
+	// Maintenance controls the server's background housekeeping
+	Maintenance maintenance.Config `yaml:"maintenance"`
+	// Notifications controls pushing notifications to users' channels
+	Notifications NotificationsConfig `yaml:"notifications"`
+	// Locale sets the language for dates and numbers in human output
+	Locale string `yaml:"locale,omitempty"`
+}
+
+type SnoozeConfig struct {
+	// Presets add to or override the built-in snooze presets
+	Presets models.SnoozePresets `yaml:"presets"`
+}
+
+type EstimatesConfig struct {
+	// Unit is "minutes" (default) or "points". In points mode the time
+	// filter sizes tasks by their effort points.
+	Unit filters.EstimateUnit `yaml:"unit"`
+	// PointsToMinutes overrides the built-in points conversion table
+	PointsToMinutes map[int]int `yaml:"points_to_minutes,omitempty"`
+}
+
+// FilterConfig returns the default filter configuration with the
+// configured estimate unit
+func (c EstimatesConfig) FilterConfig() filters.FilterConfig {
+	config := filters.DefaultFilterConfig
+	if c.Unit != "" {
+		config.EstimateUnit = c.Unit
+	}
+	config.PointsToMinutes = c.PointsToMinutes
+	return config
+}
+
+type ListsConfig struct {
+	// AutoArchiveDays archives lists with no task activity for this many
+	// days while the server runs. Zero disables auto-archiving.
+	AutoArchiveDays int `yaml:"auto_archive_days"`
+	// CompletionUndoSeconds is how long whoever completes a task in a
+	// shared list can take it back. Zero uses the default of two minutes.
+	CompletionUndoSeconds int `yaml:"completion_undo_seconds"`
+}
+
+type RecurrenceConfig struct {
+	// From is "due" (default) to schedule the next instance of a completed
+	// recurring task from its due date, or "completion" to schedule it from
+	// when it was completed
+	From string `yaml:"from"`
+}
+
+type WeatherConfig struct {
+	// DisableFilter stops the weather filter hiding outdoor tasks
+	DisableFilter bool `yaml:"disable_filter"`
+	// HideConditions is the weather that hides outdoor tasks. Empty uses
+	// rainy, snowy and stormy.
+	HideConditions []string `yaml:"hide_conditions,omitempty"`
+}
+
+type EnergyConfig struct {
+	// DisableFilter stops the energy filter hiding tasks that need more
+	// energy than you reported
+	DisableFilter bool `yaml:"disable_filter"`
+	// Tolerance is how many levels above your energy a task may need and
+	// still be shown, ranked lower
+	Tolerance int `yaml:"tolerance"`
+}
+
+type TravelConfig struct {
+	// Mode is "walking" or "driving" to add the time it takes to reach a
+	// task's nearest location to its estimate. Empty leaves travel out.
+	Mode filters.TravelMode `yaml:"mode,omitempty"`
+	// WalkingSpeedKmh overrides the 5 km/h walking speed
+	WalkingSpeedKmh float64 `yaml:"walking_speed_kmh,omitempty"`
+	// DrivingSpeedKmh overrides the 40 km/h driving speed, which heavier
+	// traffic in your context slows down
+	DrivingSpeedKmh float64 `yaml:"driving_speed_kmh,omitempty"`
+}
+
+type ContextConfig struct {
+	// MaxAgeMinutes is how old your latest context can get before its
+	// location and available time stop hiding tasks. Zero uses two hours;
+	// a negative value trusts contexts however old they are.
+	MaxAgeMinutes int `yaml:"max_age_minutes"`
+	// CategoryPreference is added to the score of tasks at a location of
+	// the same category as where you are (a gym task while at a gym), or
+	// as your social context suggests: work at work, home with family.
+	// Zero turns it off; 0.2 is a gentle nudge.
+	CategoryPreference float64 `yaml:"category_preference"`
+}
+
+// MaxAge returns the configured context max age
+func (c ContextConfig) MaxAge() time.Duration {
+	switch {
+	case c.MaxAgeMinutes == 0:
+		return models.DefaultContextMaxAge
+	case c.MaxAgeMinutes < 0:
+		return 0
+	}
+	return time.Duration(c.MaxAgeMinutes) * time.Minute
+}
+
+// FilterConfig returns the filter configuration the app runs with: the
+// default configuration with the configured estimate unit, the weather
+// and energy filters on unless disabled, the configured context max age,
+// category preference and travel settings
+func (c Config) FilterConfig() filters.FilterConfig {
+	config := c.Estimates.FilterConfig()
+	config.EnableWeatherFilter = !c.Weather.DisableFilter
+	config.WeatherHideConditions = c.Weather.HideConditions
+	config.EnableEnergyFilter = !c.Energy.DisableFilter
+	config.EnergyTolerance = c.Energy.Tolerance
+	config.ContextMaxAge = c.Context.MaxAge()
+	config.CategoryPreference = c.Context.CategoryPreference
+	config.TravelMode = c.Travel.Mode
+	config.WalkingSpeedKmh = c.Travel.WalkingSpeedKmh
+	config.DrivingSpeedKmh = c.Travel.DrivingSpeedKmh
+	return config
+}
+
+type NotificationsConfig struct {
+	// DisableDelivery stops the server pushing notifications to the
+	// webhook and ntfy channels users set up with 'user notify set'
+	DisableDelivery bool `yaml:"disable_delivery"`
+	// PollSeconds is how often the server looks for new notifications to
+	// push. Zero uses 15 seconds.
+	PollSeconds int `yaml:"poll_seconds"`
+	// NtfyServer is the ntfy server for users who haven't named their own.
+	// Empty uses https://ntfy.sh.
+	NtfyServer string `yaml:"ntfy_server,omitempty"`
+	// SMTP is the mail server notifications are emailed through to users
+	// who turned email on. Without a host no email is sent.
+	SMTP notify.SMTPConfig `yaml:"smtp"`
+}
+
+// Dispatcher returns a dispatcher pushing notifications in db to users'
+// webhook and ntfy channels, and emailing them when SMTP is configured
+func (c NotificationsConfig) Dispatcher(db *storage.DB) *notify.Dispatcher {
+	settings := storage.NewNotificationSettingsRepository(db)
+	options := notify.DefaultOptions
+	if c.PollSeconds > 0 {
+		options.PollInterval = time.Duration(c.PollSeconds) * time.Second
+	}
+	notifiers := []notify.Notifier{
+		notify.NewWebhookNotifier(settings, nil),
+		notify.NewNtfyNotifier(settings, nil, c.NtfyServer),
+	}
+	if c.SMTP.Enabled() {
+		notifiers = append(notifiers, notify.NewSMTPNotifier(settings, storage.NewUserRepository(db), c.SMTP))
+	}
+	return notify.NewDispatcher(storage.NewNotificationRepository(db), options, notifiers...)
+}
+
+type OutputConfig struct {
+	// HumanLimit caps how many tasks human output lists without --limit.
+	// Unset uses 25; zero lists every task.
+	HumanLimit *int `yaml:"human_limit,omitempty"`
+}
+
+type CalendarConfig struct {
+	// SyncConcurrency is how many calendars sync at once. Zero uses the
+	// library default.
+	SyncConcurrency int `yaml:"sync_concurrency"`
+	// RequestsPerMinute caps requests to any one provider. Zero leaves
+	// requests unspaced; 429 responses are still retried after Retry-After.
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	// SyncPastDays and SyncFutureDays bound the events a sync fetches.
+	// Zero uses the library default.
+	SyncPastDays   int `yaml:"sync_past_days"`
+	SyncFutureDays int `yaml:"sync_future_days"`
+	// CalDAV lists the CalDAV accounts added with 'calendar add caldav'
+	CalDAV []CalDAVAccount `yaml:"caldav,omitempty"`
+	// Google lists the Google accounts added with 'calendar add google'
+	Google []GoogleAccount `yaml:"google,omitempty"`
+}
+
+// CalDAVAccount is one CalDAV server login. Password may be an app password.
+type CalDAVAccount struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// GoogleAccount is one Google Calendar reached through an OAuth2 client and
+// a refresh token granted to it
+type GoogleAccount struct {
+	Name         string `yaml:"name"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RefreshToken string `yaml:"refresh_token"`
+	CalendarID   string `yaml:"calendar_id,omitempty"`
+}
+
+// HasAccount reports whether a CalDAV or Google account has the name
+func (c CalendarConfig) HasAccount(name string) bool {
+	if c.CalDAVAccount(name) != nil {
+		return true
+	}
+	for _, account := range c.Google {
+		if account.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CalDAVAccount returns the account with the name, or nil
+func (c CalendarConfig) CalDAVAccount(name string) *CalDAVAccount {
+	for i := range c.CalDAV {
+		if c.CalDAV[i].Name == name {
+			return &c.CalDAV[i]
+		}
+	}
+	return nil
+}
+
+// SyncOptions returns the calendar sync options for this configuration
+func (c CalendarConfig) SyncOptions() sync.SyncOptions {
+	options := sync.DefaultSyncOptions
+	if c.SyncConcurrency > 0 {
+		options.Concurrency = c.SyncConcurrency
+	}
+	if c.RequestsPerMinute > 0 {
+		options.ProviderInterval = time.Minute / time.Duration(c.RequestsPerMinute)
+	}
+	if c.SyncPastDays > 0 {
+		options.PastWindow = time.Duration(c.SyncPastDays) * 24 * time.Hour
+	}
+	if c.SyncFutureDays > 0 {
+		options.FutureWindow = time.Duration(c.SyncFutureDays) * 24 * time.Hour
+	}
+	return options
 }
 
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	BasePath string `yaml:"base_path,omitempty"`
+	// ContextHeaders lets clients update their context with X-Context-Lat,
+	// X-Context-Lng and X-Context-Energy headers on any API request
+	ContextHeaders bool `yaml:"context_headers"`
 }
 
 type DatabaseConfig struct {
 	Path string `yaml:"path"`
+	// Encryption is "none" (the default) or "sqlcipher", which needs a
+	// build with the sqlcipher tag and a key from HEREANDNOW_DB_KEY or
+	// --db-key. The key is never stored in the config file.
+	Encryption string `yaml:"encryption"`
+}
+
+// databaseKeyEnv holds the database encryption key
+const databaseKeyEnv = "HEREANDNOW_DB_KEY"
+
+// databaseKey returns the key to open the database with, or "" when it is
+// not encrypted
+func databaseKey(config *Config) (string, error) {
+	if config.Database.Encryption != "sqlcipher" {
+		return "", nil
+	}
+
+	key := globalConfig.DBKey
+	if key == "" {
+		key = os.Getenv(databaseKeyEnv)
+	}
+	if key == "" {
+		return "", fmt.Errorf("database is encrypted: set %s or pass --db-key", databaseKeyEnv)
+	}
+	return key, nil
 }
 
+// totpKeyEnv holds the key TOTP secrets are encrypted with. The server
+// offers two-factor authentication only when it is set.
+const totpKeyEnv = "HEREANDNOW_TOTP_KEY"
+
 type LoggingConfig struct {
 	Level string `yaml:"level"`
 	Path  string `yaml:"path"`
@@ -35,6 +325,9 @@ type FeaturesConfig struct {
 	NaturalLanguage    bool `yaml:"natural_language"`
 	CalendarSync       bool `yaml:"calendar_sync"`
 	WeatherIntegration bool `yaml:"weather_integration"`
+	EnergyFromHistory  bool `yaml:"energy_from_history"`
+	// CompletionStats shows today's completions and the streak in context show
+	CompletionStats bool `yaml:"completion_stats"`
 }
 
 func getConfigPath() string {
@@ -72,6 +365,13 @@ func LoadConfig() (*Config, error) {
 	config.Database.Path = expandPath(config.Database.Path)
 	config.Logging.Path = expandPath(config.Logging.Path)
 
+	// Configs written before location defaults existed get the built-in ones
+	if config.Locations.Radius == 0 && config.Locations.Categories == nil {
+		config.Locations = models.DefaultLocationDefaults()
+	}
+
+	config.Snooze.Presets = mergeSnoozePresets(config.Snooze.Presets)
+
 	return &config, nil
 }
 
@@ -120,10 +420,28 @@ func GetDefaultConfig() *Config {
 			NaturalLanguage:    true,
 			CalendarSync:       false,
 			WeatherIntegration: false,
+			EnergyFromHistory:  false,
+			CompletionStats:    true,
+		},
+		Locations: models.DefaultLocationDefaults(),
+		Snooze: SnoozeConfig{
+			Presets: models.DefaultSnoozePresets(),
+		},
+		Estimates: EstimatesConfig{
+			Unit: filters.EstimateUnitMinutes,
 		},
 	}
 }
 
+// mergeSnoozePresets overlays configured presets on the built-in ones
+func mergeSnoozePresets(configured models.SnoozePresets) models.SnoozePresets {
+	presets := models.DefaultSnoozePresets()
+	for name, preset := range configured {
+		presets[strings.ToLower(name)] = preset
+	}
+	return presets
+}
+
 func expandPath(path string) string {
 	if path == "" {
 		return path
@@ -160,8 +478,17 @@ func InitDatabase(dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	key, err := databaseKey(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Open database connection
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_foreign_keys=ON")
+	db, err := storage.OpenSQLite(dbPath, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -193,6 +520,8 @@ func createTables(db *sql.DB) error {
 		is_admin BOOLEAN DEFAULT 0,
 		timezone TEXT DEFAULT 'UTC',
 		preferences TEXT,
+		totp_secret TEXT,
+		totp_enabled BOOLEAN NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -208,13 +537,19 @@ func createTables(db *sql.DB) error {
 		status TEXT NOT NULL DEFAULT 'pending',
 		priority INTEGER DEFAULT 3,
 		estimated_minutes INTEGER,
+		effort_points INTEGER CHECK (effort_points > 0),
+		required_energy_level INTEGER CHECK (required_energy_level BETWEEN 1 AND 5),
 		due_at DATETIME,
 		completed_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		metadata TEXT,
 		recurrence_rule TEXT,
-		parent_task_id TEXT REFERENCES tasks(id)
+		parent_task_id TEXT REFERENCES tasks(id),
+		position REAL NOT NULL DEFAULT 0,
+		snoozed_until DATETIME,
+		recurring_snooze TEXT,
+		deleted_at DATETIME
 	);
 
 	-- Task Lists table
@@ -225,7 +560,10 @@ func createTables(db *sql.DB) error {
 		owner_id TEXT NOT NULL REFERENCES users(id),
 		is_shared BOOLEAN DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		archived_at DATETIME,
+		default_location_id TEXT REFERENCES locations(id) ON DELETE SET NULL,
+		default_estimated_minutes INTEGER CHECK (default_estimated_minutes > 0)
 	);
 
 	-- Locations table
@@ -235,6 +573,7 @@ func createTables(db *sql.DB) error {
 		latitude REAL NOT NULL,
 		longitude REAL NOT NULL,
 		radius INTEGER NOT NULL DEFAULT 100,
+		category TEXT NOT NULL DEFAULT 'other',
 		user_id TEXT NOT NULL REFERENCES users(id),
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -254,6 +593,7 @@ func createTables(db *sql.DB) error {
 		id TEXT PRIMARY KEY,
 		task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
 		location_id TEXT NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+		trigger_type TEXT NOT NULL DEFAULT 'enter',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -270,23 +610,25 @@ func createTables(db *sql.DB) error {
 		energy_level INTEGER DEFAULT 3,
 		weather_condition TEXT,
 		traffic_level TEXT,
+		min_priority INTEGER NOT NULL DEFAULT 0,
 		metadata TEXT
 	);
 
 	-- Calendar Events table
 	CREATE TABLE IF NOT EXISTS calendar_events (
 		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL REFERENCES users(id),
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		provider_id TEXT NOT NULL,
+		external_id TEXT NOT NULL,
 		title TEXT NOT NULL,
-		description TEXT,
 		start_at DATETIME NOT NULL,
 		end_at DATETIME NOT NULL,
 		location TEXT,
-		all_day BOOLEAN DEFAULT 0,
-		calendar_source TEXT,
-		external_id TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		is_all_day BOOLEAN NOT NULL DEFAULT 0,
+		is_busy BOOLEAN NOT NULL DEFAULT 1,
+		metadata TEXT DEFAULT '{}',
+		last_synced_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, provider_id, external_id)
 	);
 
 	-- List Members table
@@ -295,7 +637,9 @@ func createTables(db *sql.DB) error {
 		list_id TEXT NOT NULL REFERENCES task_lists(id) ON DELETE CASCADE,
 		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 		role TEXT NOT NULL DEFAULT 'viewer',
-		joined_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		invited_by TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		invited_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		accepted_at DATETIME
 	);
 
 	-- Task Assignments table
@@ -309,6 +653,118 @@ func createTables(db *sql.DB) error {
 		status TEXT DEFAULT 'pending'
 	);
 
+	-- Notifications table
+	CREATE TABLE IF NOT EXISTS notifications (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type TEXT NOT NULL,
+		task_id TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+		message TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		read_at DATETIME
+	);
+
+	-- Task Visibility table
+	CREATE TABLE IF NOT EXISTS task_visibility (
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		visible BOOLEAN NOT NULL,
+		changed_at DATETIME NOT NULL,
+		notified_at DATETIME,
+		PRIMARY KEY (user_id, task_id)
+	);
+
+	-- Context Presets table
+	CREATE TABLE IF NOT EXISTS context_presets (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		available_minutes INTEGER NOT NULL DEFAULT 0,
+		energy_level INTEGER NOT NULL DEFAULT 0,
+		social_context TEXT NOT NULL DEFAULT 'alone',
+		location_id TEXT REFERENCES locations(id) ON DELETE SET NULL,
+		latitude REAL,
+		longitude REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, name)
+	);
+
+	-- Task Actions table (undo log)
+	CREATE TABLE IF NOT EXISTS task_actions (
+		id TEXT NOT NULL UNIQUE,
+		user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		task_id TEXT NOT NULL,
+		action_type TEXT NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Task Links table
+	CREATE TABLE IF NOT EXISTS task_links (
+		id TEXT PRIMARY KEY,
+		task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		linked_task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		link_type TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (task_id, linked_task_id)
+	);
+
+	-- Completion Undos table
+	CREATE TABLE IF NOT EXISTS completion_undos (
+		task_id TEXT PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		snapshot TEXT NOT NULL,
+		notification_ids TEXT NOT NULL DEFAULT '[]',
+		completed_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		spawned_task_id TEXT
+	);
+
+	-- Calendar Sync Cursors table
+	CREATE TABLE IF NOT EXISTS calendar_sync_cursors (
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		provider TEXT NOT NULL,
+		token TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, provider)
+	);
+
+	-- Device Tokens table
+	CREATE TABLE IF NOT EXISTS device_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME
+	);
+
+	-- Feed Tokens table
+	CREATE TABLE IF NOT EXISTS feed_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME
+	);
+
+	-- Revoked Tokens table
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		expires_at DATETIME NOT NULL
+	);
+
+	-- Password Reset Tokens table
+	CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME
+	);
+
 	-- Filter Audit table
 	CREATE TABLE IF NOT EXISTS filter_audit (
 		id TEXT PRIMARY KEY,
@@ -335,11 +791,20 @@ func createTables(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_tasks_creator_id ON tasks(creator_id);
 	CREATE INDEX IF NOT EXISTS idx_tasks_assignee_id ON tasks(assignee_id);
 	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+	CREATE INDEX IF NOT EXISTS idx_tasks_list_position ON tasks(list_id, position);
+	CREATE INDEX IF NOT EXISTS idx_tasks_snoozed_until ON tasks(snoozed_until) WHERE snoozed_until IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks(deleted_at) WHERE deleted_at IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, read_at, created_at);
 	CREATE INDEX IF NOT EXISTS idx_contexts_user_id ON contexts(user_id);
 	CREATE INDEX IF NOT EXISTS idx_contexts_timestamp ON contexts(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_device_tokens_user_id ON device_tokens(user_id);
+	CREATE INDEX IF NOT EXISTS idx_feed_tokens_user_id ON feed_tokens(user_id);
+	CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+	CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
 	CREATE INDEX IF NOT EXISTS idx_locations_user_id ON locations(user_id);
 	CREATE INDEX IF NOT EXISTS idx_calendar_events_user_id ON calendar_events(user_id);
 	CREATE INDEX IF NOT EXISTS idx_calendar_events_start_at ON calendar_events(start_at);
+	CREATE INDEX IF NOT EXISTS idx_calendar_events_provider ON calendar_events(provider_id, external_id);
 	CREATE INDEX IF NOT EXISTS idx_filter_audit_user_id ON filter_audit(user_id);
 	CREATE INDEX IF NOT EXISTS idx_filter_audit_task_id ON filter_audit(task_id);
 	CREATE INDEX IF NOT EXISTS idx_analytics_user_id ON analytics(user_id);
@@ -358,7 +823,7 @@ func createTables(db *sql.DB) error {
 		INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES('delete', old.rowid, old.title, old.description);
 	END;
 
-	CREATE TRIGGER IF NOT EXISTS tasks_fts_update AFTER UPDATE ON tasks BEGIN
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
 		INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES('delete', old.rowid, old.title, old.description);
 		INSERT INTO tasks_fts(rowid, title, description) VALUES (new.rowid, new.title, new.description);
 	END;
@@ -384,10 +849,125 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("database path cannot be empty")
 	}
 
+	switch config.Database.Encryption {
+	case "", "none":
+	case "sqlcipher":
+		if !storage.EncryptionAvailable {
+			return fmt.Errorf("database.encryption sqlcipher requires a build with the sqlcipher tag")
+		}
+	default:
+		return fmt.Errorf("invalid database encryption: %s (must be none or sqlcipher)", config.Database.Encryption)
+	}
+
 	if config.Logging.Level != "debug" && config.Logging.Level != "info" && 
 	   config.Logging.Level != "warn" && config.Logging.Level != "error" {
 		return fmt.Errorf("invalid logging level: %s", config.Logging.Level)
 	}
 
+	if err := config.Locations.Validate(); err != nil {
+		return fmt.Errorf("invalid location defaults: %w", err)
+	}
+
+	if err := config.Snooze.Presets.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := locale.Parse(config.Locale); err != nil {
+		return err
+	}
+
+	if !filters.IsValidEstimateUnit(config.Estimates.Unit) {
+		return fmt.Errorf("invalid estimate unit: %s (must be minutes or points)", config.Estimates.Unit)
+	}
+
+	for points, minutes := range config.Estimates.PointsToMinutes {
+		if points <= 0 || minutes <= 0 {
+			return fmt.Errorf("invalid points conversion %d -> %d: points and minutes must be positive", points, minutes)
+		}
+	}
+
+	if config.Lists.AutoArchiveDays < 0 {
+		return fmt.Errorf("invalid lists.auto_archive_days: %d (must be zero or positive)", config.Lists.AutoArchiveDays)
+	}
+
+	if config.Lists.CompletionUndoSeconds < 0 {
+		return fmt.Errorf("invalid lists.completion_undo_seconds: %d (must be zero or positive)", config.Lists.CompletionUndoSeconds)
+	}
+
+	for _, condition := range config.Weather.HideConditions {
+		if !models.IsValidWeatherCondition(condition) {
+			return fmt.Errorf("invalid weather.hide_conditions entry: %s", condition)
+		}
+	}
+
+	if _, err := hereandnow.ParseRecurrenceBasis(config.Recurrence.From); err != nil {
+		return fmt.Errorf("invalid recurrence.from: %s (must be due or completion)", config.Recurrence.From)
+	}
+
+	if err := config.Maintenance.Validate(); err != nil {
+		return err
+	}
+
+	if config.Calendar.SyncConcurrency < 0 {
+		return fmt.Errorf("invalid calendar.sync_concurrency: %d (must be zero or positive)", config.Calendar.SyncConcurrency)
+	}
+
+	if config.Calendar.RequestsPerMinute < 0 {
+		return fmt.Errorf("invalid calendar.requests_per_minute: %d (must be zero or positive)", config.Calendar.RequestsPerMinute)
+	}
+
+	if config.Calendar.SyncPastDays < 0 {
+		return fmt.Errorf("invalid calendar.sync_past_days: %d (must be zero or positive)", config.Calendar.SyncPastDays)
+	}
+
+	if config.Calendar.SyncFutureDays < 0 {
+		return fmt.Errorf("invalid calendar.sync_future_days: %d (must be zero or positive)", config.Calendar.SyncFutureDays)
+	}
+
+	for _, account := range config.Calendar.CalDAV {
+		if account.Name == "" || account.URL == "" {
+			return fmt.Errorf("invalid calendar.caldav entry: name and url are required")
+		}
+	}
+
+	if config.Energy.Tolerance < 0 || config.Energy.Tolerance > 4 {
+		return fmt.Errorf("invalid energy.tolerance: %d (must be 0-4)", config.Energy.Tolerance)
+	}
+
+	if config.Context.CategoryPreference < 0 {
+		return fmt.Errorf("invalid context.category_preference: %g (must be zero or positive)", config.Context.CategoryPreference)
+	}
+
+	if !filters.IsValidTravelMode(config.Travel.Mode) {
+		return fmt.Errorf("invalid travel.mode: %s (must be walking or driving)", config.Travel.Mode)
+	}
+
+	if config.Travel.WalkingSpeedKmh < 0 || config.Travel.DrivingSpeedKmh < 0 {
+		return fmt.Errorf("invalid travel speed: speeds must be zero or positive")
+	}
+
+	if config.Notifications.PollSeconds < 0 {
+		return fmt.Errorf("invalid notifications.poll_seconds: %d (must be zero or positive)", config.Notifications.PollSeconds)
+	}
+
+	if server := config.Notifications.NtfyServer; server != "" {
+		if parsed, err := url.Parse(server); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("invalid notifications.ntfy_server: %s (must be an http or https URL)", server)
+		}
+	}
+
+	if smtp := config.Notifications.SMTP; smtp.Enabled() {
+		if smtp.From == "" {
+			return fmt.Errorf("invalid notifications.smtp: from is required")
+		}
+		if smtp.Port < 0 || smtp.Port > 65535 {
+			return fmt.Errorf("invalid notifications.smtp.port: %d", smtp.Port)
+		}
+	}
+
+	if config.Output.HumanLimit != nil && *config.Output.HumanLimit < 0 {
+		return fmt.Errorf("invalid output.human_limit: %d (must be zero or positive)", *config.Output.HumanLimit)
+	}
+
 	return nil
 }
\ No newline at end of file
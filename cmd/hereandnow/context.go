@@ -24,10 +24,13 @@ USAGE:
     hereandnow context <SUBCOMMAND> [OPTIONS]
 
 SUBCOMMANDS:
-    show                Show current context
+    show                Show current context, plus tasks completed today and
+                        your streak of days with a completion when
+                        features.completion_stats is enabled
     update              Update current context
     suggestions         Get context-based suggestions
     estimate <location> Estimate time to location
+    preset <ACTION>     Save and apply named contexts (save|apply|list|delete)
 
 DESCRIPTION:
     Context represents your current situation including location, available time,
@@ -39,8 +42,14 @@ UPDATE OPTIONS:
     --lng <longitude>       GPS longitude coordinate
     --location <name>       Set location by name (must exist)
     --available-minutes <n> Available time in minutes
-    --energy <1-5>          Energy level (1=exhausted, 5=maximum)
+    --available-points <n>  Available time in effort points, converted to
+                            minutes with the estimates.points_to_minutes table
+    --energy <1-5>          Energy level (1=exhausted, 5=maximum). When omitted,
+                            defaults to your average energy at this hour if
+                            features.energy_from_history is enabled, else 3
     --social <context>      Social context (alone|family|work|friends)
+    --min-priority <0-5>    Hide tasks below this priority until changed
+                            (0 shows all). Kept across context updates
     --help, -h              Show this help
 
 EXAMPLES:
@@ -56,15 +65,39 @@ EXAMPLES:
     # Update available time and energy
     hereandnow context update --available-minutes 45 --energy 3
 
+    # Update available time in story points
+    hereandnow context update --available-points 3
+
     # Update social context
     hereandnow context update --social family
 
+    # Only show priority 4 and 5 tasks for now
+    hereandnow context update --min-priority 4
+
     # Get context-based suggestions
     hereandnow context suggestions
 
     # Estimate travel time to a location
     hereandnow context estimate "Grocery Store"
 
+PRESET ACTIONS:
+    preset save <name> --from-current
+                            Save your current context under a name
+    preset save <name> [--location <name>] [--available-minutes <n>]
+                       [--energy <1-5>] [--social <context>]
+                            Save a preset from the given values
+    preset apply <name>     Start a new context from a preset
+    preset list             List your presets
+    preset delete <name>    Delete a preset
+
+    A preset saved at a named location stores a reference to it, so applying
+    the preset uses the location's coordinates at that time.
+
+    # Save the way you usually start a work day, then reuse it
+    hereandnow context preset save work-morning --location "Office" \
+        --available-minutes 120 --energy 4 --social alone
+    hereandnow context preset apply work-morning
+
 SOCIAL CONTEXT VALUES:
     alone    - Working alone, full focus available
     family   - With family, limited work time
@@ -74,20 +107,8 @@ SOCIAL CONTEXT VALUES:
 		return
 	}
 
-	subcommand := args[0]
-	subArgs := args[1:]
-
-	switch subcommand {
-	case "show":
-		executeContextShow(subArgs)
-	case "update":
-		executeContextUpdate(subArgs)
-	case "suggestions":
-		executeContextSuggestions(subArgs)
-	case "estimate":
-		executeContextEstimate(subArgs)
-	default:
-		fmt.Printf("Unknown context subcommand: %s\n", subcommand)
+	if !runSubcommand(contextCommands, args) {
+		fmt.Printf("Unknown context subcommand: %s\n", args[0])
 		fmt.Println("Run 'hereandnow context --help' for usage")
 		os.Exit(1)
 	}
@@ -114,15 +135,54 @@ func executeContextShow(args []string) {
 	}
 
 	formatter := NewFormatter(globalConfig.Format)
+
+	stats := loadCompletionStats(userID)
+	if stats == nil {
+		Output(formatter, *context)
+		return
+	}
+
+	// JSON stays a single document with the stats alongside the context fields
+	if isJSONFormat(globalConfig.Format) {
+		Output(formatter, struct {
+			models.Context
+			Stats models.CompletionStats `json:"stats"`
+		}{*context, *stats})
+		return
+	}
+
 	Output(formatter, *context)
+	Output(formatter, *stats)
+}
+
+// loadCompletionStats returns the user's completion stats, or nil when they
+// are disabled in the config or cannot be computed
+func loadCompletionStats(userID string) *models.CompletionStats {
+	config, err := LoadConfig()
+	if err != nil || !config.Features.CompletionStats {
+		return nil
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		return nil
+	}
+
+	stats, err := taskService.GetCompletionStats(userID)
+	if err != nil {
+		return nil
+	}
+	return stats
 }
 
 func executeContextUpdate(args []string) {
 	var lat, lng *float64
 	locationName := ""
 	availableMinutes := 0
+	availablePoints := 0
 	energyLevel := 0
 	socialContext := ""
+	var minPriority *int
 
 	for i, arg := range args {
 		switch arg {
@@ -148,6 +208,12 @@ func executeContextUpdate(args []string) {
 					availableMinutes = m
 				}
 			}
+		case "--available-points":
+			if i+1 < len(args) {
+				if p, err := strconv.Atoi(args[i+1]); err == nil && p > 0 {
+					availablePoints = p
+				}
+			}
 		case "--energy":
 			if i+1 < len(args) {
 				if e, err := strconv.Atoi(args[i+1]); err == nil && e >= 1 && e <= 5 {
@@ -161,6 +227,15 @@ func executeContextUpdate(args []string) {
 					socialContext = social
 				}
 			}
+		case "--min-priority":
+			if i+1 < len(args) {
+				p, err := strconv.Atoi(args[i+1])
+				if err != nil || p < 0 || p > 5 {
+					fmt.Fprintf(os.Stderr, "Error: --min-priority must be between 0 and 5\n")
+					os.Exit(1)
+				}
+				minPriority = &p
+			}
 		}
 	}
 
@@ -179,6 +254,19 @@ func executeContextUpdate(args []string) {
 		}
 	}
 
+	if availablePoints > 0 {
+		if availableMinutes > 0 {
+			fmt.Fprintf(os.Stderr, "Error: Use either --available-minutes or --available-points, not both\n")
+			os.Exit(1)
+		}
+		config, err := LoadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		availableMinutes = config.FilterConfig().PointsAsMinutes(availablePoints)
+	}
+
 	// If both GPS and location name provided, prefer GPS
 	if lat != nil && lng != nil && locationName != "" {
 		fmt.Println("Note: Both GPS coordinates and location name provided. Using GPS coordinates.")
@@ -219,6 +307,7 @@ func executeContextUpdate(args []string) {
 		AvailableMinutes: availableMinutes,
 		SocialContext:    socialContext,
 		EnergyLevel:      energyLevel,
+		MinPriority:      minPriority,
 	}
 
 	context, err := contextService.UpdateUserContext(userID, req)
@@ -295,6 +384,137 @@ func executeContextEstimate(args []string) {
 	Output(formatter, *estimate)
 }
 
+func executeContextPreset(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: context preset requires an action (save|apply|list|delete)\n")
+		os.Exit(1)
+	}
+
+	action := args[0]
+	actionArgs := args[1:]
+
+	if action != "list" && len(actionArgs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: context preset %s requires a preset name\n", action)
+		fmt.Printf("Usage: hereandnow context preset %s <name>\n", action)
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	contextService, err := initContextService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing context service: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+
+	switch action {
+	case "save":
+		preset, err := buildContextPreset(contextService, userID, actionArgs[0], actionArgs[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving preset: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Preset '%s' saved", preset.Name))
+		if globalConfig.Verbose {
+			Output(formatter, *preset)
+		}
+	case "apply":
+		context, err := contextService.ApplyContextPreset(userID, actionArgs[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying preset: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Context updated from preset '%s'", actionArgs[0]))
+		if globalConfig.Verbose {
+			Output(formatter, *context)
+		}
+	case "list":
+		presets, err := contextService.GetContextPresets(userID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing presets: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, presets)
+	case "delete":
+		if err := contextService.DeleteContextPreset(userID, actionArgs[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting preset: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Preset '%s' deleted", actionArgs[0]))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown preset action: %s\n", action)
+		fmt.Println("Run 'hereandnow context --help' for usage")
+		os.Exit(1)
+	}
+}
+
+// buildContextPreset saves a preset from the current context with
+// --from-current, or from the given flags otherwise
+func buildContextPreset(contextService *hereandnow.ContextService, userID, name string, args []string) (*models.ContextPreset, error) {
+	for _, arg := range args {
+		if arg == "--from-current" {
+			return contextService.SavePresetFromCurrent(userID, name)
+		}
+	}
+
+	preset, err := models.NewContextPreset(userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, arg := range args {
+		if i+1 >= len(args) {
+			break
+		}
+		value := args[i+1]
+		switch arg {
+		case "--location":
+			location, err := findLocationByNameForUser(value, userID)
+			if err != nil {
+				return nil, fmt.Errorf("location '%s' not found", value)
+			}
+			preset.LocationID = &location.ID
+		case "--available-minutes":
+			minutes, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid available minutes: %s", value)
+			}
+			preset.AvailableMinutes = minutes
+		case "--energy":
+			energy, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid energy level: %s", value)
+			}
+			preset.EnergyLevel = energy
+		case "--social":
+			preset.SocialContext = presetSocialContext(value)
+		}
+	}
+
+	return contextService.SaveContextPreset(*preset)
+}
+
+// presetSocialContext maps the CLI's short social context names to the
+// stored values
+func presetSocialContext(social string) string {
+	switch social {
+	case "family":
+		return models.SocialContextWithFamily
+	case "work":
+		return models.SocialContextAtWork
+	case "friends", "public":
+		return models.SocialContextInPublic
+	default:
+		return social
+	}
+}
+
 // Helper function to initialize context service
 func initContextService() (*hereandnow.ContextService, error) {
 	config, err := LoadConfig()
@@ -309,8 +529,22 @@ func initContextService() (*hereandnow.ContextService, error) {
 
 	contextRepo := storage.NewContextRepository(db)
 	locationRepo := storage.NewLocationRepository(db)
-	// Calendar repository would be needed for full functionality
-	// For now, we'll pass nil for optional services
-
-	return hereandnow.NewContextService(contextRepo, locationRepo, nil, nil, nil), nil
+	// Synced calendar events shorten the available time; weather and
+	// traffic services are optional
+	calendarRepo := storage.NewCalendarEventRepository(db)
+
+	contextService := hereandnow.NewContextService(contextRepo, locationRepo, calendarRepo, nil, nil)
+	contextService.SetContextMaxAge(config.Context.MaxAge())
+	if config.Features.EnergyFromHistory {
+		contextService.EnableEnergyPrediction(contextRepo)
+	}
+	contextService.EnableLocationReminders(
+		storage.NewTaskLocationRepository(db),
+		storage.NewTaskRepository(db),
+		storage.NewNotificationRepository(db),
+	)
+	contextService.EnableGeofenceEvents(storage.NewGeofenceEventRepository(db))
+	contextService.EnableContextPresets(storage.NewContextPresetRepository(db))
+
+	return contextService, nil
 }
\ No newline at end of file
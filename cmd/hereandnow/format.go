@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"sort"
@@ -11,7 +12,12 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/locale"
 	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/ndjson"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/bcnelson/hereAndNow/pkg/truncate"
 )
 
 const (
@@ -35,6 +41,8 @@ type Formatter interface {
 	FormatLocations(locations []models.Location) string
 	FormatLocation(location models.Location) string
 	FormatContext(context models.Context) string
+	FormatCompletionStats(stats models.CompletionStats) string
+	FormatImportReport(report sync.ImportReport) string
 	FormatAnalytics(analytics map[string]interface{}) string
 	FormatError(err error) string
 	FormatSuccess(message string) string
@@ -42,17 +50,68 @@ type Formatter interface {
 	FormatInfo(message string) string
 }
 
+// StreamFormatter writes task lists straight to the output one task at a
+// time instead of building the whole document first
+type StreamFormatter interface {
+	StreamTasks(w io.Writer, tasks []models.Task) error
+}
+
 func NewFormatter(format string) Formatter {
 	switch format {
 	case "json":
 		return &JSONFormatter{}
+	case "ndjson":
+		return &NDJSONFormatter{}
 	case "table":
 		return &TableFormatter{}
-	case "human":
-		return &HumanFormatter{}
 	default:
-		return &HumanFormatter{}
+		return &HumanFormatter{Locale: currentLocale(), Limit: outputLimit("human")}
+	}
+}
+
+// outputLimit returns how many tasks to list in format, from --limit or,
+// for human output, the configured default
+func outputLimit(format string) int {
+	humanDefault := truncate.DefaultHumanLimit
+	if format == "human" && globalConfig.Limit == nil {
+		if config, err := LoadConfig(); err == nil && config.Output.HumanLimit != nil {
+			humanDefault = *config.Output.HumanLimit
+		}
 	}
+	return truncate.ForFormat(format, globalConfig.Limit, humanDefault)
+}
+
+// isJSONFormat reports whether format is machine-readable JSON, so commands
+// should output a single structured document rather than prose
+func isJSONFormat(format string) bool {
+	return format == "json" || format == "ndjson"
+}
+
+// currentLocale returns the output locale from --locale or, failing that,
+// the config file
+func currentLocale() *locale.Locale {
+	name := globalConfig.Locale
+	if name == "" {
+		if config, err := LoadConfig(); err == nil {
+			name = config.Locale
+		}
+	}
+
+	loc, err := locale.Parse(name)
+	if err != nil {
+		return locale.Default()
+	}
+	return loc
+}
+
+// NewSearchFormatter returns a formatter that highlights matches of query in
+// task titles and descriptions. Only the human formatter highlights.
+func NewSearchFormatter(format, query string) Formatter {
+	formatter := NewFormatter(format)
+	if human, ok := formatter.(*HumanFormatter); ok {
+		human.Query = query
+	}
+	return formatter
 }
 
 // JSON Formatter
@@ -93,6 +152,16 @@ func (f *JSONFormatter) FormatContext(context models.Context) string {
 	return string(data)
 }
 
+func (f *JSONFormatter) FormatCompletionStats(stats models.CompletionStats) string {
+	data, _ := json.MarshalIndent(stats, "", "  ")
+	return string(data)
+}
+
+func (f *JSONFormatter) FormatImportReport(report sync.ImportReport) string {
+	data, _ := json.MarshalIndent(report, "", "  ")
+	return string(data)
+}
+
 func (f *JSONFormatter) FormatAnalytics(analytics map[string]interface{}) string {
 	data, _ := json.MarshalIndent(analytics, "", "  ")
 	return string(data)
@@ -134,6 +203,82 @@ func (f *JSONFormatter) FormatInfo(message string) string {
 	return string(data)
 }
 
+// NDJSON Formatter writes one compact JSON object per line. Lists become one
+// line per element, so an empty list writes nothing.
+type NDJSONFormatter struct{}
+
+func ndjsonLines[T any](values []T) string {
+	var sb strings.Builder
+	ndjson.Write(&sb, values)
+	return sb.String()
+}
+
+func ndjsonLine(value interface{}) string {
+	return ndjsonLines([]interface{}{value})
+}
+
+func (f *NDJSONFormatter) StreamTasks(w io.Writer, tasks []models.Task) error {
+	return ndjson.Write(w, tasks)
+}
+
+func (f *NDJSONFormatter) FormatTasks(tasks []models.Task) string {
+	return ndjsonLines(tasks)
+}
+
+func (f *NDJSONFormatter) FormatTask(task models.Task) string {
+	return ndjsonLine(task)
+}
+
+func (f *NDJSONFormatter) FormatUsers(users []models.User) string {
+	return ndjsonLines(users)
+}
+
+func (f *NDJSONFormatter) FormatUser(user models.User) string {
+	return ndjsonLine(user)
+}
+
+func (f *NDJSONFormatter) FormatLocations(locations []models.Location) string {
+	return ndjsonLines(locations)
+}
+
+func (f *NDJSONFormatter) FormatLocation(location models.Location) string {
+	return ndjsonLine(location)
+}
+
+func (f *NDJSONFormatter) FormatContext(context models.Context) string {
+	return ndjsonLine(context)
+}
+
+func (f *NDJSONFormatter) FormatCompletionStats(stats models.CompletionStats) string {
+	return ndjsonLine(stats)
+}
+
+// FormatImportReport writes one line per record; the totals can be counted
+// from the records' statuses
+func (f *NDJSONFormatter) FormatImportReport(report sync.ImportReport) string {
+	return ndjsonLines(report.Records)
+}
+
+func (f *NDJSONFormatter) FormatAnalytics(analytics map[string]interface{}) string {
+	return ndjsonLine(analytics)
+}
+
+func (f *NDJSONFormatter) FormatError(err error) string {
+	return ndjsonLine(map[string]interface{}{"error": err.Error(), "type": "error"})
+}
+
+func (f *NDJSONFormatter) FormatSuccess(message string) string {
+	return ndjsonLine(map[string]interface{}{"message": message, "type": "success"})
+}
+
+func (f *NDJSONFormatter) FormatWarning(message string) string {
+	return ndjsonLine(map[string]interface{}{"message": message, "type": "warning"})
+}
+
+func (f *NDJSONFormatter) FormatInfo(message string) string {
+	return ndjsonLine(map[string]interface{}{"message": message, "type": "info"})
+}
+
 // Table Formatter
 type TableFormatter struct{}
 
@@ -145,26 +290,36 @@ func (f *TableFormatter) FormatTasks(tasks []models.Task) string {
 	var sb strings.Builder
 	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
 
-	fmt.Fprintf(w, "ID\tTitle\tStatus\tPriority\tEstimate\tDue\tLocation\n")
-	fmt.Fprintf(w, "--\t-----\t------\t--------\t--------\t---\t--------\n")
+	fmt.Fprintf(w, "ID\tTitle\tStatus\tPriority\tEnergy\tEstimate\tDue\tLocation\tTags\n")
+	fmt.Fprintf(w, "--\t-----\t------\t--------\t------\t--------\t---\t--------\t----\n")
 
 	for _, task := range tasks {
 		id := truncateString(task.ID, 8)
 		title := truncateString(task.Title, 30)
 		status := string(task.Status)
+		if task.IsSnoozed(time.Now()) {
+			status += " (snoozed)"
+		}
 		priority := strconv.Itoa(task.Priority)
+		energy := "Any"
+		if task.RequiredEnergyLevel != nil {
+			energy = strconv.Itoa(*task.RequiredEnergyLevel)
+		}
 		estimate := "N/A"
 		if task.EstimatedMinutes != nil {
 			estimate = fmt.Sprintf("%dm", *task.EstimatedMinutes)
+		} else if task.EffortPoints != nil {
+			estimate = fmt.Sprintf("%dpt", *task.EffortPoints)
 		}
 		due := "N/A"
 		if task.DueAt != nil {
 			due = task.DueAt.Format("2006-01-02")
 		}
 		location := "Any"
+		tags := truncateString(formatTags(task.Tags), 30)
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			id, title, status, priority, estimate, due, location)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			id, title, status, priority, energy, estimate, due, location, tags)
 	}
 
 	w.Flush()
@@ -182,14 +337,34 @@ func (f *TableFormatter) FormatTask(task models.Task) string {
 	fmt.Fprintf(w, "Description\t%s\n", task.Description)
 	fmt.Fprintf(w, "Status\t%s\n", task.Status)
 	fmt.Fprintf(w, "Priority\t%d\n", task.Priority)
+
+	if task.RequiredEnergyLevel != nil {
+		fmt.Fprintf(w, "Energy\t%d\n", *task.RequiredEnergyLevel)
+	}
 	
 	if task.EstimatedMinutes != nil {
-		fmt.Fprintf(w, "Estimate\t%d minutes\n", *task.EstimatedMinutes)
+		estimate := fmt.Sprintf("%d minutes", *task.EstimatedMinutes)
+		if hereandnow.TaskInheritedDefaults(task).EstimatedMinutes {
+			estimate += fromListNote
+		}
+		fmt.Fprintf(w, "Estimate\t%s\n", estimate)
+	}
+
+	if task.EffortPoints != nil {
+		fmt.Fprintf(w, "Effort\t%d points\n", *task.EffortPoints)
 	}
 	
 	if task.DueAt != nil {
 		fmt.Fprintf(w, "Due\t%s\n", task.DueAt.Format("2006-01-02 15:04"))
 	}
+
+	if task.IsSnoozed(time.Now()) {
+		fmt.Fprintf(w, "Snoozed until\t%s\n", task.SnoozedUntil.Format("2006-01-02 15:04"))
+	}
+
+	if len(task.Tags) > 0 {
+		fmt.Fprintf(w, "Tags\t%s\n", formatTags(task.Tags))
+	}
 	
 	fmt.Fprintf(w, "Created\t%s\n", task.CreatedAt.Format("2006-01-02 15:04"))
 
@@ -289,6 +464,8 @@ func (f *TableFormatter) FormatContext(context models.Context) string {
 	fmt.Fprintf(w, "Field\tValue\n")
 	fmt.Fprintf(w, "-----\t-----\n")
 	fmt.Fprintf(w, "Timestamp\t%s\n", context.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Age\t%s\n", formatContextAge(context.Age()))
+	fmt.Fprintf(w, "Stale\t%t\n", context.IsStale)
 	
 	if context.CurrentLatitude != nil && context.CurrentLongitude != nil {
 		fmt.Fprintf(w, "Location\t%.6f, %.6f\n", *context.CurrentLatitude, *context.CurrentLongitude)
@@ -306,10 +483,43 @@ func (f *TableFormatter) FormatContext(context models.Context) string {
 		fmt.Fprintf(w, "Traffic\t%s\n", *context.TrafficLevel)
 	}
 
+	if context.MinPriority > 0 {
+		fmt.Fprintf(w, "Min Priority\t%d\n", context.MinPriority)
+	}
+
 	w.Flush()
 	return sb.String()
 }
 
+func (f *TableFormatter) FormatCompletionStats(stats models.CompletionStats) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Stat\tValue\n")
+	fmt.Fprintf(w, "----\t-----\n")
+	fmt.Fprintf(w, "Completed Today\t%d\n", stats.CompletedToday)
+	fmt.Fprintf(w, "Current Streak\t%d days\n", stats.CurrentStreak)
+	fmt.Fprintf(w, "Longest Streak\t%d days\n", stats.LongestStreak)
+
+	w.Flush()
+	return sb.String()
+}
+
+func (f *TableFormatter) FormatImportReport(report sync.ImportReport) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Line\tStatus\tTitle\tReason\n")
+	fmt.Fprintf(w, "----\t------\t-----\t------\n")
+	for _, record := range report.Records {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", record.Line, record.Status, truncateString(record.Title, 30), record.Reason)
+	}
+	w.Flush()
+
+	fmt.Fprintf(&sb, "\nImported: %d  Skipped: %d  Failed: %d\n", report.Imported, report.Skipped, report.Failed)
+	return sb.String()
+}
+
 func (f *TableFormatter) FormatAnalytics(analytics map[string]interface{}) string {
 	var sb strings.Builder
 	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
@@ -349,7 +559,14 @@ func (f *TableFormatter) FormatInfo(message string) string {
 }
 
 // Human-Readable Formatter
-type HumanFormatter struct{}
+type HumanFormatter struct {
+	// Query highlights search matches in task summaries when set
+	Query string
+	// Locale formats dates and numbers; nil uses English
+	Locale *locale.Locale
+	// Limit caps how many tasks FormatTasks lists; zero lists all
+	Limit int
+}
 
 func (f *HumanFormatter) FormatTasks(tasks []models.Task) string {
 	if len(tasks) == 0 {
@@ -359,14 +576,22 @@ func (f *HumanFormatter) FormatTasks(tasks []models.Task) string {
 	var sb strings.Builder
 	sb.WriteString(f.colorize(ColorBold, fmt.Sprintf("Found %d task(s):\n\n", len(tasks))))
 
-	for i, task := range tasks {
+	shown, hidden := truncate.Slice(tasks, f.Limit)
+	for i, task := range shown {
 		sb.WriteString(f.formatTaskSummary(task, i+1))
 		sb.WriteString("\n")
 	}
 
+	if hidden > 0 {
+		sb.WriteString(f.colorize(ColorDim, truncate.Footer(hidden)+"\n"))
+	}
+
 	return sb.String()
 }
 
+// fromListNote marks a task constraint that came from its list's defaults
+const fromListNote = " (from list)"
+
 func (f *HumanFormatter) FormatTask(task models.Task) string {
 	var sb strings.Builder
 
@@ -392,26 +617,44 @@ func (f *HumanFormatter) FormatTask(task models.Task) string {
 	
 	sb.WriteString(fmt.Sprintf("\nStatus: %s\n", f.colorize(statusColor, string(task.Status))))
 	sb.WriteString(fmt.Sprintf("Priority: %s\n", f.priorityIndicator(task.Priority)))
+	if task.RequiredEnergyLevel != nil {
+		sb.WriteString(fmt.Sprintf("Energy needed: %s\n", f.energyIndicator(*task.RequiredEnergyLevel)))
+	}
 
 	// Time information
 	if task.EstimatedMinutes != nil {
-		sb.WriteString(fmt.Sprintf("Estimated time: %d minutes\n", *task.EstimatedMinutes))
+		sb.WriteString(f.locale().Sprintf("Estimated time: %d minutes", *task.EstimatedMinutes))
+		if hereandnow.TaskInheritedDefaults(task).EstimatedMinutes {
+			sb.WriteString(f.colorize(ColorDim, fromListNote))
+		}
+		sb.WriteString("\n")
+	}
+	if task.EffortPoints != nil {
+		sb.WriteString(f.locale().Sprintf("Effort: %d points\n", *task.EffortPoints))
 	}
 	
 	if task.DueAt != nil {
-		dueStr := task.DueAt.Format("Monday, January 2, 2006 at 3:04 PM")
+		dueStr := f.locale().Format(*task.DueAt, locale.LongDateTime)
 		if task.DueAt.Before(time.Now()) {
 			dueStr = f.colorize(ColorRed, dueStr+" (OVERDUE)")
 		}
 		sb.WriteString(fmt.Sprintf("Due: %s\n", dueStr))
 	}
 
+	if task.IsSnoozed(time.Now()) {
+		sb.WriteString(fmt.Sprintf("Snoozed until: %s\n", f.colorize(ColorDim, f.locale().Format(*task.SnoozedUntil, locale.LongDateTime))))
+	}
+
 	if task.CompletedAt != nil {
-		sb.WriteString(fmt.Sprintf("Completed: %s\n", task.CompletedAt.Format("Monday, January 2, 2006 at 3:04 PM")))
+		sb.WriteString(fmt.Sprintf("Completed: %s\n", f.locale().Format(*task.CompletedAt, locale.LongDateTime)))
+	}
+
+	if len(task.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("Tags: %s\n", f.colorize(ColorPurple, formatTags(task.Tags))))
 	}
 
-	sb.WriteString(fmt.Sprintf("\nCreated: %s\n", task.CreatedAt.Format("Monday, January 2, 2006 at 3:04 PM")))
-	sb.WriteString(fmt.Sprintf("Updated: %s\n", task.UpdatedAt.Format("Monday, January 2, 2006 at 3:04 PM")))
+	sb.WriteString(fmt.Sprintf("\nCreated: %s\n", f.locale().Format(task.CreatedAt, locale.LongDateTime)))
+	sb.WriteString(fmt.Sprintf("Updated: %s\n", f.locale().Format(task.UpdatedAt, locale.LongDateTime)))
 
 	return sb.String()
 }
@@ -448,7 +691,7 @@ func (f *HumanFormatter) FormatUser(user models.User) string {
 
 	sb.WriteString(fmt.Sprintf("Email: %s\n", user.Email))
 	sb.WriteString(fmt.Sprintf("Timezone: %s\n", user.Timezone))
-	sb.WriteString(fmt.Sprintf("Created: %s\n", user.CreatedAt.Format("Monday, January 2, 2006")))
+	sb.WriteString(fmt.Sprintf("Created: %s\n", f.locale().Format(user.CreatedAt, locale.LongDate)))
 
 	return sb.String()
 }
@@ -463,8 +706,9 @@ func (f *HumanFormatter) FormatLocations(locations []models.Location) string {
 
 	for i, location := range locations {
 		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, f.colorize(ColorBold, location.Name)))
-		sb.WriteString(fmt.Sprintf("   Coordinates: %.6f, %.6f\n", location.Latitude, location.Longitude))
-		sb.WriteString(fmt.Sprintf("   Radius: %d meters\n", location.Radius))
+		sb.WriteString(fmt.Sprintf("   Category: %s\n", formatLocationCategory(location)))
+		sb.WriteString(f.locale().Sprintf("   Coordinates: %.6f, %.6f\n", location.Latitude, location.Longitude))
+		sb.WriteString(f.locale().Sprintf("   Radius: %d meters\n", location.Radius))
 		sb.WriteString(fmt.Sprintf("   Created: %s\n\n", location.CreatedAt.Format("2006-01-02")))
 	}
 
@@ -475,26 +719,38 @@ func (f *HumanFormatter) FormatLocation(location models.Location) string {
 	var sb strings.Builder
 
 	sb.WriteString(f.colorize(ColorBold, fmt.Sprintf("Location: %s\n", location.Name)))
-	sb.WriteString(fmt.Sprintf("Coordinates: %.6f, %.6f\n", location.Latitude, location.Longitude))
-	sb.WriteString(fmt.Sprintf("Radius: %d meters\n", location.Radius))
-	sb.WriteString(fmt.Sprintf("Created: %s\n", location.CreatedAt.Format("Monday, January 2, 2006")))
+	sb.WriteString(fmt.Sprintf("Category: %s\n", formatLocationCategory(location)))
+	sb.WriteString(f.locale().Sprintf("Coordinates: %.6f, %.6f\n", location.Latitude, location.Longitude))
+	sb.WriteString(f.locale().Sprintf("Radius: %d meters\n", location.Radius))
+	sb.WriteString(fmt.Sprintf("Created: %s\n", f.locale().Format(location.CreatedAt, locale.LongDate)))
 
 	return sb.String()
 }
 
+// formatLocationCategory shows the location's category with its icon
+func formatLocationCategory(location models.Location) string {
+	category := locationCategory(location)
+	return fmt.Sprintf("%s %s", category.Icon(), category)
+}
+
 func (f *HumanFormatter) FormatContext(context models.Context) string {
 	var sb strings.Builder
 
 	sb.WriteString(f.colorize(ColorBold, "Current Context\n"))
-	sb.WriteString(fmt.Sprintf("Updated: %s\n\n", context.Timestamp.Format("Monday, January 2, 2006 at 3:04 PM")))
+	sb.WriteString(fmt.Sprintf("Updated: %s\n", f.locale().Format(context.Timestamp, locale.LongDateTime)))
+	if context.IsStale {
+		sb.WriteString(f.colorize(ColorYellow, fmt.Sprintf("⚠️  Stale: %s old - location and available time are ignored when filtering\n",
+			formatContextAge(context.Age()))))
+	}
+	sb.WriteString("\n")
 
 	if context.CurrentLatitude != nil && context.CurrentLongitude != nil {
-		sb.WriteString(fmt.Sprintf("📍 Location: %.6f, %.6f\n", *context.CurrentLatitude, *context.CurrentLongitude))
+		sb.WriteString(f.locale().Sprintf("📍 Location: %.6f, %.6f\n", *context.CurrentLatitude, *context.CurrentLongitude))
 	} else {
 		sb.WriteString("📍 Location: Unknown\n")
 	}
 
-	sb.WriteString(fmt.Sprintf("⏱️  Available time: %d minutes\n", context.AvailableMinutes))
+	sb.WriteString(f.locale().Sprintf("⏱️  Available time: %d minutes\n", context.AvailableMinutes))
 	sb.WriteString(fmt.Sprintf("👥 Social context: %s\n", context.SocialContext))
 	sb.WriteString(fmt.Sprintf("⚡ Energy level: %s\n", f.energyIndicator(context.EnergyLevel)))
 
@@ -506,6 +762,65 @@ func (f *HumanFormatter) FormatContext(context models.Context) string {
 		sb.WriteString(fmt.Sprintf("🚗 Traffic: %s\n", *context.TrafficLevel))
 	}
 
+	if context.MinPriority > 0 {
+		sb.WriteString(fmt.Sprintf("🎯 Showing priority %d and above\n", context.MinPriority))
+	}
+
+	return sb.String()
+}
+
+// formatContextAge writes a context's age in hours and minutes, e.g. "8h05m"
+func formatContextAge(age time.Duration) string {
+	hours, minutes := int(age/time.Hour), int(age%time.Hour/time.Minute)
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", hours, minutes)
+}
+
+func (f *HumanFormatter) FormatCompletionStats(stats models.CompletionStats) string {
+	var sb strings.Builder
+
+	sb.WriteString(f.colorize(ColorBold, "\nProgress\n"))
+	sb.WriteString(f.locale().Sprintf("✅ Completed today: %d\n", stats.CompletedToday))
+
+	switch stats.CurrentStreak {
+	case 0:
+		sb.WriteString("🔥 Streak: none - complete a task to start one\n")
+	case 1:
+		sb.WriteString("🔥 Streak: 1 day\n")
+	default:
+		sb.WriteString(f.locale().Sprintf("🔥 Streak: %d days\n", stats.CurrentStreak))
+	}
+
+	if stats.LongestStreak > stats.CurrentStreak {
+		sb.WriteString(f.locale().Sprintf("🏆 Longest streak: %d days\n", stats.LongestStreak))
+	}
+
+	return sb.String()
+}
+
+func (f *HumanFormatter) FormatImportReport(report sync.ImportReport) string {
+	var sb strings.Builder
+
+	source := report.Source
+	if source == "" {
+		source = report.Format
+	}
+	sb.WriteString(f.colorize(ColorBold, fmt.Sprintf("Import from %s\n", source)))
+
+	for _, record := range report.Records {
+		switch record.Status {
+		case sync.ImportStatusFailed:
+			sb.WriteString(f.colorize(ColorRed, fmt.Sprintf("  ❌ line %d: %s - %s\n", record.Line, record.Title, record.Reason)))
+		case sync.ImportStatusSkipped:
+			sb.WriteString(f.colorize(ColorYellow, fmt.Sprintf("  ⏭️  line %d: %s - %s\n", record.Line, record.Title, record.Reason)))
+		default:
+			sb.WriteString(fmt.Sprintf("  ✅ line %d: %s\n", record.Line, record.Title))
+		}
+	}
+
+	sb.WriteString(f.locale().Sprintf("\n%d imported, %d skipped, %d failed\n", report.Imported, report.Skipped, report.Failed))
 	return sb.String()
 }
 
@@ -546,6 +861,13 @@ func (f *HumanFormatter) FormatInfo(message string) string {
 
 // Helper methods for HumanFormatter
 
+func (f *HumanFormatter) locale() *locale.Locale {
+	if f.Locale == nil {
+		return locale.Default()
+	}
+	return f.Locale
+}
+
 func (f *HumanFormatter) colorize(color, text string) string {
 	if globalConfig.NoColor {
 		return text
@@ -553,11 +875,20 @@ func (f *HumanFormatter) colorize(color, text string) string {
 	return color + text + ColorReset
 }
 
+// highlight colorizes text and emphasizes matches of the search query in it.
+// Without color, matches are wrapped in brackets instead.
+func (f *HumanFormatter) highlight(color, text string) string {
+	if globalConfig.NoColor {
+		return hereandnow.HighlightMatches(text, f.Query, "[", "]")
+	}
+	return f.colorize(color, hereandnow.HighlightMatches(text, f.Query, ColorReset+ColorYellow+ColorBold, ColorReset+color))
+}
+
 func (f *HumanFormatter) formatTaskSummary(task models.Task, index int) string {
 	var sb strings.Builder
 
 	// Task number and title
-	sb.WriteString(fmt.Sprintf("%d. %s", index, f.colorize(ColorBold, task.Title)))
+	sb.WriteString(fmt.Sprintf("%d. %s", index, f.highlight(ColorBold, task.Title)))
 
 	// Status indicator
 	switch task.Status {
@@ -573,10 +904,15 @@ func (f *HumanFormatter) formatTaskSummary(task models.Task, index int) string {
 
 	// Priority
 	sb.WriteString(fmt.Sprintf(" %s", f.priorityIndicator(task.Priority)))
+	if task.RequiredEnergyLevel != nil {
+		sb.WriteString(f.colorize(ColorGreen, fmt.Sprintf(" (energy %d)", *task.RequiredEnergyLevel)))
+	}
 
 	// Time estimate
 	if task.EstimatedMinutes != nil {
 		sb.WriteString(f.colorize(ColorCyan, fmt.Sprintf(" (%dm)", *task.EstimatedMinutes)))
+	} else if task.EffortPoints != nil {
+		sb.WriteString(f.colorize(ColorCyan, fmt.Sprintf(" (%dpt)", *task.EffortPoints)))
 	}
 
 	// Due date
@@ -584,14 +920,23 @@ func (f *HumanFormatter) formatTaskSummary(task models.Task, index int) string {
 		if task.DueAt.Before(time.Now()) {
 			sb.WriteString(f.colorize(ColorRed, " (OVERDUE)"))
 		} else {
-			sb.WriteString(f.colorize(ColorDim, fmt.Sprintf(" (due %s)", task.DueAt.Format("Jan 2"))))
+			sb.WriteString(f.colorize(ColorDim, fmt.Sprintf(" (due %s)", f.locale().Format(*task.DueAt, locale.ShortDate))))
 		}
 	}
 
+	// Snoozed tasks only show up in unfiltered listings
+	if task.IsSnoozed(time.Now()) {
+		sb.WriteString(f.colorize(ColorDim, fmt.Sprintf(" 💤 until %s", f.locale().Format(*task.SnoozedUntil, locale.ShortDate))))
+	}
+
+	if len(task.Tags) > 0 {
+		sb.WriteString(" " + f.colorize(ColorPurple, formatTags(task.Tags)))
+	}
+
 	// Description preview
 	if task.Description != "" {
 		desc := truncateString(task.Description, 60)
-		sb.WriteString(f.colorize(ColorDim, fmt.Sprintf("\n   %s", desc)))
+		sb.WriteString("\n   " + f.highlight(ColorDim, desc))
 	}
 
 	return sb.String()
@@ -629,11 +974,21 @@ func (f *HumanFormatter) energyIndicator(energy int) string {
 
 // Utility functions
 
+// formatTags writes tags the way they are typed, e.g. "#errands #urgent"
+func formatTags(tags []string) string {
+	hashed := make([]string, len(tags))
+	for i, tag := range tags {
+		hashed[i] = "#" + tag
+	}
+	return strings.Join(hashed, " ")
+}
+
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }
 
 func Output(formatter Formatter, data interface{}) {
@@ -641,6 +996,17 @@ func Output(formatter Formatter, data interface{}) {
 
 	switch v := data.(type) {
 	case []models.Task:
+		// Human output truncates itself with a footer; other formats are
+		// only cut short by an explicit --limit
+		if _, human := formatter.(*HumanFormatter); !human {
+			v, _ = truncate.Slice(v, outputLimit(globalConfig.Format))
+		}
+		if stream, ok := formatter.(StreamFormatter); ok {
+			if err := stream.StreamTasks(os.Stdout, v); err != nil {
+				fmt.Fprint(os.Stderr, formatter.FormatError(err))
+			}
+			return
+		}
 		output = formatter.FormatTasks(v)
 	case models.Task:
 		output = formatter.FormatTask(v)
@@ -654,6 +1020,10 @@ func Output(formatter Formatter, data interface{}) {
 		output = formatter.FormatLocation(v)
 	case models.Context:
 		output = formatter.FormatContext(v)
+	case models.CompletionStats:
+		output = formatter.FormatCompletionStats(v)
+	case sync.ImportReport:
+		output = formatter.FormatImportReport(v)
 	case map[string]interface{}:
 		output = formatter.FormatAnalytics(v)
 	case error:
@@ -673,7 +1043,9 @@ func Output(formatter Formatter, data interface{}) {
 		}
 	default:
 		// Fallback to JSON for unknown types
-		if data, err := json.MarshalIndent(v, "", "  "); err == nil {
+		if _, ok := formatter.(*NDJSONFormatter); ok {
+			output = ndjsonLine(v)
+		} else if data, err := json.MarshalIndent(v, "", "  "); err == nil {
 			output = string(data) + "\n"
 		} else {
 			output = formatter.FormatError(fmt.Errorf("unable to format data: %v", v))
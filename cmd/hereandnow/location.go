@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bcnelson/hereAndNow/internal/storage"
@@ -36,7 +37,15 @@ OPTIONS:
     --name <name>       Location name (required for add)
     --lat <latitude>    Latitude coordinate (required for add)
     --lng <longitude>   Longitude coordinate (required for add)
-    --radius <meters>   Location radius in meters (default: 100)
+    --radius <meters>   Location radius in meters (default: category default, else 100)
+    --category <name>   Location category: home, work, errand, gym, school or
+                        other (default: other); list filters by it
+    --allow-custom-category
+                        Accept a category outside that set
+    --hours <hours>     Opening hours in your timezone, e.g.
+                        "Mon-Sat 08:00-21:00; Sun 10:00-16:00"; tasks there
+                        are hidden while it is closed (default: the
+                        category's hours; "always" clears them on update)
     --help, -h          Show this help
 
 EXAMPLES:
@@ -46,9 +55,24 @@ EXAMPLES:
     # Add work location
     hereandnow location add --name "Office" --lat 37.7858 --lng -122.4065 --radius 200
 
+    # Add a store as an errand
+    hereandnow location add --name "Market" --lat 37.7793 --lng -122.4193 --category errand
+
+    # Keep a category of your own
+    hereandnow location add --name "Marina" --lat 37.8060 --lng -122.4400 --category boating --allow-custom-category
+
+    # Only show hardware store errands while it is open
+    hereandnow location add --name "Hardware Store" --lat 37.7701 --lng -122.4120 --hours "Mon-Sat 08:00-21:00"
+
+    # A bar that stays open past midnight
+    hereandnow location update "Corner Bar" --hours "Thu-Sat 18:00-02:00"
+
     # List all locations
     hereandnow location list
 
+    # List only your gyms
+    hereandnow location list --category gym
+
     # Show specific location
     hereandnow location show "Home"
 
@@ -61,24 +85,8 @@ EXAMPLES:
 		return
 	}
 
-	subcommand := args[0]
-	subArgs := args[1:]
-
-	switch subcommand {
-	case "add":
-		executeLocationAdd(subArgs)
-	case "list":
-		executeLocationList(subArgs)
-	case "show":
-		executeLocationShow(subArgs)
-	case "update":
-		executeLocationUpdate(subArgs)
-	case "delete":
-		executeLocationDelete(subArgs)
-	case "nearby":
-		executeLocationNearby(subArgs)
-	default:
-		fmt.Printf("Unknown location subcommand: %s\n", subcommand)
+	if !runSubcommand(locationCommands, args) {
+		fmt.Printf("Unknown location subcommand: %s\n", args[0])
 		fmt.Println("Run 'hereandnow location --help' for usage")
 		os.Exit(1)
 	}
@@ -88,7 +96,10 @@ func executeLocationAdd(args []string) {
 	name := ""
 	lat := 0.0
 	lng := 0.0
-	radius := 100
+	var explicitRadius *int
+	category := ""
+	allowCustomCategory := false
+	hours := ""
 
 	for i, arg := range args {
 		switch arg {
@@ -111,9 +122,38 @@ func executeLocationAdd(args []string) {
 		case "--radius":
 			if i+1 < len(args) {
 				if r, err := strconv.Atoi(args[i+1]); err == nil {
-					radius = r
+					explicitRadius = &r
 				}
 			}
+		case "--category":
+			if i+1 < len(args) {
+				category = args[i+1]
+			}
+		case "--allow-custom-category":
+			allowCustomCategory = true
+		case "--hours":
+			if i+1 < len(args) {
+				hours = args[i+1]
+			}
+		}
+	}
+
+
+	// Load config for category radius defaults
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	radius := config.Locations.ResolveRadius(category, explicitRadius)
+
+	// Explicit hours win over the category's
+	openHours := config.Locations.HoursFor(category)
+	if hours != "" {
+		if openHours, err = models.ParseOpenHours(hours); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 	}
 
@@ -152,12 +192,6 @@ func executeLocationAdd(args []string) {
 	}
 
 	// Initialize database
-	config, err := LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
-	}
-
 	db, err := InitDatabase(config.Database.Path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
@@ -185,11 +219,25 @@ func executeLocationAdd(args []string) {
 		Latitude:  lat,
 		Longitude: lng,
 		Radius:    radius,
+		OpenHours: openHours,
 		UserID:    userID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
+	if err := setLocationCategory(&location, category, allowCustomCategory); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := location.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating location: %v\n", err)
+		os.Exit(1)
+	}
+	if dryRun("create location: %s (%.6f, %.6f, radius %dm)", name, lat, lng, radius) {
+		return
+	}
+
 	if err := locationRepo.Create(location); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating location: %v\n", err)
 		os.Exit(1)
@@ -200,6 +248,15 @@ func executeLocationAdd(args []string) {
 }
 
 func executeLocationList(args []string) {
+	// Custom categories can be listed too, so any name is accepted
+	var category *models.LocationCategory
+	for i, arg := range args {
+		if arg == "--category" && i+1 < len(args) {
+			c := models.LocationCategory(strings.ToLower(strings.TrimSpace(args[i+1])))
+			category = &c
+		}
+	}
+
 	userID := getCurrentUserID()
 	if userID == "" {
 		fmt.Fprintf(os.Stderr, "Error: No current user\n")
@@ -221,10 +278,22 @@ func executeLocationList(args []string) {
 
 	locationRepo := storage.NewLocationRepository(db)
 
-	locations, err := locationRepo.GetByUserID(userID)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error retrieving locations: %v\n", err)
-		os.Exit(1)
+	var locations []models.Location
+	if category != nil {
+		found, err := locationRepo.GetByCategory(userID, *category, 0, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error retrieving locations: %v\n", err)
+			os.Exit(1)
+		}
+		for _, location := range found {
+			locations = append(locations, *location)
+		}
+	} else {
+		locations, err = locationRepo.GetByUserID(userID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error retrieving locations: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	formatter := NewFormatter(globalConfig.Format)
@@ -253,6 +322,10 @@ func executeLocationShow(args []string) {
 
 	formatter := NewFormatter(globalConfig.Format)
 	Output(formatter, *location)
+
+	if !isJSONFormat(globalConfig.Format) && location.OpenHours.IsSet() {
+		fmt.Printf("\nOpen: %s\n", location.OpenHours)
+	}
 }
 
 func executeLocationUpdate(args []string) {
@@ -265,6 +338,9 @@ func executeLocationUpdate(args []string) {
 	name := args[0]
 	var lat, lng *float64
 	var radius *int
+	var hours *string
+	var category *string
+	allowCustomCategory := false
 
 	for i := 1; i < len(args); i++ {
 		switch args[i] {
@@ -289,12 +365,24 @@ func executeLocationUpdate(args []string) {
 					i++
 				}
 			}
+		case "--hours":
+			if i+1 < len(args) {
+				hours = &args[i+1]
+				i++
+			}
+		case "--category":
+			if i+1 < len(args) {
+				category = &args[i+1]
+				i++
+			}
+		case "--allow-custom-category":
+			allowCustomCategory = true
 		}
 	}
 
-	if lat == nil && lng == nil && radius == nil {
+	if lat == nil && lng == nil && radius == nil && hours == nil && category == nil {
 		fmt.Fprintf(os.Stderr, "Error: At least one field must be updated\n")
-		fmt.Println("Available options: --lat, --lng, --radius")
+		fmt.Println("Available options: --lat, --lng, --radius, --hours, --category")
 		os.Exit(1)
 	}
 
@@ -335,6 +423,26 @@ func executeLocationUpdate(args []string) {
 		location.Radius = *radius
 	}
 
+	if hours != nil {
+		spec := *hours
+		if spec == "always" {
+			spec = ""
+		}
+		openHours, err := models.ParseOpenHours(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		location.OpenHours = openHours
+	}
+
+	if category != nil {
+		if err := setLocationCategory(location, *category, allowCustomCategory); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	location.UpdatedAt = time.Now()
 
 	// Save updated location
@@ -381,6 +489,9 @@ func executeLocationDelete(args []string) {
 		fmt.Fprintf(os.Stderr, "Error: Location '%s' not found\n", name)
 		os.Exit(1)
 	}
+	if dryRun("delete location: %s", location.Name) {
+		return
+	}
 
 	config, err := LoadConfig()
 	if err != nil {
@@ -496,4 +607,26 @@ func findLocationByNameForUser(name, userID string) (*models.Location, error) {
 	}
 
 	return nil, fmt.Errorf("location not found: %s", name)
-}
\ No newline at end of file
+}
+
+// setLocationCategory files the location under the named category, which
+// must be one of the fixed set unless allowCustom is given. No category is
+// other.
+func setLocationCategory(location *models.Location, name string, allowCustom bool) error {
+	if allowCustom && strings.TrimSpace(name) != "" {
+		return location.SetCustomCategory(name)
+	}
+	category, err := models.ParseLocationCategory(name)
+	if err != nil {
+		return fmt.Errorf("%w; pass --allow-custom-category to keep it anyway", err)
+	}
+	return location.SetCategory(category)
+}
+
+// locationCategory returns the location's category, other if it has none
+func locationCategory(location models.Location) models.LocationCategory {
+	if location.Category == "" {
+		return models.LocationCategoryOther
+	}
+	return location.Category
+}
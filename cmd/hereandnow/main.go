@@ -3,16 +3,24 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/locale"
 )
 
 const Version = "0.1.0"
 
 type GlobalConfig struct {
-	Format     string // json, table, human
+	Format     string // json, ndjson, table, human
 	ConfigPath string
 	Verbose    bool
 	NoColor    bool
+	Locale     string
+	DryRun     bool
+	Limit      *int // nil uses the format's default
+	DBKey      string
 }
 
 var globalConfig GlobalConfig
@@ -35,41 +43,13 @@ func main() {
 		return
 	}
 
-	command := args[0]
-	commandArgs := args[1:]
-
-	switch command {
-	case "help", "--help", "-h":
-		showHelp()
-	case "version", "--version", "-v":
-		showVersion()
-	case "init":
-		handleInit(commandArgs)
-	case "user":
-		handleUserCommand(commandArgs)
-	case "task":
-		handleTaskCommand(commandArgs)
-	case "location":
-		handleLocationCommand(commandArgs)
-	case "context":
-		handleContextCommand(commandArgs)
-	case "serve":
-		handleServeCommand(commandArgs)
-	case "migrate":
-		handleMigrateCommand(commandArgs)
-	case "doctor":
-		handleDoctorCommand(commandArgs)
-	case "calendar":
-		handleCalendarCommand(commandArgs)
-	case "list":
-		handleListCommand(commandArgs)
-	case "reset":
-		handleResetCommand(commandArgs)
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+	cmd := findCommand(commands, args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
 		fmt.Fprintf(os.Stderr, "Run 'hereandnow help' for usage information.\n")
 		os.Exit(1)
 	}
+	cmd.run(args[1:])
 }
 
 func parseGlobalFlags(args []string) ([]string, error) {
@@ -79,19 +59,27 @@ func parseGlobalFlags(args []string) ([]string, error) {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 		
+		// task export takes its own --format (e.g. todoist)
+		if (arg == "--format" || strings.HasPrefix(arg, "--format=")) && isExportCommand(remainingArgs) {
+			remainingArgs = append(remainingArgs, arg)
+			continue
+		}
+
 		if arg == "--format" && i+1 < len(args) {
 			format := args[i+1]
-			if format != "json" && format != "table" && format != "human" {
-				return nil, fmt.Errorf("invalid format: %s (must be json, table, or human)", format)
+			if !isValidFormat(format) {
+				return nil, fmt.Errorf("invalid format: %s (must be json, ndjson, table, or human)", format)
 			}
 			globalConfig.Format = format
 			i++ // skip the next argument as it's the format value
 		} else if strings.HasPrefix(arg, "--format=") {
 			format := strings.TrimPrefix(arg, "--format=")
-			if format != "json" && format != "table" && format != "human" {
-				return nil, fmt.Errorf("invalid format: %s (must be json, table, or human)", format)
+			if !isValidFormat(format) {
+				return nil, fmt.Errorf("invalid format: %s (must be json, ndjson, table, or human)", format)
 			}
 			globalConfig.Format = format
+		} else if arg == "--json-stream" {
+			globalConfig.Format = "ndjson"
 		} else if arg == "--config" && i+1 < len(args) {
 			globalConfig.ConfigPath = args[i+1]
 			i++
@@ -99,8 +87,40 @@ func parseGlobalFlags(args []string) ([]string, error) {
 			globalConfig.ConfigPath = strings.TrimPrefix(arg, "--config=")
 		} else if arg == "--verbose" || arg == "-v" {
 			globalConfig.Verbose = true
+		} else if arg == "--locale" && i+1 < len(args) {
+			if _, err := locale.Parse(args[i+1]); err != nil {
+				return nil, err
+			}
+			globalConfig.Locale = args[i+1]
+			i++
+		} else if strings.HasPrefix(arg, "--locale=") {
+			name := strings.TrimPrefix(arg, "--locale=")
+			if _, err := locale.Parse(name); err != nil {
+				return nil, err
+			}
+			globalConfig.Locale = name
 		} else if arg == "--no-color" {
 			globalConfig.NoColor = true
+		} else if arg == "--dry-run" {
+			globalConfig.DryRun = true
+		} else if arg == "--db-key" && i+1 < len(args) {
+			globalConfig.DBKey = args[i+1]
+			i++
+		} else if strings.HasPrefix(arg, "--db-key=") {
+			globalConfig.DBKey = strings.TrimPrefix(arg, "--db-key=")
+		} else if arg == "--limit" && i+1 < len(args) {
+			limit, err := parseLimit(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			globalConfig.Limit = &limit
+			i++
+		} else if strings.HasPrefix(arg, "--limit=") {
+			limit, err := parseLimit(strings.TrimPrefix(arg, "--limit="))
+			if err != nil {
+				return nil, err
+			}
+			globalConfig.Limit = &limit
 		} else if strings.HasPrefix(arg, "--") {
 			return nil, fmt.Errorf("unknown global flag: %s", arg)
 		} else {
@@ -111,6 +131,36 @@ func parseGlobalFlags(args []string) ([]string, error) {
 	return remainingArgs, nil
 }
 
+// dryRun reports what a mutating command would do and returns true when
+// --dry-run is set. Commands validate their input first and return without
+// writing when it reports true.
+func dryRun(format string, args ...interface{}) bool {
+	if !globalConfig.DryRun {
+		return false
+	}
+	Output(NewFormatter(globalConfig.Format), "Dry run: would "+fmt.Sprintf(format, args...))
+	return true
+}
+
+func parseLimit(value string) (int, error) {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid limit: %s (must be 0 or more)", value)
+	}
+	return limit, nil
+}
+
+// outputFormats are the formats --format takes
+var outputFormats = []string{"json", "ndjson", "table", "human"}
+
+func isValidFormat(format string) bool {
+	return slices.Contains(outputFormats, format)
+}
+
+func isExportCommand(args []string) bool {
+	return len(args) >= 2 && args[0] == "task" && args[1] == "export"
+}
+
 func showHelp() {
 	fmt.Printf(`Here and Now - Context-Aware Task Management
 
@@ -121,10 +171,22 @@ VERSION:
     %s
 
 GLOBAL OPTIONS:
-    --format <format>    Output format: json, table, human (default: human)
+    --format <format>    Output format: json, ndjson, table, human (default: human).
+                         ndjson writes one compact JSON object per line
+    --json-stream        Same as --format ndjson
     --config <path>      Config file path (default: ~/.hereandnow/config.yaml)
     --verbose, -v        Enable verbose output
+    --locale <locale>    Language for dates and numbers: en, de, fr, es
+                         (default: locale from config, else en)
     --no-color          Disable colored output
+    --dry-run           Validate and show what a command would change without
+                        writing anything
+    --db-key <key>      Key for an encrypted database (database.encryption:
+                        sqlcipher); prefer the HEREANDNOW_DB_KEY variable,
+                        since flags are visible to other users
+    --limit <n>         Show at most n tasks; 0 shows all. Human output
+                        shows output.human_limit (default 25) unless set,
+                        other formats are only limited when set
     --help, -h          Show help
     --version           Show version
 
@@ -140,6 +202,9 @@ COMMANDS:
     context              Context management commands
     list                 Task list management commands
     calendar             Calendar integration commands
+    admin                Administration commands (admins only)
+    undo                 Undo your last task complete, delete or snooze
+    completion           Print a bash, zsh or fish completion script
 
     reset                Reset all data (destructive)
 
@@ -165,6 +230,9 @@ EXAMPLES:
     # Check system status
     hereandnow doctor
 
+    # Tab-complete commands, task IDs and location names in bash
+    source <(hereandnow completion bash)
+
 Use 'hereandnow <command> --help' for more information about a specific command.
 `, Version)
 }
@@ -187,12 +255,14 @@ DESCRIPTION:
 OPTIONS:
     --force              Force initialization even if config exists
     --db-path <path>     Custom database path
+    --admin-user <name>  Make the user an admin, creating them if needed
     --help, -h          Show this help
 
 EXAMPLES:
     hereandnow init
     hereandnow init --force
     hereandnow init --db-path ./custom.db
+    hereandnow init --admin-user admin
 `)
 		return
 	}
@@ -209,10 +279,11 @@ USAGE:
 
 DESCRIPTION:
     Checks system health, database connectivity, and configuration.
+    Also scans stored metadata for invalid JSON.
     Provides detailed diagnostics for troubleshooting.
 
 OPTIONS:
-    --fix               Attempt to fix common issues
+    --fix               Attempt to fix common issues (resets invalid metadata to {})
     --help, -h         Show this help
 
 EXAMPLES:
@@ -260,19 +331,33 @@ USAGE:
     hereandnow calendar <SUBCOMMAND> [OPTIONS]
 
 SUBCOMMANDS:
-    add <provider>     Add calendar integration (google, caldav)
-    sync              Sync all calendars
+    add caldav        Add a CalDAV account and discover its calendars
+                      --url <url>         Server, calendar home or calendar URL
+                      --username <name>   Account username
+                      --password <pass>   Password or app password (default: $HEREANDNOW_CALDAV_PASSWORD)
+                      --name <name>       Name for the account (default: server host)
+    add google        Add a Google Calendar using an OAuth2 client and refresh token
+                      --client-id <id>         OAuth2 client ID
+                      --client-secret <secret> OAuth2 client secret (default: $HEREANDNOW_GOOGLE_CLIENT_SECRET)
+                      --refresh-token <token>  Refresh token for the calendar.readonly scope (default: $HEREANDNOW_GOOGLE_REFRESH_TOKEN)
+                      --calendar <id>          Calendar to read (default: primary)
+                      --name <name>            Name for the account (default: google)
+    sync              Sync all calendars, reporting created/updated/removed events per calendar
+                      --concurrency <n>  Calendars to sync at once (default: calendar.sync_concurrency)
     list              List configured calendars
     remove <name>     Remove calendar integration
+    import <file.ics> Import the events of an .ics file, skipping ones already imported
+                      --as-tasks  Also import its to-dos as tasks (default: skipped)
 
 OPTIONS:
     --help, -h         Show this help
 
 EXAMPLES:
-    hereandnow calendar add google
-    hereandnow calendar add caldav --url https://server.com/dav
+    hereandnow calendar add caldav --url https://server.com/dav --username me --password app-password
+    hereandnow calendar add google --client-id 123.apps.googleusercontent.com --refresh-token 1//0abc
     hereandnow calendar sync
     hereandnow calendar list
+    hereandnow calendar import ~/Downloads/work.ics --as-tasks
 `)
 		return
 	}
@@ -289,20 +374,38 @@ USAGE:
 
 SUBCOMMANDS:
     create <name>      Create a new task list
+    update <name>      Change a list's default location and estimate
     list              Show all task lists
     share <name>      Share a task list with users
     members <name>    Show list members
+    schedule <id>     Show due tasks in each member's timezone, flagging
+                      tasks due outside the assignee's working hours
+    archive <name>    Hide a list you own, with its child lists and tasks,
+                      without deleting anything; its tasks can't be changed
+                      until it is unarchived
+    unarchive <name>  Bring an archived list back as it was
     delete <name>     Delete a task list
 
 OPTIONS:
     --shared           Create as shared list
+    --location <name>  Location for tasks added without one (create, update)
+    --no-location      Clear the default location (update)
+    --default-minutes <n>
+                       Estimate for tasks added without one; 0 clears it (create, update)
+    --apply-to-existing
+                       Also give the defaults to the list's open tasks (update)
     --help, -h         Show this help
 
 EXAMPLES:
     hereandnow list create "Family Chores"
     hereandnow list create "Work Projects" --shared
+    hereandnow list create "Costco run" --location "Costco" --default-minutes 10
+    hereandnow list update "Costco run" --default-minutes 15 --apply-to-existing
     hereandnow list share "Family Chores" --user john --role editor
     hereandnow list list
+    hereandnow list schedule <list-id>
+    hereandnow list archive "Party planning"
+    hereandnow list unarchive "Party planning"
 `)
 		return
 	}
@@ -329,6 +432,7 @@ OPTIONS:
 EXAMPLES:
     hereandnow reset --confirm
     hereandnow reset --backup --confirm
+    hereandnow reset --dry-run
 `)
 		return
 	}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/bcnelson/hereAndNow/pkg/locale"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// command is a command or subcommand of the CLI. The dispatcher runs
+// commands found here and the completion scripts are generated from here,
+// so a command added to the registry can be both run and tab-completed.
+type command struct {
+	name    string
+	aliases []string
+	summary string
+	// run is called with the arguments after the command's name. It is nil
+	// for subcommands their parent's run dispatches itself.
+	run         func(args []string)
+	subcommands []*command
+	flags       []flag
+	// args is what the command's positional arguments complete to
+	args completion
+	// hidden commands run but aren't offered for completion
+	hidden bool
+}
+
+// flag is an option a command takes
+type flag struct {
+	name string
+	// value names what the flag takes, and is empty for a switch
+	value    string
+	complete completion
+	// choices are the values a completeChoices flag takes
+	choices []string
+}
+
+// completion is what a flag's value or a positional argument completes to.
+// The dynamic ones are also the argument 'hereandnow __complete' takes to
+// list them.
+type completion string
+
+const (
+	completeNothing   completion = ""
+	completeFiles     completion = "files"
+	completeTaskIDs   completion = "tasks"
+	completeLocations completion = "locations"
+	completeChoices   completion = "choices"
+)
+
+// dynamic reports whether the values are read from the database
+func (c completion) dynamic() bool {
+	return c == completeTaskIDs || c == completeLocations
+}
+
+func switchFlag(name string) flag {
+	return flag{name: name}
+}
+
+func valueFlag(name, value string) flag {
+	return flag{name: name, value: value}
+}
+
+func taskFlag(name string) flag {
+	return flag{name: name, value: "task-id", complete: completeTaskIDs}
+}
+
+func locationFlag(name string) flag {
+	return flag{name: name, value: "name", complete: completeLocations}
+}
+
+func fileFlag(name string) flag {
+	return flag{name: name, value: "path", complete: completeFiles}
+}
+
+func choiceFlag(name string, choices ...string) flag {
+	return flag{name: name, value: "value", complete: completeChoices, choices: choices}
+}
+
+// taskStatuses are the statuses --status takes
+var taskStatuses = []string{
+	string(models.TaskStatusPending), string(models.TaskStatusActive), string(models.TaskStatusCompleted),
+	string(models.TaskStatusCancelled), string(models.TaskStatusBlocked),
+}
+
+// socialContexts are the social contexts --social takes
+var socialContexts = []string{"alone", "family", "work", "friends", "public"}
+
+// snoozePresetNames are the built-in snooze presets; ones added in the
+// config aren't offered
+func snoozePresetNames() []string {
+	var names []string
+	for name := range models.DefaultSnoozePresets() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// commands are the top-level commands, set up in init because the
+// completion commands read them
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "help", aliases: []string{"--help", "-h"}, summary: "Show help", run: func([]string) { showHelp() }},
+		{name: "version", aliases: []string{"--version", "-v"}, summary: "Show version", run: func([]string) { showVersion() }},
+		{name: "init", summary: "Initialize database and configuration", run: handleInit, flags: []flag{
+			switchFlag("--force"), fileFlag("--db-path"), valueFlag("--admin-user", "username"),
+		}},
+		{name: "serve", summary: "Start the API server", run: handleServeCommand, flags: []flag{
+			valueFlag("--port", "port"), valueFlag("--host", "host"), valueFlag("--base-path", "path"),
+			valueFlag("--cleanup-interval", "duration"), switchFlag("--daemon"), switchFlag("--dev"),
+		}},
+		{name: "migrate", summary: "Run database migrations", run: handleMigrateCommand, subcommands: migrateCommands},
+		{name: "doctor", summary: "Check system health and configuration", run: handleDoctorCommand, flags: []flag{
+			switchFlag("--fix"),
+		}},
+		{name: "user", summary: "User management commands", run: handleUserCommand, subcommands: userCommands},
+		{name: "task", summary: "Task management commands", run: handleTaskCommand, subcommands: taskCommands},
+		{name: "location", summary: "Location management commands", run: handleLocationCommand, subcommands: locationCommands},
+		{name: "context", summary: "Context management commands", run: handleContextCommand, subcommands: contextCommands},
+		{name: "list", summary: "Task list management commands", run: handleListCommand, subcommands: listCommands},
+		{name: "calendar", summary: "Calendar integration commands", run: handleCalendarCommand, subcommands: calendarCommands},
+		{name: "admin", summary: "Administration commands (admins only)", run: handleAdminCommand, subcommands: adminCommands},
+		{name: "undo", summary: "Undo your last task complete, delete or snooze", run: handleUndoCommand},
+		{name: "reset", summary: "Reset all data (destructive)", run: handleResetCommand, flags: []flag{
+			switchFlag("--confirm"), switchFlag("--backup"),
+		}},
+		{name: "completion", summary: "Print a shell completion script", run: handleCompletionCommand, subcommands: []*command{
+			{name: "bash", summary: "Completion script for bash"},
+			{name: "zsh", summary: "Completion script for zsh"},
+			{name: "fish", summary: "Completion script for fish"},
+		}},
+		{name: "__complete", hidden: true, run: executeComplete},
+	}
+}
+
+// globalFlags are the flags parseGlobalFlags takes before or after any
+// command
+var globalFlags = []flag{
+	choiceFlag("--format", outputFormats...), switchFlag("--json-stream"), fileFlag("--config"),
+	switchFlag("--verbose"), choiceFlag("--locale", locale.Supported()...), switchFlag("--no-color"),
+	switchFlag("--dry-run"), valueFlag("--db-key", "key"), valueFlag("--limit", "n"),
+}
+
+var migrateCommands = []*command{
+	{name: "up", summary: "Apply pending migrations"},
+	{name: "down", summary: "Rollback n migrations"},
+	{name: "status", summary: "Show migration status"},
+}
+
+var userCommands = []*command{
+	{name: "create", summary: "Create a new user", run: executeUserCreate, flags: []flag{
+		switchFlag("--admin"), valueFlag("--username", "name"), valueFlag("--email", "email"), valueFlag("--timezone", "tz"),
+	}},
+	{name: "list", summary: "List all users", run: executeUserList},
+	{name: "show", summary: "Show user details", run: executeUserShow},
+	{name: "update", summary: "Update user information", run: executeUserUpdate, flags: []flag{
+		valueFlag("--email", "email"), valueFlag("--timezone", "tz"), switchFlag("--admin"), switchFlag("--no-admin"),
+		switchFlag("--activate"), switchFlag("--deactivate"),
+	}},
+	{name: "delete", summary: "Delete a user", run: executeUserDelete, flags: []flag{
+		switchFlag("--anonymize"),
+	}},
+	{name: "password", summary: "Change user password", run: executeUserPassword},
+	{name: "sessions", summary: "List the user's signed-in devices", run: executeUserSessions, subcommands: []*command{
+		{name: "revoke", summary: "Sign a device out", flags: []flag{switchFlag("--all")}},
+	}},
+	{name: "notify", summary: "Show where your notifications are pushed", run: executeUserNotify, subcommands: []*command{
+		{name: "show", summary: "Show your notification channels"},
+		{name: "set", summary: "Set your notification channels", flags: []flag{
+			valueFlag("--webhook", "url"), valueFlag("--ntfy-topic", "topic"), valueFlag("--ntfy-server", "url"),
+			choiceFlag("--email", "on", "off"), switchFlag("--test"),
+		}},
+		{name: "test", summary: "Send a test notification to your channels"},
+	}},
+}
+
+var taskCommands = []*command{
+	{name: "add", summary: "Create a new task", run: executeTaskAdd, flags: []flag{
+		valueFlag("--priority", "1-10"), valueFlag("--estimate", "mins"), valueFlag("--points", "n"),
+		valueFlag("--energy", "1-5"), valueFlag("--due", "date"), valueFlag("--remind", "when"),
+		locationFlag("--location"), switchFlag("--on-exit"), valueFlag("--assignee", "user"),
+		taskFlag("--depends-on"), valueFlag("--list", "name"), valueFlag("--description", "text"),
+		valueFlag("--repeat", "period"), switchFlag("--outdoor"), valueFlag("--tag", "tag"),
+		fileFlag("--from-file"),
+	}},
+	{name: "list", summary: "List tasks, filtered by context", run: executeTaskList, flags: []flag{
+		switchFlag("--all"), valueFlag("--limit", "n"),
+		choiceFlag("--sort", "created_at", "due_at", "priority", "title", "position"), choiceFlag("--order", "asc", "desc"),
+		choiceFlag("--status", taskStatuses...), valueFlag("--list", "name"), valueFlag("--search", "query"),
+		valueFlag("--diff-context", "changes"), valueFlag("--tag", "tag"), switchFlag("--any-tag"),
+		valueFlag("--min-priority", "n"),
+	}},
+	{name: "show", summary: "Show task details", run: executeTaskShow, args: completeTaskIDs},
+	{name: "update", summary: "Update task information", run: executeTaskUpdate, args: completeTaskIDs, flags: []flag{
+		valueFlag("--title", "title"), valueFlag("--description", "text"), valueFlag("--priority", "1-10"),
+		valueFlag("--estimate", "mins"), valueFlag("--points", "n"), valueFlag("--energy", "1-5"),
+		valueFlag("--due", "date"), choiceFlag("--status", taskStatuses...),
+	}},
+	{name: "bulk-edit", summary: "Set fields on every task matching --filter", run: executeTaskBulkEdit, flags: []flag{
+		valueFlag("--filter", "terms"), valueFlag("--priority", "1-10"), valueFlag("--estimate", "mins"),
+		valueFlag("--due", "date"),
+	}},
+	{name: "complete", summary: "Mark task as complete", run: executeTaskComplete, args: completeTaskIDs, flags: []flag{
+		taskFlag("--id"),
+	}},
+	{name: "uncomplete", summary: "Take back a completion in a shared list", run: executeTaskUncomplete, args: completeTaskIDs},
+	{name: "delete", summary: "Move a task to the trash", run: executeTaskDelete, args: completeTaskIDs, flags: []flag{
+		switchFlag("--instances"),
+	}},
+	{name: "trash", summary: "List deleted tasks that can still be restored", run: executeTaskTrash, subcommands: []*command{
+		{name: "list", summary: "List deleted tasks"},
+	}},
+	{name: "restore", summary: "Bring back a deleted task", run: executeTaskRestore, flags: []flag{
+		valueFlag("--id", "task-id"),
+	}},
+	{name: "purge", summary: "Permanently remove a deleted task", run: executeTaskPurge, flags: []flag{
+		switchFlag("--all"), switchFlag("--force"),
+	}},
+	{name: "assign", summary: "Assign task to user", run: executeTaskAssign, args: completeTaskIDs},
+	{name: "audit", summary: "Show filtering audit trail", run: executeTaskAudit, args: completeTaskIDs},
+	{name: "explain", summary: "See why a task is shown or hidden", run: executeTaskExplain, args: completeTaskIDs},
+	{name: "history", summary: "Show who changed a task, and when", run: executeTaskHistory, args: completeTaskIDs, flags: []flag{
+		taskFlag("--id"),
+	}},
+	{name: "search", summary: "Search your tasks by text", run: executeTaskSearch},
+	{name: "reorder", summary: "Move a task within its list", run: executeTaskReorder, flags: []flag{
+		taskFlag("--id"), taskFlag("--after"),
+	}},
+	{name: "snooze", summary: "Hide a task until later", run: executeTaskSnooze, args: completeTaskIDs, flags: []flag{
+		taskFlag("--id"), valueFlag("--until", "when"), valueFlag("--for", "duration"),
+		choiceFlag("--preset", snoozePresetNames()...), switchFlag("--recurring"), switchFlag("--clear"),
+	}},
+	{name: "recur", summary: "Set, change or clear a task's recurrence", run: executeTaskRecur, flags: []flag{
+		taskFlag("--id"), valueFlag("--rule", "rrule"), valueFlag("--every", "period"), switchFlag("--clear"),
+	}},
+	{name: "dedupe", summary: "Find and merge likely duplicate tasks", run: executeTaskDedupe, flags: []flag{
+		switchFlag("--auto"),
+	}},
+	{name: "export", summary: "Export tasks for another task manager", run: executeTaskExport, flags: []flag{
+		choiceFlag("--format", "todoist", "markdown", "ics", "csv"), fileFlag("--output"), switchFlag("--scrub"),
+	}},
+	{name: "import", summary: "Import tasks from a file", run: executeTaskImport, args: completeFiles, flags: []flag{
+		choiceFlag("--from", "markdown", "csv"), switchFlag("--create-missing-locations"),
+	}},
+	{name: "link", summary: "Link related or duplicate tasks", run: executeTaskLink, subcommands: taskLinkCommands},
+	{name: "depend", summary: "Make a task depend on another", run: executeTaskDepend, args: completeTaskIDs, flags: []flag{
+		taskFlag("--on"), switchFlag("--soft"),
+	}},
+	{name: "undepend", summary: "Remove a task's dependency on another", run: executeTaskUndepend, args: completeTaskIDs, flags: []flag{
+		taskFlag("--on"),
+	}},
+	{name: "stats", summary: "Show completion trends", run: executeTaskStats, flags: []flag{
+		valueFlag("--since", "period"),
+	}},
+}
+
+var taskLinkFlags = []flag{
+	taskFlag("--id"), taskFlag("--related"), choiceFlag("--type", "related", "duplicate"), switchFlag("--cancel"),
+}
+
+var taskLinkCommands = []*command{
+	{name: "add", summary: "Link two tasks", flags: taskLinkFlags},
+	{name: "remove", summary: "Unlink two tasks", flags: taskLinkFlags},
+	{name: "list", summary: "List a task's linked tasks", flags: taskLinkFlags},
+}
+
+var locationCommands = []*command{
+	{name: "add", summary: "Create a new location", run: executeLocationAdd, flags: []flag{
+		valueFlag("--name", "name"), valueFlag("--lat", "latitude"), valueFlag("--lng", "longitude"),
+		valueFlag("--radius", "meters"), valueFlag("--category", "name"), valueFlag("--hours", "hours"),
+		switchFlag("--allow-custom-category"),
+	}},
+	{name: "list", summary: "List all locations", run: executeLocationList, flags: []flag{
+		valueFlag("--category", "name"),
+	}},
+	{name: "show", summary: "Show location details", run: executeLocationShow, args: completeLocations},
+	{name: "update", summary: "Update location information", run: executeLocationUpdate, args: completeLocations, flags: []flag{
+		valueFlag("--lat", "latitude"), valueFlag("--lng", "longitude"), valueFlag("--radius", "meters"),
+		valueFlag("--hours", "hours"), valueFlag("--category", "name"), switchFlag("--allow-custom-category"),
+	}},
+	{name: "delete", summary: "Delete a location", run: executeLocationDelete, args: completeLocations},
+	{name: "nearby", summary: "Find locations near current position", run: executeLocationNearby, flags: []flag{
+		valueFlag("--radius", "meters"),
+	}},
+}
+
+var contextPresetFlags = []flag{
+	switchFlag("--from-current"), locationFlag("--location"), valueFlag("--available-minutes", "n"),
+	valueFlag("--energy", "1-5"), choiceFlag("--social", socialContexts...),
+}
+
+var contextCommands = []*command{
+	{name: "show", summary: "Show current context", run: executeContextShow},
+	{name: "update", summary: "Update current context", run: executeContextUpdate, flags: []flag{
+		valueFlag("--lat", "latitude"), valueFlag("--lng", "longitude"), locationFlag("--location"),
+		valueFlag("--available-minutes", "n"), valueFlag("--available-points", "n"), valueFlag("--energy", "1-5"),
+		choiceFlag("--social", socialContexts...), valueFlag("--min-priority", "0-5"),
+	}},
+	{name: "suggestions", summary: "Get context-based suggestions", run: executeContextSuggestions},
+	{name: "estimate", summary: "Estimate time to location", run: executeContextEstimate, args: completeLocations},
+	{name: "preset", summary: "Save and apply named contexts", run: executeContextPreset, subcommands: []*command{
+		{name: "save", summary: "Save a preset", flags: contextPresetFlags},
+		{name: "apply", summary: "Start a new context from a preset"},
+		{name: "list", summary: "List your presets"},
+		{name: "delete", summary: "Delete a preset"},
+	}},
+}
+
+var listDefaultsFlags = []flag{
+	locationFlag("--location"), valueFlag("--default-minutes", "n"),
+}
+
+var listCommands = []*command{
+	{name: "create", summary: "Create a new task list", run: executeListCreate,
+		flags: append([]flag{switchFlag("--shared")}, listDefaultsFlags...)},
+	{name: "update", summary: "Change a list's default location and estimate", run: executeListUpdate,
+		flags: append([]flag{switchFlag("--no-location"), switchFlag("--apply-to-existing")}, listDefaultsFlags...)},
+	{name: "list", summary: "Show all task lists", run: func([]string) {
+		fmt.Println("Your Task Lists:")
+		// Implementation would go here
+		fmt.Println("No lists found")
+	}},
+	{name: "schedule", summary: "Show due tasks in each member's timezone", run: func(args []string) {
+		if len(args) < 1 {
+			fmt.Println("Error: list schedule requires list ID")
+			os.Exit(1)
+		}
+		executeListSchedule(args[0])
+	}},
+	{name: "archive", summary: "Hide a list you own without deleting it", run: func(args []string) { executeListArchive(args, true) }},
+	{name: "unarchive", summary: "Bring an archived list back", run: func(args []string) { executeListArchive(args, false) }},
+}
+
+var calendarCommands = []*command{
+	{name: "add", summary: "Add a calendar account", run: executeCalendarAdd, subcommands: []*command{
+		{name: "caldav", summary: "Add a CalDAV account", flags: []flag{
+			valueFlag("--url", "url"), valueFlag("--username", "name"), valueFlag("--password", "pass"),
+			valueFlag("--name", "name"),
+		}},
+		{name: "google", summary: "Add a Google Calendar", flags: []flag{
+			valueFlag("--client-id", "id"), valueFlag("--client-secret", "secret"),
+			valueFlag("--refresh-token", "token"), valueFlag("--calendar", "id"), valueFlag("--name", "name"),
+		}},
+	}},
+	{name: "sync", summary: "Sync all calendars", run: executeCalendarSync, flags: []flag{
+		valueFlag("--concurrency", "n"),
+	}},
+	{name: "list", summary: "List configured calendars", run: func([]string) { executeCalendarList() }},
+	{name: "remove", summary: "Remove calendar integration", run: executeCalendarRemove},
+	{name: "import", summary: "Import the events of an .ics file", run: executeCalendarImport, args: completeFiles, flags: []flag{
+		switchFlag("--as-tasks"),
+	}},
+}
+
+var adminCommands = []*command{
+	{name: "reassign", summary: "Move a user's open tasks to another user", run: executeAdminReassign, flags: []flag{
+		valueFlag("--from", "username"), valueFlag("--to", "username"), switchFlag("--transfer-ownership"),
+	}},
+}
+
+// findCommand returns the command called name or one of its aliases, or
+// nil
+func findCommand(commands []*command, name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// runSubcommand runs the subcommand args[0] names with the rest of args,
+// and reports false when there is none by that name
+func runSubcommand(subcommands []*command, args []string) bool {
+	cmd := findCommand(subcommands, args[0])
+	if cmd == nil || cmd.run == nil {
+		return false
+	}
+	cmd.run(args[1:])
+	return true
+}
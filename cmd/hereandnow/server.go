@@ -17,6 +17,7 @@ import (
 	"github.com/bcnelson/hereAndNow/internal/storage"
 	"github.com/bcnelson/hereAndNow/pkg/filters"
 	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
 	"github.com/gin-gonic/gin"
 )
 
@@ -34,6 +35,11 @@ DESCRIPTION:
 OPTIONS:
     --port <port>       Server port (default: from config, usually 8080)
     --host <host>       Server host (default: from config, usually 127.0.0.1)
+    --base-path <path>  Mount all routes under a path prefix, for reverse
+                        proxies serving the app from a subpath (e.g. /app)
+    --cleanup-interval <duration>
+                        How often background maintenance runs (e.g. 30m;
+                        default: maintenance.interval from config, or 1h)
     --daemon, -d        Run as daemon (background process)
     --dev               Development mode (verbose logging, auto-reload)
     --help, -h         Show this help
@@ -43,16 +49,67 @@ EXAMPLES:
     hereandnow serve --port 3000
     hereandnow serve --host 0.0.0.0 --port 8080
     hereandnow serve --daemon
+    hereandnow serve --base-path /app
+    hereandnow serve --cleanup-interval 15m
 
-ENDPOINTS:
+ENDPOINTS (relative to --base-path):
     GET  /health                    Health check
     POST /api/v1/auth/login         User authentication
     POST /api/v1/auth/logout        User logout
+    POST /api/v1/auth/2fa/login     Finish a login with a TOTP code
+    POST /api/v1/auth/2fa/enable    Start TOTP two-factor setup
+    POST /api/v1/auth/2fa/verify    Confirm a TOTP code to turn two-factor on
+    POST /api/v1/auth/2fa/disable   Turn two-factor off with a TOTP code
+    POST /api/v1/auth/forgot        Request a password reset token
+    POST /api/v1/auth/reset         Set a new password with a reset token
     GET  /api/v1/tasks              List filtered tasks
+    GET  /api/v1/tasks/export.ics   Filtered tasks as a calendar feed; takes a
+                                    feed token as ?token=
+    POST /api/v1/users/me/feeds     Create a calendar feed token
     POST /api/v1/tasks              Create task
+    POST /api/v1/tasks/:id/reorder  Move task within its list
     GET  /api/v1/users/me           Get current user
+    GET  /api/v1/users/me/stats     Get completion count and streak
     GET  /api/v1/context            Get current context
     POST /api/v1/context            Update context
+
+TWO-FACTOR AUTHENTICATION:
+    Set HEREANDNOW_TOTP_KEY to offer TOTP two-factor login. Users' TOTP
+    secrets are encrypted with it, so keep it stable across restarts.
+
+PASSWORD RESET:
+    Reset tokens requested with /auth/forgot are written to the server log
+    for an administrator to pass on. They work once, for an hour.
+
+BACKGROUND MAINTENANCE:
+    The server runs these housekeeping jobs at startup and then every
+    cleanup interval, logging what each one did:
+
+    archive_lists            Archive lists with no task activity for
+                             lists.auto_archive_days days and notify their
+                             owners (off while that is zero)
+    prune_contexts           Delete context history older than its
+                             retention_days (default: 30)
+    prune_sessions           Delete expired login sessions
+    expire_completion_undos  Forget shared-list completions that can no
+                             longer be undone
+    prune_revoked_tokens     Forget logged-out tokens once they have expired
+    purge_trash              Permanently remove tasks deleted more than its
+                             retention_days ago (default: 30)
+    prune_password_resets    Forget password reset tokens once they have
+                             expired
+    prune_task_history       Delete task edit history older than its
+                             retention_days (default: 180)
+
+    Disable or tune a job under maintenance.jobs in the config file:
+
+        maintenance:
+          interval: 1h
+          jobs:
+            prune_contexts:
+              retention_days: 90
+            prune_sessions:
+              disabled: true
 `)
 		return
 	}
@@ -71,8 +128,10 @@ func executeServe(args []string) {
 	// Parse command line arguments
 	port := config.Server.Port
 	host := config.Server.Host
+	basePath := config.Server.BasePath
 	daemon := false
 	devMode := false
+	cleanupInterval := config.Maintenance.Interval
 
 	for i, arg := range args {
 		switch arg {
@@ -86,6 +145,14 @@ func executeServe(args []string) {
 			if i+1 < len(args) {
 				host = args[i+1]
 			}
+		case "--base-path":
+			if i+1 < len(args) {
+				basePath = args[i+1]
+			}
+		case "--cleanup-interval":
+			if i+1 < len(args) {
+				cleanupInterval = args[i+1]
+			}
 		case "--daemon", "-d":
 			daemon = true
 		case "--dev":
@@ -125,17 +192,87 @@ func executeServe(args []string) {
 
 	// Initialize services
 	authService := auth.NewAuthService(userRepo)
+	authService.EnableDeviceTokens(storage.NewDeviceTokenRepository(db))
+	authService.EnableFeedTokens(storage.NewFeedTokenRepository(db))
+	authService.EnableTokenRevocation(storage.NewRevokedTokenRepository(db))
+	authService.EnablePasswordReset(storage.NewPasswordResetRepository(db))
+	if key := os.Getenv(totpKeyEnv); key != "" {
+		totpRepo, err := storage.NewTOTPRepository(db, key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		authService.EnableTwoFactor(totpRepo)
+	}
 	filterEngine := filters.NewFilterEngine()
+	filterEngine.EnableCategoryPreference(locationRepo, taskLocationRepo)
 	taskService := hereandnow.NewTaskService(taskRepo, contextRepo, dependencyRepo, taskLocationRepo, *filterEngine)
-	contextService := hereandnow.NewContextService(contextRepo, locationRepo, nil, nil, nil)
+	taskService.SetTransactor(storageTransactor{db: db})
+	taskService.SetUserRepository(userRepo)
+	basis, _ := hereandnow.ParseRecurrenceBasis(config.Recurrence.From)
+	taskService.SetRecurrenceBasis(basis)
+	taskService.EnableVisibilityEvents(storage.NewTaskVisibilityRepository(db), storage.NewNotificationRepository(db))
+	taskService.EnableImportLocations(locationRepo)
+	taskService.SetListRepository(storage.NewTaskListRepository(db))
+	taskService.SetListMemberRepository(storage.NewListMemberRepository(db))
+	eventHub := hereandnow.NewEventHub(storage.NewTaskListRepository(db), storage.NewListMemberRepository(db), 0)
+	taskService.SetEventPublisher(eventHub)
+	taskService.EnableTaskHistory(storage.NewTaskHistoryRepository(db))
+	listService := hereandnow.NewListService(storage.NewTaskListRepository(db), storage.NewListMemberRepository(db))
+	listService.SetEventPublisher(eventHub)
+	contextService := hereandnow.NewContextService(contextRepo, locationRepo, storage.NewCalendarEventRepository(db), nil, nil)
+	contextService.SetContextMaxAge(config.Context.MaxAge())
+	contextService.EnableGeofenceEvents(storage.NewGeofenceEventRepository(db))
+
+	// Start background maintenance
+	maintenanceConfig := config.Maintenance
+	maintenanceConfig.Interval = cleanupInterval
+	interval, err := maintenanceConfig.IntervalDuration()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	loop := maintenance.NewLoop(interval, maintenanceJobs(config, maintenanceConfig, db)...)
+	log.Printf("Maintenance runs every %s: %s", loop.Interval(), strings.Join(loop.Jobs(), ", "))
+	stopMaintenance := make(chan struct{})
+	go loop.Run(stopMaintenance)
+
+	// Push new notifications to users' webhook and ntfy channels
+	stopNotifications := make(chan struct{})
+	if !config.Notifications.DisableDelivery {
+		go config.Notifications.Dispatcher(db).Run(stopNotifications)
+	}
+
+	// Turn due task reminders into notifications. Each reminder is claimed
+	// before it is sent, so several servers can share the database.
+	stopReminders := make(chan struct{})
+	reminderService := hereandnow.NewReminderService(storage.NewReminderRepository(db), storage.NewTaskRepository(db), storage.NewNotificationRepository(db))
+	go reminderService.Run(hereandnow.DefaultReminderInterval, stopReminders)
 
 	// Initialize handlers
 	authHandler := api.NewAuthHandler(authService)
+	authHandler.SetPasswordResetDelivery(logPasswordResets{})
 	taskHandler := api.NewTaskHandler(taskService, authService)
+	taskHandler.SetLocationService(taskService)
+	taskHandler.SetImportService(taskService)
+	taskHandler.SetExplainService(taskService)
+	taskHandler.SetTrashService(taskService)
+	taskHandler.SetDependencyService(taskService)
+	taskHandler.SetBatchService(taskService)
+	taskHandler.SetHistoryService(taskService)
 	userHandler := api.NewUserHandler(userRepo, authService)
+	userHandler.SetStatsService(taskService)
+	userHandler.SetNotificationSettings(storage.NewNotificationSettingsRepository(db))
+	contextHandler := api.NewContextHandler(contextService)
+	contextHandler.SetLocationRecorder(contextService)
+	eventsHandler := api.NewEventsHandler(eventHub)
+	eventsHandler.EnableListStreams(listService, eventHub)
+	analyticsHandler := api.NewAnalyticsHandler(nil)
+	analyticsHandler.SetTaskAnalyticsService(storage.NewAnalyticsRepository(db))
 
 	// Setup router
-	router := setupRouter(authHandler, taskHandler, userHandler, authService)
+	basePath = api.NormalizeBasePath(basePath)
+	router := setupRouter(authHandler, taskHandler, userHandler, contextHandler, eventsHandler, analyticsHandler, authService, basePath, config.Server.ContextHeaders)
 
 	// Server configuration
 	server := &http.Server{
@@ -148,10 +285,10 @@ func executeServe(args []string) {
 
 	// Start server in goroutine
 	go func() {
-		fmt.Printf("🚀 Server starting on %s:%d\n", host, port)
+		fmt.Printf("🚀 Server starting on %s:%d%s\n", host, port, basePath)
 		if devMode {
-			fmt.Printf("📖 API Documentation: http://%s:%d/docs\n", host, port)
-			fmt.Printf("🏥 Health Check: http://%s:%d/health\n", host, port)
+			fmt.Printf("📖 API Documentation: http://%s:%d%s/docs\n", host, port, basePath)
+			fmt.Printf("🏥 Health Check: http://%s:%d%s/health\n", host, port, basePath)
 		}
 		
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -166,6 +303,9 @@ func executeServe(args []string) {
 	<-quit
 
 	fmt.Println("\n🛑 Server shutting down...")
+	close(stopMaintenance)
+	close(stopNotifications)
+	close(stopReminders)
 
 	// Create a deadline to wait for
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -180,7 +320,29 @@ func executeServe(args []string) {
 	fmt.Println("✅ Server shutdown complete")
 }
 
-func setupRouter(authHandler *api.AuthHandler, taskHandler *api.TaskHandler, userHandler *api.UserHandler, authService *auth.AuthService) *gin.Engine {
+// maintenanceJobs returns the housekeeping jobs the configuration enables
+func maintenanceJobs(config *Config, maintenanceConfig maintenance.Config, db *storage.DB) []maintenance.Job {
+	var jobs []maintenance.Job
+	if config.Lists.AutoArchiveDays > 0 {
+		inactiveAfter := time.Duration(config.Lists.AutoArchiveDays) * 24 * time.Hour
+		archiver := hereandnow.NewListArchiver(storage.NewTaskListRepository(db), storage.NewTaskRepository(db), storage.NewNotificationRepository(db), inactiveAfter)
+		jobs = append(jobs, maintenance.ArchiveListsJob(archiver))
+	}
+
+	jobs = append(jobs,
+		maintenance.PruneContextsJob(storage.NewContextRepository(db), maintenanceConfig.Retention(maintenance.JobPruneContexts, maintenance.DefaultContextRetention)),
+		maintenance.PruneSessionsJob(storage.NewSessionRepository(db)),
+		maintenance.ExpireCompletionUndosJob(storage.NewCompletionUndoRepository(db)),
+		maintenance.PruneRevokedTokensJob(storage.NewRevokedTokenRepository(db)),
+		maintenance.PurgeTrashJob(storage.NewTaskRepository(db), maintenanceConfig.Retention(maintenance.JobPurgeTrash, maintenance.DefaultTrashRetention)),
+		maintenance.PrunePasswordResetsJob(storage.NewPasswordResetRepository(db)),
+		maintenance.PruneTaskHistoryJob(storage.NewTaskHistoryRepository(db), maintenanceConfig.Retention(maintenance.JobPruneTaskHistory, maintenance.DefaultTaskHistoryRetention)),
+	)
+
+	return maintenanceConfig.Select(jobs...)
+}
+
+func setupRouter(authHandler *api.AuthHandler, taskHandler *api.TaskHandler, userHandler *api.UserHandler, contextHandler *api.ContextHandler, eventsHandler *api.EventsHandler, analyticsHandler *api.AnalyticsHandler, authService *auth.AuthService, basePath string, captureContext bool) *gin.Engine {
 	router := gin.New()
 
 	// Middleware
@@ -188,91 +350,19 @@ func setupRouter(authHandler *api.AuthHandler, taskHandler *api.TaskHandler, use
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().Format(time.RFC3339),
-			"service":   "hereandnow-api",
-			"version":   Version,
-		})
-	})
-
-	// API v1 routes
-	v1 := router.Group("/api/v1")
-	{
-		// Authentication routes (no auth required)
-		auth := v1.Group("/auth")
-		{
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/logout", authHandler.Logout)
-		}
-
-		// Protected routes (require authentication)
-		protected := v1.Group("/")
-		protected.Use(authMiddleware(authService))
-		{
-			// User routes
-			users := protected.Group("/users")
-			{
-				users.GET("/me", userHandler.GetCurrentUser)
-				users.PATCH("/me", userHandler.UpdateCurrentUser)
-			}
-
-			// Task routes
-			tasks := protected.Group("/tasks")
-			{
-				tasks.GET("", taskHandler.GetTasks)
-				tasks.POST("", taskHandler.CreateTask)
-				tasks.GET("/:taskId", taskHandler.GetTask)
-				tasks.PATCH("/:taskId", taskHandler.UpdateTask)
-				tasks.DELETE("/:taskId", taskHandler.DeleteTask)
-				tasks.POST("/:taskId/assign", taskHandler.AssignTask)
-				tasks.POST("/:taskId/complete", taskHandler.CompleteTask)
-				tasks.GET("/:taskId/audit", taskHandler.GetTaskAudit)
-			}
-
-			// Context routes (placeholder)
-			context := protected.Group("/context")
-			{
-				context.GET("", func(c *gin.Context) {
-					c.JSON(http.StatusNotImplemented, gin.H{
-						"error": "Context endpoints not yet implemented",
-					})
-				})
-				context.POST("", func(c *gin.Context) {
-					c.JSON(http.StatusNotImplemented, gin.H{
-						"error": "Context endpoints not yet implemented",
-					})
-				})
-			}
-
-			// Location routes (placeholder)
-			locations := protected.Group("/locations")
-			{
-				locations.GET("", func(c *gin.Context) {
-					c.JSON(http.StatusNotImplemented, gin.H{
-						"error": "Location endpoints not yet implemented",
-					})
-				})
-				locations.POST("", func(c *gin.Context) {
-					c.JSON(http.StatusNotImplemented, gin.H{
-						"error": "Location endpoints not yet implemented",
-					})
-				})
-			}
-		}
-	}
-
-	// Static documentation (if exists)
-	router.Static("/docs", "./docs")
-	
-	// 404 handler
-	router.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Endpoint not found",
-			"path":  c.Request.URL.Path,
-		})
+	api.SetupRoutes(router, api.Handlers{
+		Auth:           authHandler,
+		Tasks:          taskHandler,
+		Users:          userHandler,
+		Contexts:       contextHandler,
+		Events:         eventsHandler,
+		Analytics:      analyticsHandler,
+		AuthMiddleware: authMiddleware(authService),
+	}, api.RouteConfig{
+		BasePath:              basePath,
+		DocsDir:               "./docs",
+		Version:               Version,
+		CaptureContextHeaders: captureContext,
 	})
 
 	return router
@@ -283,7 +373,7 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Authorization, Content-Type")
+		c.Header("Access-Control-Allow-Headers", "Origin, Authorization, Content-Type, Last-Event-ID, X-Context-Lat, X-Context-Lng, X-Context-Energy")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -332,4 +422,13 @@ func authMiddleware(authService *auth.AuthService) gin.HandlerFunc {
 		c.Set("username", claims.Username)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// logPasswordResets delivers password reset tokens by writing them to the
+// server log, for an administrator to pass on
+type logPasswordResets struct{}
+
+func (logPasswordResets) DeliverPasswordReset(email, token string) error {
+	log.Printf("Password reset requested for %s; reset token: %s", email, token)
+	return nil
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bcnelson/hereAndNow/internal/api"
 	"github.com/bcnelson/hereAndNow/internal/auth"
 	"github.com/bcnelson/hereAndNow/internal/storage"
 	"github.com/bcnelson/hereAndNow/pkg/filters"
 	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/locale"
 	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
 )
 
 func handleTaskCommand(args []string) {
@@ -29,25 +39,127 @@ USAGE:
 
 SUBCOMMANDS:
     add <title>         Create a new task
-    list                List tasks (filtered by context)
+    list                List tasks (filtered by context, most relevant first)
     show <task-id>      Show task details
     update <task-id>    Update task information
+    bulk-edit           Set fields on every task matching --filter
     complete <task-id>  Mark task as complete
-    delete <task-id>    Delete a task
+    uncomplete <task-id>
+                        Take back a completion in a shared list while its
+                        undo window is open
+    delete <task-id>    Move a task to the trash
+    trash list          List deleted tasks that can still be restored
+    restore <task-id>   Bring back a deleted task (or by --id)
+    purge <task-id>     Permanently remove a deleted task, or every deleted
+                        task with --all; refused while other tasks depend on
+                        it unless --force
     assign <task-id>    Assign task to user
     audit <task-id>     Show filtering audit trail
-    search <query>      Search tasks by text
+    explain <task-id>   Check the task against every filter in your current
+                        context to see why it is shown or hidden
+    history <task-id>   Show who changed which fields of a task, and when
+                        (or by --id)
+    search <query>      Search your tasks and shared lists' tasks by text;
+                        a word ending in * matches as a prefix
+    reorder             Move a task within its list
+    snooze <task-id>    Hide a task until later, or --clear to show it again
+    recur               Set, change or clear a task's recurrence
+    dedupe              Find likely duplicate open tasks across your lists
+                        and merge them
+    export              Export tasks for another task manager
+    import <file>       Import tasks, reporting each record's outcome
+    link add|remove|list
+                        Link related or duplicate tasks
+    depend <task-id>    Make a task depend on another (--on); a dependency
+                        that would form a cycle is refused
+    undepend <task-id>  Remove a task's dependency on another (--on)
+    stats               Show completion trends by day, hour of day,
+                        priority and location
 
 OPTIONS:
     --all               Show all tasks (override context filtering)
     --status <status>   Filter by status (pending|in_progress|completed|blocked)
+    --search <query>    Show tasks matching text, with matches highlighted
+    --diff-context <changes>
+                        Dry run: show which tasks would appear or disappear
+                        with a changed context, e.g. "energy=2,minutes=30"
+    --min-priority <n>  Hide tasks below priority n for this listing
+                        (0 shows all; see context update --min-priority)
+    --sort <field>      Sort the listing by created_at, due_at, priority, title
+                        or position
+    --order <asc|desc>  Sort direction (default: asc; desc for priority)
+    --limit <n>         Show at most n tasks
+    --tag <tag>         Only tasks with the tag (list), or tag the new task
+                        (add); repeat for several, which a listing must all
+                        match
+    --any-tag           List tasks with any of the --tag tags instead (list)
     --priority <1-10>   Set task priority
     --estimate <mins>   Set estimated minutes
+    --points <n>        Set effort points (used when estimates.unit is points)
+    --energy <1-5>      Set the energy the task needs; it is hidden while
+                        your context's energy is lower
     --due <date>        Set due date (YYYY-MM-DD or YYYY-MM-DD HH:MM)
+    --remind <when>     Get a notification about the new task: "30m before
+                        due", "1d before due" or a time such as
+                        "2026-03-02 09:00"; repeat for several (add)
     --location <name>   Assign task to location
+    --on-exit           Remind when leaving the location instead of arriving
     --assignee <user>   Assign to user
     --depends-on <id>   Add task dependency
-    --list <name>       Add to task list
+    --list <name>       Add to task list (with list: show in manual order)
+    --id <task-id>      Task to complete (complete), move (reorder) or bring
+                        back (restore)
+    --force             Purge even if other tasks depend on it, dropping
+                        those dependencies (purge)
+    --after <task-id>   Place after this task; omit to move to top (reorder)
+    --until <when>      Snooze until a date, or for a duration (snooze)
+    --for <duration>    Snooze for a duration: 45m, 3h, 3d, 2w or 1d12h
+                        (snooze)
+    --preset <name>     Snooze preset: later-today, this-evening,
+                        tomorrow-morning, next-week, or one from config (snooze)
+    --recurring         Reapply the preset each time a recurring task is
+                        completed (snooze)
+    --rule <rrule>      Recurrence as an RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,WE,FR
+                        (recur)
+    --every <period>    Recurrence in words: "day", "2 weeks", "weekday",
+                        "monday and friday", "2 weeks on monday" (recur)
+    --clear             Stop the task recurring (recur), or wake a snoozed
+                        task (snooze)
+    --repeat <period>   Make the new task recur, in the same words as
+                        --every; completing it creates the next instance
+                        (add)
+    --from-file <path>  Create every task in a JSON array, all or none; takes
+                        the same fields as the API (add)
+    --outdoor           Mark the task as outdoor, hidden in the weather
+                        listed under weather.hide_conditions (add)
+    --instances         Also delete the open instances created from a
+                        recurring task (delete)
+    --auto              Merge every suggested duplicate without asking
+                        (dedupe)
+    --format <format>   Export format: todoist, markdown, ics or csv (export)
+    --from <format>     Import format: markdown or csv, which also reads
+                        csv exports and Todoist CSV exports (import, default
+                        from the file extension)
+    --create-missing-locations
+                        Create locations the file names that do not exist
+                        yet, from its latitude and longitude columns (import)
+    --output <path>     Write export to a file instead of stdout (export)
+    --filter <terms>    Tasks to change, as key=value terms: list (name or
+                        ID), status, priority, text (bulk-edit)
+    --related <task-id> Task to link to or unlink from (link)
+    --type <type>       Link type: related or duplicate, where --id is the
+                        duplicate of --related (link add, default related)
+    --cancel            Cancel the duplicate when linking it (link add)
+    --on <task-id>      Task to depend on or stop depending on (depend,
+                        undepend)
+    --soft              Only suggest doing the other task first instead of
+                        hiding the task until it is done (depend)
+    --since <period>    How far back to look: 30d, 2w or 12h (stats,
+                        default 30d)
+    --scrub             Strip private fields for sharing: drops creators,
+                        assignees and descriptions of tasks with
+                        "private": true metadata, and rounds location
+                        coordinates to about 1 km (export)
     --help, -h          Show this help
 
 EXAMPLES:
@@ -57,6 +169,26 @@ EXAMPLES:
     # Add task with location and time estimate
     hereandnow task add "Review reports" --location Office --estimate 60
 
+    # Only show a task when you have the energy for it
+    hereandnow task add "Write quarterly plan" --energy 4
+
+    # Add a whole checklist at once
+    hereandnow task add --from-file tasks.json
+
+    # Hide a task while it rains
+    hereandnow task add "Mow the lawn" --outdoor
+
+    # Get reminded an hour before it is due, and the evening before
+    hereandnow task add "Submit expenses" --due "2026-03-02 17:00" \
+        --remind "1h before due" --remind "2026-03-01 20:00"
+
+    # Get reminded on the way out
+    hereandnow task add "Take out the trash" --location Home --on-exit
+
+    # Tag a task, then list everything tagged errands
+    hereandnow task add "Pick up dry cleaning" --tag errands --tag urgent
+    hereandnow task list --all --tag errands
+
     # Add task with dependency
     hereandnow task add "Send report" --depends-on draft-123 --priority 8
 
@@ -66,51 +198,120 @@ EXAMPLES:
     # List ALL tasks
     hereandnow task list --all
 
+    # Stream tasks one JSON object per line into jq
+    hereandnow task list --format ndjson | jq 'select(.priority > 3)'
+
     # List only pending tasks
     hereandnow task list --status pending
 
     # Complete a task
     hereandnow task complete abc123
 
+    # Take it back before the shared list's undo window closes
+    hereandnow task uncomplete abc123
+
     # Show task audit trail
     hereandnow task audit abc123
 
     # Search tasks
     hereandnow task search "grocery"
+    hereandnow task search "groc*"
+    hereandnow task list --search groceries
+
+    # See what lower energy would hide
+    hereandnow task list --diff-context "energy=2"
+
+    # Only the important things right now
+    hereandnow task list --min-priority 4
+
+    # The five tasks due soonest
+    hereandnow task list --all --sort due_at --limit 5
+
+    # Move a task directly after another in its list
+    hereandnow task reorder --id abc123 --after def456
+
+    # Snooze a task until tomorrow morning in your timezone
+    hereandnow task snooze --id abc123 --preset tomorrow-morning
+
+    # Snooze until a date, or for three days
+    hereandnow task snooze abc123 --until "2024-07-02"
+    hereandnow task snooze abc123 --for 3d
+
+    # Show a snoozed task again
+    hereandnow task snooze abc123 --clear
+
+    # Review duplicate errands across shared lists one by one
+    hereandnow task dedupe
+
+    # Add a task that comes back every Monday
+    hereandnow task add "Take out the bins" --due "2025-01-06 19:00" --repeat "every monday"
+
+    # Repeat a task every Monday, Wednesday and Friday
+    hereandnow task recur --id abc123 --rule "FREQ=WEEKLY;BYDAY=MO,WE,FR"
+    hereandnow task recur --id abc123 --every "mon, wed and fri"
+
+    # Export all tasks in Todoist's import format
+    hereandnow task export --format todoist --output tasks.json
+
+    # Export a shareable Markdown checklist without private details
+    hereandnow task export --format markdown --scrub --output tasks.md
+    hereandnow task export --format ics --output tasks.ics
+
+    # Import a Markdown checklist and see which lines failed
+    hereandnow task import tasks.md
+
+    # Move tasks to another database as a spreadsheet and back
+    hereandnow task export --format csv --output tasks.csv
+    hereandnow task import tasks.csv
+
+    # Import a Todoist CSV export, with a JSON report of every row
+    hereandnow task import todoist.csv --format json
+
+    # Raise every pending Work task to priority 4, due February 1st
+    hereandnow task bulk-edit --filter "list=Work status=pending" --priority 4 --due 2025-02-01
+
+    # Find out why a task is not showing up
+    hereandnow task explain abc123
+
+    # See who completed a shared task
+    hereandnow task history --id abc123
+
+    # Bring back a task deleted by mistake
+    hereandnow task trash list
+    hereandnow task restore abc123
+
+    # Empty the trash now instead of waiting for maintenance.jobs.purge_trash
+    hereandnow task purge --all
+
+    # Mark a task as a duplicate of another and cancel it
+    hereandnow task link add --id abc123 --related def456 --type duplicate --cancel
+
+    # Wait for the draft before sending, and suggest booking the room first
+    hereandnow task depend send-456 --on draft-123
+    hereandnow task depend send-456 --on room-789 --soft
+    hereandnow task undepend send-456 --on room-789
+
+    # See how the last month went
+    hereandnow task stats --since 30d
 `)
 		return
 	}
 
-	subcommand := args[0]
-	subArgs := args[1:]
-
-	switch subcommand {
-	case "add":
-		executeTaskAdd(subArgs)
-	case "list":
-		executeTaskList(subArgs)
-	case "show":
-		executeTaskShow(subArgs)
-	case "update":
-		executeTaskUpdate(subArgs)
-	case "complete":
-		executeTaskComplete(subArgs)
-	case "delete":
-		executeTaskDelete(subArgs)
-	case "assign":
-		executeTaskAssign(subArgs)
-	case "audit":
-		executeTaskAudit(subArgs)
-	case "search":
-		executeTaskSearch(subArgs)
-	default:
-		fmt.Printf("Unknown task subcommand: %s\n", subcommand)
+	if !runSubcommand(taskCommands, args) {
+		fmt.Printf("Unknown task subcommand: %s\n", args[0])
 		fmt.Println("Run 'hereandnow task --help' for usage")
 		os.Exit(1)
 	}
 }
 
 func executeTaskAdd(args []string) {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--from-file" {
+			executeTaskAddFromFile(args[i+1])
+			return
+		}
+	}
+
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: task add requires title\n")
 		fmt.Println("Usage: hereandnow task add <title> [OPTIONS]")
@@ -120,12 +321,19 @@ func executeTaskAdd(args []string) {
 	title := args[0]
 	priority := 3
 	estimate := (*int)(nil)
+	points := (*int)(nil)
+	energy := (*int)(nil)
 	dueDate := (*time.Time)(nil)
 	location := ""
+	locationTrigger := models.LocationTriggerEnter
 	assignee := ""
 	dependsOn := ""
 	listName := ""
 	description := ""
+	repeat := ""
+	outdoor := false
+	var tags []string
+	var reminders []string
 
 	for i := 1; i < len(args); i++ {
 		switch args[i] {
@@ -143,6 +351,20 @@ func executeTaskAdd(args []string) {
 					i++
 				}
 			}
+		case "--points":
+			if i+1 < len(args) {
+				if p, err := strconv.Atoi(args[i+1]); err == nil {
+					points = &p
+					i++
+				}
+			}
+		case "--energy":
+			if i+1 < len(args) {
+				if e, err := strconv.Atoi(args[i+1]); err == nil && e >= 1 && e <= 5 {
+					energy = &e
+					i++
+				}
+			}
 		case "--due":
 			if i+1 < len(args) {
 				if due, err := parseDateTime(args[i+1]); err == nil {
@@ -155,6 +377,8 @@ func executeTaskAdd(args []string) {
 				location = args[i+1]
 				i++
 			}
+		case "--on-exit":
+			locationTrigger = models.LocationTriggerExit
 		case "--assignee":
 			if i+1 < len(args) {
 				assignee = args[i+1]
@@ -175,7 +399,46 @@ func executeTaskAdd(args []string) {
 				description = args[i+1]
 				i++
 			}
+		case "--repeat":
+			if i+1 < len(args) {
+				repeat = args[i+1]
+				i++
+			}
+		case "--outdoor":
+			outdoor = true
+		case "--tag":
+			if i+1 < len(args) {
+				tags = append(tags, args[i+1])
+				i++
+			}
+		case "--remind":
+			if i+1 < len(args) {
+				reminders = append(reminders, args[i+1])
+				i++
+			}
+		}
+	}
+
+	// Absolute reminder times are read in UTC, like --due
+	var remindAt []time.Time
+	for _, spec := range reminders {
+		at, err := models.ParseRemindAt(spec, dueDate, time.UTC)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --remind: %v\n", err)
+			os.Exit(1)
+		}
+		remindAt = append(remindAt, at)
+	}
+
+	var recurrenceRule *string
+	if repeat != "" {
+		rule, err := recurrence.ParseEvery(repeat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --repeat: %v\n", err)
+			os.Exit(1)
 		}
+		rrule := rule.String()
+		recurrenceRule = &rrule
 	}
 
 	// Get current user (placeholder - would need session management)
@@ -197,7 +460,7 @@ func executeTaskAdd(args []string) {
 	if dependsOn != "" {
 		dependencies = append(dependencies, hereandnow.TaskDependencyRequest{
 			DependsOnTaskID: dependsOn,
-			DependencyType:  models.DependencyTypeBlocks,
+			DependencyType:  models.DependencyTypeBlocking,
 		})
 	}
 
@@ -230,9 +493,27 @@ func executeTaskAdd(args []string) {
 		AssigneeID:       assigneeID,
 		Priority:         priority,
 		EstimatedMinutes: estimate,
+		EffortPoints:     points,
+		RequiredEnergyLevel: energy,
 		DueAt:            dueDate,
+		RecurrenceRule:   recurrenceRule,
 		LocationIDs:      locationIDs,
+		LocationTrigger:  locationTrigger,
 		Dependencies:     dependencies,
+		Tags:             tags,
+	}
+
+	if outdoor {
+		req.Metadata = []byte(`{"` + filters.OutdoorKey + `": true}`)
+	}
+
+	preview, err := taskService.PreviewCreateTask(userID, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating task: %v\n", err)
+		os.Exit(1)
+	}
+	if dryRun("create task: %s (priority %d)", preview.Title, preview.Priority) {
+		return
 	}
 
 	task, err := taskService.CreateTask(userID, req)
@@ -241,25 +522,153 @@ func executeTaskAdd(args []string) {
 		os.Exit(1)
 	}
 
+	if len(remindAt) > 0 {
+		reminderService, err := initReminderService()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing reminder service: %v\n", err)
+			os.Exit(1)
+		}
+		for _, at := range remindAt {
+			if _, err := reminderService.AddReminder(userID, task.ID, at); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: reminder not set: %v\n", err)
+			}
+		}
+	}
+
 	formatter := NewFormatter(globalConfig.Format)
 	Output(formatter, fmt.Sprintf("Task created successfully: %s (ID: %s)", task.Title, task.ID))
 }
 
+// executeTaskAddFromFile creates every task in a JSON file, all or none.
+// The file holds the array POST /api/v1/tasks/batch takes.
+func executeTaskAddFromFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	var tasks []api.TaskCreateRequest
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s must hold a JSON array of tasks: %v\n", path, err)
+		os.Exit(1)
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s has no tasks\n", path)
+		os.Exit(1)
+	}
+
+	reqs := make([]hereandnow.CreateTaskRequest, len(tasks))
+	for i, task := range tasks {
+		reqs[i] = task.ServiceRequest()
+		if err := reqs[i].Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: task %d in %s: %v\n", i+1, path, err)
+			os.Exit(1)
+		}
+	}
+	if dryRun("create %d tasks from %s", len(reqs), path) {
+		return
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user. Please create a user first.\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	created, err := taskService.CreateTasks(userID, reqs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating tasks, none were created: %v\n", err)
+		os.Exit(1)
+	}
+
+	Output(NewFormatter(globalConfig.Format), created)
+}
+
 func executeTaskList(args []string) {
 	showAll := false
 	status := ""
+	listID := ""
+	search := ""
+	diffContext := ""
+	minPriority := -1
+	limit := 0
+	sortBy := ""
+	sortOrder := ""
+	var tags []string
+	anyTag := false
 
 	for i, arg := range args {
 		switch arg {
 		case "--all":
 			showAll = true
+		case "--limit":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: --limit must be a positive number\n")
+					os.Exit(1)
+				}
+				limit = n
+			}
+		case "--sort":
+			if i+1 < len(args) {
+				sortBy = args[i+1]
+			}
+		case "--order":
+			if i+1 < len(args) {
+				sortOrder = args[i+1]
+			}
 		case "--status":
 			if i+1 < len(args) {
 				status = args[i+1]
 			}
+		case "--list":
+			if i+1 < len(args) {
+				listID = args[i+1]
+			}
+		case "--search":
+			if i+1 < len(args) {
+				search = args[i+1]
+			}
+		case "--diff-context":
+			if i+1 < len(args) {
+				diffContext = args[i+1]
+			}
+		case "--tag":
+			if i+1 < len(args) {
+				tags = append(tags, args[i+1])
+			}
+		case "--any-tag":
+			anyTag = true
+		case "--min-priority":
+			if i+1 < len(args) {
+				p, err := strconv.Atoi(args[i+1])
+				if err != nil || p < 0 || p > 5 {
+					fmt.Fprintf(os.Stderr, "Error: --min-priority must be between 0 and 5\n")
+					os.Exit(1)
+				}
+				minPriority = p
+			}
 		}
 	}
 
+	order, err := api.ParseTaskOrder(sortBy, sortOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := models.NormalizeTags(tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --tag: %v\n", err)
+		os.Exit(1)
+	}
+
 	userID := getCurrentUserID()
 	if userID == "" {
 		fmt.Fprintf(os.Stderr, "Error: No current user\n")
@@ -272,9 +681,32 @@ func executeTaskList(args []string) {
 		os.Exit(1)
 	}
 
+	if diffContext != "" {
+		diff, err := taskService.DiffContext(userID, diffContext)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing contexts: %v\n", err)
+			os.Exit(1)
+		}
+		printContextDiff(*diff, diffContext)
+		return
+	}
+
 	var tasks []models.Task
 
-	if status != "" {
+	if search != "" {
+		tasks, err = taskService.SearchTasks(userID, search)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error searching tasks: %v\n", err)
+			os.Exit(1)
+		}
+	} else if listID != "" {
+		// Show list in manual order
+		tasks, err = taskService.GetTasksByList(listID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error retrieving list tasks: %v\n", err)
+			os.Exit(1)
+		}
+	} else if status != "" {
 		// Filter by status
 		taskStatus := models.TaskStatus(status)
 		tasks, err = taskService.GetTasksByStatus(userID, taskStatus)
@@ -293,19 +725,77 @@ func executeTaskList(args []string) {
 			fmt.Fprintf(os.Stderr, "Error retrieving tasks: %v\n", err)
 			os.Exit(1)
 		}
+	} else if minPriority >= 0 {
+		// Show context-filtered tasks with the minimum priority overridden
+		tasks, _, err = taskService.GetFilteredTasksWithMinPriority(userID, minPriority)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error retrieving filtered tasks: %v\n", err)
+			os.Exit(1)
+		}
 	} else {
-		// Show context-filtered tasks
-		tasks, _, err = taskService.GetFilteredTasks(userID)
+		// Show context-filtered tasks, most relevant first
+		scored, err := taskService.GetScoredTasks(userID)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error retrieving filtered tasks: %v\n", err)
 			os.Exit(1)
 		}
+		tasks = filters.VisibleScoredTasks(scored)
 	}
 
-	formatter := NewFormatter(globalConfig.Format)
+	// Tags narrow whichever listing was chosen, so they combine with
+	// --status, --list and --search
+	if len(tags) > 0 {
+		tagged := tasks[:0]
+		for _, task := range tasks {
+			if task.HasTags(tags, anyTag) {
+				tagged = append(tagged, task)
+			}
+		}
+		tasks = tagged
+	}
+
+	// Without --sort each listing keeps its own order, e.g. relevance
+	if sortBy != "" || sortOrder != "" {
+		api.SortTasks(tasks, order)
+	}
+	if limit > 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+
+	formatter := NewSearchFormatter(globalConfig.Format, search)
 	Output(formatter, tasks)
 }
 
+// printContextDiff shows which tasks a context change would reveal or hide
+func printContextDiff(diff filters.ContextDiff, changes string) {
+	formatter := NewFormatter(globalConfig.Format)
+	if isJSONFormat(globalConfig.Format) {
+		Output(formatter, diff)
+		return
+	}
+
+	fmt.Printf("Visibility with %s compared to your current context:\n\n", changes)
+
+	printChanges := func(label string, changes []filters.TaskVisibilityChange) {
+		fmt.Printf("%s (%d):\n", label, len(changes))
+		for _, change := range changes {
+			fmt.Printf("  %s  %s\n", truncateString(change.Task.ID, 8), change.Task.Title)
+			for _, reason := range change.Reasons {
+				if reason.Code != "" {
+					fmt.Printf("      %s [%s]: %s\n", reason.FilterName, reason.Code, reason.Reason)
+				} else {
+					fmt.Printf("      %s: %s\n", reason.FilterName, reason.Reason)
+				}
+			}
+		}
+		fmt.Println()
+	}
+
+	printChanges("Would disappear", diff.Disappeared)
+	printChanges("Would appear", diff.Appeared)
+	fmt.Printf("Unchanged: %d visible task(s)\n", len(diff.Unchanged))
+}
+
 func executeTaskShow(args []string) {
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: task show requires task ID\n")
@@ -329,16 +819,47 @@ func executeTaskShow(args []string) {
 
 	formatter := NewFormatter(globalConfig.Format)
 	Output(formatter, *task)
+
+	if !isJSONFormat(globalConfig.Format) {
+		if locations, err := taskService.GetTaskLocations(taskID); err == nil && len(locations) > 0 {
+			inherited := hereandnow.TaskInheritedDefaults(*task).LocationID
+			fmt.Println("\nLocations:")
+			for _, location := range locations {
+				note := ""
+				if location.ID == inherited {
+					note = fromListNote
+				}
+				fmt.Printf("  %s%s\n", location.Name, note)
+			}
+		}
+		if linked, err := taskService.GetLinkedTasks(taskID); err == nil && len(linked) > 0 {
+			fmt.Println("\nLinked tasks:")
+			printLinkedTasks(linked)
+		}
+		if dependencies, err := taskService.GetTaskDependencies(taskID); err == nil && len(dependencies) > 0 {
+			printTaskDependencies("Blocking dependencies", dependencies, models.DependencyTypeBlocking)
+			printTaskDependencies("Suggested dependencies", dependencies, models.DependencyTypeSuggested)
+		}
+	}
 }
 
 func executeTaskComplete(args []string) {
-	if len(args) == 0 {
+	taskID := ""
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--id" && i+1 < len(args) {
+			taskID = args[i+1]
+			i++
+		} else if !strings.HasPrefix(args[i], "--") && taskID == "" {
+			taskID = args[i]
+		}
+	}
+
+	if taskID == "" {
 		fmt.Fprintf(os.Stderr, "Error: task complete requires task ID\n")
 		fmt.Println("Usage: hereandnow task complete <task-id>")
 		os.Exit(1)
 	}
 
-	taskID := args[0]
 	userID := getCurrentUserID()
 	if userID == "" {
 		fmt.Fprintf(os.Stderr, "Error: No current user\n")
@@ -351,6 +872,15 @@ func executeTaskComplete(args []string) {
 		os.Exit(1)
 	}
 
+	existing, err := taskService.GetTask(taskID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error completing task: %v\n", err)
+		os.Exit(1)
+	}
+	if dryRun("complete task: %s", existing.Title) {
+		return
+	}
+
 	task, err := taskService.CompleteTask(taskID, userID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error completing task: %v\n", err)
@@ -361,6 +891,94 @@ func executeTaskComplete(args []string) {
 	Output(formatter, fmt.Sprintf("Task completed: %s", task.Title))
 }
 
+func executeTaskUncomplete(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: task uncomplete requires task ID\n")
+		fmt.Println("Usage: hereandnow task uncomplete <task-id>")
+		os.Exit(1)
+	}
+
+	taskID := args[0]
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	existing, err := taskService.GetTask(taskID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error undoing completion: %v\n", err)
+		os.Exit(1)
+	}
+	if dryRun("undo completion of task: %s", existing.Title) {
+		return
+	}
+
+	task, err := taskService.UndoSharedCompletion(taskID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error undoing completion: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, fmt.Sprintf("Completion undone: %s is %s again", task.Title, task.Status))
+}
+
+func handleUndoCommand(args []string) {
+	if len(args) > 0 && (args[0] == "--help" || args[0] == "-h") {
+		fmt.Printf(`Undo the Last Task Action
+
+USAGE:
+    hereandnow undo
+
+DESCRIPTION:
+    Reverses your most recent task complete, delete or snooze. A completed
+    task goes back to its previous status, a deleted task is restored with its
+    locations and dependencies, and a snooze is cleared.
+
+    Only the last action is remembered, and it can be undone once. Other
+    changes, such as edits, cannot be undone.
+`)
+		return
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	action, err := taskService.Undo(userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error undoing last action: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if action == nil {
+		Output(formatter, "Nothing to undo")
+		return
+	}
+
+	title := action.TaskID
+	if snapshot, err := action.GetSnapshot(); err == nil {
+		title = snapshot.Task.Title
+	}
+	Output(formatter, fmt.Sprintf("Undid %s: %s", action.Type, title))
+}
+
 func executeTaskUpdate(args []string) {
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: task update requires task ID\n")
@@ -370,7 +988,7 @@ func executeTaskUpdate(args []string) {
 
 	taskID := args[0]
 	var title, description *string
-	var priority, estimate *int
+	var priority, estimate, points, energy *int
 	var dueDate *time.Time
 	var status *models.TaskStatus
 
@@ -400,121 +1018,1364 @@ func executeTaskUpdate(args []string) {
 					i++
 				}
 			}
-		case "--due":
+		case "--points":
 			if i+1 < len(args) {
-				if due, err := parseDateTime(args[i+1]); err == nil {
+				if p, err := strconv.Atoi(args[i+1]); err == nil {
+					points = &p
+					i++
+				}
+			}
+		case "--energy":
+			if i+1 < len(args) {
+				if e, err := strconv.Atoi(args[i+1]); err == nil && e >= 1 && e <= 5 {
+					energy = &e
+					i++
+				}
+			}
+		case "--due":
+			if i+1 < len(args) {
+				if due, err := parseDateTime(args[i+1]); err == nil {
 					dueDate = &due
 					i++
 				}
 			}
-		case "--status":
-			if i+1 < len(args) {
-				s := models.TaskStatus(args[i+1])
-				status = &s
-				i++
+		case "--status":
+			if i+1 < len(args) {
+				s := models.TaskStatus(args[i+1])
+				status = &s
+				i++
+			}
+		}
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	req := hereandnow.UpdateTaskRequest{
+		ActorID:          getCurrentUserID(),
+		Title:            title,
+		Description:      description,
+		Priority:         priority,
+		EstimatedMinutes: estimate,
+		EffortPoints:     points,
+		RequiredEnergyLevel: energy,
+		DueAt:            dueDate,
+		Status:           status,
+	}
+
+	task, err := updateLatestTask(taskService, taskID, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating task: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, fmt.Sprintf("Task updated: %s", task.Title))
+}
+
+// updateTaskAttempts is how many times updateLatestTask reads and updates a
+// task before giving up on a task that keeps changing
+const updateTaskAttempts = 2
+
+// updateLatestTask applies req to the version of the task it has just read.
+// When someone else saves the task in between, it warns and applies req
+// again to the newer version.
+func updateLatestTask(taskService *hereandnow.TaskService, taskID string, req hereandnow.UpdateTaskRequest) (*models.Task, error) {
+	var err error
+	for attempt := 1; attempt <= updateTaskAttempts; attempt++ {
+		current, getErr := taskService.GetTask(taskID)
+		if getErr != nil {
+			return nil, getErr
+		}
+		req.Version = &current.Version
+
+		var task *models.Task
+		task, err = taskService.UpdateTask(taskID, req)
+		if !errors.Is(err, models.ErrTaskVersionConflict) {
+			return task, err
+		}
+		if attempt < updateTaskAttempts {
+			fmt.Fprintf(os.Stderr, "Warning: task %s was changed by someone else while updating; applying your changes to the latest version\n", taskID)
+		}
+	}
+	return nil, err
+}
+
+func executeTaskBulkEdit(args []string) {
+	filter := ""
+	var req hereandnow.BulkEditRequest
+
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			break
+		}
+		switch args[i] {
+		case "--filter":
+			filter = args[i+1]
+			i++
+		case "--priority":
+			p, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --priority: %s\n", args[i+1])
+				os.Exit(1)
+			}
+			req.Priority = &p
+			i++
+		case "--estimate":
+			e, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --estimate: %s\n", args[i+1])
+				os.Exit(1)
+			}
+			req.EstimatedMinutes = &e
+			i++
+		case "--due":
+			due, err := parseDateTime(args[i+1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --due: %v\n", err)
+				os.Exit(1)
+			}
+			req.DueAt = &due
+			i++
+		}
+	}
+
+	if filter == "" || req.IsEmpty() {
+		fmt.Fprintf(os.Stderr, "Error: task bulk-edit requires --filter and at least one of --priority, --estimate or --due\n")
+		fmt.Println(`Usage: hereandnow task bulk-edit --filter "list=Work status=pending" [--priority n] [--estimate mins] [--due date]`)
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	sel, err := hereandnow.ParseTaskSelector(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --filter: %v\n", err)
+		os.Exit(1)
+	}
+	if sel.ListID != "" {
+		if sel.ListID, err = findListByName(sel.ListID, userID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	preview, err := taskService.PreviewBulkEdit(userID, sel, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error editing tasks: %v\n", err)
+		os.Exit(1)
+	}
+	if dryRun("edit %d task(s) matching %q", len(preview), filter) {
+		return
+	}
+
+	tasks, err := taskService.BulkEditTasks(userID, sel, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error editing tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, fmt.Sprintf("Updated %d task(s) successfully", len(tasks)))
+}
+
+func executeTaskDelete(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: task delete requires task ID\n")
+		fmt.Println("Usage: hereandnow task delete <task-id> [--instances]")
+		os.Exit(1)
+	}
+
+	taskID := args[0]
+	withInstances := false
+	for _, arg := range args[1:] {
+		if arg == "--instances" {
+			withInstances = true
+		}
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	existing, err := taskService.GetTask(taskID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error deleting task: %v\n", err)
+		os.Exit(1)
+	}
+	if withInstances {
+		if dryRun("delete task and its open instances: %s", existing.Title) {
+			return
+		}
+	} else if dryRun("delete task: %s", existing.Title) {
+		return
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if withInstances {
+		deleted, err := taskService.DeleteTaskWithInstances(taskID, userID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting task: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Task deleted successfully, with %d open instance(s)", deleted))
+		return
+	}
+
+	if err := taskService.DeleteTask(taskID, userID); err != nil {
+		fmt.Fprintf(os.Stderr, "Error deleting task: %v\n", err)
+		os.Exit(1)
+	}
+
+	Output(formatter, "Task deleted successfully")
+}
+
+func executeTaskTrash(args []string) {
+	if len(args) > 0 && args[0] != "list" {
+		fmt.Fprintf(os.Stderr, "Error: unknown trash subcommand: %s\n", args[0])
+		fmt.Println("Usage: hereandnow task trash list")
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	tasks, err := taskService.ListTrash(userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing deleted tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if len(tasks) == 0 {
+		Output(formatter, "Trash is empty")
+		return
+	}
+	Output(formatter, tasks)
+}
+
+func executeTaskRestore(args []string) {
+	taskID := ""
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--id" && i+1 < len(args) {
+			taskID = args[i+1]
+			i++
+		} else if !strings.HasPrefix(args[i], "--") && taskID == "" {
+			taskID = args[i]
+		}
+	}
+
+	if taskID == "" {
+		fmt.Fprintf(os.Stderr, "Error: task restore requires task ID\n")
+		fmt.Println("Usage: hereandnow task restore <task-id>")
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	if dryRun("restore task: %s", taskID) {
+		return
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	task, err := taskService.RestoreTask(taskID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring task: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, fmt.Sprintf("Task restored: %s", task.Title))
+}
+
+func executeTaskPurge(args []string) {
+	taskID := ""
+	all := false
+	force := false
+	for _, arg := range args {
+		switch arg {
+		case "--all":
+			all = true
+		case "--force":
+			force = true
+		default:
+			if !strings.HasPrefix(arg, "--") && taskID == "" {
+				taskID = arg
+			}
+		}
+	}
+
+	if taskID == "" && !all {
+		fmt.Fprintf(os.Stderr, "Error: task purge requires task ID or --all\n")
+		fmt.Println("Usage: hereandnow task purge <task-id>|--all [--force]")
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	target := "deleted task: " + taskID
+	if all {
+		target = "all deleted tasks"
+	}
+	if dryRun("permanently remove %s", target) {
+		return
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if all {
+		purged, err := taskService.EmptyTrash(userID, force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error purging tasks after %d purged: %v\n", purged, err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Purged %d deleted task(s)", purged))
+		return
+	}
+
+	if err := taskService.PurgeTask(taskID, userID, force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error purging task: %v\n", err)
+		os.Exit(1)
+	}
+
+	Output(formatter, "Task purged")
+}
+
+func executeTaskAssign(args []string) {
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "Error: task assign requires task ID and username\n")
+		fmt.Println("Usage: hereandnow task assign <task-id> <username>")
+		os.Exit(1)
+	}
+
+	taskID := args[0]
+	username := args[1]
+	
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	assigneeID, err := findUserByUsername(username)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: User '%s' not found\n", username)
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	task, err := taskService.AssignTask(taskID, assigneeID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error assigning task: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, fmt.Sprintf("Task assigned to %s: %s", username, task.Title))
+}
+
+func executeTaskAudit(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: task audit requires task ID\n")
+		fmt.Println("Usage: hereandnow task audit <task-id>")
+		os.Exit(1)
+	}
+
+	taskID := args[0]
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	explanation, err := taskService.ExplainTaskVisibility(taskID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting task audit: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	Output(formatter, *explanation)
+}
+
+func executeTaskExplain(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: task explain requires task ID\n")
+		fmt.Println("Usage: hereandnow task explain <task-id>")
+		os.Exit(1)
+	}
+
+	taskID := args[0]
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	explanation, err := taskService.ExplainTaskVisibility(taskID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error explaining task: %v\n", err)
+		os.Exit(1)
+	}
+
+	printTaskExplanation(*explanation)
+}
+
+func executeTaskHistory(args []string) {
+	taskID := ""
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--id" && i+1 < len(args) {
+			taskID = args[i+1]
+			i++
+		} else if !strings.HasPrefix(args[i], "--") && taskID == "" {
+			taskID = args[i]
+		}
+	}
+
+	if taskID == "" {
+		fmt.Fprintf(os.Stderr, "Error: task history requires task ID\n")
+		fmt.Println("Usage: hereandnow task history --id <task-id>")
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	history, err := taskService.GetTaskHistory(taskID, userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting task history: %v\n", err)
+		os.Exit(1)
+	}
+
+	printTaskHistory(history)
+}
+
+// printTaskHistory lists a task's edits oldest first, one changed field per
+// line under who made the edit and when
+func printTaskHistory(history []models.TaskHistoryEntry) {
+	if isJSONFormat(globalConfig.Format) {
+		Output(NewFormatter(globalConfig.Format), history)
+		return
+	}
+	if len(history) == 0 {
+		fmt.Println("No changes recorded")
+		return
+	}
+
+	var actorIDs []string
+	for _, entry := range history {
+		if entry.ActorID != nil {
+			actorIDs = append(actorIDs, *entry.ActorID)
+		}
+	}
+	usernames := findUsernames(actorIDs)
+
+	for _, entry := range history {
+		actor := "hereandnow"
+		if entry.ActorID != nil {
+			actor = *entry.ActorID
+			if username, ok := usernames[actor]; ok {
+				actor = username
+			}
+		}
+		fmt.Printf("%s by %s\n", currentLocale().Format(entry.CreatedAt, locale.LongDateTime), actor)
+		for _, field := range entry.ChangedFields() {
+			change := entry.Changes[field]
+			fmt.Printf("  %s: %s → %s\n", field, change.Old, change.New)
+		}
+	}
+}
+
+// printTaskExplanation shows each filter's verdict on a task as a checklist,
+// under the context it was judged in
+func printTaskExplanation(explanation filters.TaskVisibilityExplanation) {
+	if isJSONFormat(globalConfig.Format) {
+		Output(NewFormatter(globalConfig.Format), explanation)
+		return
+	}
+
+	verdict := "hidden"
+	if explanation.IsVisible {
+		verdict = "visible"
+	}
+	fmt.Printf("%s is %s right now\n\n", explanation.TaskTitle, verdict)
+
+	ctx := explanation.Context
+	where := "unknown"
+	if ctx.LocationID != nil {
+		where = *ctx.LocationID
+	} else if ctx.Latitude != nil && ctx.Longitude != nil {
+		where = fmt.Sprintf("%.6f, %.6f", *ctx.Latitude, *ctx.Longitude)
+	}
+	fmt.Printf("Context from %s\n", currentLocale().Format(ctx.Timestamp, locale.LongDateTime))
+	if ctx.IsStale {
+		fmt.Printf("  Stale: %s old, so location and available time were ignored\n",
+			formatContextAge(time.Duration(ctx.AgeSeconds)*time.Second))
+	}
+	fmt.Printf("  Location: %s\n", where)
+	fmt.Printf("  Available time: %d minutes\n", ctx.AvailableMinutes)
+	fmt.Printf("  Energy level: %d/5\n\n", ctx.EnergyLevel)
+
+	for _, result := range explanation.FilterResults {
+		mark := "✅"
+		if !result.Passed {
+			mark = "❌"
+		}
+		if result.Code != "" {
+			fmt.Printf("%s %s [%s]: %s\n", mark, result.FilterName, result.Code, result.Reason)
+		} else {
+			fmt.Printf("%s %s: %s\n", mark, result.FilterName, result.Reason)
+		}
+		if result.StaleOverride {
+			fmt.Println("   (passed only because the context is stale)")
+		}
+	}
+}
+
+func executeTaskSearch(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: task search requires query\n")
+		fmt.Println("Usage: hereandnow task search <query>")
+		os.Exit(1)
+	}
+
+	query := strings.Join(args, " ")
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	tasks, err := taskService.SearchTasks(userID, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewSearchFormatter(globalConfig.Format, query)
+	Output(formatter, tasks)
+}
+
+func executeTaskReorder(args []string) {
+	taskID := ""
+	afterID := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--id":
+			if i+1 < len(args) {
+				taskID = args[i+1]
+				i++
+			}
+		case "--after":
+			if i+1 < len(args) {
+				afterID = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if taskID == "" {
+		fmt.Fprintf(os.Stderr, "Error: task reorder requires --id\n")
+		fmt.Println("Usage: hereandnow task reorder --id <task-id> [--after <task-id>]")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	task, err := taskService.ReorderTask(taskID, afterID, getCurrentUserID())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reordering task: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if afterID == "" {
+		Output(formatter, fmt.Sprintf("Task moved to top of list: %s", task.Title))
+	} else {
+		Output(formatter, fmt.Sprintf("Task moved: %s", task.Title))
+	}
+}
+
+func executeTaskSnooze(args []string) {
+	taskID := ""
+	until := ""
+	snoozeFor := ""
+	preset := ""
+	recurring := false
+	clearSnooze := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--id":
+			if i+1 < len(args) {
+				taskID = args[i+1]
+				i++
+			}
+		case "--until":
+			if i+1 < len(args) {
+				until = args[i+1]
+				i++
+			}
+		case "--for":
+			if i+1 < len(args) {
+				snoozeFor = args[i+1]
+				i++
+			}
+		case "--preset":
+			if i+1 < len(args) {
+				preset = args[i+1]
+				i++
+			}
+		case "--recurring":
+			recurring = true
+		case "--clear":
+			clearSnooze = true
+		default:
+			if taskID == "" && !strings.HasPrefix(args[i], "--") {
+				taskID = args[i]
+			}
+		}
+	}
+
+	modes := 0
+	for _, set := range []bool{until != "", snoozeFor != "", preset != "", clearSnooze} {
+		if set {
+			modes++
+		}
+	}
+	if taskID == "" || modes != 1 {
+		fmt.Fprintf(os.Stderr, "Error: task snooze requires a task ID and one of --until, --for, --preset or --clear\n")
+		fmt.Println("Usage: hereandnow task snooze <task-id> (--until <when> | --for <duration> | --preset <name> [--recurring] | --clear)")
+		os.Exit(1)
+	}
+
+	if recurring && preset == "" {
+		fmt.Fprintf(os.Stderr, "Error: --recurring requires --preset\n")
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+
+	if clearSnooze {
+		task, err := taskService.UnsnoozeTask(taskID, userID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing snooze: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Task no longer snoozed: %s", task.Title))
+		return
+	}
+
+	var task *models.Task
+	switch {
+	case preset != "":
+		task, err = taskService.SnoozeTaskWithPreset(taskID, userID, preset, recurring)
+	case snoozeFor != "":
+		d, parseErr := models.ParseSnoozeDuration(snoozeFor)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --for value: %v\n", parseErr)
+			os.Exit(1)
+		}
+		task, err = taskService.SnoozeTask(taskID, userID, time.Now().Add(d))
+	default:
+		var snoozeUntil time.Time
+		if d, parseErr := models.ParseSnoozeDuration(until); parseErr == nil {
+			snoozeUntil = time.Now().Add(d)
+		} else if snoozeUntil, err = parseDateTime(until); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --until value: %s\n", until)
+			os.Exit(1)
+		}
+		task, err = taskService.SnoozeTask(taskID, userID, snoozeUntil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error snoozing task: %v\n", err)
+		os.Exit(1)
+	}
+
+	Output(formatter, fmt.Sprintf("Task snoozed until %s: %s", task.SnoozedUntil.Format("Mon Jan 2 15:04"), task.Title))
+}
+
+// recurPreviewCount is how many upcoming occurrences task recur shows
+const recurPreviewCount = 5
+
+func executeTaskRecur(args []string) {
+	taskID := ""
+	rrule := ""
+	every := ""
+	clearRule := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--id":
+			if i+1 < len(args) {
+				taskID = args[i+1]
+				i++
+			}
+		case "--rule":
+			if i+1 < len(args) {
+				rrule = args[i+1]
+				i++
+			}
+		case "--every":
+			if i+1 < len(args) {
+				every = args[i+1]
+				i++
+			}
+		case "--clear":
+			clearRule = true
+		}
+	}
+
+	options := 0
+	for _, set := range []bool{rrule != "", every != "", clearRule} {
+		if set {
+			options++
+		}
+	}
+	if taskID == "" || options != 1 {
+		fmt.Fprintf(os.Stderr, "Error: task recur requires --id and one of --rule, --every or --clear\n")
+		fmt.Println("Usage: hereandnow task recur --id <task-id> (--rule <rrule> | --every <period> | --clear)")
+		os.Exit(1)
+	}
+
+	var rule *recurrence.Rule
+	var err error
+	switch {
+	case rrule != "":
+		rule, err = recurrence.Parse(rrule)
+	case every != "":
+		rule, err = recurrence.ParseEvery(every)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid recurrence: %v\n", err)
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	existing, err := taskService.GetTask(taskID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting recurrence: %v\n", err)
+		os.Exit(1)
+	}
+	if rule == nil {
+		if dryRun("stop task recurring: %s", existing.Title) {
+			return
+		}
+	} else if dryRun("set task recurrence to %s (%s): %s", rule, rule.Describe(), existing.Title) {
+		return
+	}
+
+	task, err := taskService.SetTaskRecurrence(taskID, userID, rule)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting recurrence: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if rule == nil {
+		Output(formatter, fmt.Sprintf("Task no longer recurs: %s", task.Title))
+		return
+	}
+
+	occurrences, err := taskService.NextOccurrences(*task, recurPreviewCount)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error computing occurrences: %v\n", err)
+		os.Exit(1)
+	}
+
+	if isJSONFormat(globalConfig.Format) {
+		Output(formatter, map[string]interface{}{
+			"task":        task,
+			"rule":        rule.String(),
+			"description": rule.Describe(),
+			"next":        occurrences,
+		})
+		return
+	}
+
+	Output(formatter, fmt.Sprintf("Task recurs %s: %s", rule.Describe(), task.Title))
+	fmt.Printf("Rule: %s\n", rule)
+	if len(occurrences) == 0 {
+		fmt.Println("No upcoming occurrences")
+		return
+	}
+	fmt.Println("Next occurrences:")
+	for _, at := range occurrences {
+		fmt.Printf("  %s\n", at.Local().Format("Mon Jan 2, 2006 15:04"))
+	}
+}
+
+func executeTaskDedupe(args []string) {
+	auto := false
+	for _, arg := range args {
+		if arg == "--auto" {
+			auto = true
+		}
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	taskService, err := initTaskService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	groups, err := taskService.FindDuplicateTasks(userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding duplicates: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+	if isJSONFormat(globalConfig.Format) && !auto {
+		Output(formatter, groups)
+		return
+	}
+	if len(groups) == 0 {
+		Output(formatter, "No duplicate tasks found")
+		return
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	merged := 0
+	for _, group := range groups {
+		fmt.Printf("Keep:   %s  %s\n", group.Keep.ID, group.Keep.Title)
+		for _, duplicate := range group.Duplicates {
+			fmt.Printf("Cancel: %s  %s\n", duplicate.ID, duplicate.Title)
+		}
+
+		if dryRun("merge %d duplicate(s) into: %s", len(group.Duplicates), group.Keep.Title) {
+			fmt.Println()
+			continue
+		}
+		if !auto {
+			fmt.Print("Merge these tasks? [y/N]: ")
+			answer, _ := reader.ReadString('\n')
+			if answer = strings.TrimSpace(strings.ToLower(answer)); answer != "y" && answer != "yes" {
+				fmt.Println("Skipped")
+				fmt.Println()
+				continue
+			}
+		}
+
+		cancelled, err := taskService.MergeDuplicates(userID, group)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging duplicates: %v\n", err)
+			os.Exit(1)
+		}
+		merged += len(cancelled)
+		fmt.Printf("Merged into %s\n\n", group.Keep.Title)
+	}
+
+	Output(formatter, fmt.Sprintf("%d duplicate task(s) cancelled", merged))
+}
+
+func executeTaskExport(args []string) {
+	format := ""
+	outputPath := ""
+	scrub := false
+
+	for i, arg := range args {
+		switch {
+		case arg == "--format":
+			if i+1 < len(args) {
+				format = args[i+1]
+			}
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case arg == "--output":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+			}
+		case arg == "--scrub":
+			scrub = true
+		}
+	}
+
+	if format != "todoist" && format != "markdown" && format != "ics" && format != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported export format: %q (supported: todoist, markdown, ics, csv)\n", format)
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
+		os.Exit(1)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	tasks, err := storage.NewTaskRepository(db).GetByUserID(userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error retrieving tasks: %v\n", err)
+		os.Exit(1)
+	}
+
+	if scrub {
+		tasks = sync.ScrubTasks(tasks)
+	}
+
+	// List names are not stored by the CLI yet, so projects are named by list ID
+	var data []byte
+	switch format {
+	case "markdown", "ics":
+		taskLocationRepo := storage.NewTaskLocationRepository(db)
+		locations := make(map[string][]models.Location, len(tasks))
+		for _, task := range tasks {
+			taskLocations, err := taskLocationRepo.GetLocationsByTaskID(task.ID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error retrieving task locations: %v\n", err)
+				os.Exit(1)
+			}
+			if scrub {
+				for i := range taskLocations {
+					taskLocations[i] = sync.ScrubLocation(taskLocations[i])
+				}
 			}
+			locations[task.ID] = taskLocations
+		}
+		if format == "ics" {
+			feed := sync.ICSFeed{Name: "Here and Now", Tasks: tasks, Locations: locations}
+			// Keep the CRLF that ends the last line, as RFC 5545 requires
+			data = []byte(sync.ExportICS(feed, time.Now()))
+			break
+		}
+		data = []byte(strings.TrimSuffix(sync.ExportMarkdown(tasks, nil, locations), "\n"))
+	case "csv":
+		var buf bytes.Buffer
+		if err := sync.ExportTasksCSV(&buf, tasks); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding export: %v\n", err)
+			os.Exit(1)
+		}
+		data = buf.Bytes()
+	default:
+		data, err = json.MarshalIndent(sync.ExportTodoist(tasks, nil), "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding export: %v\n", err)
+			os.Exit(1)
 		}
 	}
 
-	taskService, err := initTaskService()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+	if !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(data))
+		return
+	}
+
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("✓ Exported %d task(s) to %s\n", len(tasks), outputPath)
+}
 
-	req := hereandnow.UpdateTaskRequest{
-		Title:            title,
-		Description:      description,
-		Priority:         priority,
-		EstimatedMinutes: estimate,
-		DueAt:            dueDate,
-		Status:           status,
+// executeTaskImport imports tasks from a file and prints the import report.
+// It exits non-zero when any record failed.
+func executeTaskImport(args []string) {
+	from := ""
+	path := ""
+	opts := hereandnow.ImportOptions{}
+
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--from" && i+1 < len(args):
+			from = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--from="):
+			from = strings.TrimPrefix(args[i], "--from=")
+		case args[i] == "--create-missing-locations":
+			opts.CreateMissingLocations = true
+		case !strings.HasPrefix(args[i], "--"):
+			path = args[i]
+		}
+	}
+
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "Error: task import requires a file\n")
+		fmt.Println("Usage: hereandnow task import <file> [--from markdown|csv] [--create-missing-locations]")
+		os.Exit(1)
+	}
+	if from == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".md", ".markdown":
+			from = "markdown"
+		case ".csv":
+			from = "csv"
+		}
+	}
+	if from != "markdown" && from != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported import format: %q (supported: markdown, csv)\n", from)
+		os.Exit(1)
 	}
 
-	task, err := taskService.UpdateTask(taskID, req)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error updating task: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error reading import file: %v\n", err)
+		os.Exit(1)
+	}
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No current user\n")
 		os.Exit(1)
 	}
 
+	var tasks []sync.ImportedTask
+	var report *sync.ImportReport
+	if from == "csv" && sync.IsTasksCSV(string(data)) {
+		// A task export is read strictly: a bad row fails the whole file
+		parsed, err := sync.ImportTasksCSV(bytes.NewReader(data), userID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		tasks, report = importedCSVTasks(parsed), sync.NewImportReport("csv", path)
+	} else if from == "csv" {
+		tasks, report = sync.ParseCSV(string(data), path, time.Local)
+	} else {
+		tasks, report = sync.ParseMarkdown(string(data), path, time.Local)
+	}
+
 	formatter := NewFormatter(globalConfig.Format)
-	Output(formatter, fmt.Sprintf("Task updated: %s", task.Title))
-}
+	if dryRun("import %d task(s) from %s", len(tasks), path) {
+		Output(formatter, *report)
+		return
+	}
 
-func executeTaskDelete(args []string) {
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: task delete requires task ID\n")
-		fmt.Println("Usage: hereandnow task delete <task-id>")
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	taskID := args[0]
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
 
 	taskService, err := initTaskService()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
 		os.Exit(1)
 	}
+	taskService.EnableImportLocations(storage.NewLocationRepository(db))
 
-	if err := taskService.DeleteTask(taskID); err != nil {
-		fmt.Fprintf(os.Stderr, "Error deleting task: %v\n", err)
+	if err := taskService.ImportTasks(userID, tasks, report, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing tasks: %v\n", err)
 		os.Exit(1)
 	}
 
-	formatter := NewFormatter(globalConfig.Format)
-	Output(formatter, "Task deleted successfully")
+	Output(formatter, *report)
+	if report.HasFailures() {
+		os.Exit(1)
+	}
 }
 
-func executeTaskAssign(args []string) {
-	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Error: task assign requires task ID and username\n")
-		fmt.Println("Usage: hereandnow task assign <task-id> <username>")
+// importedCSVTasks turns the tasks of a task export back into import records
+// for ImportTasks. Each record's line is its CSV row, counting the header as
+// row 1.
+func importedCSVTasks(tasks []models.Task) []sync.ImportedTask {
+	imported := make([]sync.ImportedTask, len(tasks))
+	for i, task := range tasks {
+		imported[i] = sync.ImportedTask{
+			Line:             i + 2,
+			Title:            task.Title,
+			Description:      task.Description,
+			Priority:         task.Priority,
+			DueAt:            task.DueAt,
+			EstimatedMinutes: task.EstimatedMinutes,
+			Tags:             task.Tags,
+			Status:           task.Status,
+		}
+	}
+	return imported
+}
+
+func executeTaskLink(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: task link requires add, remove or list\n")
+		fmt.Println("Usage: hereandnow task link add --id <task-id> --related <task-id> [--type related|duplicate] [--cancel]")
+		os.Exit(1)
+	}
+
+	action := args[0]
+	taskID := ""
+	relatedID := ""
+	linkType := models.TaskLinkTypeRelated
+	cancel := false
+
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--id":
+			if i+1 < len(args) {
+				taskID = args[i+1]
+				i++
+			}
+		case "--related":
+			if i+1 < len(args) {
+				relatedID = args[i+1]
+				i++
+			}
+		case "--type":
+			if i+1 < len(args) {
+				linkType = models.TaskLinkType(args[i+1])
+				i++
+			}
+		case "--cancel":
+			cancel = true
+		default:
+			if !strings.HasPrefix(args[i], "--") && taskID == "" {
+				taskID = args[i]
+			}
+		}
+	}
+
+	if taskID == "" || (action != "list" && relatedID == "") {
+		fmt.Fprintf(os.Stderr, "Error: task link %s requires --id and --related\n", action)
+		fmt.Println("Usage: hereandnow task link add|remove --id <task-id> --related <task-id>")
+		fmt.Println("       hereandnow task link list <task-id>")
 		os.Exit(1)
 	}
 
-	taskID := args[0]
-	username := args[1]
-	
 	userID := getCurrentUserID()
 	if userID == "" {
 		fmt.Fprintf(os.Stderr, "Error: No current user\n")
 		os.Exit(1)
 	}
 
-	assigneeID, err := findUserByUsername(username)
+	taskService, err := initTaskService()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: User '%s' not found\n", username)
+		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+
+	switch action {
+	case "add":
+		if dryRun("link task %s to %s as %s", taskID, relatedID, linkType) {
+			return
+		}
+		if _, err := taskService.LinkTasks(taskID, relatedID, userID, linkType, cancel); err != nil {
+			fmt.Fprintf(os.Stderr, "Error linking tasks: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Tasks linked successfully (%s)", linkType))
+	case "remove":
+		if dryRun("unlink task %s from %s", taskID, relatedID) {
+			return
+		}
+		if err := taskService.UnlinkTasks(taskID, relatedID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error unlinking tasks: %v\n", err)
+			os.Exit(1)
+		}
+		Output(formatter, "Tasks unlinked successfully")
+	case "list":
+		linked, err := taskService.GetLinkedTasks(taskID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting linked tasks: %v\n", err)
+			os.Exit(1)
+		}
+		if isJSONFormat(globalConfig.Format) {
+			Output(formatter, linked)
+			return
+		}
+		if len(linked) == 0 {
+			Output(formatter, "No linked tasks")
+			return
+		}
+		printLinkedTasks(linked)
+	default:
+		fmt.Printf("Unknown task link subcommand: %s\n", action)
+		fmt.Println("Run 'hereandnow task --help' for usage")
+		os.Exit(1)
+	}
+}
+
+// printLinkedTasks lists linked tasks with their IDs, so they can be opened
+// with task show
+func printLinkedTasks(linked []hereandnow.LinkedTask) {
+	for _, l := range linked {
+		fmt.Printf("  %-14s %s  %s (%s)\n", l.Relation, l.Task.ID, l.Task.Title, l.Task.Status)
+	}
+}
+
+func executeTaskDepend(args []string) {
+	taskID, dependsOnID, soft := parseTaskDependArgs(args)
+	if taskID == "" || dependsOnID == "" {
+		fmt.Fprintf(os.Stderr, "Error: task depend requires a task ID and --on\n")
+		fmt.Println("Usage: hereandnow task depend <task-id> --on <task-id> [--soft]")
 		os.Exit(1)
 	}
 
+	dependencyType := models.DependencyTypeBlocking
+	if soft {
+		dependencyType = models.DependencyTypeSuggested
+	}
+
+	if dryRun("make task %s depend on %s (%s)", taskID, dependsOnID, dependencyType) {
+		return
+	}
+
 	taskService, err := initTaskService()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
 		os.Exit(1)
 	}
 
-	task, err := taskService.AssignTask(taskID, assigneeID, userID)
+	dependency, err := taskService.AddDependency(getCurrentUserID(), taskID, dependsOnID, dependencyType)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error assigning task: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error adding dependency: %v\n", err)
 		os.Exit(1)
 	}
 
 	formatter := NewFormatter(globalConfig.Format)
-	Output(formatter, fmt.Sprintf("Task assigned to %s: %s", username, task.Title))
+	if isJSONFormat(globalConfig.Format) {
+		Output(formatter, dependency)
+		return
+	}
+	Output(formatter, fmt.Sprintf("Task now depends on %s (%s)", dependsOnID, dependencyType))
 }
 
-func executeTaskAudit(args []string) {
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: task audit requires task ID\n")
-		fmt.Println("Usage: hereandnow task audit <task-id>")
+func executeTaskUndepend(args []string) {
+	taskID, dependsOnID, _ := parseTaskDependArgs(args)
+	if taskID == "" || dependsOnID == "" {
+		fmt.Fprintf(os.Stderr, "Error: task undepend requires a task ID and --on\n")
+		fmt.Println("Usage: hereandnow task undepend <task-id> --on <task-id>")
 		os.Exit(1)
 	}
 
-	taskID := args[0]
-	userID := getCurrentUserID()
-	if userID == "" {
-		fmt.Fprintf(os.Stderr, "Error: No current user\n")
-		os.Exit(1)
+	if dryRun("remove task %s's dependency on %s", taskID, dependsOnID) {
+		return
 	}
 
 	taskService, err := initTaskService()
@@ -523,44 +2384,97 @@ func executeTaskAudit(args []string) {
 		os.Exit(1)
 	}
 
-	explanation, err := taskService.ExplainTaskVisibility(taskID, userID)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting task audit: %v\n", err)
+	if err := taskService.RemoveDependency(getCurrentUserID(), taskID, dependsOnID); err != nil {
+		fmt.Fprintf(os.Stderr, "Error removing dependency: %v\n", err)
 		os.Exit(1)
 	}
 
 	formatter := NewFormatter(globalConfig.Format)
-	Output(formatter, *explanation)
+	Output(formatter, "Dependency removed successfully")
 }
 
-func executeTaskSearch(args []string) {
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: task search requires query\n")
-		fmt.Println("Usage: hereandnow task search <query>")
+// parseTaskDependArgs reads the task, --on and --soft of task depend and
+// undepend
+func parseTaskDependArgs(args []string) (taskID, dependsOnID string, soft bool) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--on":
+			if i+1 < len(args) {
+				dependsOnID = args[i+1]
+				i++
+			}
+		case "--soft":
+			soft = true
+		default:
+			if !strings.HasPrefix(args[i], "--") && taskID == "" {
+				taskID = args[i]
+			}
+		}
+	}
+	return taskID, dependsOnID, soft
+}
+
+// printTaskDependencies lists the dependencies of one type under a heading,
+// with whether each task they wait on is done
+func printTaskDependencies(heading string, dependencies []hereandnow.TaskDependencyDetail, dependencyType models.DependencyType) {
+	printed := false
+	for _, d := range dependencies {
+		if d.Dependency.DependencyType != dependencyType {
+			continue
+		}
+		if !printed {
+			fmt.Printf("\n%s:\n", heading)
+			printed = true
+		}
+		fmt.Printf("  %s  %s (%s)\n", d.Task.ID, d.Task.Title, d.Task.Status)
+	}
+}
+
+func executeTaskStats(args []string) {
+	since := "30d"
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				since = args[i+1]
+				i++
+			}
+		}
+	}
+
+	period, err := models.ParseSnoozeDuration(since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --since: %v\n", err)
 		os.Exit(1)
 	}
 
-	query := strings.Join(args, " ")
 	userID := getCurrentUserID()
 	if userID == "" {
 		fmt.Fprintf(os.Stderr, "Error: No current user\n")
 		os.Exit(1)
 	}
 
-	taskService, err := initTaskService()
+	config, err := LoadConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing task service: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	tasks, err := taskService.SearchTasks(userID, query)
+	db, err := InitDatabase(config.Database.Path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error searching tasks: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
 		os.Exit(1)
 	}
+	defer db.Close()
 
-	formatter := NewFormatter(globalConfig.Format)
-	Output(formatter, tasks)
+	until := time.Now()
+	analytics, err := storage.NewAnalyticsRepository(db).GetTaskAnalytics(userID, until.Add(-period), until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting task stats: %v\n", err)
+		os.Exit(1)
+	}
+
+	Output(NewFormatter(globalConfig.Format), analytics.ToMap())
 }
 
 // Helper functions
@@ -581,8 +2495,57 @@ func initTaskService() (*hereandnow.TaskService, error) {
 	dependencyRepo := storage.NewTaskDependencyRepository(db)
 	taskLocationRepo := storage.NewTaskLocationRepository(db)
 	filterEngine := filters.NewFilterEngine()
+	filterEngine.EnableCategoryPreference(storage.NewLocationRepository(db), taskLocationRepo)
+
+	taskService := hereandnow.NewTaskService(taskRepo, contextRepo, dependencyRepo, taskLocationRepo, *filterEngine)
+	taskService.SetTransactor(storageTransactor{db: db})
+	taskService.SetSnoozePresets(config.Snooze.Presets)
+	basis, _ := hereandnow.ParseRecurrenceBasis(config.Recurrence.From)
+	taskService.SetRecurrenceBasis(basis)
+	taskService.SetUserRepository(storage.NewUserRepository(db))
+	taskService.SetListMemberRepository(storage.NewListMemberRepository(db))
+	taskService.SetListRepository(storage.NewTaskListRepository(db))
+	taskService.SetNotificationRepository(storage.NewNotificationRepository(db))
+	taskService.EnableVisibilityEvents(storage.NewTaskVisibilityRepository(db), storage.NewNotificationRepository(db))
+	taskService.EnableUndo(storage.NewTaskActionRepository(db))
+	taskService.EnableTaskLinks(storage.NewTaskLinkRepository(db))
+	taskService.EnableTaskHistory(storage.NewTaskHistoryRepository(db))
+	taskService.EnableSharedCompletionUndo(storage.NewListMemberRepository(db), storage.NewCompletionUndoRepository(db),
+		time.Duration(config.Lists.CompletionUndoSeconds)*time.Second)
+
+	return taskService, nil
+}
+
+func initReminderService() (*hereandnow.ReminderService, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return hereandnow.NewReminderService(storage.NewReminderRepository(db), storage.NewTaskRepository(db), storage.NewNotificationRepository(db)), nil
+}
+
+// storageTransactor binds the task service's repositories to a database transaction
+type storageTransactor struct {
+	db *storage.DB
+}
 
-	return hereandnow.NewTaskService(taskRepo, contextRepo, dependencyRepo, taskLocationRepo, *filterEngine), nil
+func (t storageTransactor) WithTx(fn func(repos hereandnow.TxRepositories) error) error {
+	return t.db.WithTransaction(context.Background(), func(tx *storage.Tx) error {
+		return fn(hereandnow.TxRepositories{
+			Tasks:         storage.NewTaskRepository(t.db).WithTx(tx),
+			Dependencies:  storage.NewTaskDependencyRepository(tx.DB()),
+			TaskLocations: storage.NewTaskLocationRepository(t.db).WithTx(tx),
+			Notifications: storage.NewNotificationRepository(t.db).WithTx(tx),
+			Assignments:   storage.NewTaskAssignmentRepository(t.db).WithTx(tx),
+			History:       storage.NewTaskHistoryRepository(t.db).WithTx(tx),
+		})
+	})
 }
 
 func getCurrentUserID() string {
@@ -635,6 +2598,39 @@ func findLocationByName(name, userID string) (string, error) {
 	return "", fmt.Errorf("location not found: %s", name)
 }
 
+// findListByName returns the ID of the user's active list with the given
+// name, or nameOrID itself when it is already a list ID
+func findListByName(nameOrID, userID string) (string, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	lists, err := storage.NewTaskListRepository(db).GetActive()
+	if err != nil {
+		return "", err
+	}
+
+	for _, list := range lists {
+		if list.ID == nameOrID {
+			return list.ID, nil
+		}
+	}
+	for _, list := range lists {
+		if list.OwnerID == userID && strings.EqualFold(list.Name, nameOrID) {
+			return list.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("list not found: %s", nameOrID)
+}
+
 func findUserByUsername(username string) (string, error) {
 	config, err := LoadConfig()
 	if err != nil {
@@ -656,6 +2652,36 @@ func findUserByUsername(username string) (string, error) {
 	return user.ID, nil
 }
 
+// findUsernames maps the IDs of the users that still exist to their usernames
+func findUsernames(userIDs []string) map[string]string {
+	usernames := map[string]string{}
+	if len(userIDs) == 0 {
+		return usernames
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return usernames
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		return usernames
+	}
+	defer db.Close()
+
+	userRepo := storage.NewUserRepository(db)
+	for _, userID := range userIDs {
+		if _, seen := usernames[userID]; seen {
+			continue
+		}
+		if user, err := userRepo.GetByID(userID); err == nil {
+			usernames[userID] = user.Username
+		}
+	}
+	return usernames
+}
+
 func parseDateTime(dateStr string) (time.Time, error) {
 	// Try various date/time formats
 	formats := []string{
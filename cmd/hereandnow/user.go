@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"crypto/rand"
 	"fmt"
 	"os"
@@ -37,11 +38,35 @@ SUBCOMMANDS:
     update <username>   Update user information
     delete <username>   Delete a user
     password <username> Change user password
+    sessions <username> List the user's signed-in devices
+    sessions revoke <username> <session-id>
+                        Sign one device out
+    sessions revoke <username> --all
+                        Sign every device out
+    notify              Show where your notifications are pushed
+    notify set          Set your notification channels
+    notify test         Send a test notification to your channels
 
 OPTIONS:
-    --admin             Make user an admin (create only)
+    --admin             Make user an admin (create, update). The first user
+                        is always an admin; after that only admins may
+                        create admins
+    --no-admin          Revoke admin privileges (update only)
+    --activate          Reactivate a deactivated user (update only)
+    --deactivate        Stop the user logging in and sign them out
+                        everywhere (update only)
+    --anonymize         Keep the user's tasks under an anonymized account
+                        instead of deleting them (delete only)
+    --username <name>   Set username instead of prompting (create only)
     --email <email>     Set user email
     --timezone <tz>     Set user timezone (default: UTC)
+    --webhook <url>     POST notifications as JSON to url (notify set only)
+    --ntfy-topic <t>    Publish notifications to an ntfy topic (notify set only)
+    --ntfy-server <url> Use your own ntfy server (notify set only)
+    --email <on|off>    Email notifications to your account's address, when
+                        the server has notifications.smtp set (notify set
+                        only)
+    --test              Send a test notification after saving (notify set only)
     --help, -h         Show this help
 
 EXAMPLES:
@@ -62,28 +87,35 @@ EXAMPLES:
 
     # Update user timezone
     hereandnow user update john --timezone America/New_York
+
+    # Make a user an admin, or lock a departed user out
+    hereandnow user update jane --admin
+    hereandnow user update john --deactivate
+
+    # See where a user is signed in, then sign a lost phone out
+    hereandnow user sessions john
+    hereandnow user sessions revoke john 3f2a9c1e-...
+
+    # Get notifications on your phone with the ntfy app, then check it works
+    hereandnow user notify set --ntfy-topic mytasks
+    hereandnow user notify test
+
+    # Stop sending notifications to a webhook
+    hereandnow user notify set --webhook ""
+
+    # Get notifications by email, and see the test email without sending it
+    hereandnow user notify set --email on
+    hereandnow user notify test --dry-run
+
+Granting or revoking admin, activating, deactivating and deleting users
+require the current user to be an admin. The last active admin cannot be
+demoted, deactivated or deleted.
 `)
 		return
 	}
 
-	subcommand := args[0]
-	subArgs := args[1:]
-
-	switch subcommand {
-	case "create":
-		executeUserCreate(subArgs)
-	case "list":
-		executeUserList(subArgs)
-	case "show":
-		executeUserShow(subArgs)
-	case "update":
-		executeUserUpdate(subArgs)
-	case "delete":
-		executeUserDelete(subArgs)
-	case "password":
-		executeUserPassword(subArgs)
-	default:
-		fmt.Printf("Unknown user subcommand: %s\n", subcommand)
+	if !runSubcommand(userCommands, args) {
+		fmt.Printf("Unknown user subcommand: %s\n", args[0])
 		fmt.Println("Run 'hereandnow user --help' for usage")
 		os.Exit(1)
 	}
@@ -91,6 +123,7 @@ EXAMPLES:
 
 func executeUserCreate(args []string) {
 	admin := false
+	username := ""
 	email := ""
 	timezone := "UTC"
 
@@ -98,6 +131,10 @@ func executeUserCreate(args []string) {
 		switch arg {
 		case "--admin":
 			admin = true
+		case "--username":
+			if i+1 < len(args) {
+				username = args[i+1]
+			}
 		case "--email":
 			if i+1 < len(args) {
 				email = args[i+1]
@@ -126,12 +163,27 @@ func executeUserCreate(args []string) {
 	userRepo := storage.NewUserRepository(db)
 	authService := auth.NewAuthService(userRepo)
 
+	// The first user administers the install; after that only admins
+	// create admins
+	count, err := userRepo.Count()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error counting users: %v\n", err)
+		os.Exit(1)
+	}
+	if count == 0 {
+		admin = true
+	} else if admin {
+		requireAdmin(userRepo)
+	}
+
 	// Get user input
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Print("Username: ")
-	username, _ := reader.ReadString('\n')
-	username = strings.TrimSpace(username)
+	if username == "" {
+		fmt.Print("Username: ")
+		username, _ = reader.ReadString('\n')
+		username = strings.TrimSpace(username)
+	}
 
 	if username == "" {
 		fmt.Fprintf(os.Stderr, "Error: Username cannot be empty\n")
@@ -262,6 +314,7 @@ func executeUserUpdate(args []string) {
 	username := args[0]
 	email := ""
 	timezone := ""
+	var admin, active *bool
 
 	for i := 1; i < len(args); i++ {
 		switch args[i] {
@@ -275,12 +328,18 @@ func executeUserUpdate(args []string) {
 				timezone = args[i+1]
 				i++
 			}
+		case "--admin", "--no-admin":
+			grant := args[i] == "--admin"
+			admin = &grant
+		case "--activate", "--deactivate":
+			activate := args[i] == "--activate"
+			active = &activate
 		}
 	}
 
-	if email == "" && timezone == "" {
+	if email == "" && timezone == "" && admin == nil && active == nil {
 		fmt.Fprintf(os.Stderr, "Error: At least one field must be updated\n")
-		fmt.Println("Available options: --email, --timezone")
+		fmt.Println("Available options: --email, --timezone, --admin, --no-admin, --activate, --deactivate")
 		os.Exit(1)
 	}
 
@@ -306,6 +365,15 @@ func executeUserUpdate(args []string) {
 		os.Exit(1)
 	}
 
+	// Only admins change who is an admin or whose account is active
+	deactivate := active != nil && !*active && user.IsActive()
+	if admin != nil || active != nil {
+		requireAdmin(userRepo)
+		if deactivate || (admin != nil && !*admin) {
+			requireOtherAdmins(userRepo, user)
+		}
+	}
+
 	// Update fields
 	if email != "" {
 		user.Email = email
@@ -313,6 +381,17 @@ func executeUserUpdate(args []string) {
 	if timezone != "" {
 		user.Timezone = timezone
 	}
+	if admin != nil {
+		user.IsAdmin = *admin
+	}
+	if active != nil {
+		if *active {
+			user.DeactivatedAt = nil
+		} else if deactivate {
+			now := time.Now()
+			user.DeactivatedAt = &now
+		}
+	}
 	user.UpdatedAt = time.Now()
 
 	if err := userRepo.Update(*user); err != nil {
@@ -320,6 +399,13 @@ func executeUserUpdate(args []string) {
 		os.Exit(1)
 	}
 
+	if deactivate {
+		if err := signOutUser(storage.NewSessionRepository(db), storage.NewDeviceTokenRepository(db), user.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error signing user out: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	formatter := NewFormatter(globalConfig.Format)
 	Output(formatter, fmt.Sprintf("User %s updated successfully", username))
 }
@@ -332,6 +418,12 @@ func executeUserDelete(args []string) {
 	}
 
 	username := args[0]
+	anonymize := false
+	for _, arg := range args[1:] {
+		if arg == "--anonymize" {
+			anonymize = true
+		}
+	}
 
 	// Confirm deletion
 	fmt.Printf("Are you sure you want to delete user '%s'? This action cannot be undone.\n", username)
@@ -368,7 +460,21 @@ func executeUserDelete(args []string) {
 		os.Exit(1)
 	}
 
-	if err := userRepo.Delete(user.ID); err != nil {
+	requireAdmin(userRepo)
+	requireOtherAdmins(userRepo, user)
+
+	if anonymize {
+		// Keep the tasks they shared under an anonymized, deactivated account
+		user.Anonymize(time.Now())
+		if err := userRepo.Update(user); err != nil {
+			fmt.Fprintf(os.Stderr, "Error anonymizing user: %v\n", err)
+			os.Exit(1)
+		}
+		if err := signOutUser(storage.NewSessionRepository(db), storage.NewDeviceTokenRepository(db), user.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error signing user out: %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := userRepo.Delete(user.ID); err != nil {
 		fmt.Fprintf(os.Stderr, "Error deleting user: %v\n", err)
 		os.Exit(1)
 	}
@@ -458,4 +564,287 @@ func executeUserPassword(args []string) {
 
 	formatter := NewFormatter(globalConfig.Format)
 	Output(formatter, fmt.Sprintf("Password updated successfully for user %s", username))
-}
\ No newline at end of file
+}
+
+func executeUserSessions(args []string) {
+	revoke := len(args) > 0 && args[0] == "revoke"
+	if revoke {
+		args = args[1:]
+	}
+	if len(args) == 0 || (revoke && len(args) != 2) {
+		fmt.Fprintf(os.Stderr, "Error: user sessions requires username\n")
+		fmt.Println("Usage: hereandnow user sessions <username>")
+		fmt.Println("       hereandnow user sessions revoke <username> <session-id|--all>")
+		os.Exit(1)
+	}
+
+	username := args[0]
+
+	// Initialize database connection
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	userRepo := storage.NewUserRepository(db)
+
+	user, err := userRepo.GetByUsername(username)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: User '%s' not found\n", username)
+		os.Exit(1)
+	}
+
+	sessionRepo := storage.NewSessionRepository(db)
+	all, err := sessionRepo.GetByUserID(user.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error retrieving sessions: %v\n", err)
+		os.Exit(1)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+
+	if revoke {
+		// The CLI holds no session of its own, so --all signs out every device
+		revoked := 0
+		for _, session := range all {
+			if args[1] != "--all" && session.ID != args[1] {
+				continue
+			}
+			if err := sessionRepo.Delete(session.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "Error revoking session: %v\n", err)
+				os.Exit(1)
+			}
+			revoked++
+		}
+		if revoked == 0 && args[1] != "--all" {
+			fmt.Fprintf(os.Stderr, "Error: Session '%s' not found\n", args[1])
+			os.Exit(1)
+		}
+		Output(formatter, fmt.Sprintf("Revoked %d session(s) successfully for user %s", revoked, username))
+		return
+	}
+
+	sessions := []auth.Session{}
+	for _, session := range all {
+		if time.Now().Before(session.ExpiresAt) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	if isJSONFormat(globalConfig.Format) {
+		Output(formatter, sessions)
+		return
+	}
+
+	if len(sessions) == 0 {
+		fmt.Printf("%s has no active sessions\n", username)
+		return
+	}
+	fmt.Printf("Sessions for %s:\n", username)
+	for _, session := range sessions {
+		lastActive := session.CreatedAt
+		if session.LastUsedAt != nil {
+			lastActive = *session.LastUsedAt
+		}
+		fmt.Printf("  %s  %s  active %s  from %s\n", session.ID, session.DeviceName, formatLastActive(lastActive), session.IPAddress)
+	}
+}
+
+func executeUserNotify(args []string) {
+	subcommand := "show"
+	if len(args) > 0 {
+		subcommand, args = args[0], args[1:]
+	}
+
+	var webhook, ntfyTopic, ntfyServer *string
+	var email *bool
+	test := subcommand == "test" || subcommand == "--test"
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--webhook":
+			if i+1 < len(args) {
+				webhook = &args[i+1]
+				i++
+			}
+		case "--ntfy-topic":
+			if i+1 < len(args) {
+				ntfyTopic = &args[i+1]
+				i++
+			}
+		case "--ntfy-server":
+			if i+1 < len(args) {
+				ntfyServer = &args[i+1]
+				i++
+			}
+		case "--email":
+			if i+1 < len(args) {
+				switch args[i+1] {
+				case "on", "true", "yes":
+					enabled := true
+					email = &enabled
+				case "off", "false", "no":
+					enabled := false
+					email = &enabled
+				default:
+					fmt.Fprintf(os.Stderr, "Error: --email must be on or off\n")
+					os.Exit(1)
+				}
+				i++
+			}
+		case "--test":
+			test = true
+		}
+	}
+
+	switch subcommand {
+	case "show", "set", "test", "--test":
+	default:
+		fmt.Printf("Unknown user notify subcommand: %s\n", subcommand)
+		fmt.Println("Usage: hereandnow user notify [set|test] [OPTIONS]")
+		os.Exit(1)
+	}
+	if subcommand == "set" && webhook == nil && ntfyTopic == nil && ntfyServer == nil && email == nil {
+		fmt.Fprintf(os.Stderr, "Error: At least one channel must be set\n")
+		fmt.Println("Available options: --webhook, --ntfy-topic, --ntfy-server, --email")
+		os.Exit(1)
+	}
+
+	// Initialize database connection
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := InitDatabase(config.Database.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	userID := getCurrentUserID()
+	if userID == "" {
+		fmt.Fprintf(os.Stderr, "Error: No user found. Create one with 'hereandnow user create'\n")
+		os.Exit(1)
+	}
+
+	settingsRepo := storage.NewNotificationSettingsRepository(db)
+	settings, err := settingsRepo.Get(userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error retrieving notification settings: %v\n", err)
+		os.Exit(1)
+	}
+	if settings == nil {
+		settings = models.NewNotificationSettings(userID)
+	}
+
+	formatter := NewFormatter(globalConfig.Format)
+
+	if subcommand == "set" {
+		if webhook != nil {
+			settings.WebhookURL = *webhook
+		}
+		if ntfyTopic != nil {
+			settings.NtfyTopic = *ntfyTopic
+		}
+		if ntfyServer != nil {
+			settings.NtfyServer = *ntfyServer
+		}
+		if email != nil {
+			settings.EmailNotificationsEnabled = *email
+		}
+		settings.UpdatedAt = time.Now()
+
+		if err := settingsRepo.Save(*settings); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving notification settings: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if test {
+		if !settings.HasChannels() {
+			fmt.Fprintf(os.Stderr, "Error: No notification channels are set up\n")
+			fmt.Println("Set one with 'hereandnow user notify set --ntfy-topic <topic>'")
+			os.Exit(1)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		// With --dry-run the test email is logged instead of sent
+		notifications := config.Notifications
+		notifications.SMTP.DryRun = notifications.SMTP.DryRun || globalConfig.DryRun
+		if err := notifications.Dispatcher(db).SendTest(ctx, userID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending test notification: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if isJSONFormat(globalConfig.Format) {
+		Output(formatter, settings)
+		return
+	}
+
+	if subcommand == "set" {
+		fmt.Println("Notification settings updated successfully")
+	}
+	if test {
+		fmt.Println("Test notification sent")
+	}
+	if subcommand == "show" || subcommand == "set" {
+		printNotificationChannels(settings, config.Notifications)
+	}
+}
+
+// printNotificationChannels lists where notifications are pushed
+func printNotificationChannels(settings *models.NotificationSettings, notifications NotificationsConfig) {
+	defaultNtfyServer := notifications.NtfyServer
+	if !settings.HasChannels() {
+		fmt.Println("Notifications are only shown in the app")
+		return
+	}
+
+	fmt.Println("Notifications are pushed to:")
+	if settings.WebhookURL != "" {
+		fmt.Printf("  Webhook  %s\n", settings.WebhookURL)
+	}
+	if settings.NtfyTopic != "" {
+		server := settings.NtfyServer
+		if server == "" {
+			server = defaultNtfyServer
+		}
+		if server == "" {
+			server = models.DefaultNtfyServer
+		}
+		fmt.Printf("  ntfy     %s/%s\n", strings.TrimSuffix(server, "/"), settings.NtfyTopic)
+	}
+	if settings.EmailNotificationsEnabled {
+		if notifications.SMTP.Enabled() {
+			fmt.Println("  Email    your account's address")
+		} else {
+			fmt.Println("  Email    your account's address (not sent: the server has no notifications.smtp)")
+		}
+	}
+}
+
+// formatLastActive describes roughly how long ago t was, e.g. "5m ago"
+func formatLastActive(t time.Time) string {
+	since := time.Since(t)
+	switch {
+	case since < time.Minute:
+		return "just now"
+	case since < time.Hour:
+		return fmt.Sprintf("%dm ago", int(since.Minutes()))
+	case since < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(since.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(since.Hours()/24))
+	}
+}
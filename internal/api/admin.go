@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxUserPageSize caps the limit query parameter of GET /admin/users
+const MaxUserPageSize = 200
+
+// AdminHandler serves the admin-only user management endpoints
+type AdminHandler struct {
+	authService *auth.AuthService
+}
+
+func NewAdminHandler(authService *auth.AuthService) *AdminHandler {
+	return &AdminHandler{
+		authService: authService,
+	}
+}
+
+type AdminUsersResponse struct {
+	Users []auth.UserSummary `json:"users"`
+	Total int                `json:"total"`
+}
+
+// AdminMiddleware refuses requests from users who are not admins with 403.
+// It runs after the auth middleware, which sets the user.
+func AdminMiddleware(c *gin.Context) {
+	user, err := GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		c.Abort()
+		return
+	}
+
+	if !user.IsAdmin || !user.IsActive() {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error: "Admin privileges required",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// ListUsers handles GET /admin/users - every user with the number of tasks
+// they have created, newest first
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	limit, offset := 50, 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = min(parsed, MaxUserPageSize)
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	users, err := h.authService.ListUsers(userID, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list users")
+		return
+	}
+
+	c.JSON(http.StatusOK, AdminUsersResponse{
+		Users: users,
+		Total: len(users),
+	})
+}
+
+// UpdateUser handles PATCH /admin/users/:id - activates or deactivates the
+// user and grants or revokes admin privileges
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	var req auth.UserAdminUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+	if req.Active == nil && req.Admin == nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Nothing to update: set active or admin",
+		})
+		return
+	}
+
+	user, err := h.authService.UpdateUser(userID, c.Param("id"), req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser handles DELETE /admin/users/:id?mode=cascade|anonymize. The
+// default, cascade, deletes everything the user owns; anonymize keeps the
+// tasks they shared with others under an anonymized account.
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	mode := auth.UserDeleteMode(c.DefaultQuery("mode", string(auth.UserDeleteCascade)))
+	if err := h.authService.DeleteUser(userID, c.Param("id"), mode); err != nil {
+		h.writeError(c, err, "Failed to delete user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// writeError responds to a failed admin request with the status its error
+// calls for
+func (h *AdminHandler) writeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, auth.ErrAdminRequired):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Admin privileges required"})
+	case errors.Is(err, auth.ErrLastAdmin):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Cannot remove the last active admin"})
+	case errors.Is(err, auth.ErrInvalidDeleteMode):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid delete mode", Details: err.Error()})
+	case errors.Is(err, auth.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	case errors.Is(err, auth.ErrUserAdminUnavailable):
+		c.JSON(http.StatusNotImplemented, ErrorResponse{Error: "User administration is not available"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: message})
+	}
+}
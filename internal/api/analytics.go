@@ -10,7 +10,8 @@ import (
 )
 
 type AnalyticsHandler struct {
-	analyticsService AnalyticsService
+	analyticsService     AnalyticsService
+	taskAnalyticsService TaskAnalyticsService
 }
 
 type AnalyticsService interface {
@@ -21,6 +22,12 @@ type AnalyticsService interface {
 	GetProductivitySummary(userID string, startDate, endDate time.Time) (*ProductivitySummary, error)
 }
 
+// TaskAnalyticsService aggregates a user's task history over a range of
+// time, from since up to but not including until
+type TaskAnalyticsService interface {
+	GetTaskAnalytics(userID string, since, until time.Time) (*models.TaskAnalytics, error)
+}
+
 type ProductivitySummary struct {
 	Period              string                   `json:"period"`
 	StartDate           string                   `json:"start_date"`
@@ -44,6 +51,11 @@ func NewAnalyticsHandler(analyticsService AnalyticsService) *AnalyticsHandler {
 	}
 }
 
+// SetTaskAnalyticsService enables GET /analytics/tasks
+func (h *AnalyticsHandler) SetTaskAnalyticsService(taskAnalyticsService TaskAnalyticsService) {
+	h.taskAnalyticsService = taskAnalyticsService
+}
+
 // GetAnalytics handles GET /analytics - get productivity analytics with date ranges
 func (h *AnalyticsHandler) GetAnalytics(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
@@ -175,4 +187,86 @@ func (h *AnalyticsHandler) GetAnalytics(c *gin.Context) {
 			"total":      len(analytics),
 		})
 	}
-}
\ No newline at end of file
+}
+
+// GetTaskAnalytics handles GET /analytics/tasks - completion trends over the
+// last ?since (e.g. 30d, the default), or from ?start_date to ?end_date
+// inclusive
+func (h *AnalyticsHandler) GetTaskAnalytics(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.taskAnalyticsService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task analytics are not available",
+		})
+		return
+	}
+
+	until := time.Now()
+	var since time.Time
+	startDateStr := c.Query("start_date")
+	endDateStr := c.Query("end_date")
+
+	if startDateStr != "" || endDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid start_date format",
+				Details: "Use YYYY-MM-DD format",
+			})
+			return
+		}
+		since = startDate
+
+		if endDateStr != "" {
+			endDate, err := time.Parse("2006-01-02", endDateStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "Invalid end_date format",
+					Details: "Use YYYY-MM-DD format",
+				})
+				return
+			}
+			until = endDate.AddDate(0, 0, 1)
+		}
+	} else {
+		period, err := models.ParseSnoozeDuration(c.DefaultQuery("since", "30d"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid since",
+				Details: err.Error(),
+			})
+			return
+		}
+		since = until.Add(-period)
+	}
+
+	if !until.After(since) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "End date must be after start date",
+		})
+		return
+	}
+	if until.Sub(since) > 366*24*time.Hour {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Date range too large (maximum 1 year)",
+		})
+		return
+	}
+
+	analytics, err := h.taskAnalyticsService.GetTaskAnalytics(userID, since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to get task analytics",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
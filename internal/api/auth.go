@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -11,7 +12,8 @@ import (
 )
 
 type AuthHandler struct {
-	authService *auth.AuthService
+	authService   *auth.AuthService
+	resetDelivery PasswordResetDelivery
 }
 
 func NewAuthHandler(authService *auth.AuthService) *AuthHandler {
@@ -23,12 +25,20 @@ func NewAuthHandler(authService *auth.AuthService) *AuthHandler {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// DeviceName labels the session in GET /auth/sessions. Empty names it
+	// after the User-Agent.
+	DeviceName string `json:"device_name"`
 }
 
 type LoginResponse struct {
-	Token     string      `json:"token"`
-	User      models.User `json:"user"`
-	ExpiresAt time.Time   `json:"expires_at"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	User         models.User `json:"user"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type ErrorResponse struct {
@@ -51,8 +61,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	ipAddress := c.ClientIP()
 
 	authReq := auth.LoginRequest{
-		Email:    req.Username, // Using Email field to pass username/email 
-		Password: req.Password,
+		Email:      req.Username, // Using Email field to pass username/email 
+		Password:   req.Password,
+		DeviceName: req.DeviceName,
 	}
 
 	loginResp, err := h.authService.Login(authReq, userAgent, ipAddress)
@@ -61,6 +72,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, ErrorResponse{
 				Error: "Invalid credentials",
 			})
+		} else if errors.Is(err, auth.ErrAccountDeactivated) {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error: "Account is deactivated",
+			})
 		} else {
 			c.JSON(http.StatusInternalServerError, ErrorResponse{
 				Error: "Authentication failed",
@@ -69,16 +84,63 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if loginResp.TwoFactorRequired {
+		c.JSON(http.StatusOK, TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Challenge:         loginResp.Challenge,
+			ExpiresAt:         loginResp.ExpiresAt,
+		})
+		return
+	}
+
 	response := LoginResponse{
-		Token:     loginResp.Token,
-		User:      loginResp.User,
-		ExpiresAt: loginResp.ExpiresAt,
+		Token:        loginResp.Token,
+		RefreshToken: loginResp.RefreshToken,
+		User:         loginResp.User,
+		ExpiresAt:    loginResp.ExpiresAt,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// Logout handles POST /auth/logout
+// Refresh handles POST /auth/refresh - exchanges the refresh token from
+// login for a new access token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	refreshResp, err := h.authService.RefreshAccessToken(req.RefreshToken, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		message := "Invalid refresh token"
+		if errors.Is(err, auth.ErrRefreshTokenExpired) {
+			message = "Refresh token expired"
+		} else if errors.Is(err, auth.ErrAccountDeactivated) {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error: "Account is deactivated",
+			})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:     refreshResp.Token,
+		User:      refreshResp.User,
+		ExpiresAt: refreshResp.ExpiresAt,
+	})
+}
+
+// Logout handles POST /auth/logout - ends the current session only. Other
+// devices are signed out with DELETE /auth/sessions.
 func (h *AuthHandler) Logout(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
@@ -146,6 +208,17 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// TokenFromQuery lets a request authenticate with a ?token= query parameter
+// when it has no Authorization header, for browsers' EventSource and
+// WebSockets, which cannot send headers. Mount it only on stream routes,
+// since URLs end up in logs and browser history.
+func TokenFromQuery(c *gin.Context) {
+	if token := c.Query("token"); token != "" && c.GetHeader("Authorization") == "" {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	c.Next()
+}
+
 // GetCurrentUser returns the authenticated user from context
 func GetCurrentUser(c *gin.Context) (*models.User, error) {
 	user, exists := c.Get("user")
@@ -174,4 +247,249 @@ func GetCurrentUserID(c *gin.Context) (string, error) {
 	}
 
 	return id, nil
-}
\ No newline at end of file
+}
+// DeviceAuthMiddleware authenticates a device by its device token, sent as
+// a bearer token, as the password of HTTP Basic auth (how OwnTracks sends
+// credentials) or as a ?token= query parameter (for GPSLogger's custom URL).
+// Session tokens are accepted too.
+func (h *AuthHandler) DeviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := deviceTokenFromRequest(c)
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error: "Device token required",
+			})
+			c.Abort()
+			return
+		}
+
+		var user *models.User
+		var err error
+		if auth.IsDeviceToken(token) {
+			user, err = h.authService.ValidateDeviceToken(token)
+		} else {
+			user, err = h.authService.ValidateToken(token)
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error: "Invalid or expired token",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("user_id", user.ID)
+		c.Next()
+	}
+}
+
+func deviceTokenFromRequest(c *gin.Context) string {
+	if _, password, ok := c.Request.BasicAuth(); ok {
+		return password
+	}
+	if token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		return token
+	}
+	return c.Query("token")
+}
+
+type DeviceTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type DeviceTokenResponse struct {
+	// Token is only ever returned here, when the device token is created
+	Token  string           `json:"token"`
+	Device auth.DeviceToken `json:"device"`
+}
+
+// CreateDeviceToken handles POST /users/me/devices
+func (h *AuthHandler) CreateDeviceToken(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	var req DeviceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	token, device, err := h.authService.CreateDeviceToken(userID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create device token",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, DeviceTokenResponse{Token: token, Device: *device})
+}
+
+// ListDeviceTokens handles GET /users/me/devices
+func (h *AuthHandler) ListDeviceTokens(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	devices, err := h.authService.ListDeviceTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to list device tokens",
+		})
+		return
+	}
+	if devices == nil {
+		devices = []auth.DeviceToken{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+// RevokeDeviceToken handles DELETE /users/me/devices/:deviceId
+func (h *AuthHandler) RevokeDeviceToken(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeDeviceToken(userID, c.Param("deviceId")); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Device token not found",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// FeedAuthMiddleware authenticates a calendar app by the feed token in the
+// feed URL's ?token= query parameter. Calendar apps cannot send headers, and
+// only feed tokens are accepted, so a leaked feed URL exposes the feed and
+// nothing else.
+func (h *AuthHandler) FeedAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error: "Feed token required",
+			})
+			c.Abort()
+			return
+		}
+
+		user, err := h.authService.ValidateFeedToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error: "Invalid or revoked feed token",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("user_id", user.ID)
+		c.Next()
+	}
+}
+
+type FeedTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type FeedTokenResponse struct {
+	// Token is only ever returned here, when the feed token is created
+	Token string         `json:"token"`
+	Feed  auth.FeedToken `json:"feed"`
+}
+
+// CreateFeedToken handles POST /users/me/feeds
+func (h *AuthHandler) CreateFeedToken(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	var req FeedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	token, feed, err := h.authService.CreateFeedToken(userID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to create feed token",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, FeedTokenResponse{Token: token, Feed: *feed})
+}
+
+// ListFeedTokens handles GET /users/me/feeds
+func (h *AuthHandler) ListFeedTokens(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	feeds, err := h.authService.ListFeedTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to list feed tokens",
+		})
+		return
+	}
+	if feeds == nil {
+		feeds = []auth.FeedToken{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feeds": feeds})
+}
+
+// RevokeFeedToken handles DELETE /users/me/feeds/:feedId
+func (h *AuthHandler) RevokeFeedToken(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeFeedToken(userID, c.Param("feedId")); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Feed token not found",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,13 +1,25 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
 	"github.com/gin-gonic/gin"
 )
 
 type ContextHandler struct {
-	contextService ContextService
+	contextService   ContextService
+	locationRecorder LocationRecorder
+}
+
+// LocationRecorder turns location fixes posted by devices into context
+// snapshots
+type LocationRecorder interface {
+	RecordLocation(userID string, fix hereandnow.LocationFix) (*models.Context, bool, error)
 }
 
 type ContextUpdateRequest struct {
@@ -19,6 +31,7 @@ type ContextUpdateRequest struct {
 	EnergyLevel       *int     `json:"energy_level"`
 	WeatherCondition  *string  `json:"weather_condition"`
 	TrafficLevel      *string  `json:"traffic_level"`
+	MinPriority       *int     `json:"min_priority"`
 }
 
 func NewContextHandler(contextService ContextService) *ContextHandler {
@@ -27,6 +40,11 @@ func NewContextHandler(contextService ContextService) *ContextHandler {
 	}
 }
 
+// SetLocationRecorder enables POST /context/location
+func (h *ContextHandler) SetLocationRecorder(recorder LocationRecorder) {
+	h.locationRecorder = recorder
+}
+
 // GetContext handles GET /context - get current user context
 func (h *ContextHandler) GetContext(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
@@ -156,6 +174,16 @@ func (h *ContextHandler) UpdateContext(c *gin.Context) {
 		}
 	}
 
+	if req.MinPriority != nil {
+		if err := context.SetMinPriority(*req.MinPriority); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid minimum priority",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
 	// Update context
 	updatedContext, err := h.contextService.UpdateContext(*context)
 	if err != nil {
@@ -166,4 +194,194 @@ func (h *ContextHandler) UpdateContext(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, updatedContext)
-}
\ No newline at end of file
+}
+// Headers a client can send with any authenticated request to report the
+// device's current context
+const (
+	ContextLatHeader    = "X-Context-Lat"
+	ContextLngHeader    = "X-Context-Lng"
+	ContextEnergyHeader = "X-Context-Energy"
+)
+
+// CaptureHeaders updates the user's context from the X-Context-* headers
+// before the request is handled, so a GET /tasks carrying them is filtered
+// for the context just reported. Requests without the headers pass through
+// untouched; malformed headers are rejected.
+func (h *ContextHandler) CaptureHeaders(c *gin.Context) {
+	latHeader := c.GetHeader(ContextLatHeader)
+	lngHeader := c.GetHeader(ContextLngHeader)
+	energyHeader := c.GetHeader(ContextEnergyHeader)
+	if latHeader == "" && lngHeader == "" && energyHeader == "" {
+		c.Next()
+		return
+	}
+
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.Next()
+		return
+	}
+
+	context, err := h.contextService.GetCurrentContext(userID)
+	if err != nil {
+		context, err = models.NewContext(userID, 0, models.DefaultEnergyLevel)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
+				Error: "Failed to get current context",
+			})
+			return
+		}
+	}
+
+	if latHeader != "" || lngHeader != "" {
+		lat, latErr := strconv.ParseFloat(latHeader, 64)
+		lng, lngErr := strconv.ParseFloat(lngHeader, 64)
+		if latErr != nil || lngErr != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid context headers",
+				Details: ContextLatHeader + " and " + ContextLngHeader + " must both be numbers",
+			})
+			return
+		}
+		if err := context.SetCurrentPosition(lat, lng); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid coordinates",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	if energyHeader != "" {
+		energy, err := strconv.Atoi(energyHeader)
+		if err == nil {
+			err = context.SetEnergyLevel(energy)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid energy level",
+				Details: ContextEnergyHeader + " must be a number from 1 to 5",
+			})
+			return
+		}
+	}
+
+	if _, err := h.contextService.UpdateContext(*context); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to update context",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// LocationUpdateRequest is either an OwnTracks message or a plain fix of
+// lat, lng and accuracy
+type LocationUpdateRequest struct {
+	// Type is set by OwnTracks; only its "location" messages carry a fix
+	Type     string   `json:"_type"`
+	Lat      *float64 `json:"lat"`
+	Lng      *float64 `json:"lng"`
+	Lon      *float64 `json:"lon"`
+	Accuracy *float64 `json:"accuracy"`
+	Acc      *float64 `json:"acc"`
+	// Tst is the Unix time OwnTracks took the fix
+	Tst int64 `json:"tst"`
+}
+
+type LocationUpdateResponse struct {
+	// Recorded is false when the fix was too close in time and distance to
+	// the previous one to be worth a new snapshot
+	Recorded bool            `json:"recorded"`
+	Context  *models.Context `json:"context"`
+}
+
+// RecordLocation handles POST /context/location - the webhook a phone running
+// OwnTracks (HTTP mode) or GPSLogger posts its position to. OwnTracks
+// messages get the empty JSON array OwnTracks expects in reply; other
+// OwnTracks message types are accepted and ignored.
+func (h *ContextHandler) RecordLocation(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.locationRecorder == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Location updates not available",
+		})
+		return
+	}
+
+	var req LocationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	ownTracks := req.Type != ""
+	if ownTracks && req.Type != "location" {
+		c.JSON(http.StatusOK, []interface{}{})
+		return
+	}
+
+	fix, err := req.fix()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid location",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	context, recorded, err := h.locationRecorder.RecordLocation(userID, fix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to record location",
+		})
+		return
+	}
+
+	if ownTracks {
+		c.JSON(http.StatusOK, []interface{}{})
+		return
+	}
+	c.JSON(http.StatusOK, LocationUpdateResponse{Recorded: recorded, Context: context})
+}
+
+func (r LocationUpdateRequest) fix() (hereandnow.LocationFix, error) {
+	lng := r.Lng
+	if lng == nil {
+		lng = r.Lon
+	}
+	if r.Lat == nil || lng == nil {
+		return hereandnow.LocationFix{}, fmt.Errorf("lat and lng are required")
+	}
+
+	// SetCurrentPosition validates the coordinates
+	var check models.Context
+	if err := check.SetCurrentPosition(*r.Lat, *lng); err != nil {
+		return hereandnow.LocationFix{}, err
+	}
+
+	fix := hereandnow.LocationFix{Latitude: *r.Lat, Longitude: *lng}
+	if r.Accuracy != nil {
+		fix.AccuracyMeters = *r.Accuracy
+	} else if r.Acc != nil {
+		fix.AccuracyMeters = *r.Acc
+	}
+	if fix.AccuracyMeters < 0 {
+		return hereandnow.LocationFix{}, fmt.Errorf("accuracy cannot be negative")
+	}
+	if r.Tst > 0 {
+		fix.RecordedAt = time.Unix(r.Tst, 0)
+	}
+	return fix, nil
+}
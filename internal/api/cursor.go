@@ -0,0 +1,267 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued by this API
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// MaxTaskPageSize caps ?limit= on GET /tasks
+const MaxTaskPageSize = 200
+
+// TaskSortFields are the fields GET /tasks can be sorted on
+var TaskSortFields = []string{"created_at", "due_at", "priority", "title", "position", RelevanceSort}
+
+// RelevanceSort keeps tasks in the order they were listed in, such as a
+// search's best matches first. It is the default order of a search.
+const RelevanceSort = "relevance"
+
+// TaskOrder is how a task listing is sorted. Ties are broken by task ID so
+// pages are stable however many tasks share a value.
+type TaskOrder struct {
+	// SortBy is one of TaskSortFields. Empty sorts by created_at.
+	SortBy string
+	Desc   bool
+}
+
+// ParseTaskOrder reads ?sort= and ?order=. The order defaults to asc,
+// except for priority, where the most important tasks come first.
+func ParseTaskOrder(sortBy, order string) (TaskOrder, error) {
+	if sortBy != "" && !isTaskSortField(sortBy) {
+		return TaskOrder{}, fmt.Errorf("invalid sort field: %s (valid: %s)", sortBy, strings.Join(TaskSortFields, ", "))
+	}
+
+	result := TaskOrder{SortBy: sortBy, Desc: sortBy == "priority"}
+	switch order {
+	case "":
+	case "asc":
+		result.Desc = false
+	case "desc":
+		result.Desc = true
+	default:
+		return TaskOrder{}, fmt.Errorf("invalid order: %s (valid: asc, desc)", order)
+	}
+
+	// The default ordering is always written the same way so its cursors
+	// keep their original form
+	if result.SortBy == "created_at" && !result.Desc {
+		result.SortBy = ""
+	}
+	return result, nil
+}
+
+func isTaskSortField(field string) bool {
+	for _, valid := range TaskSortFields {
+		if field == valid {
+			return true
+		}
+	}
+	return false
+}
+
+func (o TaskOrder) field() string {
+	if o.SortBy == "" {
+		return "created_at"
+	}
+	return o.SortBy
+}
+
+func (o TaskOrder) direction() string {
+	if o.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// Less reports whether a sorts before b. Tasks without a due date come
+// last when sorting by due date in either direction.
+func (o TaskOrder) Less(a, b models.Task) bool {
+	if o.SortBy == RelevanceSort {
+		return false
+	}
+	if o.field() == "due_at" && (a.DueAt == nil) != (b.DueAt == nil) {
+		return b.DueAt == nil
+	}
+
+	cmp := 0
+	switch o.field() {
+	case "created_at":
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	case "due_at":
+		if a.DueAt != nil {
+			cmp = a.DueAt.Compare(*b.DueAt)
+		}
+	case "priority":
+		cmp = a.Priority - b.Priority
+	case "title":
+		cmp = strings.Compare(a.Title, b.Title)
+	case "position":
+		if a.Position != b.Position {
+			cmp = 1
+			if a.Position < b.Position {
+				cmp = -1
+			}
+		}
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID, b.ID)
+	}
+	if o.Desc {
+		cmp = -cmp
+	}
+	return cmp < 0
+}
+
+// SortTasks sorts tasks in place in the order
+func SortTasks(tasks []models.Task, order TaskOrder) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return order.Less(tasks[i], tasks[j])
+	})
+}
+
+// TaskCursor is the sort key of the last task on a page. The next page
+// starts strictly after it in the page's ordering, so tasks added or
+// completed between fetches do not shift pages the way an offset does.
+type TaskCursor struct {
+	CreatedAt time.Time
+	ID        string
+	// Order is the ordering the cursor was issued for, and Key the last
+	// task's value of its sort field. Both are zero for the default
+	// ordering (created_at, then id). A relevance cursor's Key is the last
+	// task's position in the listing, as relevance has no sort key.
+	Order TaskOrder
+	Key   string
+}
+
+// NewTaskCursor returns the cursor positioned at task in the order
+func NewTaskCursor(task models.Task, order TaskOrder) TaskCursor {
+	cursor := TaskCursor{CreatedAt: task.CreatedAt, ID: task.ID, Order: order}
+	switch order.SortBy {
+	case "due_at":
+		if task.DueAt != nil {
+			cursor.Key = task.DueAt.UTC().Format(time.RFC3339Nano)
+		}
+	case "priority":
+		cursor.Key = strconv.Itoa(task.Priority)
+	case "title":
+		cursor.Key = task.Title
+	case "position":
+		cursor.Key = strconv.FormatFloat(task.Position, 'g', -1, 64)
+	}
+	return cursor
+}
+
+// Encode returns the cursor as an opaque, URL-safe string
+func (c TaskCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	if c.Order != (TaskOrder{}) {
+		raw += "|" + c.Order.field() + "|" + c.Order.direction() + "|" + c.Key
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTaskCursor reads a cursor written by Encode
+func DecodeTaskCursor(value string) (*TaskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// The key comes last as a title may contain the separator
+	parts := strings.SplitN(string(raw), "|", 5)
+	if (len(parts) != 2 && len(parts) != 5) || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &TaskCursor{CreatedAt: at, ID: parts[1]}
+	if len(parts) == 2 {
+		return cursor, nil
+	}
+
+	if cursor.Order, err = ParseTaskOrder(parts[2], parts[3]); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor.Key = parts[4]
+	if _, err := cursor.task(); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// task returns a task with the cursor's sort key, to compare others with
+func (c TaskCursor) task() (models.Task, error) {
+	task := models.Task{ID: c.ID, CreatedAt: c.CreatedAt}
+	var err error
+	switch c.Order.SortBy {
+	case "due_at":
+		if c.Key != "" {
+			var due time.Time
+			due, err = time.Parse(time.RFC3339Nano, c.Key)
+			task.DueAt = &due
+		}
+	case "priority":
+		task.Priority, err = strconv.Atoi(c.Key)
+	case "title":
+		task.Title = c.Key
+	case "position":
+		task.Position, err = strconv.ParseFloat(c.Key, 64)
+	case RelevanceSort:
+		_, err = strconv.Atoi(c.Key)
+	}
+	return task, err
+}
+
+// Before reports whether task sorts before or at the cursor, i.e. was on an
+// earlier page
+func (c TaskCursor) Before(task models.Task) bool {
+	at, _ := c.task()
+	return !c.Order.Less(at, task)
+}
+
+// PageTasks returns the page of tasks after the cursor in the order, with
+// at most limit tasks, and the cursor for the following page. The returned
+// cursor is empty on the last page. A nil after starts from the first task;
+// a limit of zero or less returns every remaining task.
+func PageTasks(tasks []models.Task, order TaskOrder, after *TaskCursor, limit int) ([]models.Task, string) {
+	sorted := make([]models.Task, len(tasks))
+	copy(sorted, tasks)
+	SortTasks(sorted, order)
+
+	// A relevance page starts after the cursor's position
+	last := -1
+	if after != nil && order.SortBy == RelevanceSort {
+		last, _ = strconv.Atoi(after.Key)
+	}
+
+	page := []models.Task{}
+	for i, task := range sorted {
+		if order.SortBy == RelevanceSort && i <= last {
+			continue
+		}
+		if order.SortBy != RelevanceSort && after != nil && after.Before(task) {
+			continue
+		}
+		if limit > 0 && len(page) == limit {
+			cursor := NewTaskCursor(page[len(page)-1], order)
+			if order.SortBy == RelevanceSort {
+				cursor.Key = strconv.Itoa(i - 1)
+			}
+			return page, cursor.Encode()
+		}
+		page = append(page, task)
+	}
+	return page, ""
+}
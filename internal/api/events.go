@@ -1,67 +1,49 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
 	"github.com/gin-gonic/gin"
 )
 
+// defaultHeartbeat is how often an idle event stream sends a comment so
+// proxies don't close the connection
+const defaultHeartbeat = 30 * time.Second
+
 type EventsHandler struct {
 	eventService EventService
+	heartbeat    time.Duration
+	listAccess   ListAccessService
+	listEvents   ListEventService
 }
 
+// EventService feeds each connected user the changes they can see
 type EventService interface {
-	Subscribe(userID string) (<-chan Event, func(), error)
-	PublishTaskEvent(event TaskEvent) error
-	PublishContextEvent(event ContextEvent) error
-	PublishSystemEvent(event SystemEvent) error
-	GetActiveSubscribers() int
-}
-
-type Event struct {
-	ID        string      `json:"id"`
-	Type      string      `json:"type"`
-	UserID    string      `json:"user_id"`
-	Timestamp time.Time   `json:"timestamp"`
-	Data      interface{} `json:"data"`
-}
-
-type TaskEvent struct {
-	TaskID    string      `json:"task_id"`
-	Action    string      `json:"action"` // created, updated, completed, deleted, assigned
-	Task      models.Task `json:"task"`
-	Changes   []string    `json:"changes,omitempty"`
-	UserID    string      `json:"user_id"`
-}
-
-type ContextEvent struct {
-	ContextID string         `json:"context_id"`
-	Action    string         `json:"action"` // updated, location_changed
-	Context   models.Context `json:"context"`
-	UserID    string         `json:"user_id"`
-}
-
-type SystemEvent struct {
-	Message  string                 `json:"message"`
-	Level    string                 `json:"level"` // info, warning, error
-	Category string                 `json:"category"`
-	Data     map[string]interface{} `json:"data,omitempty"`
-	UserID   string                 `json:"user_id"`
+	Subscribe(userID, lastEventID string) *hereandnow.Subscription
+	ActiveSubscribers() int
 }
 
 func NewEventsHandler(eventService EventService) *EventsHandler {
 	return &EventsHandler{
 		eventService: eventService,
+		heartbeat:    defaultHeartbeat,
 	}
 }
 
-// GetEvents handles GET /events (SSE) - Server-Sent Events for real-time updates
+// SetHeartbeat changes how often idle streams send a heartbeat comment
+func (h *EventsHandler) SetHeartbeat(interval time.Duration) {
+	h.heartbeat = interval
+}
+
+// GetEvents handles GET /events (SSE) - streams task and list changes the
+// user can see as they happen. A client that reconnects with Last-Event-ID
+// (or ?last_event_id=) first receives the events it missed; when those are
+// no longer buffered it receives a reset event and should reload.
 func (h *EventsHandler) GetEvents(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
@@ -71,101 +53,80 @@ func (h *EventsHandler) GetEvents(c *gin.Context) {
 		return
 	}
 
-	// Parse query parameters
-	keepAlive := 30 // Default keep-alive interval in seconds
+	heartbeat := h.heartbeat
 	if keepAliveStr := c.Query("keep_alive"); keepAliveStr != "" {
 		if ka, err := strconv.Atoi(keepAliveStr); err == nil && ka > 0 && ka <= 300 {
-			keepAlive = ka
+			heartbeat = time.Duration(ka) * time.Second
 		}
 	}
 
-	// Set SSE headers
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub := h.eventService.Subscribe(userID, lastEventID)
+	defer sub.Close()
+
+	// The stream outlives the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Headers", "Cache-Control")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
 
-	// Subscribe to events for this user
-	eventChan, unsubscribe, err := h.eventService.Subscribe(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to subscribe to events",
+	if sub.Reset {
+		h.sendSSEEvent(c, "", "reset", map[string]string{
+			"message": "Missed events are no longer available; reload",
 		})
-		return
 	}
-
-	// Ensure cleanup on connection close
-	defer unsubscribe()
-
-	// Send initial connection event
-	h.sendSSEEvent(c, "connected", map[string]interface{}{
-		"user_id":    userID,
-		"timestamp":  time.Now(),
-		"keep_alive": keepAlive,
-		"message":    "Successfully connected to event stream",
+	for _, event := range sub.Missed {
+		h.sendSSEEvent(c, event.ID, string(event.Type), event)
+	}
+	h.sendSSEEvent(c, "", "connected", map[string]interface{}{
+		"user_id":   userID,
+		"heartbeat": heartbeat.Seconds(),
 	})
+	c.Writer.Flush()
 
-	// Context for handling client disconnection
-	ctx, cancel := context.WithCancel(c.Request.Context())
-	defer cancel()
-
-	// Keep-alive ticker
-	keepAliveTicker := time.NewTicker(time.Duration(keepAlive) * time.Second)
-	defer keepAliveTicker.Stop()
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
 
-	// Event loop
 	for {
 		select {
-		case <-ctx.Done():
-			// Client disconnected
+		case <-c.Request.Context().Done():
 			return
 
-		case event, ok := <-eventChan:
+		case event, ok := <-sub.Events():
 			if !ok {
-				// Channel closed, send close event and exit
-				h.sendSSEEvent(c, "disconnected", map[string]interface{}{
-					"message": "Event stream closed",
-					"reason":  "service_shutdown",
-				})
+				// Dropped for falling behind; the client reconnects and
+				// resumes from its last event
 				return
 			}
+			h.sendSSEEvent(c, event.ID, string(event.Type), event)
 
-			// Send the event to client
-			h.sendSSEEvent(c, event.Type, event)
-
-		case <-keepAliveTicker.C:
-			// Send keep-alive ping
-			h.sendSSEEvent(c, "ping", map[string]interface{}{
-				"timestamp": time.Now(),
-				"active_subscribers": h.eventService.GetActiveSubscribers(),
-			})
+		case <-ticker.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
 		}
 
-		// Flush the response to ensure real-time delivery
-		if f, ok := c.Writer.(http.Flusher); ok {
-			f.Flush()
-		}
+		c.Writer.Flush()
 	}
 }
 
-// sendSSEEvent formats and sends a Server-Sent Event
-func (h *EventsHandler) sendSSEEvent(c *gin.Context, eventType string, data interface{}) {
-	// Generate event ID
-	eventID := fmt.Sprintf("%d", time.Now().UnixNano())
-
-	// Marshal data to JSON
+// sendSSEEvent writes one Server-Sent Event. Events without an ID don't move
+// the client's resume point.
+func (h *EventsHandler) sendSSEEvent(c *gin.Context, id, eventType string, data interface{}) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		// Send error event if JSON marshaling fails
-		c.Writer.Write([]byte(fmt.Sprintf("event: error\n")))
-		c.Writer.Write([]byte(fmt.Sprintf("id: %s\n", eventID)))
-		c.Writer.Write([]byte(fmt.Sprintf("data: {\"error\":\"Failed to marshal event data\"}\n\n")))
-		return
+		eventType = "error"
+		jsonData = []byte(`{"error":"Failed to marshal event data"}`)
 	}
 
-	// Write SSE formatted event
-	c.Writer.Write([]byte(fmt.Sprintf("event: %s\n", eventType)))
-	c.Writer.Write([]byte(fmt.Sprintf("id: %s\n", eventID)))
-	c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", jsonData)))
-}
\ No newline at end of file
+	fmt.Fprintf(c.Writer, "event: %s\n", eventType)
+	if id != "" {
+		fmt.Fprintf(c.Writer, "id: %s\n", id)
+	}
+	fmt.Fprintf(c.Writer, "data: %s\n\n", jsonData)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// ListAccessService decides who can follow a list's changes
+type ListAccessService interface {
+	CanViewList(listID, userID string) (bool, error)
+}
+
+// ListEventService feeds a list's changes to the people following it
+type ListEventService interface {
+	SubscribeList(listID, userID string) *hereandnow.Subscription
+}
+
+// ListStreamMessage is one change pushed over a list stream: the event with
+// its type renamed for clients, e.g. task_added or member_joined
+type ListStreamMessage struct {
+	Type string `json:"type"`
+	hereandnow.ChangeEvent
+}
+
+// listStreamTypes names each change on list streams
+var listStreamTypes = map[hereandnow.ChangeEventType]string{
+	hereandnow.EventTaskCreated:       "task_added",
+	hereandnow.EventTaskUpdated:       "task_updated",
+	hereandnow.EventTaskCompleted:     "task_completed",
+	hereandnow.EventTaskDeleted:       "task_deleted",
+	hereandnow.EventListMemberAdded:   "member_joined",
+	hereandnow.EventListMemberRemoved: "member_removed",
+}
+
+// EnableListStreams enables GET /lists/{id}/stream
+func (h *EventsHandler) EnableListStreams(access ListAccessService, events ListEventService) {
+	h.listAccess = access
+	h.listEvents = events
+}
+
+// StreamList handles GET /lists/{id}/stream (WebSocket) - pushes the list's
+// changes as JSON messages to its owner and accepted members, viewers
+// included. The connection is closed when the user is removed from the
+// list.
+func (h *EventsHandler) StreamList(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.listAccess == nil || h.listEvents == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "List streams are not enabled",
+		})
+		return
+	}
+
+	listID := c.Param("id")
+	canView, err := h.listAccess.CanViewList(listID, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   "Failed to open list stream",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !canView {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error: "You are not a member of this list",
+		})
+		return
+	}
+
+	// Clients authenticate with the token, so any origin may connect
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			h.streamList(ws, listID, userID)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// streamList sends the list's changes until the client goes away or the
+// subscription ends
+func (h *EventsHandler) streamList(ws *websocket.Conn, listID, userID string) {
+	defer ws.Close()
+
+	// The stream outlives the server's timeouts
+	ws.SetDeadline(time.Time{})
+
+	sub := h.listEvents.SubscribeList(listID, userID)
+	defer sub.Close()
+
+	// Clients don't send anything; reading only notices when they leave
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		io.Copy(io.Discard, ws)
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Removed from the list or fell too far behind
+				return
+			}
+			message := ListStreamMessage{Type: listStreamTypes[event.Type], ChangeEvent: event}
+			if message.Type == "" {
+				message.Type = string(event.Type)
+			}
+			if err := websocket.JSON.Send(ws, message); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
 	"github.com/bcnelson/hereAndNow/pkg/models"
 	"github.com/gin-gonic/gin"
 )
 
 type ListHandler struct {
-	listService ListService
+	listService    ListService
+	archiveService ListArchiveService
 }
 
 type ListService interface {
@@ -19,6 +23,15 @@ type ListService interface {
 	DeleteList(listID string, userID string) error
 	GetListMembers(listID string) ([]models.ListMember, error)
 	AddListMember(member models.ListMember) (*models.ListMember, error)
+	// ApplyListDefaults gives the list's open tasks its current defaults,
+	// returning how many changed
+	ApplyListDefaults(listID string, userID string) (int, error)
+}
+
+// ListArchiveService archives and unarchives lists on behalf of their owner
+type ListArchiveService interface {
+	ArchiveList(listID, userID string) (*models.TaskList, error)
+	UnarchiveList(listID, userID string) (*models.TaskList, error)
 }
 
 type TaskListWithMembers struct {
@@ -33,6 +46,31 @@ type ListCreateRequest struct {
 	Icon        string  `json:"icon"`
 	IsShared    bool    `json:"is_shared"`
 	ParentID    *string `json:"parent_id"`
+	// DefaultLocationID and DefaultEstimatedMinutes are given to tasks
+	// added to the list without their own
+	DefaultLocationID       *string `json:"default_location_id"`
+	DefaultEstimatedMinutes *int    `json:"default_estimated_minutes"`
+}
+
+// ListUpdateRequest changes the fields that are set. An empty
+// default_location_id and a default_estimated_minutes of 0 clear those
+// defaults; apply_to_existing also applies the defaults to the list's open
+// tasks.
+type ListUpdateRequest struct {
+	Name                    *string `json:"name"`
+	Description             *string `json:"description"`
+	Color                   *string `json:"color"`
+	Icon                    *string `json:"icon"`
+	DefaultLocationID       *string `json:"default_location_id"`
+	DefaultEstimatedMinutes *int    `json:"default_estimated_minutes"`
+	ApplyToExisting         bool    `json:"apply_to_existing"`
+}
+
+// ListUpdateResponse is the updated list and, with apply_to_existing, how
+// many of its tasks took the new defaults
+type ListUpdateResponse struct {
+	models.TaskList
+	TasksUpdated int `json:"tasks_updated"`
 }
 
 func NewListHandler(listService ListService) *ListHandler {
@@ -41,7 +79,14 @@ func NewListHandler(listService ListService) *ListHandler {
 	}
 }
 
-// GetLists handles GET /lists - get user's task lists with sharing info
+// SetArchiveService enables POST /lists/{id}/archive and /unarchive
+func (h *ListHandler) SetArchiveService(service ListArchiveService) {
+	h.archiveService = service
+}
+
+// GetLists handles GET /lists - get user's task lists with sharing info.
+// Archived lists, and lists under an archived parent, are left out unless
+// includeArchived=true.
 func (h *ListHandler) GetLists(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
@@ -59,6 +104,10 @@ func (h *ListHandler) GetLists(c *gin.Context) {
 		return
 	}
 
+	if c.Query("includeArchived") != "true" {
+		lists = withoutArchivedLists(lists)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"lists": lists,
 		"total": len(lists),
@@ -123,6 +172,18 @@ func (h *ListHandler) CreateList(c *gin.Context) {
 		}
 	}
 
+	if req.DefaultLocationID != nil {
+		taskList.SetDefaultLocation(*req.DefaultLocationID)
+	}
+
+	if err := taskList.SetDefaultEstimatedMinutes(req.DefaultEstimatedMinutes); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid default estimate",
+			Details: err.Error(),
+		})
+		return
+	}
+
 	// Create task list
 	createdList, err := h.listService.CreateList(*taskList)
 	if err != nil {
@@ -133,4 +194,166 @@ func (h *ListHandler) CreateList(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, createdList)
-}
\ No newline at end of file
+}
+
+// UpdateList handles PATCH /lists/:id - update a task list the user owns
+func (h *ListHandler) UpdateList(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	var req ListUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	list, err := h.listService.GetListByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Task list not found",
+		})
+		return
+	}
+	if !list.IsOwnedBy(userID) {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error: "Only the list owner can update it",
+		})
+		return
+	}
+
+	if err := applyListUpdate(list, req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid task list data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.listService.UpdateList(*list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to update task list",
+		})
+		return
+	}
+
+	response := ListUpdateResponse{TaskList: *updated}
+	if req.ApplyToExisting {
+		response.TasksUpdated, err = h.listService.ApplyListDefaults(updated.ID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "Failed to apply list defaults to its tasks",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// applyListUpdate sets the fields of req that are present on list
+func applyListUpdate(list *models.TaskList, req ListUpdateRequest) error {
+	if req.Name != nil {
+		if err := list.SetName(*req.Name); err != nil {
+			return err
+		}
+	}
+	if req.Description != nil {
+		list.SetDescription(*req.Description)
+	}
+	if req.Color != nil {
+		if err := list.SetColor(*req.Color); err != nil {
+			return err
+		}
+	}
+	if req.Icon != nil {
+		list.SetIcon(*req.Icon)
+	}
+	if req.DefaultLocationID != nil {
+		list.SetDefaultLocation(*req.DefaultLocationID)
+	}
+	if req.DefaultEstimatedMinutes != nil {
+		minutes := req.DefaultEstimatedMinutes
+		if *minutes == 0 {
+			minutes = nil
+		}
+		if err := list.SetDefaultEstimatedMinutes(minutes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveList handles POST /lists/:id/archive - archive a list the user owns
+func (h *ListHandler) ArchiveList(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// UnarchiveList handles POST /lists/:id/unarchive - bring back a list the
+// user owns
+func (h *ListHandler) UnarchiveList(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *ListHandler) setArchived(c *gin.Context, archived bool) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.archiveService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "List archiving is not enabled",
+		})
+		return
+	}
+
+	var list *models.TaskList
+	if archived {
+		list, err = h.archiveService.ArchiveList(c.Param("id"), userID)
+	} else {
+		list, err = h.archiveService.UnarchiveList(c.Param("id"), userID)
+	}
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, list)
+	case errors.Is(err, hereandnow.ErrNotListOwner):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Task list not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to update task list",
+			Details: err.Error(),
+		})
+	}
+}
+
+// withoutArchivedLists drops archived lists and the lists under them
+func withoutArchivedLists(lists []TaskListWithMembers) []TaskListWithMembers {
+	plain := make([]models.TaskList, len(lists))
+	for i, list := range lists {
+		plain[i] = list.TaskList
+	}
+	archived := models.ArchivedListIDs(plain)
+
+	active := make([]TaskListWithMembers, 0, len(lists))
+	for _, list := range lists {
+		if !archived[list.ID] {
+			active = append(active, list)
+		}
+	}
+	return active
+}
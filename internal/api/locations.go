@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/bcnelson/hereAndNow/pkg/models"
 	"github.com/gin-gonic/gin"
@@ -25,8 +26,13 @@ type LocationCreateRequest struct {
 	Latitude  float64  `json:"latitude" binding:"required"`
 	Longitude float64  `json:"longitude" binding:"required"`
 	Radius    int      `json:"radius"`
-	Category  string   `json:"category"`
+	Category  string   `json:"category"` // One of home, work, errand, gym, school or other (the default)
 	PlaceID   *string  `json:"place_id"`
+	// AllowCustomCategory accepts a category outside the fixed set
+	AllowCustomCategory bool `json:"allow_custom_category"`
+	// OpenHours lists opening ranges by weekday, e.g.
+	// {"mon": [{"open": "08:00", "close": "21:00"}]}; omit for always open
+	OpenHours models.OpenHours `json:"open_hours"`
 }
 
 func NewLocationHandler(locationService LocationService) *LocationHandler {
@@ -83,11 +89,6 @@ func (h *LocationHandler) CreateLocation(c *gin.Context) {
 		req.Radius = 100 // Default 100 meters
 	}
 
-	// Set default category if not provided
-	if req.Category == "" {
-		req.Category = "other"
-	}
-
 	// Create location model using NewLocation constructor for validation
 	location, err := models.NewLocation(user.ID, req.Name, req.Address, req.Latitude, req.Longitude, req.Radius)
 	if err != nil {
@@ -99,8 +100,21 @@ func (h *LocationHandler) CreateLocation(c *gin.Context) {
 	}
 
 	// Set optional fields
-	location.Category = req.Category
+	if err := setLocationCategory(location, req.Category, req.AllowCustomCategory); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid category",
+			Details: err.Error(),
+		})
+		return
+	}
 	location.PlaceID = req.PlaceID
+	if err := location.SetOpenHours(req.OpenHours); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid opening hours",
+			Details: err.Error(),
+		})
+		return
+	}
 
 	// Create location
 	createdLocation, err := h.locationService.CreateLocation(*location)
@@ -112,4 +126,18 @@ func (h *LocationHandler) CreateLocation(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, createdLocation)
-}
\ No newline at end of file
+}
+
+// setLocationCategory files the location under the named category, which
+// must be a known one unless custom categories are allowed. No category is
+// other.
+func setLocationCategory(location *models.Location, name string, allowCustom bool) error {
+	if allowCustom && strings.TrimSpace(name) != "" {
+		return location.SetCustomCategory(name)
+	}
+	category, err := models.ParseLocationCategory(name)
+	if err != nil {
+		return err
+	}
+	return location.SetCategory(category)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetDelivery gets a password reset token to the user it was
+// issued for, e.g. by email
+type PasswordResetDelivery interface {
+	DeliverPasswordReset(email, token string) error
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// SetPasswordResetDelivery turns on POST /auth/forgot, which answers 501
+// until a delivery is set
+func (h *AuthHandler) SetPasswordResetDelivery(delivery PasswordResetDelivery) {
+	h.resetDelivery = delivery
+}
+
+// ForgotPassword handles POST /auth/forgot. It answers 202 whether or not
+// the email belongs to an account, so it cannot be used to find accounts.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	if h.resetDelivery == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{Error: "Password reset is not available"})
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	token, err := h.authService.RequestPasswordReset(req.Email)
+	switch {
+	case err == nil:
+		if err := h.resetDelivery.DeliverPasswordReset(req.Email, token); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "Failed to send password reset",
+				Details: err.Error(),
+			})
+			return
+		}
+	case errors.Is(err, auth.ErrPasswordResetNoAccount):
+	case errors.Is(err, auth.ErrPasswordResetUnavailable):
+		c.JSON(http.StatusNotImplemented, ErrorResponse{Error: "Password reset is not available"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to start password reset",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account uses that email, a password reset has been sent to it",
+	})
+}
+
+// ResetPassword handles POST /auth/reset - sets a new password with a
+// token from /auth/forgot
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	err := h.authService.ResetPassword(req.Token, req.NewPassword)
+	switch {
+	case err == nil:
+		c.Status(http.StatusNoContent)
+	case errors.Is(err, auth.ErrInvalidResetToken), errors.Is(err, auth.ErrInvalidNewPassword):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, auth.ErrPasswordResetUnavailable):
+		c.JSON(http.StatusNotImplemented, ErrorResponse{Error: "Password reset is not available"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to reset password",
+			Details: err.Error(),
+		})
+	}
+}
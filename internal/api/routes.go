@@ -0,0 +1,284 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const basePathKey = "base_path"
+
+// Handlers groups the handlers mounted by SetupRoutes. Nil context and
+// location handlers are served as not-implemented placeholders.
+type Handlers struct {
+	Auth           *AuthHandler
+	Tasks          *TaskHandler
+	Users          *UserHandler
+	Contexts       *ContextHandler
+	Locations      *LocationHandler
+	Lists          *ListHandler
+	Events         *EventsHandler
+	Analytics      *AnalyticsHandler
+	Admin          *AdminHandler
+	AuthMiddleware gin.HandlerFunc
+	// DeviceAuthMiddleware authenticates devices posting to
+	// /context/location. It defaults to Auth's device token middleware,
+	// then to AuthMiddleware.
+	DeviceAuthMiddleware gin.HandlerFunc
+	// FeedAuthMiddleware authenticates calendar apps fetching
+	// /tasks/export.ics. It defaults to Auth's feed token middleware, then
+	// to AuthMiddleware.
+	FeedAuthMiddleware gin.HandlerFunc
+}
+
+// RouteConfig controls where routes are mounted
+type RouteConfig struct {
+	// BasePath prefixes every route, e.g. "/app" when hosted behind a
+	// reverse proxy on a subpath. Empty mounts at the root.
+	BasePath string
+	// DocsDir is served at /docs when set
+	DocsDir string
+	Version string
+	// CaptureContextHeaders updates the user's context from X-Context-*
+	// request headers on every authenticated route. It needs a Contexts
+	// handler.
+	CaptureContextHeaders bool
+}
+
+// NormalizeBasePath returns path with a single leading slash and no trailing
+// slash. The root path normalizes to the empty string.
+func NormalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+// SetupRoutes mounts the health check, API and docs routes on router under
+// config.BasePath and returns the base route group.
+func SetupRoutes(router *gin.Engine, handlers Handlers, config RouteConfig) *gin.RouterGroup {
+	basePath := NormalizeBasePath(config.BasePath)
+
+	base := router.Group(basePath)
+	base.Use(func(c *gin.Context) {
+		c.Set(basePathKey, basePath)
+		c.Next()
+	})
+
+	// Health check endpoint
+	base.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"timestamp": time.Now().Format(time.RFC3339),
+			"service":   "hereandnow-api",
+			"version":   config.Version,
+		})
+	})
+
+	// API v1 routes
+	v1 := base.Group("/api/v1")
+	{
+		// Authentication routes (no auth required)
+		if handlers.Auth != nil {
+			auth := v1.Group("/auth")
+			auth.POST("/login", handlers.Auth.Login)
+			auth.POST("/logout", handlers.Auth.Logout)
+			auth.POST("/refresh", handlers.Auth.Refresh)
+			auth.POST("/2fa/login", handlers.Auth.TwoFactorLogin)
+			auth.POST("/forgot", handlers.Auth.ForgotPassword)
+			auth.POST("/reset", handlers.Auth.ResetPassword)
+		}
+
+		// Protected routes (require authentication)
+		authMiddleware := handlers.AuthMiddleware
+		if authMiddleware == nil && handlers.Auth != nil {
+			authMiddleware = handlers.Auth.AuthMiddleware()
+		}
+
+		// The calendar feed, which calendar apps fetch with a feed token
+		// in the URL. Session tokens are not accepted there, so the URL
+		// never carries a token that can do more than read the feed.
+		if handlers.Tasks != nil {
+			feedAuth := handlers.FeedAuthMiddleware
+			if feedAuth == nil && handlers.Auth != nil {
+				feedAuth = handlers.Auth.FeedAuthMiddleware()
+			}
+			if feedAuth == nil {
+				feedAuth = authMiddleware
+			}
+			var feed []gin.HandlerFunc
+			if feedAuth != nil {
+				feed = append(feed, feedAuth)
+			}
+			v1.GET("/tasks/export.ics", append(feed, handlers.Tasks.ExportICS)...)
+		}
+
+		// The event streams, which browsers' EventSource and WebSockets
+		// fetch with the token in the URL
+		stream := []gin.HandlerFunc{TokenFromQuery}
+		if authMiddleware != nil {
+			stream = append(stream, authMiddleware)
+		}
+		if handlers.Events != nil {
+			v1.GET("/events", append(stream, handlers.Events.GetEvents)...)
+			v1.GET("/lists/:id/stream", append(stream, handlers.Events.StreamList)...)
+		}
+
+		// The location webhook, which phones call with a device token
+		if handlers.Contexts != nil {
+			deviceAuth := handlers.DeviceAuthMiddleware
+			if deviceAuth == nil && handlers.Auth != nil {
+				deviceAuth = handlers.Auth.DeviceAuthMiddleware()
+			}
+			if deviceAuth == nil {
+				deviceAuth = authMiddleware
+			}
+			var device []gin.HandlerFunc
+			if deviceAuth != nil {
+				device = append(device, deviceAuth)
+			}
+			v1.POST("/context/location", append(device, handlers.Contexts.RecordLocation)...)
+		}
+
+		protected := v1.Group("/")
+		if authMiddleware != nil {
+			protected.Use(authMiddleware)
+		}
+		if config.CaptureContextHeaders && handlers.Contexts != nil {
+			protected.Use(handlers.Contexts.CaptureHeaders)
+		}
+
+		if handlers.Users != nil {
+			users := protected.Group("/users")
+			users.GET("/me", handlers.Users.GetMe)
+			users.PATCH("/me", handlers.Users.UpdateMe)
+			users.GET("/me/stats", handlers.Users.GetMyStats)
+			users.GET("/me/notifications", handlers.Users.GetNotificationSettings)
+			users.PATCH("/me/notifications", handlers.Users.UpdateNotificationSettings)
+		}
+
+		if handlers.Auth != nil {
+			devices := protected.Group("/users/me/devices")
+			devices.GET("", handlers.Auth.ListDeviceTokens)
+			devices.POST("", handlers.Auth.CreateDeviceToken)
+			devices.DELETE("/:deviceId", handlers.Auth.RevokeDeviceToken)
+
+			feeds := protected.Group("/users/me/feeds")
+			feeds.GET("", handlers.Auth.ListFeedTokens)
+			feeds.POST("", handlers.Auth.CreateFeedToken)
+			feeds.DELETE("/:feedId", handlers.Auth.RevokeFeedToken)
+
+			sessions := protected.Group("/auth/sessions")
+			sessions.GET("", handlers.Auth.ListSessions)
+			sessions.DELETE("", handlers.Auth.RevokeOtherSessions)
+			sessions.DELETE("/:sessionId", handlers.Auth.RevokeSession)
+
+			twoFactor := protected.Group("/auth/2fa")
+			twoFactor.POST("/enable", handlers.Auth.EnableTwoFactor)
+			twoFactor.POST("/verify", handlers.Auth.VerifyTwoFactor)
+			twoFactor.POST("/disable", handlers.Auth.DisableTwoFactor)
+		}
+
+		if handlers.Tasks != nil {
+			tasks := protected.Group("/tasks")
+			tasks.GET("", handlers.Tasks.GetTasks)
+			tasks.POST("", handlers.Tasks.CreateTask)
+			tasks.POST("/import", handlers.Tasks.ImportTasks)
+			tasks.POST("/batch", handlers.Tasks.CreateTaskBatch)
+			tasks.GET("/trash", handlers.Tasks.GetTrash)
+			tasks.GET("/:taskId", handlers.Tasks.GetTask)
+			tasks.PATCH("/:taskId", handlers.Tasks.UpdateTask)
+			tasks.DELETE("/:taskId", handlers.Tasks.DeleteTask)
+			tasks.POST("/:taskId/assign", handlers.Tasks.AssignTask)
+			tasks.POST("/:taskId/complete", handlers.Tasks.CompleteTask)
+			tasks.POST("/:taskId/reorder", handlers.Tasks.ReorderTask)
+			tasks.GET("/:taskId/audit", handlers.Tasks.GetTaskAudit)
+			tasks.GET("/:taskId/explain", handlers.Tasks.ExplainTask)
+			tasks.GET("/:taskId/history", handlers.Tasks.GetTaskHistory)
+			tasks.POST("/:taskId/restore", handlers.Tasks.RestoreTask)
+			tasks.GET("/:taskId/dependencies", handlers.Tasks.GetDependencies)
+			tasks.POST("/:taskId/dependencies", handlers.Tasks.AddDependency)
+			tasks.DELETE("/:taskId/dependencies/:depId", handlers.Tasks.RemoveDependency)
+		}
+
+		if handlers.Lists != nil {
+			lists := protected.Group("/lists")
+			lists.GET("", handlers.Lists.GetLists)
+			lists.POST("", handlers.Lists.CreateList)
+			lists.PATCH("/:id", handlers.Lists.UpdateList)
+			lists.POST("/:id/archive", handlers.Lists.ArchiveList)
+			lists.POST("/:id/unarchive", handlers.Lists.UnarchiveList)
+		}
+
+		if handlers.Analytics != nil {
+			protected.GET("/analytics/tasks", handlers.Analytics.GetTaskAnalytics)
+		}
+
+		if handlers.Admin != nil {
+			admin := protected.Group("/admin", AdminMiddleware)
+			admin.GET("/users", handlers.Admin.ListUsers)
+			admin.PATCH("/users/:id", handlers.Admin.UpdateUser)
+			admin.DELETE("/users/:id", handlers.Admin.DeleteUser)
+		}
+
+		context := protected.Group("/context")
+		if handlers.Contexts != nil {
+			context.GET("", handlers.Contexts.GetContext)
+			context.POST("", handlers.Contexts.UpdateContext)
+		} else {
+			context.GET("", notImplemented("Context endpoints not yet implemented"))
+			context.POST("", notImplemented("Context endpoints not yet implemented"))
+		}
+
+		locations := protected.Group("/locations")
+		if handlers.Locations != nil {
+			locations.GET("", handlers.Locations.GetLocations)
+			locations.POST("", handlers.Locations.CreateLocation)
+		} else {
+			locations.GET("", notImplemented("Location endpoints not yet implemented"))
+			locations.POST("", notImplemented("Location endpoints not yet implemented"))
+		}
+	}
+
+	// Static documentation (if exists)
+	if config.DocsDir != "" {
+		base.Static("/docs", config.DocsDir)
+	}
+
+	// 404 handler
+	router.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Endpoint not found",
+			"path":  c.Request.URL.Path,
+		})
+	})
+
+	return base
+}
+
+// BasePath returns the prefix the current request's route was mounted under
+func BasePath(c *gin.Context) string {
+	if basePath, exists := c.Get(basePathKey); exists {
+		if s, ok := basePath.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// URLFor builds a server-relative URL for path that includes the base path.
+// Use it for any self-referential link returned to clients.
+func URLFor(c *gin.Context, path string) string {
+	return BasePath(c) + "/" + strings.TrimPrefix(path, "/")
+}
+
+func notImplemented(message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionInfo is one of the user's signed-in devices. LastActiveAt is when
+// the session was last used, to within auth.SessionActivityInterval, or when
+// it started if it has not been used since.
+type SessionInfo struct {
+	auth.Session
+	LastActiveAt time.Time `json:"last_active_at"`
+	// Current marks the session the request was made with
+	Current bool `json:"current"`
+}
+
+type SessionsResponse struct {
+	Sessions []SessionInfo `json:"sessions"`
+	Total    int           `json:"total"`
+}
+
+// ListSessions handles GET /auth/sessions - the user's active sessions,
+// newest first
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	sessions, err := h.authService.GetUserSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to list sessions",
+		})
+		return
+	}
+
+	current := auth.SessionTokenHash(bearerToken(c))
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		info := SessionInfo{
+			Session:      session,
+			LastActiveAt: session.CreatedAt,
+			Current:      session.TokenHash == current,
+		}
+		if session.LastUsedAt != nil {
+			info.LastActiveAt = *session.LastUsedAt
+		}
+		infos = append(infos, info)
+	}
+
+	c.JSON(http.StatusOK, SessionsResponse{
+		Sessions: infos,
+		Total:    len(infos),
+	})
+}
+
+// RevokeSession handles DELETE /auth/sessions/:sessionId - signs the
+// session's device out. Its token is refused from the next request on.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, c.Param("sessionId")); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Session not found",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOtherSessions handles DELETE /auth/sessions - signs out every
+// device except the one making the request
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	token := bearerToken(c)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "The current session's token is required",
+		})
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(userID, token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// bearerToken returns the request's bearer token, or empty when it has none
+func bearerToken(c *gin.Context) string {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxTaskBatchSize caps the tasks in one POST /tasks/batch
+const MaxTaskBatchSize = 500
+
+// TaskBatchService creates many tasks at once, all or none
+type TaskBatchService interface {
+	CreateTasks(userID string, reqs []hereandnow.CreateTaskRequest) ([]models.Task, error)
+}
+
+// TaskBatchResponse lists the created tasks in the order they were sent
+type TaskBatchResponse struct {
+	Tasks []models.Task `json:"tasks"`
+	Total int           `json:"total"`
+}
+
+// TaskBatchErrorResponse reports an invalid task of a batch by its index in
+// the request, e.g. {"error": "validation failed", "index": 2,
+// "fields": {"title": "required"}}
+type TaskBatchErrorResponse struct {
+	Error  string            `json:"error"`
+	Index  int               `json:"index"`
+	Fields map[string]string `json:"fields"`
+}
+
+// SetBatchService enables POST /tasks/batch
+func (h *TaskHandler) SetBatchService(batchService TaskBatchService) {
+	h.batchService = batchService
+}
+
+// CreateTaskBatch handles POST /tasks/batch - creates a JSON array of tasks
+// in one request. Nothing is created unless every task is valid.
+func (h *TaskHandler) CreateTaskBatch(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.batchService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Batch task creation is not available",
+		})
+		return
+	}
+
+	var reqs []TaskCreateRequest
+	if err := c.ShouldBindJSON(&reqs); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: "Expected a JSON array of tasks: " + err.Error(),
+		})
+		return
+	}
+	if len(reqs) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "No tasks to create",
+		})
+		return
+	}
+	if len(reqs) > MaxTaskBatchSize {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+			Error:   "Too many tasks",
+			Details: fmt.Sprintf("A batch can create at most %d tasks", MaxTaskBatchSize),
+		})
+		return
+	}
+
+	batch := make([]hereandnow.CreateTaskRequest, len(reqs))
+	for i, req := range reqs {
+		batch[i] = req.ServiceRequest()
+		if err := batch[i].Validate(); err != nil {
+			var validationErr *models.ValidationError
+			if errors.As(err, &validationErr) {
+				c.JSON(http.StatusBadRequest, TaskBatchErrorResponse{
+					Error:  "validation failed",
+					Index:  i,
+					Fields: validationErr.Fields,
+				})
+				return
+			}
+		}
+	}
+
+	tasks, err := h.batchService.CreateTasks(userID, batch)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to create tasks",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, TaskBatchResponse{
+		Tasks: tasks,
+		Total: len(tasks),
+	})
+}
+
+// ServiceRequest converts the request for the task service, with the same
+// defaults as POST /tasks
+func (r TaskCreateRequest) ServiceRequest() hereandnow.CreateTaskRequest {
+	req := hereandnow.CreateTaskRequest{
+		Title:               r.Title,
+		Description:         r.Description,
+		Priority:            r.Priority,
+		EstimatedMinutes:    r.EstimatedMinutes,
+		EffortPoints:        r.EffortPoints,
+		RequiredEnergyLevel: r.RequiredEnergyLevel,
+		DueAt:               r.DueAt,
+		LocationIDs:         r.LocationIDs,
+		Metadata:            json.RawMessage(`{}`),
+	}
+	if r.ListID != "" {
+		listID := r.ListID
+		req.ListID = &listID
+	}
+	if req.Priority == 0 {
+		req.Priority = 3
+	}
+	if r.Outdoor {
+		req.Metadata = json.RawMessage(`{"` + filters.OutdoorKey + `": true}`)
+	}
+	for _, dependsOn := range r.DependencyIDs {
+		req.Dependencies = append(req.Dependencies, hereandnow.TaskDependencyRequest{
+			DependsOnTaskID: dependsOn,
+			DependencyType:  models.DependencyTypeBlocking,
+		})
+	}
+	return req
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// TaskDependencyService adds and removes the tasks a task depends on
+type TaskDependencyService interface {
+	AddDependency(userID, taskID, dependsOnTaskID string, dependencyType models.DependencyType) (*models.TaskDependency, error)
+	RemoveDependency(userID, taskID, dependsOnTaskID string) error
+	GetTaskDependencies(taskID string) ([]hereandnow.TaskDependencyDetail, error)
+}
+
+// TaskDependencyRequest makes the task depend on another. DependencyType
+// defaults to blocking; suggested recommends the other task without hiding
+// this one.
+type TaskDependencyRequest struct {
+	DependsOnTaskID string                `json:"depends_on_task_id" binding:"required"`
+	DependencyType  models.DependencyType `json:"dependency_type"`
+}
+
+// TaskDependenciesResponse lists the tasks a task depends on
+type TaskDependenciesResponse struct {
+	Dependencies []hereandnow.TaskDependencyDetail `json:"dependencies"`
+	Total        int                               `json:"total"`
+}
+
+// SetDependencyService enables the /tasks/{taskId}/dependencies endpoints
+func (h *TaskHandler) SetDependencyService(dependencyService TaskDependencyService) {
+	h.dependencyService = dependencyService
+}
+
+// GetDependencies handles GET /tasks/{taskId}/dependencies - the tasks the
+// task depends on, with their status
+func (h *TaskHandler) GetDependencies(c *gin.Context) {
+	userID, ok := h.dependencyUser(c)
+	if !ok {
+		return
+	}
+
+	taskID := c.Param("taskId")
+	if _, err := h.taskService.GetTaskByID(taskID, userID); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Task not found",
+		})
+		return
+	}
+
+	dependencies, err := h.dependencyService.GetTaskDependencies(taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to get task dependencies",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TaskDependenciesResponse{
+		Dependencies: dependencies,
+		Total:        len(dependencies),
+	})
+}
+
+// AddDependency handles POST /tasks/{taskId}/dependencies - makes the task
+// depend on another. A dependency that would close a cycle is refused with
+// 409 and the loop in the details.
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	userID, ok := h.dependencyUser(c)
+	if !ok {
+		return
+	}
+
+	var req TaskDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+	if req.DependencyType == "" {
+		req.DependencyType = models.DependencyTypeBlocking
+	}
+
+	taskID := c.Param("taskId")
+	if _, err := models.NewTaskDependency(taskID, req.DependsOnTaskID, req.DependencyType); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid dependency",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	dependency, err := h.dependencyService.AddDependency(userID, taskID, req.DependsOnTaskID, req.DependencyType)
+	if err != nil {
+		dependencyError(c, err, "Failed to add dependency")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dependency)
+}
+
+// RemoveDependency handles DELETE /tasks/{taskId}/dependencies/{depId},
+// where depId is the task depended on
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	userID, ok := h.dependencyUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.dependencyService.RemoveDependency(userID, c.Param("taskId"), c.Param("depId")); err != nil {
+		dependencyError(c, err, "Failed to remove dependency")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// dependencyUser returns the current user for the dependency endpoints,
+// answering the request itself when there is none or dependencies are not
+// enabled
+func (h *TaskHandler) dependencyUser(c *gin.Context) (string, bool) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return "", false
+	}
+
+	if h.dependencyService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task dependencies are not enabled",
+		})
+		return "", false
+	}
+	return userID, true
+}
+
+// dependencyError answers a failed dependency change
+func dependencyError(c *gin.Context, err error, message string) {
+	var cycle *models.DependencyCycleError
+	switch {
+	case errors.As(err, &cycle):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Dependency would create a cycle", Details: cycle.Error()})
+	case errors.Is(err, hereandnow.ErrDependencyExists):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, hereandnow.ErrDependencyNotAllowed):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: message, Details: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: message, Details: err.Error()})
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// TaskHistoryService returns who changed which fields of a task, and when
+type TaskHistoryService interface {
+	GetTaskHistory(taskID string, userID string) ([]models.TaskHistoryEntry, error)
+}
+
+// TaskHistoryResponse lists a task's edits, oldest first
+type TaskHistoryResponse struct {
+	History []models.TaskHistoryEntry `json:"history"`
+	Total   int                       `json:"total"`
+}
+
+// SetHistoryService enables GET /tasks/{taskId}/history
+func (h *TaskHandler) SetHistoryService(historyService TaskHistoryService) {
+	h.historyService = historyService
+}
+
+// GetTaskHistory handles GET /tasks/{taskId}/history - the task's edits with
+// the fields each changed, for its creator, its assignee and the members of
+// its list
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.historyService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task history is not enabled",
+		})
+		return
+	}
+
+	history, err := h.historyService.GetTaskHistory(c.Param("taskId"), userID)
+	if err != nil {
+		if errors.Is(err, hereandnow.ErrTaskNotVisible) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Task not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to get task history",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if history == nil {
+		history = []models.TaskHistoryEntry{}
+	}
+	c.JSON(http.StatusOK, TaskHistoryResponse{
+		History: history,
+		Total:   len(history),
+	})
+}
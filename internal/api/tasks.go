@@ -1,29 +1,68 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
 	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
 	"github.com/gin-gonic/gin"
 )
 
 type TaskHandler struct {
-	taskService    TaskService
-	contextService ContextService
+	taskService       TaskService
+	contextService    ContextService
+	locationService   TaskLocationService
+	importService     TaskImportService
+	explainService    TaskExplainService
+	trashService      TaskTrashService
+	dependencyService TaskDependencyService
+	batchService      TaskBatchService
+	historyService    TaskHistoryService
 }
 
 type TaskService interface {
 	GetFilteredTasks(userID string, filters TaskFilters) (*TaskListResponse, error)
 	CreateTask(task models.Task) (*models.Task, error)
 	GetTaskByID(taskID string, userID string) (*models.Task, error)
+	// UpdateTask saves the task if it is still at its Version, returning it
+	// at the new version, and otherwise fails with an error wrapping
+	// models.ErrTaskVersionConflict
 	UpdateTask(task models.Task) (*models.Task, error)
 	DeleteTask(taskID string, userID string) error
 	AssignTask(taskID string, assigneeID string, assignedBy string, message string) error
 	CompleteTask(taskID string, userID string) (*models.Task, error)
 	GetTaskAudit(taskID string, userID string) ([]models.FilterAudit, error)
 	CreateTaskFromNaturalLanguage(input string, userID string) (*models.Task, error)
+	ReorderTask(taskID string, afterTaskID string, userID string) (*models.Task, error)
+}
+
+// TaskLocationService looks up the places linked to a task
+type TaskLocationService interface {
+	GetTaskLocations(taskID string) ([]models.Location, error)
+}
+
+// TaskImportService saves tasks read from an import file
+type TaskImportService interface {
+	ImportTasks(userID string, tasks []sync.ImportedTask, report *sync.ImportReport, opts hereandnow.ImportOptions) error
+}
+
+// TaskExplainService runs the filter chain against a single task
+type TaskExplainService interface {
+	ExplainTaskVisibility(taskID string, userID string) (*filters.TaskVisibilityExplanation, error)
+}
+
+// TaskTrashService lists and restores the user's deleted tasks
+type TaskTrashService interface {
+	ListTrash(userID string) ([]models.Task, error)
+	RestoreTask(taskID string, userID string) (*models.Task, error)
 }
 
 type ContextService interface {
@@ -36,25 +75,119 @@ type TaskFilters struct {
 	AssigneeID  string
 	ListID      string
 	ShowAll     bool
+	// Search keeps the tasks matching every word of a full-text query; a
+	// word ending in * matches as a prefix
+	Search      string
+	Order       TaskOrder
 	Limit       int
 	Offset      int
+	// Cursor continues from a previous page's NextCursor. It is nil for the
+	// first page, was issued for the same Order, and cannot be combined
+	// with Offset.
+	Cursor *TaskCursor
+}
+
+// TaskTrashResponse lists deleted tasks that can still be restored
+type TaskTrashResponse struct {
+	Tasks []models.Task `json:"tasks"`
+	Total int           `json:"total"`
 }
 
 type TaskListResponse struct {
 	Tasks   []models.Task   `json:"tasks"`
 	Total   int             `json:"total"`
 	Context models.Context  `json:"context"`
+	// NextCursor is passed as ?cursor= with the same sort and order to
+	// fetch the following page. It is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Snippets holds, by task ID, the matched part of each task's title or
+	// description when the list was searched
+	Snippets map[string][]hereandnow.SnippetFragment `json:"snippets,omitempty"`
 }
 
 type TaskCreateRequest struct {
-	Title            string    `json:"title" binding:"required"`
+	Title            string    `json:"title"` // Checked by Task.Validate so it is reported per field
 	Description      string    `json:"description"`
 	ListID           string    `json:"list_id"`
 	Priority         int       `json:"priority"`
 	EstimatedMinutes *int      `json:"estimated_minutes"`
+	EffortPoints     *int      `json:"effort_points"`
+	RequiredEnergyLevel *int   `json:"required_energy_level"` // 1-5; the energy filter hides the task below it
 	DueAt            *time.Time `json:"due_at"`
 	LocationIDs      []string  `json:"location_ids"`
 	DependencyIDs    []string  `json:"dependency_ids"`
+	Outdoor          bool      `json:"outdoor"` // Sets the "outdoor" metadata flag the weather filter reads
+}
+
+// ValidationErrorResponse reports which request fields are invalid, e.g.
+// {"error": "validation failed", "fields": {"title": "required"}}
+type ValidationErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+// respondValidationError writes a 400 listing the invalid fields when err is
+// a *models.ValidationError and reports whether it did
+func respondValidationError(c *gin.Context, err error) bool {
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+		Error:  "validation failed",
+		Fields: validationErr.Fields,
+	})
+	return true
+}
+
+// respondListArchived writes a 409 when err refused a change to a task in
+// an archived list and reports whether it did
+func respondListArchived(c *gin.Context, err error) bool {
+	if !errors.Is(err, hereandnow.ErrListArchived) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, ErrorResponse{
+		Error: err.Error(),
+	})
+	return true
+}
+
+// TaskConflictResponse is the 412 for an update to a task that has changed
+// since the client read it. Task is the task as it is now, for the client
+// to merge its change into.
+type TaskConflictResponse struct {
+	Error string       `json:"error"`
+	Task  *models.Task `json:"task"`
+}
+
+// taskETag is the entity tag of the task's current version
+func taskETag(task models.Task) string {
+	return `"` + strconv.Itoa(task.Version) + `"`
+}
+
+// ifMatchesTask reports whether an If-Match header names the task's current
+// version, or is "*"
+func ifMatchesTask(ifMatch string, task models.Task) bool {
+	etag := taskETag(task)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondTaskConflict writes a 412 carrying the task as it is now, with its
+// ETag, so the client can merge and retry
+func respondTaskConflict(c *gin.Context, current *models.Task) {
+	c.Header("ETag", taskETag(*current))
+	c.JSON(http.StatusPreconditionFailed, TaskConflictResponse{
+		Error: "Task was changed by someone else",
+		Task:  current,
+	})
 }
 
 type TaskUpdateRequest struct {
@@ -63,7 +196,11 @@ type TaskUpdateRequest struct {
 	Status           *string    `json:"status"`
 	Priority         *int       `json:"priority"`
 	EstimatedMinutes *int       `json:"estimated_minutes"`
+	EffortPoints     *int       `json:"effort_points"`
+	RequiredEnergyLevel *int    `json:"required_energy_level"`
 	DueAt            *time.Time `json:"due_at"`
+	// SnoozedUntil hides the task until an RFC 3339 time; "" unsnoozes it
+	SnoozedUntil *string `json:"snoozed_until"`
 }
 
 type TaskAssignRequest struct {
@@ -71,6 +208,10 @@ type TaskAssignRequest struct {
 	Message    string `json:"message"`
 }
 
+type TaskReorderRequest struct {
+	AfterTaskID string `json:"after_task_id"` // Empty moves the task to the top of its list
+}
+
 type NaturalLanguageRequest struct {
 	Input     string `json:"input" binding:"required"`
 	InputType string `json:"input_type"`
@@ -99,6 +240,7 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 		AssigneeID: c.Query("assignee_id"),
 		ListID:     c.Query("list_id"),
 		ShowAll:    c.Query("show_all") == "true",
+		Search:     strings.TrimSpace(c.Query("search")),
 		Limit:      50, // Default
 		Offset:     0,  // Default
 	}
@@ -106,10 +248,26 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 	// Parse limit
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
-			filters.Limit = limit
+			filters.Limit = min(limit, MaxTaskPageSize)
 		}
 	}
 
+	// Parse and validate sort order. A search lists its best matches first
+	// unless asked otherwise.
+	sortBy := c.Query("sort")
+	if sortBy == "" && filters.Search != "" {
+		sortBy = RelevanceSort
+	}
+	order, err := ParseTaskOrder(sortBy, c.Query("order"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid sort order",
+			Details: err.Error(),
+		})
+		return
+	}
+	filters.Order = order
+
 	// Parse offset
 	if offsetStr := c.Query("offset"); offsetStr != "" {
 		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
@@ -117,6 +275,30 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 		}
 	}
 
+	// Parse cursor
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := DecodeTaskCursor(cursorStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "Invalid cursor",
+			})
+			return
+		}
+		if filters.Offset > 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "Cursor cannot be combined with offset",
+			})
+			return
+		}
+		if cursor.Order != filters.Order {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "Cursor was issued for a different sort order",
+			})
+			return
+		}
+		filters.Cursor = cursor
+	}
+
 	// Validate status filter
 	if filters.Status != "" {
 		validStatuses := []string{"pending", "active", "completed", "cancelled", "blocked"}
@@ -144,9 +326,161 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 		return
 	}
 
+	if filters.Search != "" {
+		response.Snippets = make(map[string][]hereandnow.SnippetFragment, len(response.Tasks))
+		for _, task := range response.Tasks {
+			if snippet := hereandnow.TaskSnippet(task, filters.Search); snippet != nil {
+				response.Snippets[task.ID] = snippet
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+// SetLocationService adds task location names to the calendar feed
+func (h *TaskHandler) SetLocationService(locationService TaskLocationService) {
+	h.locationService = locationService
+}
+
+// SetImportService enables POST /tasks/import
+func (h *TaskHandler) SetImportService(importService TaskImportService) {
+	h.importService = importService
+}
+
+// SetExplainService enables GET /tasks/{taskId}/explain
+func (h *TaskHandler) SetExplainService(explainService TaskExplainService) {
+	h.explainService = explainService
+}
+
+// SetTrashService enables GET /tasks/trash and POST /tasks/{taskId}/restore
+func (h *TaskHandler) SetTrashService(trashService TaskTrashService) {
+	h.trashService = trashService
+}
+
+// maxImportSize is the largest file POST /tasks/import accepts
+const maxImportSize = 10 << 20
+
+// ImportTasks handles POST /tasks/import - creates tasks from the CSV (or
+// Todoist CSV export) in the request body and returns the import report.
+// format=markdown, or a text/markdown Content-Type, reads a Markdown
+// checklist instead, and create_missing_locations=true creates locations the
+// file names that the user does not have. Rows that fail are reported, not
+// fatal.
+func (h *TaskHandler) ImportTasks(c *gin.Context) {
+	user, err := GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.importService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task import not available",
+		})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = "csv"
+		if strings.HasPrefix(c.ContentType(), "text/markdown") {
+			format = "markdown"
+		}
+	}
+	if format != "csv" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid import format",
+			Details: "format must be csv or markdown",
+		})
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+			Error:   "Import file too large",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	loc := time.UTC
+	if userLoc, err := time.LoadLocation(user.TimeZone); err == nil {
+		loc = userLoc
+	}
+
+	var tasks []sync.ImportedTask
+	var report *sync.ImportReport
+	if format == "markdown" {
+		tasks, report = sync.ParseMarkdown(string(data), "", loc)
+	} else {
+		tasks, report = sync.ParseCSV(string(data), "", loc)
+	}
+
+	opts := hereandnow.ImportOptions{CreateMissingLocations: c.Query("create_missing_locations") == "true"}
+	if err := h.importService.ImportTasks(user.ID, tasks, report, opts); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to import tasks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportICS handles GET /tasks/export.ics - the user's filtered tasks as an
+// iCalendar feed. It takes the status, list_id and show_all filters of
+// GET /tasks, and a token query parameter for calendar apps that cannot send
+// an Authorization header.
+func (h *TaskHandler) ExportICS(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	response, err := h.taskService.GetFilteredTasks(userID, TaskFilters{
+		Status:  c.Query("status"),
+		ListID:  c.Query("list_id"),
+		ShowAll: c.Query("show_all") == "true",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to get tasks",
+		})
+		return
+	}
+
+	locations := make(map[string][]models.Location, len(response.Tasks))
+	if h.locationService != nil {
+		for _, task := range response.Tasks {
+			taskLocations, err := h.locationService.GetTaskLocations(task.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{
+					Error: "Failed to get task locations",
+				})
+				return
+			}
+			locations[task.ID] = taskLocations
+		}
+	}
+
+	feed := sync.ExportICS(sync.ICSFeed{
+		Name:      "Here and Now",
+		Tasks:     response.Tasks,
+		Locations: locations,
+	}, time.Now())
+
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, sync.ICSContentType, []byte(feed))
+}
+
 // CreateTask handles POST /tasks
 func (h *TaskHandler) CreateTask(c *gin.Context) {
 	user, err := GetCurrentUser(c)
@@ -182,19 +516,44 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		task.EstimatedMinutes = req.EstimatedMinutes
 	}
 
+	if req.EffortPoints != nil {
+		task.EffortPoints = req.EffortPoints
+	}
+
+	if req.RequiredEnergyLevel != nil {
+		task.RequiredEnergyLevel = req.RequiredEnergyLevel
+	}
+
 	if req.DueAt != nil {
 		task.DueAt = req.DueAt
 	}
 
+	if req.Outdoor {
+		task.Metadata = json.RawMessage(`{"` + filters.OutdoorKey + `": true}`)
+	}
+
+	if task.Priority == 0 {
+		task.Priority = 3
+	}
+
+	if respondValidationError(c, task.Validate()) {
+		return
+	}
+
 	// Create task
 	createdTask, err := h.taskService.CreateTask(task)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to create task",
 		})
 		return
 	}
 
+	c.Header("Location", URLFor(c, "/api/v1/tasks/"+createdTask.ID))
+	c.Header("ETag", taskETag(*createdTask))
 	c.JSON(http.StatusCreated, createdTask)
 }
 
@@ -224,10 +583,13 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", taskETag(*task))
 	c.JSON(http.StatusOK, task)
 }
 
-// UpdateTask handles PATCH /tasks/{taskId}
+// UpdateTask handles PATCH /tasks/{taskId}. The If-Match header must carry
+// the ETag the client read the task with; a task changed since is answered
+// with 412 and the task as it is now.
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
@@ -245,6 +607,15 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, ErrorResponse{
+			Error:   "If-Match header is required",
+			Details: "Send the ETag from GET /tasks/{taskId}",
+		})
+		return
+	}
+
 	// Get existing task
 	task, err := h.taskService.GetTaskByID(taskID, userID)
 	if err != nil {
@@ -253,6 +624,10 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		})
 		return
 	}
+	if !ifMatchesTask(ifMatch, *task) {
+		respondTaskConflict(c, task)
+		return
+	}
 
 	var req TaskUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -279,21 +654,61 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	if req.EstimatedMinutes != nil {
 		task.EstimatedMinutes = req.EstimatedMinutes
 	}
+	if req.EffortPoints != nil {
+		task.EffortPoints = req.EffortPoints
+	}
+	if req.RequiredEnergyLevel != nil {
+		task.RequiredEnergyLevel = req.RequiredEnergyLevel
+	}
 	if req.DueAt != nil {
 		task.DueAt = req.DueAt
 	}
+	// After the status, so a task completed in the same request can't be
+	// snoozed
+	if req.SnoozedUntil != nil {
+		if *req.SnoozedUntil == "" {
+			task.Unsnooze()
+			task.RecurringSnooze = nil
+		} else {
+			until, err := time.Parse(time.RFC3339, *req.SnoozedUntil)
+			if err == nil {
+				err = task.Snooze(until)
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "Invalid snooze",
+					Details: err.Error(),
+				})
+				return
+			}
+		}
+	}
 
 	task.UpdatedAt = time.Now()
 
-	// Update task
+	if respondValidationError(c, task.Validate()) {
+		return
+	}
+
+	// Update task, which fails if someone else saved it since it was read
 	updatedTask, err := h.taskService.UpdateTask(*task)
 	if err != nil {
+		if respondValidationError(c, err) || respondListArchived(c, err) {
+			return
+		}
+		if errors.Is(err, models.ErrTaskVersionConflict) {
+			if current, err := h.taskService.GetTaskByID(taskID, userID); err == nil {
+				respondTaskConflict(c, current)
+				return
+			}
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to update task",
 		})
 		return
 	}
 
+	c.Header("ETag", taskETag(*updatedTask))
 	c.JSON(http.StatusOK, updatedTask)
 }
 
@@ -316,6 +731,9 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	}
 
 	if err := h.taskService.DeleteTask(taskID, userID); err != nil {
+		if respondListArchived(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to delete task",
 		})
@@ -353,6 +771,9 @@ func (h *TaskHandler) AssignTask(c *gin.Context) {
 	}
 
 	if err := h.taskService.AssignTask(taskID, req.AssigneeID, userID, req.Message); err != nil {
+		if respondListArchived(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to assign task",
 		})
@@ -382,6 +803,9 @@ func (h *TaskHandler) CompleteTask(c *gin.Context) {
 
 	task, err := h.taskService.CompleteTask(taskID, userID)
 	if err != nil {
+		if respondListArchived(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to complete task",
 		})
@@ -420,6 +844,180 @@ func (h *TaskHandler) GetTaskAudit(c *gin.Context) {
 	c.JSON(http.StatusOK, audit)
 }
 
+// ExplainTask handles GET /tasks/{taskId}/explain - runs every filter against
+// the task in the user's latest context and reports why it is shown or
+// hidden. Unlike listing tasks it records no audit entry.
+func (h *TaskHandler) ExplainTask(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.explainService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task explanations are not enabled",
+		})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	if taskID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Task ID is required",
+		})
+		return
+	}
+
+	if _, err := h.taskService.GetTaskByID(taskID, userID); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Task not found",
+		})
+		return
+	}
+
+	explanation, err := h.explainService.ExplainTaskVisibility(taskID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to explain task",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// GetTrash handles GET /tasks/trash - the user's deleted tasks, most
+// recently deleted first
+func (h *TaskHandler) GetTrash(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.trashService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task trash is not enabled",
+		})
+		return
+	}
+
+	tasks, err := h.trashService.ListTrash(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to list deleted tasks",
+			Details: err.Error(),
+		})
+		return
+	}
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+
+	c.JSON(http.StatusOK, TaskTrashResponse{
+		Tasks: tasks,
+		Total: len(tasks),
+	})
+}
+
+// RestoreTask handles POST /tasks/{taskId}/restore - brings a deleted task
+// back from the trash
+func (h *TaskHandler) RestoreTask(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.trashService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Task trash is not enabled",
+		})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	if taskID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Task ID is required",
+		})
+		return
+	}
+
+	task, err := h.trashService.RestoreTask(taskID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Deleted task not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to restore task",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// ReorderTask handles POST /tasks/{taskId}/reorder
+func (h *TaskHandler) ReorderTask(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	if taskID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Task ID is required",
+		})
+		return
+	}
+
+	var req TaskReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	task, err := h.taskService.ReorderTask(taskID, req.AfterTaskID, userID)
+	if errors.Is(err, hereandnow.ErrReorderNotAllowed) {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+	if respondListArchived(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to reorder task",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
 // CreateTaskNatural handles POST /tasks/natural
 func (h *TaskHandler) CreateTaskNatural(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorChallengeResponse answers a login whose user has two-factor
+// authentication on. The login is finished by posting the challenge and a
+// TOTP code to /auth/2fa/login before ExpiresAt.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	Challenge         string    `json:"challenge"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorSetupResponse struct {
+	// Secret and OTPAuthURL are only ever returned here; the URL is what
+	// authenticator apps scan as a QR code
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorLogin handles POST /auth/2fa/login - the second step of a login
+// that answered with a challenge
+func (h *AuthHandler) TwoFactorLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	loginResp, err := h.authService.CompleteTwoFactorLogin(req.Challenge, req.Code, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidChallenge), errors.Is(err, auth.ErrInvalidTOTPCode):
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		default:
+			twoFactorError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:        loginResp.Token,
+		RefreshToken: loginResp.RefreshToken,
+		User:         loginResp.User,
+		ExpiresAt:    loginResp.ExpiresAt,
+	})
+}
+
+// EnableTwoFactor handles POST /auth/2fa/enable - issues a new TOTP secret.
+// Two-factor login starts once a code from it is posted to /auth/2fa/verify.
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	secret, uri, err := h.authService.BeginTOTPEnrollment(userID)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorSetupResponse{Secret: secret, OTPAuthURL: uri})
+}
+
+// VerifyTwoFactor handles POST /auth/2fa/verify - confirms a code from the
+// new secret and turns two-factor login on
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	h.withTwoFactorCode(c, h.authService.ConfirmTOTP)
+}
+
+// DisableTwoFactor handles POST /auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	h.withTwoFactorCode(c, h.authService.DisableTOTP)
+}
+
+func (h *AuthHandler) withTwoFactorCode(c *gin.Context, apply func(userID, code string) error) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := apply(userID, req.Code); err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func twoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrTwoFactorUnavailable):
+		c.JSON(http.StatusNotImplemented, ErrorResponse{Error: "Two-factor authentication is not available"})
+	case errors.Is(err, auth.ErrTOTPAlreadyEnabled):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, auth.ErrTOTPNotEnrolled), errors.Is(err, auth.ErrInvalidTOTPCode):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Two-factor authentication failed",
+			Details: err.Error(),
+		})
+	}
+}
@@ -10,7 +10,9 @@ import (
 )
 
 type UserHandler struct {
-	userRepo UserRepository
+	userRepo             UserRepository
+	statsService         StatsService
+	notificationSettings NotificationSettingsRepository
 }
 
 type UserRepository interface {
@@ -18,6 +20,26 @@ type UserRepository interface {
 	Update(user *models.User) error
 }
 
+type StatsService interface {
+	GetCompletionStats(userID string) (*models.CompletionStats, error)
+}
+
+// NotificationSettingsRepository stores where users' notifications are
+// pushed
+type NotificationSettingsRepository interface {
+	Get(userID string) (*models.NotificationSettings, error)
+	Save(settings models.NotificationSettings) error
+}
+
+// NotificationSettingsUpdateRequest changes the channels that are set; an
+// empty string turns a channel off
+type NotificationSettingsUpdateRequest struct {
+	WebhookURL                *string `json:"webhook_url"`
+	NtfyTopic                 *string `json:"ntfy_topic"`
+	NtfyServer                *string `json:"ntfy_server"`
+	EmailNotificationsEnabled *bool   `json:"email_notifications_enabled"`
+}
+
 func NewUserHandler(userRepo UserRepository) *UserHandler {
 	return &UserHandler{
 		userRepo: userRepo,
@@ -63,6 +85,139 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// SetStatsService enables GET /users/me/stats
+func (h *UserHandler) SetStatsService(statsService StatsService) {
+	h.statsService = statsService
+}
+
+// GetMyStats handles GET /users/me/stats
+func (h *UserHandler) GetMyStats(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.statsService == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Stats not available",
+		})
+		return
+	}
+
+	stats, err := h.statsService.GetCompletionStats(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to compute stats",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// SetNotificationSettings enables GET and PATCH /users/me/notifications
+func (h *UserHandler) SetNotificationSettings(settings NotificationSettingsRepository) {
+	h.notificationSettings = settings
+}
+
+// GetNotificationSettings handles GET /users/me/notifications
+func (h *UserHandler) GetNotificationSettings(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.notificationSettings == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Notification settings not available",
+		})
+		return
+	}
+
+	settings, err := h.notificationSettings.Get(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to get notification settings",
+		})
+		return
+	}
+	if settings == nil {
+		settings = models.NewNotificationSettings(userID)
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateNotificationSettings handles PATCH /users/me/notifications
+func (h *UserHandler) UpdateNotificationSettings(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Authentication required",
+		})
+		return
+	}
+
+	if h.notificationSettings == nil {
+		c.JSON(http.StatusNotImplemented, ErrorResponse{
+			Error: "Notification settings not available",
+		})
+		return
+	}
+
+	var req NotificationSettingsUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.notificationSettings.Get(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to get notification settings",
+		})
+		return
+	}
+	if settings == nil {
+		settings = models.NewNotificationSettings(userID)
+	}
+
+	if req.WebhookURL != nil {
+		settings.WebhookURL = *req.WebhookURL
+	}
+	if req.NtfyTopic != nil {
+		settings.NtfyTopic = *req.NtfyTopic
+	}
+	if req.NtfyServer != nil {
+		settings.NtfyServer = *req.NtfyServer
+	}
+	if req.EmailNotificationsEnabled != nil {
+		settings.EmailNotificationsEnabled = *req.EmailNotificationsEnabled
+	}
+	settings.UpdatedAt = time.Now()
+
+	if err := h.notificationSettings.Save(*settings); err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to save notification settings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
 // UpdateMe handles PATCH /users/me
 func (h *UserHandler) UpdateMe(c *gin.Context) {
 	user, err := GetCurrentUser(c)
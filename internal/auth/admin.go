@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+var (
+	ErrUserAdminUnavailable = errors.New("user administration is not available")
+	ErrAdminRequired        = errors.New("admin privileges required")
+	ErrAccountDeactivated   = errors.New("account is deactivated")
+	ErrLastAdmin            = errors.New("cannot remove the last active admin")
+	ErrInvalidDeleteMode    = errors.New("invalid delete mode")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+// UserDeleteMode is what DeleteUser does with the user's data
+type UserDeleteMode string
+
+const (
+	// UserDeleteCascade deletes the user and everything they own
+	UserDeleteCascade UserDeleteMode = "cascade"
+	// UserDeleteAnonymize keeps the user's tasks, comments and shared lists
+	// for the people they worked with, under an anonymized, deactivated
+	// account
+	UserDeleteAnonymize UserDeleteMode = "anonymize"
+)
+
+// AdminUserRepository lists, counts and deletes users for admins
+type AdminUserRepository interface {
+	List(limit, offset int) ([]*models.User, error)
+	Delete(userID string) error
+	// CountAdmins counts the admins whose accounts are active
+	CountAdmins() (int, error)
+	// TaskCounts returns how many tasks each user has created
+	TaskCounts() (map[string]int, error)
+}
+
+// UserSummary is a user as listed to admins
+type UserSummary struct {
+	models.User
+	TaskCount int `json:"task_count"`
+}
+
+// UserAdminUpdate changes a user's account. Nil fields are left as they are.
+type UserAdminUpdate struct {
+	Active *bool `json:"active"`
+	Admin  *bool `json:"admin"`
+}
+
+// EnableUserAdmin lets admins list, deactivate and delete users and grant or
+// revoke admin privileges
+func (s *AuthService) EnableUserAdmin(users AdminUserRepository) {
+	s.adminUsers = users
+}
+
+// RequireAdmin returns the user when they are an active admin, and
+// ErrAdminRequired otherwise
+func (s *AuthService) RequireAdmin(userID string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if !user.IsAdmin || !user.IsActive() {
+		return nil, ErrAdminRequired
+	}
+	return user, nil
+}
+
+// ListUsers returns a page of users with the number of tasks each has
+// created, for the admin actorID
+func (s *AuthService) ListUsers(actorID string, limit, offset int) ([]UserSummary, error) {
+	if s.adminUsers == nil {
+		return nil, ErrUserAdminUnavailable
+	}
+	if _, err := s.RequireAdmin(actorID); err != nil {
+		return nil, err
+	}
+
+	users, err := s.adminUsers.List(limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	counts, err := s.adminUsers.TaskCounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	summaries := make([]UserSummary, 0, len(users))
+	for _, user := range users {
+		summaries = append(summaries, UserSummary{User: sanitizeUser(*user), TaskCount: counts[user.ID]})
+	}
+	return summaries, nil
+}
+
+// UpdateUser activates or deactivates userID and grants or revokes their
+// admin privileges, for the admin actorID. Deactivating a user ends their
+// sessions and device tokens. The last active admin cannot be deactivated
+// or demoted, so the install is never left without one.
+func (s *AuthService) UpdateUser(actorID, userID string, update UserAdminUpdate) (*models.User, error) {
+	if s.adminUsers == nil {
+		return nil, ErrUserAdminUnavailable
+	}
+	if _, err := s.RequireAdmin(actorID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUserNotFound, err)
+	}
+
+	deactivate := update.Active != nil && !*update.Active && user.IsActive()
+	demote := update.Admin != nil && !*update.Admin && user.IsAdmin
+	if (deactivate || demote) && user.IsAdmin && user.IsActive() {
+		if err := s.checkNotLastAdmin(); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Active != nil {
+		if *update.Active {
+			user.DeactivatedAt = nil
+		} else if user.IsActive() {
+			now := time.Now()
+			user.DeactivatedAt = &now
+		}
+	}
+	if update.Admin != nil {
+		user.IsAdmin = *update.Admin
+	}
+
+	if err := s.userRepo.Update(*user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if deactivate {
+		if err := s.signOutEverywhere(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	sanitized := sanitizeUser(*user)
+	return &sanitized, nil
+}
+
+// DeleteUser deletes userID for the admin actorID, either with everything
+// they own or by anonymizing the account so others keep the tasks they
+// shared. The last active admin cannot be deleted.
+func (s *AuthService) DeleteUser(actorID, userID string, mode UserDeleteMode) error {
+	if s.adminUsers == nil {
+		return ErrUserAdminUnavailable
+	}
+	if mode != UserDeleteCascade && mode != UserDeleteAnonymize {
+		return fmt.Errorf("%w: %q", ErrInvalidDeleteMode, mode)
+	}
+	if _, err := s.RequireAdmin(actorID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUserNotFound, err)
+	}
+	if user.IsAdmin && user.IsActive() {
+		if err := s.checkNotLastAdmin(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.signOutEverywhere(userID); err != nil {
+		return err
+	}
+
+	if mode == UserDeleteCascade {
+		if err := s.adminUsers.Delete(userID); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	}
+
+	user.Anonymize(time.Now())
+	if err := s.userRepo.Update(*user); err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	if s.totp != nil {
+		if err := s.totp.SaveTOTP(userID, nil, false); err != nil {
+			return fmt.Errorf("failed to clear two-factor secret: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkNotLastAdmin returns ErrLastAdmin when only one active admin is left
+func (s *AuthService) checkNotLastAdmin() error {
+	admins, err := s.adminUsers.CountAdmins()
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// signOutEverywhere ends every session and device token of the user
+func (s *AuthService) signOutEverywhere(userID string) error {
+	if err := s.sessionRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+	if s.deviceTokens == nil {
+		return nil
+	}
+
+	tokens, err := s.deviceTokens.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get device tokens: %w", err)
+	}
+	for _, token := range tokens {
+		if err := s.deviceTokens.Delete(userID, token.ID); err != nil {
+			return fmt.Errorf("failed to revoke device token: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkActive returns ErrAccountDeactivated for a deactivated user
+func checkActive(user *models.User) error {
+	if !user.IsActive() {
+		return ErrAccountDeactivated
+	}
+	return nil
+}
+
+// sanitizeUser returns the user without their password hash and TOTP secret
+func sanitizeUser(user models.User) models.User {
+	user.PasswordHash = ""
+	user.TOTPSecret = nil
+	return user
+}
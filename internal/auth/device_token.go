@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
+)
+
+// DeviceTokenPrefix starts every device token, so they can be told apart
+// from session JWTs
+const DeviceTokenPrefix = "hnd_"
+
+// DeviceToken is a long-lived API key for one device, such as a phone
+// posting its location, so the device never holds the user's password or a
+// session. Only a hash of the token is stored; the token itself is shown
+// once, when it is created.
+type DeviceToken struct {
+	ID         string     `db:"id" json:"id"`
+	UserID     string     `db:"user_id" json:"user_id"`
+	Name       string     `db:"name" json:"name"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+}
+
+type DeviceTokenRepository interface {
+	Create(token DeviceToken) error
+	GetByHash(tokenHash string) (*DeviceToken, error)
+	GetByUserID(userID string) ([]DeviceToken, error)
+	Delete(userID, tokenID string) error
+	UpdateLastUsed(tokenID string, at time.Time) error
+}
+
+// EnableDeviceTokens lets users create device tokens and devices
+// authenticate with them
+func (s *AuthService) EnableDeviceTokens(deviceTokens DeviceTokenRepository) {
+	s.deviceTokens = deviceTokens
+}
+
+// IsDeviceToken reports whether token looks like a device token rather than
+// a session token
+func IsDeviceToken(token string) bool {
+	return strings.HasPrefix(token, DeviceTokenPrefix)
+}
+
+// CreateDeviceToken issues a new token for the named device and returns it
+// with its stored record. The token cannot be retrieved again.
+func (s *AuthService) CreateDeviceToken(userID, name string) (string, *DeviceToken, error) {
+	if s.deviceTokens == nil {
+		return "", nil, fmt.Errorf("device tokens are not enabled")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("device name is required")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate device token: %w", err)
+	}
+	token := DeviceTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := DeviceToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now(),
+	}
+	if err := s.deviceTokens.Create(record); err != nil {
+		return "", nil, fmt.Errorf("failed to create device token: %w", err)
+	}
+
+	return token, &record, nil
+}
+
+// ValidateDeviceToken returns the user a device token belongs to and
+// records that it was used
+func (s *AuthService) ValidateDeviceToken(token string) (*models.User, error) {
+	if s.deviceTokens == nil || !IsDeviceToken(token) {
+		return nil, fmt.Errorf("invalid device token")
+	}
+
+	record, err := s.deviceTokens.GetByHash(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("invalid device token")
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
+
+	// Best effort: a failed timestamp must not lock the device out
+	s.deviceTokens.UpdateLastUsed(record.ID, time.Now())
+
+	sanitizedUser := *user
+	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
+
+	return &sanitizedUser, nil
+}
+
+// ListDeviceTokens returns the user's device tokens, without the tokens
+// themselves
+func (s *AuthService) ListDeviceTokens(userID string) ([]DeviceToken, error) {
+	if s.deviceTokens == nil {
+		return nil, fmt.Errorf("device tokens are not enabled")
+	}
+	return s.deviceTokens.GetByUserID(userID)
+}
+
+// RevokeDeviceToken deletes one of the user's device tokens
+func (s *AuthService) RevokeDeviceToken(userID, tokenID string) error {
+	if s.deviceTokens == nil {
+		return fmt.Errorf("device tokens are not enabled")
+	}
+	return s.deviceTokens.Delete(userID, tokenID)
+}
+
+// hashToken hashes a session, device, feed or password reset token for
+// storage
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
+)
+
+// FeedTokenPrefix starts every calendar feed token, so they can be told
+// apart from session JWTs and device tokens
+const FeedTokenPrefix = "hnf_"
+
+// FeedToken is a long-lived token for subscribing to the calendar feed.
+// Calendar apps put it in the feed URL, so it only ever reads the feed: no
+// other route accepts it. It stays valid until the user revokes it. Only a
+// hash of the token is stored; the token itself is shown once, when it is
+// created.
+type FeedToken struct {
+	ID         string     `db:"id" json:"id"`
+	UserID     string     `db:"user_id" json:"user_id"`
+	Name       string     `db:"name" json:"name"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+}
+
+type FeedTokenRepository interface {
+	Create(token FeedToken) error
+	GetByHash(tokenHash string) (*FeedToken, error)
+	GetByUserID(userID string) ([]FeedToken, error)
+	Delete(userID, tokenID string) error
+	UpdateLastUsed(tokenID string, at time.Time) error
+}
+
+// EnableFeedTokens lets users create calendar feed tokens and calendar apps
+// fetch the feed with them
+func (s *AuthService) EnableFeedTokens(feedTokens FeedTokenRepository) {
+	s.feedTokens = feedTokens
+}
+
+// IsFeedToken reports whether token looks like a calendar feed token
+func IsFeedToken(token string) bool {
+	return strings.HasPrefix(token, FeedTokenPrefix)
+}
+
+// CreateFeedToken issues a new token for the named calendar subscription
+// and returns it with its stored record. The token cannot be retrieved
+// again.
+func (s *AuthService) CreateFeedToken(userID, name string) (string, *FeedToken, error) {
+	if s.feedTokens == nil {
+		return "", nil, fmt.Errorf("feed tokens are not enabled")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("feed name is required")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := FeedTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := FeedToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now(),
+	}
+	if err := s.feedTokens.Create(record); err != nil {
+		return "", nil, fmt.Errorf("failed to create feed token: %w", err)
+	}
+
+	return token, &record, nil
+}
+
+// ValidateFeedToken returns the user a feed token belongs to and records
+// that it was used
+func (s *AuthService) ValidateFeedToken(token string) (*models.User, error) {
+	if s.feedTokens == nil || !IsFeedToken(token) {
+		return nil, fmt.Errorf("invalid feed token")
+	}
+
+	record, err := s.feedTokens.GetByHash(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed token")
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
+
+	// Best effort: a failed timestamp must not break the subscription
+	s.feedTokens.UpdateLastUsed(record.ID, time.Now())
+
+	sanitizedUser := *user
+	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
+
+	return &sanitizedUser, nil
+}
+
+// ListFeedTokens returns the user's feed tokens, without the tokens
+// themselves
+func (s *AuthService) ListFeedTokens(userID string) ([]FeedToken, error) {
+	if s.feedTokens == nil {
+		return nil, fmt.Errorf("feed tokens are not enabled")
+	}
+	return s.feedTokens.GetByUserID(userID)
+}
+
+// RevokeFeedToken deletes one of the user's feed tokens, ending the
+// calendar subscription that uses it
+func (s *AuthService) RevokeFeedToken(userID, tokenID string) error {
+	if s.feedTokens == nil {
+		return fmt.Errorf("feed tokens are not enabled")
+	}
+	return s.feedTokens.Delete(userID, tokenID)
+}
@@ -5,21 +5,57 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+// Token types carried in the token_type claim. Tokens from before refresh
+// tokens existed have no type and count as access tokens.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Lifetimes of the tokens GenerateTokenPair issues, unless changed with
+// SetTokenDurations
+const (
+	DefaultAccessTokenDuration  = 15 * time.Minute
+	DefaultRefreshTokenDuration = 30 * 24 * time.Hour
+)
+
+var (
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrNotRefreshToken     = errors.New("not a refresh token")
+	// ErrRefreshTokenRevoked is returned for a refresh token whose session
+	// has ended: logged out, revoked, or cleared by a password change
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
 )
 
 type JWTServiceImpl struct {
-	secret []byte
+	secret          []byte
+	accessDuration  time.Duration
+	refreshDuration time.Duration
 }
 
 func NewJWTService(secret string) *JWTServiceImpl {
 	return &JWTServiceImpl{
-		secret: []byte(secret),
+		secret:          []byte(secret),
+		accessDuration:  DefaultAccessTokenDuration,
+		refreshDuration: DefaultRefreshTokenDuration,
 	}
 }
 
+// SetTokenDurations changes how long the access and refresh tokens from
+// GenerateTokenPair and RefreshAccessToken last
+func (j *JWTServiceImpl) SetTokenDurations(access, refresh time.Duration) {
+	j.accessDuration = access
+	j.refreshDuration = refresh
+}
+
 type JWTHeader struct {
 	Algorithm string `json:"alg"`
 	Type      string `json:"typ"`
@@ -29,18 +65,87 @@ type JWTClaims struct {
 	UserID    string `json:"user_id"`
 	ExpiresAt int64  `json:"exp"`
 	IssuedAt  int64  `json:"iat"`
+	TokenType string `json:"token_type,omitempty"`
+	// ID makes each token unique, even two issued to the same user in
+	// the same second
+	ID string `json:"jti,omitempty"`
 }
 
 func (j *JWTServiceImpl) GenerateToken(userID string, expiresAt time.Time) (string, error) {
-	header := JWTHeader{
-		Algorithm: "HS256",
-		Type:      "JWT",
-	}
-
-	claims := JWTClaims{
+	return j.sign(JWTClaims{
 		UserID:    userID,
 		ExpiresAt: expiresAt.Unix(),
 		IssuedAt:  time.Now().Unix(),
+		TokenType: TokenTypeAccess,
+	})
+}
+
+// GenerateTokenPair issues a short-lived access token and a long-lived
+// refresh token that RefreshAccessToken exchanges for new access tokens
+func (j *JWTServiceImpl) GenerateTokenPair(userID string) (access string, refresh string, err error) {
+	now := time.Now()
+
+	access, err = j.GenerateToken(userID, now.Add(j.accessDuration))
+	if err != nil {
+		return "", "", err
+	}
+
+	refresh, err = j.sign(JWTClaims{
+		UserID:    userID,
+		ExpiresAt: now.Add(j.refreshDuration).Unix(),
+		IssuedAt:  now.Unix(),
+		TokenType: TokenTypeRefresh,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return access, refresh, nil
+}
+
+// RefreshAccessToken checks a refresh token from GenerateTokenPair and issues
+// a new access token for its user
+func (j *JWTServiceImpl) RefreshAccessToken(refresh string) (string, error) {
+	claims, err := j.ParseRefreshToken(refresh)
+	if err != nil {
+		return "", err
+	}
+
+	return j.GenerateToken(claims.UserID, time.Now().Add(j.accessDuration))
+}
+
+// ParseRefreshToken checks a refresh token from GenerateTokenPair and
+// returns its claims without issuing anything
+func (j *JWTServiceImpl) ParseRefreshToken(refresh string) (*TokenClaims, error) {
+	claims, err := j.parse(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeRefresh {
+		return nil, ErrNotRefreshToken
+	}
+
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	return &TokenClaims{
+		ID:        claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		IssuedAt:  time.Unix(claims.IssuedAt, 0),
+	}, nil
+}
+
+func (j *JWTServiceImpl) sign(claims JWTClaims) (string, error) {
+	if claims.ID == "" {
+		claims.ID = uuid.New().String()
+	}
+
+	header := JWTHeader{
+		Algorithm: "HS256",
+		Type:      "JWT",
 	}
 
 	headerJSON, err := json.Marshal(header)
@@ -63,7 +168,34 @@ func (j *JWTServiceImpl) GenerateToken(userID string, expiresAt time.Time) (stri
 	return token, nil
 }
 
+// ValidateToken checks an access token. Refresh tokens are rejected so one
+// cannot be used in place of the other.
 func (j *JWTServiceImpl) ValidateToken(token string) (*TokenClaims, error) {
+	claims, err := j.parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType == TokenTypeRefresh {
+		return nil, fmt.Errorf("refresh tokens cannot be used for access")
+	}
+
+	// Check expiration
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	return &TokenClaims{
+		ID:        claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		IssuedAt:  time.Unix(claims.IssuedAt, 0),
+	}, nil
+}
+
+// parse verifies a token's signature and decodes its claims without
+// checking its type or expiry
+func (j *JWTServiceImpl) parse(token string) (*JWTClaims, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("invalid token format")
@@ -89,16 +221,7 @@ func (j *JWTServiceImpl) ValidateToken(token string) (*TokenClaims, error) {
 		return nil, fmt.Errorf("failed to unmarshal claims: %w", err)
 	}
 
-	// Check expiration
-	if time.Now().Unix() > claims.ExpiresAt {
-		return nil, fmt.Errorf("token expired")
-	}
-
-	return &TokenClaims{
-		UserID:    claims.UserID,
-		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
-		IssuedAt:  time.Unix(claims.IssuedAt, 0),
-	}, nil
+	return &claims, nil
 }
 
 func (j *JWTServiceImpl) RefreshToken(token string) (string, error) {
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
+)
+
+// DefaultPasswordResetDuration is how long a password reset token works
+// when AuthConfig.PasswordResetDuration is unset
+const DefaultPasswordResetDuration = time.Hour
+
+var (
+	ErrPasswordResetUnavailable = errors.New("password reset is not available")
+	ErrPasswordResetNoAccount   = errors.New("no account with that email")
+	ErrInvalidResetToken        = errors.New("invalid or expired password reset token")
+	ErrInvalidNewPassword       = errors.New("invalid new password")
+)
+
+// PasswordResetRepository stores hashed password reset tokens
+type PasswordResetRepository interface {
+	Create(token models.PasswordResetToken) error
+	GetByHash(tokenHash string) (*models.PasswordResetToken, error)
+	// MarkUsed records that the token was used, failing when it already
+	// was, so two concurrent resets cannot both succeed
+	MarkUsed(tokenID string, at time.Time) error
+	// MarkAllUsed records every unused token of the user as used
+	MarkAllUsed(userID string, at time.Time) error
+}
+
+// EnablePasswordReset lets users who have forgotten their password set a
+// new one with a token from RequestPasswordReset
+func (s *AuthService) EnablePasswordReset(resets PasswordResetRepository) {
+	s.passwordResets = resets
+}
+
+// RequestPasswordReset issues a password reset token for the account with
+// the email and returns it for delivery to the user. Only its hash is
+// stored, so it cannot be retrieved again.
+func (s *AuthService) RequestPasswordReset(email string) (string, error) {
+	if s.passwordResets == nil {
+		return "", ErrPasswordResetUnavailable
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return "", ErrPasswordResetNoAccount
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	record := models.PasswordResetToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.passwordResetDuration()),
+	}
+	if err := s.passwordResets.Create(record); err != nil {
+		return "", fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return token, nil
+}
+
+// ResetPassword sets a new password for the user a reset token was issued
+// to. The token is used up along with any others the user still holds, and
+// the user's sessions are ended as they are by ChangePassword, taking their
+// refresh tokens with them.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	if s.passwordResets == nil {
+		return ErrPasswordResetUnavailable
+	}
+
+	record, err := s.passwordResets.GetByHash(hashToken(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	now := time.Now()
+	if !record.IsUsable(now) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	// Checked before the token is used up, so a rejected password can be
+	// retried with the same token
+	if err := user.SetPassword(newPassword); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNewPassword, err)
+	}
+	if err := s.validatePassword(newPassword); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNewPassword, err)
+	}
+
+	if err := s.passwordResets.MarkUsed(record.ID, now); err != nil {
+		return ErrInvalidResetToken
+	}
+	if err := s.passwordResets.MarkAllUsed(user.ID, now); err != nil {
+		return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+
+	// Login verifies the service's own hash format, not the model's
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.sessionRepo.DeleteByUserID(user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (s *AuthService) passwordResetDuration() time.Duration {
+	if s.config.PasswordResetDuration > 0 {
+		return s.config.PasswordResetDuration
+	}
+	return DefaultPasswordResetDuration
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+// RevokedTokenRepository records the IDs (jti claims) of access tokens that
+// were logged out before they expired. An ID only needs keeping until then.
+type RevokedTokenRepository interface {
+	Revoke(tokenID string, expiresAt time.Time) error
+	IsRevoked(tokenID string, now time.Time) (bool, error)
+}
+
+// EnableTokenRevocation makes Logout revoke the token itself, so a copy of
+// it is refused by ValidateToken even though the JWT has not expired
+func (s *AuthService) EnableTokenRevocation(revokedTokens RevokedTokenRepository) {
+	s.revokedTokens = revokedTokens
+}
+
+// revokeToken records the token's ID so it can no longer be used. Tokens
+// issued before IDs were added cannot be revoked and are skipped.
+func (s *AuthService) revokeToken(claims *TokenClaims) error {
+	if s.revokedTokens == nil || claims.ID == "" {
+		return nil
+	}
+	if err := s.revokedTokens.Revoke(claims.ID, claims.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// checkRevoked returns an error when the token has been revoked
+func (s *AuthService) checkRevoked(claims *TokenClaims) error {
+	if s.revokedTokens == nil || claims.ID == "" {
+		return nil
+	}
+	revoked, err := s.revokedTokens.IsRevoked(claims.ID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return fmt.Errorf("token revoked")
+	}
+	return nil
+}
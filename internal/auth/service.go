@@ -10,10 +10,17 @@ import (
 )
 
 type AuthService struct {
-	userRepo     UserRepository
-	sessionRepo  SessionRepository
-	jwtService   JWTService
-	config       AuthConfig
+	userRepo      UserRepository
+	sessionRepo   SessionRepository
+	jwtService    JWTService
+	config        AuthConfig
+	deviceTokens  DeviceTokenRepository
+	feedTokens    FeedTokenRepository
+	revokedTokens RevokedTokenRepository
+	totp          TOTPRepository
+	challenges    *twoFactorChallenges
+	passwordResets PasswordResetRepository
+	adminUsers     AdminUserRepository
 }
 
 type UserRepository interface {
@@ -26,17 +33,27 @@ type UserRepository interface {
 
 type SessionRepository interface {
 	Create(session Session) error
-	GetByToken(token string) (*Session, error)
+	GetByTokenHash(tokenHash string) (*Session, error)
+	// GetByRefreshTokenHash returns the session the refresh token was
+	// issued with
+	GetByRefreshTokenHash(refreshTokenHash string) (*Session, error)
+	// RotateToken gives the session the access token a refresh issued,
+	// recording the client that refreshed it
+	RotateToken(sessionID, tokenHash, userAgent, ipAddress string, at time.Time) error
 	GetByUserID(userID string) ([]Session, error)
-	Delete(token string) error
+	Delete(sessionID string) error
 	DeleteExpired() error
 	DeleteByUserID(userID string) error
+	UpdateLastUsed(sessionID string, at time.Time) error
 }
 
 type JWTService interface {
 	GenerateToken(userID string, expiresAt time.Time) (string, error)
+	GenerateTokenPair(userID string) (access string, refresh string, err error)
 	ValidateToken(token string) (*TokenClaims, error)
 	RefreshToken(token string) (string, error)
+	RefreshAccessToken(refresh string) (string, error)
+	ParseRefreshToken(refresh string) (*TokenClaims, error)
 }
 
 type AuthConfig struct {
@@ -49,18 +66,32 @@ type AuthConfig struct {
 	Argon2Memory       uint32        `json:"argon2_memory"`
 	Argon2Threads      uint8         `json:"argon2_threads"`
 	Argon2KeyLen       uint32        `json:"argon2_key_len"`
+	// PasswordResetDuration is how long a password reset token works.
+	// Zero uses DefaultPasswordResetDuration.
+	PasswordResetDuration time.Duration `json:"password_reset_duration"`
 }
 
+// Session is one signed-in device. Only hashes of its access and refresh
+// tokens are stored, so a session is found by hashing the token presented.
+// It lasts as long as its refresh token, and deleting it signs the device
+// out for good.
 type Session struct {
-	Token     string    `db:"token" json:"token"`
-	UserID    string    `db:"user_id" json:"user_id"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
-	UserAgent string    `db:"user_agent" json:"user_agent"`
-	IPAddress string    `db:"ip_address" json:"ip_address"`
+	ID         string     `db:"id" json:"id"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	UserID     string     `db:"user_id" json:"user_id"`
+	DeviceName string     `db:"device_name" json:"device_name"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	// RefreshTokenHash binds the refresh token issued with the session to
+	// it. Sessions from before refresh tokens were bound have none.
+	RefreshTokenHash string `db:"refresh_token_hash" json:"-"`
 }
 
 type TokenClaims struct {
+	ID        string    `json:"jti"`
 	UserID    string    `json:"user_id"`
 	ExpiresAt time.Time `json:"expires_at"`
 	IssuedAt  time.Time `json:"issued_at"`
@@ -69,12 +100,21 @@ type TokenClaims struct {
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// DeviceName labels the session in the user's session list. Empty
+	// names it after the user agent.
+	DeviceName string `json:"device_name"`
 }
 
+// LoginResponse carries the tokens of a finished login. When the user has
+// two-factor authentication on, Login instead sets TwoFactorRequired and
+// Challenge, which expires at ExpiresAt, and leaves the rest empty.
 type LoginResponse struct {
-	Token     string      `json:"token"`
-	ExpiresAt time.Time   `json:"expires_at"`
-	User      models.User `json:"user"`
+	Token             string      `json:"token"`
+	RefreshToken      string      `json:"refresh_token,omitempty"`
+	ExpiresAt         time.Time   `json:"expires_at"`
+	User              models.User `json:"user"`
+	TwoFactorRequired bool        `json:"two_factor_required,omitempty"`
+	Challenge         string      `json:"challenge,omitempty"`
 }
 
 type RegisterRequest struct {
@@ -121,31 +161,43 @@ func (s *AuthService) Login(req LoginRequest, userAgent, ipAddress string) (*Log
 	if !s.verifyPassword(req.Password, user.PasswordHash) {
 		return nil, fmt.Errorf("invalid credentials")
 	}
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
 
 	// Note: EmailVerified field not available in current User model
 	// TODO: Add EmailVerified field to User model if email verification is needed
 
+	challenge, err := s.loginChallenge(user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	return s.issueLogin(user, req.DeviceName, userAgent, ipAddress)
+}
+
+// issueLogin starts a session for a user who has proven who they are
+func (s *AuthService) issueLogin(user *models.User, deviceName, userAgent, ipAddress string) (*LoginResponse, error) {
+	// The user may have been deactivated during a two-factor challenge
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
+
 	if err := s.cleanupOldSessions(user.ID); err != nil {
 		return nil, fmt.Errorf("failed to cleanup old sessions: %w", err)
 	}
 
-	expiresAt := time.Now().Add(s.config.SessionDuration)
-	token, err := s.jwtService.GenerateToken(user.ID, expiresAt)
+	token, refreshToken, err := s.jwtService.GenerateTokenPair(user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	session := Session{
-		Token:     token,
-		UserID:    user.ID,
-		CreatedAt: time.Now(),
-		ExpiresAt: expiresAt,
-		UserAgent: userAgent,
-		IPAddress: ipAddress,
-	}
-
-	if err := s.sessionRepo.Create(session); err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+	session, claims, err := s.createTokenSession(token, refreshToken, deviceName, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
 	}
 
 	// Note: LastLoginAt field not available in current User model
@@ -157,14 +209,101 @@ func (s *AuthService) Login(req LoginRequest, userAgent, ipAddress string) (*Log
 
 	sanitizedUser := *user
 	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
+
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    claims.ExpiresAt,
+		User:         sanitizedUser,
+	}, nil
+}
+
+// RefreshAccessToken exchanges a refresh token from Login for a new access
+// token, so clients stay signed in without the password. The refresh token
+// only works while the session Login started with it lasts, so logging
+// out, revoking the session and changing or resetting the password end it
+// along with the access token. The session's previous access token stops
+// working.
+func (s *AuthService) RefreshAccessToken(refreshToken, userAgent, ipAddress string) (*LoginResponse, error) {
+	refreshClaims, err := s.jwtService.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(refreshClaims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.GetByRefreshTokenHash(hashToken(refreshToken))
+	if err != nil || session.UserID != user.ID {
+		return nil, ErrRefreshTokenRevoked
+	}
+	if time.Now().After(session.ExpiresAt) {
+		s.sessionRepo.Delete(session.ID)
+		return nil, ErrRefreshTokenExpired
+	}
+
+	token, err := s.jwtService.RefreshAccessToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+	if err := s.sessionRepo.RotateToken(session.ID, hashToken(token), userAgent, ipAddress, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	sanitizedUser := *user
+	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
 
 	return &LoginResponse{
 		Token:     token,
-		ExpiresAt: expiresAt,
+		ExpiresAt: claims.ExpiresAt,
 		User:      sanitizedUser,
 	}, nil
 }
 
+// createTokenSession records a session for an access token and the refresh
+// token issued with it, and returns it with the access token's claims. The
+// session lasts as long as the refresh token. An empty deviceName is taken
+// from the user agent.
+func (s *AuthService) createTokenSession(token, refreshToken, deviceName, userAgent, ipAddress string) (*Session, *TokenClaims, error) {
+	claims, err := s.jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read token: %w", err)
+	}
+	refreshClaims, err := s.jwtService.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read refresh token: %w", err)
+	}
+
+	session := Session{
+		ID:               uuid.New().String(),
+		TokenHash:        hashToken(token),
+		RefreshTokenHash: hashToken(refreshToken),
+		UserID:           claims.UserID,
+		DeviceName:       sessionDeviceName(deviceName, userAgent),
+		CreatedAt:        time.Now(),
+		ExpiresAt:        refreshClaims.ExpiresAt,
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &session, claims, nil
+}
+
 func (s *AuthService) Register(req RegisterRequest) (*models.User, error) {
 	if err := s.validateRegisterRequest(req); err != nil {
 		return nil, fmt.Errorf("invalid registration request: %w", err)
@@ -191,23 +330,42 @@ func (s *AuthService) Register(req RegisterRequest) (*models.User, error) {
 		LastSeenAt:   time.Now(),
 	}
 
+	// The first user administers the install, so it is never left without
+	// an admin
+	if counter, ok := s.userRepo.(interface{ Count() (int, error) }); ok {
+		if count, err := counter.Count(); err == nil && count == 0 {
+			user.IsAdmin = true
+		}
+	}
+
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	sanitizedUser := user
 	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
 
 	return &sanitizedUser, nil
 }
 
+// Logout ends the token's session only; the user's other devices stay
+// signed in. The access token is revoked, and the refresh token issued
+// with it stops refreshing along with the session.
 func (s *AuthService) Logout(token string) error {
-	_, err := s.sessionRepo.GetByToken(token)
+	session, err := s.sessionRepo.GetByTokenHash(hashToken(token))
 	if err != nil {
 		return fmt.Errorf("invalid session")
 	}
 
-	if err := s.sessionRepo.Delete(token); err != nil {
+	// An expired token needs no revoking; it is refused anyway
+	if claims, err := s.jwtService.ValidateToken(token); err == nil {
+		if err := s.revokeToken(claims); err != nil {
+			return err
+		}
+	}
+
+	if err := s.sessionRepo.Delete(session.ID); err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
@@ -228,35 +386,45 @@ func (s *AuthService) ValidateToken(token string) (*models.User, error) {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
-	session, err := s.sessionRepo.GetByToken(token)
+	if err := s.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	// A session revoked from another device stops its token at once
+	session, err := s.sessionRepo.GetByTokenHash(hashToken(token))
 	if err != nil {
 		return nil, fmt.Errorf("session not found")
 	}
 
 	if time.Now().After(session.ExpiresAt) {
-		s.sessionRepo.Delete(token)
+		s.sessionRepo.Delete(session.ID)
 		return nil, fmt.Errorf("session expired")
 	}
+	s.touchSession(session)
 
 	user, err := s.userRepo.GetByID(claims.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
 
 	sanitizedUser := *user
 	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
 
 	return &sanitizedUser, nil
 }
 
 func (s *AuthService) RefreshToken(token string) (*LoginResponse, error) {
-	session, err := s.sessionRepo.GetByToken(token)
+	session, err := s.sessionRepo.GetByTokenHash(hashToken(token))
 	if err != nil {
 		return nil, fmt.Errorf("session not found")
 	}
 
 	if time.Now().After(session.ExpiresAt) {
-		s.sessionRepo.Delete(token)
+		s.sessionRepo.Delete(session.ID)
 		return nil, fmt.Errorf("session expired")
 	}
 
@@ -264,6 +432,9 @@ func (s *AuthService) RefreshToken(token string) (*LoginResponse, error) {
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	if err := checkActive(user); err != nil {
+		return nil, err
+	}
 
 	newExpiresAt := time.Now().Add(s.config.SessionDuration)
 	newToken, err := s.jwtService.GenerateToken(user.ID, newExpiresAt)
@@ -271,15 +442,17 @@ func (s *AuthService) RefreshToken(token string) (*LoginResponse, error) {
 		return nil, fmt.Errorf("failed to generate new token: %w", err)
 	}
 
-	s.sessionRepo.Delete(token)
+	s.sessionRepo.Delete(session.ID)
 
 	newSession := Session{
-		Token:     newToken,
-		UserID:    user.ID,
-		CreatedAt: time.Now(),
-		ExpiresAt: newExpiresAt,
-		UserAgent: session.UserAgent,
-		IPAddress: session.IPAddress,
+		ID:         uuid.New().String(),
+		TokenHash:  hashToken(newToken),
+		UserID:     user.ID,
+		DeviceName: session.DeviceName,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  newExpiresAt,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
 	}
 
 	if err := s.sessionRepo.Create(newSession); err != nil {
@@ -288,6 +461,7 @@ func (s *AuthService) RefreshToken(token string) (*LoginResponse, error) {
 
 	sanitizedUser := *user
 	sanitizedUser.PasswordHash = ""
+	sanitizedUser.TOTPSecret = nil
 
 	return &LoginResponse{
 		Token:     newToken,
@@ -424,9 +598,10 @@ func (s *AuthService) cleanupOldSessions(userID string) error {
 		return err
 	}
 
+	// Sessions are listed newest first, so the oldest are ended
 	if len(sessions) >= s.config.MaxSessions {
 		for i := 0; i < len(sessions)-s.config.MaxSessions+1; i++ {
-			s.sessionRepo.Delete(sessions[i].Token)
+			s.sessionRepo.Delete(sessions[len(sessions)-1-i].ID)
 		}
 	}
 
@@ -506,4 +681,5 @@ var DefaultAuthConfig = AuthConfig{
 	Argon2Memory:       64 * 1024,
 	Argon2Threads:      4,
 	Argon2KeyLen:       32,
+	PasswordResetDuration: DefaultPasswordResetDuration,
 }
\ No newline at end of file
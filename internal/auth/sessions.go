@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionActivityInterval is how stale a session's last use may get before
+// a request records it again, so every request does not write to the
+// sessions table
+const SessionActivityInterval = time.Minute
+
+// SessionTokenHash returns the hash a session stores for its access token
+func SessionTokenHash(token string) string {
+	return hashToken(token)
+}
+
+// SessionForToken returns the session an access token belongs to
+func (s *AuthService) SessionForToken(token string) (*Session, error) {
+	session, err := s.sessionRepo.GetByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("session not found")
+	}
+	return session, nil
+}
+
+// RevokeSession ends one of the user's sessions. Its access token is
+// refused from the next request on, and its refresh token no longer
+// refreshes.
+func (s *AuthService) RevokeSession(userID, sessionID string) error {
+	sessions, err := s.sessionRepo.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user sessions: %w", err)
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			if err := s.sessionRepo.Delete(sessionID); err != nil {
+				return fmt.Errorf("failed to delete session: %w", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("session not found")
+}
+
+// RevokeOtherSessions ends every session of the user except the one token
+// belongs to, and returns how many it ended. An empty token ends them all.
+// The ended sessions' refresh tokens stop working with them.
+func (s *AuthService) RevokeOtherSessions(userID, token string) (int, error) {
+	sessions, err := s.sessionRepo.GetByUserID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user sessions: %w", err)
+	}
+
+	current := ""
+	if token != "" {
+		current = hashToken(token)
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.TokenHash == current {
+			continue
+		}
+		if err := s.sessionRepo.Delete(session.ID); err != nil {
+			return revoked, fmt.Errorf("failed to delete session: %w", err)
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// touchSession records that the session was just used, at most once per
+// SessionActivityInterval. Failing to record it does not fail the request.
+func (s *AuthService) touchSession(session *Session) {
+	now := time.Now()
+	if session.LastUsedAt != nil && now.Sub(*session.LastUsedAt) < SessionActivityInterval {
+		return
+	}
+	if err := s.sessionRepo.UpdateLastUsed(session.ID, now); err == nil {
+		session.LastUsedAt = &now
+	}
+}
+
+// sessionDeviceName returns the name given for a new session's device, or
+// one made from its user agent such as "Firefox on Linux"
+func sessionDeviceName(name, userAgent string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			system = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	default:
+		// Other clients name themselves first, e.g. "hereandnow/1.2"
+		product, _, _ := strings.Cut(userAgent, " ")
+		product, _, _ = strings.Cut(product, "/")
+		return product
+	}
+}
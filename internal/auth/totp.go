@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TwoFactorChallengeDuration is how long a login challenge waits for its
+// TOTP code, and TwoFactorMaxAttempts how many wrong codes it takes
+const (
+	TwoFactorChallengeDuration = 5 * time.Minute
+	TwoFactorMaxAttempts       = 5
+)
+
+var (
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication is not available")
+	ErrTOTPAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled      = errors.New("two-factor authentication has not been set up")
+	ErrInvalidTOTPCode      = errors.New("invalid two-factor code")
+	ErrInvalidChallenge     = errors.New("invalid or expired two-factor challenge")
+)
+
+// TOTPRepository stores users' TOTP secrets. Implementations are expected
+// to encrypt the secret at rest.
+type TOTPRepository interface {
+	GetTOTP(userID string) (secret *string, enabled bool, err error)
+	SaveTOTP(userID string, secret *string, enabled bool) error
+}
+
+// twoFactorChallenges holds the logins waiting for a TOTP code. They are
+// short-lived, so they are kept in memory and lost on restart.
+type twoFactorChallenges struct {
+	mu      sync.Mutex
+	pending map[string]*twoFactorChallenge
+}
+
+type twoFactorChallenge struct {
+	userID    string
+	expiresAt time.Time
+	attempts  int
+}
+
+// EnableTwoFactor lets users turn on TOTP two-factor authentication. Once a
+// user has, Login returns a challenge instead of a token and the login is
+// finished by CompleteTwoFactorLogin.
+func (s *AuthService) EnableTwoFactor(totp TOTPRepository) {
+	s.totp = totp
+	s.challenges = &twoFactorChallenges{pending: make(map[string]*twoFactorChallenge)}
+}
+
+// BeginTOTPEnrollment gives the user a new TOTP secret and returns it with
+// its otpauth:// URI. Two-factor login starts once ConfirmTOTP accepts a
+// code from it.
+func (s *AuthService) BeginTOTPEnrollment(userID string) (secret string, uri string, err error) {
+	user, err := s.totpUser(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, uri, err = user.EnableTOTP()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.totp.SaveTOTP(userID, user.TOTPSecret, false); err != nil {
+		return "", "", fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+
+	return secret, uri, nil
+}
+
+// ConfirmTOTP turns two-factor login on once the user proves their
+// authenticator has the secret from BeginTOTPEnrollment
+func (s *AuthService) ConfirmTOTP(userID, code string) error {
+	user, err := s.totpUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return ErrTOTPNotEnrolled
+	}
+	if !user.VerifyTOTP(code) {
+		return ErrInvalidTOTPCode
+	}
+
+	if err := s.totp.SaveTOTP(userID, user.TOTPSecret, true); err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	return nil
+}
+
+// DisableTOTP turns two-factor login off. It takes a current code, so a
+// stolen session alone cannot remove the second factor.
+func (s *AuthService) DisableTOTP(userID, code string) error {
+	user, err := s.totpUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnrolled
+	}
+	if !user.VerifyTOTP(code) {
+		return ErrInvalidTOTPCode
+	}
+
+	if err := s.totp.SaveTOTP(userID, nil, false); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	return nil
+}
+
+// CompleteTwoFactorLogin finishes a login that Login answered with a
+// challenge. A challenge is used up by a correct code or by
+// TwoFactorMaxAttempts wrong ones.
+func (s *AuthService) CompleteTwoFactorLogin(challenge, code, userAgent, ipAddress string) (*LoginResponse, error) {
+	if s.totp == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	userID, err := s.challenges.attempt(challenge, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.totpUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.VerifyTOTP(code) {
+		return nil, ErrInvalidTOTPCode
+	}
+	s.challenges.remove(challenge)
+
+	return s.issueLogin(user, "", userAgent, ipAddress)
+}
+
+// loginChallenge starts a login challenge for a user with TOTP enabled,
+// or returns nil when the user has not enabled it
+func (s *AuthService) loginChallenge(user *models.User) (*LoginResponse, error) {
+	if s.totp == nil {
+		return nil, nil
+	}
+
+	secret, enabled, err := s.totp.GetTOTP(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+	if !enabled || secret == nil {
+		return nil, nil
+	}
+
+	challenge, expiresAt, err := s.challenges.start(user.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		TwoFactorRequired: true,
+		Challenge:         challenge,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// totpUser returns the user with their TOTP secret loaded
+func (s *AuthService) totpUser(userID string) (*models.User, error) {
+	if s.totp == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	user.TOTPSecret, user.TOTPEnabled, err = s.totp.GetTOTP(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TOTP secret: %w", err)
+	}
+
+	return user, nil
+}
+
+func (c *twoFactorChallenges) start(userID string, now time.Time) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := now.Add(TwoFactorChallengeDuration)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, pending := range c.pending {
+		if !now.Before(pending.expiresAt) {
+			delete(c.pending, key)
+		}
+	}
+	c.pending[challenge] = &twoFactorChallenge{userID: userID, expiresAt: expiresAt}
+
+	return challenge, expiresAt, nil
+}
+
+// attempt counts a try at the challenge and returns its user
+func (c *twoFactorChallenges) attempt(challenge string, now time.Time) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending, ok := c.pending[challenge]
+	if !ok {
+		return "", ErrInvalidChallenge
+	}
+	if !now.Before(pending.expiresAt) {
+		delete(c.pending, challenge)
+		return "", ErrInvalidChallenge
+	}
+
+	pending.attempts++
+	if pending.attempts >= TwoFactorMaxAttempts {
+		delete(c.pending, challenge)
+	}
+	return pending.userID, nil
+}
+
+func (c *twoFactorChallenges) remove(challenge string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, challenge)
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// AnalyticsRepository aggregates task history in the database, so analytics
+// over long ranges do not load every task
+type AnalyticsRepository struct {
+	db *DB
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// analyticsTasks matches the user's own and assigned tasks that are not in
+// the trash
+const analyticsTasks = "(t.creator_id = ? OR t.assignee_id = ?) AND t.deleted_at IS NULL"
+
+// GetTaskAnalytics returns the user's task analytics from since up to, but
+// not including, until. A range with no tasks returns zeroed analytics.
+func (r *AnalyticsRepository) GetTaskAnalytics(userID string, since, until time.Time) (*models.TaskAnalytics, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID cannot be empty")
+	}
+	if !until.After(since) {
+		return nil, fmt.Errorf("analytics range must end after it starts")
+	}
+
+	analytics := models.NewTaskAnalytics(since, until)
+	since, until = since.UTC(), until.UTC()
+
+	if err := r.completionTotals(analytics, userID, since, until); err != nil {
+		return nil, err
+	}
+	if err := r.completionsByTime(analytics, userID, since, until); err != nil {
+		return nil, err
+	}
+	if err := r.completionsByPriority(analytics, userID, since, until); err != nil {
+		return nil, err
+	}
+	if err := r.completionsByLocation(analytics, userID, since, until); err != nil {
+		return nil, err
+	}
+
+	return analytics, nil
+}
+
+// completedIn returns the condition and arguments for the user's tasks
+// completed in the range
+func completedIn(userID string, since, until time.Time) (string, []interface{}) {
+	return analyticsTasks + " AND t.status = 'completed' AND t.completed_at >= ? AND t.completed_at < ?",
+		[]interface{}{userID, userID, since, until}
+}
+
+func (r *AnalyticsRepository) completionTotals(analytics *models.TaskAnalytics, userID string, since, until time.Time) error {
+	condition, args := completedIn(userID, since, until)
+	query := fmt.Sprintf("SELECT COUNT(*), AVG(%s) FROM tasks t WHERE %s",
+		r.db.Dialect().secondsBetween("t.created_at", "t.completed_at"), condition)
+
+	var average sql.NullFloat64
+	if err := r.db.QueryRow(query, args...).Scan(&analytics.TasksCompleted, &average); err != nil {
+		return fmt.Errorf("failed to count completed tasks: %w", err)
+	}
+	if average.Valid {
+		analytics.AverageMinutesToComplete = average.Float64 / 60
+	}
+	return nil
+}
+
+func (r *AnalyticsRepository) completionsByTime(analytics *models.TaskAnalytics, userID string, since, until time.Time) error {
+	dialect := r.db.Dialect()
+	condition, args := completedIn(userID, since, until)
+	query := fmt.Sprintf("SELECT %s, %s, COUNT(*) FROM tasks t WHERE %s GROUP BY 1, 2",
+		dialect.utcDate("t.completed_at"), dialect.utcHour("t.completed_at"), condition)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to group completions by time: %w", err)
+	}
+	defer rows.Close()
+
+	days := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var hour, count int
+		if err := rows.Scan(&day, &hour, &count); err != nil {
+			return fmt.Errorf("failed to scan completions: %w", err)
+		}
+		days[day] += count
+		if hour >= 0 && hour < len(analytics.CompletionsByHour) {
+			analytics.CompletionsByHour[hour] += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to group completions by time: %w", err)
+	}
+
+	for i := range analytics.CompletionsByDay {
+		analytics.CompletionsByDay[i].Completed = days[analytics.CompletionsByDay[i].Date]
+	}
+	return nil
+}
+
+// completionsByPriority counts, by priority, the tasks created in the range
+// and how many of them are completed
+func (r *AnalyticsRepository) completionsByPriority(analytics *models.TaskAnalytics, userID string, since, until time.Time) error {
+	query := `SELECT t.priority, COUNT(*), SUM(CASE WHEN t.status = 'completed' THEN 1 ELSE 0 END)
+		FROM tasks t WHERE ` + analyticsTasks + ` AND t.created_at >= ? AND t.created_at < ?
+		GROUP BY t.priority`
+
+	rows, err := r.db.Query(query, userID, userID, since, until)
+	if err != nil {
+		return fmt.Errorf("failed to group tasks by priority: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var priority, created, completed int
+		if err := rows.Scan(&priority, &created, &completed); err != nil {
+			return fmt.Errorf("failed to scan priority counts: %w", err)
+		}
+		analytics.TasksCreated += created
+		for i := range analytics.ByPriority {
+			p := &analytics.ByPriority[i]
+			if p.Priority == priority {
+				p.Created, p.Completed = created, completed
+				p.CompletionRate = float64(completed) / float64(created)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to group tasks by priority: %w", err)
+	}
+	return nil
+}
+
+// completionsByLocation counts completions by the location of the user's
+// context snapshot nearest each completion, most completions first
+func (r *AnalyticsRepository) completionsByLocation(analytics *models.TaskAnalytics, userID string, since, until time.Time) error {
+	condition, args := completedIn(userID, since, until)
+	query := fmt.Sprintf(`SELECT n.location_id, COALESCE(l.name, ''), COUNT(*)
+		FROM (
+			SELECT c.current_location_id AS location_id,
+				ROW_NUMBER() OVER (PARTITION BY t.id ORDER BY ABS(%s)) AS nearest
+			FROM tasks t
+			LEFT JOIN contexts c ON c.user_id = ?
+			WHERE %s
+		) n
+		LEFT JOIN locations l ON l.id = n.location_id
+		WHERE n.nearest = 1
+		GROUP BY n.location_id, l.name
+		ORDER BY COUNT(*) DESC, l.name`,
+		r.db.Dialect().secondsBetween("c.timestamp", "t.completed_at"), condition)
+
+	rows, err := r.db.Query(query, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to group completions by location: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var locationID sql.NullString
+		var counts models.LocationCompletions
+		if err := rows.Scan(&locationID, &counts.Name, &counts.Completed); err != nil {
+			return fmt.Errorf("failed to scan location counts: %w", err)
+		}
+		counts.LocationID = locationID.String
+		analytics.ByLocation = append(analytics.ByLocation, counts)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to group completions by location: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// CalendarEventRepository stores the events synced from users' calendars.
+// Times are stored in UTC so range queries compare correctly.
+type CalendarEventRepository struct {
+	db *DB
+}
+
+func NewCalendarEventRepository(db *DB) *CalendarEventRepository {
+	return &CalendarEventRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *CalendarEventRepository) WithTx(tx *Tx) *CalendarEventRepository {
+	return &CalendarEventRepository{db: tx.db}
+}
+
+const calendarEventColumns = `id, user_id, provider_id, external_id, title, start_at, end_at,
+	location, is_all_day, is_busy, metadata, last_synced_at`
+
+// Create stores the event. An event the user already has from the same
+// provider with the same external ID is updated in place instead.
+func (r *CalendarEventRepository) Create(event models.CalendarEvent) error {
+	if event.UserID == "" {
+		return fmt.Errorf("user ID cannot be empty")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO calendar_events (`+calendarEventColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, provider_id, external_id) DO UPDATE SET
+			title = excluded.title,
+			start_at = excluded.start_at,
+			end_at = excluded.end_at,
+			location = excluded.location,
+			is_all_day = excluded.is_all_day,
+			is_busy = excluded.is_busy,
+			metadata = excluded.metadata,
+			last_synced_at = excluded.last_synced_at`,
+		event.ID,
+		event.UserID,
+		event.ProviderID,
+		event.ExternalID,
+		event.Title,
+		event.StartAt.UTC(),
+		event.EndAt.UTC(),
+		event.Location,
+		event.IsAllDay,
+		event.IsBusy,
+		calendarEventMetadata(event.Metadata),
+		event.LastSyncedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create calendar event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CalendarEventRepository) Update(event models.CalendarEvent) error {
+	result, err := r.db.Exec(`
+		UPDATE calendar_events SET
+			title = ?, start_at = ?, end_at = ?, location = ?, is_all_day = ?,
+			is_busy = ?, metadata = ?, last_synced_at = ?
+		WHERE id = ?`,
+		event.Title,
+		event.StartAt.UTC(),
+		event.EndAt.UTC(),
+		event.Location,
+		event.IsAllDay,
+		event.IsBusy,
+		calendarEventMetadata(event.Metadata),
+		event.LastSyncedAt.UTC(),
+		event.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update calendar event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("calendar event not found: %s", event.ID)
+	}
+
+	return nil
+}
+
+func (r *CalendarEventRepository) Delete(eventID string) error {
+	result, err := r.db.Exec(`DELETE FROM calendar_events WHERE id = ?`, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("calendar event not found: %s", eventID)
+	}
+
+	return nil
+}
+
+// GetByExternalID returns the first event with the external ID from any
+// user or provider. Prefer GetByProviderAndExternalID.
+func (r *CalendarEventRepository) GetByExternalID(externalID string) (*models.CalendarEvent, error) {
+	row := r.db.QueryRow(`
+		SELECT `+calendarEventColumns+`
+		FROM calendar_events
+		WHERE external_id = ?
+		ORDER BY last_synced_at DESC
+		LIMIT 1`, externalID)
+	return scanCalendarEvent(row, externalID)
+}
+
+// GetByProviderAndExternalID implements sync.ProviderEventFinder
+func (r *CalendarEventRepository) GetByProviderAndExternalID(userID, providerID, externalID string) (*models.CalendarEvent, error) {
+	row := r.db.QueryRow(`
+		SELECT `+calendarEventColumns+`
+		FROM calendar_events
+		WHERE user_id = ? AND provider_id = ? AND external_id = ?`, userID, providerID, externalID)
+	return scanCalendarEvent(row, externalID)
+}
+
+func (r *CalendarEventRepository) GetByUserID(userID string) ([]models.CalendarEvent, error) {
+	return r.query(`
+		SELECT `+calendarEventColumns+`
+		FROM calendar_events
+		WHERE user_id = ?
+		ORDER BY start_at`, userID)
+}
+
+// GetEventsByUserIDAndTimeRange returns the user's events overlapping
+// [start, end), ordered by start time
+func (r *CalendarEventRepository) GetEventsByUserIDAndTimeRange(userID string, start, end time.Time) ([]models.CalendarEvent, error) {
+	return r.query(`
+		SELECT `+calendarEventColumns+`
+		FROM calendar_events
+		WHERE user_id = ? AND start_at < ? AND end_at > ?
+		ORDER BY start_at`, userID, end.UTC(), start.UTC())
+}
+
+// GetNextEvent returns the user's first event starting after the given
+// time, or nil when there is none
+func (r *CalendarEventRepository) GetNextEvent(userID string, after time.Time) (*models.CalendarEvent, error) {
+	events, err := r.query(`
+		SELECT `+calendarEventColumns+`
+		FROM calendar_events
+		WHERE user_id = ? AND start_at > ?
+		ORDER BY start_at
+		LIMIT 1`, userID, after.UTC())
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return &events[0], nil
+}
+
+func (r *CalendarEventRepository) query(query string, args ...interface{}) ([]models.CalendarEvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.CalendarEvent
+	for rows.Next() {
+		event, err := scanCalendarEventRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar event row: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar event rows: %w", err)
+	}
+
+	return events, nil
+}
+
+func scanCalendarEvent(row *sql.Row, externalID string) (*models.CalendarEvent, error) {
+	event, err := scanCalendarEventRow(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("calendar event not found: %s", externalID)
+		}
+		return nil, fmt.Errorf("failed to get calendar event: %w", err)
+	}
+	return event, nil
+}
+
+func scanCalendarEventRow(row interface{ Scan(...interface{}) error }) (*models.CalendarEvent, error) {
+	var event models.CalendarEvent
+	var location sql.NullString
+	var metadata sql.NullString
+	err := row.Scan(
+		&event.ID,
+		&event.UserID,
+		&event.ProviderID,
+		&event.ExternalID,
+		&event.Title,
+		&event.StartAt,
+		&event.EndAt,
+		&location,
+		&event.IsAllDay,
+		&event.IsBusy,
+		&metadata,
+		&event.LastSyncedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if location.Valid {
+		event.Location = &location.String
+	}
+	event.Metadata = json.RawMessage(calendarEventMetadata(json.RawMessage(metadata.String)))
+
+	return &event, nil
+}
+
+func calendarEventMetadata(metadata json.RawMessage) string {
+	if len(metadata) == 0 {
+		return "{}"
+	}
+	return string(metadata)
+}
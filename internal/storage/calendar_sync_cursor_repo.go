@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type CalendarSyncCursorRepository struct {
+	db *DB
+}
+
+func NewCalendarSyncCursorRepository(db *DB) *CalendarSyncCursorRepository {
+	return &CalendarSyncCursorRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *CalendarSyncCursorRepository) WithTx(tx *Tx) *CalendarSyncCursorRepository {
+	return &CalendarSyncCursorRepository{db: tx.db}
+}
+
+// Get returns the user's cursor for the provider, or nil when the calendar
+// has never been synced incrementally
+func (r *CalendarSyncCursorRepository) Get(userID, provider string) (*models.CalendarSyncCursor, error) {
+	var cursor models.CalendarSyncCursor
+	err := r.db.QueryRow(`
+		SELECT user_id, provider, token, updated_at
+		FROM calendar_sync_cursors
+		WHERE user_id = ? AND provider = ?`, userID, provider).Scan(
+		&cursor.UserID,
+		&cursor.Provider,
+		&cursor.Token,
+		&cursor.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get calendar sync cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
+
+// Save stores the cursor, replacing the user's previous cursor for the
+// provider
+func (r *CalendarSyncCursorRepository) Save(cursor models.CalendarSyncCursor) error {
+	query := `
+		INSERT INTO calendar_sync_cursors (user_id, provider, token, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, provider) DO UPDATE SET
+			token = excluded.token,
+			updated_at = excluded.updated_at`
+
+	_, err := r.db.Exec(query, cursor.UserID, cursor.Provider, cursor.Token, cursor.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save calendar sync cursor: %w", err)
+	}
+
+	return nil
+}
+
+// Delete forgets the cursor so the next sync of the calendar is a full one
+func (r *CalendarSyncCursorRepository) Delete(userID, provider string) error {
+	if _, err := r.db.Exec(`DELETE FROM calendar_sync_cursors WHERE user_id = ? AND provider = ?`, userID, provider); err != nil {
+		return fmt.Errorf("failed to delete calendar sync cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type CompletionUndoRepository struct {
+	db *DB
+}
+
+func NewCompletionUndoRepository(db *DB) *CompletionUndoRepository {
+	return &CompletionUndoRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *CompletionUndoRepository) WithTx(tx *Tx) *CompletionUndoRepository {
+	return &CompletionUndoRepository{db: tx.db}
+}
+
+// Save records a completion's undo, replacing any earlier one for the task
+func (r *CompletionUndoRepository) Save(undo models.CompletionUndo) error {
+	if err := undo.Validate(); err != nil {
+		return fmt.Errorf("invalid completion undo: %w", err)
+	}
+
+	notificationIDs, err := json.Marshal(undo.NotificationIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification IDs: %w", err)
+	}
+
+	query := `
+		INSERT INTO completion_undos (task_id, user_id, snapshot, notification_ids, completed_at, expires_at, spawned_task_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET
+			user_id = excluded.user_id,
+			snapshot = excluded.snapshot,
+			notification_ids = excluded.notification_ids,
+			completed_at = excluded.completed_at,
+			expires_at = excluded.expires_at,
+			spawned_task_id = excluded.spawned_task_id`
+
+	_, err = r.db.Exec(query,
+		undo.TaskID,
+		undo.UserID,
+		string(undo.Snapshot),
+		string(notificationIDs),
+		undo.CompletedAt,
+		undo.ExpiresAt,
+		undo.SpawnedTaskID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save completion undo: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CompletionUndoRepository) GetByTaskID(taskID string) (*models.CompletionUndo, error) {
+	query := `
+		SELECT task_id, user_id, snapshot, notification_ids, completed_at, expires_at, COALESCE(spawned_task_id, '')
+		FROM completion_undos
+		WHERE task_id = ?`
+
+	var undo models.CompletionUndo
+	var snapshot, notificationIDs string
+	err := r.db.QueryRow(query, taskID).Scan(
+		&undo.TaskID,
+		&undo.UserID,
+		&snapshot,
+		&notificationIDs,
+		&undo.CompletedAt,
+		&undo.ExpiresAt,
+		&undo.SpawnedTaskID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get completion undo: %w", err)
+	}
+
+	undo.Snapshot = []byte(snapshot)
+	if err := json.Unmarshal([]byte(notificationIDs), &undo.NotificationIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification IDs: %w", err)
+	}
+
+	return &undo, nil
+}
+
+func (r *CompletionUndoRepository) Delete(taskID string) error {
+	if _, err := r.db.Exec(`DELETE FROM completion_undos WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to delete completion undo: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the undos whose window had closed by now and returns
+// how many it removed
+func (r *CompletionUndoRepository) DeleteExpired(now time.Time) (int, error) {
+	result, err := r.db.Exec(`DELETE FROM completion_undos WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired completion undos: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(removed), nil
+}
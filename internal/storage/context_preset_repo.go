@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type ContextPresetRepository struct {
+	db *DB
+}
+
+func NewContextPresetRepository(db *DB) *ContextPresetRepository {
+	return &ContextPresetRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *ContextPresetRepository) WithTx(tx *Tx) *ContextPresetRepository {
+	return &ContextPresetRepository{db: tx.db}
+}
+
+const contextPresetColumns = `id, user_id, name, available_minutes, energy_level, social_context,
+	location_id, latitude, longitude, created_at, updated_at`
+
+// Save inserts a preset, or replaces the values of the user's preset with
+// the same name
+func (r *ContextPresetRepository) Save(preset models.ContextPreset) error {
+	if err := preset.Validate(); err != nil {
+		return fmt.Errorf("invalid context preset: %w", err)
+	}
+
+	query := `
+		INSERT INTO context_presets (` + contextPresetColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, name) DO UPDATE SET
+			available_minutes = excluded.available_minutes,
+			energy_level = excluded.energy_level,
+			social_context = excluded.social_context,
+			location_id = excluded.location_id,
+			latitude = excluded.latitude,
+			longitude = excluded.longitude,
+			updated_at = excluded.updated_at`
+
+	_, err := r.db.Exec(query,
+		preset.ID,
+		preset.UserID,
+		preset.Name,
+		preset.AvailableMinutes,
+		preset.EnergyLevel,
+		preset.SocialContext,
+		preset.LocationID,
+		preset.Latitude,
+		preset.Longitude,
+		preset.CreatedAt,
+		preset.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save context preset: %w", err)
+	}
+
+	return nil
+}
+
+// GetByName returns the user's preset with the given name
+func (r *ContextPresetRepository) GetByName(userID, name string) (*models.ContextPreset, error) {
+	row := r.db.QueryRow(`SELECT `+contextPresetColumns+` FROM context_presets WHERE user_id = ? AND name = ?`, userID, name)
+
+	preset, err := scanContextPreset(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("context preset not found: %s", name)
+		}
+		return nil, fmt.Errorf("failed to get context preset: %w", err)
+	}
+
+	return preset, nil
+}
+
+// GetByUserID returns the user's presets ordered by name
+func (r *ContextPresetRepository) GetByUserID(userID string) ([]models.ContextPreset, error) {
+	rows, err := r.db.Query(`SELECT `+contextPresetColumns+` FROM context_presets WHERE user_id = ? ORDER BY name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context presets: %w", err)
+	}
+	defer rows.Close()
+
+	var presets []models.ContextPreset
+	for rows.Next() {
+		preset, err := scanContextPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan context preset row: %w", err)
+		}
+		presets = append(presets, *preset)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating context preset rows: %w", err)
+	}
+
+	return presets, nil
+}
+
+// Delete removes the user's preset with the given name
+func (r *ContextPresetRepository) Delete(userID, name string) error {
+	result, err := r.db.Exec(`DELETE FROM context_presets WHERE user_id = ? AND name = ?`, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete context preset: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("context preset not found: %s", name)
+	}
+
+	return nil
+}
+
+func scanContextPreset(row rowScanner) (*models.ContextPreset, error) {
+	var preset models.ContextPreset
+	err := row.Scan(
+		&preset.ID,
+		&preset.UserID,
+		&preset.Name,
+		&preset.AvailableMinutes,
+		&preset.EnergyLevel,
+		&preset.SocialContext,
+		&preset.LocationID,
+		&preset.Latitude,
+		&preset.Longitude,
+		&preset.CreatedAt,
+		&preset.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
@@ -20,6 +20,11 @@ func NewContextRepository(db *DB) *ContextRepository {
 	return &ContextRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs inside tx
+func (r *ContextRepository) WithTx(tx *Tx) *ContextRepository {
+	return &ContextRepository{db: tx.db}
+}
+
 // ContextSearchOptions defines options for searching contexts
 type ContextSearchOptions struct {
 	UserID           string     // Filter by user ID
@@ -54,8 +59,8 @@ func (r *ContextRepository) Create(context *models.Context) error {
 		INSERT INTO contexts (
 			id, user_id, timestamp, current_latitude, current_longitude,
 			current_location_id, available_minutes, social_context, energy_level,
-			weather_condition, traffic_level, metadata
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			weather_condition, traffic_level, min_priority, metadata
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := r.db.Exec(query,
 		context.ID,
@@ -69,6 +74,7 @@ func (r *ContextRepository) Create(context *models.Context) error {
 		context.EnergyLevel,
 		context.WeatherCondition,
 		context.TrafficLevel,
+		context.MinPriority,
 		context.Metadata,
 	)
 
@@ -88,7 +94,7 @@ func (r *ContextRepository) GetByID(id string) (*models.Context, error) {
 	query := `
 		SELECT id, user_id, timestamp, current_latitude, current_longitude,
 		       current_location_id, available_minutes, social_context, energy_level,
-		       weather_condition, traffic_level, metadata
+		       weather_condition, traffic_level, min_priority, metadata
 		FROM contexts 
 		WHERE id = ?`
 
@@ -106,7 +112,8 @@ func (r *ContextRepository) GetByID(id string) (*models.Context, error) {
 		&context.EnergyLevel,
 		&context.WeatherCondition,
 		&context.TrafficLevel,
-		&context.Metadata,
+		&context.MinPriority,
+		scanMetadata(&context.Metadata),
 	)
 
 	if err != nil {
@@ -116,6 +123,7 @@ func (r *ContextRepository) GetByID(id string) (*models.Context, error) {
 		return nil, fmt.Errorf("failed to get context by ID: %w", err)
 	}
 
+	context.Metadata = normalizeMetadata("contexts", context.ID, context.Metadata)
 	return context, nil
 }
 
@@ -128,7 +136,7 @@ func (r *ContextRepository) GetLatestByUser(userID string) (*models.Context, err
 	query := `
 		SELECT id, user_id, timestamp, current_latitude, current_longitude,
 		       current_location_id, available_minutes, social_context, energy_level,
-		       weather_condition, traffic_level, metadata
+		       weather_condition, traffic_level, min_priority, metadata
 		FROM contexts 
 		WHERE user_id = ?
 		ORDER BY timestamp DESC
@@ -148,7 +156,8 @@ func (r *ContextRepository) GetLatestByUser(userID string) (*models.Context, err
 		&context.EnergyLevel,
 		&context.WeatherCondition,
 		&context.TrafficLevel,
-		&context.Metadata,
+		&context.MinPriority,
+		scanMetadata(&context.Metadata),
 	)
 
 	if err != nil {
@@ -158,6 +167,7 @@ func (r *ContextRepository) GetLatestByUser(userID string) (*models.Context, err
 		return nil, fmt.Errorf("failed to get latest context: %w", err)
 	}
 
+	context.Metadata = normalizeMetadata("contexts", context.ID, context.Metadata)
 	return context, nil
 }
 
@@ -170,7 +180,7 @@ func (r *ContextRepository) Search(options ContextSearchOptions) ([]*models.Cont
 	baseQuery := `
 		SELECT id, user_id, timestamp, current_latitude, current_longitude,
 		       current_location_id, available_minutes, social_context, energy_level,
-		       weather_condition, traffic_level, metadata
+		       weather_condition, traffic_level, min_priority, metadata
 		FROM contexts
 	`
 
@@ -292,12 +302,14 @@ func (r *ContextRepository) Search(options ContextSearchOptions) ([]*models.Cont
 			&context.EnergyLevel,
 			&context.WeatherCondition,
 			&context.TrafficLevel,
-			&context.Metadata,
+			&context.MinPriority,
+			scanMetadata(&context.Metadata),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan context row: %w", err)
 		}
 
+		context.Metadata = normalizeMetadata("contexts", context.ID, context.Metadata)
 		contexts = append(contexts, context)
 	}
 
@@ -585,6 +597,22 @@ func (r *ContextRepository) GetAggregatedStats(userID string, after, before *tim
 	return stats, nil
 }
 
+// GetEnergyProfile returns a user's energy levels since the given time,
+// bucketed by hour of day in loc
+func (r *ContextRepository) GetEnergyProfile(userID string, since time.Time, loc *time.Location) (*models.EnergyProfile, error) {
+	contexts, err := r.GetHistoryByUser(userID, &since, nil, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context history: %w", err)
+	}
+
+	history := make([]models.Context, 0, len(contexts))
+	for _, ctx := range contexts {
+		history = append(history, *ctx)
+	}
+
+	return models.NewEnergyProfile(history, loc), nil
+}
+
 // UpdateMetadata updates a context's metadata
 func (r *ContextRepository) UpdateMetadata(contextID string, metadata map[string]interface{}) error {
 	if contextID == "" {
@@ -1,51 +1,72 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// DB wraps the database connection with additional functionality
+// DB wraps the database connection with additional functionality. A DB
+// passed to a WithTx callback is bound to that transaction, so repositories
+// created from it run every statement inside the transaction.
 type DB struct {
 	*sql.DB
-	path string
+	path      string
+	tx        *sql.Tx
+	encrypted bool
+	dialect   Dialect
 }
 
 // Config holds database configuration
 type Config struct {
+	// Path is a SQLite file path or file: URL, or a postgres:// connection
+	// string; see ParseDatabaseURL
 	Path     string
 	InMemory bool
+	// EncryptionKey opens the file with SQLCipher when set; see OpenSQLite
+	EncryptionKey string
 }
 
-// NewDB creates a new database connection with WAL mode enabled
+// NewDB creates a new database connection. SQLite files are opened with WAL
+// mode enabled; a postgres:// Path connects to PostgreSQL instead, which
+// needs a build with the postgres tag.
 func NewDB(config Config) (*DB, error) {
-	var dsn string
+	var sqlDB *sql.DB
 	var dbPath string
+	var err error
 
-	if config.InMemory {
-		dsn = ":memory:"
+	dialect, location := ParseDatabaseURL(config.Path)
+	switch {
+	case config.InMemory:
+		dialect = DialectSQLite
 		dbPath = ":memory:"
-	} else {
-		if config.Path == "" {
+		sqlDB, err = sql.Open("sqlite3", dbPath)
+	case dialect == DialectPostgres:
+		if config.EncryptionKey != "" {
+			return nil, fmt.Errorf("database encryption is only supported for SQLite databases")
+		}
+		dbPath = redactDSN(location)
+		sqlDB, err = openPostgres(location)
+	default:
+		if location == "" {
 			return nil, fmt.Errorf("database path cannot be empty for file-based database")
 		}
 
 		// Ensure the directory exists
-		dir := filepath.Dir(config.Path)
+		dir := filepath.Dir(location)
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create database directory: %w", err)
 		}
 
-		// Add SQLite connection parameters for WAL mode and foreign keys
-		dsn = fmt.Sprintf("%s?_journal_mode=WAL&_foreign_keys=on&_busy_timeout=5000", config.Path)
-		dbPath = config.Path
+		dbPath = location
+		sqlDB, err = OpenSQLite(location, config.EncryptionKey)
 	}
-
-	sqlDB, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -61,12 +82,14 @@ func NewDB(config Config) (*DB, error) {
 	}
 
 	db := &DB{
-		DB:   sqlDB,
-		path: dbPath,
+		DB:        sqlDB,
+		path:      dbPath,
+		encrypted: config.EncryptionKey != "" && !config.InMemory,
+		dialect:   dialect,
 	}
 
-	// Verify WAL mode is enabled (only for file-based databases)
-	if !config.InMemory {
+	// Verify WAL mode is enabled (only for SQLite files)
+	if !config.InMemory && dialect == DialectSQLite {
 		if err := db.verifyWALMode(); err != nil {
 			sqlDB.Close()
 			return nil, fmt.Errorf("failed to verify WAL mode: %w", err)
@@ -102,16 +125,135 @@ func (db *DB) verifyWALMode() error {
 	return nil
 }
 
-// Path returns the database file path
+// Path returns the database file path, or the PostgreSQL connection string
+// without its password
 func (db *DB) Path() string {
 	return db.path
 }
 
+// Dialect returns the SQL flavour of the database
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// redactDSN hides the password in a connection string
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "postgres://"
+	}
+	return u.Redacted()
+}
+
 // BeginTx starts a new transaction with the given options
 func (db *DB) BeginTx() (*sql.Tx, error) {
+	if db.tx != nil {
+		return nil, fmt.Errorf("nested transactions are not supported")
+	}
 	return db.DB.Begin()
 }
 
+// Tx is a transaction started by WithTransaction. Repositories join it
+// through their WithTx method.
+type Tx struct {
+	db *DB
+}
+
+// DB returns the transaction-bound DB, for statements the repositories
+// don't cover
+func (tx *Tx) DB() *DB {
+	return tx.db
+}
+
+// WithTransaction runs fn inside a transaction started with ctx, committing
+// if fn returns nil and rolling back otherwise, including when fn panics.
+// Calling it on a transaction-bound DB joins the existing transaction.
+func (db *DB) WithTransaction(ctx context.Context, fn func(tx *Tx) error) error {
+	if db.tx != nil {
+		return fn(&Tx{db: db})
+	}
+
+	sqlTx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	txDB := &DB{DB: db.DB, path: db.path, tx: sqlTx, encrypted: db.encrypted, dialect: db.dialect}
+
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&Tx{db: txDB}); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// WithTx runs fn inside a transaction, committing if fn returns nil and
+// rolling back otherwise. Repositories created from the DB passed to fn share
+// the transaction. Calling WithTx on a transaction-bound DB joins the
+// existing transaction.
+func (db *DB) WithTx(fn func(tx *DB) error) error {
+	return db.WithTransaction(context.Background(), func(tx *Tx) error {
+		return fn(tx.db)
+	})
+}
+
+// InTx reports whether the DB is bound to a transaction
+func (db *DB) InTx() bool {
+	return db.tx != nil
+}
+
+// Exec executes a statement, inside the bound transaction if there is one.
+// Like Query and QueryRow it takes ? placeholders whatever the dialect.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	query = db.dialect.Rebind(query)
+	if db.tx != nil {
+		return db.tx.Exec(query, args...)
+	}
+	return db.DB.Exec(query, args...)
+}
+
+// Query runs a query, inside the bound transaction if there is one
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	query = db.dialect.Rebind(query)
+	if db.tx != nil {
+		return db.tx.Query(query, args...)
+	}
+	return db.DB.Query(query, args...)
+}
+
+// QueryRow runs a single-row query, inside the bound transaction if there is one
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	query = db.dialect.Rebind(query)
+	if db.tx != nil {
+		return db.tx.QueryRow(query, args...)
+	}
+	return db.DB.QueryRow(query, args...)
+}
+
+// inList returns the placeholders and arguments for matching a column
+// against the IDs, as in "task_id IN (" + placeholders + ")"
+func inList(ids []string) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
 // Health checks the database connection health
 func (db *DB) Health() error {
 	// Test basic connectivity
@@ -132,17 +274,26 @@ func (db *DB) Health() error {
 	return nil
 }
 
-// Close closes the database connection
+// Close closes the database connection. It is a no-op on a
+// transaction-bound DB, whose connection belongs to the parent.
 func (db *DB) Close() error {
+	if db.tx != nil {
+		return nil
+	}
 	return db.DB.Close()
 }
 
-// GetVersion returns the SQLite version
+// GetVersion returns the SQLite or PostgreSQL server version
 func (db *DB) GetVersion() (string, error) {
+	query := "SELECT sqlite_version()"
+	if db.dialect == DialectPostgres {
+		query = "SHOW server_version"
+	}
+
 	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
+	err := db.QueryRow(query).Scan(&version)
 	if err != nil {
-		return "", fmt.Errorf("failed to get SQLite version: %w", err)
+		return "", fmt.Errorf("failed to get %s version: %w", db.dialect, err)
 	}
 	return version, nil
 }
@@ -175,11 +326,59 @@ func (db *DB) Vacuum() error {
 	return nil
 }
 
-// WALCheckpoint forces a WAL checkpoint
+// WALCheckpoint forces a WAL checkpoint. PostgreSQL checkpoints on its own,
+// so it does nothing there.
 func (db *DB) WALCheckpoint() error {
+	if db.dialect == DialectPostgres {
+		return nil
+	}
+
 	_, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
 	if err != nil {
 		return fmt.Errorf("failed to checkpoint WAL: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
+// TableRowCount is the number of rows in one table
+type TableRowCount struct {
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
+
+// RowCounts returns the row count of every user table, ordered by table
+// name, for reporting what a reset would destroy
+func (db *DB) RowCounts() ([]TableRowCount, error) {
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	if db.dialect == DialectPostgres {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	counts := make([]TableRowCount, 0, len(tables))
+	for _, table := range tables {
+		count := TableRowCount{Table: table}
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q", table)).Scan(&count.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count rows in %s: %w", table, err)
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
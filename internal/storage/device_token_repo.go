@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/auth"
+)
+
+// DeviceTokenRepository stores the hashed API tokens of users' devices
+type DeviceTokenRepository struct {
+	db *DB
+}
+
+func NewDeviceTokenRepository(db *DB) *DeviceTokenRepository {
+	return &DeviceTokenRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *DeviceTokenRepository) WithTx(tx *Tx) *DeviceTokenRepository {
+	return &DeviceTokenRepository{db: tx.db}
+}
+
+func (r *DeviceTokenRepository) Create(token auth.DeviceToken) error {
+	if token.TokenHash == "" {
+		return fmt.Errorf("device token hash cannot be empty")
+	}
+	if token.UserID == "" {
+		return fmt.Errorf("user ID cannot be empty")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO device_tokens (id, user_id, name, token_hash, created_at, last_used_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.CreatedAt,
+		token.LastUsedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create device token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeviceTokenRepository) GetByHash(tokenHash string) (*auth.DeviceToken, error) {
+	token := &auth.DeviceToken{}
+	err := r.db.QueryRow(`
+		SELECT id, user_id, name, token_hash, created_at, last_used_at
+		FROM device_tokens
+		WHERE token_hash = ?`, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&token.CreatedAt,
+		&token.LastUsedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("device token not found")
+		}
+		return nil, fmt.Errorf("failed to get device token: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetByUserID returns the user's device tokens, newest first
+func (r *DeviceTokenRepository) GetByUserID(userID string) ([]auth.DeviceToken, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, token_hash, created_at, last_used_at
+		FROM device_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []auth.DeviceToken
+	for rows.Next() {
+		var token auth.DeviceToken
+		err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			&token.TokenHash,
+			&token.CreatedAt,
+			&token.LastUsedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device token row: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating device token rows: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Delete removes one of the user's device tokens
+func (r *DeviceTokenRepository) Delete(userID, tokenID string) error {
+	result, err := r.db.Exec(`DELETE FROM device_tokens WHERE id = ? AND user_id = ?`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete device token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("device token not found")
+	}
+
+	return nil
+}
+
+func (r *DeviceTokenRepository) UpdateLastUsed(tokenID string, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE device_tokens SET last_used_at = ? WHERE id = ?`, at, tokenID); err != nil {
+		return fmt.Errorf("failed to update device token: %w", err)
+	}
+	return nil
+}
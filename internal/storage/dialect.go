@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Dialect is the SQL flavour of the database a DB is connected to.
+// Repositories write SQLite-style queries with ? placeholders and DB rewrites
+// them for its dialect; the few queries that differ further, such as
+// full-text search, ask the dialect for their SQL.
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// ErrPostgresUnavailable is returned when a PostgreSQL connection string is
+// given to a build without PostgreSQL support
+var ErrPostgresUnavailable = errors.New("PostgreSQL databases require a build with the postgres tag")
+
+// ParseDatabaseURL works out the dialect of a database location.
+// postgres:// and postgresql:// URLs are PostgreSQL connection strings and
+// are returned as is; anything else is a SQLite file path, with a leading
+// file: scheme removed.
+func ParseDatabaseURL(location string) (Dialect, string) {
+	lower := strings.ToLower(location)
+	switch {
+	case strings.HasPrefix(lower, "postgres://"), strings.HasPrefix(lower, "postgresql://"):
+		return DialectPostgres, location
+	case strings.HasPrefix(lower, "file://"):
+		return DialectSQLite, location[len("file://"):]
+	case strings.HasPrefix(lower, "file:"):
+		return DialectSQLite, location[len("file:"):]
+	default:
+		return DialectSQLite, location
+	}
+}
+
+// Rebind rewrites the ? placeholders in query for the dialect. PostgreSQL
+// numbers its placeholders ($1, $2, ...); question marks inside quoted
+// strings are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != DialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+		case c == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+var (
+	pragmaLine     = regexp.MustCompile(`(?im)^\s*PRAGMA [^;]*;\s*$`)
+	datetimeType   = regexp.MustCompile(`(?i)\bDATETIME\b`)
+	realType       = regexp.MustCompile(`(?i)\bREAL\b`)
+	blobType       = regexp.MustCompile(`(?i)\bBLOB\b`)
+	booleanDefault = regexp.MustCompile(`(?i)(\bBOOLEAN\b[^,\n]*?\bDEFAULT\s+)([01])\b`)
+)
+
+// TranslateDDL rewrites a schema script written for SQLite for the dialect:
+// on PostgreSQL, DATETIME becomes TIMESTAMPTZ, REAL DOUBLE PRECISION, BLOB
+// BYTEA, boolean defaults of 0 and 1 FALSE and TRUE, and PRAGMA statements
+// are dropped. Scripts using SQLite-only features such as FTS5 tables or
+// triggers need a PostgreSQL version instead; see Migrator.
+func (d Dialect) TranslateDDL(script string) string {
+	if d != DialectPostgres {
+		return script
+	}
+
+	script = pragmaLine.ReplaceAllString(script, "")
+	script = datetimeType.ReplaceAllString(script, "TIMESTAMPTZ")
+	script = realType.ReplaceAllString(script, "DOUBLE PRECISION")
+	script = blobType.ReplaceAllString(script, "BYTEA")
+	return booleanDefault.ReplaceAllStringFunc(script, func(match string) string {
+		parts := booleanDefault.FindStringSubmatch(match)
+		if parts[2] == "1" {
+			return parts[1] + "TRUE"
+		}
+		return parts[1] + "FALSE"
+	})
+}
+
+// textSearch returns the join and condition that match a full-text query,
+// passed as one ? argument built by textQuery, against columns of the table
+// aliased alias. On SQLite it uses the table's FTS5 index, named
+// <table>_fts; on PostgreSQL it matches the columns' English tsvector.
+func (d Dialect) textSearch(table, alias string, columns ...string) (join, condition string) {
+	if d == DialectPostgres {
+		return "", fmt.Sprintf("%s @@ to_tsquery('english', ?)", tsvector(alias, columns))
+	}
+
+	return fmt.Sprintf("JOIN %[1]s_fts ON %[2]s.rowid = %[1]s_fts.rowid", table, alias), table + "_fts MATCH ?"
+}
+
+// textRank returns an ORDER BY term putting the best matches of a
+// textSearch first. On PostgreSQL it takes the textQuery as one more ?
+// argument, reported by usesQuery.
+func (d Dialect) textRank(table, alias string, columns ...string) (order string, usesQuery bool) {
+	if d == DialectPostgres {
+		return fmt.Sprintf("ts_rank(%s, to_tsquery('english', ?)) DESC", tsvector(alias, columns)), true
+	}
+
+	// bm25 scores better matches lower
+	return fmt.Sprintf("bm25(%s_fts)", table), false
+}
+
+// textQuery turns what a user typed into the dialect's full-text query: every
+// word must match, and a word ending in * matches as a prefix. Punctuation
+// and query syntax are dropped so no input can make a malformed query; one
+// with no words matches nothing.
+func (d Dialect) textQuery(query string) string {
+	terms := searchTerms(query)
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		switch {
+		case d == DialectPostgres && term.prefix:
+			parts[i] = term.word + ":*"
+		case d == DialectPostgres:
+			parts[i] = term.word
+		case term.prefix:
+			parts[i] = `"` + term.word + `"*`
+		default:
+			parts[i] = `"` + term.word + `"`
+		}
+	}
+
+	if d == DialectPostgres {
+		return strings.Join(parts, " & ")
+	}
+	if len(parts) == 0 {
+		return `""`
+	}
+	return strings.Join(parts, " ")
+}
+
+// likeSearch is the condition and arguments textSearch falls back to when
+// SQLite was built without FTS5: every word of query must appear somewhere
+// in one of the columns. It cannot rank matches.
+func likeSearch(query, alias string, columns ...string) (string, []interface{}) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return "1 = 0", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, term := range terms {
+		matches := make([]string, len(columns))
+		for i, column := range columns {
+			matches[i] = fmt.Sprintf("%s.%s LIKE ?", alias, column)
+			args = append(args, "%"+term.word+"%")
+		}
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// isTextSearchUnavailable reports whether err comes from a full-text search
+// against a SQLite built without FTS5, or a database without its index
+func isTextSearchUnavailable(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "no such module: fts5") ||
+		(strings.Contains(message, "no such table:") && strings.Contains(message, "_fts"))
+}
+
+// searchTerm is one word of a search query
+type searchTerm struct {
+	word   string
+	prefix bool
+}
+
+// searchTerms splits a search query into words of letters and digits,
+// leaving out the operators AND, OR and NOT. A trailing * makes the word
+// before it a prefix.
+func searchTerms(query string) []searchTerm {
+	var terms []searchTerm
+	for _, field := range strings.Fields(query) {
+		switch field {
+		case "AND", "OR", "NOT":
+			continue
+		}
+		words := strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for i, word := range words {
+			terms = append(terms, searchTerm{
+				word:   word,
+				prefix: i == len(words)-1 && strings.HasSuffix(field, "*"),
+			})
+		}
+	}
+	return terms
+}
+
+// tsvector is the English tsvector of columns of alias, for PostgreSQL
+func tsvector(alias string, columns []string) string {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprintf("coalesce(%s.%s, '')", alias, column)
+	}
+	return fmt.Sprintf("to_tsvector('english', %s)", strings.Join(parts, " || ' ' || "))
+}
+
+// distance returns the great-circle distance in meters, by the Haversine
+// formula, between the point (lat, lng) and the latitude and longitude
+// columns of alias. lat and lng are SQL expressions. The cosine is clamped
+// to 1 so a point exactly on a location does not fall outside acos's domain
+// through rounding, which PostgreSQL reports as an error.
+func (d Dialect) distance(alias, lat, lng string) string {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+
+	cosine := fmt.Sprintf(`cos(radians(%[1]s)) * cos(radians(%[3]slatitude)) *
+			cos(radians(%[3]slongitude) - radians(%[2]s)) +
+			sin(radians(%[1]s)) * sin(radians(%[3]slatitude))`, lat, lng, prefix)
+
+	clamp := "min"
+	if d == DialectPostgres {
+		clamp = "least"
+	}
+	return fmt.Sprintf("(6371000 * acos(%s(1.0, %s)))", clamp, cosine)
+}
+
+// utcDate returns the UTC calendar day, as YYYY-MM-DD, of the timestamp
+// expression column
+func (d Dialect) utcDate(column string) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("to_char(%s AT TIME ZONE 'UTC', 'YYYY-MM-DD')", column)
+	}
+	return fmt.Sprintf("date(%s)", column)
+}
+
+// utcHour returns the UTC hour of day, 0 to 23, of the timestamp
+// expression column
+func (d Dialect) utcHour(column string) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("CAST(EXTRACT(HOUR FROM %s AT TIME ZONE 'UTC') AS INTEGER)", column)
+	}
+	return fmt.Sprintf("CAST(strftime('%%H', %s) AS INTEGER)", column)
+}
+
+// secondsBetween returns the seconds from the timestamp expression start to
+// end, negative when end is earlier
+func (d Dialect) secondsBetween(start, end string) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s))", end, start)
+	}
+	return fmt.Sprintf("((julianday(%s) - julianday(%s)) * 86400.0)", end, start)
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEncryptionUnavailable is returned when an encryption key is given to a
+// build without SQLCipher support
+var ErrEncryptionUnavailable = errors.New("database encryption requires a build with the sqlcipher tag")
+
+// sqliteParams are the connection settings every file database uses
+const sqliteParams = "_journal_mode=WAL&_foreign_keys=on&_busy_timeout=5000"
+
+// OpenSQLite opens the SQLite database at path with WAL mode and foreign
+// keys enabled. A non-empty key opens it with SQLCipher, creating it
+// encrypted if it does not exist yet; an encrypted database cannot be read
+// without it. The key is never included in errors or logs.
+func OpenSQLite(path, key string) (*sql.DB, error) {
+	if key == "" {
+		return sql.Open("sqlite3", path+"?"+sqliteParams)
+	}
+	return openEncrypted(path, key)
+}
+
+// PrivacyWarning explains why an unencrypted database is a risk, or returns
+// "" when the database is encrypted or held in memory
+func (c Config) PrivacyWarning() string {
+	if c.InMemory || c.EncryptionKey != "" {
+		return ""
+	}
+	if dialect, _ := ParseDatabaseURL(c.Path); dialect == DialectPostgres {
+		return ""
+	}
+	return fmt.Sprintf("database %s is not encrypted and stores your location history in plaintext; "+
+		"anyone who can read the file can see where you have been", c.Path)
+}
+
+// Encrypted reports whether the database was opened with an encryption key
+func (db *DB) Encrypted() bool {
+	return db.encrypted
+}
+
+// keyPragma builds the statement that unlocks an SQLCipher database
+func keyPragma(key string) string {
+	return "PRAGMA key = '" + strings.ReplaceAll(key, "'", "''") + "'"
+}
@@ -0,0 +1,13 @@
+//go:build !sqlcipher
+
+package storage
+
+import "database/sql"
+
+// EncryptionAvailable reports whether this build can open encrypted
+// databases
+const EncryptionAvailable = false
+
+func openEncrypted(path, key string) (*sql.DB, error) {
+	return nil, ErrEncryptionUnavailable
+}
@@ -0,0 +1,74 @@
+//go:build sqlcipher
+
+// SQLCipher builds link go-sqlite3 against the SQLCipher library instead of
+// its bundled SQLite, e.g.
+//
+//	CGO_LDFLAGS="-lsqlcipher" go build -tags "sqlcipher libsqlite3" ./cmd/hereandnow
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// EncryptionAvailable reports whether this build can open encrypted
+// databases
+const EncryptionAvailable = true
+
+// openEncrypted keys every new connection before anything reads the file,
+// then applies the settings OpenSQLite passes in the DSN for plain databases
+func openEncrypted(path, key string) (*sql.DB, error) {
+	sqliteDriver := &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec(keyPragma(key), nil); err != nil {
+				return fmt.Errorf("failed to set database key: %w", err)
+			}
+
+			var cipherVersion string
+			rows, err := conn.Query("PRAGMA cipher_version", nil)
+			if err == nil {
+				values := make([]driver.Value, 1)
+				if rows.Next(values) == nil {
+					cipherVersion, _ = values[0].(string)
+				}
+				rows.Close()
+			}
+			if cipherVersion == "" {
+				return fmt.Errorf("SQLite library does not support encryption; link against SQLCipher")
+			}
+
+			for _, pragma := range []string{
+				"PRAGMA journal_mode = WAL",
+				"PRAGMA foreign_keys = ON",
+				"PRAGMA busy_timeout = 5000",
+			} {
+				if _, err := conn.Exec(pragma, nil); err != nil {
+					return fmt.Errorf("failed to unlock encrypted database (wrong key?): %w", err)
+				}
+			}
+			return nil
+		},
+	}
+
+	return sql.OpenDB(keyedConnector{driver: sqliteDriver, path: path}), nil
+}
+
+// keyedConnector opens connections through a driver whose connect hook holds
+// the key, so the key never appears in a DSN
+type keyedConnector struct {
+	driver *sqlite3.SQLiteDriver
+	path   string
+}
+
+func (c keyedConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.path)
+}
+
+func (c keyedConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/auth"
+)
+
+// FeedTokenRepository stores the hashed calendar feed tokens of users
+type FeedTokenRepository struct {
+	db *DB
+}
+
+func NewFeedTokenRepository(db *DB) *FeedTokenRepository {
+	return &FeedTokenRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *FeedTokenRepository) WithTx(tx *Tx) *FeedTokenRepository {
+	return &FeedTokenRepository{db: tx.db}
+}
+
+func (r *FeedTokenRepository) Create(token auth.FeedToken) error {
+	if token.TokenHash == "" {
+		return fmt.Errorf("feed token hash cannot be empty")
+	}
+	if token.UserID == "" {
+		return fmt.Errorf("user ID cannot be empty")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO feed_tokens (id, user_id, name, token_hash, created_at, last_used_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.CreatedAt,
+		token.LastUsedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create feed token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FeedTokenRepository) GetByHash(tokenHash string) (*auth.FeedToken, error) {
+	token := &auth.FeedToken{}
+	err := r.db.QueryRow(`
+		SELECT id, user_id, name, token_hash, created_at, last_used_at
+		FROM feed_tokens
+		WHERE token_hash = ?`, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&token.CreatedAt,
+		&token.LastUsedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("feed token not found")
+		}
+		return nil, fmt.Errorf("failed to get feed token: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetByUserID returns the user's feed tokens, newest first
+func (r *FeedTokenRepository) GetByUserID(userID string) ([]auth.FeedToken, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, token_hash, created_at, last_used_at
+		FROM feed_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []auth.FeedToken
+	for rows.Next() {
+		var token auth.FeedToken
+		err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			&token.TokenHash,
+			&token.CreatedAt,
+			&token.LastUsedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feed token row: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feed token rows: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Delete removes one of the user's feed tokens
+func (r *FeedTokenRepository) Delete(userID, tokenID string) error {
+	result, err := r.db.Exec(`DELETE FROM feed_tokens WHERE id = ? AND user_id = ?`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("feed token not found")
+	}
+
+	return nil
+}
+
+func (r *FeedTokenRepository) UpdateLastUsed(tokenID string, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE feed_tokens SET last_used_at = ? WHERE id = ?`, at, tokenID); err != nil {
+		return fmt.Errorf("failed to update feed token: %w", err)
+	}
+	return nil
+}
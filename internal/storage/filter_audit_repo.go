@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// filterAuditColumnCount is the number of columns in an audit INSERT
+const filterAuditColumnCount = 8
+
+// FilterAuditRepository stores the filter engine's visibility decisions
+type FilterAuditRepository struct {
+	db *DB
+}
+
+func NewFilterAuditRepository(db *DB) *FilterAuditRepository {
+	return &FilterAuditRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *FilterAuditRepository) WithTx(tx *Tx) *FilterAuditRepository {
+	return &FilterAuditRepository{db: tx.db}
+}
+
+func (r *FilterAuditRepository) SaveFilterResult(audit models.FilterAudit) error {
+	return r.SaveFilterResults([]models.FilterAudit{audit})
+}
+
+// SaveFilterResults saves the audits in one transaction, with as few
+// INSERTs as the database's parameter limit allows
+func (r *FilterAuditRepository) SaveFilterResults(audits []models.FilterAudit) error {
+	if len(audits) == 0 {
+		return nil
+	}
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", filterAuditColumnCount), ", ") + ")"
+	rowsPerInsert := maxInsertParams / filterAuditColumnCount
+
+	return r.db.WithTx(func(tx *DB) error {
+		for start := 0; start < len(audits); start += rowsPerInsert {
+			batch := audits[start:min(start+rowsPerInsert, len(audits))]
+
+			args := make([]interface{}, 0, len(batch)*filterAuditColumnCount)
+			for _, audit := range batch {
+				args = append(args,
+					audit.ID,
+					audit.UserID,
+					audit.TaskID,
+					audit.ContextID,
+					audit.IsVisible,
+					string(audit.Reasons),
+					audit.PriorityScore,
+					audit.CreatedAt,
+				)
+			}
+
+			query := `
+				INSERT INTO filter_audit (id, user_id, task_id, context_id, is_visible, reasons, priority_score, created_at)
+				VALUES ` + strings.TrimSuffix(strings.Repeat(row+", ", len(batch)), ", ")
+			if _, err := tx.Exec(query, args...); err != nil {
+				return fmt.Errorf("failed to save filter audits: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetAuditLogByTaskID returns up to limit of the task's audit entries,
+// newest first
+func (r *FilterAuditRepository) GetAuditLogByTaskID(taskID string, limit int) ([]models.FilterAudit, error) {
+	return r.query(`WHERE task_id = ?`, limit, taskID)
+}
+
+// GetAuditLogByUserID returns up to limit of the user's audit entries since
+// the given time, newest first
+func (r *FilterAuditRepository) GetAuditLogByUserID(userID string, since time.Time, limit int) ([]models.FilterAudit, error) {
+	return r.query(`WHERE user_id = ? AND created_at >= ?`, limit, userID, since)
+}
+
+func (r *FilterAuditRepository) query(where string, limit int, args ...interface{}) ([]models.FilterAudit, error) {
+	query := `
+		SELECT id, user_id, task_id, context_id, is_visible, reasons, priority_score, created_at
+		FROM filter_audit ` + where + `
+		ORDER BY created_at DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filter audits: %w", err)
+	}
+	defer rows.Close()
+
+	var audits []models.FilterAudit
+	for rows.Next() {
+		var audit models.FilterAudit
+		var reasons string
+		err := rows.Scan(
+			&audit.ID,
+			&audit.UserID,
+			&audit.TaskID,
+			&audit.ContextID,
+			&audit.IsVisible,
+			&reasons,
+			&audit.PriorityScore,
+			&audit.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan filter audit row: %w", err)
+		}
+		audit.Reasons = []byte(reasons)
+		audits = append(audits, audit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating filter audit rows: %w", err)
+	}
+
+	return audits, nil
+}
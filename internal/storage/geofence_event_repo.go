@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// GeofenceEventRepository stores users' arrivals at and departures from
+// their saved locations
+type GeofenceEventRepository struct {
+	db *DB
+}
+
+func NewGeofenceEventRepository(db *DB) *GeofenceEventRepository {
+	return &GeofenceEventRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *GeofenceEventRepository) WithTx(tx *Tx) *GeofenceEventRepository {
+	return &GeofenceEventRepository{db: tx.db}
+}
+
+func (r *GeofenceEventRepository) Create(event models.GeofenceEvent) error {
+	if err := event.Validate(); err != nil {
+		return fmt.Errorf("geofence event validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO geofence_events (id, user_id, location_id, context_id, event_type, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		event.ID,
+		event.UserID,
+		event.LocationID,
+		event.ContextID,
+		string(event.Type),
+		event.At,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create geofence event: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUserID returns the user's events since the given time, oldest first
+func (r *GeofenceEventRepository) GetByUserID(userID string, since time.Time) ([]models.GeofenceEvent, error) {
+	query := `
+		SELECT id, user_id, location_id, context_id, event_type, occurred_at
+		FROM geofence_events
+		WHERE user_id = ? AND occurred_at >= ?
+		ORDER BY occurred_at ASC`
+
+	rows, err := r.db.Query(query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get geofence events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.GeofenceEvent
+	for rows.Next() {
+		var event models.GeofenceEvent
+		var eventType string
+		err := rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.LocationID,
+			&event.ContextID,
+			&eventType,
+			&event.At,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan geofence event row: %w", err)
+		}
+		event.Type = models.GeofenceEventType(eventType)
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating geofence event rows: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type ListMemberRepository struct {
+	db *DB
+}
+
+func NewListMemberRepository(db *DB) *ListMemberRepository {
+	return &ListMemberRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *ListMemberRepository) WithTx(tx *Tx) *ListMemberRepository {
+	return &ListMemberRepository{db: tx.db}
+}
+
+func (r *ListMemberRepository) Create(member models.ListMember) error {
+	query := `
+		INSERT INTO list_members (id, list_id, user_id, role, invited_by, invited_at, accepted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		member.ID,
+		member.ListID,
+		member.UserID,
+		member.Role,
+		member.InvitedBy,
+		member.InvitedAt,
+		member.AcceptedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create list member: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the user's membership of the list
+func (r *ListMemberRepository) Delete(listID, userID string) error {
+	result, err := r.db.Exec(`DELETE FROM list_members WHERE list_id = ? AND user_id = ?`, listID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete list member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("list member not found: %s", userID)
+	}
+
+	return nil
+}
+
+// GetByListID returns the list's members, including pending invitations, in
+// the order they were invited
+func (r *ListMemberRepository) GetByListID(listID string) ([]models.ListMember, error) {
+	return r.query(`
+		SELECT id, list_id, user_id, role, invited_by, invited_at, accepted_at
+		FROM list_members
+		WHERE list_id = ?
+		ORDER BY invited_at`, listID)
+}
+
+// GetByUserID returns the user's memberships, including pending invitations,
+// in the order they were invited
+func (r *ListMemberRepository) GetByUserID(userID string) ([]models.ListMember, error) {
+	return r.query(`
+		SELECT id, list_id, user_id, role, invited_by, invited_at, accepted_at
+		FROM list_members
+		WHERE user_id = ?
+		ORDER BY invited_at`, userID)
+}
+
+func (r *ListMemberRepository) query(query string, args ...interface{}) ([]models.ListMember, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.ListMember
+	for rows.Next() {
+		var member models.ListMember
+		err := rows.Scan(
+			&member.ID,
+			&member.ListID,
+			&member.UserID,
+			&member.Role,
+			&member.InvitedBy,
+			&member.InvitedAt,
+			&member.AcceptedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan list member row: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating list member rows: %w", err)
+	}
+
+	return members, nil
+}
@@ -21,10 +21,15 @@ func NewLocationRepository(db *DB) *LocationRepository {
 	return &LocationRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs inside tx
+func (r *LocationRepository) WithTx(tx *Tx) *LocationRepository {
+	return &LocationRepository{db: tx.db}
+}
+
 // LocationSearchOptions defines options for searching locations
 type LocationSearchOptions struct {
 	UserID           string   // Filter by user ID
-	Category         *string  // Filter by category
+	Category         *models.LocationCategory // Filter by category
 	NearLatitude     *float64 // Latitude for proximity search
 	NearLongitude    *float64 // Longitude for proximity search
 	WithinMeters     *float64 // Maximum distance in meters for proximity search
@@ -49,10 +54,15 @@ func (r *LocationRepository) Create(location *models.Location) error {
 	query := `
 		INSERT INTO locations (
 			id, user_id, name, address, latitude, longitude, 
-			radius, category, place_id, metadata, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			radius, category, place_id, open_hours, metadata, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query,
+	openHours, err := openHoursValue(location.OpenHours)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(query,
 		location.ID,
 		location.UserID,
 		location.Name,
@@ -62,6 +72,7 @@ func (r *LocationRepository) Create(location *models.Location) error {
 		location.Radius,
 		location.Category,
 		location.PlaceID,
+		openHours,
 		location.Metadata,
 		location.CreatedAt,
 		location.UpdatedAt,
@@ -82,7 +93,7 @@ func (r *LocationRepository) GetByID(id string) (*models.Location, error) {
 
 	query := `
 		SELECT id, user_id, name, address, latitude, longitude, 
-		       radius, category, place_id, metadata, created_at, updated_at
+		       radius, category, place_id, open_hours, metadata, created_at, updated_at
 		FROM locations 
 		WHERE id = ?`
 
@@ -98,7 +109,8 @@ func (r *LocationRepository) GetByID(id string) (*models.Location, error) {
 		&location.Radius,
 		&location.Category,
 		&location.PlaceID,
-		&location.Metadata,
+		scanOpenHours(&location.OpenHours),
+		scanMetadata(&location.Metadata),
 		&location.CreatedAt,
 		&location.UpdatedAt,
 	)
@@ -110,6 +122,7 @@ func (r *LocationRepository) GetByID(id string) (*models.Location, error) {
 		return nil, fmt.Errorf("failed to get location by ID: %w", err)
 	}
 
+	location.Metadata = normalizeMetadata("locations", location.ID, location.Metadata)
 	return location, nil
 }
 
@@ -130,9 +143,14 @@ func (r *LocationRepository) Update(location *models.Location) error {
 	query := `
 		UPDATE locations 
 		SET name = ?, address = ?, latitude = ?, longitude = ?, 
-		    radius = ?, category = ?, place_id = ?, metadata = ?, updated_at = ?
+		    radius = ?, category = ?, place_id = ?, open_hours = ?, metadata = ?, updated_at = ?
 		WHERE id = ?`
 
+	openHours, err := openHoursValue(location.OpenHours)
+	if err != nil {
+		return err
+	}
+
 	result, err := r.db.Exec(query,
 		location.Name,
 		location.Address,
@@ -141,6 +159,7 @@ func (r *LocationRepository) Update(location *models.Location) error {
 		location.Radius,
 		location.Category,
 		location.PlaceID,
+		openHours,
 		location.Metadata,
 		location.UpdatedAt,
 		location.ID,
@@ -224,23 +243,24 @@ func (r *LocationRepository) Search(options LocationSearchOptions) ([]*models.Lo
 	// Base select clause
 	selectClause = `
 		SELECT l.id, l.user_id, l.name, l.address, l.latitude, l.longitude, 
-		       l.radius, l.category, l.place_id, l.metadata, l.created_at, l.updated_at
+		       l.radius, l.category, l.place_id, l.open_hours, l.metadata, l.created_at, l.updated_at
 	`
 
 	// Add distance calculation if proximity search is requested
+	var distanceSQL string
 	if options.NearLatitude != nil && options.NearLongitude != nil {
-		selectClause += fmt.Sprintf(`, %f AS distance`, 0.0) // Placeholder, calculated below
+		distanceSQL = r.db.Dialect().distance("l",
+			fmt.Sprintf("%f", *options.NearLatitude), fmt.Sprintf("%f", *options.NearLongitude))
+		selectClause += ", " + distanceSQL + " AS distance\n"
 	}
 
 	var fromClause string
 	if options.Query != "" {
 		// Use full-text search
-		fromClause = `
-			FROM locations l
-			JOIN locations_fts fts ON l.rowid = fts.rowid
-		`
-		conditions = append(conditions, "locations_fts MATCH ?")
-		args = append(args, options.Query)
+		join, condition := r.db.Dialect().textSearch("locations", "l", "name", "address")
+		fromClause = "FROM locations l " + join
+		conditions = append(conditions, condition)
+		args = append(args, r.db.Dialect().textQuery(options.Query))
 	} else {
 		fromClause = "FROM locations l"
 	}
@@ -260,14 +280,8 @@ func (r *LocationRepository) Search(options LocationSearchOptions) ([]*models.Lo
 	// Add proximity filter using Haversine formula
 	if options.NearLatitude != nil && options.NearLongitude != nil && options.WithinMeters != nil {
 		// Use Haversine formula in SQL
-		haversineSQL := fmt.Sprintf(`
-			(6371000 * acos(
-				cos(radians(%f)) * cos(radians(l.latitude)) * 
-				cos(radians(l.longitude) - radians(%f)) + 
-				sin(radians(%f)) * sin(radians(l.latitude))
-			)) <= %f`,
-			*options.NearLatitude, *options.NearLongitude,
-			*options.NearLatitude, *options.WithinMeters)
+		haversineSQL := r.db.Dialect().distance("l", "?", "?") + " <= ?"
+		args = append(args, *options.NearLatitude, *options.NearLongitude, *options.NearLatitude, *options.WithinMeters)
 		
 		conditions = append(conditions, haversineSQL)
 	}
@@ -281,13 +295,7 @@ func (r *LocationRepository) Search(options LocationSearchOptions) ([]*models.Lo
 	// Build ORDER BY clause
 	if options.NearLatitude != nil && options.NearLongitude != nil && options.OrderBy == "distance" {
 		// Order by calculated distance
-		orderClause = fmt.Sprintf(`
-			ORDER BY (6371000 * acos(
-				cos(radians(%f)) * cos(radians(l.latitude)) * 
-				cos(radians(l.longitude) - radians(%f)) + 
-				sin(radians(%f)) * sin(radians(l.latitude))
-			))`,
-			*options.NearLatitude, *options.NearLongitude, *options.NearLatitude)
+		orderClause = "ORDER BY " + distanceSQL
 		
 		if options.OrderDirection == "DESC" {
 			orderClause += " DESC"
@@ -348,7 +356,8 @@ func (r *LocationRepository) Search(options LocationSearchOptions) ([]*models.Lo
 			&location.Radius,
 			&location.Category,
 			&location.PlaceID,
-			&location.Metadata,
+			scanOpenHours(&location.OpenHours),
+			scanMetadata(&location.Metadata),
 			&location.CreatedAt,
 			&location.UpdatedAt,
 		}
@@ -363,6 +372,8 @@ func (r *LocationRepository) Search(options LocationSearchOptions) ([]*models.Lo
 			return nil, fmt.Errorf("failed to scan location row: %w", err)
 		}
 
+		location.Metadata = normalizeMetadata("locations", location.ID, location.Metadata)
+
 		// Calculate actual distance using Go function for accuracy
 		if options.NearLatitude != nil && options.NearLongitude != nil {
 			actualDistance := location.DistanceFrom(*options.NearLatitude, *options.NearLongitude)
@@ -391,8 +402,22 @@ func (r *LocationRepository) GetByUser(userID string, limit, offset int) ([]*mod
 	return r.Search(options)
 }
 
+// GetByUserID returns all of a user's locations by name, as the filter
+// engine reads them
+func (r *LocationRepository) GetByUserID(userID string) ([]models.Location, error) {
+	found, err := r.GetByUser(userID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]models.Location, len(found))
+	for i, location := range found {
+		locations[i] = *location
+	}
+	return locations, nil
+}
+
 // GetByCategory returns all locations in a specific category for a user
-func (r *LocationRepository) GetByCategory(userID, category string, limit, offset int) ([]*models.Location, error) {
+func (r *LocationRepository) GetByCategory(userID string, category models.LocationCategory, limit, offset int) ([]*models.Location, error) {
 	options := LocationSearchOptions{
 		UserID:   userID,
 		Category: &category,
@@ -422,21 +447,15 @@ func (r *LocationRepository) GetNearby(userID string, latitude, longitude, radiu
 // FindAtCoordinates finds locations that contain the given coordinates within their radius
 func (r *LocationRepository) FindAtCoordinates(userID string, latitude, longitude float64) ([]*models.Location, error) {
 	// Get all user locations and filter by those containing the coordinates
+	// The distance expression takes latitude, longitude, latitude
+	distance := r.db.Dialect().distance("", "?", "?")
 	query := `
 		SELECT id, user_id, name, address, latitude, longitude, 
-		       radius, category, place_id, metadata, created_at, updated_at,
-		       (6371000 * acos(
-				cos(radians(?)) * cos(radians(latitude)) * 
-				cos(radians(longitude) - radians(?)) + 
-				sin(radians(?)) * sin(radians(latitude))
-			)) as distance
+		       radius, category, place_id, open_hours, metadata, created_at, updated_at,
+		       ` + distance + ` as distance
 		FROM locations 
 		WHERE user_id = ? 
-		AND (6371000 * acos(
-			cos(radians(?)) * cos(radians(latitude)) * 
-			cos(radians(longitude) - radians(?)) + 
-			sin(radians(?)) * sin(radians(latitude))
-		)) <= radius
+		AND ` + distance + ` <= radius
 		ORDER BY distance ASC`
 
 	rows, err := r.db.Query(query, latitude, longitude, latitude, userID, latitude, longitude, latitude)
@@ -460,7 +479,8 @@ func (r *LocationRepository) FindAtCoordinates(userID string, latitude, longitud
 			&location.Radius,
 			&location.Category,
 			&location.PlaceID,
-			&location.Metadata,
+			scanOpenHours(&location.OpenHours),
+			scanMetadata(&location.Metadata),
 			&location.CreatedAt,
 			&location.UpdatedAt,
 			&distance,
@@ -469,6 +489,7 @@ func (r *LocationRepository) FindAtCoordinates(userID string, latitude, longitud
 			return nil, fmt.Errorf("failed to scan location row: %w", err)
 		}
 
+		location.Metadata = normalizeMetadata("locations", location.ID, location.Metadata)
 		locations = append(locations, location)
 	}
 
@@ -493,7 +514,7 @@ func (r *LocationRepository) FullTextSearch(userID, query string, limit, offset
 }
 
 // GetCategories returns all unique categories for a user's locations
-func (r *LocationRepository) GetCategories(userID string) ([]string, error) {
+func (r *LocationRepository) GetCategories(userID string) ([]models.LocationCategory, error) {
 	query := `
 		SELECT DISTINCT category 
 		FROM locations 
@@ -506,9 +527,9 @@ func (r *LocationRepository) GetCategories(userID string) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var categories []string
+	var categories []models.LocationCategory
 	for rows.Next() {
-		var category string
+		var category models.LocationCategory
 		if err := rows.Scan(&category); err != nil {
 			return nil, fmt.Errorf("failed to scan category row: %w", err)
 		}
@@ -526,12 +547,10 @@ func (r *LocationRepository) Count(options LocationSearchOptions) (int, error) {
 	// Build query conditions (similar to Search method but for count)
 	var fromClause string
 	if options.Query != "" {
-		fromClause = `
-			FROM locations l
-			JOIN locations_fts fts ON l.rowid = fts.rowid
-		`
-		conditions = append(conditions, "locations_fts MATCH ?")
-		args = append(args, options.Query)
+		join, condition := r.db.Dialect().textSearch("locations", "l", "name", "address")
+		fromClause = "FROM locations l " + join
+		conditions = append(conditions, condition)
+		args = append(args, r.db.Dialect().textQuery(options.Query))
 	} else {
 		fromClause = "FROM locations l"
 	}
@@ -547,14 +566,8 @@ func (r *LocationRepository) Count(options LocationSearchOptions) (int, error) {
 	}
 
 	if options.NearLatitude != nil && options.NearLongitude != nil && options.WithinMeters != nil {
-		haversineSQL := fmt.Sprintf(`
-			(6371000 * acos(
-				cos(radians(%f)) * cos(radians(l.latitude)) * 
-				cos(radians(l.longitude) - radians(%f)) + 
-				sin(radians(%f)) * sin(radians(l.latitude))
-			)) <= %f`,
-			*options.NearLatitude, *options.NearLongitude,
-			*options.NearLatitude, *options.WithinMeters)
+		haversineSQL := r.db.Dialect().distance("l", "?", "?") + " <= ?"
+		args = append(args, *options.NearLatitude, *options.NearLongitude, *options.NearLatitude, *options.WithinMeters)
 		
 		conditions = append(conditions, haversineSQL)
 	}
@@ -633,4 +646,48 @@ func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 
 	return R * c
-}
\ No newline at end of file
+}
+
+// openHoursColumn scans the nullable JSON open_hours column
+type openHoursColumn struct {
+	dest *models.OpenHours
+}
+
+func scanOpenHours(dest *models.OpenHours) openHoursColumn {
+	return openHoursColumn{dest: dest}
+}
+
+func (c openHoursColumn) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*c.dest = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported open hours type %T", src)
+	}
+
+	var hours models.OpenHours
+	if err := json.Unmarshal(raw, &hours); err != nil {
+		return fmt.Errorf("invalid open hours: %w", err)
+	}
+	*c.dest = hours
+	return nil
+}
+
+// openHoursValue stores hours as JSON, or NULL for a location that is always
+// open
+func openHoursValue(hours models.OpenHours) (interface{}, error) {
+	if !hours.IsSet() {
+		return nil, nil
+	}
+	data, err := json.Marshal(hours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode open hours: %w", err)
+	}
+	return string(data), nil
+}
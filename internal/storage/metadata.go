@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// emptyMetadata is stored in place of metadata that cannot be parsed
+var emptyMetadata = json.RawMessage(`{}`)
+
+// metadataColumns lists every JSON column checked by the metadata repair
+var metadataColumns = []struct {
+	Table  string
+	Column string
+}{
+	{"tasks", "metadata"},
+	{"locations", "metadata"},
+	{"contexts", "metadata"},
+	{"users", "settings"},
+}
+
+// metadataColumn scans a TEXT or BLOB JSON column into a json.RawMessage.
+// SQLite returns TEXT values as strings, which database/sql will not assign
+// to a RawMessage directly.
+type metadataColumn struct {
+	dest *json.RawMessage
+}
+
+func scanMetadata(dest *json.RawMessage) metadataColumn {
+	return metadataColumn{dest: dest}
+}
+
+func (m metadataColumn) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m.dest = nil
+	case string:
+		*m.dest = json.RawMessage(v)
+	case []byte:
+		*m.dest = append(json.RawMessage(nil), v...)
+	default:
+		return fmt.Errorf("unsupported metadata type %T", src)
+	}
+	return nil
+}
+
+// normalizeMetadata returns raw unchanged when it is valid JSON. Missing or
+// corrupt values are replaced with an empty object so a single bad row does
+// not fail the whole query.
+func normalizeMetadata(table, id string, raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return emptyMetadata
+	}
+
+	if !json.Valid(raw) {
+		log.Printf("warning: %s row %s has invalid metadata JSON, treating as empty", table, id)
+		return emptyMetadata
+	}
+
+	return raw
+}
+
+// CorruptMetadata identifies a row whose JSON column cannot be parsed
+type CorruptMetadata struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	ID     string `json:"id"`
+}
+
+// MetadataRepository finds and repairs unparseable JSON columns
+type MetadataRepository struct {
+	db *DB
+}
+
+// NewMetadataRepository creates a new metadata repository
+func NewMetadataRepository(db *DB) *MetadataRepository {
+	return &MetadataRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *MetadataRepository) WithTx(tx *Tx) *MetadataRepository {
+	return &MetadataRepository{db: tx.db}
+}
+
+// FindCorrupt scans all JSON columns and returns rows with invalid JSON
+func (r *MetadataRepository) FindCorrupt() ([]CorruptMetadata, error) {
+	var corrupt []CorruptMetadata
+
+	for _, col := range metadataColumns {
+		query := fmt.Sprintf(`SELECT id, %s FROM %s WHERE %s IS NOT NULL`, col.Column, col.Table, col.Column)
+
+		rows, err := r.db.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s.%s: %w", col.Table, col.Column, err)
+		}
+
+		for rows.Next() {
+			var id string
+			var raw []byte
+			if err := rows.Scan(&id, &raw); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s row: %w", col.Table, err)
+			}
+
+			if len(raw) > 0 && !json.Valid(raw) {
+				corrupt = append(corrupt, CorruptMetadata{Table: col.Table, Column: col.Column, ID: id})
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error iterating %s rows: %w", col.Table, err)
+		}
+		rows.Close()
+	}
+
+	return corrupt, nil
+}
+
+// Repair resets the given rows' JSON columns to an empty object
+func (r *MetadataRepository) Repair(corrupt []CorruptMetadata) (int, error) {
+	repaired := 0
+
+	err := r.db.WithTx(func(tx *DB) error {
+		for _, c := range corrupt {
+			column, ok := metadataColumnFor(c.Table)
+			if !ok || column != c.Column {
+				return fmt.Errorf("unknown metadata column: %s.%s", c.Table, c.Column)
+			}
+
+			query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, c.Table, c.Column)
+			result, err := tx.Exec(query, string(emptyMetadata), c.ID)
+			if err != nil {
+				return fmt.Errorf("failed to repair %s row %s: %w", c.Table, c.ID, err)
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to get affected rows: %w", err)
+			}
+			repaired += int(rowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return repaired, nil
+}
+
+func metadataColumnFor(table string) (string, bool) {
+	for _, col := range metadataColumns {
+		if col.Table == table {
+			return col.Column, true
+		}
+	}
+	return "", false
+}
@@ -50,7 +50,7 @@ func (m *Migrator) Init() error {
 		UNIQUE(filename)
 	)`
 
-	_, err := m.db.Exec(createTableSQL)
+	_, err := m.db.Exec(m.db.Dialect().TranslateDDL(createTableSQL))
 	if err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
@@ -217,7 +217,7 @@ func (m *Migrator) applyMigration(migration Migration) error {
 
 	// Record the migration
 	insertSQL := `INSERT INTO migrations (id, name, filename) VALUES (?, ?, ?)`
-	if _, err := tx.Exec(insertSQL, migration.ID, migration.Name, migration.Filename); err != nil {
+	if _, err := tx.Exec(m.db.Dialect().Rebind(insertSQL), migration.ID, migration.Name, migration.Filename); err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
 
@@ -239,7 +239,7 @@ func (m *Migrator) rollbackMigration(migration Migration) error {
 
 	// Remove the migration record
 	deleteSQL := `DELETE FROM migrations WHERE id = ?`
-	if _, err := tx.Exec(deleteSQL, migration.ID); err != nil {
+	if _, err := tx.Exec(m.db.Dialect().Rebind(deleteSQL), migration.ID); err != nil {
 		return fmt.Errorf("failed to remove migration record: %w", err)
 	}
 
@@ -260,6 +260,10 @@ func (m *Migrator) loadMigrationFiles() ([]Migration, error) {
 		}
 
 		if d.IsDir() {
+			// Subdirectories hold dialect-specific versions of migrations
+			if path != m.migrationsDir {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -288,7 +292,10 @@ func (m *Migrator) loadMigrationFiles() ([]Migration, error) {
 	return migrations, nil
 }
 
-// loadMigrationFile loads a single migration file
+// loadMigrationFile loads a single migration file. On PostgreSQL a file of
+// the same name in the postgres subdirectory replaces it when there is one,
+// for migrations using SQLite-only features; otherwise the file's SQL is
+// translated with Dialect.TranslateDDL.
 func (m *Migrator) loadMigrationFile(filename string) (Migration, error) {
 	// Parse migration ID and name from filename
 	// Expected format: 001_initial_schema.sql
@@ -306,13 +313,26 @@ func (m *Migrator) loadMigrationFile(filename string) (Migration, error) {
 	name := strings.ReplaceAll(matches[2], "_", " ")
 
 	// Read the migration file
+	dialect := m.db.Dialect()
 	filePath := filepath.Join(m.migrationsDir, filename)
+	translate := true
+	if dialect == DialectPostgres {
+		override := filepath.Join(m.migrationsDir, string(DialectPostgres), filename)
+		if _, err := os.Stat(override); err == nil {
+			filePath = override
+			translate = false
+		}
+	}
+
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return Migration{}, fmt.Errorf("failed to read migration file: %w", err)
 	}
 
 	upSQL, downSQL := m.parseMigrationContent(string(content))
+	if translate {
+		upSQL, downSQL = dialect.TranslateDDL(upSQL), dialect.TranslateDDL(downSQL)
+	}
 
 	return Migration{
 		ID:       id,
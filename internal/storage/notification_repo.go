@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type NotificationRepository struct {
+	db *DB
+}
+
+func NewNotificationRepository(db *DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *NotificationRepository) WithTx(tx *Tx) *NotificationRepository {
+	return &NotificationRepository{db: tx.db}
+}
+
+// Create stores a new notification
+func (r *NotificationRepository) Create(notification models.Notification) error {
+	if err := notification.Validate(); err != nil {
+		return fmt.Errorf("notification validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO notifications (id, user_id, type, task_id, message, created_at, read_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		notification.ID,
+		notification.UserID,
+		string(notification.Type),
+		notification.TaskID,
+		notification.Message,
+		notification.CreatedAt,
+		notification.ReadAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUserID returns a user's notifications, newest first
+func (r *NotificationRepository) GetByUserID(userID string, unreadOnly bool) ([]models.Notification, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID cannot be empty")
+	}
+
+	query := `
+		SELECT id, user_id, type, task_id, message, created_at, read_at, delivered_at, delivery_attempts
+		FROM notifications
+		WHERE user_id = ?`
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// MarkRead marks a notification as read
+func (r *NotificationRepository) MarkRead(notificationID string) error {
+	query := `UPDATE notifications SET read_at = ? WHERE id = ? AND read_at IS NULL`
+
+	if _, err := r.db.Exec(query, time.Now(), notificationID); err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	return nil
+}
+
+// Delete withdraws a notification, e.g. when the completion it announced
+// was undone
+func (r *NotificationRepository) Delete(notificationID string) error {
+	if _, err := r.db.Exec(`DELETE FROM notifications WHERE id = ?`, notificationID); err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	return nil
+}
+
+// GetUndelivered returns up to limit notifications, oldest first, that
+// belong to users with a notification channel turned on, were created after
+// the user's settings were, and have neither been delivered nor given up on
+func (r *NotificationRepository) GetUndelivered(limit int) ([]models.Notification, error) {
+	query := `
+		SELECT n.id, n.user_id, n.type, n.task_id, n.message, n.created_at, n.read_at, n.delivered_at, n.delivery_attempts
+		FROM notifications n
+		JOIN user_notification_settings s ON s.user_id = n.user_id
+		WHERE n.delivered_at IS NULL AND n.delivery_failed_at IS NULL
+			AND n.created_at >= s.created_at
+			AND (s.webhook_url != '' OR s.ntfy_topic != '' OR s.email_notifications_enabled = ?)
+		ORDER BY n.created_at ASC
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, true, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get undelivered notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// MarkDelivered records that the notification reached the user's channels
+func (r *NotificationRepository) MarkDelivered(notificationID string, at time.Time) error {
+	query := `UPDATE notifications SET delivered_at = ?, delivery_error = NULL WHERE id = ?`
+
+	if _, err := r.db.Exec(query, at, notificationID); err != nil {
+		return fmt.Errorf("failed to mark notification delivered: %w", err)
+	}
+
+	return nil
+}
+
+// RecordDeliveryFailure counts a failed delivery pass, and when giveUp is
+// set stops the notification being returned as undelivered
+func (r *NotificationRepository) RecordDeliveryFailure(notificationID, reason string, giveUp bool) error {
+	var failedAt *time.Time
+	if giveUp {
+		now := time.Now()
+		failedAt = &now
+	}
+
+	query := `
+		UPDATE notifications
+		SET delivery_attempts = delivery_attempts + 1, delivery_error = ?, delivery_failed_at = ?
+		WHERE id = ?`
+
+	if _, err := r.db.Exec(query, reason, failedAt, notificationID); err != nil {
+		return fmt.Errorf("failed to record notification delivery failure: %w", err)
+	}
+
+	return nil
+}
+
+func scanNotifications(rows *sql.Rows) ([]models.Notification, error) {
+	var notifications []models.Notification
+	for rows.Next() {
+		var notification models.Notification
+		var notificationType string
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notificationType,
+			&notification.TaskID,
+			&notification.Message,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+			&notification.DeliveredAt,
+			&notification.DeliveryAttempts,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+		notification.Type = models.NotificationType(notificationType)
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification rows: %w", err)
+	}
+
+	return notifications, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// NotificationSettingsRepository stores where users' notifications are
+// pushed
+type NotificationSettingsRepository struct {
+	db *DB
+}
+
+func NewNotificationSettingsRepository(db *DB) *NotificationSettingsRepository {
+	return &NotificationSettingsRepository{db: db}
+}
+
+// Get returns the user's settings, or nil when they have none
+func (r *NotificationSettingsRepository) Get(userID string) (*models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := r.db.QueryRow(`
+		SELECT user_id, webhook_url, ntfy_topic, ntfy_server, email_notifications_enabled, created_at, updated_at
+		FROM user_notification_settings
+		WHERE user_id = ?`, userID).Scan(
+		&settings.UserID,
+		&settings.WebhookURL,
+		&settings.NtfyTopic,
+		&settings.NtfyServer,
+		&settings.EmailNotificationsEnabled,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get notification settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// Save stores the settings, replacing the user's previous ones. The time
+// they were first created is kept.
+func (r *NotificationSettingsRepository) Save(settings models.NotificationSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_notification_settings (user_id, webhook_url, ntfy_topic, ntfy_server, email_notifications_enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			webhook_url = excluded.webhook_url,
+			ntfy_topic = excluded.ntfy_topic,
+			ntfy_server = excluded.ntfy_server,
+			email_notifications_enabled = excluded.email_notifications_enabled,
+			updated_at = excluded.updated_at`
+
+	_, err := r.db.Exec(query,
+		settings.UserID,
+		settings.WebhookURL,
+		settings.NtfyTopic,
+		settings.NtfyServer,
+		settings.EmailNotificationsEnabled,
+		settings.CreatedAt,
+		settings.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+
+	return nil
+}
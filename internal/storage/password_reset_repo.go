@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// PasswordResetRepository stores the hashed tokens of password resets
+type PasswordResetRepository struct {
+	db *DB
+}
+
+func NewPasswordResetRepository(db *DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) Create(token models.PasswordResetToken) error {
+	if token.TokenHash == "" {
+		return fmt.Errorf("password reset token hash cannot be empty")
+	}
+	if token.UserID == "" {
+		return fmt.Errorf("user ID cannot be empty")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, used_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		token.ID,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.UsedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PasswordResetRepository) GetByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	token := &models.PasswordResetToken{}
+	err := r.db.QueryRow(`
+		SELECT id, user_id, token_hash, expires_at, used_at
+		FROM password_reset_tokens
+		WHERE token_hash = ?`, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("password reset token not found")
+		}
+		return nil, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+
+	return token, nil
+}
+
+// MarkUsed records when the token was used. It fails for a token that is
+// already used, so only one reset can claim it.
+func (r *PasswordResetRepository) MarkUsed(tokenID string, at time.Time) error {
+	result, err := r.db.Exec(`
+		UPDATE password_reset_tokens SET used_at = ?
+		WHERE id = ? AND used_at IS NULL`, at, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to mark password reset token used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("password reset token not found or already used")
+	}
+
+	return nil
+}
+
+// MarkAllUsed records every token of the user that is not used yet as used
+// at the given time
+func (r *PasswordResetRepository) MarkAllUsed(userID string, at time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE password_reset_tokens SET used_at = ?
+		WHERE user_id = ? AND used_at IS NULL`, at, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark password reset tokens used: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the tokens that had expired by now, used or not,
+// and returns how many it removed
+func (r *PasswordResetRepository) DeleteExpired(now time.Time) (int, error) {
+	result, err := r.db.Exec(`DELETE FROM password_reset_tokens WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired password reset tokens: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(removed), nil
+}
@@ -0,0 +1,12 @@
+//go:build !postgres
+
+package storage
+
+import "database/sql"
+
+// PostgresAvailable reports whether this build can connect to PostgreSQL
+const PostgresAvailable = false
+
+func openPostgres(dsn string) (*sql.DB, error) {
+	return nil, ErrPostgresUnavailable
+}
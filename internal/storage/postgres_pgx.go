@@ -0,0 +1,20 @@
+//go:build postgres
+
+// PostgreSQL builds register the pgx driver:
+//
+//	go build -tags postgres ./cmd/hereandnow
+
+package storage
+
+import (
+	"database/sql"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// PostgresAvailable reports whether this build can connect to PostgreSQL
+const PostgresAvailable = true
+
+func openPostgres(dsn string) (*sql.DB, error) {
+	return sql.Open("pgx", dsn)
+}
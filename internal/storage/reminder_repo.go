@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ReminderRepository stores task reminders. Claim's conditional update is
+// what lets several servers send reminders from one database without
+// sending any twice.
+type ReminderRepository struct {
+	db *DB
+}
+
+func NewReminderRepository(db *DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *ReminderRepository) WithTx(tx *Tx) *ReminderRepository {
+	return &ReminderRepository{db: tx.db}
+}
+
+func (r *ReminderRepository) Create(reminder models.Reminder) error {
+	if err := reminder.Validate(); err != nil {
+		return fmt.Errorf("reminder validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_reminders (id, task_id, user_id, remind_at, sent, sent_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		reminder.ID,
+		reminder.TaskID,
+		reminder.UserID,
+		reminder.RemindAt,
+		reminder.Sent,
+		reminder.SentAt,
+		reminder.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create reminder: %w", err)
+	}
+
+	return nil
+}
+
+// GetByTaskID returns the task's reminders, earliest first
+func (r *ReminderRepository) GetByTaskID(taskID string) ([]models.Reminder, error) {
+	query := `
+		SELECT id, task_id, user_id, remind_at, sent, sent_at, created_at
+		FROM task_reminders
+		WHERE task_id = ?
+		ORDER BY remind_at ASC`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReminders(rows)
+}
+
+// GetDue returns up to limit unsent reminders due by now, earliest first
+func (r *ReminderRepository) GetDue(now time.Time, limit int) ([]models.Reminder, error) {
+	query := `
+		SELECT id, task_id, user_id, remind_at, sent, sent_at, created_at
+		FROM task_reminders
+		WHERE sent = ? AND remind_at <= ?
+		ORDER BY remind_at ASC
+		LIMIT ?`
+
+	rows, err := r.db.Query(query, false, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReminders(rows)
+}
+
+// Claim marks the reminder sent unless it already is, and reports whether
+// this call marked it
+func (r *ReminderRepository) Claim(reminderID string, at time.Time) (bool, error) {
+	query := `UPDATE task_reminders SET sent = ?, sent_at = ? WHERE id = ? AND sent = ?`
+
+	result, err := r.db.Exec(query, true, at, reminderID, false)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %w", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return claimed == 1, nil
+}
+
+func scanReminders(rows *sql.Rows) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	for rows.Next() {
+		var reminder models.Reminder
+		err := rows.Scan(
+			&reminder.ID,
+			&reminder.TaskID,
+			&reminder.UserID,
+			&reminder.RemindAt,
+			&reminder.Sent,
+			&reminder.SentAt,
+			&reminder.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder row: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reminder rows: %w", err)
+	}
+
+	return reminders, nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// RevokedTokenRepository stores the IDs of logged-out access tokens until
+// the tokens would have expired
+type RevokedTokenRepository struct {
+	db *DB
+}
+
+func NewRevokedTokenRepository(db *DB) *RevokedTokenRepository {
+	return &RevokedTokenRepository{db: db}
+}
+
+// Revoke records the token ID. Revoking it again is a no-op.
+func (r *RevokedTokenRepository) Revoke(tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return fmt.Errorf("token ID cannot be empty")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES (?, ?)
+		ON CONFLICT (jti) DO NOTHING`,
+		tokenID,
+		expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the token ID was revoked and had not yet expired
+// by now
+func (r *RevokedTokenRepository) IsRevoked(tokenID string, now time.Time) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM revoked_tokens
+		WHERE jti = ? AND expires_at > ?`, tokenID, now).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// DeleteExpired removes the IDs of tokens that had expired by now, which no
+// longer need refusing, and returns how many it removed
+func (r *RevokedTokenRepository) DeleteExpired(now time.Time) (int, error) {
+	result, err := r.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(removed), nil
+}
@@ -16,22 +16,34 @@ func NewSessionRepository(db *DB) *SessionRepository {
 	return &SessionRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs inside tx
+func (r *SessionRepository) WithTx(tx *Tx) *SessionRepository {
+	return &SessionRepository{db: tx.db}
+}
+
 func (r *SessionRepository) Create(session auth.Session) error {
-	if session.Token == "" {
-		return fmt.Errorf("session token cannot be empty")
+	if session.ID == "" {
+		return fmt.Errorf("session ID cannot be empty")
+	}
+	if session.TokenHash == "" {
+		return fmt.Errorf("session token hash cannot be empty")
 	}
 	if session.UserID == "" {
 		return fmt.Errorf("user ID cannot be empty")
 	}
 
 	query := `
-		INSERT INTO sessions (token, user_id, created_at, expires_at, user_agent, ip_address)
-		VALUES (?, ?, ?, ?, ?, ?)`
+		INSERT INTO sessions (id, token_hash, refresh_token_hash, user_id, device_name, created_at, last_used_at, expires_at, user_agent, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := r.db.Exec(query,
-		session.Token,
+		session.ID,
+		session.TokenHash,
+		session.RefreshTokenHash,
 		session.UserID,
+		session.DeviceName,
 		session.CreatedAt,
+		session.LastUsedAt,
 		session.ExpiresAt,
 		session.UserAgent,
 		session.IPAddress,
@@ -44,25 +56,19 @@ func (r *SessionRepository) Create(session auth.Session) error {
 	return nil
 }
 
-func (r *SessionRepository) GetByToken(token string) (*auth.Session, error) {
-	if token == "" {
-		return nil, fmt.Errorf("token cannot be empty")
+// GetByTokenHash returns the session whose access token has the hash
+func (r *SessionRepository) GetByTokenHash(tokenHash string) (*auth.Session, error) {
+	if tokenHash == "" {
+		return nil, fmt.Errorf("token hash cannot be empty")
 	}
 
 	query := `
-		SELECT token, user_id, created_at, expires_at, user_agent, ip_address
+		SELECT ` + sessionSelectColumns + `
 		FROM sessions
-		WHERE token = ?`
+		WHERE token_hash = ?`
 
 	session := &auth.Session{}
-	err := r.db.QueryRow(query, token).Scan(
-		&session.Token,
-		&session.UserID,
-		&session.CreatedAt,
-		&session.ExpiresAt,
-		&session.UserAgent,
-		&session.IPAddress,
-	)
+	err := r.db.QueryRow(query, tokenHash).Scan(sessionColumns(session)...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -74,13 +80,14 @@ func (r *SessionRepository) GetByToken(token string) (*auth.Session, error) {
 	return session, nil
 }
 
+// GetByUserID returns the user's sessions, newest first
 func (r *SessionRepository) GetByUserID(userID string) ([]auth.Session, error) {
 	if userID == "" {
 		return nil, fmt.Errorf("user ID cannot be empty")
 	}
 
 	query := `
-		SELECT token, user_id, created_at, expires_at, user_agent, ip_address
+		SELECT ` + sessionSelectColumns + `
 		FROM sessions
 		WHERE user_id = ?
 		ORDER BY created_at DESC`
@@ -94,15 +101,7 @@ func (r *SessionRepository) GetByUserID(userID string) ([]auth.Session, error) {
 	var sessions []auth.Session
 	for rows.Next() {
 		session := auth.Session{}
-		err := rows.Scan(
-			&session.Token,
-			&session.UserID,
-			&session.CreatedAt,
-			&session.ExpiresAt,
-			&session.UserAgent,
-			&session.IPAddress,
-		)
-		if err != nil {
+		if err := rows.Scan(sessionColumns(&session)...); err != nil {
 			return nil, fmt.Errorf("failed to scan session row: %w", err)
 		}
 		sessions = append(sessions, session)
@@ -115,14 +114,58 @@ func (r *SessionRepository) GetByUserID(userID string) ([]auth.Session, error) {
 	return sessions, nil
 }
 
-func (r *SessionRepository) Delete(token string) error {
-	if token == "" {
-		return fmt.Errorf("token cannot be empty")
+// GetByRefreshTokenHash returns the session the refresh token with the
+// hash was issued with
+func (r *SessionRepository) GetByRefreshTokenHash(refreshTokenHash string) (*auth.Session, error) {
+	if refreshTokenHash == "" {
+		return nil, fmt.Errorf("refresh token hash cannot be empty")
+	}
+
+	query := `
+		SELECT ` + sessionSelectColumns + `
+		FROM sessions
+		WHERE refresh_token_hash = ?`
+
+	session := &auth.Session{}
+	err := r.db.QueryRow(query, refreshTokenHash).Scan(sessionColumns(session)...)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session by refresh token: %w", err)
+	}
+
+	return session, nil
+}
+
+// sessionSelectColumns are the columns sessionColumns scans
+const sessionSelectColumns = `id, token_hash, COALESCE(refresh_token_hash, ''), user_id, device_name, created_at, last_used_at, expires_at, user_agent, ip_address`
+
+// sessionColumns returns scan targets for the columns the queries select
+func sessionColumns(session *auth.Session) []interface{} {
+	return []interface{}{
+		&session.ID,
+		&session.TokenHash,
+		&session.RefreshTokenHash,
+		&session.UserID,
+		&session.DeviceName,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.UserAgent,
+		&session.IPAddress,
+	}
+}
+
+func (r *SessionRepository) Delete(sessionID string) error {
+	if sessionID == "" {
+		return fmt.Errorf("session ID cannot be empty")
 	}
 
-	query := `DELETE FROM sessions WHERE token = ?`
+	query := `DELETE FROM sessions WHERE id = ?`
 
-	result, err := r.db.Exec(query, token)
+	result, err := r.db.Exec(query, sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
@@ -139,6 +182,42 @@ func (r *SessionRepository) Delete(token string) error {
 	return nil
 }
 
+// UpdateLastUsed records when the session's token was last used
+func (r *SessionRepository) UpdateLastUsed(sessionID string, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE sessions SET last_used_at = ? WHERE id = ?`, at, sessionID); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// RotateToken gives the session the access token a refresh issued, and
+// records the client that refreshed it as its latest use
+func (r *SessionRepository) RotateToken(sessionID, tokenHash, userAgent, ipAddress string, at time.Time) error {
+	if tokenHash == "" {
+		return fmt.Errorf("session token hash cannot be empty")
+	}
+
+	query := `
+		UPDATE sessions
+		SET token_hash = ?, user_agent = ?, ip_address = ?, last_used_at = ?
+		WHERE id = ?`
+
+	result, err := r.db.Exec(query, tokenHash, userAgent, ipAddress, at, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to rotate session token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
+
 func (r *SessionRepository) DeleteExpired() error {
 	query := `DELETE FROM sessions WHERE expires_at < ?`
 
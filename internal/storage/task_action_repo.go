@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type TaskActionRepository struct {
+	db *DB
+}
+
+func NewTaskActionRepository(db *DB) *TaskActionRepository {
+	return &TaskActionRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskActionRepository) WithTx(tx *Tx) *TaskActionRepository {
+	return &TaskActionRepository{db: tx.db}
+}
+
+// Save records the user's latest reversible action, replacing the previous
+// one
+func (r *TaskActionRepository) Save(action models.TaskAction) error {
+	query := `
+		INSERT INTO task_actions (id, user_id, task_id, action_type, snapshot, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			id = excluded.id,
+			task_id = excluded.task_id,
+			action_type = excluded.action_type,
+			snapshot = excluded.snapshot,
+			created_at = excluded.created_at`
+
+	_, err := r.db.Exec(query,
+		action.ID,
+		action.UserID,
+		action.TaskID,
+		action.Type,
+		string(action.Snapshot),
+		action.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save task action: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUserID returns the user's undoable actions, oldest first
+func (r *TaskActionRepository) GetByUserID(userID string) ([]models.TaskAction, error) {
+	query := `
+		SELECT id, user_id, task_id, action_type, snapshot, created_at
+		FROM task_actions
+		WHERE user_id = ?
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []models.TaskAction
+	for rows.Next() {
+		var action models.TaskAction
+		var snapshot string
+		err := rows.Scan(
+			&action.ID,
+			&action.UserID,
+			&action.TaskID,
+			&action.Type,
+			&snapshot,
+			&action.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task action row: %w", err)
+		}
+		action.Snapshot = []byte(snapshot)
+		actions = append(actions, action)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task action rows: %w", err)
+	}
+
+	return actions, nil
+}
+
+func (r *TaskActionRepository) Delete(actionID string) error {
+	if _, err := r.db.Exec(`DELETE FROM task_actions WHERE id = ?`, actionID); err != nil {
+		return fmt.Errorf("failed to delete task action: %w", err)
+	}
+	return nil
+}
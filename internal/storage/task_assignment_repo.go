@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskAssignmentRepository stores who delegated a task to whom and how they
+// responded
+type TaskAssignmentRepository struct {
+	db *DB
+}
+
+func NewTaskAssignmentRepository(db *DB) *TaskAssignmentRepository {
+	return &TaskAssignmentRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskAssignmentRepository) WithTx(tx *Tx) *TaskAssignmentRepository {
+	return &TaskAssignmentRepository{db: tx.db}
+}
+
+func (r *TaskAssignmentRepository) Create(assignment models.TaskAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return fmt.Errorf("assignment validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_assignments (id, task_id, assigned_by, assigned_to, assigned_at, status, response_at, response_message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		assignment.ID,
+		assignment.TaskID,
+		assignment.AssignedBy,
+		assignment.AssignedTo,
+		assignment.AssignedAt,
+		string(assignment.Status),
+		assignment.ResponseAt,
+		assignment.ResponseMessage,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create task assignment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TaskAssignmentRepository) GetByID(assignmentID string) (*models.TaskAssignment, error) {
+	assignments, err := r.query(`
+		SELECT id, task_id, assigned_by, assigned_to, assigned_at, status, response_at, response_message
+		FROM task_assignments
+		WHERE id = ?`, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, fmt.Errorf("task assignment not found")
+	}
+	return &assignments[0], nil
+}
+
+// GetByTaskID returns the task's assignments, oldest first
+func (r *TaskAssignmentRepository) GetByTaskID(taskID string) ([]models.TaskAssignment, error) {
+	return r.query(`
+		SELECT id, task_id, assigned_by, assigned_to, assigned_at, status, response_at, response_message
+		FROM task_assignments
+		WHERE task_id = ?
+		ORDER BY assigned_at`, taskID)
+}
+
+// Update saves the assignment's status and response
+func (r *TaskAssignmentRepository) Update(assignment models.TaskAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return fmt.Errorf("assignment validation failed: %w", err)
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE task_assignments
+		SET status = ?, response_at = ?, response_message = ?
+		WHERE id = ?`,
+		string(assignment.Status),
+		assignment.ResponseAt,
+		assignment.ResponseMessage,
+		assignment.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update task assignment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task assignment not found")
+	}
+
+	return nil
+}
+
+func (r *TaskAssignmentRepository) query(query string, args ...interface{}) ([]models.TaskAssignment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var assignments []models.TaskAssignment
+	for rows.Next() {
+		var assignment models.TaskAssignment
+		err := rows.Scan(
+			&assignment.ID,
+			&assignment.TaskID,
+			&assignment.AssignedBy,
+			&assignment.AssignedTo,
+			&assignment.AssignedAt,
+			&assignment.Status,
+			&assignment.ResponseAt,
+			&assignment.ResponseMessage,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task assignment row: %w", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task assignment rows: %w", err)
+	}
+
+	return assignments, nil
+}
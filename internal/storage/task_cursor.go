@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ErrInvalidCursor is returned for an AfterCursor that SearchPage did not
+// issue
+var ErrInvalidCursor = errors.New("invalid task cursor")
+
+// encodeTaskCursor returns an opaque cursor positioned at task. The time
+// keeps its offset so it compares with created_at exactly as stored.
+func encodeTaskCursor(task *models.Task) string {
+	raw := task.CreatedAt.Format(time.RFC3339Nano) + "|" + task.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeTaskCursor reads a cursor written by encodeTaskCursor
+func decodeTaskCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return at, id, nil
+}
+
+// cursorDirection returns the direction the search orders created_at in,
+// or false when it is ordered some other way and cursors can't be used
+func cursorDirection(options TaskSearchOptions) (string, bool) {
+	switch {
+	case options.OrderBy == "created_at" && options.OrderDirection == "ASC":
+		return "ASC", true
+	case options.OrderBy == "created_at":
+		return "DESC", true
+	case options.OrderBy == "" && options.Query == "":
+		return "DESC", true
+	default:
+		return "", false
+	}
+}
+
+// cursorCondition returns the condition for tasks after options.AfterCursor
+func cursorCondition(options TaskSearchOptions) (string, []interface{}, error) {
+	createdAt, id, err := decodeTaskCursor(options.AfterCursor)
+	if err != nil {
+		return "", nil, err
+	}
+	if options.Offset > 0 {
+		return "", nil, fmt.Errorf("a task cursor cannot be combined with an offset")
+	}
+	direction, ok := cursorDirection(options)
+	if !ok {
+		return "", nil, fmt.Errorf("task cursors need tasks ordered by created_at")
+	}
+
+	op := "<"
+	if direction == "ASC" {
+		op = ">"
+	}
+	condition := fmt.Sprintf("(t.created_at %s ? OR (t.created_at = ? AND t.id %s ?))", op, op)
+	return condition, []interface{}{createdAt, createdAt, id}, nil
+}
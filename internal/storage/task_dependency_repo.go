@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type TaskDependencyRepository struct {
+	db *DB
+}
+
+func NewTaskDependencyRepository(db *DB) *TaskDependencyRepository {
+	return &TaskDependencyRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskDependencyRepository) WithTx(tx *Tx) *TaskDependencyRepository {
+	return &TaskDependencyRepository{db: tx.db}
+}
+
+func (r *TaskDependencyRepository) Create(dependency models.TaskDependency) error {
+	if err := dependency.Validate(); err != nil {
+		return fmt.Errorf("invalid task dependency: %w", err)
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO task_dependencies (id, task_id, depends_on_task_id, dependency_type, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		dependency.ID,
+		dependency.TaskID,
+		dependency.DependsOnTaskID,
+		dependency.DependencyType,
+		dependency.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create task dependency: %w", err)
+	}
+
+	return nil
+}
+
+// AddDependency creates the dependency unless it would close a cycle. The
+// existing graph is walked from the task depended on first; a dependency
+// that would lead back to its own task fails with a
+// *models.DependencyCycleError naming the loop.
+func (r *TaskDependencyRepository) AddDependency(dependency models.TaskDependency) error {
+	if err := dependency.Validate(); err != nil {
+		return fmt.Errorf("invalid task dependency: %w", err)
+	}
+
+	cycle, err := models.FindDependencyCycle(dependency, r.GetDependenciesByTaskID)
+	if err != nil {
+		return err
+	}
+	if cycle != nil {
+		for i, taskID := range cycle {
+			var title string
+			err := r.db.QueryRow(`SELECT title FROM tasks WHERE id = ?`, taskID).Scan(&title)
+			if err == nil {
+				cycle[i] = title
+			} else if err != sql.ErrNoRows {
+				return fmt.Errorf("failed to get task title: %w", err)
+			}
+		}
+		return &models.DependencyCycleError{Path: cycle}
+	}
+
+	return r.Create(dependency)
+}
+
+// GetDependenciesByTaskID returns the dependencies taskID waits on
+func (r *TaskDependencyRepository) GetDependenciesByTaskID(taskID string) ([]models.TaskDependency, error) {
+	return r.query(`WHERE task_id = ?`, taskID)
+}
+
+// GetDependenciesByTaskIDs returns the dependencies each of the tasks waits
+// on, keyed by task ID, in one query
+func (r *TaskDependencyRepository) GetDependenciesByTaskIDs(taskIDs []string) (map[string][]models.TaskDependency, error) {
+	dependencies := make(map[string][]models.TaskDependency)
+	if len(taskIDs) == 0 {
+		return dependencies, nil
+	}
+
+	placeholders, args := inList(taskIDs)
+	found, err := r.query(`WHERE task_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, dep := range found {
+		dependencies[dep.TaskID] = append(dependencies[dep.TaskID], dep)
+	}
+	return dependencies, nil
+}
+
+// GetDependentsByTaskID returns the dependencies waiting on taskID
+func (r *TaskDependencyRepository) GetDependentsByTaskID(taskID string) ([]models.TaskDependency, error) {
+	return r.query(`WHERE depends_on_task_id = ?`, taskID)
+}
+
+func (r *TaskDependencyRepository) Delete(dependentTaskID, dependsOnTaskID string) error {
+	result, err := r.db.Exec(`
+		DELETE FROM task_dependencies
+		WHERE task_id = ? AND depends_on_task_id = ?`, dependentTaskID, dependsOnTaskID)
+	if err != nil {
+		return fmt.Errorf("failed to delete task dependency: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task dependency not found: %s -> %s", dependentTaskID, dependsOnTaskID)
+	}
+
+	return nil
+}
+
+// query returns the dependencies matching where, oldest first
+func (r *TaskDependencyRepository) query(where string, args ...interface{}) ([]models.TaskDependency, error) {
+	rows, err := r.db.Query(`
+		SELECT id, task_id, depends_on_task_id, dependency_type, created_at
+		FROM task_dependencies `+where+`
+		ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var dependencies []models.TaskDependency
+	for rows.Next() {
+		var dep models.TaskDependency
+		if err := rows.Scan(&dep.ID, &dep.TaskID, &dep.DependsOnTaskID, &dep.DependencyType, &dep.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task dependency row: %w", err)
+		}
+		dependencies = append(dependencies, dep)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task dependency rows: %w", err)
+	}
+
+	return dependencies, nil
+}
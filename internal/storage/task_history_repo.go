@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskHistoryRepository stores who changed which fields of a task, and when
+type TaskHistoryRepository struct {
+	db *DB
+}
+
+func NewTaskHistoryRepository(db *DB) *TaskHistoryRepository {
+	return &TaskHistoryRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskHistoryRepository) WithTx(tx *Tx) *TaskHistoryRepository {
+	return &TaskHistoryRepository{db: tx.db}
+}
+
+func (r *TaskHistoryRepository) Create(entry models.TaskHistoryEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_history (id, task_id, actor_id, changes, created_at)
+		VALUES (?, ?, ?, ?, ?)`
+
+	_, err = r.db.Exec(query,
+		entry.ID,
+		entry.TaskID,
+		entry.ActorID,
+		string(changes),
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create task history entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetByTaskID returns the task's history, oldest first
+func (r *TaskHistoryRepository) GetByTaskID(taskID string) ([]models.TaskHistoryEntry, error) {
+	query := `
+		SELECT id, task_id, actor_id, changes, created_at
+		FROM task_history
+		WHERE task_id = ?
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TaskHistoryEntry
+	for rows.Next() {
+		var entry models.TaskHistoryEntry
+		var changes string
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TaskID,
+			&entry.ActorID,
+			&changes,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task history row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task changes: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task history rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteOlderThan removes history entries recorded before the given time
+// (for cleanup)
+func (r *TaskHistoryRepository) DeleteOlderThan(before time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM task_history WHERE created_at < ?`, before); err != nil {
+		return fmt.Errorf("failed to delete old task history: %w", err)
+	}
+	return nil
+}
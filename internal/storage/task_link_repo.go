@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type TaskLinkRepository struct {
+	db *DB
+}
+
+func NewTaskLinkRepository(db *DB) *TaskLinkRepository {
+	return &TaskLinkRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskLinkRepository) WithTx(tx *Tx) *TaskLinkRepository {
+	return &TaskLinkRepository{db: tx.db}
+}
+
+func (r *TaskLinkRepository) Create(link models.TaskLink) error {
+	if err := link.Validate(); err != nil {
+		return fmt.Errorf("invalid task link: %w", err)
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO task_links (id, task_id, linked_task_id, link_type, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		link.ID,
+		link.TaskID,
+		link.LinkedTaskID,
+		link.LinkType,
+		link.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create task link: %w", err)
+	}
+
+	return nil
+}
+
+// GetByTaskID returns the links from and to the task, oldest first
+func (r *TaskLinkRepository) GetByTaskID(taskID string) ([]models.TaskLink, error) {
+	rows, err := r.db.Query(`
+		SELECT id, task_id, linked_task_id, link_type, created_at
+		FROM task_links
+		WHERE task_id = ? OR linked_task_id = ?
+		ORDER BY created_at`, taskID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.TaskLink
+	for rows.Next() {
+		var link models.TaskLink
+		if err := rows.Scan(&link.ID, &link.TaskID, &link.LinkedTaskID, &link.LinkType, &link.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task link row: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task link rows: %w", err)
+	}
+
+	return links, nil
+}
+
+func (r *TaskLinkRepository) Delete(linkID string) error {
+	result, err := r.db.Exec(`DELETE FROM task_links WHERE id = ?`, linkID)
+	if err != nil {
+		return fmt.Errorf("failed to delete task link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task link not found: %s", linkID)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type TaskListRepository struct {
+	db *DB
+}
+
+func NewTaskListRepository(db *DB) *TaskListRepository {
+	return &TaskListRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskListRepository) WithTx(tx *Tx) *TaskListRepository {
+	return &TaskListRepository{db: tx.db}
+}
+
+const taskListColumns = `id, name, description, owner_id, is_shared, color, icon, parent_id,
+		       position, created_at, updated_at, settings, archived_at,
+		       default_location_id, default_estimated_minutes`
+
+// Create stores a new task list
+func (r *TaskListRepository) Create(list models.TaskList) error {
+	if err := list.Validate(); err != nil {
+		return fmt.Errorf("task list validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_lists (
+			id, name, description, owner_id, is_shared, color, icon, parent_id,
+			position, created_at, updated_at, settings, archived_at,
+			default_location_id, default_estimated_minutes
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		list.ID,
+		list.Name,
+		list.Description,
+		list.OwnerID,
+		list.IsShared,
+		list.Color,
+		list.Icon,
+		list.ParentID,
+		list.Position,
+		list.CreatedAt,
+		list.UpdatedAt,
+		list.Settings,
+		list.ArchivedAt,
+		list.DefaultLocationID,
+		list.DefaultEstimatedMinutes,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create task list: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a task list by its ID
+func (r *TaskListRepository) GetByID(listID string) (*models.TaskList, error) {
+	row := r.db.QueryRow(`SELECT `+taskListColumns+` FROM task_lists WHERE id = ?`, listID)
+
+	list, err := scanTaskList(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("task list not found")
+		}
+		return nil, fmt.Errorf("failed to get task list by ID: %w", err)
+	}
+
+	return list, nil
+}
+
+// GetActive returns every list that has not been archived
+func (r *TaskListRepository) GetActive() ([]models.TaskList, error) {
+	rows, err := r.db.Query(`SELECT ` + taskListColumns + ` FROM task_lists WHERE archived_at IS NULL ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active task lists: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTaskLists(rows)
+}
+
+// GetArchived returns every archived list
+func (r *TaskListRepository) GetArchived() ([]models.TaskList, error) {
+	rows, err := r.db.Query(`SELECT ` + taskListColumns + ` FROM task_lists WHERE archived_at IS NOT NULL ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archived task lists: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTaskLists(rows)
+}
+
+func scanTaskLists(rows *sql.Rows) ([]models.TaskList, error) {
+	var lists []models.TaskList
+	for rows.Next() {
+		list, err := scanTaskList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task list row: %w", err)
+		}
+		lists = append(lists, *list)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task list rows: %w", err)
+	}
+
+	return lists, nil
+}
+
+// Update saves changes to an existing task list
+func (r *TaskListRepository) Update(list models.TaskList) error {
+	if err := list.Validate(); err != nil {
+		return fmt.Errorf("task list validation failed: %w", err)
+	}
+
+	query := `
+		UPDATE task_lists
+		SET name = ?, description = ?, is_shared = ?, color = ?, icon = ?, parent_id = ?,
+		    position = ?, updated_at = ?, settings = ?, archived_at = ?,
+		    default_location_id = ?, default_estimated_minutes = ?
+		WHERE id = ?`
+
+	result, err := r.db.Exec(query,
+		list.Name,
+		list.Description,
+		list.IsShared,
+		list.Color,
+		list.Icon,
+		list.ParentID,
+		list.Position,
+		list.UpdatedAt,
+		list.Settings,
+		list.ArchivedAt,
+		list.DefaultLocationID,
+		list.DefaultEstimatedMinutes,
+		list.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update task list: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task list not found")
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTaskList(row rowScanner) (*models.TaskList, error) {
+	var list models.TaskList
+	err := row.Scan(
+		&list.ID,
+		&list.Name,
+		&list.Description,
+		&list.OwnerID,
+		&list.IsShared,
+		&list.Color,
+		&list.Icon,
+		&list.ParentID,
+		&list.Position,
+		&list.CreatedAt,
+		&list.UpdatedAt,
+		scanMetadata(&list.Settings),
+		&list.ArchivedAt,
+		&list.DefaultLocationID,
+		&list.DefaultEstimatedMinutes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Settings = normalizeMetadata("task_lists", list.ID, list.Settings)
+	return &list, nil
+}
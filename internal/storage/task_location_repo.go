@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskLocationRepository links tasks to the locations where they can be done
+type TaskLocationRepository struct {
+	db *DB
+}
+
+func NewTaskLocationRepository(db *DB) *TaskLocationRepository {
+	return &TaskLocationRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskLocationRepository) WithTx(tx *Tx) *TaskLocationRepository {
+	return &TaskLocationRepository{db: tx.db}
+}
+
+// Create links a task to a location
+func (r *TaskLocationRepository) Create(taskLocation models.TaskLocation) error {
+	if taskLocation.Trigger == "" {
+		taskLocation.Trigger = models.LocationTriggerEnter
+	}
+
+	if err := taskLocation.Validate(); err != nil {
+		return fmt.Errorf("task location validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_locations (id, task_id, location_id, is_required, trigger_type, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.Exec(query,
+		taskLocation.ID,
+		taskLocation.TaskID,
+		taskLocation.LocationID,
+		taskLocation.IsRequired,
+		string(taskLocation.Trigger),
+		taskLocation.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create task location: %w", err)
+	}
+
+	return nil
+}
+
+// GetLocationsByTaskID returns the locations linked to a task
+func (r *TaskLocationRepository) GetLocationsByTaskID(taskID string) ([]models.Location, error) {
+	locations, err := r.linkedLocations(`tl.task_id = ?`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return locations[taskID], nil
+}
+
+// GetLocationsByTaskIDs returns the locations linked to each of the tasks,
+// keyed by task ID, in one query
+func (r *TaskLocationRepository) GetLocationsByTaskIDs(taskIDs []string) (map[string][]models.Location, error) {
+	if len(taskIDs) == 0 {
+		return map[string][]models.Location{}, nil
+	}
+	placeholders, args := inList(taskIDs)
+	return r.linkedLocations(`tl.task_id IN (`+placeholders+`)`, args...)
+}
+
+// linkedLocations returns the locations of the task links matching where,
+// keyed by task ID
+func (r *TaskLocationRepository) linkedLocations(where string, args ...interface{}) (map[string][]models.Location, error) {
+	query := `
+		SELECT tl.task_id, l.id, l.user_id, l.name, l.address, l.latitude, l.longitude,
+		       l.radius, l.category, l.place_id, l.open_hours, l.metadata, l.created_at, l.updated_at
+		FROM locations l
+		JOIN task_locations tl ON tl.location_id = l.id
+		WHERE ` + where + `
+		ORDER BY tl.created_at`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := make(map[string][]models.Location)
+	for rows.Next() {
+		var taskID string
+		var location models.Location
+		err := rows.Scan(
+			&taskID,
+			&location.ID,
+			&location.UserID,
+			&location.Name,
+			&location.Address,
+			&location.Latitude,
+			&location.Longitude,
+			&location.Radius,
+			&location.Category,
+			&location.PlaceID,
+			scanOpenHours(&location.OpenHours),
+			scanMetadata(&location.Metadata),
+			&location.CreatedAt,
+			&location.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan location row: %w", err)
+		}
+		location.Metadata = normalizeMetadata("locations", location.ID, location.Metadata)
+		locations[taskID] = append(locations[taskID], location)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating location rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// GetTaskLocationsByTaskID returns a task's location links with their triggers
+func (r *TaskLocationRepository) GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error) {
+	return r.queryTaskLocations(`WHERE task_id = ?`, taskID)
+}
+
+// GetTaskLocationsByTaskIDs returns the location links of each of the
+// tasks, keyed by task ID, in one query
+func (r *TaskLocationRepository) GetTaskLocationsByTaskIDs(taskIDs []string) (map[string][]models.TaskLocation, error) {
+	links := make(map[string][]models.TaskLocation)
+	if len(taskIDs) == 0 {
+		return links, nil
+	}
+
+	placeholders, args := inList(taskIDs)
+	taskLocations, err := r.queryTaskLocations(`WHERE task_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, taskLocation := range taskLocations {
+		links[taskLocation.TaskID] = append(links[taskLocation.TaskID], taskLocation)
+	}
+	return links, nil
+}
+
+// GetByLocationID returns the links from every task tied to a location
+func (r *TaskLocationRepository) GetByLocationID(locationID string) ([]models.TaskLocation, error) {
+	return r.queryTaskLocations(`WHERE location_id = ?`, locationID)
+}
+
+// Delete unlinks a task from a location
+func (r *TaskLocationRepository) Delete(taskID, locationID string) error {
+	query := `DELETE FROM task_locations WHERE task_id = ? AND location_id = ?`
+
+	if _, err := r.db.Exec(query, taskID, locationID); err != nil {
+		return fmt.Errorf("failed to delete task location: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TaskLocationRepository) queryTaskLocations(where string, args ...interface{}) ([]models.TaskLocation, error) {
+	query := `
+		SELECT id, task_id, location_id, is_required, trigger_type, created_at
+		FROM task_locations
+		` + where + `
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task locations: %w", err)
+	}
+	defer rows.Close()
+
+	var taskLocations []models.TaskLocation
+	for rows.Next() {
+		var taskLocation models.TaskLocation
+		var trigger string
+		err := rows.Scan(
+			&taskLocation.ID,
+			&taskLocation.TaskID,
+			&taskLocation.LocationID,
+			&taskLocation.IsRequired,
+			&trigger,
+			&taskLocation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task location row: %w", err)
+		}
+		taskLocation.Trigger = models.LocationTrigger(trigger)
+		taskLocations = append(taskLocations, taskLocation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task location rows: %w", err)
+	}
+
+	return taskLocations, nil
+}
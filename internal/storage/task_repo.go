@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -20,9 +21,15 @@ func NewTaskRepository(db *DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskRepository) WithTx(tx *Tx) *TaskRepository {
+	return &TaskRepository{db: tx.db}
+}
+
 // TaskSearchOptions defines options for searching tasks
 type TaskSearchOptions struct {
 	UserID           string              // Filter by user (creator or assignee)
+	IncludeShared    bool                // With UserID, also tasks in shared lists the user has joined
 	ListID           *string             // Filter by list
 	Status           *models.TaskStatus  // Filter by status
 	AssigneeID       *string             // Filter by assignee
@@ -32,14 +39,33 @@ type TaskSearchOptions struct {
 	CompletedAfter   *time.Time          // Filter by completion date
 	Priority         *int                // Filter by priority
 	ParentTaskID     *string             // Filter by parent task
+	Tags             []string            // Filter to tasks with all of these tags
+	MatchAnyTag      bool                // With Tags, tasks with any of them instead
 	HasDueDate       *bool               // Filter tasks with/without due dates
-	Query            string              // Full-text search query
+	IncludeDeleted   bool                // Include soft-deleted tasks
+	OnlyDeleted      bool                // Only soft-deleted tasks (the trash)
+	Query            string              // Full-text search query; words ending in * match as prefixes
 	Limit            int                 // Pagination limit
 	Offset           int                 // Pagination offset
-	OrderBy          string              // Order by field (created_at, updated_at, due_at, priority, title)
+	AfterCursor      string              // Continue after a previous page's NextCursor; needs created_at order and no Offset
+	OrderBy          string              // Order by field (created_at, updated_at, due_at, priority, title, position); unset ranks a Query's best matches first
 	OrderDirection   string              // Order direction (ASC, DESC)
 }
 
+// taskColumns are the columns Create and CreateBatch insert, in the order of
+// taskInsertArgs
+const taskColumns = `id, title, description, creator_id, assignee_id, list_id,
+			status, priority, estimated_minutes, effort_points, required_energy_level, due_at, completed_at,
+			created_at, updated_at, metadata, recurrence_rule, parent_task_id,
+			position, snoozed_until, recurring_snooze, deleted_at, version`
+
+// taskColumnCount is the number of taskColumns
+const taskColumnCount = 23
+
+// maxInsertParams is SQLite's default limit on the parameters of one
+// statement; PostgreSQL allows more
+const maxInsertParams = 32766
+
 // Create creates a new task in the database
 func (r *TaskRepository) Create(task *models.Task) error {
 	if task.ID == "" {
@@ -51,14 +77,78 @@ func (r *TaskRepository) Create(task *models.Task) error {
 		return fmt.Errorf("task validation failed: %w", err)
 	}
 
-	query := `
-		INSERT INTO tasks (
-			id, title, description, creator_id, assignee_id, list_id,
-			status, priority, estimated_minutes, due_at, completed_at,
-			created_at, updated_at, metadata, recurrence_rule, parent_task_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	tags, err := models.NormalizeTags(task.Tags)
+	if err != nil {
+		return fmt.Errorf("task validation failed: %w", err)
+	}
+
+	query := `INSERT INTO tasks (` + taskColumns + `) VALUES ` + taskPlaceholders(1)
+
+	err = r.db.WithTx(func(tx *DB) error {
+		if _, err := tx.Exec(query, taskInsertArgs(task)...); err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+		return insertTaskTags(tx, task.ID, tags)
+	})
+	if err != nil {
+		return err
+	}
+
+	task.Tags = tags
+	return nil
+}
+
+// CreateBatch creates tasks with multi-row INSERTs in one transaction. Every
+// task is validated first; if any is invalid or an insert fails, none are
+// created.
+func (r *TaskRepository) CreateBatch(tasks []*models.Task) error {
+	for i, task := range tasks {
+		if task.ID == "" {
+			return fmt.Errorf("task %d: task ID cannot be empty", i+1)
+		}
+		if err := task.Validate(); err != nil {
+			return fmt.Errorf("task %d: task validation failed: %w", i+1, err)
+		}
+		tags, err := models.NormalizeTags(task.Tags)
+		if err != nil {
+			return fmt.Errorf("task %d: task validation failed: %w", i+1, err)
+		}
+		task.Tags = tags
+	}
+
+	rowsPerInsert := maxInsertParams / taskColumnCount
+	return r.db.WithTx(func(tx *DB) error {
+		for start := 0; start < len(tasks); start += rowsPerInsert {
+			batch := tasks[start:min(start+rowsPerInsert, len(tasks))]
 
-	_, err := r.db.Exec(query,
+			args := make([]interface{}, 0, len(batch)*taskColumnCount)
+			for _, task := range batch {
+				args = append(args, taskInsertArgs(task)...)
+			}
+
+			query := `INSERT INTO tasks (` + taskColumns + `) VALUES ` + taskPlaceholders(len(batch))
+			if _, err := tx.Exec(query, args...); err != nil {
+				return fmt.Errorf("failed to create tasks: %w", err)
+			}
+		}
+		for _, task := range tasks {
+			if err := insertTaskTags(tx, task.ID, task.Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// taskPlaceholders returns the VALUES rows for inserting n tasks
+func taskPlaceholders(n int) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", taskColumnCount), ", ") + ")"
+	return strings.TrimSuffix(strings.Repeat(row+", ", n), ", ")
+}
+
+// taskInsertArgs returns the task's values for taskColumns
+func taskInsertArgs(task *models.Task) []interface{} {
+	return []interface{}{
 		task.ID,
 		task.Title,
 		task.Description,
@@ -68,6 +158,8 @@ func (r *TaskRepository) Create(task *models.Task) error {
 		string(task.Status),
 		task.Priority,
 		task.EstimatedMinutes,
+		task.EffortPoints,
+		task.RequiredEnergyLevel,
 		task.DueAt,
 		task.CompletedAt,
 		task.CreatedAt,
@@ -75,27 +167,41 @@ func (r *TaskRepository) Create(task *models.Task) error {
 		task.Metadata,
 		task.RecurrenceRule,
 		task.ParentTaskID,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to create task: %w", err)
+		task.Position,
+		task.SnoozedUntil,
+		task.RecurringSnooze,
+		task.DeletedAt,
+		task.Version,
 	}
-
-	return nil
 }
 
-// GetByID retrieves a task by its ID
+// GetByID retrieves a task by its ID. Soft-deleted tasks are not found.
 func (r *TaskRepository) GetByID(id string) (*models.Task, error) {
+	return r.getByID(id, false)
+}
+
+// GetDeletedByID retrieves a soft-deleted task by its ID
+func (r *TaskRepository) GetDeletedByID(id string) (*models.Task, error) {
+	return r.getByID(id, true)
+}
+
+func (r *TaskRepository) getByID(id string, deleted bool) (*models.Task, error) {
 	if id == "" {
 		return nil, fmt.Errorf("task ID cannot be empty")
 	}
 
+	condition := "deleted_at IS NULL"
+	if deleted {
+		condition = "deleted_at IS NOT NULL"
+	}
+
 	query := `
 		SELECT id, title, description, creator_id, assignee_id, list_id,
-		       status, priority, estimated_minutes, due_at, completed_at,
-		       created_at, updated_at, metadata, recurrence_rule, parent_task_id
+		       status, priority, estimated_minutes, effort_points, required_energy_level, due_at, completed_at,
+		       created_at, updated_at, metadata, recurrence_rule, parent_task_id,
+		       position, snoozed_until, recurring_snooze, deleted_at, version
 		FROM tasks 
-		WHERE id = ?`
+		WHERE id = ? AND ` + condition
 
 	task := &models.Task{}
 	var statusStr string
@@ -110,13 +216,20 @@ func (r *TaskRepository) GetByID(id string) (*models.Task, error) {
 		&statusStr,
 		&task.Priority,
 		&task.EstimatedMinutes,
+		&task.EffortPoints,
+		&task.RequiredEnergyLevel,
 		&task.DueAt,
 		&task.CompletedAt,
 		&task.CreatedAt,
 		&task.UpdatedAt,
-		&task.Metadata,
+		scanMetadata(&task.Metadata),
 		&task.RecurrenceRule,
 		&task.ParentTaskID,
+		&task.Position,
+		&task.SnoozedUntil,
+		&task.RecurringSnooze,
+		&task.DeletedAt,
+		&task.Version,
 	)
 
 	if err != nil {
@@ -127,10 +240,16 @@ func (r *TaskRepository) GetByID(id string) (*models.Task, error) {
 	}
 
 	task.Status = models.TaskStatus(statusStr)
+	task.Metadata = normalizeMetadata("tasks", task.ID, task.Metadata)
+	if task.Tags, err = r.GetTags(task.ID); err != nil {
+		return nil, err
+	}
 	return task, nil
 }
 
-// Update updates an existing task
+// Update saves the task if its Version is still the stored one, and bumps
+// the version. A task changed since it was read is not saved and the error
+// wraps models.ErrTaskVersionConflict.
 func (r *TaskRepository) Update(task *models.Task) error {
 	if task.ID == "" {
 		return fmt.Errorf("task ID cannot be empty")
@@ -147,10 +266,11 @@ func (r *TaskRepository) Update(task *models.Task) error {
 	query := `
 		UPDATE tasks 
 		SET title = ?, description = ?, assignee_id = ?, list_id = ?,
-		    status = ?, priority = ?, estimated_minutes = ?, due_at = ?, 
+		    status = ?, priority = ?, estimated_minutes = ?, effort_points = ?, required_energy_level = ?, due_at = ?, 
 		    completed_at = ?, updated_at = ?, metadata = ?, recurrence_rule = ?,
-		    parent_task_id = ?
-		WHERE id = ?`
+		    parent_task_id = ?, position = ?, snoozed_until = ?, recurring_snooze = ?,
+		    version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL`
 
 	result, err := r.db.Exec(query,
 		task.Title,
@@ -160,13 +280,19 @@ func (r *TaskRepository) Update(task *models.Task) error {
 		string(task.Status),
 		task.Priority,
 		task.EstimatedMinutes,
+		task.EffortPoints,
+		task.RequiredEnergyLevel,
 		task.DueAt,
 		task.CompletedAt,
 		task.UpdatedAt,
 		task.Metadata,
 		task.RecurrenceRule,
 		task.ParentTaskID,
+		task.Position,
+		task.SnoozedUntil,
+		task.RecurringSnooze,
 		task.ID,
+		task.Version,
 	)
 
 	if err != nil {
@@ -179,22 +305,35 @@ func (r *TaskRepository) Update(task *models.Task) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+		var version int
+		err := r.db.QueryRow(`SELECT version FROM tasks WHERE id = ? AND deleted_at IS NULL`, task.ID).Scan(&version)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("task not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check task version: %w", err)
+		}
+		return fmt.Errorf("task %s is at version %d, not %d: %w", task.ID, version, task.Version, models.ErrTaskVersionConflict)
 	}
 
+	task.Version++
 	return nil
 }
 
-// Delete deletes a task from the database
+// Delete soft-deletes a task by setting its deleted_at. The row keeps its
+// dependencies, locations and assignments so Restore can bring the task back.
 func (r *TaskRepository) Delete(taskID string) error {
 	if taskID == "" {
 		return fmt.Errorf("task ID cannot be empty")
 	}
 
-	// Check if task has dependencies (other tasks depend on this one)
+	// Check if task has dependencies (other tasks depend on this one),
+	// ignoring dependents that are deleted themselves
 	var dependentCount int
 	err := r.db.QueryRow(`
-		SELECT COUNT(*) FROM task_dependencies WHERE depends_on_task_id = ?
+		SELECT COUNT(*) FROM task_dependencies d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE d.depends_on_task_id = ? AND t.deleted_at IS NULL
 	`, taskID).Scan(&dependentCount)
 	
 	if err != nil {
@@ -205,35 +344,40 @@ func (r *TaskRepository) Delete(taskID string) error {
 		return fmt.Errorf("cannot delete task: %d tasks depend on this task", dependentCount)
 	}
 
-	// Use transaction to delete task and its relationships
-	tx, err := r.db.BeginTx()
+	now := time.Now()
+	result, err := r.db.Exec(`
+		UPDATE tasks SET deleted_at = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, now, now, taskID)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Delete task dependencies
-	_, err = tx.Exec(`DELETE FROM task_dependencies WHERE task_id = ?`, taskID)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to delete task dependencies: %w", err)
+		return fmt.Errorf("failed to get affected rows: %w", err)
 	}
 
-	// Delete task locations
-	_, err = tx.Exec(`DELETE FROM task_locations WHERE task_id = ?`, taskID)
-	if err != nil {
-		return fmt.Errorf("failed to delete task locations: %w", err)
+	if rowsAffected == 0 {
+		return fmt.Errorf("task not found")
 	}
 
-	// Delete task assignments
-	_, err = tx.Exec(`DELETE FROM task_assignments WHERE task_id = ?`, taskID)
-	if err != nil {
-		return fmt.Errorf("failed to delete task assignments: %w", err)
+	return nil
+}
+
+// Restore brings back a soft-deleted task with the relationships it had when
+// it was deleted
+func (r *TaskRepository) Restore(id string) error {
+	if id == "" {
+		return fmt.Errorf("task ID cannot be empty")
 	}
 
-	// Delete the task itself
-	result, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, taskID)
+	result, err := r.db.Exec(`
+		UPDATE tasks SET deleted_at = NULL, updated_at = ?
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`, time.Now(), id)
 	if err != nil {
-		return fmt.Errorf("failed to delete task: %w", err)
+		return fmt.Errorf("failed to restore task: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -242,41 +386,182 @@ func (r *TaskRepository) Delete(taskID string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+		return fmt.Errorf("deleted task not found")
+	}
+
+	return nil
+}
+
+// Purge permanently removes a soft-deleted task and its relationships. It
+// fails while other tasks depend on the task, deleted or not, unless force
+// is set, which removes those dependencies as well.
+func (r *TaskRepository) Purge(taskID string, force bool) error {
+	if taskID == "" {
+		return fmt.Errorf("task ID cannot be empty")
+	}
+
+	return r.db.WithTransaction(context.Background(), func(tx *Tx) error {
+		var dependentCount int
+		err := tx.db.QueryRow(`
+			SELECT COUNT(*) FROM task_dependencies WHERE depends_on_task_id = ?
+		`, taskID).Scan(&dependentCount)
+		if err != nil {
+			return fmt.Errorf("failed to check task dependencies: %w", err)
+		}
+
+		if dependentCount > 0 {
+			if !force {
+				return fmt.Errorf("cannot purge task: %d tasks depend on this task", dependentCount)
+			}
+			if _, err := tx.db.Exec(`DELETE FROM task_dependencies WHERE depends_on_task_id = ?`, taskID); err != nil {
+				return fmt.Errorf("failed to delete dependent task links: %w", err)
+			}
+		}
+
+		if _, err := tx.db.Exec(`DELETE FROM task_dependencies WHERE task_id = ?`, taskID); err != nil {
+			return fmt.Errorf("failed to delete task dependencies: %w", err)
+		}
+
+		if _, err := tx.db.Exec(`DELETE FROM task_locations WHERE task_id = ?`, taskID); err != nil {
+			return fmt.Errorf("failed to delete task locations: %w", err)
+		}
+
+		if _, err := tx.db.Exec(`DELETE FROM task_assignments WHERE task_id = ?`, taskID); err != nil {
+			return fmt.Errorf("failed to delete task assignments: %w", err)
+		}
+
+		if _, err := tx.db.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+			return fmt.Errorf("failed to delete task tags: %w", err)
+		}
+
+		result, err := tx.db.Exec(`DELETE FROM tasks WHERE id = ? AND deleted_at IS NOT NULL`, taskID)
+		if err != nil {
+			return fmt.Errorf("failed to purge task: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("deleted task not found")
+		}
+
+		return nil
+	})
+}
+
+// PurgeDeletedBefore permanently removes tasks deleted before the cutoff
+// and returns how many it removed. Tasks that others still depend on are
+// kept; once their dependents are purged, a later call removes them.
+func (r *TaskRepository) PurgeDeletedBefore(before time.Time) (int, error) {
+	rows, err := r.db.Query(`
+		SELECT t.id FROM tasks t
+		WHERE t.deleted_at IS NOT NULL AND t.deleted_at < ?
+		AND NOT EXISTS (
+			SELECT 1 FROM task_dependencies d WHERE d.depends_on_task_id = t.id
+		)`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired deleted tasks: %w", err)
+	}
+
+	var taskIDs []string
+	for rows.Next() {
+		var taskID string
+		if err := rows.Scan(&taskID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan task ID: %w", err)
+		}
+		taskIDs = append(taskIDs, taskID)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating deleted tasks: %w", err)
+	}
+
+	purged := 0
+	for _, taskID := range taskIDs {
+		if err := r.Purge(taskID, false); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
 
-	return tx.Commit()
+// TaskSearchPage is one page of search results
+type TaskSearchPage struct {
+	Tasks []*models.Task
+	// NextCursor is passed as AfterCursor, with the same options, to fetch
+	// the following page. It is empty on the last page and when the tasks
+	// are not ordered by created_at.
+	NextCursor string
 }
 
-// Search searches tasks with various filters and full-text search
+// Search searches tasks with various filters and full-text search. A Query
+// falls back to matching words with LIKE when SQLite was built without
+// FTS5, losing the ranking.
 func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, error) {
-	var conditions []string
-	var args []interface{}
+	page, err := r.SearchPage(options)
+	if err != nil {
+		return nil, err
+	}
+	return page.Tasks, nil
+}
+
+// SearchPage is Search returning a cursor to the next page when Limit is
+// set. Unlike an offset, the cursor holds the last task's (created_at, id),
+// so tasks added between fetches do not shift the pages.
+func (r *TaskRepository) SearchPage(options TaskSearchOptions) (*TaskSearchPage, error) {
+	limit := options.Limit
+	if limit > 0 {
+		// One more than asked for shows whether another page follows
+		options.Limit = limit + 1
+	}
+
+	tasks, err := r.search(options, true)
+	if err != nil && options.Query != "" && isTextSearchUnavailable(err) {
+		tasks, err = r.search(options, false)
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	page := &TaskSearchPage{Tasks: tasks}
+	if limit > 0 && len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		if _, ok := cursorDirection(options); ok {
+			page.NextCursor = encodeTaskCursor(tasks[limit-1])
+		}
+	}
+	return page, nil
+}
+
+func (r *TaskRepository) search(options TaskSearchOptions, fullText bool) ([]*models.Task, error) {
 	// Build base query
 	baseQuery := `
 		SELECT t.id, t.title, t.description, t.creator_id, t.assignee_id, t.list_id,
-		       t.status, t.priority, t.estimated_minutes, t.due_at, t.completed_at,
-		       t.created_at, t.updated_at, t.metadata, t.recurrence_rule, t.parent_task_id
+		       t.status, t.priority, t.estimated_minutes, t.effort_points, t.required_energy_level, t.due_at, t.completed_at,
+		       t.created_at, t.updated_at, t.metadata, t.recurrence_rule, t.parent_task_id,
+		       t.position, t.snoozed_until, t.recurring_snooze, t.deleted_at, t.version
 	`
 
-	var fromClause string
-	if options.Query != "" {
-		// Use full-text search
-		fromClause = `
-			FROM tasks t
-			JOIN tasks_fts fts ON t.rowid = fts.rowid
-		`
-		conditions = append(conditions, "tasks_fts MATCH ?")
-		args = append(args, options.Query)
-	} else {
-		fromClause = "FROM tasks t"
+	fromClause, conditions, args := r.textSearch(options.Query, fullText)
+
+	// Leave out soft-deleted tasks unless asked for
+	if options.OnlyDeleted {
+		conditions = append(conditions, "t.deleted_at IS NOT NULL")
+	} else if !options.IncludeDeleted {
+		conditions = append(conditions, "t.deleted_at IS NULL")
 	}
 
 	// Add user filter (tasks where user is creator or assignee)
 	if options.UserID != "" {
-		conditions = append(conditions, "(t.creator_id = ? OR t.assignee_id = ?)")
-		args = append(args, options.UserID, options.UserID)
+		condition, userArgs := userTasksCondition(options)
+		conditions = append(conditions, condition)
+		args = append(args, userArgs...)
 	}
 
 	// Add specific creator filter
@@ -315,6 +600,16 @@ func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, erro
 		args = append(args, *options.ParentTaskID)
 	}
 
+	// Add tag filter
+	if len(options.Tags) > 0 {
+		condition, tagArgs, err := tagsCondition(options.Tags, options.MatchAnyTag)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, tagArgs...)
+	}
+
 	// Add due date filters
 	if options.DueBefore != nil {
 		conditions = append(conditions, "t.due_at < ?")
@@ -340,6 +635,16 @@ func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, erro
 		}
 	}
 
+	// Continue after the cursor's task in created_at order
+	if options.AfterCursor != "" {
+		condition, cursorArgs, err := cursorCondition(options)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
 	// Build WHERE clause
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -347,7 +652,14 @@ func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, erro
 	}
 
 	// Build ORDER BY clause
-	orderClause := "ORDER BY t.created_at DESC" // Default ordering
+	orderClause := "ORDER BY t.created_at DESC, t.id DESC" // Default ordering
+	if options.Query != "" && options.OrderBy == "" && fullText {
+		rank, usesQuery := r.db.Dialect().textRank("tasks", "t", "title", "description")
+		orderClause = "ORDER BY " + rank + ", t.created_at DESC, t.id DESC"
+		if usesQuery {
+			args = append(args, r.db.Dialect().textQuery(options.Query))
+		}
+	}
 	if options.OrderBy != "" {
 		direction := "DESC"
 		if options.OrderDirection == "ASC" {
@@ -358,9 +670,11 @@ func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, erro
 		validOrderFields := map[string]bool{
 			"created_at": true, "updated_at": true, "due_at": true,
 			"priority": true, "title": true, "status": true,
+			"position": true, "deleted_at": true,
 		}
 		if validOrderFields[options.OrderBy] {
-			orderClause = fmt.Sprintf("ORDER BY t.%s %s", options.OrderBy, direction)
+			// Ties are broken by id so pages are stable
+			orderClause = fmt.Sprintf("ORDER BY t.%s %s, t.id %s", options.OrderBy, direction, direction)
 		}
 	}
 
@@ -397,19 +711,27 @@ func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, erro
 			&statusStr,
 			&task.Priority,
 			&task.EstimatedMinutes,
+			&task.EffortPoints,
+			&task.RequiredEnergyLevel,
 			&task.DueAt,
 			&task.CompletedAt,
 			&task.CreatedAt,
 			&task.UpdatedAt,
-			&task.Metadata,
+			scanMetadata(&task.Metadata),
 			&task.RecurrenceRule,
 			&task.ParentTaskID,
+			&task.Position,
+			&task.SnoozedUntil,
+			&task.RecurringSnooze,
+			&task.DeletedAt,
+			&task.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task row: %w", err)
 		}
 
 		task.Status = models.TaskStatus(statusStr)
+		task.Metadata = normalizeMetadata("tasks", task.ID, task.Metadata)
 		tasks = append(tasks, task)
 	}
 
@@ -417,6 +739,10 @@ func (r *TaskRepository) Search(options TaskSearchOptions) ([]*models.Task, erro
 		return nil, fmt.Errorf("error iterating task rows: %w", err)
 	}
 
+	if err := r.loadTags(tasks); err != nil {
+		return nil, err
+	}
+
 	return tasks, nil
 }
 
@@ -430,16 +756,50 @@ func (r *TaskRepository) GetByUser(userID string, limit, offset int) ([]*models.
 	return r.Search(options)
 }
 
-// GetByList returns all tasks in a specific list
+// GetByUserID returns every task the user created or is assigned, by value
+// as the hereandnow services take them. The admin reassignment reads the
+// tasks to move through it.
+func (r *TaskRepository) GetByUserID(userID string) ([]models.Task, error) {
+	tasks, err := r.GetByUser(userID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return taskValues(tasks), nil
+}
+
+// GetDeleted returns the user's soft-deleted tasks, most recently deleted
+// first
+func (r *TaskRepository) GetDeleted(userID string) ([]*models.Task, error) {
+	options := TaskSearchOptions{
+		UserID:      userID,
+		OnlyDeleted: true,
+		OrderBy:     "deleted_at",
+	}
+	return r.Search(options)
+}
+
+// GetByList returns all tasks in a specific list in manual order
 func (r *TaskRepository) GetByList(listID string, limit, offset int) ([]*models.Task, error) {
 	options := TaskSearchOptions{
-		ListID: &listID,
-		Limit:  limit,
-		Offset: offset,
+		ListID:         &listID,
+		Limit:          limit,
+		Offset:         offset,
+		OrderBy:        "position",
+		OrderDirection: "ASC",
 	}
 	return r.Search(options)
 }
 
+// GetByListID returns every task in the list in manual order, by value as
+// the hereandnow services take them
+func (r *TaskRepository) GetByListID(listID string) ([]models.Task, error) {
+	tasks, err := r.GetByList(listID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return taskValues(tasks), nil
+}
+
 // GetPendingTasks returns all pending tasks for a user
 func (r *TaskRepository) GetPendingTasks(userID string, limit, offset int) ([]*models.Task, error) {
 	status := models.TaskStatusPending
@@ -478,38 +838,70 @@ func (r *TaskRepository) GetSubtasks(parentTaskID string) ([]*models.Task, error
 	return r.Search(options)
 }
 
-// FullTextSearch performs a full-text search on task titles and descriptions
+// FullTextSearch performs a full-text search on the titles and descriptions
+// of the tasks a user can see: their own and those in shared lists they have
+// joined. The best matches come first.
 func (r *TaskRepository) FullTextSearch(userID, query string, limit, offset int) ([]*models.Task, error) {
 	options := TaskSearchOptions{
-		UserID: userID,
-		Query:  query,
-		Limit:  limit,
-		Offset: offset,
+		UserID:        userID,
+		IncludeShared: true,
+		Query:         query,
+		Limit:         limit,
+		Offset:        offset,
 	}
 	return r.Search(options)
 }
 
+// textSearch returns the FROM clause, conditions and arguments matching a
+// search query, using the full-text index when fullText is set
+func (r *TaskRepository) textSearch(query string, fullText bool) (string, []string, []interface{}) {
+	switch {
+	case query == "":
+		return "FROM tasks t", nil, nil
+	case !fullText:
+		condition, args := likeSearch(query, "t", "title", "description")
+		return "FROM tasks t", []string{condition}, args
+	}
+
+	join, condition := r.db.Dialect().textSearch("tasks", "t", "title", "description")
+	return "FROM tasks t " + join, []string{condition}, []interface{}{r.db.Dialect().textQuery(query)}
+}
+
+// userTasksCondition matches the tasks options.UserID created or is
+// assigned, and with IncludeShared those in lists they have accepted an
+// invitation to
+func userTasksCondition(options TaskSearchOptions) (string, []interface{}) {
+	if !options.IncludeShared {
+		return "(t.creator_id = ? OR t.assignee_id = ?)", []interface{}{options.UserID, options.UserID}
+	}
+	return `(t.creator_id = ? OR t.assignee_id = ? OR t.list_id IN (
+			SELECT list_id FROM list_members WHERE user_id = ? AND accepted_at IS NOT NULL))`,
+		[]interface{}{options.UserID, options.UserID, options.UserID}
+}
+
 // Count returns the total number of tasks matching the search options
 func (r *TaskRepository) Count(options TaskSearchOptions) (int, error) {
-	var conditions []string
-	var args []interface{}
+	count, err := r.count(options, true)
+	if err != nil && options.Query != "" && isTextSearchUnavailable(err) {
+		return r.count(options, false)
+	}
+	return count, err
+}
 
+func (r *TaskRepository) count(options TaskSearchOptions, fullText bool) (int, error) {
 	// Build query conditions (similar to Search method)
-	var fromClause string
-	if options.Query != "" {
-		fromClause = `
-			FROM tasks t
-			JOIN tasks_fts fts ON t.rowid = fts.rowid
-		`
-		conditions = append(conditions, "tasks_fts MATCH ?")
-		args = append(args, options.Query)
-	} else {
-		fromClause = "FROM tasks t"
+	fromClause, conditions, args := r.textSearch(options.Query, fullText)
+
+	if options.OnlyDeleted {
+		conditions = append(conditions, "t.deleted_at IS NOT NULL")
+	} else if !options.IncludeDeleted {
+		conditions = append(conditions, "t.deleted_at IS NULL")
 	}
 
 	if options.UserID != "" {
-		conditions = append(conditions, "(t.creator_id = ? OR t.assignee_id = ?)")
-		args = append(args, options.UserID, options.UserID)
+		condition, userArgs := userTasksCondition(options)
+		conditions = append(conditions, condition)
+		args = append(args, userArgs...)
 	}
 
 	if options.CreatorID != nil {
@@ -532,6 +924,15 @@ func (r *TaskRepository) Count(options TaskSearchOptions) (int, error) {
 		args = append(args, string(*options.Status))
 	}
 
+	if len(options.Tags) > 0 {
+		condition, tagArgs, err := tagsCondition(options.Tags, options.MatchAnyTag)
+		if err != nil {
+			return 0, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, tagArgs...)
+	}
+
 	// Build WHERE clause
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -563,8 +964,8 @@ func (r *TaskRepository) UpdateStatus(taskID string, status models.TaskStatus) e
 
 	query := `
 		UPDATE tasks 
-		SET status = ?, completed_at = ?, updated_at = ?
-		WHERE id = ?`
+		SET status = ?, completed_at = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND deleted_at IS NULL`
 
 	result, err := r.db.Exec(query, string(status), completedAt, time.Now(), taskID)
 	if err != nil {
@@ -583,6 +984,30 @@ func (r *TaskRepository) UpdateStatus(taskID string, status models.TaskStatus) e
 	return nil
 }
 
+// UpdatePosition updates a task's manual position within its list
+func (r *TaskRepository) UpdatePosition(taskID string, position float64) error {
+	if taskID == "" {
+		return fmt.Errorf("task ID cannot be empty")
+	}
+
+	query := `UPDATE tasks SET position = ?, updated_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL`
+	result, err := r.db.Exec(query, position, time.Now(), taskID)
+	if err != nil {
+		return fmt.Errorf("failed to update task position: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
 // UpdateMetadata updates a task's metadata
 func (r *TaskRepository) UpdateMetadata(taskID string, metadata map[string]interface{}) error {
 	if taskID == "" {
@@ -594,7 +1019,7 @@ func (r *TaskRepository) UpdateMetadata(taskID string, metadata map[string]inter
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	query := `UPDATE tasks SET metadata = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE tasks SET metadata = ?, updated_at = ?, version = version + 1 WHERE id = ?`
 	_, err = r.db.Exec(query, metadataJSON, time.Now(), taskID)
 	if err != nil {
 		return fmt.Errorf("failed to update metadata: %w", err)
@@ -610,7 +1035,7 @@ func (r *TaskRepository) Exists(taskID string) (bool, error) {
 	}
 
 	var count int
-	query := `SELECT COUNT(*) FROM tasks WHERE id = ?`
+	query := `SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL`
 	
 	err := r.db.QueryRow(query, taskID).Scan(&count)
 	if err != nil {
@@ -618,4 +1043,13 @@ func (r *TaskRepository) Exists(taskID string) (bool, error) {
 	}
 
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// taskValues copies tasks out of their pointers
+func taskValues(tasks []*models.Task) []models.Task {
+	values := make([]models.Task, len(tasks))
+	for i, task := range tasks {
+		values[i] = *task
+	}
+	return values
+}
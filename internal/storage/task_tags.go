@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// AddTag tags the task. The tag is normalized with models.NormalizeTag, and
+// adding a tag the task already has does nothing.
+func (r *TaskRepository) AddTag(taskID, tag string) error {
+	tag, err := models.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return insertTaskTags(r.db, taskID, []string{tag})
+}
+
+// RemoveTag removes a tag from the task
+func (r *TaskRepository) RemoveTag(taskID, tag string) error {
+	tag, err := models.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`DELETE FROM task_tags WHERE task_id = ? AND tag = ?`, taskID, tag)
+	if err != nil {
+		return fmt.Errorf("failed to remove tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("task is not tagged %q", tag)
+	}
+
+	return nil
+}
+
+// GetTags returns the task's tags in alphabetical order
+func (r *TaskRepository) GetTags(taskID string) ([]string, error) {
+	tags, err := r.tagsByTaskIDs([]string{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return tags[taskID], nil
+}
+
+// loadTags sets the Tags of each task
+func (r *TaskRepository) loadTags(tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	tags, err := r.tagsByTaskIDs(ids)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		task.Tags = tags[task.ID]
+	}
+	return nil
+}
+
+func (r *TaskRepository) tagsByTaskIDs(taskIDs []string) (map[string][]string, error) {
+	placeholders, args := inList(taskIDs)
+	rows, err := r.db.Query(`
+		SELECT task_id, tag FROM task_tags
+		WHERE task_id IN (`+placeholders+`)
+		ORDER BY task_id, tag`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var taskID, tag string
+		if err := rows.Scan(&taskID, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan task tag row: %w", err)
+		}
+		tags[taskID] = append(tags[taskID], tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task tag rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// insertTaskTags tags the task with already normalized tags
+func insertTaskTags(db *DB, taskID string, tags []string) error {
+	for _, tag := range tags {
+		_, err := db.Exec(`
+			INSERT INTO task_tags (task_id, tag) VALUES (?, ?)
+			ON CONFLICT (task_id, tag) DO NOTHING`, taskID, tag)
+		if err != nil {
+			return fmt.Errorf("failed to add tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// tagsCondition matches tasks that have every one of the tags, or with
+// matchAny at least one of them
+func tagsCondition(tags []string, matchAny bool) (string, []interface{}, error) {
+	tags, err := models.NormalizeTags(tags)
+	if err != nil {
+		return "", nil, err
+	}
+
+	placeholders, args := inList(tags)
+	if matchAny {
+		return "t.id IN (SELECT task_id FROM task_tags WHERE tag IN (" + placeholders + "))", args, nil
+	}
+	args = append(args, len(tags))
+	return "t.id IN (SELECT task_id FROM task_tags WHERE tag IN (" + placeholders + ") GROUP BY task_id HAVING COUNT(*) = ?)", args, nil
+}
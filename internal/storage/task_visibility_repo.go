@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+type TaskVisibilityRepository struct {
+	db *DB
+}
+
+func NewTaskVisibilityRepository(db *DB) *TaskVisibilityRepository {
+	return &TaskVisibilityRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs inside tx
+func (r *TaskVisibilityRepository) WithTx(tx *Tx) *TaskVisibilityRepository {
+	return &TaskVisibilityRepository{db: tx.db}
+}
+
+// GetByUserID returns the last visibility the user saw for each task
+func (r *TaskVisibilityRepository) GetByUserID(userID string) ([]models.TaskVisibility, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID cannot be empty")
+	}
+
+	query := `
+		SELECT user_id, task_id, visible, changed_at, notified_at
+		FROM task_visibility
+		WHERE user_id = ?`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task visibility: %w", err)
+	}
+	defer rows.Close()
+
+	var visibilities []models.TaskVisibility
+	for rows.Next() {
+		var visibility models.TaskVisibility
+		err := rows.Scan(
+			&visibility.UserID,
+			&visibility.TaskID,
+			&visibility.Visible,
+			&visibility.ChangedAt,
+			&visibility.NotifiedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task visibility row: %w", err)
+		}
+		visibilities = append(visibilities, visibility)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating task visibility rows: %w", err)
+	}
+
+	return visibilities, nil
+}
+
+// Save inserts or replaces the user's visibility record for a task
+func (r *TaskVisibilityRepository) Save(visibility models.TaskVisibility) error {
+	query := `
+		INSERT INTO task_visibility (user_id, task_id, visible, changed_at, notified_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, task_id) DO UPDATE SET
+			visible = excluded.visible,
+			changed_at = excluded.changed_at,
+			notified_at = excluded.notified_at`
+
+	_, err := r.db.Exec(query,
+		visibility.UserID,
+		visibility.TaskID,
+		visibility.Visible,
+		visibility.ChangedAt,
+		visibility.NotifiedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save task visibility: %w", err)
+	}
+
+	return nil
+}
@@ -19,6 +19,11 @@ func NewUserRepository(db *DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithTx returns a copy of the repository that runs inside tx
+func (r *UserRepository) WithTx(tx *Tx) *UserRepository {
+	return &UserRepository{db: tx.db}
+}
+
 // Create creates a new user in the database
 func (r *UserRepository) Create(user *models.User) error {
 	if user.ID == "" {
@@ -33,8 +38,8 @@ func (r *UserRepository) Create(user *models.User) error {
 	query := `
 		INSERT INTO users (
 			id, username, email, password_hash, display_name, 
-			timezone, created_at, updated_at, last_seen_at, settings
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			timezone, created_at, updated_at, last_seen_at, settings, is_admin, deactivated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := r.db.Exec(query,
 		user.ID,
@@ -47,6 +52,8 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.UpdatedAt,
 		user.LastSeenAt,
 		user.Settings,
+		user.IsAdmin,
+		user.DeactivatedAt,
 	)
 
 	if err != nil {
@@ -64,7 +71,7 @@ func (r *UserRepository) GetByID(id string) (*models.User, error) {
 
 	query := `
 		SELECT id, username, email, password_hash, display_name, 
-		       timezone, created_at, updated_at, last_seen_at, settings
+		       timezone, created_at, updated_at, last_seen_at, settings, is_admin, deactivated_at
 		FROM users 
 		WHERE id = ?`
 
@@ -79,7 +86,9 @@ func (r *UserRepository) GetByID(id string) (*models.User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastSeenAt,
-		&user.Settings,
+		scanMetadata(&user.Settings),
+		&user.IsAdmin,
+		&user.DeactivatedAt,
 	)
 
 	if err != nil {
@@ -89,6 +98,7 @@ func (r *UserRepository) GetByID(id string) (*models.User, error) {
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 
+	user.Settings = normalizeMetadata("users", user.ID, user.Settings)
 	return user, nil
 }
 
@@ -100,7 +110,7 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 
 	query := `
 		SELECT id, username, email, password_hash, display_name, 
-		       timezone, created_at, updated_at, last_seen_at, settings
+		       timezone, created_at, updated_at, last_seen_at, settings, is_admin, deactivated_at
 		FROM users 
 		WHERE username = ?`
 
@@ -115,7 +125,9 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastSeenAt,
-		&user.Settings,
+		scanMetadata(&user.Settings),
+		&user.IsAdmin,
+		&user.DeactivatedAt,
 	)
 
 	if err != nil {
@@ -125,6 +137,7 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
 
+	user.Settings = normalizeMetadata("users", user.ID, user.Settings)
 	return user, nil
 }
 
@@ -136,7 +149,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 
 	query := `
 		SELECT id, username, email, password_hash, display_name, 
-		       timezone, created_at, updated_at, last_seen_at, settings
+		       timezone, created_at, updated_at, last_seen_at, settings, is_admin, deactivated_at
 		FROM users 
 		WHERE email = ?`
 
@@ -151,7 +164,9 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastSeenAt,
-		&user.Settings,
+		scanMetadata(&user.Settings),
+		&user.IsAdmin,
+		&user.DeactivatedAt,
 	)
 
 	if err != nil {
@@ -161,6 +176,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
+	user.Settings = normalizeMetadata("users", user.ID, user.Settings)
 	return user, nil
 }
 
@@ -181,7 +197,8 @@ func (r *UserRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users 
 		SET username = ?, email = ?, password_hash = ?, display_name = ?, 
-		    timezone = ?, updated_at = ?, last_seen_at = ?, settings = ?
+		    timezone = ?, updated_at = ?, last_seen_at = ?, settings = ?, is_admin = ?,
+		    deactivated_at = ?
 		WHERE id = ?`
 
 	result, err := r.db.Exec(query,
@@ -193,6 +210,8 @@ func (r *UserRepository) Update(user *models.User) error {
 		user.UpdatedAt,
 		user.LastSeenAt,
 		user.Settings,
+		user.IsAdmin,
+		user.DeactivatedAt,
 		user.ID,
 	)
 
@@ -313,7 +332,7 @@ func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 
 	query := `
 		SELECT id, username, email, password_hash, display_name, 
-		       timezone, created_at, updated_at, last_seen_at, settings
+		       timezone, created_at, updated_at, last_seen_at, settings, is_admin, deactivated_at
 		FROM users 
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?`
@@ -337,11 +356,15 @@ func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 			&user.CreatedAt,
 			&user.UpdatedAt,
 			&user.LastSeenAt,
-			&user.Settings,
+			scanMetadata(&user.Settings),
+			&user.IsAdmin,
+			&user.DeactivatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user row: %w", err)
 		}
+
+		user.Settings = normalizeMetadata("users", user.ID, user.Settings)
 		users = append(users, user)
 	}
 
@@ -365,6 +388,40 @@ func (r *UserRepository) Count() (int, error) {
 	return count, nil
 }
 
+// CountAdmins returns the number of admins whose accounts are active
+func (r *UserRepository) CountAdmins() (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM users WHERE is_admin = 1 AND deactivated_at IS NULL`
+
+	if err := r.db.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count admins: %w", err)
+	}
+
+	return count, nil
+}
+
+// TaskCounts returns how many tasks each user has created, leaving out
+// deleted tasks. Users without tasks are not in the map.
+func (r *UserRepository) TaskCounts() (map[string]int, error) {
+	rows, err := r.db.Query(`SELECT creator_id, COUNT(*) FROM tasks WHERE deleted_at IS NULL GROUP BY creator_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks by user: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan task count: %w", err)
+		}
+		counts[userID] = count
+	}
+
+	return counts, rows.Err()
+}
+
 // Exists checks if a user exists by ID
 func (r *UserRepository) Exists(userID string) (bool, error) {
 	if userID == "" {
@@ -0,0 +1,15 @@
+-- Add manual ordering position to tasks
+-- Date: 2026-10-15
+-- Version: 1.0.2
+
+-- Position within a list, gap-based so moves only touch the moved task
+ALTER TABLE tasks ADD COLUMN position REAL NOT NULL DEFAULT 0;
+
+-- Seed existing list members in creation order
+UPDATE tasks SET position = (
+    SELECT COUNT(*) * 1024.0 FROM tasks t2
+    WHERE t2.list_id = tasks.list_id AND t2.created_at <= tasks.created_at
+) WHERE list_id IS NOT NULL;
+
+-- Index for ordered list retrieval
+CREATE INDEX idx_tasks_list_position ON tasks(list_id, position);
@@ -0,0 +1,12 @@
+-- Add snooze support to tasks
+-- Date: 2026-10-15
+-- Version: 1.0.3
+
+-- Task is hidden until this time
+ALTER TABLE tasks ADD COLUMN snoozed_until DATETIME;
+
+-- Snooze preset reapplied when a recurring task is completed
+ALTER TABLE tasks ADD COLUMN recurring_snooze TEXT;
+
+-- Index for finding snoozed tasks that have woken up
+CREATE INDEX idx_tasks_snoozed_until ON tasks(snoozed_until) WHERE snoozed_until IS NOT NULL;
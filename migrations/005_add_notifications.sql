@@ -0,0 +1,24 @@
+-- Add admin flag and user notifications
+-- Date: 2026-10-15
+-- Version: 1.0.4
+
+-- Administrators can manage other users' tasks
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT 0;
+
+-- Notifications delivered to users (e.g. a task was assigned to them)
+CREATE TABLE notifications (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    task_id TEXT NULL,
+    message TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at DATETIME NULL,
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- Index for listing a user's unread notifications
+CREATE INDEX idx_notifications_user ON notifications(user_id, read_at, created_at);
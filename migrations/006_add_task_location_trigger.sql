@@ -0,0 +1,7 @@
+-- Add enter/exit triggers to task locations
+-- Date: 2026-10-15
+-- Version: 1.0.5
+
+-- 'enter' tasks are due on arriving at the location, 'exit' tasks are
+-- reminders to act before leaving it
+ALTER TABLE task_locations ADD COLUMN trigger_type TEXT NOT NULL DEFAULT 'enter' CHECK (trigger_type IN ('enter', 'exit'));
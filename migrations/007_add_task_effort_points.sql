@@ -0,0 +1,7 @@
+-- Add effort points as an alternative to time estimates
+-- Date: 2026-10-15
+-- Version: 1.0.6
+
+-- Story-point style estimate; the time filter converts it to minutes when
+-- configured to estimate in points
+ALTER TABLE tasks ADD COLUMN effort_points INTEGER CHECK (effort_points > 0);
@@ -0,0 +1,19 @@
+-- Track each user's last-seen task visibility
+-- Date: 2026-10-15
+-- Version: 1.0.7
+
+-- Last filter verdict per user and task, used to announce tasks that become
+-- visible
+CREATE TABLE task_visibility (
+    user_id TEXT NOT NULL,
+    task_id TEXT NOT NULL,
+    visible BOOLEAN NOT NULL,
+    changed_at DATETIME NOT NULL,
+    notified_at DATETIME NULL,
+
+    PRIMARY KEY (user_id, task_id),
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
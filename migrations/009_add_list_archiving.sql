@@ -0,0 +1,9 @@
+-- Add archiving to task lists
+-- Date: 2026-10-15
+-- Version: 1.0.8
+
+-- Archived lists are hidden from everyday views; NULL means active
+ALTER TABLE task_lists ADD COLUMN archived_at DATETIME NULL;
+
+-- Index for finding active lists during the auto-archive sweep
+CREATE INDEX idx_task_lists_active ON task_lists(updated_at) WHERE archived_at IS NULL;
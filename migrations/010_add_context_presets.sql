@@ -0,0 +1,24 @@
+-- Add named context presets
+-- Date: 2026-10-15
+-- Version: 1.0.9
+
+-- Reusable contexts a user can apply by name, e.g. "work-morning"
+CREATE TABLE context_presets (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    available_minutes INTEGER NOT NULL DEFAULT 0 CHECK (available_minutes >= 0),
+    energy_level INTEGER NOT NULL DEFAULT 0 CHECK (energy_level BETWEEN 0 AND 5),
+    social_context TEXT NOT NULL DEFAULT 'alone',
+    location_id TEXT NULL,
+    latitude REAL NULL,
+    longitude REAL NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (user_id, name),
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE SET NULL
+);
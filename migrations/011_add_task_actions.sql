@@ -0,0 +1,17 @@
+-- Add the per-user undo log
+-- Date: 2026-10-15
+-- Version: 1.0.10
+
+-- Each user's most recent reversible task action. One row per user, so undo
+-- reaches back a single step.
+CREATE TABLE task_actions (
+    id TEXT NOT NULL UNIQUE,
+    user_id TEXT PRIMARY KEY,
+    task_id TEXT NOT NULL,
+    action_type TEXT NOT NULL CHECK (action_type IN ('complete', 'delete', 'snooze')),
+    snapshot TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Foreign keys. task_id has none: a deleted task's action must outlive it.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- Add per-provider calendar sync cursors
+-- Date: 2026-10-15
+-- Version: 1.0.11
+
+-- The sync token each user's calendar at each provider was last synced to.
+-- Incremental syncs ask the provider only for changes since this token.
+CREATE TABLE calendar_sync_cursors (
+    user_id TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    token TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, provider),
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
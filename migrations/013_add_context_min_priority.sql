@@ -0,0 +1,7 @@
+-- Add a minimum task priority to contexts
+-- Date: 2026-10-15
+-- Version: 1.0.12
+
+-- Tasks below this priority are hidden while the context is current; 0
+-- shows every priority
+ALTER TABLE contexts ADD COLUMN min_priority INTEGER NOT NULL DEFAULT 0 CHECK (min_priority BETWEEN 0 AND 5);
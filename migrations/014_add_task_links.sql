@@ -0,0 +1,22 @@
+-- Add non-dependency task links
+-- Date: 2026-10-15
+-- Version: 1.0.13
+
+-- Relationships between tasks that do not block either one: "related", or
+-- "duplicate" pointing from the duplicate to the original
+CREATE TABLE task_links (
+    id TEXT PRIMARY KEY NOT NULL,
+    task_id TEXT NOT NULL,
+    linked_task_id TEXT NOT NULL,
+    link_type TEXT NOT NULL CHECK (link_type IN ('related', 'duplicate')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (task_id, linked_task_id),
+    CHECK (task_id != linked_task_id),
+
+    -- Foreign keys
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (linked_task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_task_links_linked ON task_links(linked_task_id);
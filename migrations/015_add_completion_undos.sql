@@ -0,0 +1,21 @@
+-- Add undo windows for completions in shared lists
+-- Date: 2026-10-15
+-- Version: 1.0.14
+
+-- A task completed in a shared list can be taken back by whoever completed
+-- it until expires_at. snapshot is the task before completion and
+-- notification_ids the members' notifications to withdraw on undo.
+CREATE TABLE completion_undos (
+    task_id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL,
+    snapshot TEXT NOT NULL,
+    notification_ids TEXT NOT NULL DEFAULT '[]',
+    completed_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+
+    CHECK (expires_at > completed_at),
+
+    -- Foreign keys
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
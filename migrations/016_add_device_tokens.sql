@@ -0,0 +1,20 @@
+-- Add per-device API tokens
+-- Date: 2026-10-15
+-- Version: 1.0.15
+
+-- Long-lived tokens a single device, such as a phone posting location fixes,
+-- authenticates with instead of a session. Only the SHA-256 of the token is
+-- stored.
+CREATE TABLE device_tokens (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_device_tokens_user_id ON device_tokens(user_id);
@@ -0,0 +1,9 @@
+-- Add soft delete to tasks
+-- Date: 2026-10-15
+-- Version: 1.0.16
+
+-- Set when a task is deleted; the row stays so it can be restored
+ALTER TABLE tasks ADD COLUMN deleted_at DATETIME;
+
+-- Index for listing and purging deleted tasks
+CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at) WHERE deleted_at IS NOT NULL;
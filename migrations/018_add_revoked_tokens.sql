@@ -0,0 +1,14 @@
+-- Add revoked access tokens
+-- Date: 2026-10-15
+-- Version: 1.0.17
+
+-- The jti claims of access tokens that were logged out. Each row only
+-- matters until expires_at, when the token is refused anyway, and is then
+-- purged by background maintenance.
+CREATE TABLE revoked_tokens (
+    jti TEXT PRIMARY KEY NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+-- Index for purging expired rows
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
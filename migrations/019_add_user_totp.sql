@@ -0,0 +1,8 @@
+-- Add TOTP two-factor authentication to users
+-- Date: 2026-10-15
+-- Version: 1.0.18
+
+-- The TOTP secret is sealed with the server's TOTP key before it is stored.
+-- totp_enabled is set once the user has confirmed a code from the secret.
+ALTER TABLE users ADD COLUMN totp_secret TEXT NULL;
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Add a required energy level to tasks
+-- Date: 2026-10-15
+-- Version: 1.0.19
+
+-- The energy (1-5) a task takes; the energy filter hides the task while the
+-- user reports less. NULL leaves the task to the estimate-based heuristics.
+ALTER TABLE tasks ADD COLUMN required_energy_level INTEGER CHECK (required_energy_level BETWEEN 1 AND 5);
@@ -0,0 +1,17 @@
+-- Add password reset tokens
+-- Date: 2026-10-15
+-- Version: 1.0.20
+
+-- Tokens that let a user who forgot their password set a new one. Only a
+-- hash of each token is kept. A token is single-use: used_at is set when it
+-- resets a password. Expired rows are purged by background maintenance.
+CREATE TABLE password_reset_tokens (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL
+);
+
+-- Index for purging expired rows
+CREATE INDEX idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
@@ -0,0 +1,41 @@
+-- Fix full-text search index triggers
+-- Date: 2026-10-15
+-- Version: 1.0.21
+
+-- tasks_fts and locations_fts take their content from their tables, so a
+-- row's old words must be removed with the FTS5 'delete' command, which is
+-- given the old values. The original triggers deleted by rowid, which reads
+-- the row's current values and left edited and deleted rows' words behind.
+DROP TRIGGER IF EXISTS tasks_fts_delete;
+DROP TRIGGER IF EXISTS tasks_fts_update;
+
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    INSERT INTO tasks_fts(tasks_fts, rowid, title, description)
+    VALUES ('delete', old.rowid, old.title, old.description);
+END;
+
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+    INSERT INTO tasks_fts(tasks_fts, rowid, title, description)
+    VALUES ('delete', old.rowid, old.title, old.description);
+    INSERT INTO tasks_fts(rowid, title, description)
+    VALUES (new.rowid, new.title, new.description);
+END;
+
+DROP TRIGGER IF EXISTS locations_fts_delete;
+DROP TRIGGER IF EXISTS locations_fts_update;
+
+CREATE TRIGGER locations_fts_delete AFTER DELETE ON locations BEGIN
+    INSERT INTO locations_fts(locations_fts, rowid, name, address)
+    VALUES ('delete', old.rowid, old.name, old.address);
+END;
+
+CREATE TRIGGER locations_fts_update AFTER UPDATE OF name, address ON locations BEGIN
+    INSERT INTO locations_fts(locations_fts, rowid, name, address)
+    VALUES ('delete', old.rowid, old.name, old.address);
+    INSERT INTO locations_fts(rowid, name, address)
+    VALUES (new.rowid, new.name, new.address);
+END;
+
+-- Re-index from the tables to drop the stale words
+INSERT INTO tasks_fts(tasks_fts) VALUES ('rebuild');
+INSERT INTO locations_fts(locations_fts) VALUES ('rebuild');
@@ -0,0 +1,9 @@
+-- Add default task constraints to lists
+-- Date: 2026-10-15
+-- Version: 1.0.22
+
+-- The location and estimate tasks added to a list get when they have none of
+-- their own, e.g. a "Costco run" list whose tasks all need the Costco
+-- location. Deleting the location clears the default.
+ALTER TABLE task_lists ADD COLUMN default_location_id TEXT REFERENCES locations(id) ON DELETE SET NULL;
+ALTER TABLE task_lists ADD COLUMN default_estimated_minutes INTEGER CHECK (default_estimated_minutes > 0);
@@ -0,0 +1,31 @@
+-- Allow suggested task dependencies
+-- Date: 2026-10-15
+-- Version: 1.0.23
+
+-- A suggested dependency recommends finishing another task first without
+-- hiding the task until it is. SQLite cannot change a CHECK constraint, so
+-- the table is rebuilt with the new type allowed.
+CREATE TABLE task_dependencies_new (
+    id TEXT PRIMARY KEY NOT NULL,
+    task_id TEXT NOT NULL,
+    depends_on_task_id TEXT NOT NULL,
+    dependency_type TEXT NOT NULL DEFAULT 'blocking',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (depends_on_task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+
+    CHECK (dependency_type IN ('blocking', 'related', 'scheduled', 'suggested')),
+    CHECK (task_id != depends_on_task_id),
+
+    UNIQUE(task_id, depends_on_task_id)
+);
+
+INSERT INTO task_dependencies_new (id, task_id, depends_on_task_id, dependency_type, created_at)
+SELECT id, task_id, depends_on_task_id, dependency_type, created_at FROM task_dependencies;
+
+DROP TABLE task_dependencies;
+ALTER TABLE task_dependencies_new RENAME TO task_dependencies;
+
+CREATE INDEX idx_task_dependencies_task ON task_dependencies(task_id);
+CREATE INDEX idx_task_dependencies_depends ON task_dependencies(depends_on_task_id);
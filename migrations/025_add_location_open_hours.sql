@@ -0,0 +1,8 @@
+-- Add opening hours to locations
+-- Date: 2026-10-15
+-- Version: 1.0.24
+
+-- A JSON schedule of wall-clock ranges keyed by weekday, e.g.
+-- {"mon": [{"open": "08:00", "close": "21:00"}]}. Tasks tied to a location
+-- are hidden while it is closed; NULL means always open.
+ALTER TABLE locations ADD COLUMN open_hours TEXT;
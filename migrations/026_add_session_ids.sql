@@ -0,0 +1,28 @@
+-- Identify sessions and store only their token hashes
+-- Date: 2026-10-15
+-- Version: 1.0.25
+
+-- Sessions were keyed by the raw access token. They now have an ID to list
+-- and revoke them by, keep only the SHA-256 of the token, and record the
+-- device and when the session was last used. Stored tokens cannot be hashed
+-- here, so existing sessions are dropped and users sign in again.
+DROP TABLE sessions;
+
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    device_name TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    expires_at DATETIME NOT NULL,
+    user_agent TEXT DEFAULT '',
+    ip_address TEXT DEFAULT '',
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Indexes for listing a user's sessions and purging expired ones
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
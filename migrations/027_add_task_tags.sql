@@ -0,0 +1,18 @@
+-- Add free-form task tags
+-- Date: 2026-10-15
+-- Version: 1.0.26
+
+-- Labels such as "errands" or "work", stored lowercase and trimmed. A task
+-- has each tag at most once.
+CREATE TABLE task_tags (
+    task_id TEXT NOT NULL,
+    tag TEXT NOT NULL CHECK (length(tag) > 0),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (task_id, tag),
+
+    -- Foreign keys
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_task_tags_tag ON task_tags(tag);
@@ -0,0 +1,21 @@
+-- Add geofence enter/exit events
+-- Date: 2026-10-15
+-- Version: 1.0.27
+
+-- The user arriving at or leaving one of their saved locations, detected by
+-- comparing a new context's position with the previous one
+CREATE TABLE geofence_events (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL,
+    location_id TEXT NOT NULL,
+    context_id TEXT NOT NULL,
+    event_type TEXT NOT NULL CHECK (event_type IN ('enter', 'exit')),
+    occurred_at DATETIME NOT NULL,
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE CASCADE
+);
+
+-- Index for listing a user's recent events
+CREATE INDEX idx_geofence_events_user ON geofence_events(user_id, occurred_at);
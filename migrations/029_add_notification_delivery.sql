@@ -0,0 +1,27 @@
+-- Add notification delivery channels
+-- Date: 2026-10-15
+-- Version: 1.0.28
+
+-- Where each user's notifications are pushed besides the app. An empty
+-- channel is turned off.
+CREATE TABLE user_notification_settings (
+    user_id TEXT PRIMARY KEY NOT NULL,
+    webhook_url TEXT NOT NULL DEFAULT '',
+    ntfy_topic TEXT NOT NULL DEFAULT '',
+    ntfy_server TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Delivery state. A notification is pushed once; failed passes are counted
+-- and delivery_failed_at is set when the dispatcher gives up.
+ALTER TABLE notifications ADD COLUMN delivered_at DATETIME NULL;
+ALTER TABLE notifications ADD COLUMN delivery_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notifications ADD COLUMN delivery_error TEXT NULL;
+ALTER TABLE notifications ADD COLUMN delivery_failed_at DATETIME NULL;
+
+-- Index for finding notifications still to deliver
+CREATE INDEX idx_notifications_undelivered ON notifications(delivered_at, delivery_failed_at, created_at);
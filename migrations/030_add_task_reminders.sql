@@ -0,0 +1,26 @@
+-- Add time-based task reminders
+-- Date: 2026-10-15
+-- Version: 1.0.29
+
+-- A time to notify a user about a task. A scheduler claims a due reminder
+-- by flipping sent from false to true before notifying, so it is sent once even
+-- with several servers running.
+CREATE TABLE task_reminders (
+    id TEXT PRIMARY KEY NOT NULL,
+    task_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    remind_at DATETIME NOT NULL,
+    sent BOOLEAN NOT NULL DEFAULT FALSE,
+    sent_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Foreign keys
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Index for finding due reminders
+CREATE INDEX idx_task_reminders_due ON task_reminders(sent, remind_at);
+
+-- Index for listing a task's reminders
+CREATE INDEX idx_task_reminders_task ON task_reminders(task_id);
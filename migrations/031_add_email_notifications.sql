@@ -0,0 +1,7 @@
+-- Add email notification preference
+-- Date: 2026-10-15
+-- Version: 1.0.30
+
+-- Whether each notification is also emailed to the user's address. The
+-- server only sends email when notifications.smtp is configured.
+ALTER TABLE user_notification_settings ADD COLUMN email_notifications_enabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+-- Add task versions for optimistic concurrency
+-- Date: 2026-10-15
+-- Version: 1.0.31
+
+-- Counts the saved changes to each task. An update only applies while the
+-- version is still the one the writer read, and bumps it, so two people
+-- editing the same task cannot silently overwrite each other.
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
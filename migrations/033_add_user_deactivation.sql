@@ -0,0 +1,7 @@
+-- Add user deactivation
+-- Date: 2026-10-15
+-- Version: 1.0.32
+
+-- Set when an admin deactivates the account. Deactivated users cannot log
+-- in, and their data is kept until the account is deleted.
+ALTER TABLE users ADD COLUMN deactivated_at DATETIME;
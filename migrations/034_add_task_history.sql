@@ -0,0 +1,25 @@
+-- Add the task edit history
+-- Date: 2026-10-15
+-- Version: 1.0.33
+
+-- One row per saved task edit, holding only the fields it changed as a JSON
+-- object of {"field": {"old": ..., "new": ...}}. actor_id is NULL for
+-- changes the service made on its own, and for users deleted since, so a
+-- shared list keeps its history when a member leaves the install.
+CREATE TABLE task_history (
+    id TEXT PRIMARY KEY NOT NULL,
+    task_id TEXT NOT NULL,
+    actor_id TEXT,
+    changes TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Foreign keys
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Index for reading a task's history in order
+CREATE INDEX idx_task_history_task ON task_history(task_id, created_at);
+
+-- Index for the retention cleanup
+CREATE INDEX idx_task_history_created ON task_history(created_at);
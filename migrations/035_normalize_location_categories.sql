@@ -0,0 +1,19 @@
+-- Normalize location categories to the fixed set
+-- Date: 2026-10-15
+-- Version: 1.0.34
+
+-- Categories used to be free text. Map the common spellings onto home, work,
+-- errand, gym, school and other, and file everything else under other.
+-- Locations can still opt back into a custom category afterwards, which
+-- marks them with "custom_category" in their metadata.
+UPDATE locations SET category = LOWER(TRIM(category)) WHERE category IS NOT NULL;
+
+UPDATE locations SET category = 'home' WHERE category IN ('house', 'apartment');
+UPDATE locations SET category = 'work' WHERE category IN ('office', 'desk', 'workplace');
+UPDATE locations SET category = 'errand' WHERE category IN ('errands', 'grocery', 'groceries', 'store', 'shop', 'shopping', 'pharmacy', 'bank', 'post office');
+UPDATE locations SET category = 'gym' WHERE category IN ('fitness', 'workout');
+UPDATE locations SET category = 'school' WHERE category IN ('college', 'university', 'campus');
+
+UPDATE locations SET category = 'other'
+WHERE category IS NULL
+   OR category NOT IN ('home', 'work', 'errand', 'gym', 'school', 'other');
@@ -0,0 +1,12 @@
+-- Bind refresh tokens to the sessions they were issued with
+-- Date: 2026-10-15
+-- Version: 1.0.35
+
+-- Refreshing used to need only a valid refresh token, so a 30-day token
+-- outlived logout, session revocation and password resets. Each session
+-- now stores the SHA-256 of its refresh token, and a refresh token works
+-- only while its session exists. Sessions from before have no hash, so
+-- their refresh tokens stop working and those devices sign in again.
+ALTER TABLE sessions ADD COLUMN refresh_token_hash TEXT;
+
+CREATE INDEX idx_sessions_refresh_token_hash ON sessions(refresh_token_hash);
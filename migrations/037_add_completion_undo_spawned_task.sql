@@ -0,0 +1,8 @@
+-- Remember the occurrence a recurring completion spawned
+-- Date: 2026-10-15
+-- Version: 1.0.36
+
+-- Completing a recurring task creates its next occurrence. Undoing the
+-- completion within its window removes that occurrence again, so the undo
+-- records which task it was.
+ALTER TABLE completion_undos ADD COLUMN spawned_task_id TEXT;
@@ -0,0 +1,20 @@
+-- Add calendar feed tokens
+-- Date: 2026-10-15
+-- Version: 1.0.37
+
+-- Long-lived tokens calendar apps put in the feed URL to subscribe to
+-- GET /tasks/export.ics. They authenticate nothing else and last until the
+-- user revokes them. Only the SHA-256 of the token is stored.
+CREATE TABLE feed_tokens (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+
+    -- Foreign keys
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_tokens_user_id ON feed_tokens(user_id);
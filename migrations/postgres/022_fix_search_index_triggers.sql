@@ -0,0 +1,6 @@
+-- Fix full-text search index triggers, PostgreSQL version
+-- Date: 2026-10-15
+-- Version: 1.0.21
+--
+-- Nothing to do: PostgreSQL searches with expression indexes, which have no
+-- triggers to fix.
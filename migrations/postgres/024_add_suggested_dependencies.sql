@@ -0,0 +1,8 @@
+-- Allow suggested task dependencies, PostgreSQL version
+-- Date: 2026-10-15
+-- Version: 1.0.23
+--
+-- PostgreSQL can replace the CHECK constraint in place.
+ALTER TABLE task_dependencies DROP CONSTRAINT IF EXISTS task_dependencies_dependency_type_check;
+ALTER TABLE task_dependencies ADD CONSTRAINT task_dependencies_dependency_type_check
+    CHECK (dependency_type IN ('blocking', 'related', 'scheduled', 'suggested'));
@@ -0,0 +1,305 @@
+package calendar
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+)
+
+const (
+	// DefaultCalDAVRetries is how many times a failed CalDAV request is
+	// retried when CalDAVConfig.Retries is unset
+	DefaultCalDAVRetries = 3
+	// DefaultCalDAVBackoff is the wait before the first retry, doubling
+	// for each one after
+	DefaultCalDAVBackoff = time.Second
+	// DefaultCalDAVQueryWindow is how much of the sync window one
+	// calendar-query covers
+	DefaultCalDAVQueryWindow = 30 * 24 * time.Hour
+)
+
+// CalDAVCredentials looks up the HTTP basic auth login for a user's CalDAV
+// server
+type CalDAVCredentials interface {
+	CalDAVLogin(userID string) (username, password string, err error)
+}
+
+// StaticCalDAVCredentials is one login used for every user, such as an
+// account from the CLI config
+type StaticCalDAVCredentials struct {
+	Username string
+	Password string
+}
+
+func (c StaticCalDAVCredentials) CalDAVLogin(userID string) (string, string, error) {
+	return c.Username, c.Password, nil
+}
+
+// CalDAVConfig is one CalDAV calendar collection
+type CalDAVConfig struct {
+	// URL is the calendar collection, e.g. one found by
+	// sync.CalDAVProvider.DiscoverCalendars
+	URL string
+	// ProviderID is stored as the provider of the calendar's events.
+	// Empty uses models.ProviderCalDAV.
+	ProviderID  string
+	Credentials CalDAVCredentials
+	// Retries and Backoff control how network failures, server errors and
+	// rate limiting are retried. A negative Retries turns retrying off.
+	Retries int
+	Backoff time.Duration
+	// QueryWindow splits a long sync window into several queries, so one
+	// failing does not lose the rest
+	QueryWindow time.Duration
+}
+
+// CalDAVProvider reads events from a CalDAV calendar with calendar-query
+// REPORTs (RFC 4791), expanding recurring events within the sync window.
+// Events are keyed by their UID, so repeated syncs update them in place.
+type CalDAVProvider struct {
+	config     CalDAVConfig
+	httpClient HTTPClient
+}
+
+func NewCalDAVProvider(config CalDAVConfig, httpClient HTTPClient) *CalDAVProvider {
+	if config.ProviderID == "" {
+		config.ProviderID = models.ProviderCalDAV
+	}
+	if config.Retries == 0 {
+		config.Retries = DefaultCalDAVRetries
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultCalDAVBackoff
+	}
+	if config.QueryWindow <= 0 {
+		config.QueryWindow = DefaultCalDAVQueryWindow
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &CalDAVProvider{config: config, httpClient: httpClient}
+}
+
+// ProviderID implements IdentifiedProvider
+func (p *CalDAVProvider) ProviderID() string {
+	return p.config.ProviderID
+}
+
+// PartialSyncError is returned alongside the events that were fetched when
+// part of the sync window could not be
+type PartialSyncError struct {
+	Failures []error
+}
+
+func (e *PartialSyncError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		messages[i] = failure.Error()
+	}
+	return "partial sync: " + strings.Join(messages, "; ")
+}
+
+// FetchEvents implements Provider. The window is queried in QueryWindow
+// pieces; when some fail after their retries, the events from the rest are
+// returned with a *PartialSyncError. Only when every piece fails is there
+// nothing to return.
+func (p *CalDAVProvider) FetchEvents(ctx context.Context, userID string, since, until time.Time) ([]models.CalendarEvent, error) {
+	if p.config.Credentials == nil {
+		return nil, fmt.Errorf("no CalDAV credentials configured")
+	}
+	username, password, err := p.config.Credentials.CalDAVLogin(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CalDAV credentials: %w", err)
+	}
+
+	var events []models.CalendarEvent
+	seen := map[string]bool{}
+	partial := &PartialSyncError{}
+	pieces := 0
+	for start := since; start.Before(until); start = start.Add(p.config.QueryWindow) {
+		end := start.Add(p.config.QueryWindow)
+		if end.After(until) {
+			end = until
+		}
+		pieces++
+
+		vevents, err := p.query(ctx, username, password, start, end)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			partial.Failures = append(partial.Failures, fmt.Errorf("%s to %s: %w",
+				start.Format(time.DateOnly), end.Format(time.DateOnly), err))
+			continue
+		}
+
+		// An event spanning two pieces is returned by both
+		for _, event := range expandVEvents(vevents, userID, p.config.ProviderID, start, end) {
+			if !seen[event.ExternalID] {
+				seen[event.ExternalID] = true
+				events = append(events, event)
+			}
+		}
+	}
+
+	switch {
+	case len(partial.Failures) == 0:
+		return events, nil
+	case len(partial.Failures) == pieces:
+		return nil, partial.Failures[0]
+	default:
+		return events, partial
+	}
+}
+
+// query runs one calendar-query, retrying failures that may pass
+func (p *CalDAVProvider) query(ctx context.Context, username, password string, start, end time.Time) ([]vevent, error) {
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" ?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+    <D:prop>
+        <D:getetag />
+        <C:calendar-data />
+    </D:prop>
+    <C:filter>
+        <C:comp-filter name="VCALENDAR">
+            <C:comp-filter name="VEVENT">
+                <C:time-range start="%s" end="%s"/>
+            </C:comp-filter>
+        </C:comp-filter>
+    </C:filter>
+</C:calendar-query>`, start.UTC().Format("20060102T150405Z"), end.UTC().Format("20060102T150405Z"))
+
+	backoff := p.config.Backoff
+	for attempt := 0; ; attempt++ {
+		data, wait, err := p.report(ctx, username, password, body)
+		if err == nil {
+			return parseCalendarQuery(data)
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= max(p.config.Retries, 0) || wait > sync.DefaultSyncOptions.MaxRetryAfter {
+			return nil, err
+		}
+		if wait < backoff {
+			wait = backoff
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// retryableError is a failure a later attempt may not have
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// report sends a REPORT, returning the response body, or an error and how
+// long the server asked to wait before trying again
+func (p *CalDAVProvider) report(ctx context.Context, username, password, body string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "REPORT", p.config.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Depth", "1")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, &retryableError{fmt.Errorf("CalDAV request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := sync.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, retryAfter, &retryableError{&sync.RateLimitError{RetryAfter: retryAfter}}
+	case resp.StatusCode >= 500:
+		return nil, 0, &retryableError{fmt.Errorf("CalDAV server returned status %d", resp.StatusCode)}
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, 0, fmt.Errorf("invalid credentials")
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus:
+		return nil, 0, fmt.Errorf("CalDAV server returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, &retryableError{fmt.Errorf("failed to read CalDAV response: %w", err)}
+	}
+	return data, 0, nil
+}
+
+type davMultistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Prop struct {
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// parseCalendarQuery reads the events from a calendar-query response,
+// skipping resources that are not valid iCalendar. An event without a UID
+// is keyed by its resource's href instead.
+func parseCalendarQuery(data []byte) ([]vevent, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var status davMultistatus
+	if err := xml.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse CalDAV response: %w", err)
+	}
+
+	var events []vevent
+	for _, response := range status.Responses {
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.CalendarData == "" {
+				continue
+			}
+			parsed, err := parseVEvents(propstat.Prop.CalendarData)
+			if err != nil {
+				// One malformed event should not stop the rest syncing
+				continue
+			}
+			for i := range parsed {
+				if parsed[i].UID == "" {
+					parsed[i].UID = response.Href
+				}
+			}
+			events = append(events, parsed...)
+		}
+	}
+	return events, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
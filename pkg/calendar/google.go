@@ -0,0 +1,272 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+const (
+	// GoogleTokenURL is Google's OAuth2 token endpoint
+	GoogleTokenURL = "https://oauth2.googleapis.com/token"
+	// GoogleAPIURL is the base URL of the Google Calendar v3 API
+	GoogleAPIURL = "https://www.googleapis.com/calendar/v3"
+	// GooglePrimaryCalendar names the account's main calendar
+	GooglePrimaryCalendar = "primary"
+)
+
+// GoogleConfig is one Google account's OAuth2 client and refresh token.
+// The refresh token comes from the user consenting to the
+// https://www.googleapis.com/auth/calendar.readonly scope for the client.
+type GoogleConfig struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	// CalendarID is the calendar to read. Empty reads the primary calendar.
+	CalendarID string
+	// TokenURL and APIURL override Google's endpoints, for tests
+	TokenURL string
+	APIURL   string
+	// Location is where all-day events start and end. Nil uses time.Local.
+	Location *time.Location
+}
+
+// GoogleProvider reads events from Google Calendar, exchanging its refresh
+// token for access tokens as they expire
+type GoogleProvider struct {
+	config     GoogleConfig
+	httpClient HTTPClient
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func NewGoogleProvider(config GoogleConfig, httpClient HTTPClient) *GoogleProvider {
+	if config.CalendarID == "" {
+		config.CalendarID = GooglePrimaryCalendar
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = GoogleTokenURL
+	}
+	if config.APIURL == "" {
+		config.APIURL = GoogleAPIURL
+	}
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &GoogleProvider{config: config, httpClient: httpClient}
+}
+
+// ProviderID implements IdentifiedProvider
+func (p *GoogleProvider) ProviderID() string {
+	return models.ProviderGoogle
+}
+
+type googleEventTime struct {
+	Date     string `json:"date"`
+	DateTime string `json:"dateTime"`
+}
+
+type googleEvent struct {
+	ID           string          `json:"id"`
+	Status       string          `json:"status"`
+	Summary      string          `json:"summary"`
+	Location     string          `json:"location"`
+	Transparency string          `json:"transparency"`
+	Start        googleEventTime `json:"start"`
+	End          googleEventTime `json:"end"`
+}
+
+type googleEventList struct {
+	Items         []googleEvent `json:"items"`
+	NextPageToken string        `json:"nextPageToken"`
+}
+
+// FetchEvents implements Provider. Recurring events are expanded into
+// their instances; cancelled events and ones NewEvents cannot store are
+// left out.
+func (p *GoogleProvider) FetchEvents(ctx context.Context, userID string, since, until time.Time) ([]models.CalendarEvent, error) {
+	var events []models.CalendarEvent
+	pageToken := ""
+	for {
+		page, err := p.listEvents(ctx, since, until, pageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			if item.Status == "cancelled" {
+				continue
+			}
+			converted, err := p.convertEvent(userID, item)
+			if err != nil {
+				// One malformed event should not stop the rest syncing
+				continue
+			}
+			events = append(events, converted...)
+		}
+
+		if page.NextPageToken == "" {
+			return events, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (p *GoogleProvider) listEvents(ctx context.Context, since, until time.Time, pageToken string) (*googleEventList, error) {
+	query := url.Values{}
+	query.Set("timeMin", since.UTC().Format(time.RFC3339))
+	query.Set("timeMax", until.UTC().Format(time.RFC3339))
+	query.Set("singleEvents", "true")
+	query.Set("orderBy", "startTime")
+	query.Set("maxResults", "250")
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	endpoint := fmt.Sprintf("%s/calendars/%s/events?%s", p.config.APIURL, url.PathEscape(p.config.CalendarID), query.Encode())
+
+	// A token Google has revoked early is refreshed once before giving up
+	for attempt := 0; ; attempt++ {
+		token, err := p.token(ctx, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			continue
+		}
+
+		var page googleEventList
+		err = decodeGoogleResponse(resp, &page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events: %w", err)
+		}
+		return &page, nil
+	}
+}
+
+// token returns an access token, exchanging the refresh token for a new
+// one when the current one has expired or refresh is set
+func (p *GoogleProvider) token(ctx context.Context, refresh bool) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Tokens are renewed a minute early so one does not expire mid-request
+	if !refresh && p.accessToken != "" && time.Now().Add(time.Minute).Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("client_id", p.config.ClientID)
+	form.Set("client_secret", p.config.ClientSecret)
+	form.Set("refresh_token", p.config.RefreshToken)
+	form.Set("grant_type", "refresh_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := decodeGoogleResponse(resp, &token); err != nil {
+		return "", fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("failed to refresh access token: no access token in response")
+	}
+
+	p.accessToken = token.AccessToken
+	p.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// decodeGoogleResponse decodes a successful response into v, or returns the
+// error Google sent
+func decodeGoogleResponse(resp *http.Response, v interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error            json.RawMessage `json:"error"`
+			ErrorDescription string          `json:"error_description"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && len(body.Error) > 0 {
+			return fmt.Errorf("status %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+func (p *GoogleProvider) convertEvent(userID string, item googleEvent) ([]models.CalendarEvent, error) {
+	allDay := item.Start.DateTime == ""
+	start, err := p.parseEventTime(item.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := p.parseEventTime(item.End)
+	if err != nil {
+		return nil, err
+	}
+
+	title := item.Summary
+	if title == "" {
+		title = "(No title)"
+	}
+
+	events, err := NewEvents(userID, models.ProviderGoogle, item.ID, title, start, end, allDay)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if item.Location != "" {
+			events[i].SetLocation(item.Location)
+		}
+		events[i].SetBusy(item.Transparency != "transparent")
+	}
+	return events, nil
+}
+
+// parseEventTime reads a timed event's dateTime or an all-day event's date,
+// which is midnight where the user is
+func (p *GoogleProvider) parseEventTime(t googleEventTime) (time.Time, error) {
+	if t.DateTime != "" {
+		return time.Parse(time.RFC3339, t.DateTime)
+	}
+	return time.ParseInLocation("2006-01-02", t.Date, p.config.Location)
+}
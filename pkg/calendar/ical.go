@@ -0,0 +1,344 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
+)
+
+// vevent is one VEVENT component of an iCalendar object
+type vevent struct {
+	UID      string
+	Summary  string
+	Location string
+	Status   string
+	Transp   string
+	Start    time.Time
+	End      time.Time
+	AllDay   bool
+	RRule    string
+	ExDates  []time.Time
+	// RecurrenceID is set on an override of one instance of a recurring
+	// event, and is the start of the instance it replaces
+	RecurrenceID *time.Time
+}
+
+// icalProperty is one content line of an iCalendar object, with its
+// parameters left unparsed
+type icalProperty struct {
+	Name   string
+	Params string
+	Value  string
+}
+
+// icalComponent is a BEGIN/END block of an iCalendar object
+type icalComponent struct {
+	Name       string
+	Properties []icalProperty
+	Children   []*icalComponent
+}
+
+// parseICalendar reads an iCalendar object into its components, returned
+// as the children of an unnamed root. Lines outside any component are
+// ignored.
+func parseICalendar(data string) (*icalComponent, error) {
+	// Unfold continuation lines before splitting into properties
+	data = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(data)
+
+	root := &icalComponent{}
+	stack := []*icalComponent{root}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		name = strings.ToUpper(name)
+		current := stack[len(stack)-1]
+
+		switch name {
+		case "BEGIN":
+			component := &icalComponent{Name: strings.ToUpper(value)}
+			current.Children = append(current.Children, component)
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 1 || current.Name != strings.ToUpper(value) {
+				return nil, fmt.Errorf("END:%s does not close %s", value, current.Name)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if current != root {
+				current.Properties = append(current.Properties, icalProperty{Name: name, Params: params, Value: value})
+			}
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("%s is not closed", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// find returns the components named name within c, not looking inside the
+// ones it finds
+func (c *icalComponent) find(name string) []*icalComponent {
+	var found []*icalComponent
+	for _, child := range c.Children {
+		if child.Name == name {
+			found = append(found, child)
+		} else {
+			found = append(found, child.find(name)...)
+		}
+	}
+	return found
+}
+
+// parseVEvents reads every VEVENT in an iCalendar object. Components
+// nested in an event, such as VALARM, are skipped. Times in a TZID that is
+// not an IANA zone name use the object's VTIMEZONE of that name.
+func parseVEvents(data string) ([]vevent, error) {
+	root, err := parseICalendar(data)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := readVTimezones(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []vevent
+	for _, component := range root.find("VEVENT") {
+		event, err := readVEvent(component, zones)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func readVEvent(component *icalComponent, zones icalZones) (vevent, error) {
+	var event vevent
+	var duration string
+	for _, property := range component.Properties {
+		value, params := property.Value, property.Params
+		var err error
+		switch property.Name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = icalUnescape(value)
+		case "LOCATION":
+			event.Location = icalUnescape(value)
+		case "STATUS":
+			event.Status = strings.ToUpper(value)
+		case "TRANSP":
+			event.Transp = strings.ToUpper(value)
+		case "RRULE":
+			event.RRule = value
+		case "DURATION":
+			duration = value
+		case "DTSTART":
+			event.Start, event.AllDay, err = zones.parseTime(value, params)
+		case "DTEND":
+			event.End, _, err = zones.parseTime(value, params)
+		case "RECURRENCE-ID":
+			var at time.Time
+			at, _, err = zones.parseTime(value, params)
+			event.RecurrenceID = &at
+		case "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				var at time.Time
+				if at, _, err = zones.parseTime(date, params); err != nil {
+					break
+				}
+				event.ExDates = append(event.ExDates, at)
+			}
+		}
+		if err != nil {
+			return vevent{}, fmt.Errorf("invalid %s in event %s: %w", property.Name, event.UID, err)
+		}
+	}
+
+	if err := event.finish(duration); err != nil {
+		return vevent{}, err
+	}
+	return event, nil
+}
+
+// finish fills in the end of an event given by DURATION or left out
+func (e *vevent) finish(duration string) error {
+	if e.Start.IsZero() {
+		return fmt.Errorf("event %s has no DTSTART", e.UID)
+	}
+	if !e.End.IsZero() {
+		return nil
+	}
+
+	switch {
+	case duration != "":
+		days, length, err := parseICalDuration(duration)
+		if err != nil {
+			return fmt.Errorf("invalid DURATION in event %s: %w", e.UID, err)
+		}
+		e.End = e.Start.AddDate(0, 0, days).Add(length)
+	case e.AllDay:
+		// An all-day event without an end lasts the one day
+		e.End = e.Start.AddDate(0, 0, 1)
+	default:
+		e.End = e.Start
+	}
+	return nil
+}
+
+// parseICalDuration reads a duration such as P1D, PT1H30M or P2W, split into
+// days, which follow the calendar across DST changes, and the rest
+func parseICalDuration(value string) (int, time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(value, "+"), "P")
+	if !ok || rest == "" {
+		return 0, 0, fmt.Errorf("%q is not a duration", value)
+	}
+
+	days := 0
+	var length time.Duration
+	inTime := false
+	number := ""
+	for _, r := range rest {
+		switch {
+		case r >= '0' && r <= '9':
+			number += string(r)
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%q is not a duration", value)
+		}
+		number = ""
+		switch {
+		case r == 'W' && !inTime:
+			days += 7 * n
+		case r == 'D' && !inTime:
+			days += n
+		case r == 'H' && inTime:
+			length += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			length += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			length += time.Duration(n) * time.Second
+		default:
+			return 0, 0, fmt.Errorf("%q is not a duration", value)
+		}
+	}
+	if number != "" {
+		return 0, 0, fmt.Errorf("%q is not a duration", value)
+	}
+	return days, length, nil
+}
+
+func icalUnescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// expandVEvents turns parsed events into the calendar events overlapping
+// [since, until). Recurring events become one event per instance, with
+// EXDATEs left out and RECURRENCE-ID overrides in place of the instances
+// they replace; each instance's external ID is its UID and start time.
+// Events keyed by UID alone keep the same external ID on every sync.
+// Cancelled events, and ones NewEvents cannot store, are left out.
+func expandVEvents(events []vevent, userID, providerID string, since, until time.Time) []models.CalendarEvent {
+	overrides := map[string]map[int64]vevent{}
+	for _, event := range events {
+		if event.RecurrenceID != nil {
+			if overrides[event.UID] == nil {
+				overrides[event.UID] = map[int64]vevent{}
+			}
+			overrides[event.UID][event.RecurrenceID.Unix()] = event
+		}
+	}
+
+	var result []models.CalendarEvent
+	add := func(event vevent, externalID string) {
+		if event.Status == "CANCELLED" || !event.Start.Before(until) || !event.End.After(since) {
+			return
+		}
+		title := event.Summary
+		if title == "" {
+			title = "(No title)"
+		}
+		converted, err := NewEvents(userID, providerID, externalID, title, event.Start, event.End, event.AllDay)
+		if err != nil {
+			return
+		}
+		for i := range converted {
+			if event.Location != "" {
+				converted[i].SetLocation(event.Location)
+			}
+			converted[i].SetBusy(event.Transp != "TRANSPARENT")
+		}
+		result = append(result, converted...)
+	}
+
+	for _, event := range events {
+		if event.RecurrenceID != nil {
+			continue
+		}
+		rule, err := parseEventRule(event.RRule)
+		if event.RRule == "" || err != nil {
+			// A rule this package cannot expand still shows its first instance
+			add(event, event.UID)
+			continue
+		}
+
+		length := event.End.Sub(event.Start)
+		excluded := map[int64]bool{}
+		for _, at := range event.ExDates {
+			excluded[at.Unix()] = true
+		}
+		for _, at := range rule.Between(event.Start, since.Add(-length), until) {
+			if excluded[at.Unix()] {
+				continue
+			}
+			instance := event
+			if override, ok := overrides[event.UID][at.Unix()]; ok {
+				instance = override
+				delete(overrides[event.UID], at.Unix())
+			} else {
+				instance.Start, instance.End = at, at.Add(length)
+			}
+			add(instance, instanceID(event.UID, at))
+		}
+	}
+
+	// Overrides moved into the window from an instance outside it
+	for uid, moved := range overrides {
+		for _, override := range moved {
+			add(override, instanceID(uid, *override.RecurrenceID))
+		}
+	}
+
+	return result
+}
+
+// parseEventRule reads an event's RRULE. WKST is dropped, as rules start
+// their weeks on Monday.
+func parseEventRule(rrule string) (*recurrence.Rule, error) {
+	var parts []string
+	for _, part := range strings.Split(rrule, ";") {
+		if !strings.HasPrefix(strings.ToUpper(part), "WKST=") {
+			parts = append(parts, part)
+		}
+	}
+	return recurrence.Parse(strings.Join(parts, ";"))
+}
+
+func instanceID(uid string, at time.Time) string {
+	return uid + "/" + at.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,202 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+)
+
+// ICSRecurrenceHorizon is how far either side of now ImportICS expands
+// recurring events, as a rule without an end has no last instance
+const ICSRecurrenceHorizon = 365 * 24 * time.Hour
+
+// ImportStore is where ImportICS's events are saved
+type ImportStore interface {
+	GetByUserID(userID string) ([]models.CalendarEvent, error)
+	Create(event models.CalendarEvent) error
+}
+
+// ImportICS reads the events of an iCalendar (.ics) file, such as a
+// calendar app's export, for the user. Times are converted to UTC, reading
+// TZIDs through the file's VTIMEZONEs when they are not IANA zone names.
+// Recurring events become one event per instance within
+// ICSRecurrenceHorizon of now, each with its UID and start time as its
+// external ID; other events keep their UID, or get one from their summary
+// and start when they have none. Events appearing twice are returned once,
+// and VTODOs are left out; ImportICSTasks reads those.
+func ImportICS(r io.Reader, userID string) ([]models.CalendarEvent, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	events, err := parseVEvents(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	recurring := map[string]bool{}
+	for i := range events {
+		if events[i].UID == "" {
+			events[i].UID = instanceID(events[i].Summary, events[i].Start)
+		}
+		if events[i].RRule != "" {
+			recurring[events[i].UID] = true
+		}
+	}
+	var series, single []vevent
+	for _, event := range events {
+		if recurring[event.UID] {
+			series = append(series, event)
+		} else {
+			single = append(single, event)
+		}
+	}
+
+	now := time.Now()
+	imported := expandVEvents(single, userID, models.ProviderICS, time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC))
+	imported = append(imported, expandVEvents(series, userID, models.ProviderICS, now.Add(-ICSRecurrenceHorizon), now.Add(ICSRecurrenceHorizon))...)
+
+	seen := map[string]bool{}
+	result := make([]models.CalendarEvent, 0, len(imported))
+	for _, event := range imported {
+		if seen[event.ExternalID] {
+			continue
+		}
+		seen[event.ExternalID] = true
+		event.StartAt, event.EndAt = event.StartAt.UTC(), event.EndAt.UTC()
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+// ImportICSTasks reads the VTODOs of an iCalendar file as tasks, in file
+// order, with Line set to each one's position among them. SUMMARY,
+// DESCRIPTION, DUE, PRIORITY, CATEGORIES and an RRULE the task can repeat
+// by are kept. To-dos that are completed or cancelled are left out, as
+// ExportICS leaves out such tasks.
+func ImportICSTasks(r io.Reader) ([]sync.ImportedTask, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	root, err := parseICalendar(string(data))
+	if err != nil {
+		return nil, err
+	}
+	zones, err := readVTimezones(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []sync.ImportedTask
+	for _, component := range root.find("VTODO") {
+		task, open, err := readVTodo(component, zones)
+		if err != nil {
+			return nil, err
+		}
+		if open {
+			task.Line = len(tasks) + 1
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// readVTodo reads a VTODO, reporting whether it is still open
+func readVTodo(component *icalComponent, zones icalZones) (sync.ImportedTask, bool, error) {
+	task := sync.ImportedTask{Priority: 3}
+	open := true
+	for _, property := range component.Properties {
+		value := property.Value
+		var err error
+		switch property.Name {
+		case "SUMMARY":
+			task.Title = icalUnescape(value)
+		case "DESCRIPTION":
+			task.Description = icalUnescape(value)
+		case "DUE":
+			var due time.Time
+			due, _, err = zones.parseTime(value, property.Params)
+			due = due.UTC()
+			task.DueAt = &due
+		case "PRIORITY":
+			var priority int
+			priority, err = strconv.Atoi(value)
+			task.Priority = priorityFromICS(priority)
+		case "CATEGORIES":
+			for _, category := range strings.Split(value, ",") {
+				if category = strings.TrimSpace(icalUnescape(category)); category != "" {
+					task.Tags = append(task.Tags, category)
+				}
+			}
+		case "RRULE":
+			if rule, err := parseEventRule(value); err == nil {
+				ruleText := rule.String()
+				task.RecurrenceRule = &ruleText
+			}
+		case "STATUS":
+			status := strings.ToUpper(value)
+			open = status != "COMPLETED" && status != "CANCELLED"
+		case "COMPLETED":
+			open = false
+		}
+		if err != nil {
+			return sync.ImportedTask{}, false, fmt.Errorf("invalid %s in to-do %q: %w", property.Name, task.Title, err)
+		}
+	}
+	if task.Title == "" {
+		task.Title = "(No title)"
+	}
+	return task, open, nil
+}
+
+// priorityFromICS maps iCalendar's 1 (highest) to 9 (lowest) priority, 0
+// being undefined, onto this app's 1 (lowest) to 5 (highest)
+func priorityFromICS(priority int) int {
+	switch {
+	case priority == 1:
+		return 5
+	case priority >= 2 && priority <= 4:
+		return 4
+	case priority >= 6 && priority <= 8:
+		return 2
+	case priority == 9:
+		return 1
+	default:
+		return 3
+	}
+}
+
+// SaveImported creates the imported events for the user, recording each
+// in report by its 1-based position. An event whose external ID the user
+// already has, from any provider, is skipped, so importing a file twice or
+// importing events a synced calendar already has does not duplicate them.
+func SaveImported(store ImportStore, userID string, events []models.CalendarEvent, report *sync.ImportReport) error {
+	existing, err := store.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user events: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, event := range existing {
+		known[event.ExternalID] = true
+	}
+
+	for i, event := range events {
+		if known[event.ExternalID] {
+			report.Skip(i+1, event.Title, "an event with this UID already exists")
+			continue
+		}
+		if err := store.Create(event); err != nil {
+			report.Fail(i+1, event.Title, err.Error())
+			continue
+		}
+		known[event.ExternalID] = true
+		report.Import(i+1, event.Title, "")
+	}
+	return nil
+}
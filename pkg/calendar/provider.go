@@ -0,0 +1,162 @@
+// Package calendar reads users' events from external calendar providers and
+// keeps local copies of them up to date.
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// maxEventDuration mirrors the longest event models.NewCalendarEvent accepts
+const maxEventDuration = 7 * 24 * time.Hour
+
+// Provider fetches one user's events from an external calendar
+type Provider interface {
+	// FetchEvents returns the user's events overlapping [since, until)
+	FetchEvents(ctx context.Context, userID string, since, until time.Time) ([]models.CalendarEvent, error)
+}
+
+// HTTPClient sends requests to providers. *http.Client implements it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// IdentifiedProvider is implemented by providers that store all their
+// events under one provider ID. Sync removes the events such a provider no
+// longer returns.
+type IdentifiedProvider interface {
+	Provider
+	ProviderID() string
+}
+
+// EventStore is where synced events are kept
+type EventStore interface {
+	GetByProviderAndExternalID(userID, providerID, externalID string) (*models.CalendarEvent, error)
+	GetEventsByUserIDAndTimeRange(userID string, start, end time.Time) ([]models.CalendarEvent, error)
+	Create(event models.CalendarEvent) error
+	Update(event models.CalendarEvent) error
+	Delete(eventID string) error
+}
+
+// SyncResult counts what a sync did to the local events
+type SyncResult struct {
+	Created int
+	Updated int
+	Deleted int
+	// Errors lists the parts of the window a partial sync could not fetch
+	Errors []string
+}
+
+// Sync fetches the user's events in [since, until) from the provider and
+// upserts them by provider and external ID. Events already stored keep
+// their local ID and have LastSyncedAt refreshed. When the provider is an
+// IdentifiedProvider, its events in the window it no longer returns are
+// deleted.
+//
+// A provider that fetched only part of the window returns a
+// *PartialSyncError. The events it did fetch are still stored, the failures
+// are listed in the result's Errors, and nothing is deleted.
+func Sync(ctx context.Context, store EventStore, provider Provider, userID string, since, until time.Time) (*SyncResult, error) {
+	result := &SyncResult{}
+
+	events, err := provider.FetchEvents(ctx, userID, since, until)
+	var partial *PartialSyncError
+	if errors.As(err, &partial) {
+		for _, failure := range partial.Failures {
+			result.Errors = append(result.Errors, failure.Error())
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch events: %w", err)
+	}
+
+	fetched := make(map[string]bool, len(events))
+	for _, event := range events {
+		fetched[event.ExternalID] = true
+		existing, err := store.GetByProviderAndExternalID(userID, event.ProviderID, event.ExternalID)
+		if err != nil || existing == nil {
+			if err := store.Create(event); err != nil {
+				return result, fmt.Errorf("failed to store event %s: %w", event.ExternalID, err)
+			}
+			result.Created++
+			continue
+		}
+
+		event.ID = existing.ID
+		event.UpdateLastSyncedAt()
+		if err := store.Update(event); err != nil {
+			return result, fmt.Errorf("failed to update event %s: %w", event.ExternalID, err)
+		}
+		result.Updated++
+	}
+
+	identified, ok := provider.(IdentifiedProvider)
+	if !ok || partial != nil {
+		return result, nil
+	}
+	stored, err := store.GetEventsByUserIDAndTimeRange(userID, since, until)
+	if err != nil {
+		return result, fmt.Errorf("failed to get stored events: %w", err)
+	}
+	for _, event := range stored {
+		if !event.IsFromProvider(identified.ProviderID()) || fetched[event.ExternalID] {
+			continue
+		}
+		if err := store.Delete(event.ID); err != nil {
+			return result, fmt.Errorf("failed to delete event %s: %w", event.ExternalID, err)
+		}
+		result.Deleted++
+	}
+
+	return result, nil
+}
+
+// NewEvents builds the local events for one provider event. All-day events
+// longer than models.NewCalendarEvent allows are split into week-long
+// pieces; the first keeps the external ID and later ones get "#2", "#3"
+// and so on appended. Over-long timed events are skipped, returning no
+// events and no error, so one of them does not fail a whole sync.
+func NewEvents(userID, providerID, externalID, title string, startAt, endAt time.Time, allDay bool) ([]models.CalendarEvent, error) {
+	if endAt.Sub(startAt) <= maxEventDuration {
+		event, err := models.NewCalendarEvent(userID, providerID, externalID, title, startAt, endAt)
+		if err != nil {
+			return nil, err
+		}
+		event.SetAllDay(allDay)
+		return []models.CalendarEvent{*event}, nil
+	}
+	if !allDay {
+		return nil, nil
+	}
+
+	var events []models.CalendarEvent
+	for piece := 1; startAt.Before(endAt); piece++ {
+		pieceEnd := startAt.AddDate(0, 0, 7)
+		if pieceEnd.Sub(startAt) > maxEventDuration {
+			// A week that gains an hour at a DST change is one hour too long
+			pieceEnd = startAt.AddDate(0, 0, 6)
+		}
+		if pieceEnd.After(endAt) {
+			pieceEnd = endAt
+		}
+
+		id := externalID
+		if piece > 1 {
+			id = fmt.Sprintf("%s#%d", externalID, piece)
+		}
+		event, err := models.NewCalendarEvent(userID, providerID, id, title, startAt, pieceEnd)
+		if err != nil {
+			return nil, err
+		}
+		event.SetAllDay(allDay)
+		events = append(events, *event)
+
+		startAt = pieceEnd
+	}
+
+	return events, nil
+}
@@ -0,0 +1,294 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icalZones holds an iCalendar object's VTIMEZONE definitions by TZID
+type icalZones map[string]*vtimezone
+
+// parseTime reads a DATE or DATE-TIME value. A TZID naming an IANA zone is
+// read in that zone, and any other in the object's VTIMEZONE of that name,
+// as Outlook writes names such as "W. Europe Standard Time". Floating times
+// and unknown TZIDs are read as UTC.
+func (z icalZones) parseTime(value, params string) (time.Time, bool, error) {
+	tzid := ""
+	for _, param := range strings.Split(params, ";") {
+		if id, ok := strings.CutPrefix(param, "TZID="); ok {
+			tzid = strings.Trim(id, `"`)
+		}
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	layout, allDay := "20060102T150405", false
+	if len(value) == len("20060102") {
+		layout, allDay = "20060102", true
+	}
+
+	if tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			t, err := time.ParseInLocation(layout, value, loc)
+			return t, allDay, err
+		}
+	}
+	wall, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if zone := z[tzid]; zone != nil {
+		return zone.at(wall), allDay, nil
+	}
+	return wall, allDay, nil
+}
+
+// vtimezone is a VTIMEZONE: the UTC offsets a zone observes and when each
+// takes effect
+type vtimezone struct {
+	tzid        string
+	observances []tzObservance
+}
+
+// tzObservance is a STANDARD or DAYLIGHT block. It takes effect at DTSTART,
+// each RDATE and each transition of its RRULE, all wall-clock times in the
+// offset it replaces.
+type tzObservance struct {
+	offsetFrom int
+	offsetTo   int
+	start      time.Time
+	rdates     []time.Time
+	rule       *tzRule
+}
+
+// tzRule is the kind of RRULE VTIMEZONEs use: yearly on a weekday of a
+// month, such as the last Sunday of March, or on the first of that weekday
+// among some days of the month
+type tzRule struct {
+	month     time.Month
+	week      int // 1 to 5, or -1 to -5 counting back from the month's end
+	weekday   *time.Weekday
+	monthDays []int
+	until     *time.Time
+}
+
+// readVTimezones reads the VTIMEZONE components of an iCalendar object.
+// Only TZOFFSETTO is required of an observance; an RRULE that is not a
+// yearly transition is left out, keeping its DTSTART.
+func readVTimezones(root *icalComponent) (icalZones, error) {
+	zones := icalZones{}
+	for _, component := range root.find("VTIMEZONE") {
+		zone := &vtimezone{}
+		for _, property := range component.Properties {
+			if property.Name == "TZID" {
+				zone.tzid = property.Value
+			}
+		}
+
+		for _, child := range component.Children {
+			if child.Name != "STANDARD" && child.Name != "DAYLIGHT" {
+				continue
+			}
+			observance, err := readTZObservance(child)
+			if err != nil {
+				return nil, fmt.Errorf("invalid VTIMEZONE %s: %w", zone.tzid, err)
+			}
+			zone.observances = append(zone.observances, observance)
+		}
+		if zone.tzid != "" && len(zone.observances) > 0 {
+			zones[zone.tzid] = zone
+		}
+	}
+	return zones, nil
+}
+
+func readTZObservance(component *icalComponent) (tzObservance, error) {
+	var observance tzObservance
+	hasOffset := false
+	for _, property := range component.Properties {
+		var err error
+		switch property.Name {
+		case "TZOFFSETFROM":
+			observance.offsetFrom, err = parseUTCOffset(property.Value)
+		case "TZOFFSETTO":
+			observance.offsetTo, err = parseUTCOffset(property.Value)
+			hasOffset = true
+		case "DTSTART":
+			observance.start, err = time.Parse("20060102T150405", property.Value)
+		case "RDATE":
+			for _, date := range strings.Split(property.Value, ",") {
+				var at time.Time
+				if at, err = time.Parse("20060102T150405", date); err != nil {
+					break
+				}
+				observance.rdates = append(observance.rdates, at)
+			}
+		case "RRULE":
+			observance.rule, _ = parseTZRule(property.Value)
+		}
+		if err != nil {
+			return tzObservance{}, fmt.Errorf("invalid %s in %s: %w", property.Name, component.Name, err)
+		}
+	}
+	if !hasOffset {
+		return tzObservance{}, fmt.Errorf("%s has no TZOFFSETTO", component.Name)
+	}
+	return observance, nil
+}
+
+// parseUTCOffset reads an offset such as +0100, -0330 or +053000, in
+// seconds east of UTC
+func parseUTCOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("%q is not a UTC offset", value)
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a UTC offset", value)
+		}
+		seconds += n * unit
+	}
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+func parseTZRule(rrule string) (*tzRule, error) {
+	rule := &tzRule{}
+	for _, part := range strings.Split(strings.ToUpper(rrule), ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "FREQ":
+			if value != "YEARLY" {
+				return nil, fmt.Errorf("unsupported FREQ %s", value)
+			}
+		case "BYMONTH":
+			month, err := strconv.Atoi(value)
+			if err != nil || month < 1 || month > 12 {
+				return nil, fmt.Errorf("invalid BYMONTH %s", value)
+			}
+			rule.month = time.Month(month)
+		case "BYDAY":
+			code := value[max(len(value)-2, 0):]
+			day, ok := weekdayByCode[code]
+			if !ok {
+				return nil, fmt.Errorf("invalid BYDAY %s", value)
+			}
+			rule.weekday = &day
+			if week := value[:len(value)-2]; week != "" {
+				n, err := strconv.Atoi(week)
+				if err != nil || n == 0 || n < -5 || n > 5 {
+					return nil, fmt.Errorf("invalid BYDAY %s", value)
+				}
+				rule.week = n
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %s", value)
+				}
+				rule.monthDays = append(rule.monthDays, n)
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102T150405Z", value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %s", value)
+			}
+			rule.until = &until
+		}
+	}
+	if rule.month == 0 || (rule.weekday == nil && len(rule.monthDays) == 0) {
+		return nil, fmt.Errorf("unsupported time zone rule %s", rrule)
+	}
+	return rule, nil
+}
+
+var weekdayByCode = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// transitionIn returns the rule's transition date in year, at the time of
+// day of start
+func (r *tzRule) transitionIn(year int, start time.Time) (time.Time, bool) {
+	at := func(day int) time.Time {
+		return time.Date(year, r.month, day, start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+	}
+	daysInMonth := time.Date(year, r.month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	if len(r.monthDays) > 0 {
+		for _, day := range r.monthDays {
+			if day > 0 && day <= daysInMonth && (r.weekday == nil || at(day).Weekday() == *r.weekday) {
+				return at(day), true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	if r.week >= 0 {
+		first := (int(*r.weekday)-int(at(1).Weekday())+7)%7 + 1
+		day := first + 7*(max(r.week, 1)-1)
+		return at(day), day <= daysInMonth
+	}
+	last := daysInMonth - (int(at(daysInMonth).Weekday())-int(*r.weekday)+7)%7
+	day := last + 7*(r.week+1)
+	return at(day), day >= 1
+}
+
+// latestOnset returns when the observance last took effect at or before
+// the wall-clock time wall
+func (o tzObservance) latestOnset(wall time.Time) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	consider := func(onset time.Time) {
+		if !onset.Before(o.start) && !onset.After(wall) && (!found || onset.After(latest)) {
+			latest, found = onset, true
+		}
+	}
+
+	consider(o.start)
+	for _, rdate := range o.rdates {
+		consider(rdate)
+	}
+	if o.rule != nil {
+		for year := wall.Year() - 1; year <= wall.Year(); year++ {
+			onset, ok := o.rule.transitionIn(year, o.start)
+			// UNTIL is in UTC; the onset is in the offset it replaces
+			if ok && (o.rule.until == nil || !onset.Add(-time.Duration(o.offsetFrom)*time.Second).After(*o.rule.until)) {
+				consider(onset)
+			}
+		}
+	}
+	return latest, found
+}
+
+// at returns the time in the zone with the wall-clock fields of wall,
+// using the offset of the observance that most recently took effect. Before
+// any has, the earliest observance's TZOFFSETFROM applies.
+func (z *vtimezone) at(wall time.Time) time.Time {
+	offset := 0
+	var latest, earliest time.Time
+	found := false
+	for i, observance := range z.observances {
+		if onset, ok := observance.latestOnset(wall); ok && (!found || onset.After(latest)) {
+			latest, found, offset = onset, true, observance.offsetTo
+		}
+		if !found && (i == 0 || observance.start.Before(earliest)) {
+			earliest, offset = observance.start, observance.offsetFrom
+		}
+	}
+
+	zone := time.FixedZone(z.tzid, offset)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, zone)
+}
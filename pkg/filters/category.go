@@ -0,0 +1,89 @@
+package filters
+
+import (
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// EnableCategoryPreference lets ScoreTasks favour tasks at a location of the
+// same category as where the context is, by FilterConfig.CategoryPreference
+func (e *Engine) EnableCategoryPreference(locations LocationRepository, taskLocations TaskLocationRepository) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.categoryLocations = locations
+	e.categoryTaskLocations = taskLocations
+}
+
+// currentCategory returns the category of the place the context is at: its
+// current location, else a saved location whose radius holds its
+// coordinates, else the kind of place its social context suggests. A stale
+// context's location is not trusted. Other says nothing about the place, so
+// it is never preferred. Callers hold e.mu.
+func (e *Engine) currentCategory(ctx models.Context) (models.LocationCategory, bool) {
+	if !ctx.IsStale {
+		if location := e.currentLocation(ctx); location != nil {
+			if location.Category != "" && location.Category != models.LocationCategoryOther {
+				return location.Category, true
+			}
+		}
+	}
+	return models.CategoryForSocialContext(ctx.SocialContext)
+}
+
+func (e *Engine) currentLocation(ctx models.Context) *models.Location {
+	if ctx.CurrentLocationID != nil {
+		if location, err := e.categoryLocations.GetByID(*ctx.CurrentLocationID); err == nil {
+			return location
+		}
+	}
+	if ctx.CurrentLatitude == nil || ctx.CurrentLongitude == nil {
+		return nil
+	}
+
+	locations, err := e.categoryLocations.GetByUserID(ctx.UserID)
+	if err != nil {
+		return nil
+	}
+	var nearest *models.Location
+	nearestDistance := 0.0
+	for i := range locations {
+		location := &locations[i]
+		distance := location.DistanceFrom(*ctx.CurrentLatitude, *ctx.CurrentLongitude)
+		if distance > float64(location.Radius) {
+			continue
+		}
+		if nearest == nil || distance < nearestDistance {
+			nearest, nearestDistance = location, distance
+		}
+	}
+	return nearest
+}
+
+// categoryBonus returns a function giving the CategoryPreference bonus for
+// a task with a location in the context's category, or nil when there is
+// nothing to prefer. Callers hold e.mu.
+func (e *Engine) categoryBonus(ctx models.Context, tasks []models.Task) func(task models.Task) float64 {
+	if e.config.CategoryPreference == 0 || e.categoryLocations == nil || e.categoryTaskLocations == nil {
+		return nil
+	}
+	category, ok := e.currentCategory(ctx)
+	if !ok {
+		return nil
+	}
+
+	taskLocations, err := preloadTaskLocations(e.categoryTaskLocations, tasks)
+	if err != nil {
+		taskLocations = e.categoryTaskLocations
+	}
+	return func(task models.Task) float64 {
+		locations, err := taskLocations.GetLocationsByTaskID(task.ID)
+		if err != nil {
+			return 0
+		}
+		for _, location := range locations {
+			if location.Category == category {
+				return e.config.CategoryPreference
+			}
+		}
+		return 0
+	}
+}
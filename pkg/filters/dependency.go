@@ -23,6 +23,20 @@ type TaskRepository interface {
 	GetByStatus(userID string, status models.TaskStatus) ([]models.Task, error)
 }
 
+// TaskDependencyBatchRepository is a TaskDependencyRepository that can look
+// up the dependencies of many tasks in one query, keyed by task ID
+type TaskDependencyBatchRepository interface {
+	TaskDependencyRepository
+	GetDependenciesByTaskIDs(taskIDs []string) (map[string][]models.TaskDependency, error)
+}
+
+// TaskBatchRepository is a TaskRepository that can look up many tasks in
+// one query. Tasks that do not exist are left out.
+type TaskBatchRepository interface {
+	TaskRepository
+	GetByIDs(taskIDs []string) ([]models.Task, error)
+}
+
 func NewDependencyFilter(config FilterConfig, dependencyRepo TaskDependencyRepository, taskRepo TaskRepository) *DependencyFilter {
 	return &DependencyFilter{
 		config:         config,
@@ -40,22 +54,27 @@ func (f *DependencyFilter) Priority() int {
 }
 
 func (f *DependencyFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *DependencyFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
 	if !f.config.EnableDependencyFilter {
-		return true, "dependency filtering disabled"
+		return true, ReasonFilterDisabled, "dependency filtering disabled"
 	}
 
 	dependencies, err := f.dependencyRepo.GetDependenciesByTaskID(task.ID)
 	if err != nil {
-		return false, fmt.Sprintf("error checking dependencies: %v", err)
+		return false, ReasonFilterError, fmt.Sprintf("error checking dependencies: %v", err)
 	}
 
 	if len(dependencies) == 0 {
-		return true, "no dependencies"
+		return true, ReasonDepNone, "no dependencies"
 	}
 
 	hasCircularDep, circularReason := f.checkCircularDependencies(task.ID, make(map[string]bool))
 	if hasCircularDep {
-		return false, fmt.Sprintf("circular dependency detected: %s", circularReason)
+		return false, ReasonDepCircular, fmt.Sprintf("circular dependency detected: %s", circularReason)
 	}
 
 	unmetDependencies := []string{}
@@ -72,10 +91,82 @@ func (f *DependencyFilter) Apply(ctx models.Context, task models.Task) (visible
 	}
 
 	if len(unmetDependencies) > 0 {
-		return false, fmt.Sprintf("unmet dependencies: %s", strings.Join(unmetDependencies, ", "))
+		return false, ReasonDepPending, fmt.Sprintf("unmet dependencies: %s", strings.Join(unmetDependencies, ", "))
+	}
+
+	return true, ReasonDepMet, fmt.Sprintf("all %d dependencies met", len(dependencies))
+}
+
+// Preload fetches the dependencies of the tasks, and of the tasks they
+// depend on, one query per level when the repository is a
+// TaskDependencyBatchRepository, then every task depended on in one query
+// when the task repository is a TaskBatchRepository
+func (f *DependencyFilter) Preload(ctx models.Context, tasks []models.Task) (FilterRule, error) {
+	if !f.config.EnableDependencyFilter {
+		return f, nil
+	}
+	batch, ok := f.dependencyRepo.(TaskDependencyBatchRepository)
+	if !ok {
+		return f, nil
+	}
+
+	preloaded := *f
+	dependencies := &preloadedDependencies{
+		TaskDependencyRepository: f.dependencyRepo,
+		loaded:                   make(map[string]bool),
+		dependencies:             make(map[string][]models.TaskDependency),
+	}
+	pending := taskIDs(tasks)
+	queued := make(map[string]bool, len(pending))
+	for _, id := range pending {
+		queued[id] = true
+	}
+	var dependedOn []string
+	seen := make(map[string]bool)
+	for len(pending) > 0 {
+		found, err := batch.GetDependenciesByTaskIDs(pending)
+		if err != nil {
+			return nil, fmt.Errorf("error preloading dependencies: %w", err)
+		}
+
+		var next []string
+		for _, id := range pending {
+			dependencies.loaded[id] = true
+			dependencies.dependencies[id] = found[id]
+			for _, dep := range found[id] {
+				if !seen[dep.DependsOnTaskID] {
+					seen[dep.DependsOnTaskID] = true
+					dependedOn = append(dependedOn, dep.DependsOnTaskID)
+				}
+				if !queued[dep.DependsOnTaskID] {
+					queued[dep.DependsOnTaskID] = true
+					next = append(next, dep.DependsOnTaskID)
+				}
+			}
+		}
+		pending = next
 	}
+	preloaded.dependencyRepo = dependencies
 
-	return true, fmt.Sprintf("all %d dependencies met", len(dependencies))
+	if taskBatch, ok := f.taskRepo.(TaskBatchRepository); ok && len(dependedOn) > 0 {
+		found, err := taskBatch.GetByIDs(dependedOn)
+		if err != nil {
+			return nil, fmt.Errorf("error preloading dependency tasks: %w", err)
+		}
+		cached := &preloadedTasks{
+			TaskRepository: f.taskRepo,
+			loaded:         make(map[string]bool, len(dependedOn)),
+			tasks:          make(map[string]models.Task, len(found)),
+		}
+		for _, id := range dependedOn {
+			cached.loaded[id] = true
+		}
+		for _, task := range found {
+			cached.tasks[task.ID] = task
+		}
+		preloaded.taskRepo = cached
+	}
+	return &preloaded, nil
 }
 
 func (f *DependencyFilter) isDependencyMet(dep models.TaskDependency, dependentTask models.Task) bool {
@@ -87,6 +178,8 @@ func (f *DependencyFilter) isDependencyMet(dep models.TaskDependency, dependentT
 			   dependentTask.Status == models.TaskStatusCompleted
 	case models.DependencyTypeScheduled:
 		return dependentTask.Status == models.TaskStatusCompleted
+	case models.DependencyTypeSuggested:
+		return true
 	default:
 		return false
 	}
@@ -0,0 +1,80 @@
+package filters
+
+import (
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ContextDiff reports how task visibility changes between two contexts
+type ContextDiff struct {
+	Appeared    []TaskVisibilityChange `json:"appeared"`
+	Disappeared []TaskVisibilityChange `json:"disappeared"`
+	Unchanged   []models.Task          `json:"unchanged"`
+}
+
+// TaskVisibilityChange is a task whose visibility differs between two
+// contexts. Reasons holds the modified context's results for the rules
+// whose verdict changed.
+type TaskVisibilityChange struct {
+	Task    models.Task    `json:"task"`
+	Reasons []FilterResult `json:"reasons"`
+}
+
+// DiffContexts filters tasks against base and modified and reports which
+// tasks appear, disappear or stay visible. It is a dry run: nothing is
+// written to the audit log. Every rule is evaluated even when the engine
+// short-circuits, so each changed verdict is reported.
+func (e *Engine) DiffContexts(base, modified models.Context, tasks []models.Task) ContextDiff {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	base, modified = e.withAge(base), e.withAge(modified)
+	baseVisible, baseResults := e.filterTasks(base, tasks, false)
+	modifiedVisible, modifiedResults := e.filterTasks(modified, tasks, false)
+
+	wasVisible := visibleSet(baseVisible)
+	isVisible := visibleSet(modifiedVisible)
+
+	// Verdict of each rule per task in the base context
+	baseVerdicts := make(map[string]map[string]bool)
+	for _, result := range baseResults {
+		if baseVerdicts[result.TaskID] == nil {
+			baseVerdicts[result.TaskID] = make(map[string]bool)
+		}
+		baseVerdicts[result.TaskID][result.FilterName] = result.Visible
+	}
+
+	changedReasons := make(map[string][]FilterResult)
+	for _, result := range modifiedResults {
+		if baseVerdicts[result.TaskID][result.FilterName] != result.Visible {
+			changedReasons[result.TaskID] = append(changedReasons[result.TaskID], result)
+		}
+	}
+
+	diff := ContextDiff{
+		Appeared:    []TaskVisibilityChange{},
+		Disappeared: []TaskVisibilityChange{},
+		Unchanged:   []models.Task{},
+	}
+
+	for _, task := range tasks {
+		before, after := wasVisible[task.ID], isVisible[task.ID]
+		switch {
+		case before && after:
+			diff.Unchanged = append(diff.Unchanged, task)
+		case before:
+			diff.Disappeared = append(diff.Disappeared, TaskVisibilityChange{Task: task, Reasons: changedReasons[task.ID]})
+		case after:
+			diff.Appeared = append(diff.Appeared, TaskVisibilityChange{Task: task, Reasons: changedReasons[task.ID]})
+		}
+	}
+
+	return diff
+}
+
+func visibleSet(tasks []models.Task) map[string]bool {
+	set := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		set[task.ID] = true
+	}
+	return set
+}
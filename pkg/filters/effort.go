@@ -0,0 +1,90 @@
+package filters
+
+import (
+	"sort"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// EstimateUnit selects how the filters read a task's size
+type EstimateUnit string
+
+const (
+	// EstimateUnitMinutes uses each task's estimated minutes
+	EstimateUnitMinutes EstimateUnit = "minutes"
+	// EstimateUnitPoints converts each task's effort points to minutes with
+	// the configured conversion table, falling back to estimated minutes for
+	// tasks without points
+	EstimateUnitPoints EstimateUnit = "points"
+)
+
+// DefaultPointsToMinutes maps the usual Fibonacci story-point scale to
+// minutes of work
+var DefaultPointsToMinutes = map[int]int{
+	1:  15,
+	2:  30,
+	3:  60,
+	5:  120,
+	8:  240,
+	13: 480,
+}
+
+// IsValidEstimateUnit reports whether unit is a known estimate unit. The
+// empty unit is treated as minutes.
+func IsValidEstimateUnit(unit EstimateUnit) bool {
+	switch unit {
+	case "", EstimateUnitMinutes, EstimateUnitPoints:
+		return true
+	default:
+		return false
+	}
+}
+
+// UsesPoints reports whether tasks are sized in effort points
+func (c FilterConfig) UsesPoints() bool {
+	return c.EstimateUnit == EstimateUnitPoints
+}
+
+// PointsAsMinutes converts effort points to minutes. Points between two
+// entries of the conversion table round up to the larger entry; points past
+// the largest entry scale linearly from it.
+func (c FilterConfig) PointsAsMinutes(points int) int {
+	table := c.PointsToMinutes
+	if len(table) == 0 {
+		table = DefaultPointsToMinutes
+	}
+
+	if minutes, ok := table[points]; ok {
+		return minutes
+	}
+
+	keys := make([]int, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		if key > points {
+			return table[key]
+		}
+	}
+
+	largest := keys[len(keys)-1]
+	if largest <= 0 {
+		return table[largest]
+	}
+	return table[largest] * points / largest
+}
+
+// EstimatedMinutes returns how many minutes a task is expected to take in
+// the configured estimate unit, and false when the task has no estimate
+func (c FilterConfig) EstimatedMinutes(task models.Task) (int, bool) {
+	if c.UsesPoints() && task.EffortPoints != nil {
+		return c.PointsAsMinutes(*task.EffortPoints), true
+	}
+	if task.EstimatedMinutes != nil {
+		return *task.EstimatedMinutes, true
+	}
+	return 0, false
+}
@@ -0,0 +1,58 @@
+package filters
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// EnergyFilter hides tasks whose RequiredEnergyLevel is above the energy
+// the user reported. FilterConfig.EnergyTolerance lets through tasks that
+// need up to that many levels more; the priority filter scores them lower
+// for the shortfall. Tasks without a required energy level always pass.
+type EnergyFilter struct {
+	config FilterConfig
+}
+
+func NewEnergyFilter(config FilterConfig) *EnergyFilter {
+	return &EnergyFilter{config: config}
+}
+
+func (f *EnergyFilter) Name() string {
+	return "energy"
+}
+
+func (f *EnergyFilter) Priority() int {
+	return 97
+}
+
+func (f *EnergyFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *EnergyFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
+	if !f.config.EnableEnergyFilter {
+		return true, ReasonFilterDisabled, "energy filtering disabled"
+	}
+
+	if task.RequiredEnergyLevel == nil {
+		return true, ReasonEnergyNoRequirement, "task has no energy requirement"
+	}
+	required := *task.RequiredEnergyLevel
+
+	if ctx.EnergyLevel < 1 || ctx.EnergyLevel > 5 {
+		return true, ReasonEnergyUnknown, "current energy level unknown - showing task"
+	}
+
+	if required <= ctx.EnergyLevel {
+		return true, ReasonEnergySufficient, fmt.Sprintf("needs energy %d, you are at %d", required, ctx.EnergyLevel)
+	}
+
+	if required <= ctx.EnergyLevel+f.config.EnergyTolerance {
+		return true, ReasonEnergyWithinTolerance, fmt.Sprintf("needs energy %d, you are at %d (within tolerance of %d, ranked lower)",
+			required, ctx.EnergyLevel, f.config.EnergyTolerance)
+	}
+
+	return false, ReasonEnergyInsufficient, fmt.Sprintf("needs energy %d, you are at %d", required, ctx.EnergyLevel)
+}
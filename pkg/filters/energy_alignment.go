@@ -0,0 +1,62 @@
+package filters
+
+import (
+	"fmt"
+)
+
+// EnergyAlignment is how the priority filter treats tasks at one energy
+// level. Tasks below MinPriority are hidden outright; the rest have Modifier,
+// plus any PriorityModifiers entry for their priority, added to their score.
+type EnergyAlignment struct {
+	MinPriority       int             `json:"min_priority,omitempty" yaml:"min_priority,omitempty"`
+	Modifier          float64         `json:"modifier,omitempty" yaml:"modifier,omitempty"`
+	PriorityModifiers map[int]float64 `json:"priority_modifiers,omitempty" yaml:"priority_modifiers,omitempty"`
+}
+
+// EnergyAlignmentCurve maps energy levels (1-5) to an EnergyAlignment.
+// Levels without an entry use only the built-in blend of energy and
+// priority, so a nil curve is the default behavior. For example, to show
+// only priority 4-5 at energy 1 and nudge everything up at energy 5:
+//
+//	EnergyAlignmentCurve{
+//		1: {MinPriority: 4},
+//		5: {Modifier: 0.05},
+//	}
+type EnergyAlignmentCurve map[int]EnergyAlignment
+
+// At returns the alignment for an energy level, or the zero alignment when
+// the curve has no entry for it
+func (c EnergyAlignmentCurve) At(energyLevel int) EnergyAlignment {
+	return c[energyLevel]
+}
+
+// Hides reports whether a task of the given priority is below the
+// alignment's minimum
+func (a EnergyAlignment) Hides(priority int) bool {
+	return a.MinPriority > 0 && priority < a.MinPriority
+}
+
+// ScoreModifier returns the amount added to the score of a task of the
+// given priority
+func (a EnergyAlignment) ScoreModifier(priority int) float64 {
+	return a.Modifier + a.PriorityModifiers[priority]
+}
+
+// Validate checks that the curve covers only real energy levels and
+// priorities
+func (c EnergyAlignmentCurve) Validate() error {
+	for energy, alignment := range c {
+		if energy < 1 || energy > 5 {
+			return fmt.Errorf("invalid energy level in alignment curve: %d (must be 1-5)", energy)
+		}
+		if alignment.MinPriority < 0 || alignment.MinPriority > 5 {
+			return fmt.Errorf("invalid minimum priority %d at energy %d (must be 0-5)", alignment.MinPriority, energy)
+		}
+		for priority := range alignment.PriorityModifiers {
+			if priority < 1 || priority > 5 {
+				return fmt.Errorf("invalid priority %d at energy %d (must be 1-5)", priority, energy)
+			}
+		}
+	}
+	return nil
+}
@@ -7,14 +7,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bcnelson/hereAndNow/pkg/clock"
 	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
 )
 
 type Engine struct {
 	rules       []FilterRule
 	auditRepo   FilterAuditRepository
 	config      FilterConfig
+	clock       clock.Clock
 	mu          sync.RWMutex
+
+	// Set by EnableCategoryPreference
+	categoryLocations     LocationRepository
+	categoryTaskLocations TaskLocationRepository
 }
 
 type FilterAuditRepository interface {
@@ -23,11 +30,59 @@ type FilterAuditRepository interface {
 	GetAuditLogByUserID(userID string, since time.Time, limit int) ([]models.FilterAudit, error)
 }
 
+// FilterAuditBatchRepository is a FilterAuditRepository that saves many
+// results in one transaction. The engine audits each FilterTasks call with
+// a single SaveFilterResults when the repository has it.
+type FilterAuditBatchRepository interface {
+	FilterAuditRepository
+	SaveFilterResults(audits []models.FilterAudit) error
+}
+
 func NewEngine(config FilterConfig, auditRepo FilterAuditRepository) *Engine {
-	return &Engine{
+	engine := &Engine{
 		rules:     []FilterRule{},
 		auditRepo: auditRepo,
 		config:    config,
+		clock:     clock.Real(),
+	}
+	engine.syncBuiltinRules()
+	return engine
+}
+
+// SetClock replaces the clock contexts are aged against, for tests
+func (e *Engine) SetClock(c clock.Clock) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clock = c
+}
+
+// withAge marks how old ctx is now, so the rules can tell when it is past
+// the configured ContextMaxAge. Callers hold e.mu.
+func (e *Engine) withAge(ctx models.Context) models.Context {
+	ctx.MarkAge(e.clock.Now(), e.config.ContextMaxAge)
+	return ctx
+}
+
+// syncBuiltinRules adds the built-in WeatherFilter, SocialContextFilter and
+// EnergyFilter while the config enables them and removes them otherwise. These filters
+// need no repositories, so unlike the other filters they are managed by the
+// engine. Callers hold e.mu or own the engine exclusively.
+func (e *Engine) syncBuiltinRules() {
+	e.syncRule(NewWeatherFilter(e.config), e.config.EnableWeatherFilter)
+	e.syncRule(NewSocialContextFilter(e.config), e.config.EnableSocialFilter)
+	e.syncRule(NewEnergyFilter(e.config), e.config.EnableEnergyFilter)
+}
+
+func (e *Engine) syncRule(builtin FilterRule, enabled bool) {
+	for i, rule := range e.rules {
+		if rule.Name() == builtin.Name() {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+			break
+		}
+	}
+	if enabled {
+		e.rules = append(e.rules, builtin)
+		e.sortRulesByPriority()
 	}
 }
 
@@ -62,11 +117,27 @@ func (e *Engine) FilterTasks(ctx models.Context, tasks []models.Task) ([]models.
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	
+	ctx = e.withAge(ctx)
+	visibleTasks, allResults := e.filterTasks(ctx, tasks, e.config.ShortCircuit)
+	
+	e.auditFilterResults(ctx, allResults)
+	
+	return visibleTasks, allResults
+}
+
+// filterTasks evaluates the rules against every task without auditing,
+// preloading what the rules need for the whole batch first. With
+// shortCircuit each task stops at the first rule that hides it.
+func (e *Engine) filterTasks(ctx models.Context, tasks []models.Task, shortCircuit bool) ([]models.Task, []FilterResult) {
 	visibleTasks := []models.Task{}
 	allResults := []FilterResult{}
+	rules := e.preloadRules(ctx, tasks)
+	if shortCircuit {
+		rules = rulesByCost(rules)
+	}
 	
 	for _, task := range tasks {
-		visible, results := e.evaluateTask(ctx, task)
+		visible, results := e.evaluateTask(rules, ctx, task, shortCircuit)
 		allResults = append(allResults, results...)
 		
 		if visible {
@@ -74,34 +145,51 @@ func (e *Engine) FilterTasks(ctx models.Context, tasks []models.Task) ([]models.
 		}
 	}
 	
-	e.auditFilterResults(ctx, allResults)
-	
 	return visibleTasks, allResults
 }
 
-func (e *Engine) evaluateTask(ctx models.Context, task models.Task) (bool, []FilterResult) {
+// evaluateTask runs rules against task in order. With shortCircuit it
+// stops at the first rule that hides the task, whose result is marked
+// Blocking, and the rules after it have no result.
+func (e *Engine) evaluateTask(rules []FilterRule, ctx models.Context, task models.Task, shortCircuit bool) (bool, []FilterResult) {
 	results := []FilterResult{}
 	overallVisible := true
 	
-	for _, rule := range e.rules {
-		visible, reason := rule.Apply(ctx, task)
+	for _, rule := range rules {
+		visible, code, reason := e.applyRule(rule, ctx, task)
 		
 		result := FilterResult{
 			TaskID:     task.ID,
 			Visible:    visible,
+			Code:       code,
 			Reason:     reason,
 			FilterName: rule.Name(),
 		}
-		results = append(results, result)
 		
 		if !visible {
 			overallVisible = false
+			if shortCircuit {
+				result.Blocking = true
+				results = append(results, result)
+				break
+			}
 		}
+		results = append(results, result)
 	}
 	
 	return overallVisible, results
 }
 
+// rulesByCost returns rules in ascending Priority(), which short-circuit
+// evaluation reads as each rule's cost, keeping the order of equal rules
+func rulesByCost(rules []FilterRule) []FilterRule {
+	sorted := append([]FilterRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority() < sorted[j].Priority()
+	})
+	return sorted
+}
+
 func (e *Engine) GetAuditLog(taskID string, ctx models.Context) ([]FilterResult, error) {
 	audits, err := e.auditRepo.GetAuditLogByTaskID(taskID, 50)
 	if err != nil {
@@ -123,10 +211,12 @@ func (e *Engine) GetAuditLog(taskID string, ctx models.Context) ([]FilterResult,
 }
 
 func (e *Engine) auditFilterResults(ctx models.Context, results []FilterResult) {
+	audits := make([]models.FilterAudit, 0, len(results))
 	for _, result := range results {
 		reason := models.FilterReason{
 			Rule:    result.FilterName,
 			Passed:  result.Visible,
+			Code:    string(result.Code),
 			Details: result.Reason,
 		}
 		reasonJSON, _ := json.Marshal([]models.FilterReason{reason})
@@ -135,13 +225,23 @@ func (e *Engine) auditFilterResults(ctx models.Context, results []FilterResult)
 			ID:            generateAuditID(),
 			TaskID:        result.TaskID,
 			UserID:        ctx.UserID,
-			ContextID:     "",
+			ContextID:     ctx.ID,
 			IsVisible:     result.Visible,
 			Reasons:       reasonJSON,
 			PriorityScore: 0.0,
 			CreatedAt:     ctx.Timestamp,
 		}
-		
+		audits = append(audits, audit)
+	}
+	if len(audits) == 0 {
+		return
+	}
+	
+	if batch, ok := e.auditRepo.(FilterAuditBatchRepository); ok {
+		_ = batch.SaveFilterResults(audits)
+		return
+	}
+	for _, audit := range audits {
 		if err := e.auditRepo.SaveFilterResult(audit); err != nil {
 			continue
 		}
@@ -161,7 +261,12 @@ func (e *Engine) GetFilterStats(ctx models.Context, tasks []models.Task) FilterS
 		FilterResults: make(map[string]FilterRuleStats),
 	}
 	
-	for _, rule := range e.rules {
+	e.mu.RLock()
+	ctx = e.withAge(ctx)
+	rules := e.preloadRules(ctx, tasks)
+	e.mu.RUnlock()
+
+	for _, rule := range rules {
 		ruleStats := FilterRuleStats{
 			Name:         rule.Name(),
 			TasksVisible: 0,
@@ -207,7 +312,7 @@ func (e *Engine) ApplySingleFilter(filterName string, ctx models.Context, task m
 	
 	for _, rule := range e.rules {
 		if rule.Name() == filterName {
-			visible, reason := rule.Apply(ctx, task)
+			visible, reason := rule.Apply(e.withAge(ctx), task)
 			return visible, reason, nil
 		}
 	}
@@ -248,6 +353,15 @@ func (e *Engine) DisableFilter(filterName string) error {
 		e.config.EnableDependencyFilter = false
 	case "priority":
 		e.config.EnablePriorityFilter = false
+	case "weather":
+		e.config.EnableWeatherFilter = false
+		e.syncBuiltinRules()
+	case "social":
+		e.config.EnableSocialFilter = false
+		e.syncBuiltinRules()
+	case "energy":
+		e.config.EnableEnergyFilter = false
+		e.syncBuiltinRules()
 	default:
 		return fmt.Errorf("unknown filter: %s", filterName)
 	}
@@ -268,6 +382,15 @@ func (e *Engine) EnableFilter(filterName string) error {
 		e.config.EnableDependencyFilter = true
 	case "priority":
 		e.config.EnablePriorityFilter = true
+	case "weather":
+		e.config.EnableWeatherFilter = true
+		e.syncBuiltinRules()
+	case "social":
+		e.config.EnableSocialFilter = true
+		e.syncBuiltinRules()
+	case "energy":
+		e.config.EnableEnergyFilter = true
+		e.syncBuiltinRules()
 	default:
 		return fmt.Errorf("unknown filter: %s", filterName)
 	}
@@ -285,32 +408,58 @@ func (e *Engine) UpdateConfig(config FilterConfig) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.config = config
+	e.syncBuiltinRules()
 }
 
+// generateAuditID returns a unique audit ID; a batch of audits is saved
+// within the same instant, so a timestamp alone would collide
 func generateAuditID() string {
-	return fmt.Sprintf("audit_%d", time.Now().UnixNano())
+	return "audit_" + uuid.New().String()
 }
 
+// ExplainTaskVisibility runs every rule against a single task and reports
+// each rule's verdict along with the context it was judged in. Unlike
+// FilterTasks it saves no audit record, and it never short-circuits.
 func (e *Engine) ExplainTaskVisibility(ctx models.Context, task models.Task) TaskVisibilityExplanation {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	
+	ctx = e.withAge(ctx)
 	explanation := TaskVisibilityExplanation{
 		TaskID:      task.ID,
 		TaskTitle:   task.Title,
 		IsVisible:   true,
 		FilterResults: []FilterExplanation{},
+		Context: ExplainedContext{
+			ContextID:        ctx.ID,
+			Timestamp:        ctx.Timestamp,
+			LocationID:       ctx.CurrentLocationID,
+			Latitude:         ctx.CurrentLatitude,
+			Longitude:        ctx.CurrentLongitude,
+			AvailableMinutes: ctx.AvailableMinutes,
+			EnergyLevel:      ctx.EnergyLevel,
+			SocialContext:    ctx.SocialContext,
+			AgeSeconds:       ctx.AgeSeconds,
+			IsStale:          ctx.IsStale,
+		},
 	}
 	
 	for _, rule := range e.rules {
-		visible, reason := rule.Apply(ctx, task)
+		visible, code, reason := e.applyRule(rule, ctx, task)
 		
 		filterExpl := FilterExplanation{
 			FilterName: rule.Name(),
 			Passed:     visible,
+			Code:       code,
 			Reason:     reason,
 			Priority:   rule.Priority(),
 		}
+		if visible && ctx.IsStale {
+			trusted := ctx
+			trusted.IsStale = false
+			trustedVisible, _, _ := e.applyRule(rule, trusted, task)
+			filterExpl.StaleOverride = !trustedVisible
+		}
 		
 		explanation.FilterResults = append(explanation.FilterResults, filterExpl)
 		
@@ -1,6 +1,8 @@
 package filters
 
 import (
+	"time"
+
 	"github.com/bcnelson/hereAndNow/pkg/models"
 )
 
@@ -10,11 +12,24 @@ type FilterRule interface {
 	Priority() int
 }
 
+// PreloadingFilterRule is a FilterRule that can fetch what it needs for a
+// whole batch of tasks at once instead of querying per task. The engine
+// calls Preload before evaluating a batch and uses the rule it returns in
+// its place for that call only; when Preload fails the rule itself is used.
+type PreloadingFilterRule interface {
+	FilterRule
+	Preload(ctx models.Context, tasks []models.Task) (FilterRule, error)
+}
+
 type FilterResult struct {
 	TaskID   string `json:"task_id"`
 	Visible  bool   `json:"visible"`
+	Code     ReasonCode `json:"code,omitempty"`
 	Reason   string `json:"reason"`
 	FilterName string `json:"filter_name"`
+	// Blocking marks the result that hid the task and stopped evaluation
+	// when FilterConfig.ShortCircuit is set
+	Blocking bool `json:"blocking,omitempty"`
 }
 
 type FilterEngine interface {
@@ -23,6 +38,8 @@ type FilterEngine interface {
 	FilterTasks(ctx models.Context, tasks []models.Task) ([]models.Task, []FilterResult)
 	GetAuditLog(taskID string, ctx models.Context) ([]FilterResult, error)
 	ExplainTaskVisibility(ctx models.Context, task models.Task) TaskVisibilityExplanation
+	DiffContexts(base, modified models.Context, tasks []models.Task) ContextDiff
+	ScoreTasks(ctx models.Context, tasks []models.Task) ([]ScoredTask, error)
 }
 
 type FilterConfig struct {
@@ -30,9 +47,26 @@ type FilterConfig struct {
 	EnableTimeFilter      bool    `json:"enable_time_filter"`
 	EnableDependencyFilter bool    `json:"enable_dependency_filter"`
 	EnablePriorityFilter  bool    `json:"enable_priority_filter"`
+	EnableWeatherFilter   bool    `json:"enable_weather_filter"` // Off by default; the engine adds a WeatherFilter when set
+	EnableSocialFilter    bool    `json:"enable_social_filter"`  // Off by default; the engine adds a SocialContextFilter when set
+	EnableEnergyFilter    bool    `json:"enable_energy_filter"`  // Off by default; the engine adds an EnergyFilter when set
+	EnergyTolerance       int     `json:"energy_tolerance"`      // Levels a task's required energy may exceed the user's and still show
+	WeatherHideConditions []string `json:"weather_hide_conditions,omitempty"` // Weather that hides outdoor and dry tasks; DefaultWeatherHideConditions when empty
 	MaxDistanceMeters     float64 `json:"max_distance_meters"`
+	LocationGraceMeters   float64 `json:"location_grace_meters"` // Tasks this far beyond a location's radius stay visible with a warning
 	MinEnergyLevel        int     `json:"min_energy_level"`
 	DefaultPriorityWeight float64 `json:"default_priority_weight"`
+	EstimateUnit          EstimateUnit `json:"estimate_unit"`
+	PointsToMinutes       map[int]int  `json:"points_to_minutes"` // Points mode conversion table; DefaultPointsToMinutes when empty
+	ReasonVerbosity       ReasonVerbosity `json:"reason_verbosity"`  // Full when empty
+	EnergyAlignment       EnergyAlignmentCurve `json:"energy_alignment,omitempty"` // Per-energy priority floors and score modifiers; none when nil
+	ContextMaxAge         time.Duration `json:"context_max_age"` // Older contexts have their location and available time ignored; never when zero
+	TravelMode            TravelMode `json:"travel_mode,omitempty"` // How the user reaches task locations; travel time is not counted when empty
+	WalkingSpeedKmh       float64 `json:"walking_speed_kmh,omitempty"` // DefaultWalkingSpeedKmh when zero
+	DrivingSpeedKmh       float64 `json:"driving_speed_kmh,omitempty"` // Speed in light traffic; DefaultDrivingSpeedKmh when zero
+	TrafficSpeedFactors   map[string]float64 `json:"traffic_speed_factors,omitempty"` // Driving speed multipliers by traffic level; DefaultTrafficSpeedFactors when empty
+	ShortCircuit          bool `json:"short_circuit"` // Run rules cheapest first by Priority() and stop at the first that hides a task
+	CategoryPreference    float64 `json:"category_preference,omitempty"` // Score ScoreTasks adds to tasks at a location in the category of where the context is; none when zero
 }
 
 type TaskVisibilityExplanation struct {
@@ -40,13 +74,33 @@ type TaskVisibilityExplanation struct {
 	TaskTitle     string              `json:"task_title"`
 	IsVisible     bool                `json:"is_visible"`
 	FilterResults []FilterExplanation `json:"filter_results"`
+	Context       ExplainedContext    `json:"context"`
+}
+
+// ExplainedContext is the context snapshot an explanation was evaluated
+// against, so a stale location or energy level shows up beside the results
+type ExplainedContext struct {
+	ContextID        string    `json:"context_id"`
+	Timestamp        time.Time `json:"timestamp"`
+	LocationID       *string   `json:"location_id"`
+	Latitude         *float64  `json:"latitude"`
+	Longitude        *float64  `json:"longitude"`
+	AvailableMinutes int       `json:"available_minutes"`
+	EnergyLevel      int       `json:"energy_level"`
+	SocialContext    string    `json:"social_context"`
+	AgeSeconds       int       `json:"age_seconds"`
+	IsStale          bool      `json:"is_stale"`
 }
 
 type FilterExplanation struct {
 	FilterName string `json:"filter_name"`
 	Passed     bool   `json:"passed"`
+	Code       ReasonCode `json:"code,omitempty"`
 	Reason     string `json:"reason"`
 	Priority   int    `json:"priority"`
+	// StaleOverride is set when the context was too old to trust and the
+	// filter passed a task it would have failed on the context as it was
+	StaleOverride bool `json:"stale_override,omitempty"`
 }
 
 var DefaultFilterConfig = FilterConfig{
@@ -57,4 +111,7 @@ var DefaultFilterConfig = FilterConfig{
 	MaxDistanceMeters:     5000.0,
 	MinEnergyLevel:        1,
 	DefaultPriorityWeight: 1.0,
+	EstimateUnit:          EstimateUnitMinutes,
+	ReasonVerbosity:       ReasonVerbosityFull,
+	ContextMaxAge:         models.DefaultContextMaxAge,
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package filters
 import (
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/bcnelson/hereAndNow/pkg/models"
 )
@@ -11,6 +12,7 @@ type LocationFilter struct {
 	config        FilterConfig
 	locationRepo  LocationRepository
 	taskLocations TaskLocationRepository
+	users         UserRepository
 }
 
 type LocationRepository interface {
@@ -20,6 +22,22 @@ type LocationRepository interface {
 
 type TaskLocationRepository interface {
 	GetLocationsByTaskID(taskID string) ([]models.Location, error)
+	GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error)
+}
+
+// TaskLocationBatchRepository is a TaskLocationRepository that can look up
+// the locations of many tasks in one query, keyed by task ID. The location
+// filter preloads through it.
+type TaskLocationBatchRepository interface {
+	TaskLocationRepository
+	GetLocationsByTaskIDs(taskIDs []string) (map[string][]models.Location, error)
+	GetTaskLocationsByTaskIDs(taskIDs []string) (map[string][]models.TaskLocation, error)
+}
+
+// UserRepository looks up users, for the timezone locations' opening hours
+// are read in
+type UserRepository interface {
+	GetByID(userID string) (*models.User, error)
 }
 
 func NewLocationFilter(config FilterConfig, locationRepo LocationRepository, taskLocRepo TaskLocationRepository) *LocationFilter {
@@ -30,6 +48,12 @@ func NewLocationFilter(config FilterConfig, locationRepo LocationRepository, tas
 	}
 }
 
+// SetUserRepository reads locations' opening hours in each user's timezone.
+// Without it they are read in the timezone of the context's timestamp.
+func (f *LocationFilter) SetUserRepository(users UserRepository) {
+	f.users = users
+}
+
 func (f *LocationFilter) Name() string {
 	return "location"
 }
@@ -39,47 +63,178 @@ func (f *LocationFilter) Priority() int {
 }
 
 func (f *LocationFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *LocationFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
 	if !f.config.EnableLocationFilter {
-		return true, "location filtering disabled"
+		return true, ReasonFilterDisabled, "location filtering disabled"
 	}
 
 	if ctx.CurrentLatitude == nil || ctx.CurrentLongitude == nil {
-		return true, "current location unknown - showing all tasks"
+		return true, ReasonLocationUnknown, "current location unknown - showing all tasks"
+	}
+
+	// A location that old may no longer be where the user is, so it is
+	// treated as unknown
+	if ctx.IsStale {
+		return true, ReasonLocationStale, fmt.Sprintf("location is %s old - showing all tasks", formatAge(ctx.Age()))
 	}
 
 	taskLocations, err := f.taskLocations.GetLocationsByTaskID(task.ID)
 	if err != nil {
-		return false, fmt.Sprintf("error fetching task locations: %v", err)
+		return false, ReasonFilterError, fmt.Sprintf("error fetching task locations: %v", err)
 	}
 
 	if len(taskLocations) == 0 {
-		return true, "task has no location requirements"
+		return true, ReasonLocationNotRequired, "task has no location requirements"
+	}
+
+	// Only locations that are open now can be done at
+	now := f.localTime(ctx)
+	var open, closed []models.Location
+	for _, location := range taskLocations {
+		if location.OpenHours.IsOpen(now) {
+			open = append(open, location)
+		} else {
+			closed = append(closed, location)
+		}
+	}
+	if len(open) == 0 {
+		return false, ReasonLocationClosed, closedReason(closed, now)
+	}
+	taskLocations = open
+
+	exitLocations, err := f.exitLocationIDs(task.ID)
+	if err != nil {
+		return false, ReasonFilterError, fmt.Sprintf("error fetching task location triggers: %v", err)
 	}
 
 	currentLat := *ctx.CurrentLatitude
 	currentLon := *ctx.CurrentLongitude
 
-	for _, location := range taskLocations {
+	var graceLocation *models.Location
+	graceOverage := math.Inf(1)
+
+	for i, location := range taskLocations {
 		distance := f.calculateDistance(currentLat, currentLon, location.Latitude, location.Longitude)
-		maxDistance := float64(location.Radius)
-		
-		if maxDistance == 0 {
-			maxDistance = f.config.MaxDistanceMeters
-		}
+		maxDistance := f.locationRadius(location)
 
 		if distance <= maxDistance {
-			return true, fmt.Sprintf("within %dm of %s (%.0fm away)", int(maxDistance), location.Name, distance)
+			if exitLocations[location.ID] {
+				return true, ReasonLocationBeforeExit, fmt.Sprintf("still at %s - do before leaving", location.Name)
+			}
+			return true, ReasonLocationInRange, fmt.Sprintf("within %dm of %s (%.0fm away)", int(maxDistance), location.Name, distance)
+		}
+
+		// Exit tasks are only useful while still there, so no grace band
+		if exitLocations[location.ID] {
+			continue
+		}
+
+		overage := distance - maxDistance
+		if overage <= f.config.LocationGraceMeters && overage < graceOverage {
+			graceLocation = &taskLocations[i]
+			graceOverage = overage
 		}
 	}
 
+	if graceLocation != nil {
+		return true, ReasonLocationInGrace, fmt.Sprintf("just outside %s, %.0fm over", graceLocation.Name, graceOverage)
+	}
+
 	nearestLocation := f.findNearestLocation(currentLat, currentLon, taskLocations)
 	if nearestLocation != nil {
 		distance := f.calculateDistance(currentLat, currentLon, nearestLocation.Latitude, nearestLocation.Longitude)
-		return false, fmt.Sprintf("too far from %s (%.0fm away, need to be within %dm)", 
+		return false, ReasonLocationOutOfRange, fmt.Sprintf("too far from %s (%.0fm away, need to be within %dm)", 
 			nearestLocation.Name, distance, nearestLocation.Radius)
 	}
 
-	return false, "not within range of any required locations"
+	return false, ReasonLocationOutOfRange, "not within range of any required locations"
+}
+
+// Preload fetches the locations of all the tasks in two queries when the
+// repository is a TaskLocationBatchRepository, and the user once
+func (f *LocationFilter) Preload(ctx models.Context, tasks []models.Task) (FilterRule, error) {
+	// Evaluation stops before any lookup
+	if !f.config.EnableLocationFilter || ctx.CurrentLatitude == nil || ctx.CurrentLongitude == nil || ctx.IsStale {
+		return f, nil
+	}
+
+	preloaded := *f
+	taskLocations, err := preloadTaskLocations(f.taskLocations, tasks)
+	if err != nil {
+		return nil, err
+	}
+	preloaded.taskLocations = taskLocations
+	if f.users != nil {
+		user, err := f.users.GetByID(ctx.UserID)
+		preloaded.users = &preloadedUser{UserRepository: f.users, userID: ctx.UserID, user: user, err: err}
+	}
+	return &preloaded, nil
+}
+
+// localTime returns the context's time in the user's timezone
+func (f *LocationFilter) localTime(ctx models.Context) time.Time {
+	if f.users != nil {
+		if user, err := f.users.GetByID(ctx.UserID); err == nil {
+			return ctx.Timestamp.In(user.Location())
+		}
+	}
+	return ctx.Timestamp
+}
+
+// closedReason names the closed location that opens soonest and when, as
+// "Hardware Store opens at 08:00 tomorrow"
+func closedReason(closed []models.Location, now time.Time) string {
+	var soonest *models.Location
+	var opens time.Time
+	for i, location := range closed {
+		next, ok := location.OpenHours.NextOpening(now)
+		if ok && (soonest == nil || next.Before(opens)) {
+			soonest, opens = &closed[i], next
+		}
+	}
+	if soonest == nil {
+		return fmt.Sprintf("%s is closed", closed[0].Name)
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case opens.Before(today.AddDate(0, 0, 1)):
+		return fmt.Sprintf("%s opens at %s", soonest.Name, opens.Format("15:04"))
+	case opens.Before(today.AddDate(0, 0, 2)):
+		return fmt.Sprintf("%s opens at %s tomorrow", soonest.Name, opens.Format("15:04"))
+	default:
+		return fmt.Sprintf("%s opens at %s on %s", soonest.Name, opens.Format("15:04"), opens.Weekday())
+	}
+}
+
+// exitLocationIDs returns the IDs of the task's locations that trigger on
+// leaving rather than arriving
+func (f *LocationFilter) exitLocationIDs(taskID string) (map[string]bool, error) {
+	taskLocations, err := f.taskLocations.GetTaskLocationsByTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	exits := make(map[string]bool)
+	for _, taskLocation := range taskLocations {
+		if taskLocation.IsExitTrigger() {
+			exits[taskLocation.LocationID] = true
+		}
+	}
+	return exits, nil
+}
+
+// locationRadius returns the radius a location covers, falling back to
+// MaxDistanceMeters for locations without one
+func (f *LocationFilter) locationRadius(location models.Location) float64 {
+	if location.Radius == 0 {
+		return f.config.MaxDistanceMeters
+	}
+	return float64(location.Radius)
 }
 
 func (f *LocationFilter) calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
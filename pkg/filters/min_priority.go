@@ -0,0 +1,41 @@
+package filters
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// MinPriorityFilter hides tasks below the context's minimum priority. It is
+// a plain threshold on the task's own priority, separate from the scoring
+// in PriorityFilter, and shows everything when the context sets none.
+type MinPriorityFilter struct{}
+
+func NewMinPriorityFilter() *MinPriorityFilter {
+	return &MinPriorityFilter{}
+}
+
+func (f *MinPriorityFilter) Name() string {
+	return "min_priority"
+}
+
+func (f *MinPriorityFilter) Priority() int {
+	return 120
+}
+
+func (f *MinPriorityFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *MinPriorityFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
+	if ctx.MinPriority <= 0 {
+		return true, ReasonMinPriorityUnset, "no minimum priority set"
+	}
+
+	if task.Priority >= ctx.MinPriority {
+		return true, ReasonMinPriorityMet, fmt.Sprintf("priority %d meets minimum %d", task.Priority, ctx.MinPriority)
+	}
+
+	return false, ReasonMinPriorityBelow, fmt.Sprintf("priority %d is below minimum %d", task.Priority, ctx.MinPriority)
+}
@@ -0,0 +1,68 @@
+package filters
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// SubtaskRepository looks up the subtasks of a parent task
+type SubtaskRepository interface {
+	GetSubtasks(parentTaskID string) ([]models.Task, error)
+}
+
+// ParentVisibilityFilter hides parent tasks while any of their subtasks is
+// still open, so the subtasks are shown instead of the task they break
+// down. Cancelled subtasks don't hold a parent back, and recurring tasks
+// are left alone since their instances aren't subtasks. It is opt-in: add
+// it with AddRule.
+type ParentVisibilityFilter struct {
+	subtaskRepo SubtaskRepository
+}
+
+func NewParentVisibilityFilter(subtaskRepo SubtaskRepository) *ParentVisibilityFilter {
+	return &ParentVisibilityFilter{subtaskRepo: subtaskRepo}
+}
+
+func (f *ParentVisibilityFilter) Name() string {
+	return "parent"
+}
+
+func (f *ParentVisibilityFilter) Priority() int {
+	return 105
+}
+
+func (f *ParentVisibilityFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *ParentVisibilityFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
+	if task.RecurrenceRule != nil {
+		return true, ReasonParentNoSubtasks, "recurring task instances are not subtasks"
+	}
+
+	subtasks, err := f.subtaskRepo.GetSubtasks(task.ID)
+	if err != nil {
+		return false, ReasonFilterError, fmt.Sprintf("error fetching subtasks: %v", err)
+	}
+
+	total, open := 0, 0
+	for _, subtask := range subtasks {
+		if subtask.DeletedAt != nil || subtask.IsCancelled() {
+			continue
+		}
+		total++
+		if !subtask.IsCompleted() {
+			open++
+		}
+	}
+
+	if total == 0 {
+		return true, ReasonParentNoSubtasks, "no subtasks"
+	}
+	if open > 0 {
+		return false, ReasonParentSubtasksOpen, fmt.Sprintf("%d of %d subtasks still open", open, total)
+	}
+	return true, ReasonParentSubtasksDone, fmt.Sprintf("all %d subtasks done", total)
+}
@@ -0,0 +1,153 @@
+package filters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// preloadRules returns the engine's rules with each PreloadingFilterRule
+// swapped for its preloaded form for the tasks. Callers hold e.mu.
+func (e *Engine) preloadRules(ctx models.Context, tasks []models.Task) []FilterRule {
+	rules := make([]FilterRule, len(e.rules))
+	for i, rule := range e.rules {
+		rules[i] = rule
+		if len(tasks) == 0 {
+			continue
+		}
+		if preloading, ok := rule.(PreloadingFilterRule); ok {
+			if preloaded, err := preloading.Preload(ctx, tasks); err == nil {
+				rules[i] = preloaded
+			}
+		}
+	}
+	return rules
+}
+
+// taskIDs returns the IDs of the tasks, in order
+func taskIDs(tasks []models.Task) []string {
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+// preloadTaskLocations fetches the locations of all the tasks in two
+// queries when repo is a TaskLocationBatchRepository, and returns repo
+// unchanged otherwise
+func preloadTaskLocations(repo TaskLocationRepository, tasks []models.Task) (TaskLocationRepository, error) {
+	batch, ok := repo.(TaskLocationBatchRepository)
+	if !ok {
+		return repo, nil
+	}
+
+	ids := taskIDs(tasks)
+	locations, err := batch.GetLocationsByTaskIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error preloading task locations: %w", err)
+	}
+	links, err := batch.GetTaskLocationsByTaskIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error preloading task location triggers: %w", err)
+	}
+
+	loaded := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		loaded[id] = true
+	}
+	return &preloadedTaskLocations{
+		TaskLocationRepository: repo,
+		loaded:                 loaded,
+		locations:              locations,
+		links:                  links,
+	}, nil
+}
+
+// preloadedTaskLocations answers for the preloaded tasks from memory and
+// asks the repository about any other task
+type preloadedTaskLocations struct {
+	TaskLocationRepository
+	loaded    map[string]bool
+	locations map[string][]models.Location
+	links     map[string][]models.TaskLocation
+}
+
+func (r *preloadedTaskLocations) GetLocationsByTaskID(taskID string) ([]models.Location, error) {
+	if r.loaded[taskID] {
+		return r.locations[taskID], nil
+	}
+	return r.TaskLocationRepository.GetLocationsByTaskID(taskID)
+}
+
+func (r *preloadedTaskLocations) GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error) {
+	if r.loaded[taskID] {
+		return r.links[taskID], nil
+	}
+	return r.TaskLocationRepository.GetTaskLocationsByTaskID(taskID)
+}
+
+// preloadedUser is the context's user, looked up once
+type preloadedUser struct {
+	UserRepository
+	userID string
+	user   *models.User
+	err    error
+}
+
+func (r *preloadedUser) GetByID(userID string) (*models.User, error) {
+	if userID == r.userID {
+		return r.user, r.err
+	}
+	return r.UserRepository.GetByID(userID)
+}
+
+// preloadedDependencies holds the dependencies of every task reachable from
+// the preloaded tasks, so cycle checks stay in memory too
+type preloadedDependencies struct {
+	TaskDependencyRepository
+	loaded       map[string]bool
+	dependencies map[string][]models.TaskDependency
+}
+
+func (r *preloadedDependencies) GetDependenciesByTaskID(taskID string) ([]models.TaskDependency, error) {
+	if r.loaded[taskID] {
+		return r.dependencies[taskID], nil
+	}
+	return r.TaskDependencyRepository.GetDependenciesByTaskID(taskID)
+}
+
+// preloadedTasks holds the tasks the preloaded tasks depend on. A task that
+// was looked up and not found stays not found.
+type preloadedTasks struct {
+	TaskRepository
+	loaded map[string]bool
+	tasks  map[string]models.Task
+}
+
+func (r *preloadedTasks) GetByID(taskID string) (*models.Task, error) {
+	if !r.loaded[taskID] {
+		return r.TaskRepository.GetByID(taskID)
+	}
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	return &task, nil
+}
+
+// preloadedEvents holds the user's calendar for one window
+type preloadedEvents struct {
+	CalendarEventRepository
+	userID     string
+	start, end time.Time
+	events     []models.CalendarEvent
+}
+
+func (r *preloadedEvents) GetEventsByUserIDAndTimeRange(userID string, start, end time.Time) ([]models.CalendarEvent, error) {
+	if userID == r.userID && start.Equal(r.start) && end.Equal(r.end) {
+		return r.events, nil
+	}
+	return r.CalendarEventRepository.GetEventsByUserIDAndTimeRange(userID, start, end)
+}
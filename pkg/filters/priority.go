@@ -35,20 +35,36 @@ func (f *PriorityFilter) Priority() int {
 }
 
 func (f *PriorityFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *PriorityFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
 	if !f.config.EnablePriorityFilter {
-		return true, "priority filtering disabled"
+		return true, ReasonFilterDisabled, "priority filtering disabled"
+	}
+
+	if alignment := f.config.EnergyAlignment.At(ctx.EnergyLevel); alignment.Hides(task.Priority) {
+		return false, ReasonPriorityEnergyFloor, fmt.Sprintf("priority %d below minimum %d at energy level %d",
+			task.Priority, alignment.MinPriority, ctx.EnergyLevel)
 	}
 
 	score := f.CalculatePriorityScore(ctx, task)
 
+	// The energy filter decides whether a task that states the energy it
+	// needs is shown, so here the shortfall only ranks it lower
+	if f.config.EnableEnergyFilter && task.RequiredEnergyLevel != nil {
+		score.TotalScore += (1.0 - score.EnergyScore) * f.getScoreWeights(ctx).Energy
+	}
+
 	threshold := f.calculateDynamicThreshold(ctx)
 	
 	if score.TotalScore >= threshold {
-		return true, fmt.Sprintf("priority score %.1f >= threshold %.1f (%s)", 
+		return true, ReasonPriorityAboveThreshold, fmt.Sprintf("priority score %.1f >= threshold %.1f (%s)", 
 			score.TotalScore, threshold, score.Explanation)
 	}
 
-	return false, fmt.Sprintf("priority score %.1f < threshold %.1f (%s)", 
+	return false, ReasonPriorityBelowThreshold, fmt.Sprintf("priority score %.1f < threshold %.1f (%s)", 
 		score.TotalScore, threshold, score.Explanation)
 }
 
@@ -70,6 +86,11 @@ func (f *PriorityFilter) CalculatePriorityScore(ctx models.Context, task models.
 		contextScore, weights.Context,
 		energyScore, weights.Energy)
 
+	if modifier := f.config.EnergyAlignment.At(ctx.EnergyLevel).ScoreModifier(task.Priority); modifier != 0 {
+		totalScore += modifier
+		explanation += fmt.Sprintf(" + A:%+.2f", modifier)
+	}
+
 	return PriorityScore{
 		Task:           task,
 		TotalScore:     totalScore,
@@ -96,7 +117,7 @@ func (f *PriorityFilter) getScoreWeights(ctx models.Context) ScoreWeights {
 		Energy:   0.1,
 	}
 
-	if ctx.AvailableMinutes < 30 {
+	if ctx.AvailableMinutes < 30 && !ctx.IsStale {
 		baseWeights.Urgency += 0.1
 		baseWeights.Priority -= 0.1
 	}
@@ -142,8 +163,8 @@ func (f *PriorityFilter) calculateUrgencyScore(ctx models.Context, task models.T
 func (f *PriorityFilter) calculateContextScore(ctx models.Context, task models.Task) float64 {
 	score := 0.5
 
-	if task.EstimatedMinutes != nil && ctx.AvailableMinutes > 0 {
-		timeMatch := float64(ctx.AvailableMinutes) / float64(*task.EstimatedMinutes)
+	if minutes, ok := f.config.EstimatedMinutes(task); ok && ctx.AvailableMinutes > 0 && !ctx.IsStale {
+		timeMatch := float64(ctx.AvailableMinutes) / float64(minutes)
 		if timeMatch >= 1.0 {
 			score += 0.3
 		} else if timeMatch >= 0.5 {
@@ -203,11 +224,16 @@ func (f *PriorityFilter) calculateEnergyMatchScore(ctx models.Context, task mode
 	return score
 }
 
+// estimateRequiredEnergy is the task's RequiredEnergyLevel, or a guess from
+// its size, priority and wording when it has none
 func (f *PriorityFilter) estimateRequiredEnergy(task models.Task) int {
+	if task.RequiredEnergyLevel != nil {
+		return *task.RequiredEnergyLevel
+	}
+
 	baseEnergy := 1
 
-	if task.EstimatedMinutes != nil {
-		minutes := *task.EstimatedMinutes
+	if minutes, ok := f.config.EstimatedMinutes(task); ok {
 		switch {
 		case minutes > 120:
 			baseEnergy = 4
@@ -236,9 +262,12 @@ func (f *PriorityFilter) estimateRequiredEnergy(task models.Task) int {
 func (f *PriorityFilter) calculateDynamicThreshold(ctx models.Context) float64 {
 	baseThreshold := 0.5
 
-	if ctx.AvailableMinutes < 15 {
+	// Stale available time neither raises nor lowers the bar
+	switch {
+	case ctx.IsStale:
+	case ctx.AvailableMinutes < 15:
 		baseThreshold += 0.2
-	} else if ctx.AvailableMinutes > 120 {
+	case ctx.AvailableMinutes > 120:
 		baseThreshold -= 0.1
 	}
 
@@ -314,7 +343,8 @@ func (f *PriorityFilter) isComplexTask(task models.Task) bool {
 		}
 	}
 	
-	return task.EstimatedMinutes != nil && *task.EstimatedMinutes > 60
+	minutes, ok := f.config.EstimatedMinutes(task)
+	return ok && minutes > 60
 }
 
 func containsIgnoreCase(text, substr string) bool {
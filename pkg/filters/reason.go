@@ -0,0 +1,158 @@
+package filters
+
+import (
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ReasonCode is a stable, machine-readable identifier for why a filter
+// showed or hid a task. Clients can act on codes and localize them, while
+// the human-readable reason that accompanies each code may change wording.
+type ReasonCode string
+
+// Codes shared by every built-in filter
+const (
+	ReasonFilterDisabled ReasonCode = "FILTER_DISABLED"
+	ReasonFilterError    ReasonCode = "FILTER_ERROR"
+)
+
+// Location filter codes
+const (
+	ReasonLocationUnknown     ReasonCode = "LOCATION_UNKNOWN"
+	ReasonLocationNotRequired ReasonCode = "LOCATION_NOT_REQUIRED"
+	ReasonLocationInRange     ReasonCode = "LOCATION_IN_RANGE"
+	ReasonLocationBeforeExit  ReasonCode = "LOCATION_BEFORE_EXIT"
+	ReasonLocationInGrace     ReasonCode = "LOCATION_IN_GRACE"
+	ReasonLocationOutOfRange  ReasonCode = "LOCATION_OUT_OF_RANGE"
+	ReasonLocationClosed      ReasonCode = "LOCATION_CLOSED"
+	ReasonLocationStale       ReasonCode = "LOCATION_STALE"
+)
+
+// Time filter codes
+const (
+	ReasonTimeNoEstimate       ReasonCode = "TIME_NO_ESTIMATE"
+	ReasonTimeNotRequired      ReasonCode = "TIME_NOT_REQUIRED"
+	ReasonTimeNoneAvailable    ReasonCode = "TIME_NONE_AVAILABLE"
+	ReasonTimeInsufficient     ReasonCode = "TIME_INSUFFICIENT"
+	ReasonTimeCalendarConflict ReasonCode = "TIME_CALENDAR_CONFLICT"
+	ReasonTimeSnoozed          ReasonCode = "TIME_SNOOZED"
+	ReasonEnergyInsufficient   ReasonCode = "ENERGY_INSUFFICIENT"
+	ReasonTimeFits             ReasonCode = "TIME_FITS"
+	ReasonTimeStale            ReasonCode = "TIME_STALE"
+)
+
+// Energy filter codes. A task needing more energy than the user has is
+// reported with ReasonEnergyInsufficient, as the time filter does.
+const (
+	ReasonEnergyNoRequirement   ReasonCode = "ENERGY_NO_REQUIREMENT"
+	ReasonEnergyUnknown         ReasonCode = "ENERGY_UNKNOWN"
+	ReasonEnergySufficient      ReasonCode = "ENERGY_SUFFICIENT"
+	ReasonEnergyWithinTolerance ReasonCode = "ENERGY_WITHIN_TOLERANCE"
+)
+
+// Dependency filter codes
+const (
+	ReasonDepNone     ReasonCode = "DEP_NONE"
+	ReasonDepCircular ReasonCode = "DEP_CIRCULAR"
+	ReasonDepPending  ReasonCode = "DEP_PENDING"
+	ReasonDepMet      ReasonCode = "DEP_MET"
+)
+
+// Priority filter codes
+const (
+	ReasonPriorityAboveThreshold ReasonCode = "PRIORITY_ABOVE_THRESHOLD"
+	ReasonPriorityBelowThreshold ReasonCode = "PRIORITY_BELOW_THRESHOLD"
+	ReasonPriorityEnergyFloor    ReasonCode = "PRIORITY_ENERGY_FLOOR"
+)
+
+// Weather filter codes
+const (
+	ReasonWeatherNotRequired ReasonCode = "WEATHER_NOT_REQUIRED"
+	ReasonWeatherUnknown     ReasonCode = "WEATHER_UNKNOWN"
+	ReasonWeatherSuitable    ReasonCode = "WEATHER_SUITABLE"
+	ReasonWeatherUnsuitable  ReasonCode = "WEATHER_UNSUITABLE"
+)
+
+// Social context filter codes
+const (
+	ReasonSocialNoRequirement ReasonCode = "SOCIAL_NO_REQUIREMENT"
+	ReasonSocialUnknown       ReasonCode = "SOCIAL_UNKNOWN"
+	ReasonSocialCompatible    ReasonCode = "SOCIAL_COMPATIBLE"
+	ReasonSocialIncompatible  ReasonCode = "SOCIAL_INCOMPATIBLE"
+	ReasonSocialUnsafeDriving ReasonCode = "SOCIAL_UNSAFE_DRIVING"
+)
+
+// Minimum priority filter codes
+const (
+	ReasonMinPriorityUnset ReasonCode = "MIN_PRIORITY_UNSET"
+	ReasonMinPriorityMet   ReasonCode = "MIN_PRIORITY_MET"
+	ReasonMinPriorityBelow ReasonCode = "MIN_PRIORITY_BELOW"
+)
+
+// Parent visibility filter codes
+const (
+	ReasonParentNoSubtasks   ReasonCode = "PARENT_NO_SUBTASKS"
+	ReasonParentSubtasksDone ReasonCode = "PARENT_SUBTASKS_DONE"
+	ReasonParentSubtasksOpen ReasonCode = "PARENT_SUBTASKS_OPEN"
+)
+
+// CodedFilterRule is a FilterRule that also reports a ReasonCode with each
+// verdict. The engine records codes for rules that implement it; results
+// from other rules have no code.
+type CodedFilterRule interface {
+	FilterRule
+	Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string)
+}
+
+// ReasonVerbosity controls how much of each filter reason the engine keeps
+// in results and the audit log
+type ReasonVerbosity string
+
+const (
+	// ReasonVerbosityFull keeps both the code and the human-readable reason
+	ReasonVerbosityFull ReasonVerbosity = "full"
+	// ReasonVerbosityCodes keeps only the code, for clients that render
+	// their own localized messages. Rules without codes keep their reason.
+	ReasonVerbosityCodes ReasonVerbosity = "codes"
+)
+
+// IsValidReasonVerbosity reports whether verbosity is known. The empty
+// verbosity is treated as full.
+func IsValidReasonVerbosity(verbosity ReasonVerbosity) bool {
+	switch verbosity {
+	case "", ReasonVerbosityFull, ReasonVerbosityCodes:
+		return true
+	default:
+		return false
+	}
+}
+
+// applyRule runs rule against task, taking the code from rules that report
+// one, and trims the reason to the configured verbosity
+func (e *Engine) applyRule(rule FilterRule, ctx models.Context, task models.Task) (bool, ReasonCode, string) {
+	var (
+		visible bool
+		code    ReasonCode
+		reason  string
+	)
+	if coded, ok := rule.(CodedFilterRule); ok {
+		visible, code, reason = coded.Evaluate(ctx, task)
+	} else {
+		visible, reason = rule.Apply(ctx, task)
+	}
+
+	if e.config.ReasonVerbosity == ReasonVerbosityCodes && code != "" {
+		reason = ""
+	}
+
+	return visible, code, reason
+}
+
+// The built-in filters report codes
+var (
+	_ CodedFilterRule = (*LocationFilter)(nil)
+	_ CodedFilterRule = (*TimeFilter)(nil)
+	_ CodedFilterRule = (*DependencyFilter)(nil)
+	_ CodedFilterRule = (*PriorityFilter)(nil)
+	_ CodedFilterRule = (*EnergyFilter)(nil)
+	_ CodedFilterRule = (*ParentVisibilityFilter)(nil)
+)
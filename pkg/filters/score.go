@@ -0,0 +1,113 @@
+package filters
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// Score breakdown keys. Each holds a component's weighted contribution, so
+// a task's breakdown sums to its score.
+const (
+	ScorePriority        = "priority"
+	ScoreUrgency         = "urgency"
+	ScoreContext         = "context"
+	ScoreEnergy          = "energy"
+	ScoreEnergyAlignment = "energy_alignment"
+	ScoreCategory        = "category"
+)
+
+// ScoredTask is a task with how well it suits a context. Hidden tasks score
+// zero and name the filters that hid them.
+type ScoredTask struct {
+	Task      models.Task        `json:"task"`
+	Score     float64            `json:"score"`
+	Breakdown map[string]float64 `json:"breakdown"`
+	Visible   bool               `json:"visible"`
+	HiddenBy  []string           `json:"hidden_by,omitempty"`
+}
+
+// ScoreTasks runs the filters over the tasks and scores the visible ones by
+// priority, urgency (due date proximity), context fit and energy match,
+// weighted as the priority filter weighs them, plus the CategoryPreference
+// bonus when enabled. Visible tasks come first, highest score first; hidden
+// tasks follow in their original order. Nothing is audited. It fails only
+// when the context has no valid energy level.
+func (e *Engine) ScoreTasks(ctx models.Context, tasks []models.Task) ([]ScoredTask, error) {
+	// Energy match is part of every score, so it needs a real energy level
+	if ctx.EnergyLevel < 1 || ctx.EnergyLevel > 5 {
+		return nil, fmt.Errorf("cannot score tasks: energy level %d is not between 1 and 5", ctx.EnergyLevel)
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ctx = e.withAge(ctx)
+	scorer := NewPriorityFilter(e.config)
+	rules := e.preloadRules(ctx, tasks)
+	if e.config.ShortCircuit {
+		rules = rulesByCost(rules)
+	}
+	categoryBonus := e.categoryBonus(ctx, tasks)
+	scored := make([]ScoredTask, 0, len(tasks))
+	for _, task := range tasks {
+		visible, results := e.evaluateTask(rules, ctx, task, e.config.ShortCircuit)
+		if !visible {
+			var hiddenBy []string
+			for _, result := range results {
+				if !result.Visible {
+					hiddenBy = append(hiddenBy, result.FilterName)
+				}
+			}
+			scored = append(scored, ScoredTask{Task: task, Breakdown: map[string]float64{}, HiddenBy: hiddenBy})
+			continue
+		}
+
+		score := scorer.CalculatePriorityScore(ctx, task)
+		weights := scorer.getScoreWeights(ctx)
+		breakdown := map[string]float64{
+			ScorePriority: score.PriorityScore * weights.Priority,
+			ScoreUrgency:  score.UrgencyScore * weights.Urgency,
+			ScoreContext:  score.ContextScore * weights.Context,
+			ScoreEnergy:   score.EnergyScore * weights.Energy,
+		}
+		if modifier := e.config.EnergyAlignment.At(ctx.EnergyLevel).ScoreModifier(task.Priority); modifier != 0 {
+			breakdown[ScoreEnergyAlignment] = modifier
+		}
+		if categoryBonus != nil {
+			if bonus := categoryBonus(task); bonus != 0 {
+				breakdown[ScoreCategory] = bonus
+				score.TotalScore += bonus
+			}
+		}
+
+		scored = append(scored, ScoredTask{
+			Task:      task,
+			Score:     score.TotalScore,
+			Breakdown: breakdown,
+			Visible:   true,
+		})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Visible != scored[j].Visible {
+			return scored[i].Visible
+		}
+		return scored[i].Score > scored[j].Score
+	})
+
+	return scored, nil
+}
+
+// VisibleScoredTasks returns the visible tasks of a ScoreTasks result in
+// score order
+func VisibleScoredTasks(scored []ScoredTask) []models.Task {
+	tasks := []models.Task{}
+	for _, task := range scored {
+		if task.Visible {
+			tasks = append(tasks, task.Task)
+		}
+	}
+	return tasks
+}
@@ -0,0 +1,120 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// SocialTagsKey is the task metadata key that lists the task's social
+// context tags, e.g. {"social_tags": ["requires_alone"]}
+const SocialTagsKey = "social_tags"
+
+// Social context tags a task can carry. "requires_" followed by any social
+// context, such as "requires_at_work", shows the task only in that context;
+// a task with several requires tags is shown in any of them.
+const (
+	// TagRequiresAlone shows the task only while the user is alone
+	TagRequiresAlone = "requires_alone"
+	// TagUnsafeWhileDriving hides the task whenever the user is driving
+	TagUnsafeWhileDriving = "unsafe_while_driving"
+)
+
+const requiresTagPrefix = "requires_"
+
+// SocialContextFilter hides tasks whose social context tags rule out the
+// user's current social context. Tasks without tags stay visible.
+type SocialContextFilter struct {
+	config FilterConfig
+}
+
+func NewSocialContextFilter(config FilterConfig) *SocialContextFilter {
+	return &SocialContextFilter{config: config}
+}
+
+func (f *SocialContextFilter) Name() string {
+	return "social"
+}
+
+func (f *SocialContextFilter) Priority() int {
+	return 98
+}
+
+func (f *SocialContextFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *SocialContextFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
+	if !f.config.EnableSocialFilter {
+		return true, ReasonFilterDisabled, "social context filtering disabled"
+	}
+
+	tags := TaskSocialTags(task)
+	var required []string
+	unsafeWhileDriving := false
+	for _, tag := range tags {
+		if tag == TagUnsafeWhileDriving {
+			unsafeWhileDriving = true
+			continue
+		}
+		if context := strings.TrimPrefix(tag, requiresTagPrefix); context != tag && models.IsValidSocialContext(context) {
+			required = append(required, context)
+		}
+	}
+
+	// Checked before anything else so no other tag can show the task
+	if unsafeWhileDriving && ctx.SocialContext == models.SocialContextDriving {
+		return false, ReasonSocialUnsafeDriving, "task is unsafe while driving"
+	}
+
+	if len(required) == 0 {
+		return true, ReasonSocialNoRequirement, "task has no social context requirement"
+	}
+
+	if ctx.SocialContext == "" {
+		return true, ReasonSocialUnknown, "current social context unknown - showing task"
+	}
+
+	for _, context := range required {
+		if context == ctx.SocialContext {
+			return true, ReasonSocialCompatible, fmt.Sprintf("you are %s as required", socialContextLabel(context))
+		}
+	}
+
+	labels := make([]string, len(required))
+	for i, context := range required {
+		labels[i] = socialContextLabel(context)
+	}
+	return false, ReasonSocialIncompatible, fmt.Sprintf("needs you to be %s but you are %s",
+		strings.Join(labels, " or "), socialContextLabel(ctx.SocialContext))
+}
+
+// TaskSocialTags returns the task's social context tags in lower case, or
+// nil when it has none or its metadata is unreadable
+func TaskSocialTags(task models.Task) []string {
+	if len(task.Metadata) == 0 {
+		return nil
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(task.Metadata, &metadata); err != nil {
+		return nil
+	}
+
+	values, _ := metadata[SocialTagsKey].([]interface{})
+	tags := make([]string, 0, len(values))
+	for _, value := range values {
+		if tag, ok := value.(string); ok {
+			tags = append(tags, strings.ToLower(strings.TrimSpace(tag)))
+		}
+	}
+	return tags
+}
+
+// socialContextLabel turns a social context such as "at_work" into "at work"
+func socialContextLabel(context string) string {
+	return strings.ReplaceAll(context, "_", " ")
+}
@@ -1,15 +1,22 @@
 package filters
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/bcnelson/hereAndNow/pkg/models"
 )
 
+// snoozeReappearFormat is how the time filter says when a snoozed task
+// comes back
+const snoozeReappearFormat = "Mon Jan 2 15:04"
+
 type TimeFilter struct {
 	config         FilterConfig
 	calendarRepo   CalendarEventRepository
+	taskLocations  TaskLocationRepository
 }
 
 type CalendarEventRepository interface {
@@ -23,6 +30,13 @@ func NewTimeFilter(config FilterConfig, calendarRepo CalendarEventRepository) *T
 	}
 }
 
+// SetTaskLocationRepository adds the time it takes to reach a task's
+// nearest location to its estimate, when FilterConfig.TravelMode is set and
+// the context has coordinates
+func (f *TimeFilter) SetTaskLocationRepository(taskLocations TaskLocationRepository) {
+	f.taskLocations = taskLocations
+}
+
 func (f *TimeFilter) Name() string {
 	return "time"
 }
@@ -32,69 +46,261 @@ func (f *TimeFilter) Priority() int {
 }
 
 func (f *TimeFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *TimeFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
+	// A snooze defers the task without a due date; it reappears, and is
+	// judged as usual, once the snooze passes. The user asked for it, so it
+	// holds even with time filtering disabled.
+	if task.IsSnoozed(ctx.Timestamp) {
+		return false, ReasonTimeSnoozed, fmt.Sprintf("snoozed until %s", task.SnoozedUntil.Format(snoozeReappearFormat))
+	}
+
 	if !f.config.EnableTimeFilter {
-		return true, "time filtering disabled"
+		return true, ReasonFilterDisabled, "time filtering disabled"
 	}
 
-	if task.EstimatedMinutes == nil {
-		return true, "task has no time estimate"
+	estimatedMinutes, ok := f.config.EstimatedMinutes(task)
+	if !ok {
+		return true, ReasonTimeNoEstimate, "task has no time estimate"
 	}
 
-	estimatedMinutes := *task.EstimatedMinutes
 	availableMinutes := ctx.AvailableMinutes
+	travelMinutes := 0
 
 	if estimatedMinutes <= 0 {
-		return true, "task has no time requirement"
+		return true, ReasonTimeNotRequired, "task has no time requirement"
 	}
 
-	if availableMinutes <= 0 {
-		return false, "no available time in current context"
-	}
+	// Time available hours ago says little about the time available now,
+	// so a stale context doesn't hold long tasks back
+	if !ctx.IsStale {
+		if availableMinutes <= 0 {
+			return false, ReasonTimeNoneAvailable, "no available time in current context"
+		}
 
-	if estimatedMinutes > availableMinutes {
-		return false, fmt.Sprintf("task needs %d minutes but only %d available", 
-			estimatedMinutes, availableMinutes)
+		travelMinutes = f.travelMinutes(ctx, task)
+		if estimatedMinutes+travelMinutes > availableMinutes {
+			if travelMinutes > 0 {
+				return false, ReasonTimeInsufficient, fmt.Sprintf("needs %dm task + ~%dm travel, you have %dm",
+					estimatedMinutes, travelMinutes, availableMinutes)
+			}
+			return false, ReasonTimeInsufficient, fmt.Sprintf("task needs %s but only %d available", 
+				f.describeEstimate(task, estimatedMinutes), availableMinutes)
+		}
+
+		hasConflict, conflictReason := f.checkCalendarConflicts(ctx, task, estimatedMinutes+travelMinutes)
+		if hasConflict {
+			return false, ReasonTimeCalendarConflict, conflictReason
+		}
 	}
 
-	hasConflict, conflictReason := f.checkCalendarConflicts(ctx, task)
-	if hasConflict {
-		return false, conflictReason
+	// A task that states the energy it needs is left to the energy filter
+	if task.RequiredEnergyLevel == nil {
+		energyRequired := f.estimateEnergyRequirement(task)
+		if energyRequired > ctx.EnergyLevel {
+			return false, ReasonEnergyInsufficient, fmt.Sprintf("task requires energy level %d but current level is %d", 
+				energyRequired, ctx.EnergyLevel)
+		}
 	}
 
-	energyRequired := f.estimateEnergyRequirement(task)
-	if energyRequired > ctx.EnergyLevel {
-		return false, fmt.Sprintf("task requires energy level %d but current level is %d", 
-			energyRequired, ctx.EnergyLevel)
+	if ctx.IsStale {
+		return true, ReasonTimeStale, fmt.Sprintf("available time is %s old - not limiting by time", formatAge(ctx.Age()))
 	}
 
-	return true, fmt.Sprintf("task fits in %d minute window (needs %d)", 
+	if travelMinutes > 0 {
+		return true, ReasonTimeFits, fmt.Sprintf("task fits in %d minute window (needs %dm task + ~%dm travel)",
+			availableMinutes, estimatedMinutes, travelMinutes)
+	}
+	return true, ReasonTimeFits, fmt.Sprintf("task fits in %d minute window (needs %d)", 
 		availableMinutes, estimatedMinutes)
 }
 
-func (f *TimeFilter) checkCalendarConflicts(ctx models.Context, task models.Task) (bool, string) {
-	if task.EstimatedMinutes == nil {
-		return false, ""
+// Preload fetches the user's calendar for the context's available time
+// once, rather than once per task that fits in it, and the tasks' locations
+// when it counts travel time
+func (f *TimeFilter) Preload(ctx models.Context, tasks []models.Task) (FilterRule, error) {
+	if !f.config.EnableTimeFilter || ctx.AvailableMinutes <= 0 || ctx.IsStale {
+		return f, nil
 	}
 
-	now := ctx.Timestamp
-	taskEndTime := now.Add(time.Duration(*task.EstimatedMinutes) * time.Minute)
+	preloaded := *f
+	if f.taskLocations != nil && f.config.CountsTravelTime() && ctx.CurrentLatitude != nil && ctx.CurrentLongitude != nil {
+		taskLocations, err := preloadTaskLocations(f.taskLocations, tasks)
+		if err != nil {
+			return nil, err
+		}
+		preloaded.taskLocations = taskLocations
+	}
 
-	events, err := f.calendarRepo.GetEventsByUserIDAndTimeRange(
-		ctx.UserID, 
-		now.Add(-5*time.Minute),
-		taskEndTime.Add(5*time.Minute),
-	)
+	windowStart := ctx.Timestamp
+	windowEnd := windowStart.Add(time.Duration(ctx.AvailableMinutes) * time.Minute)
+	events, err := f.calendarRepo.GetEventsByUserIDAndTimeRange(ctx.UserID, windowStart, windowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error preloading calendar: %w", err)
+	}
+
+	preloaded.calendarRepo = &preloadedEvents{
+		CalendarEventRepository: f.calendarRepo,
+		userID:                  ctx.UserID,
+		start:                   windowStart,
+		end:                     windowEnd,
+		events:                  events,
+	}
+	return &preloaded, nil
+}
+
+// describeEstimate phrases a task's size in the unit it was estimated in
+func (f *TimeFilter) describeEstimate(task models.Task, minutes int) string {
+	if f.config.UsesPoints() && task.EffortPoints != nil {
+		return fmt.Sprintf("%d points (%d minutes)", *task.EffortPoints, minutes)
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// checkCalendarConflicts lays the user's calendar over the time they have
+// available and blocks the task when no free stretch of it is long enough
+// for the minutes it needs, travel included. Overlapping and back-to-back
+// events merge into one busy block, and an all-day event leaves no free
+// time unless the task ignores them.
+func (f *TimeFilter) checkCalendarConflicts(ctx models.Context, task models.Task, neededMinutes int) (bool, string) {
+	windowStart := ctx.Timestamp
+	windowEnd := windowStart.Add(time.Duration(ctx.AvailableMinutes) * time.Minute)
+
+	events, err := f.calendarRepo.GetEventsByUserIDAndTimeRange(ctx.UserID, windowStart, windowEnd)
 	if err != nil {
 		return false, fmt.Sprintf("unable to check calendar: %v", err)
 	}
 
+	blocks := busyBlocks(events, windowStart, windowEnd, TaskIgnoresAllDay(task))
+	if len(blocks) == 0 {
+		return false, ""
+	}
+
+	gap := largestFreeGap(blocks, windowStart, windowEnd)
+	if gap.length >= time.Duration(neededMinutes)*time.Minute {
+		return false, ""
+	}
+
+	switch {
+	case gap.length <= 0:
+		return true, fmt.Sprintf("no free time, busy with '%s'", blocks[0].title)
+	case gap.before != nil:
+		return true, fmt.Sprintf("only %s free before '%s'", formatGap(gap.length), gap.before.title)
+	default:
+		return true, fmt.Sprintf("only %s free after '%s'", formatGap(gap.length), blocks[len(blocks)-1].title)
+	}
+}
+
+// IgnoreAllDayKey is the task metadata flag that lets a task be shown
+// during all-day events, e.g. {"ignore_allday": true}. Without it an
+// all-day event leaves no free time for tasks with an estimate.
+const IgnoreAllDayKey = "ignore_allday"
+
+// TaskIgnoresAllDay reports whether the task's metadata sets
+// IgnoreAllDayKey
+func TaskIgnoresAllDay(task models.Task) bool {
+	if len(task.Metadata) == 0 {
+		return false
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(task.Metadata, &metadata); err != nil {
+		return false
+	}
+
+	ignore, _ := metadata[IgnoreAllDayKey].(bool)
+	return ignore
+}
+
+// busyBlock is a stretch of merged calendar events, named for the first of
+// them
+type busyBlock struct {
+	start, end time.Time
+	title      string
+}
+
+// busyBlocks clips events to [windowStart, windowEnd) and merges those that
+// overlap or touch, in start order. All-day events cover the whole window
+// unless ignoreAllDay is set, in which case they are left out.
+func busyBlocks(events []models.CalendarEvent, windowStart, windowEnd time.Time, ignoreAllDay bool) []busyBlock {
+	var blocks []busyBlock
 	for _, event := range events {
-		if f.isTimeOverlapping(now, taskEndTime, event.StartAt, event.EndAt) {
-			return true, fmt.Sprintf("conflicts with calendar event: %s", event.Title)
+		start, end := event.StartAt, event.EndAt
+		if event.IsAllDay {
+			if ignoreAllDay {
+				continue
+			}
+			start, end = windowStart, windowEnd
+		}
+		if start.Before(windowStart) {
+			start = windowStart
+		}
+		if end.After(windowEnd) {
+			end = windowEnd
+		}
+		if !start.Before(end) {
+			continue
+		}
+		blocks = append(blocks, busyBlock{start: start, end: end, title: event.Title})
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].start.Before(blocks[j].start)
+	})
+
+	var merged []busyBlock
+	for _, block := range blocks {
+		if n := len(merged); n > 0 && !block.start.After(merged[n-1].end) {
+			if block.end.After(merged[n-1].end) {
+				merged[n-1].end = block.end
+			}
+			continue
 		}
+		merged = append(merged, block)
 	}
+	return merged
+}
 
-	return false, ""
+// freeGap is a free stretch between busy blocks; before is the block that
+// ends it, or nil when it runs to the end of the window
+type freeGap struct {
+	start  time.Time
+	length time.Duration
+	before *busyBlock
+}
+
+// largestFreeGap returns the longest free stretch in the window around the
+// merged blocks, the earliest one when several are as long
+func largestFreeGap(blocks []busyBlock, windowStart, windowEnd time.Time) freeGap {
+	largest := freeGap{start: windowStart}
+	cursor := windowStart
+	for i := range blocks {
+		if length := blocks[i].start.Sub(cursor); length > largest.length {
+			largest = freeGap{start: cursor, length: length, before: &blocks[i]}
+		}
+		cursor = blocks[i].end
+	}
+	if length := windowEnd.Sub(cursor); length > largest.length {
+		largest = freeGap{start: cursor, length: length}
+	}
+	return largest
+}
+
+// formatGap writes a free stretch in whole minutes, e.g. "20m"
+func formatGap(length time.Duration) string {
+	return fmt.Sprintf("%dm", int(length/time.Minute))
+}
+
+// formatAge writes how old a context is in hours and minutes, e.g. "8h05m"
+func formatAge(age time.Duration) string {
+	hours, minutes := int(age/time.Hour), int(age%time.Hour/time.Minute)
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", hours, minutes)
 }
 
 func (f *TimeFilter) isTimeOverlapping(start1, end1, start2, end2 time.Time) bool {
@@ -104,8 +310,7 @@ func (f *TimeFilter) isTimeOverlapping(start1, end1, start2, end2 time.Time) boo
 func (f *TimeFilter) estimateEnergyRequirement(task models.Task) int {
 	baseEnergy := 1
 
-	if task.EstimatedMinutes != nil {
-		minutes := *task.EstimatedMinutes
+	if minutes, ok := f.config.EstimatedMinutes(task); ok {
 		switch {
 		case minutes > 120:
 			baseEnergy = 4
@@ -130,46 +335,32 @@ func (f *TimeFilter) estimateEnergyRequirement(task models.Task) int {
 }
 
 func (f *TimeFilter) GetNextAvailableTimeSlot(ctx models.Context, task models.Task) (*time.Time, error) {
-	if task.EstimatedMinutes == nil {
+	estimatedMinutes, ok := f.config.EstimatedMinutes(task)
+	if !ok {
 		return nil, fmt.Errorf("task has no time estimate")
 	}
 
 	now := ctx.Timestamp
 	endOfDay := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, now.Location())
 	
-	estimatedDuration := time.Duration(*task.EstimatedMinutes) * time.Minute
+	estimatedDuration := time.Duration(estimatedMinutes) * time.Minute
 
 	events, err := f.calendarRepo.GetEventsByUserIDAndTimeRange(ctx.UserID, now, endOfDay)
 	if err != nil {
 		return nil, fmt.Errorf("unable to check calendar: %v", err)
 	}
 
-	if len(events) == 0 {
-		return &now, nil
-	}
-
-	for i := 0; i < len(events); i++ {
-		var slotEnd time.Time
-		if i == 0 {
-			slotEnd = events[i].StartAt
-		} else {
-			slotEnd = events[i].StartAt
-		}
-		
-		slotStart := now
-		if i > 0 {
-			slotStart = events[i-1].EndAt
-		}
-
-		if slotEnd.Sub(slotStart) >= estimatedDuration {
-			return &slotStart, nil
+	cursor := now
+	for _, block := range busyBlocks(events, now, endOfDay, TaskIgnoresAllDay(task)) {
+		if block.start.Sub(cursor) >= estimatedDuration {
+			return &cursor, nil
 		}
+		cursor = block.end
 	}
 
-	lastEventEnd := events[len(events)-1].EndAt
-	if endOfDay.Sub(lastEventEnd) >= estimatedDuration {
-		return &lastEventEnd, nil
+	if endOfDay.Sub(cursor) >= estimatedDuration {
+		return &cursor, nil
 	}
 
 	return nil, fmt.Errorf("no available time slot found for task duration")
-}
\ No newline at end of file
+}
@@ -0,0 +1,114 @@
+package filters
+
+import (
+	"math"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TravelMode is how the user gets to a task's location, which sets the
+// speed the time filter estimates travel time at
+type TravelMode string
+
+const (
+	TravelModeWalking TravelMode = "walking"
+	TravelModeDriving TravelMode = "driving"
+)
+
+const (
+	// DefaultWalkingSpeedKmh is the walking speed when
+	// FilterConfig.WalkingSpeedKmh is zero
+	DefaultWalkingSpeedKmh = 5.0
+	// DefaultDrivingSpeedKmh is the driving speed in light traffic when
+	// FilterConfig.DrivingSpeedKmh is zero
+	DefaultDrivingSpeedKmh = 40.0
+)
+
+// DefaultTrafficSpeedFactors slow driving down by the context's traffic
+// level when FilterConfig.TrafficSpeedFactors is empty
+var DefaultTrafficSpeedFactors = map[string]float64{
+	models.TrafficLow:      1.0,
+	models.TrafficModerate: 0.75,
+	models.TrafficHeavy:    0.5,
+}
+
+// IsValidTravelMode reports whether mode is known. The empty mode turns
+// travel time off.
+func IsValidTravelMode(mode TravelMode) bool {
+	switch mode {
+	case "", TravelModeWalking, TravelModeDriving:
+		return true
+	default:
+		return false
+	}
+}
+
+// CountsTravelTime reports whether the time filter adds the time to reach a
+// task's location to its estimate
+func (c FilterConfig) CountsTravelTime() bool {
+	return c.TravelMode == TravelModeWalking || c.TravelMode == TravelModeDriving
+}
+
+// TravelSpeedKmh returns the speed the user travels at in the context.
+// Driving is slowed by the context's traffic level; walking is not.
+func (c FilterConfig) TravelSpeedKmh(ctx models.Context) float64 {
+	if c.TravelMode == TravelModeWalking {
+		if c.WalkingSpeedKmh > 0 {
+			return c.WalkingSpeedKmh
+		}
+		return DefaultWalkingSpeedKmh
+	}
+
+	speed := c.DrivingSpeedKmh
+	if speed <= 0 {
+		speed = DefaultDrivingSpeedKmh
+	}
+	if ctx.TrafficLevel != nil {
+		factors := c.TrafficSpeedFactors
+		if len(factors) == 0 {
+			factors = DefaultTrafficSpeedFactors
+		}
+		if factor, ok := factors[*ctx.TrafficLevel]; ok && factor > 0 {
+			speed *= factor
+		}
+	}
+	return speed
+}
+
+// TravelMinutes returns how many whole minutes, rounded up, it takes to
+// cover meters at the context's travel speed
+func (c FilterConfig) TravelMinutes(ctx models.Context, meters float64) int {
+	if meters <= 0 {
+		return 0
+	}
+	hours := meters / 1000 / c.TravelSpeedKmh(ctx)
+	return int(math.Ceil(hours * 60))
+}
+
+// travelMinutes returns how long it takes to reach the task's nearest
+// location, zero when the user is already within its radius or the task has
+// no location
+func (f *TimeFilter) travelMinutes(ctx models.Context, task models.Task) int {
+	if f.taskLocations == nil || !f.config.CountsTravelTime() || ctx.CurrentLatitude == nil || ctx.CurrentLongitude == nil {
+		return 0
+	}
+
+	// The location filter reports lookup errors; travel time is left out
+	locations, err := f.taskLocations.GetLocationsByTaskID(task.ID)
+	if err != nil || len(locations) == 0 {
+		return 0
+	}
+
+	nearest := 0
+	nearestDistance := math.Inf(1)
+	for i, location := range locations {
+		if distance := location.DistanceFrom(*ctx.CurrentLatitude, *ctx.CurrentLongitude); distance < nearestDistance {
+			nearest, nearestDistance = i, distance
+		}
+	}
+
+	if nearestDistance <= float64(locations[nearest].Radius) {
+		return 0
+	}
+	return f.config.TravelMinutes(ctx, nearestDistance)
+}
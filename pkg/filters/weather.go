@@ -0,0 +1,145 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// WeatherRequirementKey is the task metadata key that says what weather a
+// task needs, e.g. {"weather_requirement": "dry"}
+const WeatherRequirementKey = "weather_requirement"
+
+// OutdoorKey is the task metadata flag that marks a task done outdoors,
+// e.g. {"outdoor": true}. An outdoor task without a weather requirement is
+// treated as needing dry weather.
+const OutdoorKey = "outdoor"
+
+// Weather requirements a task can set. A requirement may also name a single
+// weather condition, such as "sunny", to show the task only then.
+const (
+	// WeatherAny shows the task in any weather
+	WeatherAny = "any"
+	// WeatherIndoor marks a task done indoors, so weather never hides it
+	WeatherIndoor = "indoor"
+	// WeatherDry hides the task while it rains, snows or storms, or in the
+	// weather FilterConfig.WeatherHideConditions lists
+	WeatherDry = "dry"
+)
+
+// DefaultWeatherHideConditions are the conditions dry and outdoor tasks
+// wait out unless FilterConfig.WeatherHideConditions says otherwise
+var DefaultWeatherHideConditions = []string{
+	models.WeatherRainy,
+	models.WeatherSnowy,
+	models.WeatherStormy,
+}
+
+// WeatherFilter hides outdoor tasks when the weather does not suit them.
+// Tasks without a weather requirement, and every task while the weather is
+// unknown, stay visible.
+type WeatherFilter struct {
+	config FilterConfig
+}
+
+func NewWeatherFilter(config FilterConfig) *WeatherFilter {
+	return &WeatherFilter{config: config}
+}
+
+func (f *WeatherFilter) Name() string {
+	return "weather"
+}
+
+func (f *WeatherFilter) Priority() int {
+	return 95
+}
+
+func (f *WeatherFilter) Apply(ctx models.Context, task models.Task) (visible bool, reason string) {
+	visible, _, reason = f.Evaluate(ctx, task)
+	return visible, reason
+}
+
+func (f *WeatherFilter) Evaluate(ctx models.Context, task models.Task) (visible bool, code ReasonCode, reason string) {
+	if !f.config.EnableWeatherFilter {
+		return true, ReasonFilterDisabled, "weather filtering disabled"
+	}
+
+	requirement := TaskWeatherRequirement(task)
+	outdoor := false
+	if requirement == "" && TaskIsOutdoor(task) {
+		requirement, outdoor = WeatherDry, true
+	}
+	if requirement == "" || requirement == WeatherAny || requirement == WeatherIndoor {
+		return true, ReasonWeatherNotRequired, "task has no weather requirement"
+	}
+
+	if ctx.WeatherCondition == nil || *ctx.WeatherCondition == "" {
+		return true, ReasonWeatherUnknown, "current weather unknown - showing task"
+	}
+	condition := *ctx.WeatherCondition
+
+	switch {
+	case requirement == WeatherDry:
+		if f.hides(condition) {
+			if outdoor {
+				return false, ReasonWeatherUnsuitable, fmt.Sprintf("task requires outdoor conditions, current weather is %s", condition)
+			}
+			return false, ReasonWeatherUnsuitable, fmt.Sprintf("needs dry weather but it is %s", condition)
+		}
+		return true, ReasonWeatherSuitable, fmt.Sprintf("weather is %s, dry enough", condition)
+	case models.IsValidWeatherCondition(requirement):
+		if condition != requirement {
+			return false, ReasonWeatherUnsuitable, fmt.Sprintf("needs %s weather but it is %s", requirement, condition)
+		}
+		return true, ReasonWeatherSuitable, fmt.Sprintf("weather is %s as required", condition)
+	default:
+		return true, ReasonWeatherNotRequired, fmt.Sprintf("unknown weather requirement %q - showing task", requirement)
+	}
+}
+
+// hides reports whether condition is one that dry and outdoor tasks wait out
+func (f *WeatherFilter) hides(condition string) bool {
+	conditions := f.config.WeatherHideConditions
+	if len(conditions) == 0 {
+		conditions = DefaultWeatherHideConditions
+	}
+	for _, hidden := range conditions {
+		if strings.EqualFold(hidden, condition) {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskIsOutdoor reports whether the task's metadata flags it as outdoor
+func TaskIsOutdoor(task models.Task) bool {
+	if len(task.Metadata) == 0 {
+		return false
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(task.Metadata, &metadata); err != nil {
+		return false
+	}
+
+	outdoor, _ := metadata[OutdoorKey].(bool)
+	return outdoor
+}
+
+// TaskWeatherRequirement returns the task's weather requirement in lower
+// case, or "" when it has none or its metadata is unreadable
+func TaskWeatherRequirement(task models.Task) string {
+	if len(task.Metadata) == 0 {
+		return ""
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(task.Metadata, &metadata); err != nil {
+		return ""
+	}
+
+	requirement, _ := metadata[WeatherRequirementKey].(string)
+	return strings.ToLower(strings.TrimSpace(requirement))
+}
@@ -0,0 +1,199 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskAssignmentRepository stores tasks delegated from one user to another
+type TaskAssignmentRepository interface {
+	Create(assignment models.TaskAssignment) error
+	GetByID(assignmentID string) (*models.TaskAssignment, error)
+	Update(assignment models.TaskAssignment) error
+}
+
+// AssignmentService delegates tasks between users. Each operation writes the
+// assignment, the task and a notification for the other user; with a
+// transactor set they are committed together or not at all.
+type AssignmentService struct {
+	taskRepo         TaskRepository
+	assignmentRepo   TaskAssignmentRepository
+	notificationRepo NotificationRepository
+	transactor       Transactor
+	events           EventPublisher
+	clock            clock.Clock
+}
+
+func NewAssignmentService(tasks TaskRepository, assignments TaskAssignmentRepository, notifications NotificationRepository) *AssignmentService {
+	return &AssignmentService{
+		taskRepo:         tasks,
+		assignmentRepo:   assignments,
+		notificationRepo: notifications,
+		clock:            clock.Real(),
+	}
+}
+
+// SetTransactor makes each operation atomic. Without a transactor a failure
+// part way through can leave, for example, an assignment whose notification
+// was never sent.
+func (s *AssignmentService) SetTransactor(transactor Transactor) {
+	s.transactor = transactor
+}
+
+// SetEventPublisher makes the service publish the task changes it makes
+func (s *AssignmentService) SetEventPublisher(publisher EventPublisher) {
+	s.events = publisher
+}
+
+// AssignTask delegates the task from assignerID to assigneeID: it records a
+// pending assignment, makes assigneeID the task's assignee and notifies them
+func (s *AssignmentService) AssignTask(taskID, assigneeID, assignerID string) (*models.TaskAssignment, error) {
+	var assignment *models.TaskAssignment
+	var task *models.Task
+
+	err := s.withTx(func(tx *AssignmentService) error {
+		var err error
+		task, err = tx.taskRepo.GetByID(taskID)
+		if err != nil {
+			return fmt.Errorf("task not found: %w", err)
+		}
+		if task.IsCompleted() || task.IsCancelled() {
+			return fmt.Errorf("cannot assign a %s task", task.Status)
+		}
+
+		assignment, err = models.NewTaskAssignment(taskID, assignerID, assigneeID)
+		if err != nil {
+			return err
+		}
+		now := tx.clock.Now()
+		assignment.AssignedAt = now
+
+		if err := tx.assignmentRepo.Create(*assignment); err != nil {
+			return fmt.Errorf("failed to create assignment: %w", err)
+		}
+
+		task.AssigneeID = &assigneeID
+		task.UpdatedAt = now
+		if err := tx.taskRepo.Update(*task); err != nil {
+			return fmt.Errorf("failed to assign task: %w", err)
+		}
+
+		message := fmt.Sprintf("You were assigned a task: %s", task.Title)
+		return tx.notify(assigneeID, models.NotificationTypeTaskAssigned, taskID, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(EventTaskUpdated, assignerID, *task)
+
+	return assignment, nil
+}
+
+// CompleteAssignedTask completes the task of an assignment on behalf of its
+// assignee, accepting the assignment if it was still pending, and tells the
+// user who assigned it
+func (s *AssignmentService) CompleteAssignedTask(assignmentID, userID string) (*models.Task, error) {
+	var task *models.Task
+
+	err := s.withTx(func(tx *AssignmentService) error {
+		assignment, err := tx.assignmentRepo.GetByID(assignmentID)
+		if err != nil {
+			return fmt.Errorf("assignment not found: %w", err)
+		}
+		if !assignment.IsAssignedTo(userID) {
+			return fmt.Errorf("only the assignee can complete an assigned task")
+		}
+		if assignment.IsRejected() {
+			return fmt.Errorf("cannot complete a rejected assignment")
+		}
+
+		task, err = tx.taskRepo.GetByID(assignment.TaskID)
+		if err != nil {
+			return fmt.Errorf("task not found: %w", err)
+		}
+		if task.IsCompleted() {
+			return fmt.Errorf("task is already completed")
+		}
+
+		now := tx.clock.Now()
+		if assignment.IsPending() {
+			if err := assignment.Accept(nil); err != nil {
+				return err
+			}
+			assignment.ResponseAt = &now
+			if err := tx.assignmentRepo.Update(*assignment); err != nil {
+				return fmt.Errorf("failed to accept assignment: %w", err)
+			}
+		}
+
+		task.Status = models.TaskStatusCompleted
+		task.CompletedAt = &now
+		task.UpdatedAt = now
+		if err := tx.taskRepo.Update(*task); err != nil {
+			return fmt.Errorf("failed to complete task: %w", err)
+		}
+
+		message := fmt.Sprintf("A task you assigned was completed: %s", task.Title)
+		return tx.notify(assignment.AssignedBy, models.NotificationTypeTaskCompleted, task.ID, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(EventTaskCompleted, userID, *task)
+
+	return task, nil
+}
+
+// withTx runs fn against a copy of the service whose repositories are bound
+// to a transaction, or against the service itself when no transactor is set
+func (s *AssignmentService) withTx(fn func(tx *AssignmentService) error) error {
+	if s.transactor == nil {
+		return fn(s)
+	}
+
+	return s.transactor.WithTx(func(repos TxRepositories) error {
+		txService := *s
+		txService.transactor = nil
+		if repos.Tasks != nil {
+			txService.taskRepo = repos.Tasks
+		}
+		if repos.Assignments != nil {
+			txService.assignmentRepo = repos.Assignments
+		}
+		if repos.Notifications != nil {
+			txService.notificationRepo = repos.Notifications
+		}
+		return fn(&txService)
+	})
+}
+
+func (s *AssignmentService) notify(userID string, notificationType models.NotificationType, taskID, message string) error {
+	notification, err := models.NewTaskNotification(userID, notificationType, taskID, message)
+	if err != nil {
+		return fmt.Errorf("invalid notification: %w", err)
+	}
+	notification.CreatedAt = s.clock.Now()
+
+	if err := s.notificationRepo.Create(*notification); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+func (s *AssignmentService) publish(eventType ChangeEventType, actorID string, task models.Task) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(ChangeEvent{
+		Type:      eventType,
+		ActorID:   actorID,
+		ListID:    task.ListID,
+		Task:      &task,
+		Timestamp: s.clock.Now(),
+	})
+}
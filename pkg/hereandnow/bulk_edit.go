@@ -0,0 +1,91 @@
+package hereandnow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// BulkEditRequest holds the fields to set on every selected task. Nil fields
+// are left as they are.
+type BulkEditRequest struct {
+	Priority         *int
+	EstimatedMinutes *int
+	DueAt            *time.Time
+}
+
+// IsEmpty reports whether the request changes nothing
+func (r BulkEditRequest) IsEmpty() bool {
+	return r.Priority == nil && r.EstimatedMinutes == nil && r.DueAt == nil
+}
+
+// BulkEditTasks applies req to each of the user's tasks matching sel and
+// returns the updated tasks. Every change is checked before anything is
+// written, and the tasks are saved in one transaction, so either all of
+// them change or none do.
+func (s *TaskService) BulkEditTasks(userID string, sel TaskSelector, req BulkEditRequest) ([]models.Task, error) {
+	tasks, err := s.PreviewBulkEdit(userID, sel, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withTx(func(tx *TaskService) error {
+		for i, task := range tasks {
+			if err := tx.saveTask(userID, &tasks[i]); err != nil {
+				return fmt.Errorf("failed to update task %s: %w", task.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		s.publishTask(EventTaskUpdated, userID, task)
+	}
+
+	return tasks, nil
+}
+
+// PreviewBulkEdit returns the tasks BulkEditTasks would change, after
+// checking the changes, without writing anything
+func (s *TaskService) PreviewBulkEdit(userID string, sel TaskSelector, req BulkEditRequest) ([]models.Task, error) {
+	if req.IsEmpty() {
+		return nil, fmt.Errorf("bulk edit requires at least one field to change")
+	}
+
+	tasks, err := s.SelectTasks(userID, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if err := s.checkListNotArchived(tasks[i]); err != nil {
+			return nil, err
+		}
+		if err := req.apply(&tasks[i]); err != nil {
+			return nil, fmt.Errorf("invalid change to task %s: %w", tasks[i].ID, err)
+		}
+	}
+
+	return tasks, nil
+}
+
+func (r BulkEditRequest) apply(task *models.Task) error {
+	if r.Priority != nil {
+		if err := task.SetPriority(*r.Priority); err != nil {
+			return err
+		}
+	}
+	if r.EstimatedMinutes != nil {
+		if err := task.SetEstimatedMinutes(*r.EstimatedMinutes); err != nil {
+			return err
+		}
+	}
+	if r.DueAt != nil {
+		task.SetDueDate(*r.DueAt)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package hereandnow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ContextPresetRepository stores each user's named context presets
+type ContextPresetRepository interface {
+	Save(preset models.ContextPreset) error
+	GetByName(userID, name string) (*models.ContextPreset, error)
+	GetByUserID(userID string) ([]models.ContextPreset, error)
+	Delete(userID, name string) error
+}
+
+// EnableContextPresets lets users save contexts under a name and apply them
+// later
+func (s *ContextService) EnableContextPresets(presets ContextPresetRepository) {
+	s.presetRepo = presets
+}
+
+// SaveContextPreset stores preset, replacing the user's preset of the same
+// name
+func (s *ContextService) SaveContextPreset(preset models.ContextPreset) (*models.ContextPreset, error) {
+	if s.presetRepo == nil {
+		return nil, fmt.Errorf("context presets are not enabled")
+	}
+
+	if err := preset.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid context preset: %w", err)
+	}
+
+	if preset.LocationID != nil {
+		location, err := s.locationRepo.GetByID(*preset.LocationID)
+		if err != nil || location.UserID != preset.UserID {
+			return nil, fmt.Errorf("preset location not found: %s", *preset.LocationID)
+		}
+	}
+
+	// Saving over an existing preset keeps its identity
+	if existing, err := s.presetRepo.GetByName(preset.UserID, preset.Name); err == nil {
+		preset.ID = existing.ID
+		preset.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.presetRepo.Save(preset); err != nil {
+		return nil, fmt.Errorf("failed to save context preset: %w", err)
+	}
+
+	return &preset, nil
+}
+
+// SavePresetFromCurrent saves the user's latest context as a preset named
+// name
+func (s *ContextService) SavePresetFromCurrent(userID, name string) (*models.ContextPreset, error) {
+	current, err := s.contextRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current context: %w", err)
+	}
+
+	preset, err := models.NewContextPreset(userID, name)
+	if err != nil {
+		return nil, err
+	}
+	preset.CaptureContext(*current)
+
+	return s.SaveContextPreset(*preset)
+}
+
+// ApplyContextPreset creates a new context snapshot from the user's preset.
+// A preset at a saved location takes the location's coordinates as they are
+// now.
+func (s *ContextService) ApplyContextPreset(userID, name string) (*models.Context, error) {
+	if s.presetRepo == nil {
+		return nil, fmt.Errorf("context presets are not enabled")
+	}
+
+	preset, err := s.presetRepo.GetByName(userID, strings.ToLower(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context preset: %w", err)
+	}
+
+	req := UpdateContextRequest{
+		Latitude:         preset.Latitude,
+		Longitude:        preset.Longitude,
+		AvailableMinutes: preset.AvailableMinutes,
+		SocialContext:    preset.SocialContext,
+		EnergyLevel:      preset.EnergyLevel,
+	}
+
+	if preset.LocationID != nil {
+		location, err := s.locationRepo.GetByID(*preset.LocationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve preset location: %w", err)
+		}
+		if location.UserID != userID {
+			return nil, fmt.Errorf("failed to resolve preset location: location not found")
+		}
+		req.LocationID = &location.ID
+		req.Latitude = &location.Latitude
+		req.Longitude = &location.Longitude
+	}
+
+	return s.UpdateUserContext(userID, req)
+}
+
+func (s *ContextService) GetContextPresets(userID string) ([]models.ContextPreset, error) {
+	if s.presetRepo == nil {
+		return nil, fmt.Errorf("context presets are not enabled")
+	}
+
+	presets, err := s.presetRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context presets: %w", err)
+	}
+
+	return presets, nil
+}
+
+func (s *ContextService) DeleteContextPreset(userID, name string) error {
+	if s.presetRepo == nil {
+		return fmt.Errorf("context presets are not enabled")
+	}
+
+	if err := s.presetRepo.Delete(userID, strings.ToLower(name)); err != nil {
+		return fmt.Errorf("failed to delete context preset: %w", err)
+	}
+
+	return nil
+}
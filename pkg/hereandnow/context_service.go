@@ -5,16 +5,33 @@ import (
 	"math"
 	"time"
 
+	"github.com/bcnelson/hereAndNow/pkg/clock"
 	"github.com/bcnelson/hereAndNow/pkg/models"
 	"github.com/google/uuid"
 )
 
 type ContextService struct {
-	contextRepo     ContextRepository
-	locationRepo    LocationRepository
-	calendarRepo    CalendarEventRepository
-	weatherService  WeatherService
-	trafficService  TrafficService
+	contextRepo      ContextRepository
+	locationRepo     LocationRepository
+	calendarRepo     CalendarEventRepository
+	weatherService   WeatherService
+	trafficService   TrafficService
+	energyProfiles   EnergyProfileRepository
+	locationTasks    LocationTaskRepository
+	reminderTasks    ReminderTaskRepository
+	notificationRepo NotificationRepository
+	geofenceRepo     GeofenceEventRepository
+	presetRepo       ContextPresetRepository
+	clock            clock.Clock
+	contextMaxAge    time.Duration
+}
+
+// EnergyProfileWindow is how far back energy history is considered when
+// predicting a default energy level
+const EnergyProfileWindow = 30 * 24 * time.Hour
+
+type EnergyProfileRepository interface {
+	GetEnergyProfile(userID string, since time.Time, loc *time.Location) (*models.EnergyProfile, error)
 }
 
 type LocationRepository interface {
@@ -61,14 +78,51 @@ func NewContextService(
 		calendarRepo:   calendarRepo,
 		weatherService: weatherService,
 		trafficService: trafficService,
+		clock:          clock.Real(),
+		contextMaxAge:  models.DefaultContextMaxAge,
+	}
+}
+
+// SetClock replaces the clock context snapshots are timestamped with, for
+// tests that need to control it
+func (s *ContextService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetContextMaxAge sets how old the current context can get before
+// GetCurrentContext reports it stale; it should match the filter config's
+// ContextMaxAge. Zero never reports it stale.
+func (s *ContextService) SetContextMaxAge(maxAge time.Duration) {
+	s.contextMaxAge = maxAge
+}
+
+// EnableEnergyPrediction makes context updates without an energy level
+// default to the user's historical average for that hour of day instead of
+// models.DefaultEnergyLevel.
+func (s *ContextService) EnableEnergyPrediction(energyProfiles EnergyProfileRepository) {
+	s.energyProfiles = energyProfiles
+}
+
+// DefaultEnergyLevel returns the energy level assumed for a context at the
+// given time when the user does not specify one.
+func (s *ContextService) DefaultEnergyLevel(userID string, at time.Time) int {
+	if s.energyProfiles == nil {
+		return models.DefaultEnergyLevel
+	}
+
+	profile, err := s.energyProfiles.GetEnergyProfile(userID, at.Add(-EnergyProfileWindow), at.Location())
+	if err != nil {
+		return models.DefaultEnergyLevel
 	}
+
+	return profile.PredictEnergyLevel(at)
 }
 
 func (s *ContextService) UpdateUserContext(userID string, req UpdateContextRequest) (*models.Context, error) {
 	context := models.Context{
 		ID:                uuid.New().String(),
 		UserID:            userID,
-		Timestamp:         time.Now(),
+		Timestamp:         s.clock.Now(),
 		CurrentLatitude:   req.Latitude,
 		CurrentLongitude:  req.Longitude,
 		CurrentLocationID: req.LocationID,
@@ -80,6 +134,18 @@ func (s *ContextService) UpdateUserContext(userID string, req UpdateContextReque
 		Metadata:          req.Metadata,
 	}
 
+	if context.EnergyLevel == 0 {
+		context.EnergyLevel = s.DefaultEnergyLevel(userID, context.Timestamp)
+	}
+
+	if req.MinPriority != nil {
+		if err := context.SetMinPriority(*req.MinPriority); err != nil {
+			return nil, err
+		}
+	} else if latest, err := s.contextRepo.GetLatestByUserID(userID); err == nil && latest != nil {
+		context.MinPriority = latest.MinPriority
+	}
+
 	if req.Latitude != nil && req.Longitude != nil {
 		if err := s.enrichContextWithLocation(&context); err != nil {
 			return nil, fmt.Errorf("failed to enrich context with location: %w", err)
@@ -102,10 +168,40 @@ func (s *ContextService) UpdateUserContext(userID string, req UpdateContextReque
 		context.AvailableMinutes = availableMinutes
 	}
 
+	previous := s.previousContext(userID)
+
+	if err := s.contextRepo.Create(context); err != nil {
+		return nil, fmt.Errorf("failed to save context: %w", err)
+	}
+
+	s.crossGeofences(userID, previous, context)
+
+	return &context, nil
+}
+
+// UpdateContext records context as the user's latest context. Contexts are
+// kept as snapshots, so it is saved under a new ID and timestamp, with the
+// current location re-derived from its coordinates.
+func (s *ContextService) UpdateContext(context models.Context) (*models.Context, error) {
+	context.ID = uuid.New().String()
+	context.Timestamp = s.clock.Now()
+	context.AgeSeconds, context.IsStale = 0, false
+
+	if context.CurrentLatitude != nil && context.CurrentLongitude != nil {
+		context.CurrentLocationID = nil
+		if err := s.enrichContextWithLocation(&context); err != nil {
+			return nil, fmt.Errorf("failed to enrich context with location: %w", err)
+		}
+	}
+
+	previous := s.previousContext(context.UserID)
+
 	if err := s.contextRepo.Create(context); err != nil {
 		return nil, fmt.Errorf("failed to save context: %w", err)
 	}
 
+	s.crossGeofences(context.UserID, previous, context)
+
 	return &context, nil
 }
 
@@ -115,7 +211,10 @@ func (s *ContextService) GetCurrentContext(userID string) (*models.Context, erro
 		return nil, fmt.Errorf("failed to get current context: %w", err)
 	}
 
-	if time.Since(context.Timestamp) > 15*time.Minute {
+	// A stale context is returned as it is, so its position isn't carried
+	// into a fresh snapshot
+	context.MarkAge(s.clock.Now(), s.contextMaxAge)
+	if context.Age() > 15*time.Minute && !context.IsStale {
 		context, err = s.refreshContext(userID, *context)
 		if err != nil {
 			return nil, fmt.Errorf("failed to refresh context: %w", err)
@@ -126,7 +225,7 @@ func (s *ContextService) GetCurrentContext(userID string) (*models.Context, erro
 }
 
 func (s *ContextService) CreateContextFromLocation(userID string, latitude, longitude float64) (*models.Context, error) {
-	availableMinutes, err := s.calculateAvailableMinutes(userID, time.Now())
+	availableMinutes, err := s.calculateAvailableMinutes(userID, s.clock.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate available minutes: %w", err)
 	}
@@ -134,7 +233,7 @@ func (s *ContextService) CreateContextFromLocation(userID string, latitude, long
 	context := models.Context{
 		ID:               uuid.New().String(),
 		UserID:           userID,
-		Timestamp:        time.Now(),
+		Timestamp:        s.clock.Now(),
 		CurrentLatitude:  &latitude,
 		CurrentLongitude: &longitude,
 		AvailableMinutes: availableMinutes,
@@ -154,10 +253,14 @@ func (s *ContextService) CreateContextFromLocation(userID string, latitude, long
 		return nil, fmt.Errorf("failed to enrich traffic: %w", err)
 	}
 
+	previous := s.previousContext(userID)
+
 	if err := s.contextRepo.Create(context); err != nil {
 		return nil, fmt.Errorf("failed to save context: %w", err)
 	}
 
+	s.crossGeofences(userID, previous, context)
+
 	return &context, nil
 }
 
@@ -261,7 +364,7 @@ func (s *ContextService) refreshContext(userID string, oldContext models.Context
 	newContext := models.Context{
 		ID:                uuid.New().String(),
 		UserID:            userID,
-		Timestamp:         time.Now(),
+		Timestamp:         s.clock.Now(),
 		CurrentLatitude:   oldContext.CurrentLatitude,
 		CurrentLongitude:  oldContext.CurrentLongitude,
 		CurrentLocationID: oldContext.CurrentLocationID,
@@ -411,7 +514,10 @@ type UpdateContextRequest struct {
 	EnergyLevel      int      `json:"energy_level"`
 	WeatherCondition *string  `json:"weather_condition"`
 	TrafficLevel     *string  `json:"traffic_level"`
-	Metadata         []byte   `json:"metadata"`
+	// MinPriority replaces the minimum priority; when nil the previous
+	// context's minimum carries over
+	MinPriority *int   `json:"min_priority"`
+	Metadata    []byte `json:"metadata"`
 }
 
 type ContextSuggestions struct {
@@ -0,0 +1,295 @@
+package hereandnow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// duplicateThreshold is the title similarity, from 0 to 1, at which two open
+// tasks are suggested as duplicates
+const duplicateThreshold = 0.8
+
+// titleStopWords are left out when comparing titles, so "Buy milk" and "Buy
+// some milk" match
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "some": true, "more": true, "to": true,
+}
+
+// listMembershipLister is implemented by list member repositories that can
+// find the lists a user belongs to
+type listMembershipLister interface {
+	GetByUserID(userID string) ([]models.ListMember, error)
+}
+
+// SetListMemberRepository lets the service see the shared lists a user
+// belongs to
+func (s *TaskService) SetListMemberRepository(members ListMemberRepository) {
+	s.memberRepo = members
+}
+
+// DuplicateGroup is a set of open tasks that look like the same errand.
+// Keep is the one a merge keeps: the earliest created, so the original
+// creator is preserved.
+type DuplicateGroup struct {
+	Keep       models.Task   `json:"keep"`
+	Duplicates []models.Task `json:"duplicates"`
+	// Similarity is the lowest title similarity within the group, from 0 to 1
+	Similarity float64 `json:"similarity"`
+}
+
+// FindDuplicateTasks suggests merges among the open tasks the user can see:
+// their own and those in shared lists they have joined. Tasks match when
+// their normalized titles are similar and they are not tied to different
+// places; a task with no location matches any. Groups are ordered by their
+// kept task's title.
+func (s *TaskService) FindDuplicateTasks(userID string) ([]DuplicateGroup, error) {
+	tasks, err := s.visibleOpenTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make([]string, len(tasks))
+	locations := make([]map[string]bool, len(tasks))
+	for i, task := range tasks {
+		titles[i] = normalizeTitle(task.Title)
+		taskLocations, err := s.taskLocationRepo.GetLocationsByTaskID(task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get locations for task %s: %w", task.ID, err)
+		}
+		locations[i] = map[string]bool{}
+		for _, location := range taskLocations {
+			locations[i][location.ID] = true
+		}
+	}
+
+	// Union similar pairs into groups
+	parent := make([]int, len(tasks))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	similarity := map[int]float64{}
+	for i := range tasks {
+		for j := i + 1; j < len(tasks); j++ {
+			if !sharesPlace(locations[i], locations[j]) {
+				continue
+			}
+			score := titleSimilarity(titles[i], titles[j])
+			if score < duplicateThreshold {
+				continue
+			}
+			ri, rj := find(i), find(j)
+			lowest := score
+			for _, root := range []int{ri, rj} {
+				if existing, ok := similarity[root]; ok && existing < lowest {
+					lowest = existing
+				}
+			}
+			parent[rj] = ri
+			delete(similarity, rj)
+			similarity[ri] = lowest
+		}
+	}
+
+	members := map[int][]models.Task{}
+	for i, task := range tasks {
+		root := find(i)
+		members[root] = append(members[root], task)
+	}
+
+	groups := []DuplicateGroup{}
+	for root, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].CreatedAt.Before(group[j].CreatedAt)
+		})
+		groups = append(groups, DuplicateGroup{
+			Keep:       group[0],
+			Duplicates: group[1:],
+			Similarity: similarity[root],
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Keep.Title) < strings.ToLower(groups[j].Keep.Title)
+	})
+
+	return groups, nil
+}
+
+// MergeDuplicates keeps the group's Keep task and cancels its duplicates in
+// one transaction, as a change by userID. With task links enabled each cancelled task is also
+// linked to the kept one as its duplicate. Duplicates that were completed or
+// cancelled since the group was found are left alone. It returns the tasks
+// it cancelled.
+func (s *TaskService) MergeDuplicates(userID string, group DuplicateGroup) ([]models.Task, error) {
+	if _, err := s.taskRepo.GetByID(group.Keep.ID); err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	var cancelled []models.Task
+	err := s.withTx(func(tx *TaskService) error {
+		for _, duplicate := range group.Duplicates {
+			task, err := tx.taskRepo.GetByID(duplicate.ID)
+			if err != nil {
+				return fmt.Errorf("task not found: %w", err)
+			}
+			if task.IsCompleted() || task.IsCancelled() {
+				continue
+			}
+
+			task.Status = models.TaskStatusCancelled
+			task.UpdatedAt = s.clock.Now()
+			if err := tx.saveTask(userID, task); err != nil {
+				return fmt.Errorf("failed to cancel duplicate task %s: %w", task.ID, err)
+			}
+
+			if tx.linkRepo != nil {
+				if existing, err := tx.findTaskLink(task.ID, group.Keep.ID); err == nil && existing == nil {
+					link, err := models.NewTaskLink(task.ID, group.Keep.ID, models.TaskLinkTypeDuplicate)
+					if err != nil {
+						return err
+					}
+					if err := tx.linkRepo.Create(*link); err != nil {
+						return fmt.Errorf("failed to link duplicate task %s: %w", task.ID, err)
+					}
+				}
+			}
+
+			cancelled = append(cancelled, *task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range cancelled {
+		s.publishTask(EventTaskUpdated, userID, task)
+	}
+
+	return cancelled, nil
+}
+
+// visibleOpenTasks returns the user's open tasks and those in shared lists
+// they have joined, each once
+func (s *TaskService) visibleOpenTasks(userID string) ([]models.Task, error) {
+	tasks, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tasks: %w", err)
+	}
+
+	if lister, ok := s.memberRepo.(listMembershipLister); ok {
+		memberships, err := lister.GetByUserID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list memberships: %w", err)
+		}
+		for _, membership := range memberships {
+			if !membership.HasAccepted() {
+				continue
+			}
+			listTasks, err := s.taskRepo.GetByListID(membership.ListID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get tasks for list %s: %w", membership.ListID, err)
+			}
+			tasks = append(tasks, listTasks...)
+		}
+	}
+
+	seen := map[string]bool{}
+	open := []models.Task{}
+	for _, task := range tasks {
+		if seen[task.ID] || task.IsCompleted() || task.IsCancelled() {
+			continue
+		}
+		seen[task.ID] = true
+		open = append(open, task)
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].CreatedAt.Before(open[j].CreatedAt)
+	})
+	return open, nil
+}
+
+// sharesPlace reports whether two tasks' locations allow them to be the same
+// errand: either has none, or they have one in common
+func sharesPlace(a, b map[string]bool) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for id := range a {
+		if b[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTitle lowercases a title, drops punctuation and stop words, and
+// trims plural "s", so trivially different titles compare equal
+func normalizeTitle(title string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, title)
+
+	var words []string
+	for _, word := range strings.Fields(cleaned) {
+		if titleStopWords[word] {
+			continue
+		}
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = strings.TrimSuffix(word, "s")
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+// titleSimilarity scores two normalized titles from 0 to 1 by edit distance,
+// so small typos still match
+func titleSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,168 @@
+package hereandnow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ErrDependencyNotAllowed is returned when a user links tasks that are not
+// all theirs outside the shared lists they can edit
+var ErrDependencyNotAllowed = errors.New("tasks that are not all yours can only depend on each other in shared lists you can edit")
+
+// ErrDependencyExists is returned for a dependency the task already has
+var ErrDependencyExists = errors.New("task already depends on that task")
+
+// TaskDependencyDetail is one of a task's dependencies with the task it
+// waits on
+type TaskDependencyDetail struct {
+	Dependency models.TaskDependency `json:"dependency"`
+	Task       models.Task           `json:"task"`
+}
+
+// AddDependency makes taskID depend on dependsOnTaskID. A blocking
+// dependency hides the task until the other is completed; a suggested one
+// only recommends doing the other first. Dependencies that would make a
+// task wait on itself fail with a *models.DependencyCycleError naming the
+// loop.
+func (s *TaskService) AddDependency(userID, taskID, dependsOnTaskID string, dependencyType models.DependencyType) (*models.TaskDependency, error) {
+	dependency, err := models.NewTaskDependency(taskID, dependsOnTaskID, dependencyType)
+	if err != nil {
+		return nil, err
+	}
+	dependency.CreatedAt = s.clock.Now()
+
+	task, err := s.checkDependencyAccess(userID, taskID, dependsOnTaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.dependencyRepo.GetDependenciesByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task dependencies: %w", err)
+	}
+	if dependsOn(existing, dependsOnTaskID) {
+		return nil, ErrDependencyExists
+	}
+
+	if err := s.dependencyRepo.AddDependency(*dependency); err != nil {
+		return nil, fmt.Errorf("failed to add dependency: %w", err)
+	}
+
+	s.publishTask(EventTaskUpdated, userID, *task)
+	return dependency, nil
+}
+
+// RemoveDependency stops taskID depending on dependsOnTaskID
+func (s *TaskService) RemoveDependency(userID, taskID, dependsOnTaskID string) error {
+	task, err := s.checkDependencyAccess(userID, taskID, dependsOnTaskID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.dependencyRepo.GetDependenciesByTaskID(taskID)
+	if err != nil {
+		return fmt.Errorf("failed to get task dependencies: %w", err)
+	}
+	if !dependsOn(existing, dependsOnTaskID) {
+		return fmt.Errorf("task dependency not found")
+	}
+
+	if err := s.dependencyRepo.Delete(taskID, dependsOnTaskID); err != nil {
+		return fmt.Errorf("failed to remove dependency: %w", err)
+	}
+
+	s.publishTask(EventTaskUpdated, userID, *task)
+	return nil
+}
+
+// GetTaskDependencies returns the tasks taskID depends on, skipping any
+// that no longer exist
+func (s *TaskService) GetTaskDependencies(taskID string) ([]TaskDependencyDetail, error) {
+	dependencies, err := s.dependencyRepo.GetDependenciesByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task dependencies: %w", err)
+	}
+
+	details := make([]TaskDependencyDetail, 0, len(dependencies))
+	for _, dep := range dependencies {
+		other, err := s.taskRepo.GetByID(dep.DependsOnTaskID)
+		if err != nil {
+			continue
+		}
+		details = append(details, TaskDependencyDetail{Dependency: dep, Task: *other})
+	}
+	return details, nil
+}
+
+// checkDependencyAccess returns taskID's task when userID may change its
+// dependency on dependsOnTaskID: both tasks are the user's, or both are in
+// lists the user can edit, which needs SetListMemberRepository
+func (s *TaskService) checkDependencyAccess(userID, taskID, dependsOnTaskID string) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+	other, err := s.taskRepo.GetByID(dependsOnTaskID)
+	if err != nil {
+		return nil, fmt.Errorf("dependency task not found: %w", err)
+	}
+
+	if ownsTask(*task, userID) && ownsTask(*other, userID) {
+		return task, nil
+	}
+
+	for _, t := range []models.Task{*task, *other} {
+		canEdit, err := s.canEditListOf(t, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !canEdit {
+			return nil, ErrDependencyNotAllowed
+		}
+	}
+	return task, nil
+}
+
+// dependsOn reports whether any of the dependencies waits on taskID
+func dependsOn(dependencies []models.TaskDependency, taskID string) bool {
+	for _, dep := range dependencies {
+		if dep.DependsOnTaskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsTask reports whether the user created the task or is assigned it
+func ownsTask(task models.Task, userID string) bool {
+	return task.CreatorID == userID || (task.AssigneeID != nil && *task.AssigneeID == userID)
+}
+
+// canEditListOf reports whether the task is in a list the user owns or has
+// joined as an owner or editor
+func (s *TaskService) canEditListOf(task models.Task, userID string) (bool, error) {
+	if task.ListID == nil {
+		return false, nil
+	}
+	if s.listRepo != nil {
+		if list, err := s.listRepo.GetByID(*task.ListID); err == nil && list.IsOwnedBy(userID) {
+			return true, nil
+		}
+	}
+	if s.memberRepo == nil {
+		return false, nil
+	}
+
+	members, err := s.memberRepo.GetByListID(*task.ListID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get list members: %w", err)
+	}
+	for _, member := range members {
+		if member.UserID == userID && member.HasAccepted() && member.CanEdit() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,321 @@
+package hereandnow
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ChangeEventType names a change pushed to connected clients
+type ChangeEventType string
+
+const (
+	EventTaskCreated     ChangeEventType = "task.created"
+	EventTaskUpdated     ChangeEventType = "task.updated"
+	EventTaskCompleted   ChangeEventType = "task.completed"
+	EventTaskDeleted     ChangeEventType = "task.deleted"
+	EventListMemberAdded ChangeEventType = "list.member_added"
+	// EventListMemberRemoved also ends the removed member's list streams
+	EventListMemberRemoved ChangeEventType = "list.member_removed"
+)
+
+// DefaultEventBufferSize is how many recent events an EventHub keeps for
+// clients that reconnect
+const DefaultEventBufferSize = 256
+
+// subscriberQueueSize is how many events a subscriber can fall behind before
+// it is dropped and has to resume from its last event ID
+const subscriberQueueSize = 64
+
+// ChangeEvent is a change to a task or list that the users who can see it
+// are told about as it happens
+type ChangeEvent struct {
+	ID        string             `json:"id"`
+	Type      ChangeEventType    `json:"type"`
+	ActorID   string             `json:"actor_id,omitempty"`
+	ListID    *string            `json:"list_id,omitempty"`
+	Task      *models.Task       `json:"task,omitempty"`
+	Member    *models.ListMember `json:"member,omitempty"`
+	Timestamp time.Time          `json:"timestamp"`
+
+	recipients []string
+}
+
+// EventPublisher receives every change a service makes once it is saved
+type EventPublisher interface {
+	Publish(event ChangeEvent)
+}
+
+// SetEventPublisher makes the service publish task changes, e.g. to an
+// EventHub serving connected clients
+func (s *TaskService) SetEventPublisher(publisher EventPublisher) {
+	s.events = publisher
+}
+
+// publishTask announces a saved change to task. actorID is the user who made
+// it, when known.
+func (s *TaskService) publishTask(eventType ChangeEventType, actorID string, task models.Task) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(ChangeEvent{
+		Type:      eventType,
+		ActorID:   actorID,
+		ListID:    task.ListID,
+		Task:      &task,
+		Timestamp: s.clock.Now(),
+	})
+}
+
+// EventHub fans published changes out to subscribed users. Each user only
+// receives changes to tasks they created or are assigned and to lists they
+// own or are an accepted member of. The most recent events are kept in a
+// ring buffer so a client that reconnects can resume where it left off.
+// Clients can also follow a single list, keyed by its ID, with SubscribeList.
+type EventHub struct {
+	lists   ListLookupRepository
+	members ListMemberRepository
+	clock   clock.Clock
+
+	mu              sync.Mutex
+	lastID          uint64
+	buffer          []ChangeEvent
+	next            int
+	subscribers     map[*Subscription]bool
+	listSubscribers map[string]map[*Subscription]bool
+}
+
+// Subscription is one connected client's feed of events
+type Subscription struct {
+	UserID string
+	// ListID is set for subscriptions to a single list's changes
+	ListID string
+	// Missed are the buffered events after the ID the client resumed from
+	Missed []ChangeEvent
+	// Reset is set when the client resumed from an event that is no longer
+	// buffered, so it may have missed changes and should reload
+	Reset bool
+
+	events chan ChangeEvent
+	hub    *EventHub
+}
+
+// NewEventHub creates a hub that keeps the last bufferSize events for
+// resuming, DefaultEventBufferSize when bufferSize is not positive. lists
+// and members work out who can see changes to shared lists; without them
+// only a task's creator and assignee are told.
+func NewEventHub(lists ListLookupRepository, members ListMemberRepository, bufferSize int) *EventHub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBufferSize
+	}
+	return &EventHub{
+		lists:           lists,
+		members:         members,
+		clock:           clock.Real(),
+		buffer:          make([]ChangeEvent, 0, bufferSize),
+		subscribers:     map[*Subscription]bool{},
+		listSubscribers: map[string]map[*Subscription]bool{},
+	}
+}
+
+// Publish numbers the event, buffers it and sends it to the subscribers who
+// can see it. A subscriber too far behind to take it is dropped: its events
+// channel is closed and it can resume from the buffer.
+func (h *EventHub) Publish(event ChangeEvent) {
+	event.recipients = h.recipients(event)
+	if event.Timestamp.IsZero() {
+		event.Timestamp = h.clock.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event.ID = strconv.FormatUint(h.lastID, 10)
+	if len(h.buffer) < cap(h.buffer) {
+		h.buffer = append(h.buffer, event)
+	} else {
+		h.buffer[h.next] = event
+		h.next = (h.next + 1) % len(h.buffer)
+	}
+
+	for sub := range h.subscribers {
+		if !event.isFor(sub.UserID) {
+			continue
+		}
+		h.send(sub, event)
+	}
+
+	if event.ListID == nil {
+		return
+	}
+	for sub := range h.listSubscribers[*event.ListID] {
+		h.send(sub, event)
+		// A removed member's streams end once they are told
+		if event.Type == EventListMemberRemoved && event.Member != nil && event.Member.UserID == sub.UserID {
+			h.drop(sub)
+		}
+	}
+}
+
+// send queues the event for the subscriber, dropping a subscriber too far
+// behind to take it
+func (h *EventHub) send(sub *Subscription, event ChangeEvent) {
+	select {
+	case sub.events <- event:
+	default:
+		h.drop(sub)
+	}
+}
+
+// Subscribe starts a feed of the user's events. lastEventID is the ID of the
+// last event the client saw, from the Last-Event-ID header, or empty for a
+// new connection.
+func (h *EventHub) Subscribe(userID, lastEventID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		UserID: userID,
+		events: make(chan ChangeEvent, subscriberQueueSize),
+		hub:    h,
+	}
+	if lastEventID != "" {
+		sub.Missed, sub.Reset = h.since(userID, lastEventID)
+	}
+	h.subscribers[sub] = true
+	return sub
+}
+
+// SubscribeList starts a feed of every change to the list for userID, who
+// the caller has checked can view it. The feed ends when the user is
+// removed from the list.
+func (h *EventHub) SubscribeList(listID, userID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		UserID: userID,
+		ListID: listID,
+		events: make(chan ChangeEvent, subscriberQueueSize),
+		hub:    h,
+	}
+	if h.listSubscribers[listID] == nil {
+		h.listSubscribers[listID] = map[*Subscription]bool{}
+	}
+	h.listSubscribers[listID][sub] = true
+	return sub
+}
+
+// ActiveSubscribers returns how many clients are connected
+func (h *EventHub) ActiveSubscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := len(h.subscribers)
+	for _, subs := range h.listSubscribers {
+		count += len(subs)
+	}
+	return count
+}
+
+// Events delivers the user's events as they are published. It is closed
+// when the subscription ends.
+func (s *Subscription) Events() <-chan ChangeEvent {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+func (h *EventHub) drop(sub *Subscription) {
+	if sub.ListID != "" {
+		if h.listSubscribers[sub.ListID][sub] {
+			delete(h.listSubscribers[sub.ListID], sub)
+			if len(h.listSubscribers[sub.ListID]) == 0 {
+				delete(h.listSubscribers, sub.ListID)
+			}
+			close(sub.events)
+		}
+		return
+	}
+
+	if h.subscribers[sub] {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// since returns the user's buffered events after lastEventID, oldest first,
+// and whether the buffer no longer reaches back to it
+func (h *EventHub) since(userID, lastEventID string) ([]ChangeEvent, bool) {
+	last, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil || last > h.lastID {
+		// Not one of ours, e.g. from before a restart
+		return nil, true
+	}
+
+	oldest := h.lastID - uint64(len(h.buffer)) + 1
+	if last+1 < oldest {
+		return nil, true
+	}
+
+	var missed []ChangeEvent
+	for i := range h.buffer {
+		event := h.buffer[(h.next+i)%len(h.buffer)]
+		if id, _ := strconv.ParseUint(event.ID, 10, 64); id > last && event.isFor(userID) {
+			missed = append(missed, event)
+		}
+	}
+	return missed, false
+}
+
+// recipients lists the users who can see the event: the task's creator and
+// assignee, the member it is about, and the owner and accepted members of
+// its list
+func (h *EventHub) recipients(event ChangeEvent) []string {
+	var users []string
+	add := func(userID string) {
+		if userID != "" && !slices.Contains(users, userID) {
+			users = append(users, userID)
+		}
+	}
+
+	if event.Task != nil {
+		add(event.Task.CreatorID)
+		if event.Task.AssigneeID != nil {
+			add(*event.Task.AssigneeID)
+		}
+	}
+	if event.Member != nil {
+		add(event.Member.UserID)
+	}
+	if event.ListID != nil && h.lists != nil {
+		if list, err := h.lists.GetByID(*event.ListID); err == nil {
+			add(list.OwnerID)
+		}
+	}
+	if event.ListID != nil && h.members != nil {
+		members, err := h.members.GetByListID(*event.ListID)
+		if err == nil {
+			for _, member := range members {
+				if member.HasAccepted() {
+					add(member.UserID)
+				}
+			}
+		}
+	}
+	return users
+}
+
+func (e ChangeEvent) isFor(userID string) bool {
+	return slices.Contains(e.recipients, userID)
+}
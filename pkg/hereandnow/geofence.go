@@ -0,0 +1,134 @@
+package hereandnow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// GeofenceEventRepository stores the user's arrivals at and departures from
+// their saved locations
+type GeofenceEventRepository interface {
+	Create(event models.GeofenceEvent) error
+	GetByUserID(userID string, since time.Time) ([]models.GeofenceEvent, error)
+}
+
+// EnableGeofenceEvents makes context updates record a models.GeofenceEvent
+// each time the user's position enters or leaves one of their saved
+// locations. Location reminders, when enabled, are sent for the same
+// crossings.
+func (s *ContextService) EnableGeofenceEvents(events GeofenceEventRepository) {
+	s.geofenceRepo = events
+}
+
+// GetGeofenceEvents returns the user's geofence events since the given
+// time, oldest first
+func (s *ContextService) GetGeofenceEvents(userID string, since time.Time) ([]models.GeofenceEvent, error) {
+	if s.geofenceRepo == nil {
+		return nil, fmt.Errorf("geofence events are not enabled")
+	}
+
+	events, err := s.geofenceRepo.GetByUserID(userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get geofence events: %w", err)
+	}
+	return events, nil
+}
+
+// previousContext returns the user's last context when geofence events or
+// location reminders need it to detect arrivals and departures
+func (s *ContextService) previousContext(userID string) *models.Context {
+	if s.geofenceRepo == nil && s.locationTasks == nil {
+		return nil
+	}
+
+	previous, err := s.contextRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return previous
+}
+
+// crossGeofences records the locations the user entered or left between
+// previous and current and sends their location reminders. A missing
+// previous context or position counts as being nowhere, so the user's first
+// context enters every location it is in. Like reminders, events are best
+// effort and never fail the context update.
+func (s *ContextService) crossGeofences(userID string, previous *models.Context, current models.Context) {
+	if s.geofenceRepo == nil && s.locationTasks == nil {
+		return
+	}
+
+	inside, err := s.locationsAt(userID, current)
+	if err != nil || current.CurrentLatitude == nil || current.CurrentLongitude == nil {
+		return
+	}
+	var wasInside []*models.Location
+	if previous != nil {
+		if wasInside, err = s.locationsAt(userID, *previous); err != nil {
+			return
+		}
+	}
+
+	for _, location := range inside {
+		if !containsLocation(wasInside, location.ID) {
+			s.crossGeofence(userID, current, location, models.GeofenceEventEnter)
+		}
+	}
+	for _, location := range wasInside {
+		if !containsLocation(inside, location.ID) {
+			s.crossGeofence(userID, current, location, models.GeofenceEventExit)
+		}
+	}
+}
+
+func (s *ContextService) crossGeofence(userID string, context models.Context, location *models.Location, eventType models.GeofenceEventType) {
+	if s.geofenceRepo != nil {
+		if event, err := models.NewGeofenceEvent(userID, location.ID, context.ID, eventType, context.Timestamp); err == nil {
+			s.geofenceRepo.Create(*event)
+		}
+	}
+
+	if s.locationTasks != nil {
+		trigger := models.LocationTriggerEnter
+		if eventType == models.GeofenceEventExit {
+			trigger = models.LocationTriggerExit
+		}
+		s.notifyLocationTasks(userID, location, trigger)
+	}
+}
+
+// locationsAt returns the user's saved locations whose radius contains the
+// context's position, nearest first, or none when it has no position
+func (s *ContextService) locationsAt(userID string, context models.Context) ([]*models.Location, error) {
+	if context.CurrentLatitude == nil || context.CurrentLongitude == nil {
+		return nil, nil
+	}
+	latitude, longitude := *context.CurrentLatitude, *context.CurrentLongitude
+
+	if finder, ok := s.locationRepo.(coordinateLocationFinder); ok {
+		return finder.FindAtCoordinates(userID, latitude, longitude)
+	}
+
+	locations, err := s.locationRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var inside []*models.Location
+	for i := range locations {
+		if locations[i].IsWithinRadius(latitude, longitude) {
+			inside = append(inside, &locations[i])
+		}
+	}
+	return inside, nil
+}
+
+func containsLocation(locations []*models.Location, locationID string) bool {
+	for _, location := range locations {
+		if location.ID == locationID {
+			return true
+		}
+	}
+	return false
+}
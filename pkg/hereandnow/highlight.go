@@ -0,0 +1,216 @@
+package hereandnow
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// MatchRanges returns the byte ranges of text that match any word of query,
+// ignoring case. Overlapping and adjacent matches are merged so each range
+// can be wrapped in markers without nesting.
+func MatchRanges(text, query string) [][2]int {
+	runes := []rune(text)
+
+	// Byte offset of each rune, plus the end of the text
+	offsets := make([]int, 0, len(runes)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	var ranges [][2]int
+	for _, term := range searchTerms(query) {
+		n := len([]rune(term))
+		for i := 0; i+n <= len(runes); i++ {
+			if strings.EqualFold(string(runes[i:i+n]), term) {
+				ranges = append(ranges, [2]int{i, i + n})
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	for i := range merged {
+		merged[i] = [2]int{offsets[merged[i][0]], offsets[merged[i][1]]}
+	}
+	return merged
+}
+
+// HighlightMatches wraps every match of query in text with open and close
+func HighlightMatches(text, query, open, close string) string {
+	ranges := MatchRanges(text, query)
+	if len(ranges) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, r := range ranges {
+		sb.WriteString(text[last:r[0]])
+		sb.WriteString(open)
+		sb.WriteString(text[r[0]:r[1]])
+		sb.WriteString(close)
+		last = r[1]
+	}
+	sb.WriteString(text[last:])
+
+	return sb.String()
+}
+
+// MatchesSearch reports whether every word of query appears in text,
+// ignoring case. A word ending in * matches wherever its prefix does.
+func MatchesSearch(text, query string) bool {
+	text = strings.ToLower(text)
+	for _, term := range searchTerms(query) {
+		if !strings.Contains(text, strings.ToLower(term)) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchTerms splits a search query into words, dropping full-text search
+// syntax that never appears in the matched text
+func searchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		term := strings.Trim(field, `"*()`)
+		switch term {
+		case "", "AND", "OR", "NOT":
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// DefaultSnippetWidth is about how many characters a search snippet shows
+const DefaultSnippetWidth = 80
+
+// SnippetFragment is a piece of a search snippet. Match marks the pieces
+// that matched the query, so a client can emphasize them without parsing
+// markup out of the task's text.
+type SnippetFragment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match"`
+}
+
+// Snippet returns about width characters of text around its first match of
+// query, split into matched and unmatched fragments. Text cut from either
+// end is marked with an ellipsis. It returns nil when nothing matches.
+func Snippet(text, query string, width int) []SnippetFragment {
+	ranges := wordRanges(text, MatchRanges(text, query))
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	// Keep a little context before the first match, without a cut word
+	start, end := 0, len(text)
+	if runes := []rune(text); len(runes) > width {
+		first := utf8.RuneCountInString(text[:ranges[0][0]])
+		from := max(first-width/4, 0)
+		to := min(from+width, len(runes))
+		from = max(to-width, 0)
+		if from > 0 && !unicode.IsSpace(runes[from-1]) {
+			if space := strings.IndexFunc(string(runes[from:first]), unicode.IsSpace); space >= 0 {
+				from += utf8.RuneCountInString(string(runes[from:first])[:space]) + 1
+			}
+		}
+		start, end = len(string(runes[:from])), len(string(runes[:to]))
+	}
+
+	var fragments []SnippetFragment
+	last := start
+	for _, r := range ranges {
+		if r[0] >= end {
+			break
+		}
+		if r[0] > last {
+			fragments = append(fragments, SnippetFragment{Text: text[last:r[0]]})
+		}
+		fragments = append(fragments, SnippetFragment{Text: text[r[0]:min(r[1], end)], Match: true})
+		last = min(r[1], end)
+	}
+	if last < end {
+		fragments = append(fragments, SnippetFragment{Text: text[last:end]})
+	}
+
+	if start > 0 {
+		if fragments[0].Match {
+			fragments = append([]SnippetFragment{{Text: "…"}}, fragments...)
+		} else {
+			fragments[0].Text = "…" + fragments[0].Text
+		}
+	}
+	if end < len(text) {
+		if tail := &fragments[len(fragments)-1]; !tail.Match {
+			tail.Text += "…"
+		} else {
+			fragments = append(fragments, SnippetFragment{Text: "…"})
+		}
+	}
+	return fragments
+}
+
+// wordRanges widens merged match ranges to the whole words they fall in, as
+// a prefix search matches the words beginning with it
+func wordRanges(text string, ranges [][2]int) [][2]int {
+	var words [][2]int
+	for _, r := range ranges {
+		for r[0] > 0 {
+			prev, size := utf8.DecodeLastRuneInString(text[:r[0]])
+			if !isWordRune(prev) {
+				break
+			}
+			r[0] -= size
+		}
+		for r[1] < len(text) {
+			next, size := utf8.DecodeRuneInString(text[r[1]:])
+			if !isWordRune(next) {
+				break
+			}
+			r[1] += size
+		}
+
+		if n := len(words); n > 0 && r[0] <= words[n-1][1] {
+			words[n-1][1] = max(words[n-1][1], r[1])
+			continue
+		}
+		words = append(words, r)
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// TaskSnippet returns the snippet of a task for a search: from its title
+// when that matches, otherwise from its description
+func TaskSnippet(task models.Task, query string) []SnippetFragment {
+	if snippet := Snippet(task.Title, query, DefaultSnippetWidth); snippet != nil {
+		return snippet
+	}
+	return Snippet(task.Description, query, DefaultSnippetWidth)
+}
@@ -0,0 +1,187 @@
+package hereandnow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ErrListArchived is returned when a task in an archived list, or in a list
+// under an archived parent, is created or changed
+var ErrListArchived = errors.New("task is in an archived list")
+
+// ArchiveListRepository loads and saves the lists the auto-archive sweep
+// looks at
+type ArchiveListRepository interface {
+	GetActive() ([]models.TaskList, error)
+	Update(list models.TaskList) error
+}
+
+// ListTaskRepository finds the tasks in a list
+type ListTaskRepository interface {
+	GetByListID(listID string) ([]models.Task, error)
+}
+
+// ListArchiver archives lists nobody has touched for a while
+type ListArchiver struct {
+	listRepo         ArchiveListRepository
+	taskRepo         ListTaskRepository
+	notificationRepo NotificationRepository
+	inactiveAfter    time.Duration
+}
+
+// NewListArchiver returns an archiver for lists with no activity for
+// inactiveAfter. notifications may be nil to archive without telling the
+// owner.
+func NewListArchiver(lists ArchiveListRepository, tasks ListTaskRepository, notifications NotificationRepository, inactiveAfter time.Duration) *ListArchiver {
+	return &ListArchiver{
+		listRepo:         lists,
+		taskRepo:         tasks,
+		notificationRepo: notifications,
+		inactiveAfter:    inactiveAfter,
+	}
+}
+
+// Sweep archives every active list whose last activity is older than the
+// inactivity period as of now, notifies each owner, and returns the lists it
+// archived. Running it again archives nothing new, since archived lists are
+// skipped.
+func (a *ListArchiver) Sweep(now time.Time) ([]models.TaskList, error) {
+	lists, err := a.listRepo.GetActive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active lists: %w", err)
+	}
+
+	cutoff := now.Add(-a.inactiveAfter)
+	var archived []models.TaskList
+	for _, list := range lists {
+		if list.IsArchived() {
+			continue
+		}
+
+		lastActivity, err := a.lastActivity(list)
+		if err != nil {
+			return archived, err
+		}
+		if !lastActivity.Before(cutoff) {
+			continue
+		}
+
+		list.Archive()
+		if err := a.listRepo.Update(list); err != nil {
+			return archived, fmt.Errorf("failed to archive list %s: %w", list.ID, err)
+		}
+		archived = append(archived, list)
+
+		a.notifyOwner(list, lastActivity)
+	}
+
+	return archived, nil
+}
+
+// lastActivity is the latest time the list or any of its tasks was created,
+// updated or completed
+func (a *ListArchiver) lastActivity(list models.TaskList) (time.Time, error) {
+	latest := list.CreatedAt
+	if list.UpdatedAt.After(latest) {
+		latest = list.UpdatedAt
+	}
+
+	tasks, err := a.taskRepo.GetByListID(list.ID)
+	if err != nil {
+		return latest, fmt.Errorf("failed to get tasks for list %s: %w", list.ID, err)
+	}
+
+	for _, task := range tasks {
+		for _, at := range []time.Time{task.CreatedAt, task.UpdatedAt} {
+			if at.After(latest) {
+				latest = at
+			}
+		}
+		if task.CompletedAt != nil && task.CompletedAt.After(latest) {
+			latest = *task.CompletedAt
+		}
+	}
+
+	return latest, nil
+}
+
+// notifyOwner tells the owner their list was archived. Notifications are
+// best effort and never undo the archive.
+func (a *ListArchiver) notifyOwner(list models.TaskList, lastActivity time.Time) {
+	if a.notificationRepo == nil {
+		return
+	}
+
+	message := fmt.Sprintf("List %q was archived after no activity since %s",
+		list.Name, lastActivity.Format("Jan 2, 2006"))
+	notification, err := models.NewNotification(list.OwnerID, models.NotificationTypeListArchived, message)
+	if err != nil {
+		return
+	}
+	a.notificationRepo.Create(*notification)
+}
+
+// archivedList returns the list that keeps listID archived: the list itself
+// or the nearest archived parent. It returns nil when none is archived or
+// the lists can't be loaded.
+func (s *TaskService) archivedList(listID string) *models.TaskList {
+	if s.listRepo == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !seen[listID] {
+		seen[listID] = true
+		list, err := s.listRepo.GetByID(listID)
+		if err != nil {
+			return nil
+		}
+		if list.IsArchived() {
+			return list
+		}
+		if list.ParentID == nil {
+			return nil
+		}
+		listID = *list.ParentID
+	}
+	return nil
+}
+
+// checkListNotArchived refuses changes to a task in an archived list
+func (s *TaskService) checkListNotArchived(task models.Task) error {
+	if task.ListID == nil {
+		return nil
+	}
+	if list := s.archivedList(*task.ListID); list != nil {
+		return fmt.Errorf("%w %q; unarchive the list to change it", ErrListArchived, list.Name)
+	}
+	return nil
+}
+
+// withoutArchivedLists drops the tasks in archived lists and in lists under
+// an archived parent
+func (s *TaskService) withoutArchivedLists(tasks []models.Task) []models.Task {
+	if s.listRepo == nil {
+		return tasks
+	}
+
+	archived := make(map[string]bool)
+	visible := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ListID != nil {
+			inArchive, checked := archived[*task.ListID]
+			if !checked {
+				inArchive = s.archivedList(*task.ListID) != nil
+				archived[*task.ListID] = inArchive
+			}
+			if inArchive {
+				continue
+			}
+		}
+		visible = append(visible, task)
+	}
+	return visible
+}
@@ -0,0 +1,200 @@
+package hereandnow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// InheritedKey is the task metadata key recording which of the task's
+// constraints it took from its list's defaults
+const InheritedKey = "inherited_from_list"
+
+// InheritedDefaults is what a task took from its list. LocationID is the
+// location the task was given; EstimatedMinutes is set when its estimate
+// is the list's.
+type InheritedDefaults struct {
+	LocationID       string `json:"location_id,omitempty"`
+	EstimatedMinutes bool   `json:"estimated_minutes,omitempty"`
+}
+
+// TaskInheritedDefaults returns what the task took from its list, which is
+// nothing when its metadata is unreadable
+func TaskInheritedDefaults(task models.Task) InheritedDefaults {
+	var metadata struct {
+		Inherited InheritedDefaults `json:"inherited_from_list"`
+	}
+	if len(task.Metadata) > 0 {
+		_ = json.Unmarshal(task.Metadata, &metadata)
+	}
+	return metadata.Inherited
+}
+
+// setInheritedDefaults records inherited in the task's metadata, keeping
+// its other keys
+func setInheritedDefaults(task *models.Task, inherited InheritedDefaults) error {
+	metadata := map[string]json.RawMessage{}
+	if len(task.Metadata) > 0 {
+		if err := json.Unmarshal(task.Metadata, &metadata); err != nil {
+			return fmt.Errorf("invalid task metadata: %w", err)
+		}
+	}
+
+	if inherited == (InheritedDefaults{}) {
+		delete(metadata, InheritedKey)
+	} else {
+		value, _ := json.Marshal(inherited)
+		metadata[InheritedKey] = value
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	task.Metadata = data
+	return nil
+}
+
+// SetListRepository makes tasks created in a list take the list's default
+// location and estimate when they have none of their own
+func (s *TaskService) SetListRepository(lists ListLookupRepository) {
+	s.listRepo = lists
+}
+
+// applyListDefaults gives a new task in a list the list's default estimate
+// when it has none, and returns the locations to link it to: locationIDs,
+// or the list's default location when there are none
+func (s *TaskService) applyListDefaults(task *models.Task, locationIDs []string) ([]string, error) {
+	if s.listRepo == nil || task.ListID == nil {
+		return locationIDs, nil
+	}
+
+	list, err := s.listRepo.GetByID(*task.ListID)
+	if err != nil {
+		return nil, fmt.Errorf("list not found: %w", err)
+	}
+
+	var inherited InheritedDefaults
+	if task.EstimatedMinutes == nil && list.DefaultEstimatedMinutes != nil {
+		minutes := *list.DefaultEstimatedMinutes
+		task.EstimatedMinutes = &minutes
+		inherited.EstimatedMinutes = true
+	}
+	if len(locationIDs) == 0 && list.DefaultLocationID != nil {
+		locationIDs = []string{*list.DefaultLocationID}
+		inherited.LocationID = *list.DefaultLocationID
+	}
+
+	if inherited != (InheritedDefaults{}) {
+		if err := setInheritedDefaults(task, inherited); err != nil {
+			return nil, err
+		}
+	}
+	return locationIDs, nil
+}
+
+// ApplyListDefaults brings the list's open tasks in line with its current
+// defaults, for after the defaults change. Tasks without an estimate or a
+// location of their own, and those whose one was inherited, take the
+// list's; an inherited one whose default was cleared is removed. It returns
+// how many tasks changed, recording the changes as made by userID.
+func (s *TaskService) ApplyListDefaults(listID string, userID string) (int, error) {
+	if s.listRepo == nil {
+		return 0, fmt.Errorf("list defaults are not enabled")
+	}
+	list, err := s.listRepo.GetByID(listID)
+	if err != nil {
+		return 0, fmt.Errorf("list not found: %w", err)
+	}
+	tasks, err := s.taskRepo.GetByListID(listID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list tasks: %w", err)
+	}
+
+	var changed []models.Task
+	err = s.withTx(func(tx *TaskService) error {
+		for _, task := range tasks {
+			if task.IsCompleted() || task.IsCancelled() {
+				continue
+			}
+			updated, err := tx.applyDefaultsToTask(userID, &task, *list)
+			if err != nil {
+				return fmt.Errorf("failed to apply list defaults to task %s: %w", task.ID, err)
+			}
+			if updated {
+				changed = append(changed, task)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, task := range changed {
+		s.publishTask(EventTaskUpdated, userID, task)
+	}
+	return len(changed), nil
+}
+
+// applyDefaultsToTask updates one existing task to the list's defaults,
+// reporting whether anything changed
+func (s *TaskService) applyDefaultsToTask(userID string, task *models.Task, list models.TaskList) (bool, error) {
+	inherited := TaskInheritedDefaults(*task)
+	updated := false
+
+	if task.EstimatedMinutes == nil || inherited.EstimatedMinutes {
+		switch {
+		case list.DefaultEstimatedMinutes != nil:
+			if task.EstimatedMinutes == nil || *task.EstimatedMinutes != *list.DefaultEstimatedMinutes {
+				minutes := *list.DefaultEstimatedMinutes
+				task.EstimatedMinutes = &minutes
+				updated = true
+			}
+			inherited.EstimatedMinutes = true
+		case inherited.EstimatedMinutes:
+			task.EstimatedMinutes = nil
+			inherited.EstimatedMinutes = false
+			updated = true
+		}
+	}
+
+	defaultLocation := ""
+	if list.DefaultLocationID != nil {
+		defaultLocation = *list.DefaultLocationID
+	}
+	if inherited.LocationID != defaultLocation {
+		if inherited.LocationID != "" {
+			if err := s.taskLocationRepo.Delete(task.ID, inherited.LocationID); err != nil {
+				return false, fmt.Errorf("failed to remove inherited location: %w", err)
+			}
+			inherited.LocationID = ""
+			updated = true
+		}
+
+		locations, err := s.taskLocationRepo.GetLocationsByTaskID(task.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get task locations: %w", err)
+		}
+		if defaultLocation != "" && len(locations) == 0 {
+			if err := s.addTaskLocations(task.ID, []string{defaultLocation}, models.LocationTriggerEnter); err != nil {
+				return false, err
+			}
+			inherited.LocationID = defaultLocation
+			updated = true
+		}
+	}
+
+	if !updated {
+		return false, nil
+	}
+	if err := setInheritedDefaults(task, inherited); err != nil {
+		return false, err
+	}
+	task.UpdatedAt = s.clock.Now()
+	if err := s.saveTask(userID, task); err != nil {
+		return false, fmt.Errorf("failed to update task: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,135 @@
+package hereandnow
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ListMemberRepository finds the people a list is shared with
+type ListMemberRepository interface {
+	GetByListID(listID string) ([]models.ListMember, error)
+}
+
+// ListScheduler shows a shared list's due dates from each member's side of
+// the world
+type ListScheduler struct {
+	taskRepo   ListTaskRepository
+	memberRepo ListMemberRepository
+	userRepo   UserRepository
+}
+
+func NewListScheduler(tasks ListTaskRepository, members ListMemberRepository, users UserRepository) *ListScheduler {
+	return &ListScheduler{
+		taskRepo:   tasks,
+		memberRepo: members,
+		userRepo:   users,
+	}
+}
+
+// ListSchedule is every open task with a due date in a list, soonest first
+type ListSchedule struct {
+	ListID  string          `json:"list_id"`
+	Members []models.User   `json:"members"`
+	Entries []ScheduleEntry `json:"entries"`
+}
+
+// ScheduleEntry is one task's due time as each member sees it
+type ScheduleEntry struct {
+	Task     models.Task     `json:"task"`
+	DueTimes []MemberDueTime `json:"due_times"`
+	// OutOfHours is set when the task falls due outside its assignee's
+	// working hours
+	OutOfHours bool `json:"out_of_hours"`
+}
+
+// MemberDueTime is a due time rendered in one member's timezone
+type MemberDueTime struct {
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	TimeZone     string    `json:"timezone"`
+	DueAt        time.Time `json:"due_at"`
+	WorkingHours bool      `json:"working_hours"`
+	IsAssignee   bool      `json:"is_assignee"`
+}
+
+// Schedule builds the list's schedule for its accepted members. An assignee
+// who is not a member is still checked against their working hours.
+func (s *ListScheduler) Schedule(listID string) (*ListSchedule, error) {
+	members, err := s.memberRepo.GetByListID(listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list members: %w", err)
+	}
+
+	users := make(map[string]*models.User)
+	schedule := &ListSchedule{ListID: listID, Members: []models.User{}, Entries: []ScheduleEntry{}}
+	for _, member := range members {
+		if !member.HasAccepted() || users[member.UserID] != nil {
+			continue
+		}
+		user, err := s.userRepo.GetByID(member.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list member %s: %w", member.UserID, err)
+		}
+		users[user.ID] = user
+		schedule.Members = append(schedule.Members, *user)
+	}
+
+	tasks, err := s.taskRepo.GetByListID(listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list tasks: %w", err)
+	}
+
+	for _, task := range tasks {
+		if task.DueAt == nil || task.IsCompleted() || task.IsCancelled() {
+			continue
+		}
+
+		entry := ScheduleEntry{Task: task, DueTimes: []MemberDueTime{}}
+		for _, member := range schedule.Members {
+			isAssignee := task.AssigneeID != nil && *task.AssigneeID == member.ID
+			entry.DueTimes = append(entry.DueTimes, s.dueTimeFor(member, *task.DueAt, isAssignee))
+		}
+
+		if assignee := s.assignee(task, users); assignee != nil {
+			entry.OutOfHours = !assignee.WorkingHours().Contains(*task.DueAt, assignee.Location())
+		}
+
+		schedule.Entries = append(schedule.Entries, entry)
+	}
+
+	sort.SliceStable(schedule.Entries, func(i, j int) bool {
+		return schedule.Entries[i].Task.DueAt.Before(*schedule.Entries[j].Task.DueAt)
+	})
+	return schedule, nil
+}
+
+// assignee returns the task's assignee, looking up assignees who are not
+// members, or nil when the task is unassigned or the user is gone
+func (s *ListScheduler) assignee(task models.Task, members map[string]*models.User) *models.User {
+	if task.AssigneeID == nil {
+		return nil
+	}
+	if user, ok := members[*task.AssigneeID]; ok {
+		return user
+	}
+	user, err := s.userRepo.GetByID(*task.AssigneeID)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+func (s *ListScheduler) dueTimeFor(user models.User, dueAt time.Time, isAssignee bool) MemberDueTime {
+	loc := user.Location()
+	return MemberDueTime{
+		UserID:       user.ID,
+		Username:     user.Username,
+		TimeZone:     loc.String(),
+		DueAt:        dueAt.In(loc),
+		WorkingHours: user.WorkingHours().Contains(dueAt, loc),
+		IsAssignee:   isAssignee,
+	}
+}
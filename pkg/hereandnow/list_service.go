@@ -0,0 +1,219 @@
+package hereandnow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ListLookupRepository finds task lists
+type ListLookupRepository interface {
+	GetByID(listID string) (*models.TaskList, error)
+}
+
+// ErrNotListOwner is returned when someone other than a list's owner tries
+// to archive or unarchive it
+var ErrNotListOwner = errors.New("only the list owner can archive or unarchive it")
+
+// listUpdater is a list repository that can also save lists
+type listUpdater interface {
+	Update(list models.TaskList) error
+}
+
+// ListMemberStore stores and lists the people lists are shared with
+type ListMemberStore interface {
+	ListMemberRepository
+	Create(member models.ListMember) error
+	Delete(listID, userID string) error
+}
+
+// ListService manages who a list is shared with and whether it is archived
+type ListService struct {
+	listRepo   ListLookupRepository
+	memberRepo ListMemberStore
+	events     EventPublisher
+	clock      clock.Clock
+}
+
+func NewListService(lists ListLookupRepository, members ListMemberStore) *ListService {
+	return &ListService{
+		listRepo:   lists,
+		memberRepo: members,
+		clock:      clock.Real(),
+	}
+}
+
+// SetEventPublisher makes the service publish membership changes
+func (s *ListService) SetEventPublisher(publisher EventPublisher) {
+	s.events = publisher
+}
+
+// AddMember shares the list with userID in the given role on behalf of
+// invitedBy, who must own the list or be an owner member of it. The list's
+// owner and members and the new member are told through the event
+// publisher.
+func (s *ListService) AddMember(listID, userID string, role models.MemberRole, invitedBy string) (*models.ListMember, error) {
+	list, err := s.listRepo.GetByID(listID)
+	if err != nil {
+		return nil, fmt.Errorf("list not found: %w", err)
+	}
+	if list.OwnerID == userID {
+		return nil, fmt.Errorf("user %s already owns this list", userID)
+	}
+
+	members, err := s.memberRepo.GetByListID(listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list members: %w", err)
+	}
+
+	canManage := list.OwnerID == invitedBy
+	for _, member := range members {
+		if member.IsUser(userID) {
+			return nil, fmt.Errorf("user %s is already a member of this list", userID)
+		}
+		if member.IsUser(invitedBy) && member.HasAccepted() && member.CanManageMembers() {
+			canManage = true
+		}
+	}
+	if !canManage {
+		return nil, fmt.Errorf("only the list owner can add members")
+	}
+
+	member, err := models.NewListMember(listID, userID, invitedBy, role)
+	if err != nil {
+		return nil, err
+	}
+	member.InvitedAt = s.clock.Now()
+
+	if err := s.memberRepo.Create(*member); err != nil {
+		return nil, fmt.Errorf("failed to add list member: %w", err)
+	}
+
+	if s.events != nil {
+		s.events.Publish(ChangeEvent{
+			Type:      EventListMemberAdded,
+			ActorID:   invitedBy,
+			ListID:    &member.ListID,
+			Member:    member,
+			Timestamp: member.InvitedAt,
+		})
+	}
+
+	return member, nil
+}
+
+// RemoveMember stops sharing the list with userID on behalf of removedBy,
+// who must own the list, be an owner member of it or be leaving it. The
+// removed member's list streams are closed once they are told.
+func (s *ListService) RemoveMember(listID, userID, removedBy string) error {
+	list, err := s.listRepo.GetByID(listID)
+	if err != nil {
+		return fmt.Errorf("list not found: %w", err)
+	}
+
+	members, err := s.memberRepo.GetByListID(listID)
+	if err != nil {
+		return fmt.Errorf("failed to get list members: %w", err)
+	}
+
+	var removed *models.ListMember
+	canManage := list.OwnerID == removedBy || userID == removedBy
+	for i := range members {
+		if members[i].IsUser(userID) {
+			removed = &members[i]
+		}
+		if members[i].IsUser(removedBy) && members[i].HasAccepted() && members[i].CanManageMembers() {
+			canManage = true
+		}
+	}
+	if removed == nil {
+		return fmt.Errorf("list member not found: %s", userID)
+	}
+	if !canManage {
+		return fmt.Errorf("only the list owner can remove members")
+	}
+
+	if err := s.memberRepo.Delete(listID, userID); err != nil {
+		return fmt.Errorf("failed to remove list member: %w", err)
+	}
+
+	if s.events != nil {
+		s.events.Publish(ChangeEvent{
+			Type:      EventListMemberRemoved,
+			ActorID:   removedBy,
+			ListID:    &removed.ListID,
+			Member:    removed,
+			Timestamp: s.clock.Now(),
+		})
+	}
+
+	return nil
+}
+
+// CanViewList reports whether userID owns the list or has accepted an
+// invitation to it
+func (s *ListService) CanViewList(listID, userID string) (bool, error) {
+	list, err := s.listRepo.GetByID(listID)
+	if err != nil {
+		return false, fmt.Errorf("list not found: %w", err)
+	}
+	if list.IsOwnedBy(userID) {
+		return true, nil
+	}
+
+	members, err := s.memberRepo.GetByListID(listID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get list members: %w", err)
+	}
+	for _, member := range members {
+		if member.IsUser(userID) && member.HasAccepted() && member.CanView() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ArchiveList archives the list on behalf of userID, who must own it.
+// Nothing is deleted: the list, its child lists and their tasks are only
+// hidden from everyday views, and their tasks can't be changed until
+// UnarchiveList brings them back. Members, editors included, can't archive
+// a list.
+func (s *ListService) ArchiveList(listID, userID string) (*models.TaskList, error) {
+	return s.setArchived(listID, userID, true)
+}
+
+// UnarchiveList brings an archived list back on behalf of its owner, with
+// its child lists and tasks as they were
+func (s *ListService) UnarchiveList(listID, userID string) (*models.TaskList, error) {
+	return s.setArchived(listID, userID, false)
+}
+
+func (s *ListService) setArchived(listID, userID string, archived bool) (*models.TaskList, error) {
+	lists, ok := s.listRepo.(listUpdater)
+	if !ok {
+		return nil, fmt.Errorf("list archiving is not enabled")
+	}
+
+	list, err := s.listRepo.GetByID(listID)
+	if err != nil {
+		return nil, fmt.Errorf("list not found: %w", err)
+	}
+	if !list.IsOwnedBy(userID) {
+		return nil, ErrNotListOwner
+	}
+	if list.IsArchived() == archived {
+		return list, nil
+	}
+
+	if archived {
+		list.Archive()
+	} else {
+		list.Unarchive()
+	}
+	if err := lists.Update(*list); err != nil {
+		return nil, fmt.Errorf("failed to update list: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,55 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// LocationTaskRepository finds the tasks tied to a location
+type LocationTaskRepository interface {
+	GetByLocationID(locationID string) ([]models.TaskLocation, error)
+}
+
+// ReminderTaskRepository looks up the tasks a location reminder is about
+type ReminderTaskRepository interface {
+	GetByID(taskID string) (*models.Task, error)
+}
+
+// EnableLocationReminders makes context updates notify the user when they
+// arrive at a location with enter-triggered tasks or leave one with
+// exit-triggered tasks.
+func (s *ContextService) EnableLocationReminders(taskLocations LocationTaskRepository, tasks ReminderTaskRepository, notifications NotificationRepository) {
+	s.locationTasks = taskLocations
+	s.reminderTasks = tasks
+	s.notificationRepo = notifications
+}
+
+func (s *ContextService) notifyLocationTasks(userID string, location *models.Location, trigger models.LocationTrigger) {
+	taskLocations, err := s.locationTasks.GetByLocationID(location.ID)
+	if err != nil {
+		return
+	}
+
+	for _, taskLocation := range taskLocations {
+		if taskLocation.IsExitTrigger() != (trigger == models.LocationTriggerExit) {
+			continue
+		}
+
+		task, err := s.reminderTasks.GetByID(taskLocation.TaskID)
+		if err != nil || task.IsCompleted() || task.IsCancelled() {
+			continue
+		}
+
+		message := fmt.Sprintf("You're at %s: %s", location.Name, task.Title)
+		if trigger == models.LocationTriggerExit {
+			message = fmt.Sprintf("You left %s - don't forget: %s", location.Name, task.Title)
+		}
+
+		notification, err := models.NewTaskNotification(userID, models.NotificationTypeLocationReminder, task.ID, message)
+		if err != nil {
+			continue
+		}
+		s.notificationRepo.Create(*notification)
+	}
+}
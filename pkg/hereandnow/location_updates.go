@@ -0,0 +1,127 @@
+package hereandnow
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
+)
+
+// A location fix only becomes a new context snapshot when the previous
+// snapshot is at least LocationSnapshotInterval old or the user has moved
+// LocationSnapshotDistance meters, or the fix's accuracy when that is worse.
+// Phones report every few seconds while moving; without this the contexts
+// table grows by thousands of rows a day.
+const (
+	LocationSnapshotInterval = 2 * time.Minute
+	LocationSnapshotDistance = 50.0
+)
+
+// LocationFix is a position reported by a device, such as a phone running
+// OwnTracks or GPSLogger
+type LocationFix struct {
+	Latitude  float64
+	Longitude float64
+	// AccuracyMeters is the reported horizontal accuracy, 0 when unknown
+	AccuracyMeters float64
+	// RecordedAt is when the device took the fix. Zero means now.
+	RecordedAt time.Time
+}
+
+// coordinateLocationFinder is implemented by location repositories that can
+// match coordinates against a single user's saved locations
+type coordinateLocationFinder interface {
+	FindAtCoordinates(userID string, latitude, longitude float64) ([]*models.Location, error)
+}
+
+// RecordLocation saves a context snapshot at the fix's position. Only the
+// position, the location it falls in and the weather and traffic there
+// change; available minutes, energy, social context and minimum priority
+// carry over from the previous snapshot. A fix that is rate limited, or older
+// than the latest snapshot because the device queued it while offline, is
+// not recorded: the latest context is returned with recorded false.
+func (s *ContextService) RecordLocation(userID string, fix LocationFix) (context *models.Context, recorded bool, err error) {
+	now := s.clock.Now()
+
+	latest, err := s.contextRepo.GetLatestByUserID(userID)
+	if err != nil {
+		latest = nil
+	}
+	if latest != nil && s.skipLocationFix(*latest, fix, now) {
+		return latest, false, nil
+	}
+
+	snapshot := models.Context{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Timestamp:     now,
+		SocialContext: models.SocialContextAlone,
+		EnergyLevel:   s.DefaultEnergyLevel(userID, now),
+	}
+	if latest != nil {
+		snapshot.AvailableMinutes = latest.AvailableMinutes
+		snapshot.SocialContext = latest.SocialContext
+		snapshot.EnergyLevel = latest.EnergyLevel
+		snapshot.MinPriority = latest.MinPriority
+		snapshot.Metadata = latest.Metadata
+	} else if s.calendarRepo != nil {
+		if minutes, err := s.calculateAvailableMinutes(userID, now); err == nil {
+			snapshot.AvailableMinutes = minutes
+		}
+	}
+
+	if err := snapshot.SetCurrentPosition(fix.Latitude, fix.Longitude); err != nil {
+		return nil, false, err
+	}
+	if err := s.resolveCurrentLocation(userID, &snapshot); err != nil {
+		return nil, false, fmt.Errorf("failed to enrich context with location: %w", err)
+	}
+	s.enrichContextWithWeather(&snapshot)
+	s.enrichContextWithTraffic(&snapshot)
+
+	if err := s.contextRepo.Create(snapshot); err != nil {
+		return nil, false, fmt.Errorf("failed to save context: %w", err)
+	}
+
+	s.crossGeofences(userID, latest, snapshot)
+
+	return &snapshot, true, nil
+}
+
+func (s *ContextService) skipLocationFix(latest models.Context, fix LocationFix, now time.Time) bool {
+	if !fix.RecordedAt.IsZero() && fix.RecordedAt.Before(latest.Timestamp) {
+		return true
+	}
+	if latest.CurrentLatitude == nil || latest.CurrentLongitude == nil {
+		return false
+	}
+	if now.Sub(latest.Timestamp) >= LocationSnapshotInterval {
+		return false
+	}
+	// Movement within the fix's own error radius may be nothing but GPS
+	// jitter
+	threshold := math.Max(LocationSnapshotDistance, fix.AccuracyMeters)
+	moved := s.calculateDistance(*latest.CurrentLatitude, *latest.CurrentLongitude, fix.Latitude, fix.Longitude)
+	return moved < threshold
+}
+
+// resolveCurrentLocation sets the context's location to the user's saved
+// location containing its position, nearest first, or clears it
+func (s *ContextService) resolveCurrentLocation(userID string, context *models.Context) error {
+	finder, ok := s.locationRepo.(coordinateLocationFinder)
+	if !ok {
+		return s.enrichContextWithLocation(context)
+	}
+
+	locations, err := finder.FindAtCoordinates(userID, *context.CurrentLatitude, *context.CurrentLongitude)
+	if err != nil {
+		return err
+	}
+	context.CurrentLocationID = nil
+	if len(locations) > 0 {
+		context.CurrentLocationID = &locations[0].ID
+	}
+	return nil
+}
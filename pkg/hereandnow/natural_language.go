@@ -0,0 +1,41 @@
+package hereandnow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/nlp"
+)
+
+// naturalLanguagePriority is the priority of tasks created from a sentence
+const naturalLanguagePriority = 3
+
+// CreateTaskFromNaturalLanguage creates the task a sentence such as "buy
+// milk when at grocery store" describes, read by nlp.ParseTask. With
+// EnableImportLocations the locations it names are matched against the
+// user's; otherwise location phrases stay in the title. Dates such as
+// "tomorrow at 5pm" are read in the user's timezone.
+func (s *TaskService) CreateTaskFromNaturalLanguage(input string, userID string) (*models.Task, error) {
+	var locations []models.Location
+	if s.importLocationRepo != nil {
+		var err error
+		if locations, err = s.importLocationRepo.GetByUserID(userID); err != nil {
+			return nil, fmt.Errorf("failed to get user locations: %w", err)
+		}
+	}
+
+	parsed, err := nlp.ParseTaskAt(input, locations, s.clock.Now().In(s.userLocation(userID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse task: %w", err)
+	}
+
+	return s.CreateTask(userID, CreateTaskRequest{
+		Title:            parsed.Title,
+		Priority:         naturalLanguagePriority,
+		EstimatedMinutes: parsed.EstimatedMinutes,
+		DueAt:            parsed.DueAt,
+		Metadata:         json.RawMessage(`{}`),
+		LocationIDs:      parsed.LocationIDs,
+	})
+}
@@ -0,0 +1,138 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ReassignRequest moves a user's open work to another user, e.g. when
+// someone leaves the team
+type ReassignRequest struct {
+	FromUserID string `json:"from_user_id"`
+	ToUserID   string `json:"to_user_id"`
+	// TransferOwnership also makes ToUserID the creator of FromUserID's open tasks
+	TransferOwnership bool `json:"transfer_ownership"`
+}
+
+// ReassignReport lists what a reassignment sweep changed
+type ReassignReport struct {
+	Reassigned  []models.Task `json:"reassigned"`
+	Transferred []models.Task `json:"transferred"`
+	Skipped     []models.Task `json:"skipped"`
+}
+
+// SetNotificationRepository lets task changes notify the affected users
+func (s *TaskService) SetNotificationRepository(notificationRepo NotificationRepository) {
+	s.notificationRepo = notificationRepo
+}
+
+// ReassignUserTasks moves every open task assigned to req.FromUserID to
+// req.ToUserID, and optionally ownership of the tasks FromUserID created.
+// Completed and cancelled tasks, and tasks in archived lists, are left alone
+// and reported as skipped. The new assignee is notified of each task they
+// receive. All changes are made in a single transaction. Only active admins
+// may reassign.
+func (s *TaskService) ReassignUserTasks(adminID string, req ReassignRequest) (*ReassignReport, error) {
+	if s.userRepo == nil {
+		return nil, fmt.Errorf("user repository not configured")
+	}
+
+	admin, err := s.userRepo.GetByID(adminID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if !admin.IsAdmin || !admin.IsActive() {
+		return nil, fmt.Errorf("admin privileges required")
+	}
+
+	if req.FromUserID == "" || req.ToUserID == "" {
+		return nil, fmt.Errorf("both from and to users are required")
+	}
+	if req.FromUserID == req.ToUserID {
+		return nil, fmt.Errorf("cannot reassign tasks to the same user")
+	}
+
+	from, err := s.userRepo.GetByID(req.FromUserID)
+	if err != nil {
+		return nil, fmt.Errorf("from user not found: %w", err)
+	}
+	if _, err := s.userRepo.GetByID(req.ToUserID); err != nil {
+		return nil, fmt.Errorf("to user not found: %w", err)
+	}
+
+	report := &ReassignReport{}
+	var updated []models.Task
+	err = s.withTx(func(tx *TaskService) error {
+		tasks, err := tx.taskRepo.GetByUserID(req.FromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to get tasks: %w", err)
+		}
+
+		for _, task := range tasks {
+			assigned := task.AssigneeID != nil && *task.AssigneeID == req.FromUserID
+			owned := req.TransferOwnership && task.CreatorID == req.FromUserID
+			if !assigned && !owned {
+				continue
+			}
+
+			if task.Status == models.TaskStatusCompleted || task.Status == models.TaskStatusCancelled || tx.checkListNotArchived(task) != nil {
+				report.Skipped = append(report.Skipped, task)
+				continue
+			}
+
+			if assigned {
+				task.AssigneeID = &req.ToUserID
+			}
+			if owned {
+				task.CreatorID = req.ToUserID
+			}
+			task.UpdatedAt = s.clock.Now()
+
+			if err := tx.saveTask(adminID, &task); err != nil {
+				return fmt.Errorf("failed to reassign task %s: %w", task.ID, err)
+			}
+			updated = append(updated, task)
+
+			if assigned {
+				message := fmt.Sprintf("%s assigned you a task previously assigned to %s: %s", admin.Username, from.Username, task.Title)
+				if err := tx.notify(req.ToUserID, models.NotificationTypeTaskAssigned, task.ID, message); err != nil {
+					return err
+				}
+				report.Reassigned = append(report.Reassigned, task)
+			}
+			if owned {
+				report.Transferred = append(report.Transferred, task)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range updated {
+		s.publishTask(EventTaskUpdated, adminID, task)
+	}
+
+	return report, nil
+}
+
+// notify records a task notification when a notification repository is set
+func (s *TaskService) notify(userID string, notificationType models.NotificationType, taskID, message string) error {
+	if s.notificationRepo == nil {
+		return nil
+	}
+
+	notification, err := models.NewTaskNotification(userID, notificationType, taskID, message)
+	if err != nil {
+		return fmt.Errorf("invalid notification: %w", err)
+	}
+
+	if err := s.notificationRepo.Create(*notification); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
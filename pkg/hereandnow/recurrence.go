@@ -0,0 +1,292 @@
+package hereandnow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
+	"github.com/google/uuid"
+)
+
+// RecurrenceBasis is what the next instance of a completed recurring task
+// is scheduled from
+type RecurrenceBasis string
+
+const (
+	// RecurFromDueDate schedules the next instance from the completed
+	// instance's due date, so completing late does not shift the series
+	RecurFromDueDate RecurrenceBasis = "due"
+	// RecurFromCompletion schedules the next instance from when the task
+	// was completed, keeping the due date's time of day
+	RecurFromCompletion RecurrenceBasis = "completion"
+)
+
+// ParseRecurrenceBasis reads a RecurrenceBasis, treating "" as
+// RecurFromDueDate
+func ParseRecurrenceBasis(value string) (RecurrenceBasis, error) {
+	switch RecurrenceBasis(value) {
+	case "", RecurFromDueDate:
+		return RecurFromDueDate, nil
+	case RecurFromCompletion:
+		return RecurFromCompletion, nil
+	default:
+		return "", fmt.Errorf("invalid recurrence basis: %s (must be due or completion)", value)
+	}
+}
+
+// SetRecurrenceBasis chooses what the next instance of a completed
+// recurring task is scheduled from. The default is RecurFromDueDate.
+func (s *TaskService) SetRecurrenceBasis(basis RecurrenceBasis) {
+	s.recurrenceBasis = basis
+}
+
+// SetTaskRecurrence attaches rule to the task, replacing any rule it had,
+// or removes the task's rule when rule is nil. The rule is stored in its
+// canonical RRULE form.
+func (s *TaskService) SetTaskRecurrence(taskID string, userID string, rule *recurrence.Rule) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	if rule == nil {
+		task.RecurrenceRule = nil
+	} else {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+		}
+		rrule := rule.String()
+		task.RecurrenceRule = &rrule
+	}
+	task.UpdatedAt = s.clock.Now()
+
+	if err := s.saveTask(userID, task); err != nil {
+		return nil, fmt.Errorf("failed to update task recurrence: %w", err)
+	}
+	s.publishTask(EventTaskUpdated, userID, *task)
+
+	return task, nil
+}
+
+// NextOccurrences returns up to n upcoming occurrences of a recurring task,
+// counted from its due date, or from now when it has none. It returns nil
+// for a task without a rule.
+func (s *TaskService) NextOccurrences(task models.Task, n int) ([]time.Time, error) {
+	if task.RecurrenceRule == nil {
+		return nil, nil
+	}
+
+	rule, err := recurrence.Parse(*task.RecurrenceRule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+	}
+
+	now := s.clock.Now()
+	start := now
+	if task.DueAt != nil {
+		start = *task.DueAt
+	}
+
+	occurrences := []time.Time{}
+	after := now.Add(-time.Nanosecond)
+	for len(occurrences) < n {
+		next, ok := rule.Next(start, after)
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+		after = next
+	}
+	return occurrences, nil
+}
+
+// withNextOccurrences gives each open recurring task without a due date the
+// due date of its next occurrence, counted from when it was created, so the
+// filters see when it is next due. Tasks keep their IDs and nothing is
+// stored. A task with a due date already holds its current occurrence:
+// completing it creates the next one, so an overdue occurrence stays
+// overdue rather than silently moving on.
+func (s *TaskService) withNextOccurrences(tasks []models.Task) []models.Task {
+	now := s.clock.Now()
+	for i, task := range tasks {
+		if task.RecurrenceRule == nil || task.DueAt != nil || task.IsCompleted() || task.IsCancelled() {
+			continue
+		}
+		next, ok, err := recurrence.NextOccurrence(task, now.Add(-time.Nanosecond))
+		if err != nil || !ok {
+			continue
+		}
+		tasks[i].DueAt = &next
+	}
+	return tasks
+}
+
+// nextInstance returns the pending task that follows a completed recurring
+// task, or nil when the task does not recur, its rule is invalid or the
+// series has ended. The instance carries the rule with its COUNT reduced by
+// one, so COUNT holds the occurrences left including the instance itself.
+func (s *TaskService) nextInstance(task models.Task, completedAt time.Time) *models.Task {
+	if task.RecurrenceRule == nil {
+		return nil
+	}
+
+	rule, err := recurrence.Parse(*task.RecurrenceRule)
+	if err != nil || rule.Count == 1 {
+		return nil
+	}
+	if rule.Count > 1 {
+		rule.Count--
+	}
+
+	// COUNT is already accounted for by the instances, so the search itself
+	// must not stop early
+	unlimited := *rule
+	unlimited.Count = 0
+
+	var start, after time.Time
+	switch {
+	case task.DueAt == nil:
+		start, after = completedAt, completedAt
+	case s.recurrenceBasis == RecurFromCompletion:
+		due := task.DueAt
+		done := completedAt.In(due.Location())
+		start = time.Date(done.Year(), done.Month(), done.Day(),
+			due.Hour(), due.Minute(), due.Second(), due.Nanosecond(), due.Location())
+		after = start
+	default:
+		start, after = *task.DueAt, *task.DueAt
+	}
+
+	dueAt, ok := unlimited.Next(start, after)
+	if !ok {
+		return nil
+	}
+
+	rrule := rule.String()
+	parentID := s.seriesRootID(task)
+	return &models.Task{
+		ID:                  uuid.New().String(),
+		Title:               task.Title,
+		Description:         task.Description,
+		CreatorID:           task.CreatorID,
+		AssigneeID:          task.AssigneeID,
+		ListID:              task.ListID,
+		Status:              models.TaskStatusPending,
+		Priority:            task.Priority,
+		EstimatedMinutes:    task.EstimatedMinutes,
+		EffortPoints:        task.EffortPoints,
+		RequiredEnergyLevel: task.RequiredEnergyLevel,
+		DueAt:               &dueAt,
+		CreatedAt:           completedAt,
+		UpdatedAt:           completedAt,
+		Metadata:            task.Metadata,
+		RecurrenceRule:      &rrule,
+		ParentTaskID:        &parentID,
+		Tags:                task.Tags,
+		Version:             1,
+	}
+}
+
+// seriesRootID returns the ID of the task a recurring series started from:
+// the task's parent when the task is itself an instance of a recurring
+// parent, or the task otherwise
+func (s *TaskService) seriesRootID(task models.Task) string {
+	if task.ParentTaskID == nil {
+		return task.ID
+	}
+
+	parent, err := s.taskRepo.GetByID(*task.ParentTaskID)
+	if err != nil || parent.RecurrenceRule == nil {
+		return task.ID
+	}
+	return parent.ID
+}
+
+// createInstance stores the next instance of a recurring task at the end of
+// its list, linked to the same locations as the task it follows
+func (s *TaskService) createInstance(previous, instance models.Task) error {
+	if instance.ListID != nil {
+		position, err := s.nextListPosition(*instance.ListID)
+		if err != nil {
+			return fmt.Errorf("failed to position task in list: %w", err)
+		}
+		instance.Position = position
+	}
+
+	if err := s.taskRepo.Create(instance); err != nil {
+		return err
+	}
+
+	if s.taskLocationRepo == nil {
+		return nil
+	}
+	if lister, ok := s.taskLocationRepo.(taskLocationLister); ok {
+		links, err := lister.GetTaskLocationsByTaskID(previous.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get task locations: %w", err)
+		}
+		for _, link := range links {
+			if err := s.addTaskLocations(instance.ID, []string{link.LocationID}, link.Trigger); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	locations, err := s.taskLocationRepo.GetLocationsByTaskID(previous.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get task locations: %w", err)
+	}
+	locationIDs := make([]string, len(locations))
+	for i, location := range locations {
+		locationIDs[i] = location.ID
+	}
+	return s.addTaskLocations(instance.ID, locationIDs, models.LocationTriggerEnter)
+}
+
+// DeleteTaskWithInstances deletes a recurring task the way DeleteTask does,
+// together with the instances created from it that are still open.
+// Completed and cancelled instances are kept as history. It returns how many
+// instances were deleted.
+func (s *TaskService) DeleteTaskWithInstances(taskID string, userID string) (int, error) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return 0, fmt.Errorf("task not found: %w", err)
+	}
+
+	tasks, err := s.taskRepo.GetByUserID(task.CreatorID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user tasks: %w", err)
+	}
+
+	var instances []models.Task
+	for _, candidate := range tasks {
+		if candidate.ParentTaskID != nil && *candidate.ParentTaskID == taskID &&
+			candidate.RecurrenceRule != nil && !candidate.IsCompleted() && !candidate.IsCancelled() {
+			instances = append(instances, candidate)
+		}
+	}
+
+	err = s.withTx(func(tx *TaskService) error {
+		if err := tx.DeleteTask(taskID, userID); err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			if err := tx.taskRepo.Delete(instance.ID); err != nil {
+				return fmt.Errorf("failed to delete task instance: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.publishTask(EventTaskDeleted, userID, *task)
+	for _, instance := range instances {
+		s.publishTask(EventTaskDeleted, userID, instance)
+	}
+
+	return len(instances), nil
+}
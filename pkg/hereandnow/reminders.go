@@ -0,0 +1,182 @@
+package hereandnow
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// DefaultReminderInterval is how often the scheduler looks for due
+// reminders when no interval is given
+const DefaultReminderInterval = time.Minute
+
+// reminderBatchSize is how many due reminders are fetched at once
+const reminderBatchSize = 100
+
+// reminderDueFormat is how a reminder says when its task is due
+const reminderDueFormat = "Mon Jan 2 15:04"
+
+// ReminderRepository stores task reminders. Claim is what keeps a reminder
+// from being sent twice when several schedulers share the repository: only
+// the call that flips it from unsent to sent may send it.
+type ReminderRepository interface {
+	Create(reminder models.Reminder) error
+	GetByTaskID(taskID string) ([]models.Reminder, error)
+	// GetDue returns up to limit unsent reminders due by now, earliest first
+	GetDue(now time.Time, limit int) ([]models.Reminder, error)
+	// Claim marks the reminder sent at the given time unless it already
+	// is, and reports whether this call marked it
+	Claim(reminderID string, at time.Time) (bool, error)
+}
+
+// ReminderService schedules reminders about tasks and turns the due ones
+// into notifications
+type ReminderService struct {
+	reminderRepo     ReminderRepository
+	taskRepo         ReminderTaskRepository
+	notificationRepo NotificationRepository
+	clock            clock.Clock
+	logger           *log.Logger
+}
+
+func NewReminderService(reminders ReminderRepository, tasks ReminderTaskRepository, notifications NotificationRepository) *ReminderService {
+	return &ReminderService{
+		reminderRepo:     reminders,
+		taskRepo:         tasks,
+		notificationRepo: notifications,
+		clock:            clock.Real(),
+		logger:           log.Default(),
+	}
+}
+
+// SetClock replaces the clock reminders are checked against, for tests
+// that need to control it
+func (s *ReminderService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetLogger replaces the logger failed sends are reported to
+func (s *ReminderService) SetLogger(logger *log.Logger) {
+	s.logger = logger
+}
+
+// AddReminder schedules a reminder to the user about the task at remindAt,
+// which must still be to come. models.ParseRemindAt reads the times the
+// CLI accepts.
+func (s *ReminderService) AddReminder(userID, taskID string, remindAt time.Time) (*models.Reminder, error) {
+	if _, err := s.taskRepo.GetByID(taskID); err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	if !remindAt.After(s.clock.Now()) {
+		return nil, fmt.Errorf("reminder time %s has already passed", remindAt.Format(reminderDueFormat))
+	}
+
+	reminder, err := models.NewReminder(taskID, userID, remindAt)
+	if err != nil {
+		return nil, err
+	}
+	reminder.CreatedAt = s.clock.Now()
+
+	if err := s.reminderRepo.Create(*reminder); err != nil {
+		return nil, fmt.Errorf("failed to create reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// GetReminders returns the task's reminders, sent or not
+func (s *ReminderService) GetReminders(taskID string) ([]models.Reminder, error) {
+	reminders, err := s.reminderRepo.GetByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// SendDue notifies users of every reminder that has come due and returns
+// how many it sent. Each reminder is claimed before its notification is
+// created, so a reminder another scheduler claimed first is skipped, and
+// one whose notification then fails to save is lost rather than sent
+// twice. Reminders about tasks that are done, cancelled or deleted are
+// claimed without sending.
+func (s *ReminderService) SendDue() (int, error) {
+	sent := 0
+	for {
+		now := s.clock.Now()
+		due, err := s.reminderRepo.GetDue(now, reminderBatchSize)
+		if err != nil {
+			return sent, fmt.Errorf("failed to get due reminders: %w", err)
+		}
+
+		for _, reminder := range due {
+			claimed, err := s.reminderRepo.Claim(reminder.ID, now)
+			if err != nil {
+				return sent, fmt.Errorf("failed to claim reminder: %w", err)
+			}
+			if !claimed {
+				continue
+			}
+
+			notified, err := s.notify(reminder)
+			if err != nil {
+				return sent, err
+			}
+			if notified {
+				sent++
+			}
+		}
+
+		if len(due) < reminderBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+func (s *ReminderService) notify(reminder models.Reminder) (bool, error) {
+	task, err := s.taskRepo.GetByID(reminder.TaskID)
+	if err != nil || task.IsCompleted() || task.IsCancelled() || task.DeletedAt != nil {
+		return false, nil
+	}
+
+	message := "Reminder: " + task.Title
+	if task.DueAt != nil {
+		message += fmt.Sprintf(" (due %s)", task.DueAt.Format(reminderDueFormat))
+	}
+
+	notification, err := models.NewTaskNotification(reminder.UserID, models.NotificationTypeTaskReminder, task.ID, message)
+	if err != nil {
+		return false, err
+	}
+	if err := s.notificationRepo.Create(*notification); err != nil {
+		return false, fmt.Errorf("failed to send reminder %s: %w", reminder.ID, err)
+	}
+	return true, nil
+}
+
+// Run sends due reminders every interval until stop is closed. An interval
+// of zero or less uses DefaultReminderInterval. It is meant to run in its
+// own goroutine, and any number of servers may run it against one
+// database.
+func (s *ReminderService) Run(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultReminderInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDue(); err != nil {
+			s.logger.Printf("Sending reminders failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package hereandnow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// DefaultCompletionUndoWindow is how long the user who completed a task in a
+// shared list can take it back when no window is configured
+const DefaultCompletionUndoWindow = 2 * time.Minute
+
+// CompletionUndoRepository stores shared-list completions that can still be
+// undone
+type CompletionUndoRepository interface {
+	Save(undo models.CompletionUndo) error
+	GetByTaskID(taskID string) (*models.CompletionUndo, error)
+	Delete(taskID string) error
+}
+
+// notificationRetractor is implemented by notification repositories that
+// can take back a notification
+type notificationRetractor interface {
+	Delete(notificationID string) error
+}
+
+// EnableSharedCompletionUndo makes completing a task in a shared list notify
+// the list's other members and gives the user who completed it window to
+// take it back with UndoSharedCompletion. A window of zero uses
+// DefaultCompletionUndoWindow. Notifications also need a notification
+// repository.
+func (s *TaskService) EnableSharedCompletionUndo(members ListMemberRepository, undos CompletionUndoRepository, window time.Duration) {
+	if window <= 0 {
+		window = DefaultCompletionUndoWindow
+	}
+	s.memberRepo = members
+	s.completionUndoRepo = undos
+	s.completionUndoWindow = window
+}
+
+// UndoSharedCompletion takes back a completion in a shared list within its
+// undo window. The task is put back exactly as it was, including when it was
+// last updated, the next occurrence a recurring task spawned is removed and
+// the members' completion notifications are withdrawn, so the completion
+// leaves nothing behind. Only the user who completed the task can undo it,
+// and once the window has passed the completion is final.
+func (s *TaskService) UndoSharedCompletion(taskID string, userID string) (*models.Task, error) {
+	if s.completionUndoRepo == nil {
+		return nil, fmt.Errorf("shared completion undo is not enabled")
+	}
+
+	undo, err := s.completionUndoRepo.GetByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("no completion to undo for task %s", taskID)
+	}
+
+	if undo.UserID != userID {
+		return nil, fmt.Errorf("only the user who completed the task can undo it")
+	}
+
+	if undo.IsExpiredAt(s.clock.Now()) {
+		s.completionUndoRepo.Delete(taskID)
+		return nil, fmt.Errorf("undo window closed at %s; the completion is final", undo.ExpiresAt.Format(time.RFC3339))
+	}
+
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+	if !task.IsCompleted() {
+		s.completionUndoRepo.Delete(taskID)
+		return nil, fmt.Errorf("task is no longer completed")
+	}
+
+	before, err := undo.GetTask()
+	if err != nil {
+		return nil, err
+	}
+
+	task.Status = before.Status
+	task.CompletedAt = before.CompletedAt
+	task.SnoozedUntil = before.SnoozedUntil
+	task.UpdatedAt = before.UpdatedAt
+	err = s.withTx(func(tx *TaskService) error {
+		if err := tx.saveTask(userID, task); err != nil {
+			return err
+		}
+		return tx.deleteSpawnedInstance(undo.SpawnedTaskID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to undo completion: %w", err)
+	}
+	parents, err := s.rollUp(*task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen parent tasks: %w", err)
+	}
+
+	if retractor, ok := s.notificationRepo.(notificationRetractor); ok {
+		for _, notificationID := range undo.NotificationIDs {
+			retractor.Delete(notificationID)
+		}
+	}
+
+	if err := s.completionUndoRepo.Delete(taskID); err != nil {
+		return nil, fmt.Errorf("failed to clear undone completion: %w", err)
+	}
+	s.forgetCompleteAction(userID, taskID)
+	s.publishTask(EventTaskUpdated, userID, *task)
+	s.publishRollUp(userID, parents)
+
+	return task, nil
+}
+
+// announceSharedCompletion notifies the other members of the task's list
+// that it was completed and opens its undo window. Like recordAction it is
+// best effort: the completion stands even if members can't be told.
+func (s *TaskService) announceSharedCompletion(userID string, snapshot models.TaskSnapshot, task models.Task) {
+	if s.completionUndoRepo == nil || task.ListID == nil || !task.IsCompleted() {
+		return
+	}
+
+	members, err := s.memberRepo.GetByListID(*task.ListID)
+	if err != nil {
+		return
+	}
+
+	completer := "Someone"
+	if s.userRepo != nil {
+		if user, err := s.userRepo.GetByID(userID); err == nil {
+			completer = user.Username
+		}
+	}
+	message := fmt.Sprintf("%s completed a task in a shared list: %s", completer, task.Title)
+
+	shared := false
+	notificationIDs := []string{}
+	for _, member := range members {
+		if member.IsUser(userID) || !member.HasAccepted() {
+			continue
+		}
+		shared = true
+
+		if s.notificationRepo == nil {
+			continue
+		}
+		notification, err := models.NewTaskNotification(member.UserID, models.NotificationTypeTaskCompleted, task.ID, message)
+		if err != nil {
+			continue
+		}
+		if err := s.notificationRepo.Create(*notification); err == nil {
+			notificationIDs = append(notificationIDs, notification.ID)
+		}
+	}
+	if !shared {
+		return
+	}
+
+	undo, err := models.NewCompletionUndo(userID, snapshot.Task, notificationIDs, *task.CompletedAt, s.completionUndoWindow)
+	if err != nil {
+		return
+	}
+	undo.SpawnedTaskID = snapshot.SpawnedTaskID
+	s.completionUndoRepo.Save(*undo)
+}
+
+// forgetCompleteAction drops the user's undo log entry for a completion that
+// was already undone, so Undo can't reverse it a second time
+func (s *TaskService) forgetCompleteAction(userID, taskID string) {
+	if s.actionRepo == nil {
+		return
+	}
+
+	actions, err := s.actionRepo.GetByUserID(userID)
+	if err != nil {
+		return
+	}
+	for _, action := range actions {
+		if action.Type == models.TaskActionComplete && action.TaskID == taskID {
+			s.actionRepo.Delete(action.ID)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package hereandnow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// GetCompletionStats returns the user's completions today and their
+// completion streaks, with days split in the user's timezone. A task counts
+// for its assignee, or for its creator when unassigned.
+func (s *TaskService) GetCompletionStats(userID string) (*models.CompletionStats, error) {
+	tasks, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	var completions []time.Time
+	for _, task := range tasks {
+		if task.CompletedAt == nil {
+			continue
+		}
+
+		owner := task.CreatorID
+		if task.AssigneeID != nil {
+			owner = *task.AssigneeID
+		}
+		if owner == userID {
+			completions = append(completions, *task.CompletedAt)
+		}
+	}
+
+	return models.NewCompletionStats(completions, s.clock.Now(), s.userLocation(userID)), nil
+}
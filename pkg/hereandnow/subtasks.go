@@ -0,0 +1,144 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// subtaskLister is a task repository that can look up a task's subtasks
+// directly
+type subtaskLister interface {
+	GetSubtasks(parentTaskID string) ([]models.Task, error)
+}
+
+// GetSubtasks returns the tasks whose parent is parentID, oldest first
+func (s *TaskService) GetSubtasks(parentID string) ([]models.Task, error) {
+	parent, err := s.taskRepo.GetByID(parentID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+	return s.subtasks(*parent)
+}
+
+func (s *TaskService) subtasks(parent models.Task) ([]models.Task, error) {
+	if lister, ok := s.taskRepo.(subtaskLister); ok {
+		return lister.GetSubtasks(parent.ID)
+	}
+
+	tasks, err := s.taskRepo.GetByUserID(parent.CreatorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks: %w", err)
+	}
+	var subtasks []models.Task
+	for _, task := range tasks {
+		if task.ParentTaskID != nil && *task.ParentTaskID == parent.ID {
+			subtasks = append(subtasks, task)
+		}
+	}
+	return subtasks, nil
+}
+
+// checkParent checks that parentID exists and that making it taskID's
+// parent would not make the task its own ancestor
+func (s *TaskService) checkParent(taskID, parentID string) error {
+	errs := &models.ValidationError{}
+	seen := make(map[string]bool)
+	for id := parentID; !seen[id]; {
+		if id == taskID {
+			errs.Add("parent_task_id", "would make the task its own ancestor")
+			break
+		}
+		seen[id] = true
+
+		ancestor, err := s.taskRepo.GetByID(id)
+		if err != nil {
+			if id == parentID {
+				errs.Add("parent_task_id", "not found")
+			}
+			break
+		}
+		if ancestor.ParentTaskID == nil {
+			break
+		}
+		id = *ancestor.ParentTaskID
+	}
+	return errs.Err()
+}
+
+// rollUp carries a change in the task's status up its ancestors. A parent
+// is completed once every subtask that isn't cancelled is completed, and a
+// completed parent is reopened when the task is reopened or a new open
+// subtask is added under it. Recurring parents are a series rather than a
+// breakdown of work and are left alone, as are cancelled ones. It returns
+// the parents it changed, nearest first.
+func (s *TaskService) rollUp(task models.Task) ([]models.Task, error) {
+	var changed []models.Task
+	seen := map[string]bool{task.ID: true}
+	for child := task; child.ParentTaskID != nil && !seen[*child.ParentTaskID]; {
+		parent, err := s.taskRepo.GetByID(*child.ParentTaskID)
+		if err != nil || parent.RecurrenceRule != nil || parent.IsCancelled() {
+			break
+		}
+		seen[parent.ID] = true
+
+		now := s.clock.Now()
+		switch {
+		case !parent.IsCompleted() && (child.IsCompleted() || child.IsCancelled()):
+			done, err := s.subtasksDone(*parent)
+			if err != nil {
+				return nil, err
+			}
+			if !done {
+				return changed, nil
+			}
+			parent.Status = models.TaskStatusCompleted
+			parent.CompletedAt = &now
+		case parent.IsCompleted() && !child.IsCompleted() && !child.IsCancelled():
+			parent.Status = models.TaskStatusPending
+			parent.CompletedAt = nil
+		default:
+			return changed, nil
+		}
+
+		parent.UpdatedAt = now
+		if err := s.saveTask("", parent); err != nil {
+			return nil, fmt.Errorf("failed to update parent task: %w", err)
+		}
+		changed = append(changed, *parent)
+		child = *parent
+	}
+	return changed, nil
+}
+
+// subtasksDone reports whether at least one of the parent's subtasks is
+// completed and the rest are completed or cancelled
+func (s *TaskService) subtasksDone(parent models.Task) (bool, error) {
+	subtasks, err := s.subtasks(parent)
+	if err != nil {
+		return false, err
+	}
+
+	completed := 0
+	for _, subtask := range subtasks {
+		switch {
+		case subtask.DeletedAt != nil, subtask.IsCancelled():
+		case subtask.IsCompleted():
+			completed++
+		default:
+			return false, nil
+		}
+	}
+	return completed > 0, nil
+}
+
+// publishRollUp announces the parents rollUp changed
+func (s *TaskService) publishRollUp(actorID string, parents []models.Task) {
+	for _, parent := range parents {
+		eventType := EventTaskUpdated
+		if parent.IsCompleted() {
+			eventType = EventTaskCompleted
+		}
+		s.publishTask(eventType, actorID, parent)
+	}
+}
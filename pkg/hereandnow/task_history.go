@@ -0,0 +1,103 @@
+package hereandnow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ErrTaskNotVisible is returned for a task that does not exist or that the
+// user can't see: they didn't create it, aren't assigned it, and aren't a
+// member of its list
+var ErrTaskNotVisible = errors.New("task not found")
+
+// TaskHistoryRepository stores who changed which fields of a task, and when
+type TaskHistoryRepository interface {
+	Create(entry models.TaskHistoryEntry) error
+	// GetByTaskID returns the task's history, oldest first
+	GetByTaskID(taskID string) ([]models.TaskHistoryEntry, error)
+}
+
+// EnableTaskHistory records the fields every saved task edit changes, and
+// who changed them, so the people sharing a task can see its history
+func (s *TaskService) EnableTaskHistory(history TaskHistoryRepository) {
+	s.historyRepo = history
+}
+
+// GetTaskHistory returns the task's edits, oldest first, to a user who
+// created it, is assigned it or is a member of its list
+func (s *TaskService) GetTaskHistory(taskID string, userID string) ([]models.TaskHistoryEntry, error) {
+	if s.historyRepo == nil {
+		return nil, fmt.Errorf("task history is not enabled")
+	}
+
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTaskNotVisible, err)
+	}
+	if !ownsTask(*task, userID) {
+		canView, err := s.canViewListOf(*task, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !canView {
+			return nil, ErrTaskNotVisible
+		}
+	}
+
+	entries, err := s.historyRepo.GetByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task history: %w", err)
+	}
+	return entries, nil
+}
+
+// recordHistory saves the fields that differ between before and after as an
+// edit by actorID. An edit that changes nothing is not recorded.
+func (s *TaskService) recordHistory(actorID string, before, after models.Task) error {
+	changes, err := models.DiffTasks(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff task: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	entry, err := models.NewTaskHistoryEntry(after.ID, actorID, changes)
+	if err != nil {
+		return err
+	}
+	entry.CreatedAt = s.clock.Now()
+	if err := s.historyRepo.Create(*entry); err != nil {
+		return fmt.Errorf("failed to record task history: %w", err)
+	}
+	return nil
+}
+
+// canViewListOf reports whether the task is in a list the user owns or has
+// joined
+func (s *TaskService) canViewListOf(task models.Task, userID string) (bool, error) {
+	if task.ListID == nil {
+		return false, nil
+	}
+	if s.listRepo != nil {
+		if list, err := s.listRepo.GetByID(*task.ListID); err == nil && list.IsOwnedBy(userID) {
+			return true, nil
+		}
+	}
+	if s.memberRepo == nil {
+		return false, nil
+	}
+
+	members, err := s.memberRepo.GetByListID(*task.ListID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get list members: %w", err)
+	}
+	for _, member := range members {
+		if member.IsUser(userID) && member.HasAccepted() && member.CanView() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
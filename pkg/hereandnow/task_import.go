@@ -0,0 +1,209 @@
+package hereandnow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+)
+
+// importBatchSize is how many tasks ImportTasks saves per transaction
+const importBatchSize = 100
+
+// importLocationRadius is the radius, in meters, of locations created for
+// imported tasks
+const importLocationRadius = 100
+
+// ImportLocationRepository finds and creates the locations imported tasks
+// name
+type ImportLocationRepository interface {
+	GetByUserID(userID string) ([]models.Location, error)
+	Create(location models.Location) error
+}
+
+// ImportOptions controls how ImportTasks saves tasks
+type ImportOptions struct {
+	// CreateMissingLocations creates a location a task names that the user
+	// does not have, from the task's coordinates, instead of failing the task
+	CreateMissingLocations bool
+}
+
+// EnableImportLocations lets ImportTasks link tasks to the locations they
+// name. Without it, tasks that name a location fail.
+func (s *TaskService) EnableImportLocations(locations ImportLocationRepository) {
+	s.importLocationRepo = locations
+}
+
+type pendingImport struct {
+	line        int
+	task        models.Task
+	locationIDs []string
+}
+
+// ImportTasks creates the parsed tasks for the user and records each outcome
+// in report. A task with the same title as one of the user's open tasks is
+// skipped, so importing the same file twice does not duplicate it. Each
+// task is validated and its location resolved first, so a bad task fails on
+// its own; the rest are saved importBatchSize at a time, each batch in one
+// transaction. When a batch cannot be saved every task in it fails, since
+// none of them were kept. Locations are created before the batches and are
+// kept even if their tasks fail.
+func (s *TaskService) ImportTasks(userID string, tasks []sync.ImportedTask, report *sync.ImportReport, opts ImportOptions) error {
+	existing, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user tasks: %w", err)
+	}
+
+	open := make(map[string]bool, len(existing))
+	for _, task := range existing {
+		if !task.IsCompleted() && !task.IsCancelled() {
+			open[strings.ToLower(task.Title)] = true
+		}
+	}
+
+	var locations map[string]string
+	var pending []pendingImport
+	for _, imported := range tasks {
+		key := strings.ToLower(imported.Title)
+		if open[key] {
+			report.Skip(imported.Line, imported.Title, "an open task with this title already exists")
+			continue
+		}
+
+		task, err := newImportedTask(userID, imported, s.clock.Now())
+		if err != nil {
+			report.Fail(imported.Line, imported.Title, err.Error())
+			continue
+		}
+
+		var locationIDs []string
+		if imported.Location != "" {
+			if locations == nil {
+				if locations, err = s.importLocations(userID); err != nil {
+					return err
+				}
+			}
+			locationID, err := s.resolveImportLocation(userID, imported, locations, opts)
+			if err != nil {
+				report.Fail(imported.Line, imported.Title, err.Error())
+				continue
+			}
+			locationIDs = []string{locationID}
+		}
+
+		open[key] = true
+		pending = append(pending, pendingImport{line: imported.Line, task: task, locationIDs: locationIDs})
+	}
+
+	for start := 0; start < len(pending); start += importBatchSize {
+		batch := pending[start:min(start+importBatchSize, len(pending))]
+		saved := 0
+		err := s.withTx(func(tx *TaskService) error {
+			for _, item := range batch {
+				if err := tx.taskRepo.Create(item.task); err != nil {
+					return fmt.Errorf("failed to create task %q: %w", item.task.Title, err)
+				}
+				if err := tx.addTaskLocations(item.task.ID, item.locationIDs, ""); err != nil {
+					return fmt.Errorf("failed to add task locations: %w", err)
+				}
+				saved++
+			}
+			return nil
+		})
+
+		for i, item := range batch {
+			// Without a transactor the tasks saved before the failure stay
+			if err == nil || (s.transactor == nil && i < saved) {
+				report.Import(item.line, item.task.Title, item.task.ID)
+				s.publishTask(EventTaskCreated, userID, item.task)
+				continue
+			}
+			report.Fail(item.line, item.task.Title, fmt.Sprintf("batch not saved: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// newImportedTask builds and validates the task an imported record becomes
+func newImportedTask(userID string, imported sync.ImportedTask, now time.Time) (models.Task, error) {
+	task, err := models.NewTask(imported.Title, imported.Description, userID)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if imported.Priority != 0 {
+		task.Priority = imported.Priority
+	}
+	task.DueAt = imported.DueAt
+	task.EstimatedMinutes = imported.EstimatedMinutes
+	task.RecurrenceRule = imported.RecurrenceRule
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	if imported.Status != "" {
+		task.Status = imported.Status
+		if task.IsCompleted() {
+			task.CompletedAt = &now
+		}
+	}
+	if len(imported.Tags) > 0 {
+		task.Metadata, _ = json.Marshal(map[string][]string{"tags": imported.Tags})
+		task.Tags = imported.Tags
+	}
+
+	if err := task.Validate(); err != nil {
+		return models.Task{}, err
+	}
+	return *task, nil
+}
+
+// importLocations maps the user's location names, in lower case, to their IDs
+func (s *TaskService) importLocations(userID string) (map[string]string, error) {
+	locations := map[string]string{}
+	if s.importLocationRepo == nil {
+		return locations, nil
+	}
+
+	existing, err := s.importLocationRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user locations: %w", err)
+	}
+	for _, location := range existing {
+		locations[strings.ToLower(location.Name)] = location.ID
+	}
+	return locations, nil
+}
+
+// resolveImportLocation returns the ID of the location an imported task
+// names, creating it when opts allow and adding it to locations
+func (s *TaskService) resolveImportLocation(userID string, imported sync.ImportedTask, locations map[string]string, opts ImportOptions) (string, error) {
+	if s.importLocationRepo == nil {
+		return "", fmt.Errorf("cannot link location %q: locations are not available", imported.Location)
+	}
+
+	key := strings.ToLower(imported.Location)
+	if id, ok := locations[key]; ok {
+		return id, nil
+	}
+
+	if !opts.CreateMissingLocations {
+		return "", fmt.Errorf("unknown location %q", imported.Location)
+	}
+	if imported.Latitude == nil || imported.Longitude == nil {
+		return "", fmt.Errorf("cannot create location %q without latitude and longitude", imported.Location)
+	}
+
+	location, err := models.NewLocation(userID, imported.Location, "", *imported.Latitude, *imported.Longitude, importLocationRadius)
+	if err != nil {
+		return "", fmt.Errorf("cannot create location %q: %w", imported.Location, err)
+	}
+	if err := s.importLocationRepo.Create(*location); err != nil {
+		return "", fmt.Errorf("failed to create location %q: %w", imported.Location, err)
+	}
+
+	locations[key] = location.ID
+	return location.ID, nil
+}
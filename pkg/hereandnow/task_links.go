@@ -0,0 +1,138 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskLinkRepository stores related and duplicate links between tasks
+type TaskLinkRepository interface {
+	Create(link models.TaskLink) error
+	GetByTaskID(taskID string) ([]models.TaskLink, error)
+	Delete(linkID string) error
+}
+
+// LinkedTask is a task linked to the one being viewed. Relation is how the
+// viewed task relates to it: "related", "duplicate-of" or "duplicated-by".
+type LinkedTask struct {
+	LinkID   string      `json:"link_id"`
+	Relation string      `json:"relation"`
+	Task     models.Task `json:"task"`
+}
+
+// EnableTaskLinks lets tasks be linked as related or as duplicates without
+// making either depend on the other
+func (s *TaskService) EnableTaskLinks(links TaskLinkRepository) {
+	s.linkRepo = links
+}
+
+// LinkTasks links taskID to linkedTaskID. A duplicate link marks taskID as a
+// duplicate of linkedTaskID, and with cancelDuplicate also cancels taskID
+// unless it is already closed, as a change by userID. Two tasks can be
+// linked only once, in either direction.
+func (s *TaskService) LinkTasks(taskID, linkedTaskID string, userID string, linkType models.TaskLinkType, cancelDuplicate bool) (*models.TaskLink, error) {
+	if s.linkRepo == nil {
+		return nil, fmt.Errorf("task links are not enabled")
+	}
+
+	link, err := models.NewTaskLink(taskID, linkedTaskID, linkType)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+	if _, err := s.taskRepo.GetByID(linkedTaskID); err != nil {
+		return nil, fmt.Errorf("linked task not found: %w", err)
+	}
+
+	existing, err := s.findTaskLink(taskID, linkedTaskID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("tasks are already linked as %s", existing.LinkType)
+	}
+
+	if err := s.linkRepo.Create(*link); err != nil {
+		return nil, fmt.Errorf("failed to link tasks: %w", err)
+	}
+
+	if linkType == models.TaskLinkTypeDuplicate && cancelDuplicate && !task.IsCompleted() && !task.IsCancelled() {
+		if err := task.SetStatus(models.TaskStatusCancelled); err != nil {
+			return nil, err
+		}
+		if err := s.saveTask(userID, task); err != nil {
+			return nil, fmt.Errorf("failed to cancel duplicate task: %w", err)
+		}
+		s.publishTask(EventTaskUpdated, userID, *task)
+	}
+
+	return link, nil
+}
+
+// UnlinkTasks removes the link between two tasks, whichever way it points
+func (s *TaskService) UnlinkTasks(taskID, linkedTaskID string) error {
+	if s.linkRepo == nil {
+		return fmt.Errorf("task links are not enabled")
+	}
+
+	link, err := s.findTaskLink(taskID, linkedTaskID)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return fmt.Errorf("tasks are not linked")
+	}
+
+	if err := s.linkRepo.Delete(link.ID); err != nil {
+		return fmt.Errorf("failed to unlink tasks: %w", err)
+	}
+
+	return nil
+}
+
+// GetLinkedTasks returns the tasks linked to taskID from either end of the
+// link, so a related link shows up on both tasks
+func (s *TaskService) GetLinkedTasks(taskID string) ([]LinkedTask, error) {
+	if s.linkRepo == nil {
+		return nil, fmt.Errorf("task links are not enabled")
+	}
+
+	links, err := s.linkRepo.GetByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task links: %w", err)
+	}
+
+	linked := make([]LinkedTask, 0, len(links))
+	for _, link := range links {
+		other, err := s.taskRepo.GetByID(link.OtherTaskID(taskID))
+		if err != nil {
+			continue
+		}
+		linked = append(linked, LinkedTask{
+			LinkID:   link.ID,
+			Relation: link.RelationFrom(taskID),
+			Task:     *other,
+		})
+	}
+
+	return linked, nil
+}
+
+func (s *TaskService) findTaskLink(taskID, otherTaskID string) (*models.TaskLink, error) {
+	links, err := s.linkRepo.GetByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task links: %w", err)
+	}
+
+	for _, link := range links {
+		if link.OtherTaskID(taskID) == otherTaskID {
+			return &link, nil
+		}
+	}
+	return nil, nil
+}
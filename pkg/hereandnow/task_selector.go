@@ -0,0 +1,97 @@
+package hereandnow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskSelector picks out a user's tasks by field. Every set field must
+// match. Text matches like SearchTasks: every word must appear in the title
+// or description.
+type TaskSelector struct {
+	ListID   string
+	Status   models.TaskStatus
+	Priority int
+	Text     string
+}
+
+// ParseTaskSelector reads space-separated key=value terms such as
+// "list=<list-id> status=pending priority=3 text=report". Repeated text
+// terms add words.
+func ParseTaskSelector(selector string) (TaskSelector, error) {
+	var sel TaskSelector
+	var words []string
+
+	for _, term := range strings.Fields(selector) {
+		key, value, ok := strings.Cut(term, "=")
+		if !ok || value == "" {
+			return TaskSelector{}, fmt.Errorf("invalid selector term %q (want key=value)", term)
+		}
+
+		switch strings.ToLower(key) {
+		case "list":
+			sel.ListID = value
+		case "status":
+			sel.Status = models.TaskStatus(strings.ToLower(value))
+			if !sel.Status.IsValid() {
+				return TaskSelector{}, fmt.Errorf("invalid task status: %s", value)
+			}
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil || priority < 1 || priority > 5 {
+				return TaskSelector{}, fmt.Errorf("invalid priority %q (must be 1-5)", value)
+			}
+			sel.Priority = priority
+		case "text":
+			words = append(words, value)
+		default:
+			return TaskSelector{}, fmt.Errorf("unknown selector key %q (want list, status, priority or text)", key)
+		}
+	}
+
+	sel.Text = strings.Join(words, " ")
+	return sel, nil
+}
+
+// IsEmpty reports whether the selector matches every task
+func (sel TaskSelector) IsEmpty() bool {
+	return sel == TaskSelector{}
+}
+
+func (sel TaskSelector) matches(task models.Task) bool {
+	if sel.ListID != "" && (task.ListID == nil || *task.ListID != sel.ListID) {
+		return false
+	}
+	if sel.Status != "" && task.Status != sel.Status {
+		return false
+	}
+	if sel.Priority != 0 && task.Priority != sel.Priority {
+		return false
+	}
+	return true
+}
+
+// SelectTasks returns the user's tasks matching sel
+func (s *TaskService) SelectTasks(userID string, sel TaskSelector) ([]models.Task, error) {
+	var tasks []models.Task
+	var err error
+	if sel.Text != "" {
+		tasks, err = s.taskRepo.Search(userID, sel.Text)
+	} else {
+		tasks, err = s.taskRepo.GetByUserID(userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	var selected []models.Task
+	for _, task := range tasks {
+		if sel.matches(task) {
+			selected = append(selected, task)
+		}
+	}
+	return selected, nil
+}
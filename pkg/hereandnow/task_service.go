@@ -1,11 +1,14 @@
 package hereandnow
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/bcnelson/hereAndNow/pkg/clock"
 	"github.com/bcnelson/hereAndNow/pkg/filters"
 	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
 	"github.com/google/uuid"
 )
 
@@ -15,6 +18,31 @@ type TaskService struct {
 	dependencyRepo   TaskDependencyRepository
 	taskLocationRepo TaskLocationRepository
 	filterEngine     filters.FilterEngine
+	transactor       Transactor
+	userRepo         UserRepository
+	notificationRepo NotificationRepository
+	visibilityRepo   VisibilityRepository
+	actionRepo       ActionLogRepository
+	linkRepo         TaskLinkRepository
+	snoozePresets    models.SnoozePresets
+	recurrenceBasis  RecurrenceBasis
+	clock            clock.Clock
+
+	memberRepo           ListMemberRepository
+	listRepo             ListLookupRepository
+	completionUndoRepo   CompletionUndoRepository
+	completionUndoWindow time.Duration
+	importLocationRepo   ImportLocationRepository
+	events               EventPublisher
+	historyRepo          TaskHistoryRepository
+}
+
+type UserRepository interface {
+	GetByID(userID string) (*models.User, error)
+}
+
+type NotificationRepository interface {
+	Create(notification models.Notification) error
 }
 
 type TaskRepository interface {
@@ -35,6 +63,9 @@ type ContextRepository interface {
 
 type TaskDependencyRepository interface {
 	Create(dependency models.TaskDependency) error
+	// AddDependency creates the dependency unless it would close a cycle,
+	// which fails with a *models.DependencyCycleError
+	AddDependency(dependency models.TaskDependency) error
 	GetDependenciesByTaskID(taskID string) ([]models.TaskDependency, error)
 	GetDependentsByTaskID(taskID string) ([]models.TaskDependency, error)
 	Delete(dependentTaskID, dependsOnTaskID string) error
@@ -59,6 +90,23 @@ func NewTaskService(
 		dependencyRepo:   dependencyRepo,
 		taskLocationRepo: taskLocationRepo,
 		filterEngine:     filterEngine,
+		snoozePresets:    models.DefaultSnoozePresets(),
+		clock:            clock.Real(),
+	}
+}
+
+// clockedFilterEngine is a filter engine that ages contexts against a clock
+type clockedFilterEngine interface {
+	SetClock(c clock.Clock)
+}
+
+// SetClock replaces the clock the service reads the current time from, for
+// tests that need to control it. A filter engine with a clock of its own,
+// which it ages contexts against, is given it too.
+func (s *TaskService) SetClock(c clock.Clock) {
+	s.clock = c
+	if engine, ok := s.filterEngine.(clockedFilterEngine); ok {
+		engine.SetClock(c)
 	}
 }
 
@@ -67,7 +115,202 @@ func (s *TaskService) CreateTask(userID string, req CreateTaskRequest) (*models.
 		return nil, fmt.Errorf("invalid task request: %w", err)
 	}
 
-	task := models.Task{
+	task := newTaskFromRequest(userID, req, s.clock.Now())
+	if task.ParentTaskID != nil {
+		if err := s.checkParent(task.ID, *task.ParentTaskID); err != nil {
+			return nil, fmt.Errorf("invalid task request: %w", err)
+		}
+	}
+	if err := s.checkListNotArchived(task); err != nil {
+		return nil, err
+	}
+	locationIDs, err := s.applyListDefaults(&task, req.LocationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var parents []models.Task
+	err = s.withTx(func(tx *TaskService) error {
+		if task.ListID != nil {
+			position, err := tx.nextListPosition(*task.ListID)
+			if err != nil {
+				return fmt.Errorf("failed to position task in list: %w", err)
+			}
+			task.Position = position
+		}
+
+		if err := tx.taskRepo.Create(task); err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+
+		if err := tx.addTaskLocations(task.ID, locationIDs, req.LocationTrigger); err != nil {
+			return fmt.Errorf("failed to add task locations: %w", err)
+		}
+
+		if err := tx.addTaskDependencies(task.ID, req.Dependencies); err != nil {
+			return fmt.Errorf("failed to add task dependencies: %w", err)
+		}
+
+		parents, err = tx.rollUp(task)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishTask(EventTaskCreated, userID, task)
+	s.publishRollUp(userID, parents)
+
+	return &task, nil
+}
+
+// taskBatchCreator is a task repository that can insert many tasks at once
+type taskBatchCreator interface {
+	CreateBatch(tasks []*models.Task) error
+}
+
+// CreateTasks creates a task for each request, all or none. Every request
+// is checked before anything is written, and tasks added to the same list
+// are placed in the order given.
+func (s *TaskService) CreateTasks(userID string, reqs []CreateTaskRequest) ([]models.Task, error) {
+	now := s.clock.Now()
+	tasks := make([]models.Task, len(reqs))
+	locationIDs := make([][]string, len(reqs))
+	for i, req := range reqs {
+		if err := req.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid task request %d: %w", i+1, err)
+		}
+
+		tasks[i] = newTaskFromRequest(userID, req, now)
+		if req.ParentTaskID != nil {
+			if err := s.checkParent(tasks[i].ID, *req.ParentTaskID); err != nil {
+				return nil, fmt.Errorf("invalid task request %d: %w", i+1, err)
+			}
+		}
+		if err := s.checkListNotArchived(tasks[i]); err != nil {
+			return nil, fmt.Errorf("task request %d: %w", i+1, err)
+		}
+		ids, err := s.applyListDefaults(&tasks[i], req.LocationIDs)
+		if err != nil {
+			return nil, fmt.Errorf("task request %d: %w", i+1, err)
+		}
+		locationIDs[i] = ids
+	}
+
+	var parents []models.Task
+	err := s.withTx(func(tx *TaskService) error {
+		last := make(map[string]float64)
+		for i := range tasks {
+			listID := tasks[i].ListID
+			if listID == nil {
+				continue
+			}
+			if previous, ok := last[*listID]; ok {
+				tasks[i].Position, _ = models.PositionBetween(&previous, nil)
+			} else {
+				position, err := tx.nextListPosition(*listID)
+				if err != nil {
+					return fmt.Errorf("failed to position task in list: %w", err)
+				}
+				tasks[i].Position = position
+			}
+			last[*listID] = tasks[i].Position
+		}
+
+		if batch, ok := tx.taskRepo.(taskBatchCreator); ok {
+			pointers := make([]*models.Task, len(tasks))
+			for i := range tasks {
+				pointers[i] = &tasks[i]
+			}
+			if err := batch.CreateBatch(pointers); err != nil {
+				return fmt.Errorf("failed to create tasks: %w", err)
+			}
+		} else {
+			for i := range tasks {
+				if err := tx.taskRepo.Create(tasks[i]); err != nil {
+					return fmt.Errorf("failed to create task %d: %w", i+1, err)
+				}
+			}
+		}
+
+		for i, req := range reqs {
+			if err := tx.addTaskLocations(tasks[i].ID, locationIDs[i], req.LocationTrigger); err != nil {
+				return fmt.Errorf("failed to add task locations: %w", err)
+			}
+			if err := tx.addTaskDependencies(tasks[i].ID, req.Dependencies); err != nil {
+				return fmt.Errorf("failed to add task dependencies: %w", err)
+			}
+		}
+
+		for _, task := range tasks {
+			changed, err := tx.rollUp(task)
+			if err != nil {
+				return err
+			}
+			parents = append(parents, changed...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		s.publishTask(EventTaskCreated, userID, task)
+	}
+	s.publishRollUp(userID, parents)
+
+	return tasks, nil
+}
+
+// PreviewCreateTask checks a create request the way CreateTask does and
+// returns the task it would create, without writing anything
+func (s *TaskService) PreviewCreateTask(userID string, req CreateTaskRequest) (*models.Task, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid task request: %w", err)
+	}
+
+	task := newTaskFromRequest(userID, req, s.clock.Now())
+	if task.ParentTaskID != nil {
+		if err := s.checkParent(task.ID, *task.ParentTaskID); err != nil {
+			return nil, fmt.Errorf("invalid task request: %w", err)
+		}
+	}
+	if err := s.checkListNotArchived(task); err != nil {
+		return nil, err
+	}
+	locationIDs, err := s.applyListDefaults(&task, req.LocationIDs)
+	if err != nil {
+		return nil, err
+	}
+	if task.ListID != nil {
+		position, err := s.nextListPosition(*task.ListID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to position task in list: %w", err)
+		}
+		task.Position = position
+	}
+
+	for _, locationID := range locationIDs {
+		taskLocation := models.TaskLocation{TaskID: task.ID, LocationID: locationID, Trigger: req.LocationTrigger}
+		if err := taskLocation.Validate(); err != nil {
+			return nil, fmt.Errorf("failed to add task locations: %w", err)
+		}
+	}
+
+	for _, dep := range req.Dependencies {
+		if _, err := s.taskRepo.GetByID(dep.DependsOnTaskID); err != nil {
+			return nil, fmt.Errorf("failed to add task dependencies: dependency %s not found", dep.DependsOnTaskID)
+		}
+	}
+
+	return &task, nil
+}
+
+func newTaskFromRequest(userID string, req CreateTaskRequest, now time.Time) models.Task {
+	// Validate has already rejected tags that don't normalize
+	tags, _ := models.NormalizeTags(req.Tags)
+	return models.Task{
 		ID:               uuid.New().String(),
 		Title:            req.Title,
 		Description:      req.Description,
@@ -77,30 +320,42 @@ func (s *TaskService) CreateTask(userID string, req CreateTaskRequest) (*models.
 		Status:           models.TaskStatusPending,
 		Priority:         req.Priority,
 		EstimatedMinutes: req.EstimatedMinutes,
+		EffortPoints:     req.EffortPoints,
+		RequiredEnergyLevel: req.RequiredEnergyLevel,
 		DueAt:            req.DueAt,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
 		Metadata:         req.Metadata,
 		RecurrenceRule:   req.RecurrenceRule,
 		ParentTaskID:     req.ParentTaskID,
+		Tags:             tags,
+		Version:          1,
 	}
+}
 
-	if err := s.taskRepo.Create(task); err != nil {
-		return nil, fmt.Errorf("failed to create task: %w", err)
-	}
-
-	if err := s.addTaskLocations(task.ID, req.LocationIDs); err != nil {
-		return nil, fmt.Errorf("failed to add task locations: %w", err)
+func (s *TaskService) GetFilteredTasks(userID string) ([]models.Task, []filters.FilterResult, error) {
+	allTasks, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user tasks: %w", err)
 	}
 
-	if err := s.addTaskDependencies(task.ID, req.Dependencies); err != nil {
-		return nil, fmt.Errorf("failed to add task dependencies: %w", err)
+	context, err := s.contextRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user context: %w", err)
 	}
 
-	return &task, nil
+	allTasks = s.withNextOccurrences(s.withoutArchivedLists(allTasks))
+	filteredTasks, filterResults := s.filterEngine.FilterTasks(*context, allTasks)
+	s.recordVisibility(userID, allTasks, filteredTasks)
+	
+	return filteredTasks, filterResults, nil
 }
 
-func (s *TaskService) GetFilteredTasks(userID string) ([]models.Task, []filters.FilterResult, error) {
+// GetFilteredTasksWithMinPriority filters the user's tasks against their
+// current context with its minimum priority replaced for this call only.
+// Visibility is not recorded, so a one-off narrower view does not later
+// report the tasks it hid as newly visible.
+func (s *TaskService) GetFilteredTasksWithMinPriority(userID string, minPriority int) ([]models.Task, []filters.FilterResult, error) {
 	allTasks, err := s.taskRepo.GetByUserID(userID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get user tasks: %w", err)
@@ -111,8 +366,11 @@ func (s *TaskService) GetFilteredTasks(userID string) ([]models.Task, []filters.
 		return nil, nil, fmt.Errorf("failed to get user context: %w", err)
 	}
 
-	filteredTasks, filterResults := s.filterEngine.FilterTasks(*context, allTasks)
-	
+	if err := context.SetMinPriority(minPriority); err != nil {
+		return nil, nil, err
+	}
+
+	filteredTasks, filterResults := s.filterEngine.FilterTasks(*context, s.withoutArchivedLists(allTasks))
 	return filteredTasks, filterResults, nil
 }
 
@@ -124,11 +382,56 @@ func (s *TaskService) GetTask(taskID string) (*models.Task, error) {
 	return task, nil
 }
 
+// GetTaskLocations returns the locations linked to a task
+func (s *TaskService) GetTaskLocations(taskID string) ([]models.Location, error) {
+	locations, err := s.taskLocationRepo.GetLocationsByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task locations: %w", err)
+	}
+	return locations, nil
+}
+
+// saveTask updates the task in the repository and, once saved, moves it to
+// the version the repository stored. With task history enabled the fields
+// that changed are recorded as an edit by actorID, which is empty for
+// changes the service makes on its own. Tasks in archived lists are never
+// saved, whichever path changed them.
+func (s *TaskService) saveTask(actorID string, task *models.Task) error {
+	if err := s.checkListNotArchived(*task); err != nil {
+		return err
+	}
+
+	var before *models.Task
+	if s.historyRepo != nil {
+		stored, err := s.taskRepo.GetByID(task.ID)
+		if err != nil {
+			return fmt.Errorf("task not found: %w", err)
+		}
+		before = stored
+	}
+
+	if err := s.taskRepo.Update(*task); err != nil {
+		return err
+	}
+	task.Version++
+
+	if before == nil {
+		return nil
+	}
+	return s.recordHistory(actorID, *before, *task)
+}
+
 func (s *TaskService) UpdateTask(taskID string, req UpdateTaskRequest) (*models.Task, error) {
 	task, err := s.taskRepo.GetByID(taskID)
 	if err != nil {
 		return nil, fmt.Errorf("task not found: %w", err)
 	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != task.Version {
+		return nil, fmt.Errorf("task %s is at version %d, not %d: %w", task.ID, task.Version, *req.Version, models.ErrTaskVersionConflict)
+	}
 
 	if req.Title != nil {
 		task.Title = *req.Title
@@ -141,6 +444,19 @@ func (s *TaskService) UpdateTask(taskID string, req UpdateTaskRequest) (*models.
 	}
 	if req.EstimatedMinutes != nil {
 		task.EstimatedMinutes = req.EstimatedMinutes
+		// An estimate set on the task is its own, not the list's
+		if inherited := TaskInheritedDefaults(*task); inherited.EstimatedMinutes {
+			inherited.EstimatedMinutes = false
+			if err := setInheritedDefaults(task, inherited); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.EffortPoints != nil {
+		task.EffortPoints = req.EffortPoints
+	}
+	if req.RequiredEnergyLevel != nil {
+		task.RequiredEnergyLevel = req.RequiredEnergyLevel
 	}
 	if req.DueAt != nil {
 		task.DueAt = req.DueAt
@@ -151,12 +467,40 @@ func (s *TaskService) UpdateTask(taskID string, req UpdateTaskRequest) (*models.
 	if req.AssigneeID != nil {
 		task.AssigneeID = req.AssigneeID
 	}
+	if req.ParentTaskID != nil {
+		if *req.ParentTaskID == "" {
+			task.ParentTaskID = nil
+		} else {
+			if err := s.checkParent(task.ID, *req.ParentTaskID); err != nil {
+				return nil, err
+			}
+			task.ParentTaskID = req.ParentTaskID
+		}
+	}
 
-	task.UpdatedAt = time.Now()
+	task.UpdatedAt = s.clock.Now()
 
-	if err := s.taskRepo.Update(*task); err != nil {
-		return nil, fmt.Errorf("failed to update task: %w", err)
+	var parents []models.Task
+	err = s.withTx(func(tx *TaskService) error {
+		if err := tx.saveTask(req.ActorID, task); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+		if req.Status == nil && req.ParentTaskID == nil {
+			return nil
+		}
+		parents, err = tx.rollUp(*task)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eventType := EventTaskUpdated
+	if task.IsCompleted() {
+		eventType = EventTaskCompleted
 	}
+	s.publishTask(eventType, "", *task)
+	s.publishRollUp("", parents)
 
 	return task, nil
 }
@@ -170,14 +514,56 @@ func (s *TaskService) CompleteTask(taskID string, userID string) (*models.Task,
 	if task.Status == models.TaskStatusCompleted {
 		return task, nil
 	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
 
-	completedAt := time.Now()
+	before := *task
+	completedAt := s.clock.Now()
 	task.Status = models.TaskStatusCompleted
 	task.CompletedAt = &completedAt
 	task.UpdatedAt = completedAt
 
-	if err := s.taskRepo.Update(*task); err != nil {
-		return nil, fmt.Errorf("failed to complete task: %w", err)
+	// A recurring task stays completed and its next instance is created in
+	// its place. A recurring snooze carries over to the instance, hiding it
+	// until the preset's next time.
+	next := s.nextInstance(*task, completedAt)
+	if next != nil && task.RecurringSnooze != nil {
+		until, err := s.resolveSnoozePreset(task.CreatorID, *task.RecurringSnooze, completedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reapply recurring snooze: %w", err)
+		}
+		next.RecurringSnooze = task.RecurringSnooze
+		next.SnoozedUntil = &until
+	}
+
+	var parents []models.Task
+	err = s.withTx(func(tx *TaskService) error {
+		if err := tx.saveTask(userID, task); err != nil {
+			return fmt.Errorf("failed to complete task: %w", err)
+		}
+		if next != nil {
+			if err := tx.createInstance(*task, *next); err != nil {
+				return fmt.Errorf("failed to create next occurrence: %w", err)
+			}
+		}
+		parents, err = tx.rollUp(*task)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := models.TaskSnapshot{Task: before}
+	if next != nil {
+		snapshot.SpawnedTaskID = next.ID
+	}
+	s.recordAction(userID, models.TaskActionComplete, snapshot)
+	s.announceSharedCompletion(userID, snapshot, *task)
+	s.publishTask(EventTaskCompleted, userID, *task)
+	s.publishRollUp(userID, parents)
+	if next != nil {
+		s.publishTask(EventTaskCreated, userID, *next)
 	}
 
 	return task, nil
@@ -188,18 +574,160 @@ func (s *TaskService) AssignTask(taskID string, assigneeID string, assignerID st
 	if err != nil {
 		return nil, fmt.Errorf("task not found: %w", err)
 	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
 
 	task.AssigneeID = &assigneeID
-	task.UpdatedAt = time.Now()
+	task.UpdatedAt = s.clock.Now()
 
-	if err := s.taskRepo.Update(*task); err != nil {
+	if err := s.saveTask(assignerID, task); err != nil {
 		return nil, fmt.Errorf("failed to assign task: %w", err)
 	}
 
+	s.publishTask(EventTaskUpdated, assignerID, *task)
+
+	return task, nil
+}
+
+// SetSnoozePresets replaces the named snooze presets
+func (s *TaskService) SetSnoozePresets(presets models.SnoozePresets) {
+	s.snoozePresets = presets
+}
+
+// SetUserRepository lets snooze presets resolve in each user's timezone
+// instead of the server's local time. Admin operations require it.
+func (s *TaskService) SetUserRepository(userRepo UserRepository) {
+	s.userRepo = userRepo
+}
+
+// SnoozeTask hides a task until the given time
+func (s *TaskService) SnoozeTask(taskID string, userID string, until time.Time) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
+
+	before := *task
+	if err := task.Snooze(until); err != nil {
+		return nil, err
+	}
+
+	if err := s.saveTask(userID, task); err != nil {
+		return nil, fmt.Errorf("failed to snooze task: %w", err)
+	}
+
+	s.recordAction(userID, models.TaskActionSnooze, models.TaskSnapshot{Task: before})
+	s.publishTask(EventTaskUpdated, userID, *task)
+
 	return task, nil
 }
 
-func (s *TaskService) DeleteTask(taskID string) error {
+// SnoozeTaskWithPreset snoozes a task until the named preset resolved in the
+// user's timezone. When recurring is set the preset is remembered and
+// reapplied each time the (recurring) task is completed.
+func (s *TaskService) SnoozeTaskWithPreset(taskID string, userID string, preset string, recurring bool) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
+
+	if recurring && task.RecurrenceRule == nil {
+		return nil, fmt.Errorf("recurring snooze requires a recurring task")
+	}
+
+	until, err := s.resolveSnoozePreset(userID, preset, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	before := *task
+	if err := task.Snooze(until); err != nil {
+		return nil, err
+	}
+
+	if recurring {
+		task.RecurringSnooze = &preset
+	}
+
+	if err := s.saveTask(userID, task); err != nil {
+		return nil, fmt.Errorf("failed to snooze task: %w", err)
+	}
+
+	s.recordAction(userID, models.TaskActionSnooze, models.TaskSnapshot{Task: before})
+	s.publishTask(EventTaskUpdated, userID, *task)
+
+	return task, nil
+}
+
+// UnsnoozeTask shows a snoozed task again straight away and drops its
+// recurring snooze, if any. Undo snoozes it again.
+func (s *TaskService) UnsnoozeTask(taskID string, userID string) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
+
+	if task.SnoozedUntil == nil && task.RecurringSnooze == nil {
+		return task, nil
+	}
+
+	before := *task
+	task.Unsnooze()
+	task.RecurringSnooze = nil
+	task.UpdatedAt = s.clock.Now()
+
+	if err := s.saveTask(userID, task); err != nil {
+		return nil, fmt.Errorf("failed to unsnooze task: %w", err)
+	}
+
+	s.recordAction(userID, models.TaskActionSnooze, models.TaskSnapshot{Task: before})
+	s.publishTask(EventTaskUpdated, userID, *task)
+
+	return task, nil
+}
+
+func (s *TaskService) resolveSnoozePreset(userID string, preset string, now time.Time) (time.Time, error) {
+	return s.snoozePresets.Resolve(preset, now, s.userLocation(userID))
+}
+
+// userLocation returns the user's timezone, falling back to local time
+func (s *TaskService) userLocation(userID string) *time.Location {
+	if s.userRepo == nil {
+		return time.Local
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || user.TimeZone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func (s *TaskService) DeleteTask(taskID string, userID string) error {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return err
+	}
+
 	dependencies, err := s.dependencyRepo.GetDependentsByTaskID(taskID)
 	if err != nil {
 		return fmt.Errorf("failed to check task dependencies: %w", err)
@@ -209,19 +737,83 @@ func (s *TaskService) DeleteTask(taskID string) error {
 		return fmt.Errorf("cannot delete task with %d dependent tasks", len(dependencies))
 	}
 
+	snapshot := s.deleteSnapshot(*task)
+
 	if err := s.taskRepo.Delete(taskID); err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
+	s.recordAction(userID, models.TaskActionDelete, snapshot)
+	s.publishTask(EventTaskDeleted, userID, *task)
+
 	return nil
 }
 
+// RestoreTask brings back a deleted task, with the locations and dependencies
+// it had, when the task repository soft-deletes
+func (s *TaskService) RestoreTask(taskID string, userID string) (*models.Task, error) {
+	restorer, ok := s.taskRepo.(taskRestorer)
+	if !ok {
+		return nil, fmt.Errorf("deleted tasks cannot be restored from this store")
+	}
+
+	if trash, ok := s.taskRepo.(taskTrash); ok {
+		if _, err := s.deletedTask(trash, taskID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := restorer.Restore(taskID); err != nil {
+		return nil, fmt.Errorf("failed to restore task: %w", err)
+	}
+
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored task: %w", err)
+	}
+
+	s.publishTask(EventTaskCreated, userID, *task)
+
+	return task, nil
+}
+
+// SearchTasks returns the user's tasks matching every word of query and,
+// with a list member repository set, those in shared lists they have joined
 func (s *TaskService) SearchTasks(userID string, query string) ([]models.Task, error) {
 	tasks, err := s.taskRepo.Search(userID, query)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
+	lister, ok := s.memberRepo.(listMembershipLister)
+	if !ok {
+		return tasks, nil
+	}
+	memberships, err := lister.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list memberships: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, task := range tasks {
+		seen[task.ID] = true
+	}
+	for _, membership := range memberships {
+		if !membership.HasAccepted() {
+			continue
+		}
+		listTasks, err := s.taskRepo.GetByListID(membership.ListID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tasks for list %s: %w", membership.ListID, err)
+		}
+		for _, task := range listTasks {
+			if !seen[task.ID] && MatchesSearch(task.Title+" "+task.Description, query) {
+				seen[task.ID] = true
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
 	return tasks, nil
 }
 
@@ -234,6 +826,152 @@ func (s *TaskService) GetTasksByStatus(userID string, status models.TaskStatus)
 	return tasks, nil
 }
 
+// GetOverdueTasks returns the user's open tasks whose due time has passed
+func (s *TaskService) GetOverdueTasks(userID string) ([]models.Task, error) {
+	now := s.clock.Now()
+	return s.userTasksWhere(userID, func(task models.Task) bool {
+		return task.IsOverdueAt(now) && !task.IsCancelled()
+	})
+}
+
+// GetSnoozedTasks returns the user's tasks that are still snoozed
+func (s *TaskService) GetSnoozedTasks(userID string) ([]models.Task, error) {
+	now := s.clock.Now()
+	return s.userTasksWhere(userID, func(task models.Task) bool {
+		return task.IsSnoozed(now)
+	})
+}
+
+func (s *TaskService) userTasksWhere(userID string, match func(task models.Task) bool) ([]models.Task, error) {
+	tasks, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tasks: %w", err)
+	}
+
+	var matched []models.Task
+	for _, task := range tasks {
+		if match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched, nil
+}
+
+func (s *TaskService) GetTasksByList(listID string) ([]models.Task, error) {
+	tasks, err := s.taskRepo.GetByListID(listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list tasks: %w", err)
+	}
+
+	models.SortTasksByPosition(tasks)
+	return tasks, nil
+}
+
+// ErrReorderNotAllowed is returned when a user who can't edit a task's list
+// tries to reorder it
+var ErrReorderNotAllowed = errors.New("only users who can edit a list can reorder its tasks")
+
+// ReorderTask moves a task so it sits directly after afterTaskID in its list,
+// or at the top of the list when afterTaskID is empty. The user must own the
+// list or have joined it as an owner or editor. Only the moved task is
+// written unless the gap between its new neighbours is exhausted, in which
+// case the whole list is renumbered.
+func (s *TaskService) ReorderTask(taskID string, afterTaskID string, userID string) (*models.Task, error) {
+	if taskID == afterTaskID {
+		return nil, fmt.Errorf("cannot move a task after itself")
+	}
+
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	if task.ListID == nil {
+		return nil, fmt.Errorf("task %s is not in a list", taskID)
+	}
+	if err := s.checkListNotArchived(*task); err != nil {
+		return nil, err
+	}
+
+	canEdit, err := s.canEditListOf(*task, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, ErrReorderNotAllowed
+	}
+
+	listTasks, err := s.GetTasksByList(*task.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	siblings := make([]models.Task, 0, len(listTasks))
+	for _, t := range listTasks {
+		if t.ID != taskID {
+			siblings = append(siblings, t)
+		}
+	}
+
+	insertAt := 0
+	if afterTaskID != "" {
+		insertAt = -1
+		for i, t := range siblings {
+			if t.ID == afterTaskID {
+				insertAt = i + 1
+				break
+			}
+		}
+		if insertAt < 0 {
+			return nil, fmt.Errorf("task %s is not in the same list", afterTaskID)
+		}
+	}
+
+	var before, after *float64
+	if insertAt > 0 {
+		before = &siblings[insertAt-1].Position
+	}
+	if insertAt < len(siblings) {
+		after = &siblings[insertAt].Position
+	}
+
+	if position, ok := models.PositionBetween(before, after); ok {
+		task.SetPosition(position)
+		if err := s.saveTask(userID, task); err != nil {
+			return nil, fmt.Errorf("failed to reorder task: %w", err)
+		}
+		s.publishTask(EventTaskUpdated, userID, *task)
+		return task, nil
+	}
+
+	ordered := make([]models.Task, 0, len(siblings)+1)
+	ordered = append(ordered, siblings[:insertAt]...)
+	ordered = append(ordered, *task)
+	ordered = append(ordered, siblings[insertAt:]...)
+	models.RebalancePositions(ordered)
+
+	err = s.withTx(func(tx *TaskService) error {
+		for i := range ordered {
+			if err := tx.saveTask(userID, &ordered[i]); err != nil {
+				return fmt.Errorf("failed to renumber list: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ordered {
+		if ordered[i].ID == taskID {
+			*task = ordered[i]
+		}
+		s.publishTask(EventTaskUpdated, userID, ordered[i])
+	}
+
+	return task, nil
+}
+
 func (s *TaskService) ExplainTaskVisibility(taskID string, userID string) (*filters.TaskVisibilityExplanation, error) {
 	task, err := s.taskRepo.GetByID(taskID)
 	if err != nil {
@@ -249,6 +987,52 @@ func (s *TaskService) ExplainTaskVisibility(taskID string, userID string) (*filt
 	return &explanation, nil
 }
 
+// DiffContext reports which of the user's tasks would appear or disappear
+// if their current context changed as described by changes, e.g.
+// "energy=2". Nothing is saved.
+func (s *TaskService) DiffContext(userID string, changes string) (*filters.ContextDiff, error) {
+	allTasks, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tasks: %w", err)
+	}
+
+	context, err := s.contextRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user context: %w", err)
+	}
+
+	modified := *context
+	if err := modified.ApplyChanges(changes); err != nil {
+		return nil, fmt.Errorf("invalid context changes: %w", err)
+	}
+
+	diff := s.filterEngine.DiffContexts(*context, modified, allTasks)
+	return &diff, nil
+}
+
+// GetScoredTasks scores the user's tasks against their current context,
+// most relevant first, with the tasks the filters hide at the end. Like
+// GetFilteredTasks it records which tasks are visible.
+func (s *TaskService) GetScoredTasks(userID string) ([]filters.ScoredTask, error) {
+	allTasks, err := s.taskRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tasks: %w", err)
+	}
+
+	context, err := s.contextRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user context: %w", err)
+	}
+
+	scored, err := s.filterEngine.ScoreTasks(*context, allTasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to score tasks: %w", err)
+	}
+
+	s.recordVisibility(userID, allTasks, filters.VisibleScoredTasks(scored))
+	return scored, nil
+}
+
 func (s *TaskService) GetAuditLog(taskID string, userID string) ([]filters.FilterResult, error) {
 	context, err := s.contextRepo.GetLatestByUserID(userID)
 	if err != nil {
@@ -263,13 +1047,39 @@ func (s *TaskService) GetAuditLog(taskID string, userID string) ([]filters.Filte
 	return auditLog, nil
 }
 
-func (s *TaskService) addTaskLocations(taskID string, locationIDs []string) error {
+func (s *TaskService) nextListPosition(listID string) (float64, error) {
+	tasks, err := s.taskRepo.GetByListID(listID)
+	if err != nil {
+		return 0, err
+	}
+
+	var last *float64
+	for i := range tasks {
+		if last == nil || tasks[i].Position > *last {
+			last = &tasks[i].Position
+		}
+	}
+
+	position, _ := models.PositionBetween(last, nil)
+	return position, nil
+}
+
+func (s *TaskService) addTaskLocations(taskID string, locationIDs []string, trigger models.LocationTrigger) error {
+	if trigger == "" {
+		trigger = models.LocationTriggerEnter
+	}
+
 	for _, locationID := range locationIDs {
 		taskLocation := models.TaskLocation{
 			ID:         uuid.New().String(),
 			TaskID:     taskID,
 			LocationID: locationID,
-			CreatedAt:  time.Now(),
+			Trigger:    trigger,
+			CreatedAt:  s.clock.Now(),
+		}
+
+		if err := taskLocation.Validate(); err != nil {
+			return err
 		}
 		
 		if err := s.taskLocationRepo.Create(taskLocation); err != nil {
@@ -286,7 +1096,7 @@ func (s *TaskService) addTaskDependencies(taskID string, dependencies []TaskDepe
 			TaskID:           taskID,
 			DependsOnTaskID:  dep.DependsOnTaskID,
 			DependencyType:   dep.DependencyType,
-			CreatedAt:        time.Now(),
+			CreatedAt:        s.clock.Now(),
 		}
 		
 		if err := s.dependencyRepo.Create(taskDep); err != nil {
@@ -303,12 +1113,16 @@ type CreateTaskRequest struct {
 	ListID           *string                   `json:"list_id"`
 	Priority         int                       `json:"priority"`
 	EstimatedMinutes *int                      `json:"estimated_minutes"`
+	EffortPoints     *int                      `json:"effort_points"`
+	RequiredEnergyLevel *int                   `json:"required_energy_level"`
 	DueAt            *time.Time                `json:"due_at"`
 	Metadata         []byte                    `json:"metadata"`
 	RecurrenceRule   *string                   `json:"recurrence_rule"`
 	ParentTaskID     *string                   `json:"parent_task_id"`
 	LocationIDs      []string                  `json:"location_ids"`
+	LocationTrigger  models.LocationTrigger    `json:"location_trigger"`
 	Dependencies     []TaskDependencyRequest   `json:"dependencies"`
+	Tags             []string                  `json:"tags"` // Normalized with models.NormalizeTag
 }
 
 type UpdateTaskRequest struct {
@@ -316,9 +1130,18 @@ type UpdateTaskRequest struct {
 	Description      *string            `json:"description"`
 	Priority         *int               `json:"priority"`
 	EstimatedMinutes *int               `json:"estimated_minutes"`
+	EffortPoints     *int               `json:"effort_points"`
+	RequiredEnergyLevel *int            `json:"required_energy_level"`
 	DueAt            *time.Time         `json:"due_at"`
 	Status           *models.TaskStatus `json:"status"`
 	AssigneeID       *string            `json:"assignee_id"`
+	ParentTaskID     *string            `json:"parent_task_id"` // Empty detaches the task from its parent
+	// Version is the version of the task the change was made to. When set,
+	// a task changed since then is left alone and the error wraps
+	// models.ErrTaskVersionConflict.
+	Version *int `json:"version,omitempty"`
+	// ActorID is the user making the change, recorded in the task's history
+	ActorID string `json:"-"`
 }
 
 type TaskDependencyRequest struct {
@@ -327,14 +1150,29 @@ type TaskDependencyRequest struct {
 }
 
 func (r CreateTaskRequest) Validate() error {
+	errs := &models.ValidationError{}
 	if r.Title == "" {
-		return fmt.Errorf("title is required")
+		errs.Add("title", "required")
 	}
 	if r.Priority < 1 || r.Priority > 10 {
-		return fmt.Errorf("priority must be between 1 and 10")
+		errs.Add("priority", "must be between 1 and 10")
 	}
 	if r.EstimatedMinutes != nil && *r.EstimatedMinutes < 0 {
-		return fmt.Errorf("estimated minutes cannot be negative")
+		errs.Add("estimated_minutes", "cannot be negative")
 	}
-	return nil
+	if r.EffortPoints != nil && *r.EffortPoints <= 0 {
+		errs.Add("effort_points", "must be positive")
+	}
+	if r.RequiredEnergyLevel != nil && (*r.RequiredEnergyLevel < 1 || *r.RequiredEnergyLevel > 5) {
+		errs.Add("required_energy_level", "must be between 1 and 5")
+	}
+	if r.RecurrenceRule != nil {
+		if _, err := recurrence.Parse(*r.RecurrenceRule); err != nil {
+			errs.Add("recurrence_rule", err.Error())
+		}
+	}
+	if _, err := models.NormalizeTags(r.Tags); err != nil {
+		errs.Add("tags", err.Error())
+	}
+	return errs.Err()
 }
\ No newline at end of file
@@ -0,0 +1,58 @@
+package hereandnow
+
+// TxRepositories are repositories bound to a single transaction
+type TxRepositories struct {
+	Tasks         TaskRepository
+	Dependencies  TaskDependencyRepository
+	TaskLocations TaskLocationRepository
+	Notifications NotificationRepository
+	Assignments   TaskAssignmentRepository
+	History       TaskHistoryRepository
+}
+
+// Transactor runs fn with repositories that share one transaction. The
+// transaction is committed when fn returns nil and rolled back otherwise.
+type Transactor interface {
+	WithTx(fn func(repos TxRepositories) error) error
+}
+
+// SetTransactor makes multi-step operations such as creating a task with its
+// locations and dependencies atomic. Without a transactor each step is
+// written independently.
+func (s *TaskService) SetTransactor(transactor Transactor) {
+	s.transactor = transactor
+}
+
+// withTx runs fn against a copy of the service whose repositories are bound
+// to a transaction, or that shares the service's repositories when no
+// transactor is set. The copy publishes no events, so nothing is announced
+// that might be rolled back; callers publish once withTx succeeds.
+func (s *TaskService) withTx(fn func(txService *TaskService) error) error {
+	if s.transactor == nil {
+		txService := *s
+		txService.events = nil
+		return fn(&txService)
+	}
+
+	return s.transactor.WithTx(func(repos TxRepositories) error {
+		txService := *s
+		txService.transactor = nil
+		txService.events = nil
+		if repos.Tasks != nil {
+			txService.taskRepo = repos.Tasks
+		}
+		if repos.Dependencies != nil {
+			txService.dependencyRepo = repos.Dependencies
+		}
+		if repos.TaskLocations != nil {
+			txService.taskLocationRepo = repos.TaskLocations
+		}
+		if repos.Notifications != nil {
+			txService.notificationRepo = repos.Notifications
+		}
+		if repos.History != nil && txService.historyRepo != nil {
+			txService.historyRepo = repos.History
+		}
+		return fn(&txService)
+	})
+}
@@ -0,0 +1,86 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// taskTrash is implemented by task repositories that soft-delete and can
+// list and permanently remove the deleted tasks
+type taskTrash interface {
+	GetDeleted(userID string) ([]models.Task, error)
+	GetDeletedByID(taskID string) (*models.Task, error)
+	Purge(taskID string, force bool) error
+}
+
+// ListTrash returns the user's deleted tasks, most recently deleted first
+func (s *TaskService) ListTrash(userID string) ([]models.Task, error) {
+	trash, ok := s.taskRepo.(taskTrash)
+	if !ok {
+		return nil, fmt.Errorf("this store does not keep deleted tasks")
+	}
+
+	tasks, err := trash.GetDeleted(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// PurgeTask permanently removes one of the user's deleted tasks. It fails
+// while other tasks depend on it, unless force is set.
+func (s *TaskService) PurgeTask(taskID string, userID string, force bool) error {
+	trash, ok := s.taskRepo.(taskTrash)
+	if !ok {
+		return fmt.Errorf("this store does not keep deleted tasks")
+	}
+
+	if _, err := s.deletedTask(trash, taskID, userID); err != nil {
+		return err
+	}
+
+	if err := trash.Purge(taskID, force); err != nil {
+		return fmt.Errorf("failed to purge task: %w", err)
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently removes all of the user's deleted tasks and returns
+// how many it removed. Without force it stops at the first task that others
+// depend on.
+func (s *TaskService) EmptyTrash(userID string, force bool) (int, error) {
+	tasks, err := s.ListTrash(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Oldest first: a task cannot be deleted before the tasks depending on
+	// it, so those are purged ahead of it
+	purged := 0
+	for i := len(tasks) - 1; i >= 0; i-- {
+		task := tasks[i]
+		if err := s.PurgeTask(task.ID, userID, force); err != nil {
+			return purged, fmt.Errorf("task %s: %w", task.ID, err)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// deletedTask returns a deleted task the user created or is assigned
+func (s *TaskService) deletedTask(trash taskTrash, taskID string, userID string) (*models.Task, error) {
+	task, err := trash.GetDeletedByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("deleted task not found: %w", err)
+	}
+
+	if task.CreatorID != userID && (task.AssigneeID == nil || *task.AssigneeID != userID) {
+		return nil, fmt.Errorf("deleted task not found")
+	}
+
+	return task, nil
+}
@@ -0,0 +1,223 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ActionLogRepository stores each user's undoable task actions
+type ActionLogRepository interface {
+	Save(action models.TaskAction) error
+	GetByUserID(userID string) ([]models.TaskAction, error)
+	Delete(actionID string) error
+}
+
+// taskLocationLister is implemented by task location repositories that can
+// return the task's location links, triggers included
+type taskLocationLister interface {
+	GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error)
+}
+
+// taskRestorer is implemented by task repositories that soft-delete, keeping
+// a deleted task's row and relationships so it can be brought back in place
+type taskRestorer interface {
+	Restore(taskID string) error
+}
+
+// EnableUndo records completes, deletes and snoozes so the user can reverse
+// the most recent one with Undo
+func (s *TaskService) EnableUndo(actions ActionLogRepository) {
+	s.actionRepo = actions
+}
+
+// Undo reverses the user's most recent reversible action: a completed task
+// goes back to its previous status, a deleted task is restored with its
+// locations and dependencies, and a snooze is cleared. It returns the action
+// it reversed, or nil when there is nothing to undo. An action can be undone
+// only once.
+func (s *TaskService) Undo(userID string) (*models.TaskAction, error) {
+	if s.actionRepo == nil {
+		return nil, fmt.Errorf("undo is not enabled")
+	}
+
+	actions, err := s.actionRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last action: %w", err)
+	}
+
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	action := actions[len(actions)-1]
+	snapshot, err := action.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	switch action.Type {
+	case models.TaskActionComplete:
+		err = s.undoComplete(userID, *snapshot)
+	case models.TaskActionSnooze:
+		err = s.undoSnooze(userID, snapshot.Task)
+	case models.TaskActionDelete:
+		err = s.restoreTask(*snapshot)
+	default:
+		err = fmt.Errorf("action cannot be undone")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to undo %s: %w", action.Type, err)
+	}
+
+	if err := s.actionRepo.Delete(action.ID); err != nil {
+		return nil, fmt.Errorf("failed to clear undone action: %w", err)
+	}
+
+	if s.events != nil {
+		if task, err := s.taskRepo.GetByID(snapshot.Task.ID); err == nil {
+			eventType := EventTaskUpdated
+			if action.Type == models.TaskActionDelete {
+				eventType = EventTaskCreated
+			}
+			s.publishTask(eventType, userID, *task)
+		}
+	}
+
+	return &action, nil
+}
+
+// recordAction remembers the task's state before an action for undo. It is
+// best effort: failing to record never fails the action itself.
+func (s *TaskService) recordAction(userID string, actionType models.TaskActionType, snapshot models.TaskSnapshot) {
+	if s.actionRepo == nil {
+		return
+	}
+
+	action, err := models.NewTaskAction(userID, actionType, snapshot)
+	if err != nil {
+		return
+	}
+	s.actionRepo.Save(*action)
+}
+
+// deleteSnapshot captures what restoring a deleted task needs
+func (s *TaskService) deleteSnapshot(task models.Task) models.TaskSnapshot {
+	snapshot := models.TaskSnapshot{Task: task}
+	if s.actionRepo == nil {
+		return snapshot
+	}
+
+	if lister, ok := s.taskLocationRepo.(taskLocationLister); ok {
+		if locations, err := lister.GetTaskLocationsByTaskID(task.ID); err == nil {
+			snapshot.Locations = locations
+		}
+	}
+
+	if dependencies, err := s.dependencyRepo.GetDependenciesByTaskID(task.ID); err == nil {
+		snapshot.Dependencies = dependencies
+	}
+
+	return snapshot
+}
+
+func (s *TaskService) undoComplete(userID string, snapshot models.TaskSnapshot) error {
+	before := snapshot.Task
+	task, err := s.taskRepo.GetByID(before.ID)
+	if err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+
+	task.Status = before.Status
+	task.CompletedAt = before.CompletedAt
+	task.SnoozedUntil = before.SnoozedUntil
+	task.UpdatedAt = s.clock.Now()
+
+	err = s.withTx(func(tx *TaskService) error {
+		if err := tx.saveTask(userID, task); err != nil {
+			return err
+		}
+		return tx.deleteSpawnedInstance(snapshot.SpawnedTaskID)
+	})
+	if err != nil {
+		return err
+	}
+
+	parents, err := s.rollUp(*task)
+	if err != nil {
+		return err
+	}
+	s.publishRollUp(task.CreatorID, parents)
+	return nil
+}
+
+// deleteSpawnedInstance removes the next occurrence a completion created,
+// so undoing it leaves one pending occurrence of the series. An occurrence
+// already gone needs nothing.
+func (s *TaskService) deleteSpawnedInstance(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	if _, err := s.taskRepo.GetByID(taskID); err != nil {
+		return nil
+	}
+	if err := s.taskRepo.Delete(taskID); err != nil {
+		return fmt.Errorf("failed to remove next occurrence: %w", err)
+	}
+	return nil
+}
+
+func (s *TaskService) undoSnooze(userID string, before models.Task) error {
+	task, err := s.taskRepo.GetByID(before.ID)
+	if err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+
+	task.SnoozedUntil = before.SnoozedUntil
+	task.RecurringSnooze = before.RecurringSnooze
+	task.UpdatedAt = s.clock.Now()
+
+	return s.saveTask(userID, task)
+}
+
+// restoreTask recreates a deleted task with its locations and the
+// dependencies whose tasks still exist
+func (s *TaskService) restoreTask(snapshot models.TaskSnapshot) error {
+	if _, err := s.taskRepo.GetByID(snapshot.Task.ID); err == nil {
+		return fmt.Errorf("task already exists: %s", snapshot.Task.ID)
+	}
+	if err := s.checkListNotArchived(snapshot.Task); err != nil {
+		return err
+	}
+
+	if restorer, ok := s.taskRepo.(taskRestorer); ok {
+		if err := restorer.Restore(snapshot.Task.ID); err == nil {
+			return nil
+		}
+	}
+
+	return s.withTx(func(tx *TaskService) error {
+		task := snapshot.Task
+		task.UpdatedAt = s.clock.Now()
+		if err := tx.taskRepo.Create(task); err != nil {
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+
+		for _, location := range snapshot.Locations {
+			if err := tx.taskLocationRepo.Create(location); err != nil {
+				return fmt.Errorf("failed to restore task location: %w", err)
+			}
+		}
+
+		for _, dependency := range snapshot.Dependencies {
+			if _, err := tx.taskRepo.GetByID(dependency.DependsOnTaskID); err != nil {
+				continue
+			}
+			if err := tx.dependencyRepo.Create(dependency); err != nil {
+				return fmt.Errorf("failed to restore task dependency: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
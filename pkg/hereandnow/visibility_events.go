@@ -0,0 +1,75 @@
+package hereandnow
+
+import (
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// VisibilityRepository remembers the last visibility each user saw for each
+// task
+type VisibilityRepository interface {
+	GetByUserID(userID string) ([]models.TaskVisibility, error)
+	Save(visibility models.TaskVisibility) error
+}
+
+// EnableVisibilityEvents makes GetFilteredTasks notify the user when a task
+// turns from hidden to visible, e.g. once its blocking dependency completes
+// or the user arrives where it can be done. A task seen for the first time
+// is recorded without a notification.
+func (s *TaskService) EnableVisibilityEvents(visibility VisibilityRepository, notifications NotificationRepository) {
+	s.visibilityRepo = visibility
+	s.notificationRepo = notifications
+}
+
+// recordVisibility compares each task's filter verdict with the one last
+// seen and announces the tasks that became visible. It is best effort and
+// never fails the filter run.
+func (s *TaskService) recordVisibility(userID string, tasks, visibleTasks []models.Task) {
+	if s.visibilityRepo == nil {
+		return
+	}
+
+	previous, err := s.visibilityRepo.GetByUserID(userID)
+	if err != nil {
+		return
+	}
+
+	known := make(map[string]models.TaskVisibility, len(previous))
+	for _, visibility := range previous {
+		known[visibility.TaskID] = visibility
+	}
+
+	visible := make(map[string]bool, len(visibleTasks))
+	for _, task := range visibleTasks {
+		visible[task.ID] = true
+	}
+
+	now := s.clock.Now()
+	for _, task := range tasks {
+		visibility, seen := known[task.ID]
+		if !seen {
+			record, err := models.NewTaskVisibility(userID, task.ID, visible[task.ID], now)
+			if err == nil {
+				s.visibilityRepo.Save(*record)
+			}
+			continue
+		}
+
+		changed, becameVisible := visibility.Observe(visible[task.ID], now)
+		if !changed {
+			continue
+		}
+		if err := s.visibilityRepo.Save(visibility); err != nil {
+			continue
+		}
+
+		if becameVisible && s.notificationRepo != nil && !task.IsCompleted() && !task.IsCancelled() {
+			notification, err := models.NewTaskNotification(userID, models.NotificationTypeTaskAvailable, task.ID,
+				fmt.Sprintf("Now available: %s", task.Title))
+			if err == nil {
+				s.notificationRepo.Create(*notification)
+			}
+		}
+	}
+}
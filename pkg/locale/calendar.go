@@ -0,0 +1,79 @@
+package locale
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// calendar holds a language's date layouts and month and weekday names.
+// Layouts use the English names of time.Format, which FormatLayout then
+// replaces.
+type calendar struct {
+	layouts     map[Style]string
+	months      [12]string
+	shortMonths [12]string
+	days        [7]string
+	shortDays   [7]string
+}
+
+// element returns the localized name for the layout element at the start
+// of layout and the element's width, or a zero width when layout does not
+// start with a month or weekday name
+func (c *calendar) element(t time.Time, layout string) (string, int) {
+	switch {
+	case strings.HasPrefix(layout, "January"):
+		return c.months[t.Month()-1], len("January")
+	case strings.HasPrefix(layout, "Jan"):
+		return c.shortMonths[t.Month()-1], len("Jan")
+	case strings.HasPrefix(layout, "Monday"):
+		return c.days[t.Weekday()], len("Monday")
+	case strings.HasPrefix(layout, "Mon"):
+		return c.shortDays[t.Weekday()], len("Mon")
+	}
+	return "", 0
+}
+
+var calendars = map[language.Tag]*calendar{
+	language.English: {
+		layouts: map[Style]string{
+			LongDateTime: "Monday, January 2, 2006 at 3:04 PM",
+			LongDate:     "Monday, January 2, 2006",
+			ShortDate:    "Jan 2",
+		},
+	},
+	language.German: {
+		layouts: map[Style]string{
+			LongDateTime: "Monday, 2. January 2006 um 15:04",
+			LongDate:     "Monday, 2. January 2006",
+			ShortDate:    "2. Jan",
+		},
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+	},
+	language.French: {
+		layouts: map[Style]string{
+			LongDateTime: "Monday 2 January 2006 à 15:04",
+			LongDate:     "Monday 2 January 2006",
+			ShortDate:    "2 Jan",
+		},
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	},
+	language.Spanish: {
+		layouts: map[Style]string{
+			LongDateTime: "Monday, 2 de January de 2006, 15:04",
+			LongDate:     "Monday, 2 de January de 2006",
+			ShortDate:    "2 Jan",
+		},
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+}
@@ -0,0 +1,127 @@
+// Package locale formats dates and numbers for display in the user's language.
+package locale
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Style selects one of a locale's date layouts
+type Style int
+
+const (
+	// LongDateTime renders e.g. "Monday, January 2, 2006 at 3:04 PM"
+	LongDateTime Style = iota
+	// LongDate renders e.g. "Monday, January 2, 2006"
+	LongDate
+	// ShortDate renders e.g. "Jan 2"
+	ShortDate
+)
+
+// Locale formats dates and numbers for one language
+type Locale struct {
+	Tag     language.Tag
+	names   *calendar
+	printer *message.Printer
+}
+
+var supported = []language.Tag{
+	language.English,
+	language.German,
+	language.French,
+	language.Spanish,
+}
+
+var matcher = language.NewMatcher(supported)
+
+// Default returns the English locale, which formats exactly as the
+// standard library does
+func Default() *Locale {
+	return &Locale{Tag: language.English, names: calendars[language.English]}
+}
+
+// Parse returns the locale for a BCP 47 tag or POSIX locale name such as
+// "de", "fr-CA" or "es_ES.UTF-8". Regional variants use their base
+// language. An empty name returns the default locale.
+func Parse(name string) (*Locale, error) {
+	name = strings.TrimSpace(name)
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name == "C" || name == "POSIX" {
+		return Default(), nil
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale %q: %w", name, err)
+	}
+
+	_, index, confidence := matcher.Match(tag)
+	if confidence == language.No {
+		return nil, fmt.Errorf("unsupported locale: %s (supported: %s)", name, strings.Join(Supported(), ", "))
+	}
+
+	base := supported[index]
+	if base == language.English {
+		return Default(), nil
+	}
+
+	return &Locale{
+		Tag:     base,
+		names:   calendars[base],
+		printer: message.NewPrinter(base),
+	}, nil
+}
+
+// Supported returns the supported language codes
+func Supported() []string {
+	codes := make([]string, len(supported))
+	for i, tag := range supported {
+		codes[i] = tag.String()
+	}
+	return codes
+}
+
+// Format renders t in one of the locale's date layouts
+func (l *Locale) Format(t time.Time, style Style) string {
+	return l.FormatLayout(t, l.names.layouts[style])
+}
+
+// FormatLayout renders t using a time.Format layout, replacing the English
+// month and weekday names with the locale's
+func (l *Locale) FormatLayout(t time.Time, layout string) string {
+	if l.Tag == language.English {
+		return t.Format(layout)
+	}
+
+	var sb strings.Builder
+	start := 0
+	for i := 0; i < len(layout); {
+		name, width := l.names.element(t, layout[i:])
+		if width == 0 {
+			i++
+			continue
+		}
+		sb.WriteString(t.Format(layout[start:i]))
+		sb.WriteString(name)
+		i += width
+		start = i
+	}
+	sb.WriteString(t.Format(layout[start:]))
+
+	return sb.String()
+}
+
+// Sprintf formats numbers with the locale's digit grouping and decimal
+// separator. English output is identical to fmt.Sprintf.
+func (l *Locale) Sprintf(format string, args ...interface{}) string {
+	if l.printer == nil {
+		return fmt.Sprintf(format, args...)
+	}
+	return l.printer.Sprintf(format, args...)
+}
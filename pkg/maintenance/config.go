@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config sets how often the loop runs and which jobs it runs. Every job is
+// enabled unless disabled here.
+type Config struct {
+	// Interval between runs, e.g. "30m". Empty uses DefaultInterval.
+	Interval string `yaml:"interval,omitempty"`
+	// Jobs tunes jobs by name (see JobNames)
+	Jobs map[string]JobConfig `yaml:"jobs,omitempty"`
+}
+
+// JobConfig toggles and tunes one job
+type JobConfig struct {
+	Disabled bool `yaml:"disabled,omitempty"`
+	// RetentionDays is how much history a pruning job keeps. Zero uses the
+	// job's default; jobs that keep no history ignore it.
+	RetentionDays int `yaml:"retention_days,omitempty"`
+}
+
+func (c Config) Validate() error {
+	if _, err := c.IntervalDuration(); err != nil {
+		return err
+	}
+
+	for name, job := range c.Jobs {
+		if !isJobName(name) {
+			return fmt.Errorf("unknown maintenance job %s (available: %s)", name, strings.Join(JobNames, ", "))
+		}
+		if job.RetentionDays < 0 {
+			return fmt.Errorf("invalid retention_days for maintenance job %s: %d (must be zero or positive)", name, job.RetentionDays)
+		}
+	}
+
+	return nil
+}
+
+// IntervalDuration returns the configured interval, or DefaultInterval when
+// none is set
+func (c Config) IntervalDuration() (time.Duration, error) {
+	if c.Interval == "" {
+		return DefaultInterval, nil
+	}
+	interval, err := time.ParseDuration(c.Interval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid maintenance interval %q (want a positive duration such as 30m or 1h)", c.Interval)
+	}
+	return interval, nil
+}
+
+// Enabled reports whether the named job should run
+func (c Config) Enabled(name string) bool {
+	return !c.Jobs[name].Disabled
+}
+
+// Retention returns the named job's configured retention, or fallback when
+// none is set
+func (c Config) Retention(name string, fallback time.Duration) time.Duration {
+	if days := c.Jobs[name].RetentionDays; days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return fallback
+}
+
+// Select returns the jobs that are enabled, in order
+func (c Config) Select(jobs ...Job) []Job {
+	var enabled []Job
+	for _, job := range jobs {
+		if c.Enabled(job.Name) {
+			enabled = append(enabled, job)
+		}
+	}
+	return enabled
+}
+
+func isJobName(name string) bool {
+	for _, known := range JobNames {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// Job names, used to enable, disable and tune jobs in Config
+const (
+	JobArchiveLists          = "archive_lists"
+	JobPruneContexts         = "prune_contexts"
+	JobPruneSessions         = "prune_sessions"
+	JobExpireCompletionUndos = "expire_completion_undos"
+	JobPruneRevokedTokens    = "prune_revoked_tokens"
+	JobPurgeTrash            = "purge_trash"
+	JobPrunePasswordResets   = "prune_password_resets"
+	JobPruneTaskHistory      = "prune_task_history"
+)
+
+// JobNames lists every job this package provides
+var JobNames = []string{JobArchiveLists, JobPruneContexts, JobPruneSessions, JobExpireCompletionUndos, JobPruneRevokedTokens, JobPurgeTrash, JobPrunePasswordResets, JobPruneTaskHistory}
+
+// DefaultContextRetention is how long context snapshots are kept when no
+// retention is configured
+const DefaultContextRetention = 30 * 24 * time.Hour
+
+// DefaultTrashRetention is how long deleted tasks can be restored when no
+// retention is configured
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// DefaultTaskHistoryRetention is how long task edits are kept in the task
+// history when no retention is configured
+const DefaultTaskHistoryRetention = 180 * 24 * time.Hour
+
+// ListSweeper archives idle lists, such as a hereandnow.ListArchiver
+type ListSweeper interface {
+	Sweep(now time.Time) ([]models.TaskList, error)
+}
+
+// ContextPruner deletes context snapshots recorded before a time
+type ContextPruner interface {
+	DeleteOlderThan(before time.Time) error
+}
+
+// TaskHistoryPruner deletes task history entries recorded before a time
+type TaskHistoryPruner interface {
+	DeleteOlderThan(before time.Time) error
+}
+
+// SessionPruner deletes sessions that have expired
+type SessionPruner interface {
+	DeleteExpired() error
+}
+
+// CompletionUndoPruner deletes completion undos whose window has closed
+type CompletionUndoPruner interface {
+	DeleteExpired(now time.Time) (int, error)
+}
+
+// RevokedTokenPruner deletes revoked token IDs whose tokens have expired
+type RevokedTokenPruner interface {
+	DeleteExpired(now time.Time) (int, error)
+}
+
+// PasswordResetPruner deletes password reset tokens that have expired
+type PasswordResetPruner interface {
+	DeleteExpired(now time.Time) (int, error)
+}
+
+// TrashPurger permanently removes tasks deleted before a time
+type TrashPurger interface {
+	PurgeDeletedBefore(before time.Time) (int, error)
+}
+
+// ArchiveListsJob archives lists with no recent activity
+func ArchiveListsJob(lists ListSweeper) Job {
+	return Job{
+		Name: JobArchiveLists,
+		Run: func(now time.Time) (string, error) {
+			archived, err := lists.Sweep(now)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("archived %d inactive list(s)", len(archived)), nil
+		},
+	}
+}
+
+// PruneContextsJob deletes context snapshots older than retention
+func PruneContextsJob(contexts ContextPruner, retention time.Duration) Job {
+	return Job{
+		Name: JobPruneContexts,
+		Run: func(now time.Time) (string, error) {
+			cutoff := now.Add(-retention)
+			if err := contexts.DeleteOlderThan(cutoff); err != nil {
+				return "", err
+			}
+			return "removed contexts from before " + cutoff.Format(time.RFC3339), nil
+		},
+	}
+}
+
+// PruneSessionsJob deletes expired login sessions
+func PruneSessionsJob(sessions SessionPruner) Job {
+	return Job{
+		Name: JobPruneSessions,
+		Run: func(now time.Time) (string, error) {
+			if err := sessions.DeleteExpired(); err != nil {
+				return "", err
+			}
+			return "removed expired sessions", nil
+		},
+	}
+}
+
+// ExpireCompletionUndosJob deletes the undo records of shared-list
+// completions that can no longer be undone
+func ExpireCompletionUndosJob(undos CompletionUndoPruner) Job {
+	return Job{
+		Name: JobExpireCompletionUndos,
+		Run: func(now time.Time) (string, error) {
+			removed, err := undos.DeleteExpired(now)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("removed %d expired completion undo(s)", removed), nil
+		},
+	}
+}
+
+// PruneRevokedTokensJob forgets revoked tokens that have since expired and
+// would be refused anyway
+func PruneRevokedTokensJob(tokens RevokedTokenPruner) Job {
+	return Job{
+		Name: JobPruneRevokedTokens,
+		Run: func(now time.Time) (string, error) {
+			removed, err := tokens.DeleteExpired(now)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("removed %d expired revoked token(s)", removed), nil
+		},
+	}
+}
+
+// PurgeTrashJob permanently removes tasks that have been deleted for longer
+// than retention
+func PurgeTrashJob(trash TrashPurger, retention time.Duration) Job {
+	return Job{
+		Name: JobPurgeTrash,
+		Run: func(now time.Time) (string, error) {
+			purged, err := trash.PurgeDeletedBefore(now.Add(-retention))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("purged %d deleted task(s)", purged), nil
+		},
+	}
+}
+
+// PrunePasswordResetsJob deletes password reset tokens that have expired,
+// whether or not they were used
+func PrunePasswordResetsJob(tokens PasswordResetPruner) Job {
+	return Job{
+		Name: JobPrunePasswordResets,
+		Run: func(now time.Time) (string, error) {
+			removed, err := tokens.DeleteExpired(now)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("removed %d expired password reset token(s)", removed), nil
+		},
+	}
+}
+
+// PruneTaskHistoryJob deletes task history entries older than retention
+func PruneTaskHistoryJob(history TaskHistoryPruner, retention time.Duration) Job {
+	return Job{
+		Name: JobPruneTaskHistory,
+		Run: func(now time.Time) (string, error) {
+			cutoff := now.Add(-retention)
+			if err := history.DeleteOlderThan(cutoff); err != nil {
+				return "", err
+			}
+			return "removed task history from before " + cutoff.Format(time.RFC3339), nil
+		},
+	}
+}
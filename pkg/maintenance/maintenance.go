@@ -0,0 +1,125 @@
+// Package maintenance runs the server's periodic housekeeping jobs, such as
+// archiving idle lists and pruning old contexts and expired sessions, on one
+// shared interval.
+package maintenance
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+)
+
+// DefaultInterval is how often the loop runs when no interval is configured
+const DefaultInterval = time.Hour
+
+// Job is one housekeeping task. Run must be idempotent, since a run that
+// overlaps the previous one's work or follows a failed run should do no
+// harm. It returns a short summary of what it did for the log.
+type Job struct {
+	Name string
+	Run  func(now time.Time) (string, error)
+}
+
+// Result is the outcome of one job in one run
+type Result struct {
+	Job     string
+	Summary string
+	Err     error
+}
+
+// Loop runs its jobs every interval
+type Loop struct {
+	interval time.Duration
+	jobs     []Job
+	clock    clock.Clock
+	logger   *log.Logger
+}
+
+// NewLoop returns a loop that runs jobs in order every interval. An interval
+// of zero or less uses DefaultInterval.
+func NewLoop(interval time.Duration, jobs ...Job) *Loop {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Loop{
+		interval: interval,
+		jobs:     jobs,
+		clock:    clock.Real(),
+		logger:   log.Default(),
+	}
+}
+
+// SetClock replaces the clock jobs are given the time from
+func (l *Loop) SetClock(c clock.Clock) {
+	l.clock = c
+}
+
+// SetLogger replaces the logger runs are reported to
+func (l *Loop) SetLogger(logger *log.Logger) {
+	l.logger = logger
+}
+
+// Interval returns how long the loop waits between runs
+func (l *Loop) Interval() time.Duration {
+	return l.interval
+}
+
+// Jobs returns the names of the jobs the loop runs, in order
+func (l *Loop) Jobs() []string {
+	names := make([]string, 0, len(l.jobs))
+	for _, job := range l.jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+// RunOnce runs every job once, logs each outcome and returns them. A job
+// that fails or panics is reported and the remaining jobs still run.
+func (l *Loop) RunOnce() []Result {
+	now := l.clock.Now()
+	results := make([]Result, 0, len(l.jobs))
+	for _, job := range l.jobs {
+		result := runJob(job, now)
+		if result.Err != nil {
+			l.logger.Printf("Maintenance job %s failed: %v", result.Job, result.Err)
+		} else {
+			l.logger.Printf("Maintenance job %s: %s", result.Job, result.Summary)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Run runs the jobs immediately and then every interval until stop is
+// closed
+func (l *Loop) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.RunOnce()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runJob runs one job, turning a panic into an error so one broken job
+// cannot stop the loop
+func runJob(job Job, now time.Time) (result Result) {
+	result.Job = job.Name
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result.Summary = ""
+			result.Err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	result.Summary, result.Err = job.Run(now)
+	return result
+}
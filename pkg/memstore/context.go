@@ -0,0 +1,433 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ContextRepository stores each user's context history
+type ContextRepository struct {
+	store *Store
+}
+
+func (r *ContextRepository) Create(context models.Context) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.contexts = append(r.store.data.contexts, context)
+	return nil
+}
+
+// GetLatestByUserID returns the user's most recent context
+func (r *ContextRepository) GetLatestByUserID(userID string) (*models.Context, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var latest *models.Context
+	for i, context := range r.store.data.contexts {
+		if context.UserID != userID {
+			continue
+		}
+		if latest == nil || !context.Timestamp.Before(latest.Timestamp) {
+			latest = &r.store.data.contexts[i]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no context found for user: %s", userID)
+	}
+
+	context := *latest
+	return &context, nil
+}
+
+// GetEnergyProfile buckets the energy levels of the user's contexts since
+// the given time by hour of day in loc
+func (r *ContextRepository) GetEnergyProfile(userID string, since time.Time, loc *time.Location) (*models.EnergyProfile, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var history []models.Context
+	for _, context := range r.store.data.contexts {
+		if context.UserID == userID && !context.Timestamp.Before(since) {
+			history = append(history, context)
+		}
+	}
+	return models.NewEnergyProfile(history, loc), nil
+}
+
+// ContextPresetRepository stores users' named context presets
+type ContextPresetRepository struct {
+	store *Store
+}
+
+// Save inserts a preset, or replaces the user's preset with the same name
+func (r *ContextPresetRepository) Save(preset models.ContextPreset) error {
+	if err := preset.Validate(); err != nil {
+		return fmt.Errorf("invalid context preset: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.presets {
+		if existing.UserID == preset.UserID && existing.Name == preset.Name {
+			preset.ID = existing.ID
+			preset.CreatedAt = existing.CreatedAt
+			r.store.data.presets[i] = preset
+			return nil
+		}
+	}
+	r.store.data.presets = append(r.store.data.presets, preset)
+	return nil
+}
+
+func (r *ContextPresetRepository) GetByName(userID, name string) (*models.ContextPreset, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, preset := range r.store.data.presets {
+		if preset.UserID == userID && preset.Name == name {
+			return &preset, nil
+		}
+	}
+	return nil, fmt.Errorf("context preset not found: %s", name)
+}
+
+// GetByUserID returns the user's presets ordered by name
+func (r *ContextPresetRepository) GetByUserID(userID string) ([]models.ContextPreset, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var presets []models.ContextPreset
+	for _, preset := range r.store.data.presets {
+		if preset.UserID == userID {
+			presets = append(presets, preset)
+		}
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+	return presets, nil
+}
+
+func (r *ContextPresetRepository) Delete(userID, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, preset := range r.store.data.presets {
+		if preset.UserID == userID && preset.Name == name {
+			r.store.data.presets = append(r.store.data.presets[:i], r.store.data.presets[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("context preset not found: %s", name)
+}
+
+// LocationRepository stores users' saved locations
+type LocationRepository struct {
+	store *Store
+}
+
+func (r *LocationRepository) Create(location models.Location) error {
+	if err := location.Validate(); err != nil {
+		return fmt.Errorf("location validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.locations[location.ID]; exists {
+		return fmt.Errorf("location already exists: %s", location.ID)
+	}
+	r.store.data.locations[location.ID] = location
+	return nil
+}
+
+func (r *LocationRepository) GetByID(locationID string) (*models.Location, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	location, exists := r.store.data.locations[locationID]
+	if !exists {
+		return nil, fmt.Errorf("location not found: %s", locationID)
+	}
+	return &location, nil
+}
+
+func (r *LocationRepository) Update(location models.Location) error {
+	if err := location.Validate(); err != nil {
+		return fmt.Errorf("location validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.locations[location.ID]; !exists {
+		return fmt.Errorf("location not found: %s", location.ID)
+	}
+	r.store.data.locations[location.ID] = location
+	return nil
+}
+
+// GetByUserID returns the user's locations ordered by name
+func (r *LocationRepository) GetByUserID(userID string) ([]models.Location, error) {
+	return r.where(func(location models.Location) bool {
+		return location.UserID == userID
+	}), nil
+}
+
+// FindNearby returns the locations within radiusMeters of a point, nearest
+// first
+func (r *LocationRepository) FindNearby(latitude, longitude float64, radiusMeters int) ([]models.Location, error) {
+	locations := r.where(func(location models.Location) bool {
+		return location.DistanceFrom(latitude, longitude) <= float64(radiusMeters)
+	})
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].DistanceFrom(latitude, longitude) < locations[j].DistanceFrom(latitude, longitude)
+	})
+	return locations, nil
+}
+
+// FindAtCoordinates returns the user's locations whose radius contains the
+// point, nearest first
+func (r *LocationRepository) FindAtCoordinates(userID string, latitude, longitude float64) ([]*models.Location, error) {
+	locations := r.where(func(location models.Location) bool {
+		return location.UserID == userID && location.IsWithinRadius(latitude, longitude)
+	})
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].DistanceFrom(latitude, longitude) < locations[j].DistanceFrom(latitude, longitude)
+	})
+
+	at := make([]*models.Location, len(locations))
+	for i := range locations {
+		at[i] = &locations[i]
+	}
+	return at, nil
+}
+
+func (r *LocationRepository) Delete(locationID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.locations[locationID]; !exists {
+		return fmt.Errorf("location not found: %s", locationID)
+	}
+	delete(r.store.data.locations, locationID)
+
+	taskLocations := r.store.data.taskLocations[:0]
+	for _, taskLocation := range r.store.data.taskLocations {
+		if taskLocation.LocationID != locationID {
+			taskLocations = append(taskLocations, taskLocation)
+		}
+	}
+	r.store.data.taskLocations = taskLocations
+
+	for i, preset := range r.store.data.presets {
+		if preset.LocationID != nil && *preset.LocationID == locationID {
+			r.store.data.presets[i].LocationID = nil
+		}
+	}
+
+	return nil
+}
+
+func (r *LocationRepository) where(match func(location models.Location) bool) []models.Location {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var locations []models.Location
+	for _, location := range r.store.data.locations {
+		if match(location) {
+			locations = append(locations, location)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Name != locations[j].Name {
+			return locations[i].Name < locations[j].Name
+		}
+		return locations[i].ID < locations[j].ID
+	})
+	return locations
+}
+
+// GeofenceEventRepository stores users' arrivals at and departures from
+// their saved locations
+type GeofenceEventRepository struct {
+	store *Store
+}
+
+func (r *GeofenceEventRepository) Create(event models.GeofenceEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.geofenceEvents = append(r.store.data.geofenceEvents, event)
+	return nil
+}
+
+// GetByUserID returns the user's events since the given time, oldest first
+func (r *GeofenceEventRepository) GetByUserID(userID string, since time.Time) ([]models.GeofenceEvent, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var events []models.GeofenceEvent
+	for _, event := range r.store.data.geofenceEvents {
+		if event.UserID == userID && !event.At.Before(since) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+	return events, nil
+}
+
+// CalendarEventRepository stores users' calendar events
+type CalendarEventRepository struct {
+	store *Store
+}
+
+func (r *CalendarEventRepository) Create(event models.CalendarEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.events = append(r.store.data.events, event)
+	return nil
+}
+
+func (r *CalendarEventRepository) Update(event models.CalendarEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.events {
+		if existing.ID == event.ID {
+			r.store.data.events[i] = event
+			return nil
+		}
+	}
+	return fmt.Errorf("calendar event not found: %s", event.ID)
+}
+
+func (r *CalendarEventRepository) Delete(eventID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.events {
+		if existing.ID == eventID {
+			r.store.data.events = append(r.store.data.events[:i], r.store.data.events[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("calendar event not found: %s", eventID)
+}
+
+func (r *CalendarEventRepository) GetByExternalID(externalID string) (*models.CalendarEvent, error) {
+	events := r.where(func(event models.CalendarEvent) bool {
+		return event.ExternalID == externalID
+	})
+	if len(events) == 0 {
+		return nil, fmt.Errorf("calendar event not found: %s", externalID)
+	}
+	return &events[0], nil
+}
+
+// GetByProviderAndExternalID returns the user's event from the provider
+// with the external ID
+func (r *CalendarEventRepository) GetByProviderAndExternalID(userID, providerID, externalID string) (*models.CalendarEvent, error) {
+	events := r.where(func(event models.CalendarEvent) bool {
+		return event.UserID == userID && event.ProviderID == providerID && event.ExternalID == externalID
+	})
+	if len(events) == 0 {
+		return nil, fmt.Errorf("calendar event not found: %s", externalID)
+	}
+	return &events[0], nil
+}
+
+func (r *CalendarEventRepository) GetByUserID(userID string) ([]models.CalendarEvent, error) {
+	return r.where(func(event models.CalendarEvent) bool {
+		return event.UserID == userID
+	}), nil
+}
+
+// GetEventsByUserIDAndTimeRange returns the user's events overlapping
+// [start, end), ordered by start time
+func (r *CalendarEventRepository) GetEventsByUserIDAndTimeRange(userID string, start, end time.Time) ([]models.CalendarEvent, error) {
+	return r.where(func(event models.CalendarEvent) bool {
+		return event.UserID == userID && event.StartAt.Before(end) && event.EndAt.After(start)
+	}), nil
+}
+
+// GetNextEvent returns the user's first event starting after the given
+// time, or nil when there is none
+func (r *CalendarEventRepository) GetNextEvent(userID string, after time.Time) (*models.CalendarEvent, error) {
+	events := r.where(func(event models.CalendarEvent) bool {
+		return event.UserID == userID && event.StartAt.After(after)
+	})
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
+
+func (r *CalendarEventRepository) where(match func(event models.CalendarEvent) bool) []models.CalendarEvent {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var events []models.CalendarEvent
+	for _, event := range r.store.data.events {
+		if match(event) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartAt.Before(events[j].StartAt)
+	})
+	return events
+}
+
+// CalendarSyncCursorRepository stores each user's calendar sync tokens
+type CalendarSyncCursorRepository struct {
+	store *Store
+}
+
+// Get returns the user's cursor for the provider, or nil when there is none
+func (r *CalendarSyncCursorRepository) Get(userID, provider string) (*models.CalendarSyncCursor, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, cursor := range r.store.data.cursors {
+		if cursor.UserID == userID && cursor.Provider == provider {
+			return &cursor, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *CalendarSyncCursorRepository) Save(cursor models.CalendarSyncCursor) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.cursors {
+		if existing.UserID == cursor.UserID && existing.Provider == cursor.Provider {
+			r.store.data.cursors[i] = cursor
+			return nil
+		}
+	}
+	r.store.data.cursors = append(r.store.data.cursors, cursor)
+	return nil
+}
+
+func (r *CalendarSyncCursorRepository) Delete(userID, provider string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.cursors {
+		if existing.UserID == userID && existing.Provider == provider {
+			r.store.data.cursors = append(r.store.data.cursors[:i], r.store.data.cursors[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
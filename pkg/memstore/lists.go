@@ -0,0 +1,137 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskListRepository stores task lists. Listings are ordered by creation
+// time.
+type TaskListRepository struct {
+	store *Store
+}
+
+func (r *TaskListRepository) Create(list models.TaskList) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.lists[list.ID]; exists {
+		return fmt.Errorf("task list already exists: %s", list.ID)
+	}
+	r.store.data.lists[list.ID] = list
+	return nil
+}
+
+func (r *TaskListRepository) GetByID(listID string) (*models.TaskList, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	list, exists := r.store.data.lists[listID]
+	if !exists {
+		return nil, fmt.Errorf("task list not found: %s", listID)
+	}
+	return &list, nil
+}
+
+// GetActive returns every list that has not been archived
+func (r *TaskListRepository) GetActive() ([]models.TaskList, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var lists []models.TaskList
+	for _, list := range r.store.data.lists {
+		if !list.IsArchived() {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].CreatedAt.Before(lists[j].CreatedAt)
+	})
+	return lists, nil
+}
+
+// GetArchived returns every archived list
+func (r *TaskListRepository) GetArchived() ([]models.TaskList, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var lists []models.TaskList
+	for _, list := range r.store.data.lists {
+		if list.IsArchived() {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].CreatedAt.Before(lists[j].CreatedAt)
+	})
+	return lists, nil
+}
+
+func (r *TaskListRepository) Update(list models.TaskList) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.lists[list.ID]; !exists {
+		return fmt.Errorf("task list not found: %s", list.ID)
+	}
+	r.store.data.lists[list.ID] = list
+	return nil
+}
+
+// ListMemberRepository stores the people lists are shared with. Listings are
+// ordered by invitation time.
+type ListMemberRepository struct {
+	store *Store
+}
+
+func (r *ListMemberRepository) Create(member models.ListMember) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.members = append(r.store.data.members, member)
+	return nil
+}
+
+// Delete removes the user's membership of the list
+func (r *ListMemberRepository) Delete(listID, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, member := range r.store.data.members {
+		if member.ListID == listID && member.UserID == userID {
+			r.store.data.members = append(r.store.data.members[:i], r.store.data.members[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("list member not found: %s", userID)
+}
+
+func (r *ListMemberRepository) GetByListID(listID string) ([]models.ListMember, error) {
+	return r.where(func(member models.ListMember) bool {
+		return member.ListID == listID
+	}), nil
+}
+
+func (r *ListMemberRepository) GetByUserID(userID string) ([]models.ListMember, error) {
+	return r.where(func(member models.ListMember) bool {
+		return member.UserID == userID
+	}), nil
+}
+
+func (r *ListMemberRepository) where(match func(member models.ListMember) bool) []models.ListMember {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var members []models.ListMember
+	for _, member := range r.store.data.members {
+		if match(member) {
+			members = append(members, member)
+		}
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].InvitedAt.Before(members[j].InvitedAt)
+	})
+	return members
+}
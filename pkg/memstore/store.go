@@ -0,0 +1,338 @@
+// Package memstore provides thread-safe in-memory implementations of the
+// repository interfaces the hereandnow services and filters depend on, for
+// embedding the library without a database and for fast tests.
+//
+// All repositories obtained from one Store share its data, so a task
+// location created through TaskLocations() is visible to Locations() and to
+// the location filter. Records are stored and returned by value; pointer
+// fields inside them are not deep-copied.
+package memstore
+
+import (
+	"sync"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/notify"
+	calsync "github.com/bcnelson/hereAndNow/pkg/sync"
+)
+
+// Store holds every record in memory behind a single lock
+type Store struct {
+	mu   sync.RWMutex
+	txMu sync.Mutex
+	data data
+}
+
+type visibilityKey struct {
+	userID string
+	taskID string
+}
+
+type data struct {
+	tasks          map[string]models.Task
+	locations      map[string]models.Location
+	users          map[string]models.User
+	lists          map[string]models.TaskList
+	visibility     map[visibilityKey]models.TaskVisibility
+	contexts       []models.Context
+	members        []models.ListMember
+	presets        []models.ContextPreset
+	dependencies   []models.TaskDependency
+	links          []models.TaskLink
+	taskLocations  []models.TaskLocation
+	assignments    []models.TaskAssignment
+	events         []models.CalendarEvent
+	cursors        []models.CalendarSyncCursor
+	notifications  []models.Notification
+	actions        []models.TaskAction
+	history        []models.TaskHistoryEntry
+	undos          map[string]models.CompletionUndo
+	audits         []models.FilterAudit
+	geofenceEvents []models.GeofenceEvent
+	// notificationSettings are keyed by user ID
+	notificationSettings map[string]models.NotificationSettings
+	// undeliverable holds the IDs of notifications delivery gave up on
+	undeliverable map[string]bool
+	reminders     []models.Reminder
+}
+
+// Option seeds a new Store
+type Option func(*Store)
+
+// WithTasks seeds the store with tasks
+func WithTasks(tasks ...models.Task) Option {
+	return func(s *Store) {
+		for _, task := range tasks {
+			s.data.tasks[task.ID] = task
+		}
+	}
+}
+
+// WithLocations seeds the store with locations
+func WithLocations(locations ...models.Location) Option {
+	return func(s *Store) {
+		for _, location := range locations {
+			s.data.locations[location.ID] = location
+		}
+	}
+}
+
+// WithUsers seeds the store with users
+func WithUsers(users ...models.User) Option {
+	return func(s *Store) {
+		for _, user := range users {
+			s.data.users[user.ID] = user
+		}
+	}
+}
+
+// WithLists seeds the store with task lists
+func WithLists(lists ...models.TaskList) Option {
+	return func(s *Store) {
+		for _, list := range lists {
+			s.data.lists[list.ID] = list
+		}
+	}
+}
+
+// WithCalendarEvents seeds the store with calendar events
+func WithCalendarEvents(events ...models.CalendarEvent) Option {
+	return func(s *Store) {
+		s.data.events = append(s.data.events, events...)
+	}
+}
+
+// New returns an empty store, seeded by opts
+func New(opts ...Option) *Store {
+	s := &Store{
+		data: data{
+			tasks:      make(map[string]models.Task),
+			locations:  make(map[string]models.Location),
+			users:      make(map[string]models.User),
+			lists:      make(map[string]models.TaskList),
+			visibility: make(map[visibilityKey]models.TaskVisibility),
+			undos:      make(map[string]models.CompletionUndo),
+
+			notificationSettings: make(map[string]models.NotificationSettings),
+			undeliverable:        make(map[string]bool),
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) Tasks() *TaskRepository {
+	return &TaskRepository{s}
+}
+
+func (s *Store) Dependencies() *TaskDependencyRepository {
+	return &TaskDependencyRepository{s}
+}
+
+func (s *Store) TaskLinks() *TaskLinkRepository {
+	return &TaskLinkRepository{s}
+}
+
+func (s *Store) TaskLocations() *TaskLocationRepository {
+	return &TaskLocationRepository{s}
+}
+
+func (s *Store) TaskAssignments() *TaskAssignmentRepository {
+	return &TaskAssignmentRepository{s}
+}
+
+func (s *Store) Reminders() *ReminderRepository {
+	return &ReminderRepository{s}
+}
+
+func (s *Store) ContextPresets() *ContextPresetRepository {
+	return &ContextPresetRepository{s}
+}
+
+func (s *Store) Locations() *LocationRepository {
+	return &LocationRepository{s}
+}
+
+func (s *Store) Contexts() *ContextRepository {
+	return &ContextRepository{s}
+}
+
+func (s *Store) GeofenceEvents() *GeofenceEventRepository {
+	return &GeofenceEventRepository{s}
+}
+
+func (s *Store) CalendarEvents() *CalendarEventRepository {
+	return &CalendarEventRepository{s}
+}
+
+func (s *Store) CalendarSyncCursors() *CalendarSyncCursorRepository {
+	return &CalendarSyncCursorRepository{s}
+}
+
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{s}
+}
+
+func (s *Store) Notifications() *NotificationRepository {
+	return &NotificationRepository{s}
+}
+
+func (s *Store) NotificationSettings() *NotificationSettingsRepository {
+	return &NotificationSettingsRepository{s}
+}
+
+func (s *Store) TaskLists() *TaskListRepository {
+	return &TaskListRepository{s}
+}
+
+func (s *Store) ListMembers() *ListMemberRepository {
+	return &ListMemberRepository{s}
+}
+
+func (s *Store) TaskVisibility() *TaskVisibilityRepository {
+	return &TaskVisibilityRepository{s}
+}
+
+func (s *Store) TaskActions() *TaskActionRepository {
+	return &TaskActionRepository{s}
+}
+
+func (s *Store) TaskHistory() *TaskHistoryRepository {
+	return &TaskHistoryRepository{s}
+}
+
+func (s *Store) CompletionUndos() *CompletionUndoRepository {
+	return &CompletionUndoRepository{s}
+}
+
+func (s *Store) FilterAudits() *FilterAuditRepository {
+	return &FilterAuditRepository{s}
+}
+
+// WithTx runs fn with the store's repositories and restores the store to
+// its prior state when fn fails. Transactions run one at a time, but are not
+// isolated from writes made outside a transaction while fn runs; a rollback
+// discards those too.
+func (s *Store) WithTx(fn func(repos hereandnow.TxRepositories) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.RLock()
+	snapshot := s.data.clone()
+	s.mu.RUnlock()
+
+	err := fn(hereandnow.TxRepositories{
+		Tasks:         s.Tasks(),
+		Dependencies:  s.Dependencies(),
+		TaskLocations: s.TaskLocations(),
+		Notifications: s.Notifications(),
+		Assignments:   s.TaskAssignments(),
+		History:       s.TaskHistory(),
+	})
+	if err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (d data) clone() data {
+	c := data{
+		tasks:          make(map[string]models.Task, len(d.tasks)),
+		locations:      make(map[string]models.Location, len(d.locations)),
+		users:          make(map[string]models.User, len(d.users)),
+		lists:          make(map[string]models.TaskList, len(d.lists)),
+		visibility:     make(map[visibilityKey]models.TaskVisibility, len(d.visibility)),
+		contexts:       append([]models.Context(nil), d.contexts...),
+		members:        append([]models.ListMember(nil), d.members...),
+		presets:        append([]models.ContextPreset(nil), d.presets...),
+		dependencies:   append([]models.TaskDependency(nil), d.dependencies...),
+		links:          append([]models.TaskLink(nil), d.links...),
+		taskLocations:  append([]models.TaskLocation(nil), d.taskLocations...),
+		assignments:    append([]models.TaskAssignment(nil), d.assignments...),
+		events:         append([]models.CalendarEvent(nil), d.events...),
+		cursors:        append([]models.CalendarSyncCursor(nil), d.cursors...),
+		notifications:  append([]models.Notification(nil), d.notifications...),
+		actions:        append([]models.TaskAction(nil), d.actions...),
+		history:        append([]models.TaskHistoryEntry(nil), d.history...),
+		undos:          make(map[string]models.CompletionUndo, len(d.undos)),
+		audits:         append([]models.FilterAudit(nil), d.audits...),
+		geofenceEvents: append([]models.GeofenceEvent(nil), d.geofenceEvents...),
+
+		notificationSettings: make(map[string]models.NotificationSettings, len(d.notificationSettings)),
+		undeliverable:        make(map[string]bool, len(d.undeliverable)),
+		reminders:            append([]models.Reminder(nil), d.reminders...),
+	}
+	for id, task := range d.tasks {
+		c.tasks[id] = task
+	}
+	for id, location := range d.locations {
+		c.locations[id] = location
+	}
+	for id, user := range d.users {
+		c.users[id] = user
+	}
+	for id, list := range d.lists {
+		c.lists[id] = list
+	}
+	for key, visibility := range d.visibility {
+		c.visibility[key] = visibility
+	}
+	for taskID, undo := range d.undos {
+		c.undos[taskID] = undo
+	}
+	for userID, settings := range d.notificationSettings {
+		c.notificationSettings[userID] = settings
+	}
+	for notificationID := range d.undeliverable {
+		c.undeliverable[notificationID] = true
+	}
+	return c
+}
+
+// The store's repositories satisfy every repository interface the services
+// and filters depend on
+var (
+	_ hereandnow.TaskRepository           = (*TaskRepository)(nil)
+	_ hereandnow.ContextRepository        = (*ContextRepository)(nil)
+	_ hereandnow.TaskDependencyRepository = (*TaskDependencyRepository)(nil)
+	_ hereandnow.TaskLocationRepository   = (*TaskLocationRepository)(nil)
+	_ hereandnow.UserRepository           = (*UserRepository)(nil)
+	_ hereandnow.NotificationRepository   = (*NotificationRepository)(nil)
+	_ hereandnow.LocationRepository       = (*LocationRepository)(nil)
+	_ hereandnow.CalendarEventRepository  = (*CalendarEventRepository)(nil)
+	_ hereandnow.EnergyProfileRepository  = (*ContextRepository)(nil)
+	_ hereandnow.LocationTaskRepository   = (*TaskLocationRepository)(nil)
+	_ hereandnow.ReminderTaskRepository   = (*TaskRepository)(nil)
+	_ hereandnow.VisibilityRepository     = (*TaskVisibilityRepository)(nil)
+	_ hereandnow.ArchiveListRepository    = (*TaskListRepository)(nil)
+	_ hereandnow.ListTaskRepository       = (*TaskRepository)(nil)
+	_ hereandnow.ContextPresetRepository  = (*ContextPresetRepository)(nil)
+	_ hereandnow.ActionLogRepository      = (*TaskActionRepository)(nil)
+	_ hereandnow.TaskHistoryRepository    = (*TaskHistoryRepository)(nil)
+	_ hereandnow.ListMemberRepository     = (*ListMemberRepository)(nil)
+	_ hereandnow.TaskLinkRepository       = (*TaskLinkRepository)(nil)
+	_ hereandnow.CompletionUndoRepository = (*CompletionUndoRepository)(nil)
+	_ hereandnow.TaskAssignmentRepository = (*TaskAssignmentRepository)(nil)
+	_ hereandnow.GeofenceEventRepository  = (*GeofenceEventRepository)(nil)
+	_ hereandnow.ReminderRepository       = (*ReminderRepository)(nil)
+	_ hereandnow.Transactor               = (*Store)(nil)
+
+	_ filters.TaskBatchRepository           = (*TaskRepository)(nil)
+	_ filters.TaskDependencyBatchRepository = (*TaskDependencyRepository)(nil)
+	_ filters.TaskLocationBatchRepository   = (*TaskLocationRepository)(nil)
+	_ filters.LocationRepository            = (*LocationRepository)(nil)
+	_ filters.CalendarEventRepository       = (*CalendarEventRepository)(nil)
+	_ filters.FilterAuditBatchRepository    = (*FilterAuditRepository)(nil)
+
+	_ calsync.CalendarEventRepository = (*CalendarEventRepository)(nil)
+	_ calsync.CursorRepository        = (*CalendarSyncCursorRepository)(nil)
+
+	_ notify.DeliveryRepository = (*NotificationRepository)(nil)
+	_ notify.SettingsRepository = (*NotificationSettingsRepository)(nil)
+)
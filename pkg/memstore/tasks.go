@@ -0,0 +1,772 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskRepository stores tasks. Listings are ordered by creation time.
+type TaskRepository struct {
+	store *Store
+}
+
+func (r *TaskRepository) Create(task models.Task) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.tasks[task.ID]; exists {
+		return fmt.Errorf("task already exists: %s", task.ID)
+	}
+	r.store.data.tasks[task.ID] = task
+	return nil
+}
+
+func (r *TaskRepository) GetByID(taskID string) (*models.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	task, exists := r.store.data.tasks[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	return &task, nil
+}
+
+// GetByIDs returns the tasks with the given IDs, leaving out those that do
+// not exist
+func (r *TaskRepository) GetByIDs(taskIDs []string) ([]models.Task, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tasks := make([]models.Task, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		if task, exists := r.store.data.tasks[taskID]; exists {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// GetByUserID returns the tasks the user created or is assigned
+func (r *TaskRepository) GetByUserID(userID string) ([]models.Task, error) {
+	return r.where(func(task models.Task) bool {
+		return isUserTask(task, userID)
+	}), nil
+}
+
+func (r *TaskRepository) GetByStatus(userID string, status models.TaskStatus) ([]models.Task, error) {
+	return r.where(func(task models.Task) bool {
+		return isUserTask(task, userID) && task.Status == status
+	}), nil
+}
+
+// Update saves the task if its Version is still the stored one, as one
+// version higher
+func (r *TaskRepository) Update(task models.Task) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, exists := r.store.data.tasks[task.ID]
+	if !exists {
+		return fmt.Errorf("task not found: %s", task.ID)
+	}
+	if stored.Version != task.Version {
+		return fmt.Errorf("task %s is at version %d, not %d: %w", task.ID, stored.Version, task.Version, models.ErrTaskVersionConflict)
+	}
+	task.Version++
+	r.store.data.tasks[task.ID] = task
+	return nil
+}
+
+// Delete removes a task along with its dependencies, location links and
+// task links
+func (r *TaskRepository) Delete(taskID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.tasks[taskID]; !exists {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	delete(r.store.data.tasks, taskID)
+
+	dependencies := r.store.data.dependencies[:0]
+	for _, dep := range r.store.data.dependencies {
+		if dep.TaskID != taskID && dep.DependsOnTaskID != taskID {
+			dependencies = append(dependencies, dep)
+		}
+	}
+	r.store.data.dependencies = dependencies
+
+	taskLocations := r.store.data.taskLocations[:0]
+	for _, taskLocation := range r.store.data.taskLocations {
+		if taskLocation.TaskID != taskID {
+			taskLocations = append(taskLocations, taskLocation)
+		}
+	}
+	r.store.data.taskLocations = taskLocations
+
+	links := r.store.data.links[:0]
+	for _, link := range r.store.data.links {
+		if !link.InvolvesTask(taskID) {
+			links = append(links, link)
+		}
+	}
+	r.store.data.links = links
+	delete(r.store.data.undos, taskID)
+
+	for key := range r.store.data.visibility {
+		if key.taskID == taskID {
+			delete(r.store.data.visibility, key)
+		}
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) GetByListID(listID string) ([]models.Task, error) {
+	return r.where(func(task models.Task) bool {
+		return task.ListID != nil && *task.ListID == listID
+	}), nil
+}
+
+// GetSubtasks returns the tasks whose parent is parentTaskID
+func (r *TaskRepository) GetSubtasks(parentTaskID string) ([]models.Task, error) {
+	return r.where(func(task models.Task) bool {
+		return task.ParentTaskID != nil && *task.ParentTaskID == parentTaskID
+	}), nil
+}
+
+// Search returns the user's tasks whose title or description contains every
+// word of query, ignoring case
+func (r *TaskRepository) Search(userID string, query string) ([]models.Task, error) {
+	return r.where(func(task models.Task) bool {
+		return isUserTask(task, userID) && hereandnow.MatchesSearch(task.Title+" "+task.Description, query)
+	}), nil
+}
+
+func (r *TaskRepository) where(match func(task models.Task) bool) []models.Task {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tasks []models.Task
+	for _, task := range r.store.data.tasks {
+		if match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+func isUserTask(task models.Task, userID string) bool {
+	return task.CreatorID == userID || (task.AssigneeID != nil && *task.AssigneeID == userID)
+}
+
+// idSet returns the IDs as a set, for the batch lookups
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// TaskDependencyRepository stores which tasks block which
+type TaskDependencyRepository struct {
+	store *Store
+}
+
+func (r *TaskDependencyRepository) Create(dependency models.TaskDependency) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.data.dependencies {
+		if existing.TaskID == dependency.TaskID && existing.DependsOnTaskID == dependency.DependsOnTaskID {
+			return fmt.Errorf("dependency already exists: %s -> %s", dependency.TaskID, dependency.DependsOnTaskID)
+		}
+	}
+	r.store.data.dependencies = append(r.store.data.dependencies, dependency)
+	return nil
+}
+
+// AddDependency creates the dependency unless it would close a cycle, which
+// fails with a *models.DependencyCycleError
+func (r *TaskDependencyRepository) AddDependency(dependency models.TaskDependency) error {
+	if err := dependency.Validate(); err != nil {
+		return fmt.Errorf("invalid task dependency: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.data.dependencies {
+		if existing.TaskID == dependency.TaskID && existing.DependsOnTaskID == dependency.DependsOnTaskID {
+			return fmt.Errorf("dependency already exists: %s -> %s", dependency.TaskID, dependency.DependsOnTaskID)
+		}
+	}
+
+	cycle, err := models.FindDependencyCycle(dependency, func(taskID string) ([]models.TaskDependency, error) {
+		var dependencies []models.TaskDependency
+		for _, dep := range r.store.data.dependencies {
+			if dep.TaskID == taskID {
+				dependencies = append(dependencies, dep)
+			}
+		}
+		return dependencies, nil
+	})
+	if err != nil {
+		return err
+	}
+	if cycle != nil {
+		for i, taskID := range cycle {
+			if task, exists := r.store.data.tasks[taskID]; exists {
+				cycle[i] = task.Title
+			}
+		}
+		return &models.DependencyCycleError{Path: cycle}
+	}
+
+	r.store.data.dependencies = append(r.store.data.dependencies, dependency)
+	return nil
+}
+
+// GetDependenciesByTaskID returns the dependencies taskID waits on
+func (r *TaskDependencyRepository) GetDependenciesByTaskID(taskID string) ([]models.TaskDependency, error) {
+	return r.where(func(dep models.TaskDependency) bool {
+		return dep.TaskID == taskID
+	}), nil
+}
+
+// GetDependenciesByTaskIDs returns the dependencies each of the tasks waits
+// on, keyed by task ID
+func (r *TaskDependencyRepository) GetDependenciesByTaskIDs(taskIDs []string) (map[string][]models.TaskDependency, error) {
+	wanted := idSet(taskIDs)
+	dependencies := make(map[string][]models.TaskDependency)
+	for _, dep := range r.where(func(dep models.TaskDependency) bool {
+		return wanted[dep.TaskID]
+	}) {
+		dependencies[dep.TaskID] = append(dependencies[dep.TaskID], dep)
+	}
+	return dependencies, nil
+}
+
+// GetDependentsByTaskID returns the dependencies waiting on taskID
+func (r *TaskDependencyRepository) GetDependentsByTaskID(taskID string) ([]models.TaskDependency, error) {
+	return r.where(func(dep models.TaskDependency) bool {
+		return dep.DependsOnTaskID == taskID
+	}), nil
+}
+
+func (r *TaskDependencyRepository) Delete(dependentTaskID, dependsOnTaskID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	dependencies := r.store.data.dependencies[:0]
+	for _, dep := range r.store.data.dependencies {
+		if dep.TaskID != dependentTaskID || dep.DependsOnTaskID != dependsOnTaskID {
+			dependencies = append(dependencies, dep)
+		}
+	}
+	r.store.data.dependencies = dependencies
+	return nil
+}
+
+func (r *TaskDependencyRepository) where(match func(dep models.TaskDependency) bool) []models.TaskDependency {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var dependencies []models.TaskDependency
+	for _, dep := range r.store.data.dependencies {
+		if match(dep) {
+			dependencies = append(dependencies, dep)
+		}
+	}
+	return dependencies
+}
+
+// TaskLinkRepository stores related and duplicate links between tasks
+type TaskLinkRepository struct {
+	store *Store
+}
+
+func (r *TaskLinkRepository) Create(link models.TaskLink) error {
+	if err := link.Validate(); err != nil {
+		return fmt.Errorf("invalid task link: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.data.links {
+		if existing.TaskID == link.TaskID && existing.LinkedTaskID == link.LinkedTaskID {
+			return fmt.Errorf("task link already exists: %s -> %s", link.TaskID, link.LinkedTaskID)
+		}
+	}
+	r.store.data.links = append(r.store.data.links, link)
+	return nil
+}
+
+// GetByTaskID returns the links from and to the task, oldest first
+func (r *TaskLinkRepository) GetByTaskID(taskID string) ([]models.TaskLink, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var links []models.TaskLink
+	for _, link := range r.store.data.links {
+		if link.InvolvesTask(taskID) {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (r *TaskLinkRepository) Delete(linkID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, link := range r.store.data.links {
+		if link.ID == linkID {
+			r.store.data.links = append(r.store.data.links[:i], r.store.data.links[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("task link not found: %s", linkID)
+}
+
+// TaskLocationRepository links tasks to locations in the same store
+type TaskLocationRepository struct {
+	store *Store
+}
+
+func (r *TaskLocationRepository) Create(taskLocation models.TaskLocation) error {
+	if taskLocation.Trigger == "" {
+		taskLocation.Trigger = models.LocationTriggerEnter
+	}
+	if err := taskLocation.Validate(); err != nil {
+		return fmt.Errorf("task location validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.locations[taskLocation.LocationID]; !exists {
+		return fmt.Errorf("location not found: %s", taskLocation.LocationID)
+	}
+	r.store.data.taskLocations = append(r.store.data.taskLocations, taskLocation)
+	return nil
+}
+
+// GetLocationsByTaskID returns the locations linked to a task
+func (r *TaskLocationRepository) GetLocationsByTaskID(taskID string) ([]models.Location, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var locations []models.Location
+	for _, taskLocation := range r.store.data.taskLocations {
+		if taskLocation.TaskID != taskID {
+			continue
+		}
+		if location, exists := r.store.data.locations[taskLocation.LocationID]; exists {
+			locations = append(locations, location)
+		}
+	}
+	return locations, nil
+}
+
+// GetTaskLocationsByTaskID returns a task's location links with their triggers
+func (r *TaskLocationRepository) GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error) {
+	return r.where(func(taskLocation models.TaskLocation) bool {
+		return taskLocation.TaskID == taskID
+	}), nil
+}
+
+// GetLocationsByTaskIDs returns the locations linked to each of the tasks,
+// keyed by task ID
+func (r *TaskLocationRepository) GetLocationsByTaskIDs(taskIDs []string) (map[string][]models.Location, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	wanted := idSet(taskIDs)
+	locations := make(map[string][]models.Location)
+	for _, taskLocation := range r.store.data.taskLocations {
+		if !wanted[taskLocation.TaskID] {
+			continue
+		}
+		if location, exists := r.store.data.locations[taskLocation.LocationID]; exists {
+			locations[taskLocation.TaskID] = append(locations[taskLocation.TaskID], location)
+		}
+	}
+	return locations, nil
+}
+
+// GetTaskLocationsByTaskIDs returns the location links of each of the tasks,
+// keyed by task ID
+func (r *TaskLocationRepository) GetTaskLocationsByTaskIDs(taskIDs []string) (map[string][]models.TaskLocation, error) {
+	wanted := idSet(taskIDs)
+	links := make(map[string][]models.TaskLocation)
+	for _, taskLocation := range r.where(func(taskLocation models.TaskLocation) bool {
+		return wanted[taskLocation.TaskID]
+	}) {
+		links[taskLocation.TaskID] = append(links[taskLocation.TaskID], taskLocation)
+	}
+	return links, nil
+}
+
+// GetByLocationID returns the links from every task tied to a location
+func (r *TaskLocationRepository) GetByLocationID(locationID string) ([]models.TaskLocation, error) {
+	return r.where(func(taskLocation models.TaskLocation) bool {
+		return taskLocation.LocationID == locationID
+	}), nil
+}
+
+func (r *TaskLocationRepository) Delete(taskID, locationID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	taskLocations := r.store.data.taskLocations[:0]
+	for _, taskLocation := range r.store.data.taskLocations {
+		if taskLocation.TaskID != taskID || taskLocation.LocationID != locationID {
+			taskLocations = append(taskLocations, taskLocation)
+		}
+	}
+	r.store.data.taskLocations = taskLocations
+	return nil
+}
+
+func (r *TaskLocationRepository) where(match func(taskLocation models.TaskLocation) bool) []models.TaskLocation {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var taskLocations []models.TaskLocation
+	for _, taskLocation := range r.store.data.taskLocations {
+		if match(taskLocation) {
+			taskLocations = append(taskLocations, taskLocation)
+		}
+	}
+	return taskLocations
+}
+
+// TaskVisibilityRepository stores the last visibility each user saw for
+// each task
+type TaskVisibilityRepository struct {
+	store *Store
+}
+
+func (r *TaskVisibilityRepository) GetByUserID(userID string) ([]models.TaskVisibility, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var visibilities []models.TaskVisibility
+	for key, visibility := range r.store.data.visibility {
+		if key.userID == userID {
+			visibilities = append(visibilities, visibility)
+		}
+	}
+	return visibilities, nil
+}
+
+// Save inserts or replaces the user's visibility record for a task
+func (r *TaskVisibilityRepository) Save(visibility models.TaskVisibility) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.visibility[visibilityKey{visibility.UserID, visibility.TaskID}] = visibility
+	return nil
+}
+
+// TaskActionRepository keeps each user's most recent undoable action
+type TaskActionRepository struct {
+	store *Store
+}
+
+// Save records the user's latest action, replacing the previous one
+func (r *TaskActionRepository) Save(action models.TaskAction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.actions {
+		if existing.UserID == action.UserID {
+			r.store.data.actions[i] = action
+			return nil
+		}
+	}
+	r.store.data.actions = append(r.store.data.actions, action)
+	return nil
+}
+
+func (r *TaskActionRepository) GetByUserID(userID string) ([]models.TaskAction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var actions []models.TaskAction
+	for _, action := range r.store.data.actions {
+		if action.UserID == userID {
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+func (r *TaskActionRepository) Delete(actionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, action := range r.store.data.actions {
+		if action.ID == actionID {
+			r.store.data.actions = append(r.store.data.actions[:i], r.store.data.actions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// TaskHistoryRepository keeps every recorded task edit
+type TaskHistoryRepository struct {
+	store *Store
+}
+
+func (r *TaskHistoryRepository) Create(entry models.TaskHistoryEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.history = append(r.store.data.history, entry)
+	return nil
+}
+
+// GetByTaskID returns the task's history, oldest first
+func (r *TaskHistoryRepository) GetByTaskID(taskID string) ([]models.TaskHistoryEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var entries []models.TaskHistoryEntry
+	for _, entry := range r.store.data.history {
+		if entry.TaskID == taskID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// DeleteOlderThan removes the entries recorded before the given time
+func (r *TaskHistoryRepository) DeleteOlderThan(before time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.data.history[:0]
+	for _, entry := range r.store.data.history {
+		if !entry.CreatedAt.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	r.store.data.history = kept
+	return nil
+}
+
+// CompletionUndoRepository keeps the shared-list completions that can still
+// be undone, one per task
+type CompletionUndoRepository struct {
+	store *Store
+}
+
+// Save records a completion's undo, replacing any earlier one for the task
+func (r *CompletionUndoRepository) Save(undo models.CompletionUndo) error {
+	if err := undo.Validate(); err != nil {
+		return fmt.Errorf("completion undo validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.undos[undo.TaskID] = undo
+	return nil
+}
+
+func (r *CompletionUndoRepository) GetByTaskID(taskID string) (*models.CompletionUndo, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	undo, exists := r.store.data.undos[taskID]
+	if !exists {
+		return nil, fmt.Errorf("completion undo not found: %s", taskID)
+	}
+	return &undo, nil
+}
+
+func (r *CompletionUndoRepository) Delete(taskID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.data.undos, taskID)
+	return nil
+}
+
+// DeleteExpired removes the undos whose window had closed by now and returns
+// how many it removed
+func (r *CompletionUndoRepository) DeleteExpired(now time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	removed := 0
+	for taskID, undo := range r.store.data.undos {
+		if undo.IsExpiredAt(now) {
+			delete(r.store.data.undos, taskID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// TaskAssignmentRepository stores task delegations. Listings are ordered by
+// assignment time.
+type TaskAssignmentRepository struct {
+	store *Store
+}
+
+func (r *TaskAssignmentRepository) Create(assignment models.TaskAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return fmt.Errorf("assignment validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.data.assignments {
+		if existing.ID == assignment.ID {
+			return fmt.Errorf("task assignment already exists: %s", assignment.ID)
+		}
+	}
+	r.store.data.assignments = append(r.store.data.assignments, assignment)
+	return nil
+}
+
+func (r *TaskAssignmentRepository) GetByID(assignmentID string) (*models.TaskAssignment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, assignment := range r.store.data.assignments {
+		if assignment.ID == assignmentID {
+			return &assignment, nil
+		}
+	}
+	return nil, fmt.Errorf("task assignment not found")
+}
+
+func (r *TaskAssignmentRepository) GetByTaskID(taskID string) ([]models.TaskAssignment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var assignments []models.TaskAssignment
+	for _, assignment := range r.store.data.assignments {
+		if assignment.BelongsToTask(taskID) {
+			assignments = append(assignments, assignment)
+		}
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].AssignedAt.Before(assignments[j].AssignedAt)
+	})
+	return assignments, nil
+}
+
+func (r *TaskAssignmentRepository) Update(assignment models.TaskAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return fmt.Errorf("assignment validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, existing := range r.store.data.assignments {
+		if existing.ID == assignment.ID {
+			r.store.data.assignments[i] = assignment
+			return nil
+		}
+	}
+	return fmt.Errorf("task assignment not found")
+}
+
+// ReminderRepository stores task reminders
+type ReminderRepository struct {
+	store *Store
+}
+
+func (r *ReminderRepository) Create(reminder models.Reminder) error {
+	if err := reminder.Validate(); err != nil {
+		return fmt.Errorf("reminder validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.reminders = append(r.store.data.reminders, reminder)
+	return nil
+}
+
+// GetByTaskID returns the task's reminders, earliest first
+func (r *ReminderRepository) GetByTaskID(taskID string) ([]models.Reminder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var reminders []models.Reminder
+	for _, reminder := range r.store.data.reminders {
+		if reminder.TaskID == taskID {
+			reminders = append(reminders, reminder)
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return reminders[i].RemindAt.Before(reminders[j].RemindAt)
+	})
+	return reminders, nil
+}
+
+// GetDue returns up to limit unsent reminders due by now, earliest first
+func (r *ReminderRepository) GetDue(now time.Time, limit int) ([]models.Reminder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var due []models.Reminder
+	for _, reminder := range r.store.data.reminders {
+		if reminder.IsDue(now) {
+			due = append(due, reminder)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].RemindAt.Before(due[j].RemindAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Claim marks the reminder sent unless it already is, and reports whether
+// this call marked it
+func (r *ReminderRepository) Claim(reminderID string, at time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.data.reminders {
+		reminder := &r.store.data.reminders[i]
+		if reminder.ID != reminderID {
+			continue
+		}
+		if reminder.Sent {
+			return false, nil
+		}
+		reminder.Sent = true
+		reminder.SentAt = &at
+		return true, nil
+	}
+	return false, fmt.Errorf("reminder not found: %s", reminderID)
+}
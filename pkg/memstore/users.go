@@ -0,0 +1,260 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// UserRepository stores user accounts
+type UserRepository struct {
+	store *Store
+}
+
+func (r *UserRepository) Create(user models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.users[user.ID]; exists {
+		return fmt.Errorf("user already exists: %s", user.ID)
+	}
+	for _, existing := range r.store.data.users {
+		if existing.Username == user.Username {
+			return fmt.Errorf("username already taken: %s", user.Username)
+		}
+	}
+	r.store.data.users[user.ID] = user
+	return nil
+}
+
+func (r *UserRepository) GetByID(userID string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, exists := r.store.data.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found: %s", userID)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) Update(user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.data.users[user.ID]; !exists {
+		return fmt.Errorf("user not found: %s", user.ID)
+	}
+	r.store.data.users[user.ID] = *user
+	return nil
+}
+
+// NotificationRepository stores notifications delivered to users
+type NotificationRepository struct {
+	store *Store
+}
+
+func (r *NotificationRepository) Create(notification models.Notification) error {
+	if err := notification.Validate(); err != nil {
+		return fmt.Errorf("notification validation failed: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.notifications = append(r.store.data.notifications, notification)
+	return nil
+}
+
+// GetByUserID returns a user's notifications, newest first
+func (r *NotificationRepository) GetByUserID(userID string, unreadOnly bool) ([]models.Notification, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var notifications []models.Notification
+	for _, notification := range r.store.data.notifications {
+		if notification.UserID == userID && (!unreadOnly || !notification.IsRead()) {
+			notifications = append(notifications, notification)
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+	return notifications, nil
+}
+
+func (r *NotificationRepository) MarkRead(notificationID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.data.notifications {
+		if r.store.data.notifications[i].ID == notificationID {
+			r.store.data.notifications[i].MarkRead()
+			return nil
+		}
+	}
+	return fmt.Errorf("notification not found: %s", notificationID)
+}
+
+func (r *NotificationRepository) Delete(notificationID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, notification := range r.store.data.notifications {
+		if notification.ID == notificationID {
+			r.store.data.notifications = append(r.store.data.notifications[:i], r.store.data.notifications[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// GetUndelivered returns up to limit notifications, oldest first, that
+// belong to users with a notification channel turned on, were created after
+// the user's settings were, and have neither been delivered nor given up on
+func (r *NotificationRepository) GetUndelivered(limit int) ([]models.Notification, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var notifications []models.Notification
+	for _, notification := range r.store.data.notifications {
+		settings, ok := r.store.data.notificationSettings[notification.UserID]
+		if !ok || !settings.HasChannels() || notification.CreatedAt.Before(settings.CreatedAt) {
+			continue
+		}
+		if notification.DeliveredAt == nil && !r.store.data.undeliverable[notification.ID] {
+			notifications = append(notifications, notification)
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) MarkDelivered(notificationID string, at time.Time) error {
+	return r.update(notificationID, func(notification *models.Notification) {
+		notification.DeliveredAt = &at
+	})
+}
+
+// RecordDeliveryFailure counts a failed delivery pass, and when giveUp is
+// set stops the notification being returned as undelivered
+func (r *NotificationRepository) RecordDeliveryFailure(notificationID, reason string, giveUp bool) error {
+	return r.update(notificationID, func(notification *models.Notification) {
+		notification.DeliveryAttempts++
+		if giveUp {
+			r.store.data.undeliverable[notificationID] = true
+		}
+	})
+}
+
+func (r *NotificationRepository) update(notificationID string, apply func(notification *models.Notification)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.data.notifications {
+		if r.store.data.notifications[i].ID == notificationID {
+			apply(&r.store.data.notifications[i])
+			return nil
+		}
+	}
+	return fmt.Errorf("notification not found: %s", notificationID)
+}
+
+// NotificationSettingsRepository stores where users' notifications are
+// pushed
+type NotificationSettingsRepository struct {
+	store *Store
+}
+
+// Get returns the user's settings, or nil when they have none
+func (r *NotificationSettingsRepository) Get(userID string) (*models.NotificationSettings, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	settings, ok := r.store.data.notificationSettings[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+// Save stores the settings, replacing the user's previous ones. The time
+// they were first created is kept.
+func (r *NotificationSettingsRepository) Save(settings models.NotificationSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing, ok := r.store.data.notificationSettings[settings.UserID]; ok {
+		settings.CreatedAt = existing.CreatedAt
+	}
+	r.store.data.notificationSettings[settings.UserID] = settings
+	return nil
+}
+
+// FilterAuditRepository stores the filter engine's visibility decisions
+type FilterAuditRepository struct {
+	store *Store
+}
+
+func (r *FilterAuditRepository) SaveFilterResult(audit models.FilterAudit) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.audits = append(r.store.data.audits, audit)
+	return nil
+}
+
+// SaveFilterResults saves the audits together
+func (r *FilterAuditRepository) SaveFilterResults(audits []models.FilterAudit) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.data.audits = append(r.store.data.audits, audits...)
+	return nil
+}
+
+// GetAuditLogByTaskID returns up to limit of the task's audit entries,
+// newest first
+func (r *FilterAuditRepository) GetAuditLogByTaskID(taskID string, limit int) ([]models.FilterAudit, error) {
+	return r.where(limit, func(audit models.FilterAudit) bool {
+		return audit.TaskID == taskID
+	}), nil
+}
+
+// GetAuditLogByUserID returns up to limit of the user's audit entries since
+// the given time, newest first
+func (r *FilterAuditRepository) GetAuditLogByUserID(userID string, since time.Time, limit int) ([]models.FilterAudit, error) {
+	return r.where(limit, func(audit models.FilterAudit) bool {
+		return audit.UserID == userID && !audit.CreatedAt.Before(since)
+	}), nil
+}
+
+func (r *FilterAuditRepository) where(limit int, match func(audit models.FilterAudit) bool) []models.FilterAudit {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var audits []models.FilterAudit
+	for i := len(r.store.data.audits) - 1; i >= 0; i-- {
+		if match(r.store.data.audits[i]) {
+			audits = append(audits, r.store.data.audits[i])
+		}
+	}
+	sort.SliceStable(audits, func(i, j int) bool {
+		return audits[i].CreatedAt.After(audits[j].CreatedAt)
+	})
+	if limit > 0 && len(audits) > limit {
+		audits = audits[:limit]
+	}
+	return audits
+}
@@ -28,6 +28,7 @@ const (
 	ProviderOutlook  = "outlook"
 	ProviderApple    = "apple"
 	ProviderCalDAV   = "caldav"
+	ProviderICS      = "ics"
 )
 
 func NewCalendarEvent(userID, providerID, externalID, title string, startAt, endAt time.Time) (*CalendarEvent, error) {
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CalendarSyncCursor remembers where the last successful incremental sync of
+// one user's calendar at a provider left off, such as a Google sync token or
+// a CalDAV sync-collection token
+type CalendarSyncCursor struct {
+	UserID    string    `db:"user_id" json:"user_id"`
+	Provider  string    `db:"provider" json:"provider"`
+	Token     string    `db:"token" json:"token"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func NewCalendarSyncCursor(userID, provider, token string) (*CalendarSyncCursor, error) {
+	cursor := &CalendarSyncCursor{
+		UserID:    userID,
+		Provider:  provider,
+		Token:     token,
+		UpdatedAt: time.Now(),
+	}
+	if err := cursor.Validate(); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func (c *CalendarSyncCursor) Validate() error {
+	if c.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if c.Provider == "" {
+		return fmt.Errorf("provider is required")
+	}
+	if c.Token == "" {
+		return fmt.Errorf("sync token is required")
+	}
+	return nil
+}
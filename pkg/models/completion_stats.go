@@ -0,0 +1,83 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// CompletionStats summarizes a user's task completions by calendar day.
+// A streak counts consecutive days with at least one completion; any day
+// with zero completions breaks it. Today only breaks the current streak
+// once it is over, so a streak ending yesterday is still current.
+type CompletionStats struct {
+	CompletedToday  int        `json:"completed_today"`
+	CurrentStreak   int        `json:"current_streak"`
+	LongestStreak   int        `json:"longest_streak"`
+	LastCompletedAt *time.Time `json:"last_completed_at,omitempty"`
+}
+
+// NewCompletionStats computes stats from completion timestamps as of now,
+// splitting days in loc. A nil loc uses now's location. Completions after
+// now are ignored.
+func NewCompletionStats(completions []time.Time, now time.Time, loc *time.Location) *CompletionStats {
+	if loc == nil {
+		loc = now.Location()
+	}
+	today := calendarDay(now, loc)
+
+	stats := &CompletionStats{}
+	days := make(map[time.Time]int)
+	for _, completedAt := range completions {
+		if completedAt.After(now) {
+			continue
+		}
+
+		day := calendarDay(completedAt, loc)
+		days[day]++
+		if day.Equal(today) {
+			stats.CompletedToday++
+		}
+		if stats.LastCompletedAt == nil || completedAt.After(*stats.LastCompletedAt) {
+			last := completedAt
+			stats.LastCompletedAt = &last
+		}
+	}
+
+	day := today
+	if days[day] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day] > 0 {
+		stats.CurrentStreak++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	sorted := make([]time.Time, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+
+	run := 0
+	for i, day := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > stats.LongestStreak {
+			stats.LongestStreak = run
+		}
+	}
+
+	return stats
+}
+
+// calendarDay returns t's date in loc as midnight UTC, so consecutive days
+// are always exactly one AddDate apart regardless of DST
+func calendarDay(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CompletionUndo holds a shared-list task completion open for a grace
+// period, during which the user who completed it can take it back. It keeps
+// the task as it was before and the notifications sent to the list's other
+// members, so undoing leaves no trace of the completion. There is at most
+// one per task.
+type CompletionUndo struct {
+	TaskID          string          `db:"task_id" json:"task_id"`
+	UserID          string          `db:"user_id" json:"user_id"`
+	Snapshot        json.RawMessage `db:"snapshot" json:"snapshot"`
+	NotificationIDs []string        `db:"notification_ids" json:"notification_ids"`
+	CompletedAt     time.Time       `db:"completed_at" json:"completed_at"`
+	ExpiresAt       time.Time       `db:"expires_at" json:"expires_at"`
+	// SpawnedTaskID is the next occurrence completing a recurring task
+	// created, which undoing the completion removes
+	SpawnedTaskID string `db:"spawned_task_id" json:"spawned_task_id,omitempty"`
+}
+
+func NewCompletionUndo(userID string, before Task, notificationIDs []string, completedAt time.Time, window time.Duration) (*CompletionUndo, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	if before.ID == "" {
+		return nil, fmt.Errorf("task ID is required")
+	}
+
+	if window <= 0 {
+		return nil, fmt.Errorf("undo window must be positive")
+	}
+
+	snapshot, err := json.Marshal(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	if notificationIDs == nil {
+		notificationIDs = []string{}
+	}
+
+	return &CompletionUndo{
+		TaskID:          before.ID,
+		UserID:          userID,
+		Snapshot:        snapshot,
+		NotificationIDs: notificationIDs,
+		CompletedAt:     completedAt,
+		ExpiresAt:       completedAt.Add(window),
+	}, nil
+}
+
+// IsExpiredAt reports whether the grace period is over at the given time,
+// after which the completion is final
+func (u *CompletionUndo) IsExpiredAt(at time.Time) bool {
+	return !at.Before(u.ExpiresAt)
+}
+
+// GetTask returns the task as it was before it was completed
+func (u *CompletionUndo) GetTask() (*Task, error) {
+	var task Task
+	if err := json.Unmarshal(u.Snapshot, &task); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+	return &task, nil
+}
+
+func (u *CompletionUndo) Validate() error {
+	if u.TaskID == "" {
+		return fmt.Errorf("task ID is required")
+	}
+
+	if u.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	if len(u.Snapshot) == 0 {
+		return fmt.Errorf("snapshot is required")
+	}
+
+	if !u.ExpiresAt.After(u.CompletedAt) {
+		return fmt.Errorf("expiry must be after completion")
+	}
+
+	return nil
+}
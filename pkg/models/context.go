@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,9 +22,18 @@ type Context struct {
 	EnergyLevel       int             `db:"energy_level" json:"energy_level"`
 	WeatherCondition  *string         `db:"weather_condition" json:"weather_condition"`
 	TrafficLevel      *string         `db:"traffic_level" json:"traffic_level"`
+	MinPriority       int             `db:"min_priority" json:"min_priority"` // Hide tasks below this priority; 0 shows everything
 	Metadata          json.RawMessage `db:"metadata" json:"metadata"`
+	// AgeSeconds and IsStale describe the snapshot as of when it was read
+	// and are not stored; see MarkAge
+	AgeSeconds int  `db:"-" json:"age_seconds"`
+	IsStale    bool `db:"-" json:"is_stale"`
 }
 
+// DefaultContextMaxAge is how old a context snapshot can get before its
+// location and available time are no longer trusted
+const DefaultContextMaxAge = 2 * time.Hour
+
 const (
 	SocialContextAlone      = "alone"
 	SocialContextWithFamily = "with_family"
@@ -97,7 +108,7 @@ func (c *Context) SetAvailableMinutes(minutes int) error {
 }
 
 func (c *Context) SetSocialContext(socialContext string) error {
-	if !isValidSocialContext(socialContext) {
+	if !IsValidSocialContext(socialContext) {
 		return fmt.Errorf("invalid social context: %s", socialContext)
 	}
 	c.SocialContext = socialContext
@@ -113,7 +124,7 @@ func (c *Context) SetEnergyLevel(energyLevel int) error {
 }
 
 func (c *Context) SetWeatherCondition(condition string) error {
-	if !isValidWeatherCondition(condition) {
+	if !IsValidWeatherCondition(condition) {
 		return fmt.Errorf("invalid weather condition: %s", condition)
 	}
 	c.WeatherCondition = &condition
@@ -136,6 +147,16 @@ func (c *Context) ClearTrafficLevel() {
 	c.TrafficLevel = nil
 }
 
+// SetMinPriority hides tasks below the given priority. Zero shows every
+// priority again.
+func (c *Context) SetMinPriority(priority int) error {
+	if err := validateMinPriority(priority); err != nil {
+		return err
+	}
+	c.MinPriority = priority
+	return nil
+}
+
 func (c *Context) HasCurrentPosition() bool {
 	return c.CurrentLatitude != nil && c.CurrentLongitude != nil
 }
@@ -159,10 +180,107 @@ func (c *Context) HasEnoughEnergy(requiredEnergyLevel int) bool {
 	return c.EnergyLevel >= requiredEnergyLevel
 }
 
+// MarkAge records how old the snapshot is at now and whether that is older
+// than maxAge. A maxAge of zero never marks it stale.
+func (c *Context) MarkAge(now time.Time, maxAge time.Duration) {
+	age := now.Sub(c.Timestamp)
+	if age < 0 {
+		age = 0
+	}
+	c.AgeSeconds = int(age / time.Second)
+	c.IsStale = maxAge > 0 && age > maxAge
+}
+
+// Age is how old the snapshot was when MarkAge was last called
+func (c *Context) Age() time.Duration {
+	return time.Duration(c.AgeSeconds) * time.Second
+}
+
 func (c *Context) IsOwnedBy(userID string) bool {
 	return c.UserID == userID
 }
 
+// ApplyChanges sets fields from comma-separated key=value pairs such as
+// "energy=2,minutes=30". Keys are energy, minutes, social, weather,
+// traffic, min_priority, lat and lng.
+func (c *Context) ApplyChanges(spec string) error {
+	var lat, lng *float64
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid context change %q (want key=value)", pair)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "energy":
+			var energy int
+			if energy, err = strconv.Atoi(value); err == nil {
+				err = c.SetEnergyLevel(energy)
+			}
+		case "minutes", "available_minutes":
+			var minutes int
+			if minutes, err = strconv.Atoi(value); err == nil {
+				err = c.SetAvailableMinutes(minutes)
+			}
+		case "social", "social_context":
+			err = c.SetSocialContext(value)
+		case "weather":
+			err = c.SetWeatherCondition(value)
+		case "traffic":
+			err = c.SetTrafficLevel(value)
+		case "min_priority":
+			var priority int
+			if priority, err = strconv.Atoi(value); err == nil {
+				err = c.SetMinPriority(priority)
+			}
+		case "lat", "latitude":
+			var v float64
+			if v, err = strconv.ParseFloat(value, 64); err == nil {
+				lat = &v
+			}
+		case "lng", "longitude":
+			var v float64
+			if v, err = strconv.ParseFloat(value, 64); err == nil {
+				lng = &v
+			}
+		default:
+			return fmt.Errorf("unknown context field: %s", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if lat != nil || lng != nil {
+		if lat == nil || lng == nil {
+			if !c.HasCurrentPosition() {
+				return fmt.Errorf("lat and lng must be changed together when there is no current position")
+			}
+			if lat == nil {
+				lat = c.CurrentLatitude
+			}
+			if lng == nil {
+				lng = c.CurrentLongitude
+			}
+		}
+		if err := c.SetCurrentPosition(*lat, *lng); err != nil {
+			return err
+		}
+		// An explicit position replaces the matched saved location
+		c.ClearCurrentLocation()
+	}
+
+	return nil
+}
+
 func (c *Context) Validate() error {
 	if c.UserID == "" {
 		return fmt.Errorf("user ID is required")
@@ -176,7 +294,7 @@ func (c *Context) Validate() error {
 		return err
 	}
 
-	if !isValidSocialContext(c.SocialContext) {
+	if !IsValidSocialContext(c.SocialContext) {
 		return fmt.Errorf("invalid social context: %s", c.SocialContext)
 	}
 
@@ -186,7 +304,7 @@ func (c *Context) Validate() error {
 		}
 	}
 
-	if c.WeatherCondition != nil && !isValidWeatherCondition(*c.WeatherCondition) {
+	if c.WeatherCondition != nil && !IsValidWeatherCondition(*c.WeatherCondition) {
 		return fmt.Errorf("invalid weather condition: %s", *c.WeatherCondition)
 	}
 
@@ -194,6 +312,10 @@ func (c *Context) Validate() error {
 		return fmt.Errorf("invalid traffic level: %s", *c.TrafficLevel)
 	}
 
+	if err := validateMinPriority(c.MinPriority); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -204,7 +326,16 @@ func validateEnergyLevel(energyLevel int) error {
 	return nil
 }
 
-func isValidSocialContext(context string) bool {
+func validateMinPriority(priority int) error {
+	if priority < 0 || priority > 5 {
+		return fmt.Errorf("minimum priority must be between 0 and 5")
+	}
+	return nil
+}
+
+// IsValidSocialContext reports whether context is one of the SocialContext
+// constants
+func IsValidSocialContext(context string) bool {
 	validContexts := []string{
 		SocialContextAlone,
 		SocialContextWithFamily,
@@ -221,7 +352,9 @@ func isValidSocialContext(context string) bool {
 	return false
 }
 
-func isValidWeatherCondition(condition string) bool {
+// IsValidWeatherCondition reports whether condition is one of the Weather
+// constants
+func IsValidWeatherCondition(condition string) bool {
 	validConditions := []string{
 		WeatherSunny,
 		WeatherCloudy,
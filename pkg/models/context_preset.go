@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContextPreset is a named, reusable context such as "work-morning". Applying
+// a preset creates a new context snapshot from its values. A preset located
+// at a saved location stores only the location's ID, so the location's
+// coordinates are looked up when the preset is applied.
+type ContextPreset struct {
+	ID               string    `db:"id" json:"id"`
+	UserID           string    `db:"user_id" json:"user_id"`
+	Name             string    `db:"name" json:"name"`
+	AvailableMinutes int       `db:"available_minutes" json:"available_minutes"`
+	EnergyLevel      int       `db:"energy_level" json:"energy_level"`
+	SocialContext    string    `db:"social_context" json:"social_context"`
+	LocationID       *string   `db:"location_id" json:"location_id,omitempty"`
+	Latitude         *float64  `db:"latitude" json:"latitude,omitempty"`
+	Longitude        *float64  `db:"longitude" json:"longitude,omitempty"`
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func NewContextPreset(userID, name string) (*ContextPreset, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if err := validatePresetName(name); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &ContextPreset{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Name:          name,
+		SocialContext: SocialContextAlone,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// CaptureContext copies a context's available time, energy, social context
+// and location into the preset. A context at a saved location is captured as
+// a reference to it rather than as coordinates.
+func (p *ContextPreset) CaptureContext(context Context) {
+	p.AvailableMinutes = context.AvailableMinutes
+	p.EnergyLevel = context.EnergyLevel
+	p.SocialContext = context.SocialContext
+	p.LocationID = nil
+	p.Latitude = nil
+	p.Longitude = nil
+
+	if context.CurrentLocationID != nil {
+		locationID := *context.CurrentLocationID
+		p.LocationID = &locationID
+	} else if context.CurrentLatitude != nil && context.CurrentLongitude != nil {
+		latitude, longitude := *context.CurrentLatitude, *context.CurrentLongitude
+		p.Latitude = &latitude
+		p.Longitude = &longitude
+	}
+
+	p.UpdatedAt = time.Now()
+}
+
+func (p *ContextPreset) Validate() error {
+	if p.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	if err := validatePresetName(p.Name); err != nil {
+		return err
+	}
+
+	if p.AvailableMinutes < 0 {
+		return fmt.Errorf("available minutes cannot be negative")
+	}
+
+	if p.EnergyLevel != 0 {
+		if err := validateEnergyLevel(p.EnergyLevel); err != nil {
+			return err
+		}
+	}
+
+	if p.SocialContext != "" && !IsValidSocialContext(p.SocialContext) {
+		return fmt.Errorf("invalid social context: %s", p.SocialContext)
+	}
+
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+
+	if p.Latitude != nil {
+		if p.LocationID != nil {
+			return fmt.Errorf("preset cannot have both a saved location and coordinates")
+		}
+		if err := validateCoordinates(*p.Latitude, *p.Longitude); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePresetName(name string) error {
+	if name == "" {
+		return fmt.Errorf("preset name is required")
+	}
+	if len(name) > 50 {
+		return fmt.Errorf("preset name must not exceed 50 characters")
+	}
+	if strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("preset name cannot contain whitespace")
+	}
+	return nil
+}
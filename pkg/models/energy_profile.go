@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultEnergyLevel is the neutral energy assumed when nothing better is known
+	DefaultEnergyLevel = 3
+	// MinEnergyProfileSamples is the number of snapshots needed at an hour
+	// before the historical average is trusted over the neutral default
+	MinEnergyProfileSamples = 5
+)
+
+// EnergyProfile holds a user's historical energy levels bucketed by hour of day
+type EnergyProfile struct {
+	Totals [24]int `json:"totals"`
+	Counts [24]int `json:"counts"`
+}
+
+// NewEnergyProfile buckets the energy level of each context by the hour of
+// its timestamp in loc. A nil loc uses each timestamp's own location.
+func NewEnergyProfile(contexts []Context, loc *time.Location) *EnergyProfile {
+	profile := &EnergyProfile{}
+	for _, ctx := range contexts {
+		if validateEnergyLevel(ctx.EnergyLevel) != nil {
+			continue
+		}
+
+		ts := ctx.Timestamp
+		if loc != nil {
+			ts = ts.In(loc)
+		}
+		profile.Totals[ts.Hour()] += ctx.EnergyLevel
+		profile.Counts[ts.Hour()]++
+	}
+	return profile
+}
+
+// Average returns the mean energy level recorded at hour and the number of
+// samples it is based on.
+func (p *EnergyProfile) Average(hour int) (float64, int) {
+	if hour < 0 || hour > 23 || p.Counts[hour] == 0 {
+		return 0, 0
+	}
+	return float64(p.Totals[hour]) / float64(p.Counts[hour]), p.Counts[hour]
+}
+
+// PredictEnergyLevel returns the rounded historical average at t's hour, or
+// DefaultEnergyLevel when there are fewer than MinEnergyProfileSamples.
+func (p *EnergyProfile) PredictEnergyLevel(t time.Time) int {
+	if p == nil {
+		return DefaultEnergyLevel
+	}
+
+	average, samples := p.Average(t.Hour())
+	if samples < MinEnergyProfileSamples {
+		return DefaultEnergyLevel
+	}
+
+	level := int(math.Round(average))
+	if level < 1 {
+		return 1
+	}
+	if level > 5 {
+		return 5
+	}
+	return level
+}
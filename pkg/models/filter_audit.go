@@ -22,6 +22,7 @@ type FilterAudit struct {
 type FilterReason struct {
 	Rule        string      `json:"rule"`
 	Passed      bool        `json:"passed"`
+	Code        string      `json:"code,omitempty"`
 	Details     string      `json:"details"`
 	Score       float64     `json:"score,omitempty"`
 	Metadata    interface{} `json:"metadata,omitempty"`
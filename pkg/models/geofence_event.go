@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GeofenceEvent records the user crossing the radius of one of their saved
+// locations, as seen between two context updates
+type GeofenceEvent struct {
+	ID         string            `db:"id" json:"id"`
+	UserID     string            `db:"user_id" json:"user_id"`
+	LocationID string            `db:"location_id" json:"location_id"`
+	ContextID  string            `db:"context_id" json:"context_id"`
+	Type       GeofenceEventType `db:"event_type" json:"type"`
+	At         time.Time         `db:"occurred_at" json:"at"`
+}
+
+type GeofenceEventType string
+
+const (
+	GeofenceEventEnter GeofenceEventType = "enter"
+	GeofenceEventExit  GeofenceEventType = "exit"
+)
+
+// NewGeofenceEvent returns an event for the user entering or leaving the
+// location, detected by the context saved at the given time
+func NewGeofenceEvent(userID, locationID, contextID string, eventType GeofenceEventType, at time.Time) (*GeofenceEvent, error) {
+	event := &GeofenceEvent{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		LocationID: locationID,
+		ContextID:  contextID,
+		Type:       eventType,
+		At:         at,
+	}
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (e *GeofenceEvent) Validate() error {
+	if e.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	if e.LocationID == "" {
+		return fmt.Errorf("location ID is required")
+	}
+
+	if e.Type != GeofenceEventEnter && e.Type != GeofenceEventExit {
+		return fmt.Errorf("invalid geofence event type: %s", e.Type)
+	}
+
+	if e.At.IsZero() {
+		return fmt.Errorf("event time is required")
+	}
+
+	return nil
+}
@@ -10,18 +10,19 @@ import (
 )
 
 type Location struct {
-	ID        string          `db:"id" json:"id"`
-	UserID    string          `db:"user_id" json:"user_id"`
-	Name      string          `db:"name" json:"name"`
-	Address   string          `db:"address" json:"address"`
-	Latitude  float64         `db:"latitude" json:"latitude"`
-	Longitude float64         `db:"longitude" json:"longitude"`
-	Radius    int             `db:"radius" json:"radius"`
-	Category  string          `db:"category" json:"category"`
-	PlaceID   *string         `db:"place_id" json:"place_id"`
-	Metadata  json.RawMessage `db:"metadata" json:"metadata"`
-	CreatedAt time.Time       `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
+	ID        string           `db:"id" json:"id"`
+	UserID    string           `db:"user_id" json:"user_id"`
+	Name      string           `db:"name" json:"name"`
+	Address   string           `db:"address" json:"address"`
+	Latitude  float64          `db:"latitude" json:"latitude"`
+	Longitude float64          `db:"longitude" json:"longitude"`
+	Radius    int              `db:"radius" json:"radius"`
+	Category  LocationCategory `db:"category" json:"category"` // One of LocationCategories unless set with SetCustomCategory
+	PlaceID   *string          `db:"place_id" json:"place_id"`
+	OpenHours OpenHours        `db:"open_hours" json:"open_hours,omitempty"` // Tasks here are hidden while it is closed; none is always open
+	Metadata  json.RawMessage  `db:"metadata" json:"metadata"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time        `db:"updated_at" json:"updated_at"`
 }
 
 const (
@@ -50,7 +51,7 @@ func NewLocation(userID, name, address string, latitude, longitude float64, radi
 		Latitude:  latitude,
 		Longitude: longitude,
 		Radius:    radius,
-		Category:  "general",
+		Category:  LocationCategoryOther,
 		CreatedAt: now,
 		UpdatedAt: now,
 		Metadata:  json.RawMessage(`{}`),
@@ -90,11 +91,6 @@ func (l *Location) SetRadius(radius int) error {
 	return nil
 }
 
-func (l *Location) SetCategory(category string) {
-	l.Category = category
-	l.UpdatedAt = time.Now()
-}
-
 func (l *Location) SetPlaceID(placeID string) {
 	l.PlaceID = &placeID
 	l.UpdatedAt = time.Now()
@@ -105,6 +101,16 @@ func (l *Location) ClearPlaceID() {
 	l.UpdatedAt = time.Now()
 }
 
+// SetOpenHours replaces the location's hours; nil makes it always open
+func (l *Location) SetOpenHours(hours OpenHours) error {
+	if err := hours.Validate(); err != nil {
+		return err
+	}
+	l.OpenHours = hours
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
 func (l *Location) DistanceFrom(latitude, longitude float64) float64 {
 	return haversineDistance(l.Latitude, l.Longitude, latitude, longitude)
 }
@@ -135,6 +141,14 @@ func (l *Location) Validate() error {
 		return err
 	}
 
+	if err := l.validateCategory(); err != nil {
+		return err
+	}
+
+	if err := l.OpenHours.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LocationCategory is the kind of place a location is
+type LocationCategory string
+
+const (
+	LocationCategoryHome   LocationCategory = "home"
+	LocationCategoryWork   LocationCategory = "work"
+	LocationCategoryErrand LocationCategory = "errand"
+	LocationCategoryGym    LocationCategory = "gym"
+	LocationCategorySchool LocationCategory = "school"
+	LocationCategoryOther  LocationCategory = "other"
+)
+
+// LocationCategories lists every known category
+var LocationCategories = []LocationCategory{
+	LocationCategoryHome,
+	LocationCategoryWork,
+	LocationCategoryErrand,
+	LocationCategoryGym,
+	LocationCategorySchool,
+	LocationCategoryOther,
+}
+
+// CustomCategoryKey is the location metadata key marking a category outside
+// LocationCategories as intended, so Validate accepts it
+const CustomCategoryKey = "custom_category"
+
+var locationCategoryIcons = map[LocationCategory]string{
+	LocationCategoryHome:   "🏠",
+	LocationCategoryWork:   "💼",
+	LocationCategoryErrand: "🛒",
+	LocationCategoryGym:    "🏋️",
+	LocationCategorySchool: "🏫",
+	LocationCategoryOther:  "📍",
+}
+
+// ParseLocationCategory reads a category name, ignoring case and
+// surrounding space. Empty is other.
+func ParseLocationCategory(name string) (LocationCategory, error) {
+	category := LocationCategory(strings.ToLower(strings.TrimSpace(name)))
+	if category == "" {
+		return LocationCategoryOther, nil
+	}
+	if !category.IsKnown() {
+		return "", fmt.Errorf("unknown location category %q (must be one of %s)", name, locationCategoryNames())
+	}
+	return category, nil
+}
+
+// IsKnown reports whether the category is one of LocationCategories
+func (c LocationCategory) IsKnown() bool {
+	_, ok := locationCategoryIcons[c]
+	return ok
+}
+
+// Icon returns the category's display icon. Custom categories, and
+// locations with none, get other's.
+func (c LocationCategory) Icon() string {
+	if icon, ok := locationCategoryIcons[c]; ok {
+		return icon
+	}
+	return locationCategoryIcons[LocationCategoryOther]
+}
+
+// CategoryForSocialContext returns the category of place a social context
+// suggests: work at work and home with family. The others suggest none.
+func CategoryForSocialContext(socialContext string) (LocationCategory, bool) {
+	switch socialContext {
+	case SocialContextAtWork:
+		return LocationCategoryWork, true
+	case SocialContextWithFamily:
+		return LocationCategoryHome, true
+	default:
+		return "", false
+	}
+}
+
+// SetCategory files the location under one of LocationCategories
+func (l *Location) SetCategory(category LocationCategory) error {
+	if !category.IsKnown() {
+		return fmt.Errorf("unknown location category %q (must be one of %s)", category, locationCategoryNames())
+	}
+	if err := l.setCustomCategory(false); err != nil {
+		return err
+	}
+	l.Category = category
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetCustomCategory files the location under any non-empty category, such
+// as one used before categories were fixed. The choice is recorded in the
+// metadata so Validate accepts it.
+func (l *Location) SetCustomCategory(name string) error {
+	category := LocationCategory(strings.ToLower(strings.TrimSpace(name)))
+	if category == "" {
+		return fmt.Errorf("category is required")
+	}
+	if category.IsKnown() {
+		return l.SetCategory(category)
+	}
+	if err := l.setCustomCategory(true); err != nil {
+		return err
+	}
+	l.Category = category
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+// HasCustomCategory reports whether the location's category was set with
+// SetCustomCategory
+func (l *Location) HasCustomCategory() bool {
+	var metadata struct {
+		Custom bool `json:"custom_category"`
+	}
+	if len(l.Metadata) > 0 {
+		_ = json.Unmarshal(l.Metadata, &metadata)
+	}
+	return metadata.Custom
+}
+
+// validateCategory accepts known categories, custom ones the location was
+// given on purpose, and no category, which counts as other
+func (l *Location) validateCategory() error {
+	if l.Category == "" || l.Category.IsKnown() || l.HasCustomCategory() {
+		return nil
+	}
+	return fmt.Errorf("unknown location category %q (must be one of %s)", l.Category, locationCategoryNames())
+}
+
+// setCustomCategory records or clears the custom category mark in the
+// location's metadata, keeping its other keys
+func (l *Location) setCustomCategory(custom bool) error {
+	metadata := map[string]json.RawMessage{}
+	if len(l.Metadata) > 0 {
+		if err := json.Unmarshal(l.Metadata, &metadata); err != nil {
+			return fmt.Errorf("invalid location metadata: %w", err)
+		}
+	}
+
+	if custom {
+		metadata[CustomCategoryKey] = json.RawMessage("true")
+	} else {
+		if _, ok := metadata[CustomCategoryKey]; !ok {
+			return nil
+		}
+		delete(metadata, CustomCategoryKey)
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	l.Metadata = data
+	return nil
+}
+
+func locationCategoryNames() string {
+	names := make([]string, len(LocationCategories))
+	for i, category := range LocationCategories {
+		names[i] = string(category)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const DefaultLocationRadius = 100
+
+// CategoryRadius is the default geofence size for a location category,
+// expressed in Unit (m, km, ft or mi; meters when empty). Hours, in the
+// form ParseOpenHours reads, are the opening hours new locations in the
+// category get.
+type CategoryRadius struct {
+	Radius float64 `yaml:"radius" json:"radius"`
+	Unit   string  `yaml:"unit" json:"unit"`
+	Hours  string  `yaml:"hours" json:"hours,omitempty"`
+}
+
+// LocationDefaults holds the radius used when a location is added without an
+// explicit one. Categories override the global Radius; they are keyed by
+// LocationCategory name, or by a custom category's name.
+type LocationDefaults struct {
+	Radius     int                       `yaml:"default_radius" json:"default_radius"`
+	Categories map[string]CategoryRadius `yaml:"categories" json:"categories"`
+}
+
+func DefaultLocationDefaults() LocationDefaults {
+	return LocationDefaults{
+		Radius: DefaultLocationRadius,
+		Categories: map[string]CategoryRadius{
+			string(LocationCategoryErrand): {Radius: 200, Unit: "m"},
+			string(LocationCategoryGym):    {Radius: 150, Unit: "m"},
+			string(LocationCategorySchool): {Radius: 300, Unit: "m"},
+		},
+	}
+}
+
+// ResolveRadius returns the radius in meters for a new location. An explicit
+// radius always wins, then the category default, then the global default.
+func (d LocationDefaults) ResolveRadius(category string, explicit *int) int {
+	if explicit != nil {
+		return *explicit
+	}
+	return d.RadiusFor(category)
+}
+
+// RadiusFor returns the default radius in meters for a category, falling back
+// to the global default for unknown or misconfigured categories.
+func (d LocationDefaults) RadiusFor(category string) int {
+	if c, ok := d.Categories[categoryKey(category)]; ok && c.Radius > 0 {
+		if meters, err := RadiusToMeters(c.Radius, c.Unit); err == nil {
+			return meters
+		}
+	}
+
+	if d.Radius > 0 {
+		return d.Radius
+	}
+	return DefaultLocationRadius
+}
+
+// HoursFor returns the default opening hours for a category, or nil (always
+// open) for categories without valid hours
+func (d LocationDefaults) HoursFor(category string) OpenHours {
+	c, ok := d.Categories[categoryKey(category)]
+	if !ok {
+		return nil
+	}
+	hours, err := ParseOpenHours(c.Hours)
+	if err != nil {
+		return nil
+	}
+	return hours
+}
+
+// categoryKey returns the Categories key for a category name, read the way
+// ParseLocationCategory reads it: no category is other
+func categoryKey(category string) string {
+	key := strings.ToLower(strings.TrimSpace(category))
+	if key == "" {
+		return string(LocationCategoryOther)
+	}
+	return key
+}
+
+func (d LocationDefaults) Validate() error {
+	if d.Radius != 0 {
+		if err := validateRadius(d.Radius); err != nil {
+			return fmt.Errorf("invalid default radius: %w", err)
+		}
+	}
+
+	for category, c := range d.Categories {
+		if _, err := ParseOpenHours(c.Hours); err != nil {
+			return fmt.Errorf("invalid hours for category %s: %w", category, err)
+		}
+		// A category may set only hours, keeping the default radius
+		if c.Radius == 0 && c.Hours != "" {
+			continue
+		}
+
+		meters, err := RadiusToMeters(c.Radius, c.Unit)
+		if err != nil {
+			return fmt.Errorf("invalid radius for category %s: %w", category, err)
+		}
+		if err := validateRadius(meters); err != nil {
+			return fmt.Errorf("invalid radius for category %s: %w", category, err)
+		}
+	}
+
+	return nil
+}
+
+// RadiusToMeters converts a radius in the given unit to whole meters.
+func RadiusToMeters(value float64, unit string) (int, error) {
+	var factor float64
+	switch strings.ToLower(unit) {
+	case "", "m", "meters":
+		factor = 1
+	case "km", "kilometers":
+		factor = 1000
+	case "ft", "feet":
+		factor = 0.3048
+	case "mi", "miles":
+		factor = 1609.344
+	default:
+		return 0, fmt.Errorf("unknown radius unit: %s", unit)
+	}
+
+	return int(math.Round(value * factor)), nil
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        string           `db:"id" json:"id"`
+	UserID    string           `db:"user_id" json:"user_id"`
+	Type      NotificationType `db:"type" json:"type"`
+	TaskID    *string          `db:"task_id" json:"task_id"`
+	Message   string           `db:"message" json:"message"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+	ReadAt    *time.Time       `db:"read_at" json:"read_at"`
+	// DeliveredAt is when the notification was pushed to the user's
+	// notification channels
+	DeliveredAt *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+	// DeliveryAttempts counts the delivery passes that have failed
+	DeliveryAttempts int `db:"delivery_attempts" json:"-"`
+}
+
+type NotificationType string
+
+const (
+	NotificationTypeTaskAssigned     NotificationType = "task_assigned"
+	NotificationTypeLocationReminder NotificationType = "location_reminder"
+	NotificationTypeTaskAvailable    NotificationType = "task_available"
+	NotificationTypeListArchived     NotificationType = "list_archived"
+	NotificationTypeTaskCompleted    NotificationType = "task_completed"
+	NotificationTypeTaskReminder     NotificationType = "task_reminder"
+	// NotificationTypeTest is a ping sent to check a user's notification
+	// channels. It is never stored.
+	NotificationTypeTest NotificationType = "test"
+)
+
+func NewNotification(userID string, notificationType NotificationType, message string) (*Notification, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	if message == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+
+	if !isValidNotificationType(notificationType) {
+		return nil, fmt.Errorf("invalid notification type: %s", notificationType)
+	}
+
+	return &Notification{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Type:      notificationType,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// NewTaskNotification creates a notification about a specific task
+func NewTaskNotification(userID string, notificationType NotificationType, taskID, message string) (*Notification, error) {
+	notification, err := NewNotification(userID, notificationType, message)
+	if err != nil {
+		return nil, err
+	}
+
+	if taskID == "" {
+		return nil, fmt.Errorf("task ID is required")
+	}
+
+	notification.TaskID = &taskID
+	return notification, nil
+}
+
+func (n *Notification) MarkRead() {
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+	}
+}
+
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+func (n *Notification) Validate() error {
+	if n.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	if n.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+
+	if !isValidNotificationType(n.Type) {
+		return fmt.Errorf("invalid notification type: %s", n.Type)
+	}
+
+	return nil
+}
+
+func isValidNotificationType(notificationType NotificationType) bool {
+	switch notificationType {
+	case NotificationTypeTaskAssigned, NotificationTypeLocationReminder, NotificationTypeTaskAvailable,
+		NotificationTypeListArchived, NotificationTypeTaskCompleted, NotificationTypeTaskReminder:
+		return true
+	default:
+		return false
+	}
+}
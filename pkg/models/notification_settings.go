@@ -0,0 +1,67 @@
+package models
+
+import (
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// DefaultNtfyServer is where ntfy topics are published when neither the
+// user nor the server names another ntfy server
+const DefaultNtfyServer = "https://ntfy.sh"
+
+var ntfyTopicRegex = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// NotificationSettings are where a user's notifications are pushed besides
+// the app. An empty channel is turned off.
+type NotificationSettings struct {
+	UserID string `db:"user_id" json:"user_id"`
+	// WebhookURL receives each notification as a JSON POST
+	WebhookURL string `db:"webhook_url" json:"webhook_url"`
+	// NtfyTopic is the ntfy topic notifications are published to, on
+	// NtfyServer or the default server when that is empty
+	NtfyTopic  string `db:"ntfy_topic" json:"ntfy_topic"`
+	NtfyServer string `db:"ntfy_server" json:"ntfy_server"`
+	// EmailNotificationsEnabled emails each notification to the user's
+	// address, when the server has a mail server configured
+	EmailNotificationsEnabled bool      `db:"email_notifications_enabled" json:"email_notifications_enabled"`
+	CreatedAt                 time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt                 time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// NewNotificationSettings returns settings with every channel turned off
+func NewNotificationSettings(userID string) *NotificationSettings {
+	now := time.Now()
+	return &NotificationSettings{
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// HasChannels reports whether any channel is turned on
+func (s *NotificationSettings) HasChannels() bool {
+	return s.WebhookURL != "" || s.NtfyTopic != "" || s.EmailNotificationsEnabled
+}
+
+func (s *NotificationSettings) Validate() error {
+	errs := &ValidationError{}
+	if s.UserID == "" {
+		errs.Add("user_id", "is required")
+	}
+	if s.WebhookURL != "" && !isHTTPURL(s.WebhookURL) {
+		errs.Add("webhook_url", "must be an http or https URL")
+	}
+	if s.NtfyTopic != "" && !ntfyTopicRegex.MatchString(s.NtfyTopic) {
+		errs.Add("ntfy_topic", "must be 1 to 64 letters, digits, dashes or underscores")
+	}
+	if s.NtfyServer != "" && !isHTTPURL(s.NtfyServer) {
+		errs.Add("ntfy_server", "must be an http or https URL")
+	}
+	return errs.Err()
+}
+
+func isHTTPURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OpenHours is when a location is open, as wall-clock ranges for each
+// weekday (keyed "mon" to "sun") in the user's timezone. Days without ranges
+// are closed, and a location without any hours is always open. A range that
+// closes before it opens runs past midnight into the next day.
+type OpenHours map[string][]OpenRange
+
+// OpenRange is one opening, as HH:MM times. Close may be "24:00" for a
+// range open until midnight.
+type OpenRange struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// ParseOpenHours reads hours written as "Mon-Sat 08:00-21:00", with
+// further days separated by semicolons and further ranges by commas:
+// "Mon-Fri 07:00-12:00,13:00-19:00; Sat,Sun 09:00-17:00". "Daily" covers
+// every day and a range such as "22:00-02:00" runs past midnight. An empty
+// spec gives no hours, which is always open.
+func ParseOpenHours(spec string) (OpenHours, error) {
+	hours := OpenHours{}
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid open hours %q (want e.g. \"Mon-Sat 08:00-21:00\")", part)
+		}
+
+		days, err := parseOpenDays(fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		var ranges []OpenRange
+		for _, r := range strings.Split(fields[1], ",") {
+			open, close, ok := strings.Cut(r, "-")
+			if !ok {
+				return nil, fmt.Errorf("invalid open hours range %q (want HH:MM-HH:MM)", r)
+			}
+			ranges = append(ranges, OpenRange{Open: open, Close: close})
+		}
+
+		for _, day := range days {
+			key := weekdayKey(day)
+			hours[key] = append(hours[key], ranges...)
+		}
+	}
+
+	if err := hours.Validate(); err != nil {
+		return nil, err
+	}
+	if len(hours) == 0 {
+		return nil, nil
+	}
+	return hours, nil
+}
+
+// parseOpenDays reads "daily", a day, a range of days such as "Mon-Fri" or
+// "Fri-Mon", or a comma-separated list of those
+func parseOpenDays(spec string) ([]time.Weekday, error) {
+	if strings.EqualFold(spec, "daily") {
+		return []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}, nil
+	}
+
+	var days []time.Weekday
+	for _, part := range strings.Split(spec, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := parseWeekday(first)
+		if err != nil {
+			return nil, err
+		}
+		if !isRange {
+			days = append(days, from)
+			continue
+		}
+
+		to, err := parseWeekday(last)
+		if err != nil {
+			return nil, err
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// Validate checks every day and range. Nil or empty hours are valid.
+func (h OpenHours) Validate() error {
+	for day, ranges := range h {
+		if _, err := parseWeekday(day); err != nil {
+			return err
+		}
+		for _, r := range ranges {
+			open, err := parseClockMinutes(r.Open)
+			if err != nil {
+				return fmt.Errorf("invalid opening time %q on %s (want HH:MM)", r.Open, day)
+			}
+			if open == minutesPerDay {
+				return fmt.Errorf("invalid opening time %q on %s (want HH:MM)", r.Open, day)
+			}
+			close, err := parseClockMinutes(r.Close)
+			if err != nil {
+				return fmt.Errorf("invalid closing time %q on %s (want HH:MM)", r.Close, day)
+			}
+			if open == close {
+				return fmt.Errorf("opening and closing times on %s must differ", day)
+			}
+		}
+	}
+	return nil
+}
+
+// IsSet reports whether any hours are given. Locations without hours are
+// always open.
+func (h OpenHours) IsSet() bool {
+	for _, ranges := range h {
+		if len(ranges) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether t, in the timezone it carries, falls within the
+// hours. Hours that are not set are always open.
+func (h OpenHours) IsOpen(t time.Time) bool {
+	if !h.IsSet() {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	for _, r := range h.ranges(day) {
+		open, close := r.minutes()
+		if open < close {
+			if minute >= open && minute < close {
+				return true
+			}
+		} else if minute >= open {
+			return true
+		}
+	}
+
+	// Overnight ranges from the day before run into the early hours
+	for _, r := range h.ranges((day + 6) % 7) {
+		if open, close := r.minutes(); close <= open && minute < close {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns when the hours next open after t, in t's timezone, or
+// false when they never do
+func (h OpenHours) NextOpening(t time.Time) (time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	var next time.Time
+	for offset := 0; offset <= 7; offset++ {
+		date := midnight.AddDate(0, 0, offset)
+		for _, r := range h.ranges(date.Weekday()) {
+			open, _ := r.minutes()
+			at := time.Date(date.Year(), date.Month(), date.Day(), open/60, open%60, 0, 0, t.Location())
+			if at.After(t) && (next.IsZero() || at.Before(next)) {
+				next = at
+			}
+		}
+		if !next.IsZero() {
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// String writes the hours in the form ParseOpenHours reads, with runs of
+// days that share their ranges grouped: "Mon-Sat 08:00-21:00"
+func (h OpenHours) String() string {
+	order := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+	var parts []string
+	for i := 0; i < len(order); {
+		ranges := formatOpenRanges(h.ranges(order[i]))
+		j := i + 1
+		for j < len(order) && formatOpenRanges(h.ranges(order[j])) == ranges {
+			j++
+		}
+		if ranges != "" {
+			days := order[i].String()[:3]
+			if j-i > 1 {
+				days += "-" + order[j-1].String()[:3]
+			}
+			parts = append(parts, days+" "+ranges)
+		}
+		i = j
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatOpenRanges(ranges []OpenRange) string {
+	formatted := make([]string, len(ranges))
+	for i, r := range ranges {
+		formatted[i] = r.Open + "-" + r.Close
+	}
+	return strings.Join(formatted, ",")
+}
+
+// ranges returns the ranges opening on day, whichever weekday name keys them
+func (h OpenHours) ranges(day time.Weekday) []OpenRange {
+	var ranges []OpenRange
+	for name, dayRanges := range h {
+		if d, err := parseWeekday(name); err == nil && d == day {
+			ranges = append(ranges, dayRanges...)
+		}
+	}
+	return ranges
+}
+
+// minutes returns the range's opening and closing minute of the day
+func (r OpenRange) minutes() (open, close int) {
+	open, _ = parseClockMinutes(r.Open)
+	close, _ = parseClockMinutes(r.Close)
+	if close == minutesPerDay {
+		close = 0
+	}
+	return open, close
+}
+
+const minutesPerDay = 24 * 60
+
+// parseClockMinutes reads HH:MM as minutes after midnight, allowing "24:00"
+// for the end of the day
+func parseClockMinutes(clock string) (int, error) {
+	if clock == "24:00" {
+		return minutesPerDay, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// weekdayKey is the key OpenHours uses for day
+func weekdayKey(day time.Weekday) string {
+	return strings.ToLower(day.String()[:3])
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PasswordResetToken lets a user who has forgotten their password set a new
+// one. Only a hash of the token is stored; the token itself is delivered to
+// the user once and cannot be recovered.
+type PasswordResetToken struct {
+	ID        string     `db:"id" json:"id"`
+	UserID    string     `db:"user_id" json:"user_id"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at"`
+}
+
+// IsUsable reports whether the token can still reset a password at now:
+// it has not been used and has not expired
+func (t PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
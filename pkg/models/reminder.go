@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reminder is a time at which the user is notified about a task. Sent is
+// set once a scheduler has claimed it, so it is sent at most once.
+type Reminder struct {
+	ID        string     `db:"id" json:"id"`
+	TaskID    string     `db:"task_id" json:"task_id"`
+	UserID    string     `db:"user_id" json:"user_id"`
+	RemindAt  time.Time  `db:"remind_at" json:"remind_at"`
+	Sent      bool       `db:"sent" json:"sent"`
+	SentAt    *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// reminderBeforeDue ends a reminder spec relative to the task's due date
+const reminderBeforeDue = " before due"
+
+// reminderTimeFormats are the absolute times a reminder spec can give
+var reminderTimeFormats = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"01/02/2006 15:04",
+	time.RFC3339,
+}
+
+// NewReminder returns an unsent reminder for the user about the task at
+// remindAt
+func NewReminder(taskID, userID string, remindAt time.Time) (*Reminder, error) {
+	reminder := &Reminder{
+		ID:        uuid.New().String(),
+		TaskID:    taskID,
+		UserID:    userID,
+		RemindAt:  remindAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := reminder.Validate(); err != nil {
+		return nil, err
+	}
+
+	return reminder, nil
+}
+
+func (r *Reminder) Validate() error {
+	if r.TaskID == "" {
+		return fmt.Errorf("task ID is required")
+	}
+
+	if r.UserID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+
+	if r.RemindAt.IsZero() {
+		return fmt.Errorf("reminder time is required")
+	}
+
+	return nil
+}
+
+// IsDue reports whether the reminder is unsent and its time has come
+func (r *Reminder) IsDue(now time.Time) bool {
+	return !r.Sent && !r.RemindAt.After(now)
+}
+
+// ParseRemindAt resolves a reminder spec to the time it fires. The spec is
+// either relative to the task's due date, such as "30m before due" or "1d
+// before due", or an absolute time such as "2026-03-02 09:00", read in loc.
+func ParseRemindAt(spec string, dueAt *time.Time, loc *time.Location) (time.Time, error) {
+	spec = strings.TrimSpace(spec)
+
+	if offset, ok := strings.CutSuffix(spec, reminderBeforeDue); ok {
+		if dueAt == nil {
+			return time.Time{}, fmt.Errorf("reminder %q needs the task to have a due date", spec)
+		}
+		before, err := ParseSnoozeDuration(offset)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return dueAt.Add(-before), nil
+	}
+
+	for _, format := range reminderTimeFormats {
+		if at, err := time.ParseInLocation(format, spec, loc); err == nil {
+			return at, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid reminder %q (use e.g. \"30m before due\" or \"2006-01-02 15:04\")", spec)
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnoozePreset describes a named snooze time relative to now in the user's
+// timezone. After gives a plain duration ("3h"); otherwise the snooze lands
+// on At (HH:MM, default 09:00) either Days ahead or on the next Weekday.
+// A calendar preset that would resolve to the past rolls over to the next day.
+type SnoozePreset struct {
+	After   string `yaml:"after,omitempty" json:"after,omitempty"`
+	Days    int    `yaml:"days,omitempty" json:"days,omitempty"`
+	Weekday string `yaml:"weekday,omitempty" json:"weekday,omitempty"`
+	At      string `yaml:"at,omitempty" json:"at,omitempty"`
+}
+
+// SnoozePresets maps preset names to their definitions
+type SnoozePresets map[string]SnoozePreset
+
+const defaultSnoozeTime = "09:00"
+
+func DefaultSnoozePresets() SnoozePresets {
+	return SnoozePresets{
+		"later-today":      {After: "3h"},
+		"this-evening":     {At: "18:00"},
+		"tomorrow-morning": {Days: 1, At: "09:00"},
+		"next-week":        {Weekday: "monday", At: "09:00"},
+	}
+}
+
+// Resolve returns the time the named preset refers to
+func (p SnoozePresets) Resolve(name string, now time.Time, loc *time.Location) (time.Time, error) {
+	preset, ok := p[strings.ToLower(name)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown snooze preset: %s (available: %s)", name, strings.Join(p.Names(), ", "))
+	}
+	return preset.Resolve(now, loc)
+}
+
+// Names returns the preset names in alphabetical order
+func (p SnoozePresets) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p SnoozePresets) Validate() error {
+	for name, preset := range p {
+		if _, err := preset.Resolve(time.Now(), time.UTC); err != nil {
+			return fmt.Errorf("invalid snooze preset %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the snooze time for now in loc. A nil loc uses now's location.
+func (p SnoozePreset) Resolve(now time.Time, loc *time.Location) (time.Time, error) {
+	if loc != nil {
+		now = now.In(loc)
+	}
+
+	if p.After != "" {
+		after, err := time.ParseDuration(p.After)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid duration %q: %w", p.After, err)
+		}
+		if after <= 0 {
+			return time.Time{}, fmt.Errorf("duration must be positive: %s", p.After)
+		}
+		return now.Add(after), nil
+	}
+
+	at := p.At
+	if at == "" {
+		at = defaultSnoozeTime
+	}
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day %q (want HH:MM)", at)
+	}
+
+	if p.Days < 0 {
+		return time.Time{}, fmt.Errorf("days cannot be negative")
+	}
+
+	days := p.Days
+	if p.Weekday != "" {
+		weekday, err := parseWeekday(p.Weekday)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Always the next occurrence, never today
+		days = int(weekday-now.Weekday()+7) % 7
+		if days == 0 {
+			days = 7
+		}
+	}
+
+	// time.Date normalizes day overflow, so month and year boundaries and
+	// DST changes resolve to the intended local wall-clock time
+	resolved := time.Date(now.Year(), now.Month(), now.Day()+days, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !resolved.After(now) {
+		resolved = time.Date(now.Year(), now.Month(), now.Day()+days+1, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	}
+
+	return resolved, nil
+}
+
+// ParseSnoozeDuration parses a relative snooze such as "3d", "2w" or "1d12h".
+// Leading day and week components are added to whatever time.ParseDuration
+// accepts; the total must be positive.
+func ParseSnoozeDuration(value string) (time.Duration, error) {
+	rest := strings.TrimSpace(value)
+	var total time.Duration
+
+	for rest != "" {
+		digits := 0
+		for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits++
+		}
+		if digits == 0 || digits == len(rest) {
+			break
+		}
+		var unit time.Duration
+		switch rest[digits] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		}
+		if unit == 0 {
+			break
+		}
+		n, err := strconv.Atoi(rest[:digits])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		total += time.Duration(n) * unit
+		rest = rest[digits+1:]
+	}
+
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q (use e.g. 45m, 3h, 3d or 2w)", value)
+		}
+		total += d
+	}
+
+	if total <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %s", value)
+	}
+	return total, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if strings.EqualFold(name, full) || strings.EqualFold(name, full[:3]) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid weekday: %s", name)
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +20,10 @@ type Task struct {
 	Status           TaskStatus      `db:"status" json:"status"`
 	Priority         int             `db:"priority" json:"priority"`
 	EstimatedMinutes *int            `db:"estimated_minutes" json:"estimated_minutes"`
+	EffortPoints     *int            `db:"effort_points" json:"effort_points,omitempty"`
+	// RequiredEnergyLevel is the energy (1-5) the task takes; the energy
+	// filter hides it from contexts with less. Nil means any energy will do.
+	RequiredEnergyLevel *int `db:"required_energy_level" json:"required_energy_level,omitempty"`
 	DueAt            *time.Time      `db:"due_at" json:"due_at"`
 	CompletedAt      *time.Time      `db:"completed_at" json:"completed_at"`
 	CreatedAt        time.Time       `db:"created_at" json:"created_at"`
@@ -25,8 +31,22 @@ type Task struct {
 	Metadata         json.RawMessage `db:"metadata" json:"metadata"`
 	RecurrenceRule   *string         `db:"recurrence_rule" json:"recurrence_rule"`
 	ParentTaskID     *string         `db:"parent_task_id" json:"parent_task_id"`
+	Position         float64         `db:"position" json:"position"`
+	SnoozedUntil     *time.Time      `db:"snoozed_until" json:"snoozed_until"`
+	RecurringSnooze  *string         `db:"recurring_snooze" json:"recurring_snooze"`
+	DeletedAt        *time.Time      `db:"deleted_at" json:"deleted_at,omitempty"`
+	// Tags are the task's lowercase labels, kept in task_tags rather than
+	// a column of their own
+	Tags []string `db:"-" json:"tags,omitempty"`
+	// Version counts the task's saved changes. Repositories only update a
+	// task whose Version is still the stored one, and store it one higher.
+	Version int `db:"version" json:"version"`
 }
 
+// ErrTaskVersionConflict is returned when a task is updated from a version
+// that someone else has changed since it was read
+var ErrTaskVersionConflict = errors.New("task was changed since it was read")
+
 type TaskStatus string
 
 const (
@@ -37,6 +57,14 @@ const (
 	TaskStatusBlocked   TaskStatus = "blocked"
 )
 
+// TaskPositionGap is the spacing between neighbouring tasks in a list. Moves
+// land halfway between neighbours, so a list only needs renumbering once the
+// gap between two tasks has been halved down to minTaskPositionGap.
+const (
+	TaskPositionGap    = 1024.0
+	minTaskPositionGap = 1e-6
+)
+
 func NewTask(title, description, creatorID string) (*Task, error) {
 	if err := validateTitle(title); err != nil {
 		return nil, err
@@ -53,6 +81,7 @@ func NewTask(title, description, creatorID string) (*Task, error) {
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		Metadata:    json.RawMessage(`{}`),
+		Version:     1,
 	}, nil
 }
 
@@ -92,6 +121,24 @@ func (t *Task) SetEstimatedMinutes(minutes int) error {
 	return nil
 }
 
+func (t *Task) SetEffortPoints(points int) error {
+	if points <= 0 {
+		return fmt.Errorf("effort points must be positive")
+	}
+	t.EffortPoints = &points
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
+func (t *Task) SetRequiredEnergyLevel(level int) error {
+	if level < 1 || level > 5 {
+		return fmt.Errorf("required energy level must be between 1 and 5")
+	}
+	t.RequiredEnergyLevel = &level
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
 func (t *Task) Assign(userID string) error {
 	t.AssigneeID = &userID
 	t.UpdatedAt = time.Now()
@@ -113,8 +160,38 @@ func (t *Task) ClearDueDate() {
 	t.UpdatedAt = time.Now()
 }
 
+func (t *Task) SetPosition(position float64) {
+	t.Position = position
+	t.UpdatedAt = time.Now()
+}
+
+// Snooze hides the task until the given time. Completed and cancelled tasks
+// cannot be snoozed.
+func (t *Task) Snooze(until time.Time) error {
+	if t.Status == TaskStatusCompleted || t.Status == TaskStatusCancelled {
+		return fmt.Errorf("cannot snooze a %s task", t.Status)
+	}
+	t.SnoozedUntil = &until
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
+func (t *Task) Unsnooze() {
+	t.SnoozedUntil = nil
+	t.UpdatedAt = time.Now()
+}
+
+func (t *Task) IsSnoozed(at time.Time) bool {
+	return t.SnoozedUntil != nil && at.Before(*t.SnoozedUntil)
+}
+
 func (t *Task) IsOverdue() bool {
-	return t.DueAt != nil && t.DueAt.Before(time.Now()) && t.Status != TaskStatusCompleted
+	return t.IsOverdueAt(time.Now())
+}
+
+// IsOverdueAt reports whether the task is past due at the given time
+func (t *Task) IsOverdueAt(at time.Time) bool {
+	return t.DueAt != nil && t.DueAt.Before(at) && t.Status != TaskStatusCompleted
 }
 
 func (t *Task) IsCompleted() bool {
@@ -129,28 +206,42 @@ func (t *Task) IsActive() bool {
 	return t.Status == TaskStatusActive || t.Status == TaskStatusPending
 }
 
+// Validate checks every field and returns a *ValidationError listing all
+// the invalid ones
 func (t *Task) Validate() error {
-	if err := validateTitle(t.Title); err != nil {
-		return err
+	errs := &ValidationError{}
+
+	if len(t.Title) == 0 {
+		errs.Add("title", "required")
+	} else if len(t.Title) > maxTitleLength {
+		errs.Add("title", fmt.Sprintf("must not exceed %d characters", maxTitleLength))
 	}
 
 	if t.CreatorID == "" {
-		return fmt.Errorf("creator ID is required")
+		errs.Add("creator_id", "required")
 	}
 
 	if t.Priority < 1 || t.Priority > 5 {
-		return fmt.Errorf("priority must be between 1 and 5")
+		errs.Add("priority", "must be between 1 and 5")
 	}
 
 	if t.EstimatedMinutes != nil && *t.EstimatedMinutes <= 0 {
-		return fmt.Errorf("estimated minutes must be positive")
+		errs.Add("estimated_minutes", "must be positive")
+	}
+
+	if t.EffortPoints != nil && *t.EffortPoints <= 0 {
+		errs.Add("effort_points", "must be positive")
+	}
+
+	if t.RequiredEnergyLevel != nil && (*t.RequiredEnergyLevel < 1 || *t.RequiredEnergyLevel > 5) {
+		errs.Add("required_energy_level", "must be between 1 and 5")
 	}
 
 	if !isValidTaskStatus(t.Status) {
-		return fmt.Errorf("invalid task status: %s", t.Status)
+		errs.Add("status", fmt.Sprintf("invalid task status: %s", t.Status))
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func (t *Task) validateStatusTransition(newStatus TaskStatus) error {
@@ -178,16 +269,61 @@ func (t *Task) validateStatusTransition(newStatus TaskStatus) error {
 	return nil
 }
 
+// PositionBetween returns a position between before and after, either of which
+// may be nil to mean the start or end of the list. It returns false when the
+// neighbours are too close together and the list needs renumbering.
+func PositionBetween(before, after *float64) (float64, bool) {
+	switch {
+	case before == nil && after == nil:
+		return TaskPositionGap, true
+	case before == nil:
+		return *after - TaskPositionGap, true
+	case after == nil:
+		return *before + TaskPositionGap, true
+	}
+
+	if *after-*before < minTaskPositionGap {
+		return 0, false
+	}
+	return *before + (*after-*before)/2, true
+}
+
+// SortTasksByPosition orders tasks by manual position, falling back to
+// creation time for tasks that share a position.
+func SortTasksByPosition(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Position != tasks[j].Position {
+			return tasks[i].Position < tasks[j].Position
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
+// RebalancePositions renumbers tasks in their current slice order, evenly
+// spaced by TaskPositionGap.
+func RebalancePositions(tasks []Task) {
+	for i := range tasks {
+		tasks[i].SetPosition(float64(i+1) * TaskPositionGap)
+	}
+}
+
+const maxTitleLength = 500
+
 func validateTitle(title string) error {
 	if len(title) == 0 {
 		return fmt.Errorf("title is required")
 	}
-	if len(title) > 500 {
-		return fmt.Errorf("title must not exceed 500 characters")
+	if len(title) > maxTitleLength {
+		return fmt.Errorf("title must not exceed %d characters", maxTitleLength)
 	}
 	return nil
 }
 
+// IsValid reports whether s is a known task status
+func (s TaskStatus) IsValid() bool {
+	return isValidTaskStatus(s)
+}
+
 func isValidTaskStatus(status TaskStatus) bool {
 	switch status {
 	case TaskStatusPending, TaskStatusActive, TaskStatusCompleted, TaskStatusCancelled, TaskStatusBlocked:
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskActionType is a task mutation that undo can reverse
+type TaskActionType string
+
+const (
+	TaskActionComplete TaskActionType = "complete"
+	TaskActionDelete   TaskActionType = "delete"
+	TaskActionSnooze   TaskActionType = "snooze"
+)
+
+// TaskAction records a user's most recent reversible task mutation along
+// with the task as it was beforehand. Only one action is kept per user, so
+// undo goes back a single step.
+type TaskAction struct {
+	ID        string          `db:"id" json:"id"`
+	UserID    string          `db:"user_id" json:"user_id"`
+	TaskID    string          `db:"task_id" json:"task_id"`
+	Type      TaskActionType  `db:"action_type" json:"type"`
+	Snapshot  json.RawMessage `db:"snapshot" json:"snapshot"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// TaskSnapshot is a task's state before an action. Deletes also capture the
+// task's locations and dependencies so a restore can put them back.
+type TaskSnapshot struct {
+	Task         Task             `json:"task"`
+	Locations    []TaskLocation   `json:"locations,omitempty"`
+	Dependencies []TaskDependency `json:"dependencies,omitempty"`
+	// SpawnedTaskID is the next occurrence completing a recurring task
+	// created, which undoing the completion removes
+	SpawnedTaskID string `json:"spawned_task_id,omitempty"`
+}
+
+func NewTaskAction(userID string, actionType TaskActionType, snapshot TaskSnapshot) (*TaskAction, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	if !isValidTaskActionType(actionType) {
+		return nil, fmt.Errorf("invalid action type: %s", actionType)
+	}
+
+	if snapshot.Task.ID == "" {
+		return nil, fmt.Errorf("snapshot task ID is required")
+	}
+
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	return &TaskAction{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		TaskID:    snapshot.Task.ID,
+		Type:      actionType,
+		Snapshot:  snapshotJSON,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (a *TaskAction) GetSnapshot() (*TaskSnapshot, error) {
+	var snapshot TaskSnapshot
+	if err := json.Unmarshal(a.Snapshot, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func isValidTaskActionType(actionType TaskActionType) bool {
+	switch actionType {
+	case TaskActionComplete, TaskActionDelete, TaskActionSnooze:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+// TaskAnalytics summarizes a user's task completions over a range of time.
+// Days and hours are UTC. Every day in the range, every hour and every
+// priority is present, with zero counts when nothing happened.
+type TaskAnalytics struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// TasksCreated and TasksCompleted count tasks created and completed in
+	// the range
+	TasksCreated   int `json:"tasks_created"`
+	TasksCompleted int `json:"tasks_completed"`
+	// AverageMinutesToComplete is the mean time from creation to completion
+	// of the tasks completed in the range
+	AverageMinutesToComplete float64               `json:"average_minutes_to_complete"`
+	CompletionsByDay         []DayCompletions      `json:"completions_by_day"`
+	CompletionsByHour        [24]int               `json:"completions_by_hour"`
+	ByPriority               []PriorityCompletions `json:"by_priority"`
+	ByLocation               []LocationCompletions `json:"by_location"`
+}
+
+// DayCompletions counts the tasks completed on one day
+type DayCompletions struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Completed int    `json:"completed"`
+}
+
+// PriorityCompletions is how many of the tasks created in the range at one
+// priority have been completed
+type PriorityCompletions struct {
+	Priority       int     `json:"priority"`
+	Created        int     `json:"created"`
+	Completed      int     `json:"completed"`
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// LocationCompletions counts the tasks completed at a location: the one in
+// the user's context snapshot nearest each completion. An empty LocationID
+// collects completions with no known location.
+type LocationCompletions struct {
+	LocationID string `json:"location_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Completed  int    `json:"completed"`
+}
+
+// NewTaskAnalytics returns zeroed analytics for the range, with a day entry
+// for every UTC day from since to until and an entry for every priority
+func NewTaskAnalytics(since, until time.Time) *TaskAnalytics {
+	analytics := &TaskAnalytics{
+		Since:            since,
+		Until:            until,
+		CompletionsByDay: []DayCompletions{},
+		ByPriority:       make([]PriorityCompletions, 0, 5),
+		ByLocation:       []LocationCompletions{},
+	}
+
+	first := since.UTC().Truncate(24 * time.Hour)
+	for day := first; day.Before(until); day = day.AddDate(0, 0, 1) {
+		analytics.CompletionsByDay = append(analytics.CompletionsByDay, DayCompletions{Date: day.Format("2006-01-02")})
+	}
+	for priority := 1; priority <= 5; priority++ {
+		analytics.ByPriority = append(analytics.ByPriority, PriorityCompletions{Priority: priority})
+	}
+
+	return analytics
+}
+
+// ToMap flattens the analytics into named values for display
+func (a *TaskAnalytics) ToMap() map[string]interface{} {
+	values := map[string]interface{}{
+		"since":                       a.Since.Format("2006-01-02"),
+		"until":                       a.Until.Format("2006-01-02"),
+		"tasks_created":               a.TasksCreated,
+		"tasks_completed":             a.TasksCompleted,
+		"average_minutes_to_complete": a.AverageMinutesToComplete,
+	}
+
+	days := make(map[string]int, len(a.CompletionsByDay))
+	for _, day := range a.CompletionsByDay {
+		days[day.Date] = day.Completed
+	}
+	values["completions_by_day"] = days
+
+	hours := make(map[int]int)
+	for hour, count := range a.CompletionsByHour {
+		if count > 0 {
+			hours[hour] = count
+		}
+	}
+	values["completions_by_hour"] = hours
+
+	priorities := make(map[int]float64, len(a.ByPriority))
+	for _, p := range a.ByPriority {
+		priorities[p.Priority] = p.CompletionRate
+	}
+	values["completion_rate_by_priority"] = priorities
+
+	locations := make(map[string]int, len(a.ByLocation))
+	for _, l := range a.ByLocation {
+		name := l.Name
+		if name == "" {
+			name = "unknown"
+		}
+		locations[name] += l.Completed
+	}
+	values["completions_by_location"] = locations
+
+	return values
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,6 +22,9 @@ const (
 	DependencyTypeBlocking  DependencyType = "blocking"
 	DependencyTypeRelated   DependencyType = "related"
 	DependencyTypeScheduled DependencyType = "scheduled"
+	// DependencyTypeSuggested recommends finishing the other task first
+	// without hiding the task until it is
+	DependencyTypeSuggested DependencyType = "suggested"
 )
 
 func NewTaskDependency(taskID, dependsOnTaskID string, dependencyType DependencyType) (*TaskDependency, error) {
@@ -69,6 +73,10 @@ func (td *TaskDependency) IsScheduled() bool {
 	return td.DependencyType == DependencyTypeScheduled
 }
 
+func (td *TaskDependency) IsSuggested() bool {
+	return td.DependencyType == DependencyTypeSuggested
+}
+
 func (td *TaskDependency) BelongsToTask(taskID string) bool {
 	return td.TaskID == taskID
 }
@@ -107,9 +115,74 @@ func (td *TaskDependency) Validate() error {
 
 func isValidDependencyType(dependencyType DependencyType) bool {
 	switch dependencyType {
-	case DependencyTypeBlocking, DependencyTypeRelated, DependencyTypeScheduled:
+	case DependencyTypeBlocking, DependencyTypeRelated, DependencyTypeScheduled, DependencyTypeSuggested:
 		return true
 	default:
 		return false
 	}
-}
\ No newline at end of file
+}
+
+// DependencyCycleError is returned for a dependency that would make a task
+// wait, through other tasks, on itself. Path is the loop it would close,
+// from the dependent task back to it, naming each task by its title.
+type DependencyCycleError struct {
+	Path []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	names := make([]string, len(e.Path))
+	for i, name := range e.Path {
+		names[i] = fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("dependency would create a cycle: %s", strings.Join(names, " -> "))
+}
+
+// FindDependencyCycle walks the tasks dependency.DependsOnTaskID already
+// waits on, through dependenciesOf, looking for dependency.TaskID. It
+// returns the IDs of the shortest loop adding the dependency would close,
+// starting and ending with dependency.TaskID, or nil when there is none.
+func FindDependencyCycle(dependency TaskDependency, dependenciesOf func(taskID string) ([]TaskDependency, error)) ([]string, error) {
+	if dependency.TaskID == dependency.DependsOnTaskID {
+		return []string{dependency.TaskID, dependency.TaskID}, nil
+	}
+
+	// reachedFrom records the task each visited task was reached from
+	reachedFrom := map[string]string{dependency.DependsOnTaskID: dependency.TaskID}
+	queue := []string{dependency.DependsOnTaskID}
+	for len(queue) > 0 {
+		taskID := queue[0]
+		queue = queue[1:]
+
+		dependencies, err := dependenciesOf(taskID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dependencies of task %s: %w", taskID, err)
+		}
+		for _, next := range dependencies {
+			if next.DependsOnTaskID == dependency.TaskID {
+				return dependencyLoop(reachedFrom, taskID, dependency.TaskID), nil
+			}
+			if _, seen := reachedFrom[next.DependsOnTaskID]; !seen {
+				reachedFrom[next.DependsOnTaskID] = taskID
+				queue = append(queue, next.DependsOnTaskID)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// dependencyLoop follows reachedFrom back from last to start and returns the
+// loop start -> ... -> last -> start
+func dependencyLoop(reachedFrom map[string]string, last, start string) []string {
+	path := []string{start, last}
+	for taskID := last; reachedFrom[taskID] != start; {
+		taskID = reachedFrom[taskID]
+		path = append(path, taskID)
+	}
+	path = append(path, start)
+
+	// The tasks between the two ends were collected backwards
+	for i, j := 1, len(path)-2; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
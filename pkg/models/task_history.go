@@ -0,0 +1,124 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskFieldChange is a task field's JSON value before and after an edit
+type TaskFieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// TaskHistoryEntry records one saved edit of a task: who made it, when, and
+// the fields it changed, keyed by their JSON names
+type TaskHistoryEntry struct {
+	ID     string `db:"id" json:"id"`
+	TaskID string `db:"task_id" json:"task_id"`
+	// ActorID is the user who made the change, or nil when the service
+	// made it on its own, such as a parent completed by its subtasks
+	ActorID   *string                    `db:"actor_id" json:"actor_id"`
+	Changes   map[string]TaskFieldChange `db:"changes" json:"changes"`
+	CreatedAt time.Time                  `db:"created_at" json:"created_at"`
+}
+
+// untrackedTaskFields change with every save, so recording them would only
+// repeat the entry's own timestamp
+var untrackedTaskFields = map[string]bool{
+	"updated_at": true,
+	"version":    true,
+}
+
+// DiffTasks returns the fields whose values differ between before and after,
+// leaving out the ones every save changes
+func DiffTasks(before, after Task) (map[string]TaskFieldChange, error) {
+	oldFields, err := taskFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := taskFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]TaskFieldChange{}
+	for name := range mergedKeys(oldFields, newFields) {
+		if untrackedTaskFields[name] {
+			continue
+		}
+		oldValue, newValue := jsonOrNull(oldFields[name]), jsonOrNull(newFields[name])
+		if !bytes.Equal(oldValue, newValue) {
+			changes[name] = TaskFieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes, nil
+}
+
+// NewTaskHistoryEntry records changes made to taskID by actorID, which is
+// empty for changes the service made on its own
+func NewTaskHistoryEntry(taskID, actorID string, changes map[string]TaskFieldChange) (*TaskHistoryEntry, error) {
+	if taskID == "" {
+		return nil, fmt.Errorf("task ID is required")
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("at least one change is required")
+	}
+
+	entry := &TaskHistoryEntry{
+		ID:        uuid.New().String(),
+		TaskID:    taskID,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	}
+	if actorID != "" {
+		entry.ActorID = &actorID
+	}
+	return entry, nil
+}
+
+// ChangedFields returns the names of the changed fields in alphabetical order
+func (e TaskHistoryEntry) ChangedFields() []string {
+	fields := make([]string, 0, len(e.Changes))
+	for name := range e.Changes {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func taskFields(task Task) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task fields: %w", err)
+	}
+	return fields, nil
+}
+
+func mergedKeys(a, b map[string]json.RawMessage) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
+
+// jsonOrNull stands in null for fields left out by omitempty
+func jsonOrNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
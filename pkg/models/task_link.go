@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskLink records a relationship between two tasks that does not affect
+// whether either can be worked on, unlike a TaskDependency. A duplicate link
+// points from the duplicate to the task it duplicates.
+type TaskLink struct {
+	ID           string       `db:"id" json:"id"`
+	TaskID       string       `db:"task_id" json:"task_id"`
+	LinkedTaskID string       `db:"linked_task_id" json:"linked_task_id"`
+	LinkType     TaskLinkType `db:"link_type" json:"link_type"`
+	CreatedAt    time.Time    `db:"created_at" json:"created_at"`
+}
+
+type TaskLinkType string
+
+const (
+	TaskLinkTypeRelated   TaskLinkType = "related"
+	TaskLinkTypeDuplicate TaskLinkType = "duplicate"
+)
+
+// Relations of a linked task as seen from the other end of the link
+const (
+	TaskRelationRelated      = "related"
+	TaskRelationDuplicateOf  = "duplicate-of"
+	TaskRelationDuplicatedBy = "duplicated-by"
+)
+
+func NewTaskLink(taskID, linkedTaskID string, linkType TaskLinkType) (*TaskLink, error) {
+	link := &TaskLink{
+		ID:           uuid.New().String(),
+		TaskID:       taskID,
+		LinkedTaskID: linkedTaskID,
+		LinkType:     linkType,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := link.Validate(); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (l *TaskLink) Validate() error {
+	if l.TaskID == "" {
+		return fmt.Errorf("task ID is required")
+	}
+
+	if l.LinkedTaskID == "" {
+		return fmt.Errorf("linked task ID is required")
+	}
+
+	if l.TaskID == l.LinkedTaskID {
+		return fmt.Errorf("task cannot be linked to itself")
+	}
+
+	if !isValidTaskLinkType(l.LinkType) {
+		return fmt.Errorf("invalid link type: %s", l.LinkType)
+	}
+
+	return nil
+}
+
+func (l *TaskLink) InvolvesTask(taskID string) bool {
+	return l.TaskID == taskID || l.LinkedTaskID == taskID
+}
+
+// OtherTaskID returns the task at the other end of the link from taskID
+func (l *TaskLink) OtherTaskID(taskID string) string {
+	if l.TaskID == taskID {
+		return l.LinkedTaskID
+	}
+	return l.TaskID
+}
+
+// RelationFrom describes how taskID relates to the other task: a duplicate
+// is the "duplicate-of" its original, which is "duplicated-by" it
+func (l *TaskLink) RelationFrom(taskID string) string {
+	if l.LinkType != TaskLinkTypeDuplicate {
+		return TaskRelationRelated
+	}
+	if l.TaskID == taskID {
+		return TaskRelationDuplicateOf
+	}
+	return TaskRelationDuplicatedBy
+}
+
+func isValidTaskLinkType(linkType TaskLinkType) bool {
+	switch linkType {
+	case TaskLinkTypeRelated, TaskLinkTypeDuplicate:
+		return true
+	default:
+		return false
+	}
+}
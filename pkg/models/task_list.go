@@ -22,6 +22,11 @@ type TaskList struct {
 	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at"`
 	Settings    json.RawMessage `db:"settings" json:"settings"`
+	ArchivedAt  *time.Time      `db:"archived_at" json:"archived_at"`
+	// DefaultLocationID and DefaultEstimatedMinutes are given to tasks added
+	// to the list without a location or estimate of their own
+	DefaultLocationID       *string `db:"default_location_id" json:"default_location_id"`
+	DefaultEstimatedMinutes *int    `db:"default_estimated_minutes" json:"default_estimated_minutes"`
 }
 
 var (
@@ -100,6 +105,34 @@ func (tl *TaskList) ClearParent() {
 	tl.UpdatedAt = time.Now()
 }
 
+// SetDefaultLocation makes tasks added to the list without a location
+// require locationID. An empty ID clears the default.
+func (tl *TaskList) SetDefaultLocation(locationID string) {
+	if locationID == "" {
+		tl.DefaultLocationID = nil
+	} else {
+		tl.DefaultLocationID = &locationID
+	}
+	tl.UpdatedAt = time.Now()
+}
+
+// SetDefaultEstimatedMinutes gives tasks added to the list without an
+// estimate this one. Nil clears the default.
+func (tl *TaskList) SetDefaultEstimatedMinutes(minutes *int) error {
+	if minutes != nil && *minutes <= 0 {
+		return fmt.Errorf("default estimated minutes must be positive")
+	}
+	tl.DefaultEstimatedMinutes = minutes
+	tl.UpdatedAt = time.Now()
+	return nil
+}
+
+// HasDefaults reports whether the list gives its tasks a location or
+// estimate
+func (tl *TaskList) HasDefaults() bool {
+	return tl.DefaultLocationID != nil || tl.DefaultEstimatedMinutes != nil
+}
+
 func (tl *TaskList) Share() {
 	tl.IsShared = true
 	tl.UpdatedAt = time.Now()
@@ -110,6 +143,56 @@ func (tl *TaskList) Unshare() {
 	tl.UpdatedAt = time.Now()
 }
 
+// Archive hides the list from everyday views without deleting its tasks
+func (tl *TaskList) Archive() {
+	if tl.ArchivedAt != nil {
+		return
+	}
+	now := time.Now()
+	tl.ArchivedAt = &now
+	tl.UpdatedAt = now
+}
+
+// Unarchive brings the list back. Its tasks were kept as they were.
+func (tl *TaskList) Unarchive() {
+	if tl.ArchivedAt == nil {
+		return
+	}
+	tl.ArchivedAt = nil
+	tl.UpdatedAt = time.Now()
+}
+
+func (tl *TaskList) IsArchived() bool {
+	return tl.ArchivedAt != nil
+}
+
+// ArchivedListIDs returns the IDs of the lists that are archived or sit
+// under an archived parent, following parents among lists
+func ArchivedListIDs(lists []TaskList) map[string]bool {
+	byID := make(map[string]TaskList, len(lists))
+	for _, list := range lists {
+		byID[list.ID] = list
+	}
+
+	archived := make(map[string]bool)
+	for _, list := range lists {
+		seen := make(map[string]bool)
+		current, ok := list, true
+		for ok && !seen[current.ID] {
+			seen[current.ID] = true
+			if current.IsArchived() {
+				archived[list.ID] = true
+				break
+			}
+			if current.ParentID == nil {
+				break
+			}
+			current, ok = byID[*current.ParentID]
+		}
+	}
+	return archived
+}
+
 func (tl *TaskList) IsOwnedBy(userID string) bool {
 	return tl.OwnerID == userID
 }
@@ -139,6 +222,10 @@ func (tl *TaskList) Validate() error {
 		return fmt.Errorf("task list cannot be its own parent")
 	}
 
+	if tl.DefaultEstimatedMinutes != nil && *tl.DefaultEstimatedMinutes <= 0 {
+		return fmt.Errorf("default estimated minutes must be positive")
+	}
+
 	return nil
 }
 
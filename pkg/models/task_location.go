@@ -8,13 +8,23 @@ import (
 )
 
 type TaskLocation struct {
-	ID         string    `db:"id" json:"id"`
-	TaskID     string    `db:"task_id" json:"task_id"`
-	LocationID string    `db:"location_id" json:"location_id"`
-	IsRequired bool      `db:"is_required" json:"is_required"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	ID         string          `db:"id" json:"id"`
+	TaskID     string          `db:"task_id" json:"task_id"`
+	LocationID string          `db:"location_id" json:"location_id"`
+	IsRequired bool            `db:"is_required" json:"is_required"`
+	Trigger    LocationTrigger `db:"trigger_type" json:"trigger"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
 }
 
+// LocationTrigger says whether a task is due on arriving at or on leaving
+// its location
+type LocationTrigger string
+
+const (
+	LocationTriggerEnter LocationTrigger = "enter"
+	LocationTriggerExit  LocationTrigger = "exit"
+)
+
 func NewTaskLocation(taskID, locationID string, isRequired bool) (*TaskLocation, error) {
 	if taskID == "" {
 		return nil, fmt.Errorf("task ID is required")
@@ -29,6 +39,7 @@ func NewTaskLocation(taskID, locationID string, isRequired bool) (*TaskLocation,
 		TaskID:     taskID,
 		LocationID: locationID,
 		IsRequired: isRequired,
+		Trigger:    LocationTriggerEnter,
 		CreatedAt:  time.Now(),
 	}, nil
 }
@@ -37,6 +48,12 @@ func (tl *TaskLocation) SetRequired(required bool) {
 	tl.IsRequired = required
 }
 
+// IsExitTrigger reports whether the task should be done before leaving
+// the location. An empty trigger is treated as enter.
+func (tl *TaskLocation) IsExitTrigger() bool {
+	return tl.Trigger == LocationTriggerExit
+}
+
 func (tl *TaskLocation) BelongsToTask(taskID string) bool {
 	return tl.TaskID == taskID
 }
@@ -54,5 +71,18 @@ func (tl *TaskLocation) Validate() error {
 		return fmt.Errorf("location ID is required")
 	}
 
+	if tl.Trigger != "" && !isValidLocationTrigger(tl.Trigger) {
+		return fmt.Errorf("invalid location trigger: %s", tl.Trigger)
+	}
+
 	return nil
+}
+
+func isValidLocationTrigger(trigger LocationTrigger) bool {
+	switch trigger {
+	case LocationTriggerEnter, LocationTriggerExit:
+		return true
+	default:
+		return false
+	}
 }
\ No newline at end of file
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeTag returns tag trimmed and lowercased, without a leading "#",
+// so "#Errands" and "errands" are the same tag. Empty tags are rejected.
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+	if normalized == "" {
+		return "", fmt.Errorf("tag cannot be empty")
+	}
+	return normalized, nil
+}
+
+// NormalizeTags normalizes each tag and drops repeats, keeping the order
+// they were first given in
+func NormalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// HasTags reports whether the task has every one of tags, or with matchAny
+// at least one of them. The tags are compared normalized.
+func (t *Task) HasTags(tags []string, matchAny bool) bool {
+	own := make(map[string]bool, len(t.Tags))
+	for _, tag := range t.Tags {
+		own[tag] = true
+	}
+
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		has := err == nil && own[normalized]
+		if matchAny && has {
+			return true
+		}
+		if !matchAny && !has {
+			return false
+		}
+	}
+	return !matchAny || len(tags) == 0
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// BecameVisibleCooldown is how long after announcing that a task became
+// visible another announcement for it is suppressed. Together with the
+// location filter's grace band this keeps a task that flickers in and out of
+// view from notifying on every filter run.
+const BecameVisibleCooldown = 15 * time.Minute
+
+// TaskVisibility is the last filter verdict a user saw for a task
+type TaskVisibility struct {
+	UserID     string     `db:"user_id" json:"user_id"`
+	TaskID     string     `db:"task_id" json:"task_id"`
+	Visible    bool       `db:"visible" json:"visible"`
+	ChangedAt  time.Time  `db:"changed_at" json:"changed_at"`
+	NotifiedAt *time.Time `db:"notified_at" json:"notified_at"`
+}
+
+func NewTaskVisibility(userID, taskID string, visible bool, now time.Time) (*TaskVisibility, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	if taskID == "" {
+		return nil, fmt.Errorf("task ID is required")
+	}
+
+	return &TaskVisibility{
+		UserID:    userID,
+		TaskID:    taskID,
+		Visible:   visible,
+		ChangedAt: now,
+	}, nil
+}
+
+// Observe records a new filter verdict and reports whether it changed
+// anything and whether the task just became visible and should be announced.
+// Announcements are suppressed within BecameVisibleCooldown of the last one.
+func (v *TaskVisibility) Observe(visible bool, now time.Time) (changed, becameVisible bool) {
+	if v.Visible == visible {
+		return false, false
+	}
+
+	v.Visible = visible
+	v.ChangedAt = now
+
+	if !visible {
+		return true, false
+	}
+
+	if v.NotifiedAt != nil && now.Sub(*v.NotifiedAt) < BecameVisibleCooldown {
+		return true, false
+	}
+
+	v.NotifiedAt = &now
+	return true, true
+}
@@ -22,6 +22,16 @@ type User struct {
 	UpdatedAt    time.Time       `db:"updated_at" json:"updated_at"`
 	LastSeenAt   time.Time       `db:"last_seen_at" json:"last_seen_at"`
 	Settings     json.RawMessage `db:"settings" json:"settings"`
+	IsAdmin      bool            `db:"is_admin" json:"is_admin"`
+	// TOTPSecret is the base32 secret for two-factor codes, set by
+	// EnableTOTP. Like PasswordHash it is never marshaled.
+	TOTPSecret *string `db:"totp_secret" json:"-"`
+	// TOTPEnabled is set once the user has confirmed a code from the
+	// secret; until then login does not ask for one
+	TOTPEnabled bool `db:"totp_enabled" json:"totp_enabled"`
+	// DeactivatedAt is set when an admin deactivates the account. A
+	// deactivated user cannot log in or use existing tokens.
+	DeactivatedAt *time.Time `db:"deactivated_at" json:"deactivated_at,omitempty"`
 }
 
 var (
@@ -96,6 +106,32 @@ func (u *User) CheckPassword(password string) bool {
 	return true
 }
 
+// IsActive reports whether the user has not been deactivated
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
+// Anonymize replaces everything identifying the user with placeholders and
+// deactivates the account, for deleting a user whose tasks others still
+// share. The placeholder password hash matches no password.
+func (u *User) Anonymize(at time.Time) {
+	u.Username = "deleted_" + strings.ReplaceAll(u.ID, "-", "")
+	if len(u.Username) > 50 {
+		u.Username = u.Username[:50]
+	}
+	u.Email = u.Username + "@deleted.invalid"
+	u.DisplayName = "Deleted user"
+	u.PasswordHash = "!"
+	u.Settings = json.RawMessage(`{}`)
+	u.IsAdmin = false
+	u.TOTPSecret = nil
+	u.TOTPEnabled = false
+	if u.DeactivatedAt == nil {
+		u.DeactivatedAt = &at
+	}
+	u.UpdatedAt = at
+}
+
 func (u *User) Validate() error {
 	if err := validateUsername(u.Username); err != nil {
 		return err
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError collects validation failures keyed by the field's JSON
+// name, so API clients can map each message to a form field
+type ValidationError struct {
+	Fields map[string]string `json:"fields"`
+}
+
+// Add records a problem with a field. The first problem reported for a
+// field wins.
+func (e *ValidationError) Add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	if _, exists := e.Fields[field]; !exists {
+		e.Fields[field] = message
+	}
+}
+
+func (e *ValidationError) HasErrors() bool {
+	return len(e.Fields) > 0
+}
+
+// Err returns e when any field failed validation and nil otherwise
+func (e *ValidationError) Err() error {
+	if !e.HasErrors() {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	problems := make([]string, len(fields))
+	for i, field := range fields {
+		problems[i] = fmt.Sprintf("%s: %s", field, e.Fields[field])
+	}
+	return "validation failed: " + strings.Join(problems, "; ")
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WorkingHours is the part of the week a user normally works, as wall-clock
+// times (HH:MM) on the listed weekdays in the user's own timezone. An End
+// before Start spans midnight.
+type WorkingHours struct {
+	Start string   `yaml:"start" json:"start"`
+	End   string   `yaml:"end" json:"end"`
+	Days  []string `yaml:"days" json:"days"`
+}
+
+// DefaultWorkingHours is used for users who have not set their own
+func DefaultWorkingHours() WorkingHours {
+	return WorkingHours{
+		Start: "09:00",
+		End:   "17:00",
+		Days:  []string{"mon", "tue", "wed", "thu", "fri"},
+	}
+}
+
+func (w WorkingHours) Validate() error {
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("invalid working hours start %q (want HH:MM)", w.Start)
+	}
+	if _, err := time.Parse("15:04", w.End); err != nil {
+		return fmt.Errorf("invalid working hours end %q (want HH:MM)", w.End)
+	}
+	if w.Start == w.End {
+		return fmt.Errorf("working hours start and end must differ")
+	}
+	if len(w.Days) == 0 {
+		return fmt.Errorf("working hours need at least one day")
+	}
+	for _, day := range w.Days {
+		if _, err := parseWeekday(day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the working hours in loc. Invalid
+// working hours contain nothing.
+func (w WorkingHours) Contains(t time.Time, loc *time.Location) bool {
+	if w.Validate() != nil {
+		return false
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	start, _ := time.Parse("15:04", w.Start)
+	end, _ := time.Parse("15:04", w.End)
+	minute := t.Hour()*60 + t.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	day := t.Weekday()
+	if startMinute < endMinute {
+		return w.worksOn(day) && minute >= startMinute && minute < endMinute
+	}
+
+	// Overnight shift: the late part belongs to today's shift, the early part
+	// to the one that started yesterday
+	if minute >= startMinute {
+		return w.worksOn(day)
+	}
+	return minute < endMinute && w.worksOn((day+6)%7)
+}
+
+func (w WorkingHours) worksOn(day time.Weekday) bool {
+	for _, name := range w.Days {
+		if d, err := parseWeekday(name); err == nil && d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkingHours returns the working hours stored under "working_hours" in the
+// user's settings, or DefaultWorkingHours when none are set
+func (u *User) WorkingHours() WorkingHours {
+	var settings struct {
+		WorkingHours *WorkingHours `json:"working_hours"`
+	}
+	if len(u.Settings) == 0 || json.Unmarshal(u.Settings, &settings) != nil || settings.WorkingHours == nil {
+		return DefaultWorkingHours()
+	}
+	return *settings.WorkingHours
+}
+
+// Location returns the user's timezone, falling back to UTC
+func (u *User) Location() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
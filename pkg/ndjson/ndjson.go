@@ -0,0 +1,41 @@
+// Package ndjson writes newline-delimited JSON: one compact value per line,
+// for line-oriented tools such as jq.
+package ndjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Encoder writes each value it is given as one line of compact JSON
+type Encoder struct {
+	enc *json.Encoder
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Encoder{enc: enc}
+}
+
+// Encode writes v followed by a newline
+func (e *Encoder) Encode(v any) error {
+	if err := e.enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode ndjson line: %w", err)
+	}
+	return nil
+}
+
+// Write streams values to w one line each, encoding every value as it is
+// written rather than building the whole document first. An empty slice
+// writes nothing.
+func Write[T any](w io.Writer, values []T) error {
+	enc := NewEncoder(w)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+// Package nlp reads a task written as a sentence, such as "buy milk when at
+// grocery store" or "call mom tomorrow at 5pm for 15 minutes", into its
+// title and the constraints the sentence states: the locations it names, a
+// time estimate and a due date. The phrases it recognises are removed from
+// the title.
+package nlp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ParsedTask is what ParseTask read from a sentence
+type ParsedTask struct {
+	Title string
+	// LocationIDs are the known locations the sentence names, several when
+	// they match it equally well
+	LocationIDs      []string
+	EstimatedMinutes *int
+	DueAt            *time.Time
+}
+
+// endOfDayHour and endOfDayMinute are when a due day without a time is due
+const endOfDayHour, endOfDayMinute = 23, 59
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+const weekdayPattern = `sunday|sun|monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thu|friday|fri|saturday|sat`
+
+var (
+	// estimatePattern matches "for 30 minutes", "for 1.5 hours", "for 45m",
+	// "for an hour" and "for half an hour"
+	estimatePattern = regexp.MustCompile(`(?i)\bfor\s+(half\s+an|an?|\d+(?:\.\d+)?)\s*(minutes?|mins?|m|hours?|hrs?|h)\b`)
+
+	// dayPattern matches "today", "tomorrow", "tonight", "by friday", "on
+	// monday", "next tuesday" and "by 2026-10-20"
+	dayPattern = regexp.MustCompile(`(?i)\b(?:(?:by|on|due)\s+)?(today|tonight|tomorrow)\b|\b(?:by|on|due|next)\s+(?:(?:next|this)\s+)?(` + weekdayPattern + `|\d{4}-\d{2}-\d{2})\b`)
+
+	// clockPattern matches "at 5pm", "by 17:30", "at 9:15 am" and "at noon".
+	// A bare hour ("at 5") is left alone, since it may be part of the title.
+	clockPattern = regexp.MustCompile(`(?i)\b(?:at|by)\s+(noon|midnight|\d{1,2}(?::\d{2})?\s*(?:am|pm)|\d{1,2}:\d{2})\b`)
+
+	// locationTriggers introduce a location, in the order they are tried.
+	// The location named runs from the end of the trigger to the end of the
+	// sentence, or the end of its clause.
+	locationTriggers = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bwhen\s+(?:i\s+(?:am|'m|get|arrive)\s+|i'm\s+)?(?:at|to|near|in)\s+`),
+		regexp.MustCompile(`(?i)\bon\s+(?:the|my)\s+way\s+(?:to\s+)?`),
+		regexp.MustCompile(`(?i)\b(?:at|near)\s+`),
+	}
+
+	// clauseEnd ends the location a trigger names
+	clauseEnd = regexp.MustCompile(`(?i)[,;.!?]|\s+(?:and|then|to|for|before|after)\s+`)
+)
+
+// filler words are ignored when matching a location by its words
+var filler = map[string]bool{"the": true, "my": true, "a": true, "an": true, "our": true}
+
+// ParseTask reads a task sentence, matching the locations it names against
+// knownLocations. Dates are relative to now.
+func ParseTask(text string, knownLocations []models.Location) (ParsedTask, error) {
+	return ParseTaskAt(text, knownLocations, time.Now())
+}
+
+// ParseTaskAt is ParseTask with dates relative to now, in now's time zone.
+// A due day without a time is due at the end of that day; a time without
+// a day is due today, or tomorrow once it has passed. A weekday is its next
+// occurrence, today included.
+//
+// Locations match case-insensitively, by their whole name or part of it:
+// "at the store" finds "Grocery Store". A location phrase that matches none
+// of knownLocations is left in the title.
+func ParseTaskAt(text string, knownLocations []models.Location, now time.Time) (ParsedTask, error) {
+	title := strings.TrimSpace(text)
+	if title == "" {
+		return ParsedTask{}, fmt.Errorf("task text is empty")
+	}
+
+	var parsed ParsedTask
+	var err error
+
+	if match := estimatePattern.FindStringSubmatchIndex(title); match != nil {
+		minutes, err := estimateMinutes(title[match[2]:match[3]], title[match[4]:match[5]])
+		if err != nil {
+			return ParsedTask{}, err
+		}
+		parsed.EstimatedMinutes = &minutes
+		title = cut(title, match[0], match[1])
+	}
+
+	if title, parsed.DueAt, err = parseDue(title, now); err != nil {
+		return ParsedTask{}, err
+	}
+
+	title, parsed.LocationIDs = parseLocation(title, knownLocations)
+
+	parsed.Title = tidyTitle(title)
+	if parsed.Title == "" {
+		return ParsedTask{}, fmt.Errorf("no task title in %q", text)
+	}
+	return parsed, nil
+}
+
+// estimateMinutes converts an estimate's amount and unit to minutes
+func estimateMinutes(amount, unit string) (int, error) {
+	var value float64
+	switch strings.ToLower(strings.Join(strings.Fields(amount), " ")) {
+	case "a", "an":
+		value = 1
+	case "half an":
+		value = 0.5
+	default:
+		var err error
+		if value, err = strconv.ParseFloat(amount, 64); err != nil {
+			return 0, fmt.Errorf("invalid time estimate %q", amount)
+		}
+	}
+
+	if strings.HasPrefix(strings.ToLower(unit), "h") {
+		value *= 60
+	}
+	if value < 1 {
+		return 0, fmt.Errorf("time estimate of %s %s is too short", amount, unit)
+	}
+	return int(value + 0.5), nil
+}
+
+// parseDue removes the due day and time from title and returns when the
+// task is due, or nil when the title gives neither
+func parseDue(title string, now time.Time) (string, *time.Time, error) {
+	var day *time.Time
+	if match := dayPattern.FindStringSubmatchIndex(title); match != nil {
+		word := submatch(title, match, 1)
+		if word == "" {
+			word = submatch(title, match, 2)
+		}
+		date, err := dueDay(strings.ToLower(word), now)
+		if err != nil {
+			return "", nil, err
+		}
+		day = &date
+		title = cut(title, match[0], match[1])
+	}
+
+	hour, minute := endOfDayHour, endOfDayMinute
+	hasClock := false
+	if match := clockPattern.FindStringSubmatchIndex(title); match != nil {
+		var err error
+		if hour, minute, err = clockTime(submatch(title, match, 1)); err != nil {
+			return "", nil, err
+		}
+		hasClock = true
+		title = cut(title, match[0], match[1])
+	}
+
+	if day == nil && !hasClock {
+		return title, nil, nil
+	}
+
+	date := now
+	if day != nil {
+		date = *day
+	}
+	due := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+	if day == nil && due.Before(now) {
+		due = due.AddDate(0, 0, 1)
+	}
+	return title, &due, nil
+}
+
+// dueDay returns the day a due date word names
+func dueDay(word string, now time.Time) (time.Time, error) {
+	switch word {
+	case "today", "tonight":
+		return now, nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+
+	if weekday, ok := weekdays[word]; ok {
+		days := (int(weekday) - int(now.Weekday()) + 7) % 7
+		return now.AddDate(0, 0, days), nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", word, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date %q", word)
+	}
+	return date, nil
+}
+
+// clockTime reads "5pm", "9:15 am", "17:30", "noon" or "midnight"
+func clockTime(value string) (int, int, error) {
+	value = strings.ToLower(strings.Join(strings.Fields(value), ""))
+	switch value {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+
+	meridiem := ""
+	if strings.HasSuffix(value, "am") || strings.HasSuffix(value, "pm") {
+		meridiem = value[len(value)-2:]
+		value = value[:len(value)-2]
+	}
+
+	hourText, minuteText, _ := strings.Cut(value, ":")
+	hour, err := strconv.Atoi(hourText)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q", value)
+	}
+	minute := 0
+	if minuteText != "" {
+		if minute, err = strconv.Atoi(minuteText); err != nil || minute > 59 {
+			return 0, 0, fmt.Errorf("invalid time %q", value)
+		}
+	}
+
+	switch meridiem {
+	case "":
+		if hour > 23 {
+			return 0, 0, fmt.Errorf("invalid time %q", value)
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("invalid time %q%s", value, meridiem)
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, nil
+}
+
+// parseLocation finds a location phrase in title that names one of
+// locations, removing it with its trigger. The last phrase introduced by a
+// trigger is tried first, since location phrases usually end the sentence.
+func parseLocation(title string, locations []models.Location) (string, []string) {
+	if len(locations) == 0 {
+		return title, nil
+	}
+
+	for _, trigger := range locationTriggers {
+		matches := trigger.FindAllStringIndex(title, -1)
+		for i := len(matches) - 1; i >= 0; i-- {
+			start, phraseStart := matches[i][0], matches[i][1]
+			phraseEnd := len(title)
+			if end := clauseEnd.FindStringIndex(title[phraseStart:]); end != nil {
+				phraseEnd = phraseStart + end[0]
+			}
+
+			if ids := matchLocations(title[phraseStart:phraseEnd], locations); len(ids) > 0 {
+				return cut(title, start, phraseEnd), ids
+			}
+		}
+	}
+	return title, nil
+}
+
+// Location match strengths, strongest last
+const (
+	matchNone = iota
+	matchWord
+	matchPart
+	matchExact
+)
+
+// matchLocations returns the IDs of the locations phrase names best
+func matchLocations(phrase string, locations []models.Location) []string {
+	words := significantWords(phrase)
+	if len(words) == 0 {
+		return nil
+	}
+	phrase = strings.Join(words, " ")
+
+	best := matchNone
+	var ids []string
+	for _, location := range locations {
+		strength := locationMatch(phrase, words, location.Name)
+		if strength == matchNone || strength < best {
+			continue
+		}
+		if strength > best {
+			best = strength
+			ids = nil
+		}
+		ids = append(ids, location.ID)
+	}
+	return ids
+}
+
+// locationMatch is how well phrase, made of words, names a location
+func locationMatch(phrase string, words []string, name string) int {
+	nameWords := significantWords(name)
+	if len(nameWords) == 0 {
+		return matchNone
+	}
+	full := strings.Join(nameWords, " ")
+
+	switch {
+	case phrase == full:
+		return matchExact
+	case containsWords(full, phrase), containsWords(phrase, full):
+		return matchPart
+	}
+
+	for _, word := range words {
+		for _, nameWord := range nameWords {
+			if len(word) >= 3 && strings.HasPrefix(nameWord, word) {
+				return matchWord
+			}
+		}
+	}
+	return matchNone
+}
+
+// significantWords is text in lower case without filler words
+func significantWords(text string) []string {
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		if !filler[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// containsWords reports whether text contains part as whole words
+func containsWords(text, part string) bool {
+	return strings.Contains(" "+text+" ", " "+part+" ")
+}
+
+// tidyTitle collapses the gaps left by removed phrases and drops
+// connecting words and punctuation they leave dangling at the end
+func tidyTitle(title string) string {
+	words := strings.Fields(title)
+	for len(words) > 0 {
+		last := strings.ToLower(strings.TrimRight(words[len(words)-1], ",;:-"))
+		if last == "" || last == "and" || last == "then" || last == "due" {
+			words = words[:len(words)-1]
+			continue
+		}
+		words[len(words)-1] = strings.TrimRight(words[len(words)-1], ",;:-")
+		break
+	}
+	return strings.Join(words, " ")
+}
+
+// submatch returns the text of group n of match, or "" when it did not
+// take part
+func submatch(text string, match []int, n int) string {
+	if match[2*n] < 0 {
+		return ""
+	}
+	return text[match[2*n]:match[2*n+1]]
+}
+
+// cut removes text[start:end], leaving a space in its place
+func cut(text string, start, end int) string {
+	return text[:start] + " " + text[end:]
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// WebhookNotifier POSTs each notification as JSON to the user's webhook URL
+type WebhookNotifier struct {
+	settings   SettingsRepository
+	httpClient HTTPClient
+}
+
+func NewWebhookNotifier(settings SettingsRepository, httpClient HTTPClient) *WebhookNotifier {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &WebhookNotifier{settings: settings, httpClient: httpClient}
+}
+
+// Send implements Notifier
+func (n *WebhookNotifier) Send(ctx context.Context, notification models.Notification) error {
+	settings, err := n.settings.Get(notification.UserID)
+	if err != nil {
+		return &TransientError{fmt.Errorf("failed to get notification settings: %w", err)}
+	}
+	if settings == nil || settings.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return post(n.httpClient, req, "webhook")
+}
+
+// NtfyNotifier publishes each notification's message to the user's ntfy
+// topic
+type NtfyNotifier struct {
+	settings   SettingsRepository
+	httpClient HTTPClient
+	server     string
+}
+
+// NewNtfyNotifier returns a notifier publishing to server for users who
+// haven't named their own ntfy server. An empty server uses
+// models.DefaultNtfyServer.
+func NewNtfyNotifier(settings SettingsRepository, httpClient HTTPClient, server string) *NtfyNotifier {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if server == "" {
+		server = models.DefaultNtfyServer
+	}
+	return &NtfyNotifier{settings: settings, httpClient: httpClient, server: server}
+}
+
+// Send implements Notifier
+func (n *NtfyNotifier) Send(ctx context.Context, notification models.Notification) error {
+	settings, err := n.settings.Get(notification.UserID)
+	if err != nil {
+		return &TransientError{fmt.Errorf("failed to get notification settings: %w", err)}
+	}
+	if settings == nil || settings.NtfyTopic == "" {
+		return nil
+	}
+
+	server := settings.NtfyServer
+	if server == "" {
+		server = n.server
+	}
+	url := strings.TrimSuffix(server, "/") + "/" + settings.NtfyTopic
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(notification.Message))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %w", err)
+	}
+	req.Header.Set("Title", "Here and Now")
+	req.Header.Set("Tags", string(notification.Type))
+
+	return post(n.httpClient, req, "ntfy")
+}
+
+// post sends req, treating network failures, rate limiting and server
+// errors as transient
+func post(httpClient HTTPClient, req *http.Request, channel string) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &TransientError{fmt.Errorf("%s request failed: %w", channel, err)}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return &TransientError{fmt.Errorf("%s returned status %d", channel, resp.StatusCode)}
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("%s returned status %d", channel, resp.StatusCode)
+	}
+	return nil
+}
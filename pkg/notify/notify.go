@@ -0,0 +1,272 @@
+// Package notify pushes users' notifications out of the app to the channels
+// they configure, such as a webhook, an ntfy topic or email. A Dispatcher
+// watches for new notifications in the background and hands each to every
+// Notifier, retrying transient failures, so creating a notification never
+// waits on delivery.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
+)
+
+// Notifier delivers notifications over one channel. Send returns nil
+// without sending when the user hasn't set the channel up, and a
+// *TransientError for failures that may pass on a later attempt.
+type Notifier interface {
+	Send(ctx context.Context, notification models.Notification) error
+}
+
+// HTTPClient sends the requests of the HTTP notifiers
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// SettingsRepository looks up where users want their notifications pushed
+type SettingsRepository interface {
+	// Get returns the user's settings, or nil when they have none
+	Get(userID string) (*models.NotificationSettings, error)
+}
+
+// DeliveryRepository tracks which notifications have been delivered
+type DeliveryRepository interface {
+	// GetUndelivered returns up to limit notifications, oldest first, that
+	// belong to users with a channel turned on, were created after the
+	// user's settings were, and have neither been delivered nor given up on
+	GetUndelivered(limit int) ([]models.Notification, error)
+	MarkDelivered(notificationID string, at time.Time) error
+	// RecordDeliveryFailure counts a failed delivery pass in the
+	// notification's DeliveryAttempts. When giveUp is set the notification
+	// is no longer returned as undelivered.
+	RecordDeliveryFailure(notificationID, reason string, giveUp bool) error
+}
+
+// TransientError is a delivery failure a later attempt may not have, such
+// as a network error or a server error response
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient reports whether err is worth retrying
+func IsTransient(err error) bool {
+	var transient *TransientError
+	return errors.As(err, &transient)
+}
+
+// Options tune how the dispatcher polls and retries
+type Options struct {
+	// PollInterval is how often the dispatcher looks for new notifications
+	PollInterval time.Duration
+	// BatchSize caps how many notifications one pass delivers
+	BatchSize int
+	// Retries is how many times a transient failure is retried within a
+	// pass, waiting Backoff before the first retry and doubling after
+	Retries int
+	Backoff time.Duration
+	// MaxAttempts is how many passes may fail before a notification is
+	// given up on
+	MaxAttempts int
+}
+
+// DefaultOptions fill in unset Options
+var DefaultOptions = Options{
+	PollInterval: 15 * time.Second,
+	BatchSize:    50,
+	Retries:      3,
+	Backoff:      time.Second,
+	MaxAttempts:  5,
+}
+
+func (o Options) withDefaults() Options {
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultOptions.PollInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultOptions.BatchSize
+	}
+	if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultOptions.Backoff
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultOptions.MaxAttempts
+	}
+	return o
+}
+
+// Dispatcher delivers new notifications through its notifiers
+type Dispatcher struct {
+	deliveries DeliveryRepository
+	notifiers  []Notifier
+	options    Options
+	clock      clock.Clock
+	logger     *log.Logger
+}
+
+// NewDispatcher returns a dispatcher delivering through notifiers. Unset
+// options use DefaultOptions.
+func NewDispatcher(deliveries DeliveryRepository, options Options, notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{
+		deliveries: deliveries,
+		notifiers:  notifiers,
+		options:    options.withDefaults(),
+		clock:      clock.Real(),
+		logger:     log.Default(),
+	}
+}
+
+// SetClock replaces the clock deliveries are timestamped with
+func (d *Dispatcher) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+// SetLogger replaces the logger failures are reported to
+func (d *Dispatcher) SetLogger(logger *log.Logger) {
+	d.logger = logger
+}
+
+// DispatchOnce delivers the notifications waiting now and returns how many
+// were delivered and how many failed
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (delivered, failed int, err error) {
+	pending, err := d.deliveries.GetUndelivered(d.options.BatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get undelivered notifications: %w", err)
+	}
+
+	for _, notification := range pending {
+		if ctx.Err() != nil {
+			return delivered, failed, ctx.Err()
+		}
+
+		if sendErr := d.deliver(ctx, notification); sendErr != nil {
+			if ctx.Err() != nil {
+				return delivered, failed, ctx.Err()
+			}
+			failed++
+			giveUp := !IsTransient(sendErr) || notification.DeliveryAttempts+1 >= d.options.MaxAttempts
+			d.logger.Printf("Notification %s not delivered (attempt %d): %v", notification.ID, notification.DeliveryAttempts+1, sendErr)
+			if err := d.deliveries.RecordDeliveryFailure(notification.ID, sendErr.Error(), giveUp); err != nil {
+				return delivered, failed, fmt.Errorf("failed to record delivery failure: %w", err)
+			}
+			continue
+		}
+
+		delivered++
+		if err := d.deliveries.MarkDelivered(notification.ID, d.clock.Now()); err != nil {
+			return delivered, failed, fmt.Errorf("failed to mark notification delivered: %w", err)
+		}
+	}
+	return delivered, failed, nil
+}
+
+// Run delivers notifications every PollInterval until stop is closed. It
+// is meant to run in its own goroutine.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(d.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, _, err := d.DispatchOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Printf("Notification delivery failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SendTest sends a test notification to the user through every notifier
+// once, without retrying, so a misconfigured channel is reported at once
+func (d *Dispatcher) SendTest(ctx context.Context, userID string) error {
+	test := models.Notification{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Type:      models.NotificationTypeTest,
+		Message:   "Test notification from Here and Now",
+		CreatedAt: d.clock.Now(),
+	}
+
+	var errs []error
+	for _, notifier := range d.notifiers {
+		if err := notifier.Send(ctx, test); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends the notification through every notifier, retrying transient
+// failures. A notifier that already succeeded is sent to again if a later
+// pass retries the notification.
+func (d *Dispatcher) deliver(ctx context.Context, notification models.Notification) error {
+	var errs []error
+	for _, notifier := range d.notifiers {
+		if err := d.send(ctx, notifier, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	err := errors.Join(errs...)
+	for _, e := range errs {
+		if !IsTransient(e) {
+			return err
+		}
+	}
+	return &TransientError{err}
+}
+
+func (d *Dispatcher) send(ctx context.Context, notifier Notifier, notification models.Notification) error {
+	backoff := d.options.Backoff
+	for attempt := 0; ; attempt++ {
+		err := notifier.Send(ctx, notification)
+		if err == nil || !IsTransient(err) || attempt >= d.options.Retries {
+			return err
+		}
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
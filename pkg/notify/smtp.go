@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// DefaultSMTPPort is the submission port used when SMTPConfig.Port is zero
+const DefaultSMTPPort = 587
+
+// emailSubjectLimit caps how much of a message the subject line repeats
+const emailSubjectLimit = 72
+
+// SMTPConfig is the mail server notifications are emailed through
+type SMTPConfig struct {
+	Host string `yaml:"host"`
+	// Port zero uses DefaultSMTPPort
+	Port int `yaml:"port"`
+	// Username and Password authenticate with PLAIN auth when Username is
+	// set. The server must offer STARTTLS unless it is on localhost.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// From is the address emails are sent from
+	From string `yaml:"from"`
+	// DryRun logs each rendered email instead of sending it
+	DryRun bool `yaml:"dry_run"`
+}
+
+// Enabled reports whether emails are sent or, in a dry run, logged
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != "" || c.DryRun
+}
+
+func (c SMTPConfig) address() string {
+	port := c.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// UserRepository looks up the address a user's emails go to
+type UserRepository interface {
+	GetByID(userID string) (*models.User, error)
+}
+
+// SMTPNotifier emails each notification to users who turned email on
+type SMTPNotifier struct {
+	settings SettingsRepository
+	users    UserRepository
+	config   SMTPConfig
+	logger   *log.Logger
+}
+
+func NewSMTPNotifier(settings SettingsRepository, users UserRepository, config SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{settings: settings, users: users, config: config, logger: log.Default()}
+}
+
+// SetLogger replaces the logger dry runs write emails to
+func (n *SMTPNotifier) SetLogger(logger *log.Logger) {
+	n.logger = logger
+}
+
+// Send implements Notifier
+func (n *SMTPNotifier) Send(ctx context.Context, notification models.Notification) error {
+	settings, err := n.settings.Get(notification.UserID)
+	if err != nil {
+		return &TransientError{fmt.Errorf("failed to get notification settings: %w", err)}
+	}
+	if settings == nil || !settings.EmailNotificationsEnabled {
+		return nil
+	}
+
+	user, err := n.users.GetByID(notification.UserID)
+	if err != nil {
+		return &TransientError{fmt.Errorf("failed to get user: %w", err)}
+	}
+	if user.Email == "" {
+		return fmt.Errorf("user %s has no email address", user.Username)
+	}
+
+	message := RenderEmail(n.config.From, user.Email, notification)
+	if n.config.DryRun {
+		n.logger.Printf("Dry run: email to %s not sent:\n%s", user.Email, message)
+		return nil
+	}
+	return n.sendMail(ctx, user.Email, message)
+}
+
+// sendMail delivers message to one recipient, upgrading to TLS when the
+// server offers it
+func (n *SMTPNotifier) sendMail(ctx context.Context, to string, message []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.config.address())
+	if err != nil {
+		return &TransientError{fmt.Errorf("smtp connection failed: %w", err)}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return smtpError("greeting", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return smtpError("STARTTLS", err)
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return smtpError("auth", err)
+		}
+	}
+	if err := client.Mail(n.config.From); err != nil {
+		return smtpError("MAIL", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return smtpError("RCPT", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("DATA", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return smtpError("DATA", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("DATA", err)
+	}
+	if err := client.Quit(); err != nil {
+		return smtpError("QUIT", err)
+	}
+	return nil
+}
+
+// smtpError wraps a failed SMTP step. Permanent (5xx) replies are final;
+// temporary replies and connection failures are transient.
+func smtpError(step string, err error) error {
+	err = fmt.Errorf("smtp %s failed: %w", step, err)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return err
+	}
+	return &TransientError{err}
+}
+
+// RenderEmail returns the plain text email for the notification, headers
+// included, as it is sent
+func RenderEmail(from, to string, notification models.Notification) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", emailSubject(notification)) + "\r\n")
+	b.WriteString("Date: " + notification.CreatedAt.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("X-Hereandnow-Type: " + string(notification.Type) + "\r\n")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(notification.Message, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n") + "\r\n")
+	return []byte(b.String())
+}
+
+// emailSubject is the message's first line, shortened to fit a subject
+func emailSubject(notification models.Notification) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(notification.Message), "\n")
+	line = strings.TrimSpace(line)
+	if runes := []rune(line); len(runes) > emailSubjectLimit {
+		line = string(runes[:emailSubjectLimit-3]) + "..."
+	}
+	if line == "" {
+		return "Here and Now notification"
+	}
+	return "Here and Now: " + line
+}
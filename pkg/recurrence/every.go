@@ -0,0 +1,131 @@
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var everyUnits = map[string]Frequency{
+	"day": Daily, "days": Daily,
+	"week": Weekly, "weeks": Weekly,
+	"month": Monthly, "months": Monthly,
+	"year": Yearly, "years": Yearly,
+}
+
+var everyDays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// ParseEvery compiles a friendlier description into a rule:
+//
+//	"day", "3 days", "week", "2 weeks", "other month", "year"
+//	"weekday", "weekend"
+//	"monday", "mon,wed,fri", "tuesday and thursday"
+//	"2 weeks on monday and friday"
+//
+// A leading "every" is optional.
+func ParseEvery(phrase string) (*Rule, error) {
+	text := strings.ToLower(strings.TrimSpace(phrase))
+	text = strings.TrimSpace(strings.TrimPrefix(text, "every "))
+	if text == "" || text == "every" {
+		return nil, fmt.Errorf(`recurrence is empty (e.g. "2 weeks" or "monday and friday")`)
+	}
+
+	period, days, hasDays := strings.Cut(text, " on ")
+
+	rule := &Rule{Interval: 1}
+	switch period {
+	case "weekday", "weekdays":
+		rule.Freq = Weekly
+		rule.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case "weekend", "weekends":
+		rule.Freq = Weekly
+		rule.ByDay = []time.Weekday{time.Saturday, time.Sunday}
+	default:
+		if byDay, err := parseEveryDays(period); err == nil {
+			rule.Freq = Weekly
+			rule.ByDay = byDay
+			break
+		}
+		if err := parseEveryPeriod(period, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasDays {
+		if rule.Freq != Weekly || len(rule.ByDay) > 0 {
+			return nil, fmt.Errorf(`"on <days>" only follows a number of weeks, e.g. "2 weeks on monday"`)
+		}
+		byDay, err := parseEveryDays(days)
+		if err != nil {
+			return nil, err
+		}
+		rule.ByDay = byDay
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	rule.ByDay = sortedDays(rule.ByDay)
+	return rule, nil
+}
+
+// parseEveryPeriod reads "[N|other] unit"
+func parseEveryPeriod(period string, rule *Rule) error {
+	fields := strings.Fields(period)
+	unit := fields[len(fields)-1]
+	freq, ok := everyUnits[unit]
+	if !ok {
+		return fmt.Errorf(`cannot understand %q: want e.g. "day", "2 weeks", "weekday" or "monday and friday"`, period)
+	}
+	rule.Freq = freq
+
+	switch len(fields) {
+	case 1:
+		return nil
+	case 2:
+		if fields[0] == "other" {
+			rule.Interval = 2
+			return nil
+		}
+		interval, err := strconv.Atoi(fields[0])
+		if err != nil || interval < 1 {
+			return fmt.Errorf("invalid interval %q: want a positive whole number", fields[0])
+		}
+		rule.Interval = interval
+		return nil
+	default:
+		return fmt.Errorf(`cannot understand %q: want e.g. "day", "2 weeks", "weekday" or "monday and friday"`, period)
+	}
+}
+
+// parseEveryDays reads a list of weekdays separated by commas, spaces or
+// "and"
+func parseEveryDays(list string) ([]time.Weekday, error) {
+	list = strings.NewReplacer(",", " ", " and ", " ").Replace(list)
+	names := strings.Fields(list)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no days given")
+	}
+
+	var days []time.Weekday
+	for _, name := range names {
+		day, ok := everyDays[strings.TrimSuffix(name, "s")]
+		if !ok {
+			day, ok = everyDays[name]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q: want e.g. monday or mon", name)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
@@ -0,0 +1,70 @@
+package recurrence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/google/uuid"
+)
+
+// ExpandRecurrence returns the occurrences of a recurring task that fall
+// within [from, to) as dated copies of it. Each copy has a new ID, DueAt set
+// to its occurrence, ParentTaskID pointing at the task and no rule of its
+// own. Occurrences are counted from the task's due date, or from when it was
+// created when it has none, so COUNT and UNTIL apply to the whole series.
+func ExpandRecurrence(task models.Task, from, to time.Time) ([]models.Task, error) {
+	rule, err := taskRule(task)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := rule.Between(seriesStart(task), from, to)
+	instances := make([]models.Task, len(occurrences))
+	for i, at := range occurrences {
+		instances[i] = occurrenceOf(task, at)
+	}
+	return instances, nil
+}
+
+// NextOccurrence returns the first occurrence of a recurring task after the
+// given time, counted as ExpandRecurrence counts them, or false when the
+// series has ended by then
+func NextOccurrence(task models.Task, after time.Time) (time.Time, bool, error) {
+	rule, err := taskRule(task)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	next, ok := rule.Next(seriesStart(task), after)
+	return next, ok, nil
+}
+
+func taskRule(task models.Task) (*Rule, error) {
+	if task.RecurrenceRule == nil {
+		return nil, fmt.Errorf("task %s has no recurrence rule", task.ID)
+	}
+
+	rule, err := Parse(*task.RecurrenceRule)
+	if err != nil {
+		return nil, fmt.Errorf("task %s has an invalid recurrence rule: %w", task.ID, err)
+	}
+	return rule, nil
+}
+
+func seriesStart(task models.Task) time.Time {
+	if task.DueAt != nil {
+		return *task.DueAt
+	}
+	return task.CreatedAt
+}
+
+func occurrenceOf(task models.Task, at time.Time) models.Task {
+	instance := task
+	instance.ID = uuid.New().String()
+	instance.DueAt = &at
+	instance.RecurrenceRule = nil
+	parentID := task.ID
+	instance.ParentTaskID = &parentID
+	return instance
+}
@@ -0,0 +1,359 @@
+// Package recurrence parses and evaluates the subset of iCalendar RRULEs
+// (RFC 5545) that tasks use: FREQ, INTERVAL, BYDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a rule repeats
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// Rule is a parsed recurrence rule. Occurrences are counted from a start
+// time, normally the task's due date, which keeps its time of day.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// ByDay limits daily and weekly rules to these weekdays
+	ByDay []time.Weekday
+	// Count stops the rule after this many occurrences; zero is unlimited
+	Count int
+	// Until stops the rule after this time, inclusive
+	Until *time.Time
+}
+
+// maxCandidates bounds how many periods a search steps through, so a rule
+// that can never match (e.g. February 30th) cannot loop forever
+const maxCandidates = 100000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse reads an RRULE such as "FREQ=WEEKLY;BYDAY=MO,WE,FR". An optional
+// "RRULE:" prefix is accepted. Errors say which part is wrong and what is
+// supported.
+func Parse(rrule string) (*Rule, error) {
+	value := strings.TrimSpace(rrule)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "RRULE:"), "rrule:")
+	if value == "" {
+		return nil, fmt.Errorf("recurrence rule is empty (e.g. FREQ=WEEKLY;BYDAY=MO)")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		name, arg, ok := strings.Cut(part, "=")
+		if !ok || arg == "" {
+			return nil, fmt.Errorf("invalid rule part %q: want NAME=VALUE", part)
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+		arg = strings.ToUpper(strings.TrimSpace(arg))
+		if seen[name] {
+			return nil, fmt.Errorf("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(arg)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(arg)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q: want a positive whole number", arg)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(arg, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY day %q: want MO, TU, WE, TH, FR, SA or SU", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(arg)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q: want a positive whole number", arg)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(arg)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s (supported: FREQ, INTERVAL, BYDAY, COUNT, UNTIL)", name)
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	rule.ByDay = sortedDays(rule.ByDay)
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q: want YYYYMMDD or YYYYMMDDTHHMMSSZ", value)
+}
+
+// Validate checks the rule is complete and consistent
+func (r Rule) Validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return fmt.Errorf("FREQ is required (DAILY, WEEKLY, MONTHLY or YEARLY)")
+	default:
+		return fmt.Errorf("invalid FREQ %q: want DAILY, WEEKLY, MONTHLY or YEARLY", string(r.Freq))
+	}
+
+	if r.Interval < 1 {
+		return fmt.Errorf("INTERVAL must be positive")
+	}
+
+	if len(r.ByDay) > 0 && r.Freq != Daily && r.Freq != Weekly {
+		return fmt.Errorf("BYDAY is only supported with FREQ=DAILY or FREQ=WEEKLY")
+	}
+
+	if r.Count > 0 && r.Until != nil {
+		return fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+
+	if r.Count < 0 {
+		return fmt.Errorf("COUNT cannot be negative")
+	}
+
+	return nil
+}
+
+// String returns the rule as an RRULE value, with parts in a fixed order and
+// defaults left out, so equivalent rules compare equal
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range sortedDays(r.ByDay) {
+			codes = append(codes, dayCode(day))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe returns the rule in words, e.g. "every 2 weeks on Monday and
+// Friday, 10 times"
+func (r Rule) Describe() string {
+	units := map[Frequency]string{Daily: "day", Weekly: "week", Monthly: "month", Yearly: "year"}
+	description := "every " + units[r.Freq]
+	if r.Interval > 1 {
+		description = fmt.Sprintf("every %d %ss", r.Interval, units[r.Freq])
+	}
+
+	if len(r.ByDay) > 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, day := range sortedDays(r.ByDay) {
+			names = append(names, day.String())
+		}
+		if len(names) == 1 {
+			description += " on " + names[0]
+		} else {
+			description += " on " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+		}
+	}
+
+	if r.Count > 0 {
+		description += fmt.Sprintf(", %d times", r.Count)
+	}
+	if r.Until != nil {
+		description += ", until " + r.Until.Format("Jan 2, 2006")
+	}
+	return description
+}
+
+// Occurrences returns up to n occurrences counted from start, start itself
+// included when it matches the rule
+func (r Rule) Occurrences(start time.Time, n int) []time.Time {
+	occurrences := []time.Time{}
+	if n <= 0 {
+		return occurrences
+	}
+	r.each(start, func(at time.Time) bool {
+		occurrences = append(occurrences, at)
+		return len(occurrences) < n
+	})
+	return occurrences
+}
+
+// Next returns the first occurrence counted from start that is after the
+// given time, or false when the rule has ended by then
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.each(start, func(at time.Time) bool {
+		if at.After(after) {
+			next, found = at, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Between returns the occurrences counted from start that fall within
+// [from, to)
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	occurrences := []time.Time{}
+	r.each(start, func(at time.Time) bool {
+		if !at.Before(to) {
+			return false
+		}
+		if !at.Before(from) {
+			occurrences = append(occurrences, at)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// each calls fn with every occurrence in order until fn returns false or the
+// rule's COUNT or UNTIL ends it
+func (r Rule) each(start time.Time, fn func(at time.Time) bool) {
+	interval := max(r.Interval, 1)
+	emitted := 0
+	emit := func(at time.Time) bool {
+		if at.Before(start) {
+			return true
+		}
+		if r.Until != nil && at.After(*r.Until) {
+			return false
+		}
+		emitted++
+		if !fn(at) {
+			return false
+		}
+		return r.Count == 0 || emitted < r.Count
+	}
+
+	for period := 0; period < maxCandidates; period++ {
+		switch r.Freq {
+		case Daily:
+			at := start.AddDate(0, 0, period*interval)
+			if len(r.ByDay) > 0 && !containsDay(r.ByDay, at.Weekday()) {
+				continue
+			}
+			if !emit(at) {
+				return
+			}
+
+		case Weekly:
+			// Weeks start on Monday (RFC 5545's default WKST)
+			weekStart := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+period*7*interval)
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []time.Weekday{start.Weekday()}
+			}
+			for _, day := range sortedDays(days) {
+				if !emit(weekStart.AddDate(0, 0, (int(day)+6)%7)) {
+					return
+				}
+			}
+
+		case Monthly:
+			at, ok := sameDayIn(start, 0, period*interval)
+			if ok && !emit(at) {
+				return
+			}
+
+		case Yearly:
+			at, ok := sameDayIn(start, period*interval, 0)
+			if ok && !emit(at) {
+				return
+			}
+
+		default:
+			return
+		}
+	}
+}
+
+// sameDayIn returns start moved by years and months, or false when the
+// target month has no such day (e.g. the 31st in April), which RFC 5545
+// skips rather than moving to another day
+func sameDayIn(start time.Time, years, months int) (time.Time, bool) {
+	at := time.Date(start.Year()+years, start.Month()+time.Month(months), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	at = at.AddDate(0, 0, start.Day()-1)
+	return at, at.Day() == start.Day()
+}
+
+func containsDay(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedDays orders weekdays Monday first and drops duplicates
+func sortedDays(days []time.Weekday) []time.Weekday {
+	var sorted []time.Weekday
+	for _, day := range days {
+		if !containsDay(sorted, day) {
+			sorted = append(sorted, day)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return (int(sorted[i])+6)%7 < (int(sorted[j])+6)%7
+	})
+	return sorted
+}
+
+func dayCode(day time.Weekday) string {
+	for code, d := range weekdayCodes {
+		if d == day {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package sync
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// CursorKey implements IncrementalProvider
+func (p *CalDAVProvider) CursorKey() string {
+	return p.providerID()
+}
+
+// GetChanges lists the calendar with a sync-collection REPORT (RFC 6578).
+// Events are identified by their resource name, since that is all the server
+// reports for deleted members.
+func (p *CalDAVProvider) GetChanges(userID, token string) (*EventChanges, error) {
+	reqBody := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" ?>
+<D:sync-collection xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+    <D:sync-token>%s</D:sync-token>
+    <D:sync-level>1</D:sync-level>
+    <D:prop>
+        <D:getetag />
+        <C:calendar-data />
+    </D:prop>
+</D:sync-collection>`, xmlEscape(token))
+
+	req, err := http.NewRequest("REPORT", p.BaseURL, strings.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(p.Username, p.Password)
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CalDAV request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CalDAV response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, rateLimitResponse(resp)
+	case token != "" && isExpiredSyncToken(resp.StatusCode, body):
+		return nil, ErrSyncTokenExpired
+	case resp.StatusCode != http.StatusMultiStatus:
+		return nil, fmt.Errorf("CalDAV server returned status %d", resp.StatusCode)
+	}
+
+	var status davMultistatus
+	if err := xml.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse CalDAV response: %w", err)
+	}
+
+	changes := &EventChanges{Events: []ExternalEvent{}, Deleted: []string{}, NextToken: status.SyncToken}
+	for _, response := range status.Responses {
+		id := eventResourceName(response.Href)
+		if strings.Contains(response.Status, " 404 ") {
+			changes.Deleted = append(changes.Deleted, id)
+			continue
+		}
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.CalendarData == "" {
+				continue
+			}
+			event, err := parseVEvent(propstat.Prop.CalendarData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse event %s: %w", response.Href, err)
+			}
+			event.ID = id
+			event.URL = response.Href
+			event.Source = p.providerID()
+			changes.Events = append(changes.Events, *event)
+		}
+	}
+
+	return changes, nil
+}
+
+// eventResourceName identifies an event by the name of its resource, such as
+// "abc" for /calendars/me/work/abc.ics
+func eventResourceName(href string) string {
+	return strings.TrimSuffix(path.Base(href), ".ics")
+}
+
+// isExpiredSyncToken recognises the ways servers reject a sync token: the
+// RFC 6578 valid-sync-token precondition, or a bare 409 or 410
+func isExpiredSyncToken(statusCode int, body []byte) bool {
+	switch statusCode {
+	case http.StatusConflict, http.StatusGone:
+		return true
+	case http.StatusForbidden:
+		return strings.Contains(string(body), "valid-sync-token")
+	}
+	return false
+}
+
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+	SyncToken string        `xml:"DAV: sync-token"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	Status    string        `xml:"DAV: status"`
+	Propstats []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop struct {
+		CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+	} `xml:"DAV: prop"`
+}
+
+// parseVEvent reads the first VEVENT of an iCalendar object
+func parseVEvent(data string) (*ExternalEvent, error) {
+	// Unfold continuation lines before splitting into properties
+	data = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(data)
+
+	event := &ExternalEvent{Source: "caldav"}
+	inEvent := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch line {
+		case "BEGIN:VEVENT":
+			inEvent = true
+			continue
+		case "END:VEVENT":
+			if event.StartTime.IsZero() {
+				return nil, fmt.Errorf("event has no DTSTART")
+			}
+			if event.EndTime.IsZero() {
+				event.EndTime = event.StartTime
+			}
+			return event, nil
+		}
+		if !inEvent {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		switch name {
+		case "SUMMARY":
+			event.Title = icalUnescape(value)
+		case "DESCRIPTION":
+			event.Description = icalUnescape(value)
+		case "LOCATION":
+			event.Location = icalUnescape(value)
+		case "RRULE":
+			event.Recurring = true
+		case "DTSTART", "DTEND":
+			t, allDay, err := parseICalTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+			if name == "DTSTART" {
+				event.StartTime = t
+				event.AllDay = allDay
+			} else {
+				event.EndTime = t
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no VEVENT found")
+}
+
+func parseICalTime(value, params string) (time.Time, bool, error) {
+	loc := time.UTC
+	for _, param := range strings.Split(params, ";") {
+		if tzid, ok := strings.CutPrefix(param, "TZID="); ok {
+			if l, err := time.LoadLocation(tzid); err == nil {
+				loc = l
+			}
+		}
+	}
+
+	if len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+func icalUnescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func xmlEscape(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,228 @@
+package sync
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// CalDAVCalendar is a calendar found on a CalDAV server
+type CalDAVCalendar struct {
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+const calDAVDiscoveryBody = `<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+    <D:prop>
+        <D:displayname />
+        <D:resourcetype />
+        <D:current-user-principal />
+        <C:calendar-home-set />
+        <C:supported-calendar-component-set />
+    </D:prop>
+</D:propfind>`
+
+// DiscoverCalendars finds the user's event calendars with PROPFIND
+// (RFC 4791 and RFC 5397). BaseURL may be a calendar itself, the user's
+// calendar home, or the server's DAV root, from which the home is found
+// through the current user's principal.
+func (p *CalDAVProvider) DiscoverCalendars() ([]CalDAVCalendar, error) {
+	responses, err := p.propfind(p.BaseURL, "0")
+	if err != nil {
+		return nil, err
+	}
+
+	home := ""
+	principal := ""
+	for _, response := range responses {
+		if response.isCalendar() {
+			return []CalDAVCalendar{{URL: p.BaseURL, Name: response.displayName()}}, nil
+		}
+		for _, propstat := range response.Propstats {
+			if href := propstat.Prop.CalendarHomeSet.Href; href != "" && home == "" {
+				home = href
+			}
+			if href := propstat.Prop.CurrentUserPrincipal.Href; href != "" && principal == "" {
+				principal = href
+			}
+		}
+	}
+
+	if home == "" && principal != "" {
+		principalURL, err := p.resolve(principal)
+		if err != nil {
+			return nil, err
+		}
+		responses, err := p.propfind(principalURL, "0")
+		if err != nil {
+			return nil, err
+		}
+		for _, response := range responses {
+			for _, propstat := range response.Propstats {
+				if href := propstat.Prop.CalendarHomeSet.Href; href != "" && home == "" {
+					home = href
+				}
+			}
+		}
+	}
+
+	homeURL := p.BaseURL
+	if home != "" {
+		if homeURL, err = p.resolve(home); err != nil {
+			return nil, err
+		}
+	}
+
+	responses, err = p.propfind(homeURL, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := []CalDAVCalendar{}
+	for _, response := range responses {
+		if !response.isCalendar() || !response.holdsEvents() {
+			continue
+		}
+		calendarURL, err := p.resolve(response.Href)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, CalDAVCalendar{URL: calendarURL, Name: response.displayName()})
+	}
+
+	if len(calendars) == 0 {
+		return nil, fmt.Errorf("no calendars found at %s", p.BaseURL)
+	}
+
+	return calendars, nil
+}
+
+// ForCalendar returns a provider for one discovered calendar with the same
+// credentials. Its events are stored under a provider ID of their own, so
+// syncing one calendar never touches another's events.
+func (p *CalDAVProvider) ForCalendar(calendar CalDAVCalendar) *CalDAVProvider {
+	return &CalDAVProvider{
+		BaseURL:    strings.TrimSuffix(calendar.URL, "/"),
+		Username:   p.Username,
+		Password:   p.Password,
+		HTTPClient: p.HTTPClient,
+		ProviderID: models.ProviderCalDAV + ":" + calendar.URL,
+	}
+}
+
+func (p *CalDAVProvider) propfind(target, depth string) ([]davPropfindResponse, error) {
+	req, err := http.NewRequest("PROPFIND", target, strings.NewReader(calDAVDiscoveryBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(p.Username, p.Password)
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Depth", depth)
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CalDAV discovery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMultiStatus:
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("invalid credentials")
+	case http.StatusTooManyRequests:
+		return nil, rateLimitResponse(resp)
+	default:
+		return nil, fmt.Errorf("CalDAV server returned status %d for %s", resp.StatusCode, target)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CalDAV response: %w", err)
+	}
+
+	var status struct {
+		Responses []davPropfindResponse `xml:"DAV: response"`
+	}
+	if err := xml.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse CalDAV response: %w", err)
+	}
+
+	return status.Responses, nil
+}
+
+// resolve turns an href from the server into an absolute URL
+func (p *CalDAVProvider) resolve(href string) (string, error) {
+	base, err := url.Parse(p.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid CalDAV URL %q: %w", p.BaseURL, err)
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", fmt.Errorf("invalid href %q from CalDAV server: %w", href, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+type davPropfindResponse struct {
+	Href      string `xml:"DAV: href"`
+	Propstats []struct {
+		Status string `xml:"DAV: status"`
+		Prop   struct {
+			DisplayName  string `xml:"DAV: displayname"`
+			ResourceType struct {
+				Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+			} `xml:"DAV: resourcetype"`
+			CurrentUserPrincipal davHref `xml:"DAV: current-user-principal"`
+			CalendarHomeSet      davHref `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+			Components           struct {
+				Comps []struct {
+					Name string `xml:"name,attr"`
+				} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+			} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+		} `xml:"DAV: prop"`
+	} `xml:"DAV: propstat"`
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+func (r davPropfindResponse) isCalendar() bool {
+	for _, propstat := range r.Propstats {
+		if propstat.Prop.ResourceType.Calendar != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsEvents reports whether the calendar accepts VEVENTs. Servers that
+// do not say are assumed to.
+func (r davPropfindResponse) holdsEvents() bool {
+	listed := false
+	for _, propstat := range r.Propstats {
+		for _, comp := range propstat.Prop.Components.Comps {
+			listed = true
+			if strings.EqualFold(comp.Name, "VEVENT") {
+				return true
+			}
+		}
+	}
+	return !listed
+}
+
+func (r davPropfindResponse) displayName() string {
+	for _, propstat := range r.Propstats {
+		if name := strings.TrimSpace(propstat.Prop.DisplayName); name != "" {
+			return name
+		}
+	}
+	return eventResourceName(strings.TrimSuffix(r.Href, "/"))
+}
@@ -1,7 +1,9 @@
 package sync
 
 import (
+	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -12,6 +14,7 @@ import (
 
 type CalendarSyncService struct {
 	calendarRepo CalendarEventRepository
+	cursorRepo   CursorRepository
 	httpClient   HTTPClient
 }
 
@@ -56,82 +59,10 @@ func NewCalendarSyncService(calendarRepo CalendarEventRepository, httpClient HTT
 	}
 }
 
+// SyncUserCalendar syncs one calendar with DefaultSyncOptions, retrying
+// requests the provider rate-limits
 func (s *CalendarSyncService) SyncUserCalendar(userID string, provider CalendarProvider) (*SyncResult, error) {
-	result := &SyncResult{
-		UserID:    userID,
-		StartTime: time.Now(),
-		Created:   0,
-		Updated:   0,
-		Deleted:   0,
-		Errors:    []string{},
-	}
-
-	if err := provider.ValidateCredentials(userID); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("credential validation failed: %v", err))
-		return result, err
-	}
-
-	start := time.Now().AddDate(0, -1, 0)
-	end := time.Now().AddDate(0, 3, 0)
-
-	externalEvents, err := provider.GetEvents(userID, start, end)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("failed to fetch events: %v", err))
-		return result, err
-	}
-
-	existingEvents, err := s.calendarRepo.GetEventsByUserIDAndTimeRange(userID, start, end)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("failed to get existing events: %v", err))
-		return result, err
-	}
-
-	externalMap := make(map[string]ExternalEvent)
-	for _, event := range externalEvents {
-		externalMap[event.ID] = event
-	}
-
-	existingMap := make(map[string]models.CalendarEvent)
-	for _, event := range existingEvents {
-		if event.ExternalID != "" {
-			existingMap[event.ExternalID] = event
-		}
-	}
-
-	for externalID, externalEvent := range externalMap {
-		if existingEvent, exists := existingMap[externalID]; exists {
-			if s.shouldUpdateEvent(existingEvent, externalEvent) {
-				updatedEvent := s.convertToInternalEvent(userID, externalEvent, &existingEvent.ID)
-				if err := s.calendarRepo.Update(updatedEvent); err != nil {
-					result.Errors = append(result.Errors, fmt.Sprintf("failed to update event %s: %v", externalID, err))
-				} else {
-					result.Updated++
-				}
-			}
-		} else {
-			newEvent := s.convertToInternalEvent(userID, externalEvent, nil)
-			if err := s.calendarRepo.Create(newEvent); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("failed to create event %s: %v", externalID, err))
-			} else {
-				result.Created++
-			}
-		}
-	}
-
-	for externalID, existingEvent := range existingMap {
-		if _, exists := externalMap[externalID]; !exists {
-			if err := s.calendarRepo.Delete(existingEvent.ID); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("failed to delete event %s: %v", externalID, err))
-			} else {
-				result.Deleted++
-			}
-		}
-	}
-
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
-
-	return result, nil
+	return s.syncCalendar(userID, provider, &providerLimiter{}, DefaultSyncOptions)
 }
 
 func (s *CalendarSyncService) CreateEventInExternalCalendar(userID string, eventID string, provider CalendarProvider) error {
@@ -256,6 +187,7 @@ func (s *CalendarSyncService) convertToInternalEvent(userID string, external Ext
 		EndAt:        external.EndTime,
 		Location:     stringPtr(external.Location),
 		IsAllDay:     external.AllDay,
+		IsBusy:       true,
 		ExternalID:   external.ID,
 		ProviderID:   external.Source,
 		LastSyncedAt: time.Now(),
@@ -306,6 +238,10 @@ type SyncResult struct {
 	Updated   int           `json:"updated"`
 	Deleted   int           `json:"deleted"`
 	Errors    []string      `json:"errors"`
+	// Incremental is set when only the changes since the stored cursor were
+	// applied, and Resynced when an expired cursor forced a full listing
+	Incremental bool `json:"incremental"`
+	Resynced    bool `json:"resynced"`
 }
 
 type TimeSlot struct {
@@ -313,11 +249,18 @@ type TimeSlot struct {
 	End   time.Time `json:"end"`
 }
 
+// CalDAVProvider reads and writes one CalDAV calendar, authenticating with
+// HTTP basic auth: the account password, or an app password where the
+// server requires one
 type CalDAVProvider struct {
 	BaseURL    string
 	Username   string
 	Password   string
 	HTTPClient HTTPClient
+	// ProviderID is stored as the provider of the calendar's events.
+	// Empty uses models.ProviderCalDAV; ForCalendar gives each calendar
+	// its own.
+	ProviderID string
 }
 
 func NewCalDAVProvider(baseURL, username, password string, httpClient HTTPClient) *CalDAVProvider {
@@ -329,6 +272,8 @@ func NewCalDAVProvider(baseURL, username, password string, httpClient HTTPClient
 	}
 }
 
+// GetEvents lists the events overlapping [start, end) with a calendar-query
+// REPORT. Events are identified by their resource name, as in GetChanges.
 func (p *CalDAVProvider) GetEvents(userID string, start, end time.Time) ([]ExternalEvent, error) {
 	reqBody := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" ?>
 <C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
@@ -343,7 +288,7 @@ func (p *CalDAVProvider) GetEvents(userID string, start, end time.Time) ([]Exter
             </C:comp-filter>
         </C:comp-filter>
     </C:filter>
-</C:calendar-query>`, start.Format("20060102T150405Z"), end.Format("20060102T150405Z"))
+</C:calendar-query>`, start.UTC().Format("20060102T150405Z"), end.UTC().Format("20060102T150405Z"))
 
 	req, err := http.NewRequest("REPORT", p.BaseURL, strings.NewReader(reqBody))
 	if err != nil {
@@ -360,11 +305,55 @@ func (p *CalDAVProvider) GetEvents(userID string, start, end time.Time) ([]Exter
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, rateLimitResponse(resp)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("invalid credentials")
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
 		return nil, fmt.Errorf("CalDAV server returned status %d", resp.StatusCode)
 	}
 
-	return []ExternalEvent{}, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CalDAV response: %w", err)
+	}
+
+	events := []ExternalEvent{}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return events, nil
+	}
+
+	var status davMultistatus
+	if err := xml.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse CalDAV response: %w", err)
+	}
+
+	for _, response := range status.Responses {
+		for _, propstat := range response.Propstats {
+			if propstat.Prop.CalendarData == "" {
+				continue
+			}
+			event, err := parseVEvent(propstat.Prop.CalendarData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse event %s: %w", response.Href, err)
+			}
+			event.ID = eventResourceName(response.Href)
+			event.URL = response.Href
+			event.Source = p.providerID()
+			events = append(events, *event)
+		}
+	}
+
+	return events, nil
+}
+
+func (p *CalDAVProvider) providerID() string {
+	if p.ProviderID == "" {
+		return models.ProviderCalDAV
+	}
+	return p.ProviderID
 }
 
 func (p *CalDAVProvider) CreateEvent(userID string, event ExternalEvent) (*ExternalEvent, error) {
@@ -458,6 +447,9 @@ func (p *CalDAVProvider) ValidateCredentials(userID string) error {
 	if resp.StatusCode == http.StatusUnauthorized {
 		return fmt.Errorf("invalid credentials")
 	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return rateLimitResponse(resp)
+	}
 
 	return nil
 }
@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ErrSyncTokenExpired is returned by an IncrementalProvider when the provider
+// no longer accepts a sync token and the calendar has to be listed in full
+var ErrSyncTokenExpired = errors.New("sync token expired: full resync required")
+
+// EventChanges is what changed in a calendar since a sync token, or the
+// whole calendar when no token was given
+type EventChanges struct {
+	Events []ExternalEvent
+	// Deleted lists the external IDs of events removed since the token
+	Deleted   []string
+	NextToken string
+}
+
+// IncrementalProvider is a CalendarProvider that can list only the changes
+// since an earlier sync, such as a Google sync token or a CalDAV
+// sync-collection report
+type IncrementalProvider interface {
+	CalendarProvider
+	// CursorKey names the provider the tokens belong to. It should match the
+	// Source of the events the provider returns.
+	CursorKey() string
+	// GetChanges returns the changes since token, or every event when token
+	// is empty. It returns ErrSyncTokenExpired when the token is no longer
+	// valid.
+	GetChanges(userID, token string) (*EventChanges, error)
+}
+
+// CursorRepository stores each user's sync token per provider
+type CursorRepository interface {
+	Get(userID, provider string) (*models.CalendarSyncCursor, error)
+	Save(cursor models.CalendarSyncCursor) error
+}
+
+// SetCursorRepository enables incremental sync for providers that implement
+// IncrementalProvider. Without it every sync is a full one.
+func (s *CalendarSyncService) SetCursorRepository(repo CursorRepository) {
+	s.cursorRepo = repo
+}
+
+// syncIncremental applies the changes since the stored cursor, falling back
+// to a full listing when there is no cursor or the provider has expired it.
+// The new cursor is stored only when every change was applied, so changes
+// that failed are fetched again next time.
+func (s *CalendarSyncService) syncIncremental(userID string, provider IncrementalProvider, limiter *providerLimiter, opts SyncOptions, result *SyncResult) error {
+	key := provider.CursorKey()
+	cursor, err := s.cursorRepo.Get(userID, key)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to get sync cursor: %v", err))
+		return err
+	}
+
+	if cursor != nil {
+		changes, err := s.fetchChanges(userID, provider, cursor.Token, limiter, opts)
+		if err == nil {
+			result.Incremental = true
+			s.applyChanges(userID, key, changes, result)
+			return s.saveCursor(userID, key, changes.NextToken, result)
+		}
+		if !errors.Is(err, ErrSyncTokenExpired) {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to fetch changes: %v", err))
+			return err
+		}
+		result.Resynced = true
+	}
+
+	changes, err := s.fetchChanges(userID, provider, "", limiter, opts)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to fetch events: %v", err))
+		return err
+	}
+
+	existingEvents, err := s.calendarRepo.GetByUserID(userID)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to get existing events: %v", err))
+		return err
+	}
+	existingMap := make(map[string]models.CalendarEvent)
+	for _, event := range existingEvents {
+		if event.ExternalID != "" && event.IsFromProvider(key) {
+			existingMap[event.ExternalID] = event
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range changes.Events {
+		seen[event.ID] = true
+		s.persistEvent(userID, event, existingMap, result)
+	}
+	for externalID, existingEvent := range existingMap {
+		if seen[externalID] {
+			continue
+		}
+		if err := s.calendarRepo.Delete(existingEvent.ID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to delete event %s: %v", externalID, err))
+		} else {
+			result.Deleted++
+		}
+	}
+
+	return s.saveCursor(userID, key, changes.NextToken, result)
+}
+
+func (s *CalendarSyncService) fetchChanges(userID string, provider IncrementalProvider, token string, limiter *providerLimiter, opts SyncOptions) (*EventChanges, error) {
+	var changes *EventChanges
+	err := limiter.call(opts, func() error {
+		var err error
+		changes, err = provider.GetChanges(userID, token)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// applyChanges persists a delta, looking each event up by its external ID.
+// Deleting an event that is already gone is not an error, so replaying a
+// delta is harmless.
+func (s *CalendarSyncService) applyChanges(userID, providerID string, changes *EventChanges, result *SyncResult) {
+	for _, event := range changes.Events {
+		existing := make(map[string]models.CalendarEvent)
+		if current := s.userEvent(userID, providerID, event.ID); current != nil {
+			existing[event.ID] = *current
+		}
+		s.persistEvent(userID, event, existing, result)
+	}
+
+	for _, externalID := range changes.Deleted {
+		current := s.userEvent(userID, providerID, externalID)
+		if current == nil {
+			continue
+		}
+		if err := s.calendarRepo.Delete(current.ID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to delete event %s: %v", externalID, err))
+		} else {
+			result.Deleted++
+		}
+	}
+}
+
+// ProviderEventFinder is implemented by event repositories that can look an
+// event up by its provider as well as its external ID. External IDs are only
+// unique within one provider, so the syncer prefers it when available.
+type ProviderEventFinder interface {
+	GetByProviderAndExternalID(userID, providerID, externalID string) (*models.CalendarEvent, error)
+}
+
+// userEvent returns the user's event from the provider with the external ID,
+// or nil
+func (s *CalendarSyncService) userEvent(userID, providerID, externalID string) *models.CalendarEvent {
+	if finder, ok := s.calendarRepo.(ProviderEventFinder); ok {
+		event, err := finder.GetByProviderAndExternalID(userID, providerID, externalID)
+		if err != nil {
+			return nil
+		}
+		return event
+	}
+
+	event, err := s.calendarRepo.GetByExternalID(externalID)
+	if err != nil || event == nil || event.UserID != userID {
+		return nil
+	}
+	return event
+}
+
+func (s *CalendarSyncService) saveCursor(userID, provider, token string, result *SyncResult) error {
+	if len(result.Errors) > 0 || token == "" {
+		return nil
+	}
+
+	cursor, err := models.NewCalendarSyncCursor(userID, provider, token)
+	if err != nil {
+		return err
+	}
+	if err := s.cursorRepo.Save(*cursor); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to save sync cursor: %v", err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	gosync "sync"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// SyncOptions bounds how hard a calendar sync presses on providers
+type SyncOptions struct {
+	// Concurrency is how many calendars sync at once
+	Concurrency int
+	// ProviderInterval is the minimum time between two requests to the same
+	// provider. Zero sends requests as fast as the workers allow.
+	ProviderInterval time.Duration
+	// MaxRetries is how many times a rate-limited request is retried
+	MaxRetries int
+	// MaxRetryAfter is the longest Retry-After the syncer waits out; a
+	// provider asking for longer fails the calendar instead
+	MaxRetryAfter time.Duration
+	// PastWindow and FutureWindow bound a full sync to events from
+	// PastWindow ago until FutureWindow from now
+	PastWindow   time.Duration
+	FutureWindow time.Duration
+}
+
+// DefaultSyncOptions are used for single-calendar syncs and fill in unset
+// options for SyncAll
+var DefaultSyncOptions = SyncOptions{
+	Concurrency:   4,
+	MaxRetries:    3,
+	MaxRetryAfter: 5 * time.Minute,
+	PastWindow:    30 * 24 * time.Hour,
+	FutureWindow:  90 * 24 * time.Hour,
+}
+
+func (o SyncOptions) withDefaults() SyncOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultSyncOptions.Concurrency
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.MaxRetryAfter <= 0 {
+		o.MaxRetryAfter = DefaultSyncOptions.MaxRetryAfter
+	}
+	if o.PastWindow <= 0 {
+		o.PastWindow = DefaultSyncOptions.PastWindow
+	}
+	if o.FutureWindow <= 0 {
+		o.FutureWindow = DefaultSyncOptions.FutureWindow
+	}
+	return o
+}
+
+// SyncJob is one user's calendar at one provider
+type SyncJob struct {
+	UserID   string
+	Provider CalendarProvider
+	// ProviderKey groups jobs that share a provider's rate limit, such as
+	// "google" or a CalDAV host. Jobs without a key are limited on their own.
+	ProviderKey string
+}
+
+// keyedProvider is implemented by providers that stamp their events' Source
+// with a key of their own
+type keyedProvider interface {
+	CursorKey() string
+}
+
+// EventStreamer is implemented by providers that page through events. The
+// syncer persists each event as it arrives instead of holding the whole
+// range in memory.
+type EventStreamer interface {
+	StreamEvents(userID string, start, end time.Time, fn func(ExternalEvent) error) error
+}
+
+// RateLimitError is returned by providers when the server answers 429
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by provider (retry after %s)", e.RetryAfter)
+}
+
+// ParseRetryAfter reads a Retry-After header given either as seconds or as
+// an HTTP date. It returns zero when the header is missing or invalid.
+func ParseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// rateLimitResponse turns a 429 response into a RateLimitError
+func rateLimitResponse(resp *http.Response) error {
+	return &RateLimitError{RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+}
+
+// providerLimiter spaces requests to one provider and holds them all back
+// while the provider has asked callers to wait
+type providerLimiter struct {
+	mu       gosync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *providerLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
+
+func (l *providerLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// call runs fn once the provider's limiter allows it, retrying when the
+// provider rate-limits the request
+func (l *providerLimiter) call(opts SyncOptions, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		l.wait()
+		err := fn()
+
+		var limited *RateLimitError
+		if !errors.As(err, &limited) || attempt >= opts.MaxRetries || limited.RetryAfter > opts.MaxRetryAfter {
+			return err
+		}
+		l.pause(limited.RetryAfter)
+	}
+}
+
+// SyncAll syncs every job with at most opts.Concurrency calendars in flight,
+// spacing and retrying requests per provider. Results are returned in job
+// order; a calendar that failed has its error in the result's Errors.
+func (s *CalendarSyncService) SyncAll(jobs []SyncJob, opts SyncOptions) []*SyncResult {
+	opts = opts.withDefaults()
+
+	limiters := make(map[string]*providerLimiter)
+	jobLimiters := make([]*providerLimiter, len(jobs))
+	for i, job := range jobs {
+		if job.ProviderKey == "" {
+			jobLimiters[i] = &providerLimiter{interval: opts.ProviderInterval}
+			continue
+		}
+		limiter, ok := limiters[job.ProviderKey]
+		if !ok {
+			limiter = &providerLimiter{interval: opts.ProviderInterval}
+			limiters[job.ProviderKey] = limiter
+		}
+		jobLimiters[i] = limiter
+	}
+
+	results := make([]*SyncResult, len(jobs))
+	queue := make(chan int)
+
+	var wg gosync.WaitGroup
+	for w := 0; w < opts.Concurrency && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i], _ = s.syncCalendar(jobs[i].UserID, jobs[i].Provider, jobLimiters[i], opts)
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// syncCalendar mirrors one provider's events into the repository, persisting
+// each event as it is read and deleting local events the provider no longer
+// has once the whole range has been seen. Incremental providers are synced
+// from their stored cursor instead.
+func (s *CalendarSyncService) syncCalendar(userID string, provider CalendarProvider, limiter *providerLimiter, opts SyncOptions) (*SyncResult, error) {
+	result := &SyncResult{
+		UserID:    userID,
+		StartTime: time.Now(),
+		Errors:    []string{},
+	}
+	finish := func() {
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+	}
+
+	if err := limiter.call(opts, func() error { return provider.ValidateCredentials(userID) }); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("credential validation failed: %v", err))
+		finish()
+		return result, err
+	}
+
+	if incremental, ok := provider.(IncrementalProvider); ok && s.cursorRepo != nil {
+		err := s.syncIncremental(userID, incremental, limiter, opts, result)
+		finish()
+		return result, err
+	}
+
+	now := time.Now()
+	start := now.Add(-opts.PastWindow)
+	end := now.Add(opts.FutureWindow)
+
+	existingEvents, err := s.calendarRepo.GetEventsByUserIDAndTimeRange(userID, start, end)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to get existing events: %v", err))
+		finish()
+		return result, err
+	}
+
+	// A provider that names its events only owns those, so syncing one
+	// calendar leaves the user's other calendars alone
+	keyed, scoped := provider.(keyedProvider)
+	existingMap := make(map[string]models.CalendarEvent)
+	for _, event := range existingEvents {
+		if event.ExternalID == "" || (scoped && !event.IsFromProvider(keyed.CursorKey())) {
+			continue
+		}
+		existingMap[event.ExternalID] = event
+	}
+
+	seen := make(map[string]bool)
+	persist := func(externalEvent ExternalEvent) error {
+		seen[externalEvent.ID] = true
+		s.persistEvent(userID, externalEvent, existingMap, result)
+		return nil
+	}
+
+	err = limiter.call(opts, func() error {
+		if streamer, ok := provider.(EventStreamer); ok {
+			return streamer.StreamEvents(userID, start, end, persist)
+		}
+		events, err := provider.GetEvents(userID, start, end)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			persist(event)
+		}
+		return nil
+	})
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to fetch events: %v", err))
+		finish()
+		return result, err
+	}
+
+	for externalID, existingEvent := range existingMap {
+		if seen[externalID] {
+			continue
+		}
+		if err := s.calendarRepo.Delete(existingEvent.ID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to delete event %s: %v", externalID, err))
+		} else {
+			result.Deleted++
+		}
+	}
+
+	finish()
+	return result, nil
+}
+
+// persistEvent creates or updates the local copy of one external event. A
+// retried fetch may deliver an event twice, so new events are remembered in
+// existing.
+func (s *CalendarSyncService) persistEvent(userID string, externalEvent ExternalEvent, existing map[string]models.CalendarEvent, result *SyncResult) {
+	if existingEvent, ok := existing[externalEvent.ID]; ok {
+		if !s.shouldUpdateEvent(existingEvent, externalEvent) {
+			return
+		}
+		updatedEvent := s.convertToInternalEvent(userID, externalEvent, &existingEvent.ID)
+		if err := s.calendarRepo.Update(updatedEvent); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to update event %s: %v", externalEvent.ID, err))
+			return
+		}
+		existing[externalEvent.ID] = updatedEvent
+		result.Updated++
+		return
+	}
+
+	newEvent := s.convertToInternalEvent(userID, externalEvent, nil)
+	if err := s.calendarRepo.Create(newEvent); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to create event %s: %v", externalEvent.ID, err))
+		return
+	}
+	existing[externalEvent.ID] = newEvent
+	result.Created++
+}
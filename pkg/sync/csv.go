@@ -0,0 +1,199 @@
+package sync
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TaskCSVColumns are the columns ExportTasksCSV writes, in order
+var TaskCSVColumns = []string{"title", "description", "status", "priority", "estimated_minutes", "due_at", "tags"}
+
+// taskCSVTagSeparator joins a task's tags in the tags column
+const taskCSVTagSeparator = ";"
+
+// ExportTasksCSV writes tasks as CSV with a header row of TaskCSVColumns.
+// Due dates are RFC 3339 and tags are joined with ";". The file is read back
+// by ImportTasksCSV.
+func ExportTasksCSV(w io.Writer, tasks []models.Task) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(TaskCSVColumns); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, task := range tasks {
+		estimate := ""
+		if task.EstimatedMinutes != nil {
+			estimate = strconv.Itoa(*task.EstimatedMinutes)
+		}
+		due := ""
+		if task.DueAt != nil {
+			due = task.DueAt.Format(time.RFC3339)
+		}
+
+		record := []string{
+			task.Title,
+			task.Description,
+			string(task.Status),
+			strconv.Itoa(task.Priority),
+			estimate,
+			due,
+			strings.Join(task.Tags, taskCSVTagSeparator),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write task %q: %w", task.Title, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportTasksCSV reads tasks for the user from CSV in the layout
+// ExportTasksCSV writes. Columns are matched by name, ignoring case and
+// order; only title is required, and unknown columns are ignored. Each row
+// is validated as the task it becomes, and the first invalid one fails the
+// whole import with its row number, counting the header as row 1. The tasks
+// are not saved.
+func ImportTasksCSV(r io.Reader, userID string) ([]models.Task, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("row 1: file has no header row")
+		}
+		return nil, fmt.Errorf("row 1: unreadable header row: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if _, seen := index[name]; !seen {
+			index[name] = i
+		}
+	}
+	if _, ok := index["title"]; !ok {
+		return nil, fmt.Errorf("row 1: header has no title column")
+	}
+
+	var tasks []models.Task
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: unreadable row: %w", row, err)
+		}
+
+		field := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		task, err := taskFromCSV(field, userID)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		tasks = append(tasks, *task)
+	}
+}
+
+// IsTasksCSV reports whether data starts with a header naming every one of
+// TaskCSVColumns, as files written by ExportTasksCSV do
+func IsTasksCSV(data string) bool {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "\ufeff")))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return false
+	}
+
+	names := make(map[string]bool, len(header))
+	for _, name := range header {
+		names[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, column := range TaskCSVColumns {
+		if !names[column] {
+			return false
+		}
+	}
+	return true
+}
+
+// taskFromCSV builds the task a row describes, through the same setters and
+// validation as any other task
+func taskFromCSV(field func(string) string, userID string) (*models.Task, error) {
+	task, err := models.NewTask(strings.TrimSpace(field("title")), field("description"), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if value := strings.TrimSpace(field("status")); value != "" {
+		status := models.TaskStatus(strings.ToLower(value))
+		if !status.IsValid() {
+			return nil, fmt.Errorf("invalid status %q", value)
+		}
+		// A new task starts pending, and not every status can be reached
+		// from there, so the status is set rather than transitioned to
+		task.Status = status
+		if status == models.TaskStatusCompleted {
+			completedAt := task.CreatedAt
+			task.CompletedAt = &completedAt
+		}
+	}
+
+	if value := strings.TrimSpace(field("priority")); value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority %q", value)
+		}
+		if err := task.SetPriority(priority); err != nil {
+			return nil, err
+		}
+	}
+
+	if value := strings.TrimSpace(field("estimated_minutes")); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid estimated minutes %q", value)
+		}
+		if err := task.SetEstimatedMinutes(minutes); err != nil {
+			return nil, err
+		}
+	}
+
+	if value := strings.TrimSpace(field("due_at")); value != "" {
+		due, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid due date %q (want RFC 3339)", value)
+		}
+		task.SetDueDate(due)
+	}
+
+	if value := strings.TrimSpace(field("tags")); value != "" {
+		tags, err := models.NormalizeTags(strings.Split(value, taskCSVTagSeparator))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tags %q: %w", value, err)
+		}
+		task.Tags = tags
+	}
+
+	if err := task.Validate(); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
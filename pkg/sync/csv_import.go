@@ -0,0 +1,250 @@
+package sync
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
+)
+
+// CSV columns ParseCSV reads, matched case-insensitively against the header
+// row. Unknown columns are ignored.
+var csvColumns = map[string]string{
+	"title":             "title",
+	"name":              "title",
+	"description":       "description",
+	"notes":             "description",
+	"priority":          "priority",
+	"due":               "due",
+	"due_date":          "due",
+	"due_at":            "due",
+	"estimated_minutes": "estimated_minutes",
+	"estimate":          "estimated_minutes",
+	"location":          "location",
+	"location_name":     "location",
+	"latitude":          "latitude",
+	"longitude":         "longitude",
+}
+
+// Columns of Todoist's CSV export and template that ParseCSV reads
+var todoistColumns = map[string]string{
+	"type":          "type",
+	"content":       "title",
+	"description":   "description",
+	"priority":      "todoist_priority",
+	"date":          "todoist_date",
+	"duration":      "duration",
+	"duration_unit": "duration_unit",
+}
+
+// ParseCSV reads tasks from a CSV file with a header row. A generic file has
+// title, description, priority (1-5), due (YYYY-MM-DD or YYYY-MM-DD HH:MM),
+// estimated_minutes, location, latitude and longitude columns, of which only
+// title is required. A header with TYPE and CONTENT columns is read as a
+// Todoist export instead, and the report's format is "todoist". Rows with
+// unreadable fields fail, in the returned report; loc is used for due dates.
+func ParseCSV(data, source string, loc *time.Location) ([]ImportedTask, *ImportReport) {
+	report := NewImportReport("csv", source)
+
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "\ufeff")))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			report.Fail(1, "", "file has no header row")
+		} else {
+			report.Fail(1, "", fmt.Sprintf("unreadable header row: %v", err))
+		}
+		return nil, report
+	}
+
+	columns := csvColumns
+	if isTodoistHeader(header) {
+		columns = todoistColumns
+		report.Format = "todoist"
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if field, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, seen := index[field]; !seen {
+				index[field] = i
+			}
+		}
+	}
+	if _, ok := index["title"]; !ok {
+		report.Fail(1, "", "header has no title column")
+		return nil, report
+	}
+
+	var tasks []ImportedTask
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			report.Fail(line, "", fmt.Sprintf("unreadable row: %v", err))
+			continue
+		}
+
+		row := make(map[string]string, len(index))
+		empty := true
+		for field, i := range index {
+			if i < len(record) {
+				row[field] = strings.TrimSpace(record[i])
+				empty = empty && row[field] == ""
+			}
+		}
+		if empty {
+			continue
+		}
+
+		title := row["title"]
+		if report.Format == "todoist" && !strings.EqualFold(row["type"], "task") {
+			report.Skip(line, title, fmt.Sprintf("not a task (TYPE %q)", row["type"]))
+			continue
+		}
+		if title == "" {
+			report.Fail(line, "", "row has no title")
+			continue
+		}
+
+		task := ImportedTask{Line: line, Title: title, Description: row["description"], Priority: 3}
+		if err := applyCSVRow(&task, row, loc); err != nil {
+			report.Fail(line, title, err.Error())
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, report
+}
+
+func isTodoistHeader(header []string) bool {
+	names := make(map[string]bool, len(header))
+	for _, name := range header {
+		names[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return names["type"] && names["content"]
+}
+
+func applyCSVRow(task *ImportedTask, row map[string]string, loc *time.Location) error {
+	if value := row["priority"]; value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > 5 {
+			return fmt.Errorf("invalid priority %q (want 1-5)", value)
+		}
+		task.Priority = priority
+	}
+
+	if value := row["todoist_priority"]; value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > 4 {
+			return fmt.Errorf("invalid Todoist priority %q (want 1-4)", value)
+		}
+		task.Priority = priorityFromTodoistCSV(priority)
+	}
+
+	if value := row["due"]; value != "" {
+		due, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			due, err = parseImportDate(value, loc)
+		}
+		if err != nil {
+			return err
+		}
+		task.DueAt = &due
+	}
+
+	if value := row["todoist_date"]; value != "" {
+		if err := applyTodoistDate(task, value, loc); err != nil {
+			return err
+		}
+	}
+
+	if value := row["estimated_minutes"]; value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes <= 0 {
+			return fmt.Errorf("invalid estimated minutes %q (want a positive number)", value)
+		}
+		task.EstimatedMinutes = &minutes
+	}
+
+	if value := row["duration"]; value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes <= 0 {
+			return fmt.Errorf("invalid duration %q (want a positive number)", value)
+		}
+		switch unit := strings.ToLower(row["duration_unit"]); unit {
+		case "", "minute":
+		case "day":
+			minutes *= 24 * 60
+		default:
+			return fmt.Errorf("invalid duration unit %q (want minute or day)", unit)
+		}
+		task.EstimatedMinutes = &minutes
+	}
+
+	task.Location = row["location"]
+	latitude, longitude := row["latitude"], row["longitude"]
+	if latitude != "" || longitude != "" {
+		lat, latErr := strconv.ParseFloat(latitude, 64)
+		lng, lngErr := strconv.ParseFloat(longitude, 64)
+		if latErr != nil || lngErr != nil {
+			return fmt.Errorf("invalid coordinates %q, %q (want both latitude and longitude as numbers)", latitude, longitude)
+		}
+		task.Latitude, task.Longitude = &lat, &lng
+	}
+
+	return nil
+}
+
+// priorityFromTodoistCSV maps the priority in Todoist's CSV files, where 1
+// is p1 (urgent) and 4 is p4 (normal), onto this app's 1 to 5 so that it
+// reverses TodoistPriority
+func priorityFromTodoistCSV(priority int) int {
+	switch priority {
+	case 1:
+		return 5
+	case 2:
+		return 4
+	case 3:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// applyTodoistDate reads a Todoist DATE column: a date, or a recurring date
+// such as "every monday", which becomes the task's recurrence. Other natural
+// language dates ("tomorrow") cannot be read and fail the row.
+func applyTodoistDate(task *ImportedTask, value string, loc *time.Location) error {
+	if strings.HasPrefix(strings.ToLower(value), "every ") {
+		rule, err := recurrence.ParseEvery(value)
+		if err != nil {
+			return fmt.Errorf("invalid recurring date %q: %w", value, err)
+		}
+		ruleText := rule.String()
+		task.RecurrenceRule = &ruleText
+		return nil
+	}
+
+	due, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		due, err = parseImportDate(value, loc)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid date %q (want YYYY-MM-DD, YYYY-MM-DD HH:MM or \"every ...\")", value)
+	}
+	task.DueAt = &due
+	return nil
+}
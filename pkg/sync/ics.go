@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
+)
+
+// ICSContentType is the media type of an iCalendar feed
+const ICSContentType = "text/calendar; charset=utf-8"
+
+// icsLineLimit is the longest a content line may be, in octets, before it
+// is folded (RFC 5545 section 3.1)
+const icsLineLimit = 75
+
+// ICSFeed is what ExportICS writes into one calendar
+type ICSFeed struct {
+	// Name is shown by calendar apps as the calendar's title
+	Name  string
+	Tasks []models.Task
+	// Locations maps task IDs to the locations linked to each task
+	Locations map[string][]models.Location
+	// Events are written as they are, e.g. to share a synced schedule
+	Events []models.CalendarEvent
+}
+
+// ExportICS renders the feed as an iCalendar (RFC 5545) file that calendar
+// apps can import or subscribe to. Open tasks with a due date become events
+// that end at the due time and start the estimated duration before it, so
+// they show up in calendar views; a due time of midnight is an all-day
+// event. Open tasks without a due date become to-dos without DUE. Completed
+// and cancelled tasks are left out, so a subscribed calendar drops tasks as
+// they are done. A task's valid recurrence rule becomes its RRULE and its
+// location names its LOCATION. now stamps every entry.
+func ExportICS(feed ICSFeed, now time.Time) string {
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//Here and Now//Task Export//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	if feed.Name != "" {
+		w.line("X-WR-CALNAME", icsText(feed.Name))
+	}
+
+	stamp := icsUTC(now)
+	for _, task := range feed.Tasks {
+		if task.IsCompleted() || task.IsCancelled() {
+			continue
+		}
+		writeICSTask(w, task, feed.Locations[task.ID], stamp)
+	}
+	for _, event := range feed.Events {
+		writeICSEvent(w, event, stamp)
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.sb.String()
+}
+
+func writeICSTask(w *icsWriter, task models.Task, locations []models.Location, stamp string) {
+	component := "VTODO"
+	if task.DueAt != nil {
+		component = "VEVENT"
+	}
+
+	w.line("BEGIN", component)
+	w.line("UID", task.ID+"@hereandnow")
+	w.line("DTSTAMP", stamp)
+	w.line("LAST-MODIFIED", icsUTC(task.UpdatedAt))
+	w.line("SUMMARY", icsText(task.Title))
+	if task.Description != "" {
+		w.line("DESCRIPTION", icsText(task.Description))
+	}
+	w.line("PRIORITY", fmt.Sprintf("%d", icsPriority(task.Priority)))
+
+	if task.DueAt != nil {
+		due := *task.DueAt
+		if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+			w.line("DTSTART;VALUE=DATE", due.Format("20060102"))
+			w.line("DTEND;VALUE=DATE", due.AddDate(0, 0, 1).Format("20060102"))
+		} else {
+			start := due
+			if task.EstimatedMinutes != nil && *task.EstimatedMinutes > 0 {
+				start = due.Add(-time.Duration(*task.EstimatedMinutes) * time.Minute)
+			}
+			w.line("DTSTART", icsUTC(start))
+			w.line("DTEND", icsUTC(due))
+		}
+
+		if task.RecurrenceRule != nil {
+			if rule, err := recurrence.Parse(*task.RecurrenceRule); err == nil {
+				w.line("RRULE", rule.String())
+			}
+		}
+	} else {
+		w.line("STATUS", "NEEDS-ACTION")
+		if task.EstimatedMinutes != nil && *task.EstimatedMinutes > 0 {
+			// DURATION needs a DTSTART on a to-do, so the estimate is
+			// carried in an extension property instead
+			w.line("X-HEREANDNOW-ESTIMATED-MINUTES", fmt.Sprintf("%d", *task.EstimatedMinutes))
+		}
+	}
+
+	if len(locations) > 0 {
+		names := make([]string, 0, len(locations))
+		for _, location := range locations {
+			names = append(names, location.Name)
+		}
+		w.line("LOCATION", icsText(strings.Join(names, ", ")))
+		w.line("GEO", fmt.Sprintf("%f;%f", locations[0].Latitude, locations[0].Longitude))
+	}
+
+	w.line("END", component)
+}
+
+func writeICSEvent(w *icsWriter, event models.CalendarEvent, stamp string) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", event.ID+"@hereandnow")
+	w.line("DTSTAMP", stamp)
+	w.line("SUMMARY", icsText(event.Title))
+	if event.IsAllDay {
+		w.line("DTSTART;VALUE=DATE", event.StartAt.Format("20060102"))
+		w.line("DTEND;VALUE=DATE", event.EndAt.Format("20060102"))
+	} else {
+		w.line("DTSTART", icsUTC(event.StartAt))
+		w.line("DTEND", icsUTC(event.EndAt))
+	}
+	if event.Location != nil && *event.Location != "" {
+		w.line("LOCATION", icsText(*event.Location))
+	}
+	if !event.IsBusy {
+		w.line("TRANSP", "TRANSPARENT")
+	}
+	w.line("END", "VEVENT")
+}
+
+// icsPriority maps this app's 1 (lowest) to 5 (highest) priority onto
+// iCalendar's 9 (lowest) to 1 (highest)
+func icsPriority(priority int) int {
+	switch {
+	case priority >= 5:
+		return 1
+	case priority == 4:
+		return 3
+	case priority == 3:
+		return 5
+	case priority == 2:
+		return 7
+	default:
+		return 9
+	}
+}
+
+func icsUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsText escapes a TEXT value (RFC 5545 section 3.3.11)
+func icsText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(value)
+}
+
+// icsWriter writes CRLF-terminated content lines, folding long ones
+type icsWriter struct {
+	sb strings.Builder
+}
+
+func (w *icsWriter) line(name, value string) {
+	line := name + ":" + value
+	for len(line) > icsLineLimit {
+		cut := icsLineLimit
+		// Never split a UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.sb.WriteString(line[:cut] + "\r\n")
+		// Continuation lines start with a space, which counts toward the
+		// limit
+		line = " " + line[cut:]
+	}
+	w.sb.WriteString(line + "\r\n")
+}
@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"sort"
+)
+
+// ImportStatus is what happened to one record of an import
+type ImportStatus string
+
+const (
+	ImportStatusImported ImportStatus = "imported"
+	ImportStatusSkipped  ImportStatus = "skipped"
+	ImportStatusFailed   ImportStatus = "failed"
+)
+
+// ImportRecord is the outcome of one record. Line is the record's 1-based
+// line in the source file, or its 1-based position for formats that are not
+// line oriented.
+type ImportRecord struct {
+	Line   int          `json:"line"`
+	Status ImportStatus `json:"status"`
+	Title  string       `json:"title,omitempty"`
+	TaskID string       `json:"task_id,omitempty"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// ImportReport collects the outcome of every record in an import, in source
+// order, so users can see what failed and where
+type ImportReport struct {
+	Format   string         `json:"format"`
+	Source   string         `json:"source,omitempty"`
+	Imported int            `json:"imported"`
+	Skipped  int            `json:"skipped"`
+	Failed   int            `json:"failed"`
+	Records  []ImportRecord `json:"records"`
+}
+
+func NewImportReport(format, source string) *ImportReport {
+	return &ImportReport{
+		Format:  format,
+		Source:  source,
+		Records: []ImportRecord{},
+	}
+}
+
+// Import records a task created from the record at line
+func (r *ImportReport) Import(line int, title, taskID string) {
+	r.Imported++
+	r.add(ImportRecord{Line: line, Status: ImportStatusImported, Title: title, TaskID: taskID})
+}
+
+// Skip records a record that was deliberately left out
+func (r *ImportReport) Skip(line int, title, reason string) {
+	r.Skipped++
+	r.add(ImportRecord{Line: line, Status: ImportStatusSkipped, Title: title, Reason: reason})
+}
+
+// Fail records a record that could not be imported
+func (r *ImportReport) Fail(line int, title, reason string) {
+	r.Failed++
+	r.add(ImportRecord{Line: line, Status: ImportStatusFailed, Title: title, Reason: reason})
+}
+
+// HasFailures reports whether any record failed
+func (r *ImportReport) HasFailures() bool {
+	return r.Failed > 0
+}
+
+// add keeps records in source order, since parsing and saving report on
+// different passes over the file
+func (r *ImportReport) add(record ImportRecord) {
+	r.Records = append(r.Records, record)
+	sort.SliceStable(r.Records, func(i, j int) bool {
+		return r.Records[i].Line < r.Records[j].Line
+	})
+}
@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ExportMarkdown renders tasks as a Markdown checklist with one section per
+// list, in the order lists first appear. Tasks without a list go under
+// "Inbox". locations maps task IDs to the locations linked to each task.
+func ExportMarkdown(tasks []models.Task, lists []models.TaskList, locations map[string][]models.Location) string {
+	listNames := make(map[string]string, len(lists))
+	for _, list := range lists {
+		listNames[list.ID] = list.Name
+	}
+
+	var sections []string
+	sectionTasks := make(map[string][]models.Task)
+	for _, task := range tasks {
+		section := TodoistInboxProject
+		if task.ListID != nil && *task.ListID != "" {
+			section = *task.ListID
+			if name, ok := listNames[section]; ok {
+				section = name
+			}
+		}
+		if _, seen := sectionTasks[section]; !seen {
+			sections = append(sections, section)
+		}
+		sectionTasks[section] = append(sectionTasks[section], task)
+	}
+
+	var sb strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n\n", section)
+		for _, task := range sectionTasks[section] {
+			writeMarkdownTask(&sb, task, locations[task.ID])
+		}
+	}
+	return sb.String()
+}
+
+func writeMarkdownTask(sb *strings.Builder, task models.Task, locations []models.Location) {
+	check := " "
+	if task.Status == models.TaskStatusCompleted {
+		check = "x"
+	}
+	fmt.Fprintf(sb, "- [%s] %s\n", check, task.Title)
+
+	if task.DueAt != nil {
+		due := task.DueAt.Format("2006-01-02")
+		if task.DueAt.Hour() != 0 || task.DueAt.Minute() != 0 {
+			due = task.DueAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(sb, "  - Due: %s\n", due)
+	}
+	fmt.Fprintf(sb, "  - Priority: %d\n", task.Priority)
+	if task.AssigneeID != nil && *task.AssigneeID != "" {
+		fmt.Fprintf(sb, "  - Assignee: %s\n", *task.AssigneeID)
+	}
+	for _, location := range locations {
+		fmt.Fprintf(sb, "  - Location: %s (%s, %s)", location.Name,
+			strconv.FormatFloat(location.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(location.Longitude, 'f', -1, 64))
+		if location.Address != "" {
+			fmt.Fprintf(sb, ", %s", location.Address)
+		}
+		sb.WriteString("\n")
+	}
+	if labels := taskLabels(task); len(labels) > 0 {
+		fmt.Fprintf(sb, "  - Tags: %s\n", strings.Join(labels, ", "))
+	}
+	if task.Description != "" {
+		for _, line := range strings.Split(strings.TrimSpace(task.Description), "\n") {
+			fmt.Fprintf(sb, "  > %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ImportedTask is a task read from an import file that has not been saved
+// yet. Line is where it starts in the source, for the ImportReport.
+type ImportedTask struct {
+	Line             int
+	Title            string
+	Description      string
+	Priority         int
+	DueAt            *time.Time
+	EstimatedMinutes *int
+	RecurrenceRule   *string
+	Tags             []string
+	// Status is the status the task is created with; empty means pending
+	Status models.TaskStatus
+	// Location names one of the user's locations to link the task to.
+	// Latitude and Longitude, when both are set, place it if it has to be
+	// created.
+	Location  string
+	Latitude  *float64
+	Longitude *float64
+}
+
+// ParseMarkdown reads a Markdown checklist in the layout ExportMarkdown
+// writes. Open items ("- [ ] Title") become tasks; their "Due", "Priority"
+// and "Tags" sub-items and "> " quoted lines are read, other sub-items are
+// ignored. Completed items and plain bullets are skipped, and items with
+// unreadable fields fail, in the returned report. loc is used for due dates.
+func ParseMarkdown(data, source string, loc *time.Location) ([]ImportedTask, *ImportReport) {
+	report := NewImportReport("markdown", source)
+	var tasks []ImportedTask
+
+	var current *ImportedTask
+	var description []string
+	failed := false
+	finish := func() {
+		if current != nil && !failed {
+			current.Description = strings.Join(description, "\n")
+			tasks = append(tasks, *current)
+		}
+		current, description, failed = nil, nil, false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+
+		case strings.HasPrefix(text, "- "):
+			finish()
+			item := strings.TrimPrefix(text, "- ")
+			title := strings.TrimSpace(item[min(len(item), 3):])
+			switch {
+			case strings.HasPrefix(item, "[ ]"):
+				if title == "" {
+					report.Fail(line, "", "checklist item has no title")
+					continue
+				}
+				current = &ImportedTask{Line: line, Title: title, Priority: 3}
+			case strings.HasPrefix(item, "[x]"), strings.HasPrefix(item, "[X]"):
+				report.Skip(line, title, "already completed")
+			default:
+				report.Skip(line, strings.TrimSpace(item), `not a checklist item (want "- [ ] Title")`)
+			}
+
+		case current == nil:
+			// Prose between items, or details of a skipped item
+			continue
+
+		case strings.HasPrefix(trimmed, ">"):
+			description = append(description, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+
+		case strings.HasPrefix(trimmed, "- "):
+			if failed {
+				continue
+			}
+			if err := applyMarkdownField(current, strings.TrimPrefix(trimmed, "- "), loc); err != nil {
+				report.Fail(line, current.Title, err.Error())
+				failed = true
+			}
+		}
+	}
+	finish()
+
+	return tasks, report
+}
+
+func applyMarkdownField(task *ImportedTask, field string, loc *time.Location) error {
+	name, value, ok := strings.Cut(field, ":")
+	if !ok {
+		return nil
+	}
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "due":
+		due, err := parseImportDate(value, loc)
+		if err != nil {
+			return err
+		}
+		task.DueAt = &due
+	case "priority":
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > 5 {
+			return fmt.Errorf("invalid priority %q (want 1-5)", value)
+		}
+		task.Priority = priority
+	case "tags":
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// parseImportDate reads a due date written as YYYY-MM-DD or YYYY-MM-DD HH:MM
+// in loc
+func parseImportDate(value string, loc *time.Location) (time.Time, error) {
+	due, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		due, err = time.ParseInLocation("2006-01-02", value, loc)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date %q (want YYYY-MM-DD or YYYY-MM-DD HH:MM)", value)
+	}
+	return due, nil
+}
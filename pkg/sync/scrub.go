@@ -0,0 +1,72 @@
+package sync
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// ScrubCoordinateDecimals is how many decimal places scrubbed coordinates
+// keep. Two places is roughly 1 km, enough to tell neighbourhoods apart
+// without pinpointing a home.
+const ScrubCoordinateDecimals = 2
+
+// ScrubTask strips the fields of a task that should not leave the app when
+// an export is shared publicly:
+//   - the creator and assignee are removed
+//   - the description is removed when task metadata has "private": true
+//   - metadata is reduced to its "tags" array, which may still become labels
+//
+// Title, status, priority, due date, list and parent are kept.
+func ScrubTask(task models.Task) models.Task {
+	var metadata struct {
+		Private bool            `json:"private"`
+		Tags    json.RawMessage `json:"tags,omitempty"`
+	}
+	if len(task.Metadata) > 0 {
+		json.Unmarshal(task.Metadata, &metadata)
+	}
+
+	task.CreatorID = ""
+	task.AssigneeID = nil
+	if metadata.Private {
+		task.Description = ""
+	}
+
+	task.Metadata = json.RawMessage(`{}`)
+	if len(metadata.Tags) > 0 {
+		if scrubbed, err := json.Marshal(map[string]json.RawMessage{"tags": metadata.Tags}); err == nil {
+			task.Metadata = scrubbed
+		}
+	}
+
+	return task
+}
+
+// ScrubTasks scrubs each task with ScrubTask
+func ScrubTasks(tasks []models.Task) []models.Task {
+	scrubbed := make([]models.Task, len(tasks))
+	for i, task := range tasks {
+		scrubbed[i] = ScrubTask(task)
+	}
+	return scrubbed
+}
+
+// ScrubLocation coarsens a location for a public export: coordinates are
+// rounded to ScrubCoordinateDecimals, and the owner, street address, place
+// ID and metadata are removed. The name, radius and category are kept.
+func ScrubLocation(location models.Location) models.Location {
+	location.UserID = ""
+	location.Address = ""
+	location.PlaceID = nil
+	location.Metadata = json.RawMessage(`{}`)
+	location.Latitude = roundCoordinate(location.Latitude)
+	location.Longitude = roundCoordinate(location.Longitude)
+	return location
+}
+
+func roundCoordinate(coordinate float64) float64 {
+	scale := math.Pow(10, ScrubCoordinateDecimals)
+	return math.Round(coordinate*scale) / scale
+}
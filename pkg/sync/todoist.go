@@ -0,0 +1,163 @@
+package sync
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// TodoistInboxProject is the project name used for tasks that are not in a list
+const TodoistInboxProject = "Inbox"
+
+// TodoistExport mirrors the items/projects/labels layout of Todoist's import format
+type TodoistExport struct {
+	Projects []TodoistProject `json:"projects"`
+	Items    []TodoistItem    `json:"items"`
+	Labels   []TodoistLabel   `json:"labels"`
+}
+
+type TodoistProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type TodoistLabel struct {
+	Name string `json:"name"`
+}
+
+type TodoistItem struct {
+	ID          string      `json:"id"`
+	Content     string      `json:"content"`
+	Description string      `json:"description"`
+	Priority    int         `json:"priority"`
+	Due         *TodoistDue `json:"due"`
+	Labels      []string    `json:"labels"`
+	ProjectID   string      `json:"project_id"`
+	ParentID    *string     `json:"parent_id"`
+	Checked     bool        `json:"checked"`
+}
+
+type TodoistDue struct {
+	Date     string `json:"date"`
+	Datetime string `json:"datetime,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// TodoistPriority maps this app's 1 (lowest) to 5 (highest) priority onto
+// Todoist's 1 (normal) to 4 (urgent). Priorities 1 and 2 both become normal;
+// out of range values are clamped.
+func TodoistPriority(priority int) int {
+	switch {
+	case priority >= 5:
+		return 4
+	case priority == 4:
+		return 3
+	case priority == 3:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ExportTodoist converts tasks into Todoist's import structure. Each list
+// becomes a project, tasks without a list go to the Inbox project, and the
+// "tags" array in task metadata becomes labels.
+func ExportTodoist(tasks []models.Task, lists []models.TaskList) TodoistExport {
+	export := TodoistExport{
+		Projects: []TodoistProject{},
+		Items:    make([]TodoistItem, 0, len(tasks)),
+		Labels:   []TodoistLabel{},
+	}
+
+	listNames := make(map[string]string, len(lists))
+	for _, list := range lists {
+		listNames[list.ID] = list.Name
+	}
+
+	seenProjects := make(map[string]bool)
+	seenLabels := make(map[string]bool)
+
+	for _, task := range tasks {
+		projectID := TodoistInboxProject
+		projectName := TodoistInboxProject
+		if task.ListID != nil && *task.ListID != "" {
+			projectID = *task.ListID
+			if name, ok := listNames[projectID]; ok {
+				projectName = name
+			} else {
+				projectName = projectID
+			}
+		}
+
+		if !seenProjects[projectID] {
+			seenProjects[projectID] = true
+			export.Projects = append(export.Projects, TodoistProject{ID: projectID, Name: projectName})
+		}
+
+		labels := taskLabels(task)
+		for _, label := range labels {
+			if !seenLabels[label] {
+				seenLabels[label] = true
+				export.Labels = append(export.Labels, TodoistLabel{Name: label})
+			}
+		}
+
+		export.Items = append(export.Items, TodoistItem{
+			ID:          task.ID,
+			Content:     task.Title,
+			Description: task.Description,
+			Priority:    TodoistPriority(task.Priority),
+			Due:         todoistDue(task.DueAt),
+			Labels:      labels,
+			ProjectID:   projectID,
+			ParentID:    task.ParentTaskID,
+			Checked:     task.Status == models.TaskStatusCompleted,
+		})
+	}
+
+	return export
+}
+
+func todoistDue(dueAt *time.Time) *TodoistDue {
+	if dueAt == nil {
+		return nil
+	}
+
+	due := &TodoistDue{Date: dueAt.Format("2006-01-02")}
+
+	// A due time of exactly midnight is treated as a date-only due date
+	if dueAt.Hour() != 0 || dueAt.Minute() != 0 || dueAt.Second() != 0 {
+		due.Datetime = dueAt.UTC().Format("2006-01-02T15:04:05Z")
+		if name := dueAt.Location().String(); name != "Local" && name != "UTC" {
+			due.Timezone = name
+		}
+	}
+
+	return due
+}
+
+// taskLabels reads the "tags" array from task metadata. Todoist labels cannot
+// contain spaces, so they are replaced with underscores.
+func taskLabels(task models.Task) []string {
+	labels := []string{}
+	if len(task.Metadata) == 0 {
+		return labels
+	}
+
+	var metadata struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(task.Metadata, &metadata); err != nil {
+		return labels
+	}
+
+	for _, tag := range metadata.Tags {
+		tag = strings.Join(strings.Fields(tag), "_")
+		if tag != "" {
+			labels = append(labels, tag)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,42 @@
+// Package truncate caps how many items a listing shows on screen. It is a
+// display limit, separate from API pagination: every item is still fetched,
+// and a footer tells the user how many were left out.
+package truncate
+
+import "fmt"
+
+// DefaultHumanLimit is how many items human-readable output shows when no
+// limit is given
+const DefaultHumanLimit = 25
+
+// ForFormat returns how many items to show in an output format, where zero
+// shows all of them. explicit is the limit the user asked for, or nil. Only
+// human output is capped by default, at humanDefault; machine-readable
+// formats are limited only when asked, so scripts always see every item.
+func ForFormat(format string, explicit *int, humanDefault int) int {
+	if explicit != nil {
+		return max(*explicit, 0)
+	}
+	if format == "human" {
+		return max(humanDefault, 0)
+	}
+	return 0
+}
+
+// Slice returns the first limit items and how many were left out. A limit
+// of zero keeps every item.
+func Slice[T any](items []T, limit int) ([]T, int) {
+	if limit <= 0 || len(items) <= limit {
+		return items, 0
+	}
+	return items[:limit], len(items) - limit
+}
+
+// Footer returns the line that follows a truncated listing, or "" when
+// nothing was left out
+func Footer(hidden int) string {
+	if hidden <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("... and %d more (use --limit 0 to show all)", hidden)
+}
@@ -0,0 +1,177 @@
+package performance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// queryCounter counts the repository lookups the filters make
+type queryCounter struct {
+	queries int
+}
+
+type countedTaskLocations struct {
+	*memstore.TaskLocationRepository
+	counter *queryCounter
+}
+
+func (r countedTaskLocations) GetLocationsByTaskID(taskID string) ([]models.Location, error) {
+	r.counter.queries++
+	return r.TaskLocationRepository.GetLocationsByTaskID(taskID)
+}
+
+func (r countedTaskLocations) GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error) {
+	r.counter.queries++
+	return r.TaskLocationRepository.GetTaskLocationsByTaskID(taskID)
+}
+
+func (r countedTaskLocations) GetLocationsByTaskIDs(taskIDs []string) (map[string][]models.Location, error) {
+	r.counter.queries++
+	return r.TaskLocationRepository.GetLocationsByTaskIDs(taskIDs)
+}
+
+func (r countedTaskLocations) GetTaskLocationsByTaskIDs(taskIDs []string) (map[string][]models.TaskLocation, error) {
+	r.counter.queries++
+	return r.TaskLocationRepository.GetTaskLocationsByTaskIDs(taskIDs)
+}
+
+type countedDependencies struct {
+	*memstore.TaskDependencyRepository
+	counter *queryCounter
+}
+
+func (r countedDependencies) GetDependenciesByTaskID(taskID string) ([]models.TaskDependency, error) {
+	r.counter.queries++
+	return r.TaskDependencyRepository.GetDependenciesByTaskID(taskID)
+}
+
+func (r countedDependencies) GetDependenciesByTaskIDs(taskIDs []string) (map[string][]models.TaskDependency, error) {
+	r.counter.queries++
+	return r.TaskDependencyRepository.GetDependenciesByTaskIDs(taskIDs)
+}
+
+type countedTasks struct {
+	*memstore.TaskRepository
+	counter *queryCounter
+}
+
+func (r countedTasks) GetByID(taskID string) (*models.Task, error) {
+	r.counter.queries++
+	return r.TaskRepository.GetByID(taskID)
+}
+
+func (r countedTasks) GetByIDs(taskIDs []string) ([]models.Task, error) {
+	r.counter.queries++
+	return r.TaskRepository.GetByIDs(taskIDs)
+}
+
+type countedCalendar struct {
+	*memstore.CalendarEventRepository
+	counter *queryCounter
+}
+
+func (r countedCalendar) GetEventsByUserIDAndTimeRange(userID string, start, end time.Time) ([]models.CalendarEvent, error) {
+	r.counter.queries++
+	return r.CalendarEventRepository.GetEventsByUserIDAndTimeRange(userID, start, end)
+}
+
+type countedAudits struct {
+	*memstore.FilterAuditRepository
+	counter *queryCounter
+}
+
+func (r countedAudits) SaveFilterResult(audit models.FilterAudit) error {
+	r.counter.queries++
+	return nil
+}
+
+func (r countedAudits) SaveFilterResults(audits []models.FilterAudit) error {
+	r.counter.queries++
+	return nil
+}
+
+// setupPreloadEngine returns an engine over a store of tasks where every
+// third task needs a location and every other task waits on the one before.
+// Without preload its repositories offer only the per-task lookups, as
+// before filters could preload.
+func setupPreloadEngine(b *testing.B, count int, preload bool) (*filters.Engine, *queryCounter, models.Context, []models.Task) {
+	store := memstore.New()
+	ctx := generateTestContext()
+	ctx.AvailableMinutes = 240
+
+	home, err := models.NewLocation("test-user", "Home", "", *ctx.CurrentLatitude, *ctx.CurrentLongitude, 100)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := store.Locations().Create(*home); err != nil {
+		b.Fatal(err)
+	}
+
+	tasks := generateTestTasks(count)
+	for i, task := range tasks {
+		if err := store.Tasks().Create(task); err != nil {
+			b.Fatal(err)
+		}
+		if i%3 == 0 {
+			link, _ := models.NewTaskLocation(task.ID, home.ID, true)
+			if err := store.TaskLocations().Create(*link); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if i%2 == 1 {
+			dep, _ := models.NewTaskDependency(task.ID, tasks[i-1].ID, models.DependencyTypeBlocking)
+			if err := store.Dependencies().Create(*dep); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	counter := &queryCounter{}
+	var (
+		taskLocations filters.TaskLocationRepository   = countedTaskLocations{store.TaskLocations(), counter}
+		dependencies  filters.TaskDependencyRepository = countedDependencies{store.Dependencies(), counter}
+		taskRepo      filters.TaskRepository           = countedTasks{store.Tasks(), counter}
+		audits        filters.FilterAuditRepository    = countedAudits{store.FilterAudits(), counter}
+	)
+	if !preload {
+		taskLocations = struct{ filters.TaskLocationRepository }{taskLocations}
+		dependencies = struct {
+			filters.TaskDependencyRepository
+		}{dependencies}
+		taskRepo = struct{ filters.TaskRepository }{taskRepo}
+		audits = struct{ filters.FilterAuditRepository }{audits}
+	}
+
+	config := filters.DefaultFilterConfig
+	engine := filters.NewEngine(config, audits)
+	engine.AddRule(filters.NewLocationFilter(config, store.Locations(), taskLocations))
+	engine.AddRule(filters.NewDependencyFilter(config, dependencies, taskRepo))
+	engine.AddRule(filters.NewTimeFilter(config, countedCalendar{store.CalendarEvents(), counter}))
+	return engine, counter, ctx, tasks
+}
+
+func benchmarkFilterQueries(b *testing.B, preload bool) {
+	engine, counter, ctx, tasks := setupPreloadEngine(b, 2000, preload)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.FilterTasks(ctx, tasks)
+	}
+	b.ReportMetric(float64(counter.queries)/float64(b.N), "queries/op")
+}
+
+// BenchmarkFilterEngine_PerTaskQueries filters 2,000 tasks with a lookup per
+// task per filter and an audit write per result
+func BenchmarkFilterEngine_PerTaskQueries(b *testing.B) {
+	benchmarkFilterQueries(b, false)
+}
+
+// BenchmarkFilterEngine_PreloadedQueries filters the same tasks with the
+// filters preloading in bulk and the audits saved together
+func BenchmarkFilterEngine_PreloadedQueries(b *testing.B) {
+	benchmarkFilterQueries(b, true)
+}
@@ -0,0 +1,75 @@
+package performance
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+)
+
+// evaluationCounter counts rule evaluations
+type evaluationCounter struct {
+	evaluations int
+}
+
+// costedFilter hides every task whose index is not a multiple of keepEvery,
+// after the MockFilter's work for its complexity
+type costedFilter struct {
+	MockFilter
+	keepEvery int
+	counter   *evaluationCounter
+	index     map[string]int
+}
+
+func (f *costedFilter) Apply(ctx models.Context, task models.Task) (bool, string) {
+	f.counter.evaluations++
+	f.MockFilter.Apply(ctx, task)
+	if f.keepEvery > 0 && f.index[task.ID]%f.keepEvery != 0 {
+		return false, f.name + " filter blocked task"
+	}
+	return true, f.name + " filter applied"
+}
+
+// setupShortCircuitEngine returns an engine whose cheapest rule hides nine
+// tasks in ten, ahead of three expensive rules that hide nothing
+func setupShortCircuitEngine(count int, shortCircuit bool) (*filters.Engine, *evaluationCounter, []models.Task) {
+	tasks := generateTestTasks(count)
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+	}
+
+	config := filters.DefaultFilterConfig
+	config.ShortCircuit = shortCircuit
+	engine := filters.NewEngine(config, &MockAuditRepo{})
+
+	counter := &evaluationCounter{}
+	engine.AddRule(&costedFilter{MockFilter: MockFilter{name: "cheap", passRate: 1, priority: 10, complexity: 1}, keepEvery: 10, counter: counter, index: index})
+	engine.AddRule(&costedFilter{MockFilter: MockFilter{name: "location", passRate: 1, priority: 100, complexity: 50}, counter: counter, index: index})
+	engine.AddRule(&costedFilter{MockFilter: MockFilter{name: "dependency", passRate: 1, priority: 110, complexity: 100}, counter: counter, index: index})
+	engine.AddRule(&costedFilter{MockFilter: MockFilter{name: "time", passRate: 1, priority: 90, complexity: 20}, counter: counter, index: index})
+	return engine, counter, tasks
+}
+
+func benchmarkShortCircuit(b *testing.B, shortCircuit bool) {
+	engine, counter, tasks := setupShortCircuitEngine(1000, shortCircuit)
+	ctx := generateTestContext()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.FilterTasks(ctx, tasks)
+	}
+	b.ReportMetric(float64(counter.evaluations)/float64(b.N*len(tasks)), "evaluations/task")
+}
+
+// BenchmarkFilterEngine_FullEvaluation runs every rule against 1,000 tasks,
+// nine in ten of which the cheapest rule hides
+func BenchmarkFilterEngine_FullEvaluation(b *testing.B) {
+	benchmarkShortCircuit(b, false)
+}
+
+// BenchmarkFilterEngine_ShortCircuit runs the same rules cheapest first and
+// stops at the first that hides a task
+func BenchmarkFilterEngine_ShortCircuit(b *testing.B) {
+	benchmarkShortCircuit(b, true)
+}
@@ -0,0 +1,326 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adminUserRepository lists, counts and deletes users from memory
+type adminUserRepository struct {
+	loginUserRepository
+	taskCounts map[string]int
+}
+
+func newAdminUserRepository() *adminUserRepository {
+	return &adminUserRepository{
+		loginUserRepository: loginUserRepository{authUserRepository{users: map[string]models.User{}}},
+		taskCounts:          map[string]int{},
+	}
+}
+
+func (r *adminUserRepository) Count() (int, error) {
+	return len(r.users), nil
+}
+
+func (r *adminUserRepository) List(limit, offset int) ([]*models.User, error) {
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, &user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+	if offset > len(users) {
+		return nil, nil
+	}
+	return users[offset:min(offset+limit, len(users))], nil
+}
+
+func (r *adminUserRepository) Delete(userID string) error {
+	if _, ok := r.users[userID]; !ok {
+		return fmt.Errorf("user not found")
+	}
+	delete(r.users, userID)
+	return nil
+}
+
+func (r *adminUserRepository) CountAdmins() (int, error) {
+	count := 0
+	for _, user := range r.users {
+		if user.IsAdmin && user.IsActive() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *adminUserRepository) TaskCounts() (map[string]int, error) {
+	return r.taskCounts, nil
+}
+
+// setupUserAdmin returns an auth service with user administration, an admin
+// (the first user registered) and a second user
+func setupUserAdmin(t *testing.T) (*auth.AuthService, *adminUserRepository, *models.User, *models.User) {
+	users := newAdminUserRepository()
+	authService := auth.NewAuthService(users, storage.NewSessionRepository(setupSessionDB(t)),
+		auth.NewJWTService("test-secret-key-32-chars-long!!"), auth.DefaultAuthConfig)
+	authService.EnableDeviceTokens(storage.NewDeviceTokenRepository(setupDeviceTokenDB(t)))
+	authService.EnableUserAdmin(users)
+
+	admin, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+	require.NoError(t, err)
+	user, err := authService.Register(auth.RegisterRequest{Email: "bob@example.com", Password: "battery-staple", FirstName: "Bob"})
+	require.NoError(t, err)
+	return authService, users, admin, user
+}
+
+func TestAuthService_UserAdmin(t *testing.T) {
+	yes, no := true, false
+	deactivate := auth.UserAdminUpdate{Active: &no}
+
+	t.Run("FirstRegisteredUserIsAdmin", func(t *testing.T) {
+		_, _, admin, user := setupUserAdmin(t)
+		assert.True(t, admin.IsAdmin)
+		assert.False(t, user.IsAdmin)
+	})
+
+	t.Run("DeactivatedUserIsSignedOutAndCannotLogIn", func(t *testing.T) {
+		authService, _, admin, user := setupUserAdmin(t)
+		login, err := authService.Login(auth.LoginRequest{Email: "bob@example.com", Password: "battery-staple"}, "", "")
+		require.NoError(t, err)
+		deviceToken, _, err := authService.CreateDeviceToken(user.ID, "Phone")
+		require.NoError(t, err)
+
+		updated, err := authService.UpdateUser(admin.ID, user.ID, deactivate)
+		require.NoError(t, err)
+		require.NotNil(t, updated.DeactivatedAt)
+
+		_, err = authService.ValidateToken(login.Token)
+		assert.Error(t, err)
+		_, err = authService.ValidateDeviceToken(deviceToken)
+		assert.Error(t, err)
+		_, err = authService.RefreshAccessToken(login.RefreshToken, "", "")
+		assert.ErrorIs(t, err, auth.ErrAccountDeactivated)
+		_, err = authService.Login(auth.LoginRequest{Email: "bob@example.com", Password: "battery-staple"}, "", "")
+		assert.ErrorIs(t, err, auth.ErrAccountDeactivated)
+
+		_, err = authService.UpdateUser(admin.ID, user.ID, auth.UserAdminUpdate{Active: &yes})
+		require.NoError(t, err)
+		_, err = authService.Login(auth.LoginRequest{Email: "bob@example.com", Password: "battery-staple"}, "", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("WrongPasswordDoesNotRevealDeactivation", func(t *testing.T) {
+		authService, _, admin, user := setupUserAdmin(t)
+		_, err := authService.UpdateUser(admin.ID, user.ID, deactivate)
+		require.NoError(t, err)
+
+		_, err = authService.Login(auth.LoginRequest{Email: "bob@example.com", Password: "wrong-password"}, "", "")
+		assert.NotErrorIs(t, err, auth.ErrAccountDeactivated)
+	})
+
+	t.Run("OnlyAdminsManageUsers", func(t *testing.T) {
+		authService, _, admin, user := setupUserAdmin(t)
+		_, err := authService.UpdateUser(user.ID, admin.ID, deactivate)
+		assert.ErrorIs(t, err, auth.ErrAdminRequired)
+		_, err = authService.ListUsers(user.ID, 50, 0)
+		assert.ErrorIs(t, err, auth.ErrAdminRequired)
+		assert.ErrorIs(t, authService.DeleteUser(user.ID, admin.ID, auth.UserDeleteCascade), auth.ErrAdminRequired)
+	})
+
+	t.Run("LastAdminIsKept", func(t *testing.T) {
+		authService, _, admin, user := setupUserAdmin(t)
+		_, err := authService.UpdateUser(admin.ID, admin.ID, auth.UserAdminUpdate{Admin: &no})
+		assert.ErrorIs(t, err, auth.ErrLastAdmin)
+		_, err = authService.UpdateUser(admin.ID, admin.ID, deactivate)
+		assert.ErrorIs(t, err, auth.ErrLastAdmin)
+		assert.ErrorIs(t, authService.DeleteUser(admin.ID, admin.ID, auth.UserDeleteCascade), auth.ErrLastAdmin)
+
+		_, err = authService.UpdateUser(admin.ID, user.ID, auth.UserAdminUpdate{Admin: &yes})
+		require.NoError(t, err)
+		_, err = authService.UpdateUser(user.ID, admin.ID, auth.UserAdminUpdate{Admin: &no})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ListsUsersWithTaskCounts", func(t *testing.T) {
+		authService, users, admin, user := setupUserAdmin(t)
+		users.taskCounts[user.ID] = 3
+
+		listed, err := authService.ListUsers(admin.ID, 50, 0)
+		require.NoError(t, err)
+		require.Len(t, listed, 2)
+		counts := map[string]int{}
+		for _, summary := range listed {
+			assert.Empty(t, summary.PasswordHash)
+			counts[summary.Email] = summary.TaskCount
+		}
+		assert.Equal(t, map[string]int{"alice@example.com": 0, "bob@example.com": 3}, counts)
+	})
+
+	t.Run("DeleteCascade", func(t *testing.T) {
+		authService, users, admin, user := setupUserAdmin(t)
+		require.NoError(t, authService.DeleteUser(admin.ID, user.ID, auth.UserDeleteCascade))
+		_, err := users.GetByID(user.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("DeleteAnonymize", func(t *testing.T) {
+		authService, users, admin, user := setupUserAdmin(t)
+		require.NoError(t, authService.DeleteUser(admin.ID, user.ID, auth.UserDeleteAnonymize))
+
+		kept, err := users.GetByID(user.ID)
+		require.NoError(t, err)
+		assert.False(t, kept.IsActive())
+		assert.Equal(t, "Deleted user", kept.DisplayName)
+		assert.NotContains(t, kept.Email, "bob")
+		assert.NoError(t, kept.Validate())
+
+		_, err = authService.Login(auth.LoginRequest{Email: "bob@example.com", Password: "battery-staple"}, "", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("UnknownDeleteMode", func(t *testing.T) {
+		authService, _, admin, user := setupUserAdmin(t)
+		assert.ErrorIs(t, authService.DeleteUser(admin.ID, user.ID, "shred"), auth.ErrInvalidDeleteMode)
+	})
+}
+
+func TestAdminRoutes(t *testing.T) {
+	setup := func(t *testing.T) (http.Handler, map[string]string, *models.User) {
+		authService, _, _, user := setupUserAdmin(t)
+
+		tokens := map[string]string{}
+		for email, password := range map[string]string{"alice@example.com": "correct-horse", "bob@example.com": "battery-staple"} {
+			login, err := authService.Login(auth.LoginRequest{Email: email, Password: password}, "", "")
+			require.NoError(t, err)
+			tokens[email] = "Bearer " + login.Token
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Auth:  api.NewAuthHandler(authService),
+			Admin: api.NewAdminHandler(authService),
+		}, api.RouteConfig{})
+		return router, tokens, user
+	}
+	as := func(token string) map[string]string {
+		return map[string]string{"Authorization": token}
+	}
+
+	t.Run("NonAdminForbidden", func(t *testing.T) {
+		router, tokens, user := setup(t)
+		w := serveRequestWithHeaders(router, http.MethodGet, "/api/v1/admin/users", "", as(tokens["bob@example.com"]))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = serveRequestWithHeaders(router, http.MethodDelete, "/api/v1/admin/users/"+user.ID, "", as(tokens["bob@example.com"]))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("AdminListsUsers", func(t *testing.T) {
+		router, tokens, _ := setup(t)
+		w := serveRequestWithHeaders(router, http.MethodGet, "/api/v1/admin/users", "", as(tokens["alice@example.com"]))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response api.AdminUsersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Total)
+	})
+
+	t.Run("DeactivateLocksUserOut", func(t *testing.T) {
+		router, tokens, user := setup(t)
+		w := serveRequestWithHeaders(router, http.MethodPatch, "/api/v1/admin/users/"+user.ID, `{"active": false}`, as(tokens["alice@example.com"]))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = serveRequestWithHeaders(router, http.MethodGet, "/api/v1/auth/sessions", "", as(tokens["bob@example.com"]))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/login", `{"username": "bob@example.com", "password": "battery-staple"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("LastAdminConflict", func(t *testing.T) {
+		router, tokens, _ := setup(t)
+		w := serveRequestWithHeaders(router, http.MethodGet, "/api/v1/admin/users", "", as(tokens["alice@example.com"]))
+		var response api.AdminUsersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+		for _, listed := range response.Users {
+			if listed.IsAdmin {
+				w = serveRequestWithHeaders(router, http.MethodPatch, "/api/v1/admin/users/"+listed.ID, `{"admin": false}`, as(tokens["alice@example.com"]))
+				assert.Equal(t, http.StatusConflict, w.Code)
+			}
+		}
+	})
+
+	t.Run("DeleteAndUnknownUser", func(t *testing.T) {
+		router, tokens, user := setup(t)
+		w := serveRequestWithHeaders(router, http.MethodDelete, "/api/v1/admin/users/"+user.ID+"?mode=anonymize", "", as(tokens["alice@example.com"]))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		w = serveRequestWithHeaders(router, http.MethodDelete, "/api/v1/admin/users/missing", "", as(tokens["alice@example.com"]))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = serveRequestWithHeaders(router, http.MethodDelete, "/api/v1/admin/users/"+user.ID+"?mode=shred", "", as(tokens["alice@example.com"]))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestUserRepository_Admin(t *testing.T) {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			id TEXT PRIMARY KEY NOT NULL, username TEXT NOT NULL, email TEXT NOT NULL,
+			password_hash TEXT NOT NULL, display_name TEXT NOT NULL DEFAULT '', timezone TEXT NOT NULL,
+			created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, last_seen_at DATETIME NOT NULL,
+			settings TEXT, is_admin BOOLEAN NOT NULL DEFAULT 0, deactivated_at DATETIME
+		);
+		CREATE TABLE tasks (id TEXT PRIMARY KEY NOT NULL, creator_id TEXT NOT NULL, deleted_at DATETIME);
+		INSERT INTO tasks (id, creator_id, deleted_at) VALUES
+			('t1', 'alice', NULL), ('t2', 'alice', NULL), ('t3', 'alice', CURRENT_TIMESTAMP), ('t4', 'bob', NULL);
+	`)
+	require.NoError(t, err)
+
+	repo := storage.NewUserRepository(db)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user, err := models.NewUser(name, name+"@example.com", name, "UTC")
+		require.NoError(t, err)
+		user.ID = name
+		user.IsAdmin = name != "carol"
+		require.NoError(t, user.SetPassword("correct-horse"))
+		require.NoError(t, repo.Create(user))
+	}
+
+	counts, err := repo.TaskCounts()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"alice": 2, "bob": 1}, counts)
+
+	admins, err := repo.CountAdmins()
+	require.NoError(t, err)
+	assert.Equal(t, 2, admins)
+
+	bob, err := repo.GetByID("bob")
+	require.NoError(t, err)
+	now := time.Now()
+	bob.DeactivatedAt = &now
+	require.NoError(t, repo.Update(bob))
+
+	bob, err = repo.GetByID("bob")
+	require.NoError(t, err)
+	require.NotNil(t, bob.DeactivatedAt)
+	assert.False(t, bob.IsActive())
+
+	admins, err = repo.CountAdmins()
+	require.NoError(t, err)
+	assert.Equal(t, 1, admins, "deactivated admins do not count")
+}
@@ -0,0 +1,58 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTask_ValidationErrors(t *testing.T) {
+	router := newBasePathRouter("")
+
+	decode := func(t *testing.T, body []byte) api.ValidationErrorResponse {
+		var response api.ValidationErrorResponse
+		require.NoError(t, json.Unmarshal(body, &response))
+		return response
+	}
+
+	t.Run("ReportsAllInvalidFields", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks", `{"title":"","priority":7,"estimated_minutes":0}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		response := decode(t, w.Body.Bytes())
+		assert.Equal(t, "validation failed", response.Error)
+		assert.Equal(t, map[string]string{
+			"title":             "required",
+			"priority":          "must be between 1 and 5",
+			"estimated_minutes": "must be positive",
+		}, response.Fields)
+	})
+
+	t.Run("ReportsSingleFieldPrecisely", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks", `{"title":"Buy milk","estimated_minutes":-10}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		response := decode(t, w.Body.Bytes())
+		assert.Equal(t, map[string]string{"estimated_minutes": "must be positive"}, response.Fields)
+	})
+
+	t.Run("ValidTaskCreated", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks", `{"title":"Buy milk","estimated_minutes":10}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("OutdoorFlagSetsMetadata", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks", `{"title":"Mow the lawn","outdoor":true}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var task models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+		assert.True(t, filters.TaskIsOutdoor(task))
+	})
+}
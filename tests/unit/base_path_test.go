@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// StubAPITaskService implements api.TaskService with canned responses
+type StubAPITaskService struct{}
+
+func (s *StubAPITaskService) GetFilteredTasks(userID string, filters api.TaskFilters) (*api.TaskListResponse, error) {
+	return &api.TaskListResponse{Tasks: []models.Task{}}, nil
+}
+
+func (s *StubAPITaskService) CreateTask(task models.Task) (*models.Task, error) {
+	task.ID = "task-1"
+	return &task, nil
+}
+
+func (s *StubAPITaskService) GetTaskByID(taskID string, userID string) (*models.Task, error) {
+	return &models.Task{ID: taskID}, nil
+}
+
+func (s *StubAPITaskService) UpdateTask(task models.Task) (*models.Task, error) {
+	return &task, nil
+}
+
+func (s *StubAPITaskService) DeleteTask(taskID string, userID string) error {
+	return nil
+}
+
+func (s *StubAPITaskService) AssignTask(taskID string, assigneeID string, assignedBy string, message string) error {
+	return nil
+}
+
+func (s *StubAPITaskService) CompleteTask(taskID string, userID string) (*models.Task, error) {
+	return &models.Task{ID: taskID}, nil
+}
+
+func (s *StubAPITaskService) GetTaskAudit(taskID string, userID string) ([]models.FilterAudit, error) {
+	return nil, nil
+}
+
+func (s *StubAPITaskService) CreateTaskFromNaturalLanguage(input string, userID string) (*models.Task, error) {
+	return &models.Task{Title: input}, nil
+}
+
+func (s *StubAPITaskService) ReorderTask(taskID string, afterTaskID string, userID string) (*models.Task, error) {
+	return &models.Task{ID: taskID}, nil
+}
+
+func newBasePathRouter(basePath string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	api.SetupRoutes(router, api.Handlers{
+		Tasks: api.NewTaskHandler(&StubAPITaskService{}, nil),
+		AuthMiddleware: func(c *gin.Context) {
+			c.Set("user", &models.User{ID: "test-user-id"})
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{BasePath: basePath})
+
+	return router
+}
+
+func serveRequest(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	return serveRequestWithHeaders(router, method, path, body, nil)
+}
+
+func serveRequestWithHeaders(router http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetupRoutes_BasePath(t *testing.T) {
+	router := newBasePathRouter("/app")
+
+	t.Run("TasksServedUnderBasePath", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/app/api/v1/tasks", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = serveRequest(router, http.MethodGet, "/app/health", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("BarePathNotFound", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serveRequest(router, http.MethodGet, "/health", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("LocationHeaderIncludesBasePath", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/app/api/v1/tasks", `{"title":"Buy milk"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/app/api/v1/tasks/task-1", w.Header().Get("Location"))
+	})
+
+	t.Run("RootMountWithoutBasePath", func(t *testing.T) {
+		w := serveRequest(newBasePathRouter(""), http.MethodGet, "/api/v1/tasks", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	assert.Equal(t, "", api.NormalizeBasePath(""))
+	assert.Equal(t, "", api.NormalizeBasePath("/"))
+	assert.Equal(t, "/app", api.NormalizeBasePath("app"))
+	assert.Equal(t, "/app", api.NormalizeBasePath("/app/"))
+	assert.Equal(t, "/app/v2", api.NormalizeBasePath(" /app/v2 "))
+}
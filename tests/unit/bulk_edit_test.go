@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTaskSelector(t *testing.T) {
+	sel, err := hereandnow.ParseTaskSelector("list=work-id status=pending priority=3 text=quarterly text=report")
+	require.NoError(t, err)
+	assert.Equal(t, hereandnow.TaskSelector{
+		ListID:   "work-id",
+		Status:   models.TaskStatusPending,
+		Priority: 3,
+		Text:     "quarterly report",
+	}, sel)
+
+	for _, bad := range []string{"list", "status=sleeping", "priority=9", "colour=red"} {
+		_, err := hereandnow.ParseTaskSelector(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTaskService_BulkEditTasks(t *testing.T) {
+	workList := "work-id"
+	estimate := 20
+	due := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	newTask := func(title string, listID *string, status models.TaskStatus) models.Task {
+		task := createTestTask(title, &estimate, 2)
+		task.ListID = listID
+		task.Status = status
+		task.DueAt = &due
+		return task
+	}
+	report := newTask("Quarterly report", &workList, models.TaskStatusPending)
+	slides := newTask("Review slides", &workList, models.TaskStatusPending)
+	started := newTask("Expense claims", &workList, models.TaskStatusActive)
+	groceries := newTask("Buy groceries", nil, models.TaskStatusPending)
+
+	newService := func() *hereandnow.TaskService {
+		service, _ := newMemstoreServices(memstore.New(memstore.WithTasks(report, slides, started, groceries)))
+		return service
+	}
+	sel := hereandnow.TaskSelector{ListID: workList, Status: models.TaskStatusPending}
+
+	t.Run("ChangesOnlyGivenFieldsOnMatchingTasks", func(t *testing.T) {
+		service := newService()
+		priority := 4
+
+		updated, err := service.BulkEditTasks("test-user-id", sel, hereandnow.BulkEditRequest{Priority: &priority})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Quarterly report", "Review slides"}, taskTitles(updated))
+
+		for _, id := range []string{report.ID, slides.ID} {
+			task, err := service.GetTask(id)
+			require.NoError(t, err)
+			assert.Equal(t, 4, task.Priority)
+			assert.Equal(t, estimate, *task.EstimatedMinutes, "estimate was not given")
+			assert.Equal(t, due, *task.DueAt, "due date was not given")
+		}
+
+		for _, id := range []string{started.ID, groceries.ID} {
+			task, err := service.GetTask(id)
+			require.NoError(t, err)
+			assert.Equal(t, 2, task.Priority, "%s does not match the selector", task.Title)
+		}
+	})
+
+	t.Run("SetsSeveralFields", func(t *testing.T) {
+		service := newService()
+		minutes := 45
+		newDue := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		sel, err := hereandnow.ParseTaskSelector("text=groceries")
+		require.NoError(t, err)
+		updated, err := service.BulkEditTasks("test-user-id", sel, hereandnow.BulkEditRequest{EstimatedMinutes: &minutes, DueAt: &newDue})
+		require.NoError(t, err)
+		require.Len(t, updated, 1)
+
+		task, err := service.GetTask(groceries.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 45, *task.EstimatedMinutes)
+		assert.Equal(t, newDue, *task.DueAt)
+		assert.Equal(t, 2, task.Priority)
+	})
+
+	t.Run("RejectsInvalidChangeBeforeWriting", func(t *testing.T) {
+		service := newService()
+		priority := 9
+
+		_, err := service.BulkEditTasks("test-user-id", sel, hereandnow.BulkEditRequest{Priority: &priority})
+		assert.ErrorContains(t, err, "priority must be between 1 and 5")
+
+		for _, id := range []string{report.ID, slides.ID} {
+			task, err := service.GetTask(id)
+			require.NoError(t, err)
+			assert.Equal(t, 2, task.Priority)
+		}
+
+		_, err = service.BulkEditTasks("test-user-id", sel, hereandnow.BulkEditRequest{})
+		assert.Error(t, err, "nothing to change")
+	})
+}
@@ -0,0 +1,241 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/calendar"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientFunc answers requests with a function
+type clientFunc func(req *http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// userLogins holds a CalDAV login per user
+type userLogins map[string][2]string
+
+func (l userLogins) CalDAVLogin(userID string) (string, string, error) {
+	login, ok := l[userID]
+	if !ok {
+		return "", "", fmt.Errorf("no CalDAV login for %s", userID)
+	}
+	return login[0], login[1], nil
+}
+
+func calendarDataResponse(objects ...string) *http.Response {
+	var body strings.Builder
+	body.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+	for i, object := range objects {
+		fmt.Fprintf(&body, `<D:response><D:href>/cal/%d.ics</D:href><D:propstat><D:prop><C:calendar-data>%s</C:calendar-data></D:prop></D:propstat></D:response>`, i, object)
+	}
+	body.WriteString(`</D:multistatus>`)
+	return multistatusResponse(body.String())
+}
+
+const weeklyStandup = `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Standup
+DTSTART;TZID=Europe/London:20261005T093000
+DURATION:PT15M
+RRULE:FREQ=WEEKLY;WKST=SU;BYDAY=MO
+EXDATE;TZID=Europe/London:20261012T093000
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Reminder
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+RECURRENCE-ID;TZID=Europe/London:20261019T093000
+SUMMARY:Standup (moved)
+DTSTART;TZID=Europe/London:20261019T110000
+DTEND;TZID=Europe/London:20261019T111500
+END:VEVENT
+END:VCALENDAR`
+
+func TestCalDAVCalendarProvider(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 0, 28)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	t.Run("ExpandsRecurringEvents", func(t *testing.T) {
+		var auth [2]string
+		client := clientFunc(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "REPORT", req.Method)
+			body, _ := io.ReadAll(req.Body)
+			assert.Contains(t, string(body), `<C:time-range start="20261001T000000Z" end="20261029T000000Z"/>`)
+			auth[0], auth[1], _ = req.BasicAuth()
+			return calendarDataResponse(weeklyStandup, `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:offsite
+SUMMARY:Offsite
+DTSTART;VALUE=DATE:20261020
+TRANSP:TRANSPARENT
+END:VEVENT
+END:VCALENDAR`, "not an iCalendar object"), nil
+		})
+		provider := calendar.NewCalDAVProvider(calendar.CalDAVConfig{
+			URL:         "https://dav.example.com/cal",
+			Credentials: userLogins{"alice": {"alice", "app-password"}},
+		}, client)
+
+		events, err := provider.FetchEvents(context.Background(), "alice", since, until)
+		require.NoError(t, err)
+		assert.Equal(t, [2]string{"alice", "app-password"}, auth)
+
+		byID := map[string]int{}
+		for i, event := range events {
+			byID[event.ExternalID] = i
+		}
+		require.Len(t, events, 4, "%v", byID)
+
+		first := events[byID["standup/20261005T083000Z"]]
+		assert.Equal(t, "Standup", first.Title)
+		assert.Equal(t, "caldav", first.ProviderID)
+		assert.Equal(t, 15, first.DurationMinutes())
+		assert.Equal(t, time.Date(2026, 10, 26, 9, 30, 0, 0, london), events[byID["standup/20261026T093000Z"]].StartAt,
+			"instances keep their local time across the DST change")
+		assert.NotContains(t, byID, "standup/20261012T083000Z", "EXDATE removes an instance")
+
+		moved := events[byID["standup/20261019T083000Z"]]
+		assert.Equal(t, "Standup (moved)", moved.Title)
+		assert.Equal(t, time.Date(2026, 10, 19, 11, 0, 0, 0, london), moved.StartAt)
+
+		offsite := events[byID["offsite"]]
+		assert.True(t, offsite.IsAllDay)
+		assert.False(t, offsite.IsBusy)
+		assert.Equal(t, 24*time.Hour, offsite.Duration())
+	})
+
+	t.Run("RequiresLoginForUser", func(t *testing.T) {
+		provider := calendar.NewCalDAVProvider(calendar.CalDAVConfig{
+			URL:         "https://dav.example.com/cal",
+			Credentials: userLogins{},
+		}, &stubHTTPClient{})
+
+		_, err := provider.FetchEvents(context.Background(), "bob", since, until)
+		assert.ErrorContains(t, err, "no CalDAV login for bob")
+	})
+
+	t.Run("RetriesWithBackoff", func(t *testing.T) {
+		client := &stubHTTPClient{responses: []*http.Response{
+			stubResponse(http.StatusServiceUnavailable, nil),
+			stubResponse(http.StatusTooManyRequests, nil),
+			calendarDataResponse(weeklyStandup),
+		}}
+		provider := calendar.NewCalDAVProvider(calendar.CalDAVConfig{
+			URL:         "https://dav.example.com/cal",
+			Credentials: calendar.StaticCalDAVCredentials{Username: "alice"},
+			Backoff:     10 * time.Millisecond,
+		}, client)
+
+		events, err := provider.FetchEvents(context.Background(), "alice", since, until)
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+		require.Len(t, client.requests, 3)
+		assert.GreaterOrEqual(t, client.requests[2].Sub(client.requests[1]), 20*time.Millisecond, "the wait doubles")
+	})
+
+	t.Run("DoesNotRetryBadCredentials", func(t *testing.T) {
+		client := &stubHTTPClient{responses: []*http.Response{stubResponse(http.StatusUnauthorized, nil)}}
+		provider := calendar.NewCalDAVProvider(calendar.CalDAVConfig{
+			URL:         "https://dav.example.com/cal",
+			Credentials: calendar.StaticCalDAVCredentials{Username: "alice"},
+			Backoff:     time.Millisecond,
+		}, client)
+
+		_, err := provider.FetchEvents(context.Background(), "alice", since, until)
+		assert.ErrorContains(t, err, "invalid credentials")
+		assert.Len(t, client.requests, 1)
+	})
+}
+
+func TestCalDAVCalendarProvider_Sync(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 0, 3)
+	event := func(uid string, start time.Time) string {
+		return fmt.Sprintf("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:%s\nSUMMARY:%s\nDTSTART:%s\nDURATION:PT1H\nEND:VEVENT\nEND:VCALENDAR",
+			uid, uid, start.Format("20060102T150405Z"))
+	}
+	// serve answers each day's query with that day's event, or fails the
+	// days in down
+	serve := func(down map[int]bool) clientFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			for day := 0; day < 3; day++ {
+				start := since.AddDate(0, 0, day)
+				if strings.Contains(string(body), `start="`+start.Format("20060102T150405Z")+`"`) {
+					if down[day] {
+						return nil, errors.New("connection reset")
+					}
+					return calendarDataResponse(event(fmt.Sprintf("day-%d", day), start.Add(9*time.Hour))), nil
+				}
+			}
+			return nil, fmt.Errorf("unexpected query %s", body)
+		}
+	}
+	newProvider := func(client calendar.HTTPClient) *calendar.CalDAVProvider {
+		return calendar.NewCalDAVProvider(calendar.CalDAVConfig{
+			URL:         "https://dav.example.com/cal",
+			ProviderID:  "caldav:https://dav.example.com/cal/",
+			Credentials: calendar.StaticCalDAVCredentials{Username: "alice"},
+			Retries:     1,
+			Backoff:     time.Millisecond,
+			QueryWindow: 24 * time.Hour,
+		}, client)
+	}
+	store := memstore.New()
+	events := store.CalendarEvents()
+
+	result, err := calendar.Sync(context.Background(), events, newProvider(serve(nil)), "alice", since, until)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Created)
+
+	result, err = calendar.Sync(context.Background(), events, newProvider(serve(nil)), "alice", since, until)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created, "repeated syncs are keyed on the UID")
+	assert.Equal(t, 3, result.Updated)
+
+	// A failing day is reported without losing the others or deleting its
+	// events
+	result, err = calendar.Sync(context.Background(), events, newProvider(serve(map[int]bool{1: true})), "alice", since, until)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, 0, result.Deleted)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "2026-10-02 to 2026-10-03")
+	stored, err := events.GetByUserID("alice")
+	require.NoError(t, err)
+	assert.Len(t, stored, 3)
+
+	_, err = calendar.Sync(context.Background(), events, newProvider(serve(map[int]bool{0: true, 1: true, 2: true})), "alice", since, until)
+	assert.ErrorContains(t, err, "connection reset")
+
+	// An event the server no longer has is removed once a sync completes
+	result, err = calendar.Sync(context.Background(), events, newProvider(clientFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(body), `start="20261003T000000Z"`) {
+			return calendarDataResponse(), nil
+		}
+		req.Body = io.NopCloser(strings.NewReader(string(body)))
+		return serve(nil)(req)
+	})), "alice", since, until)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Deleted)
+	_, err = events.GetByProviderAndExternalID("alice", "caldav:https://dav.example.com/cal/", "day-2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalDAVProvider_DiscoverCalendars(t *testing.T) {
+	t.Run("FollowsPrincipalToCalendarHome", func(t *testing.T) {
+		client := &stubHTTPClient{responses: []*http.Response{
+			multistatusResponse(`<D:multistatus xmlns:D="DAV:">
+  <D:response><D:href>/</D:href><D:propstat><D:prop>
+    <D:current-user-principal><D:href>/principals/me/</D:href></D:current-user-principal>
+    <D:resourcetype><D:collection/></D:resourcetype>
+  </D:prop></D:propstat></D:response>
+</D:multistatus>`),
+			multistatusResponse(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response><D:href>/principals/me/</D:href><D:propstat><D:prop>
+    <C:calendar-home-set><D:href>/calendars/me/</D:href></C:calendar-home-set>
+  </D:prop></D:propstat></D:response>
+</D:multistatus>`),
+			multistatusResponse(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response><D:href>/calendars/me/</D:href><D:propstat><D:prop>
+    <D:resourcetype><D:collection/></D:resourcetype>
+  </D:prop></D:propstat></D:response>
+  <D:response><D:href>/calendars/me/work/</D:href><D:propstat><D:prop>
+    <D:displayname>Work</D:displayname>
+    <D:resourcetype><D:collection/><C:calendar/></D:resourcetype>
+    <C:supported-calendar-component-set><C:comp name="VEVENT"/><C:comp name="VTODO"/></C:supported-calendar-component-set>
+  </D:prop></D:propstat></D:response>
+  <D:response><D:href>/calendars/me/todo/</D:href><D:propstat><D:prop>
+    <D:displayname>Reminders</D:displayname>
+    <D:resourcetype><D:collection/><C:calendar/></D:resourcetype>
+    <C:supported-calendar-component-set><C:comp name="VTODO"/></C:supported-calendar-component-set>
+  </D:prop></D:propstat></D:response>
+  <D:response><D:href>/calendars/me/personal/</D:href><D:propstat><D:prop>
+    <D:resourcetype><D:collection/><C:calendar/></D:resourcetype>
+  </D:prop></D:propstat></D:response>
+</D:multistatus>`),
+		}}
+		provider := sync.NewCalDAVProvider("https://dav.example.com/", "user", "app-password", client)
+
+		calendars, err := provider.DiscoverCalendars()
+		require.NoError(t, err)
+		assert.Equal(t, []sync.CalDAVCalendar{
+			{URL: "https://dav.example.com/calendars/me/work/", Name: "Work"},
+			{URL: "https://dav.example.com/calendars/me/personal/", Name: "personal"},
+		}, calendars)
+		assert.Empty(t, client.responses)
+	})
+
+	t.Run("BaseURLIsCalendar", func(t *testing.T) {
+		client := &stubHTTPClient{responses: []*http.Response{multistatusResponse(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response><D:href>/cal/</D:href><D:propstat><D:prop>
+    <D:displayname>Home</D:displayname>
+    <D:resourcetype><D:collection/><C:calendar/></D:resourcetype>
+  </D:prop></D:propstat></D:response>
+</D:multistatus>`)}}
+		provider := sync.NewCalDAVProvider("https://dav.example.com/cal/", "user", "secret", client)
+
+		calendars, err := provider.DiscoverCalendars()
+		require.NoError(t, err)
+		assert.Equal(t, []sync.CalDAVCalendar{{URL: "https://dav.example.com/cal/", Name: "Home"}}, calendars)
+	})
+
+	t.Run("InvalidCredentials", func(t *testing.T) {
+		client := &stubHTTPClient{responses: []*http.Response{stubResponse(http.StatusUnauthorized, nil)}}
+		provider := sync.NewCalDAVProvider("https://dav.example.com/", "user", "wrong", client)
+
+		_, err := provider.DiscoverCalendars()
+		assert.EqualError(t, err, "invalid credentials")
+	})
+
+	t.Run("ForCalendarKeysEventsByCalendar", func(t *testing.T) {
+		provider := sync.NewCalDAVProvider("https://dav.example.com/", "user", "secret", nil)
+
+		work := provider.ForCalendar(sync.CalDAVCalendar{URL: "https://dav.example.com/calendars/me/work/"})
+		assert.Equal(t, "https://dav.example.com/calendars/me/work", work.BaseURL)
+		assert.Equal(t, "caldav:https://dav.example.com/calendars/me/work/", work.CursorKey())
+		assert.Equal(t, "user", work.Username)
+	})
+}
+
+func setupCalendarEventDB(t *testing.T) *storage.DB {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE calendar_events (
+			id TEXT PRIMARY KEY NOT NULL, user_id TEXT NOT NULL,
+			provider_id TEXT NOT NULL, external_id TEXT NOT NULL, title TEXT NOT NULL,
+			start_at DATETIME NOT NULL, end_at DATETIME NOT NULL, location TEXT NULL,
+			is_all_day BOOLEAN NOT NULL DEFAULT FALSE, is_busy BOOLEAN NOT NULL DEFAULT TRUE,
+			metadata TEXT DEFAULT '{}', last_synced_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, provider_id, external_id)
+		);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestCalendarEventRepository(t *testing.T) {
+	repo := storage.NewCalendarEventRepository(setupCalendarEventDB(t))
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.FixedZone("BST", 3600))
+
+	event, err := models.NewCalendarEvent("test-user-id", "caldav:work", "standup", "Standup", start, start.Add(15*time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(*event))
+
+	t.Run("UpsertsOnProviderAndExternalID", func(t *testing.T) {
+		again, err := models.NewCalendarEvent("test-user-id", "caldav:work", "standup", "Standup (moved)", start, start.Add(30*time.Minute))
+		require.NoError(t, err)
+		require.NoError(t, repo.Create(*again))
+
+		stored, err := repo.GetByProviderAndExternalID("test-user-id", "caldav:work", "standup")
+		require.NoError(t, err)
+		assert.Equal(t, event.ID, stored.ID)
+		assert.Equal(t, "Standup (moved)", stored.Title)
+		assert.True(t, stored.IsBusy)
+		assert.True(t, start.Equal(stored.StartAt))
+
+		_, err = repo.GetByProviderAndExternalID("test-user-id", "caldav:home", "standup")
+		assert.Error(t, err)
+	})
+
+	t.Run("TimeRangeOverlap", func(t *testing.T) {
+		events, err := repo.GetEventsByUserIDAndTimeRange("test-user-id", start.Add(10*time.Minute), start.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		events, err = repo.GetEventsByUserIDAndTimeRange("test-user-id", start.Add(30*time.Minute), start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		next, err := repo.GetNextEvent("test-user-id", start.Add(-time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, next)
+		assert.Equal(t, "standup", next.ExternalID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, repo.Delete(event.ID))
+		assert.Error(t, repo.Delete(event.ID))
+
+		events, err := repo.GetByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
+
+// calDAVEventsResponse answers a calendar-query REPORT with one resource per
+// event, each starting at the given time and lasting an hour
+func calDAVEventsResponse(events map[string]time.Time) *http.Response {
+	var body strings.Builder
+	body.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+	for name, start := range events {
+		fmt.Fprintf(&body, `<D:response><D:href>/cal/%s.ics</D:href><D:propstat><D:prop><C:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:%s
+SUMMARY:Meeting %s
+DTSTART:%s
+DTEND:%s
+END:VEVENT
+END:VCALENDAR</C:calendar-data></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`,
+			name, name, name, start.UTC().Format("20060102T150405Z"), start.Add(time.Hour).UTC().Format("20060102T150405Z"))
+	}
+	body.WriteString(`</D:multistatus>`)
+	return multistatusResponse(body.String())
+}
+
+func TestCalDAVSync(t *testing.T) {
+	repo := storage.NewCalendarEventRepository(setupCalendarEventDB(t))
+	service := sync.NewCalendarSyncService(repo, nil)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	other, err := models.NewCalendarEvent("test-user-id", "caldav:https://dav.example.com/home/", "review", "Review", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(*other))
+
+	client := &stubHTTPClient{}
+	provider := sync.NewCalDAVProvider("https://dav.example.com/", "user", "secret", client).
+		ForCalendar(sync.CalDAVCalendar{URL: "https://dav.example.com/work/"})
+	syncWork := func(events map[string]time.Time) *sync.SyncResult {
+		client.responses = append(client.responses, stubResponse(http.StatusOK, nil), calDAVEventsResponse(events))
+		results := service.SyncAll([]sync.SyncJob{{UserID: "test-user-id", Provider: provider}}, sync.SyncOptions{
+			PastWindow:   7 * 24 * time.Hour,
+			FutureWindow: 30 * 24 * time.Hour,
+		})
+		require.Len(t, results, 1)
+		require.Empty(t, results[0].Errors)
+		return results[0]
+	}
+
+	result := syncWork(map[string]time.Time{"review": start, "standup": start.Add(2 * time.Hour)})
+	assert.Equal(t, 2, result.Created)
+
+	stored, err := repo.GetByProviderAndExternalID("test-user-id", provider.CursorKey(), "review")
+	require.NoError(t, err)
+	assert.True(t, stored.IsBusy)
+
+	t.Run("UpdatesAndRemovesOnlyItsOwnEvents", func(t *testing.T) {
+		result := syncWork(map[string]time.Time{"standup": start.Add(3 * time.Hour)})
+		assert.Equal(t, 0, result.Created)
+		assert.Equal(t, 1, result.Updated)
+		assert.Equal(t, 1, result.Deleted)
+
+		events, err := repo.GetByUserID("test-user-id")
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		_, err = repo.GetByProviderAndExternalID("test-user-id", other.ProviderID, "review")
+		assert.NoError(t, err, "another calendar's event with the same external ID is kept")
+	})
+
+	t.Run("TimeFilterSeesSyncedBusyBlock", func(t *testing.T) {
+		timeFilter := filters.NewTimeFilter(filters.DefaultFilterConfig, repo)
+		minutes := 30
+		task := createTestTask("Write report", &minutes, 3)
+		ctx := createTestContext(nil, nil, 60, 5)
+		ctx.Timestamp = start.Add(3*time.Hour - 10*time.Minute)
+
+		visible, code, reason := timeFilter.Evaluate(ctx, task)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonTimeCalendarConflict, code)
+		assert.Contains(t, reason, "Meeting standup")
+	})
+}
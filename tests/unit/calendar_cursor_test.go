@@ -0,0 +1,251 @@
+package unit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIncrementalProvider answers GetChanges from a table of deltas keyed by
+// token and records the tokens it was sent
+type stubIncrementalProvider struct {
+	stubCalendarProvider
+	full    sync.EventChanges
+	deltas  map[string]sync.EventChanges
+	expired map[string]bool
+	tokens  []string
+}
+
+func (p *stubIncrementalProvider) CursorKey() string {
+	return "google"
+}
+
+func (p *stubIncrementalProvider) GetChanges(userID, token string) (*sync.EventChanges, error) {
+	p.tokens = append(p.tokens, token)
+	if token == "" {
+		return &p.full, nil
+	}
+	if p.expired[token] {
+		return nil, sync.ErrSyncTokenExpired
+	}
+	delta, ok := p.deltas[token]
+	if !ok {
+		return nil, fmt.Errorf("unknown token %q", token)
+	}
+	return &delta, nil
+}
+
+// failingEventRepository refuses to create one external event
+type failingEventRepository struct {
+	*memstore.CalendarEventRepository
+	failExternalID string
+}
+
+func (r *failingEventRepository) Create(event models.CalendarEvent) error {
+	if event.ExternalID == r.failExternalID {
+		return fmt.Errorf("disk full")
+	}
+	return r.CalendarEventRepository.Create(event)
+}
+
+func newCursorSyncService(t *testing.T, store *memstore.Store, token string) *sync.CalendarSyncService {
+	service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+	service.SetCursorRepository(store.CalendarSyncCursors())
+	if token != "" {
+		cursor, err := models.NewCalendarSyncCursor("test-user-id", "google", token)
+		require.NoError(t, err)
+		require.NoError(t, store.CalendarSyncCursors().Save(*cursor))
+	}
+	return service
+}
+
+func storedToken(t *testing.T, store *memstore.Store) string {
+	cursor, err := store.CalendarSyncCursors().Get("test-user-id", "google")
+	require.NoError(t, err)
+	if cursor == nil {
+		return ""
+	}
+	return cursor.Token
+}
+
+func externalIDs(t *testing.T, store *memstore.Store) []string {
+	events, err := store.CalendarEvents().GetByUserID("test-user-id")
+	require.NoError(t, err)
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ExternalID)
+	}
+	return ids
+}
+
+func TestCalendarSync_Cursors(t *testing.T) {
+	t.Run("FirstSyncIsFullAndStoresToken", func(t *testing.T) {
+		store := memstore.New()
+		service := newCursorSyncService(t, store, "")
+		provider := &stubIncrementalProvider{full: sync.EventChanges{
+			Events:    []sync.ExternalEvent{upcomingExternalEvent("ext-1"), upcomingExternalEvent("ext-2")},
+			NextToken: "token-1",
+		}}
+
+		result, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{""}, provider.tokens)
+		assert.False(t, result.Incremental)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, "token-1", storedToken(t, store))
+	})
+
+	t.Run("IncrementalSyncSendsTokenAndAppliesDelta", func(t *testing.T) {
+		renamed := upcomingExternalEvent("ext-2")
+		renamed.Title = "Renamed"
+		store := memstore.New()
+		service := newCursorSyncService(t, store, "")
+		provider := &stubIncrementalProvider{
+			full: sync.EventChanges{
+				Events:    []sync.ExternalEvent{upcomingExternalEvent("ext-1"), upcomingExternalEvent("ext-2"), upcomingExternalEvent("ext-3")},
+				NextToken: "token-1",
+			},
+			deltas: map[string]sync.EventChanges{"token-1": {
+				Events:    []sync.ExternalEvent{renamed, upcomingExternalEvent("ext-4")},
+				Deleted:   []string{"ext-1", "never-synced"},
+				NextToken: "token-2",
+			}},
+		}
+		_, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+
+		result, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"", "token-1"}, provider.tokens)
+		assert.True(t, result.Incremental)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 1, result.Updated)
+		assert.Equal(t, 1, result.Deleted)
+		assert.ElementsMatch(t, []string{"ext-2", "ext-3", "ext-4"}, externalIDs(t, store))
+		assert.Equal(t, "token-2", storedToken(t, store))
+
+		event, err := store.CalendarEvents().GetByExternalID("ext-2")
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", event.Title)
+	})
+
+	t.Run("ExpiredTokenTriggersFullResync", func(t *testing.T) {
+		stale := models.CalendarEvent{ID: "stale-id", UserID: "test-user-id", ExternalID: "ext-gone", ProviderID: "google"}
+		other := models.CalendarEvent{ID: "other-id", UserID: "test-user-id", ExternalID: "ext-caldav", ProviderID: "caldav"}
+		store := memstore.New(memstore.WithCalendarEvents(stale, other))
+		service := newCursorSyncService(t, store, "old-token")
+		provider := &stubIncrementalProvider{
+			full: sync.EventChanges{
+				Events:    []sync.ExternalEvent{upcomingExternalEvent("ext-1")},
+				NextToken: "fresh-token",
+			},
+			expired: map[string]bool{"old-token": true},
+		}
+
+		result, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"old-token", ""}, provider.tokens)
+		assert.True(t, result.Resynced)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 1, result.Deleted)
+		assert.ElementsMatch(t, []string{"ext-1", "ext-caldav"}, externalIDs(t, store), "other providers' events are kept")
+		assert.Equal(t, "fresh-token", storedToken(t, store))
+	})
+
+	t.Run("FailedApplyKeepsOldToken", func(t *testing.T) {
+		store := memstore.New()
+		repo := &failingEventRepository{CalendarEventRepository: store.CalendarEvents(), failExternalID: "ext-2"}
+		service := sync.NewCalendarSyncService(repo, nil)
+		service.SetCursorRepository(store.CalendarSyncCursors())
+		cursor, err := models.NewCalendarSyncCursor("test-user-id", "google", "token-1")
+		require.NoError(t, err)
+		require.NoError(t, store.CalendarSyncCursors().Save(*cursor))
+
+		provider := &stubIncrementalProvider{deltas: map[string]sync.EventChanges{"token-1": {
+			Events:    []sync.ExternalEvent{upcomingExternalEvent("ext-1"), upcomingExternalEvent("ext-2")},
+			NextToken: "token-2",
+		}}}
+
+		result, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+		require.Len(t, result.Errors, 1)
+		assert.Equal(t, "token-1", storedToken(t, store))
+
+		// Replaying the delta once the failure clears is harmless
+		repo.failExternalID = ""
+		result, err = service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, 1, result.Created)
+		assert.ElementsMatch(t, []string{"ext-1", "ext-2"}, externalIDs(t, store))
+		assert.Equal(t, "token-2", storedToken(t, store))
+	})
+}
+
+func multistatusResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusMultiStatus, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestCalDAVProvider_GetChanges(t *testing.T) {
+	t.Run("ParsesDeltaAndDeletions", func(t *testing.T) {
+		client := &stubHTTPClient{responses: []*http.Response{multistatusResponse(`<?xml version="1.0"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/cal/standup.ics</D:href>
+    <D:propstat>
+      <D:prop><D:getetag>"1"</D:getetag><C:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Stand\, up
+DTSTART;TZID=Europe/London:20261015T093000
+DTEND;TZID=Europe/London:20261015T094500
+RRULE:FREQ=DAILY
+END:VEVENT
+END:VCALENDAR</C:calendar-data></D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+  <D:response>
+    <D:href>/cal/lunch.ics</D:href>
+    <D:status>HTTP/1.1 404 Not Found</D:status>
+  </D:response>
+  <D:sync-token>http://example.com/sync/2</D:sync-token>
+</D:multistatus>`)}}
+		provider := sync.NewCalDAVProvider("https://dav.example.com/cal", "user", "secret", client)
+
+		changes, err := provider.GetChanges("test-user-id", "http://example.com/sync/1")
+		require.NoError(t, err)
+
+		assert.Equal(t, "http://example.com/sync/2", changes.NextToken)
+		assert.Equal(t, []string{"lunch"}, changes.Deleted)
+		require.Len(t, changes.Events, 1)
+		event := changes.Events[0]
+		assert.Equal(t, "standup", event.ID)
+		assert.Equal(t, "Stand, up", event.Title)
+		assert.Equal(t, "caldav", event.Source)
+		assert.True(t, event.Recurring)
+		assert.Equal(t, "2026-10-15T08:30:00Z", event.StartTime.UTC().Format("2006-01-02T15:04:05Z"))
+		assert.Equal(t, 15*60.0, event.EndTime.Sub(event.StartTime).Seconds())
+	})
+
+	t.Run("InvalidTokenIsExpired", func(t *testing.T) {
+		forbidden := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(
+			`<D:error xmlns:D="DAV:"><D:valid-sync-token/></D:error>`))}
+		client := &stubHTTPClient{responses: []*http.Response{forbidden}}
+		provider := sync.NewCalDAVProvider("https://dav.example.com/cal", "user", "secret", client)
+
+		_, err := provider.GetChanges("test-user-id", "stale")
+		assert.ErrorIs(t, err, sync.ErrSyncTokenExpired)
+	})
+}
@@ -0,0 +1,239 @@
+package unit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCalendarProvider serves a fixed set of events, can rate-limit its
+// first fetches and tracks how many fetches run at once
+type stubCalendarProvider struct {
+	events     []sync.ExternalEvent
+	limitTimes int32
+	retryAfter time.Duration
+	delay      time.Duration
+
+	calls     atomic.Int32
+	inFlight  *atomic.Int32
+	maxFlight *atomic.Int32
+}
+
+func (p *stubCalendarProvider) GetEvents(userID string, start, end time.Time) ([]sync.ExternalEvent, error) {
+	if p.inFlight != nil {
+		current := p.inFlight.Add(1)
+		defer p.inFlight.Add(-1)
+		for {
+			seen := p.maxFlight.Load()
+			if current <= seen || p.maxFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+	}
+	time.Sleep(p.delay)
+
+	if p.calls.Add(1) <= p.limitTimes {
+		return nil, &sync.RateLimitError{RetryAfter: p.retryAfter}
+	}
+	return p.events, nil
+}
+
+func (p *stubCalendarProvider) CreateEvent(userID string, event sync.ExternalEvent) (*sync.ExternalEvent, error) {
+	return &event, nil
+}
+
+func (p *stubCalendarProvider) UpdateEvent(userID string, eventID string, event sync.ExternalEvent) (*sync.ExternalEvent, error) {
+	return &event, nil
+}
+
+func (p *stubCalendarProvider) DeleteEvent(userID string, eventID string) error {
+	return nil
+}
+
+func (p *stubCalendarProvider) ValidateCredentials(userID string) error {
+	return nil
+}
+
+// stubHTTPClient replays canned responses in order
+type stubHTTPClient struct {
+	responses []*http.Response
+	requests  []time.Time
+}
+
+func (c *stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, time.Now())
+	if len(c.responses) == 0 {
+		return nil, fmt.Errorf("unexpected request %s %s", req.Method, req.URL)
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func stubResponse(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+	for key, value := range headers {
+		resp.Header.Set(key, value)
+	}
+	return resp
+}
+
+func upcomingExternalEvent(id string) sync.ExternalEvent {
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	return sync.ExternalEvent{ID: id, Title: "Meeting " + id, StartTime: start, EndTime: start.Add(time.Hour), Source: "google"}
+}
+
+func TestCalendarSync_RateLimiting(t *testing.T) {
+	t.Run("WaitsOutRetryAfterAndRetries", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+		provider := &stubCalendarProvider{
+			events:     []sync.ExternalEvent{upcomingExternalEvent("ext-1")},
+			limitTimes: 1,
+			retryAfter: 200 * time.Millisecond,
+		}
+
+		started := time.Now()
+		result, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, time.Since(started), 200*time.Millisecond)
+		assert.Equal(t, int32(2), provider.calls.Load())
+		assert.Equal(t, 1, result.Created)
+		assert.Empty(t, result.Errors)
+	})
+
+	t.Run("GivesUpAfterMaxRetries", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+		provider := &stubCalendarProvider{limitTimes: 10, retryAfter: time.Millisecond}
+
+		options := sync.DefaultSyncOptions
+		options.MaxRetries = 2
+		results := service.SyncAll([]sync.SyncJob{{UserID: "test-user-id", Provider: provider}}, options)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, int32(3), provider.calls.Load())
+		require.Len(t, results[0].Errors, 1)
+		assert.Contains(t, results[0].Errors[0], "rate limited")
+	})
+
+	t.Run("RefusesRetryAfterPastTheCap", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+		provider := &stubCalendarProvider{limitTimes: 1, retryAfter: time.Hour}
+
+		_, err := service.SyncUserCalendar("test-user-id", provider)
+		require.Error(t, err)
+		assert.Equal(t, int32(1), provider.calls.Load())
+	})
+
+	t.Run("CalDAVHonorsRetryAfterHeader", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+		client := &stubHTTPClient{responses: []*http.Response{
+			stubResponse(http.StatusOK, nil),
+			stubResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}),
+			stubResponse(http.StatusMultiStatus, nil),
+		}}
+		provider := sync.NewCalDAVProvider("https://dav.example.com/cal", "user", "secret", client)
+
+		_, err := service.SyncUserCalendar("test-user-id", provider)
+		require.NoError(t, err)
+
+		require.Len(t, client.requests, 3)
+		assert.GreaterOrEqual(t, client.requests[2].Sub(client.requests[1]), time.Second)
+	})
+
+	t.Run("SpacesRequestsToOneProvider", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+
+		var jobs []sync.SyncJob
+		for i := 0; i < 3; i++ {
+			jobs = append(jobs, sync.SyncJob{UserID: fmt.Sprintf("user-%d", i), Provider: &stubCalendarProvider{}, ProviderKey: "google"})
+		}
+
+		options := sync.DefaultSyncOptions
+		options.ProviderInterval = 50 * time.Millisecond
+		started := time.Now()
+		service.SyncAll(jobs, options)
+
+		// Two requests per calendar, the first of which goes out immediately
+		assert.GreaterOrEqual(t, time.Since(started), 250*time.Millisecond)
+	})
+}
+
+func TestCalendarSync_Concurrency(t *testing.T) {
+	t.Run("NeverExceedsLimit", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+
+		var inFlight, maxFlight atomic.Int32
+		var jobs []sync.SyncJob
+		for i := 0; i < 12; i++ {
+			provider := &stubCalendarProvider{
+				events:    []sync.ExternalEvent{upcomingExternalEvent(fmt.Sprintf("ext-%d", i))},
+				delay:     20 * time.Millisecond,
+				inFlight:  &inFlight,
+				maxFlight: &maxFlight,
+			}
+			jobs = append(jobs, sync.SyncJob{UserID: fmt.Sprintf("user-%d", i), Provider: provider})
+		}
+
+		options := sync.DefaultSyncOptions
+		options.Concurrency = 3
+		results := service.SyncAll(jobs, options)
+
+		require.Len(t, results, 12)
+		for i, result := range results {
+			assert.Equal(t, fmt.Sprintf("user-%d", i), result.UserID)
+			assert.Equal(t, 1, result.Created)
+		}
+		assert.LessOrEqual(t, maxFlight.Load(), int32(3))
+		assert.Equal(t, int32(3), maxFlight.Load(), "the pool should run at its limit")
+	})
+
+	t.Run("StreamsAndRemovesStaleEvents", func(t *testing.T) {
+		store := memstore.New()
+		service := sync.NewCalendarSyncService(store.CalendarEvents(), nil)
+
+		first := &stubCalendarProvider{events: []sync.ExternalEvent{upcomingExternalEvent("ext-1"), upcomingExternalEvent("ext-2")}}
+		_, err := service.SyncUserCalendar("test-user-id", first)
+		require.NoError(t, err)
+
+		renamed := upcomingExternalEvent("ext-2")
+		renamed.Title = "Renamed"
+		second := &stubCalendarProvider{events: []sync.ExternalEvent{renamed}}
+		result, err := service.SyncUserCalendar("test-user-id", second)
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, result.Created)
+		assert.Equal(t, 1, result.Updated)
+		assert.Equal(t, 1, result.Deleted)
+
+		events, err := store.CalendarEvents().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "Renamed", events[0].Title)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, sync.ParseRetryAfter("30", now))
+	assert.Equal(t, 90*time.Second, sync.ParseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, sync.ParseRetryAfter("", now))
+	assert.Zero(t, sync.ParseRetryAfter("soon", now))
+	assert.Zero(t, sync.ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+}
@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	assert.Equal(t, start, fake.Now())
+
+	fake.Advance(90 * time.Minute)
+	assert.Equal(t, start.Add(90*time.Minute), fake.Now())
+
+	fake.Set(start)
+	assert.Equal(t, start, fake.Now())
+
+	assert.WithinDuration(t, time.Now(), clock.Real().Now(), time.Second)
+}
+
+func TestServices_Clock(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	newServices := func() (*hereandnow.TaskService, *hereandnow.ContextService, *clock.Fake) {
+		fake := clock.NewFake(start)
+		taskService, contextService := newMemstoreServices(memstore.New())
+		taskService.SetClock(fake)
+		contextService.SetClock(fake)
+		return taskService, contextService, fake
+	}
+
+	t.Run("AdvancingTimeMakesTaskOverdue", func(t *testing.T) {
+		service, _, fake := newServices()
+		due := start.Add(time.Hour)
+		req := memstoreTaskRequest("Submit timesheet")
+		req.DueAt = &due
+		task, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+		assert.Equal(t, start, task.CreatedAt)
+
+		overdue, err := service.GetOverdueTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, overdue)
+
+		fake.Advance(59 * time.Minute)
+		overdue, err = service.GetOverdueTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, overdue)
+
+		fake.Advance(2 * time.Minute)
+		overdue, err = service.GetOverdueTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Submit timesheet"}, taskTitles(overdue))
+		assert.True(t, overdue[0].IsOverdueAt(fake.Now()))
+	})
+
+	t.Run("AdvancingTimeExpiresSnooze", func(t *testing.T) {
+		service, _, fake := newServices()
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call the bank"))
+		require.NoError(t, err)
+
+		_, err = service.SnoozeTask(task.ID, "test-user-id", fake.Now().Add(3*time.Hour))
+		require.NoError(t, err)
+
+		snoozed, err := service.GetSnoozedTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Call the bank"}, taskTitles(snoozed))
+
+		fake.Advance(3 * time.Hour)
+		snoozed, err = service.GetSnoozedTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, snoozed)
+	})
+
+	t.Run("ResolvesSnoozePresetsFromClock", func(t *testing.T) {
+		service, _, fake := newServices()
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Water plants"))
+		require.NoError(t, err)
+
+		snoozed, err := service.SnoozeTaskWithPreset(task.ID, "test-user-id", "tomorrow-morning", false)
+		require.NoError(t, err)
+
+		expected, err := models.DefaultSnoozePresets().Resolve("tomorrow-morning", fake.Now(), time.Local)
+		require.NoError(t, err)
+		assert.Equal(t, expected, *snoozed.SnoozedUntil)
+	})
+
+	t.Run("TimestampsContextsFromClock", func(t *testing.T) {
+		_, contextService, fake := newServices()
+		fake.Advance(15 * time.Minute)
+
+		context, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			AvailableMinutes: 30,
+			SocialContext:    models.SocialContextAlone,
+			EnergyLevel:      3,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, start.Add(15*time.Minute), context.Timestamp)
+	})
+}
@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionStats(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	now := time.Date(2024, time.March, 13, 15, 0, 0, 0, newYork)
+	daysAgo := func(days, hour int) time.Time {
+		return time.Date(2024, time.March, 13-days, hour, 30, 0, 0, newYork)
+	}
+
+	t.Run("CountsCompletionsToday", func(t *testing.T) {
+		stats := models.NewCompletionStats([]time.Time{daysAgo(0, 8), daysAgo(0, 11), daysAgo(0, 14)}, now, newYork)
+
+		assert.Equal(t, 3, stats.CompletedToday)
+		assert.Equal(t, 1, stats.CurrentStreak)
+		require.NotNil(t, stats.LastCompletedAt)
+		assert.True(t, daysAgo(0, 14).Equal(*stats.LastCompletedAt))
+	})
+
+	t.Run("ConsecutiveDaysBuildStreak", func(t *testing.T) {
+		stats := models.NewCompletionStats([]time.Time{daysAgo(0, 9), daysAgo(1, 9), daysAgo(1, 20), daysAgo(2, 9), daysAgo(3, 9)}, now, newYork)
+
+		assert.Equal(t, 1, stats.CompletedToday)
+		assert.Equal(t, 4, stats.CurrentStreak)
+		assert.Equal(t, 4, stats.LongestStreak)
+	})
+
+	t.Run("GapDayResetsStreak", func(t *testing.T) {
+		// Nothing two days ago
+		stats := models.NewCompletionStats([]time.Time{daysAgo(0, 9), daysAgo(1, 9), daysAgo(3, 9), daysAgo(4, 9), daysAgo(5, 9)}, now, newYork)
+
+		assert.Equal(t, 2, stats.CurrentStreak)
+		assert.Equal(t, 3, stats.LongestStreak)
+	})
+
+	t.Run("StreakEndingYesterdayStillCurrent", func(t *testing.T) {
+		stats := models.NewCompletionStats([]time.Time{daysAgo(1, 9), daysAgo(2, 9)}, now, newYork)
+
+		assert.Equal(t, 0, stats.CompletedToday)
+		assert.Equal(t, 2, stats.CurrentStreak)
+	})
+
+	t.Run("MissedYesterdayBreaksStreak", func(t *testing.T) {
+		stats := models.NewCompletionStats([]time.Time{daysAgo(2, 9), daysAgo(3, 9)}, now, newYork)
+
+		assert.Equal(t, 0, stats.CurrentStreak)
+		assert.Equal(t, 2, stats.LongestStreak)
+	})
+
+	t.Run("UsesUserTimezone", func(t *testing.T) {
+		// 02:00 UTC on the 13th is still the evening of the 12th in New York
+		lateYesterday := time.Date(2024, time.March, 13, 2, 0, 0, 0, time.UTC)
+		stats := models.NewCompletionStats([]time.Time{lateYesterday, daysAgo(0, 9)}, now, newYork)
+
+		assert.Equal(t, 1, stats.CompletedToday)
+		assert.Equal(t, 2, stats.CurrentStreak)
+	})
+
+	t.Run("StreakAcrossDST", func(t *testing.T) {
+		// Clocks spring forward on March 10
+		stats := models.NewCompletionStats([]time.Time{daysAgo(2, 9), daysAgo(3, 9), daysAgo(4, 9)}, daysAgo(2, 12), newYork)
+
+		assert.Equal(t, 3, stats.CurrentStreak)
+	})
+}
+
+func TestTaskService_GetCompletionStats(t *testing.T) {
+	repo := NewMockServiceTaskRepository()
+	service := newTestTaskService(repo)
+
+	completedAt := time.Now()
+	for _, title := range []string{"Buy milk", "Call bank"} {
+		task := createTestTask(title, nil, 3)
+		task.Status = models.TaskStatusCompleted
+		task.CompletedAt = &completedAt
+		require.NoError(t, repo.Create(task))
+	}
+
+	// Completed by someone else
+	other := "other-user-id"
+	delegated := createTestTask("Mow lawn", nil, 3)
+	delegated.AssigneeID = &other
+	delegated.Status = models.TaskStatusCompleted
+	delegated.CompletedAt = &completedAt
+	require.NoError(t, repo.Create(delegated))
+
+	require.NoError(t, repo.Create(createTestTask("Still pending", nil, 3)))
+
+	stats, err := service.GetCompletionStats("test-user-id")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.CompletedToday)
+	assert.Equal(t, 1, stats.CurrentStreak)
+}
+
+func TestGetMyStats(t *testing.T) {
+	repo := NewMockServiceTaskRepository()
+	completedAt := time.Now()
+	task := createTestTask("Buy milk", nil, 3)
+	task.CompletedAt = &completedAt
+	require.NoError(t, repo.Create(task))
+
+	users := api.NewUserHandler(nil)
+	users.SetStatsService(newTestTaskService(repo))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{
+		Users: users,
+		AuthMiddleware: func(c *gin.Context) {
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{})
+
+	w := serveRequest(router, http.MethodGet, "/api/v1/users/me/stats", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats models.CompletionStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 1, stats.CompletedToday)
+	assert.Equal(t, 1, stats.CurrentStreak)
+}
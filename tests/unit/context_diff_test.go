@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CountingAuditRepo counts saved audit entries
+type CountingAuditRepo struct {
+	MockAuditRepo
+	saved int
+}
+
+func (m *CountingAuditRepo) SaveFilterResult(audit models.FilterAudit) error {
+	m.saved++
+	return nil
+}
+
+func taskIDs(changes []filters.TaskVisibilityChange) []string {
+	var ids []string
+	for _, change := range changes {
+		ids = append(ids, change.Task.ID)
+	}
+	return ids
+}
+
+func TestEngine_DiffContexts(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	auditRepo := &CountingAuditRepo{}
+	engine := filters.NewEngine(config, auditRepo)
+	engine.AddRule(filters.NewTimeFilter(config, NewMockCalendarEventRepository()))
+
+	quick, medium, long, tooLong := 15, 45, 90, 240
+	quickTask := createTestTask("Reply to email", &quick, 3)
+	mediumTask := createTestTask("Tidy desk", &medium, 3)
+	longTask := createTestTask("Write report", &long, 3)
+	hiddenTask := createTestTask("Deep clean garage", &tooLong, 3)
+	tasks := []models.Task{quickTask, mediumTask, longTask, hiddenTask}
+
+	base := createTestContext(nil, nil, 120, 4)
+	tired := base
+	require.NoError(t, tired.ApplyChanges("energy=2"))
+
+	t.Run("LowerEnergyHidesDemandingTasks", func(t *testing.T) {
+		diff := engine.DiffContexts(base, tired, tasks)
+
+		require.Len(t, diff.Disappeared, 1)
+		dropped := diff.Disappeared[0]
+		assert.Equal(t, longTask.ID, dropped.Task.ID)
+		require.Len(t, dropped.Reasons, 1)
+		assert.Equal(t, "time", dropped.Reasons[0].FilterName)
+		assert.False(t, dropped.Reasons[0].Visible)
+		assert.Equal(t, "task requires energy level 3 but current level is 2", dropped.Reasons[0].Reason)
+
+		assert.Empty(t, diff.Appeared)
+	})
+
+	t.Run("UnchangedTasksReported", func(t *testing.T) {
+		diff := engine.DiffContexts(base, tired, tasks)
+
+		var unchanged []string
+		for _, task := range diff.Unchanged {
+			unchanged = append(unchanged, task.ID)
+		}
+		assert.Equal(t, []string{quickTask.ID, mediumTask.ID}, unchanged)
+
+		// Hidden in both contexts: not a change
+		assert.NotContains(t, taskIDs(diff.Disappeared), hiddenTask.ID)
+		assert.NotContains(t, taskIDs(diff.Appeared), hiddenTask.ID)
+	})
+
+	t.Run("RaisingEnergyRevealsTasks", func(t *testing.T) {
+		diff := engine.DiffContexts(tired, base, tasks)
+
+		assert.Equal(t, []string{longTask.ID}, taskIDs(diff.Appeared))
+		require.Len(t, diff.Appeared[0].Reasons, 1)
+		assert.True(t, diff.Appeared[0].Reasons[0].Visible)
+		assert.Empty(t, diff.Disappeared)
+	})
+
+	t.Run("DoesNotAudit", func(t *testing.T) {
+		auditRepo.saved = 0
+		engine.DiffContexts(base, tired, tasks)
+		assert.Equal(t, 0, auditRepo.saved)
+	})
+}
+
+func TestContext_ApplyChanges(t *testing.T) {
+	lat, lng := 37.7749, -122.4194
+	base := createTestContext(&lat, &lng, 60, 3)
+
+	t.Run("SetsFields", func(t *testing.T) {
+		ctx := base
+		require.NoError(t, ctx.ApplyChanges("energy=2, minutes=15,social=at_work,weather=rainy"))
+
+		assert.Equal(t, 2, ctx.EnergyLevel)
+		assert.Equal(t, 15, ctx.AvailableMinutes)
+		assert.Equal(t, models.SocialContextAtWork, ctx.SocialContext)
+		require.NotNil(t, ctx.WeatherCondition)
+		assert.Equal(t, "rainy", *ctx.WeatherCondition)
+
+		// The original is untouched
+		assert.Equal(t, 3, base.EnergyLevel)
+		assert.Nil(t, base.WeatherCondition)
+	})
+
+	t.Run("MovesPosition", func(t *testing.T) {
+		ctx := base
+		require.NoError(t, ctx.ApplyChanges("lat=40.7128"))
+
+		assert.Equal(t, 40.7128, *ctx.CurrentLatitude)
+		assert.Equal(t, lng, *ctx.CurrentLongitude)
+		assert.Equal(t, lat, *base.CurrentLatitude)
+	})
+
+	t.Run("RejectsInvalidChanges", func(t *testing.T) {
+		for _, spec := range []string{"energy=9", "energy", "mood=happy", "minutes=-5", "social=partying"} {
+			ctx := base
+			assert.Error(t, ctx.ApplyChanges(spec), spec)
+		}
+	})
+}
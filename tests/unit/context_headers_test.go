@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memstoreAPITaskService serves GET /tasks from a real task service
+type memstoreAPITaskService struct {
+	StubAPITaskService
+	service *hereandnow.TaskService
+}
+
+func (s *memstoreAPITaskService) GetFilteredTasks(userID string, filters api.TaskFilters) (*api.TaskListResponse, error) {
+	tasks, _, err := s.service.GetFilteredTasks(userID)
+	if filters.Search != "" {
+		tasks, err = s.service.SearchTasks(userID, filters.Search)
+	}
+	if err != nil {
+		return nil, err
+	}
+	page, next := api.PageTasks(tasks, filters.Order, filters.Cursor, filters.Limit)
+	return &api.TaskListResponse{Tasks: page, Total: len(tasks), NextCursor: next}, nil
+}
+
+func newContextHeaderRouter(taskService *hereandnow.TaskService, contextService *hereandnow.ContextService, capture bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	api.SetupRoutes(router, api.Handlers{
+		Tasks:    api.NewTaskHandler(&memstoreAPITaskService{service: taskService}, contextService),
+		Contexts: api.NewContextHandler(contextService),
+		AuthMiddleware: func(c *gin.Context) {
+			c.Set("user", &models.User{ID: "test-user-id"})
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{CaptureContextHeaders: capture})
+
+	return router
+}
+
+func getTaskTitles(t *testing.T, router http.Handler, headers map[string]string) []string {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response api.TaskListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	var titles []string
+	for _, task := range response.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func TestContextHeaders(t *testing.T) {
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+
+	setup := func(t *testing.T, capture bool) (*memstore.Store, http.Handler) {
+		store := memstore.New(memstore.WithLocations(home))
+		taskService, contextService := newMemstoreServices(store)
+
+		req := memstoreTaskRequest("Water plants")
+		req.LocationIDs = []string{home.ID}
+		_, err := taskService.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+		_, err = taskService.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+
+		// Far from home
+		lat, lng := 37.8000, -122.5000
+		_, err = contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			Latitude: &lat, Longitude: &lng, AvailableMinutes: 60, EnergyLevel: 3,
+		})
+		require.NoError(t, err)
+
+		return store, newContextHeaderRouter(taskService, contextService, capture)
+	}
+
+	atHome := map[string]string{
+		api.ContextLatHeader:    "37.7749",
+		api.ContextLngHeader:    "-122.4194",
+		api.ContextEnergyHeader: "5",
+	}
+
+	t.Run("HeadersChangeReturnedTasks", func(t *testing.T) {
+		store, router := setup(t, true)
+
+		assert.Equal(t, []string{"Call bank"}, getTaskTitles(t, router, nil))
+		assert.ElementsMatch(t, []string{"Call bank", "Water plants"}, getTaskTitles(t, router, atHome))
+
+		latest, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+		require.NotNil(t, latest.CurrentLatitude)
+		assert.Equal(t, 37.7749, *latest.CurrentLatitude)
+		assert.Equal(t, -122.4194, *latest.CurrentLongitude)
+		assert.Equal(t, 5, latest.EnergyLevel)
+		assert.Equal(t, 60, latest.AvailableMinutes, "fields without headers carry over")
+		require.NotNil(t, latest.CurrentLocationID)
+		assert.Equal(t, home.ID, *latest.CurrentLocationID)
+	})
+
+	t.Run("WithoutHeadersUsesStoredContext", func(t *testing.T) {
+		store, router := setup(t, true)
+		before, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Call bank"}, getTaskTitles(t, router, nil))
+
+		after, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, before.ID, after.ID)
+	})
+
+	t.Run("IgnoredWhenDisabled", func(t *testing.T) {
+		_, router := setup(t, false)
+		assert.Equal(t, []string{"Call bank"}, getTaskTitles(t, router, atHome))
+	})
+
+	t.Run("RejectsMalformedHeaders", func(t *testing.T) {
+		_, router := setup(t, true)
+
+		for _, headers := range []map[string]string{
+			{api.ContextLatHeader: "37.7749"},
+			{api.ContextLatHeader: "north", api.ContextLngHeader: "-122.4194"},
+			{api.ContextLatHeader: "137.0", api.ContextLngHeader: "-122.4194"},
+			{api.ContextEnergyHeader: "9"},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+			for key, value := range headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, headers)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPresetContextService(store *memstore.Store) *hereandnow.ContextService {
+	service := hereandnow.NewContextService(store.Contexts(), store.Locations(), store.CalendarEvents(), nil, nil)
+	service.EnableContextPresets(store.ContextPresets())
+	return service
+}
+
+func TestContextPresets(t *testing.T) {
+	office := *createTestLocation("office-id", "Office", 37.7897, -122.3972, "test-user-id")
+
+	t.Run("SaveCapturesCurrentContext", func(t *testing.T) {
+		store := memstore.New()
+		service := newPresetContextService(store)
+
+		lat, lng := 37.7749, -122.4194
+		_, err := service.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			Latitude: &lat, Longitude: &lng, AvailableMinutes: 120, EnergyLevel: 4, SocialContext: models.SocialContextAlone,
+		})
+		require.NoError(t, err)
+
+		preset, err := service.SavePresetFromCurrent("test-user-id", "Work-Morning")
+		require.NoError(t, err)
+		assert.Equal(t, "work-morning", preset.Name)
+		assert.Equal(t, 120, preset.AvailableMinutes)
+		assert.Equal(t, 4, preset.EnergyLevel)
+		assert.Equal(t, models.SocialContextAlone, preset.SocialContext)
+		require.NotNil(t, preset.Latitude)
+		assert.Equal(t, lat, *preset.Latitude)
+		assert.Nil(t, preset.LocationID)
+
+		stored, err := store.ContextPresets().GetByName("test-user-id", "work-morning")
+		require.NoError(t, err)
+		assert.Equal(t, preset.ID, stored.ID)
+	})
+
+	t.Run("ApplyCreatesMatchingContext", func(t *testing.T) {
+		store := memstore.New()
+		service := newPresetContextService(store)
+
+		preset, err := models.NewContextPreset("test-user-id", "evening")
+		require.NoError(t, err)
+		preset.AvailableMinutes = 30
+		preset.EnergyLevel = 2
+		preset.SocialContext = models.SocialContextWithFamily
+		_, err = service.SaveContextPreset(*preset)
+		require.NoError(t, err)
+
+		context, err := service.ApplyContextPreset("test-user-id", "evening")
+		require.NoError(t, err)
+		assert.Equal(t, 30, context.AvailableMinutes)
+		assert.Equal(t, 2, context.EnergyLevel)
+		assert.Equal(t, models.SocialContextWithFamily, context.SocialContext)
+
+		latest, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, context.ID, latest.ID)
+	})
+
+	t.Run("ResolvesLocationReferenceAtApplyTime", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(office))
+		service := newPresetContextService(store)
+
+		_, err := service.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			LocationID: &office.ID, Latitude: &office.Latitude, Longitude: &office.Longitude,
+			AvailableMinutes: 90, EnergyLevel: 4, SocialContext: models.SocialContextAtWork,
+		})
+		require.NoError(t, err)
+
+		preset, err := service.SavePresetFromCurrent("test-user-id", "office")
+		require.NoError(t, err)
+		require.NotNil(t, preset.LocationID)
+		assert.Equal(t, office.ID, *preset.LocationID)
+		assert.Nil(t, preset.Latitude, "a saved location is stored by reference")
+
+		// The office moves after the preset was saved
+		moved := office
+		moved.Latitude, moved.Longitude = 37.7858, -122.4064
+		require.NoError(t, store.Locations().Update(moved))
+
+		context, err := service.ApplyContextPreset("test-user-id", "office")
+		require.NoError(t, err)
+		require.NotNil(t, context.CurrentLocationID)
+		assert.Equal(t, office.ID, *context.CurrentLocationID)
+		assert.Equal(t, moved.Latitude, *context.CurrentLatitude)
+		assert.Equal(t, moved.Longitude, *context.CurrentLongitude)
+		assert.Equal(t, 90, context.AvailableMinutes)
+	})
+
+	t.Run("RejectsUnknownLocation", func(t *testing.T) {
+		service := newPresetContextService(memstore.New())
+
+		preset, err := models.NewContextPreset("test-user-id", "gym")
+		require.NoError(t, err)
+		missing := "no-such-location"
+		preset.LocationID = &missing
+
+		_, err = service.SaveContextPreset(*preset)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,147 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterEngine_StaleContext(t *testing.T) {
+	office := createTestLocation("office-id", "Office", 37.7749, -122.4194, "test-user-id")
+	home := createTestLocation("home-id", "Home", 37.8649, -122.4194, "test-user-id")
+	store := memstore.New(memstore.WithLocations(*office, *home))
+
+	shortMinutes, longMinutes := 10, 120
+	homeTask := createTestTask("Water the plants", &shortMinutes, 3)
+	longTask := createTestTask("Write the report", &longMinutes, 3)
+	link, err := models.NewTaskLocation(homeTask.ID, home.ID, true)
+	require.NoError(t, err)
+	require.NoError(t, store.TaskLocations().Create(*link))
+	tasks := []models.Task{homeTask, longTask}
+
+	// At the office with half an hour free
+	ctx := createTestContext(&office.Latitude, &office.Longitude, 30, 5)
+	fake := clock.NewFake(ctx.Timestamp)
+
+	newEngine := func(config filters.FilterConfig) *filters.Engine {
+		engine := filters.NewEngine(config, store.FilterAudits())
+		engine.AddRule(filters.NewLocationFilter(config, store.Locations(), store.TaskLocations()))
+		engine.AddRule(filters.NewTimeFilter(config, store.CalendarEvents()))
+		engine.SetClock(fake)
+		return engine
+	}
+	engine := newEngine(filters.DefaultFilterConfig)
+
+	// codes collects each filter's code for a task
+	codes := func(results []filters.FilterResult, taskID string) map[string]filters.ReasonCode {
+		byFilter := make(map[string]filters.ReasonCode)
+		for _, result := range results {
+			if result.TaskID == taskID {
+				byFilter[result.FilterName] = result.Code
+			}
+		}
+		return byFilter
+	}
+
+	t.Run("FreshContextFilters", func(t *testing.T) {
+		fake.Set(ctx.Timestamp.Add(time.Hour))
+		visible, results := engine.FilterTasks(ctx, tasks)
+		assert.Empty(t, visible)
+		assert.Equal(t, filters.ReasonLocationOutOfRange, codes(results, homeTask.ID)["location"])
+		assert.Equal(t, filters.ReasonTimeInsufficient, codes(results, longTask.ID)["time"])
+	})
+
+	t.Run("StaleContextShowsEverything", func(t *testing.T) {
+		fake.Set(ctx.Timestamp.Add(8 * time.Hour))
+		visible, results := engine.FilterTasks(ctx, tasks)
+		assert.Len(t, visible, 2)
+		assert.Equal(t, filters.ReasonLocationStale, codes(results, homeTask.ID)["location"])
+		assert.Equal(t, filters.ReasonTimeStale, codes(results, longTask.ID)["time"])
+		for _, result := range results {
+			if result.Code == filters.ReasonLocationStale {
+				assert.Contains(t, result.Reason, "8h00m old")
+			}
+		}
+	})
+
+	t.Run("ExplainShowsStalenessChangedTheDecision", func(t *testing.T) {
+		fake.Set(ctx.Timestamp.Add(3 * time.Hour))
+		explanation := engine.ExplainTaskVisibility(ctx, homeTask)
+		assert.True(t, explanation.IsVisible)
+		assert.True(t, explanation.Context.IsStale)
+		assert.Equal(t, 3*60*60, explanation.Context.AgeSeconds)
+
+		overridden := make(map[string]bool)
+		for _, result := range explanation.FilterResults {
+			overridden[result.FilterName] = result.StaleOverride
+		}
+		assert.Equal(t, map[string]bool{"location": true, "time": false}, overridden,
+			"the short task fits the stale time anyway")
+	})
+
+	t.Run("ZeroMaxAgeNeverStale", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.ContextMaxAge = 0
+		fake.Set(ctx.Timestamp.Add(48 * time.Hour))
+		visible, _ := newEngine(config).FilterTasks(ctx, tasks)
+		assert.Empty(t, visible)
+	})
+}
+
+func TestContextService_StaleContext(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	lat, lng := 37.7749, -122.4194
+
+	setup := func(t *testing.T) (*memstore.Store, *clock.Fake, models.Context) {
+		store := memstore.New()
+		ctx := createTestContext(&lat, &lng, 30, 3)
+		ctx.Timestamp = start
+		require.NoError(t, store.Contexts().Create(ctx))
+		return store, clock.NewFake(start), ctx
+	}
+
+	t.Run("ReportsAgeAndStaleness", func(t *testing.T) {
+		store, fake, saved := setup(t)
+		_, service := newMemstoreServices(store)
+		service.SetClock(fake)
+		fake.Advance(8 * time.Hour)
+
+		context, err := service.GetCurrentContext("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, saved.ID, context.ID, "a stale context isn't refreshed")
+		assert.True(t, context.IsStale)
+		assert.Equal(t, 8*time.Hour, context.Age())
+	})
+
+	t.Run("RefreshedContextIsFresh", func(t *testing.T) {
+		store, fake, saved := setup(t)
+		_, service := newMemstoreServices(store)
+		service.SetClock(fake)
+		fake.Advance(time.Hour)
+
+		context, err := service.GetCurrentContext("test-user-id")
+		require.NoError(t, err)
+		assert.NotEqual(t, saved.ID, context.ID)
+		assert.False(t, context.IsStale)
+		assert.Zero(t, context.AgeSeconds)
+	})
+
+	t.Run("ConfiguredMaxAge", func(t *testing.T) {
+		store, fake, _ := setup(t)
+		_, service := newMemstoreServices(store)
+		service.SetClock(fake)
+		service.SetContextMaxAge(10 * time.Minute)
+		fake.Advance(12 * time.Minute)
+
+		context, err := service.GetCurrentContext("test-user-id")
+		require.NoError(t, err)
+		assert.True(t, context.IsStale)
+		assert.Equal(t, 12*60, context.AgeSeconds)
+	})
+}
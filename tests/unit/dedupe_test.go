@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_Dedupe(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "alice")
+	office := *createTestLocation("office-id", "Office", 37.7858, -122.4064, "alice")
+
+	newTask := func(title, creatorID string, age time.Duration) models.Task {
+		task := createTestTask(title, nil, 3)
+		task.CreatorID = creatorID
+		task.CreatedAt = start.Add(-age)
+		return task
+	}
+
+	setup := func(t *testing.T, tasks ...models.Task) (*memstore.Store, *hereandnow.TaskService) {
+		groceries, err := models.NewTaskList("Groceries", "", "alice")
+		require.NoError(t, err)
+		household, err := models.NewTaskList("Household", "", "bob")
+		require.NoError(t, err)
+
+		// Alice's tasks go in her list, Bob's in his
+		for i := range tasks {
+			if tasks[i].CreatorID == "alice" {
+				tasks[i].ListID = &groceries.ID
+			} else {
+				tasks[i].ListID = &household.ID
+			}
+		}
+
+		store := memstore.New(memstore.WithLists(*groceries, *household), memstore.WithLocations(home, office), memstore.WithTasks(tasks...))
+		for _, list := range []*models.TaskList{groceries, household} {
+			member, err := models.NewListMember(list.ID, "alice", list.OwnerID, models.MemberRoleEditor)
+			require.NoError(t, err)
+			member.Accept()
+			require.NoError(t, store.ListMembers().Create(*member))
+		}
+
+		service, _ := newMemstoreServices(store)
+		service.SetListMemberRepository(store.ListMembers())
+		service.EnableTaskLinks(store.TaskLinks())
+		return store, service
+	}
+
+	t.Run("DetectsNearDuplicatesAcrossLists", func(t *testing.T) {
+		original := newTask("Buy milk", "alice", 48*time.Hour)
+		duplicate := newTask("buy some milk!", "bob", 24*time.Hour)
+		plural := newTask("Buy milks", "bob", time.Hour)
+		_, service := setup(t, original, duplicate, plural)
+
+		groups, err := service.FindDuplicateTasks("alice")
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, original.ID, groups[0].Keep.ID, "the earliest task is kept")
+		assert.ElementsMatch(t, []string{duplicate.ID, plural.ID}, []string{groups[0].Duplicates[0].ID, groups[0].Duplicates[1].ID})
+		assert.GreaterOrEqual(t, groups[0].Similarity, 0.8)
+	})
+
+	t.Run("AutoMergeKeepsOneAndCancelsTheRest", func(t *testing.T) {
+		original := newTask("Buy milk", "alice", 48*time.Hour)
+		duplicate := newTask("Buy Milk.", "bob", time.Hour)
+		store, service := setup(t, duplicate, original)
+
+		groups, err := service.FindDuplicateTasks("alice")
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+
+		cancelled, err := service.MergeDuplicates("alice", groups[0])
+		require.NoError(t, err)
+		assert.Equal(t, []string{duplicate.ID}, []string{cancelled[0].ID})
+
+		kept, err := store.Tasks().GetByID(original.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, kept.Status)
+		assert.Equal(t, "alice", kept.CreatorID, "the earliest creator is preserved")
+
+		dropped, err := store.Tasks().GetByID(duplicate.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCancelled, dropped.Status)
+
+		linked, err := service.GetLinkedTasks(duplicate.ID)
+		require.NoError(t, err)
+		require.Len(t, linked, 1)
+		assert.Equal(t, original.ID, linked[0].Task.ID)
+		assert.Equal(t, models.TaskRelationDuplicateOf, linked[0].Relation)
+
+		groups, err = service.FindDuplicateTasks("alice")
+		require.NoError(t, err)
+		assert.Empty(t, groups, "merged duplicates are not suggested again")
+	})
+
+	t.Run("IgnoresDissimilarTasks", func(t *testing.T) {
+		_, service := setup(t,
+			newTask("Buy milk", "alice", 3*time.Hour),
+			newTask("Buy bread", "bob", 2*time.Hour),
+			newTask("Mow the lawn", "bob", time.Hour),
+		)
+
+		groups, err := service.FindDuplicateTasks("alice")
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+
+	t.Run("IgnoresSameTitleAtDifferentPlaces", func(t *testing.T) {
+		atHome := newTask("Pick up package", "alice", 2*time.Hour)
+		atOffice := newTask("Pick up packages", "bob", time.Hour)
+		store, service := setup(t, atHome, atOffice)
+
+		for taskID, locationID := range map[string]string{atHome.ID: home.ID, atOffice.ID: office.ID} {
+			link, err := models.NewTaskLocation(taskID, locationID, true)
+			require.NoError(t, err)
+			require.NoError(t, store.TaskLocations().Create(*link))
+		}
+
+		groups, err := service.FindDuplicateTasks("alice")
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+}
@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDatabaseURL(t *testing.T) {
+	tests := []struct {
+		location string
+		dialect  storage.Dialect
+		path     string
+	}{
+		{"/var/lib/hereandnow/data.db", storage.DialectSQLite, "/var/lib/hereandnow/data.db"},
+		{"file:/tmp/data.db", storage.DialectSQLite, "/tmp/data.db"},
+		{"file:///tmp/data.db", storage.DialectSQLite, "/tmp/data.db"},
+		{"postgres://app:secret@db:5432/hereandnow", storage.DialectPostgres, "postgres://app:secret@db:5432/hereandnow"},
+		{"PostgreSQL://db/hereandnow", storage.DialectPostgres, "PostgreSQL://db/hereandnow"},
+	}
+
+	for _, tt := range tests {
+		dialect, path := storage.ParseDatabaseURL(tt.location)
+		assert.Equal(t, tt.dialect, dialect, tt.location)
+		assert.Equal(t, tt.path, path, tt.location)
+	}
+}
+
+func TestDialect_Rebind(t *testing.T) {
+	query := "SELECT * FROM tasks WHERE title = '?' AND creator_id = ? AND priority > ?"
+
+	assert.Equal(t, query, storage.DialectSQLite.Rebind(query))
+	assert.Equal(t,
+		"SELECT * FROM tasks WHERE title = '?' AND creator_id = $1 AND priority > $2",
+		storage.DialectPostgres.Rebind(query))
+	assert.Equal(t, "SELECT 1", storage.DialectPostgres.Rebind("SELECT 1"))
+}
+
+func TestDialect_TranslateDDL(t *testing.T) {
+	script := `PRAGMA foreign_keys = ON;
+CREATE TABLE users (
+    id TEXT PRIMARY KEY NOT NULL,
+    is_admin BOOLEAN NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT 1,
+    latitude REAL NULL,
+    avatar BLOB,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+	assert.Equal(t, script, storage.DialectSQLite.TranslateDDL(script))
+
+	translated := storage.DialectPostgres.TranslateDDL(script)
+	assert.NotContains(t, translated, "PRAGMA")
+	assert.Contains(t, translated, "is_admin BOOLEAN NOT NULL DEFAULT FALSE")
+	assert.Contains(t, translated, "is_active BOOLEAN NOT NULL DEFAULT TRUE")
+	assert.Contains(t, translated, "latitude DOUBLE PRECISION NULL")
+	assert.Contains(t, translated, "avatar BYTEA")
+	assert.Contains(t, translated, "created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP")
+	assert.True(t, strings.HasPrefix(strings.TrimSpace(translated), "CREATE TABLE users"))
+}
+
+func TestNewDB_PostgresWithoutDriver(t *testing.T) {
+	if storage.PostgresAvailable {
+		t.Skip("built with PostgreSQL support")
+	}
+
+	_, err := storage.NewDB(storage.Config{Path: "postgres://localhost/hereandnow"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, storage.ErrPostgresUnavailable)
+}
+
+func TestNewDB_FileURL(t *testing.T) {
+	path := t.TempDir() + "/data.db"
+	db, err := storage.NewDB(storage.Config{Path: "file:" + path})
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, storage.DialectSQLite, db.Dialect())
+	assert.Equal(t, path, db.Path())
+}
@@ -0,0 +1,91 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_PreviewCreateTask(t *testing.T) {
+	t.Run("ReportsTaskWithoutCreatingIt", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+
+		preview, err := service.PreviewCreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+		assert.Equal(t, "Call bank", preview.Title)
+		assert.Equal(t, "test-user-id", preview.CreatorID)
+		assert.Equal(t, models.TaskStatusPending, preview.Status)
+
+		tasks, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+	})
+
+	t.Run("ValidatesLikeCreate", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+
+		req := memstoreTaskRequest("Call bank")
+		req.Priority = 11
+		_, err := service.PreviewCreateTask("test-user-id", req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "priority")
+
+		req = memstoreTaskRequest("Send report")
+		req.Dependencies = []hereandnow.TaskDependencyRequest{{DependsOnTaskID: "no-such-task", DependencyType: models.DependencyTypeBlocking}}
+		_, err = service.PreviewCreateTask("test-user-id", req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no-such-task")
+
+		req = memstoreTaskRequest("Water plants")
+		req.LocationIDs = []string{"home-id"}
+		req.LocationTrigger = "linger"
+		_, err = service.PreviewCreateTask("test-user-id", req)
+		require.Error(t, err)
+	})
+
+	t.Run("PositionsListTasks", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		createListTasks(t, service, "list-1", "A", "B")
+
+		listID := "list-1"
+		req := memstoreTaskRequest("C")
+		req.ListID = &listID
+		preview, err := service.PreviewCreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"A", "B"}, listTitles(t, service, "list-1"))
+		tasks, err := service.GetTasksByList(listID)
+		require.NoError(t, err)
+		assert.Greater(t, preview.Position, tasks[len(tasks)-1].Position)
+	})
+}
+
+func TestDB_RowCounts(t *testing.T) {
+	db := setupMetadataDB(t)
+	_, err := db.Exec(`INSERT INTO tasks (id, title) VALUES ('task-1', 'A'), ('task-2', 'B')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO users (id) VALUES ('user-1')`)
+	require.NoError(t, err)
+
+	counts, err := db.RowCounts()
+	require.NoError(t, err)
+	assert.Equal(t, []storage.TableRowCount{
+		{Table: "contexts", Rows: 0},
+		{Table: "locations", Rows: 0},
+		{Table: "task_tags", Rows: 0},
+		{Table: "tasks", Rows: 2},
+		{Table: "users", Rows: 1},
+	}, counts)
+
+	// Reporting the scope leaves the data in place
+	var remaining int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&remaining))
+	assert.Equal(t, 2, remaining)
+}
@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeFilter_EffortPoints(t *testing.T) {
+	calendarRepo := NewMockCalendarEventRepository()
+
+	pointsConfig := filters.DefaultFilterConfig
+	pointsConfig.EstimateUnit = filters.EstimateUnitPoints
+
+	t.Run("PointsModeConvertsPointsToMinutes", func(t *testing.T) {
+		filter := filters.NewTimeFilter(pointsConfig, calendarRepo)
+		task := createTestTask("Write proposal", nil, 3)
+		points := 3
+		task.EffortPoints = &points
+
+		visible, reason := filter.Apply(createTestContext(nil, nil, 60, 5), task)
+		assert.True(t, visible, reason)
+
+		visible, reason = filter.Apply(createTestContext(nil, nil, 45, 5), task)
+		assert.False(t, visible)
+		assert.Equal(t, "task needs 3 points (60 minutes) but only 45 available", reason)
+	})
+
+	t.Run("PointsTakePrecedenceOverMinutes", func(t *testing.T) {
+		filter := filters.NewTimeFilter(pointsConfig, calendarRepo)
+		minutes := 10
+		task := createTestTask("Write proposal", &minutes, 3)
+		points := 5
+		task.EffortPoints = &points
+
+		visible, _ := filter.Apply(createTestContext(nil, nil, 30, 5), task)
+		assert.False(t, visible)
+	})
+
+	t.Run("PointsModeFallsBackToMinutes", func(t *testing.T) {
+		filter := filters.NewTimeFilter(pointsConfig, calendarRepo)
+		minutes := 20
+		task := createTestTask("Call bank", &minutes, 3)
+
+		visible, reason := filter.Apply(createTestContext(nil, nil, 30, 5), task)
+		assert.True(t, visible, reason)
+	})
+
+	t.Run("ConversionTableIsRespected", func(t *testing.T) {
+		config := pointsConfig
+		config.PointsToMinutes = map[int]int{1: 5, 2: 10, 4: 20}
+		filter := filters.NewTimeFilter(config, calendarRepo)
+		task := createTestTask("Review PR", nil, 3)
+		points := 2
+		task.EffortPoints = &points
+
+		visible, reason := filter.Apply(createTestContext(nil, nil, 10, 5), task)
+		assert.True(t, visible, reason)
+
+		visible, _ = filter.Apply(createTestContext(nil, nil, 9, 5), task)
+		assert.False(t, visible)
+	})
+
+	t.Run("MinutesModeIgnoresPoints", func(t *testing.T) {
+		filter := filters.NewTimeFilter(filters.DefaultFilterConfig, calendarRepo)
+		minutes := 30
+		task := createTestTask("Write proposal", &minutes, 3)
+		points := 13
+		task.EffortPoints = &points
+
+		visible, reason := filter.Apply(createTestContext(nil, nil, 30, 5), task)
+		assert.True(t, visible, reason)
+		assert.Equal(t, "task fits in 30 minute window (needs 30)", reason)
+
+		pointsOnly := createTestTask("Refactor parser", nil, 3)
+		pointsOnly.EffortPoints = &points
+		visible, reason = filter.Apply(createTestContext(nil, nil, 5, 5), pointsOnly)
+		assert.True(t, visible)
+		assert.Equal(t, "task has no time estimate", reason)
+	})
+}
+
+func TestFilterConfig_PointsAsMinutes(t *testing.T) {
+	config := filters.DefaultFilterConfig
+
+	assert.Equal(t, 60, config.PointsAsMinutes(3))
+	assert.Equal(t, 120, config.PointsAsMinutes(4), "points between entries round up")
+	assert.Equal(t, 960, config.PointsAsMinutes(26), "points past the table scale linearly")
+
+	config.PointsToMinutes = map[int]int{1: 25, 3: 90}
+	assert.Equal(t, 90, config.PointsAsMinutes(2))
+	assert.Equal(t, 150, config.PointsAsMinutes(5))
+}
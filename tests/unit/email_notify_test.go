@@ -0,0 +1,261 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer is a fake mail server that records the emails it accepts and
+// answers each RCPT with the next reply in rcptReplies, then 250
+type smtpServer struct {
+	listener    net.Listener
+	mu          sync.Mutex
+	rcptReplies []string
+	emails      []smtpEmail
+}
+
+type smtpEmail struct {
+	From string
+	To   string
+	Data string
+}
+
+func newSMTPServer(t *testing.T, rcptReplies ...string) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{listener: listener, rcptReplies: rcptReplies}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost fake ESMTP")
+	var email smtpEmail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(line)
+		switch verb := strings.ToUpper(strings.Fields(command + " ")[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			email = smtpEmail{From: strings.TrimPrefix(command, "MAIL FROM:")}
+			reply("250 OK")
+		case "RCPT":
+			email.To = strings.TrimPrefix(command, "RCPT TO:")
+			s.mu.Lock()
+			answer := "250 OK"
+			if len(s.rcptReplies) > 0 {
+				answer, s.rcptReplies = s.rcptReplies[0], s.rcptReplies[1:]
+			}
+			s.mu.Unlock()
+			reply(answer)
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			email.Data = data.String()
+			s.mu.Lock()
+			s.emails = append(s.emails, email)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) received() []smtpEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpEmail(nil), s.emails...)
+}
+
+func (s *smtpServer) config() notify.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return notify.SMTPConfig{Host: host, Port: portNumber, From: "tasks@example.com"}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	options := notify.Options{Retries: 1, Backoff: time.Millisecond, MaxAttempts: 2}
+
+	setup := func(t *testing.T, config notify.SMTPConfig, emailEnabled bool) (*memstore.Store, *notify.SMTPNotifier, *notify.Dispatcher) {
+		user, err := models.NewUser("alice", "alice@example.com", "Alice", "UTC")
+		require.NoError(t, err)
+		user.ID = "test-user-id"
+		store := memstore.New(memstore.WithUsers(*user))
+
+		settings := models.NewNotificationSettings("test-user-id")
+		settings.CreatedAt = settings.CreatedAt.Add(-time.Minute)
+		settings.EmailNotificationsEnabled = emailEnabled
+		require.NoError(t, store.NotificationSettings().Save(*settings))
+
+		notifier := notify.NewSMTPNotifier(store.NotificationSettings(), store.Users(), config)
+		return store, notifier, notify.NewDispatcher(store.Notifications(), options, notifier)
+	}
+
+	notifyUser := func(t *testing.T, store *memstore.Store, message string) {
+		notification, err := models.NewNotification("test-user-id", models.NotificationTypeTaskReminder, message)
+		require.NoError(t, err)
+		require.NoError(t, store.Notifications().Create(*notification))
+	}
+
+	t.Run("EmailsUsersWhoTurnedEmailOn", func(t *testing.T) {
+		server := newSMTPServer(t)
+		store, _, dispatcher := setup(t, server.config(), true)
+		notifyUser(t, store, "Reminder: Submit expenses (due Mon Mar 2 17:00)")
+
+		delivered, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Zero(t, failed)
+
+		emails := server.received()
+		require.Len(t, emails, 1)
+		assert.Contains(t, emails[0].From, "<tasks@example.com>")
+		assert.Contains(t, emails[0].To, "<alice@example.com>")
+		assert.Contains(t, emails[0].Data, "To: alice@example.com\r\n")
+		assert.Contains(t, emails[0].Data, "Subject: Here and Now: Reminder: Submit expenses (due Mon Mar 2 17:00)\r\n")
+		assert.Contains(t, emails[0].Data, "X-Hereandnow-Type: task_reminder\r\n")
+		assert.True(t, strings.HasSuffix(emails[0].Data, "\r\n\r\nReminder: Submit expenses (due Mon Mar 2 17:00)\r\n"))
+
+		delivered, _, err = dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, delivered)
+		assert.Len(t, server.received(), 1, "sent once")
+	})
+
+	t.Run("SkipsUsersWithEmailOff", func(t *testing.T) {
+		server := newSMTPServer(t)
+		store, notifier, dispatcher := setup(t, server.config(), false)
+		notifyUser(t, store, "Buy milk")
+
+		delivered, _, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, delivered, "no channel is on")
+		require.NoError(t, notifier.Send(context.Background(), models.Notification{UserID: "test-user-id", Message: "Buy milk"}))
+		assert.Empty(t, server.received())
+	})
+
+	t.Run("RetriesTemporaryRejections", func(t *testing.T) {
+		server := newSMTPServer(t, "451 Try again later")
+		store, _, dispatcher := setup(t, server.config(), true)
+		notifyUser(t, store, "Buy milk")
+
+		delivered, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Zero(t, failed)
+		assert.Len(t, server.received(), 1)
+	})
+
+	t.Run("RecordsPermanentRejections", func(t *testing.T) {
+		server := newSMTPServer(t, "550 No such user")
+		store, _, dispatcher := setup(t, server.config(), true)
+		notifyUser(t, store, "Buy milk")
+
+		_, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, failed)
+		assert.Empty(t, server.received())
+
+		notifications, err := store.Notifications().GetByUserID("test-user-id", false)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1, "the in-app notification is kept")
+		assert.Equal(t, 1, notifications[0].DeliveryAttempts)
+		pending, err := store.Notifications().GetUndelivered(10)
+		require.NoError(t, err)
+		assert.Empty(t, pending, "given up on")
+	})
+
+	t.Run("UnreachableServerIsTransient", func(t *testing.T) {
+		server := newSMTPServer(t)
+		config := server.config()
+		server.listener.Close()
+		_, notifier, _ := setup(t, config, true)
+
+		err := notifier.Send(context.Background(), models.Notification{UserID: "test-user-id", Message: "Buy milk"})
+		require.Error(t, err)
+		assert.True(t, notify.IsTransient(err))
+	})
+
+	t.Run("DryRunLogsTheEmail", func(t *testing.T) {
+		server := newSMTPServer(t)
+		config := server.config()
+		config.DryRun = true
+		store, notifier, dispatcher := setup(t, config, true)
+		var logged bytes.Buffer
+		notifier.SetLogger(log.New(&logged, "", 0))
+		notifyUser(t, store, "Buy milk")
+
+		delivered, _, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Empty(t, server.received(), "nothing is sent")
+		assert.Contains(t, logged.String(), "Dry run: email to alice@example.com not sent")
+		assert.Contains(t, logged.String(), "Subject: Here and Now: Buy milk")
+	})
+}
+
+func TestRenderEmail(t *testing.T) {
+	notification := models.Notification{
+		Type:      models.NotificationTypeTaskAssigned,
+		Message:   "Bob assigned you: Écrire le rapport\nSecond line",
+		CreatedAt: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+	}
+	email := string(notify.RenderEmail("tasks@example.com", "alice@example.com", notification))
+
+	assert.Contains(t, email, "Date: Mon, 02 Mar 2026 09:00:00 +0000\r\n")
+	assert.Contains(t, email, "Subject: =?utf-8?q?", "non-ASCII subjects are encoded")
+	assert.NotContains(t, email, "Second_line", "only the first line is the subject")
+	assert.True(t, strings.HasSuffix(email, "\r\n\r\nBob assigned you: Écrire le rapport\r\nSecond line\r\n"))
+
+	long := notification
+	long.Message = strings.Repeat("a", 100)
+	assert.Contains(t, string(notify.RenderEmail("f@example.com", "t@example.com", long)), "Subject: Here and Now: "+strings.Repeat("a", 69)+"...\r\n")
+}
@@ -0,0 +1,47 @@
+//go:build sqlcipher
+
+package unit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseEncryption_SQLCipher(t *testing.T) {
+	const key = "correct horse battery staple"
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	db, err := storage.NewDB(storage.Config{Path: path, EncryptionKey: key})
+	require.NoError(t, err)
+	assert.True(t, db.Encrypted())
+	_, err = db.Exec(`CREATE TABLE locations (id TEXT PRIMARY KEY, name TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO locations (id, name) VALUES ('home', 'Home')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	t.Run("CannotOpenWithoutKey", func(t *testing.T) {
+		_, err := storage.NewDB(storage.Config{Path: path})
+		assert.Error(t, err)
+	})
+
+	t.Run("CannotOpenWithWrongKey", func(t *testing.T) {
+		_, err := storage.NewDB(storage.Config{Path: path, EncryptionKey: "wrong key"})
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "wrong key", "the key is never part of an error")
+	})
+
+	t.Run("OpensWithKey", func(t *testing.T) {
+		db, err := storage.NewDB(storage.Config{Path: path, EncryptionKey: key})
+		require.NoError(t, err)
+		defer db.Close()
+
+		var name string
+		require.NoError(t, db.QueryRow(`SELECT name FROM locations WHERE id = 'home'`).Scan(&name))
+		assert.Equal(t, "Home", name)
+	})
+}
@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseEncryption_PrivacyWarning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	t.Run("WarnsWhenUnencrypted", func(t *testing.T) {
+		warning := storage.Config{Path: path}.PrivacyWarning()
+		assert.Contains(t, warning, path)
+		assert.Contains(t, warning, "not encrypted")
+		assert.Contains(t, warning, "location history")
+	})
+
+	t.Run("SilentWhenEncrypted", func(t *testing.T) {
+		const key = "correct horse battery staple"
+		warning := storage.Config{Path: path, EncryptionKey: key}.PrivacyWarning()
+		assert.Empty(t, warning)
+	})
+
+	t.Run("SilentInMemory", func(t *testing.T) {
+		assert.Empty(t, storage.Config{InMemory: true}.PrivacyWarning())
+	})
+
+	t.Run("PlainDatabaseIsNotEncrypted", func(t *testing.T) {
+		db, err := storage.NewDB(storage.Config{Path: path})
+		require.NoError(t, err)
+		defer db.Close()
+		assert.False(t, db.Encrypted())
+	})
+}
+
+func TestDatabaseEncryption_Unavailable(t *testing.T) {
+	if storage.EncryptionAvailable {
+		t.Skip("built with sqlcipher")
+	}
+
+	const key = "correct horse battery staple"
+	_, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "data.db"), EncryptionKey: key})
+	require.ErrorIs(t, err, storage.ErrEncryptionUnavailable)
+	assert.NotContains(t, err.Error(), key)
+}
@@ -0,0 +1,101 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func alignedPriorityFilter(curve filters.EnergyAlignmentCurve) *filters.PriorityFilter {
+	config := filters.DefaultFilterConfig
+	config.EnergyAlignment = curve
+	return filters.NewPriorityFilter(config)
+}
+
+func TestPriorityFilter_EnergyAlignment(t *testing.T) {
+	minutes := 30
+
+	t.Run("HidesLowPrioritiesAtLowEnergy", func(t *testing.T) {
+		filter := alignedPriorityFilter(filters.EnergyAlignmentCurve{1: {MinPriority: 4}})
+		ctx := createTestContext(nil, nil, 60, 1)
+
+		for priority := 1; priority <= 3; priority++ {
+			visible, code, reason := filter.Evaluate(ctx, createTestTask("Tidy desk", &minutes, priority))
+			assert.False(t, visible, "priority %d", priority)
+			assert.Equal(t, filters.ReasonPriorityEnergyFloor, code)
+			assert.Contains(t, reason, "below minimum 4 at energy level 1")
+		}
+
+		for priority := 4; priority <= 5; priority++ {
+			_, code, _ := filter.Evaluate(ctx, createTestTask("File taxes", &minutes, priority))
+			assert.NotEqual(t, filters.ReasonPriorityEnergyFloor, code, "priority %d", priority)
+		}
+
+		_, code, _ := filter.Evaluate(createTestContext(nil, nil, 60, 2), createTestTask("Tidy desk", &minutes, 1))
+		assert.NotEqual(t, filters.ReasonPriorityEnergyFloor, code, "the floor applies only at energy 1")
+	})
+
+	t.Run("ModifiesScoresAtEnergyLevel", func(t *testing.T) {
+		filter := alignedPriorityFilter(filters.EnergyAlignmentCurve{
+			5: {Modifier: 0.05, PriorityModifiers: map[int]float64{1: -0.1}},
+		})
+		base := filters.NewPriorityFilter(filters.DefaultFilterConfig)
+		ctx := createTestContext(nil, nil, 60, 5)
+
+		boosted := filter.CalculatePriorityScore(ctx, createTestTask("Read article", &minutes, 3))
+		plain := base.CalculatePriorityScore(ctx, createTestTask("Read article", &minutes, 3))
+		assert.InDelta(t, plain.TotalScore+0.05, boosted.TotalScore, 1e-9)
+		assert.Contains(t, boosted.Explanation, "A:+0.05")
+
+		lowered := filter.CalculatePriorityScore(ctx, createTestTask("Read article", &minutes, 1))
+		plain = base.CalculatePriorityScore(ctx, createTestTask("Read article", &minutes, 1))
+		assert.InDelta(t, plain.TotalScore-0.05, lowered.TotalScore, 1e-9)
+	})
+
+	t.Run("DefaultCurveKeepsExistingBehavior", func(t *testing.T) {
+		base := filters.NewPriorityFilter(filters.DefaultFilterConfig)
+		empty := alignedPriorityFilter(filters.EnergyAlignmentCurve{})
+		elsewhere := alignedPriorityFilter(filters.EnergyAlignmentCurve{1: {MinPriority: 5, Modifier: 0.3}})
+
+		for energy := 2; energy <= 5; energy++ {
+			for priority := 1; priority <= 5; priority++ {
+				ctx := createTestContext(nil, nil, 60, energy)
+				task := createTestTask("Water plants", &minutes, priority)
+
+				visible, code, reason := base.Evaluate(ctx, task)
+				for _, filter := range []*filters.PriorityFilter{empty, elsewhere} {
+					gotVisible, gotCode, gotReason := filter.Evaluate(ctx, task)
+					assert.Equal(t, visible, gotVisible)
+					assert.Equal(t, code, gotCode)
+					assert.Equal(t, reason, gotReason)
+				}
+			}
+		}
+
+		// The existing priority filter expectations hold with the default
+		// curve, at midday so the time-of-day threshold does not interfere
+		midday := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+		energetic := createTestContext(nil, nil, 60, 5)
+		energetic.Timestamp = midday
+		visible, _ := empty.Apply(energetic, createTestTask("High Priority Task", &minutes, 5))
+		assert.True(t, visible)
+
+		urgent := createTestTask("Urgent Task", &minutes, 3)
+		dueAt := midday.Add(time.Hour)
+		urgent.DueAt = &dueAt
+		ctx := createTestContext(nil, nil, 60, 3)
+		ctx.Timestamp = midday
+		visible, _ = empty.Apply(ctx, urgent)
+		assert.True(t, visible)
+	})
+
+	t.Run("ValidatesCurve", func(t *testing.T) {
+		require.NoError(t, filters.EnergyAlignmentCurve{1: {MinPriority: 4}, 5: {Modifier: 0.1}}.Validate())
+		assert.Error(t, filters.EnergyAlignmentCurve{0: {MinPriority: 4}}.Validate())
+		assert.Error(t, filters.EnergyAlignmentCurve{1: {MinPriority: 6}}.Validate())
+		assert.Error(t, filters.EnergyAlignmentCurve{3: {PriorityModifiers: map[int]float64{9: 0.1}}}.Validate())
+	})
+}
@@ -0,0 +1,131 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnergyFilter(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	config.EnableEnergyFilter = true
+	filter := filters.NewEnergyFilter(config)
+
+	taskNeeding := func(energy int) models.Task {
+		task := createTestTask("Write quarterly plan", nil, 3)
+		require.NoError(t, task.SetRequiredEnergyLevel(energy))
+		return task
+	}
+
+	t.Run("HidesTaskNeedingMoreEnergy", func(t *testing.T) {
+		visible, code, reason := filter.Evaluate(createTestContext(nil, nil, 60, 2), taskNeeding(4))
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonEnergyInsufficient, code)
+		assert.Equal(t, "needs energy 4, you are at 2", reason)
+
+		visible, code, _ = filter.Evaluate(createTestContext(nil, nil, 60, 4), taskNeeding(4))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonEnergySufficient, code)
+	})
+
+	t.Run("TaskWithoutRequirementPasses", func(t *testing.T) {
+		visible, code, _ := filter.Evaluate(createTestContext(nil, nil, 60, 1), createTestTask("Buy milk", nil, 3))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonEnergyNoRequirement, code)
+	})
+
+	t.Run("UnknownEnergyShowsTask", func(t *testing.T) {
+		visible, code, _ := filter.Evaluate(createTestContext(nil, nil, 60, 0), taskNeeding(5))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonEnergyUnknown, code)
+	})
+
+	t.Run("ToleranceShowsTaskRankedLower", func(t *testing.T) {
+		tolerant := config
+		tolerant.EnergyTolerance = 1
+		ctx := createTestContext(nil, nil, 60, 3)
+
+		visible, code, _ := filters.NewEnergyFilter(tolerant).Evaluate(ctx, taskNeeding(4))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonEnergyWithinTolerance, code)
+
+		visible, _, _ = filters.NewEnergyFilter(tolerant).Evaluate(ctx, taskNeeding(5))
+		assert.False(t, visible)
+
+		scorer := filters.NewPriorityFilter(tolerant)
+		fits := scorer.CalculatePriorityScore(ctx, taskNeeding(3))
+		stretch := scorer.CalculatePriorityScore(ctx, taskNeeding(4))
+		assert.Less(t, stretch.EnergyScore, fits.EnergyScore)
+		assert.Less(t, stretch.TotalScore, fits.TotalScore)
+	})
+
+	t.Run("NotCountedTwiceWithOtherFilters", func(t *testing.T) {
+		ctx := createTestContext(nil, nil, 60, 2)
+		demanding := taskNeeding(4)
+		plain := demanding
+		plain.RequiredEnergyLevel = nil
+
+		// Only the energy filter hides a task for the energy it states; the
+		// priority and time filters treat it like the same task without one
+		priority := filters.NewPriorityFilter(config)
+		visible, _ := priority.Apply(ctx, demanding)
+		plainVisible, _ := priority.Apply(ctx, plain)
+		assert.Equal(t, plainVisible, visible)
+		assert.Less(t, priority.CalculatePriorityScore(ctx, demanding).TotalScore,
+			priority.CalculatePriorityScore(ctx, plain).TotalScore, "the shortfall still ranks it lower")
+
+		timeFilter := filters.NewTimeFilter(config, nil)
+		visible, _ = timeFilter.Apply(ctx, demanding)
+		plainVisible, _ = timeFilter.Apply(ctx, plain)
+		assert.Equal(t, plainVisible, visible)
+
+		// and a task the user has the energy for is not scored lower for it
+		assert.Equal(t, 1.0, priority.CalculatePriorityScore(ctx, taskNeeding(2)).EnergyScore)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		visible, code, _ := filters.NewEnergyFilter(filters.DefaultFilterConfig).Evaluate(createTestContext(nil, nil, 60, 1), taskNeeding(5))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonFilterDisabled, code)
+	})
+}
+
+func TestTask_RequiredEnergyLevel(t *testing.T) {
+	task := createTestTask("Write quarterly plan", nil, 3)
+	assert.Error(t, task.SetRequiredEnergyLevel(0))
+	assert.Error(t, task.SetRequiredEnergyLevel(6))
+	assert.Nil(t, task.RequiredEnergyLevel)
+
+	level := 6
+	task.RequiredEnergyLevel = &level
+	assert.Error(t, task.Validate())
+
+	level = 5
+	assert.NoError(t, task.Validate())
+}
+
+func TestFilterEngine_EnergyFilter(t *testing.T) {
+	demanding := createTestTask("Write quarterly plan", nil, 3)
+	require.NoError(t, demanding.SetRequiredEnergyLevel(4))
+	easy := createTestTask("Water plants", nil, 3)
+	ctx := createTestContext(nil, nil, 60, 2)
+
+	engine := filters.NewEngine(filters.DefaultFilterConfig, &MockAuditRepo{})
+	visible, _ := engine.FilterTasks(ctx, []models.Task{demanding, easy})
+	assert.Len(t, visible, 2)
+
+	require.NoError(t, engine.EnableFilter("energy"))
+	visible, results := engine.FilterTasks(ctx, []models.Task{demanding, easy})
+	require.Len(t, visible, 1)
+	assert.Equal(t, "Water plants", visible[0].Title)
+	require.Len(t, results, 2)
+	assert.Equal(t, "energy", results[0].FilterName)
+	assert.Equal(t, "needs energy 4, you are at 2", results[0].Reason)
+
+	require.NoError(t, engine.DisableFilter("energy"))
+	visible, _ = engine.FilterTasks(ctx, []models.Task{demanding, easy})
+	assert.Len(t, visible, 2)
+}
@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockHistoryContextRepository stores contexts in memory and serves energy profiles from them
+type MockHistoryContextRepository struct {
+	contexts []models.Context
+}
+
+func (m *MockHistoryContextRepository) GetLatestByUserID(userID string) (*models.Context, error) {
+	for i := len(m.contexts) - 1; i >= 0; i-- {
+		if m.contexts[i].UserID == userID {
+			ctx := m.contexts[i]
+			return &ctx, nil
+		}
+	}
+	return nil, fmt.Errorf("no context for user: %s", userID)
+}
+
+func (m *MockHistoryContextRepository) Create(context models.Context) error {
+	m.contexts = append(m.contexts, context)
+	return nil
+}
+
+func (m *MockHistoryContextRepository) GetEnergyProfile(userID string, since time.Time, loc *time.Location) (*models.EnergyProfile, error) {
+	var history []models.Context
+	for _, ctx := range m.contexts {
+		if ctx.UserID == userID && !ctx.Timestamp.Before(since) {
+			history = append(history, ctx)
+		}
+	}
+	return models.NewEnergyProfile(history, loc), nil
+}
+
+// addEnergyHistory records one snapshot per day for the given number of days at hour
+func (m *MockHistoryContextRepository) addEnergyHistory(userID string, now time.Time, hour, days, energy int) {
+	for d := 1; d <= days; d++ {
+		day := now.AddDate(0, 0, -d)
+		m.contexts = append(m.contexts, models.Context{
+			ID:          fmt.Sprintf("%s-%d-%d", userID, hour, d),
+			UserID:      userID,
+			Timestamp:   time.Date(day.Year(), day.Month(), day.Day(), hour, 15, 0, 0, now.Location()),
+			EnergyLevel: energy,
+		})
+	}
+}
+
+func TestContextService_DefaultEnergyLevel(t *testing.T) {
+	now := time.Now()
+	evening := time.Date(now.Year(), now.Month(), now.Day(), 20, 30, 0, 0, now.Location())
+	morning := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, now.Location())
+
+	t.Run("LowEveningHistoryDefaultsLow", func(t *testing.T) {
+		repo := &MockHistoryContextRepository{}
+		repo.addEnergyHistory("user-1", evening, 20, 10, 1)
+		repo.addEnergyHistory("user-1", evening, 9, 10, 5)
+
+		service := hereandnow.NewContextService(repo, nil, nil, nil, nil)
+		service.EnableEnergyPrediction(repo)
+
+		assert.Equal(t, 1, service.DefaultEnergyLevel("user-1", evening))
+		assert.Equal(t, 5, service.DefaultEnergyLevel("user-1", morning))
+	})
+
+	t.Run("SparseHistoryUsesNeutralDefault", func(t *testing.T) {
+		repo := &MockHistoryContextRepository{}
+		repo.addEnergyHistory("user-1", evening, 20, models.MinEnergyProfileSamples-1, 1)
+
+		service := hereandnow.NewContextService(repo, nil, nil, nil, nil)
+		service.EnableEnergyPrediction(repo)
+
+		assert.Equal(t, models.DefaultEnergyLevel, service.DefaultEnergyLevel("user-1", evening))
+	})
+
+	t.Run("HistoryOutsideWindowIgnored", func(t *testing.T) {
+		repo := &MockHistoryContextRepository{}
+		repo.addEnergyHistory("user-1", evening.Add(-2*hereandnow.EnergyProfileWindow), 20, 10, 1)
+
+		service := hereandnow.NewContextService(repo, nil, nil, nil, nil)
+		service.EnableEnergyPrediction(repo)
+
+		assert.Equal(t, models.DefaultEnergyLevel, service.DefaultEnergyLevel("user-1", evening))
+	})
+
+	t.Run("DisabledUsesNeutralDefault", func(t *testing.T) {
+		repo := &MockHistoryContextRepository{}
+		repo.addEnergyHistory("user-1", evening, 20, 10, 1)
+
+		service := hereandnow.NewContextService(repo, nil, nil, nil, nil)
+
+		assert.Equal(t, models.DefaultEnergyLevel, service.DefaultEnergyLevel("user-1", evening))
+	})
+
+	t.Run("UpdateWithoutEnergyUsesPrediction", func(t *testing.T) {
+		repo := &MockHistoryContextRepository{}
+		for hour := 0; hour < 24; hour++ {
+			repo.addEnergyHistory("user-1", now, hour, 10, 2)
+		}
+
+		service := hereandnow.NewContextService(repo, nil, nil, nil, nil)
+		service.EnableEnergyPrediction(repo)
+
+		context, err := service.UpdateUserContext("user-1", hereandnow.UpdateContextRequest{
+			AvailableMinutes: 30,
+			SocialContext:    models.SocialContextAlone,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, context.EnergyLevel)
+
+		context, err = service.UpdateUserContext("user-1", hereandnow.UpdateContextRequest{
+			AvailableMinutes: 30,
+			SocialContext:    models.SocialContextAlone,
+			EnergyLevel:      4,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 4, context.EnergyLevel, "explicit energy should win")
+	})
+}
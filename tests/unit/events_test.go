@@ -0,0 +1,319 @@
+package unit
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSharedListStore returns a store with Alice's family list, shared with
+// Bob, who accepted, and Carol, who has not
+func newSharedListStore(t *testing.T) (*memstore.Store, *models.TaskList) {
+	list, err := models.NewTaskList("Family", "", "alice")
+	require.NoError(t, err)
+
+	store := memstore.New(memstore.WithLists(*list))
+	bob, err := models.NewListMember(list.ID, "bob", "alice", models.MemberRoleEditor)
+	require.NoError(t, err)
+	bob.Accept()
+	require.NoError(t, store.ListMembers().Create(*bob))
+	carol, err := models.NewListMember(list.ID, "carol", "alice", models.MemberRoleViewer)
+	require.NoError(t, err)
+	require.NoError(t, store.ListMembers().Create(*carol))
+
+	return store, list
+}
+
+func receiveEvent(t *testing.T, sub *hereandnow.Subscription) hereandnow.ChangeEvent {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event within a second")
+		return hereandnow.ChangeEvent{}
+	}
+}
+
+func assertNoEvent(t *testing.T, sub *hereandnow.Subscription) {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected %s event", event.Type)
+	default:
+	}
+}
+
+func TestEventHub(t *testing.T) {
+	t.Run("DeliversToListOwnerMembersAndAssignee", func(t *testing.T) {
+		store, list := newSharedListStore(t)
+		hub := hereandnow.NewEventHub(store.TaskLists(), store.ListMembers(), 0)
+
+		alice := hub.Subscribe("alice", "")
+		bob := hub.Subscribe("bob", "")
+		carol := hub.Subscribe("carol", "")
+		dave := hub.Subscribe("dave", "")
+		defer alice.Close()
+		defer bob.Close()
+		defer carol.Close()
+		defer dave.Close()
+		assert.Equal(t, 4, hub.ActiveSubscribers())
+
+		task := createTestTask("Buy milk", nil, 3)
+		task.CreatorID = "bob"
+		task.ListID = &list.ID
+		hub.Publish(hereandnow.ChangeEvent{Type: hereandnow.EventTaskCreated, ListID: task.ListID, Task: &task})
+
+		assert.Equal(t, "Buy milk", receiveEvent(t, alice).Task.Title)
+		assert.Equal(t, "1", receiveEvent(t, bob).ID)
+		assertNoEvent(t, carol)
+		assertNoEvent(t, dave)
+
+		private := createTestTask("Dentist", nil, 3)
+		private.CreatorID = "alice"
+		assignee := "dave"
+		private.AssigneeID = &assignee
+		hub.Publish(hereandnow.ChangeEvent{Type: hereandnow.EventTaskUpdated, Task: &private})
+
+		assert.Equal(t, "Dentist", receiveEvent(t, alice).Task.Title)
+		assert.Equal(t, "Dentist", receiveEvent(t, dave).Task.Title)
+		assertNoEvent(t, bob)
+	})
+
+	t.Run("ResumesFromLastEventID", func(t *testing.T) {
+		hub := hereandnow.NewEventHub(nil, nil, 0)
+		for _, creator := range []string{"alice", "bob", "alice", "alice"} {
+			task := createTestTask("Task", nil, 3)
+			task.CreatorID = creator
+			hub.Publish(hereandnow.ChangeEvent{Type: hereandnow.EventTaskCreated, Task: &task})
+		}
+
+		sub := hub.Subscribe("alice", "1")
+		defer sub.Close()
+		assert.False(t, sub.Reset)
+		require.Len(t, sub.Missed, 2)
+		assert.Equal(t, "3", sub.Missed[0].ID)
+		assert.Equal(t, "4", sub.Missed[1].ID)
+
+		upToDate := hub.Subscribe("alice", "4")
+		defer upToDate.Close()
+		assert.False(t, upToDate.Reset)
+		assert.Empty(t, upToDate.Missed)
+	})
+
+	t.Run("ResetWhenResumePointIsGone", func(t *testing.T) {
+		hub := hereandnow.NewEventHub(nil, nil, 2)
+		for i := 0; i < 5; i++ {
+			task := createTestTask("Task", nil, 3)
+			task.CreatorID = "alice"
+			hub.Publish(hereandnow.ChangeEvent{Type: hereandnow.EventTaskUpdated, Task: &task})
+		}
+
+		evicted := hub.Subscribe("alice", "1")
+		defer evicted.Close()
+		assert.True(t, evicted.Reset)
+		assert.Empty(t, evicted.Missed)
+
+		buffered := hub.Subscribe("alice", "3")
+		defer buffered.Close()
+		assert.False(t, buffered.Reset)
+		require.Len(t, buffered.Missed, 2)
+		assert.Equal(t, "4", buffered.Missed[0].ID)
+
+		for _, unknown := range []string{"99", "not-a-number"} {
+			sub := hub.Subscribe("alice", unknown)
+			assert.True(t, sub.Reset, unknown)
+			sub.Close()
+		}
+	})
+
+	t.Run("DropsSubscriberThatFallsBehind", func(t *testing.T) {
+		hub := hereandnow.NewEventHub(nil, nil, 0)
+		sub := hub.Subscribe("alice", "")
+
+		task := createTestTask("Task", nil, 3)
+		task.CreatorID = "alice"
+		for i := 0; i < 100; i++ {
+			hub.Publish(hereandnow.ChangeEvent{Type: hereandnow.EventTaskUpdated, Task: &task})
+		}
+
+		assert.Equal(t, 0, hub.ActiveSubscribers())
+		received := 0
+		for range sub.Events() {
+			received++
+		}
+		assert.Less(t, received, 100)
+		sub.Close()
+	})
+}
+
+func TestTaskService_PublishesEvents(t *testing.T) {
+	store, list := newSharedListStore(t)
+	service, _ := newMemstoreServices(store)
+	hub := hereandnow.NewEventHub(store.TaskLists(), store.ListMembers(), 0)
+	service.SetEventPublisher(hub)
+
+	alice := hub.Subscribe("alice", "")
+	defer alice.Close()
+
+	req := memstoreTaskRequest("Buy milk")
+	req.ListID = &list.ID
+	task, err := service.CreateTask("bob", req)
+	require.NoError(t, err)
+
+	event := receiveEvent(t, alice)
+	assert.Equal(t, hereandnow.EventTaskCreated, event.Type)
+	assert.Equal(t, "bob", event.ActorID)
+	assert.Equal(t, task.ID, event.Task.ID)
+
+	_, err = service.CompleteTask(task.ID, "bob")
+	require.NoError(t, err)
+	event = receiveEvent(t, alice)
+	assert.Equal(t, hereandnow.EventTaskCompleted, event.Type)
+	assert.True(t, event.Task.IsCompleted())
+
+	require.NoError(t, service.DeleteTask(task.ID, "bob"))
+	assert.Equal(t, hereandnow.EventTaskDeleted, receiveEvent(t, alice).Type)
+	assertNoEvent(t, alice)
+}
+
+func TestListService_AddMember(t *testing.T) {
+	store, list := newSharedListStore(t)
+	hub := hereandnow.NewEventHub(store.TaskLists(), store.ListMembers(), 0)
+	service := hereandnow.NewListService(store.TaskLists(), store.ListMembers())
+	service.SetEventPublisher(hub)
+
+	bob := hub.Subscribe("bob", "")
+	dave := hub.Subscribe("dave", "")
+	defer bob.Close()
+	defer dave.Close()
+
+	t.Run("OwnerAddsMember", func(t *testing.T) {
+		member, err := service.AddMember(list.ID, "dave", models.MemberRoleViewer, "alice")
+		require.NoError(t, err)
+		assert.True(t, member.IsPending())
+
+		event := receiveEvent(t, bob)
+		assert.Equal(t, hereandnow.EventListMemberAdded, event.Type)
+		assert.Equal(t, "dave", event.Member.UserID)
+		assert.Equal(t, list.ID, *event.ListID)
+		assert.Equal(t, hereandnow.EventListMemberAdded, receiveEvent(t, dave).Type)
+	})
+
+	t.Run("OnlyOwnerCanAdd", func(t *testing.T) {
+		_, err := service.AddMember(list.ID, "erin", models.MemberRoleViewer, "bob")
+		assert.ErrorContains(t, err, "only the list owner")
+	})
+
+	t.Run("RejectsExistingMember", func(t *testing.T) {
+		_, err := service.AddMember(list.ID, "bob", models.MemberRoleViewer, "alice")
+		assert.ErrorContains(t, err, "already a member")
+	})
+}
+
+func TestEventsHandler_Stream(t *testing.T) {
+	store, list := newSharedListStore(t)
+	service, _ := newMemstoreServices(store)
+	hub := hereandnow.NewEventHub(store.TaskLists(), store.ListMembers(), 0)
+	service.SetEventPublisher(hub)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	events := api.NewEventsHandler(hub)
+	events.SetHeartbeat(50 * time.Millisecond)
+	api.SetupRoutes(router, api.Handlers{
+		Events: events,
+		AuthMiddleware: func(c *gin.Context) {
+			userID := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			c.Set("user", &models.User{ID: userID})
+			c.Set("user_id", userID)
+			c.Next()
+		},
+	}, api.RouteConfig{})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	// connect opens a stream for the user and returns its lines
+	connect := func(t *testing.T, userID, lastEventID string) <-chan string {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/events?token="+userID, nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		lines := make(chan string, 100)
+		go func() {
+			defer close(lines)
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+		return lines
+	}
+	waitFor := func(t *testing.T, lines <-chan string, want string) {
+		t.Helper()
+		deadline := time.After(time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				require.True(t, ok, "stream closed before %q", want)
+				if strings.HasPrefix(line, want) {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("no %q within a second", want)
+			}
+		}
+	}
+
+	alice := connect(t, "alice", "")
+	waitFor(t, alice, "event: connected")
+
+	req := memstoreTaskRequest("Buy milk")
+	req.ListID = &list.ID
+	task, err := service.CreateTask("bob", req)
+	require.NoError(t, err)
+	_, err = service.CompleteTask(task.ID, "bob")
+	require.NoError(t, err)
+
+	t.Run("MemberSeesChangesWithinASecond", func(t *testing.T) {
+		waitFor(t, alice, "event: task.created")
+		waitFor(t, alice, "id: 1")
+		waitFor(t, alice, "event: task.completed")
+		waitFor(t, alice, "id: 2")
+	})
+
+	t.Run("SendsHeartbeats", func(t *testing.T) {
+		waitFor(t, alice, ": heartbeat")
+	})
+
+	t.Run("ResumesFromLastEventID", func(t *testing.T) {
+		resumed := connect(t, "bob", "1")
+		waitFor(t, resumed, "event: task.completed")
+		waitFor(t, resumed, "id: 2")
+		waitFor(t, resumed, "event: connected")
+	})
+
+	t.Run("ResetWhenResumePointUnknown", func(t *testing.T) {
+		stale := connect(t, "bob", "500")
+		waitFor(t, stale, "event: reset")
+	})
+}
@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_ExplainTaskVisibility(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	auditRepo := &CountingAuditRepo{}
+	engine := filters.NewEngine(config, auditRepo)
+	engine.AddRule(filters.NewTimeFilter(config, NewMockCalendarEventRepository()))
+
+	minutes := 45
+	task := createTestTask("Write report", &minutes, 3)
+	lat, lng := 37.7749, -122.4194
+	ctx := createTestContext(&lat, &lng, 30, 2)
+
+	explanation := engine.ExplainTaskVisibility(ctx, task)
+
+	assert.False(t, explanation.IsVisible)
+	require.Len(t, explanation.FilterResults, 1)
+	assert.Equal(t, "time", explanation.FilterResults[0].FilterName)
+	assert.False(t, explanation.FilterResults[0].Passed)
+	assert.Equal(t, filters.ReasonTimeInsufficient, explanation.FilterResults[0].Code)
+
+	assert.Equal(t, ctx.ID, explanation.Context.ContextID)
+	assert.True(t, ctx.Timestamp.Equal(explanation.Context.Timestamp))
+	require.NotNil(t, explanation.Context.Latitude)
+	assert.Equal(t, lat, *explanation.Context.Latitude)
+	assert.Equal(t, 30, explanation.Context.AvailableMinutes)
+	assert.Equal(t, 2, explanation.Context.EnergyLevel)
+
+	assert.Zero(t, auditRepo.saved, "explaining must not record audits")
+}
+
+// explainAPITaskService finds every task except "missing"
+type explainAPITaskService struct {
+	StubAPITaskService
+}
+
+func (s *explainAPITaskService) GetTaskByID(taskID string, userID string) (*models.Task, error) {
+	if taskID == "missing" {
+		return nil, fmt.Errorf("task not found")
+	}
+	return &models.Task{ID: taskID}, nil
+}
+
+func TestExplainTask(t *testing.T) {
+	setup := func(explainer api.TaskExplainService) http.Handler {
+		handler := api.NewTaskHandler(&explainAPITaskService{}, nil)
+		if explainer != nil {
+			handler.SetExplainService(explainer)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: handler,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router
+	}
+
+	store := memstore.New()
+	service, _ := newMemstoreServices(store)
+	task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+	require.NoError(t, err)
+	ctx := createTestContext(nil, nil, 60, 4)
+	require.NoError(t, store.Contexts().Create(ctx))
+
+	t.Run("ExplainsEachFilter", func(t *testing.T) {
+		w := serveRequest(setup(service), http.MethodGet, "/api/v1/tasks/"+task.ID+"/explain", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var explanation filters.TaskVisibilityExplanation
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &explanation))
+		assert.Equal(t, task.ID, explanation.TaskID)
+		assert.NotEmpty(t, explanation.FilterResults)
+		assert.Equal(t, ctx.ID, explanation.Context.ContextID)
+		assert.Equal(t, 60, explanation.Context.AvailableMinutes)
+		assert.Equal(t, 4, explanation.Context.EnergyLevel)
+
+		audits, err := store.FilterAudits().GetAuditLogByTaskID(task.ID, 0)
+		require.NoError(t, err)
+		assert.Empty(t, audits)
+	})
+
+	t.Run("UnknownTask", func(t *testing.T) {
+		w := serveRequest(setup(service), http.MethodGet, "/api/v1/tasks/missing/explain", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		w := serveRequest(setup(nil), http.MethodGet, "/api/v1/tasks/"+task.ID+"/explain", "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
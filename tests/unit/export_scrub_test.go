@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportScrub(t *testing.T) {
+	listID := "list-home"
+	assignee := "assignee-user-id"
+	placeID := "place-123"
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	private := createTestTask("Fix the leaking tap", nil, 4)
+	private.ListID = &listID
+	private.AssigneeID = &assignee
+	private.DueAt = &due
+	private.Description = "Spare key is under the blue pot"
+	private.Metadata = json.RawMessage(`{"private": true, "tags": ["plumbing"], "notes": "landlord owes us"}`)
+
+	public := createTestTask("Water plants", nil, 2)
+	public.ListID = &listID
+	public.Description = "Twice a week"
+
+	home := *createTestLocation("home-id", "Home", 37.774929, -122.419416, "test-user-id")
+	home.Address = "1 Main St"
+	home.PlaceID = &placeID
+
+	tasks := []models.Task{private, public}
+	lists := []models.TaskList{{ID: listID, Name: "Home"}}
+	locations := map[string][]models.Location{private.ID: {home}}
+
+	t.Run("UnscrubbedIncludesEverything", func(t *testing.T) {
+		markdown := sync.ExportMarkdown(tasks, lists, locations)
+
+		assert.Contains(t, markdown, "## Home")
+		assert.Contains(t, markdown, "- [ ] Fix the leaking tap")
+		assert.Contains(t, markdown, "Due: 2026-10-20")
+		assert.Contains(t, markdown, "Assignee: assignee-user-id")
+		assert.Contains(t, markdown, "Location: Home (37.774929, -122.419416), 1 Main St")
+		assert.Contains(t, markdown, "Spare key is under the blue pot")
+		assert.Contains(t, markdown, "Tags: plumbing")
+		assert.Contains(t, markdown, "Twice a week")
+	})
+
+	t.Run("ScrubbedOmitsPrivateFields", func(t *testing.T) {
+		scrubbedLocations := map[string][]models.Location{private.ID: {sync.ScrubLocation(home)}}
+		markdown := sync.ExportMarkdown(sync.ScrubTasks(tasks), lists, scrubbedLocations)
+
+		assert.Contains(t, markdown, "- [ ] Fix the leaking tap")
+		assert.Contains(t, markdown, "- [ ] Water plants")
+		assert.Contains(t, markdown, "Due: 2026-10-20")
+		assert.Contains(t, markdown, "Tags: plumbing")
+		assert.Contains(t, markdown, "Location: Home (37.77, -122.42)\n")
+		assert.Contains(t, markdown, "Twice a week", "descriptions of tasks not marked private are kept")
+
+		assert.NotContains(t, markdown, "assignee-user-id")
+		assert.NotContains(t, markdown, "Spare key")
+		assert.NotContains(t, markdown, "1 Main St")
+		assert.NotContains(t, markdown, "37.774929")
+	})
+
+	t.Run("ScrubbedTaskFields", func(t *testing.T) {
+		scrubbed := sync.ScrubTask(private)
+
+		assert.Equal(t, private.Title, scrubbed.Title)
+		assert.Equal(t, private.DueAt, scrubbed.DueAt)
+		assert.Equal(t, private.Priority, scrubbed.Priority)
+		assert.Empty(t, scrubbed.CreatorID)
+		assert.Nil(t, scrubbed.AssigneeID)
+		assert.Empty(t, scrubbed.Description)
+		assert.JSONEq(t, `{"tags": ["plumbing"]}`, string(scrubbed.Metadata))
+
+		assert.Equal(t, "test-user-id", private.CreatorID, "scrubbing works on a copy")
+	})
+
+	t.Run("ScrubbedLocationFields", func(t *testing.T) {
+		scrubbed := sync.ScrubLocation(home)
+
+		assert.Equal(t, "Home", scrubbed.Name)
+		assert.Equal(t, 37.77, scrubbed.Latitude)
+		assert.Equal(t, -122.42, scrubbed.Longitude)
+		assert.Empty(t, scrubbed.Address)
+		assert.Empty(t, scrubbed.UserID)
+		assert.Nil(t, scrubbed.PlaceID)
+	})
+
+	t.Run("ScrubbedTodoistExport", func(t *testing.T) {
+		export := sync.ExportTodoist(sync.ScrubTasks(tasks), lists)
+		require.Len(t, export.Items, 2)
+
+		assert.Empty(t, export.Items[0].Description)
+		assert.Equal(t, []string{"plumbing"}, export.Items[0].Labels)
+		assert.Equal(t, "Twice a week", export.Items[1].Description)
+	})
+}
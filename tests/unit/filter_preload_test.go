@@ -0,0 +1,209 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lookupCounts counts repository calls by method name
+type lookupCounts map[string]int
+
+type countingTaskLocations struct {
+	*memstore.TaskLocationRepository
+	counts lookupCounts
+}
+
+func (r countingTaskLocations) GetLocationsByTaskID(taskID string) ([]models.Location, error) {
+	r.counts["GetLocationsByTaskID"]++
+	return r.TaskLocationRepository.GetLocationsByTaskID(taskID)
+}
+
+func (r countingTaskLocations) GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error) {
+	r.counts["GetTaskLocationsByTaskID"]++
+	return r.TaskLocationRepository.GetTaskLocationsByTaskID(taskID)
+}
+
+func (r countingTaskLocations) GetLocationsByTaskIDs(taskIDs []string) (map[string][]models.Location, error) {
+	r.counts["GetLocationsByTaskIDs"]++
+	return r.TaskLocationRepository.GetLocationsByTaskIDs(taskIDs)
+}
+
+func (r countingTaskLocations) GetTaskLocationsByTaskIDs(taskIDs []string) (map[string][]models.TaskLocation, error) {
+	r.counts["GetTaskLocationsByTaskIDs"]++
+	return r.TaskLocationRepository.GetTaskLocationsByTaskIDs(taskIDs)
+}
+
+type countingDependencies struct {
+	*memstore.TaskDependencyRepository
+	counts lookupCounts
+}
+
+func (r countingDependencies) GetDependenciesByTaskID(taskID string) ([]models.TaskDependency, error) {
+	r.counts["GetDependenciesByTaskID"]++
+	return r.TaskDependencyRepository.GetDependenciesByTaskID(taskID)
+}
+
+func (r countingDependencies) GetDependenciesByTaskIDs(taskIDs []string) (map[string][]models.TaskDependency, error) {
+	r.counts["GetDependenciesByTaskIDs"]++
+	return r.TaskDependencyRepository.GetDependenciesByTaskIDs(taskIDs)
+}
+
+type countingTasks struct {
+	*memstore.TaskRepository
+	counts lookupCounts
+}
+
+func (r countingTasks) GetByID(taskID string) (*models.Task, error) {
+	r.counts["GetByID"]++
+	return r.TaskRepository.GetByID(taskID)
+}
+
+func (r countingTasks) GetByIDs(taskIDs []string) ([]models.Task, error) {
+	r.counts["GetByIDs"]++
+	return r.TaskRepository.GetByIDs(taskIDs)
+}
+
+type countingCalendar struct {
+	*memstore.CalendarEventRepository
+	counts lookupCounts
+}
+
+func (r countingCalendar) GetEventsByUserIDAndTimeRange(userID string, start, end time.Time) ([]models.CalendarEvent, error) {
+	r.counts["GetEventsByUserIDAndTimeRange"]++
+	return r.CalendarEventRepository.GetEventsByUserIDAndTimeRange(userID, start, end)
+}
+
+type countingAudits struct {
+	*memstore.FilterAuditRepository
+	counts lookupCounts
+}
+
+func (r countingAudits) SaveFilterResult(audit models.FilterAudit) error {
+	r.counts["SaveFilterResult"]++
+	return r.FilterAuditRepository.SaveFilterResult(audit)
+}
+
+func (r countingAudits) SaveFilterResults(audits []models.FilterAudit) error {
+	r.counts["SaveFilterResults"]++
+	return r.FilterAuditRepository.SaveFilterResults(audits)
+}
+
+// newPreloadStore returns a store of tasks where every third task is tied to
+// a location and every odd task waits on the one before it, with a meeting
+// twenty minutes from now
+func newPreloadStore(t *testing.T, now time.Time, count int) (*memstore.Store, []models.Task) {
+	store := memstore.New()
+	home := createTestLocation("home", "Home", 37.7749, -122.4194, "test-user-id")
+	require.NoError(t, store.Locations().Create(*home))
+
+	meeting, err := models.NewCalendarEvent("test-user-id", "google", "standup", "Standup", now.Add(20*time.Minute), now.Add(40*time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, store.CalendarEvents().Create(*meeting))
+
+	minutes := 15
+	tasks := make([]models.Task, count)
+	for i := range tasks {
+		tasks[i] = createTestTask("Task", &minutes, 3)
+		if i%4 == 0 {
+			tasks[i].Status = models.TaskStatusCompleted
+		}
+		require.NoError(t, store.Tasks().Create(tasks[i]))
+
+		if i%3 == 0 {
+			link, err := models.NewTaskLocation(tasks[i].ID, home.ID, true)
+			require.NoError(t, err)
+			require.NoError(t, store.TaskLocations().Create(*link))
+		}
+		if i%2 == 1 {
+			dep, err := models.NewTaskDependency(tasks[i].ID, tasks[i-1].ID, models.DependencyTypeBlocking)
+			require.NoError(t, err)
+			require.NoError(t, store.Dependencies().Create(*dep))
+		}
+	}
+	return store, tasks
+}
+
+func TestFilterEngine_Preload(t *testing.T) {
+	lat, lng := 37.7749, -122.4194
+	ctx := createTestContext(&lat, &lng, 60, 3)
+	store, tasks := newPreloadStore(t, ctx.Timestamp, 30)
+
+	// newEngine returns an engine over the store; without batch its
+	// repositories offer only the per-task lookups
+	newEngine := func(batch bool) (*filters.Engine, lookupCounts) {
+		counts := lookupCounts{}
+		var (
+			taskLocations filters.TaskLocationRepository   = countingTaskLocations{store.TaskLocations(), counts}
+			dependencies  filters.TaskDependencyRepository = countingDependencies{store.Dependencies(), counts}
+			taskRepo      filters.TaskRepository           = countingTasks{store.Tasks(), counts}
+			audits        filters.FilterAuditRepository    = countingAudits{store.FilterAudits(), counts}
+		)
+		if !batch {
+			taskLocations = struct{ filters.TaskLocationRepository }{taskLocations}
+			dependencies = struct {
+				filters.TaskDependencyRepository
+			}{dependencies}
+			taskRepo = struct{ filters.TaskRepository }{taskRepo}
+			audits = struct{ filters.FilterAuditRepository }{audits}
+		}
+
+		config := filters.DefaultFilterConfig
+		engine := filters.NewEngine(config, audits)
+		engine.AddRule(filters.NewLocationFilter(config, store.Locations(), taskLocations))
+		engine.AddRule(filters.NewDependencyFilter(config, dependencies, taskRepo))
+		engine.AddRule(filters.NewTimeFilter(config, countingCalendar{store.CalendarEvents(), counts}))
+		return engine, counts
+	}
+
+	perTask, perTaskCounts := newEngine(false)
+	wantVisible, wantResults := perTask.FilterTasks(ctx, tasks)
+	assert.Equal(t, len(tasks), perTaskCounts["GetLocationsByTaskID"])
+	assert.Equal(t, len(wantResults), perTaskCounts["SaveFilterResult"])
+
+	preloaded, counts := newEngine(true)
+	visible, results := preloaded.FilterTasks(ctx, tasks)
+
+	t.Run("SameResults", func(t *testing.T) {
+		assert.Equal(t, wantVisible, visible)
+		assert.Equal(t, wantResults, results)
+		assert.NotEmpty(t, visible)
+		assert.Less(t, len(visible), len(tasks), "some tasks are waiting on others")
+	})
+
+	t.Run("NoPerTaskLookups", func(t *testing.T) {
+		assert.Equal(t, lookupCounts{
+			"GetLocationsByTaskIDs":         1,
+			"GetTaskLocationsByTaskIDs":     1,
+			"GetDependenciesByTaskIDs":      1,
+			"GetByIDs":                      1,
+			"GetEventsByUserIDAndTimeRange": 1,
+			"SaveFilterResults":             1,
+		}, counts)
+	})
+
+	t.Run("AuditsSavedTogether", func(t *testing.T) {
+		audits, err := store.FilterAudits().GetAuditLogByUserID("test-user-id", time.Time{}, 0)
+		require.NoError(t, err)
+		assert.Len(t, audits, 2*len(results), "once per engine")
+		for _, audit := range audits {
+			assert.Equal(t, ctx.ID, audit.ContextID)
+		}
+	})
+
+	t.Run("DependenciesLoadALevelAtATime", func(t *testing.T) {
+		// The task depended on is outside the batch, so its own
+		// dependencies take a second query for the cycle check
+		engine, counts := newEngine(true)
+		engine.FilterTasks(ctx, tasks[1:2])
+		assert.Equal(t, 2, counts["GetDependenciesByTaskIDs"])
+		assert.Equal(t, 1, counts["GetByIDs"])
+		assert.Zero(t, counts["GetDependenciesByTaskID"])
+		assert.Zero(t, counts["GetByID"])
+	})
+}
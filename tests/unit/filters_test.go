@@ -48,11 +48,13 @@ func (m *MockLocationRepository) AddLocation(location *models.Location) {
 
 type MockTaskLocationRepository struct {
 	taskLocations map[string][]models.Location
+	triggers      map[string]models.LocationTrigger
 }
 
 func NewMockTaskLocationRepository() *MockTaskLocationRepository {
 	return &MockTaskLocationRepository{
 		taskLocations: make(map[string][]models.Location),
+		triggers:      make(map[string]models.LocationTrigger),
 	}
 }
 
@@ -64,10 +66,42 @@ func (m *MockTaskLocationRepository) GetLocationsByTaskID(taskID string) ([]mode
 	return locations, nil
 }
 
+func (m *MockTaskLocationRepository) GetTaskLocationsByTaskID(taskID string) ([]models.TaskLocation, error) {
+	var taskLocations []models.TaskLocation
+	for _, location := range m.taskLocations[taskID] {
+		taskLocations = append(taskLocations, m.taskLocation(taskID, location.ID))
+	}
+	return taskLocations, nil
+}
+
+func (m *MockTaskLocationRepository) GetByLocationID(locationID string) ([]models.TaskLocation, error) {
+	var taskLocations []models.TaskLocation
+	for taskID, locations := range m.taskLocations {
+		for _, location := range locations {
+			if location.ID == locationID {
+				taskLocations = append(taskLocations, m.taskLocation(taskID, locationID))
+			}
+		}
+	}
+	return taskLocations, nil
+}
+
+func (m *MockTaskLocationRepository) taskLocation(taskID, locationID string) models.TaskLocation {
+	trigger, exists := m.triggers[taskID+"/"+locationID]
+	if !exists {
+		trigger = models.LocationTriggerEnter
+	}
+	return models.TaskLocation{ID: taskID + "/" + locationID, TaskID: taskID, LocationID: locationID, Trigger: trigger}
+}
+
 func (m *MockTaskLocationRepository) SetTaskLocations(taskID string, locations []models.Location) {
 	m.taskLocations[taskID] = locations
 }
 
+func (m *MockTaskLocationRepository) SetLocationTrigger(taskID, locationID string, trigger models.LocationTrigger) {
+	m.triggers[taskID+"/"+locationID] = trigger
+}
+
 type MockCalendarEventRepository struct {
 	events map[string][]models.CalendarEvent
 }
@@ -303,6 +337,52 @@ func TestLocationFilter_Apply(t *testing.T) {
 	})
 }
 
+func TestLocationFilter_GraceBand(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	config.LocationGraceMeters = 25
+	taskLocationRepo := NewMockTaskLocationRepository()
+	filter := filters.NewLocationFilter(config, NewMockLocationRepository(), taskLocationRepo)
+
+	homeLocation := createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	task := createTestTask("Water plants", nil, 3)
+	taskLocationRepo.SetTaskLocations(task.ID, []models.Location{*homeLocation})
+
+	// contextNorthOfHome places the user the given distance due north of Home
+	metersPerDegree := models.EarthRadiusMeters * math.Pi / 180
+	contextNorthOfHome := func(meters float64) models.Context {
+		lat, lng := homeLocation.Latitude+meters/metersPerDegree, homeLocation.Longitude
+		return createTestContext(&lat, &lng, 60, 3)
+	}
+
+	t.Run("InsideRadius", func(t *testing.T) {
+		visible, reason := filter.Apply(contextNorthOfHome(50), task)
+
+		assert.True(t, visible)
+		assert.Equal(t, "within 100m of Home (50m away)", reason)
+	})
+
+	t.Run("WithinGrace", func(t *testing.T) {
+		visible, reason := filter.Apply(contextNorthOfHome(108), task)
+
+		assert.True(t, visible)
+		assert.Equal(t, "just outside Home, 8m over", reason)
+	})
+
+	t.Run("BeyondGrace", func(t *testing.T) {
+		visible, reason := filter.Apply(contextNorthOfHome(140), task)
+
+		assert.False(t, visible)
+		assert.Equal(t, "too far from Home (140m away, need to be within 100m)", reason)
+	})
+
+	t.Run("NoGraceByDefault", func(t *testing.T) {
+		strict := filters.NewLocationFilter(filters.DefaultFilterConfig, NewMockLocationRepository(), taskLocationRepo)
+		visible, _ := strict.Apply(contextNorthOfHome(108), task)
+
+		assert.False(t, visible)
+	})
+}
+
 // TimeFilter Tests
 func TestTimeFilter_Apply(t *testing.T) {
 	config := filters.DefaultFilterConfig
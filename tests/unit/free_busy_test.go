@@ -0,0 +1,112 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeFilter_FreeBusy(t *testing.T) {
+	calendarRepo := NewMockCalendarEventRepository()
+	filter := filters.NewTimeFilter(filters.DefaultFilterConfig, calendarRepo)
+	minutes := 30
+	task := createTestTask("Write report", &minutes, 3)
+
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	event := func(title string, from, to int) models.CalendarEvent {
+		return models.CalendarEvent{
+			Title:   title,
+			StartAt: now.Add(time.Duration(from) * time.Minute),
+			EndAt:   now.Add(time.Duration(to) * time.Minute),
+			IsBusy:  true,
+		}
+	}
+	// evaluate judges the task with 90 minutes available and the events on
+	// the calendar
+	evaluate := func(t *testing.T, task models.Task, events ...models.CalendarEvent) (bool, filters.ReasonCode, string) {
+		ctx := createTestContext(nil, nil, 90, 5)
+		ctx.Timestamp = now
+		for _, event := range events {
+			calendarRepo.AddEvent(ctx.UserID, event)
+		}
+		t.Cleanup(func() { delete(calendarRepo.events, ctx.UserID) })
+		return filter.Evaluate(ctx, task)
+	}
+
+	t.Run("FitsBetweenEvents", func(t *testing.T) {
+		visible, code, _ := evaluate(t, task, event("Standup", 10, 25), event("Lunch", 60, 90))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonTimeFits, code)
+	})
+
+	t.Run("NamesTheEventAfterTheLargestGap", func(t *testing.T) {
+		visible, code, reason := evaluate(t, task, event("Standup", 20, 40), event("Lunch", 60, 90))
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonTimeCalendarConflict, code)
+		assert.Equal(t, "only 20m free before 'Standup'", reason)
+	})
+
+	t.Run("GapAtTheEndOfTheWindow", func(t *testing.T) {
+		_, code, reason := evaluate(t, task, event("Planning", 0, 70))
+		assert.Equal(t, filters.ReasonTimeCalendarConflict, code)
+		assert.Equal(t, "only 20m free after 'Planning'", reason)
+	})
+
+	t.Run("MergesOverlappingAndBackToBackEvents", func(t *testing.T) {
+		// 0-15 and 55-70 are free on their own, but 15-55 is one busy block
+		// and no gap is 30 minutes
+		_, code, reason := evaluate(t, task,
+			event("Review", 30, 45), event("Design", 15, 35), event("1:1", 45, 55), event("Sync", 70, 90))
+		assert.Equal(t, filters.ReasonTimeCalendarConflict, code)
+		assert.Equal(t, "only 15m free before 'Design'", reason)
+	})
+
+	t.Run("EventsOutsideTheWindowDoNotCount", func(t *testing.T) {
+		visible, _, _ := evaluate(t, task, event("Earlier", -60, -10), event("Later", 100, 160))
+		assert.True(t, visible)
+	})
+
+	t.Run("AllDayEventsBlock", func(t *testing.T) {
+		holiday := event("Company offsite", -600, 800)
+		holiday.IsAllDay = true
+
+		visible, code, reason := evaluate(t, task, holiday)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonTimeCalendarConflict, code)
+		assert.Equal(t, "no free time, busy with 'Company offsite'", reason)
+
+		ignoring := task
+		ignoring.Metadata = json.RawMessage(`{"ignore_allday": true}`)
+		require.True(t, filters.TaskIgnoresAllDay(ignoring))
+		visible, code, _ = evaluate(t, ignoring, holiday)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonTimeFits, code)
+
+		// Other events still count for a task that ignores all-day ones
+		_, code, _ = evaluate(t, ignoring, holiday, event("Standup", 20, 80))
+		assert.Equal(t, filters.ReasonTimeCalendarConflict, code)
+	})
+}
+
+func TestTimeFilter_NextAvailableSlotMergesEvents(t *testing.T) {
+	calendarRepo := NewMockCalendarEventRepository()
+	filter := filters.NewTimeFilter(filters.DefaultFilterConfig, calendarRepo)
+	minutes := 30
+	task := createTestTask("Write report", &minutes, 3)
+
+	ctx := createTestContext(nil, nil, 60, 5)
+	ctx.Timestamp = time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	calendarRepo.AddEvent(ctx.UserID, models.CalendarEvent{Title: "Long meeting",
+		StartAt: ctx.Timestamp.Add(10 * time.Minute), EndAt: ctx.Timestamp.Add(2 * time.Hour)})
+	calendarRepo.AddEvent(ctx.UserID, models.CalendarEvent{Title: "Inside it",
+		StartAt: ctx.Timestamp.Add(30 * time.Minute), EndAt: ctx.Timestamp.Add(45 * time.Minute)})
+
+	slot, err := filter.GetNextAvailableTimeSlot(ctx, task)
+	require.NoError(t, err)
+	assert.Equal(t, ctx.Timestamp.Add(2*time.Hour), *slot)
+}
@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextService_GeofenceEvents(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	home := createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	grocery := createTestLocation("grocery-id", "Grocery Store", 37.7839, -122.4194, "test-user-id")
+	// The neighborhood contains both home and the grocery store, a
+	// kilometer apart
+	neighborhood := createTestLocation("neighborhood-id", "Neighborhood", 37.7794, -122.4194, "test-user-id")
+	neighborhood.Radius = 2000
+
+	setup := func(t *testing.T) (*memstore.Store, *hereandnow.ContextService, *clock.Fake) {
+		store := memstore.New(memstore.WithLocations(*home, *grocery, *neighborhood))
+		_, service := newMemstoreServices(store)
+		service.EnableGeofenceEvents(store.GeofenceEvents())
+		fake := clock.NewFake(start)
+		service.SetClock(fake)
+		return store, service, fake
+	}
+
+	moveTo := func(t *testing.T, service *hereandnow.ContextService, location *models.Location, metersNorth float64) *models.Context {
+		lat, lng := metersNorthOf(location, metersNorth)
+		context, err := service.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			Latitude:         &lat,
+			Longitude:        &lng,
+			AvailableMinutes: 60,
+			EnergyLevel:      3,
+		})
+		require.NoError(t, err)
+		return context
+	}
+
+	// crossings summarizes events as "type location" for comparison
+	crossings := func(events []models.GeofenceEvent) []string {
+		var summary []string
+		for _, event := range events {
+			summary = append(summary, string(event.Type)+" "+event.LocationID)
+		}
+		return summary
+	}
+
+	t.Run("FirstContextEntersContainingLocations", func(t *testing.T) {
+		_, service, _ := setup(t)
+		context := moveTo(t, service, home, 0)
+
+		events, err := service.GetGeofenceEvents("test-user-id", time.Time{})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"enter home-id", "enter neighborhood-id"}, crossings(events))
+		for _, event := range events {
+			assert.Equal(t, context.ID, event.ContextID)
+			assert.Equal(t, start, event.At)
+		}
+	})
+
+	t.Run("MovingBetweenLocations", func(t *testing.T) {
+		_, service, fake := setup(t)
+		moveTo(t, service, home, 0)
+		fake.Advance(20 * time.Minute)
+		moveTo(t, service, grocery, 10)
+
+		events, err := service.GetGeofenceEvents("test-user-id", start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"enter grocery-id", "exit home-id"}, crossings(events),
+			"still inside the neighborhood")
+	})
+
+	t.Run("StayingPutRecordsNothing", func(t *testing.T) {
+		_, service, fake := setup(t)
+		moveTo(t, service, home, 0)
+		fake.Advance(5 * time.Minute)
+		moveTo(t, service, home, 20)
+
+		events, err := service.GetGeofenceEvents("test-user-id", time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, events, 2, "only the first context's enters")
+	})
+
+	t.Run("LeavingEverything", func(t *testing.T) {
+		_, service, fake := setup(t)
+		moveTo(t, service, home, 0)
+		fake.Advance(time.Hour)
+		moveTo(t, service, home, 10000)
+
+		events, err := service.GetGeofenceEvents("test-user-id", start.Add(time.Minute))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"exit home-id", "exit neighborhood-id"}, crossings(events))
+	})
+
+	t.Run("EnteringNotifiesLocationTasks", func(t *testing.T) {
+		store, service, fake := setup(t)
+		taskService, _ := newMemstoreServices(store)
+		task, err := taskService.CreateTask("test-user-id", memstoreTaskRequest("Buy milk"))
+		require.NoError(t, err)
+		link, err := models.NewTaskLocation(task.ID, grocery.ID, true)
+		require.NoError(t, err)
+		require.NoError(t, store.TaskLocations().Create(*link))
+
+		moveTo(t, service, home, 0)
+		notifications, err := store.Notifications().GetByUserID("test-user-id", false)
+		require.NoError(t, err)
+		assert.Empty(t, notifications)
+
+		fake.Advance(20 * time.Minute)
+		moveTo(t, service, grocery, 0)
+		notifications, err = store.Notifications().GetByUserID("test-user-id", false)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, task.ID, *notifications[0].TaskID)
+		assert.Contains(t, notifications[0].Message, "Grocery Store")
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		_, service := newMemstoreServices(memstore.New())
+		_, err := service.GetGeofenceEvents("test-user-id", time.Time{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/calendar"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGoogle serves the token endpoint and an events list split over pages
+type fakeGoogle struct {
+	pages         []map[string]interface{}
+	tokenRequests int
+	rejectToken   string
+}
+
+func (g *fakeGoogle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		g.tokenRequests++
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh-me" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-" + string(rune('0'+g.tokenRequests)),
+			"expires_in":   3600,
+		})
+		return
+	}
+
+	auth := r.Header.Get("Authorization")
+	if auth == "" || auth == "Bearer "+g.rejectToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	page := 0
+	if token := r.URL.Query().Get("pageToken"); token != "" {
+		page = int(token[0] - '0')
+	}
+	json.NewEncoder(w).Encode(g.pages[page])
+}
+
+func newGoogleProvider(t *testing.T, google *fakeGoogle) *calendar.GoogleProvider {
+	server := httptest.NewServer(google)
+	t.Cleanup(server.Close)
+	return calendar.NewGoogleProvider(calendar.GoogleConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RefreshToken: "refresh-me",
+		TokenURL:     server.URL + "/token",
+		APIURL:       server.URL,
+		Location:     time.UTC,
+	}, server.Client())
+}
+
+func TestGoogleProvider_FetchEvents(t *testing.T) {
+	google := &fakeGoogle{pages: []map[string]interface{}{
+		{
+			"items": []map[string]interface{}{
+				{"id": "standup", "summary": "Standup", "location": "Room 1",
+					"start": map[string]string{"dateTime": "2026-10-15T09:00:00Z"},
+					"end":   map[string]string{"dateTime": "2026-10-15T09:15:00Z"}},
+				{"id": "gone", "status": "cancelled"},
+			},
+			"nextPageToken": "1",
+		},
+		{
+			"items": []map[string]interface{}{
+				{"id": "holiday", "summary": "Holiday", "transparency": "transparent",
+					"start": map[string]string{"date": "2026-10-16"},
+					"end":   map[string]string{"date": "2026-10-26"}},
+				{"id": "conference", "summary": "Conference",
+					"start": map[string]string{"dateTime": "2026-10-16T09:00:00Z"},
+					"end":   map[string]string{"dateTime": "2026-10-24T17:00:00Z"}},
+			},
+		},
+	}}
+	provider := newGoogleProvider(t, google)
+
+	events, err := provider.FetchEvents(context.Background(), "alice", time.Now(), time.Now().Add(30*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 3, "the long timed event is skipped and the long all-day event split")
+	assert.Equal(t, 1, google.tokenRequests, "the access token is reused across pages")
+
+	standup := events[0]
+	assert.Equal(t, models.ProviderGoogle, standup.ProviderID)
+	assert.Equal(t, "standup", standup.ExternalID)
+	assert.Equal(t, 15, standup.DurationMinutes())
+	require.NotNil(t, standup.Location)
+	assert.Equal(t, "Room 1", *standup.Location)
+	assert.True(t, standup.IsBusy)
+
+	assert.Equal(t, "holiday", events[1].ExternalID)
+	assert.Equal(t, "holiday#2", events[2].ExternalID)
+	assert.True(t, events[1].IsAllDay)
+	assert.False(t, events[1].IsBusy)
+	assert.Equal(t, 7*24*time.Hour, events[1].Duration())
+	assert.Equal(t, 3*24*time.Hour, events[2].Duration())
+	assert.Equal(t, events[1].EndAt, events[2].StartAt)
+}
+
+func TestGoogleProvider_RefreshesRejectedToken(t *testing.T) {
+	google := &fakeGoogle{rejectToken: "access-1", pages: []map[string]interface{}{{"items": []interface{}{}}}}
+	provider := newGoogleProvider(t, google)
+
+	_, err := provider.FetchEvents(context.Background(), "alice", time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, google.tokenRequests, "a rejected access token is refreshed once")
+}
+
+func TestGoogleProvider_InvalidRefreshToken(t *testing.T) {
+	google := &fakeGoogle{}
+	server := httptest.NewServer(google)
+	defer server.Close()
+	provider := calendar.NewGoogleProvider(calendar.GoogleConfig{
+		RefreshToken: "revoked",
+		TokenURL:     server.URL + "/token",
+		APIURL:       server.URL,
+	}, server.Client())
+
+	_, err := provider.FetchEvents(context.Background(), "alice", time.Now(), time.Now().Add(time.Hour))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_grant")
+}
+
+func TestCalendarSync_UpsertsByExternalID(t *testing.T) {
+	google := &fakeGoogle{pages: []map[string]interface{}{{
+		"items": []map[string]interface{}{
+			{"id": "standup", "summary": "Standup",
+				"start": map[string]string{"dateTime": "2026-10-15T09:00:00Z"},
+				"end":   map[string]string{"dateTime": "2026-10-15T09:15:00Z"}},
+		},
+	}}}
+	provider := newGoogleProvider(t, google)
+	store := memstore.New()
+	events := store.CalendarEvents()
+
+	result, err := calendar.Sync(context.Background(), events, provider, "alice", time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	first, err := events.GetByProviderAndExternalID("alice", models.ProviderGoogle, "standup")
+	require.NoError(t, err)
+
+	google.pages[0]["items"].([]map[string]interface{})[0]["summary"] = "Daily standup"
+	result, err = calendar.Sync(context.Background(), events, provider, "alice", time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 1, result.Updated)
+
+	all, err := events.GetByUserID("alice")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, first.ID, all[0].ID)
+	assert.Equal(t, "Daily standup", all[0].Title)
+	assert.False(t, all[0].LastSyncedAt.Before(first.LastSyncedAt))
+}
@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		query    string
+		expected string
+	}{
+		{"SingleMatch", "Buy groceries", "groceries", "Buy [groceries]"},
+		{"CaseInsensitive", "Buy GROCERIES at Groceries R Us", "groceries", "Buy [GROCERIES] at [Groceries] R Us"},
+		{"MultipleTerms", "Pick up milk and bread", "bread milk", "Pick up [milk] and [bread]"},
+		{"OverlappingMatches", "aaaa", "aa", "[aaaa]"},
+		{"OverlappingTerms", "groceries", "groc ceries", "[groceries]"},
+		{"AdjacentTerms", "foobar", "foo bar", "[foobar]"},
+		{"NoMatch", "Call mom", "groceries", "Call mom"},
+		{"EmptyQuery", "Call mom", "", "Call mom"},
+		{"Multibyte", "Café crème brûlée", "CRÈME", "Café [crème] brûlée"},
+		{"MultibyteRepeated", "日本語と日本", "日本", "[日本]語と[日本]"},
+		{"MultibyteAroundMatch", "🛒 milk 🥛", "milk", "🛒 [milk] 🥛"},
+		{"SearchSyntaxIgnored", "Buy groceries", `"groceries" OR groc*`, "Buy [groceries]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hereandnow.HighlightMatches(tt.text, tt.query, "[", "]"))
+		})
+	}
+
+	t.Run("ColorMarkers", func(t *testing.T) {
+		highlighted := hereandnow.HighlightMatches("Buy groceries", "Groceries", "\033[1m", "\033[0m")
+		assert.Equal(t, "Buy \033[1mgroceries\033[0m", highlighted)
+	})
+}
+
+func TestMatchRanges(t *testing.T) {
+	text := "Déjà vu, déjà"
+	ranges := hereandnow.MatchRanges(text, "DÉJÀ")
+
+	// Byte offsets that land on rune boundaries
+	assert.Equal(t, [][2]int{{0, 6}, {11, 17}}, ranges)
+	assert.Equal(t, "Déjà", text[ranges[0][0]:ranges[0][1]])
+	assert.Equal(t, "déjà", text[ranges[1][0]:ranges[1][1]])
+}
@@ -0,0 +1,219 @@
+package unit
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportICS(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 10, 16, 17, 30, 0, 0, time.UTC)
+	minutes := 45
+	rule := "FREQ=WEEKLY;BYDAY=FR"
+
+	dated := createTestTask("Submit timesheet", &minutes, 5)
+	dated.DueAt = &due
+	dated.RecurrenceRule = &rule
+
+	undated := createTestTask("Call bank", nil, 3)
+
+	allDay := createTestTask("Pay rent", nil, 3)
+	midnight := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	allDay.DueAt = &midnight
+
+	done := createTestTask("Old errand", nil, 3)
+	done.Status = models.TaskStatusCompleted
+
+	store := *createTestLocation("store-id", "Grocery Store", 37.7749, -122.4194, "test-user-id")
+
+	ics := sync.ExportICS(sync.ICSFeed{
+		Name:      "Here and Now",
+		Tasks:     []models.Task{dated, undated, allDay, done},
+		Locations: map[string][]models.Location{dated.ID: {store}},
+	}, now)
+
+	t.Run("WrapsCalendarWithCRLF", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+		assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+		assert.Contains(t, ics, "X-WR-CALNAME:Here and Now\r\n")
+	})
+
+	t.Run("DatedTaskIsEventEndingAtDue", func(t *testing.T) {
+		event := icsComponent(t, ics, dated.ID)
+		assert.Contains(t, event, "BEGIN:VEVENT")
+		assert.Contains(t, event, "SUMMARY:Submit timesheet")
+		assert.Contains(t, event, "DTSTART:20261016T164500Z")
+		assert.Contains(t, event, "DTEND:20261016T173000Z")
+		assert.Contains(t, event, "RRULE:FREQ=WEEKLY;BYDAY=FR")
+		assert.Contains(t, event, "LOCATION:Grocery Store")
+		assert.Contains(t, event, "PRIORITY:1")
+		assert.Contains(t, event, "DTSTAMP:20261015T120000Z")
+	})
+
+	t.Run("MidnightDueIsAllDay", func(t *testing.T) {
+		event := icsComponent(t, ics, allDay.ID)
+		assert.Contains(t, event, "DTSTART;VALUE=DATE:20261101")
+		assert.Contains(t, event, "DTEND;VALUE=DATE:20261102")
+	})
+
+	t.Run("UndatedTaskIsTodoWithoutDue", func(t *testing.T) {
+		todo := icsComponent(t, ics, undated.ID)
+		assert.Contains(t, todo, "BEGIN:VTODO")
+		assert.NotContains(t, todo, "DUE")
+		assert.NotContains(t, todo, "DTSTART")
+	})
+
+	t.Run("CompletedTasksLeftOut", func(t *testing.T) {
+		assert.NotContains(t, ics, done.ID)
+	})
+
+	t.Run("EscapesAndFoldsText", func(t *testing.T) {
+		task := createTestTask("Milk, eggs; bread", nil, 3)
+		task.Description = strings.Repeat("long description ", 10) + "\nsecond line"
+		out := sync.ExportICS(sync.ICSFeed{Tasks: []models.Task{task}}, now)
+
+		assert.Contains(t, out, `SUMMARY:Milk\, eggs\; bread`)
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+			assert.LessOrEqual(t, len(line), 75, line)
+		}
+		unfolded := strings.ReplaceAll(out, "\r\n ", "")
+		assert.Contains(t, unfolded, `long description \nsecond line`)
+	})
+}
+
+// icsComponent returns the lines of the component with the task's UID
+func icsComponent(t *testing.T, ics, taskID string) string {
+	for _, component := range strings.Split(ics, "BEGIN:")[1:] {
+		if strings.Contains(component, "UID:"+taskID+"@hereandnow") {
+			return "BEGIN:" + component
+		}
+	}
+	require.Fail(t, "no component for task", taskID)
+	return ""
+}
+
+func TestExportICSEndpoint(t *testing.T) {
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	store := memstore.New(memstore.WithLocations(home))
+	taskService, contextService := newMemstoreServices(store)
+
+	due := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+	req := memstoreTaskRequest("Water plants")
+	req.LocationIDs = []string{home.ID}
+	req.DueAt = &due
+	task, err := taskService.CreateTask("test-user-id", req)
+	require.NoError(t, err)
+
+	lat, lng := home.Latitude, home.Longitude
+	_, err = contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+		Latitude: &lat, Longitude: &lng, AvailableMinutes: 60, EnergyLevel: 3,
+	})
+	require.NoError(t, err)
+
+	users := &authUserRepository{users: map[string]models.User{
+		"test-user-id": {ID: "test-user-id", PasswordHash: "secret"},
+	}}
+	authService := auth.NewAuthService(users, nil, nil, auth.DefaultAuthConfig)
+	authService.EnableFeedTokens(storage.NewFeedTokenRepository(setupFeedTokenDB(t)))
+	feedToken, feed, err := authService.CreateFeedToken("test-user-id", "iPhone calendar")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	taskHandler := api.NewTaskHandler(&memstoreAPITaskService{service: taskService}, contextService)
+	taskHandler.SetLocationService(taskService)
+	api.SetupRoutes(router, api.Handlers{
+		Auth:  api.NewAuthHandler(authService),
+		Tasks: taskHandler,
+		AuthMiddleware: func(c *gin.Context) {
+			if c.GetHeader("Authorization") != "Bearer secret" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{})
+
+	t.Run("FeedTokenAuthenticates", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/export.ics?token="+feedToken, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, sync.ICSContentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "SUMMARY:Water plants")
+		assert.Contains(t, w.Body.String(), "LOCATION:Home")
+	})
+
+	t.Run("RejectsMissingOrWrongToken", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/export.ics", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveRequest(router, http.MethodGet, "/api/v1/tasks/export.ics?token=hnf_wrong", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("SessionTokensRejected", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/export.ics?token=secret", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveRequestWithHeaders(router, http.MethodGet, "/api/v1/tasks/export.ics", "", map[string]string{"Authorization": "Bearer secret"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("CompletedTaskDropsOutOfFeed", func(t *testing.T) {
+		_, err := taskService.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/export.ics?token="+feedToken, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "Water plants")
+	})
+
+	t.Run("FeedTokenReadsOnlyTheFeed", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks?token="+feedToken, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = serveRequestWithHeaders(router, http.MethodGet, "/api/v1/tasks", "", map[string]string{"Authorization": "Bearer " + feedToken})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("RevokedFeedTokenRejected", func(t *testing.T) {
+		feeds, err := authService.ListFeedTokens("test-user-id")
+		require.NoError(t, err)
+		require.Len(t, feeds, 1)
+		assert.Equal(t, "iPhone calendar", feeds[0].Name)
+		assert.NotNil(t, feeds[0].LastUsedAt)
+
+		require.NoError(t, authService.RevokeFeedToken("test-user-id", feed.ID))
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/export.ics?token="+feedToken, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func setupFeedTokenDB(t *testing.T) *storage.DB {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE feed_tokens (
+			id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE, created_at DATETIME NOT NULL, last_used_at DATETIME
+		);
+	`)
+	require.NoError(t, err)
+	return db
+}
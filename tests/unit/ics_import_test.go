@@ -0,0 +1,169 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/calendar"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outlookExport is an .ics file as Outlook writes it, with a Windows time
+// zone name defined by its VTIMEZONE
+const outlookExport = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Microsoft Corporation//Outlook 16.0 MIMEDIR//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:W. Europe Standard Time\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:16011028T030000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:16010325T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3\r\n" +
+	"TZOFFSETFROM:+0100\r\n" +
+	"TZOFFSETTO:+0200\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:summer@example.com\r\n" +
+	"SUMMARY:Summer review\r\n" +
+	"DTSTART;TZID=\"W. Europe Standard Time\":20260715T090000\r\n" +
+	"DTEND;TZID=\"W. Europe Standard Time\":20260715T100000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:winter@example.com\r\n" +
+	"SUMMARY:Winter review\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20261215T090000\r\n" +
+	"DURATION:PT30M\r\n" +
+	"LOCATION:Room 4\\, Berlin\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:switch@example.com\r\n" +
+	"SUMMARY:Morning after the switch\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20260329T080000\r\n" +
+	"DTEND;TZID=W. Europe Standard Time:20260329T090000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:summer@example.com\r\n" +
+	"SUMMARY:Summer review\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20260715T090000\r\n" +
+	"DTEND;TZID=W. Europe Standard Time:20260715T100000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:todo-1@example.com\r\n" +
+	"SUMMARY:Book flights\r\n" +
+	"DESCRIPTION:Check the\\nearly ones\r\n" +
+	"DUE;TZID=W. Europe Standard Time:20261201T170000\r\n" +
+	"PRIORITY:1\r\n" +
+	"CATEGORIES:travel,work\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:todo-2@example.com\r\n" +
+	"SUMMARY:Renew passport\r\n" +
+	"STATUS:COMPLETED\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportICS(t *testing.T) {
+	t.Run("VTIMEZONE", func(t *testing.T) {
+		events, err := calendar.ImportICS(strings.NewReader(outlookExport), "alice")
+		require.NoError(t, err)
+		require.Len(t, events, 3, "duplicates are returned once and to-dos left out")
+
+		byUID := map[string]models.CalendarEvent{}
+		for _, event := range events {
+			assert.Equal(t, "alice", event.UserID)
+			assert.Equal(t, models.ProviderICS, event.ProviderID)
+			assert.Equal(t, time.UTC, event.StartAt.Location())
+			byUID[event.ExternalID] = event
+		}
+		assert.Equal(t, time.Date(2026, 7, 15, 7, 0, 0, 0, time.UTC), byUID["summer@example.com"].StartAt, "summer time is UTC+2")
+		assert.Equal(t, time.Date(2026, 7, 15, 8, 0, 0, 0, time.UTC), byUID["summer@example.com"].EndAt)
+		assert.Equal(t, time.Date(2026, 12, 15, 8, 0, 0, 0, time.UTC), byUID["winter@example.com"].StartAt, "standard time is UTC+1")
+		assert.Equal(t, time.Date(2026, 12, 15, 8, 30, 0, 0, time.UTC), byUID["winter@example.com"].EndAt)
+		assert.Equal(t, time.Date(2026, 3, 29, 6, 0, 0, 0, time.UTC), byUID["switch@example.com"].StartAt, "summer time starts on the last Sunday of March")
+	})
+
+	t.Run("Recurring events and events without a UID", func(t *testing.T) {
+		start := time.Now().UTC().Truncate(24 * time.Hour).Add(9 * time.Hour)
+		data := "BEGIN:VCALENDAR\n" +
+			"BEGIN:VEVENT\nUID:daily\nSUMMARY:Daily\nDTSTART:" + start.Format("20060102T150405Z") + "\nDURATION:PT15M\nRRULE:FREQ=DAILY;COUNT=3\nEND:VEVENT\n" +
+			"BEGIN:VEVENT\nSUMMARY:Dentist\nDTSTART:20260110T140000Z\nDTEND:20260110T150000Z\nEND:VEVENT\n" +
+			"BEGIN:VEVENT\nSUMMARY:Cancelled\nSTATUS:CANCELLED\nDTSTART:20260111T140000Z\nDTEND:20260111T150000Z\nEND:VEVENT\n" +
+			"END:VCALENDAR\n"
+
+		events, err := calendar.ImportICS(strings.NewReader(data), "alice")
+		require.NoError(t, err)
+		require.Len(t, events, 4)
+		assert.Equal(t, "Dentist", events[0].Title)
+		assert.NotEmpty(t, events[0].ExternalID)
+		for i, event := range events[1:] {
+			assert.Equal(t, "daily/"+start.AddDate(0, 0, i).Format("20060102T150405Z"), event.ExternalID)
+		}
+
+		again, err := calendar.ImportICS(strings.NewReader(data), "alice")
+		require.NoError(t, err)
+		assert.Equal(t, events[0].ExternalID, again[0].ExternalID, "events without a UID are keyed the same each time")
+	})
+
+	t.Run("Invalid files", func(t *testing.T) {
+		_, err := calendar.ImportICS(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:x\nEND:VCALENDAR\n"), "alice")
+		assert.ErrorContains(t, err, "does not close VEVENT")
+
+		_, err = calendar.ImportICS(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VTIMEZONE\nTZID:Nowhere\nBEGIN:STANDARD\nDTSTART:16010101T000000\nEND:STANDARD\nEND:VTIMEZONE\nEND:VCALENDAR\n"), "alice")
+		assert.ErrorContains(t, err, "no TZOFFSETTO")
+	})
+}
+
+func TestImportICSTasks(t *testing.T) {
+	tasks, err := calendar.ImportICSTasks(strings.NewReader(outlookExport))
+	require.NoError(t, err)
+	require.Len(t, tasks, 1, "completed to-dos are left out")
+
+	task := tasks[0]
+	assert.Equal(t, 1, task.Line)
+	assert.Equal(t, "Book flights", task.Title)
+	assert.Equal(t, "Check the\nearly ones", task.Description)
+	assert.Equal(t, 5, task.Priority)
+	assert.Equal(t, []string{"travel", "work"}, task.Tags)
+	require.NotNil(t, task.DueAt)
+	assert.Equal(t, time.Date(2026, 12, 1, 16, 0, 0, 0, time.UTC), *task.DueAt)
+}
+
+func TestSaveImported(t *testing.T) {
+	store := memstore.New()
+	repo := store.CalendarEvents()
+
+	// The same meeting synced from a CalDAV calendar already
+	synced, err := models.NewCalendarEvent("alice", models.ProviderCalDAV, "winter@example.com", "Winter review",
+		time.Date(2026, 12, 15, 8, 0, 0, 0, time.UTC), time.Date(2026, 12, 15, 8, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(*synced))
+
+	events, err := calendar.ImportICS(strings.NewReader(outlookExport), "alice")
+	require.NoError(t, err)
+
+	report := sync.NewImportReport("ics", "outlook.ics")
+	require.NoError(t, calendar.SaveImported(repo, "alice", events, report))
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 1, report.Skipped)
+
+	stored, err := repo.GetByUserID("alice")
+	require.NoError(t, err)
+	assert.Len(t, stored, 3)
+
+	report = sync.NewImportReport("ics", "outlook.ics")
+	require.NoError(t, calendar.SaveImported(repo, "alice", events, report))
+	assert.Equal(t, 0, report.Imported, "importing the file again adds nothing")
+	assert.Equal(t, 3, report.Skipped)
+	assert.Equal(t, "an event with this UID already exists", report.Records[0].Reason)
+}
@@ -0,0 +1,317 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMarkdown_Report(t *testing.T) {
+	data := `# Tasks
+
+- [ ] Buy milk
+  - Due: 2026-10-20 09:30
+  - Priority: 4
+  - Tags: errands, home
+  > From the corner shop
+- [ ] Call plumber
+  - Due: next tuesday
+- [ ] Book flights
+  - Priority: 9
+- [x] Pay rent
+- Remember to stretch
+- [ ]
+- [ ] Water plants
+`
+
+	tasks, report := sync.ParseMarkdown(data, "tasks.md", time.UTC)
+
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "Buy milk", tasks[0].Title)
+	assert.Equal(t, 3, tasks[0].Line)
+	assert.Equal(t, 4, tasks[0].Priority)
+	assert.Equal(t, []string{"errands", "home"}, tasks[0].Tags)
+	assert.Equal(t, "From the corner shop", tasks[0].Description)
+	require.NotNil(t, tasks[0].DueAt)
+	assert.Equal(t, time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC), *tasks[0].DueAt)
+	assert.Equal(t, "Water plants", tasks[1].Title)
+	assert.Equal(t, 3, tasks[1].Priority)
+
+	assert.Equal(t, "markdown", report.Format)
+	assert.Equal(t, "tasks.md", report.Source)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 3, report.Failed)
+	assert.True(t, report.HasFailures())
+
+	expected := []sync.ImportRecord{
+		{Line: 9, Status: sync.ImportStatusFailed, Title: "Call plumber", Reason: `invalid due date "next tuesday" (want YYYY-MM-DD or YYYY-MM-DD HH:MM)`},
+		{Line: 11, Status: sync.ImportStatusFailed, Title: "Book flights", Reason: `invalid priority "9" (want 1-5)`},
+		{Line: 12, Status: sync.ImportStatusSkipped, Title: "Pay rent", Reason: "already completed"},
+		{Line: 13, Status: sync.ImportStatusSkipped, Title: "Remember to stretch", Reason: `not a checklist item (want "- [ ] Title")`},
+		{Line: 14, Status: sync.ImportStatusFailed, Reason: "checklist item has no title"},
+	}
+	assert.Equal(t, expected, report.Records)
+}
+
+func TestTaskService_ImportTasks(t *testing.T) {
+	t.Run("ReportsAllImportedForValidFile", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+
+		tasks, report := sync.ParseMarkdown("- [ ] Draft report\n  - Tags: work\n\n- [ ] Review budget\n  > Q4 numbers\n", "tasks.md", time.UTC)
+		require.NoError(t, service.ImportTasks("test-user-id", tasks, report, hereandnow.ImportOptions{}))
+
+		assert.Equal(t, 2, report.Imported)
+		assert.False(t, report.HasFailures())
+		require.Len(t, report.Records, 2)
+		for i, line := range []int{1, 4} {
+			assert.Equal(t, line, report.Records[i].Line)
+			assert.Equal(t, sync.ImportStatusImported, report.Records[i].Status)
+			assert.NotEmpty(t, report.Records[i].TaskID)
+		}
+
+		draft, err := service.GetTask(report.Records[0].TaskID)
+		require.NoError(t, err)
+		var metadata map[string][]string
+		require.NoError(t, json.Unmarshal(draft.Metadata, &metadata))
+		assert.Equal(t, []string{"work"}, metadata["tags"])
+
+		review, err := service.GetTask(report.Records[1].TaskID)
+		require.NoError(t, err)
+		assert.Equal(t, "Q4 numbers", review.Description)
+	})
+
+	t.Run("SkipsDuplicatesOfOpenTasks", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		_, err := service.CreateTask("test-user-id", memstoreTaskRequest("Draft report"))
+		require.NoError(t, err)
+
+		tasks, report := sync.ParseMarkdown("- [ ] draft report\n- [ ] Review budget\n- [ ] Review Budget\n", "tasks.md", time.UTC)
+		require.NoError(t, service.ImportTasks("test-user-id", tasks, report, hereandnow.ImportOptions{}))
+
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, 2, report.Skipped)
+		assert.Equal(t, []int{1, 2, 3}, []int{report.Records[0].Line, report.Records[1].Line, report.Records[2].Line})
+		assert.Equal(t, sync.ImportStatusSkipped, report.Records[0].Status)
+		assert.Equal(t, "an open task with this title already exists", report.Records[0].Reason)
+		assert.Equal(t, sync.ImportStatusImported, report.Records[1].Status)
+		assert.Equal(t, sync.ImportStatusSkipped, report.Records[2].Status)
+	})
+	t.Run("KeepsStatusAndTags", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+
+		tasks := []sync.ImportedTask{{Line: 2, Title: "File taxes", Priority: 4, Status: models.TaskStatusCompleted, Tags: []string{"home"}}}
+		report := sync.NewImportReport("csv", "tasks.csv")
+		require.NoError(t, service.ImportTasks("test-user-id", tasks, report, hereandnow.ImportOptions{}))
+		require.Equal(t, 1, report.Imported)
+
+		task, err := service.GetTask(report.Records[0].TaskID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, task.Status)
+		assert.NotNil(t, task.CompletedAt)
+		assert.Equal(t, []string{"home"}, task.Tags)
+	})
+}
+
+func TestParseCSV(t *testing.T) {
+	t.Run("GenericColumns", func(t *testing.T) {
+		data := `title,description,priority,due,estimated_minutes,location
+Buy milk,From the corner shop,4,2026-10-20 09:30,15,Grocery
+Call plumber,,,next tuesday,,
+,Orphan description,,,,
+Book flights,,9,,,
+Water plants,,,,,
+`
+		tasks, report := sync.ParseCSV(data, "tasks.csv", time.UTC)
+
+		require.Len(t, tasks, 2)
+		assert.Equal(t, "Buy milk", tasks[0].Title)
+		assert.Equal(t, 2, tasks[0].Line)
+		assert.Equal(t, "From the corner shop", tasks[0].Description)
+		assert.Equal(t, 4, tasks[0].Priority)
+		require.NotNil(t, tasks[0].DueAt)
+		assert.Equal(t, time.Date(2026, 10, 20, 9, 30, 0, 0, time.UTC), *tasks[0].DueAt)
+		require.NotNil(t, tasks[0].EstimatedMinutes)
+		assert.Equal(t, 15, *tasks[0].EstimatedMinutes)
+		assert.Equal(t, "Grocery", tasks[0].Location)
+		assert.Equal(t, "Water plants", tasks[1].Title)
+		assert.Equal(t, 3, tasks[1].Priority)
+
+		assert.Equal(t, "csv", report.Format)
+		expected := []sync.ImportRecord{
+			{Line: 3, Status: sync.ImportStatusFailed, Title: "Call plumber", Reason: `invalid due date "next tuesday" (want YYYY-MM-DD or YYYY-MM-DD HH:MM)`},
+			{Line: 4, Status: sync.ImportStatusFailed, Reason: "row has no title"},
+			{Line: 5, Status: sync.ImportStatusFailed, Title: "Book flights", Reason: `invalid priority "9" (want 1-5)`},
+		}
+		assert.Equal(t, expected, report.Records)
+	})
+
+	t.Run("TodoistExport", func(t *testing.T) {
+		data := "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE,DURATION,DURATION_UNIT\n" +
+			"section,Errands,,,,,,,,,,\n" +
+			"task,Pay rent,Transfer from savings,1,1,Sam,,2026-11-01,en,UTC,,\n" +
+			"task,Take out the bins,,4,1,Sam,,every monday,en,UTC,10,minute\n" +
+			"task,Renew passport,,2,1,Sam,,tomorrow,en,UTC,,\n"
+
+		tasks, report := sync.ParseCSV(data, "todoist.csv", time.UTC)
+
+		assert.Equal(t, "todoist", report.Format)
+		require.Len(t, tasks, 2)
+		assert.Equal(t, "Pay rent", tasks[0].Title)
+		assert.Equal(t, "Transfer from savings", tasks[0].Description)
+		assert.Equal(t, 5, tasks[0].Priority)
+		require.NotNil(t, tasks[0].DueAt)
+		assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), *tasks[0].DueAt)
+
+		assert.Equal(t, "Take out the bins", tasks[1].Title)
+		assert.Equal(t, 2, tasks[1].Priority)
+		assert.Nil(t, tasks[1].DueAt)
+		require.NotNil(t, tasks[1].RecurrenceRule)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", *tasks[1].RecurrenceRule)
+		require.NotNil(t, tasks[1].EstimatedMinutes)
+		assert.Equal(t, 10, *tasks[1].EstimatedMinutes)
+
+		expected := []sync.ImportRecord{
+			{Line: 2, Status: sync.ImportStatusSkipped, Title: "Errands", Reason: `not a task (TYPE "section")`},
+			{Line: 5, Status: sync.ImportStatusFailed, Title: "Renew passport", Reason: `invalid date "tomorrow" (want YYYY-MM-DD, YYYY-MM-DD HH:MM or "every ...")`},
+		}
+		assert.Equal(t, expected, report.Records)
+	})
+
+	t.Run("HeaderWithoutTitle", func(t *testing.T) {
+		tasks, report := sync.ParseCSV("description,priority\nSomething,3\n", "tasks.csv", time.UTC)
+		assert.Empty(t, tasks)
+		require.Len(t, report.Records, 1)
+		assert.Equal(t, "header has no title column", report.Records[0].Reason)
+	})
+}
+
+func TestTaskService_ImportTasksWithLocations(t *testing.T) {
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	data := `title,location,latitude,longitude
+Water plants,home,,
+Buy bread,Bakery,37.78,-122.41
+Fix bike,Garage,,
+`
+
+	t.Run("LinksKnownLocationsAndFailsUnknown", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(home))
+		service, _ := newMemstoreServices(store)
+		service.EnableImportLocations(store.Locations())
+
+		tasks, report := sync.ParseCSV(data, "tasks.csv", time.UTC)
+		require.NoError(t, service.ImportTasks("test-user-id", tasks, report, hereandnow.ImportOptions{}))
+
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, 2, report.Failed)
+		assert.Equal(t, `unknown location "Bakery"`, report.Records[1].Reason)
+
+		locations, err := service.GetTaskLocations(report.Records[0].TaskID)
+		require.NoError(t, err)
+		require.Len(t, locations, 1)
+		assert.Equal(t, "home-id", locations[0].ID)
+	})
+
+	t.Run("CreatesMissingLocationsWithCoordinates", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(home))
+		service, _ := newMemstoreServices(store)
+		service.EnableImportLocations(store.Locations())
+
+		tasks, report := sync.ParseCSV(data, "tasks.csv", time.UTC)
+		require.NoError(t, service.ImportTasks("test-user-id", tasks, report, hereandnow.ImportOptions{CreateMissingLocations: true}))
+
+		assert.Equal(t, 2, report.Imported)
+		require.Len(t, report.Records, 3)
+		assert.Equal(t, sync.ImportStatusFailed, report.Records[2].Status)
+		assert.Equal(t, `cannot create location "Garage" without latitude and longitude`, report.Records[2].Reason)
+
+		locations, err := store.Locations().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Len(t, locations, 2)
+
+		linked, err := service.GetTaskLocations(report.Records[1].TaskID)
+		require.NoError(t, err)
+		require.Len(t, linked, 1)
+		assert.Equal(t, "Bakery", linked[0].Name)
+	})
+
+	t.Run("SavesLargeFilesInBatches", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+
+		var csv strings.Builder
+		csv.WriteString("title,priority\n")
+		for i := 0; i < 250; i++ {
+			fmt.Fprintf(&csv, "Task %d,2\n", i)
+		}
+		csv.WriteString("Bad task,7\n")
+
+		tasks, report := sync.ParseCSV(csv.String(), "tasks.csv", time.UTC)
+		require.NoError(t, service.ImportTasks("test-user-id", tasks, report, hereandnow.ImportOptions{}))
+
+		assert.Equal(t, 250, report.Imported)
+		assert.Equal(t, 1, report.Failed)
+		all, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Len(t, all, 250)
+	})
+}
+
+func TestTaskHandler_ImportTasks(t *testing.T) {
+	newImportRouter := func(importService api.TaskImportService) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		handler := api.NewTaskHandler(&StubAPITaskService{}, nil)
+		if importService != nil {
+			handler.SetImportService(importService)
+		}
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: handler,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id", TimeZone: "UTC"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router
+	}
+
+	t.Run("ReturnsPerRowReport", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		router := newImportRouter(service)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks/import", "title,priority\nBuy milk,4\nBook flights,9\n")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var report sync.ImportReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, "csv", report.Format)
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, 1, report.Failed)
+		require.Len(t, report.Records, 2)
+		assert.Equal(t, 3, report.Records[1].Line)
+	})
+
+	t.Run("NotImplementedWithoutService", func(t *testing.T) {
+		w := serveRequest(newImportRouter(nil), http.MethodPost, "/api/v1/tasks/import", "title\nBuy milk\n")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	t.Run("RejectsUnknownFormat", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		w := serveRequest(newImportRouter(service), http.MethodPost, "/api/v1/tasks/import?format=xlsx", "title\nBuy milk\n")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
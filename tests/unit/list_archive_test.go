@@ -0,0 +1,359 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListArchiver_Sweep(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	longAgo := now.Add(-60 * 24 * time.Hour)
+
+	staleList := models.TaskList{ID: "stale-list", Name: "Party planning", OwnerID: "owner-id", IsShared: true, CreatedAt: longAgo, UpdatedAt: longAgo}
+	activeList := models.TaskList{ID: "active-list", Name: "Groceries", OwnerID: "owner-id", IsShared: true, CreatedAt: longAgo, UpdatedAt: longAgo}
+
+	staleTask := createTestTask("Book venue", nil, 3)
+	staleTask.ListID = &staleList.ID
+	staleTask.CreatedAt, staleTask.UpdatedAt = longAgo, longAgo
+
+	recentlyCompleted := now.Add(-2 * 24 * time.Hour)
+	activeTask := createTestTask("Buy milk", nil, 3)
+	activeTask.ListID = &activeList.ID
+	activeTask.CreatedAt, activeTask.UpdatedAt = longAgo, longAgo
+	activeTask.CompletedAt = &recentlyCompleted
+
+	store := memstore.New(
+		memstore.WithLists(staleList, activeList),
+		memstore.WithTasks(staleTask, activeTask),
+	)
+	archiver := hereandnow.NewListArchiver(store.TaskLists(), store.Tasks(), store.Notifications(), 30*24*time.Hour)
+
+	archived, err := archiver.Sweep(now)
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Equal(t, staleList.ID, archived[0].ID)
+
+	t.Run("ArchivesInactiveListAndNotifiesOwner", func(t *testing.T) {
+		list, err := store.TaskLists().GetByID(staleList.ID)
+		require.NoError(t, err)
+		assert.True(t, list.IsArchived())
+
+		notifications, err := store.Notifications().GetByUserID("owner-id", true)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, models.NotificationTypeListArchived, notifications[0].Type)
+		assert.Contains(t, notifications[0].Message, "Party planning")
+	})
+
+	t.Run("LeavesActiveListAlone", func(t *testing.T) {
+		list, err := store.TaskLists().GetByID(activeList.ID)
+		require.NoError(t, err)
+		assert.False(t, list.IsArchived())
+	})
+
+	t.Run("SkipsArchivedListsOnLaterSweeps", func(t *testing.T) {
+		before, err := store.TaskLists().GetByID(staleList.ID)
+		require.NoError(t, err)
+
+		archived, err := archiver.Sweep(now.Add(24 * time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, archived)
+
+		after, err := store.TaskLists().GetByID(staleList.ID)
+		require.NoError(t, err)
+		assert.Equal(t, before.ArchivedAt, after.ArchivedAt)
+
+		notifications, err := store.Notifications().GetByUserID("owner-id", true)
+		require.NoError(t, err)
+		assert.Len(t, notifications, 1)
+	})
+}
+
+func TestListArchiver_SQLStore(t *testing.T) {
+	db := setupSoftDeleteDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE task_lists (
+			id TEXT PRIMARY KEY, name TEXT, description TEXT, owner_id TEXT, is_shared BOOLEAN,
+			color TEXT, icon TEXT, parent_id TEXT, position INTEGER, created_at DATETIME,
+			updated_at DATETIME, settings TEXT, archived_at DATETIME,
+			default_location_id TEXT, default_estimated_minutes INTEGER
+		)`)
+	require.NoError(t, err)
+
+	longAgo := time.Now().Add(-60 * 24 * time.Hour)
+	for _, id := range []string{"busy", "idle"} {
+		_, err := db.Exec(`
+			INSERT INTO task_lists (id, name, description, owner_id, is_shared, color, icon, position, created_at, updated_at, settings)
+			VALUES (?, ?, '', 'user-1', 0, '#3B82F6', 'list', 0, ?, ?, '{}')`, id, id, longAgo, longAgo)
+		require.NoError(t, err)
+	}
+	insertTaskWithMetadata(t, db, "recent", `{}`)
+	_, err = db.Exec(`UPDATE tasks SET list_id = 'busy' WHERE id = 'recent'`)
+	require.NoError(t, err)
+
+	// Wired in the server with the SQL stores
+	archiver := hereandnow.NewListArchiver(storage.NewTaskListRepository(db), storage.NewTaskRepository(db), nil, 30*24*time.Hour)
+	archived, err := archiver.Sweep(time.Now())
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Equal(t, "idle", archived[0].ID, "a recent task keeps its list active")
+}
+
+func TestArchivedListIDs(t *testing.T) {
+	parentID, childID := "parent", "child"
+	lists := []models.TaskList{
+		{ID: parentID, Name: "Wedding"},
+		{ID: childID, Name: "Catering", ParentID: &parentID},
+		{ID: "grandchild", Name: "Cake", ParentID: &childID},
+		{ID: "other", Name: "Groceries"},
+	}
+	assert.Empty(t, models.ArchivedListIDs(lists))
+
+	lists[0].Archive()
+	assert.Equal(t, map[string]bool{parentID: true, childID: true, "grandchild": true}, models.ArchivedListIDs(lists))
+
+	// A loop in the parents doesn't hang
+	lists[0].ParentID = &childID
+	lists[0].Unarchive()
+	assert.Empty(t, models.ArchivedListIDs(lists))
+}
+
+func TestListService_ArchiveList(t *testing.T) {
+	newService := func(t *testing.T) (*memstore.Store, *hereandnow.ListService, models.TaskList) {
+		list, err := models.NewTaskList("Family chores", "", "owner-id")
+		require.NoError(t, err)
+		store := memstore.New(memstore.WithLists(*list))
+		editor, err := models.NewListMember(list.ID, "editor-id", "owner-id", models.MemberRoleEditor)
+		require.NoError(t, err)
+		require.NoError(t, store.ListMembers().Create(*editor))
+		return store, hereandnow.NewListService(store.TaskLists(), store.ListMembers()), *list
+	}
+
+	t.Run("OwnerArchivesAndUnarchives", func(t *testing.T) {
+		store, service, list := newService(t)
+
+		archived, err := service.ArchiveList(list.ID, "owner-id")
+		require.NoError(t, err)
+		assert.True(t, archived.IsArchived())
+		saved, err := store.TaskLists().GetByID(list.ID)
+		require.NoError(t, err)
+		assert.True(t, saved.IsArchived())
+
+		restored, err := service.UnarchiveList(list.ID, "owner-id")
+		require.NoError(t, err)
+		assert.False(t, restored.IsArchived())
+		saved, err = store.TaskLists().GetByID(list.ID)
+		require.NoError(t, err)
+		assert.Nil(t, saved.ArchivedAt)
+	})
+
+	t.Run("EditorsCannotArchive", func(t *testing.T) {
+		store, service, list := newService(t)
+
+		_, err := service.ArchiveList(list.ID, "editor-id")
+		assert.ErrorIs(t, err, hereandnow.ErrNotListOwner)
+		saved, err := store.TaskLists().GetByID(list.ID)
+		require.NoError(t, err)
+		assert.False(t, saved.IsArchived())
+	})
+
+	t.Run("MissingList", func(t *testing.T) {
+		_, service, _ := newService(t)
+		_, err := service.ArchiveList("missing", "owner-id")
+		assert.ErrorContains(t, err, "not found")
+	})
+}
+
+func TestTaskService_ArchivedLists(t *testing.T) {
+	parent := models.TaskList{ID: "parent-list", Name: "Wedding", OwnerID: "test-user-id"}
+	child := models.TaskList{ID: "child-list", Name: "Catering", OwnerID: "test-user-id", ParentID: &parent.ID}
+
+	inParent := createTestTask("Book venue", nil, 3)
+	inParent.ListID = &parent.ID
+	inChild := createTestTask("Taste cakes", nil, 3)
+	inChild.ListID = &child.ID
+	loose := createTestTask("Call mom", nil, 3)
+
+	newServices := func(t *testing.T) (*memstore.Store, *hereandnow.TaskService, *hereandnow.ListService) {
+		store := memstore.New(
+			memstore.WithLists(parent, child),
+			memstore.WithTasks(inParent, inChild, loose),
+		)
+		require.NoError(t, store.Contexts().Create(createTestContext(nil, nil, 60, 3)))
+		tasks, _ := newMemstoreServices(store)
+		tasks.SetListRepository(store.TaskLists())
+		lists := hereandnow.NewListService(store.TaskLists(), store.ListMembers())
+		_, err := lists.ArchiveList(parent.ID, "test-user-id")
+		require.NoError(t, err)
+		return store, tasks, lists
+	}
+
+	t.Run("HidesTasksInArchivedListsAndTheirChildren", func(t *testing.T) {
+		_, tasks, lists := newServices(t)
+
+		visible, _, err := tasks.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Call mom"}, taskTitles(visible))
+
+		_, err = lists.UnarchiveList(parent.ID, "test-user-id")
+		require.NoError(t, err)
+		visible, _, err = tasks.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Book venue", "Taste cakes", "Call mom"}, taskTitles(visible))
+	})
+
+	t.Run("RejectsChanges", func(t *testing.T) {
+		store, tasks, _ := newServices(t)
+
+		title := "Book a bigger venue"
+		_, err := tasks.UpdateTask(inParent.ID, hereandnow.UpdateTaskRequest{Title: &title})
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+		assert.ErrorContains(t, err, `"Wedding"`)
+
+		_, err = tasks.CompleteTask(inChild.ID, "test-user-id")
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+
+		err = tasks.DeleteTask(inChild.ID, "test-user-id")
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+
+		req := memstoreTaskRequest("Send invitations")
+		req.ListID = &child.ID
+		_, err = tasks.CreateTask("test-user-id", req)
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+
+		priority := 5
+		_, err = tasks.BulkEditTasks("test-user-id", hereandnow.TaskSelector{ListID: child.ID}, hereandnow.BulkEditRequest{Priority: &priority})
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+
+		_, err = tasks.ReorderTask(inChild.ID, "", "test-user-id")
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+
+		_, err = tasks.AssignTask(inParent.ID, "other-user-id", "test-user-id")
+		assert.ErrorIs(t, err, hereandnow.ErrListArchived)
+
+		saved, err := store.Tasks().GetByID(inParent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Book venue", saved.Title)
+		assert.Nil(t, saved.AssigneeID)
+		saved, err = store.Tasks().GetByID(inChild.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, saved.Priority)
+
+		_, err = tasks.UpdateTask(loose.ID, hereandnow.UpdateTaskRequest{Title: &title})
+		assert.NoError(t, err, "tasks outside the list are unaffected")
+	})
+}
+
+// archiveListService serves lists from memory alongside fakeListService
+type archiveListService struct {
+	fakeListService
+}
+
+func (s *archiveListService) GetListsByUserID(userID string) ([]api.TaskListWithMembers, error) {
+	var lists []api.TaskListWithMembers
+	for _, list := range s.lists {
+		lists = append(lists, api.TaskListWithMembers{TaskList: list})
+	}
+	return lists, nil
+}
+
+func (s *archiveListService) ArchiveList(listID, userID string) (*models.TaskList, error) {
+	return s.setArchived(listID, userID, true)
+}
+
+func (s *archiveListService) UnarchiveList(listID, userID string) (*models.TaskList, error) {
+	return s.setArchived(listID, userID, false)
+}
+
+func (s *archiveListService) setArchived(listID, userID string, archived bool) (*models.TaskList, error) {
+	list, ok := s.lists[listID]
+	if !ok {
+		return nil, errors.New("list not found")
+	}
+	if !list.IsOwnedBy(userID) {
+		return nil, hereandnow.ErrNotListOwner
+	}
+	if archived {
+		list.Archive()
+	} else {
+		list.Unarchive()
+	}
+	s.lists[listID] = list
+	return &list, nil
+}
+
+func TestListHandler_Archive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mine, err := models.NewTaskList("Wedding", "", "test-user-id")
+	require.NoError(t, err)
+	theirs, err := models.NewTaskList("Their list", "", "other-user")
+	require.NoError(t, err)
+	service := &archiveListService{fakeListService: fakeListService{lists: map[string]models.TaskList{
+		mine.ID: *mine, theirs.ID: *theirs,
+	}}}
+
+	newRouter := func(archiving bool) *gin.Engine {
+		handler := api.NewListHandler(service)
+		if archiving {
+			handler.SetArchiveService(service)
+		}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", "test-user-id")
+			c.Set("user", &models.User{ID: "test-user-id"})
+		})
+		router.GET("/lists", handler.GetLists)
+		router.POST("/lists/:id/archive", handler.ArchiveList)
+		router.POST("/lists/:id/unarchive", handler.UnarchiveList)
+		return router
+	}
+	router := newRouter(true)
+
+	listNames := func(path string) []string {
+		w := serveRequest(router, http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Lists []models.TaskList `json:"lists"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var names []string
+		for _, list := range response.Lists {
+			names = append(names, list.Name)
+		}
+		return names
+	}
+
+	w := serveRequest(router, http.MethodPost, "/lists/"+mine.ID+"/archive", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var archived models.TaskList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &archived))
+	assert.NotNil(t, archived.ArchivedAt)
+
+	assert.Equal(t, []string{"Their list"}, listNames("/lists"))
+	assert.ElementsMatch(t, []string{"Wedding", "Their list"}, listNames("/lists?includeArchived=true"))
+
+	w = serveRequest(router, http.MethodPost, "/lists/"+mine.ID+"/unarchive", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []string{"Wedding", "Their list"}, listNames("/lists"))
+
+	w = serveRequest(router, http.MethodPost, "/lists/"+theirs.ID+"/archive", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveRequest(router, http.MethodPost, "/lists/missing/archive", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveRequest(newRouter(false), http.MethodPost, "/lists/"+mine.ID+"/archive", "")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,254 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDefaults(t *testing.T) {
+	costco := *createTestLocation("costco-id", "Costco", 37.7700, -122.4100, "test-user-id")
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+
+	newStore := func(t *testing.T) (*memstore.Store, *hereandnow.TaskService, models.TaskList) {
+		store := memstore.New(memstore.WithLocations(costco, home))
+		service, _ := newMemstoreServices(store)
+		service.SetListRepository(store.TaskLists())
+
+		list, err := models.NewTaskList("Costco run", "", "test-user-id")
+		require.NoError(t, err)
+		list.SetDefaultLocation(costco.ID)
+		ten := 10
+		require.NoError(t, list.SetDefaultEstimatedMinutes(&ten))
+		require.NoError(t, store.TaskLists().Create(*list))
+		return store, service, *list
+	}
+	inList := func(listID, title string) hereandnow.CreateTaskRequest {
+		req := memstoreTaskRequest(title)
+		req.ListID = &listID
+		return req
+	}
+	locationIDs := func(t *testing.T, store *memstore.Store, taskID string) []string {
+		locations, err := store.TaskLocations().GetLocationsByTaskID(taskID)
+		require.NoError(t, err)
+		var ids []string
+		for _, location := range locations {
+			ids = append(ids, location.ID)
+		}
+		return ids
+	}
+
+	t.Run("NewTasksInheritDefaults", func(t *testing.T) {
+		store, service, list := newStore(t)
+
+		task, err := service.CreateTask("test-user-id", inList(list.ID, "Paper towels"))
+		require.NoError(t, err)
+		require.NotNil(t, task.EstimatedMinutes)
+		assert.Equal(t, 10, *task.EstimatedMinutes)
+		assert.Equal(t, []string{costco.ID}, locationIDs(t, store, task.ID))
+		assert.Equal(t, hereandnow.InheritedDefaults{LocationID: costco.ID, EstimatedMinutes: true}, hereandnow.TaskInheritedDefaults(*task))
+
+		// The task's own constraints win
+		req := inList(list.ID, "Return blender")
+		thirty := 30
+		req.EstimatedMinutes = &thirty
+		req.LocationIDs = []string{home.ID}
+		own, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+		assert.Equal(t, 30, *own.EstimatedMinutes)
+		assert.Equal(t, []string{home.ID}, locationIDs(t, store, own.ID))
+		assert.Equal(t, hereandnow.InheritedDefaults{}, hereandnow.TaskInheritedDefaults(*own))
+
+		// Tasks outside a list are left alone
+		loose, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call mom"))
+		require.NoError(t, err)
+		assert.Nil(t, loose.EstimatedMinutes)
+		assert.Empty(t, locationIDs(t, store, loose.ID))
+	})
+
+	t.Run("LocationFilterTreatsInheritedLocationsAsTheTasksOwn", func(t *testing.T) {
+		store, service, list := newStore(t)
+		task, err := service.CreateTask("test-user-id", inList(list.ID, "Paper towels"))
+		require.NoError(t, err)
+
+		filter := filters.NewLocationFilter(filters.DefaultFilterConfig, store.Locations(), store.TaskLocations())
+		visible, code, _ := filter.Evaluate(createTestContext(&costco.Latitude, &costco.Longitude, 60, 3), *task)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonLocationInRange, code)
+
+		farLat, farLng := 40.7128, -74.0060
+		visible, code, _ = filter.Evaluate(createTestContext(&farLat, &farLng, 60, 3), *task)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonLocationOutOfRange, code)
+	})
+
+	t.Run("ApplyToExistingTasks", func(t *testing.T) {
+		store, service, list := newStore(t)
+		inherited, err := service.CreateTask("test-user-id", inList(list.ID, "Paper towels"))
+		require.NoError(t, err)
+		req := inList(list.ID, "Return blender")
+		thirty := 30
+		req.EstimatedMinutes = &thirty
+		own, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		fifteen := 15
+		require.NoError(t, list.SetDefaultEstimatedMinutes(&fifteen))
+		list.SetDefaultLocation(home.ID)
+		require.NoError(t, store.TaskLists().Update(list))
+
+		updated, err := service.ApplyListDefaults(list.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, 2, updated, "the own estimate stays but the inherited location moves")
+
+		got, err := service.GetTask(inherited.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 15, *got.EstimatedMinutes)
+		assert.Equal(t, []string{home.ID}, locationIDs(t, store, inherited.ID))
+
+		got, err = service.GetTask(own.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 30, *got.EstimatedMinutes)
+		assert.Equal(t, []string{home.ID}, locationIDs(t, store, own.ID))
+		assert.Equal(t, hereandnow.InheritedDefaults{LocationID: home.ID}, hereandnow.TaskInheritedDefaults(*got))
+
+		updated, err = service.ApplyListDefaults(list.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Zero(t, updated, "applying the same defaults again changes nothing")
+
+		// Clearing a default removes what tasks inherited from it
+		require.NoError(t, list.SetDefaultEstimatedMinutes(nil))
+		list.SetDefaultLocation("")
+		require.NoError(t, store.TaskLists().Update(list))
+		_, err = service.ApplyListDefaults(list.ID, "test-user-id")
+		require.NoError(t, err)
+		got, err = service.GetTask(inherited.ID)
+		require.NoError(t, err)
+		assert.Nil(t, got.EstimatedMinutes)
+		assert.Empty(t, locationIDs(t, store, inherited.ID))
+		assert.Equal(t, hereandnow.InheritedDefaults{}, hereandnow.TaskInheritedDefaults(*got))
+	})
+
+	t.Run("SettingAnEstimateMakesItTheTasksOwn", func(t *testing.T) {
+		_, service, list := newStore(t)
+		task, err := service.CreateTask("test-user-id", inList(list.ID, "Paper towels"))
+		require.NoError(t, err)
+
+		five := 5
+		task, err = service.UpdateTask(task.ID, hereandnow.UpdateTaskRequest{EstimatedMinutes: &five})
+		require.NoError(t, err)
+		assert.Equal(t, hereandnow.InheritedDefaults{LocationID: costco.ID}, hereandnow.TaskInheritedDefaults(*task))
+	})
+
+	t.Run("InvalidDefaults", func(t *testing.T) {
+		list, err := models.NewTaskList("Costco run", "", "test-user-id")
+		require.NoError(t, err)
+		zero := 0
+		assert.Error(t, list.SetDefaultEstimatedMinutes(&zero))
+
+		list.DefaultEstimatedMinutes = &zero
+		assert.Error(t, list.Validate())
+	})
+}
+
+// fakeListService keeps lists in memory for the list handlers
+type fakeListService struct {
+	lists   map[string]models.TaskList
+	applied []string
+}
+
+func (s *fakeListService) GetListsByUserID(userID string) ([]api.TaskListWithMembers, error) {
+	return nil, nil
+}
+
+func (s *fakeListService) CreateList(list models.TaskList) (*models.TaskList, error) {
+	s.lists[list.ID] = list
+	return &list, nil
+}
+
+func (s *fakeListService) GetListByID(listID string) (*models.TaskList, error) {
+	list, ok := s.lists[listID]
+	if !ok {
+		return nil, assert.AnError
+	}
+	return &list, nil
+}
+
+func (s *fakeListService) UpdateList(list models.TaskList) (*models.TaskList, error) {
+	s.lists[list.ID] = list
+	return &list, nil
+}
+
+func (s *fakeListService) DeleteList(listID string, userID string) error {
+	return nil
+}
+
+func (s *fakeListService) GetListMembers(listID string) ([]models.ListMember, error) {
+	return nil, nil
+}
+
+func (s *fakeListService) AddListMember(member models.ListMember) (*models.ListMember, error) {
+	return &member, nil
+}
+
+func (s *fakeListService) ApplyListDefaults(listID string, userID string) (int, error) {
+	s.applied = append(s.applied, listID)
+	return 3, nil
+}
+
+func TestListHandler_Defaults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &fakeListService{lists: map[string]models.TaskList{}}
+	handler := api.NewListHandler(service)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "test-user-id")
+		c.Set("user", &models.User{ID: "test-user-id"})
+	})
+	router.POST("/lists", handler.CreateList)
+	router.PATCH("/lists/:id", handler.UpdateList)
+
+	w := serveRequest(router, http.MethodPost, "/lists", `{"name":"Costco run","default_location_id":"costco-id","default_estimated_minutes":10}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.TaskList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.NotNil(t, created.DefaultLocationID)
+	assert.Equal(t, "costco-id", *created.DefaultLocationID)
+	assert.Equal(t, 10, *created.DefaultEstimatedMinutes)
+
+	w = serveRequest(router, http.MethodPost, "/lists", `{"name":"Bad","default_estimated_minutes":-5}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveRequest(router, http.MethodPatch, "/lists/"+created.ID, `{"default_estimated_minutes":0,"default_location_id":""}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, service.lists[created.ID].DefaultEstimatedMinutes)
+	assert.Nil(t, service.lists[created.ID].DefaultLocationID)
+	assert.Empty(t, service.applied, "tasks are only changed when asked")
+
+	w = serveRequest(router, http.MethodPatch, "/lists/"+created.ID, `{"default_estimated_minutes":15,"apply_to_existing":true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response api.ListUpdateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 15, *response.DefaultEstimatedMinutes)
+	assert.Equal(t, 3, response.TasksUpdated)
+	assert.Equal(t, []string{created.ID}, service.applied)
+
+	// Only the owner can change a list
+	other, err := models.NewTaskList("Someone else's", "", "other-user")
+	require.NoError(t, err)
+	service.lists[other.ID] = *other
+	w = serveRequest(router, http.MethodPatch, "/lists/"+other.ID, `{"default_estimated_minutes":15}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveRequest(router, http.MethodPatch, "/lists/missing", `{}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newScheduleStore(t *testing.T, tasks ...models.Task) *memstore.Store {
+	london := models.User{ID: "london-id", Username: "alice", TimeZone: "Europe/London", Settings: json.RawMessage(`{}`)}
+	newYork := models.User{ID: "newyork-id", Username: "bob", TimeZone: "America/New_York", Settings: json.RawMessage(`{}`)}
+	store := memstore.New(memstore.WithUsers(london, newYork), memstore.WithTasks(tasks...))
+
+	for _, userID := range []string{london.ID, newYork.ID} {
+		member, err := models.NewListMember("project-list", userID, london.ID, models.MemberRoleEditor)
+		require.NoError(t, err)
+		member.Accept()
+		require.NoError(t, store.ListMembers().Create(*member))
+	}
+	return store
+}
+
+func listTaskDueAt(title string, due time.Time, assigneeID string) models.Task {
+	task := createTestTask(title, nil, 3)
+	listID := "project-list"
+	task.ListID = &listID
+	task.DueAt = &due
+	if assigneeID != "" {
+		task.AssigneeID = &assigneeID
+	}
+	return task
+}
+
+func TestListScheduler_Schedule(t *testing.T) {
+	// Wednesday 2026-10-14 15:00 UTC is 16:00 in London and 11:00 in New York
+	due := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+
+	t.Run("RendersDueTimePerMember", func(t *testing.T) {
+		store := newScheduleStore(t, listTaskDueAt("Ship release", due, "london-id"))
+		scheduler := hereandnow.NewListScheduler(store.Tasks(), store.ListMembers(), store.Users())
+
+		schedule, err := scheduler.Schedule("project-list")
+		require.NoError(t, err)
+		require.Len(t, schedule.Members, 2)
+		require.Len(t, schedule.Entries, 1)
+
+		dueTimes := schedule.Entries[0].DueTimes
+		require.Len(t, dueTimes, 2)
+
+		assert.Equal(t, "alice", dueTimes[0].Username)
+		assert.Equal(t, "Europe/London", dueTimes[0].TimeZone)
+		assert.Equal(t, "Wed Oct 14 16:00 BST", dueTimes[0].DueAt.Format("Mon Jan 2 15:04 MST"))
+		assert.True(t, dueTimes[0].IsAssignee)
+
+		assert.Equal(t, "bob", dueTimes[1].Username)
+		assert.Equal(t, "Wed Oct 14 11:00 EDT", dueTimes[1].DueAt.Format("Mon Jan 2 15:04 MST"))
+		assert.False(t, dueTimes[1].IsAssignee)
+
+		assert.True(t, dueTimes[0].DueAt.Equal(dueTimes[1].DueAt), "same instant in both zones")
+		assert.False(t, schedule.Entries[0].OutOfHours)
+	})
+
+	t.Run("FlagsTaskOutsideAssigneeWorkingHours", func(t *testing.T) {
+		// 21:00 UTC is 22:00 in London but 17:00 in New York
+		late := time.Date(2026, 10, 14, 21, 0, 0, 0, time.UTC)
+		store := newScheduleStore(t,
+			listTaskDueAt("Deploy hotfix", late, "london-id"),
+			listTaskDueAt("Review hotfix", late.Add(-30*time.Minute), "newyork-id"),
+		)
+		scheduler := hereandnow.NewListScheduler(store.Tasks(), store.ListMembers(), store.Users())
+
+		schedule, err := scheduler.Schedule("project-list")
+		require.NoError(t, err)
+		require.Len(t, schedule.Entries, 2)
+
+		assert.Equal(t, "Review hotfix", schedule.Entries[0].Task.Title)
+		assert.False(t, schedule.Entries[0].OutOfHours, "16:30 in New York is working hours")
+
+		assert.Equal(t, "Deploy hotfix", schedule.Entries[1].Task.Title)
+		assert.True(t, schedule.Entries[1].OutOfHours, "22:00 in London is after hours")
+		assert.False(t, schedule.Entries[1].DueTimes[0].WorkingHours)
+	})
+
+	t.Run("UsesMemberWorkingHours", func(t *testing.T) {
+		store := newScheduleStore(t, listTaskDueAt("Ship release", due, "newyork-id"))
+		bob, err := store.Users().GetByID("newyork-id")
+		require.NoError(t, err)
+		bob.Settings = json.RawMessage(`{"working_hours": {"start": "12:00", "end": "20:00", "days": ["mon", "tue", "wed", "thu", "fri"]}}`)
+		require.NoError(t, store.Users().Update(bob))
+
+		scheduler := hereandnow.NewListScheduler(store.Tasks(), store.ListMembers(), store.Users())
+		schedule, err := scheduler.Schedule("project-list")
+		require.NoError(t, err)
+		require.Len(t, schedule.Entries, 1)
+		assert.True(t, schedule.Entries[0].OutOfHours, "11:00 is before bob's 12:00 start")
+	})
+
+	t.Run("SkipsUndatedDoneAndPendingMembers", func(t *testing.T) {
+		undated := createTestTask("Write docs", nil, 3)
+		listID := "project-list"
+		undated.ListID = &listID
+		done := listTaskDueAt("Old release", due, "")
+		done.Status = models.TaskStatusCompleted
+
+		store := newScheduleStore(t, undated, done)
+		invite, err := models.NewListMember("project-list", "pending-id", "london-id", models.MemberRoleViewer)
+		require.NoError(t, err)
+		require.NoError(t, store.ListMembers().Create(*invite))
+
+		scheduler := hereandnow.NewListScheduler(store.Tasks(), store.ListMembers(), store.Users())
+		schedule, err := scheduler.Schedule("project-list")
+		require.NoError(t, err)
+		assert.Len(t, schedule.Members, 2)
+		assert.Empty(t, schedule.Entries)
+	})
+}
+
+func TestWorkingHours_Contains(t *testing.T) {
+	hours := models.DefaultWorkingHours()
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	assert.True(t, hours.Contains(time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), london), "09:00 BST on a Wednesday")
+	assert.False(t, hours.Contains(time.Date(2026, 10, 14, 16, 0, 0, 0, time.UTC), london), "17:00 BST is the end")
+	assert.False(t, hours.Contains(time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC), london), "Saturday")
+
+	night := models.WorkingHours{Start: "22:00", End: "06:00", Days: []string{"fri"}}
+	assert.True(t, night.Contains(time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC), time.UTC), "Friday night")
+	assert.True(t, night.Contains(time.Date(2026, 10, 17, 5, 0, 0, 0, time.UTC), time.UTC), "early Saturday is Friday's shift")
+	assert.False(t, night.Contains(time.Date(2026, 10, 16, 5, 0, 0, 0, time.UTC), time.UTC), "early Friday is Thursday's shift")
+
+	assert.Error(t, models.WorkingHours{Start: "9am", End: "17:00", Days: []string{"mon"}}.Validate())
+	assert.Error(t, models.WorkingHours{Start: "09:00", End: "17:00", Days: []string{"someday"}}.Validate())
+}
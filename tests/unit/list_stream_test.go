@@ -0,0 +1,183 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestListService_RemoveMember(t *testing.T) {
+	store, list := newSharedListStore(t)
+	hub := hereandnow.NewEventHub(store.TaskLists(), store.ListMembers(), 0)
+	service := hereandnow.NewListService(store.TaskLists(), store.ListMembers())
+	service.SetEventPublisher(hub)
+
+	t.Run("OnlyOwnerCanRemoveOthers", func(t *testing.T) {
+		assert.ErrorContains(t, service.RemoveMember(list.ID, "carol", "bob"), "only the list owner")
+	})
+
+	t.Run("OwnerRemovesMember", func(t *testing.T) {
+		bob := hub.SubscribeList(list.ID, "bob")
+		alice := hub.SubscribeList(list.ID, "alice")
+		defer alice.Close()
+
+		require.NoError(t, service.RemoveMember(list.ID, "bob", "alice"))
+		event := receiveEvent(t, bob)
+		assert.Equal(t, hereandnow.EventListMemberRemoved, event.Type)
+		assert.Equal(t, "bob", event.Member.UserID)
+		_, open := <-bob.Events()
+		assert.False(t, open, "the removed member's stream ends")
+		assert.Equal(t, hereandnow.EventListMemberRemoved, receiveEvent(t, alice).Type)
+
+		canView, err := service.CanViewList(list.ID, "bob")
+		require.NoError(t, err)
+		assert.False(t, canView)
+	})
+
+	t.Run("MemberLeaves", func(t *testing.T) {
+		require.NoError(t, service.RemoveMember(list.ID, "carol", "carol"))
+		assert.ErrorContains(t, service.RemoveMember(list.ID, "carol", "alice"), "not found")
+	})
+}
+
+func TestListService_CanViewList(t *testing.T) {
+	store, list := newSharedListStore(t)
+	service := hereandnow.NewListService(store.TaskLists(), store.ListMembers())
+
+	for user, want := range map[string]bool{"alice": true, "bob": true, "carol": false, "dave": false} {
+		canView, err := service.CanViewList(list.ID, user)
+		require.NoError(t, err)
+		assert.Equal(t, want, canView, user)
+	}
+
+	_, err := service.CanViewList("missing", "alice")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestEventsHandler_ListStream(t *testing.T) {
+	store, list := newSharedListStore(t)
+	dave, err := models.NewListMember(list.ID, "dave", "alice", models.MemberRoleViewer)
+	require.NoError(t, err)
+	dave.Accept()
+	require.NoError(t, store.ListMembers().Create(*dave))
+
+	taskService, _ := newMemstoreServices(store)
+	listService := hereandnow.NewListService(store.TaskLists(), store.ListMembers())
+	hub := hereandnow.NewEventHub(store.TaskLists(), store.ListMembers(), 0)
+	taskService.SetEventPublisher(hub)
+	listService.SetEventPublisher(hub)
+
+	setup := func(enable bool) *httptest.Server {
+		events := api.NewEventsHandler(hub)
+		if enable {
+			events.EnableListStreams(listService, hub)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Events: events,
+			AuthMiddleware: func(c *gin.Context) {
+				userID := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+				c.Set("user", &models.User{ID: userID})
+				c.Set("user_id", userID)
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		server := httptest.NewServer(router)
+		t.Cleanup(server.Close)
+		return server
+	}
+	server := setup(true)
+
+	// connect opens the list's stream for the user once it is subscribed
+	connect := func(t *testing.T, listID, userID string) *websocket.Conn {
+		subscribers := hub.ActiveSubscribers()
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/lists/" + listID + "/stream?token=" + userID
+		ws, err := websocket.Dial(url, "", server.URL)
+		require.NoError(t, err)
+		t.Cleanup(func() { ws.Close() })
+		require.Eventually(t, func() bool { return hub.ActiveSubscribers() > subscribers }, time.Second, 5*time.Millisecond)
+		return ws
+	}
+	receive := func(t *testing.T, ws *websocket.Conn) api.ListStreamMessage {
+		t.Helper()
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(time.Second)))
+		var message api.ListStreamMessage
+		require.NoError(t, websocket.JSON.Receive(ws, &message))
+		return message
+	}
+	status := func(t *testing.T, listID, userID string) int {
+		resp, err := http.Get(server.URL + "/api/v1/lists/" + listID + "/stream?token=" + userID)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	alice := connect(t, list.ID, "alice")
+	viewer := connect(t, list.ID, "dave")
+
+	t.Run("MembersSeeTaskChanges", func(t *testing.T) {
+		req := memstoreTaskRequest("Buy milk")
+		req.ListID = &list.ID
+		task, err := taskService.CreateTask("bob", req)
+		require.NoError(t, err)
+		_, err = taskService.CompleteTask(task.ID, "bob")
+		require.NoError(t, err)
+
+		for _, ws := range []*websocket.Conn{alice, viewer} {
+			message := receive(t, ws)
+			assert.Equal(t, "task_added", message.Type)
+			assert.Equal(t, "Buy milk", message.Task.Title)
+			assert.Equal(t, "task_completed", receive(t, ws).Type)
+		}
+	})
+
+	t.Run("MembersSeeNewMembers", func(t *testing.T) {
+		_, err := listService.AddMember(list.ID, "erin", models.MemberRoleViewer, "alice")
+		require.NoError(t, err)
+
+		message := receive(t, viewer)
+		assert.Equal(t, "member_joined", message.Type)
+		assert.Equal(t, "erin", message.Member.UserID)
+		assert.Equal(t, "member_joined", receive(t, alice).Type)
+	})
+
+	t.Run("RefusesNonMembers", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, status(t, list.ID, "mallory"))
+		assert.Equal(t, http.StatusForbidden, status(t, list.ID, "carol"), "invitation not accepted")
+		assert.Equal(t, http.StatusNotFound, status(t, "missing", "alice"))
+	})
+
+	t.Run("RemovedMemberIsDisconnected", func(t *testing.T) {
+		require.NoError(t, listService.RemoveMember(list.ID, "dave", "alice"))
+
+		message := receive(t, viewer)
+		assert.Equal(t, "member_removed", message.Type)
+		assert.Equal(t, "dave", message.Member.UserID)
+
+		require.NoError(t, viewer.SetReadDeadline(time.Now().Add(time.Second)))
+		var next api.ListStreamMessage
+		assert.Error(t, websocket.JSON.Receive(viewer, &next), "connection closed")
+
+		assert.Equal(t, "member_removed", receive(t, alice).Type)
+		assert.Equal(t, http.StatusForbidden, status(t, list.ID, "dave"))
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		resp, err := http.Get(setup(false).URL + "/api/v1/lists/" + list.ID + "/stream?token=alice")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	})
+}
@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/locale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocale_Format(t *testing.T) {
+	// Friday afternoon
+	at := time.Date(2024, time.March, 15, 17, 30, 0, 0, time.UTC)
+
+	t.Run("DefaultUnchanged", func(t *testing.T) {
+		for _, name := range []string{"", "en", "en-US", "en_GB.UTF-8", "C"} {
+			l, err := locale.Parse(name)
+			require.NoError(t, err, name)
+
+			assert.Equal(t, at.Format("Monday, January 2, 2006 at 3:04 PM"), l.Format(at, locale.LongDateTime), name)
+			assert.Equal(t, at.Format("Monday, January 2, 2006"), l.Format(at, locale.LongDate), name)
+			assert.Equal(t, at.Format("Jan 2"), l.Format(at, locale.ShortDate), name)
+			assert.Equal(t, "37.774900, 1234 meters", l.Sprintf("%.6f, %d meters", 37.7749, 1234), name)
+		}
+		assert.Equal(t, "Friday, March 15, 2024 at 5:30 PM", locale.Default().Format(at, locale.LongDateTime))
+	})
+
+	tests := []struct {
+		locale string
+		long   string
+		date   string
+		short  string
+		coords string
+	}{
+		{"de", "Freitag, 15. März 2024 um 17:30", "Freitag, 15. März 2024", "15. März", "37,774900"},
+		{"de_DE.UTF-8", "Freitag, 15. März 2024 um 17:30", "Freitag, 15. März 2024", "15. März", "37,774900"},
+		{"fr", "vendredi 15 mars 2024 à 17:30", "vendredi 15 mars 2024", "15 mars", "37,774900"},
+		{"es-MX", "viernes, 15 de marzo de 2024, 17:30", "viernes, 15 de marzo de 2024", "15 mar", "37,774900"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			l, err := locale.Parse(tt.locale)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.long, l.Format(at, locale.LongDateTime))
+			assert.Equal(t, tt.date, l.Format(at, locale.LongDate))
+			assert.Equal(t, tt.short, l.Format(at, locale.ShortDate))
+			assert.Equal(t, tt.coords, l.Sprintf("%.6f", 37.7749))
+		})
+	}
+
+	t.Run("AllMonthsAndWeekdaysTranslated", func(t *testing.T) {
+		l, err := locale.Parse("fr")
+		require.NoError(t, err)
+
+		for month := time.January; month <= time.December; month++ {
+			day := time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)
+			formatted := l.FormatLayout(day, "Monday January Mon Jan")
+			assert.NotContains(t, formatted, day.Format("January"))
+			assert.NotContains(t, formatted, day.Format("Monday"))
+		}
+		assert.Equal(t, "dimanche 1 décembre", l.FormatLayout(time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC), "Monday 2 January"))
+	})
+
+	t.Run("NumberGrouping", func(t *testing.T) {
+		l, err := locale.Parse("de")
+		require.NoError(t, err)
+		assert.Equal(t, "12.345 minutes", l.Sprintf("%d minutes", 12345))
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := locale.Parse("ja")
+		assert.ErrorContains(t, err, "unsupported locale")
+
+		_, err = locale.Parse("not a locale!")
+		assert.Error(t, err)
+	})
+}
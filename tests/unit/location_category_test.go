@@ -0,0 +1,146 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocationCategory(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		category, err := models.ParseLocationCategory(" Gym ")
+		require.NoError(t, err)
+		assert.Equal(t, models.LocationCategoryGym, category)
+
+		category, err = models.ParseLocationCategory("")
+		require.NoError(t, err)
+		assert.Equal(t, models.LocationCategoryOther, category)
+
+		_, err = models.ParseLocationCategory("grocery")
+		assert.Error(t, err)
+	})
+
+	t.Run("Icons", func(t *testing.T) {
+		for _, category := range models.LocationCategories {
+			assert.NotEmpty(t, category.Icon(), category)
+		}
+		assert.Equal(t, models.LocationCategoryOther.Icon(), models.LocationCategory("boating").Icon())
+	})
+
+	t.Run("NewLocationIsOther", func(t *testing.T) {
+		location, err := models.NewLocation("user-1", "Somewhere", "", 40.0, -74.0, 100)
+		require.NoError(t, err)
+		assert.Equal(t, models.LocationCategoryOther, location.Category)
+	})
+
+	t.Run("ValidateRejectsUnknown", func(t *testing.T) {
+		location, err := models.NewLocation("user-1", "Market", "", 40.0, -74.0, 100)
+		require.NoError(t, err)
+
+		assert.Error(t, location.SetCategory("grocery"))
+		location.Category = "grocery"
+		assert.Error(t, location.Validate())
+
+		location.Category = ""
+		assert.NoError(t, location.Validate(), "no category counts as other")
+	})
+
+	t.Run("CustomCategory", func(t *testing.T) {
+		location, err := models.NewLocation("user-1", "Marina", "", 40.0, -74.0, 100)
+		require.NoError(t, err)
+		location.Metadata = []byte(`{"slip": 12}`)
+
+		require.NoError(t, location.SetCustomCategory("Boating"))
+		assert.Equal(t, models.LocationCategory("boating"), location.Category)
+		assert.True(t, location.HasCustomCategory())
+		assert.NoError(t, location.Validate())
+		assert.JSONEq(t, `{"slip": 12, "custom_category": true}`, string(location.Metadata))
+
+		require.NoError(t, location.SetCategory(models.LocationCategoryErrand))
+		assert.False(t, location.HasCustomCategory())
+		assert.JSONEq(t, `{"slip": 12}`, string(location.Metadata))
+
+		require.NoError(t, location.SetCustomCategory("work"))
+		assert.False(t, location.HasCustomCategory(), "a known category is not custom")
+	})
+}
+
+func TestFilterEngine_CategoryPreference(t *testing.T) {
+	gym := *createTestLocation("gym", "Gym", 40.0, -74.0, "test-user-id")
+	gym.Category = models.LocationCategoryGym
+	office := *createTestLocation("office", "Office", 40.1, -74.1, "test-user-id")
+	office.Category = models.LocationCategoryWork
+
+	stretch := createTestTask("Stretch", nil, 3)
+	report := createTestTask("Write report", nil, 3)
+	anywhere := createTestTask("Call mum", nil, 3)
+	tasks := []models.Task{anywhere, report, stretch}
+
+	setup := func(t *testing.T, preference float64) *filters.Engine {
+		store := memstore.New(memstore.WithLocations(gym, office), memstore.WithTasks(tasks...))
+		for taskID, locationID := range map[string]string{stretch.ID: gym.ID, report.ID: office.ID} {
+			link, err := models.NewTaskLocation(taskID, locationID, true)
+			require.NoError(t, err)
+			require.NoError(t, store.TaskLocations().Create(*link))
+		}
+
+		config := filters.DefaultFilterConfig
+		config.CategoryPreference = preference
+		engine := filters.NewEngine(config, &MockAuditRepo{})
+		engine.EnableCategoryPreference(store.Locations(), store.TaskLocations())
+		return engine
+	}
+
+	t.Run("PrefersTasksAtTheCurrentKindOfPlace", func(t *testing.T) {
+		engine := setup(t, 0.5)
+		lat, lng := gym.Latitude, gym.Longitude
+		ctx := createTestContext(&lat, &lng, 60, 3)
+
+		scored, err := engine.ScoreTasks(ctx, tasks)
+		require.NoError(t, err)
+		assert.Equal(t, "Stretch", scored[0].Task.Title)
+		assert.Equal(t, 0.5, scored[0].Breakdown[filters.ScoreCategory])
+		for _, task := range scored[1:] {
+			assert.NotContains(t, task.Breakdown, filters.ScoreCategory)
+		}
+	})
+
+	t.Run("SocialContextStandsIn", func(t *testing.T) {
+		engine := setup(t, 0.5)
+		ctx := createTestContext(nil, nil, 60, 3)
+		ctx.SocialContext = models.SocialContextAtWork
+
+		scored, err := engine.ScoreTasks(ctx, tasks)
+		require.NoError(t, err)
+		assert.Equal(t, "Write report", scored[0].Task.Title)
+	})
+
+	t.Run("StaleLocationIsIgnored", func(t *testing.T) {
+		engine := setup(t, 0.5)
+		lat, lng := gym.Latitude, gym.Longitude
+		ctx := createTestContext(&lat, &lng, 60, 3)
+		ctx.Timestamp = ctx.Timestamp.Add(-models.DefaultContextMaxAge * 2)
+
+		scored, err := engine.ScoreTasks(ctx, tasks)
+		require.NoError(t, err)
+		for _, task := range scored {
+			assert.NotContains(t, task.Breakdown, filters.ScoreCategory)
+		}
+	})
+
+	t.Run("OffByDefault", func(t *testing.T) {
+		engine := setup(t, 0)
+		lat, lng := gym.Latitude, gym.Longitude
+		ctx := createTestContext(&lat, &lng, 60, 3)
+
+		scored, err := engine.ScoreTasks(ctx, tasks)
+		require.NoError(t, err)
+		for _, task := range scored {
+			assert.NotContains(t, task.Breakdown, filters.ScoreCategory)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationDefaults_ResolveRadius(t *testing.T) {
+	defaults := models.DefaultLocationDefaults()
+
+	t.Run("CategoryDefaultUsedWithoutRadius", func(t *testing.T) {
+		assert.Equal(t, 200, defaults.ResolveRadius(string(models.LocationCategoryErrand), nil))
+		assert.Equal(t, 150, defaults.ResolveRadius(string(models.LocationCategoryGym), nil))
+		assert.Equal(t, models.DefaultLocationRadius, defaults.ResolveRadius(string(models.LocationCategoryHome), nil))
+	})
+
+	t.Run("CategoryLookupIgnoresCaseAndSpace", func(t *testing.T) {
+		assert.Equal(t, 200, defaults.ResolveRadius(" Errand ", nil))
+	})
+
+	t.Run("DefaultsCoverOnlyKnownCategories", func(t *testing.T) {
+		for category := range defaults.Categories {
+			assert.True(t, models.LocationCategory(category).IsKnown(), category)
+		}
+	})
+
+	t.Run("NoCategoryIsOther", func(t *testing.T) {
+		custom := models.LocationDefaults{Categories: map[string]models.CategoryRadius{
+			string(models.LocationCategoryOther): {Radius: 80},
+		}}
+		assert.Equal(t, 80, custom.ResolveRadius("", nil))
+	})
+
+	t.Run("ExplicitRadiusOverridesCategory", func(t *testing.T) {
+		explicit := 75
+		assert.Equal(t, 75, defaults.ResolveRadius(string(models.LocationCategoryErrand), &explicit))
+	})
+
+	t.Run("UnknownCategoryUsesGlobalDefault", func(t *testing.T) {
+		assert.Equal(t, models.DefaultLocationRadius, defaults.ResolveRadius("observatory", nil))
+
+		custom := models.LocationDefaults{Radius: 250}
+		assert.Equal(t, 250, custom.ResolveRadius("observatory", nil))
+	})
+
+	t.Run("ZeroValueFallsBackToBuiltInDefault", func(t *testing.T) {
+		var empty models.LocationDefaults
+		assert.Equal(t, models.DefaultLocationRadius, empty.ResolveRadius(string(models.LocationCategoryErrand), nil))
+	})
+
+	t.Run("CategoryUnitsConvertToMeters", func(t *testing.T) {
+		custom := models.LocationDefaults{
+			Radius: 100,
+			Categories: map[string]models.CategoryRadius{
+				"campus": {Radius: 1.5, Unit: "km"},
+				"desk":   {Radius: 30, Unit: "ft"},
+				"broken": {Radius: 5, Unit: "furlongs"},
+			},
+		}
+		assert.Equal(t, 1500, custom.ResolveRadius("campus", nil))
+		assert.Equal(t, 9, custom.ResolveRadius("desk", nil))
+		assert.Equal(t, 100, custom.ResolveRadius("broken", nil))
+		assert.Error(t, custom.Validate())
+	})
+
+	t.Run("CategoryHours", func(t *testing.T) {
+		custom := models.LocationDefaults{
+			Categories: map[string]models.CategoryRadius{
+				"hardware": {Hours: "Mon-Sat 08:00-21:00"},
+			},
+		}
+		assert.NoError(t, custom.Validate(), "hours alone keep the default radius")
+		assert.Equal(t, models.DefaultLocationRadius, custom.ResolveRadius("hardware", nil))
+		assert.Equal(t, "Mon-Sat 08:00-21:00", custom.HoursFor("Hardware").String())
+		assert.Nil(t, custom.HoursFor(string(models.LocationCategoryErrand)))
+
+		custom.Categories["bar"] = models.CategoryRadius{Radius: 50, Hours: "Fri 18:00"}
+		assert.Error(t, custom.Validate())
+	})
+
+	t.Run("BuiltInDefaultsAreValid", func(t *testing.T) {
+		assert.NoError(t, defaults.Validate())
+	})
+}
@@ -0,0 +1,190 @@
+package unit
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockGeofenceLocationRepository adds the nearby lookup the context service
+// needs to the filter tests' location repository
+type MockGeofenceLocationRepository struct {
+	*MockLocationRepository
+}
+
+func (m *MockGeofenceLocationRepository) FindNearby(latitude, longitude float64, radiusMeters int) ([]models.Location, error) {
+	var nearby []models.Location
+	for _, location := range m.locations {
+		if location.DistanceFrom(latitude, longitude) <= float64(radiusMeters) {
+			nearby = append(nearby, *location)
+		}
+	}
+	return nearby, nil
+}
+
+// metersNorthOf returns coordinates the given distance due north of location
+func metersNorthOf(location *models.Location, meters float64) (float64, float64) {
+	metersPerDegree := models.EarthRadiusMeters * math.Pi / 180
+	return location.Latitude + meters/metersPerDegree, location.Longitude
+}
+
+func TestContextService_LocationReminders(t *testing.T) {
+	home := createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+
+	setup := func(t *testing.T) (*hereandnow.ContextService, *MockNotificationRepository, models.Task, models.Task) {
+		locations := &MockGeofenceLocationRepository{NewMockLocationRepository()}
+		locations.AddLocation(home)
+
+		tasks := NewMockServiceTaskRepository()
+		trash := createTestTask("Take out the trash", nil, 3)
+		plants := createTestTask("Water plants", nil, 3)
+		require.NoError(t, tasks.Create(trash))
+		require.NoError(t, tasks.Create(plants))
+
+		taskLocations := NewMockTaskLocationRepository()
+		taskLocations.SetTaskLocations(trash.ID, []models.Location{*home})
+		taskLocations.SetLocationTrigger(trash.ID, home.ID, models.LocationTriggerExit)
+		taskLocations.SetTaskLocations(plants.ID, []models.Location{*home})
+
+		notifications := &MockNotificationRepository{}
+		service := hereandnow.NewContextService(&MockHistoryContextRepository{}, locations, nil, nil, nil)
+		service.EnableLocationReminders(taskLocations, tasks, notifications)
+		return service, notifications, trash, plants
+	}
+
+	moveTo := func(t *testing.T, service *hereandnow.ContextService, meters float64) {
+		lat, lng := metersNorthOf(home, meters)
+		_, err := service.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			Latitude:         &lat,
+			Longitude:        &lng,
+			AvailableMinutes: 60,
+			EnergyLevel:      3,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("EnterTaskNotifiesOnArriving", func(t *testing.T) {
+		service, notifications, _, plants := setup(t)
+
+		moveTo(t, service, 5000)
+		assert.Empty(t, notifications.notifications)
+
+		moveTo(t, service, 20)
+		require.Len(t, notifications.notifications, 1)
+		notification := notifications.notifications[0]
+		assert.Equal(t, models.NotificationTypeLocationReminder, notification.Type)
+		assert.Equal(t, "test-user-id", notification.UserID)
+		assert.Equal(t, plants.ID, *notification.TaskID)
+		assert.Contains(t, notification.Message, "Home")
+	})
+
+	t.Run("ExitTaskNotifiesOnLeaving", func(t *testing.T) {
+		service, notifications, trash, _ := setup(t)
+
+		moveTo(t, service, 20)
+		notifications.notifications = nil
+
+		// Moving around inside the location is not a departure
+		moveTo(t, service, 60)
+		assert.Empty(t, notifications.notifications)
+
+		moveTo(t, service, 500)
+		require.Len(t, notifications.notifications, 1)
+		notification := notifications.notifications[0]
+		assert.Equal(t, models.NotificationTypeLocationReminder, notification.Type)
+		assert.Equal(t, trash.ID, *notification.TaskID)
+		assert.Contains(t, notification.Message, "You left Home")
+	})
+
+	t.Run("SkipsCompletedTasks", func(t *testing.T) {
+		locations := &MockGeofenceLocationRepository{NewMockLocationRepository()}
+		locations.AddLocation(home)
+
+		tasks := NewMockServiceTaskRepository()
+		done := createTestTask("Already done", nil, 3)
+		done.Status = models.TaskStatusCompleted
+		require.NoError(t, tasks.Create(done))
+
+		taskLocations := NewMockTaskLocationRepository()
+		taskLocations.SetTaskLocations(done.ID, []models.Location{*home})
+
+		notifications := &MockNotificationRepository{}
+		service := hereandnow.NewContextService(&MockHistoryContextRepository{}, locations, nil, nil, nil)
+		service.EnableLocationReminders(taskLocations, tasks, notifications)
+
+		moveTo(t, service, 20)
+		assert.Empty(t, notifications.notifications)
+	})
+
+	t.Run("NotificationFailureDoesNotFailUpdate", func(t *testing.T) {
+		service, notifications, _, _ := setup(t)
+		notifications.fail = true
+
+		moveTo(t, service, 20)
+	})
+}
+
+func TestLocationFilter_ExitTrigger(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	config.LocationGraceMeters = 25
+	taskLocationRepo := NewMockTaskLocationRepository()
+	filter := filters.NewLocationFilter(config, NewMockLocationRepository(), taskLocationRepo)
+
+	home := createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	task := createTestTask("Take out the trash", nil, 3)
+	taskLocationRepo.SetTaskLocations(task.ID, []models.Location{*home})
+	taskLocationRepo.SetLocationTrigger(task.ID, home.ID, models.LocationTriggerExit)
+
+	contextAt := func(meters float64) models.Context {
+		lat, lng := metersNorthOf(home, meters)
+		return createTestContext(&lat, &lng, 60, 3)
+	}
+
+	t.Run("VisibleWhileStillThere", func(t *testing.T) {
+		visible, reason := filter.Apply(contextAt(50), task)
+
+		assert.True(t, visible)
+		assert.Equal(t, "still at Home - do before leaving", reason)
+	})
+
+	t.Run("HiddenOnceLeftEvenWithinGrace", func(t *testing.T) {
+		visible, _ := filter.Apply(contextAt(108), task)
+
+		assert.False(t, visible)
+	})
+
+	t.Run("HiddenWhenAway", func(t *testing.T) {
+		visible, _ := filter.Apply(contextAt(5000), task)
+
+		assert.False(t, visible)
+	})
+
+	t.Run("EnterTaskKeepsGrace", func(t *testing.T) {
+		other := createTestTask("Water plants", nil, 3)
+		taskLocationRepo.SetTaskLocations(other.ID, []models.Location{*home})
+
+		visible, reason := filter.Apply(contextAt(108), other)
+
+		assert.True(t, visible)
+		assert.Equal(t, "just outside Home, 8m over", reason)
+	})
+}
+
+func TestTaskLocation_Trigger(t *testing.T) {
+	taskLocation, err := models.NewTaskLocation("task-id", "location-id", true)
+	require.NoError(t, err)
+	assert.Equal(t, models.LocationTriggerEnter, taskLocation.Trigger)
+	assert.False(t, taskLocation.IsExitTrigger())
+
+	taskLocation.Trigger = models.LocationTriggerExit
+	assert.True(t, taskLocation.IsExitTrigger())
+	assert.NoError(t, taskLocation.Validate())
+
+	taskLocation.Trigger = "sometimes"
+	assert.Error(t, taskLocation.Validate())
+}
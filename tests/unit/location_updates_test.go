@@ -0,0 +1,327 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// About 22 and 111 meters of latitude
+const (
+	smallMove = 0.0002
+	largeMove = 0.001
+)
+
+func newLocationUpdateService(t *testing.T) (*memstore.Store, *hereandnow.ContextService, *clock.Fake) {
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	office := *createTestLocation("office-id", "Office", 37.7849, -122.4094, "test-user-id")
+	store := memstore.New(memstore.WithLocations(home, office))
+	_, contextService := newMemstoreServices(store)
+
+	fake := clock.NewFake(time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC))
+	contextService.SetClock(fake)
+
+	min := 2
+	_, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+		AvailableMinutes: 45,
+		EnergyLevel:      2,
+		SocialContext:    models.SocialContextWithFamily,
+		MinPriority:      &min,
+	})
+	require.NoError(t, err)
+
+	return store, contextService, fake
+}
+
+func TestContextService_RecordLocation(t *testing.T) {
+	t.Run("KeepsTheRestOfTheContext", func(t *testing.T) {
+		_, service, fake := newLocationUpdateService(t)
+		fake.Advance(time.Minute)
+
+		context, recorded, err := service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7749, Longitude: -122.4194})
+		require.NoError(t, err)
+
+		assert.True(t, recorded)
+		require.NotNil(t, context.CurrentLocationID)
+		assert.Equal(t, "home-id", *context.CurrentLocationID)
+		assert.Equal(t, 45, context.AvailableMinutes)
+		assert.Equal(t, 2, context.EnergyLevel)
+		assert.Equal(t, models.SocialContextWithFamily, context.SocialContext)
+		assert.Equal(t, 2, context.MinPriority)
+		assert.Equal(t, fake.Now(), context.Timestamp)
+	})
+
+	t.Run("RateLimitsSmallMoves", func(t *testing.T) {
+		_, service, fake := newLocationUpdateService(t)
+		first, _, err := service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7749, Longitude: -122.4194})
+		require.NoError(t, err)
+
+		fake.Advance(30 * time.Second)
+		latest, recorded, err := service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7749 + smallMove, Longitude: -122.4194})
+		require.NoError(t, err)
+		assert.False(t, recorded)
+		assert.Equal(t, first.ID, latest.ID)
+
+		// Inside its accuracy, a larger move may be jitter
+		_, recorded, err = service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7749 + largeMove, Longitude: -122.4194, AccuracyMeters: 500})
+		require.NoError(t, err)
+		assert.False(t, recorded)
+
+		_, recorded, err = service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7749 + largeMove, Longitude: -122.4194})
+		require.NoError(t, err)
+		assert.True(t, recorded)
+
+		fake.Advance(hereandnow.LocationSnapshotInterval)
+		_, recorded, err = service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7749 + largeMove, Longitude: -122.4194})
+		require.NoError(t, err)
+		assert.True(t, recorded)
+	})
+
+	t.Run("ResolvesLocationAsTheUserMoves", func(t *testing.T) {
+		_, service, fake := newLocationUpdateService(t)
+
+		fake.Advance(hereandnow.LocationSnapshotInterval)
+		context, _, err := service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.7849, Longitude: -122.4094})
+		require.NoError(t, err)
+		assert.Equal(t, "office-id", *context.CurrentLocationID)
+
+		fake.Advance(hereandnow.LocationSnapshotInterval)
+		context, _, err = service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 37.80, Longitude: -122.40})
+		require.NoError(t, err)
+		assert.Nil(t, context.CurrentLocationID)
+	})
+
+	t.Run("IgnoresQueuedFixOlderThanLatest", func(t *testing.T) {
+		_, service, fake := newLocationUpdateService(t)
+		fake.Advance(time.Hour)
+
+		_, recorded, err := service.RecordLocation("test-user-id", hereandnow.LocationFix{
+			Latitude:   37.7849,
+			Longitude:  -122.4094,
+			RecordedAt: fake.Now().Add(-2 * time.Hour),
+		})
+		require.NoError(t, err)
+		assert.False(t, recorded)
+	})
+
+	t.Run("RejectsInvalidCoordinates", func(t *testing.T) {
+		_, service, _ := newLocationUpdateService(t)
+
+		_, _, err := service.RecordLocation("test-user-id", hereandnow.LocationFix{Latitude: 91, Longitude: 0})
+		assert.Error(t, err)
+	})
+}
+
+func newLocationWebhookRouter(service *hereandnow.ContextService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	contexts := api.NewContextHandler(service)
+	contexts.SetLocationRecorder(service)
+	api.SetupRoutes(router, api.Handlers{
+		Contexts: contexts,
+		AuthMiddleware: func(c *gin.Context) {
+			c.Set("user", &models.User{ID: "test-user-id"})
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{})
+
+	return router
+}
+
+func TestContextHandler_RecordLocation(t *testing.T) {
+	t.Run("OwnTracksLocation", func(t *testing.T) {
+		store, service, fake := newLocationUpdateService(t)
+		router := newLocationWebhookRouter(service)
+		fake.Advance(hereandnow.LocationSnapshotInterval)
+
+		body := fmt.Sprintf(`{"_type":"location","lat":37.7849,"lon":-122.4094,"acc":10,"tst":%d,"tid":"ph"}`, fake.Now().Unix())
+		w := serveRequest(router, http.MethodPost, "/api/v1/context/location", body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `[]`, w.Body.String())
+
+		latest, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+		require.NotNil(t, latest.CurrentLocationID)
+		assert.Equal(t, "office-id", *latest.CurrentLocationID)
+	})
+
+	t.Run("OwnTracksOtherMessagesIgnored", func(t *testing.T) {
+		store, service, _ := newLocationUpdateService(t)
+		router := newLocationWebhookRouter(service)
+		before, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/context/location", `{"_type":"lwt","tst":1717405200}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+
+		after, err := store.Contexts().GetLatestByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, before.ID, after.ID)
+	})
+
+	t.Run("PlainFix", func(t *testing.T) {
+		_, service, fake := newLocationUpdateService(t)
+		router := newLocationWebhookRouter(service)
+		fake.Advance(hereandnow.LocationSnapshotInterval)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/context/location", `{"lat":37.7749,"lng":-122.4194,"accuracy":8}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response api.LocationUpdateResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Recorded)
+		assert.Equal(t, "home-id", *response.Context.CurrentLocationID)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/context/location", `{"lat":37.7749,"lng":-122.4194,"accuracy":8}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.False(t, response.Recorded)
+	})
+
+	t.Run("RejectsMissingOrInvalidCoordinates", func(t *testing.T) {
+		_, service, _ := newLocationUpdateService(t)
+		router := newLocationWebhookRouter(service)
+
+		for _, body := range []string{`{"lat":37.7749}`, `{"lat":95,"lng":0}`, `{"lat":1,"lng":1,"accuracy":-1}`} {
+			w := serveRequest(router, http.MethodPost, "/api/v1/context/location", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+}
+
+// authUserRepository serves users to the auth service from memory
+type authUserRepository struct {
+	users map[string]models.User
+}
+
+func (r *authUserRepository) Create(user models.User) error {
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *authUserRepository) GetByID(userID string) (*models.User, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("user not found: %s", userID)
+	}
+	return &user, nil
+}
+
+func (r *authUserRepository) GetByEmail(email string) (*models.User, error) {
+	return nil, fmt.Errorf("user not found: %s", email)
+}
+
+func (r *authUserRepository) Update(user models.User) error {
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *authUserRepository) UpdatePassword(userID string, hashedPassword string) error {
+	return nil
+}
+
+func setupDeviceTokenDB(t *testing.T) *storage.DB {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE device_tokens (
+			id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE, created_at DATETIME NOT NULL, last_used_at DATETIME
+		);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestDeviceTokens(t *testing.T) {
+	users := &authUserRepository{users: map[string]models.User{
+		"test-user-id": {ID: "test-user-id", PasswordHash: "secret"},
+	}}
+	authService := auth.NewAuthService(users, nil, nil, auth.DefaultAuthConfig)
+	deviceTokens := storage.NewDeviceTokenRepository(setupDeviceTokenDB(t))
+	authService.EnableDeviceTokens(deviceTokens)
+
+	token, device, err := authService.CreateDeviceToken("test-user-id", "Pixel")
+	require.NoError(t, err)
+	assert.True(t, auth.IsDeviceToken(token))
+	assert.NotContains(t, device.TokenHash, token)
+
+	t.Run("ValidatesAndRecordsUse", func(t *testing.T) {
+		user, err := authService.ValidateDeviceToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "test-user-id", user.ID)
+		assert.Empty(t, user.PasswordHash)
+
+		devices, err := authService.ListDeviceTokens("test-user-id")
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assert.Equal(t, "Pixel", devices[0].Name)
+		assert.NotNil(t, devices[0].LastUsedAt)
+
+		_, err = authService.ValidateDeviceToken(token + "x")
+		assert.Error(t, err)
+	})
+
+	t.Run("RequiresName", func(t *testing.T) {
+		_, _, err := authService.CreateDeviceToken("test-user-id", "  ")
+		assert.Error(t, err)
+	})
+
+	t.Run("AuthenticatesWebhook", func(t *testing.T) {
+		_, service, fake := newLocationUpdateService(t)
+		fake.Advance(hereandnow.LocationSnapshotInterval)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		contexts := api.NewContextHandler(service)
+		contexts.SetLocationRecorder(service)
+		api.SetupRoutes(router, api.Handlers{
+			Auth:     api.NewAuthHandler(authService),
+			Contexts: contexts,
+		}, api.RouteConfig{})
+
+		post := func(setAuth func(req *http.Request), query string) int {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/context/location"+query, strings.NewReader(`{"lat":37.7749,"lng":-122.4194}`))
+			req.Header.Set("Content-Type", "application/json")
+			setAuth(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, post(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }, ""))
+		assert.Equal(t, http.StatusOK, post(func(req *http.Request) { req.SetBasicAuth("pixel", token) }, ""))
+		assert.Equal(t, http.StatusOK, post(func(req *http.Request) {}, "?token="+token))
+		assert.Equal(t, http.StatusUnauthorized, post(func(req *http.Request) {}, ""))
+		assert.Equal(t, http.StatusUnauthorized, post(func(req *http.Request) { req.SetBasicAuth("pixel", "hnd_wrong") }, ""))
+	})
+
+	t.Run("RevokedTokenRejected", func(t *testing.T) {
+		require.NoError(t, authService.RevokeDeviceToken("test-user-id", device.ID))
+
+		_, err := authService.ValidateDeviceToken(token)
+		assert.Error(t, err)
+		assert.Error(t, authService.RevokeDeviceToken("test-user-id", device.ID))
+	})
+}
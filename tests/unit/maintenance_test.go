@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingJobs records how often each job ran
+type countingJobs struct {
+	mu   sync.Mutex
+	runs map[string]int
+}
+
+func (c *countingJobs) job(name string, run func() (string, error)) maintenance.Job {
+	return maintenance.Job{
+		Name: name,
+		Run: func(now time.Time) (string, error) {
+			c.mu.Lock()
+			c.runs[name]++
+			c.mu.Unlock()
+			return run()
+		},
+	}
+}
+
+func (c *countingJobs) count(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.runs[name]
+}
+
+func TestMaintenanceLoop(t *testing.T) {
+	ok := func() (string, error) { return "done", nil }
+
+	newLoop := func(interval time.Duration, jobs ...maintenance.Job) (*maintenance.Loop, *bytes.Buffer) {
+		var logs bytes.Buffer
+		loop := maintenance.NewLoop(interval, jobs...)
+		loop.SetLogger(log.New(&logs, "", 0))
+		return loop, &logs
+	}
+
+	t.Run("RunsEveryEnabledJob", func(t *testing.T) {
+		counter := &countingJobs{runs: map[string]int{}}
+		config := maintenance.Config{Jobs: map[string]maintenance.JobConfig{
+			maintenance.JobPruneSessions: {Disabled: true},
+		}}
+
+		jobs := config.Select(
+			counter.job(maintenance.JobPruneContexts, ok),
+			counter.job(maintenance.JobPruneSessions, ok),
+			counter.job(maintenance.JobExpireCompletionUndos, ok),
+		)
+		loop, logs := newLoop(time.Hour, jobs...)
+
+		results := loop.RunOnce()
+		require.Len(t, results, 2)
+		assert.Equal(t, 1, counter.count(maintenance.JobPruneContexts))
+		assert.Equal(t, 0, counter.count(maintenance.JobPruneSessions))
+		assert.Equal(t, 1, counter.count(maintenance.JobExpireCompletionUndos))
+		assert.Equal(t, []string{maintenance.JobPruneContexts, maintenance.JobExpireCompletionUndos}, loop.Jobs())
+		assert.Contains(t, logs.String(), "Maintenance job prune_contexts: done")
+	})
+
+	t.Run("PanickingJobDoesNotStopLoop", func(t *testing.T) {
+		counter := &countingJobs{runs: map[string]int{}}
+		loop, logs := newLoop(time.Millisecond,
+			counter.job("broken", func() (string, error) { panic("boom") }),
+			counter.job("failing", func() (string, error) { return "", errors.New("disk full") }),
+			counter.job("healthy", ok),
+		)
+
+		results := loop.RunOnce()
+		require.Len(t, results, 3)
+		assert.EqualError(t, results[0].Err, "panic: boom")
+		assert.EqualError(t, results[1].Err, "disk full")
+		assert.NoError(t, results[2].Err)
+		assert.Contains(t, logs.String(), "Maintenance job broken failed: panic: boom")
+
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			loop.Run(stop)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool { return counter.count("healthy") >= 4 }, time.Second, time.Millisecond)
+		close(stop)
+		<-done
+		assert.GreaterOrEqual(t, counter.count("broken"), 4)
+	})
+
+	t.Run("IntervalFromConfig", func(t *testing.T) {
+		interval, err := maintenance.Config{}.IntervalDuration()
+		require.NoError(t, err)
+		assert.Equal(t, maintenance.DefaultInterval, interval)
+
+		interval, err = maintenance.Config{Interval: "15m"}.IntervalDuration()
+		require.NoError(t, err)
+		assert.Equal(t, 15*time.Minute, interval)
+
+		assert.Error(t, maintenance.Config{Interval: "0s"}.Validate())
+		assert.Error(t, maintenance.Config{Interval: "soon"}.Validate())
+		assert.Error(t, maintenance.Config{Jobs: map[string]maintenance.JobConfig{"vacuum": {}}}.Validate())
+		assert.Error(t, maintenance.Config{Jobs: map[string]maintenance.JobConfig{
+			maintenance.JobPruneContexts: {RetentionDays: -1},
+		}}.Validate())
+
+		config := maintenance.Config{Jobs: map[string]maintenance.JobConfig{
+			maintenance.JobPruneContexts: {RetentionDays: 7},
+		}}
+		assert.Equal(t, 7*24*time.Hour, config.Retention(maintenance.JobPruneContexts, maintenance.DefaultContextRetention))
+		assert.Equal(t, maintenance.DefaultContextRetention, maintenance.Config{}.Retention(maintenance.JobPruneContexts, maintenance.DefaultContextRetention))
+	})
+
+	t.Run("ExpiresCompletionUndosIdempotently", func(t *testing.T) {
+		store := memstore.New()
+		completedAt := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+		for _, task := range []models.Task{createTestTask("Buy milk", nil, 3), createTestTask("Buy eggs", nil, 3)} {
+			undo, err := models.NewCompletionUndo("test-user-id", task, nil, completedAt, 2*time.Minute)
+			require.NoError(t, err)
+			require.NoError(t, store.CompletionUndos().Save(*undo))
+		}
+
+		loop, logs := newLoop(time.Hour, maintenance.ExpireCompletionUndosJob(store.CompletionUndos()))
+		loop.SetClock(clock.NewFake(completedAt.Add(time.Minute)))
+		assert.NoError(t, loop.RunOnce()[0].Err)
+		assert.Contains(t, logs.String(), "removed 0 expired completion undo(s)")
+
+		loop.SetClock(clock.NewFake(completedAt.Add(5 * time.Minute)))
+		assert.Equal(t, "removed 2 expired completion undo(s)", loop.RunOnce()[0].Summary)
+		assert.Equal(t, "removed 0 expired completion undo(s)", loop.RunOnce()[0].Summary)
+	})
+}
@@ -0,0 +1,200 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMemstoreServices wires the services to an in-memory store the way an
+// embedding application would, with no database
+func newMemstoreServices(store *memstore.Store) (*hereandnow.TaskService, *hereandnow.ContextService) {
+	engine := filters.NewEngine(filters.DefaultFilterConfig, store.FilterAudits())
+	engine.AddRule(filters.NewLocationFilter(filters.DefaultFilterConfig, store.Locations(), store.TaskLocations()))
+	engine.AddRule(filters.NewDependencyFilter(filters.DefaultFilterConfig, store.Dependencies(), store.Tasks()))
+
+	taskService := hereandnow.NewTaskService(store.Tasks(), store.Contexts(), store.Dependencies(), store.TaskLocations(), engine)
+	taskService.SetTransactor(store)
+	taskService.SetUserRepository(store.Users())
+	taskService.SetNotificationRepository(store.Notifications())
+
+	contextService := hereandnow.NewContextService(store.Contexts(), store.Locations(), store.CalendarEvents(), nil, nil)
+	contextService.EnableLocationReminders(store.TaskLocations(), store.Tasks(), store.Notifications())
+
+	return taskService, contextService
+}
+
+func memstoreTaskRequest(title string) hereandnow.CreateTaskRequest {
+	return hereandnow.CreateTaskRequest{Title: title, Priority: 3, Metadata: json.RawMessage(`{}`)}
+}
+
+func TestMemstore_TaskService(t *testing.T) {
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+
+	t.Run("CreatesTaskWithLocationsAndDependencies", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(home))
+		service, _ := newMemstoreServices(store)
+
+		draft, err := service.CreateTask("test-user-id", memstoreTaskRequest("Draft report"))
+		require.NoError(t, err)
+
+		req := memstoreTaskRequest("Print report")
+		req.LocationIDs = []string{home.ID}
+		req.Dependencies = []hereandnow.TaskDependencyRequest{{DependsOnTaskID: draft.ID, DependencyType: models.DependencyTypeBlocking}}
+		task, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		locations, err := store.TaskLocations().GetLocationsByTaskID(task.ID)
+		require.NoError(t, err)
+		require.Len(t, locations, 1)
+		assert.Equal(t, "Home", locations[0].Name)
+
+		dependents, err := store.Dependencies().GetDependentsByTaskID(draft.ID)
+		require.NoError(t, err)
+		require.Len(t, dependents, 1)
+		assert.Equal(t, task.ID, dependents[0].TaskID)
+	})
+
+	t.Run("RollsBackFailedCreate", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+
+		req := memstoreTaskRequest("Print report")
+		req.LocationIDs = []string{"no-such-location"}
+		_, err := service.CreateTask("test-user-id", req)
+		require.Error(t, err)
+
+		tasks, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+	})
+
+	t.Run("ReordersListTasks", func(t *testing.T) {
+		store := memstore.New(memstore.WithLists(models.TaskList{ID: "list-1", Name: "Chores", OwnerID: "test-user-id"}))
+		service, _ := newMemstoreServices(store)
+		service.SetListRepository(store.TaskLists())
+		ids := createListTasks(t, service, "list-1", "A", "B", "C")
+
+		_, err := service.ReorderTask(ids[2], ids[0], "test-user-id")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"A", "C", "B"}, listTitles(t, service, "list-1"))
+	})
+
+	t.Run("SearchesTitleAndDescription", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		req := memstoreTaskRequest("Call bank")
+		req.Description = "Ask about the mortgage rate"
+		_, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+		_, err = service.CreateTask("test-user-id", memstoreTaskRequest("Buy milk"))
+		require.NoError(t, err)
+
+		results, err := service.SearchTasks("test-user-id", "MORTGAGE")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Call bank", results[0].Title)
+	})
+
+	t.Run("FiltersByContext", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(home))
+		taskService, contextService := newMemstoreServices(store)
+
+		draft, err := taskService.CreateTask("test-user-id", memstoreTaskRequest("Draft report"))
+		require.NoError(t, err)
+
+		atHome := memstoreTaskRequest("Water plants")
+		atHome.LocationIDs = []string{home.ID}
+		_, err = taskService.CreateTask("test-user-id", atHome)
+		require.NoError(t, err)
+
+		blocked := memstoreTaskRequest("Send report")
+		blocked.Dependencies = []hereandnow.TaskDependencyRequest{{DependsOnTaskID: draft.ID, DependencyType: models.DependencyTypeBlocking}}
+		_, err = taskService.CreateTask("test-user-id", blocked)
+		require.NoError(t, err)
+
+		// Far from home
+		lat, lng := 37.8000, -122.5000
+		_, err = contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			Latitude: &lat, Longitude: &lng, AvailableMinutes: 60, EnergyLevel: 3,
+		})
+		require.NoError(t, err)
+
+		visible, results, err := taskService.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Len(t, results, 6)
+		require.Len(t, visible, 1)
+		assert.Equal(t, "Draft report", visible[0].Title)
+
+		audits, err := store.FilterAudits().GetAuditLogByUserID("test-user-id", time.Time{}, 0)
+		require.NoError(t, err)
+		assert.NotEmpty(t, audits)
+	})
+
+	t.Run("NotifiesOnArrival", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(home))
+		taskService, contextService := newMemstoreServices(store)
+
+		req := memstoreTaskRequest("Water plants")
+		req.LocationIDs = []string{home.ID}
+		_, err := taskService.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		_, err = contextService.CreateContextFromLocation("test-user-id", home.Latitude, home.Longitude)
+		require.NoError(t, err)
+
+		notifications, err := store.Notifications().GetByUserID("test-user-id", true)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, models.NotificationTypeLocationReminder, notifications[0].Type)
+	})
+
+	t.Run("ResolvesSnoozeInUserTimezone", func(t *testing.T) {
+		store := memstore.New(memstore.WithUsers(models.User{ID: "test-user-id", Username: "test", TimeZone: "Asia/Tokyo"}))
+		service, _ := newMemstoreServices(store)
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+
+		snoozed, err := service.SnoozeTaskWithPreset(task.ID, "test-user-id", "tomorrow-morning", false)
+		require.NoError(t, err)
+
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+		assert.Equal(t, 9, snoozed.SnoozedUntil.In(tokyo).Hour())
+	})
+}
+
+func TestMemstore_ConcurrentAccess(t *testing.T) {
+	store := memstore.New()
+	service, _ := newMemstoreServices(store)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			task, err := service.CreateTask("test-user-id", memstoreTaskRequest(fmt.Sprintf("Task %d", i)))
+			if assert.NoError(t, err) {
+				_, err = service.CompleteTask(task.ID, "test-user-id")
+				assert.NoError(t, err)
+			}
+			_, err = service.GetTasksByStatus("test-user-id", models.TaskStatusCompleted)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	completed, err := store.Tasks().GetByStatus("test-user-id", models.TaskStatusCompleted)
+	require.NoError(t, err)
+	assert.Len(t, completed, 20)
+}
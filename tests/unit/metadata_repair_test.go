@@ -0,0 +1,99 @@
+package unit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMetadataDB creates the subset of the schema touched by metadata scans
+func setupMetadataDB(t *testing.T) *storage.DB {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE tasks (
+			id TEXT PRIMARY KEY, title TEXT, description TEXT, creator_id TEXT,
+			assignee_id TEXT, list_id TEXT, status TEXT, priority INTEGER,
+			estimated_minutes INTEGER, effort_points INTEGER, required_energy_level INTEGER, due_at DATETIME, completed_at DATETIME,
+			created_at DATETIME, updated_at DATETIME, metadata TEXT,
+			recurrence_rule TEXT, parent_task_id TEXT, position REAL NOT NULL DEFAULT 0,
+			snoozed_until DATETIME, recurring_snooze TEXT, deleted_at DATETIME,
+			version INTEGER NOT NULL DEFAULT 1
+		);
+		CREATE TABLE task_tags (task_id TEXT, tag TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (task_id, tag));
+		CREATE TABLE locations (id TEXT PRIMARY KEY, metadata TEXT);
+		CREATE TABLE contexts (id TEXT PRIMARY KEY, metadata TEXT);
+		CREATE TABLE users (id TEXT PRIMARY KEY, settings TEXT);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func insertTaskWithMetadata(t *testing.T, db *storage.DB, id, metadata string) {
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO tasks (id, title, description, creator_id, status, priority, created_at, updated_at, metadata)
+		VALUES (?, 'Task', '', 'user-1', 'pending', 3, ?, ?, ?)`, id, now, now, metadata)
+	require.NoError(t, err)
+}
+
+func TestCorruptMetadata(t *testing.T) {
+	t.Run("CorruptRowReadsAsEmpty", func(t *testing.T) {
+		db := setupMetadataDB(t)
+		insertTaskWithMetadata(t, db, "bad", `{"tags": [1, 2`)
+		insertTaskWithMetadata(t, db, "good", `{"tags": [1, 2]}`)
+
+		repo := storage.NewTaskRepository(db)
+
+		task, err := repo.GetByID("bad")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{}`, string(task.Metadata))
+
+		tasks, err := repo.Search(storage.TaskSearchOptions{})
+		require.NoError(t, err)
+		require.Len(t, tasks, 2)
+		for _, task := range tasks {
+			assert.True(t, json.Valid(task.Metadata), "task %s metadata should be valid", task.ID)
+		}
+
+		good, err := repo.GetByID("good")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"tags": [1, 2]}`, string(good.Metadata))
+	})
+
+	t.Run("DoctorFixResetsCorruptRows", func(t *testing.T) {
+		db := setupMetadataDB(t)
+		insertTaskWithMetadata(t, db, "bad", `not json`)
+		insertTaskWithMetadata(t, db, "good", `{}`)
+		_, err := db.Exec(`INSERT INTO users (id, settings) VALUES ('user-1', '{oops')`)
+		require.NoError(t, err)
+
+		repo := storage.NewMetadataRepository(db)
+
+		corrupt, err := repo.FindCorrupt()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []storage.CorruptMetadata{
+			{Table: "tasks", Column: "metadata", ID: "bad"},
+			{Table: "users", Column: "settings", ID: "user-1"},
+		}, corrupt)
+
+		repaired, err := repo.Repair(corrupt)
+		require.NoError(t, err)
+		assert.Equal(t, 2, repaired)
+
+		var raw string
+		require.NoError(t, db.QueryRow(`SELECT metadata FROM tasks WHERE id = 'bad'`).Scan(&raw))
+		assert.Equal(t, `{}`, raw)
+
+		corrupt, err = repo.FindCorrupt()
+		require.NoError(t, err)
+		assert.Empty(t, corrupt)
+	})
+}
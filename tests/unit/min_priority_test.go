@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMinPriorityServices(store *memstore.Store) (*hereandnow.TaskService, *hereandnow.ContextService) {
+	engine := filters.NewEngine(filters.DefaultFilterConfig, store.FilterAudits())
+	engine.AddRule(filters.NewDependencyFilter(filters.DefaultFilterConfig, store.Dependencies(), store.Tasks()))
+	engine.AddRule(filters.NewMinPriorityFilter())
+
+	taskService := hereandnow.NewTaskService(store.Tasks(), store.Contexts(), store.Dependencies(), store.TaskLocations(), engine)
+	contextService := hereandnow.NewContextService(store.Contexts(), store.Locations(), store.CalendarEvents(), nil, nil)
+	return taskService, contextService
+}
+
+func TestMinPriorityFilter(t *testing.T) {
+	filter := filters.NewMinPriorityFilter()
+	ctx := createTestContext(nil, nil, 60, 3)
+
+	t.Run("ShowsEverythingWhenUnset", func(t *testing.T) {
+		visible, code, _ := filter.Evaluate(ctx, createTestTask("Someday", nil, 1))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonMinPriorityUnset, code)
+	})
+
+	t.Run("HidesTasksBelowMinimum", func(t *testing.T) {
+		require.NoError(t, ctx.SetMinPriority(4))
+
+		visible, code, reason := filter.Evaluate(ctx, createTestTask("Routine", nil, 3))
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonMinPriorityBelow, code)
+		assert.Contains(t, reason, "below minimum 4")
+
+		for _, priority := range []int{4, 5} {
+			visible, code, _ = filter.Evaluate(ctx, createTestTask("Important", nil, priority))
+			assert.True(t, visible)
+			assert.Equal(t, filters.ReasonMinPriorityMet, code)
+		}
+	})
+
+	t.Run("RejectsOutOfRangeMinimum", func(t *testing.T) {
+		assert.Error(t, ctx.SetMinPriority(6))
+		assert.Error(t, ctx.ApplyChanges("min_priority=-1"))
+		require.NoError(t, ctx.ApplyChanges("min_priority=2"))
+		assert.Equal(t, 2, ctx.MinPriority)
+	})
+}
+
+func TestTaskService_MinPriority(t *testing.T) {
+	routine := createTestTask("Water plants", nil, 3)
+	urgent := createTestTask("Renew passport", nil, 4)
+	critical := createTestTask("File taxes", nil, 5)
+	paperwork := createTestTask("Collect paperwork", nil, 2)
+
+	newStore := func(t *testing.T) *memstore.Store {
+		store := memstore.New(memstore.WithTasks(routine, urgent, critical, paperwork))
+		dependency, err := models.NewTaskDependency(critical.ID, paperwork.ID, models.DependencyTypeBlocking)
+		require.NoError(t, err)
+		require.NoError(t, store.Dependencies().Create(*dependency))
+		require.NoError(t, store.Contexts().Create(createTestContext(nil, nil, 60, 3)))
+		return store
+	}
+
+	t.Run("DefaultsToShowingEverything", func(t *testing.T) {
+		service, _ := newMinPriorityServices(newStore(t))
+
+		tasks, _, err := service.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Water plants", "Renew passport", "Collect paperwork"}, taskTitles(tasks))
+	})
+
+	t.Run("OverrideHidesLowerPriorities", func(t *testing.T) {
+		service, _ := newMinPriorityServices(newStore(t))
+
+		tasks, _, err := service.GetFilteredTasksWithMinPriority("test-user-id", 4)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Renew passport"}, taskTitles(tasks))
+	})
+
+	t.Run("ComposesWithOtherFilters", func(t *testing.T) {
+		service, _ := newMinPriorityServices(newStore(t))
+
+		tasks, results, err := service.GetFilteredTasksWithMinPriority("test-user-id", 4)
+		require.NoError(t, err)
+		assert.NotContains(t, taskTitles(tasks), "File taxes", "priority 5 but still blocked by its dependency")
+
+		var reasons []filters.ReasonCode
+		for _, result := range results {
+			if result.TaskID == critical.ID {
+				reasons = append(reasons, result.Code)
+			}
+		}
+		assert.Contains(t, reasons, filters.ReasonMinPriorityMet)
+		assert.Contains(t, reasons, filters.ReasonDepPending)
+	})
+
+	t.Run("PersistsAcrossContextUpdates", func(t *testing.T) {
+		store := newStore(t)
+		service, contextService := newMinPriorityServices(store)
+
+		minPriority := 4
+		_, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			AvailableMinutes: 30,
+			SocialContext:    models.SocialContextAlone,
+			EnergyLevel:      3,
+			MinPriority:      &minPriority,
+		})
+		require.NoError(t, err)
+
+		later, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			AvailableMinutes: 45,
+			SocialContext:    models.SocialContextAlone,
+			EnergyLevel:      2,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 4, later.MinPriority)
+
+		tasks, _, err := service.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Renew passport"}, taskTitles(tasks))
+
+		tasks, _, err = service.GetFilteredTasksWithMinPriority("test-user-id", 0)
+		require.NoError(t, err)
+		assert.Len(t, tasks, 3, "an override of 0 shows everything again")
+	})
+}
+
+func taskTitles(tasks []models.Task) []string {
+	titles := []string{}
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
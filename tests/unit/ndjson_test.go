@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/ndjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSON_Write(t *testing.T) {
+	t.Run("OneValidObjectPerTask", func(t *testing.T) {
+		minutes := 30
+		tasks := []models.Task{
+			createTestTask("Buy milk", nil, 2),
+			createTestTask("Call <bank> & \"insurer\"", &minutes, 5),
+			createTestTask("Multi\nline title", nil, 4),
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, ndjson.Write(&buf, tasks))
+
+		output := buf.String()
+		assert.True(t, strings.HasSuffix(output, "\n"))
+
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		require.Len(t, lines, len(tasks))
+
+		for i, line := range lines {
+			var task models.Task
+			require.NoError(t, json.Unmarshal([]byte(line), &task), "line %d: %s", i, line)
+			assert.Equal(t, tasks[i].ID, task.ID)
+			assert.Equal(t, tasks[i].Title, task.Title)
+			assert.NotContains(t, line, "\n  ", "lines are compact")
+		}
+		assert.Contains(t, lines[1], `<bank> &`, "HTML characters are not escaped for jq")
+	})
+
+	t.Run("EmptyListWritesNothing", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, ndjson.Write(&buf, []models.Task{}))
+		assert.Empty(t, buf.String())
+
+		require.NoError(t, ndjson.Write[models.Task](&buf, nil))
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("EncoderWritesSingleLine", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, ndjson.NewEncoder(&buf).Encode(map[string]string{"type": "info"}))
+		assert.Equal(t, "{\"type\":\"info\"}\n", buf.String())
+	})
+
+	t.Run("EncodeErrorIsReported", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := ndjson.Write(&buf, []interface{}{make(chan int)})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,97 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/nlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTask(t *testing.T) {
+	grocery := *createTestLocation("grocery-id", "Grocery Store", 40.7260, -73.9897, "test-user-id")
+	office := *createTestLocation("office-id", "Office", 40.7580, -73.9855, "test-user-id")
+	home := *createTestLocation("home-id", "home", 40.7128, -74.0060, "test-user-id")
+	locations := []models.Location{grocery, office, home}
+
+	// Wednesday afternoon
+	now := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) *time.Time {
+		due := time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+		return &due
+	}
+	minutes := func(n int) *int { return &n }
+
+	tests := []struct {
+		input     string
+		title     string
+		locations []string
+		estimate  *int
+		due       *time.Time
+	}{
+		{input: "buy milk when at grocery store", title: "buy milk", locations: []string{grocery.ID}},
+		{input: "Buy milk when at GROCERY", title: "Buy milk", locations: []string{grocery.ID}},
+		{input: "pick up dry cleaning on the way home", title: "pick up dry cleaning", locations: []string{home.ID}},
+		{input: "submit report when I get to the office", title: "submit report", locations: []string{office.ID}},
+		{input: "buy groceries at the store", title: "buy groceries", locations: []string{grocery.ID}},
+		{input: "look at photos at home", title: "look at photos", locations: []string{home.ID}},
+		{input: "meet Sam at the cafe", title: "meet Sam at the cafe"},
+		{input: "call mom", title: "call mom"},
+		{input: "call mom tomorrow at 5pm for 15 minutes", title: "call mom", estimate: minutes(15), due: at(15, 17, 0)},
+		{input: "file taxes by Friday", title: "file taxes", due: at(16, 23, 59)},
+		{input: "water plants by wednesday", title: "water plants", due: at(14, 23, 59)},
+		{input: "plan trip next monday at 9:30am", title: "plan trip", due: at(19, 9, 30)},
+		{input: "book dentist by 2026-10-20", title: "book dentist", due: at(20, 23, 59)},
+		{input: "stretch at noon", title: "stretch", due: at(15, 12, 0)},
+		{input: "review slides for an hour at the office", title: "review slides", locations: []string{office.ID}, estimate: minutes(60)},
+		{input: "read for 1.5 hours", title: "read", estimate: minutes(90)},
+		{input: "tidy desk for half an hour today", title: "tidy desk", estimate: minutes(30), due: at(14, 23, 59)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			parsed, err := nlp.ParseTaskAt(tc.input, locations, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.title, parsed.Title)
+			assert.Equal(t, tc.locations, parsed.LocationIDs)
+			assert.Equal(t, tc.estimate, parsed.EstimatedMinutes)
+			assert.Equal(t, tc.due, parsed.DueAt)
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := nlp.ParseTaskAt("  ", locations, now)
+		assert.ErrorContains(t, err, "empty")
+
+		_, err = nlp.ParseTaskAt("tomorrow at 5pm", locations, now)
+		assert.ErrorContains(t, err, "no task title")
+
+		_, err = nlp.ParseTaskAt("call mom at 13pm", locations, now)
+		assert.ErrorContains(t, err, "invalid time")
+	})
+}
+
+func TestTaskService_CreateTaskFromNaturalLanguage(t *testing.T) {
+	grocery := *createTestLocation("grocery-id", "Grocery Store", 40.7260, -73.9897, "test-user-id")
+	store := memstore.New(memstore.WithLocations(grocery))
+	service, _ := newMemstoreServices(store)
+
+	// Without the user's locations the phrase stays in the title
+	task, err := service.CreateTaskFromNaturalLanguage("buy milk when at grocery store for 5 minutes", "test-user-id")
+	require.NoError(t, err)
+	assert.Equal(t, "buy milk when at grocery store", task.Title)
+
+	service.EnableImportLocations(store.Locations())
+	task, err = service.CreateTaskFromNaturalLanguage("buy milk when at grocery store for 5 minutes", "test-user-id")
+	require.NoError(t, err)
+	assert.Equal(t, "buy milk", task.Title)
+	assert.Equal(t, 5, *task.EstimatedMinutes)
+
+	linked, err := store.TaskLocations().GetLocationsByTaskID(task.ID)
+	require.NoError(t, err)
+	require.Len(t, linked, 1)
+	assert.Equal(t, grocery.ID, linked[0].ID)
+}
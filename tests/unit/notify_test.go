@@ -0,0 +1,247 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/notify"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pushServer records the requests it receives and answers each with the
+// next status in statuses, then 200
+type pushServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []pushRequest
+}
+
+type pushRequest struct {
+	Path  string
+	Title string
+	Body  string
+}
+
+func newPushServer(t *testing.T, statuses ...int) *pushServer {
+	s := &pushServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, pushRequest{Path: r.URL.Path, Title: r.Header.Get("Title"), Body: string(body)})
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *pushServer) received() []pushRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pushRequest(nil), s.requests...)
+}
+
+func TestNotificationDispatcher(t *testing.T) {
+	options := notify.Options{Retries: 2, Backoff: time.Millisecond, MaxAttempts: 2}
+
+	setup := func(t *testing.T, ntfy, webhook *pushServer) (*memstore.Store, *notify.Dispatcher) {
+		store := memstore.New()
+		settings := models.NewNotificationSettings("test-user-id")
+		settings.CreatedAt = settings.CreatedAt.Add(-time.Minute)
+		settings.NtfyTopic = "mytasks"
+		if webhook != nil {
+			settings.WebhookURL = webhook.URL + "/hook"
+		}
+		require.NoError(t, store.NotificationSettings().Save(*settings))
+
+		ntfyNotifier := notify.NewNtfyNotifier(store.NotificationSettings(), ntfy.Client(), ntfy.URL)
+		webhookNotifier := notify.NewWebhookNotifier(store.NotificationSettings(), nil)
+		return store, notify.NewDispatcher(store.Notifications(), options, ntfyNotifier, webhookNotifier)
+	}
+
+	notifyUser := func(t *testing.T, store *memstore.Store, userID, message string) models.Notification {
+		notification, err := models.NewNotification(userID, models.NotificationTypeTaskAssigned, message)
+		require.NoError(t, err)
+		require.NoError(t, store.Notifications().Create(*notification))
+		return *notification
+	}
+
+	t.Run("DeliversToEveryChannelOnce", func(t *testing.T) {
+		ntfy, webhook := newPushServer(t), newPushServer(t)
+		store, dispatcher := setup(t, ntfy, webhook)
+		sent := notifyUser(t, store, "test-user-id", "Alice assigned you: Buy milk")
+
+		delivered, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Zero(t, failed)
+
+		require.Len(t, ntfy.received(), 1)
+		assert.Equal(t, "/mytasks", ntfy.received()[0].Path)
+		assert.Equal(t, "Here and Now", ntfy.received()[0].Title)
+		assert.Equal(t, "Alice assigned you: Buy milk", ntfy.received()[0].Body)
+
+		require.Len(t, webhook.received(), 1)
+		assert.Equal(t, "/hook", webhook.received()[0].Path)
+		var payload models.Notification
+		require.NoError(t, json.Unmarshal([]byte(webhook.received()[0].Body), &payload))
+		assert.Equal(t, sent.ID, payload.ID)
+		assert.Equal(t, models.NotificationTypeTaskAssigned, payload.Type)
+
+		notifications, err := store.Notifications().GetByUserID("test-user-id", false)
+		require.NoError(t, err)
+		assert.NotNil(t, notifications[0].DeliveredAt)
+
+		delivered, _, err = dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, delivered)
+		assert.Len(t, ntfy.received(), 1)
+	})
+
+	t.Run("SkipsUsersWithoutChannelsAndOlderNotifications", func(t *testing.T) {
+		ntfy := newPushServer(t)
+		store, dispatcher := setup(t, ntfy, nil)
+		notifyUser(t, store, "other-user-id", "Not set up")
+		old, err := models.NewNotification("test-user-id", models.NotificationTypeTaskAssigned, "Before settings")
+		require.NoError(t, err)
+		old.CreatedAt = old.CreatedAt.Add(-time.Hour)
+		require.NoError(t, store.Notifications().Create(*old))
+
+		delivered, _, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, delivered)
+		assert.Empty(t, ntfy.received())
+	})
+
+	t.Run("RetriesTransientFailures", func(t *testing.T) {
+		ntfy := newPushServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		store, dispatcher := setup(t, ntfy, nil)
+		notifyUser(t, store, "test-user-id", "Buy milk")
+
+		delivered, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Zero(t, failed)
+		assert.Len(t, ntfy.received(), 3)
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		ntfy := newPushServer(t, 500, 500, 500, 500, 500, 500)
+		store, dispatcher := setup(t, ntfy, nil)
+		notifyUser(t, store, "test-user-id", "Buy milk")
+
+		_, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, failed)
+		pending, err := store.Notifications().GetUndelivered(10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1, "retried on the next pass")
+		assert.Equal(t, 1, pending[0].DeliveryAttempts)
+
+		_, failed, err = dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, failed)
+		pending, err = store.Notifications().GetUndelivered(10)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+		assert.Len(t, ntfy.received(), 6)
+	})
+
+	t.Run("GivesUpOnPermanentFailures", func(t *testing.T) {
+		ntfy := newPushServer(t, http.StatusForbidden)
+		store, dispatcher := setup(t, ntfy, nil)
+		notifyUser(t, store, "test-user-id", "Buy milk")
+
+		_, failed, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, failed)
+		assert.Len(t, ntfy.received(), 1, "not retried")
+
+		pending, err := store.Notifications().GetUndelivered(10)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("SendTest", func(t *testing.T) {
+		ntfy := newPushServer(t, http.StatusUnauthorized)
+		store, dispatcher := setup(t, ntfy, nil)
+
+		assert.Error(t, dispatcher.SendTest(context.Background(), "test-user-id"), "errors are reported without retrying")
+		require.NoError(t, dispatcher.SendTest(context.Background(), "test-user-id"))
+		require.Len(t, ntfy.received(), 2)
+		assert.Equal(t, "Test notification from Here and Now", ntfy.received()[1].Body)
+
+		notifications, err := store.Notifications().GetByUserID("test-user-id", false)
+		require.NoError(t, err)
+		assert.Empty(t, notifications, "test pings are not stored")
+	})
+}
+
+func TestNotificationSettings_Validate(t *testing.T) {
+	settings := models.NewNotificationSettings("test-user-id")
+	require.NoError(t, settings.Validate())
+	assert.False(t, settings.HasChannels())
+
+	settings.NtfyTopic = "my-tasks_2"
+	settings.WebhookURL = "https://example.com/hook"
+	require.NoError(t, settings.Validate())
+	assert.True(t, settings.HasChannels())
+
+	settings.NtfyTopic = "my tasks"
+	settings.WebhookURL = "example.com/hook"
+	settings.NtfyServer = "ftp://ntfy.example.com"
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, settings.Validate(), &validationErr)
+	assert.Len(t, validationErr.Fields, 3)
+}
+
+func TestUpdateNotificationSettings(t *testing.T) {
+	store := memstore.New()
+	users := api.NewUserHandler(nil)
+	users.SetNotificationSettings(store.NotificationSettings())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{
+		Users: users,
+		AuthMiddleware: func(c *gin.Context) {
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{})
+
+	w := serveRequest(router, http.MethodPatch, "/api/v1/users/me/notifications", `{"ntfy_topic": "mytasks"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serveRequest(router, http.MethodPatch, "/api/v1/users/me/notifications", `{"webhook_url": "https://example.com/hook"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var settings models.NotificationSettings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, "mytasks", settings.NtfyTopic, "unchanged fields are kept")
+	assert.Equal(t, "https://example.com/hook", settings.WebhookURL)
+
+	w = serveRequest(router, http.MethodPatch, "/api/v1/users/me/notifications", `{"ntfy_topic": "not a topic"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "ntfy_topic")
+
+	w = serveRequest(router, http.MethodGet, "/api/v1/users/me/notifications", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, "mytasks", settings.NtfyTopic)
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOpenHours(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    models.OpenHours
+		format  string
+		wantErr bool
+	}{
+		{
+			name:   "DayRange",
+			spec:   "Mon-Sat 08:00-21:00",
+			want:   models.OpenHours{"mon": {{Open: "08:00", Close: "21:00"}}, "tue": {{Open: "08:00", Close: "21:00"}}, "wed": {{Open: "08:00", Close: "21:00"}}, "thu": {{Open: "08:00", Close: "21:00"}}, "fri": {{Open: "08:00", Close: "21:00"}}, "sat": {{Open: "08:00", Close: "21:00"}}},
+			format: "Mon-Sat 08:00-21:00",
+		},
+		{
+			name:   "SeveralRangesAndDays",
+			spec:   "mon,wed 07:00-12:00,13:00-19:00; Sunday 10:00-16:00",
+			want:   models.OpenHours{"mon": {{Open: "07:00", Close: "12:00"}, {Open: "13:00", Close: "19:00"}}, "wed": {{Open: "07:00", Close: "12:00"}, {Open: "13:00", Close: "19:00"}}, "sun": {{Open: "10:00", Close: "16:00"}}},
+			format: "Mon 07:00-12:00,13:00-19:00; Wed 07:00-12:00,13:00-19:00; Sun 10:00-16:00",
+		},
+		{
+			name:   "WrappingDayRange",
+			spec:   "Fri-Mon 22:00-02:00",
+			want:   models.OpenHours{"fri": {{Open: "22:00", Close: "02:00"}}, "sat": {{Open: "22:00", Close: "02:00"}}, "sun": {{Open: "22:00", Close: "02:00"}}, "mon": {{Open: "22:00", Close: "02:00"}}},
+			format: "Mon 22:00-02:00; Fri-Sun 22:00-02:00",
+		},
+		{name: "Daily", spec: "daily 06:00-24:00", format: "Mon-Sun 06:00-24:00"},
+		{name: "Empty", spec: "  ", format: ""},
+		{name: "UnknownDay", spec: "Mon-Funday 08:00-21:00", wantErr: true},
+		{name: "BadTime", spec: "Mon 8am-9pm", wantErr: true},
+		{name: "MissingTimes", spec: "Mon-Sat", wantErr: true},
+		{name: "SameOpenAndClose", spec: "Mon 08:00-08:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours, err := models.ParseOpenHours(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want != nil {
+				assert.Equal(t, tt.want, hours)
+			}
+			assert.Equal(t, tt.format, hours.String())
+		})
+	}
+}
+
+func TestOpenHours_IsOpen(t *testing.T) {
+	// 2025-01-06 is a Monday
+	at := func(day int, clock string) time.Time {
+		ts, _ := time.Parse("2006-01-02 15:04", "2025-01-"+[]string{"05", "06", "07", "08", "09", "10", "11"}[day]+" "+clock)
+		return ts
+	}
+
+	store, err := models.ParseOpenHours("Mon-Sat 08:00-21:00")
+	require.NoError(t, err)
+	assert.True(t, store.IsOpen(at(1, "08:00")))
+	assert.True(t, store.IsOpen(at(6, "20:59")))
+	assert.False(t, store.IsOpen(at(1, "21:00")))
+	assert.False(t, store.IsOpen(at(1, "07:59")))
+	assert.False(t, store.IsOpen(at(0, "12:00")), "closed on Sunday")
+
+	bar, err := models.ParseOpenHours("Fri-Sat 22:00-02:00")
+	require.NoError(t, err)
+	assert.True(t, bar.IsOpen(at(5, "23:30")))
+	assert.True(t, bar.IsOpen(at(6, "01:30")), "Friday night runs into Saturday")
+	assert.True(t, bar.IsOpen(at(0, "01:59")), "Saturday night runs into Sunday")
+	assert.False(t, bar.IsOpen(at(5, "01:30")), "Thursday night is closed")
+	assert.False(t, bar.IsOpen(at(0, "02:00")))
+
+	var always models.OpenHours
+	assert.True(t, always.IsOpen(at(3, "03:00")))
+
+	next, ok := store.NextOpening(at(6, "21:30"))
+	require.True(t, ok)
+	assert.Equal(t, at(1, "08:00").AddDate(0, 0, 7), next, "closed Sunday, so Monday")
+
+	_, ok = models.OpenHours{"mon": {}}.NextOpening(at(1, "09:00"))
+	assert.False(t, ok)
+}
+
+func TestLocationFilter_OpenHours(t *testing.T) {
+	newTask := func(hours string, tz string) (*filters.LocationFilter, models.Task, *models.Location) {
+		hardware := createTestLocation("hardware-id", "Hardware Store", 37.7749, -122.4194, "test-user-id")
+		openHours, err := models.ParseOpenHours(hours)
+		require.NoError(t, err)
+		require.NoError(t, hardware.SetOpenHours(openHours))
+
+		user := models.User{ID: "test-user-id", TimeZone: tz}
+		store := memstore.New(memstore.WithUsers(user))
+
+		taskLocationRepo := NewMockTaskLocationRepository()
+		filter := filters.NewLocationFilter(filters.DefaultFilterConfig, NewMockLocationRepository(), taskLocationRepo)
+		filter.SetUserRepository(store.Users())
+
+		task := createTestTask("Buy screws", nil, 3)
+		taskLocationRepo.SetTaskLocations(task.ID, []models.Location{*hardware})
+		return filter, task, hardware
+	}
+
+	contextAt := func(location *models.Location, at string) models.Context {
+		ctx := createTestContext(&location.Latitude, &location.Longitude, 60, 3)
+		ctx.Timestamp, _ = time.Parse(time.RFC3339, at)
+		return ctx
+	}
+
+	t.Run("HiddenWhileClosed", func(t *testing.T) {
+		filter, task, hardware := newTask("Mon-Sat 08:00-21:00", "America/Los_Angeles")
+
+		// Monday 22:30 in Los Angeles
+		ctx := contextAt(hardware, "2025-01-07T06:30:00Z")
+		assertBlocked(t, filter, ctx, task, filters.ReasonLocationClosed)
+		_, reason := filter.Apply(ctx, task)
+		assert.Equal(t, "Hardware Store opens at 08:00 tomorrow", reason)
+
+		// Monday 06:00 in Los Angeles, 14:00 in UTC
+		ctx = contextAt(hardware, "2025-01-06T14:00:00Z")
+		_, reason = filter.Apply(ctx, task)
+		assert.Equal(t, "Hardware Store opens at 08:00", reason)
+
+		// Saturday 21:30 opens again on Monday
+		ctx = contextAt(hardware, "2025-01-12T05:30:00Z")
+		_, reason = filter.Apply(ctx, task)
+		assert.Equal(t, "Hardware Store opens at 08:00 on Monday", reason)
+
+		visible, code, _ := filter.Evaluate(contextAt(hardware, "2025-01-06T17:00:00Z"), task)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonLocationInRange, code)
+	})
+
+	t.Run("OvernightRange", func(t *testing.T) {
+		filter, task, hardware := newTask("Fri 22:00-02:00", "UTC")
+
+		visible, _, _ := filter.Evaluate(contextAt(hardware, "2025-01-11T01:00:00Z"), task)
+		assert.True(t, visible, "still open early Saturday")
+		assertBlocked(t, filter, contextAt(hardware, "2025-01-11T03:00:00Z"), task, filters.ReasonLocationClosed)
+	})
+
+	t.Run("NoHoursIsAlwaysOpen", func(t *testing.T) {
+		filter, task, hardware := newTask("", "UTC")
+
+		visible, code, _ := filter.Evaluate(contextAt(hardware, "2025-01-12T03:00:00Z"), task)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonLocationInRange, code)
+	})
+}
@@ -0,0 +1,209 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPasswordResetDB(t *testing.T) *storage.DB {
+	db := setupSessionDB(t)
+	_, err := db.Exec(`CREATE TABLE password_reset_tokens (
+		id TEXT PRIMARY KEY NOT NULL, user_id TEXT NOT NULL, token_hash TEXT NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL, used_at DATETIME NULL)`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestPasswordResetRepository(t *testing.T) {
+	now := time.Now()
+	repo := storage.NewPasswordResetRepository(setupPasswordResetDB(t))
+
+	require.NoError(t, repo.Create(models.PasswordResetToken{ID: "live", UserID: "test-user-id", TokenHash: "hash-live", ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, repo.Create(models.PasswordResetToken{ID: "expired", UserID: "test-user-id", TokenHash: "hash-expired", ExpiresAt: now.Add(-time.Minute)}))
+	assert.Error(t, repo.Create(models.PasswordResetToken{ID: "no-hash", UserID: "test-user-id", ExpiresAt: now}))
+
+	token, err := repo.GetByHash("hash-live")
+	require.NoError(t, err)
+	assert.Equal(t, "live", token.ID)
+	assert.True(t, token.IsUsable(now))
+
+	require.NoError(t, repo.MarkUsed("live", now))
+	assert.Error(t, repo.MarkUsed("live", now), "a token can only be used once")
+
+	token, err = repo.GetByHash("hash-live")
+	require.NoError(t, err)
+	require.NotNil(t, token.UsedAt)
+	assert.False(t, token.IsUsable(now))
+
+	require.NoError(t, repo.Create(models.PasswordResetToken{ID: "outstanding", UserID: "test-user-id", TokenHash: "hash-outstanding", ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, repo.Create(models.PasswordResetToken{ID: "other-user", UserID: "other-user-id", TokenHash: "hash-other-user", ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, repo.MarkAllUsed("test-user-id", now))
+	token, err = repo.GetByHash("hash-outstanding")
+	require.NoError(t, err)
+	assert.False(t, token.IsUsable(now))
+	token, err = repo.GetByHash("hash-other-user")
+	require.NoError(t, err)
+	assert.True(t, token.IsUsable(now), "other users' tokens are untouched")
+
+	_, err = repo.GetByHash("missing")
+	assert.Error(t, err)
+
+	job := maintenance.PrunePasswordResetsJob(repo)
+	assert.Equal(t, maintenance.JobPrunePasswordResets, job.Name)
+	summary, err := job.Run(now)
+	require.NoError(t, err)
+	assert.Equal(t, "removed 1 expired password reset token(s)", summary)
+
+	_, err = repo.GetByHash("hash-expired")
+	assert.Error(t, err)
+}
+
+// resetUserRepository stores password changes so logins see them
+type resetUserRepository struct {
+	loginUserRepository
+}
+
+func (r *resetUserRepository) UpdatePassword(userID string, hashedPassword string) error {
+	user := r.users[userID]
+	user.PasswordHash = hashedPassword
+	r.users[userID] = user
+	return nil
+}
+
+// capturedResets records delivered password reset tokens by email
+type capturedResets map[string]string
+
+func (d capturedResets) DeliverPasswordReset(email, token string) error {
+	d[email] = token
+	return nil
+}
+
+func TestPasswordResetRoutes(t *testing.T) {
+	newRouter := func(t *testing.T, config auth.AuthConfig) (*gin.Engine, capturedResets) {
+		users := &resetUserRepository{loginUserRepository{authUserRepository{users: map[string]models.User{}}}}
+		authService := auth.NewAuthService(users, storage.NewSessionRepository(setupSessionDB(t)),
+			auth.NewJWTService("test-secret-key-32-chars-long!!"), config)
+		authService.EnablePasswordReset(storage.NewPasswordResetRepository(setupPasswordResetDB(t)))
+
+		_, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+		require.NoError(t, err)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		handler := api.NewAuthHandler(authService)
+		delivered := capturedResets{}
+		handler.SetPasswordResetDelivery(delivered)
+		api.SetupRoutes(router, api.Handlers{Auth: handler}, api.RouteConfig{})
+		return router, delivered
+	}
+	login := func(router *gin.Engine, password string) int {
+		return serveRequest(router, http.MethodPost, "/api/v1/auth/login", `{"username":"alice@example.com","password":"`+password+`"}`).Code
+	}
+
+	t.Run("ResetsPasswordOnce", func(t *testing.T) {
+		router, delivered := newRouter(t, auth.DefaultAuthConfig)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"alice@example.com"}`)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		token := delivered["alice@example.com"]
+		require.NotEmpty(t, token)
+		assert.NotContains(t, w.Body.String(), token, "the token only goes to the user")
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+token+`","new_password":"short"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+token+`","new_password":"battery-staple"}`)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusOK, login(router, "battery-staple"))
+		assert.Equal(t, http.StatusUnauthorized, login(router, "correct-horse"))
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+token+`","new_password":"another-password"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "a token cannot be reused")
+		assert.Equal(t, http.StatusOK, login(router, "battery-staple"))
+	})
+
+	t.Run("UsesUpOtherOutstandingTokens", func(t *testing.T) {
+		router, delivered := newRouter(t, auth.DefaultAuthConfig)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"alice@example.com"}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		first := delivered["alice@example.com"]
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"alice@example.com"}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		second := delivered["alice@example.com"]
+		require.NotEqual(t, first, second)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+second+`","new_password":"battery-staple"}`)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+first+`","new_password":"another-password"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "an older token stops working after a reset")
+		assert.Equal(t, http.StatusOK, login(router, "battery-staple"))
+	})
+
+	t.Run("EndsRefreshTokens", func(t *testing.T) {
+		router, delivered := newRouter(t, auth.DefaultAuthConfig)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/login", `{"username":"alice@example.com","password":"correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var stolen api.LoginResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stolen))
+		require.NotEmpty(t, stolen.RefreshToken)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"alice@example.com"}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+delivered["alice@example.com"]+`","new_password":"battery-staple"}`)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+stolen.RefreshToken+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "a reset signs every device out for good")
+	})
+
+	t.Run("RejectsExpiredToken", func(t *testing.T) {
+		config := auth.DefaultAuthConfig
+		config.PasswordResetDuration = time.Nanosecond
+		router, delivered := newRouter(t, config)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"alice@example.com"}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		time.Sleep(time.Millisecond)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"`+delivered["alice@example.com"]+`","new_password":"battery-staple"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusOK, login(router, "correct-horse"))
+	})
+
+	t.Run("UnknownEmailLooksTheSame", func(t *testing.T) {
+		router, delivered := newRouter(t, auth.DefaultAuthConfig)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"nobody@example.com"}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Empty(t, delivered)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"made-up","new_password":"battery-staple"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("NotAvailableWithoutDelivery", func(t *testing.T) {
+		plain := auth.NewAuthService(&resetUserRepository{}, storage.NewSessionRepository(setupSessionDB(t)),
+			auth.NewJWTService("test-secret-key-32-chars-long!!"), auth.DefaultAuthConfig)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{Auth: api.NewAuthHandler(plain)}, api.RouteConfig{})
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/forgot", `{"email":"alice@example.com"}`)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/reset", `{"token":"made-up","new_password":"battery-staple"}`)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
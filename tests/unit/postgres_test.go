@@ -0,0 +1,44 @@
+//go:build postgres
+
+package unit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRepositories_Postgres runs the repository suite against the PostgreSQL
+// database in HERENOW_TEST_POSTGRES_URL, in a schema of its own that is
+// dropped afterwards
+func TestRepositories_Postgres(t *testing.T) {
+	url := os.Getenv("HERENOW_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("HERENOW_TEST_POSTGRES_URL is not set")
+	}
+
+	admin, err := storage.NewDB(storage.Config{Path: url})
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+	require.Equal(t, storage.DialectPostgres, admin.Dialect())
+
+	schema := fmt.Sprintf("hereandnow_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	db, err := storage.NewDB(storage.Config{Path: url + separator + "search_path=" + schema})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	runRepositorySuite(t, db)
+}
@@ -0,0 +1,192 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertBlocked checks a filter hides the task with the given code and a
+// human-readable message alongside it
+func assertBlocked(t *testing.T, rule filters.CodedFilterRule, ctx models.Context, task models.Task, want filters.ReasonCode) {
+	t.Helper()
+
+	visible, code, reason := rule.Evaluate(ctx, task)
+	assert.False(t, visible)
+	assert.Equal(t, want, code)
+	assert.NotEmpty(t, reason, "code %s should come with a message", code)
+
+	_, applyReason := rule.Apply(ctx, task)
+	assert.Equal(t, reason, applyReason)
+}
+
+func TestReasonCodes_LocationFilter(t *testing.T) {
+	locationRepo := NewMockLocationRepository()
+	taskLocationRepo := NewMockTaskLocationRepository()
+	filter := filters.NewLocationFilter(filters.DefaultFilterConfig, locationRepo, taskLocationRepo)
+
+	home := createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+	task := createTestTask("Water plants", nil, 3)
+	taskLocationRepo.SetTaskLocations(task.ID, []models.Location{*home})
+
+	farLat, farLng := 37.8000, -122.5000
+	assertBlocked(t, filter, createTestContext(&farLat, &farLng, 60, 3), task, filters.ReasonLocationOutOfRange)
+
+	visible, code, _ := filter.Evaluate(createTestContext(&home.Latitude, &home.Longitude, 60, 3), task)
+	assert.True(t, visible)
+	assert.Equal(t, filters.ReasonLocationInRange, code)
+
+	_, code, _ = filter.Evaluate(createTestContext(nil, nil, 60, 3), task)
+	assert.Equal(t, filters.ReasonLocationUnknown, code)
+}
+
+func TestReasonCodes_TimeFilter(t *testing.T) {
+	calendarRepo := NewMockCalendarEventRepository()
+	filter := filters.NewTimeFilter(filters.DefaultFilterConfig, calendarRepo)
+
+	minutes := 45
+	task := createTestTask("Write report", &minutes, 3)
+
+	t.Run("TimeInsufficient", func(t *testing.T) {
+		assertBlocked(t, filter, createTestContext(nil, nil, 30, 5), task, filters.ReasonTimeInsufficient)
+	})
+
+	t.Run("NoneAvailable", func(t *testing.T) {
+		assertBlocked(t, filter, createTestContext(nil, nil, 0, 5), task, filters.ReasonTimeNoneAvailable)
+	})
+
+	t.Run("EnergyInsufficient", func(t *testing.T) {
+		assertBlocked(t, filter, createTestContext(nil, nil, 60, 1), task, filters.ReasonEnergyInsufficient)
+	})
+
+	t.Run("CalendarConflict", func(t *testing.T) {
+		ctx := createTestContext(nil, nil, 60, 5)
+		calendarRepo.AddEvent(ctx.UserID, models.CalendarEvent{
+			Title:   "Standup",
+			StartAt: ctx.Timestamp.Add(10 * time.Minute),
+			EndAt:   ctx.Timestamp.Add(25 * time.Minute),
+		})
+		defer delete(calendarRepo.events, ctx.UserID)
+
+		assertBlocked(t, filter, ctx, task, filters.ReasonTimeCalendarConflict)
+	})
+
+	t.Run("Fits", func(t *testing.T) {
+		visible, code, _ := filter.Evaluate(createTestContext(nil, nil, 60, 5), task)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonTimeFits, code)
+	})
+}
+
+func TestReasonCodes_DependencyFilter(t *testing.T) {
+	dependencyRepo := NewMockTaskDependencyRepository()
+	taskRepo := NewMockTaskRepository()
+	filter := filters.NewDependencyFilter(filters.DefaultFilterConfig, dependencyRepo, taskRepo)
+
+	draft := createTestTask("Draft report", nil, 3)
+	send := createTestTask("Send report", nil, 3)
+	taskRepo.AddTask(&draft)
+	taskRepo.AddTask(&send)
+	dependencyRepo.AddDependency(models.TaskDependency{
+		ID: "dep-1", TaskID: send.ID, DependsOnTaskID: draft.ID, DependencyType: models.DependencyTypeBlocking,
+	})
+
+	ctx := createTestContext(nil, nil, 60, 3)
+	assertBlocked(t, filter, ctx, send, filters.ReasonDepPending)
+
+	_, code, _ := filter.Evaluate(ctx, draft)
+	assert.Equal(t, filters.ReasonDepNone, code)
+
+	a := createTestTask("A", nil, 3)
+	b := createTestTask("B", nil, 3)
+	taskRepo.AddTask(&a)
+	taskRepo.AddTask(&b)
+	dependencyRepo.AddDependency(models.TaskDependency{ID: "dep-2", TaskID: a.ID, DependsOnTaskID: b.ID, DependencyType: models.DependencyTypeBlocking})
+	dependencyRepo.AddDependency(models.TaskDependency{ID: "dep-3", TaskID: b.ID, DependsOnTaskID: a.ID, DependencyType: models.DependencyTypeBlocking})
+	assertBlocked(t, filter, ctx, a, filters.ReasonDepCircular)
+}
+
+func TestReasonCodes_PriorityFilter(t *testing.T) {
+	filter := filters.NewPriorityFilter(filters.DefaultFilterConfig)
+
+	minutes := 30
+	low := createTestTask("Reorganize bookshelf", &minutes, 1)
+	assertBlocked(t, filter, createTestContext(nil, nil, 60, 1), low, filters.ReasonPriorityBelowThreshold)
+
+	high := createTestTask("Urgent fix", &minutes, 5)
+	visible, code, _ := filter.Evaluate(createTestContext(nil, nil, 60, 5), high)
+	assert.True(t, visible)
+	assert.Equal(t, filters.ReasonPriorityAboveThreshold, code)
+}
+
+func TestReasonCodes_Disabled(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	config.EnableLocationFilter = false
+	config.EnableTimeFilter = false
+	config.EnableDependencyFilter = false
+	config.EnablePriorityFilter = false
+
+	rules := []filters.CodedFilterRule{
+		filters.NewLocationFilter(config, NewMockLocationRepository(), NewMockTaskLocationRepository()),
+		filters.NewTimeFilter(config, NewMockCalendarEventRepository()),
+		filters.NewDependencyFilter(config, NewMockTaskDependencyRepository(), NewMockTaskRepository()),
+		filters.NewPriorityFilter(config),
+	}
+
+	task := createTestTask("Anything", nil, 3)
+	for _, rule := range rules {
+		visible, code, reason := rule.Evaluate(createTestContext(nil, nil, 60, 3), task)
+		assert.True(t, visible, rule.Name())
+		assert.Equal(t, filters.ReasonFilterDisabled, code, rule.Name())
+		assert.Contains(t, reason, "disabled", rule.Name())
+	}
+}
+
+func TestReasonCodes_Engine(t *testing.T) {
+	minutes := 45
+	task := createTestTask("Write report", &minutes, 3)
+	ctx := createTestContext(nil, nil, 30, 5)
+
+	t.Run("ResultsAndAuditCarryCodeAndMessage", func(t *testing.T) {
+		store := memstore.New()
+		engine := filters.NewEngine(filters.DefaultFilterConfig, store.FilterAudits())
+		engine.AddRule(filters.NewTimeFilter(filters.DefaultFilterConfig, NewMockCalendarEventRepository()))
+
+		visible, results := engine.FilterTasks(ctx, []models.Task{task})
+		assert.Empty(t, visible)
+		require.Len(t, results, 1)
+		assert.Equal(t, filters.ReasonTimeInsufficient, results[0].Code)
+		assert.Equal(t, "task needs 45 minutes but only 30 available", results[0].Reason)
+
+		audits, err := store.FilterAudits().GetAuditLogByTaskID(task.ID, 0)
+		require.NoError(t, err)
+		require.Len(t, audits, 1)
+		var reasons []models.FilterReason
+		require.NoError(t, json.Unmarshal(audits[0].Reasons, &reasons))
+		require.Len(t, reasons, 1)
+		assert.Equal(t, "TIME_INSUFFICIENT", reasons[0].Code)
+		assert.Equal(t, results[0].Reason, reasons[0].Details)
+
+		explanation := engine.ExplainTaskVisibility(ctx, task)
+		require.Len(t, explanation.FilterResults, 1)
+		assert.Equal(t, filters.ReasonTimeInsufficient, explanation.FilterResults[0].Code)
+	})
+
+	t.Run("CodesVerbosityDropsMessages", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.ReasonVerbosity = filters.ReasonVerbosityCodes
+		engine := filters.NewEngine(config, memstore.New().FilterAudits())
+		engine.AddRule(filters.NewTimeFilter(config, NewMockCalendarEventRepository()))
+
+		_, results := engine.FilterTasks(ctx, []models.Task{task})
+		require.Len(t, results, 1)
+		assert.Equal(t, filters.ReasonTimeInsufficient, results[0].Code)
+		assert.Empty(t, results[0].Reason)
+	})
+}
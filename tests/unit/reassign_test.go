@@ -0,0 +1,192 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockNotificationRepository records notifications in memory
+type MockNotificationRepository struct {
+	notifications []models.Notification
+	fail          bool
+}
+
+func (m *MockNotificationRepository) Create(notification models.Notification) error {
+	if m.fail {
+		return fmt.Errorf("insert failed")
+	}
+	m.notifications = append(m.notifications, notification)
+	return nil
+}
+
+func newReassignTaskService(repo *MockServiceTaskRepository) (*hereandnow.TaskService, *MockNotificationRepository) {
+	notifications := &MockNotificationRepository{}
+	deactivatedAt := time.Now().Add(-time.Hour)
+	service := newTestTaskService(repo)
+	service.SetUserRepository(&MockUserRepository{users: map[string]*models.User{
+		"admin":   {ID: "admin", Username: "admin", IsAdmin: true},
+		"former":  {ID: "former", Username: "former", IsAdmin: true, DeactivatedAt: &deactivatedAt},
+		"alice":   {ID: "alice", Username: "alice"},
+		"bob":     {ID: "bob", Username: "bob"},
+		"manager": {ID: "manager", Username: "manager"},
+	}})
+	service.SetNotificationRepository(notifications)
+	return service, notifications
+}
+
+func createAssignedTask(t *testing.T, repo *MockServiceTaskRepository, title, creatorID, assigneeID string, status models.TaskStatus) models.Task {
+	task := createTestTask(title, nil, 3)
+	task.CreatorID = creatorID
+	task.AssigneeID = &assigneeID
+	task.Status = status
+	require.NoError(t, repo.Create(task))
+	return task
+}
+
+func TestTaskService_ReassignUserTasks(t *testing.T) {
+	t.Run("MovesOpenAssignmentsWithNotifications", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		pending := createAssignedTask(t, repo, "Write report", "manager", "alice", models.TaskStatusPending)
+		active := createAssignedTask(t, repo, "Review budget", "manager", "alice", models.TaskStatusActive)
+		other := createAssignedTask(t, repo, "Plan offsite", "manager", "bob", models.TaskStatusPending)
+
+		report, err := service.ReassignUserTasks("admin", hereandnow.ReassignRequest{FromUserID: "alice", ToUserID: "bob"})
+		require.NoError(t, err)
+
+		assert.Len(t, report.Reassigned, 2)
+		assert.Empty(t, report.Transferred)
+		assert.Empty(t, report.Skipped)
+
+		for _, id := range []string{pending.ID, active.ID} {
+			task := repo.tasks[id]
+			require.NotNil(t, task.AssigneeID)
+			assert.Equal(t, "bob", *task.AssigneeID)
+			assert.Equal(t, "manager", task.CreatorID)
+		}
+		assert.Equal(t, other, repo.tasks[other.ID])
+
+		require.Len(t, notifications.notifications, 2)
+		notifiedTasks := []string{}
+		for _, notification := range notifications.notifications {
+			assert.Equal(t, "bob", notification.UserID)
+			assert.Equal(t, models.NotificationTypeTaskAssigned, notification.Type)
+			assert.Contains(t, notification.Message, "assigned you a task")
+			require.NotNil(t, notification.TaskID)
+			notifiedTasks = append(notifiedTasks, *notification.TaskID)
+		}
+		assert.ElementsMatch(t, []string{pending.ID, active.ID}, notifiedTasks)
+	})
+
+	t.Run("SkipsCompletedTasks", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		open := createAssignedTask(t, repo, "Write report", "manager", "alice", models.TaskStatusPending)
+		done := createAssignedTask(t, repo, "Old report", "manager", "alice", models.TaskStatusCompleted)
+		cancelled := createAssignedTask(t, repo, "Dropped report", "manager", "alice", models.TaskStatusCancelled)
+
+		report, err := service.ReassignUserTasks("admin", hereandnow.ReassignRequest{FromUserID: "alice", ToUserID: "bob"})
+		require.NoError(t, err)
+
+		require.Len(t, report.Reassigned, 1)
+		assert.Equal(t, open.ID, report.Reassigned[0].ID)
+		assert.Len(t, report.Skipped, 2)
+
+		assert.Equal(t, "alice", *repo.tasks[done.ID].AssigneeID)
+		assert.Equal(t, "alice", *repo.tasks[cancelled.ID].AssigneeID)
+		assert.Len(t, notifications.notifications, 1)
+	})
+
+	t.Run("TransfersOwnershipWhenRequested", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		own := createTestTask("Personal errand", nil, 3)
+		own.CreatorID = "alice"
+		require.NoError(t, repo.Create(own))
+		delegated := createAssignedTask(t, repo, "Delegated work", "alice", "manager", models.TaskStatusPending)
+
+		report, err := service.ReassignUserTasks("admin", hereandnow.ReassignRequest{
+			FromUserID:        "alice",
+			ToUserID:          "bob",
+			TransferOwnership: true,
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, report.Reassigned)
+		assert.Len(t, report.Transferred, 2)
+		assert.Equal(t, "bob", repo.tasks[own.ID].CreatorID)
+		assert.Equal(t, "bob", repo.tasks[delegated.ID].CreatorID)
+		assert.Equal(t, "manager", *repo.tasks[delegated.ID].AssigneeID)
+		assert.Empty(t, notifications.notifications)
+	})
+
+	t.Run("RejectsNonAdmin", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		task := createAssignedTask(t, repo, "Write report", "manager", "alice", models.TaskStatusPending)
+
+		_, err := service.ReassignUserTasks("manager", hereandnow.ReassignRequest{FromUserID: "alice", ToUserID: "bob"})
+		assert.ErrorContains(t, err, "admin privileges required")
+
+		assert.Equal(t, "alice", *repo.tasks[task.ID].AssigneeID)
+		assert.Equal(t, 0, repo.updates)
+		assert.Empty(t, notifications.notifications)
+	})
+
+	t.Run("RejectsDeactivatedAdmin", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		task := createAssignedTask(t, repo, "Write report", "manager", "alice", models.TaskStatusPending)
+
+		_, err := service.ReassignUserTasks("former", hereandnow.ReassignRequest{FromUserID: "alice", ToUserID: "bob"})
+		assert.ErrorContains(t, err, "admin privileges required")
+
+		assert.Equal(t, "alice", *repo.tasks[task.ID].AssigneeID)
+		assert.Equal(t, 0, repo.updates)
+		assert.Empty(t, notifications.notifications)
+	})
+
+	t.Run("RollsBackWhenNotificationFails", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		transactor := &MockTransactor{taskRepo: repo}
+		service.SetTransactor(transactor)
+		notifications.fail = true
+		task := createAssignedTask(t, repo, "Write report", "manager", "alice", models.TaskStatusPending)
+
+		_, err := service.ReassignUserTasks("admin", hereandnow.ReassignRequest{FromUserID: "alice", ToUserID: "bob"})
+		assert.Error(t, err)
+
+		assert.Equal(t, 1, transactor.rollbacks)
+		assert.Equal(t, "alice", *repo.tasks[task.ID].AssigneeID)
+	})
+}
+
+func TestTaskRepository_GetByUserID(t *testing.T) {
+	db := setupSoftDeleteDB(t)
+	insertTaskWithMetadata(t, db, "created", `{}`)
+	insertTaskWithMetadata(t, db, "assigned", `{}`)
+	insertTaskWithMetadata(t, db, "deleted", `{}`)
+	insertTaskWithMetadata(t, db, "someone-elses", `{}`)
+	_, err := db.Exec(`UPDATE tasks SET creator_id = 'user-2' WHERE id IN ('assigned', 'someone-elses')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE tasks SET assignee_id = 'user-1' WHERE id = 'assigned'`)
+	require.NoError(t, err)
+	tasks := storage.NewTaskRepository(db)
+	require.NoError(t, tasks.Delete("deleted"))
+
+	mine, err := tasks.GetByUserID("user-1")
+	require.NoError(t, err)
+	var ids []string
+	for _, task := range mine {
+		ids = append(ids, task.ID)
+	}
+	assert.ElementsMatch(t, []string{"created", "assigned"}, ids)
+}
@@ -0,0 +1,436 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/recurrence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrence_Parse(t *testing.T) {
+	t.Run("ParsesWeeklyRule", func(t *testing.T) {
+		rule, err := recurrence.Parse("FREQ=WEEKLY;BYDAY=FR,MO,WE")
+		require.NoError(t, err)
+		assert.Equal(t, recurrence.Weekly, rule.Freq)
+		assert.Equal(t, []time.Weekday{time.Monday, time.Wednesday, time.Friday}, rule.ByDay)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE,FR", rule.String())
+		assert.Equal(t, "every week on Monday, Wednesday and Friday", rule.Describe())
+	})
+
+	t.Run("ParsesCountAndUntil", func(t *testing.T) {
+		rule, err := recurrence.Parse("RRULE:FREQ=DAILY;INTERVAL=2;COUNT=3")
+		require.NoError(t, err)
+		assert.Equal(t, 2, rule.Interval)
+		assert.Equal(t, 3, rule.Count)
+
+		rule, err = recurrence.Parse("FREQ=MONTHLY;UNTIL=20261231")
+		require.NoError(t, err)
+		require.NotNil(t, rule.Until)
+		assert.Equal(t, "FREQ=MONTHLY;UNTIL=20261231T235959Z", rule.String())
+	})
+
+	t.Run("RejectsInvalidRulesHelpfully", func(t *testing.T) {
+		cases := map[string]string{
+			"":                                  "empty",
+			"BYDAY=MO":                          "FREQ is required",
+			"FREQ=HOURLY":                       "want DAILY, WEEKLY, MONTHLY or YEARLY",
+			"FREQ=WEEKLY;BYDAY=MO,XX":           `invalid BYDAY day "XX"`,
+			"FREQ=DAILY;INTERVAL=0":             "positive whole number",
+			"FREQ=DAILY;COUNT=2;UNTIL=20261231": "cannot both be set",
+			"FREQ=DAILY;BYHOUR=9":               "supported: FREQ, INTERVAL, BYDAY, COUNT, UNTIL",
+			"FREQ=MONTHLY;BYDAY=MO":             "BYDAY is only supported",
+			"FREQ":                              "want NAME=VALUE",
+		}
+		for rrule, message := range cases {
+			_, err := recurrence.Parse(rrule)
+			require.Error(t, err, rrule)
+			assert.Contains(t, err.Error(), message, rrule)
+		}
+	})
+}
+
+func TestRecurrence_ParseEvery(t *testing.T) {
+	cases := map[string]string{
+		"day":                    "FREQ=DAILY",
+		"3 days":                 "FREQ=DAILY;INTERVAL=3",
+		"2 weeks":                "FREQ=WEEKLY;INTERVAL=2",
+		"every other month":      "FREQ=MONTHLY;INTERVAL=2",
+		"year":                   "FREQ=YEARLY",
+		"weekday":                "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"weekend":                "FREQ=WEEKLY;BYDAY=SA,SU",
+		"monday":                 "FREQ=WEEKLY;BYDAY=MO",
+		"mon, wed and fri":       "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		"Tuesdays and Thursdays": "FREQ=WEEKLY;BYDAY=TU,TH",
+		"2 weeks on monday":      "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO",
+	}
+	for phrase, expected := range cases {
+		rule, err := recurrence.ParseEvery(phrase)
+		require.NoError(t, err, phrase)
+		assert.Equal(t, expected, rule.String(), phrase)
+	}
+
+	for _, phrase := range []string{"", "fortnight", "0 days", "blursday", "month on monday"} {
+		_, err := recurrence.ParseEvery(phrase)
+		assert.Error(t, err, phrase)
+	}
+}
+
+func TestRecurrence_Occurrences(t *testing.T) {
+	// Monday 9:00
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+
+	t.Run("WeeklyByDay", func(t *testing.T) {
+		rule, err := recurrence.Parse("FREQ=WEEKLY;BYDAY=MO,WE,FR")
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{
+			start,
+			start.AddDate(0, 0, 2),
+			start.AddDate(0, 0, 4),
+			start.AddDate(0, 0, 7),
+		}, rule.Occurrences(start, 4))
+	})
+
+	t.Run("CountLimited", func(t *testing.T) {
+		rule, err := recurrence.Parse("FREQ=DAILY;INTERVAL=2;COUNT=3")
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 4)}, rule.Occurrences(start, 10))
+	})
+
+	t.Run("MonthlySkipsShortMonths", func(t *testing.T) {
+		jan31 := time.Date(2027, 1, 31, 9, 0, 0, 0, time.UTC)
+		rule, err := recurrence.Parse("FREQ=MONTHLY")
+		require.NoError(t, err)
+		occurrences := rule.Occurrences(jan31, 3)
+		assert.Equal(t, []time.Month{time.January, time.March, time.May},
+			[]time.Month{occurrences[0].Month(), occurrences[1].Month(), occurrences[2].Month()})
+	})
+}
+
+func TestTaskService_SetTaskRecurrence(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	task := createTestTask("Water plants", nil, 3)
+	due := time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC)
+	task.DueAt = &due
+
+	store := memstore.New(memstore.WithTasks(task))
+	service, _ := newMemstoreServices(store)
+	service.SetClock(clock.NewFake(now))
+
+	t.Run("StoresCanonicalRule", func(t *testing.T) {
+		rule, err := recurrence.ParseEvery("fri, mon and wed")
+		require.NoError(t, err)
+
+		updated, err := service.SetTaskRecurrence(task.ID, "test-user-id", rule)
+		require.NoError(t, err)
+		require.NotNil(t, updated.RecurrenceRule)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE,FR", *updated.RecurrenceRule)
+
+		stored, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+		parsed, err := recurrence.Parse(*stored.RecurrenceRule)
+		require.NoError(t, err)
+		assert.Equal(t, rule.String(), parsed.String())
+	})
+
+	t.Run("PreviewsUpcomingOccurrences", func(t *testing.T) {
+		stored, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+
+		next, err := service.NextOccurrences(*stored, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{
+			due.AddDate(0, 0, 4), // Friday Oct 16; Monday and Wednesday have passed
+			due.AddDate(0, 0, 7),
+			due.AddDate(0, 0, 9),
+		}, next)
+	})
+
+	t.Run("ClearsRule", func(t *testing.T) {
+		updated, err := service.SetTaskRecurrence(task.ID, "test-user-id", nil)
+		require.NoError(t, err)
+		assert.Nil(t, updated.RecurrenceRule)
+	})
+
+	t.Run("CreateRejectsInvalidRule", func(t *testing.T) {
+		req := memstoreTaskRequest("Stretch")
+		rule := "FREQ=SOMETIMES"
+		req.RecurrenceRule = &rule
+
+		_, err := service.CreateTask("test-user-id", req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "recurrence_rule")
+		assert.Contains(t, err.Error(), "want DAILY, WEEKLY, MONTHLY or YEARLY")
+	})
+
+}
+
+func TestTaskService_CompleteRecurringTask(t *testing.T) {
+	due := time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC) // Monday
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+
+	setup := func(t *testing.T, rrule string, completedAt time.Time) (*memstore.Store, *hereandnow.TaskService, *models.Task) {
+		store := memstore.New(memstore.WithLocations(home))
+		service, _ := newMemstoreServices(store)
+		service.SetClock(clock.NewFake(completedAt))
+
+		req := memstoreTaskRequest("Water plants")
+		req.DueAt = &due
+		req.RecurrenceRule = &rrule
+		req.LocationIDs = []string{home.ID}
+		req.LocationTrigger = models.LocationTriggerExit
+		task, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+		return store, service, task
+	}
+
+	// pending returns the open instances that follow the given task
+	pending := func(t *testing.T, store *memstore.Store, parentID string) []models.Task {
+		tasks, err := store.Tasks().GetByStatus("test-user-id", models.TaskStatusPending)
+		require.NoError(t, err)
+		var instances []models.Task
+		for _, task := range tasks {
+			if task.ParentTaskID != nil && *task.ParentTaskID == parentID {
+				instances = append(instances, task)
+			}
+		}
+		return instances
+	}
+
+	t.Run("CreatesNextInstanceFromDueDate", func(t *testing.T) {
+		// Completed three days late
+		store, service, task := setup(t, "FREQ=WEEKLY;BYDAY=MO,TH", due.AddDate(0, 0, 3))
+
+		completed, err := service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, completed.Status)
+
+		instances := pending(t, store, task.ID)
+		require.Len(t, instances, 1)
+		next := instances[0]
+		assert.Equal(t, "Water plants", next.Title)
+		assert.Equal(t, due.AddDate(0, 0, 3), *next.DueAt) // Thursday after the original Monday
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,TH", *next.RecurrenceRule)
+
+		links, err := store.TaskLocations().GetTaskLocationsByTaskID(next.ID)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, home.ID, links[0].LocationID)
+		assert.Equal(t, models.LocationTriggerExit, links[0].Trigger)
+
+		// Instances of instances still point at the task the series began with
+		_, err = service.CompleteTask(next.ID, "test-user-id")
+		require.NoError(t, err)
+		instances = pending(t, store, task.ID)
+		require.Len(t, instances, 1)
+		assert.Equal(t, due.AddDate(0, 0, 7), *instances[0].DueAt)
+	})
+
+	t.Run("CanScheduleFromCompletion", func(t *testing.T) {
+		completedAt := time.Date(2026, 10, 20, 21, 30, 0, 0, time.UTC)
+		store, service, task := setup(t, "FREQ=DAILY;INTERVAL=2", completedAt)
+		service.SetRecurrenceBasis(hereandnow.RecurFromCompletion)
+
+		_, err := service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+
+		instances := pending(t, store, task.ID)
+		require.Len(t, instances, 1)
+		assert.Equal(t, time.Date(2026, 10, 22, 18, 0, 0, 0, time.UTC), *instances[0].DueAt)
+	})
+
+	t.Run("CountDecrementsUntilSeriesEnds", func(t *testing.T) {
+		store, service, task := setup(t, "FREQ=DAILY;COUNT=3", due)
+
+		current := task
+		var rules []string
+		for {
+			_, err := service.CompleteTask(current.ID, "test-user-id")
+			require.NoError(t, err)
+			instances := pending(t, store, task.ID)
+			if len(instances) == 0 {
+				break
+			}
+			require.Len(t, instances, 1)
+			current = &instances[0]
+			rules = append(rules, *current.RecurrenceRule)
+		}
+		assert.Equal(t, []string{"FREQ=DAILY;COUNT=2", "FREQ=DAILY;COUNT=1"}, rules)
+		assert.Equal(t, due.AddDate(0, 0, 2), *current.DueAt)
+	})
+
+	t.Run("RecurringSnoozeStillEndsWithCount", func(t *testing.T) {
+		store, service, task := setup(t, "FREQ=DAILY;COUNT=2", due)
+		_, err := service.SnoozeTaskWithPreset(task.ID, "test-user-id", "later-today", true)
+		require.NoError(t, err)
+
+		_, err = service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		instances := pending(t, store, task.ID)
+		require.Len(t, instances, 1)
+		next := instances[0]
+		assert.Equal(t, due.AddDate(0, 0, 1), *next.DueAt)
+		assert.NotNil(t, next.SnoozedUntil, "the recurring snooze carries over")
+
+		completed, err := service.CompleteTask(next.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, completed.Status)
+		assert.Empty(t, pending(t, store, task.ID), "the series ends after COUNT occurrences")
+	})
+
+	t.Run("UndoRemovesNextInstance", func(t *testing.T) {
+		store, service, task := setup(t, "FREQ=DAILY", due)
+		service.EnableUndo(store.TaskActions())
+
+		_, err := service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		require.Len(t, pending(t, store, task.ID), 1)
+
+		_, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+
+		open, err := store.Tasks().GetByStatus("test-user-id", models.TaskStatusPending)
+		require.NoError(t, err)
+		require.Len(t, open, 1, "exactly one pending occurrence remains")
+		assert.Equal(t, task.ID, open[0].ID)
+	})
+
+	t.Run("UntilEndsSeries", func(t *testing.T) {
+		store, service, task := setup(t, "FREQ=WEEKLY;UNTIL=20261015T000000Z", due)
+
+		_, err := service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Empty(t, pending(t, store, task.ID))
+	})
+
+	t.Run("DeleteCanCascadeToOpenInstances", func(t *testing.T) {
+		store, service, task := setup(t, "FREQ=DAILY", due)
+		_, err := service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		first := pending(t, store, task.ID)[0]
+		_, err = service.CompleteTask(first.ID, "test-user-id")
+		require.NoError(t, err)
+		require.Len(t, pending(t, store, task.ID), 1)
+
+		deleted, err := service.DeleteTaskWithInstances(task.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Empty(t, pending(t, store, task.ID))
+
+		_, err = store.Tasks().GetByID(task.ID)
+		assert.Error(t, err)
+		kept, err := store.Tasks().GetByID(first.ID)
+		require.NoError(t, err, "completed instances are kept")
+		assert.Equal(t, models.TaskStatusCompleted, kept.Status)
+	})
+
+	t.Run("PlainDeleteKeepsInstances", func(t *testing.T) {
+		store, service, task := setup(t, "FREQ=DAILY", due)
+		_, err := service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+
+		require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+		assert.Len(t, pending(t, store, task.ID), 1)
+	})
+}
+
+func TestRecurrence_ExpandRecurrence(t *testing.T) {
+	due := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC) // Monday
+	recurring := func(rrule string) models.Task {
+		task := createTestTask("Stand-up", nil, 3)
+		task.DueAt = &due
+		task.RecurrenceRule = &rrule
+		return task
+	}
+	dueDates := func(instances []models.Task) []time.Time {
+		dates := make([]time.Time, len(instances))
+		for i, instance := range instances {
+			dates[i] = *instance.DueAt
+		}
+		return dates
+	}
+
+	t.Run("DailyWithInterval", func(t *testing.T) {
+		task := recurring("FREQ=DAILY;INTERVAL=2")
+		instances, err := recurrence.ExpandRecurrence(task, due, due.AddDate(0, 0, 7))
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{due, due.AddDate(0, 0, 2), due.AddDate(0, 0, 4), due.AddDate(0, 0, 6)}, dueDates(instances))
+
+		ids := map[string]bool{}
+		for _, instance := range instances {
+			assert.NotEqual(t, task.ID, instance.ID)
+			assert.False(t, ids[instance.ID])
+			ids[instance.ID] = true
+			require.NotNil(t, instance.ParentTaskID)
+			assert.Equal(t, task.ID, *instance.ParentTaskID)
+			assert.Nil(t, instance.RecurrenceRule)
+			assert.Equal(t, "Stand-up", instance.Title)
+		}
+		assert.Equal(t, due, *task.DueAt, "the task itself is unchanged")
+	})
+
+	t.Run("WeeklyByWeekday", func(t *testing.T) {
+		instances, err := recurrence.ExpandRecurrence(recurring("FREQ=WEEKLY;BYDAY=MO,WE"), due.AddDate(0, 0, 1), due.AddDate(0, 0, 14))
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{due.AddDate(0, 0, 2), due.AddDate(0, 0, 7), due.AddDate(0, 0, 9)}, dueDates(instances))
+	})
+
+	t.Run("CountLimited", func(t *testing.T) {
+		instances, err := recurrence.ExpandRecurrence(recurring("FREQ=DAILY;COUNT=3"), due, due.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		assert.Equal(t, []time.Time{due, due.AddDate(0, 0, 1), due.AddDate(0, 0, 2)}, dueDates(instances))
+
+		// COUNT applies to the series, not to the window
+		instances, err = recurrence.ExpandRecurrence(recurring("FREQ=DAILY;COUNT=3"), due.AddDate(0, 0, 2), due.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		assert.Len(t, instances, 1)
+	})
+
+	t.Run("UntilLimited", func(t *testing.T) {
+		instances, err := recurrence.ExpandRecurrence(recurring("FREQ=WEEKLY;UNTIL=20261026T090000Z"), due, due.AddDate(0, 2, 0))
+		require.NoError(t, err)
+		assert.Len(t, instances, 3)
+	})
+
+	t.Run("InvalidOrMissingRule", func(t *testing.T) {
+		_, err := recurrence.ExpandRecurrence(recurring("FREQ=HOURLY"), due, due.AddDate(0, 0, 7))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid recurrence rule")
+
+		_, err = recurrence.ExpandRecurrence(createTestTask("One-off", nil, 3), due, due.AddDate(0, 0, 7))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no recurrence rule")
+	})
+}
+
+func TestTaskService_FilteredRecurringTaskGetsNextDueDate(t *testing.T) {
+	created := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC) // Monday
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)    // Thursday
+
+	rrule := "FREQ=WEEKLY;BYDAY=MO,FR"
+	undated := createTestTask("Water plants", nil, 3)
+	undated.CreatedAt = created
+	undated.RecurrenceRule = &rrule
+
+	store := memstore.New(memstore.WithTasks(undated))
+	service, contextService := newMemstoreServices(store)
+	service.SetClock(clock.NewFake(now))
+	_, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{AvailableMinutes: 60, EnergyLevel: 3})
+	require.NoError(t, err)
+
+	tasks, _, err := service.GetFilteredTasks("test-user-id")
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, undated.ID, tasks[0].ID)
+	require.NotNil(t, tasks[0].DueAt)
+	assert.Equal(t, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), *tasks[0].DueAt)
+
+	stored, err := store.Tasks().GetByID(undated.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.DueAt, "the next due date is not stored")
+}
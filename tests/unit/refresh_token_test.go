@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTRefreshTokens(t *testing.T) {
+	jwtService := auth.NewJWTService("test-secret-key-32-chars-long!!")
+
+	t.Run("PairRefreshesAccess", func(t *testing.T) {
+		access, refresh, err := jwtService.GenerateTokenPair("test-user-id")
+		require.NoError(t, err)
+		assert.NotEqual(t, access, refresh)
+
+		claims, err := jwtService.ValidateToken(access)
+		require.NoError(t, err)
+		assert.Equal(t, "test-user-id", claims.UserID)
+		assert.WithinDuration(t, time.Now().Add(auth.DefaultAccessTokenDuration), claims.ExpiresAt, 2*time.Second)
+
+		renewed, err := jwtService.RefreshAccessToken(refresh)
+		require.NoError(t, err)
+		assert.NotEqual(t, access, renewed)
+		claims, err = jwtService.ValidateToken(renewed)
+		require.NoError(t, err)
+		assert.Equal(t, "test-user-id", claims.UserID)
+	})
+
+	t.Run("TokenTypesAreNotInterchangeable", func(t *testing.T) {
+		access, refresh, err := jwtService.GenerateTokenPair("test-user-id")
+		require.NoError(t, err)
+
+		_, err = jwtService.ValidateToken(refresh)
+		assert.Error(t, err, "a refresh token must not grant access")
+
+		_, err = jwtService.RefreshAccessToken(access)
+		assert.ErrorIs(t, err, auth.ErrNotRefreshToken)
+	})
+
+	t.Run("ExpiredRefreshToken", func(t *testing.T) {
+		expiring := auth.NewJWTService("test-secret-key-32-chars-long!!")
+		expiring.SetTokenDurations(time.Minute, -time.Hour)
+
+		_, refresh, err := expiring.GenerateTokenPair("test-user-id")
+		require.NoError(t, err)
+
+		_, err = expiring.RefreshAccessToken(refresh)
+		assert.ErrorIs(t, err, auth.ErrRefreshTokenExpired)
+	})
+
+	t.Run("RejectsOtherSecret", func(t *testing.T) {
+		_, refresh, err := auth.NewJWTService("secret-key-2-32-characters-long").GenerateTokenPair("test-user-id")
+		require.NoError(t, err)
+
+		_, err = jwtService.RefreshAccessToken(refresh)
+		assert.Error(t, err)
+	})
+}
+
+func setupSessionDB(t *testing.T) *storage.DB {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE sessions (
+			id TEXT PRIMARY KEY NOT NULL, token_hash TEXT NOT NULL UNIQUE, refresh_token_hash TEXT,
+			user_id TEXT NOT NULL, device_name TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL, last_used_at DATETIME,
+			expires_at DATETIME NOT NULL,
+			user_agent TEXT DEFAULT '', ip_address TEXT DEFAULT ''
+		);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestAuthRefreshRoute(t *testing.T) {
+	users := &loginUserRepository{authUserRepository{users: map[string]models.User{}}}
+	jwtService := auth.NewJWTService("test-secret-key-32-chars-long!!")
+	sessions := storage.NewSessionRepository(setupSessionDB(t))
+	authService := auth.NewAuthService(users, sessions, jwtService, auth.DefaultAuthConfig)
+	user, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{Auth: api.NewAuthHandler(authService)}, api.RouteConfig{})
+
+	t.Run("IssuesUsableAccessToken", func(t *testing.T) {
+		login, err := authService.Login(auth.LoginRequest{Email: "alice@example.com", Password: "correct-horse"}, "test-agent", "127.0.0.1")
+		require.NoError(t, err)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response api.LoginResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, user.ID, response.User.ID)
+		assert.Empty(t, response.RefreshToken)
+
+		refreshed, err := authService.ValidateToken(response.Token)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, refreshed.ID)
+
+		_, err = authService.ValidateToken(login.Token)
+		assert.Error(t, err, "the session's previous access token is replaced")
+
+		stored, err := sessions.GetByUserID(user.ID)
+		require.NoError(t, err)
+		assert.Len(t, stored, 1, "refreshing reuses the login's session")
+	})
+
+	t.Run("RejectsUnboundRefreshToken", func(t *testing.T) {
+		_, refresh, err := jwtService.GenerateTokenPair(user.ID)
+		require.NoError(t, err)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+refresh+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid refresh token")
+	})
+
+	t.Run("RejectsAccessToken", func(t *testing.T) {
+		access, _, err := jwtService.GenerateTokenPair(user.ID)
+		require.NoError(t, err)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+access+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid refresh token")
+	})
+
+	t.Run("ExpiredRefreshToken", func(t *testing.T) {
+		expiring := auth.NewJWTService("test-secret-key-32-chars-long!!")
+		expiring.SetTokenDurations(time.Minute, -time.Hour)
+		_, refresh, err := expiring.GenerateTokenPair("test-user-id")
+		require.NoError(t, err)
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/refresh", `{"refresh_token":"`+refresh+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Refresh token expired")
+	})
+
+	t.Run("RequiresToken", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/refresh", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemindAt(t *testing.T) {
+	due := time.Date(2026, 3, 2, 17, 0, 0, 0, time.UTC)
+
+	at, err := models.ParseRemindAt("30m before due", &due, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, due.Add(-30*time.Minute), at)
+
+	at, err = models.ParseRemindAt("1d before due", &due, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, due.Add(-24*time.Hour), at)
+
+	at, err = models.ParseRemindAt("2026-03-01 20:00", nil, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC), at)
+
+	_, err = models.ParseRemindAt("30m before due", nil, time.UTC)
+	assert.Error(t, err, "relative reminders need a due date")
+
+	_, err = models.ParseRemindAt("soonish", &due, time.UTC)
+	assert.Error(t, err)
+}
+
+func TestReminderService(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) (*memstore.Store, *clock.Fake, func() *hereandnow.ReminderService) {
+		store := memstore.New()
+		fake := clock.NewFake(start)
+		newService := func() *hereandnow.ReminderService {
+			service := hereandnow.NewReminderService(store.Reminders(), store.Tasks(), store.Notifications())
+			service.SetClock(fake)
+			return service
+		}
+		return store, fake, newService
+	}
+	newTask := func(t *testing.T, store *memstore.Store, title string) *models.Task {
+		task, err := models.NewTask(title, "", "test-user-id")
+		require.NoError(t, err)
+		due := start.Add(8 * time.Hour)
+		task.DueAt = &due
+		require.NoError(t, store.Tasks().Create(*task))
+		return task
+	}
+
+	t.Run("RejectsPastTimes", func(t *testing.T) {
+		store, _, newService := setup(t)
+		task := newTask(t, store, "Submit expenses")
+
+		_, err := newService().AddReminder("test-user-id", task.ID, start.Add(-time.Minute))
+		assert.Error(t, err)
+		_, err = newService().AddReminder("test-user-id", "missing-task", start.Add(time.Hour))
+		assert.Error(t, err)
+	})
+
+	t.Run("SendsEachReminderOnceAcrossSchedulers", func(t *testing.T) {
+		store, fake, newService := setup(t)
+		expenses := newTask(t, store, "Submit expenses")
+		report := newTask(t, store, "Write report")
+
+		service := newService()
+		_, err := service.AddReminder("test-user-id", expenses.ID, start.Add(30*time.Minute))
+		require.NoError(t, err)
+		_, err = service.AddReminder("test-user-id", expenses.ID, start.Add(2*time.Hour))
+		require.NoError(t, err)
+		_, err = service.AddReminder("test-user-id", report.ID, start.Add(45*time.Minute))
+		require.NoError(t, err)
+
+		// Several servers tick every five minutes over the same store
+		schedulers := []*hereandnow.ReminderService{newService(), newService(), newService()}
+		tick := func() int {
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			total := 0
+			for _, scheduler := range schedulers {
+				wg.Add(1)
+				go func(scheduler *hereandnow.ReminderService) {
+					defer wg.Done()
+					sent, err := scheduler.SendDue()
+					assert.NoError(t, err)
+					mu.Lock()
+					total += sent
+					mu.Unlock()
+				}(scheduler)
+			}
+			wg.Wait()
+			return total
+		}
+
+		sentBy := make(map[time.Duration]int)
+		for elapsed := time.Duration(0); elapsed <= 3*time.Hour; elapsed += 5 * time.Minute {
+			fake.Set(start.Add(elapsed))
+			if sent := tick(); sent > 0 {
+				sentBy[elapsed] = sent
+			}
+		}
+		assert.Equal(t, map[time.Duration]int{
+			30 * time.Minute: 1,
+			45 * time.Minute: 1,
+			2 * time.Hour:    1,
+		}, sentBy, "each reminder goes out once, on the first tick after it is due")
+
+		notifications, err := store.Notifications().GetByUserID("test-user-id", false)
+		require.NoError(t, err)
+		require.Len(t, notifications, 3)
+		for _, notification := range notifications {
+			assert.Equal(t, models.NotificationTypeTaskReminder, notification.Type)
+			assert.Contains(t, notification.Message, "(due Mon Mar 2 17:00)")
+		}
+
+		reminders, err := service.GetReminders(expenses.ID)
+		require.NoError(t, err)
+		require.Len(t, reminders, 2)
+		for _, reminder := range reminders {
+			assert.True(t, reminder.Sent)
+			require.NotNil(t, reminder.SentAt)
+			assert.False(t, reminder.SentAt.Before(reminder.RemindAt))
+		}
+	})
+
+	t.Run("SkipsCancelledTasks", func(t *testing.T) {
+		store, fake, newService := setup(t)
+		task := newTask(t, store, "Submit expenses")
+		service := newService()
+		_, err := service.AddReminder("test-user-id", task.ID, start.Add(time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, task.SetStatus(models.TaskStatusCancelled))
+		require.NoError(t, store.Tasks().Update(*task))
+
+		fake.Advance(2 * time.Hour)
+		sent, err := service.SendDue()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+
+		reminders, err := service.GetReminders(task.ID)
+		require.NoError(t, err)
+		assert.True(t, reminders[0].Sent, "claimed so it isn't checked again")
+	})
+}
@@ -0,0 +1,703 @@
+package unit
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// repositorySchema is the part of the schema the repository suite uses,
+// written for SQLite and translated for the database's dialect
+const repositorySchema = `
+	CREATE TABLE tasks (
+		id TEXT PRIMARY KEY NOT NULL, title TEXT NOT NULL, description TEXT DEFAULT '',
+		creator_id TEXT NOT NULL, assignee_id TEXT NULL, list_id TEXT NULL,
+		status TEXT NOT NULL DEFAULT 'pending', priority INTEGER NOT NULL DEFAULT 3,
+		estimated_minutes INTEGER NULL, effort_points INTEGER NULL, required_energy_level INTEGER NULL,
+		due_at DATETIME NULL, completed_at DATETIME NULL,
+		created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, metadata TEXT DEFAULT '{}',
+		recurrence_rule TEXT NULL, parent_task_id TEXT NULL, position REAL NOT NULL DEFAULT 0,
+		snoozed_until DATETIME NULL, recurring_snooze TEXT NULL, deleted_at DATETIME NULL,
+		version INTEGER NOT NULL DEFAULT 1
+	);
+	CREATE TABLE locations (
+		id TEXT PRIMARY KEY NOT NULL, user_id TEXT NOT NULL, name TEXT NOT NULL,
+		address TEXT DEFAULT '', latitude REAL NOT NULL, longitude REAL NOT NULL,
+		radius INTEGER NOT NULL DEFAULT 100, category TEXT DEFAULT 'other', place_id TEXT NULL, open_hours TEXT NULL,
+		metadata TEXT DEFAULT '{}', created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL
+	);
+	CREATE TABLE contexts (
+		id TEXT PRIMARY KEY NOT NULL, user_id TEXT NOT NULL, timestamp DATETIME NOT NULL,
+		current_location_id TEXT NULL
+	);
+	CREATE TABLE task_locations (
+		id TEXT PRIMARY KEY NOT NULL, task_id TEXT NOT NULL, location_id TEXT NOT NULL,
+		is_required BOOLEAN NOT NULL DEFAULT TRUE, trigger_type TEXT NOT NULL DEFAULT 'enter',
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE task_dependencies (
+		id TEXT PRIMARY KEY NOT NULL, task_id TEXT NOT NULL, depends_on_task_id TEXT NOT NULL,
+		dependency_type TEXT NOT NULL DEFAULT 'blocking', created_at DATETIME NOT NULL
+	);
+	CREATE TABLE filter_audit (
+		id TEXT PRIMARY KEY NOT NULL, user_id TEXT NOT NULL, task_id TEXT NOT NULL,
+		context_id TEXT NOT NULL, is_visible BOOLEAN NOT NULL, reasons TEXT NOT NULL,
+		priority_score REAL NOT NULL DEFAULT 0.0, created_at DATETIME NOT NULL
+	);
+	CREATE TABLE task_tags (
+		task_id TEXT NOT NULL, tag TEXT NOT NULL, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (task_id, tag)
+	);
+	CREATE TABLE task_reminders (
+		id TEXT PRIMARY KEY NOT NULL, task_id TEXT NOT NULL, user_id TEXT NOT NULL,
+		remind_at DATETIME NOT NULL, sent BOOLEAN NOT NULL DEFAULT FALSE, sent_at DATETIME NULL,
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE list_members (
+		id TEXT PRIMARY KEY NOT NULL, list_id TEXT NOT NULL, user_id TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'viewer', invited_by TEXT NOT NULL,
+		invited_at DATETIME NOT NULL, accepted_at DATETIME NULL
+	);
+	CREATE TABLE task_history (
+		id TEXT PRIMARY KEY NOT NULL, task_id TEXT NOT NULL, actor_id TEXT NULL,
+		changes TEXT NOT NULL, created_at DATETIME NOT NULL
+	);
+`
+
+// sqliteSearchSchema adds the FTS5 tables SQLite searches, which need a
+// build of go-sqlite3 with the sqlite_fts5 tag
+const sqliteSearchSchema = `
+	CREATE VIRTUAL TABLE tasks_fts USING fts5(title, description, content='tasks', content_rowid='rowid');
+	CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts(rowid, title, description) VALUES (new.rowid, new.title, new.description);
+	END;
+	CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+		INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+	END;
+	CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+		INSERT INTO tasks_fts(tasks_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+		INSERT INTO tasks_fts(rowid, title, description) VALUES (new.rowid, new.title, new.description);
+	END;
+	CREATE VIRTUAL TABLE locations_fts USING fts5(name, address, content='locations', content_rowid='rowid');
+	CREATE TRIGGER locations_fts_insert AFTER INSERT ON locations BEGIN
+		INSERT INTO locations_fts(rowid, name, address) VALUES (new.rowid, new.name, new.address);
+	END;
+`
+
+// createRepositorySchema creates the suite's tables and reports whether the
+// database can run full-text searches
+func createRepositorySchema(t *testing.T, db *storage.DB) bool {
+	_, err := db.Exec(db.Dialect().TranslateDDL(repositorySchema))
+	require.NoError(t, err)
+
+	if db.Dialect() == storage.DialectPostgres {
+		return true
+	}
+	_, err = db.Exec(sqliteSearchSchema)
+	return err == nil
+}
+
+// runRepositorySuite runs the same repository tests against any database,
+// so each storage backend is held to the same behaviour
+func runRepositorySuite(t *testing.T, db *storage.DB) {
+	textSearch := createRepositorySchema(t, db)
+	// SQLite has no trigonometric functions without sqlite_math_functions
+	_, err := db.Exec("SELECT acos(1.0)")
+	trigonometry := err == nil
+
+	tasks := storage.NewTaskRepository(db)
+	locations := storage.NewLocationRepository(db)
+
+	newTask := func(title, description string, priority int) *models.Task {
+		task, err := models.NewTask(title, description, "user-1")
+		require.NoError(t, err)
+		task.Priority = priority
+		task.CreatedAt = task.CreatedAt.Truncate(time.Second)
+		task.UpdatedAt = task.CreatedAt
+		require.NoError(t, tasks.Create(task))
+		return task
+	}
+	newLocation := func(name, address string, lat, lng float64, radius int) *models.Location {
+		location, err := models.NewLocation("user-1", name, address, lat, lng, radius)
+		require.NoError(t, err)
+		require.NoError(t, locations.Create(location))
+		return location
+	}
+
+	groceries := newTask("Buy groceries", "Milk, eggs and bread", 4)
+	newTask("Call plumber", "The kitchen sink is leaking", 2)
+	newTask("Water plants", "", 3)
+
+	home := newLocation("Home", "12 Oak Street", 40.7128, -74.0060, 100)
+	newLocation("Office", "1 Market Street", 40.7580, -73.9855, 200)
+	newLocation("Cabin", "Lake Road", 44.2601, -72.5754, 500)
+
+	t.Run("TaskRoundTrip", func(t *testing.T) {
+		got, err := tasks.GetByID(groceries.ID)
+		require.NoError(t, err)
+		assert.Equal(t, groceries.Title, got.Title)
+		assert.Equal(t, groceries.Description, got.Description)
+		assert.Equal(t, 4, got.Priority)
+		assert.True(t, groceries.CreatedAt.Equal(got.CreatedAt), "created at %v, read back %v", groceries.CreatedAt, got.CreatedAt)
+
+		got.Title = "Buy groceries and flowers"
+		require.NoError(t, tasks.Update(got))
+		got, err = tasks.GetByID(groceries.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Buy groceries and flowers", got.Title)
+	})
+
+	t.Run("TaskUpdateConflicts", func(t *testing.T) {
+		task := newTask("Plan the party", "", 3)
+		defer func() {
+			_, err := db.Exec("DELETE FROM tasks WHERE id = ?", task.ID)
+			require.NoError(t, err)
+		}()
+
+		// Two people read the same version and save their edits at once
+		first, err := tasks.GetByID(task.ID)
+		require.NoError(t, err)
+		second, err := tasks.GetByID(task.ID)
+		require.NoError(t, err)
+		first.Title = "Plan the birthday party"
+		second.Priority = 5
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, edit := range []*models.Task{first, second} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = tasks.Update(edit)
+			}()
+		}
+		wg.Wait()
+
+		saved := 0
+		for _, err := range errs {
+			if err == nil {
+				saved++
+				continue
+			}
+			assert.ErrorIs(t, err, models.ErrTaskVersionConflict)
+		}
+		assert.Equal(t, 1, saved, "only one of the concurrent edits is saved")
+
+		got, err := tasks.GetByID(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, task.Version+1, got.Version)
+		assert.True(t, got.Title == first.Title || got.Priority == 5)
+		assert.False(t, got.Title == first.Title && got.Priority == 5, "the edits are not merged")
+
+		// The loser retries from the version now stored
+		got.Priority = 5
+		require.NoError(t, tasks.Update(got))
+		assert.Equal(t, task.Version+2, got.Version)
+	})
+
+	t.Run("TaskSearchAndCount", func(t *testing.T) {
+		results, err := tasks.Search(storage.TaskSearchOptions{UserID: "user-1", OrderBy: "priority", OrderDirection: "DESC"})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, groceries.ID, results[0].ID)
+
+		priority := 2
+		results, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-1", Priority: &priority})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Call plumber", results[0].Title)
+
+		pending := models.TaskStatusPending
+		count, err := tasks.Count(storage.TaskSearchOptions{UserID: "user-1", Status: &pending})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("TaskSearchPagesWithCursor", func(t *testing.T) {
+		all, err := tasks.Search(storage.TaskSearchOptions{UserID: "user-1"})
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+
+		first, err := tasks.SearchPage(storage.TaskSearchOptions{UserID: "user-1", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, first.Tasks, 2)
+		require.NotEmpty(t, first.NextCursor)
+
+		// A task added between pages doesn't shift the next one
+		added, err := models.NewTask("Renew passport", "", "user-1")
+		require.NoError(t, err)
+		added.CreatedAt = added.CreatedAt.Truncate(time.Second).Add(time.Hour)
+		require.NoError(t, tasks.Create(added))
+		defer func() {
+			_, err := db.Exec("DELETE FROM tasks WHERE id = ?", added.ID)
+			require.NoError(t, err)
+		}()
+
+		second, err := tasks.SearchPage(storage.TaskSearchOptions{UserID: "user-1", Limit: 2, AfterCursor: first.NextCursor})
+		require.NoError(t, err)
+		require.Len(t, second.Tasks, 1)
+		assert.Equal(t, all[2].ID, second.Tasks[0].ID)
+		assert.Empty(t, second.NextCursor, "last page")
+
+		ascending, err := tasks.SearchPage(storage.TaskSearchOptions{UserID: "user-1", Limit: 1, OrderBy: "created_at", OrderDirection: "ASC", AfterCursor: first.NextCursor})
+		require.NoError(t, err)
+		require.Len(t, ascending.Tasks, 1)
+		assert.Equal(t, all[0].ID, ascending.Tasks[0].ID)
+		assert.NotEmpty(t, ascending.NextCursor)
+
+		_, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-1", AfterCursor: "not a cursor"})
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor)
+		_, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-1", AfterCursor: first.NextCursor, OrderBy: "priority"})
+		assert.Error(t, err, "cursors follow created_at")
+		_, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-1", AfterCursor: first.NextCursor, Offset: 1})
+		assert.Error(t, err)
+
+		page, err := tasks.SearchPage(storage.TaskSearchOptions{UserID: "user-1", Limit: 1, OrderBy: "priority"})
+		require.NoError(t, err)
+		assert.Empty(t, page.NextCursor, "no cursor for other orderings")
+	})
+
+	t.Run("TaskCreateBatch", func(t *testing.T) {
+		newBatch := func(titles ...string) []*models.Task {
+			var batch []*models.Task
+			for _, title := range titles {
+				task, err := models.NewTask("placeholder", "", "user-4")
+				require.NoError(t, err)
+				task.Title = title
+				batch = append(batch, task)
+			}
+			return batch
+		}
+		count := func() int {
+			n, err := tasks.Count(storage.TaskSearchOptions{UserID: "user-4"})
+			require.NoError(t, err)
+			return n
+		}
+
+		assert.Error(t, tasks.CreateBatch(newBatch("Milk", "", "Eggs")), "an invalid task fails the batch")
+		assert.Zero(t, count())
+
+		batch := newBatch("Milk", "Eggs", "Bread")
+		require.NoError(t, tasks.CreateBatch(batch))
+		assert.Equal(t, 3, count())
+		got, err := tasks.GetByID(batch[2].ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Bread", got.Title)
+
+		assert.Error(t, tasks.CreateBatch(append(newBatch("Butter"), batch[0])), "a duplicate ID rolls back the insert")
+		assert.Equal(t, 3, count())
+	})
+
+	t.Run("TaskAnalytics", func(t *testing.T) {
+		analyticsRepo := storage.NewAnalyticsRepository(db)
+		since := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+		until := since.AddDate(0, 0, 3)
+
+		addTask := func(priority int, created time.Time, completed *time.Time, deleted bool) {
+			task, err := models.NewTask("Analytics task", "", "user-2")
+			require.NoError(t, err)
+			task.Priority = priority
+			task.CreatedAt, task.UpdatedAt = created, created
+			if completed != nil {
+				task.Status = models.TaskStatusCompleted
+				task.CompletedAt = completed
+			}
+			if deleted {
+				task.DeletedAt = &created
+			}
+			require.NoError(t, tasks.Create(task))
+		}
+		at := func(d time.Duration) *time.Time {
+			t := since.Add(d)
+			return &t
+		}
+
+		addTask(4, since.Add(time.Hour), at(3*time.Hour), false)
+		addTask(4, since.Add(2*time.Hour), nil, false)
+		addTask(2, since.Add(24*time.Hour), at(28*time.Hour), false)
+		addTask(2, since.Add(-48*time.Hour), at(-24*time.Hour), false)
+		addTask(2, since.Add(time.Hour), at(2*time.Hour), true)
+
+		_, err := db.Exec("INSERT INTO contexts (id, user_id, timestamp, current_location_id) VALUES (?, ?, ?, ?), (?, ?, ?, ?)",
+			"context-1", "user-2", since.Add(2*time.Hour+50*time.Minute), home.ID,
+			"context-2", "user-2", since.Add(25*time.Hour), nil)
+		require.NoError(t, err)
+
+		analytics, err := analyticsRepo.GetTaskAnalytics("user-2", since, until)
+		require.NoError(t, err)
+		assert.Equal(t, 3, analytics.TasksCreated)
+		assert.Equal(t, 2, analytics.TasksCompleted)
+		assert.InDelta(t, 180, analytics.AverageMinutesToComplete, 0.01)
+		assert.Equal(t, []models.DayCompletions{
+			{Date: "2026-03-02", Completed: 1}, {Date: "2026-03-03", Completed: 1}, {Date: "2026-03-04", Completed: 0},
+		}, analytics.CompletionsByDay)
+		assert.Equal(t, 1, analytics.CompletionsByHour[3])
+		assert.Equal(t, 1, analytics.CompletionsByHour[4])
+		assert.Equal(t, models.PriorityCompletions{Priority: 2, Created: 1, Completed: 1, CompletionRate: 1}, analytics.ByPriority[1])
+		assert.Equal(t, models.PriorityCompletions{Priority: 4, Created: 2, Completed: 1, CompletionRate: 0.5}, analytics.ByPriority[3])
+		assert.ElementsMatch(t, []models.LocationCompletions{
+			{LocationID: home.ID, Name: "Home", Completed: 1},
+			{Completed: 1},
+		}, analytics.ByLocation)
+
+		empty, err := analyticsRepo.GetTaskAnalytics("user-3", since, until)
+		require.NoError(t, err)
+		assert.Zero(t, empty.TasksCompleted)
+		assert.Zero(t, empty.AverageMinutesToComplete)
+		assert.Len(t, empty.CompletionsByDay, 3)
+		assert.Len(t, empty.ByPriority, 5)
+		assert.Empty(t, empty.ByLocation)
+	})
+
+	// Without FTS5, SQLite falls back to matching words with LIKE
+	t.Run("TaskFullTextSearch", func(t *testing.T) {
+		results, err := tasks.FullTextSearch("user-1", "sink", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Call plumber", results[0].Title)
+
+		count, err := tasks.Count(storage.TaskSearchOptions{UserID: "user-1", Query: "plants"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		results, err = tasks.FullTextSearch("user-1", "flow*", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1, "a trailing * matches a prefix")
+		assert.Equal(t, groceries.ID, results[0].ID)
+
+		results, err = tasks.FullTextSearch("user-1", `"sink" OR (leaking`, 10, 0)
+		require.NoError(t, err, "query syntax is not passed through")
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("TaskSearchIncludesSharedLists", func(t *testing.T) {
+		listID := "shared-list"
+		shared, err := models.NewTask("Buy stamps", "", "user-2")
+		require.NoError(t, err)
+		shared.ListID = &listID
+		require.NoError(t, tasks.Create(shared))
+		private, err := models.NewTask("Buy more stamps", "", "user-2")
+		require.NoError(t, err)
+		require.NoError(t, tasks.Create(private))
+		defer func() {
+			_, err := db.Exec("DELETE FROM tasks WHERE creator_id = ?", "user-2")
+			require.NoError(t, err)
+		}()
+
+		results, err := tasks.FullTextSearch("user-1", "stamps", 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results, "an unaccepted invitation does not share the list")
+
+		_, err = db.Exec(`INSERT INTO list_members (id, list_id, user_id, role, invited_by, invited_at, accepted_at)
+			VALUES ('member-1', ?, 'user-1', 'editor', 'user-2', ?, ?)`, listID, time.Now(), time.Now())
+		require.NoError(t, err)
+		defer func() {
+			_, err := db.Exec("DELETE FROM list_members")
+			require.NoError(t, err)
+		}()
+
+		results, err = tasks.FullTextSearch("user-1", "stamps", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, shared.ID, results[0].ID)
+	})
+
+	t.Run("TaskSearchRanksMatches", func(t *testing.T) {
+		if !textSearch {
+			t.Skip("ranking needs the sqlite_fts5 build tag")
+		}
+
+		kitchen := newTask("Kitchen sink", "Fix the kitchen sink, the sink drips", 3)
+		defer func() {
+			_, err := db.Exec("DELETE FROM tasks WHERE id = ?", kitchen.ID)
+			require.NoError(t, err)
+		}()
+
+		results, err := tasks.FullTextSearch("user-1", "sink", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, kitchen.ID, results[0].ID, "the task mentioning sink most comes first")
+
+		// Edits are re-indexed and old words forgotten
+		kitchen.Title, kitchen.Description = "Kitchen tap", "Fix the tap"
+		require.NoError(t, tasks.Update(kitchen))
+		results, err = tasks.FullTextSearch("user-1", "sink", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Call plumber", results[0].Title)
+	})
+
+	t.Run("LocationRoundTrip", func(t *testing.T) {
+		got, err := locations.GetByID(home.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Home", got.Name)
+		assert.InDelta(t, 40.7128, got.Latitude, 1e-9)
+		assert.InDelta(t, -74.0060, got.Longitude, 1e-9)
+		assert.Nil(t, got.OpenHours, "always open")
+
+		hours, err := models.ParseOpenHours("Mon-Sat 08:00-21:00; Sun 22:00-02:00")
+		require.NoError(t, err)
+		require.NoError(t, got.SetOpenHours(hours))
+		require.NoError(t, locations.Update(got))
+		got, err = locations.GetByID(home.ID)
+		require.NoError(t, err)
+		assert.Equal(t, hours, got.OpenHours)
+
+		count, err := locations.Count(storage.LocationSearchOptions{UserID: "user-1"})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("LocationFullTextSearch", func(t *testing.T) {
+		if !textSearch {
+			t.Skip("full-text search needs the sqlite_fts5 build tag")
+		}
+
+		results, err := locations.FullTextSearch("user-1", "market", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Office", results[0].Name)
+	})
+
+	t.Run("LocationProximity", func(t *testing.T) {
+		if !trigonometry {
+			t.Skip("distance queries need the sqlite_math_functions build tag")
+		}
+
+		nearby, err := locations.GetNearby("user-1", 40.7128, -74.0060, 10000, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, nearby, 2)
+		assert.Equal(t, "Home", nearby[0].Name)
+		assert.Equal(t, "Office", nearby[1].Name)
+
+		// Exactly on a location must not fall outside acos's domain
+		at, err := locations.FindAtCoordinates("user-1", 40.7128, -74.0060)
+		require.NoError(t, err)
+		require.Len(t, at, 1)
+		assert.Equal(t, home.ID, at[0].ID)
+	})
+
+	t.Run("FilterBatchLookups", func(t *testing.T) {
+		var _ filters.TaskLocationBatchRepository = storage.NewTaskLocationRepository(db)
+		var _ filters.LocationRepository = storage.NewLocationRepository(db)
+		var _ filters.TaskDependencyBatchRepository = storage.NewTaskDependencyRepository(db)
+		var _ filters.FilterAuditBatchRepository = storage.NewFilterAuditRepository(db)
+
+		var ids []string
+		for _, title := range []string{"Mow lawn", "Buy fuel", "Fix mower"} {
+			task, err := models.NewTask(title, "", "user-5")
+			require.NoError(t, err)
+			require.NoError(t, tasks.Create(task))
+			ids = append(ids, task.ID)
+		}
+
+		taskLocations := storage.NewTaskLocationRepository(db)
+		link, err := models.NewTaskLocation(ids[0], home.ID, true)
+		require.NoError(t, err)
+		require.NoError(t, taskLocations.Create(*link))
+
+		dependencies := storage.NewTaskDependencyRepository(db)
+		for _, dependsOn := range []string{ids[1], ids[2]} {
+			dep, err := models.NewTaskDependency(ids[0], dependsOn, models.DependencyTypeBlocking)
+			require.NoError(t, err)
+			require.NoError(t, dependencies.Create(*dep))
+		}
+
+		locationsByTask, err := taskLocations.GetLocationsByTaskIDs(ids)
+		require.NoError(t, err)
+		require.Len(t, locationsByTask[ids[0]], 1)
+		assert.Equal(t, "Home", locationsByTask[ids[0]][0].Name)
+		assert.Empty(t, locationsByTask[ids[1]])
+
+		links, err := taskLocations.GetTaskLocationsByTaskIDs(ids)
+		require.NoError(t, err)
+		require.Len(t, links[ids[0]], 1)
+		assert.Equal(t, models.LocationTriggerEnter, links[ids[0]][0].Trigger)
+
+		depsByTask, err := dependencies.GetDependenciesByTaskIDs(ids)
+		require.NoError(t, err)
+		assert.Len(t, depsByTask[ids[0]], 2)
+		assert.Empty(t, depsByTask[ids[1]])
+
+		audits := storage.NewFilterAuditRepository(db)
+		batch := make([]models.FilterAudit, len(ids))
+		for i, id := range ids {
+			batch[i] = models.FilterAudit{
+				ID: "audit-" + id, UserID: "user-5", TaskID: id, ContextID: "context-1",
+				IsVisible: i > 0, Reasons: []byte(`[]`), CreatedAt: time.Now().Truncate(time.Second),
+			}
+		}
+		require.NoError(t, audits.SaveFilterResults(batch))
+		assert.Error(t, audits.SaveFilterResults(append(batch[:1:1], models.FilterAudit{ID: "audit-new", Reasons: []byte(`[]`)}, batch[0])),
+			"a duplicate ID rolls back the batch")
+
+		saved, err := audits.GetAuditLogByUserID("user-5", time.Now().Add(-time.Hour), 0)
+		require.NoError(t, err)
+		assert.Len(t, saved, 3)
+		forTask, err := audits.GetAuditLogByTaskID(ids[0], 10)
+		require.NoError(t, err)
+		require.Len(t, forTask, 1)
+		assert.False(t, forTask[0].IsVisible)
+		assert.JSONEq(t, `[]`, string(forTask[0].Reasons))
+	})
+
+	t.Run("TaskTags", func(t *testing.T) {
+		newTagged := func(title string, status models.TaskStatus, tags ...string) *models.Task {
+			task, err := models.NewTask(title, "", "user-6")
+			require.NoError(t, err)
+			task.Status = status
+			task.Tags = tags
+			require.NoError(t, tasks.Create(task))
+			return task
+		}
+		ids := func(found []*models.Task) []string {
+			var ids []string
+			for _, task := range found {
+				ids = append(ids, task.ID)
+			}
+			return ids
+		}
+
+		pickup := newTagged("Pick up parcel", models.TaskStatusPending, " Errands", "#URGENT", "errands")
+		assert.Equal(t, []string{"errands", "urgent"}, pickup.Tags, "normalized on create")
+		bank := newTagged("Visit bank", models.TaskStatusPending, "errands")
+		report := newTagged("Write report", models.TaskStatusCompleted, "work", "urgent")
+
+		got, err := tasks.GetByID(pickup.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"errands", "urgent"}, got.Tags)
+
+		found, err := tasks.Search(storage.TaskSearchOptions{UserID: "user-6", Tags: []string{"errands", "URGENT"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{pickup.ID}, ids(found), "all tags by default")
+		assert.Equal(t, []string{"errands", "urgent"}, found[0].Tags, "tags load with search results")
+
+		found, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-6", Tags: []string{"errands", "work"}, MatchAnyTag: true})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{pickup.ID, bank.ID, report.ID}, ids(found))
+
+		completed := models.TaskStatusCompleted
+		found, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-6", Tags: []string{"urgent"}, Status: &completed})
+		require.NoError(t, err)
+		assert.Equal(t, []string{report.ID}, ids(found), "composes with other filters")
+
+		count, err := tasks.Count(storage.TaskSearchOptions{UserID: "user-6", Tags: []string{"errands"}})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		_, err = tasks.Search(storage.TaskSearchOptions{UserID: "user-6", Tags: []string{"  "}})
+		assert.Error(t, err, "empty tags are rejected")
+
+		require.NoError(t, tasks.AddTag(bank.ID, " Finance "))
+		require.NoError(t, tasks.AddTag(bank.ID, "finance"), "adding a tag twice does nothing")
+		assert.Error(t, tasks.AddTag(bank.ID, "#"))
+		tags, err := tasks.GetTags(bank.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"errands", "finance"}, tags)
+
+		require.NoError(t, tasks.RemoveTag(bank.ID, "ERRANDS"))
+		assert.Error(t, tasks.RemoveTag(bank.ID, "errands"), "no longer tagged")
+		tags, err = tasks.GetTags(bank.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"finance"}, tags)
+	})
+
+	t.Run("ReminderClaim", func(t *testing.T) {
+		reminders := storage.NewReminderRepository(db)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		due, err := models.NewReminder(groceries.ID, "user-1", now.Add(-time.Minute))
+		require.NoError(t, err)
+		later, err := models.NewReminder(groceries.ID, "user-1", now.Add(time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, reminders.Create(*due))
+		require.NoError(t, reminders.Create(*later))
+
+		found, err := reminders.GetDue(now, 10)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, due.ID, found[0].ID)
+
+		claimed, err := reminders.Claim(due.ID, now)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = reminders.Claim(due.ID, now)
+		require.NoError(t, err)
+		assert.False(t, claimed, "a reminder is only claimed once")
+
+		found, err = reminders.GetDue(now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+
+		all, err := reminders.GetByTaskID(groceries.ID)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.True(t, all[0].Sent)
+		require.NotNil(t, all[0].SentAt)
+		assert.False(t, all[1].Sent)
+	})
+
+	t.Run("TaskHistory", func(t *testing.T) {
+		history := storage.NewTaskHistoryRepository(db)
+		old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+
+		before := *groceries
+		after := before
+		after.Status = models.TaskStatusCompleted
+		changes, err := models.DiffTasks(before, after)
+		require.NoError(t, err)
+
+		byUser, err := models.NewTaskHistoryEntry(groceries.ID, "user-1", changes)
+		require.NoError(t, err)
+		byUser.CreatedAt = byUser.CreatedAt.Truncate(time.Second)
+		automatic, err := models.NewTaskHistoryEntry(groceries.ID, "", changes)
+		require.NoError(t, err)
+		automatic.CreatedAt = old
+		require.NoError(t, history.Create(*byUser))
+		require.NoError(t, history.Create(*automatic))
+
+		entries, err := history.GetByTaskID(groceries.ID)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, automatic.ID, entries[0].ID, "oldest first")
+		assert.Nil(t, entries[0].ActorID)
+		require.NotNil(t, entries[1].ActorID)
+		assert.Equal(t, "user-1", *entries[1].ActorID)
+		assert.JSONEq(t, `"completed"`, string(entries[1].Changes["status"].New))
+
+		require.NoError(t, history.DeleteOlderThan(time.Now().Add(-time.Hour)))
+		entries, err = history.GetByTaskID(groceries.ID)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, byUser.ID, entries[0].ID)
+	})
+
+	t.Run("TransactionRollback", func(t *testing.T) {
+		err := db.WithTx(func(tx *storage.DB) error {
+			task, err := models.NewTask("Rolled back", "", "user-1")
+			require.NoError(t, err)
+			require.NoError(t, storage.NewTaskRepository(tx).Create(task))
+			return assert.AnError
+		})
+		require.ErrorIs(t, err, assert.AnError)
+
+		count, err := tasks.Count(storage.TaskSearchOptions{UserID: "user-1"})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+}
+
+func TestRepositories_SQLite(t *testing.T) {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	runRepositorySuite(t, db)
+}
@@ -0,0 +1,166 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRoutes(t *testing.T) {
+	users := &loginUserRepository{authUserRepository{users: map[string]models.User{}}}
+	sessions := storage.NewSessionRepository(setupSessionDB(t))
+	authService := auth.NewAuthService(users, sessions,
+		auth.NewJWTService("test-secret-key-32-chars-long!!"), auth.DefaultAuthConfig)
+	_, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{Auth: api.NewAuthHandler(authService)}, api.RouteConfig{})
+
+	serve := func(method, path, token, userAgent, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(userAgent, deviceName string) string {
+		body := `{"username":"alice@example.com","password":"correct-horse","device_name":"` + deviceName + `"}`
+		w := serve(http.MethodPost, "/api/v1/auth/login", "", userAgent, body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response api.LoginResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Token
+	}
+	list := func(token string) api.SessionsResponse {
+		w := serve(http.MethodGet, "/api/v1/auth/sessions", token, "", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response api.SessionsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	laptop := login("Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0", "")
+	phone := login("hereandnow-ios/2.1", "Alice's phone")
+	tablet := login("Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Version/17.0 Safari/604.1", "")
+
+	t.Run("ListsDevicesWithCurrentMarked", func(t *testing.T) {
+		response := list(laptop)
+		require.Equal(t, 3, response.Total)
+
+		names := map[string]bool{}
+		for _, session := range response.Sessions {
+			names[session.DeviceName] = session.Current
+			assert.False(t, session.LastActiveAt.IsZero())
+		}
+		assert.Equal(t, map[string]bool{"Firefox on Linux": true, "Alice's phone": false, "Safari on iPad": false}, names)
+		assert.NotContains(t, serve(http.MethodGet, "/api/v1/auth/sessions", laptop, "", "").Body.String(), "token_hash")
+	})
+
+	t.Run("RecordsLastUse", func(t *testing.T) {
+		stored, err := sessions.GetByTokenHash(auth.SessionTokenHash(laptop))
+		require.NoError(t, err)
+		assert.NotNil(t, stored.LastUsedAt, "set by the listing request")
+	})
+
+	t.Run("RevokedSessionStopsAtOnce", func(t *testing.T) {
+		var phoneID string
+		for _, session := range list(laptop).Sessions {
+			if session.DeviceName == "Alice's phone" {
+				phoneID = session.ID
+			}
+		}
+		require.NotEmpty(t, phoneID)
+
+		w := serve(http.MethodDelete, "/api/v1/auth/sessions/"+phoneID, laptop, "", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/auth/sessions", phone, "", "").Code)
+
+		w = serve(http.MethodDelete, "/api/v1/auth/sessions/"+phoneID, laptop, "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("OtherUsersSessionsAreNotFound", func(t *testing.T) {
+		require.NoError(t, sessions.Create(auth.Session{ID: "bobs-session", TokenHash: "bobs-hash", UserID: "bob", ExpiresAt: time.Now().Add(time.Hour)}))
+		w := serve(http.MethodDelete, "/api/v1/auth/sessions/bobs-session", laptop, "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("RevokeOthersKeepsCurrent", func(t *testing.T) {
+		other := login("curl/8.5.0", "")
+
+		w := serve(http.MethodDelete, "/api/v1/auth/sessions", laptop, "", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"revoked":2}`, w.Body.String())
+
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/auth/sessions", tablet, "", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/auth/sessions", other, "", "").Code)
+		response := list(laptop)
+		require.Equal(t, 1, response.Total)
+		assert.True(t, response.Sessions[0].Current)
+	})
+
+	t.Run("LogoutEndsOnlyCurrentSession", func(t *testing.T) {
+		second := login("curl/8.5.0", "")
+		require.Equal(t, "curl", list(second).Sessions[0].DeviceName)
+
+		w := serve(http.MethodPost, "/api/v1/auth/logout", second, "", "")
+		require.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/auth/sessions", second, "", "").Code)
+		assert.Equal(t, 1, list(laptop).Total)
+	})
+}
+
+func TestRevokedSessionCannotRefresh(t *testing.T) {
+	users := &loginUserRepository{authUserRepository{users: map[string]models.User{}}}
+	authService := auth.NewAuthService(users, storage.NewSessionRepository(setupSessionDB(t)),
+		auth.NewJWTService("test-secret-key-32-chars-long!!"), auth.DefaultAuthConfig)
+	user, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+	require.NoError(t, err)
+
+	login := func() *auth.LoginResponse {
+		response, err := authService.Login(auth.LoginRequest{Email: "alice@example.com", Password: "correct-horse"}, "test-agent", "127.0.0.1")
+		require.NoError(t, err)
+		return response
+	}
+
+	t.Run("RevokeSession", func(t *testing.T) {
+		phone := login()
+		session, err := authService.SessionForToken(phone.Token)
+		require.NoError(t, err)
+
+		require.NoError(t, authService.RevokeSession(user.ID, session.ID))
+
+		_, err = authService.RefreshAccessToken(phone.RefreshToken, "test-agent", "127.0.0.1")
+		assert.ErrorIs(t, err, auth.ErrRefreshTokenRevoked)
+	})
+
+	t.Run("RevokeOtherSessions", func(t *testing.T) {
+		laptop := login()
+		tablet := login()
+
+		_, err := authService.RevokeOtherSessions(user.ID, laptop.Token)
+		require.NoError(t, err)
+
+		_, err = authService.RefreshAccessToken(tablet.RefreshToken, "test-agent", "127.0.0.1")
+		assert.ErrorIs(t, err, auth.ErrRefreshTokenRevoked)
+		_, err = authService.RefreshAccessToken(laptop.RefreshToken, "test-agent", "127.0.0.1")
+		assert.NoError(t, err, "the current session keeps refreshing")
+	})
+}
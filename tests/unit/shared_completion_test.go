@@ -0,0 +1,176 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_SharedCompletionUndo(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	type fixture struct {
+		store   *memstore.Store
+		service *hereandnow.TaskService
+		clock   *clock.Fake
+		task    models.Task
+		alice   models.User
+		bob     models.User
+		carol   models.User
+	}
+
+	setup := func(t *testing.T) fixture {
+		users := make([]models.User, 3)
+		for i, name := range []string{"alice", "bob", "carol"} {
+			user, err := models.NewUser(name, name+"@example.com", name, "UTC")
+			require.NoError(t, err)
+			users[i] = *user
+		}
+		alice, bob, carol := users[0], users[1], users[2]
+
+		list, err := models.NewTaskList("Groceries", "", alice.ID)
+		require.NoError(t, err)
+
+		task := createTestTask("Buy milk", nil, 3)
+		task.CreatorID = alice.ID
+		task.ListID = &list.ID
+		task.UpdatedAt = start.Add(-time.Hour)
+
+		store := memstore.New(memstore.WithUsers(users...), memstore.WithLists(*list), memstore.WithTasks(task))
+		for _, user := range users {
+			member, err := models.NewListMember(list.ID, user.ID, alice.ID, models.MemberRoleEditor)
+			require.NoError(t, err)
+			member.Accept()
+			require.NoError(t, store.ListMembers().Create(*member))
+		}
+
+		fake := clock.NewFake(start)
+		service, _ := newMemstoreServices(store)
+		service.SetClock(fake)
+		service.SetUserRepository(store.Users())
+		service.SetNotificationRepository(store.Notifications())
+		service.EnableUndo(store.TaskActions())
+		service.EnableSharedCompletionUndo(store.ListMembers(), store.CompletionUndos(), 5*time.Minute)
+
+		return fixture{store: store, service: service, clock: fake, task: task, alice: alice, bob: bob, carol: carol}
+	}
+
+	notifications := func(t *testing.T, store *memstore.Store, userID string) []models.Notification {
+		found, err := store.Notifications().GetByUserID(userID, false)
+		require.NoError(t, err)
+		return found
+	}
+
+	t.Run("CompletionNotifiesOtherMembers", func(t *testing.T) {
+		f := setup(t)
+
+		_, err := f.service.CompleteTask(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		for _, member := range []models.User{f.alice, f.carol} {
+			received := notifications(t, f.store, member.ID)
+			require.Len(t, received, 1, member.Username)
+			assert.Equal(t, models.NotificationTypeTaskCompleted, received[0].Type)
+			assert.Equal(t, f.task.ID, *received[0].TaskID)
+			assert.Contains(t, received[0].Message, "bob completed")
+		}
+		assert.Empty(t, notifications(t, f.store, f.bob.ID), "the completer is not notified")
+	})
+
+	t.Run("UndoWithinWindowRevertsCleanly", func(t *testing.T) {
+		f := setup(t)
+
+		_, err := f.service.CompleteTask(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		f.clock.Advance(4 * time.Minute)
+		task, err := f.service.UndoSharedCompletion(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, task.Status)
+
+		stored, err := f.store.Tasks().GetByID(f.task.ID)
+		require.NoError(t, err)
+		assert.Nil(t, stored.CompletedAt)
+		assert.Equal(t, f.task.UpdatedAt, stored.UpdatedAt, "no trace of the completion is left on the task")
+
+		assert.Empty(t, notifications(t, f.store, f.alice.ID), "completion notifications are withdrawn")
+		assert.Empty(t, notifications(t, f.store, f.carol.ID))
+
+		action, err := f.service.Undo(f.bob.ID)
+		require.NoError(t, err)
+		assert.Nil(t, action, "the undo log no longer holds the completion")
+
+		_, err = f.service.UndoSharedCompletion(f.task.ID, f.bob.ID)
+		assert.Error(t, err, "a completion can only be undone once")
+	})
+
+	t.Run("UndoRemovesNextRecurringInstance", func(t *testing.T) {
+		f := setup(t)
+		rule := "FREQ=DAILY"
+		task, err := f.store.Tasks().GetByID(f.task.ID)
+		require.NoError(t, err)
+		task.RecurrenceRule = &rule
+		require.NoError(t, f.store.Tasks().Update(*task))
+
+		_, err = f.service.CompleteTask(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+		_, err = f.service.UndoSharedCompletion(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		open, err := f.store.Tasks().GetByListID(*f.task.ListID)
+		require.NoError(t, err)
+		require.Len(t, open, 1, "exactly one occurrence remains")
+		assert.Equal(t, f.task.ID, open[0].ID)
+		assert.Equal(t, models.TaskStatusPending, open[0].Status)
+	})
+
+	t.Run("UndoAfterWindowIsRefused", func(t *testing.T) {
+		f := setup(t)
+
+		_, err := f.service.CompleteTask(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		f.clock.Advance(5 * time.Minute)
+		_, err = f.service.UndoSharedCompletion(f.task.ID, f.bob.ID)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "final")
+
+		stored, err := f.store.Tasks().GetByID(f.task.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.IsCompleted())
+		assert.Len(t, notifications(t, f.store, f.alice.ID), 1)
+	})
+
+	t.Run("OnlyCompleterCanUndo", func(t *testing.T) {
+		f := setup(t)
+
+		_, err := f.service.CompleteTask(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		_, err = f.service.UndoSharedCompletion(f.task.ID, f.alice.ID)
+		assert.Error(t, err)
+
+		_, err = f.service.UndoSharedCompletion(f.task.ID, f.bob.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("UnsharedTasksHaveNoWindow", func(t *testing.T) {
+		f := setup(t)
+		personal := createTestTask("Call mum", nil, 3)
+		personal.CreatorID = f.bob.ID
+		require.NoError(t, f.store.Tasks().Create(personal))
+
+		_, err := f.service.CompleteTask(personal.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		_, err = f.service.UndoSharedCompletion(personal.ID, f.bob.ID)
+		assert.Error(t, err)
+		assert.Empty(t, notifications(t, f.store, f.alice.ID))
+	})
+}
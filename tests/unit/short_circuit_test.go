@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRule hides the tasks named in hides and counts its evaluations
+type countingRule struct {
+	name     string
+	priority int
+	hides    map[string]bool
+	calls    int
+}
+
+func (r *countingRule) Name() string  { return r.name }
+func (r *countingRule) Priority() int { return r.priority }
+
+func (r *countingRule) Apply(ctx models.Context, task models.Task) (bool, string) {
+	r.calls++
+	if r.hides[task.Title] {
+		return false, r.name + " hides " + task.Title
+	}
+	return true, r.name + " shows " + task.Title
+}
+
+func TestFilterEngine_ShortCircuit(t *testing.T) {
+	setup := func(shortCircuit bool) (*filters.Engine, []*countingRule, []models.Task) {
+		config := filters.DefaultFilterConfig
+		config.EnableLocationFilter, config.EnableTimeFilter = false, false
+		config.EnableDependencyFilter, config.EnablePriorityFilter = false, false
+		config.ShortCircuit = shortCircuit
+
+		rules := []*countingRule{
+			{name: "expensive", priority: 100, hides: map[string]bool{"Blocked twice": true}},
+			{name: "cheap", priority: 10, hides: map[string]bool{"Blocked": true, "Blocked twice": true}},
+			{name: "middling", priority: 50},
+		}
+		engine := filters.NewEngine(config, &MockAuditRepo{})
+		for _, rule := range rules {
+			engine.AddRule(rule)
+		}
+
+		tasks := []models.Task{
+			createTestTask("Shown", nil, 3),
+			createTestTask("Blocked", nil, 3),
+			createTestTask("Blocked twice", nil, 3),
+		}
+		return engine, rules, tasks
+	}
+
+	// resultsFor returns the filter names and results for one task, in
+	// evaluation order
+	resultsFor := func(results []filters.FilterResult, task models.Task) ([]string, []filters.FilterResult) {
+		var names []string
+		var own []filters.FilterResult
+		for _, result := range results {
+			if result.TaskID == task.ID {
+				names = append(names, result.FilterName)
+				own = append(own, result)
+			}
+		}
+		return names, own
+	}
+
+	t.Run("StopsAtFirstBlockingRule", func(t *testing.T) {
+		engine, rules, tasks := setup(true)
+		visible, results := engine.FilterTasks(createTestContext(nil, nil, 60, 3), tasks)
+		require.Len(t, visible, 1)
+		assert.Equal(t, "Shown", visible[0].Title)
+
+		names, shown := resultsFor(results, tasks[0])
+		assert.Equal(t, []string{"cheap", "middling", "expensive"}, names, "cheapest first")
+		for _, result := range shown {
+			assert.False(t, result.Blocking)
+		}
+
+		for _, blocked := range tasks[1:] {
+			names, own := resultsFor(results, blocked)
+			assert.Equal(t, []string{"cheap"}, names, blocked.Title)
+			assert.False(t, own[0].Visible)
+			assert.True(t, own[0].Blocking)
+		}
+
+		assert.Equal(t, 3, rules[1].calls, "cheap rule sees every task")
+		assert.Equal(t, 1, rules[0].calls, "expensive rule sees only the task that got past the others")
+	})
+
+	t.Run("FullEvaluationByDefault", func(t *testing.T) {
+		engine, rules, tasks := setup(false)
+		visible, results := engine.FilterTasks(createTestContext(nil, nil, 60, 3), tasks)
+		require.Len(t, visible, 1)
+
+		names, own := resultsFor(results, tasks[2])
+		assert.Equal(t, []string{"expensive", "middling", "cheap"}, names)
+		for _, result := range own {
+			assert.False(t, result.Blocking)
+		}
+		assert.Equal(t, 3, rules[0].calls)
+	})
+
+	t.Run("ScoresNameOnlyTheBlockingRule", func(t *testing.T) {
+		engine, _, tasks := setup(true)
+		scored, err := engine.ScoreTasks(createTestContext(nil, nil, 60, 3), tasks)
+		require.NoError(t, err)
+		for _, task := range scored {
+			if !task.Visible {
+				assert.Equal(t, []string{"cheap"}, task.HiddenBy, task.Task.Title)
+			}
+		}
+	})
+
+	t.Run("DiffEvaluatesEveryRule", func(t *testing.T) {
+		engine, rules, tasks := setup(true)
+		ctx := createTestContext(nil, nil, 60, 3)
+		engine.DiffContexts(ctx, ctx, tasks)
+		assert.Equal(t, 2*len(tasks), rules[0].calls)
+	})
+}
@@ -0,0 +1,386 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUserRepository serves users from memory
+type MockUserRepository struct {
+	users map[string]*models.User
+}
+
+func (m *MockUserRepository) GetByID(userID string) (*models.User, error) {
+	user, exists := m.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found: %s", userID)
+	}
+	return user, nil
+}
+
+func TestSnoozePresets_Resolve(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	presets := models.DefaultSnoozePresets()
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, newYork)
+	}
+
+	// Wednesday afternoon
+	wednesday := at(2024, time.March, 13, 14, 20)
+
+	tests := []struct {
+		name     string
+		preset   string
+		now      time.Time
+		expected time.Time
+	}{
+		{"LaterToday", "later-today", wednesday, at(2024, time.March, 13, 17, 20)},
+		{"ThisEvening", "this-evening", wednesday, at(2024, time.March, 13, 18, 0)},
+		{"ThisEveningAfterSix", "this-evening", at(2024, time.March, 13, 19, 0), at(2024, time.March, 14, 18, 0)},
+		{"TomorrowMorning", "tomorrow-morning", wednesday, at(2024, time.March, 14, 9, 0)},
+		{"TomorrowMorningBeforeMidnight", "tomorrow-morning", at(2024, time.March, 13, 23, 30), at(2024, time.March, 14, 9, 0)},
+		{"TomorrowMorningAfterMidnight", "tomorrow-morning", at(2024, time.March, 14, 0, 30), at(2024, time.March, 15, 9, 0)},
+		{"TomorrowMorningAcrossYear", "tomorrow-morning", at(2024, time.December, 31, 23, 30), at(2025, time.January, 1, 9, 0)},
+		{"TomorrowMorningAcrossDST", "tomorrow-morning", at(2024, time.March, 9, 23, 30), at(2024, time.March, 10, 9, 0)},
+		{"NextWeek", "next-week", wednesday, at(2024, time.March, 18, 9, 0)},
+		{"NextWeekFromMonday", "next-week", at(2024, time.March, 18, 8, 0), at(2024, time.March, 25, 9, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := presets.Resolve(tt.preset, tt.now, newYork)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(resolved), "expected %s, got %s", tt.expected, resolved)
+		})
+	}
+
+	t.Run("UsesUserTimezone", func(t *testing.T) {
+		// 03:30 UTC on the 14th is still the evening of the 13th in New York
+		now := time.Date(2024, time.March, 14, 3, 30, 0, 0, time.UTC)
+
+		resolved, err := presets.Resolve("tomorrow-morning", now, newYork)
+		require.NoError(t, err)
+		assert.True(t, at(2024, time.March, 14, 9, 0).Equal(resolved), "got %s", resolved)
+	})
+
+	t.Run("ConfiguredPreset", func(t *testing.T) {
+		custom := models.SnoozePresets{"lunch": {At: "12:30"}}
+
+		resolved, err := custom.Resolve("Lunch", wednesday, newYork)
+		require.NoError(t, err)
+		assert.True(t, at(2024, time.March, 14, 12, 30).Equal(resolved), "got %s", resolved)
+	})
+
+	t.Run("UnknownPreset", func(t *testing.T) {
+		_, err := presets.Resolve("someday", wednesday, newYork)
+		assert.ErrorContains(t, err, "unknown snooze preset")
+	})
+
+	t.Run("InvalidPreset", func(t *testing.T) {
+		assert.Error(t, models.SnoozePresets{"bad": {At: "25:00"}}.Validate())
+		assert.Error(t, models.SnoozePresets{"bad": {Weekday: "someday"}}.Validate())
+		assert.NoError(t, presets.Validate())
+	})
+}
+
+func TestTaskService_RecurringSnooze(t *testing.T) {
+	rule := "FREQ=DAILY"
+
+	t.Run("ReappliesAfterCompletion", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service := newTestTaskService(repo)
+		task := createTestTask("Water plants", nil, 3)
+		task.RecurrenceRule = &rule
+		require.NoError(t, repo.Create(task))
+
+		_, err := service.SnoozeTaskWithPreset(task.ID, task.CreatorID, "later-today", true)
+		require.NoError(t, err)
+
+		// Wake up early and complete it
+		snoozed := repo.tasks[task.ID]
+		snoozed.SnoozedUntil = nil
+		repo.tasks[task.ID] = snoozed
+
+		before := time.Now()
+		completed, err := service.CompleteTask(task.ID, task.CreatorID)
+		require.NoError(t, err)
+
+		assert.Equal(t, models.TaskStatusCompleted, completed.Status)
+		assert.NotNil(t, completed.CompletedAt)
+
+		var next *models.Task
+		for _, candidate := range repo.tasks {
+			if candidate.ParentTaskID != nil && *candidate.ParentTaskID == task.ID {
+				next = &candidate
+			}
+		}
+		require.NotNil(t, next, "the next occurrence is created")
+		assert.Equal(t, models.TaskStatusPending, next.Status)
+		require.NotNil(t, next.SnoozedUntil)
+		assert.WithinDuration(t, before.Add(3*time.Hour), *next.SnoozedUntil, time.Minute)
+		assert.True(t, next.IsSnoozed(time.Now()))
+		require.NotNil(t, next.RecurringSnooze)
+		assert.Equal(t, "later-today", *next.RecurringSnooze)
+	})
+
+	t.Run("OneOffSnoozeNotReapplied", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service := newTestTaskService(repo)
+		task := createTestTask("Water plants", nil, 3)
+		task.RecurrenceRule = &rule
+		require.NoError(t, repo.Create(task))
+
+		_, err := service.SnoozeTaskWithPreset(task.ID, task.CreatorID, "later-today", false)
+		require.NoError(t, err)
+
+		completed, err := service.CompleteTask(task.ID, task.CreatorID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, completed.Status)
+	})
+
+	t.Run("RequiresRecurringTask", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service := newTestTaskService(repo)
+		task := createTestTask("Buy milk", nil, 3)
+		require.NoError(t, repo.Create(task))
+
+		_, err := service.SnoozeTaskWithPreset(task.ID, task.CreatorID, "tomorrow-morning", true)
+		assert.Error(t, err)
+	})
+
+	t.Run("ResolvesInUserTimezone", func(t *testing.T) {
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+
+		repo := NewMockServiceTaskRepository()
+		service := newTestTaskService(repo)
+		service.SetUserRepository(&MockUserRepository{users: map[string]*models.User{
+			"test-user-id": {ID: "test-user-id", TimeZone: "Asia/Tokyo"},
+		}})
+		task := createTestTask("Call bank", nil, 3)
+		require.NoError(t, repo.Create(task))
+
+		snoozed, err := service.SnoozeTaskWithPreset(task.ID, "test-user-id", "tomorrow-morning", false)
+		require.NoError(t, err)
+
+		local := snoozed.SnoozedUntil.In(tokyo)
+		assert.Equal(t, 9, local.Hour())
+		assert.Equal(t, 0, local.Minute())
+	})
+
+	t.Run("RejectsCompletedTask", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service := newTestTaskService(repo)
+		task := createTestTask("Done already", nil, 3)
+		task.Status = models.TaskStatusCompleted
+		require.NoError(t, repo.Create(task))
+
+		_, err := service.SnoozeTask(task.ID, task.CreatorID, time.Now().Add(time.Hour))
+		assert.Error(t, err)
+	})
+}
+
+func TestParseSnoozeDuration(t *testing.T) {
+	valid := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"45m", 45 * time.Minute},
+		{"3h", 3 * time.Hour},
+		{"3d", 72 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"1w2d", 9 * 24 * time.Hour},
+	}
+	for _, tt := range valid {
+		t.Run(tt.value, func(t *testing.T) {
+			d, err := models.ParseSnoozeDuration(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+
+	for _, value := range []string{"", "0d", "-3h", "3", "d", "2024-07-02", "soon"} {
+		_, err := models.ParseSnoozeDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTimeFilter_HidesSnoozedTasks(t *testing.T) {
+	filter := filters.NewTimeFilter(filters.DefaultFilterConfig, NewMockCalendarEventRepository())
+	ctx := createTestContext(nil, nil, 60, 3)
+
+	minutes := 30
+	task := createTestTask("Call bank", &minutes, 3)
+	until := time.Date(2024, time.July, 2, 9, 0, 0, 0, time.UTC)
+	task.SnoozedUntil = &until
+
+	t.Run("HiddenUntilSnoozeEnds", func(t *testing.T) {
+		ctx.Timestamp = until.Add(-time.Hour)
+		visible, code, reason := filter.Evaluate(ctx, task)
+
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonTimeSnoozed, code)
+		assert.Equal(t, "snoozed until Tue Jul 2 09:00", reason)
+	})
+
+	t.Run("ReappearsAfterSnooze", func(t *testing.T) {
+		ctx.Timestamp = until
+		visible, code, _ := filter.Evaluate(ctx, task)
+
+		assert.True(t, visible)
+		assert.NotEqual(t, filters.ReasonTimeSnoozed, code)
+	})
+
+	t.Run("HiddenWithTimeFilteringDisabled", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.EnableTimeFilter = false
+		filter := filters.NewTimeFilter(config, NewMockCalendarEventRepository())
+
+		ctx.Timestamp = until.Add(-time.Hour)
+		visible, code, _ := filter.Evaluate(ctx, task)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonTimeSnoozed, code)
+
+		ctx.Timestamp = until
+		visible, code, _ = filter.Evaluate(ctx, task)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonFilterDisabled, code)
+	})
+
+	t.Run("SnoozeTaskHidesFromFilteredTasksUntilItEnds", func(t *testing.T) {
+		start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+		fake := clock.NewFake(start)
+		store := memstore.New()
+		engine := filters.NewEngine(filters.DefaultFilterConfig, store.FilterAudits())
+		engine.AddRule(filters.NewTimeFilter(filters.DefaultFilterConfig, store.CalendarEvents()))
+		service := hereandnow.NewTaskService(store.Tasks(), store.Contexts(), store.Dependencies(), store.TaskLocations(), engine)
+		service.SetClock(fake)
+		contexts := hereandnow.NewContextService(store.Contexts(), store.Locations(), store.CalendarEvents(), nil, nil)
+		contexts.SetClock(fake)
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call the bank"))
+		require.NoError(t, err)
+		_, err = service.SnoozeTask(task.ID, "test-user-id", start.Add(3*time.Hour))
+		require.NoError(t, err)
+
+		visible := func() []string {
+			_, err := contexts.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{AvailableMinutes: 60, EnergyLevel: 3})
+			require.NoError(t, err)
+			tasks, _, err := service.GetFilteredTasks("test-user-id")
+			require.NoError(t, err)
+			return taskTitles(tasks)
+		}
+
+		fake.Advance(time.Hour)
+		assert.Empty(t, visible(), "hidden before the snooze ends")
+
+		fake.Advance(2 * time.Hour)
+		assert.Equal(t, []string{"Call the bank"}, visible(), "shown once it ends")
+	})
+}
+
+func TestTaskService_UnsnoozeTask(t *testing.T) {
+	repo := NewMockServiceTaskRepository()
+	service := newTestTaskService(repo)
+	task := createTestTask("Call bank", nil, 3)
+	require.NoError(t, repo.Create(task))
+
+	_, err := service.SnoozeTask(task.ID, task.CreatorID, time.Now().Add(72*time.Hour))
+	require.NoError(t, err)
+	snoozed := repo.tasks[task.ID]
+	assert.True(t, snoozed.IsSnoozed(time.Now()))
+
+	unsnoozed, err := service.UnsnoozeTask(task.ID, task.CreatorID)
+	require.NoError(t, err)
+	assert.Nil(t, unsnoozed.SnoozedUntil)
+	assert.Nil(t, repo.tasks[task.ID].SnoozedUntil)
+
+	// Clearing a task that isn't snoozed is a no-op
+	_, err = service.UnsnoozeTask(task.ID, task.CreatorID)
+	assert.NoError(t, err)
+}
+
+// snoozeAPITaskService serves a single stored task to the update handler
+type snoozeAPITaskService struct {
+	StubAPITaskService
+	task models.Task
+}
+
+func (s *snoozeAPITaskService) GetTaskByID(taskID string, userID string) (*models.Task, error) {
+	task := s.task
+	return &task, nil
+}
+
+func (s *snoozeAPITaskService) UpdateTask(task models.Task) (*models.Task, error) {
+	s.task = task
+	return &task, nil
+}
+
+func TestUpdateTask_SnoozedUntil(t *testing.T) {
+	patchTask := func(router http.Handler, body string) *httptest.ResponseRecorder {
+		return serveRequestWithHeaders(router, http.MethodPatch, "/api/v1/tasks/1", body, map[string]string{"If-Match": "*"})
+	}
+
+	setup := func(status models.TaskStatus) (*snoozeAPITaskService, http.Handler) {
+		task := createTestTask("Call bank", nil, 3)
+		task.Status = status
+		service := &snoozeAPITaskService{task: task}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: api.NewTaskHandler(service, nil),
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return service, router
+	}
+
+	t.Run("SnoozesAndClears", func(t *testing.T) {
+		service, router := setup(models.TaskStatusPending)
+
+		w := patchTask(router, `{"snoozed_until":"2099-07-02T09:00:00Z"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NotNil(t, service.task.SnoozedUntil)
+		assert.Equal(t, 2099, service.task.SnoozedUntil.Year())
+
+		w = patchTask(router, `{"snoozed_until":""}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Nil(t, service.task.SnoozedUntil)
+	})
+
+	t.Run("RejectsInvalidTime", func(t *testing.T) {
+		_, router := setup(models.TaskStatusPending)
+
+		w := patchTask(router, `{"snoozed_until":"tomorrow"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("RejectsCompletedTask", func(t *testing.T) {
+		service, router := setup(models.TaskStatusCompleted)
+
+		w := patchTask(router, `{"snoozed_until":"2099-07-02T09:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "cannot snooze a completed task")
+		assert.Nil(t, service.task.SnoozedUntil)
+	})
+}
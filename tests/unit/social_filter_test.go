@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocialContextFilter(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	config.EnableSocialFilter = true
+	filter := filters.NewSocialContextFilter(config)
+
+	taskTagged := func(tags ...string) models.Task {
+		task := createTestTask("Call the doctor", nil, 3)
+		metadata, err := json.Marshal(map[string][]string{filters.SocialTagsKey: tags})
+		require.NoError(t, err)
+		task.Metadata = metadata
+		return task
+	}
+	contextWhile := func(social string) models.Context {
+		ctx := createTestContext(nil, nil, 60, 3)
+		ctx.SocialContext = social
+		return ctx
+	}
+
+	t.Run("RequiresAloneHiddenAtWork", func(t *testing.T) {
+		visible, code, reason := filter.Evaluate(contextWhile(models.SocialContextAtWork), taskTagged(filters.TagRequiresAlone))
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonSocialIncompatible, code)
+		assert.Equal(t, "needs you to be alone but you are at work", reason)
+
+		visible, code, _ = filter.Evaluate(contextWhile(models.SocialContextAlone), taskTagged(filters.TagRequiresAlone))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonSocialCompatible, code)
+	})
+
+	t.Run("AnyRequiredContextIsEnough", func(t *testing.T) {
+		task := taskTagged("requires_with_family", "Requires_In_Public")
+		visible, _, _ := filter.Evaluate(contextWhile(models.SocialContextInPublic), task)
+		assert.True(t, visible)
+
+		visible, _, reason := filter.Evaluate(contextWhile(models.SocialContextAlone), task)
+		assert.False(t, visible)
+		assert.Equal(t, "needs you to be with family or in public but you are alone", reason)
+	})
+
+	t.Run("UnsafeWhileDrivingAlwaysHiddenWhenDriving", func(t *testing.T) {
+		for _, task := range []models.Task{
+			taskTagged(filters.TagUnsafeWhileDriving),
+			taskTagged(filters.TagUnsafeWhileDriving, "requires_driving"),
+		} {
+			visible, code, _ := filter.Evaluate(contextWhile(models.SocialContextDriving), task)
+			assert.False(t, visible)
+			assert.Equal(t, filters.ReasonSocialUnsafeDriving, code)
+		}
+
+		visible, code, _ := filter.Evaluate(contextWhile(models.SocialContextAtWork), taskTagged(filters.TagUnsafeWhileDriving))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonSocialNoRequirement, code)
+	})
+
+	t.Run("UntaggedAndUnknownTagsShown", func(t *testing.T) {
+		for _, task := range []models.Task{createTestTask("Buy milk", nil, 3), taskTagged("requires_silence")} {
+			visible, code, _ := filter.Evaluate(contextWhile(models.SocialContextDriving), task)
+			assert.True(t, visible)
+			assert.Equal(t, filters.ReasonSocialNoRequirement, code)
+		}
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		visible, code, _ := filters.NewSocialContextFilter(filters.DefaultFilterConfig).Evaluate(contextWhile(models.SocialContextDriving), taskTagged(filters.TagUnsafeWhileDriving))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonFilterDisabled, code)
+	})
+}
+
+func TestFilterEngine_SocialContextFilter(t *testing.T) {
+	private := createTestTask("Review payslip", nil, 3)
+	private.Metadata = json.RawMessage(`{"social_tags": ["requires_alone"]}`)
+	shared := createTestTask("Plan weekend", nil, 3)
+
+	ctx := createTestContext(nil, nil, 60, 3)
+	ctx.SocialContext = models.SocialContextAtWork
+
+	t.Run("HidesIncompatibleTaskWhenEnabled", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.EnableSocialFilter = true
+		engine := filters.NewEngine(config, &MockAuditRepo{})
+
+		visible, results := engine.FilterTasks(ctx, []models.Task{private, shared})
+		require.Len(t, visible, 1)
+		assert.Equal(t, "Plan weekend", visible[0].Title)
+		require.Len(t, results, 2)
+		assert.Equal(t, "social", results[0].FilterName)
+		assert.Equal(t, filters.ReasonSocialIncompatible, results[0].Code)
+	})
+
+	t.Run("TogglesAtRuntime", func(t *testing.T) {
+		engine := filters.NewEngine(filters.DefaultFilterConfig, &MockAuditRepo{})
+		visible, _ := engine.FilterTasks(ctx, []models.Task{private, shared})
+		assert.Len(t, visible, 2)
+
+		require.NoError(t, engine.EnableFilter("social"))
+		visible, _ = engine.FilterTasks(ctx, []models.Task{private, shared})
+		assert.Len(t, visible, 1)
+
+		require.NoError(t, engine.DisableFilter("social"))
+		visible, _ = engine.FilterTasks(ctx, []models.Task{private, shared})
+		assert.Len(t, visible, 2)
+	})
+}
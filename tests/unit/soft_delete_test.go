@@ -0,0 +1,452 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSoftDeleteDB(t *testing.T) *storage.DB {
+	db := setupMetadataDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE task_dependencies (task_id TEXT, depends_on_task_id TEXT);
+		CREATE TABLE task_locations (task_id TEXT, location_id TEXT);
+		CREATE TABLE task_assignments (task_id TEXT, assigned_to TEXT);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func countRows(t *testing.T, db *storage.DB, query string, args ...interface{}) int {
+	var count int
+	require.NoError(t, db.QueryRow(query, args...).Scan(&count))
+	return count
+}
+
+func TestTaskRepository_SoftDelete(t *testing.T) {
+	t.Run("HidesDeletedTasks", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+		insertTaskWithMetadata(t, db, "task-2", `{}`)
+		tasks := storage.NewTaskRepository(db)
+
+		require.NoError(t, tasks.Delete("task-1"))
+
+		_, err := tasks.GetByID("task-1")
+		assert.EqualError(t, err, "task not found")
+
+		found, err := tasks.Search(storage.TaskSearchOptions{UserID: "user-1"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, "task-2", found[0].ID)
+
+		count, err := tasks.Count(storage.TaskSearchOptions{UserID: "user-1"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		exists, err := tasks.Exists("task-1")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		pending := models.TaskStatusPending
+		found, err = tasks.Search(storage.TaskSearchOptions{Status: &pending, IncludeDeleted: true})
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+
+		deleted, err := tasks.GetDeletedByID("task-1")
+		require.NoError(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+
+		assert.Error(t, tasks.Delete("task-1"), "a deleted task cannot be deleted again")
+	})
+
+	t.Run("RestoreBringsTaskBack", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+		tasks := storage.NewTaskRepository(db)
+
+		require.NoError(t, tasks.Delete("task-1"))
+		require.NoError(t, tasks.Restore("task-1"))
+
+		restored, err := tasks.GetByID("task-1")
+		require.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+
+		assert.EqualError(t, tasks.Restore("task-1"), "deleted task not found")
+	})
+
+	t.Run("DeletedDependentsDoNotBlockDelete", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		insertTaskWithMetadata(t, db, "blocker", `{}`)
+		insertTaskWithMetadata(t, db, "dependent", `{}`)
+		_, err := db.Exec(`INSERT INTO task_dependencies (task_id, depends_on_task_id) VALUES ('dependent', 'blocker')`)
+		require.NoError(t, err)
+		tasks := storage.NewTaskRepository(db)
+
+		assert.Error(t, tasks.Delete("blocker"))
+
+		require.NoError(t, tasks.Delete("dependent"))
+		assert.NoError(t, tasks.Delete("blocker"))
+	})
+}
+
+func TestTaskRepository_Trash(t *testing.T) {
+	t.Run("ListsDeletedTasks", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+		insertTaskWithMetadata(t, db, "task-2", `{}`)
+		insertTaskWithMetadata(t, db, "task-3", `{}`)
+		tasks := storage.NewTaskRepository(db)
+
+		require.NoError(t, tasks.Delete("task-1"))
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, tasks.Delete("task-2"))
+
+		deleted, err := tasks.GetDeleted("user-1")
+		require.NoError(t, err)
+		require.Len(t, deleted, 2)
+		assert.Equal(t, "task-2", deleted[0].ID, "most recently deleted first")
+		assert.Equal(t, "task-1", deleted[1].ID)
+
+		deleted, err = tasks.GetDeleted("user-2")
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+	})
+
+	t.Run("PurgeRemovesTaskAndRelationships", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+		_, err := db.Exec(`
+			INSERT INTO task_locations (task_id, location_id) VALUES ('task-1', 'loc-1');
+			INSERT INTO task_assignments (task_id, assigned_to) VALUES ('task-1', 'user-2');
+		`)
+		require.NoError(t, err)
+		tasks := storage.NewTaskRepository(db)
+
+		assert.EqualError(t, tasks.Purge("task-1", false), "deleted task not found", "only deleted tasks can be purged")
+
+		require.NoError(t, tasks.Delete("task-1"))
+		require.NoError(t, tasks.Purge("task-1", false))
+
+		assert.Zero(t, countRows(t, db, `SELECT COUNT(*) FROM tasks`))
+		assert.Zero(t, countRows(t, db, `SELECT COUNT(*) FROM task_locations`))
+		assert.Zero(t, countRows(t, db, `SELECT COUNT(*) FROM task_assignments`))
+		assert.Error(t, tasks.Restore("task-1"))
+	})
+
+	t.Run("PurgeBlockedByDependents", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		insertTaskWithMetadata(t, db, "blocker", `{}`)
+		insertTaskWithMetadata(t, db, "dependent", `{}`)
+		_, err := db.Exec(`INSERT INTO task_dependencies (task_id, depends_on_task_id) VALUES ('dependent', 'blocker')`)
+		require.NoError(t, err)
+		tasks := storage.NewTaskRepository(db)
+
+		require.NoError(t, tasks.Delete("dependent"))
+		require.NoError(t, tasks.Delete("blocker"))
+
+		// The dependent could still be restored, so its link counts
+		err = tasks.Purge("blocker", false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "1 tasks depend on this task")
+
+		require.NoError(t, tasks.Purge("blocker", true))
+		assert.Zero(t, countRows(t, db, `SELECT COUNT(*) FROM task_dependencies`))
+		assert.Equal(t, 1, countRows(t, db, `SELECT COUNT(*) FROM tasks`))
+	})
+
+	t.Run("PurgeDeletedBefore", func(t *testing.T) {
+		db := setupSoftDeleteDB(t)
+		for _, id := range []string{"old", "old-blocker", "old-dependent", "recent", "live"} {
+			insertTaskWithMetadata(t, db, id, `{}`)
+		}
+		_, err := db.Exec(`INSERT INTO task_dependencies (task_id, depends_on_task_id) VALUES ('old-dependent', 'old-blocker')`)
+		require.NoError(t, err)
+		tasks := storage.NewTaskRepository(db)
+
+		for _, id := range []string{"old", "old-dependent", "old-blocker", "recent"} {
+			require.NoError(t, tasks.Delete(id))
+		}
+		old := time.Now().Add(-48 * time.Hour)
+		_, err = db.Exec(`UPDATE tasks SET deleted_at = ? WHERE id IN ('old', 'old-blocker', 'old-dependent')`, old)
+		require.NoError(t, err)
+
+		cutoff := time.Now().Add(-24 * time.Hour)
+		purged, err := tasks.PurgeDeletedBefore(cutoff)
+		require.NoError(t, err)
+		assert.Equal(t, 2, purged, "the blocker waits for its dependent")
+
+		purged, err = tasks.PurgeDeletedBefore(cutoff)
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+
+		remaining, err := tasks.Search(storage.TaskSearchOptions{IncludeDeleted: true})
+		require.NoError(t, err)
+		var ids []string
+		for _, task := range remaining {
+			ids = append(ids, task.ID)
+		}
+		assert.ElementsMatch(t, []string{"recent", "live"}, ids)
+
+		job := maintenance.PurgeTrashJob(tasks, time.Hour)
+		summary, err := job.Run(time.Now().Add(2 * time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, "purged 1 deleted task(s)", summary)
+	})
+}
+
+// softDeleteTaskRepository keeps deleted tasks aside so they can be restored,
+// the way the SQL task repository does
+type softDeleteTaskRepository struct {
+	*MockServiceTaskRepository
+	deleted map[string]models.Task
+}
+
+func newSoftDeleteTaskRepository() *softDeleteTaskRepository {
+	return &softDeleteTaskRepository{
+		MockServiceTaskRepository: NewMockServiceTaskRepository(),
+		deleted:                   make(map[string]models.Task),
+	}
+}
+
+func (r *softDeleteTaskRepository) Delete(taskID string) error {
+	task, exists := r.tasks[taskID]
+	if !exists {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	delete(r.tasks, taskID)
+	r.deleted[taskID] = task
+	return nil
+}
+
+func (r *softDeleteTaskRepository) Restore(taskID string) error {
+	task, exists := r.deleted[taskID]
+	if !exists {
+		return fmt.Errorf("deleted task not found")
+	}
+	delete(r.deleted, taskID)
+	r.tasks[taskID] = task
+	return nil
+}
+
+func (r *softDeleteTaskRepository) GetDeleted(userID string) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range r.deleted {
+		if task.CreatorID == userID {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *softDeleteTaskRepository) GetDeletedByID(taskID string) (*models.Task, error) {
+	task, exists := r.deleted[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found")
+	}
+	return &task, nil
+}
+
+func (r *softDeleteTaskRepository) Purge(taskID string, force bool) error {
+	if _, exists := r.deleted[taskID]; !exists {
+		return fmt.Errorf("deleted task not found")
+	}
+	delete(r.deleted, taskID)
+	return nil
+}
+
+func TestTaskService_RestoreTask(t *testing.T) {
+	newService := func(store *memstore.Store, repo *softDeleteTaskRepository) *hereandnow.TaskService {
+		return hereandnow.NewTaskService(repo, store.Contexts(), store.Dependencies(), store.TaskLocations(), nil)
+	}
+
+	t.Run("RestoresDeletedTask", func(t *testing.T) {
+		repo := newSoftDeleteTaskRepository()
+		service := newService(memstore.New(), repo)
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Water plants"))
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+
+		restored, err := service.RestoreTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, "Water plants", restored.Title)
+
+		_, err = service.RestoreTask(task.ID, "test-user-id")
+		assert.Error(t, err)
+	})
+
+	t.Run("UndoRestoresInPlace", func(t *testing.T) {
+		store := memstore.New()
+		repo := newSoftDeleteTaskRepository()
+		service := newService(store, repo)
+		service.EnableUndo(store.TaskActions())
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Water plants"))
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+
+		_, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+
+		_, err = service.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Empty(t, repo.deleted)
+	})
+
+	t.Run("OnlyOwnTasks", func(t *testing.T) {
+		repo := newSoftDeleteTaskRepository()
+		service := newService(memstore.New(), repo)
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Water plants"))
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+
+		_, err = service.RestoreTask(task.ID, "other-user-id")
+		assert.ErrorContains(t, err, "not found")
+		assert.Len(t, repo.deleted, 1)
+	})
+
+	t.Run("StoreWithoutSoftDelete", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Water plants"))
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+
+		_, err = service.RestoreTask(task.ID, "test-user-id")
+		assert.Error(t, err)
+
+		_, err = service.ListTrash("test-user-id")
+		assert.Error(t, err)
+	})
+}
+
+func TestTaskService_Trash(t *testing.T) {
+	setup := func(t *testing.T) (*hereandnow.TaskService, *softDeleteTaskRepository, []string) {
+		store := memstore.New()
+		repo := newSoftDeleteTaskRepository()
+		service := hereandnow.NewTaskService(repo, store.Contexts(), store.Dependencies(), store.TaskLocations(), nil)
+
+		var ids []string
+		for _, title := range []string{"Water plants", "Buy stamps"} {
+			task, err := service.CreateTask("test-user-id", memstoreTaskRequest(title))
+			require.NoError(t, err)
+			require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+			ids = append(ids, task.ID)
+		}
+		return service, repo, ids
+	}
+
+	t.Run("ListTrash", func(t *testing.T) {
+		service, _, _ := setup(t)
+
+		trash, err := service.ListTrash("test-user-id")
+		require.NoError(t, err)
+		assert.Len(t, trash, 2)
+	})
+
+	t.Run("PurgeTask", func(t *testing.T) {
+		service, repo, ids := setup(t)
+
+		assert.Error(t, service.PurgeTask(ids[0], "other-user-id", false))
+		require.NoError(t, service.PurgeTask(ids[0], "test-user-id", false))
+
+		_, err := service.RestoreTask(ids[0], "test-user-id")
+		assert.Error(t, err, "a purged task is gone for good")
+		assert.Len(t, repo.deleted, 1)
+	})
+
+	t.Run("EmptyTrash", func(t *testing.T) {
+		service, repo, _ := setup(t)
+
+		purged, err := service.EmptyTrash("test-user-id", false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, purged)
+		assert.Empty(t, repo.deleted)
+	})
+}
+
+// trashAPITaskService holds one deleted task, "deleted-1"
+type trashAPITaskService struct {
+	restored []string
+}
+
+func (s *trashAPITaskService) ListTrash(userID string) ([]models.Task, error) {
+	if len(s.restored) > 0 {
+		return nil, nil
+	}
+	return []models.Task{{ID: "deleted-1", Title: "Water plants"}}, nil
+}
+
+func (s *trashAPITaskService) RestoreTask(taskID string, userID string) (*models.Task, error) {
+	if taskID != "deleted-1" {
+		return nil, fmt.Errorf("deleted task not found")
+	}
+	s.restored = append(s.restored, taskID)
+	return &models.Task{ID: taskID, Title: "Water plants"}, nil
+}
+
+func TestTaskTrashRoutes(t *testing.T) {
+	setup := func(trash api.TaskTrashService) http.Handler {
+		handler := api.NewTaskHandler(&StubAPITaskService{}, nil)
+		if trash != nil {
+			handler.SetTrashService(trash)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: handler,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router
+	}
+
+	t.Run("ListAndRestore", func(t *testing.T) {
+		trash := &trashAPITaskService{}
+		router := setup(trash)
+
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/trash", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response api.TaskTrashResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Total)
+		require.Len(t, response.Tasks, 1)
+		assert.Equal(t, "deleted-1", response.Tasks[0].ID)
+
+		w = serveRequest(router, http.MethodPost, "/api/v1/tasks/deleted-1/restore", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, []string{"deleted-1"}, trash.restored)
+
+		w = serveRequest(router, http.MethodGet, "/api/v1/tasks/trash", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"tasks": [], "total": 0}`, w.Body.String())
+	})
+
+	t.Run("RestoreUnknownTask", func(t *testing.T) {
+		w := serveRequest(setup(&trashAPITaskService{}), http.MethodPost, "/api/v1/tasks/missing/restore", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		w := serveRequest(setup(nil), http.MethodGet, "/api/v1/tasks/trash", "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
@@ -0,0 +1,260 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subtaskTree is a two-level tree: a project with a phase under it, and two
+// steps under the phase
+type subtaskTree struct {
+	project, phase, first, second *models.Task
+}
+
+func newSubtaskTree(t *testing.T, service *hereandnow.TaskService) subtaskTree {
+	create := func(title string, parent *models.Task) *models.Task {
+		req := memstoreTaskRequest(title)
+		if parent != nil {
+			req.ParentTaskID = &parent.ID
+		}
+		task, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+		return task
+	}
+
+	var tree subtaskTree
+	tree.project = create("Move house", nil)
+	tree.phase = create("Pack", tree.project)
+	tree.first = create("Pack kitchen", tree.phase)
+	tree.second = create("Pack books", tree.phase)
+	return tree
+}
+
+func TestTaskService_Subtasks(t *testing.T) {
+	status := func(t *testing.T, store *memstore.Store, task *models.Task) models.TaskStatus {
+		current, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+		return current.Status
+	}
+
+	t.Run("GetSubtasks", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+
+		subtasks, err := service.GetSubtasks(tree.phase.ID)
+		require.NoError(t, err)
+		require.Len(t, subtasks, 2)
+		assert.Equal(t, tree.first.ID, subtasks[0].ID)
+		assert.Equal(t, tree.second.ID, subtasks[1].ID)
+
+		subtasks, err = service.GetSubtasks(tree.project.ID)
+		require.NoError(t, err)
+		require.Len(t, subtasks, 1)
+		assert.Equal(t, tree.phase.ID, subtasks[0].ID)
+
+		_, err = service.GetSubtasks("missing")
+		assert.Error(t, err)
+	})
+
+	t.Run("CompletingLastSubtaskRollsUpToTheRoot", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+
+		_, err := service.CompleteTask(tree.first.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, status(t, store, tree.phase), "a subtask is still open")
+
+		_, err = service.CompleteTask(tree.second.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, status(t, store, tree.phase))
+		assert.Equal(t, models.TaskStatusCompleted, status(t, store, tree.project))
+
+		project, err := store.Tasks().GetByID(tree.project.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, project.CompletedAt)
+	})
+
+	t.Run("ReopeningSubtaskReopensAncestors", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+		for _, task := range []*models.Task{tree.first, tree.second} {
+			_, err := service.CompleteTask(task.ID, "test-user-id")
+			require.NoError(t, err)
+		}
+
+		pending := models.TaskStatusPending
+		_, err := service.UpdateTask(tree.first.ID, hereandnow.UpdateTaskRequest{Status: &pending})
+		require.NoError(t, err)
+
+		assert.Equal(t, models.TaskStatusPending, status(t, store, tree.phase))
+		assert.Equal(t, models.TaskStatusPending, status(t, store, tree.project))
+		project, err := store.Tasks().GetByID(tree.project.ID)
+		require.NoError(t, err)
+		assert.Nil(t, project.CompletedAt)
+	})
+
+	t.Run("NewSubtaskReopensCompletedParent", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+		for _, task := range []*models.Task{tree.first, tree.second} {
+			_, err := service.CompleteTask(task.ID, "test-user-id")
+			require.NoError(t, err)
+		}
+
+		req := memstoreTaskRequest("Pack garage")
+		req.ParentTaskID = &tree.phase.ID
+		_, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		assert.Equal(t, models.TaskStatusPending, status(t, store, tree.phase))
+		assert.Equal(t, models.TaskStatusPending, status(t, store, tree.project))
+	})
+
+	t.Run("CancelledSubtasksDoNotHoldParentBack", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+
+		cancelled := models.TaskStatusCancelled
+		_, err := service.UpdateTask(tree.second.ID, hereandnow.UpdateTaskRequest{Status: &cancelled})
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, status(t, store, tree.phase), "nothing is completed yet")
+
+		_, err = service.CompleteTask(tree.first.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, status(t, store, tree.phase))
+	})
+
+	t.Run("RecurringParentIsNotRolledUp", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+
+		req := memstoreTaskRequest("Water plants")
+		rule := "FREQ=WEEKLY"
+		req.RecurrenceRule = &rule
+		parent, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		req = memstoreTaskRequest("Water ferns")
+		req.ParentTaskID = &parent.ID
+		child, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		_, err = service.CompleteTask(child.ID, "test-user-id")
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, status(t, store, parent))
+	})
+
+	t.Run("RejectsCycles", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+
+		for name, parentID := range map[string]string{
+			"self":       tree.project.ID,
+			"child":      tree.phase.ID,
+			"grandchild": tree.first.ID,
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := service.UpdateTask(tree.project.ID, hereandnow.UpdateTaskRequest{ParentTaskID: &parentID})
+				var validationErr *models.ValidationError
+				require.True(t, errors.As(err, &validationErr), "got %v", err)
+				assert.Equal(t, "would make the task its own ancestor", validationErr.Fields["parent_task_id"])
+			})
+		}
+
+		project, err := store.Tasks().GetByID(tree.project.ID)
+		require.NoError(t, err)
+		assert.Nil(t, project.ParentTaskID)
+	})
+
+	t.Run("ReparentsAndDetaches", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+
+		task, err := service.UpdateTask(tree.second.ID, hereandnow.UpdateTaskRequest{ParentTaskID: &tree.project.ID})
+		require.NoError(t, err)
+		require.NotNil(t, task.ParentTaskID)
+		assert.Equal(t, tree.project.ID, *task.ParentTaskID)
+
+		detach := ""
+		task, err = service.UpdateTask(tree.second.ID, hereandnow.UpdateTaskRequest{ParentTaskID: &detach})
+		require.NoError(t, err)
+		assert.Nil(t, task.ParentTaskID)
+
+		missing := "missing"
+		_, err = service.UpdateTask(tree.second.ID, hereandnow.UpdateTaskRequest{ParentTaskID: &missing})
+		var validationErr *models.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "not found", validationErr.Fields["parent_task_id"])
+
+		req := memstoreTaskRequest("Orphan")
+		req.ParentTaskID = &missing
+		_, err = service.CreateTask("test-user-id", req)
+		assert.True(t, errors.As(err, &validationErr))
+	})
+}
+
+func TestParentVisibilityFilter(t *testing.T) {
+	store := memstore.New()
+	service, _ := newMemstoreServices(store)
+	tree := newSubtaskTree(t, service)
+	filter := filters.NewParentVisibilityFilter(store.Tasks())
+	ctx := createTestContext(nil, nil, 60, 3)
+
+	evaluate := func(task *models.Task) (bool, filters.ReasonCode) {
+		current, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+		visible, code, _ := filter.Evaluate(ctx, *current)
+		return visible, code
+	}
+
+	visible, code := evaluate(tree.first)
+	assert.True(t, visible)
+	assert.Equal(t, filters.ReasonParentNoSubtasks, code)
+
+	for _, task := range []*models.Task{tree.project, tree.phase} {
+		visible, code = evaluate(task)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonParentSubtasksOpen, code)
+	}
+
+	_, err := service.CompleteTask(tree.first.ID, "test-user-id")
+	require.NoError(t, err)
+	_, err = service.CompleteTask(tree.second.ID, "test-user-id")
+	require.NoError(t, err)
+
+	visible, code = evaluate(tree.phase)
+	assert.True(t, visible)
+	assert.Equal(t, filters.ReasonParentSubtasksDone, code)
+
+	t.Run("InEngine", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		tree := newSubtaskTree(t, service)
+
+		engine := filters.NewEngine(filters.DefaultFilterConfig, store.FilterAudits())
+		engine.AddRule(filters.NewParentVisibilityFilter(store.Tasks()))
+		tasks, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+
+		visible, _ := engine.FilterTasks(ctx, tasks)
+		var ids []string
+		for _, task := range visible {
+			ids = append(ids, task.ID)
+		}
+		assert.ElementsMatch(t, []string{tree.first.ID, tree.second.ID}, ids)
+	})
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAnalytics returns zeroed analytics and records the range asked for
+type recordingAnalytics struct {
+	userID       string
+	since, until time.Time
+}
+
+func (r *recordingAnalytics) GetTaskAnalytics(userID string, since, until time.Time) (*models.TaskAnalytics, error) {
+	r.userID, r.since, r.until = userID, since, until
+	return models.NewTaskAnalytics(since, until), nil
+}
+
+func TestGetTaskAnalytics(t *testing.T) {
+	setup := func(service api.TaskAnalyticsService) *gin.Engine {
+		analytics := api.NewAnalyticsHandler(nil)
+		if service != nil {
+			analytics.SetTaskAnalyticsService(service)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Analytics: analytics,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router
+	}
+	service := &recordingAnalytics{}
+	router := setup(service)
+
+	t.Run("DefaultsToThirtyDays", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/analytics/tasks", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "test-user-id", service.userID)
+		assert.Equal(t, 30*24*time.Hour, service.until.Sub(service.since))
+
+		var analytics models.TaskAnalytics
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &analytics))
+		assert.Len(t, analytics.CompletionsByDay, 31, "partial days at both ends")
+		assert.Len(t, analytics.ByPriority, 5)
+		assert.NotNil(t, analytics.ByLocation)
+	})
+
+	t.Run("Since", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/analytics/tasks?since=2w", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, 14*24*time.Hour, service.until.Sub(service.since))
+	})
+
+	t.Run("DateRangeIncludesEndDate", func(t *testing.T) {
+		w := serveRequest(router, http.MethodGet, "/api/v1/analytics/tasks?start_date=2026-03-01&end_date=2026-03-07", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), service.since)
+		assert.Equal(t, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), service.until)
+
+		var analytics models.TaskAnalytics
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &analytics))
+		require.Len(t, analytics.CompletionsByDay, 7)
+		assert.Equal(t, "2026-03-07", analytics.CompletionsByDay[6].Date)
+	})
+
+	t.Run("RejectsBadRanges", func(t *testing.T) {
+		for _, query := range []string{
+			"?since=soon",
+			"?start_date=03/01/2026",
+			"?start_date=2026-03-07&end_date=2026-03-01",
+			"?start_date=2024-01-01&end_date=2026-01-01",
+		} {
+			w := serveRequest(router, http.MethodGet, "/api/v1/analytics/tasks"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		w := serveRequest(setup(nil), http.MethodGet, "/api/v1/analytics/tasks", "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
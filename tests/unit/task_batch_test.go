@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_CreateTasks(t *testing.T) {
+	store := memstore.New()
+	taskService, _ := newMemstoreServices(store)
+
+	t.Run("PlacesListTasksInOrder", func(t *testing.T) {
+		listID := "groceries"
+		existing := memstoreTaskRequest("Coffee")
+		existing.ListID = &listID
+		_, err := taskService.CreateTask("test-user-id", existing)
+		require.NoError(t, err)
+
+		var reqs []hereandnow.CreateTaskRequest
+		for _, title := range []string{"Milk", "Eggs", "Bread"} {
+			req := memstoreTaskRequest(title)
+			req.ListID = &listID
+			reqs = append(reqs, req)
+		}
+		created, err := taskService.CreateTasks("test-user-id", reqs)
+		require.NoError(t, err)
+		require.Len(t, created, 3)
+
+		listed, err := store.Tasks().GetByListID(listID)
+		require.NoError(t, err)
+		api.SortTasks(listed, api.TaskOrder{SortBy: "position"})
+		var titles []string
+		for _, task := range listed {
+			titles = append(titles, task.Title)
+		}
+		assert.Equal(t, []string{"Coffee", "Milk", "Eggs", "Bread"}, titles)
+	})
+
+	t.Run("AllOrNone", func(t *testing.T) {
+		before, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+
+		_, err = taskService.CreateTasks("test-user-id", []hereandnow.CreateTaskRequest{
+			memstoreTaskRequest("Call bank"), memstoreTaskRequest(""),
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid task request 2")
+
+		after, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		assert.Len(t, after, len(before))
+	})
+}
+
+func TestCreateTaskBatch(t *testing.T) {
+	setup := func(enable bool) (*memstore.Store, *gin.Engine) {
+		store := memstore.New()
+		taskService, contextService := newMemstoreServices(store)
+		tasks := api.NewTaskHandler(&memstoreAPITaskService{service: taskService}, contextService)
+		if enable {
+			tasks.SetBatchService(taskService)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: tasks,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return store, router
+	}
+	store, router := setup(true)
+	userTasks := func() []models.Task {
+		tasks, err := store.Tasks().GetByUserID("test-user-id")
+		require.NoError(t, err)
+		return tasks
+	}
+
+	t.Run("CreatesAll", func(t *testing.T) {
+		body := `[{"title": "Milk", "priority": 4}, {"title": "Eggs", "outdoor": true}, {"title": "Bread"}]`
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks/batch", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var response api.TaskBatchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, 3, response.Total)
+		assert.Equal(t, "Milk", response.Tasks[0].Title)
+		assert.Equal(t, 4, response.Tasks[0].Priority)
+		assert.Equal(t, 3, response.Tasks[2].Priority, "defaults like POST /tasks")
+		assert.JSONEq(t, `{"outdoor": true}`, string(response.Tasks[1].Metadata))
+		for _, task := range response.Tasks {
+			assert.NotEmpty(t, task.ID)
+		}
+		assert.Len(t, userTasks(), 3)
+	})
+
+	t.Run("InvalidTaskCreatesNone", func(t *testing.T) {
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks/batch", `[{"title": "Butter"}, {"title": ""}]`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response api.TaskBatchErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Index)
+		assert.Equal(t, "required", response.Fields["title"])
+		assert.Len(t, userTasks(), 3)
+	})
+
+	t.Run("RejectsBadBodies", func(t *testing.T) {
+		for _, body := range []string{`{"title": "Milk"}`, `[]`} {
+			w := serveRequest(router, http.MethodPost, "/api/v1/tasks/batch", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("CapsBatchSize", func(t *testing.T) {
+		body := "[" + strings.TrimSuffix(strings.Repeat(`{"title": "Task"},`, api.MaxTaskBatchSize+1), ",") + "]"
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks/batch", body)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Len(t, userTasks(), 3)
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		_, router := setup(false)
+		w := serveRequest(router, http.MethodPost, "/api/v1/tasks/batch", `[{"title": "Milk"}]`)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTasksCSV_RoundTrip(t *testing.T) {
+	estimate := 45
+	due := time.Date(2026, 3, 2, 17, 30, 0, 0, time.UTC)
+
+	full := createTestTask("Buy milk, eggs and \"good\" bread", &estimate, 5)
+	full.Description = "From the corner shop\nnot the supermarket"
+	full.DueAt = &due
+	full.Tags = []string{"errands", "quick win"}
+
+	bare := createTestTask("Call mom", nil, 1)
+	bare.Description = ""
+
+	done := createTestTask("File taxes", nil, 4)
+	done.Status = models.TaskStatusCompleted
+	now := time.Now()
+	done.CompletedAt = &now
+
+	blocked := createTestTask("Paint the fence", nil, 3)
+	blocked.Status = models.TaskStatusBlocked
+
+	original := []models.Task{full, bare, done, blocked}
+
+	var buf bytes.Buffer
+	require.NoError(t, sync.ExportTasksCSV(&buf, original))
+	assert.True(t, sync.IsTasksCSV(buf.String()))
+
+	imported, err := sync.ImportTasksCSV(&buf, "test-user-id")
+	require.NoError(t, err)
+	require.Len(t, imported, len(original))
+
+	// withoutIdentity clears the fields that are not exported
+	withoutIdentity := func(task models.Task) models.Task {
+		task.ID, task.Version = "", 0
+		task.CreatedAt, task.UpdatedAt, task.CompletedAt = time.Time{}, time.Time{}, nil
+		return task
+	}
+	for i := range original {
+		assert.NotEqual(t, original[i].ID, imported[i].ID)
+		assert.Equal(t, withoutIdentity(original[i]), withoutIdentity(imported[i]))
+	}
+	assert.NotNil(t, imported[2].CompletedAt, "a completed task keeps a completion time")
+}
+
+func TestImportTasksCSV(t *testing.T) {
+	header := strings.Join(sync.TaskCSVColumns, ",") + "\n"
+
+	t.Run("ColumnsInAnyOrder", func(t *testing.T) {
+		tasks, err := sync.ImportTasksCSV(strings.NewReader("Priority,Title,notes\n2,Water plants,ignored\n"), "test-user-id")
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		assert.Equal(t, "Water plants", tasks[0].Title)
+		assert.Equal(t, 2, tasks[0].Priority)
+		assert.Equal(t, models.TaskStatusPending, tasks[0].Status)
+		assert.Empty(t, tasks[0].Description)
+	})
+
+	t.Run("ReportsFirstInvalidRow", func(t *testing.T) {
+		data := header +
+			"Fine,,pending,3,,,\n" +
+			"Too urgent,,pending,9,,,\n" +
+			",,pending,3,,,\n"
+		_, err := sync.ImportTasksCSV(strings.NewReader(data), "test-user-id")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "row 3:")
+		assert.Contains(t, err.Error(), "priority must be between 1 and 5")
+	})
+
+	t.Run("InvalidFields", func(t *testing.T) {
+		rows := map[string]string{
+			"no title":      ",,pending,3,,,",
+			"status":        "Task,,done,3,,,",
+			"estimate":      "Task,,pending,3,-5,,",
+			"estimate text": "Task,,pending,3,soon,,",
+			"due date":      "Task,,pending,3,,2026-03-02,",
+			"empty tag":     "Task,,pending,3,,,home;;work",
+		}
+		for name, row := range rows {
+			_, err := sync.ImportTasksCSV(strings.NewReader(header+row+"\n"), "test-user-id")
+			require.Error(t, err, name)
+			assert.Contains(t, err.Error(), "row 2:", name)
+		}
+	})
+
+	t.Run("NoTitleColumn", func(t *testing.T) {
+		_, err := sync.ImportTasksCSV(strings.NewReader("description\nSomething\n"), "test-user-id")
+		assert.ErrorContains(t, err, "row 1: header has no title column")
+	})
+
+	t.Run("GenericCSVIsNotATaskExport", func(t *testing.T) {
+		assert.False(t, sync.IsTasksCSV("title,priority\nBuy milk,3\n"))
+		assert.False(t, sync.IsTasksCSV("TYPE,CONTENT\ntask,Buy milk\n"))
+	})
+}
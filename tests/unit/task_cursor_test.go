@@ -0,0 +1,206 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskCursor(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T, count int) (*hereandnow.TaskService, http.Handler, func(title string) *models.Task) {
+		store := memstore.New()
+		taskService, contextService := newMemstoreServices(store)
+		fake := clock.NewFake(start)
+		taskService.SetClock(fake)
+
+		create := func(title string) *models.Task {
+			task, err := taskService.CreateTask("test-user-id", memstoreTaskRequest(title))
+			require.NoError(t, err)
+			fake.Advance(time.Minute)
+			return task
+		}
+		for i := 1; i <= count; i++ {
+			create(fmt.Sprintf("Task %d", i))
+		}
+
+		lat, lng := 37.7749, -122.4194
+		_, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+			Latitude: &lat, Longitude: &lng, AvailableMinutes: 60, EnergyLevel: 3,
+		})
+		require.NoError(t, err)
+
+		return taskService, newContextHeaderRouter(taskService, contextService, false), create
+	}
+
+	getPage := func(t *testing.T, router http.Handler, query url.Values) api.TaskListResponse {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks?"+query.Encode(), "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response api.TaskListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("PagesThroughEachTaskOnce", func(t *testing.T) {
+		service, router, create := setup(t, 5)
+		first := map[string]string{}
+
+		seen := map[string]int{}
+		query := url.Values{"limit": {"2"}}
+		pages := 0
+		for {
+			page := getPage(t, router, query)
+			pages++
+			for _, task := range page.Tasks {
+				seen[task.Title]++
+				if pages == 1 {
+					first[task.Title] = task.ID
+				}
+			}
+
+			if pages == 1 {
+				// Changed between fetches: with an offset, completing a task
+				// on the first page would skip one on the second
+				_, err := service.CompleteTask(first["Task 1"], "test-user-id")
+				require.NoError(t, err)
+				create("Task 6")
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			require.Less(t, pages, 10, "paging did not end")
+			query.Set("cursor", page.NextCursor)
+		}
+
+		assert.Equal(t, 3, pages)
+		for i := 1; i <= 6; i++ {
+			assert.Equal(t, 1, seen[fmt.Sprintf("Task %d", i)], "Task %d", i)
+		}
+	})
+
+	t.Run("LastPageHasNoCursor", func(t *testing.T) {
+		_, router, _ := setup(t, 2)
+
+		page := getPage(t, router, url.Values{"limit": {"2"}})
+		assert.Len(t, page.Tasks, 2)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("RejectsInvalidCursor", func(t *testing.T) {
+		_, router, _ := setup(t, 1)
+
+		for _, cursor := range []string{"not-a-cursor!", "bm8tc2VwYXJhdG9y", api.TaskCursor{ID: "x"}.Encode()[:4]} {
+			w := serveRequest(router, http.MethodGet, "/api/v1/tasks?cursor="+url.QueryEscape(cursor), "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, cursor)
+			assert.Contains(t, w.Body.String(), "Invalid cursor")
+		}
+	})
+
+	t.Run("RejectsCursorWithSortOrOffset", func(t *testing.T) {
+		_, router, _ := setup(t, 3)
+		page := getPage(t, router, url.Values{"limit": {"1"}})
+		require.NotEmpty(t, page.NextCursor)
+
+		for _, extra := range []string{"&sort=priority", "&offset=1"} {
+			w := serveRequest(router, http.MethodGet, "/api/v1/tasks?cursor="+page.NextCursor+extra, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, extra)
+		}
+	})
+
+	t.Run("PagesInRequestedSortOrder", func(t *testing.T) {
+		_, router, create := setup(t, 0)
+		for _, title := range []string{"B", "A", "B", "C"} {
+			create(title)
+		}
+
+		var titles []string
+		seen := map[string]bool{}
+		query := url.Values{"limit": {"1"}, "sort": {"title"}, "order": {"desc"}}
+		for {
+			page := getPage(t, router, query)
+			for _, task := range page.Tasks {
+				assert.False(t, seen[task.ID], "task %s repeated", task.ID)
+				seen[task.ID] = true
+				titles = append(titles, task.Title)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			require.Less(t, len(titles), 10, "paging did not end")
+			query.Set("cursor", page.NextCursor)
+		}
+		assert.Equal(t, []string{"C", "B", "B", "A"}, titles)
+
+		// A cursor only continues the ordering it came from
+		first := getPage(t, router, url.Values{"limit": {"1"}, "sort": {"title"}})
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks?sort=title&order=desc&cursor="+first.NextCursor, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("RejectsUnknownSortOrOrder", func(t *testing.T) {
+		_, router, _ := setup(t, 1)
+
+		for _, query := range []string{"sort=updated_at", "sort=title&order=sideways"} {
+			w := serveRequest(router, http.MethodGet, "/api/v1/tasks?"+query, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Contains(t, w.Body.String(), "Invalid sort order")
+		}
+	})
+
+	t.Run("CapsLimit", func(t *testing.T) {
+		_, router, _ := setup(t, api.MaxTaskPageSize+1)
+
+		page := getPage(t, router, url.Values{"limit": {"1000"}})
+		assert.Len(t, page.Tasks, api.MaxTaskPageSize)
+		assert.NotEmpty(t, page.NextCursor)
+	})
+
+	t.Run("DueDateSortPutsUndatedLast", func(t *testing.T) {
+		soon, later := start.Add(time.Hour), start.Add(48*time.Hour)
+		tasks := []models.Task{
+			{ID: "undated", CreatedAt: start},
+			{ID: "later", CreatedAt: start, DueAt: &later},
+			{ID: "soon", CreatedAt: start, DueAt: &soon},
+		}
+		ids := func(order api.TaskOrder) []string {
+			page, _ := api.PageTasks(tasks, order, nil, 0)
+			var result []string
+			for _, task := range page {
+				result = append(result, task.ID)
+			}
+			return result
+		}
+
+		assert.Equal(t, []string{"soon", "later", "undated"}, ids(api.TaskOrder{SortBy: "due_at"}))
+		assert.Equal(t, []string{"later", "soon", "undated"}, ids(api.TaskOrder{SortBy: "due_at", Desc: true}))
+
+		page, next := api.PageTasks(tasks, api.TaskOrder{SortBy: "due_at"}, nil, 2)
+		require.Len(t, page, 2)
+		cursor, err := api.DecodeTaskCursor(next)
+		require.NoError(t, err)
+		rest, _ := api.PageTasks(tasks, api.TaskOrder{SortBy: "due_at"}, cursor, 2)
+		require.Len(t, rest, 1)
+		assert.Equal(t, "undated", rest[0].ID)
+	})
+
+	t.Run("CursorRoundTrips", func(t *testing.T) {
+		cursor := api.TaskCursor{CreatedAt: start.Add(1500 * time.Millisecond), ID: "task-1"}
+		decoded, err := api.DecodeTaskCursor(cursor.Encode())
+		require.NoError(t, err)
+		assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+		assert.Equal(t, "task-1", decoded.ID)
+	})
+}
@@ -0,0 +1,183 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_Dependencies(t *testing.T) {
+	create := func(t *testing.T, service *hereandnow.TaskService, title string) *models.Task {
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest(title))
+		require.NoError(t, err)
+		return task
+	}
+
+	t.Run("RejectsCycleNamingTheLoop", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		a := create(t, service, "A")
+		b := create(t, service, "B")
+		c := create(t, service, "C")
+
+		_, err := service.AddDependency("test-user-id", a.ID, b.ID, models.DependencyTypeBlocking)
+		require.NoError(t, err)
+		_, err = service.AddDependency("test-user-id", b.ID, c.ID, models.DependencyTypeSuggested)
+		require.NoError(t, err)
+
+		_, err = service.AddDependency("test-user-id", c.ID, a.ID, models.DependencyTypeBlocking)
+		var cycle *models.DependencyCycleError
+		require.True(t, errors.As(err, &cycle), "got %v", err)
+		assert.Contains(t, err.Error(), `"C" -> "A" -> "B" -> "C"`)
+
+		_, err = service.AddDependency("test-user-id", a.ID, a.ID, models.DependencyTypeBlocking)
+		assert.Error(t, err, "a task cannot depend on itself")
+	})
+
+	t.Run("RejectsDuplicatesAndRemoves", func(t *testing.T) {
+		service, _ := newMemstoreServices(memstore.New())
+		send := create(t, service, "Send report")
+		draft := create(t, service, "Write draft")
+
+		_, err := service.AddDependency("test-user-id", send.ID, draft.ID, models.DependencyTypeBlocking)
+		require.NoError(t, err)
+		_, err = service.AddDependency("test-user-id", send.ID, draft.ID, models.DependencyTypeSuggested)
+		assert.ErrorIs(t, err, hereandnow.ErrDependencyExists)
+
+		details, err := service.GetTaskDependencies(send.ID)
+		require.NoError(t, err)
+		require.Len(t, details, 1)
+		assert.Equal(t, "Write draft", details[0].Task.Title)
+
+		require.NoError(t, service.RemoveDependency("test-user-id", send.ID, draft.ID))
+		details, err = service.GetTaskDependencies(send.ID)
+		require.NoError(t, err)
+		assert.Empty(t, details)
+		assert.Error(t, service.RemoveDependency("test-user-id", send.ID, draft.ID), "already removed")
+	})
+
+	t.Run("SuggestedDependencyDoesNotHideTask", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		send := create(t, service, "Send invitations")
+		room := create(t, service, "Book room")
+		draft := create(t, service, "Write agenda")
+
+		_, err := service.AddDependency("test-user-id", send.ID, room.ID, models.DependencyTypeSuggested)
+		require.NoError(t, err)
+
+		filter := filters.NewDependencyFilter(filters.DefaultFilterConfig, store.Dependencies(), store.Tasks())
+		ctx := createTestContext(nil, nil, 60, 3)
+		visible, _ := filter.Apply(ctx, *send)
+		assert.True(t, visible)
+
+		_, err = service.AddDependency("test-user-id", send.ID, draft.ID, models.DependencyTypeBlocking)
+		require.NoError(t, err)
+		visible, _ = filter.Apply(ctx, *send)
+		assert.False(t, visible)
+	})
+
+	t.Run("OtherUsersTasksNeedEditableSharedList", func(t *testing.T) {
+		shared, err := models.NewTaskList("Household", "", "bob")
+		require.NoError(t, err)
+		private, err := models.NewTaskList("Bob's errands", "", "bob")
+		require.NoError(t, err)
+
+		mine := createTestTask("Paint fence", nil, 3)
+		mine.ListID = &shared.ID
+		theirs := createTestTask("Buy paint", nil, 3)
+		theirs.CreatorID = "bob"
+		theirs.ListID = &shared.ID
+		hidden := createTestTask("Return ladder", nil, 3)
+		hidden.CreatorID = "bob"
+		hidden.ListID = &private.ID
+
+		store := memstore.New(memstore.WithLists(*shared, *private), memstore.WithTasks(mine, theirs, hidden))
+		service, _ := newMemstoreServices(store)
+		service.SetListRepository(store.TaskLists())
+		service.SetListMemberRepository(store.ListMembers())
+
+		_, err = service.AddDependency("test-user-id", mine.ID, theirs.ID, models.DependencyTypeBlocking)
+		assert.ErrorIs(t, err, hereandnow.ErrDependencyNotAllowed, "not a member yet")
+
+		member, err := models.NewListMember(shared.ID, "test-user-id", "bob", models.MemberRoleEditor)
+		require.NoError(t, err)
+		member.Accept()
+		require.NoError(t, store.ListMembers().Create(*member))
+
+		_, err = service.AddDependency("test-user-id", mine.ID, theirs.ID, models.DependencyTypeBlocking)
+		assert.NoError(t, err)
+		_, err = service.AddDependency("test-user-id", mine.ID, hidden.ID, models.DependencyTypeBlocking)
+		assert.ErrorIs(t, err, hereandnow.ErrDependencyNotAllowed, "the other list is not shared")
+	})
+}
+
+func TestTaskDependencyEndpoints(t *testing.T) {
+	setup := func(service api.TaskDependencyService) http.Handler {
+		handler := api.NewTaskHandler(&StubAPITaskService{}, nil)
+		if service != nil {
+			handler.SetDependencyService(service)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: handler,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router
+	}
+
+	service, _ := newMemstoreServices(memstore.New())
+	a, err := service.CreateTask("test-user-id", memstoreTaskRequest("A"))
+	require.NoError(t, err)
+	b, err := service.CreateTask("test-user-id", memstoreTaskRequest("B"))
+	require.NoError(t, err)
+	router := setup(service)
+	path := "/api/v1/tasks/" + a.ID + "/dependencies"
+
+	w := serveRequest(router, http.MethodPost, path, `{"depends_on_task_id":"`+b.ID+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"dependency_type":"blocking"`)
+
+	w = serveRequest(router, http.MethodPost, path, `{"depends_on_task_id":"`+b.ID+`"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, "duplicate")
+
+	w = serveRequest(router, http.MethodPost, "/api/v1/tasks/"+b.ID+"/dependencies", `{"depends_on_task_id":"`+a.ID+`","dependency_type":"suggested"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var cycleErr api.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cycleErr))
+	assert.Contains(t, cycleErr.Details, `"B" -> "A" -> "B"`)
+
+	w = serveRequest(router, http.MethodPost, path, `{"depends_on_task_id":"`+a.ID+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "self-dependency")
+	w = serveRequest(router, http.MethodPost, path, `{"depends_on_task_id":"`+b.ID+`","dependency_type":"blocks"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "unknown type")
+	w = serveRequest(router, http.MethodPost, path, `{"depends_on_task_id":"missing"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveRequest(router, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+
+	w = serveRequest(router, http.MethodDelete, path+"/"+b.ID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = serveRequest(router, http.MethodDelete, path+"/"+b.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveRequest(setup(nil), http.MethodPost, path, `{"depends_on_task_id":"`+b.ID+`"}`)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
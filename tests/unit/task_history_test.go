@@ -0,0 +1,258 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/clock"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTasks(t *testing.T) {
+	before := createTestTask("Buy milk", nil, 3)
+	after := before
+	after.Title = "Buy oat milk"
+	after.Priority = 5
+	after.UpdatedAt = before.UpdatedAt.Add(time.Minute)
+	after.Version = before.Version + 1
+
+	changes, err := models.DiffTasks(before, after)
+	require.NoError(t, err)
+	require.Len(t, changes, 2, "updated_at and version are left out")
+	assert.JSONEq(t, `"Buy milk"`, string(changes["title"].Old))
+	assert.JSONEq(t, `"Buy oat milk"`, string(changes["title"].New))
+	assert.JSONEq(t, `3`, string(changes["priority"].Old))
+	assert.JSONEq(t, `5`, string(changes["priority"].New))
+
+	t.Run("OmittedFieldsAreNull", func(t *testing.T) {
+		points := 3
+		after := before
+		after.EffortPoints = &points
+
+		changes, err := models.DiffTasks(before, after)
+		require.NoError(t, err)
+		assert.JSONEq(t, `null`, string(changes["effort_points"].Old))
+		assert.JSONEq(t, `3`, string(changes["effort_points"].New))
+	})
+
+	t.Run("NoChanges", func(t *testing.T) {
+		changes, err := models.DiffTasks(before, before)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+}
+
+func TestTaskService_TaskHistory(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	type fixture struct {
+		store   *memstore.Store
+		service *hereandnow.TaskService
+		clock   *clock.Fake
+		task    models.Task
+		alice   models.User
+		bob     models.User
+		carol   models.User
+	}
+
+	setup := func(t *testing.T) fixture {
+		users := make([]models.User, 3)
+		for i, name := range []string{"alice", "bob", "carol"} {
+			user, err := models.NewUser(name, name+"@example.com", name, "UTC")
+			require.NoError(t, err)
+			users[i] = *user
+		}
+		alice, bob, carol := users[0], users[1], users[2]
+
+		list, err := models.NewTaskList("Groceries", "", alice.ID)
+		require.NoError(t, err)
+
+		task := createTestTask("Buy milk", nil, 3)
+		task.CreatorID = alice.ID
+		task.ListID = &list.ID
+
+		store := memstore.New(memstore.WithUsers(users...), memstore.WithLists(*list), memstore.WithTasks(task))
+		member, err := models.NewListMember(list.ID, bob.ID, alice.ID, models.MemberRoleEditor)
+		require.NoError(t, err)
+		member.Accept()
+		require.NoError(t, store.ListMembers().Create(*member))
+
+		fake := clock.NewFake(start)
+		service, _ := newMemstoreServices(store)
+		service.SetClock(fake)
+		service.SetListRepository(store.TaskLists())
+		service.SetListMemberRepository(store.ListMembers())
+		service.EnableTaskHistory(store.TaskHistory())
+
+		return fixture{store: store, service: service, clock: fake, task: task, alice: alice, bob: bob, carol: carol}
+	}
+
+	t.Run("RecordsWhoChangedWhat", func(t *testing.T) {
+		f := setup(t)
+
+		title := "Buy oat milk"
+		_, err := f.service.UpdateTask(f.task.ID, hereandnow.UpdateTaskRequest{Title: &title, ActorID: f.alice.ID})
+		require.NoError(t, err)
+		f.clock.Advance(time.Hour)
+		_, err = f.service.CompleteTask(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+
+		history, err := f.service.GetTaskHistory(f.task.ID, f.bob.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+
+		assert.Equal(t, f.alice.ID, *history[0].ActorID)
+		assert.Equal(t, []string{"title"}, history[0].ChangedFields())
+		assert.Equal(t, start, history[0].CreatedAt)
+
+		completed := history[1]
+		assert.Equal(t, f.bob.ID, *completed.ActorID, "a member sees that bob completed the task")
+		assert.Equal(t, start.Add(time.Hour), completed.CreatedAt)
+		assert.Equal(t, []string{"completed_at", "status"}, completed.ChangedFields())
+		assert.JSONEq(t, `"pending"`, string(completed.Changes["status"].Old))
+		assert.JSONEq(t, `"completed"`, string(completed.Changes["status"].New))
+	})
+
+	t.Run("UnchangedSaveIsNotRecorded", func(t *testing.T) {
+		f := setup(t)
+
+		title := f.task.Title
+		_, err := f.service.UpdateTask(f.task.ID, hereandnow.UpdateTaskRequest{Title: &title, ActorID: f.alice.ID})
+		require.NoError(t, err)
+
+		history, err := f.service.GetTaskHistory(f.task.ID, f.alice.ID)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("SnoozeAndReorderRecordTheActor", func(t *testing.T) {
+		f := setup(t)
+
+		_, err := f.service.SnoozeTask(f.task.ID, f.bob.ID, start.Add(24*time.Hour))
+		require.NoError(t, err)
+		_, err = f.service.ReorderTask(f.task.ID, "", f.bob.ID)
+		require.NoError(t, err)
+
+		history, err := f.service.GetTaskHistory(f.task.ID, f.alice.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, []string{"snoozed_until"}, history[0].ChangedFields())
+		assert.Equal(t, []string{"position"}, history[1].ChangedFields())
+		for _, entry := range history {
+			require.NotNil(t, entry.ActorID)
+			assert.Equal(t, f.bob.ID, *entry.ActorID)
+		}
+	})
+
+	t.Run("HiddenFromNonMembers", func(t *testing.T) {
+		f := setup(t)
+
+		_, err := f.service.GetTaskHistory(f.task.ID, f.carol.ID)
+		assert.ErrorIs(t, err, hereandnow.ErrTaskNotVisible)
+		_, err = f.service.GetTaskHistory("missing-task", f.alice.ID)
+		assert.ErrorIs(t, err, hereandnow.ErrTaskNotVisible)
+	})
+
+	t.Run("FailedUpdateRecordsNothing", func(t *testing.T) {
+		f := setup(t)
+
+		stale := f.task.Version - 1
+		title := "Buy oat milk"
+		_, err := f.service.UpdateTask(f.task.ID, hereandnow.UpdateTaskRequest{Title: &title, Version: &stale, ActorID: f.alice.ID})
+		require.ErrorIs(t, err, models.ErrTaskVersionConflict)
+
+		history, err := f.service.GetTaskHistory(f.task.ID, f.alice.ID)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("RetentionCleanup", func(t *testing.T) {
+		f := setup(t)
+
+		title := "Buy oat milk"
+		_, err := f.service.UpdateTask(f.task.ID, hereandnow.UpdateTaskRequest{Title: &title, ActorID: f.alice.ID})
+		require.NoError(t, err)
+
+		job := maintenance.PruneTaskHistoryJob(f.store.TaskHistory(), 24*time.Hour)
+		assert.Equal(t, maintenance.JobPruneTaskHistory, job.Name)
+
+		_, err = job.Run(start.Add(time.Hour))
+		require.NoError(t, err)
+		history, err := f.service.GetTaskHistory(f.task.ID, f.alice.ID)
+		require.NoError(t, err)
+		assert.Len(t, history, 1, "recent history is kept")
+
+		_, err = job.Run(start.Add(48 * time.Hour))
+		require.NoError(t, err)
+		history, err = f.service.GetTaskHistory(f.task.ID, f.alice.ID)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
+
+func TestTaskHandler_GetTaskHistory(t *testing.T) {
+	setup := func(t *testing.T, enabled bool) (http.Handler, models.Task) {
+		task := createTestTask("Plan the party", nil, 3)
+		task.CreatorID = "test-user-id"
+		store := memstore.New(memstore.WithTasks(task))
+
+		service, _ := newMemstoreServices(store)
+		service.EnableTaskHistory(store.TaskHistory())
+		priority := 7
+		_, err := service.UpdateTask(task.ID, hereandnow.UpdateTaskRequest{Priority: &priority, ActorID: "test-user-id"})
+		require.NoError(t, err)
+
+		handler := api.NewTaskHandler(&versionAPITaskService{tasks: store.Tasks()}, nil)
+		if enabled {
+			handler.SetHistoryService(service)
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: handler,
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router, task
+	}
+
+	t.Run("ListsChanges", func(t *testing.T) {
+		router, task := setup(t, true)
+
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/"+task.ID+"/history", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response api.TaskHistoryResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, 1, response.Total)
+		assert.Equal(t, "test-user-id", *response.History[0].ActorID)
+		assert.JSONEq(t, `7`, string(response.History[0].Changes["priority"].New))
+	})
+
+	t.Run("UnknownTask", func(t *testing.T) {
+		router, _ := setup(t, true)
+
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/missing-task/history", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		router, task := setup(t, false)
+
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/"+task.ID+"/history", "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTaskLinkService(store *memstore.Store) *hereandnow.TaskService {
+	service, _ := newMemstoreServices(store)
+	service.EnableTaskLinks(store.TaskLinks())
+	return service
+}
+
+func linkedRelations(t *testing.T, service *hereandnow.TaskService, taskID string) map[string]string {
+	linked, err := service.GetLinkedTasks(taskID)
+	require.NoError(t, err)
+
+	relations := make(map[string]string)
+	for _, l := range linked {
+		relations[l.Task.Title] = l.Relation
+	}
+	return relations
+}
+
+func TestTaskService_TaskLinks(t *testing.T) {
+	t.Run("RelatedLinkShowsOnBothTasks", func(t *testing.T) {
+		store := memstore.New()
+		service := newTaskLinkService(store)
+		plan, err := service.CreateTask("test-user-id", memstoreTaskRequest("Plan offsite"))
+		require.NoError(t, err)
+		venue, err := service.CreateTask("test-user-id", memstoreTaskRequest("Book venue"))
+		require.NoError(t, err)
+
+		link, err := service.LinkTasks(plan.ID, venue.ID, "test-user-id", models.TaskLinkTypeRelated, false)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskLinkTypeRelated, link.LinkType)
+
+		assert.Equal(t, map[string]string{"Book venue": models.TaskRelationRelated}, linkedRelations(t, service, plan.ID))
+		assert.Equal(t, map[string]string{"Plan offsite": models.TaskRelationRelated}, linkedRelations(t, service, venue.ID))
+
+		_, err = service.LinkTasks(venue.ID, plan.ID, "test-user-id", models.TaskLinkTypeRelated, false)
+		assert.Error(t, err, "tasks can be linked only once, whichever way round")
+
+		deps, err := store.Dependencies().GetDependenciesByTaskID(plan.ID)
+		require.NoError(t, err)
+		assert.Empty(t, deps, "a link is not a dependency")
+	})
+
+	t.Run("DuplicateLinkCancelsSourceWhenAsked", func(t *testing.T) {
+		service := newTaskLinkService(memstore.New())
+		original, err := service.CreateTask("test-user-id", memstoreTaskRequest("Renew passport"))
+		require.NoError(t, err)
+		duplicate, err := service.CreateTask("test-user-id", memstoreTaskRequest("Passport renewal"))
+		require.NoError(t, err)
+		kept, err := service.CreateTask("test-user-id", memstoreTaskRequest("Renew my passport"))
+		require.NoError(t, err)
+
+		_, err = service.LinkTasks(duplicate.ID, original.ID, "test-user-id", models.TaskLinkTypeDuplicate, true)
+		require.NoError(t, err)
+		_, err = service.LinkTasks(kept.ID, original.ID, "test-user-id", models.TaskLinkTypeDuplicate, false)
+		require.NoError(t, err)
+
+		cancelled, err := service.GetTask(duplicate.ID)
+		require.NoError(t, err)
+		assert.True(t, cancelled.IsCancelled())
+
+		notCancelled, err := service.GetTask(kept.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, notCancelled.Status)
+
+		stillOpen, err := service.GetTask(original.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, stillOpen.Status)
+
+		assert.Equal(t, map[string]string{"Renew passport": models.TaskRelationDuplicateOf}, linkedRelations(t, service, duplicate.ID))
+		assert.Equal(t, map[string]string{
+			"Passport renewal":  models.TaskRelationDuplicatedBy,
+			"Renew my passport": models.TaskRelationDuplicatedBy,
+		}, linkedRelations(t, service, original.ID))
+	})
+
+	t.Run("RemovesLinkFromEitherEnd", func(t *testing.T) {
+		service := newTaskLinkService(memstore.New())
+		a, err := service.CreateTask("test-user-id", memstoreTaskRequest("Write draft"))
+		require.NoError(t, err)
+		b, err := service.CreateTask("test-user-id", memstoreTaskRequest("Collect feedback"))
+		require.NoError(t, err)
+
+		_, err = service.LinkTasks(a.ID, b.ID, "test-user-id", models.TaskLinkTypeRelated, false)
+		require.NoError(t, err)
+
+		require.NoError(t, service.UnlinkTasks(b.ID, a.ID))
+		assert.Empty(t, linkedRelations(t, service, a.ID))
+		assert.Empty(t, linkedRelations(t, service, b.ID))
+
+		assert.Error(t, service.UnlinkTasks(a.ID, b.ID), "already removed")
+	})
+
+	t.Run("RejectsInvalidLinks", func(t *testing.T) {
+		service := newTaskLinkService(memstore.New())
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Water plants"))
+		require.NoError(t, err)
+		other, err := service.CreateTask("test-user-id", memstoreTaskRequest("Feed cat"))
+		require.NoError(t, err)
+
+		_, err = service.LinkTasks(task.ID, task.ID, "test-user-id", models.TaskLinkTypeRelated, false)
+		assert.Error(t, err)
+		_, err = service.LinkTasks(task.ID, other.ID, "test-user-id", models.TaskLinkType("blocks"), false)
+		assert.Error(t, err)
+		_, err = service.LinkTasks(task.ID, "missing", "test-user-id", models.TaskLinkTypeRelated, false)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,37 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskRepository_GetByListID(t *testing.T) {
+	db := setupSoftDeleteDB(t)
+	for id, position := range map[string]float64{"second": 2048, "first": 1024, "deleted": 512, "elsewhere": 0} {
+		insertTaskWithMetadata(t, db, id, `{}`)
+		listID := "list-1"
+		if id == "elsewhere" {
+			listID = "list-2"
+		}
+		_, err := db.Exec(`UPDATE tasks SET list_id = ?, position = ? WHERE id = ?`, listID, position, id)
+		require.NoError(t, err)
+	}
+	tasks := storage.NewTaskRepository(db)
+	require.NoError(t, tasks.Delete("deleted"))
+
+	// The list services read tasks through it
+	var repo hereandnow.ListTaskRepository = tasks
+	inList, err := repo.GetByListID("list-1")
+	require.NoError(t, err)
+	require.Len(t, inList, 2)
+	assert.Equal(t, "first", inList[0].ID)
+	assert.Equal(t, "second", inList[1].ID)
+
+	empty, err := repo.GetByListID("no-such-list")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
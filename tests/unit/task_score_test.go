@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterEngine_ScoreTasks(t *testing.T) {
+	ctx := createTestContext(nil, nil, 60, 3)
+
+	dueSoon := createTestTask("File taxes", nil, 3)
+	soon := ctx.Timestamp.Add(time.Hour)
+	dueSoon.DueAt = &soon
+
+	important := createTestTask("Renew passport", nil, 5)
+	minor := createTestTask("Sort photos", nil, 1)
+
+	engine := filters.NewEngine(filters.DefaultFilterConfig, &MockAuditRepo{})
+
+	t.Run("SortsByScoreWithBreakdown", func(t *testing.T) {
+		scored, err := engine.ScoreTasks(ctx, []models.Task{minor, important, dueSoon})
+		require.NoError(t, err)
+		require.Len(t, scored, 3)
+
+		assert.Equal(t, "File taxes", scored[0].Task.Title)
+		assert.Equal(t, "Renew passport", scored[1].Task.Title)
+		assert.Equal(t, "Sort photos", scored[2].Task.Title)
+
+		for i, task := range scored {
+			assert.True(t, task.Visible)
+			if i > 0 {
+				assert.GreaterOrEqual(t, scored[i-1].Score, task.Score)
+			}
+
+			sum := 0.0
+			for _, part := range task.Breakdown {
+				sum += part
+			}
+			assert.InDelta(t, task.Score, sum, 1e-9)
+			assert.Contains(t, task.Breakdown, filters.ScoreUrgency)
+		}
+		assert.Greater(t, scored[0].Breakdown[filters.ScoreUrgency], scored[1].Breakdown[filters.ScoreUrgency])
+	})
+
+	t.Run("HiddenTasksScoreZeroAtTheEnd", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.EnableSocialFilter = true
+		engine := filters.NewEngine(config, &MockAuditRepo{})
+
+		driving := ctx
+		driving.SocialContext = models.SocialContextDriving
+		unsafe := createTestTask("Reply to email", nil, 5)
+		unsafe.Metadata = []byte(`{"social_tags": ["unsafe_while_driving"]}`)
+
+		scored, err := engine.ScoreTasks(driving, []models.Task{unsafe, minor})
+		require.NoError(t, err)
+		require.Len(t, scored, 2)
+		assert.Equal(t, "Sort photos", scored[0].Task.Title)
+		assert.False(t, scored[1].Visible)
+		assert.Zero(t, scored[1].Score)
+		assert.Equal(t, []string{"social"}, scored[1].HiddenBy)
+
+		assert.Len(t, filters.VisibleScoredTasks(scored), 1)
+	})
+
+	t.Run("RejectsInvalidContext", func(t *testing.T) {
+		invalid := ctx
+		invalid.EnergyLevel = 9
+		_, err := engine.ScoreTasks(invalid, []models.Task{minor})
+		assert.Error(t, err)
+	})
+}
+
+func TestTaskService_GetScoredTasks(t *testing.T) {
+	store := memstore.New()
+	service, contextService := newMemstoreServices(store)
+	lat, lng := 37.7749, -122.4194
+	_, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+		Latitude: &lat, Longitude: &lng, AvailableMinutes: 60, EnergyLevel: 3,
+	})
+	require.NoError(t, err)
+
+	for _, title := range []string{"Low", "High"} {
+		req := memstoreTaskRequest(title)
+		if title == "High" {
+			req.Priority = 5
+		}
+		_, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+	}
+
+	scored, err := service.GetScoredTasks("test-user-id")
+	require.NoError(t, err)
+	require.Len(t, scored, 2)
+	assert.Equal(t, "High", scored[0].Task.Title)
+}
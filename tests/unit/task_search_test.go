@@ -0,0 +1,180 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnippet(t *testing.T) {
+	t.Run("MarksWholeWords", func(t *testing.T) {
+		assert.Equal(t, []hereandnow.SnippetFragment{
+			{Text: "Buy "}, {Text: "groceries", Match: true}, {Text: " and "}, {Text: "milk", Match: true},
+		}, hereandnow.Snippet("Buy groceries and milk", "milk groc*", 80))
+	})
+
+	t.Run("CutsAroundFirstMatch", func(t *testing.T) {
+		text := "Before leaving on the long trip remember to water the tomato plants in the back garden every morning"
+		snippet := hereandnow.Snippet(text, "tomato", 40)
+		require.Len(t, snippet, 3)
+		assert.Equal(t, "…water the ", snippet[0].Text, "starts at a word shortly before the match")
+		assert.Equal(t, hereandnow.SnippetFragment{Text: "tomato", Match: true}, snippet[1])
+		assert.Equal(t, " plants in the back gard…", snippet[2].Text)
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		assert.Nil(t, hereandnow.Snippet("Call mom", "groceries", 80))
+	})
+
+	t.Run("TaskSnippetFallsBackToDescription", func(t *testing.T) {
+		task := models.Task{Title: "Errands", Description: "Pick up the dry cleaning"}
+		assert.Equal(t, []hereandnow.SnippetFragment{
+			{Text: "Pick up the dry "}, {Text: "cleaning", Match: true},
+		}, hereandnow.TaskSnippet(task, "clean*"))
+	})
+}
+
+func TestSearchTasks_SharedLists(t *testing.T) {
+	store := memstore.New()
+	taskService, _ := newMemstoreServices(store)
+	taskService.SetListMemberRepository(store.ListMembers())
+
+	shared, pending := "shared-list", "pending-list"
+	for _, listID := range []string{shared, pending} {
+		owner, err := models.NewListMember(listID, "owner-id", "owner-id", models.MemberRoleOwner)
+		require.NoError(t, err)
+		require.NoError(t, store.ListMembers().Create(*owner))
+
+		_, err = taskService.CreateTask("owner-id", hereandnow.CreateTaskRequest{
+			Title: "Groceries for " + listID, ListID: &listID, Priority: 3, Metadata: json.RawMessage(`{}`),
+		})
+		require.NoError(t, err)
+	}
+	joined, err := models.NewListMember(shared, "test-user-id", "owner-id", models.MemberRoleEditor)
+	require.NoError(t, err)
+	joined.Accept()
+	require.NoError(t, store.ListMembers().Create(*joined))
+	invited, err := models.NewListMember(pending, "test-user-id", "owner-id", models.MemberRoleEditor)
+	require.NoError(t, err)
+	require.NoError(t, store.ListMembers().Create(*invited))
+
+	_, err = taskService.CreateTask("test-user-id", memstoreTaskRequest("Buy groceries"))
+	require.NoError(t, err)
+	_, err = taskService.CreateTask("owner-id", memstoreTaskRequest("Owner's groceries"))
+	require.NoError(t, err)
+
+	tasks, err := taskService.SearchTasks("test-user-id", "groceries")
+	require.NoError(t, err)
+	var titles []string
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	assert.ElementsMatch(t, []string{"Buy groceries", "Groceries for shared-list"}, titles,
+		"lists the user has not joined, and other users' own tasks, are left out")
+}
+
+func TestGetTasks_Search(t *testing.T) {
+	store := memstore.New()
+	taskService, contextService := newMemstoreServices(store)
+	router := newContextHeaderRouter(taskService, contextService, false)
+
+	groceries, err := taskService.CreateTask("test-user-id", hereandnow.CreateTaskRequest{
+		Title: "Errands", Description: "Buy groceries on the way home", Priority: 3, Metadata: json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+	_, err = taskService.CreateTask("test-user-id", memstoreTaskRequest("Call plumber"))
+	require.NoError(t, err)
+	lat, lng := 37.7749, -122.4194
+	_, err = contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{
+		Latitude: &lat, Longitude: &lng, AvailableMinutes: 60, EnergyLevel: 3,
+	})
+	require.NoError(t, err)
+
+	w := serveRequest(router, http.MethodGet, "/api/v1/tasks?search=groc*", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response api.TaskListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Tasks, 1)
+	assert.Equal(t, groceries.ID, response.Tasks[0].ID)
+	assert.Equal(t, []hereandnow.SnippetFragment{
+		{Text: "Buy "}, {Text: "groceries", Match: true}, {Text: " on the way home"},
+	}, response.Snippets[groceries.ID])
+
+	w = serveRequest(router, http.MethodGet, "/api/v1/tasks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"snippets"`, "snippets are only sent for searches")
+}
+
+// rankedSearchService lists its tasks in a fixed order, as the storage
+// search ranks them, and pages them like the server does
+type rankedSearchService struct {
+	StubAPITaskService
+	ranked []models.Task
+}
+
+func (s *rankedSearchService) GetFilteredTasks(userID string, filters api.TaskFilters) (*api.TaskListResponse, error) {
+	page, next := api.PageTasks(s.ranked, filters.Order, filters.Cursor, filters.Limit)
+	return &api.TaskListResponse{Tasks: page, Total: len(s.ranked), NextCursor: next}, nil
+}
+
+func TestGetTasks_SearchKeepsRank(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	service := &rankedSearchService{ranked: []models.Task{
+		{ID: "best", Title: "Groceries", CreatedAt: start.Add(2 * time.Hour)},
+		{ID: "good", Title: "Buy groceries", CreatedAt: start},
+		{ID: "weak", Title: "Errands", Description: "groceries, bank and post office", CreatedAt: start.Add(time.Hour)},
+	}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{
+		Tasks: api.NewTaskHandler(service, nil),
+		AuthMiddleware: func(c *gin.Context) {
+			c.Set("user_id", "test-user-id")
+			c.Next()
+		},
+	}, api.RouteConfig{})
+
+	get := func(t *testing.T, query string) api.TaskListResponse {
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks?"+query, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response api.TaskListResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	ids := func(tasks []models.Task) []string {
+		var result []string
+		for _, task := range tasks {
+			result = append(result, task.ID)
+		}
+		return result
+	}
+
+	t.Run("BestMatchFirst", func(t *testing.T) {
+		assert.Equal(t, []string{"best", "good", "weak"}, ids(get(t, "search=groceries").Tasks))
+	})
+
+	t.Run("PagesFollowRank", func(t *testing.T) {
+		first := get(t, "search=groceries&limit=2")
+		assert.Equal(t, []string{"best", "good"}, ids(first.Tasks))
+		require.NotEmpty(t, first.NextCursor)
+
+		second := get(t, "search=groceries&limit=2&cursor="+first.NextCursor)
+		assert.Equal(t, []string{"weak"}, ids(second.Tasks))
+		assert.Empty(t, second.NextCursor)
+	})
+
+	t.Run("ExplicitSortOverridesRank", func(t *testing.T) {
+		assert.Equal(t, []string{"good", "weak", "best"}, ids(get(t, "search=groceries&sort=created_at").Tasks))
+	})
+}
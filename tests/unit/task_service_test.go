@@ -0,0 +1,276 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockServiceTaskRepository implements hereandnow.TaskRepository in memory
+type MockServiceTaskRepository struct {
+	tasks   map[string]models.Task
+	updates int
+}
+
+func NewMockServiceTaskRepository() *MockServiceTaskRepository {
+	return &MockServiceTaskRepository{
+		tasks: make(map[string]models.Task),
+	}
+}
+
+func (m *MockServiceTaskRepository) Create(task models.Task) error {
+	if _, exists := m.tasks[task.ID]; exists {
+		return fmt.Errorf("task already exists: %s", task.ID)
+	}
+	m.tasks[task.ID] = task
+	return nil
+}
+
+func (m *MockServiceTaskRepository) GetByID(taskID string) (*models.Task, error) {
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	return &task, nil
+}
+
+func (m *MockServiceTaskRepository) GetByUserID(userID string) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range m.tasks {
+		if task.CreatorID == userID || (task.AssigneeID != nil && *task.AssigneeID == userID) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *MockServiceTaskRepository) GetByStatus(userID string, status models.TaskStatus) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range m.tasks {
+		if task.CreatorID == userID && task.Status == status {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *MockServiceTaskRepository) Update(task models.Task) error {
+	if _, exists := m.tasks[task.ID]; !exists {
+		return fmt.Errorf("task not found: %s", task.ID)
+	}
+	m.tasks[task.ID] = task
+	m.updates++
+	return nil
+}
+
+func (m *MockServiceTaskRepository) Delete(taskID string) error {
+	delete(m.tasks, taskID)
+	return nil
+}
+
+func (m *MockServiceTaskRepository) GetByListID(listID string) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range m.tasks {
+		if task.ListID != nil && *task.ListID == listID {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *MockServiceTaskRepository) Search(userID string, query string) ([]models.Task, error) {
+	return m.GetByUserID(userID)
+}
+
+func newTestTaskService(taskRepo *MockServiceTaskRepository) *hereandnow.TaskService {
+	return hereandnow.NewTaskService(taskRepo, nil, nil, nil, nil)
+}
+
+// newReorderTestService returns a task service whose lists list-1 and
+// list-2 are owned by test-user-id, and a member repository for sharing them
+func newReorderTestService(taskRepo *MockServiceTaskRepository) (*hereandnow.TaskService, *memstore.Store) {
+	var lists []models.TaskList
+	for _, id := range []string{"list-1", "list-2"} {
+		lists = append(lists, models.TaskList{ID: id, Name: id, OwnerID: "test-user-id"})
+	}
+	store := memstore.New(memstore.WithLists(lists...))
+
+	service := newTestTaskService(taskRepo)
+	service.SetListRepository(store.TaskLists())
+	service.SetListMemberRepository(store.ListMembers())
+	return service, store
+}
+
+func createListTasks(t *testing.T, service *hereandnow.TaskService, listID string, titles ...string) []string {
+	var ids []string
+	for _, title := range titles {
+		task, err := service.CreateTask("test-user-id", hereandnow.CreateTaskRequest{
+			Title:    title,
+			ListID:   &listID,
+			Priority: 3,
+			Metadata: json.RawMessage(`{}`),
+		})
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func listTitles(t *testing.T, service *hereandnow.TaskService, listID string) []string {
+	tasks, err := service.GetTasksByList(listID)
+	require.NoError(t, err)
+
+	var titles []string
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func TestTaskService_ReorderTask(t *testing.T) {
+	t.Run("NewTasksAppendToList", func(t *testing.T) {
+		service, _ := newReorderTestService(NewMockServiceTaskRepository())
+		createListTasks(t, service, "list-1", "A", "B", "C")
+
+		assert.Equal(t, []string{"A", "B", "C"}, listTitles(t, service, "list-1"))
+	})
+
+	t.Run("MovesBetweenTwoTasks", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, _ := newReorderTestService(repo)
+		ids := createListTasks(t, service, "list-1", "A", "B", "C")
+
+		moved, err := service.ReorderTask(ids[2], ids[0], "test-user-id")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"A", "C", "B"}, listTitles(t, service, "list-1"))
+		assert.Greater(t, moved.Position, repo.tasks[ids[0]].Position)
+		assert.Less(t, moved.Position, repo.tasks[ids[1]].Position)
+		assert.Equal(t, 1, repo.updates, "only the moved task should be written")
+	})
+
+	t.Run("MovesToTop", func(t *testing.T) {
+		service, _ := newReorderTestService(NewMockServiceTaskRepository())
+		ids := createListTasks(t, service, "list-1", "A", "B", "C")
+
+		_, err := service.ReorderTask(ids[2], "", "test-user-id")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"C", "A", "B"}, listTitles(t, service, "list-1"))
+	})
+
+	t.Run("StableAfterSeveralMoves", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, _ := newReorderTestService(repo)
+		ids := createListTasks(t, service, "list-1", "A", "B", "C", "D")
+
+		_, err := service.ReorderTask(ids[3], ids[0], "test-user-id") // A D B C
+		require.NoError(t, err)
+		_, err = service.ReorderTask(ids[0], ids[2], "test-user-id") // D B C A
+		require.NoError(t, err)
+		_, err = service.ReorderTask(ids[1], "", "test-user-id") // B D C A
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"B", "D", "C", "A"}, listTitles(t, service, "list-1"))
+
+		// Untouched tasks keep their original positions
+		assert.Equal(t, 3*models.TaskPositionGap, repo.tasks[ids[2]].Position)
+	})
+
+	t.Run("RenumbersWhenGapExhausted", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, _ := newReorderTestService(repo)
+		ids := createListTasks(t, service, "list-1", "A", "B", "C")
+
+		// Repeatedly insert between A and whatever follows it, halving the gap
+		for i := 0; i < 60; i++ {
+			mover := ids[1+i%2]
+			_, err := service.ReorderTask(mover, ids[0], "test-user-id")
+			require.NoError(t, err)
+		}
+
+		tasks, err := service.GetTasksByList("list-1")
+		require.NoError(t, err)
+		require.Len(t, tasks, 3)
+		assert.Greater(t, repo.updates, 60, "list should have been renumbered")
+		assert.Equal(t, "A", tasks[0].Title)
+		assert.Equal(t, "C", tasks[1].Title)
+		assert.Equal(t, "B", tasks[2].Title)
+		for i := 1; i < len(tasks); i++ {
+			assert.Less(t, tasks[i-1].Position, tasks[i].Position)
+		}
+	})
+
+	t.Run("RejectsTaskFromOtherList", func(t *testing.T) {
+		service, _ := newReorderTestService(NewMockServiceTaskRepository())
+		ids := createListTasks(t, service, "list-1", "A")
+		other := createListTasks(t, service, "list-2", "X")
+
+		_, err := service.ReorderTask(ids[0], other[0], "test-user-id")
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectsTaskWithoutList", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, _ := newReorderTestService(repo)
+		task := createTestTask("Loose", nil, 3)
+		require.NoError(t, repo.Create(task))
+
+		_, err := service.ReorderTask(task.ID, "", "test-user-id")
+		assert.Error(t, err)
+	})
+
+	t.Run("RequiresListEditAccess", func(t *testing.T) {
+		service, store := newReorderTestService(NewMockServiceTaskRepository())
+		ids := createListTasks(t, service, "list-1", "A", "B")
+
+		_, err := service.ReorderTask(ids[1], "", "bob")
+		assert.ErrorIs(t, err, hereandnow.ErrReorderNotAllowed, "bob is not a member")
+
+		viewer, err := models.NewListMember("list-1", "bob", "test-user-id", models.MemberRoleViewer)
+		require.NoError(t, err)
+		viewer.Accept()
+		require.NoError(t, store.ListMembers().Create(*viewer))
+		_, err = service.ReorderTask(ids[1], "", "bob")
+		assert.ErrorIs(t, err, hereandnow.ErrReorderNotAllowed, "viewers cannot reorder")
+		assert.Equal(t, []string{"A", "B"}, listTitles(t, service, "list-1"))
+
+		editor, err := models.NewListMember("list-1", "carol", "test-user-id", models.MemberRoleEditor)
+		require.NoError(t, err)
+		editor.Accept()
+		require.NoError(t, store.ListMembers().Create(*editor))
+		_, err = service.ReorderTask(ids[1], "", "carol")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"B", "A"}, listTitles(t, service, "list-1"))
+	})
+}
+
+func TestPositionBetween(t *testing.T) {
+	before, after := 1024.0, 2048.0
+
+	pos, ok := models.PositionBetween(&before, &after)
+	assert.True(t, ok)
+	assert.Equal(t, 1536.0, pos)
+
+	pos, ok = models.PositionBetween(nil, nil)
+	assert.True(t, ok)
+	assert.Equal(t, models.TaskPositionGap, pos)
+
+	pos, ok = models.PositionBetween(&after, nil)
+	assert.True(t, ok)
+	assert.Greater(t, pos, after)
+
+	pos, ok = models.PositionBetween(nil, &before)
+	assert.True(t, ok)
+	assert.Less(t, pos, before)
+
+	same := 1024.0
+	_, ok = models.PositionBetween(&before, &same)
+	assert.False(t, ok)
+}
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for input, want := range map[string]string{
+		"errands":     "errands",
+		"  Errands  ": "errands",
+		"#Work":       "work",
+		" # Home ":    "home",
+	} {
+		got, err := models.NormalizeTag(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "   ", "#", " # "} {
+		_, err := models.NormalizeTag(input)
+		assert.Error(t, err, "%q", input)
+	}
+
+	tags, err := models.NormalizeTags([]string{"Work", "errands", "#work"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work", "errands"}, tags)
+}
+
+func TestTask_HasTags(t *testing.T) {
+	task := models.Task{Tags: []string{"errands", "urgent"}}
+
+	assert.True(t, task.HasTags([]string{"errands"}, false))
+	assert.True(t, task.HasTags([]string{"#Errands", "urgent"}, false))
+	assert.False(t, task.HasTags([]string{"errands", "work"}, false))
+	assert.True(t, task.HasTags([]string{"errands", "work"}, true))
+	assert.False(t, task.HasTags([]string{"work", "home"}, true))
+	assert.True(t, task.HasTags(nil, false), "no tags asked for")
+}
+
+func TestTaskService_CreateTaskWithTags(t *testing.T) {
+	store := memstore.New()
+	service, _ := newMemstoreServices(store)
+
+	req := memstoreTaskRequest("Pick up parcel")
+	req.Tags = []string{"Errands", " urgent ", "#errands"}
+	task, err := service.CreateTask("test-user-id", req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"errands", "urgent"}, task.Tags)
+
+	stored, err := store.Tasks().GetByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"errands", "urgent"}, stored.Tags)
+
+	req.Tags = []string{"errands", " "}
+	_, err = service.CreateTask("test-user-id", req)
+	var validationErr *models.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Contains(t, validationErr.Fields, "tags")
+}
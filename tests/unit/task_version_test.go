@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_UpdateTaskVersion(t *testing.T) {
+	setup := func(t *testing.T) (*hereandnow.TaskService, *models.Task) {
+		service, _ := newMemstoreServices(memstore.New())
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Plan the party"))
+		require.NoError(t, err)
+		return service, task
+	}
+
+	t.Run("ReturnsNewVersion", func(t *testing.T) {
+		service, task := setup(t)
+		title := "Plan the birthday party"
+
+		updated, err := service.UpdateTask(task.ID, hereandnow.UpdateTaskRequest{Title: &title, Version: &task.Version})
+		require.NoError(t, err)
+		assert.Equal(t, task.Version+1, updated.Version)
+
+		stored, err := service.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updated.Version, stored.Version)
+	})
+
+	t.Run("RefusesStaleVersion", func(t *testing.T) {
+		service, task := setup(t)
+		priority := 5
+		_, err := service.UpdateTask(task.ID, hereandnow.UpdateTaskRequest{Priority: &priority})
+		require.NoError(t, err)
+
+		title := "Plan the birthday party"
+		_, err = service.UpdateTask(task.ID, hereandnow.UpdateTaskRequest{Title: &title, Version: &task.Version})
+		assert.ErrorIs(t, err, models.ErrTaskVersionConflict)
+
+		stored, err := service.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Plan the party", stored.Title)
+	})
+
+	t.Run("ConcurrentUpdatesFromOneVersion", func(t *testing.T) {
+		service, task := setup(t)
+		first, second := "First title", "Second title"
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, title := range []*string{&first, &second} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = service.UpdateTask(task.ID, hereandnow.UpdateTaskRequest{Title: title, Version: &task.Version})
+			}()
+		}
+		wg.Wait()
+
+		if errs[0] == nil {
+			assert.ErrorIs(t, errs[1], models.ErrTaskVersionConflict)
+		} else {
+			assert.ErrorIs(t, errs[0], models.ErrTaskVersionConflict)
+			assert.NoError(t, errs[1])
+		}
+	})
+}
+
+// versionAPITaskService serves the task handler straight from a memstore
+// task repository, which checks versions on update
+type versionAPITaskService struct {
+	StubAPITaskService
+	tasks *memstore.TaskRepository
+}
+
+func (s *versionAPITaskService) GetTaskByID(taskID string, userID string) (*models.Task, error) {
+	return s.tasks.GetByID(taskID)
+}
+
+func (s *versionAPITaskService) UpdateTask(task models.Task) (*models.Task, error) {
+	if err := s.tasks.Update(task); err != nil {
+		return nil, err
+	}
+	task.Version++
+	return &task, nil
+}
+
+func TestTaskHandler_UpdateTaskIfMatch(t *testing.T) {
+	setup := func(t *testing.T) (http.Handler, models.Task) {
+		task := createTestTask("Plan the party", nil, 3)
+		task.Version = 1
+		store := memstore.New(memstore.WithTasks(task))
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Tasks: api.NewTaskHandler(&versionAPITaskService{tasks: store.Tasks()}, nil),
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user", &models.User{ID: "test-user-id"})
+				c.Set("user_id", "test-user-id")
+				c.Next()
+			},
+		}, api.RouteConfig{})
+		return router, task
+	}
+	patch := func(router http.Handler, task models.Task, ifMatch, body string) *httptest.ResponseRecorder {
+		headers := map[string]string{}
+		if ifMatch != "" {
+			headers["If-Match"] = ifMatch
+		}
+		return serveRequestWithHeaders(router, http.MethodPatch, "/api/v1/tasks/"+task.ID, body, headers)
+	}
+
+	t.Run("GetReturnsETag", func(t *testing.T) {
+		router, task := setup(t)
+		w := serveRequest(router, http.MethodGet, "/api/v1/tasks/"+task.ID, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("RequiresIfMatch", func(t *testing.T) {
+		router, task := setup(t)
+		w := patch(router, task, "", `{"priority": 5}`)
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	})
+
+	t.Run("UpdatesMatchingVersion", func(t *testing.T) {
+		router, task := setup(t)
+		w := patch(router, task, `"1"`, `{"priority": 5}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		var updated models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, 2, updated.Version)
+		assert.Equal(t, 5, updated.Priority)
+	})
+
+	t.Run("StaleVersionGetsCurrentTask", func(t *testing.T) {
+		router, task := setup(t)
+		require.Equal(t, http.StatusOK, patch(router, task, `"1"`, `{"title": "Plan the birthday party"}`).Code)
+
+		w := patch(router, task, `"1"`, `{"priority": 5}`)
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		var conflict api.TaskConflictResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conflict))
+		require.NotNil(t, conflict.Task)
+		assert.Equal(t, "Plan the birthday party", conflict.Task.Title)
+		assert.Equal(t, 3, conflict.Task.Priority, "the stale change is not applied")
+	})
+
+	t.Run("ConcurrentPatchesFromOneVersion", func(t *testing.T) {
+		router, task := setup(t)
+
+		var wg sync.WaitGroup
+		codes := make([]int, 2)
+		for i, body := range []string{`{"title": "First title"}`, `{"title": "Second title"}`} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes[i] = patch(router, task, `"1"`, body).Code
+			}()
+		}
+		wg.Wait()
+
+		assert.ElementsMatch(t, []int{http.StatusOK, http.StatusPreconditionFailed}, codes)
+	})
+
+	t.Run("WildcardMatchesAnyVersion", func(t *testing.T) {
+		router, task := setup(t)
+		require.Equal(t, http.StatusOK, patch(router, task, `"1"`, `{"priority": 4}`).Code)
+		assert.Equal(t, http.StatusOK, patch(router, task, "*", `{"priority": 5}`).Code)
+	})
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoistPriority(t *testing.T) {
+	tests := []struct {
+		priority int
+		expected int
+	}{
+		{0, 1},
+		{1, 1},
+		{2, 1},
+		{3, 2},
+		{4, 3},
+		{5, 4},
+		{6, 4},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, sync.TodoistPriority(tt.priority), "priority %d", tt.priority)
+	}
+}
+
+func TestExportTodoist(t *testing.T) {
+	listID := "list-groceries"
+	due := time.Date(2024, 3, 15, 17, 30, 0, 0, time.UTC)
+	dueDate := time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)
+
+	inList := createTestTask("Buy milk", nil, 5)
+	inList.ListID = &listID
+	inList.DueAt = &due
+	inList.Metadata = json.RawMessage(`{"tags": ["errands", "quick win"]}`)
+
+	loose := createTestTask("Call mom", nil, 1)
+	loose.DueAt = &dueDate
+	loose.Status = models.TaskStatusCompleted
+
+	lists := []models.TaskList{{ID: listID, Name: "Groceries"}}
+
+	export := sync.ExportTodoist([]models.Task{inList, loose}, lists)
+
+	t.Run("FieldNames", func(t *testing.T) {
+		data, err := json.Marshal(export)
+		require.NoError(t, err)
+
+		var raw map[string][]map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &raw))
+
+		require.Len(t, raw["items"], 2)
+		for _, key := range []string{"content", "description", "priority", "due", "labels", "project_id", "checked"} {
+			assert.Contains(t, raw["items"][0], key)
+		}
+		assert.Contains(t, raw["projects"][0], "name")
+		assert.Contains(t, raw["labels"][0], "name")
+
+		dueJSON := raw["items"][0]["due"].(map[string]interface{})
+		assert.Equal(t, "2024-03-15", dueJSON["date"])
+		assert.Equal(t, "2024-03-15T17:30:00Z", dueJSON["datetime"])
+	})
+
+	t.Run("ProjectFromList", func(t *testing.T) {
+		assert.Equal(t, listID, export.Items[0].ProjectID)
+		assert.Equal(t, sync.TodoistInboxProject, export.Items[1].ProjectID)
+		assert.Equal(t, []sync.TodoistProject{
+			{ID: listID, Name: "Groceries"},
+			{ID: sync.TodoistInboxProject, Name: sync.TodoistInboxProject},
+		}, export.Projects)
+	})
+
+	t.Run("PriorityAndLabels", func(t *testing.T) {
+		assert.Equal(t, 4, export.Items[0].Priority)
+		assert.Equal(t, 1, export.Items[1].Priority)
+
+		assert.Equal(t, []string{"errands", "quick_win"}, export.Items[0].Labels)
+		assert.Empty(t, export.Items[1].Labels)
+		assert.Equal(t, []sync.TodoistLabel{{Name: "errands"}, {Name: "quick_win"}}, export.Labels)
+	})
+
+	t.Run("DateOnlyDueAndCompletion", func(t *testing.T) {
+		require.NotNil(t, export.Items[1].Due)
+		assert.Equal(t, "2024-03-16", export.Items[1].Due.Date)
+		assert.Empty(t, export.Items[1].Due.Datetime)
+		assert.True(t, export.Items[1].Checked)
+		assert.False(t, export.Items[0].Checked)
+	})
+}
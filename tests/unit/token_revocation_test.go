@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/maintenance"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRevokedTokenDB(t *testing.T) *storage.DB {
+	db := setupSessionDB(t)
+	_, err := db.Exec(`CREATE TABLE revoked_tokens (jti TEXT PRIMARY KEY NOT NULL, expires_at DATETIME NOT NULL)`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestRevokedTokenRepository(t *testing.T) {
+	now := time.Now()
+
+	t.Run("RevokeUntilExpiry", func(t *testing.T) {
+		repo := storage.NewRevokedTokenRepository(setupRevokedTokenDB(t))
+
+		require.NoError(t, repo.Revoke("jti-1", now.Add(time.Hour)))
+		require.NoError(t, repo.Revoke("jti-1", now.Add(time.Hour)), "revoking twice is a no-op")
+
+		revoked, err := repo.IsRevoked("jti-1", now)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = repo.IsRevoked("jti-2", now)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		revoked, err = repo.IsRevoked("jti-1", now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.False(t, revoked, "an expired token no longer needs refusing")
+
+		assert.Error(t, repo.Revoke("", now.Add(time.Hour)))
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		repo := storage.NewRevokedTokenRepository(setupRevokedTokenDB(t))
+		require.NoError(t, repo.Revoke("expired", now.Add(-time.Minute)))
+		require.NoError(t, repo.Revoke("boundary", now))
+		require.NoError(t, repo.Revoke("live", now.Add(time.Hour)))
+
+		removed, err := repo.DeleteExpired(now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, removed)
+
+		revoked, err := repo.IsRevoked("live", now)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		removed, err = repo.DeleteExpired(now)
+		require.NoError(t, err)
+		assert.Zero(t, removed)
+	})
+
+	t.Run("MaintenanceJob", func(t *testing.T) {
+		repo := storage.NewRevokedTokenRepository(setupRevokedTokenDB(t))
+		require.NoError(t, repo.Revoke("expired", now.Add(-time.Minute)))
+
+		job := maintenance.PruneRevokedTokensJob(repo)
+		assert.Equal(t, maintenance.JobPruneRevokedTokens, job.Name)
+
+		summary, err := job.Run(now)
+		require.NoError(t, err)
+		assert.Equal(t, "removed 1 expired revoked token(s)", summary)
+	})
+}
+
+func TestLogoutRevokesToken(t *testing.T) {
+	users := &loginUserRepository{authUserRepository{users: map[string]models.User{}}}
+	jwtService := auth.NewJWTService("test-secret-key-32-chars-long!!")
+	db := setupRevokedTokenDB(t)
+	sessions := storage.NewSessionRepository(db)
+	authService := auth.NewAuthService(users, sessions, jwtService, auth.DefaultAuthConfig)
+	authService.EnableTokenRevocation(storage.NewRevokedTokenRepository(db))
+	user, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+	require.NoError(t, err)
+	authHandler := api.NewAuthHandler(authService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{Auth: authHandler}, api.RouteConfig{})
+	router.GET("/probe", authHandler.AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	probe := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	login, err := authService.Login(auth.LoginRequest{Email: "alice@example.com", Password: "correct-horse"}, "test-agent", "127.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, probe(login.Token))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, probe(login.Token))
+
+	_, err = authService.RefreshAccessToken(login.RefreshToken, "test-agent", "127.0.0.1")
+	assert.ErrorIs(t, err, auth.ErrRefreshTokenRevoked, "logging out ends the refresh token too")
+
+	// Even with its session back, the token stays refused
+	claims, err := jwtService.ValidateToken(login.Token)
+	require.NoError(t, err)
+	require.NoError(t, sessions.Create(auth.Session{
+		ID:        "restored-session",
+		TokenHash: auth.SessionTokenHash(login.Token),
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		ExpiresAt: claims.ExpiresAt,
+	}))
+	_, err = authService.ValidateToken(login.Token)
+	assert.EqualError(t, err, "token revoked")
+	assert.Equal(t, http.StatusUnauthorized, probe(login.Token))
+}
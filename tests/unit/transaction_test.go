@@ -0,0 +1,381 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTransactor snapshots the in-memory task repository and restores it when
+// the transaction function fails
+type MockTransactor struct {
+	taskRepo      *MockServiceTaskRepository
+	taskLocations hereandnow.TaskLocationRepository
+	notifications hereandnow.NotificationRepository
+	rollbacks     int
+}
+
+func (m *MockTransactor) WithTx(fn func(repos hereandnow.TxRepositories) error) error {
+	snapshot := make(map[string]models.Task, len(m.taskRepo.tasks))
+	for id, task := range m.taskRepo.tasks {
+		snapshot[id] = task
+	}
+
+	err := fn(hereandnow.TxRepositories{
+		Tasks:         m.taskRepo,
+		TaskLocations: m.taskLocations,
+		Notifications: m.notifications,
+	})
+	if err != nil {
+		m.taskRepo.tasks = snapshot
+		m.rollbacks++
+	}
+	return err
+}
+
+// FailingTaskLocationRepository rejects every write
+type FailingTaskLocationRepository struct{}
+
+func (r *FailingTaskLocationRepository) Create(taskLocation models.TaskLocation) error {
+	return fmt.Errorf("insert failed")
+}
+
+func (r *FailingTaskLocationRepository) GetLocationsByTaskID(taskID string) ([]models.Location, error) {
+	return nil, nil
+}
+
+func (r *FailingTaskLocationRepository) Delete(taskID, locationID string) error {
+	return nil
+}
+
+func TestDB_WithTx(t *testing.T) {
+	t.Run("CommitsOnSuccess", func(t *testing.T) {
+		db := setupMetadataDB(t)
+
+		err := db.WithTx(func(tx *storage.DB) error {
+			assert.True(t, tx.InTx())
+			insertTaskWithMetadata(t, tx, "task-1", `{}`)
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = storage.NewTaskRepository(db).GetByID("task-1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("RollsBackEarlierWritesOnFailure", func(t *testing.T) {
+		db := setupMetadataDB(t)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+
+		err := db.WithTx(func(tx *storage.DB) error {
+			repo := storage.NewTaskRepository(tx)
+			task, err := repo.GetByID("task-1")
+			require.NoError(t, err)
+
+			task.Title = "Renamed"
+			require.NoError(t, repo.Update(task))
+
+			// A later step in the same operation fails
+			_, err = tx.Exec(`INSERT INTO missing_table (id) VALUES ('x')`)
+			return err
+		})
+		require.Error(t, err)
+
+		task, err := storage.NewTaskRepository(db).GetByID("task-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Task", task.Title, "task update should have been rolled back")
+	})
+
+	t.Run("NestedWithTxJoinsOuterTransaction", func(t *testing.T) {
+		db := setupMetadataDB(t)
+
+		err := db.WithTx(func(tx *storage.DB) error {
+			err := tx.WithTx(func(inner *storage.DB) error {
+				insertTaskWithMetadata(t, inner, "task-1", `{}`)
+				return nil
+			})
+			require.NoError(t, err)
+			return fmt.Errorf("outer failure")
+		})
+		require.Error(t, err)
+
+		_, err = storage.NewTaskRepository(db).GetByID("task-1")
+		assert.Error(t, err, "inner write should roll back with the outer transaction")
+	})
+}
+
+func TestTaskService_CreateTaskAtomic(t *testing.T) {
+	request := hereandnow.CreateTaskRequest{
+		Title:       "Pick up package",
+		Priority:    3,
+		LocationIDs: []string{"post-office"},
+		Metadata:    json.RawMessage(`{}`),
+	}
+
+	t.Run("FailedLocationInsertRollsBackTask", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service := hereandnow.NewTaskService(repo, nil, nil, &FailingTaskLocationRepository{}, nil)
+		transactor := &MockTransactor{taskRepo: repo, taskLocations: &FailingTaskLocationRepository{}}
+		service.SetTransactor(transactor)
+
+		_, err := service.CreateTask("test-user-id", request)
+		require.Error(t, err)
+
+		assert.Empty(t, repo.tasks, "task should not be persisted")
+		assert.Equal(t, 1, transactor.rollbacks)
+	})
+
+	t.Run("WithoutTransactorStepsAreIndependent", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service := hereandnow.NewTaskService(repo, nil, nil, &FailingTaskLocationRepository{}, nil)
+
+		_, err := service.CreateTask("test-user-id", request)
+		require.Error(t, err)
+
+		assert.Len(t, repo.tasks, 1)
+	})
+}
+
+func TestTaskService_ReassignAtomic(t *testing.T) {
+	t.Run("FailedNotificationInsertRollsBackTaskUpdate", func(t *testing.T) {
+		repo := NewMockServiceTaskRepository()
+		service, notifications := newReassignTaskService(repo)
+		transactor := &MockTransactor{taskRepo: repo, notifications: &MockNotificationRepository{fail: true}}
+		service.SetTransactor(transactor)
+		first := createAssignedTask(t, repo, "Write report", "manager", "alice", models.TaskStatusPending)
+		second := createAssignedTask(t, repo, "Review budget", "manager", "alice", models.TaskStatusPending)
+
+		_, err := service.ReassignUserTasks("admin", hereandnow.ReassignRequest{FromUserID: "alice", ToUserID: "bob"})
+		require.Error(t, err)
+
+		assert.Equal(t, 1, transactor.rollbacks)
+		assert.Equal(t, "alice", *repo.tasks[first.ID].AssigneeID, "task update should have been rolled back")
+		assert.Equal(t, "alice", *repo.tasks[second.ID].AssigneeID)
+		assert.Empty(t, notifications.notifications, "notifications should go through the transaction")
+	})
+}
+
+func setupAssignmentDB(t *testing.T) *storage.DB {
+	db := setupMetadataDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE task_assignments (
+			id TEXT PRIMARY KEY, task_id TEXT NOT NULL, assigned_by TEXT NOT NULL,
+			assigned_to TEXT NOT NULL, assigned_at DATETIME NOT NULL, status TEXT NOT NULL,
+			response_at DATETIME, response_message TEXT
+		);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestDB_WithTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RepositoriesShareTheTransaction", func(t *testing.T) {
+		db := setupAssignmentDB(t)
+		_, err := db.Exec(`CREATE TABLE notifications (
+			id TEXT PRIMARY KEY, user_id TEXT, type TEXT, task_id TEXT,
+			message TEXT, created_at DATETIME, read_at DATETIME,
+			delivered_at DATETIME, delivery_attempts INTEGER NOT NULL DEFAULT 0
+		)`)
+		require.NoError(t, err)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+
+		assignments := storage.NewTaskAssignmentRepository(db)
+		notifications := storage.NewNotificationRepository(db)
+
+		err = db.WithTransaction(ctx, func(tx *storage.Tx) error {
+			assert.True(t, tx.DB().InTx())
+			assignment, err := models.NewTaskAssignment("task-1", "alice", "bob")
+			require.NoError(t, err)
+			if err := assignments.WithTx(tx).Create(*assignment); err != nil {
+				return err
+			}
+			notification, err := models.NewTaskNotification("bob", models.NotificationTypeTaskAssigned, "task-1", "Task")
+			require.NoError(t, err)
+			return notifications.WithTx(tx).Create(*notification)
+		})
+		require.NoError(t, err)
+
+		saved, err := assignments.GetByTaskID("task-1")
+		require.NoError(t, err)
+		assert.Len(t, saved, 1)
+		unread, err := notifications.GetByUserID("bob", true)
+		require.NoError(t, err)
+		assert.Len(t, unread, 1)
+	})
+
+	t.Run("FailedNotificationInsertRollsBackAssignment", func(t *testing.T) {
+		// No notifications table, so the notification insert fails
+		db := setupAssignmentDB(t)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+		assignments := storage.NewTaskAssignmentRepository(db)
+
+		err := db.WithTransaction(ctx, func(tx *storage.Tx) error {
+			assignment, err := models.NewTaskAssignment("task-1", "alice", "bob")
+			require.NoError(t, err)
+			require.NoError(t, assignments.WithTx(tx).Create(*assignment))
+
+			notification, err := models.NewTaskNotification("bob", models.NotificationTypeTaskAssigned, "task-1", "Task")
+			require.NoError(t, err)
+			return storage.NewNotificationRepository(db).WithTx(tx).Create(*notification)
+		})
+		require.Error(t, err)
+
+		saved, err := assignments.GetByTaskID("task-1")
+		require.NoError(t, err)
+		assert.Empty(t, saved, "assignment should have been rolled back")
+	})
+
+	t.Run("RollsBackOnPanic", func(t *testing.T) {
+		db := setupMetadataDB(t)
+
+		assert.Panics(t, func() {
+			db.WithTransaction(ctx, func(tx *storage.Tx) error {
+				insertTaskWithMetadata(t, tx.DB(), "task-1", `{}`)
+				panic("boom")
+			})
+		})
+
+		_, err := storage.NewTaskRepository(db).GetByID("task-1")
+		assert.Error(t, err, "write should roll back when fn panics")
+	})
+
+	t.Run("CancelledContextDoesNotStart", func(t *testing.T) {
+		db := setupMetadataDB(t)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		called := false
+		err := db.WithTransaction(cancelled, func(tx *storage.Tx) error {
+			called = true
+			return nil
+		})
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+
+	t.Run("TaskDeleteJoinsOuterTransaction", func(t *testing.T) {
+		db := setupAssignmentDB(t)
+		_, err := db.Exec(`
+			CREATE TABLE task_dependencies (task_id TEXT, depends_on_task_id TEXT);
+			CREATE TABLE task_locations (task_id TEXT, location_id TEXT);
+		`)
+		require.NoError(t, err)
+		insertTaskWithMetadata(t, db, "task-1", `{}`)
+		tasks := storage.NewTaskRepository(db)
+
+		err = db.WithTransaction(ctx, func(tx *storage.Tx) error {
+			require.NoError(t, tasks.WithTx(tx).Delete("task-1"))
+			return fmt.Errorf("later step failed")
+		})
+		require.Error(t, err)
+
+		_, err = tasks.GetByID("task-1")
+		assert.NoError(t, err, "delete should roll back with the outer transaction")
+	})
+}
+
+// failingNotificationsTransactor runs transactions on a memstore but hands
+// out a notification repository whose inserts fail
+type failingNotificationsTransactor struct {
+	store *memstore.Store
+}
+
+func (f failingNotificationsTransactor) WithTx(fn func(repos hereandnow.TxRepositories) error) error {
+	return f.store.WithTx(func(repos hereandnow.TxRepositories) error {
+		repos.Notifications = &MockNotificationRepository{fail: true}
+		return fn(repos)
+	})
+}
+
+func TestAssignmentService(t *testing.T) {
+	newService := func(t *testing.T) (*hereandnow.AssignmentService, *memstore.Store, models.Task) {
+		task := createTestTask("Mow the lawn", nil, 3)
+		task.CreatorID = "alice"
+		store := memstore.New(memstore.WithTasks(task))
+		service := hereandnow.NewAssignmentService(store.Tasks(), store.TaskAssignments(), store.Notifications())
+		service.SetTransactor(store)
+		return service, store, task
+	}
+
+	t.Run("AssignTask", func(t *testing.T) {
+		service, store, task := newService(t)
+
+		assignment, err := service.AssignTask(task.ID, "bob", "alice")
+		require.NoError(t, err)
+		assert.True(t, assignment.IsPending())
+
+		saved, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+		require.NotNil(t, saved.AssigneeID)
+		assert.Equal(t, "bob", *saved.AssigneeID)
+
+		notifications, err := store.Notifications().GetByUserID("bob", true)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, models.NotificationTypeTaskAssigned, notifications[0].Type)
+	})
+
+	t.Run("FailedNotificationLeavesNoAssignment", func(t *testing.T) {
+		service, store, task := newService(t)
+		service.SetTransactor(failingNotificationsTransactor{store: store})
+
+		_, err := service.AssignTask(task.ID, "bob", "alice")
+		require.Error(t, err)
+
+		assignments, err := store.TaskAssignments().GetByTaskID(task.ID)
+		require.NoError(t, err)
+		assert.Empty(t, assignments, "assignment should not be persisted")
+		saved, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+		assert.Nil(t, saved.AssigneeID)
+	})
+
+	t.Run("CompleteAssignedTask", func(t *testing.T) {
+		service, store, task := newService(t)
+		assignment, err := service.AssignTask(task.ID, "bob", "alice")
+		require.NoError(t, err)
+
+		_, err = service.CompleteAssignedTask(assignment.ID, "alice")
+		assert.ErrorContains(t, err, "only the assignee")
+
+		completed, err := service.CompleteAssignedTask(assignment.ID, "bob")
+		require.NoError(t, err)
+		assert.True(t, completed.IsCompleted())
+
+		accepted, err := store.TaskAssignments().GetByID(assignment.ID)
+		require.NoError(t, err)
+		assert.True(t, accepted.IsAccepted())
+
+		notifications, err := store.Notifications().GetByUserID("alice", true)
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		assert.Equal(t, models.NotificationTypeTaskCompleted, notifications[0].Type)
+	})
+
+	t.Run("FailedNotificationLeavesTaskOpen", func(t *testing.T) {
+		service, store, task := newService(t)
+		assignment, err := service.AssignTask(task.ID, "bob", "alice")
+		require.NoError(t, err)
+		service.SetTransactor(failingNotificationsTransactor{store: store})
+
+		_, err = service.CompleteAssignedTask(assignment.ID, "bob")
+		require.Error(t, err)
+
+		saved, err := store.Tasks().GetByID(task.ID)
+		require.NoError(t, err)
+		assert.False(t, saved.IsCompleted())
+		pending, err := store.TaskAssignments().GetByID(assignment.ID)
+		require.NoError(t, err)
+		assert.True(t, pending.IsPending())
+	})
+}
@@ -0,0 +1,115 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeFilter_TravelTime(t *testing.T) {
+	lat, lng := 37.7749, -122.4194
+	minutes := 30
+
+	// A hundredth of a degree of latitude is about 1112m: 14 minutes on
+	// foot, and two hundredths 27 minutes
+	nearby := *createTestLocation("nearby", "Corner Shop", lat+0.01, lng, "test-user-id")
+	farther := *createTestLocation("farther", "Hardware Store", lat+0.02, lng, "test-user-id")
+	here := *createTestLocation("here", "Home", lat, lng, "test-user-id")
+
+	newFilter := func(config filters.FilterConfig, locations ...models.Location) (*filters.TimeFilter, models.Task) {
+		task := createTestTask("Pick up paint", &minutes, 3)
+		taskLocations := NewMockTaskLocationRepository()
+		taskLocations.SetTaskLocations(task.ID, locations)
+
+		filter := filters.NewTimeFilter(config, NewMockCalendarEventRepository())
+		filter.SetTaskLocationRepository(taskLocations)
+		return filter, task
+	}
+
+	walking := filters.DefaultFilterConfig
+	walking.TravelMode = filters.TravelModeWalking
+
+	t.Run("HidesTaskWithoutTimeToGetThere", func(t *testing.T) {
+		filter, task := newFilter(walking, farther)
+		visible, code, reason := filter.Evaluate(createTestContext(&lat, &lng, 45, 3), task)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonTimeInsufficient, code)
+		assert.Equal(t, "needs 30m task + ~27m travel, you have 45m", reason)
+	})
+
+	t.Run("ShowsTaskWithTimeToGetThere", func(t *testing.T) {
+		filter, task := newFilter(walking, farther)
+		visible, code, reason := filter.Evaluate(createTestContext(&lat, &lng, 60, 3), task)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonTimeFits, code)
+		assert.Equal(t, "task fits in 60 minute window (needs 30m task + ~27m travel)", reason)
+	})
+
+	t.Run("UsesNearestLocation", func(t *testing.T) {
+		filter, task := newFilter(walking, farther, nearby)
+		visible, _, reason := filter.Evaluate(createTestContext(&lat, &lng, 45, 3), task)
+		assert.True(t, visible)
+		assert.Contains(t, reason, "~14m travel")
+	})
+
+	t.Run("NoTravelWithinRadius", func(t *testing.T) {
+		filter, task := newFilter(walking, farther, here)
+		visible, _, reason := filter.Evaluate(createTestContext(&lat, &lng, 30, 3), task)
+		assert.True(t, visible)
+		assert.Equal(t, "task fits in 30 minute window (needs 30)", reason)
+	})
+
+	t.Run("NotCountedWithoutModeOrCoordinates", func(t *testing.T) {
+		filter, task := newFilter(filters.DefaultFilterConfig, farther)
+		visible, _ := filter.Apply(createTestContext(&lat, &lng, 45, 3), task)
+		assert.True(t, visible, "no travel mode")
+
+		filter, task = newFilter(walking, farther)
+		visible, _ = filter.Apply(createTestContext(nil, nil, 45, 3), task)
+		assert.True(t, visible, "no coordinates")
+	})
+
+	t.Run("DrivingSlowsInTraffic", func(t *testing.T) {
+		driving := filters.DefaultFilterConfig
+		driving.TravelMode = filters.TravelModeDriving
+		ctx := createTestContext(&lat, &lng, 45, 3)
+
+		// A fifth of a degree is about 22.2km: 34 minutes at 40 km/h
+		meters := here.DistanceFrom(lat+0.2, lng)
+		assert.Equal(t, 34, driving.TravelMinutes(ctx, meters))
+
+		require.NoError(t, ctx.SetTrafficLevel(models.TrafficHeavy))
+		assert.Equal(t, 67, driving.TravelMinutes(ctx, meters))
+
+		driving.TrafficSpeedFactors = map[string]float64{models.TrafficHeavy: 0.25}
+		assert.Equal(t, 134, driving.TravelMinutes(ctx, meters))
+
+		walking := driving
+		walking.TravelMode = filters.TravelModeWalking
+		walking.WalkingSpeedKmh = 4
+		assert.Equal(t, 334, walking.TravelMinutes(ctx, meters), "walking ignores traffic")
+	})
+}
+
+func TestTimeFilter_TravelTimePreload(t *testing.T) {
+	lat, lng := 37.7749, -122.4194
+	ctx := createTestContext(&lat, &lng, 60, 3)
+	store, tasks := newPreloadStore(t, ctx.Timestamp, 6)
+
+	config := filters.DefaultFilterConfig
+	config.TravelMode = filters.TravelModeWalking
+	counts := lookupCounts{}
+	filter := filters.NewTimeFilter(config, store.CalendarEvents())
+	filter.SetTaskLocationRepository(countingTaskLocations{store.TaskLocations(), counts})
+
+	engine := filters.NewEngine(config, store.FilterAudits())
+	engine.AddRule(filter)
+	_, results := engine.FilterTasks(ctx, tasks)
+	require.Len(t, results, len(tasks))
+
+	assert.Equal(t, 1, counts["GetLocationsByTaskIDs"])
+	assert.Zero(t, counts["GetLocationsByTaskID"])
+}
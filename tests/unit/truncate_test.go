@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/bcnelson/hereAndNow/pkg/truncate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	tasks := make([]models.Task, 200)
+	for i := range tasks {
+		tasks[i] = createTestTask(fmt.Sprintf("Task %d", i+1), nil, 3)
+	}
+	limit := func(n int) *int { return &n }
+
+	t.Run("CapsHumanOutputByDefault", func(t *testing.T) {
+		shown, hidden := truncate.Slice(tasks, truncate.ForFormat("human", nil, truncate.DefaultHumanLimit))
+		assert.Len(t, shown, 25)
+		assert.Equal(t, 175, hidden)
+		assert.Equal(t, "Task 25", shown[24].Title)
+		assert.Equal(t, "... and 175 more (use --limit 0 to show all)", truncate.Footer(hidden))
+	})
+
+	t.Run("LimitZeroShowsAll", func(t *testing.T) {
+		shown, hidden := truncate.Slice(tasks, truncate.ForFormat("human", limit(0), truncate.DefaultHumanLimit))
+		assert.Len(t, shown, 200)
+		assert.Zero(t, hidden)
+		assert.Empty(t, truncate.Footer(hidden))
+	})
+
+	t.Run("MachineFormatsAreNeverImplicitlyTruncated", func(t *testing.T) {
+		for _, format := range []string{"json", "ndjson", "csv"} {
+			shown, hidden := truncate.Slice(tasks, truncate.ForFormat(format, nil, truncate.DefaultHumanLimit))
+			assert.Len(t, shown, 200, format)
+			assert.Zero(t, hidden, format)
+		}
+	})
+
+	t.Run("ExplicitLimitAppliesToEveryFormat", func(t *testing.T) {
+		shown, hidden := truncate.Slice(tasks, truncate.ForFormat("json", limit(10), truncate.DefaultHumanLimit))
+		assert.Len(t, shown, 10)
+		assert.Equal(t, 190, hidden)
+	})
+
+	t.Run("HonoursConfiguredHumanDefault", func(t *testing.T) {
+		shown, _ := truncate.Slice(tasks, truncate.ForFormat("human", nil, 50))
+		assert.Len(t, shown, 50)
+
+		shown, _ = truncate.Slice(tasks, truncate.ForFormat("human", nil, 0))
+		assert.Len(t, shown, 200, "a configured default of 0 shows everything")
+	})
+
+	t.Run("ShortListsAreUntouched", func(t *testing.T) {
+		shown, hidden := truncate.Slice(tasks[:3], truncate.DefaultHumanLimit)
+		assert.Len(t, shown, 3)
+		assert.Zero(t, hidden)
+	})
+}
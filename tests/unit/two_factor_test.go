@@ -0,0 +1,281 @@
+package unit
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/internal/api"
+	"github.com/bcnelson/hereAndNow/internal/auth"
+	"github.com/bcnelson/hereAndNow/internal/storage"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserTOTP(t *testing.T) {
+	t.Run("MatchesRFC6238", func(t *testing.T) {
+		secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+		code, err := models.GenerateTOTPCode(secret, time.Unix(59, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "287082", code)
+
+		code, err = models.GenerateTOTPCode(secret, time.Unix(1111111109, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "081804", code)
+	})
+
+	t.Run("EnableReturnsProvisioningURI", func(t *testing.T) {
+		user := &models.User{Email: "alice@example.com", TOTPEnabled: true}
+
+		secret, uri, err := user.EnableTOTP()
+		require.NoError(t, err)
+		require.NotNil(t, user.TOTPSecret)
+		assert.Equal(t, secret, *user.TOTPSecret)
+		assert.False(t, user.TOTPEnabled, "a new secret must be confirmed first")
+
+		parsed, err := url.Parse(uri)
+		require.NoError(t, err)
+		assert.Equal(t, "otpauth", parsed.Scheme)
+		assert.Equal(t, "totp", parsed.Host)
+		assert.Equal(t, "/HereAndNow:alice@example.com", parsed.Path)
+		assert.Equal(t, secret, parsed.Query().Get("secret"))
+		assert.Equal(t, "HereAndNow", parsed.Query().Get("issuer"))
+	})
+
+	t.Run("AcceptsOneStepEitherSide", func(t *testing.T) {
+		user := &models.User{}
+		secret, _, err := user.EnableTOTP()
+		require.NoError(t, err)
+		now := time.Now()
+
+		for step, accepted := range map[int]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+			code, err := models.GenerateTOTPCode(secret, now.Add(time.Duration(step)*models.TOTPPeriod))
+			require.NoError(t, err)
+			assert.Equal(t, accepted, user.VerifyTOTPAt(code, now), "step %d", step)
+		}
+
+		code, err := models.GenerateTOTPCode(secret, now)
+		require.NoError(t, err)
+		assert.True(t, user.VerifyTOTP(code))
+		assert.False(t, user.VerifyTOTP(""))
+		assert.False(t, (&models.User{}).VerifyTOTP(code), "no secret, no code")
+	})
+
+	t.Run("SecretIsNeverMarshaled", func(t *testing.T) {
+		user := &models.User{ID: "test-user-id", PasswordHash: "hash"}
+		secret, _, err := user.EnableTOTP()
+		require.NoError(t, err)
+
+		data, err := json.Marshal(user)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), secret)
+		assert.NotContains(t, string(data), "totp_secret")
+	})
+}
+
+func setupTOTPDB(t *testing.T) *storage.DB {
+	db, err := storage.NewDB(storage.Config{Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE users (id TEXT PRIMARY KEY NOT NULL, totp_secret TEXT NULL, totp_enabled BOOLEAN NOT NULL DEFAULT FALSE);
+		INSERT INTO users (id) VALUES ('test-user-id');
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestTOTPRepository(t *testing.T) {
+	db := setupTOTPDB(t)
+	repo, err := storage.NewTOTPRepository(db, "server-key")
+	require.NoError(t, err)
+	secret := "JBSWY3DPEHPK3PXP"
+
+	require.NoError(t, repo.SaveTOTP("test-user-id", &secret, true))
+
+	stored, enabled, err := repo.GetTOTP("test-user-id")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, secret, *stored)
+	assert.True(t, enabled)
+
+	var raw string
+	require.NoError(t, db.QueryRow(`SELECT totp_secret FROM users WHERE id = 'test-user-id'`).Scan(&raw))
+	assert.NotContains(t, raw, secret, "the secret is encrypted at rest")
+
+	other, err := storage.NewTOTPRepository(db, "other-key")
+	require.NoError(t, err)
+	_, _, err = other.GetTOTP("test-user-id")
+	assert.Error(t, err, "another key cannot read the secret")
+
+	require.NoError(t, repo.SaveTOTP("test-user-id", nil, true))
+	stored, enabled, err = repo.GetTOTP("test-user-id")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	assert.False(t, enabled)
+
+	assert.Error(t, repo.SaveTOTP("missing", &secret, false))
+	_, err = storage.NewTOTPRepository(db, "")
+	assert.Error(t, err)
+}
+
+// memoryTOTPRepository keeps TOTP secrets in a map
+type memoryTOTPRepository struct {
+	secrets map[string]*string
+	enabled map[string]bool
+}
+
+func (r *memoryTOTPRepository) GetTOTP(userID string) (*string, bool, error) {
+	return r.secrets[userID], r.enabled[userID], nil
+}
+
+func (r *memoryTOTPRepository) SaveTOTP(userID string, secret *string, enabled bool) error {
+	r.secrets[userID] = secret
+	r.enabled[userID] = enabled
+	return nil
+}
+
+// loginUserRepository finds users by email so Login can find them
+type loginUserRepository struct {
+	authUserRepository
+}
+
+func (r *loginUserRepository) GetByEmail(email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found: %s", email)
+}
+
+func TestTwoFactorRoutes(t *testing.T) {
+	users := &loginUserRepository{authUserRepository{users: map[string]models.User{}}}
+	authService := auth.NewAuthService(users, storage.NewSessionRepository(setupSessionDB(t)),
+		auth.NewJWTService("test-secret-key-32-chars-long!!"), auth.DefaultAuthConfig)
+	totp := &memoryTOTPRepository{secrets: map[string]*string{}, enabled: map[string]bool{}}
+	authService.EnableTwoFactor(totp)
+
+	user, err := authService.Register(auth.RegisterRequest{Email: "alice@example.com", Password: "correct-horse", FirstName: "Alice"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.SetupRoutes(router, api.Handlers{Auth: api.NewAuthHandler(authService)}, api.RouteConfig{})
+
+	authed := func(method, path, token, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+	login := func() map[string]interface{} {
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/login", `{"username":"alice@example.com","password":"correct-horse"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	currentCode := func(secret string) string {
+		code, err := models.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+		return code
+	}
+
+	token := login()["token"].(string)
+
+	// Enrolling does not change login until a code is confirmed
+	code, body := authed(http.MethodPost, "/api/v1/auth/2fa/enable", token, "")
+	require.Equal(t, http.StatusOK, code, body)
+	var setup api.TwoFactorSetupResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &setup))
+	assert.True(t, strings.HasPrefix(setup.OTPAuthURL, "otpauth://totp/"))
+	assert.Contains(t, login(), "token")
+
+	code, _ = authed(http.MethodPost, "/api/v1/auth/2fa/verify", token, `{"code":"000000"}`)
+	if currentCode(setup.Secret) != "000000" {
+		assert.Equal(t, http.StatusBadRequest, code)
+	}
+	code, body = authed(http.MethodPost, "/api/v1/auth/2fa/verify", token, `{"code":"`+currentCode(setup.Secret)+`"}`)
+	require.Equal(t, http.StatusNoContent, code, body)
+	assert.True(t, totp.enabled[user.ID])
+
+	code, _ = authed(http.MethodPost, "/api/v1/auth/2fa/enable", token, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	t.Run("LoginRequiresSecondStep", func(t *testing.T) {
+		challenge := login()
+		assert.Equal(t, true, challenge["two_factor_required"])
+		assert.NotContains(t, challenge, "token")
+		require.NotEmpty(t, challenge["challenge"])
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/2fa/login",
+			`{"challenge":"`+challenge["challenge"].(string)+`","code":"`+currentCode(setup.Secret)+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response api.LoginResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.Token)
+		assert.True(t, response.User.TOTPEnabled)
+		assert.NotContains(t, w.Body.String(), setup.Secret)
+
+		// The challenge is spent
+		w = serveRequest(router, http.MethodPost, "/api/v1/auth/2fa/login",
+			`{"challenge":"`+challenge["challenge"].(string)+`","code":"`+currentCode(setup.Secret)+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("ChallengeLocksAfterWrongCodes", func(t *testing.T) {
+		challenge := login()["challenge"].(string)
+		wrong := "000000"
+		if currentCode(setup.Secret) == wrong {
+			wrong = "111111"
+		}
+		for i := 0; i < auth.TwoFactorMaxAttempts; i++ {
+			w := serveRequest(router, http.MethodPost, "/api/v1/auth/2fa/login", `{"challenge":"`+challenge+`","code":"`+wrong+`"}`)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/2fa/login",
+			`{"challenge":"`+challenge+`","code":"`+currentCode(setup.Secret)+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("DisableNeedsCode", func(t *testing.T) {
+		code, _ := authed(http.MethodPost, "/api/v1/auth/2fa/disable", token, `{}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, body := authed(http.MethodPost, "/api/v1/auth/2fa/disable", token, `{"code":"`+currentCode(setup.Secret)+`"}`)
+		require.Equal(t, http.StatusNoContent, code, body)
+		assert.Nil(t, totp.secrets[user.ID])
+		assert.Contains(t, login(), "token")
+	})
+
+	t.Run("NotAvailable", func(t *testing.T) {
+		plain := auth.NewAuthService(users, storage.NewSessionRepository(setupSessionDB(t)),
+			auth.NewJWTService("test-secret-key-32-chars-long!!"), auth.DefaultAuthConfig)
+		router := gin.New()
+		api.SetupRoutes(router, api.Handlers{
+			Auth: api.NewAuthHandler(plain),
+			AuthMiddleware: func(c *gin.Context) {
+				c.Set("user_id", user.ID)
+				c.Next()
+			},
+		}, api.RouteConfig{})
+
+		w := serveRequest(router, http.MethodPost, "/api/v1/auth/2fa/enable", "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
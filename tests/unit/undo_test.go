@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskService_Undo(t *testing.T) {
+	home := *createTestLocation("home-id", "Home", 37.7749, -122.4194, "test-user-id")
+
+	t.Run("RevertsComplete", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		service.EnableUndo(store.TaskActions())
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+		_, err = service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+
+		action, err := service.Undo("test-user-id")
+		require.NoError(t, err)
+		require.NotNil(t, action)
+		assert.Equal(t, models.TaskActionComplete, action.Type)
+
+		reverted, err := service.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusPending, reverted.Status)
+		assert.Nil(t, reverted.CompletedAt)
+	})
+
+	t.Run("RestoresDeletedTask", func(t *testing.T) {
+		store := memstore.New(memstore.WithLocations(home))
+		service, _ := newMemstoreServices(store)
+		service.EnableUndo(store.TaskActions())
+
+		req := memstoreTaskRequest("Water plants")
+		req.LocationIDs = []string{home.ID}
+		task, err := service.CreateTask("test-user-id", req)
+		require.NoError(t, err)
+
+		require.NoError(t, service.DeleteTask(task.ID, "test-user-id"))
+		_, err = service.GetTask(task.ID)
+		require.Error(t, err)
+
+		action, err := service.Undo("test-user-id")
+		require.NoError(t, err)
+		require.NotNil(t, action)
+		assert.Equal(t, models.TaskActionDelete, action.Type)
+
+		restored, err := service.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Water plants", restored.Title)
+
+		locations, err := store.TaskLocations().GetLocationsByTaskID(task.ID)
+		require.NoError(t, err)
+		require.Len(t, locations, 1)
+		assert.Equal(t, home.ID, locations[0].ID)
+	})
+
+	t.Run("ClearsSnooze", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		service.EnableUndo(store.TaskActions())
+
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+		_, err = service.SnoozeTask(task.ID, "test-user-id", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+
+		unsnoozed, err := service.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Nil(t, unsnoozed.SnoozedUntil)
+	})
+
+	t.Run("NothingToUndo", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		service.EnableUndo(store.TaskActions())
+
+		action, err := service.Undo("test-user-id")
+		require.NoError(t, err)
+		assert.Nil(t, action)
+
+		// Creating a task is not reversible, and an undo is only done once
+		task, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+		action, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+		assert.Nil(t, action)
+
+		_, err = service.CompleteTask(task.ID, "test-user-id")
+		require.NoError(t, err)
+		_, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+		action, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+		assert.Nil(t, action)
+	})
+
+	t.Run("OnlyTheLastActionIsKept", func(t *testing.T) {
+		store := memstore.New()
+		service, _ := newMemstoreServices(store)
+		service.EnableUndo(store.TaskActions())
+
+		first, err := service.CreateTask("test-user-id", memstoreTaskRequest("Call bank"))
+		require.NoError(t, err)
+		second, err := service.CreateTask("test-user-id", memstoreTaskRequest("Buy milk"))
+		require.NoError(t, err)
+		_, err = service.CompleteTask(first.ID, "test-user-id")
+		require.NoError(t, err)
+		_, err = service.CompleteTask(second.ID, "test-user-id")
+		require.NoError(t, err)
+
+		_, err = service.Undo("test-user-id")
+		require.NoError(t, err)
+		action, err := service.Undo("test-user-id")
+		require.NoError(t, err)
+		assert.Nil(t, action)
+
+		stillCompleted, err := service.GetTask(first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.TaskStatusCompleted, stillCompleted.Status)
+	})
+}
@@ -550,10 +550,17 @@ func TestContextValidation(t *testing.T) {
 		
 		for _, socialCtx := range validSocialContexts {
 			context.SocialContext = socialCtx
-			// In a real implementation, this would be validated
-			// For now, we just ensure the constants are accessible
-			assert.NotEmpty(t, socialCtx)
+			assert.NoError(t, context.Validate(), socialCtx)
 		}
+		
+		for _, socialCtx := range []string{"", "sleeping", "Alone"} {
+			context.SocialContext = socialCtx
+			err := context.Validate()
+			require.Error(t, err, socialCtx)
+			assert.Contains(t, err.Error(), "social context")
+		}
+		
+		assert.Error(t, context.SetSocialContext("at_the_gym"))
 	})
 }
 
@@ -716,4 +723,29 @@ func TestValidationEdgeCases(t *testing.T) {
 		_, err = models.NewLocation("user-id", longString, "", 37.7749, -122.4194, 100)
 		assert.Error(t, err, "Very long location name should be rejected")
 	})
-}
\ No newline at end of file
+}
+
+func TestTaskValidation_FieldErrors(t *testing.T) {
+	t.Run("ReportsEveryInvalidField", func(t *testing.T) {
+		minutes := -5
+		task := models.Task{Status: models.TaskStatusPending, Priority: 9, EstimatedMinutes: &minutes}
+
+		err := task.Validate()
+		var validationErr *models.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+
+		assert.Equal(t, map[string]string{
+			"title":             "required",
+			"creator_id":        "required",
+			"priority":          "must be between 1 and 5",
+			"estimated_minutes": "must be positive",
+		}, validationErr.Fields)
+		assert.Equal(t, "validation failed: creator_id: required; estimated_minutes: must be positive; priority: must be between 1 and 5; title: required", err.Error())
+	})
+
+	t.Run("ValidTaskHasNoError", func(t *testing.T) {
+		task, err := models.NewTask("Buy milk", "", "user-id")
+		require.NoError(t, err)
+		assert.NoError(t, task.Validate())
+	})
+}
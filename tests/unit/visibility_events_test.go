@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bcnelson/hereAndNow/pkg/hereandnow"
+	"github.com/bcnelson/hereAndNow/pkg/memstore"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func taskAvailableNotifications(t *testing.T, store *memstore.Store) []models.Notification {
+	notifications, err := store.Notifications().GetByUserID("test-user-id", false)
+	require.NoError(t, err)
+
+	var available []models.Notification
+	for _, notification := range notifications {
+		if notification.Type == models.NotificationTypeTaskAvailable {
+			available = append(available, notification)
+		}
+	}
+	return available
+}
+
+func TestVisibilityEvents(t *testing.T) {
+	store := memstore.New()
+	taskService, contextService := newMemstoreServices(store)
+	taskService.EnableVisibilityEvents(store.TaskVisibility(), store.Notifications())
+
+	_, err := contextService.UpdateUserContext("test-user-id", hereandnow.UpdateContextRequest{AvailableMinutes: 60, EnergyLevel: 3})
+	require.NoError(t, err)
+
+	draft, err := taskService.CreateTask("test-user-id", memstoreTaskRequest("Draft report"))
+	require.NoError(t, err)
+
+	send := memstoreTaskRequest("Send report")
+	send.Dependencies = []hereandnow.TaskDependencyRequest{{DependsOnTaskID: draft.ID, DependencyType: models.DependencyTypeBlocking}}
+	blocked, err := taskService.CreateTask("test-user-id", send)
+	require.NoError(t, err)
+
+	visible, _, err := taskService.GetFilteredTasks("test-user-id")
+	require.NoError(t, err)
+	require.Len(t, visible, 1)
+	assert.Empty(t, taskAvailableNotifications(t, store), "first sighting is recorded without an event")
+
+	_, err = taskService.CompleteTask(draft.ID, "test-user-id")
+	require.NoError(t, err)
+
+	t.Run("BecomingVisibleEmitsOneEvent", func(t *testing.T) {
+		visible, _, err := taskService.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		require.Len(t, visible, 2)
+
+		events := taskAvailableNotifications(t, store)
+		require.Len(t, events, 1)
+		assert.Equal(t, blocked.ID, *events[0].TaskID)
+		assert.Equal(t, "Now available: Send report", events[0].Message)
+	})
+
+	t.Run("StayingVisibleEmitsNone", func(t *testing.T) {
+		_, _, err := taskService.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+		_, _, err = taskService.GetFilteredTasks("test-user-id")
+		require.NoError(t, err)
+
+		assert.Len(t, taskAvailableNotifications(t, store), 1)
+	})
+}
+
+func TestTaskVisibility_Observe(t *testing.T) {
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	t.Run("HiddenToVisibleAnnounces", func(t *testing.T) {
+		visibility, err := models.NewTaskVisibility("test-user-id", "task-1", false, start)
+		require.NoError(t, err)
+
+		changed, becameVisible := visibility.Observe(true, start.Add(time.Minute))
+		assert.True(t, changed)
+		assert.True(t, becameVisible)
+		assert.Equal(t, start.Add(time.Minute), visibility.ChangedAt)
+	})
+
+	t.Run("UnchangedVerdictIsIgnored", func(t *testing.T) {
+		visibility, err := models.NewTaskVisibility("test-user-id", "task-1", true, start)
+		require.NoError(t, err)
+
+		changed, becameVisible := visibility.Observe(true, start.Add(time.Minute))
+		assert.False(t, changed)
+		assert.False(t, becameVisible)
+		assert.Equal(t, start, visibility.ChangedAt)
+	})
+
+	t.Run("FlappingWithinCooldownAnnouncesOnce", func(t *testing.T) {
+		visibility, err := models.NewTaskVisibility("test-user-id", "task-1", false, start)
+		require.NoError(t, err)
+
+		_, becameVisible := visibility.Observe(true, start)
+		assert.True(t, becameVisible)
+
+		changed, _ := visibility.Observe(false, start.Add(time.Minute))
+		assert.True(t, changed)
+
+		_, becameVisible = visibility.Observe(true, start.Add(2*time.Minute))
+		assert.False(t, becameVisible)
+
+		visibility.Observe(false, start.Add(3*time.Minute))
+		_, becameVisible = visibility.Observe(true, start.Add(models.BecameVisibleCooldown+time.Minute))
+		assert.True(t, becameVisible)
+	})
+
+	t.Run("RequiresIDs", func(t *testing.T) {
+		_, err := models.NewTaskVisibility("", "task-1", true, start)
+		assert.Error(t, err)
+
+		_, err = models.NewTaskVisibility("test-user-id", "", true, start)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bcnelson/hereAndNow/pkg/filters"
+	"github.com/bcnelson/hereAndNow/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeatherFilter(t *testing.T) {
+	config := filters.DefaultFilterConfig
+	config.EnableWeatherFilter = true
+	filter := filters.NewWeatherFilter(config)
+
+	taskNeeding := func(requirement string) models.Task {
+		task := createTestTask("Mow the lawn", nil, 3)
+		task.Metadata = json.RawMessage(`{"weather_requirement": "` + requirement + `"}`)
+		return task
+	}
+	contextIn := func(condition string) models.Context {
+		ctx := createTestContext(nil, nil, 60, 3)
+		ctx.WeatherCondition = &condition
+		return ctx
+	}
+
+	t.Run("DryTaskHiddenInRain", func(t *testing.T) {
+		visible, code, reason := filter.Evaluate(contextIn(models.WeatherRainy), taskNeeding("dry"))
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonWeatherUnsuitable, code)
+		assert.Equal(t, "needs dry weather but it is rainy", reason)
+
+		visible, _, _ = filter.Evaluate(contextIn(models.WeatherStormy), taskNeeding("Dry"))
+		assert.False(t, visible)
+	})
+
+	t.Run("DryTaskShownWhenDry", func(t *testing.T) {
+		visible, code, _ := filter.Evaluate(contextIn(models.WeatherCloudy), taskNeeding("dry"))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonWeatherSuitable, code)
+	})
+
+	t.Run("NamedConditionMustMatch", func(t *testing.T) {
+		visible, _, reason := filter.Evaluate(contextIn(models.WeatherCloudy), taskNeeding("sunny"))
+		assert.False(t, visible)
+		assert.Equal(t, "needs sunny weather but it is cloudy", reason)
+
+		visible, _, _ = filter.Evaluate(contextIn(models.WeatherSunny), taskNeeding("sunny"))
+		assert.True(t, visible)
+	})
+
+	t.Run("IndoorAnyAndMissingRequirementsAlwaysShown", func(t *testing.T) {
+		for _, task := range []models.Task{taskNeeding("indoor"), taskNeeding("any"), createTestTask("Call bank", nil, 3)} {
+			visible, code, _ := filter.Evaluate(contextIn(models.WeatherStormy), task)
+			assert.True(t, visible)
+			assert.Equal(t, filters.ReasonWeatherNotRequired, code)
+		}
+	})
+
+	t.Run("UnknownWeatherShowsTask", func(t *testing.T) {
+		ctx := createTestContext(nil, nil, 60, 3)
+		ctx.WeatherCondition = nil
+		visible, code, _ := filter.Evaluate(ctx, taskNeeding("dry"))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonWeatherUnknown, code)
+	})
+
+	t.Run("UnknownRequirementShowsTask", func(t *testing.T) {
+		visible, code, _ := filter.Evaluate(contextIn(models.WeatherRainy), taskNeeding("balmy"))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonWeatherNotRequired, code)
+	})
+
+	t.Run("OutdoorTaskHiddenInBadWeather", func(t *testing.T) {
+		outdoor := createTestTask("Wash the car", nil, 3)
+		outdoor.Metadata = json.RawMessage(`{"outdoor": true}`)
+
+		visible, code, reason := filter.Evaluate(contextIn(models.WeatherRainy), outdoor)
+		assert.False(t, visible)
+		assert.Equal(t, filters.ReasonWeatherUnsuitable, code)
+		assert.Equal(t, "task requires outdoor conditions, current weather is rainy", reason)
+
+		visible, _, _ = filter.Evaluate(contextIn(models.WeatherSunny), outdoor)
+		assert.True(t, visible)
+
+		ctx := createTestContext(nil, nil, 60, 3)
+		visible, code, _ = filter.Evaluate(ctx, outdoor)
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonWeatherUnknown, code)
+
+		outdoor.Metadata = json.RawMessage(`{"outdoor": false}`)
+		visible, _, _ = filter.Evaluate(contextIn(models.WeatherRainy), outdoor)
+		assert.True(t, visible)
+	})
+
+	t.Run("HideConditionsConfigurable", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.EnableWeatherFilter = true
+		config.WeatherHideConditions = []string{models.WeatherStormy, models.WeatherFoggy}
+		filter := filters.NewWeatherFilter(config)
+
+		outdoor := createTestTask("Fly a kite", nil, 3)
+		outdoor.Metadata = json.RawMessage(`{"outdoor": true}`)
+
+		visible, _, _ := filter.Evaluate(contextIn(models.WeatherRainy), outdoor)
+		assert.True(t, visible, "rain no longer hides outdoor tasks")
+		visible, _, _ = filter.Evaluate(contextIn(models.WeatherFoggy), outdoor)
+		assert.False(t, visible)
+		visible, _, _ = filter.Evaluate(contextIn(models.WeatherFoggy), taskNeeding("dry"))
+		assert.False(t, visible)
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		visible, code, _ := filters.NewWeatherFilter(filters.DefaultFilterConfig).Evaluate(contextIn(models.WeatherRainy), taskNeeding("dry"))
+		assert.True(t, visible)
+		assert.Equal(t, filters.ReasonFilterDisabled, code)
+	})
+}
+
+func TestFilterEngine_WeatherFilter(t *testing.T) {
+	dry := createTestTask("Mow the lawn", nil, 3)
+	dry.Metadata = json.RawMessage(`{"weather_requirement": "dry"}`)
+	indoor := createTestTask("Fold laundry", nil, 3)
+
+	ctx := createTestContext(nil, nil, 60, 3)
+	rainy := models.WeatherRainy
+	ctx.WeatherCondition = &rainy
+
+	t.Run("NotAppliedByDefault", func(t *testing.T) {
+		engine := filters.NewEngine(filters.DefaultFilterConfig, &MockAuditRepo{})
+		visible, results := engine.FilterTasks(ctx, []models.Task{dry, indoor})
+		assert.Len(t, visible, 2)
+		assert.Empty(t, results)
+	})
+
+	t.Run("HidesDryTaskWhenEnabled", func(t *testing.T) {
+		config := filters.DefaultFilterConfig
+		config.EnableWeatherFilter = true
+		engine := filters.NewEngine(config, &MockAuditRepo{})
+
+		visible, results := engine.FilterTasks(ctx, []models.Task{dry, indoor})
+		require.Len(t, visible, 1)
+		assert.Equal(t, "Fold laundry", visible[0].Title)
+		require.Len(t, results, 2)
+		assert.Equal(t, "weather", results[0].FilterName)
+		assert.Equal(t, filters.ReasonWeatherUnsuitable, results[0].Code)
+	})
+
+	t.Run("TogglesAtRuntime", func(t *testing.T) {
+		engine := filters.NewEngine(filters.DefaultFilterConfig, &MockAuditRepo{})
+
+		require.NoError(t, engine.EnableFilter("weather"))
+		visible, _ := engine.FilterTasks(ctx, []models.Task{dry, indoor})
+		assert.Len(t, visible, 1)
+
+		require.NoError(t, engine.DisableFilter("weather"))
+		visible, _ = engine.FilterTasks(ctx, []models.Task{dry, indoor})
+		assert.Len(t, visible, 2)
+	})
+}